@@ -19,17 +19,26 @@ require (
 	github.com/op/go-logging v0.0.0-20160315200505-970db520ece7
 	github.com/orcaman/concurrent-map v1.0.0
 	github.com/patrickmn/go-cache v2.1.0+incompatible
+	github.com/segmentio/kafka-go v0.4.27
 	github.com/stretchr/testify v1.7.0
 	github.com/up9inc/basenine/client/go v0.0.0-20220315070758-3a76cfc4378e
 	github.com/up9inc/mizu/shared v0.0.0
 	github.com/up9inc/mizu/tap v0.0.0
 	github.com/up9inc/mizu/tap/api v0.0.0
 	github.com/up9inc/mizu/tap/extensions/amqp v0.0.0
+	github.com/up9inc/mizu/tap/extensions/cassandra v0.0.0
+	github.com/up9inc/mizu/tap/extensions/dns v0.0.0
 	github.com/up9inc/mizu/tap/extensions/http v0.0.0
 	github.com/up9inc/mizu/tap/extensions/kafka v0.0.0
+	github.com/up9inc/mizu/tap/extensions/mongodb v0.0.0
+	github.com/up9inc/mizu/tap/extensions/mqtt v0.0.0
+	github.com/up9inc/mizu/tap/extensions/mysql v0.0.0
+	github.com/up9inc/mizu/tap/extensions/postgres v0.0.0
 	github.com/up9inc/mizu/tap/extensions/redis v0.0.0
+	github.com/up9inc/mizu/tap/extensions/thrift v0.0.0
 	github.com/wI2L/jsondiff v0.1.1
 	github.com/yalp/jsonpath v0.0.0-20180802001716-5cc68e5049a0
+	golang.org/x/net v0.0.0-20220127200216-cd36cc0744dd
 	k8s.io/api v0.23.3
 	k8s.io/apimachinery v0.23.3
 	k8s.io/client-go v0.23.3
@@ -77,7 +86,6 @@ require (
 	github.com/pierrec/lz4 v2.6.1+incompatible // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
 	github.com/santhosh-tekuri/jsonschema/v5 v5.0.0 // indirect
-	github.com/segmentio/kafka-go v0.4.27 // indirect
 	github.com/tidwall/gjson v1.14.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
@@ -85,7 +93,6 @@ require (
 	github.com/ugorji/go/codec v1.2.6 // indirect
 	github.com/vishvananda/netns v0.0.0-20211101163701-50045581ed74 // indirect
 	golang.org/x/crypto v0.0.0-20220208050332-20e1d8d225ab // indirect
-	golang.org/x/net v0.0.0-20220127200216-cd36cc0744dd // indirect
 	golang.org/x/oauth2 v0.0.0-20211104180415-d3ed0bb246c8 // indirect
 	golang.org/x/sys v0.0.0-20220207234003-57398862261d // indirect
 	golang.org/x/term v0.0.0-20210927222741-03fcf44c2211 // indirect
@@ -116,4 +123,18 @@ replace github.com/up9inc/mizu/tap/extensions/http v0.0.0 => ../tap/extensions/h
 
 replace github.com/up9inc/mizu/tap/extensions/kafka v0.0.0 => ../tap/extensions/kafka
 
+replace github.com/up9inc/mizu/tap/extensions/postgres v0.0.0 => ../tap/extensions/postgres
+
+replace github.com/up9inc/mizu/tap/extensions/mongodb v0.0.0 => ../tap/extensions/mongodb
+
+replace github.com/up9inc/mizu/tap/extensions/mysql v0.0.0 => ../tap/extensions/mysql
+
+replace github.com/up9inc/mizu/tap/extensions/dns v0.0.0 => ../tap/extensions/dns
+
+replace github.com/up9inc/mizu/tap/extensions/mqtt v0.0.0 => ../tap/extensions/mqtt
+
+replace github.com/up9inc/mizu/tap/extensions/thrift v0.0.0 => ../tap/extensions/thrift
+
+replace github.com/up9inc/mizu/tap/extensions/cassandra v0.0.0 => ../tap/extensions/cassandra
+
 replace github.com/up9inc/mizu/tap/extensions/redis v0.0.0 => ../tap/extensions/redis
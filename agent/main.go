@@ -1,14 +1,18 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"path"
 	"strconv"
 	"strings"
 	"syscall"
@@ -16,18 +20,40 @@ import (
 
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/archive"
 	"github.com/up9inc/mizu/agent/pkg/dependency"
 	"github.com/up9inc/mizu/agent/pkg/elastic"
+	"github.com/up9inc/mizu/agent/pkg/entryindex"
+	"github.com/up9inc/mizu/agent/pkg/issues"
+	"github.com/up9inc/mizu/agent/pkg/kafka"
+	"github.com/up9inc/mizu/agent/pkg/keepalive"
+	"github.com/up9inc/mizu/agent/pkg/lifecycle"
+	"github.com/up9inc/mizu/agent/pkg/markers"
+	"github.com/up9inc/mizu/agent/pkg/metrics"
 	"github.com/up9inc/mizu/agent/pkg/middlewares"
+	"github.com/up9inc/mizu/agent/pkg/mirror"
 	"github.com/up9inc/mizu/agent/pkg/models"
 	"github.com/up9inc/mizu/agent/pkg/oas"
+	"github.com/up9inc/mizu/agent/pkg/operator"
+	"github.com/up9inc/mizu/agent/pkg/provenance"
+	"github.com/up9inc/mizu/agent/pkg/querycache"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/relay"
+	"github.com/up9inc/mizu/agent/pkg/retention"
 	"github.com/up9inc/mizu/agent/pkg/routes"
 	"github.com/up9inc/mizu/agent/pkg/servicemap"
+	"github.com/up9inc/mizu/agent/pkg/sinks"
+	"github.com/up9inc/mizu/agent/pkg/summary"
+	"github.com/up9inc/mizu/agent/pkg/tapperauth"
+	"github.com/up9inc/mizu/agent/pkg/trends"
 	"github.com/up9inc/mizu/agent/pkg/up9"
 	"github.com/up9inc/mizu/agent/pkg/utils"
+	"github.com/up9inc/mizu/agent/pkg/watermark"
 
+	"github.com/up9inc/mizu/agent/pkg/alerts"
 	"github.com/up9inc/mizu/agent/pkg/api"
 	"github.com/up9inc/mizu/agent/pkg/app"
+	"github.com/up9inc/mizu/agent/pkg/chatops"
 	"github.com/up9inc/mizu/agent/pkg/config"
 
 	v1 "k8s.io/api/core/v1"
@@ -35,9 +61,13 @@ import (
 	"github.com/gorilla/websocket"
 	"github.com/op/go-logging"
 	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
 	"github.com/up9inc/mizu/shared/logger"
 	"github.com/up9inc/mizu/tap"
 	tapApi "github.com/up9inc/mizu/tap/api"
+	"github.com/up9inc/mizu/tap/capturepolicy"
+	"github.com/up9inc/mizu/tap/diagnose"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
 var tapperMode = flag.Bool("tap", false, "Run in tapper mode without API")
@@ -46,6 +76,7 @@ var standaloneMode = flag.Bool("standalone", false, "Run in standalone tapper an
 var apiServerAddress = flag.String("api-server-address", "", "Address of mizu API server")
 var namespace = flag.String("namespace", "", "Resolve IPs if they belong to resources in this namespace (default is all)")
 var harsReaderMode = flag.Bool("hars-read", false, "Run in hars-read mode")
+var relayMode = flag.Bool("relay", false, "Run in relay mode, forwarding the tappers of a node pool to the API server at --api-server-address")
 var harsDir = flag.String("hars-dir", "", "Directory to read hars from")
 var startTime int64
 
@@ -53,6 +84,7 @@ const (
 	socketConnectionRetries    = 30
 	socketConnectionRetryDelay = time.Second * 2
 	socketHandshakeTimeout     = time.Second * 2
+	heartbeatInterval          = time.Second * 5
 )
 
 func main() {
@@ -63,8 +95,8 @@ func main() {
 
 	app.LoadExtensions()
 
-	if !*tapperMode && !*apiServerMode && !*standaloneMode && !*harsReaderMode {
-		panic("One of the flags --tap, --api or --standalone or --hars-read must be provided")
+	if !*tapperMode && !*apiServerMode && !*standaloneMode && !*harsReaderMode && !*relayMode {
+		panic("One of the flags --tap, --api, --standalone, --hars-read or --relay must be provided")
 	}
 
 	if *standaloneMode {
@@ -75,6 +107,8 @@ func main() {
 		utils.StartServer(runInApiServerMode(*namespace))
 	} else if *harsReaderMode {
 		runInHarReaderMode()
+	} else if *relayMode {
+		runInRelayMode()
 	}
 
 	signalChan := make(chan os.Signal, 1)
@@ -122,8 +156,28 @@ func hostApi(socketHarOutputChannel chan<- *tapApi.OutputChannelItem) *gin.Engin
 
 	routes.QueryRoutes(app)
 	routes.EntriesRoutes(app)
+	routes.ExportRoutes(app)
+	routes.CompareRoutes(app)
+	routes.TrendsRoutes(app)
+	routes.ProvenanceRoutes(app)
 	routes.MetadataRoutes(app)
+	if config.GetFeatures().Metrics {
+		routes.MetricsRoutes(app)
+	}
 	routes.StatusRoutes(app)
+	routes.MaintenanceRoutes(app)
+	routes.IngestionRoutes(app)
+	routes.SavedQueriesRoutes(app)
+	routes.AlertsRoutes(app)
+	routes.SendRoutes(app)
+	routes.ReplayRoutes(app)
+	routes.RedactionRoutes(app)
+	routes.LifecycleRoutes(app)
+	routes.SessionsRoutes(app)
+	if config.Config.ChatOps.IsEnabled() {
+		routes.ChatOpsRoutes(app)
+	}
+	routes.ArchiveRoutes(app)
 
 	return app
 }
@@ -135,8 +189,18 @@ func runInApiServerMode(namespace string) *gin.Engine {
 	app.ConfigureBasenineServer(shared.BasenineHost, shared.BaseninePort, config.Config.MaxDBSizeBytes, config.Config.LogLevel, config.Config.InsertionFilter)
 	startTime = time.Now().UnixNano() / int64(time.Millisecond)
 	api.StartResolving(namespace)
+	if config.Config.DnsResolution {
+		api.StartDnsResolving()
+	}
 
 	enableExpFeatureIfNeeded()
+	lifecycle.GetInstance().SessionStarted(startTime)
+	// maintenance windows are recorded as markers even when the markers watcher isn't started
+	markers.GetInstance().SetEntryIdScheme(config.Config.EntryIdScheme)
+	startMarkersIfNeeded(namespace)
+	startEntryIndexIfNeeded()
+	startTapperAuthenticationIfNeeded()
+	startOperatorIfNeeded()
 
 	syncEntriesConfig := getSyncEntriesConfig()
 	if syncEntriesConfig != nil {
@@ -149,13 +213,16 @@ func runInApiServerMode(namespace string) *gin.Engine {
 }
 
 func runInTapperMode() {
-	logger.Log.Infof("Starting tapper, websocket address: %s", *apiServerAddress)
 	if *apiServerAddress == "" {
 		panic("API server address must be provided with --api-server-address when using --tap")
 	}
+	if relayAddress := getRelayAddress(); relayAddress != "" {
+		*apiServerAddress = relayAddress
+	}
+	logger.Log.Infof("Starting tapper, websocket address: %s", *apiServerAddress)
 
 	hostMode := os.Getenv(shared.HostModeEnvVar) == "1"
-	tapOpts := &tap.TapOpts{HostMode: hostMode}
+	tapOpts := &tap.TapOpts{HostMode: hostMode, CapturePolicySource: getCapturePolicySource()}
 	tapTargets := getTapTargets()
 	if tapTargets != nil {
 		tapOpts.FilterAuthorities = tapTargets
@@ -193,6 +260,17 @@ func runInStandaloneMode() {
 	utils.StartServer(ginApp)
 }
 
+func runInRelayMode() {
+	logger.Log.Infof("Starting relay, websocket address: %s", *apiServerAddress)
+	if *apiServerAddress == "" {
+		panic("API server address must be provided with --api-server-address when using --relay")
+	}
+
+	ginApp := gin.Default()
+	relay.Routes(ginApp, *apiServerAddress, getSocketKeepAliveConfig())
+	utils.StartServer(ginApp)
+}
+
 func runInHarReaderMode() {
 	outputItemsChannel := make(chan *tapApi.OutputChannelItem, 1000)
 	filteredHarChannel := make(chan *tapApi.OutputChannelItem)
@@ -212,7 +290,78 @@ func enableExpFeatureIfNeeded() {
 		serviceMapGenerator := dependency.GetInstance(dependency.ServiceMapGeneratorDependency).(servicemap.ServiceMap)
 		serviceMapGenerator.Enable()
 	}
-	elastic.GetInstance().Configure(config.Config.Elastic)
+	elastic.GetInstance().Configure(config.Config.Elastic, config.Config.Cluster, config.Config.MaxExportQueueDiskSizeBytes)
+	kafka.GetInstance().Configure(config.Config.Kafka, config.Config.Cluster, config.Config.MaxExportQueueDiskSizeBytes)
+	sinks.GetInstance().Configure(config.Config.Sinks, config.Config.Cluster, config.Config.MaxExportQueueDiskSizeBytes)
+	archive.GetInstance().Configure(config.Config.Archive, config.Config.Cluster)
+	querycache.GetInstance().Configure(config.Config.QueryCache)
+	querylimit.GetInstance().Configure(config.Config.QueryLimits)
+	mirror.GetInstance().Configure(config.Config.Mirror)
+	issues.GetInstance().Configure(config.Config.Issues, config.Config.Cluster)
+	alerts.GetInstance().Configure(config.Config.Cluster)
+	trends.GetInstance().Configure()
+	lifecycle.GetInstance().Configure(config.Config.LifecycleWebhooks, config.Config.MizuResourcesNamespace, config.Config.Cluster, config.Config.MaxDBSizeBytes)
+	chatops.GetInstance().Configure(config.Config.ChatOps)
+	watermark.GetInstance().Configure(config.Config.Watermarks)
+	retention.GetInstance().Configure(config.Config.Retention, config.Config.MaxDBSizeBytes)
+	metrics.GetInstance().SetCluster(config.Config.Cluster)
+	provenance.GetInstance().Configure(config.Config.Provenance)
+	if err := summary.Configure(config.Config.Summary); err != nil {
+		logger.Log.Errorf("Error configuring the entry summaries, err: %v", err)
+	}
+}
+
+func startMarkersIfNeeded(namespace string) {
+	if !config.Config.DeploymentMarkers && !config.Config.KubernetesEvents {
+		return
+	}
+
+	watcher, err := markers.NewFromInCluster(namespace)
+	if err != nil {
+		logger.Log.Infof("error creating markers watcher %s", err)
+		return
+	}
+
+	watcher.Start(context.Background(), config.Config.DeploymentMarkers, config.Config.KubernetesEvents)
+}
+
+func startEntryIndexIfNeeded() {
+	if config.Config.EntryIdScheme == shared.EntryIdSchemeIndex {
+		return
+	}
+
+	entryindex.GetInstance().Start()
+}
+
+func startTapperAuthenticationIfNeeded() {
+	if !config.Config.TapperAuthentication {
+		logger.Log.Infof("Tapper authentication is disabled, accepting entries from any tapper connection")
+		return
+	}
+
+	authenticator, err := tapperauth.NewFromInCluster(config.Config.MizuResourcesNamespace)
+	if err != nil {
+		logger.Log.Fatalf("Error creating the tapper authenticator, tapper connections can't be authenticated: %v", err)
+	}
+
+	api.InitTapperAuthenticator(authenticator)
+}
+
+// startOperatorIfNeeded reconciles the tap of the session when mizu runs as an operator, otherwise the cli syncs the
+// tappers with the pods, the session routes control the tap through the operator
+func startOperatorIfNeeded() {
+	if !config.Config.Operator {
+		return
+	}
+
+	tapOperator, err := operator.NewFromInCluster(config.Config)
+	if err != nil {
+		logger.Log.Errorf("Error creating the tap operator, the tap isn't reconciled: %v", err)
+		return
+	}
+	dependency.RegisterGenerator(dependency.SessionTapDependency, func() interface{} { return tapOperator })
+
+	tapOperator.Start(context.Background())
 }
 
 func getSyncEntriesConfig() *shared.SyncEntriesConfig {
@@ -249,6 +398,9 @@ func setUIFlags(uiIndexPath string) error {
 
 	replacedContent := strings.Replace(string(read), "__IS_OAS_ENABLED__", strconv.FormatBool(config.Config.OAS), 1)
 	replacedContent = strings.Replace(replacedContent, "__IS_SERVICE_MAP_ENABLED__", strconv.FormatBool(config.Config.ServiceMap), 1)
+	replacedContent = strings.Replace(replacedContent, "__IS_STATS_ENABLED__", strconv.FormatBool(config.Config.Stats), 1)
+	replacedContent = strings.Replace(replacedContent, "__IS_METRICS_ENABLED__", strconv.FormatBool(config.Config.Metrics), 1)
+	replacedContent = strings.Replace(replacedContent, "__IS_INSIGHTS_ENABLED__", strconv.FormatBool(config.Config.Insights), 1)
 
 	err = ioutil.WriteFile(uiIndexPath, []byte(replacedContent), 0)
 	if err != nil {
@@ -280,6 +432,70 @@ func getTapTargets() []v1.Pod {
 	return tappedAddressesPerNodeDict[nodeName]
 }
 
+// getCapturePolicySource returns the source of the cluster admin's capture policy. The admin publishes it by granting
+// the service accounts the get of its config map in a namespace mizu doesn't write, so a policy that can't be read for
+// lack of permissions wasn't published and a policy that can be read but doesn't exist is missing
+func getCapturePolicySource() capturepolicy.Source {
+	provider, err := kubernetes.NewProviderInCluster()
+	if err != nil {
+		return func() ([]byte, error) {
+			return nil, err
+		}
+	}
+
+	return func() ([]byte, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		configMap, err := provider.GetConfigMap(ctx, shared.CapturePolicyNamespace, shared.CapturePolicyConfigMapName)
+		if k8serrors.IsForbidden(err) {
+			return nil, capturepolicy.ErrNotPublished
+		} else if k8serrors.IsNotFound(err) {
+			return nil, capturepolicy.ErrMissing
+		} else if err != nil {
+			return nil, err
+		}
+
+		content, ok := configMap.Data[shared.CapturePolicyFileName]
+		if !ok {
+			return nil, fmt.Errorf("%w, config map %s/%s has no %s key", capturepolicy.ErrMissing, shared.CapturePolicyNamespace, shared.CapturePolicyConfigMapName, shared.CapturePolicyFileName)
+		}
+
+		return []byte(content), nil
+	}
+}
+
+// getRelayAddress returns the address of the relay the tapper connects through, when the node is in a pool that can't
+// connect to the api server directly
+func getRelayAddress() string {
+	relayAddressesJson := os.Getenv(shared.RelayAddressesPerNodeEnvVar)
+	if relayAddressesJson == "" {
+		return ""
+	}
+
+	var relayAddresses map[string]string
+	if err := json.Unmarshal([]byte(relayAddressesJson), &relayAddresses); err != nil {
+		panic(fmt.Sprintf("env var %s's value of %s is invalid! must be map[string]string %v", shared.RelayAddressesPerNodeEnvVar, relayAddressesJson, err))
+	}
+
+	return relayAddresses[os.Getenv(shared.NodeNameEnvVar)]
+}
+
+// getSocketKeepAliveConfig returns the keepalive of the socket to the api server, with the defaults when the tapper
+// wasn't given one
+func getSocketKeepAliveConfig() shared.SocketKeepAliveConfig {
+	socketKeepAliveJson := os.Getenv(shared.SocketKeepAliveEnvVar)
+	if socketKeepAliveJson == "" {
+		return shared.SocketKeepAliveConfig{PingIntervalSec: shared.DefaultSocketPingIntervalSec, IdleTimeoutSec: shared.DefaultSocketIdleTimeoutSec}
+	}
+	var socketKeepAlive shared.SocketKeepAliveConfig
+	if err := json.Unmarshal([]byte(socketKeepAliveJson), &socketKeepAlive); err != nil {
+		panic(fmt.Sprintf("env var %s's value of %s is invalid! json must match the shared.SocketKeepAliveConfig struct %v", shared.SocketKeepAliveEnvVar, socketKeepAliveJson, err))
+	}
+
+	return socketKeepAlive
+}
+
 func getTrafficFilteringOptions() *tapApi.TrafficFilteringOptions {
 	filteringOptionsJson := os.Getenv(shared.MizuFilteringOptionsEnvVar)
 	if filteringOptionsJson == "" {
@@ -305,19 +521,46 @@ func pipeTapChannelToSocket(connection *websocket.Conn, messageDataChannel <-cha
 		panic("Channel of captured messages is nil")
 	}
 
-	for messageData := range messageDataChannel {
-		marshaledData, err := models.CreateWebsocketTappedEntryMessage(messageData)
-		if err != nil {
-			logger.Log.Errorf("error converting message to json %v, err: %s, (%v,%+v)", messageData, err, err, err)
-			continue
+	// heartbeats are sent from this goroutine as well, since the socket doesn't support concurrent writes
+	heartbeatTicker := time.NewTicker(heartbeatInterval)
+	defer heartbeatTicker.Stop()
+
+	for {
+		var marshaledData []byte
+		var err error
+
+		select {
+		case messageData, ok := <-messageDataChannel:
+			if !ok {
+				return
+			}
+
+			marshaledData, err = models.CreateWebsocketTappedEntryMessage(messageData)
+			if err != nil {
+				logger.Log.Errorf("error converting message to json %v, err: %s, (%v,%+v)", messageData, err, err, err)
+				continue
+			}
+		case <-heartbeatTicker.C:
+			// the api server compares the tapper clock to its own to detect skewed nodes, and exposes the drops as metrics
+			drops := shared.TapperDrops{
+				DroppedTcpStreams:  diagnose.AppStats.DroppedTcpStreamsTotal(),
+				SampledOutEntries:  diagnose.AppStats.SampledOutEntriesTotal(),
+				RateLimitedEntries: diagnose.AppStats.RateLimitedEntriesTotal(),
+			}
+			marshaledData, err = json.Marshal(shared.CreateWebSocketHeartbeatMessage(time.Now().UnixNano()/int64(time.Millisecond), drops))
+			if err != nil {
+				logger.Log.Errorf("error converting heartbeat to json, err: %s", err)
+				continue
+			}
 		}
 
 		// NOTE: This is where the `*tapApi.OutputChannelItem` leaves the code
 		// and goes into the intermediate WebSocket.
 		err = connection.WriteMessage(websocket.TextMessage, marshaledData)
 		if err != nil {
-			logger.Log.Errorf("error sending message through socket server %v, err: %s, (%v,%+v)", messageData, err, err, err)
-			if errors.Is(err, syscall.EPIPE) {
+			logger.Log.Errorf("error sending message through socket server, err: %s, (%v,%+v)", err, err, err)
+			// the socket is closed by the reads when the api server stopped answering the pings
+			if errors.Is(err, syscall.EPIPE) || errors.Is(err, net.ErrClosed) {
 				logger.Log.Warning("detected socket disconnection, reestablishing socket connection")
 				connection, err = dialSocketWithRetry(*apiServerAddress, socketConnectionRetries, socketConnectionRetryDelay)
 				if err != nil {
@@ -346,30 +589,59 @@ func dialSocketWithRetry(socketAddress string, retryAmount int, retryDelay time.
 		Proxy:            http.ProxyFromEnvironment,
 		HandshakeTimeout: socketHandshakeTimeout,
 	}
+	// the api server uses the node name to send messages to a specific tapper
+	socketAddress = fmt.Sprintf("%s?%s=%s", socketAddress, shared.TapperNodeNameQueryParam, url.QueryEscape(os.Getenv(shared.NodeNameEnvVar)))
 	for i := 1; i < retryAmount; i++ {
-		socketConnection, _, err := dialer.Dial(socketAddress, nil)
+		// the token is read on every attempt since the kubelet rotates it
+		socketConnection, response, err := dialer.Dial(socketAddress, getTapperSocketHeader())
 		if err != nil {
+			lastErr = err
+			if response != nil && response.StatusCode == http.StatusUnauthorized {
+				logger.Log.Errorf("socket connection to %s was refused, the api server couldn't authenticate this tapper", socketAddress)
+			}
 			if i < retryAmount {
 				logger.Log.Infof("socket connection to %s failed: %v, retrying %d out of %d in %d seconds...", socketAddress, err, i, retryAmount, retryDelay/time.Second)
 				time.Sleep(retryDelay)
 			}
 		} else {
-			go handleIncomingMessageAsTapper(socketConnection)
+			go handleIncomingMessageAsTapper(socketConnection, keepalive.Start(socketConnection, getSocketKeepAliveConfig()))
 			return socketConnection, nil
 		}
 	}
 	return nil, lastErr
 }
 
-func handleIncomingMessageAsTapper(socketConnection *websocket.Conn) {
+// getTapperSocketHeader watermarks the socket of the tapper so the tappers don't capture it, and adds the projected
+// service account token the api server authenticates the tappers with, tappers that don't have the token mounted
+// (e.g. when tapping docker) connect without it
+func getTapperSocketHeader() http.Header {
+	header := http.Header{}
+	header.Set(tapApi.MizuTrafficHeaderName, tapApi.MizuTrafficTapper)
+
+	token, err := ioutil.ReadFile(shared.TapperTokenDirPath + shared.TapperTokenFileName)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logger.Log.Errorf("Error reading the tapper token, err: %v", err)
+		}
+		return header
+	}
+
+	header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	return header
+}
+
+func handleIncomingMessageAsTapper(socketConnection *websocket.Conn, socketKeepAlive *keepalive.Socket) {
 	for {
 		if _, message, err := socketConnection.ReadMessage(); err != nil {
 			logger.Log.Errorf("error reading message from socket connection, err: %s, (%v,%+v)", err, err, err)
-			if errors.Is(err, syscall.EPIPE) {
-				// socket has disconnected, we can safely stop this goroutine
-				return
+			// the reads of a socket fail for good after an error, closing it makes the next message reconnect
+			if err := socketConnection.Close(); err != nil {
+				logger.Log.Debugf("error closing socket connection, err: %s", err)
 			}
+			return
 		} else {
+			socketKeepAlive.Touch()
+
 			var socketMessageBase shared.WebSocketMessageMetadata
 			if err := json.Unmarshal(message, &socketMessageBase); err != nil {
 				logger.Log.Errorf("Could not unmarshal websocket message %v", err)
@@ -382,6 +654,13 @@ func handleIncomingMessageAsTapper(socketConnection *websocket.Conn) {
 					} else {
 						tap.UpdateTapTargets(tapConfigMessage.TapTargets)
 					}
+				case shared.WebSocketMessageTypeTapperDebug:
+					var tapperDebugMessage *shared.WebSocketTapperDebugMessage
+					if err := json.Unmarshal(message, &tapperDebugMessage); err != nil {
+						logger.Log.Errorf("received unknown message from socket connection: %s, err: %s, (%v,%+v)", string(message), err, err, err)
+					} else {
+						applyTapperDebugConfig(tapperDebugMessage.Config)
+					}
 				default:
 					logger.Log.Warningf("Received socket message of type %s for which no handlers are defined", socketMessageBase.MessageType)
 				}
@@ -390,6 +669,32 @@ func handleIncomingMessageAsTapper(socketConnection *websocket.Conn) {
 	}
 }
 
+func applyTapperDebugConfig(debugConfig shared.TapperDebugConfig) {
+	if debugConfig.LogLevel != "" {
+		if logLevel, err := logging.LogLevel(debugConfig.LogLevel); err != nil {
+			logger.Log.Errorf("Invalid log level %s: %v", debugConfig.LogLevel, err)
+		} else {
+			logger.InitLoggerStd(logLevel)
+			logger.Log.Infof("Log level changed to %v", logLevel)
+		}
+	}
+
+	if debugConfig.StopPacketDump {
+		tap.StopPacketDump()
+	} else if debugConfig.PacketDumpMaxBytes > 0 {
+		if err := os.MkdirAll(shared.DataDirPath, os.ModePerm); err != nil {
+			logger.Log.Errorf("Failed to make dir: %s, err: %v", shared.DataDirPath, err)
+			return
+		}
+
+		dumpPath := path.Join(shared.DataDirPath, fmt.Sprintf("packets-%d.pcap", time.Now().Unix()))
+		duration := time.Duration(debugConfig.PacketDumpDurationSec) * time.Second
+		if err := tap.StartPacketDump(dumpPath, debugConfig.PacketDumpMaxBytes, duration); err != nil {
+			logger.Log.Errorf("Failed starting packet dump: %v", err)
+		}
+	}
+}
+
 func initializeDependencies() {
 	dependency.RegisterGenerator(dependency.ServiceMapGeneratorDependency, func() interface{} { return servicemap.GetDefaultServiceMapInstance() })
 	dependency.RegisterGenerator(dependency.OasGeneratorDependency, func() interface{} { return oas.GetDefaultOasGeneratorInstance() })
@@ -0,0 +1,335 @@
+package alerts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	basenine "github.com/up9inc/basenine/client/go"
+	"github.com/up9inc/mizu/agent/pkg/maintenance"
+	"github.com/up9inc/mizu/agent/pkg/timequery"
+	"github.com/up9inc/mizu/agent/pkg/utils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	FilePath          = shared.DataDirPath + shared.AlertRulesFileName
+	maxRules          = 50
+	maxWindow         = 24 * time.Hour
+	reconnectInterval = 10 * time.Second
+)
+
+var ErrNotFound = errors.New("alert rule not found")
+
+type ruleState struct {
+	rule        shared.AlertRule
+	window      *window
+	fired       int
+	lastFiredAt int64
+	err         string
+	stop        chan struct{}
+}
+
+func (state *ruleState) status(now int64) *shared.AlertRuleStatus {
+	count := state.window.count(now)
+	return &shared.AlertRuleStatus{
+		AlertRule:   state.rule,
+		Count:       count,
+		Firing:      count >= state.rule.Threshold,
+		Fired:       state.fired,
+		LastFiredAt: state.lastFiredAt,
+		Error:       state.err,
+	}
+}
+
+// observe counts an entry stored at now and returns the alert to send when the rule fires
+func (state *ruleState) observe(now int64, entryId string) *shared.Alert {
+	count := state.window.add(now)
+	if count < state.rule.Threshold {
+		return nil
+	}
+	if state.lastFiredAt != 0 && now-state.lastFiredAt < int64(state.rule.CooldownSec)*1000 {
+		return nil
+	}
+
+	state.fired++
+	state.lastFiredAt = now
+	return &shared.Alert{
+		Rule:        state.rule.Name,
+		Query:       state.rule.Query,
+		Count:       count,
+		Threshold:   state.rule.Threshold,
+		WindowSec:   state.rule.WindowSec,
+		LastEntryId: entryId,
+		Timestamp:   now,
+	}
+}
+
+// Manager evaluates the alert rules on the entries as they're stored, every rule streams the entries matching its
+// query from the database and counts them over its window. The rules are kept in a file of the data directory, so
+// they're kept across restarts of the API server
+type Manager struct {
+	mutex    sync.Mutex
+	rules    map[int]*ruleState
+	lastId   int
+	cluster  string
+	filePath string
+	notifier *notifier
+}
+
+var instance *Manager
+var once sync.Once
+
+func GetInstance() *Manager {
+	once.Do(func() {
+		instance = &Manager{rules: make(map[int]*ruleState), notifier: newNotifier()}
+	})
+	return instance
+}
+
+// Configure starts evaluating the saved rules
+func (manager *Manager) Configure(cluster string) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	manager.cluster = cluster
+	manager.filePath = FilePath
+
+	var rules []shared.AlertRule
+	if err := utils.ReadJsonFile(manager.filePath, &rules); err != nil {
+		if !os.IsNotExist(err) {
+			logger.Log.Errorf("Error reading the alert rules from file, err: %v", err)
+		}
+		return
+	}
+
+	for _, rule := range rules {
+		manager.start(rule)
+		if rule.Id > manager.lastId {
+			manager.lastId = rule.Id
+		}
+	}
+	logger.Log.Infof("Evaluating %d alert rules", len(rules))
+}
+
+func (manager *Manager) GetRules() []*shared.AlertRuleStatus {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	now := nowMs()
+	statuses := make([]*shared.AlertRuleStatus, 0, len(manager.rules))
+	for _, state := range manager.rules {
+		statuses = append(statuses, state.status(now))
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Id < statuses[j].Id
+	})
+
+	return statuses
+}
+
+// AddRule validates a rule and starts evaluating it, a zero cooldown is the window of the rule
+func (manager *Manager) AddRule(rule shared.AlertRule) (*shared.AlertRuleStatus, error) {
+	if rule.CooldownSec == 0 {
+		rule.CooldownSec = rule.WindowSec
+	}
+	if err := validateRule(&rule); err != nil {
+		return nil, err
+	}
+
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	if len(manager.rules) >= maxRules {
+		return nil, fmt.Errorf("there can't be more than %d alert rules", maxRules)
+	}
+
+	manager.lastId++
+	rule.Id = manager.lastId
+	state := manager.start(rule)
+	manager.save()
+
+	return state.status(nowMs()), nil
+}
+
+func (manager *Manager) DeleteRule(id int) (*shared.AlertRuleStatus, error) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+
+	state, ok := manager.rules[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	close(state.stop)
+	delete(manager.rules, id)
+	manager.save()
+
+	return state.status(nowMs()), nil
+}
+
+// start must be called while holding the mutex
+func (manager *Manager) start(rule shared.AlertRule) *ruleState {
+	state := &ruleState{
+		rule:   rule,
+		window: newWindow(int64(rule.WindowSec) * 1000),
+		stop:   make(chan struct{}),
+	}
+	manager.rules[rule.Id] = state
+
+	go manager.watch(state)
+	return state
+}
+
+// watch streams the entries of the rule that are stored from now on until the rule is deleted, the connection is
+// opened again when it fails
+func (manager *Manager) watch(state *ruleState) {
+	for {
+		err := manager.stream(state)
+
+		manager.mutex.Lock()
+		if err != nil {
+			state.err = err.Error()
+			logger.Log.Warningf("Error evaluating the alert rule %s, retrying in %v: %v", state.rule.Name, reconnectInterval, err)
+		}
+		manager.mutex.Unlock()
+
+		select {
+		case <-state.stop:
+			return
+		case <-time.After(reconnectInterval):
+		}
+	}
+}
+
+func (manager *Manager) stream(state *ruleState) error {
+	expanded, err := timequery.Expand(state.rule.Query, time.Now())
+	if err != nil {
+		return err
+	}
+	// the entries stored before the rule was started don't count
+	query := fmt.Sprintf("(%s) and timestamp >= %d", expanded.Query, nowMs())
+
+	connection, err := basenine.NewConnection(shared.BasenineHost, shared.BaseninePort)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	manager.mutex.Lock()
+	state.err = ""
+	manager.mutex.Unlock()
+
+	data := make(chan []byte)
+	meta := make(chan []byte)
+	go func() {
+		// the metadata of the stream isn't needed
+		for bytes := range meta {
+			if string(bytes) == basenine.CloseChannel {
+				return
+			}
+		}
+	}()
+	defer func() {
+		meta <- []byte(basenine.CloseChannel)
+	}()
+
+	connection.Query(query, data, meta)
+
+	for {
+		select {
+		case <-state.stop:
+			return nil
+		case bytes := <-data:
+			if string(bytes) == basenine.CloseChannel {
+				return errors.New("the database closed the stream")
+			}
+			manager.entryStored(state, bytes)
+		}
+	}
+}
+
+func (manager *Manager) entryStored(state *ruleState, data []byte) {
+	var entry *tapApi.Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		logger.Log.Debugf("Error parsing an entry of the alert rule %s: %v", state.rule.Name, err)
+		return
+	}
+
+	// the failures are expected during maintenance windows
+	if maintenance.GetInstance().IsActive(entry.Namespace, entry.Timestamp) {
+		return
+	}
+
+	manager.mutex.Lock()
+	alert := state.observe(nowMs(), entry.EntryId)
+	cluster := manager.cluster
+	manager.mutex.Unlock()
+
+	if alert != nil {
+		alert.Cluster = cluster
+		logger.Log.Infof("Alert rule %s fired, %d entries in %ds", alert.Rule, alert.Count, alert.WindowSec)
+		go manager.notifier.send(state.rule, alert)
+	}
+}
+
+// save must be called while holding the mutex
+func (manager *Manager) save() {
+	if manager.filePath == "" {
+		return
+	}
+
+	rules := make([]shared.AlertRule, 0, len(manager.rules))
+	for _, state := range manager.rules {
+		rules = append(rules, state.rule)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].Id < rules[j].Id
+	})
+
+	if err := utils.SaveJsonFile(manager.filePath, rules); err != nil {
+		logger.Log.Errorf("Error saving the alert rules, err: %v", err)
+	}
+}
+
+func validateRule(rule *shared.AlertRule) error {
+	if rule.Name == "" {
+		return errors.New("the name of the rule is missing")
+	}
+	if rule.Query == "" {
+		return errors.New("the query of the rule is missing")
+	}
+	if rule.Threshold <= 0 || rule.Threshold > maxWindowEntries {
+		return fmt.Errorf("the threshold must be between 1 and %d", maxWindowEntries)
+	}
+	if rule.WindowSec <= 0 || time.Duration(rule.WindowSec)*time.Second > maxWindow {
+		return fmt.Errorf("the window must be between 1s and %v", maxWindow)
+	}
+	if rule.CooldownSec < 0 {
+		return errors.New("the cooldown can't be negative")
+	}
+	if rule.WebhookUrl == "" && rule.SlackUrl == "" {
+		return errors.New("a webhook url or a slack url is required to send the alerts to")
+	}
+	for _, rawUrl := range []string{rule.WebhookUrl, rule.SlackUrl} {
+		if rawUrl == "" {
+			continue
+		}
+		if parsedUrl, err := url.Parse(rawUrl); err != nil || (parsedUrl.Scheme != "http" && parsedUrl.Scheme != "https") || parsedUrl.Host == "" {
+			return fmt.Errorf("invalid url %s, an http or https url is required", rawUrl)
+		}
+	}
+
+	return nil
+}
+
+func nowMs() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
@@ -0,0 +1,97 @@
+package alerts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+func TestWindowCount(t *testing.T) {
+	w := newWindow(1000)
+
+	w.add(0)
+	w.add(500)
+	if count := w.add(900); count != 3 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 3, count)
+	}
+	if count := w.count(1200); count != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 2, count)
+	}
+	if count := w.count(5000); count != 0 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 0, count)
+	}
+}
+
+func TestObserveFiresOncePerCooldown(t *testing.T) {
+	state := &ruleState{
+		rule:   shared.AlertRule{Name: "payments-5xx", Threshold: 2, WindowSec: 60, CooldownSec: 120},
+		window: newWindow(60 * 1000),
+	}
+
+	if alert := state.observe(1000, "a"); alert != nil {
+		t.Errorf("expected no alert below the threshold")
+	}
+	alert := state.observe(2000, "b")
+	if alert == nil || alert.Count != 2 || alert.LastEntryId != "b" {
+		t.Fatalf("unexpected alert: %+v", alert)
+	}
+	if alert := state.observe(3000, "c"); alert != nil {
+		t.Errorf("expected no alert during the cooldown")
+	}
+
+	state.observe(122000, "d")
+	if alert := state.observe(123000, "e"); alert == nil {
+		t.Errorf("expected an alert after the cooldown")
+	}
+	if state.fired != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 2, state.fired)
+	}
+}
+
+func TestValidateRule(t *testing.T) {
+	valid := shared.AlertRule{Name: "errors", Query: "response.status >= 500", Threshold: 10, WindowSec: 60, SlackUrl: "https://hooks.slack.com/services/x"}
+	if err := validateRule(&valid); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	invalid := []shared.AlertRule{
+		{Query: "http", Threshold: 1, WindowSec: 60, WebhookUrl: "http://hook"},
+		{Name: "x", Query: "http", Threshold: 0, WindowSec: 60, WebhookUrl: "http://hook"},
+		{Name: "x", Query: "http", Threshold: 1, WindowSec: 0, WebhookUrl: "http://hook"},
+		{Name: "x", Query: "http", Threshold: 1, WindowSec: 60},
+		{Name: "x", Query: "http", Threshold: 1, WindowSec: 60, WebhookUrl: "ftp://hook"},
+	}
+	for _, rule := range invalid {
+		rule := rule
+		if err := validateRule(&rule); err == nil {
+			t.Errorf("expected an error for %+v", rule)
+		}
+	}
+}
+
+func TestNotifierSend(t *testing.T) {
+	var webhookAlert shared.Alert
+	var slackMessage map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.URL.Path == "/slack" {
+			_ = json.NewDecoder(request.Body).Decode(&slackMessage)
+		} else {
+			_ = json.NewDecoder(request.Body).Decode(&webhookAlert)
+		}
+	}))
+	defer server.Close()
+
+	rule := shared.AlertRule{Name: "errors", WebhookUrl: server.URL + "/hook", SlackUrl: server.URL + "/slack"}
+	newNotifier().send(rule, &shared.Alert{Rule: "errors", Query: "response.status >= 500", Count: 12, Threshold: 10, WindowSec: 60})
+
+	if webhookAlert.Rule != "errors" || webhookAlert.Count != 12 {
+		t.Errorf("unexpected webhook alert: %+v", webhookAlert)
+	}
+	expected := ":rotating_light: *errors*: 12 entries matching `response.status >= 500` in the last 1m0s, the threshold is 10"
+	if slackMessage["text"] != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, slackMessage["text"])
+	}
+}
@@ -0,0 +1,68 @@
+package alerts
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const requestTimeout = 10 * time.Second
+
+type notifier struct {
+	client *http.Client
+}
+
+func newNotifier() *notifier {
+	return &notifier{client: &http.Client{Timeout: requestTimeout}}
+}
+
+// send posts the alert to the webhook and to slack, the failures are logged, the rule fires again after its cooldown
+func (notifier *notifier) send(rule shared.AlertRule, alert *shared.Alert) {
+	if rule.WebhookUrl != "" {
+		if err := notifier.post(rule.WebhookUrl, alert); err != nil {
+			logger.Log.Warningf("Error sending the alert of rule %s to its webhook: %v", rule.Name, err)
+		}
+	}
+
+	if rule.SlackUrl != "" {
+		if err := notifier.post(rule.SlackUrl, map[string]string{"text": slackText(alert)}); err != nil {
+			logger.Log.Warningf("Error sending the alert of rule %s to slack: %v", rule.Name, err)
+		}
+	}
+}
+
+func (notifier *notifier) post(url string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	response, err := notifier.client.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
+
+func slackText(alert *shared.Alert) string {
+	text := fmt.Sprintf(":rotating_light: *%s*: %d entries matching `%s` in the last %v, the threshold is %d",
+		alert.Rule, alert.Count, alert.Query, time.Duration(alert.WindowSec)*time.Second, alert.Threshold)
+	if alert.Cluster != "" {
+		text = fmt.Sprintf("%s, on %s", text, alert.Cluster)
+	}
+
+	return text
+}
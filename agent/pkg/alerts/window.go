@@ -0,0 +1,39 @@
+package alerts
+
+// maxWindowEntries bounds the memory of a window, the threshold of a rule can't be higher
+const maxWindowEntries = 100000
+
+// window counts the entries stored in the last duration milliseconds
+type window struct {
+	duration   int64
+	timestamps []int64
+}
+
+func newWindow(duration int64) *window {
+	return &window{duration: duration, timestamps: make([]int64, 0)}
+}
+
+// add counts an entry stored at now, in unix milliseconds, and returns the count of the window
+func (w *window) add(now int64) int {
+	w.prune(now)
+	if len(w.timestamps) >= maxWindowEntries {
+		w.timestamps = w.timestamps[1:]
+	}
+	w.timestamps = append(w.timestamps, now)
+	return len(w.timestamps)
+}
+
+func (w *window) count(now int64) int {
+	w.prune(now)
+	return len(w.timestamps)
+}
+
+func (w *window) prune(now int64) {
+	expired := 0
+	for expired < len(w.timestamps) && w.timestamps[expired] <= now-w.duration {
+		expired++
+	}
+	if expired > 0 {
+		w.timestamps = append(w.timestamps[:0], w.timestamps[expired:]...)
+	}
+}
@@ -11,13 +11,28 @@ import (
 	"strings"
 	"time"
 
+	"github.com/up9inc/mizu/agent/pkg/archive"
+	"github.com/up9inc/mizu/agent/pkg/config"
 	"github.com/up9inc/mizu/agent/pkg/dependency"
 	"github.com/up9inc/mizu/agent/pkg/elastic"
+	"github.com/up9inc/mizu/agent/pkg/entryid"
 	"github.com/up9inc/mizu/agent/pkg/har"
 	"github.com/up9inc/mizu/agent/pkg/holder"
+	"github.com/up9inc/mizu/agent/pkg/ingestion"
+	"github.com/up9inc/mizu/agent/pkg/issues"
+	"github.com/up9inc/mizu/agent/pkg/kafka"
+	"github.com/up9inc/mizu/agent/pkg/lifecycle"
+	"github.com/up9inc/mizu/agent/pkg/maintenance"
+	"github.com/up9inc/mizu/agent/pkg/metrics"
+	"github.com/up9inc/mizu/agent/pkg/mirror"
+	"github.com/up9inc/mizu/agent/pkg/provenance"
 	"github.com/up9inc/mizu/agent/pkg/providers"
+	"github.com/up9inc/mizu/agent/pkg/querycache"
+	"github.com/up9inc/mizu/agent/pkg/retention"
+	"github.com/up9inc/mizu/agent/pkg/watermark"
 
 	"github.com/up9inc/mizu/agent/pkg/servicemap"
+	"github.com/up9inc/mizu/agent/pkg/trends"
 
 	"github.com/up9inc/mizu/agent/pkg/models"
 	"github.com/up9inc/mizu/agent/pkg/oas"
@@ -31,7 +46,11 @@ import (
 	basenine "github.com/up9inc/basenine/client/go"
 )
 
+// the tags are shown in the entries list and queried, longer values are cut
+const maxTagLength = 100
+
 var k8sResolver *resolver.Resolver
+var dnsResolver *resolver.DnsResolver
 
 func StartResolving(namespace string) {
 	errOut := make(chan error, 100)
@@ -40,6 +59,9 @@ func StartResolving(namespace string) {
 		logger.Log.Infof("error creating k8s resolver %s", err)
 		return
 	}
+	if config.Config != nil {
+		res.EnableEnrichment(config.Config.Enrichment)
+	}
 	ctx := context.Background()
 	res.Start(ctx)
 	go func() {
@@ -53,6 +75,13 @@ func StartResolving(namespace string) {
 	holder.SetResolver(res)
 }
 
+// StartDnsResolving annotates the destinations that aren't resolved to a kubernetes name with their hostname
+func StartDnsResolving() {
+	res := resolver.NewDnsResolver()
+	res.Start(context.Background())
+	dnsResolver = res
+}
+
 func StartReadingEntries(harChannel <-chan *tapApi.OutputChannelItem, workingDir *string, extensionsMap map[string]*tapApi.Extension) {
 	if workingDir != nil && *workingDir != "" {
 		startReadingFiles(*workingDir)
@@ -117,11 +146,54 @@ func startReadingChannel(outputItems <-chan *tapApi.OutputChannelItem, extension
 		disableOASValidation = true
 	}
 
+	// the index assigned by the database changes when it's recreated, ulids stay stable across restarts
+	var entryIdGenerator *entryid.Generator
+	if config.Config == nil || config.Config.EntryIdScheme != shared.EntryIdSchemeIndex {
+		entryIdGenerator = entryid.NewGenerator()
+	}
+
 	for item := range outputItems {
+		if !isRecordedProtocol(item.Protocol.Name) {
+			continue
+		}
+
 		extension := extensionsMap[item.Protocol.Name]
 		resolvedSource, resolvedDestionation, namespace := resolveIP(item.ConnectionInfo)
+		if ingestion.GetInstance().IsMuted(item.Protocol.Name, namespace) {
+			continue
+		}
+		keep, shedBodies := watermark.GetInstance().Admit()
+		if !keep {
+			continue
+		}
 		mizuEntry := extension.Dissector.Analyze(item, resolvedSource, resolvedDestionation, namespace)
-		if extension.Protocol.Name == "http" {
+		if shedBodies {
+			watermark.ShedBodies(mizuEntry)
+		}
+		// checked after the analysis since dissectors prefer names from the traffic, like the http/2 authority
+		if dnsResolver != nil && mizuEntry.Destination != nil && mizuEntry.Destination.Name == "" {
+			mizuEntry.Destination.Name = dnsResolver.Resolve(mizuEntry.Destination.IP)
+		}
+		if k8sResolver != nil {
+			enrichEndpoint(mizuEntry.Source)
+			enrichEndpoint(mizuEntry.Destination)
+		}
+		// the entries forwarded from the other clusters of a multi-cluster tap are told apart by their cluster
+		if config.Config != nil {
+			mizuEntry.Cluster = config.Config.Cluster
+		}
+		if config.Config != nil && config.Config.TagHeader != "" && extension.Protocol.Name == "http" {
+			mizuEntry.Tag = getEntryTag(mizuEntry, config.Config.TagHeader)
+		}
+		if entryIdGenerator != nil {
+			if entryId, err := entryIdGenerator.New(mizuEntry.StartTime); err != nil {
+				logger.Log.Errorf("Failed generating entry id: %v", err)
+			} else {
+				mizuEntry.EntryId = entryId
+			}
+		}
+		// the WebSocket frames are http entries without an HTTP pair
+		if extension.Protocol.Name == "http" && mizuEntry.HTTPPair != "" {
 			if !disableOASValidation {
 				var httpPair tapApi.HTTPRequestResponsePair
 				if err := json.Unmarshal([]byte(mizuEntry.HTTPPair), &httpPair); err != nil {
@@ -137,8 +209,18 @@ func startReadingChannel(outputItems <-chan *tapApi.OutputChannelItem, extension
 
 			harEntry, err := har.NewEntry(mizuEntry.Request, mizuEntry.Response, mizuEntry.StartTime, mizuEntry.ElapsedTime)
 			if err == nil {
-				rules, _, _ := models.RunValidationRulesState(*harEntry, mizuEntry.Destination.Name)
-				mizuEntry.Rules = rules
+				// failures are expected during maintenance windows, they aren't reported as failed rules
+				if !maintenance.GetInstance().IsActive(mizuEntry.Namespace, mizuEntry.Timestamp) {
+					rules, _, _ := models.RunValidationRulesState(*harEntry, mizuEntry.Destination.Name)
+					mizuEntry.Rules = rules
+				}
+
+				if item.Protocol.Version != "2.0" {
+					mirror.GetInstance().PushEntry(&harEntry.Request)
+				}
+
+				issues.GetInstance().PushEntry(mizuEntry, harEntry)
+				trends.GetInstance().PushEntry(getEntryService(mizuEntry), harEntry.Request.Method, harEntry.Request.URL, harEntry.Response.Status, int64(harEntry.Time), mizuEntry.Timestamp)
 			}
 
 			entryWSource := oas.EntryWithSource{
@@ -161,14 +243,89 @@ func startReadingChannel(outputItems <-chan *tapApi.OutputChannelItem, extension
 			panic(err)
 		}
 
-		providers.EntryAdded(len(data))
+		features := config.GetFeatures()
+		if features.Stats {
+			providers.EntryAdded(len(data))
+		}
+		lifecycle.GetInstance().EntryStored(len(data))
+		if features.Insights {
+			providers.ConnectionRequestAdded(mizuEntry.Source, mizuEntry.Destination, mizuEntry.StartTime)
+		}
 
 		connection.SendText(string(data))
+		retention.GetInstance().EntryStored(len(data))
+		querycache.GetInstance().EntryAdded()
+		provenance.GetInstance().PushEntry(mizuEntry.EntryId, data)
+		archive.GetInstance().PushEntry(mizuEntry.EntryId, mizuEntry.Timestamp, data)
+		if features.Metrics {
+			pushEntryMetrics(extension, mizuEntry)
+		}
 
-		serviceMapGenerator := dependency.GetInstance(dependency.ServiceMapGeneratorDependency).(servicemap.ServiceMapSink)
-		serviceMapGenerator.NewTCPEntry(mizuEntry.Source, mizuEntry.Destination, &item.Protocol)
+		if config.Config.ServiceMap {
+			serviceMapGenerator := dependency.GetInstance(dependency.ServiceMapGeneratorDependency).(servicemap.ServiceMapSink)
+			serviceMapGenerator.NewTCPEntryWithStatus(mizuEntry.Source, mizuEntry.Destination, &item.Protocol, extension.Dissector.Summarize(mizuEntry).Status)
+		}
 
 		elastic.GetInstance().PushEntry(mizuEntry)
+		kafka.GetInstance().PushEntry(mizuEntry)
+	}
+}
+
+func pushEntryMetrics(extension *tapApi.Extension, mizuEntry *tapApi.Entry) {
+	base := extension.Dissector.Summarize(mizuEntry)
+	metrics.GetInstance().PushEntry(mizuEntry.Protocol.Name, getEntryService(mizuEntry), base.Status, base.Latency)
+}
+
+// getEntryService is the resolved destination of the entry, or its address when it isn't resolved
+func getEntryService(mizuEntry *tapApi.Entry) string {
+	if mizuEntry.Destination == nil {
+		return ""
+	}
+
+	if mizuEntry.Destination.Name != "" {
+		return mizuEntry.Destination.Name
+	}
+	return fmt.Sprintf("%s:%s", mizuEntry.Destination.IP, mizuEntry.Destination.Port)
+}
+
+// getEntryTag is the value of the tag header of the request, the senders mark their own requests with it to find them
+// by the tag query, the value is trimmed to maxTagLength
+func getEntryTag(mizuEntry *tapApi.Entry, tagHeader string) string {
+	headers, ok := mizuEntry.Request["_headers"].([]interface{})
+	if !ok {
+		return ""
+	}
+
+	for _, header := range headers {
+		pair, ok := header.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		name, _ := pair["name"].(string)
+		if !strings.EqualFold(name, tagHeader) {
+			continue
+		}
+
+		value, _ := pair["value"].(string)
+		value = strings.TrimSpace(value)
+		if runes := []rune(value); len(runes) > maxTagLength {
+			value = string(runes[:maxTagLength])
+		}
+		return value
+	}
+
+	return ""
+}
+
+// enrichEndpoint copies the allowlisted labels and annotations of the workload of the endpoint onto its metadata
+func enrichEndpoint(endpoint *tapApi.TCP) {
+	if endpoint == nil {
+		return
+	}
+
+	if metadata := k8sResolver.GetMetadata(endpoint.IP); metadata != nil {
+		endpoint.Metadata = metadata
 	}
 }
 
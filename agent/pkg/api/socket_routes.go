@@ -7,7 +7,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/up9inc/mizu/agent/pkg/config"
+	"github.com/up9inc/mizu/agent/pkg/keepalive"
 	"github.com/up9inc/mizu/agent/pkg/models"
+	"github.com/up9inc/mizu/agent/pkg/summary"
+	"github.com/up9inc/mizu/agent/pkg/tapperauth"
+	"github.com/up9inc/mizu/agent/pkg/timequery"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -23,6 +28,12 @@ func InitExtensionsMap(ref map[string]*tapApi.Extension) {
 	extensionsMap = ref
 }
 
+var tapperAuthenticator *tapperauth.Authenticator // nil when the tappers aren't authenticated
+
+func InitTapperAuthenticator(ref *tapperauth.Authenticator) {
+	tapperAuthenticator = ref
+}
+
 type EventHandlers interface {
 	WebSocketConnect(socketId int, isTapper bool)
 	WebSocketDisconnect(socketId int, isTapper bool)
@@ -34,11 +45,16 @@ type SocketConnection struct {
 	lock          *sync.Mutex
 	eventHandlers EventHandlers
 	isTapper      bool
+	nodeName      string
 }
 
+// WebSocketParams is the first message of a browser socket, MaxBytesPerSec caps the bandwidth of the socket below the
+// configured one, for viewing over slow links, and Adaptive sends the summaries of the full entries that don't fit it
 type WebSocketParams struct {
 	Query             string `json:"query"`
 	EnableFullEntries bool   `json:"enableFullEntries"`
+	MaxBytesPerSec    int64  `json:"maxBytesPerSec"`
+	Adaptive          bool   `json:"adaptive"`
 }
 
 var (
@@ -72,7 +88,17 @@ func WebSocketRoutes(app *gin.Engine, eventHandlers EventHandlers, startTime int
 		SocketGetBrowserHandler(c)
 	})
 
-	app.GET("/wsTapper", func(c *gin.Context) { // TODO: add m2m authentication to this route
+	app.GET("/wsTapper", func(c *gin.Context) {
+		if tapperAuthenticator != nil {
+			userName, err := tapperAuthenticator.Authenticate(c.Request)
+			if err != nil {
+				logger.Log.Warningf("Rejected tapper connection from %s, node: %s, err: %v", c.ClientIP(), c.Query(shared.TapperNodeNameQueryParam), err)
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "tapper authentication failed"})
+				return
+			}
+			logger.Log.Debugf("Authenticated tapper %s, node: %s", userName, c.Query(shared.TapperNodeNameQueryParam))
+		}
+
 		SocketGetTapperHandler(c)
 	})
 }
@@ -89,9 +115,14 @@ func websocketHandler(w http.ResponseWriter, r *http.Request, eventHandlers Even
 	connectedWebsocketIdCounter++
 	socketId := connectedWebsocketIdCounter
 	connectedWebsockets[socketId] = &SocketConnection{connection: ws, lock: &sync.Mutex{}, eventHandlers: eventHandlers, isTapper: isTapper}
+	if isTapper {
+		connectedWebsockets[socketId].nodeName = r.URL.Query().Get(shared.TapperNodeNameQueryParam)
+	}
 
 	websocketIdsLock.Unlock()
 
+	socketKeepAlive := keepalive.Start(ws, getSocketKeepAliveConfig())
+
 	var connection *basenine.Connection
 	var isQuerySet bool
 
@@ -135,13 +166,20 @@ func websocketHandler(w http.ResponseWriter, r *http.Request, eventHandlers Even
 			break
 		}
 
+		socketKeepAlive.Touch()
+
 		if !isTapper && !isQuerySet {
 			if err := json.Unmarshal(msg, &params); err != nil {
 				logger.Log.Errorf("Error: %v", socketId, err)
 			}
 
-			query := params.Query
-			err = basenine.Validate(shared.BasenineHost, shared.BaseninePort, query)
+			// the time operators of a live query are relative to when it starts
+			var query string
+			var expanded *timequery.Expanded
+			if expanded, err = timequery.Expand(params.Query, time.Now()); err == nil {
+				query = expanded.Query
+				err = basenine.Validate(shared.BasenineHost, shared.BaseninePort, query)
+			}
 			if err != nil {
 				toastBytes, _ := models.CreateWebsocketToastMessage(&models.ToastMessage{
 					Type:      "error",
@@ -156,7 +194,19 @@ func websocketHandler(w http.ResponseWriter, r *http.Request, eventHandlers Even
 
 			isQuerySet = true
 
+			streamConfig := &models.StreamConfig{
+				MaxBytesPerSec: negotiateMaxBytesPerSec(params.MaxBytesPerSec, getMaxStreamBytesPerSec()),
+				FullEntries:    params.EnableFullEntries,
+				Adaptive:       params.Adaptive,
+			}
+			streamConfigBytes, _ := models.CreateWebsocketStreamConfigMessage(streamConfig)
+			if err := SendToSocket(socketId, streamConfigBytes); err != nil {
+				logger.Log.Error(err)
+			}
+
 			handleDataChannel := func(c *basenine.Connection, data chan []byte) {
+				limiter := newStreamLimiter(streamConfig.MaxBytesPerSec, time.Now())
+
 				for {
 					bytes := <-data
 
@@ -167,18 +217,42 @@ func websocketHandler(w http.ResponseWriter, r *http.Request, eventHandlers Even
 					var entry *tapApi.Entry
 					err = json.Unmarshal(bytes, &entry)
 
+					now := time.Now()
 					var message []byte
-					if params.EnableFullEntries {
+					if streamConfig.FullEntries {
 						message, _ = models.CreateFullEntryWebSocketMessage(entry)
-					} else {
+						if !limiter.take(len(message), now) {
+							message = nil
+						}
+					}
+
+					// the UI fetches the body of a summarized entry when it's clicked
+					if message == nil && (!streamConfig.FullEntries || streamConfig.Adaptive) {
 						extension := extensionsMap[entry.Protocol.Name]
 						base := extension.Dissector.Summarize(entry)
+						summary.Apply(entry, base)
 						message, _ = models.CreateBaseEntryWebSocketMessage(base)
+						if !limiter.take(len(message), now) {
+							message = nil
+						}
 					}
 
-					if err := SendToSocket(socketId, message); err != nil {
+					if message == nil {
+						limiter.drop()
+					} else if err := SendToSocket(socketId, message); err != nil {
 						logger.Log.Error(err)
 					}
+
+					if dropped := limiter.takeDropped(now); dropped > 0 {
+						toastBytes, _ := models.CreateWebsocketToastMessage(&models.ToastMessage{
+							Type:      "warning",
+							AutoClose: 5000,
+							Text:      fmt.Sprintf("Skipped %d entries to stay within %.1fKB/s", dropped, float64(streamConfig.MaxBytesPerSec)/1000),
+						})
+						if err := SendToSocket(socketId, toastBytes); err != nil {
+							logger.Log.Error(err)
+						}
+					}
 				}
 			}
 
@@ -213,6 +287,24 @@ func websocketHandler(w http.ResponseWriter, r *http.Request, eventHandlers Even
 	}
 }
 
+func getMaxStreamBytesPerSec() int64 {
+	if config.Config == nil {
+		return 0
+	}
+
+	return config.Config.MaxStreamBytesPerSec
+}
+
+// getSocketKeepAliveConfig returns the keepalive of the sockets of the tappers and of the browsers, with the defaults
+// when no config was loaded
+func getSocketKeepAliveConfig() shared.SocketKeepAliveConfig {
+	if config.Config == nil {
+		return shared.SocketKeepAliveConfig{PingIntervalSec: shared.DefaultSocketPingIntervalSec, IdleTimeoutSec: shared.DefaultSocketIdleTimeoutSec}
+	}
+
+	return config.Config.SocketKeepAlive
+}
+
 func socketCleanup(socketId int, socketConnection *SocketConnection) {
 	err := socketConnection.connection.Close()
 	if err != nil {
@@ -226,6 +318,50 @@ func socketCleanup(socketId int, socketConnection *SocketConnection) {
 	socketConnection.eventHandlers.WebSocketDisconnect(socketId, socketConnection.isTapper)
 }
 
+func getTapperNodeName(socketId int) string {
+	websocketIdsLock.Lock()
+	defer websocketIdsLock.Unlock()
+
+	if socketConnection := connectedWebsockets[socketId]; socketConnection != nil {
+		return socketConnection.nodeName
+	}
+
+	return ""
+}
+
+func getConnectedTapperNodeNames() []string {
+	websocketIdsLock.Lock()
+	defer websocketIdsLock.Unlock()
+
+	nodeNames := make([]string, 0)
+	for _, socketConnection := range connectedWebsockets {
+		if socketConnection != nil && socketConnection.isTapper && socketConnection.nodeName != "" {
+			nodeNames = append(nodeNames, socketConnection.nodeName)
+		}
+	}
+
+	return nodeNames
+}
+
+// SendToTapper sends a message to the tapper running on nodeName, tappers identify their node when they connect
+func SendToTapper(nodeName string, message []byte) error {
+	tapperSocketId := -1
+	websocketIdsLock.Lock()
+	for socketId, socketConnection := range connectedWebsockets {
+		if socketConnection != nil && socketConnection.isTapper && socketConnection.nodeName == nodeName {
+			tapperSocketId = socketId
+			break
+		}
+	}
+	websocketIdsLock.Unlock()
+
+	if tapperSocketId == -1 {
+		return fmt.Errorf("no tapper is connected from node %s", nodeName)
+	}
+
+	return SendToSocket(tapperSocketId, message)
+}
+
 func SendToSocket(socketId int, message []byte) error {
 	socketObj := connectedWebsockets[socketId]
 	if socketObj == nil {
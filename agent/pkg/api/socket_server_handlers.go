@@ -4,11 +4,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"sync"
+	"time"
 
+	"github.com/up9inc/mizu/agent/pkg/metrics"
 	"github.com/up9inc/mizu/agent/pkg/models"
 	"github.com/up9inc/mizu/agent/pkg/providers"
 	"github.com/up9inc/mizu/agent/pkg/providers/tappers"
 	"github.com/up9inc/mizu/agent/pkg/up9"
+	"github.com/up9inc/mizu/agent/pkg/watermark"
 
 	tapApi "github.com/up9inc/mizu/tap/api"
 
@@ -32,6 +35,9 @@ func (h *RoutesEventHandlers) WebSocketConnect(socketId int, isTapper bool) {
 	if isTapper {
 		logger.Log.Infof("Websocket event - Tapper connected, socket ID: %d", socketId)
 		tappers.Connected()
+
+		// the pods of the tapper may have changed since its daemon set was applied
+		go sendTapTargets(getTapperNodeName(socketId))
 	} else {
 		logger.Log.Infof("Websocket event - Browser socket connected, socket ID: %d", socketId)
 
@@ -65,7 +71,7 @@ func BroadcastToBrowserClients(message []byte) {
 	}
 }
 
-func (h *RoutesEventHandlers) WebSocketMessage(_ int, message []byte) {
+func (h *RoutesEventHandlers) WebSocketMessage(socketId int, message []byte) {
 	var socketMessageBase shared.WebSocketMessageMetadata
 	err := json.Unmarshal(message, &socketMessageBase)
 	if err != nil {
@@ -73,14 +79,33 @@ func (h *RoutesEventHandlers) WebSocketMessage(_ int, message []byte) {
 	} else {
 		switch socketMessageBase.MessageType {
 		case shared.WebSocketMessageTypeTappedEntry:
+			// the entries are dropped before they're parsed while the API server is under critical pressure
+			if !watermark.GetInstance().AdmitTapped() {
+				return
+			}
+
 			var tappedEntryMessage models.WebSocketTappedEntryMessage
 			err := json.Unmarshal(message, &tappedEntryMessage)
 			if err != nil {
 				logger.Log.Infof("Could not unmarshal message of message type %s %v", socketMessageBase.MessageType, err)
 			} else {
+				if correction := tappers.GetClockSkewCorrection(getTapperNodeName(socketId)); correction != 0 {
+					correctClockSkew(tappedEntryMessage.Data, correction)
+				}
+
 				// NOTE: This is where the message comes back from the intermediate WebSocket to code.
 				h.SocketOutChannel <- tappedEntryMessage.Data
 			}
+		case shared.WebSocketMessageTypeHeartbeat:
+			var heartbeatMessage shared.WebSocketHeartbeatMessage
+			err := json.Unmarshal(message, &heartbeatMessage)
+			if err != nil {
+				logger.Log.Infof("Could not unmarshal message of message type %s %v", socketMessageBase.MessageType, err)
+			} else {
+				tappers.HeartbeatReceived(getTapperNodeName(socketId), heartbeatMessage.Timestamp, time.Now().UnixNano()/int64(time.Millisecond))
+				metrics.GetInstance().SetTapperDrops(getTapperNodeName(socketId), heartbeatMessage.TapperDrops)
+				tappers.SetDrops(getTapperNodeName(socketId), heartbeatMessage.TapperDrops)
+			}
 		case shared.WebSocketMessageTypeUpdateStatus:
 			var statusMessage shared.WebSocketStatusMessage
 			err := json.Unmarshal(message, &statusMessage)
@@ -103,6 +128,16 @@ func (h *RoutesEventHandlers) WebSocketMessage(_ int, message []byte) {
 	}
 }
 
+// correctClockSkew moves the timestamps of an item captured on a skewed node to the api server clock, so latencies
+// between entries of different nodes are comparable
+func correctClockSkew(item *tapApi.OutputChannelItem, correction time.Duration) {
+	item.Timestamp -= correction.Milliseconds()
+	if item.Pair != nil {
+		item.Pair.Request.CaptureTime = item.Pair.Request.CaptureTime.Add(-correction)
+		item.Pair.Response.CaptureTime = item.Pair.Response.CaptureTime.Add(-correction)
+	}
+}
+
 func handleTLSLink(outboundLinkMessage models.WebsocketOutboundLinkMessage) {
 	resolvedNameObject := k8sResolver.Resolve(outboundLinkMessage.Data.DstIP)
 	if resolvedNameObject != nil {
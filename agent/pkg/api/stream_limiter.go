@@ -0,0 +1,81 @@
+package api
+
+import (
+	"time"
+)
+
+const (
+	// a browser socket may burst up to this much of its bandwidth, so single large entries still fit
+	streamLimiterBurst = time.Second
+	// a throttled browser socket is told about the entries it missed at most once in this interval
+	streamDropReportInterval = 10 * time.Second
+)
+
+// streamLimiter caps the bytes per second streamed to a browser socket with a token bucket, a zero cap is unlimited
+type streamLimiter struct {
+	bytesPerSec float64
+	tokens      float64
+	last        time.Time
+	dropped     int
+	lastReport  time.Time
+}
+
+func newStreamLimiter(bytesPerSec int64, now time.Time) *streamLimiter {
+	limiter := &streamLimiter{bytesPerSec: float64(bytesPerSec), last: now, lastReport: now}
+	limiter.tokens = limiter.burst()
+	return limiter
+}
+
+func (limiter *streamLimiter) burst() float64 {
+	return limiter.bytesPerSec * streamLimiterBurst.Seconds()
+}
+
+// take spends the size of a message when the bucket holds enough of the bandwidth for it
+func (limiter *streamLimiter) take(size int, now time.Time) bool {
+	if limiter.bytesPerSec <= 0 {
+		return true
+	}
+
+	limiter.tokens += now.Sub(limiter.last).Seconds() * limiter.bytesPerSec
+	if burst := limiter.burst(); limiter.tokens > burst {
+		limiter.tokens = burst
+	}
+	limiter.last = now
+
+	if float64(size) > limiter.tokens {
+		return false
+	}
+
+	limiter.tokens -= float64(size)
+	return true
+}
+
+func (limiter *streamLimiter) drop() {
+	limiter.dropped++
+}
+
+// takeDropped returns the number of the entries dropped since it last returned, at most once in
+// streamDropReportInterval, or 0
+func (limiter *streamLimiter) takeDropped(now time.Time) int {
+	if limiter.dropped == 0 || now.Sub(limiter.lastReport) < streamDropReportInterval {
+		return 0
+	}
+
+	dropped := limiter.dropped
+	limiter.dropped = 0
+	limiter.lastReport = now
+	return dropped
+}
+
+// negotiateMaxBytesPerSec returns the bandwidth of a browser socket, the lower of the bandwidth the client asked for
+// and the configured one, where 0 is unlimited
+func negotiateMaxBytesPerSec(requested int64, configured int64) int64 {
+	if requested <= 0 {
+		return configured
+	}
+	if configured <= 0 || requested < configured {
+		return requested
+	}
+
+	return configured
+}
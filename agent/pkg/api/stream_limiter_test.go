@@ -0,0 +1,66 @@
+package api
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStreamLimiter(t *testing.T) {
+	now := time.Now()
+	limiter := newStreamLimiter(1000, now)
+
+	if !limiter.take(800, now) {
+		t.Errorf("expected a message within the burst to be taken")
+	}
+	if limiter.take(300, now) {
+		t.Errorf("expected a message over the remaining bandwidth to be refused")
+	}
+	if !limiter.take(300, now.Add(100*time.Millisecond)) {
+		t.Errorf("expected the bandwidth to refill over time")
+	}
+	if limiter.take(1001, now.Add(time.Hour)) {
+		t.Errorf("expected a message larger than the burst to be refused")
+	}
+
+	unlimited := newStreamLimiter(0, now)
+	if !unlimited.take(1<<30, now) {
+		t.Errorf("expected an unlimited limiter to take any message")
+	}
+}
+
+func TestStreamLimiterTakeDropped(t *testing.T) {
+	now := time.Now()
+	limiter := newStreamLimiter(1000, now)
+	limiter.drop()
+	limiter.drop()
+
+	if dropped := limiter.takeDropped(now); dropped != 0 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 0, dropped)
+	}
+	if dropped := limiter.takeDropped(now.Add(streamDropReportInterval)); dropped != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 2, dropped)
+	}
+	if dropped := limiter.takeDropped(now.Add(2 * streamDropReportInterval)); dropped != 0 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 0, dropped)
+	}
+}
+
+func TestNegotiateMaxBytesPerSec(t *testing.T) {
+	tests := []struct {
+		requested  int64
+		configured int64
+		expected   int64
+	}{
+		{requested: 0, configured: 0, expected: 0},
+		{requested: 500, configured: 0, expected: 500},
+		{requested: 0, configured: 1000, expected: 1000},
+		{requested: 500, configured: 1000, expected: 500},
+		{requested: 5000, configured: 1000, expected: 1000},
+	}
+
+	for _, test := range tests {
+		if actual := negotiateMaxBytesPerSec(test.requested, test.configured); actual != test.expected {
+			t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+		}
+	}
+}
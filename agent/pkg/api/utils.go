@@ -2,12 +2,36 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
+	"sync/atomic"
 
 	"github.com/up9inc/mizu/agent/pkg/providers/tappedPods"
 	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/logger"
 )
 
+// BroadcastTapTargets sends the connected tappers the pods of their node, the tappers update their capture without a
+// restart so the pods that live only seconds, like the pods of jobs, are tapped
+func BroadcastTapTargets() {
+	for _, nodeName := range getConnectedTapperNodeNames() {
+		sendTapTargets(nodeName)
+	}
+}
+
+func sendTapTargets(nodeName string) {
+	pods, ok := tappedPods.GetTapTargets(nodeName)
+	if !ok {
+		return
+	}
+
+	message := shared.CreateWebSocketTapConfigMessage(pods)
+	if jsonBytes, err := json.Marshal(message); err != nil {
+		logger.Log.Errorf("Could not Marshal message %v", err)
+	} else if err := SendToTapper(nodeName, jsonBytes); err != nil {
+		logger.Log.Debugf("Couldn't send the tap targets to the tapper of node %s, err: %v", nodeName, err)
+	}
+}
+
 func BroadcastTappedPodsStatus() {
 	tappedPodsStatus := tappedPods.GetTappedPodsStatus()
 
@@ -18,3 +42,29 @@ func BroadcastTappedPodsStatus() {
 		BroadcastToBrowserClients(jsonBytes)
 	}
 }
+
+var recordedProtocols atomic.Value // map[string]bool, nil when every protocol is recorded
+
+// SetRecordedProtocols records only the entries of the protocols, all of them when there are none
+func SetRecordedProtocols(protocols []string) error {
+	if len(protocols) == 0 {
+		recordedProtocols.Store(map[string]bool(nil))
+		return nil
+	}
+
+	protocolsSet := make(map[string]bool)
+	for _, protocol := range protocols {
+		if _, ok := extensionsMap[protocol]; !ok {
+			return fmt.Errorf("unknown protocol %s", protocol)
+		}
+		protocolsSet[protocol] = true
+	}
+
+	recordedProtocols.Store(protocolsSet)
+	return nil
+}
+
+func isRecordedProtocol(protocol string) bool {
+	protocolsSet, _ := recordedProtocols.Load().(map[string]bool)
+	return protocolsSet == nil || protocolsSet[protocol]
+}
@@ -10,12 +10,20 @@ import (
 	basenine "github.com/up9inc/basenine/client/go"
 	"github.com/up9inc/mizu/agent/pkg/api"
 	"github.com/up9inc/mizu/agent/pkg/controllers"
+	"github.com/up9inc/mizu/agent/pkg/markers"
 	"github.com/up9inc/mizu/shared/logger"
 	tapApi "github.com/up9inc/mizu/tap/api"
 	amqpExt "github.com/up9inc/mizu/tap/extensions/amqp"
+	cassandraExt "github.com/up9inc/mizu/tap/extensions/cassandra"
+	dnsExt "github.com/up9inc/mizu/tap/extensions/dns"
 	httpExt "github.com/up9inc/mizu/tap/extensions/http"
 	kafkaExt "github.com/up9inc/mizu/tap/extensions/kafka"
+	mongodbExt "github.com/up9inc/mizu/tap/extensions/mongodb"
+	mqttExt "github.com/up9inc/mizu/tap/extensions/mqtt"
+	mysqlExt "github.com/up9inc/mizu/tap/extensions/mysql"
+	postgresExt "github.com/up9inc/mizu/tap/extensions/postgres"
 	redisExt "github.com/up9inc/mizu/tap/extensions/redis"
+	thriftExt "github.com/up9inc/mizu/tap/extensions/thrift"
 )
 
 var (
@@ -24,7 +32,7 @@ var (
 )
 
 func LoadExtensions() {
-	Extensions = make([]*tapApi.Extension, 4)
+	Extensions = make([]*tapApi.Extension, 11)
 	ExtensionsMap = make(map[string]*tapApi.Extension)
 
 	extensionAmqp := &tapApi.Extension{}
@@ -55,10 +63,66 @@ func LoadExtensions() {
 	Extensions[3] = extensionRedis
 	ExtensionsMap[extensionRedis.Protocol.Name] = extensionRedis
 
+	extensionPostgres := &tapApi.Extension{}
+	dissectorPostgres := postgresExt.NewDissector()
+	dissectorPostgres.Register(extensionPostgres)
+	extensionPostgres.Dissector = dissectorPostgres
+	Extensions[4] = extensionPostgres
+	ExtensionsMap[extensionPostgres.Protocol.Name] = extensionPostgres
+
+	extensionMongodb := &tapApi.Extension{}
+	dissectorMongodb := mongodbExt.NewDissector()
+	dissectorMongodb.Register(extensionMongodb)
+	extensionMongodb.Dissector = dissectorMongodb
+	Extensions[5] = extensionMongodb
+	ExtensionsMap[extensionMongodb.Protocol.Name] = extensionMongodb
+
+	extensionMysql := &tapApi.Extension{}
+	dissectorMysql := mysqlExt.NewDissector()
+	dissectorMysql.Register(extensionMysql)
+	extensionMysql.Dissector = dissectorMysql
+	Extensions[6] = extensionMysql
+	ExtensionsMap[extensionMysql.Protocol.Name] = extensionMysql
+
+	extensionDns := &tapApi.Extension{}
+	dissectorDns := dnsExt.NewDissector()
+	dissectorDns.Register(extensionDns)
+	extensionDns.Dissector = dissectorDns
+	Extensions[7] = extensionDns
+	ExtensionsMap[extensionDns.Protocol.Name] = extensionDns
+
+	extensionMqtt := &tapApi.Extension{}
+	dissectorMqtt := mqttExt.NewDissector()
+	dissectorMqtt.Register(extensionMqtt)
+	extensionMqtt.Dissector = dissectorMqtt
+	Extensions[8] = extensionMqtt
+	ExtensionsMap[extensionMqtt.Protocol.Name] = extensionMqtt
+
+	extensionThrift := &tapApi.Extension{}
+	dissectorThrift := thriftExt.NewDissector()
+	dissectorThrift.Register(extensionThrift)
+	extensionThrift.Dissector = dissectorThrift
+	Extensions[9] = extensionThrift
+	ExtensionsMap[extensionThrift.Protocol.Name] = extensionThrift
+
+	extensionCassandra := &tapApi.Extension{}
+	dissectorCassandra := cassandraExt.NewDissector()
+	dissectorCassandra.Register(extensionCassandra)
+	extensionCassandra.Dissector = dissectorCassandra
+	Extensions[10] = extensionCassandra
+	ExtensionsMap[extensionCassandra.Protocol.Name] = extensionCassandra
+
 	sort.Slice(Extensions, func(i, j int) bool {
 		return Extensions[i].Protocol.Priority < Extensions[j].Protocol.Priority
 	})
 
+	// markers are only summarized and represented, they are never dissected so the tappers don't load them
+	extensionMarkers := &tapApi.Extension{}
+	dissectorMarkers := markers.NewDissector()
+	dissectorMarkers.Register(extensionMarkers)
+	extensionMarkers.Dissector = dissectorMarkers
+	ExtensionsMap[extensionMarkers.Protocol.Name] = extensionMarkers
+
 	controllers.InitExtensionsMap(ExtensionsMap)
 	api.InitExtensionsMap(ExtensionsMap)
 }
@@ -79,7 +143,7 @@ func ConfigureBasenineServer(host string, port string, dbSize int64, logLevel lo
 	}
 
 	// Define the macros
-	for _, extension := range Extensions {
+	for _, extension := range ExtensionsMap {
 		macros := extension.Dissector.Macros()
 		for macro, expanded := range macros {
 			if err := basenine.Macro(host, port, macro, expanded); err != nil {
@@ -0,0 +1,386 @@
+package archive
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/version"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/archiveformat"
+	"github.com/up9inc/mizu/shared/cloudauth"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/objectstorage"
+)
+
+const (
+	contentType   = "application/x-tar"
+	uploadTimeout = 60 * time.Second
+
+	// an archive is cut before its interval once it reaches this compressed size
+	maxArchiveBytes = 64 * 1024 * 1024
+	// archives that failed uploading are retried on the next interval, the oldest are dropped past this count
+	maxPendingArchives = 3
+	retentionInterval  = time.Hour
+
+	healthCheckSink   = "archive"
+	healthCheckObject = "mizu-health-check"
+)
+
+// the permissions of the cloud identity of an archive auth, uploading the archives and deleting the expired ones
+var cloudAuthPermissions = map[string][]string{
+	cloudauth.AuthAws: {"s3:PutObject", "s3:ListBucket", "s3:DeleteObject"},
+	cloudauth.AuthGcp: {"storage.objects.create", "storage.objects.list", "storage.objects.delete"},
+}
+
+type Stats struct {
+	Archives        int    `json:"archives"`
+	ArchivedEntries int    `json:"archivedEntries"`
+	Deleted         int    `json:"deleted"`
+	Failed          int    `json:"failed"`
+	Dropped         int    `json:"dropped"`
+	LastObject      string `json:"lastObject,omitempty"`
+}
+
+type pendingArchive struct {
+	key     string
+	data    []byte
+	entries int
+}
+
+// Archiver uploads the stored entries to a bucket in the background, an archive of the entries of every interval
+type Archiver struct {
+	mutex         sync.Mutex
+	client        *objectstorage.Client
+	url           string
+	auth          string
+	cluster       string
+	prefix        string
+	interval      time.Duration
+	retention     time.Duration
+	writer        *archiveformat.Writer
+	pending       []*pendingArchive
+	lastRetention time.Time
+	stats         Stats
+	full          chan struct{}
+	flushes       chan chan struct{}
+	stop          chan struct{}
+}
+
+var instance *Archiver
+var once sync.Once
+
+func GetInstance() *Archiver {
+	once.Do(func() {
+		instance = &Archiver{}
+	})
+	return instance
+}
+
+// Configure starts archiving to the bucket of the config, the archives of the cluster are kept under its own prefix
+func (archiver *Archiver) Configure(config shared.ArchiveConfig, cluster string) {
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	if archiver.stop != nil {
+		close(archiver.stop)
+		archiver.stop = nil
+	}
+	archiver.client = nil
+
+	if config.Url == "" {
+		logger.Log.Infof("No archive url was supplied, archiving disabled")
+		return
+	}
+
+	location, err := objectstorage.ParseUrl(config.Url)
+	if err != nil {
+		logger.Log.Errorf("Invalid archive url, archiving disabled: %v", err)
+		return
+	}
+
+	client, err := objectstorage.NewClientWithAuth(location, config.Endpoint, config.Region, config.Auth, config.AccessKeyId, config.SecretAccessKey, uploadTimeout)
+	if err != nil {
+		logger.Log.Errorf("Invalid archive endpoint or auth, archiving disabled: %v", err)
+		return
+	}
+
+	archiver.client = client
+	archiver.url = config.Url
+	archiver.auth = config.Auth
+	archiver.cluster = cluster
+	archiver.prefix = path.Join(location.Key, cluster)
+	if archiver.prefix != "" {
+		archiver.prefix += "/"
+	}
+	archiver.interval = time.Duration(config.IntervalSec) * time.Second
+	archiver.retention = time.Duration(config.RetentionDays) * 24 * time.Hour
+	archiver.pending = nil
+	archiver.resetWriter()
+	archiver.full = make(chan struct{}, 1)
+	archiver.flushes = make(chan chan struct{})
+	archiver.stop = make(chan struct{})
+
+	go archiver.run(archiver.client, archiver.interval, archiver.full, archiver.flushes, archiver.stop)
+
+	logger.Log.Infof("Archiving the entries to %s every %v", location, archiver.interval)
+}
+
+// PushEntry adds the json of a stored entry to the current archive, timestamp is the unix milliseconds of the entry
+func (archiver *Archiver) PushEntry(entryId string, timestamp int64, data []byte) {
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	if archiver.client == nil {
+		return
+	}
+
+	if err := archiver.writer.Add(entryId, timestamp, data); err != nil {
+		logger.Log.Debugf("Failed archiving an entry: %v", err)
+		return
+	}
+
+	if archiver.writer.Size() >= maxArchiveBytes {
+		select {
+		case archiver.full <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Flush uploads the current archive without waiting for its interval, false is returned when it didn't finish in
+// time or archiving is disabled
+func (archiver *Archiver) Flush(timeout time.Duration) bool {
+	archiver.mutex.Lock()
+	flushes := archiver.flushes
+	stop := archiver.stop
+	archiver.mutex.Unlock()
+
+	if stop == nil {
+		return false
+	}
+
+	done := make(chan struct{})
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case flushes <- done:
+	case <-stop:
+		return false
+	case <-timer.C:
+		return false
+	}
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		return false
+	}
+}
+
+func (archiver *Archiver) GetStats() *Stats {
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	if archiver.client == nil {
+		return nil
+	}
+
+	stats := archiver.stats
+	return &stats
+}
+
+// CheckHealth validates that the bucket is reachable, accepts the credentials and allows writing, listing and deleting
+// like archiving and the retention do, with a probe object that is deleted right after. nil is returned when archiving
+// isn't configured
+func (archiver *Archiver) CheckHealth() *shared.SinkHealth {
+	archiver.mutex.Lock()
+	client := archiver.client
+	destination := archiver.url
+	auth := archiver.auth
+	key := archiver.prefix + healthCheckObject
+	archiver.mutex.Unlock()
+
+	if client == nil {
+		return nil
+	}
+
+	health := &shared.SinkHealth{Sink: healthCheckSink, Destination: destination}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	// a cloud identity that can't get its credentials fails the authentication, the bucket isn't called without them
+	err := client.Authenticate(ctx)
+	if auth != "" {
+		health.Identity = client.Identity()
+		health.Permissions = cloudAuthPermissions[auth]
+	}
+	if err != nil {
+		health.Connectivity = true
+		health.Error = err.Error()
+		return health
+	}
+
+	if err := client.PutObject(ctx, key, []byte(healthCheckObject), "text/plain"); err != nil {
+		var responseErr *objectstorage.ResponseError
+		if errors.As(err, &responseErr) {
+			health.Connectivity = true
+			health.Authentication = !responseErr.IsAuthenticationFailure()
+		}
+		health.Error = err.Error()
+		return health
+	}
+	health.Connectivity = true
+	health.Authentication = true
+
+	if _, err := client.ListObjects(ctx, key); err != nil {
+		health.Error = fmt.Sprintf("failed listing the archives, %v", err)
+		return health
+	}
+	if err := client.DeleteObject(ctx, key); err != nil {
+		health.Error = fmt.Sprintf("failed deleting the health check object, %v", err)
+		return health
+	}
+	health.Write = true
+
+	return health
+}
+
+func (archiver *Archiver) run(client *objectstorage.Client, interval time.Duration, full <-chan struct{}, flushes <-chan chan struct{}, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		var done chan struct{}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-full:
+		case done = <-flushes:
+		}
+
+		archiver.cut()
+		archiver.upload(client)
+		archiver.deleteExpired(client)
+
+		if done != nil {
+			close(done)
+		}
+	}
+}
+
+// cut closes the current archive and queues it for uploading
+func (archiver *Archiver) cut() {
+	archiver.mutex.Lock()
+	defer archiver.mutex.Unlock()
+
+	writer := archiver.writer
+	if writer.Entries() == 0 {
+		return
+	}
+	archiver.resetWriter()
+
+	var data bytes.Buffer
+	if err := writer.WriteArchive(&data); err != nil {
+		logger.Log.Errorf("Failed writing the archive: %v", err)
+		return
+	}
+
+	// the keys sort by the time of their entries, so an archive can be replayed in order
+	key := fmt.Sprintf("%s%s/%d-%d%s", archiver.prefix, time.UnixMilli(writer.FirstTimestamp()).UTC().Format("2006/01/02"), writer.FirstTimestamp(), writer.LastTimestamp(), archiveformat.ObjectSuffix)
+	archiver.pending = append(archiver.pending, &pendingArchive{key: key, data: data.Bytes(), entries: writer.Entries()})
+	if len(archiver.pending) > maxPendingArchives {
+		logger.Log.Warningf("Dropping archive %s, the bucket is unavailable", archiver.pending[0].key)
+		archiver.pending = archiver.pending[1:]
+		archiver.stats.Dropped++
+	}
+}
+
+func (archiver *Archiver) upload(client *objectstorage.Client) {
+	for {
+		archiver.mutex.Lock()
+		if len(archiver.pending) == 0 {
+			archiver.mutex.Unlock()
+			return
+		}
+		pending := archiver.pending[0]
+		archiver.mutex.Unlock()
+
+		ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+		err := client.PutObject(ctx, pending.key, pending.data, contentType)
+		cancel()
+
+		archiver.mutex.Lock()
+		if err != nil {
+			// the archive stays pending and is retried on the next interval
+			archiver.stats.Failed++
+			archiver.mutex.Unlock()
+			logger.Log.Errorf("Failed uploading archive %s: %v", pending.key, err)
+			return
+		}
+
+		if len(archiver.pending) > 0 && archiver.pending[0] == pending {
+			archiver.pending = archiver.pending[1:]
+		}
+		archiver.stats.Archives++
+		archiver.stats.ArchivedEntries += pending.entries
+		archiver.stats.LastObject = pending.key
+		archiver.mutex.Unlock()
+
+		logger.Log.Debugf("Archived %d entries to %s", pending.entries, pending.key)
+	}
+}
+
+// deleteExpired deletes the archives of the prefix that are older than the retention, at most once in an hour
+func (archiver *Archiver) deleteExpired(client *objectstorage.Client) {
+	archiver.mutex.Lock()
+	retention := archiver.retention
+	prefix := archiver.prefix
+	due := retention > 0 && time.Since(archiver.lastRetention) >= retentionInterval
+	if due {
+		archiver.lastRetention = time.Now()
+	}
+	archiver.mutex.Unlock()
+
+	if !due {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), uploadTimeout)
+	defer cancel()
+
+	objects, err := client.ListObjects(ctx, prefix)
+	if err != nil {
+		logger.Log.Errorf("Failed listing the archives to delete the expired ones: %v", err)
+		return
+	}
+
+	expiry := time.Now().Add(-retention)
+	for _, object := range objects {
+		if !archiveformat.IsArchiveKey(object.Key) || object.LastModified.After(expiry) {
+			continue
+		}
+
+		if err := client.DeleteObject(ctx, object.Key); err != nil {
+			logger.Log.Errorf("Failed deleting the expired archive %s: %v", object.Key, err)
+			continue
+		}
+
+		archiver.mutex.Lock()
+		archiver.stats.Deleted++
+		archiver.mutex.Unlock()
+	}
+}
+
+// resetWriter must be called while holding the mutex
+func (archiver *Archiver) resetWriter() {
+	archiver.writer = archiveformat.NewWriter(fmt.Sprintf("mizu-agent %s", version.Ver), archiver.cluster, archiveformat.DefaultChunkEntries)
+}
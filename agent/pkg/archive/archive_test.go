@@ -0,0 +1,110 @@
+package archive
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/archiveformat"
+)
+
+func TestFlush(t *testing.T) {
+	var mutex sync.Mutex
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPut || !strings.HasPrefix(request.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		body, _ := ioutil.ReadAll(request.Body)
+		mutex.Lock()
+		objects[request.URL.Path] = body
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	archiver := &Archiver{}
+	archiver.Configure(shared.ArchiveConfig{
+		Url:             "s3://mizu/archives",
+		Endpoint:        server.URL,
+		AccessKeyId:     "key",
+		SecretAccessKey: "secret",
+		IntervalSec:     3600,
+	}, "prod-eu")
+	defer archiver.Configure(shared.ArchiveConfig{}, "")
+
+	archiver.PushEntry("1", 1646128800000, []byte(`{"id":1}`))
+	archiver.PushEntry("2", 1646128801000, []byte(`{"id":2}`))
+
+	if !archiver.Flush(5 * time.Second) {
+		t.Fatalf("timed out flushing the archive")
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	expectedPath := "/mizu/archives/prod-eu/2022/03/01/1646128800000-1646128801000" + archiveformat.ObjectSuffix
+	data, ok := objects[expectedPath]
+	if !ok {
+		t.Fatalf("unexpected result - expected the object %s, actual: %v", expectedPath, objects)
+	}
+
+	reader, err := archiveformat.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if manifest := reader.Manifest(); manifest.Entries != 2 || manifest.Cluster != "prod-eu" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+	entries := make([]string, 0)
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		entries = append(entries, string(entry))
+	}
+	if expected := []string{`{"id":1}`, `{"id":2}`}; !reflect.DeepEqual(entries, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, entries)
+	}
+
+	if stats := archiver.GetStats(); stats.Archives != 1 || stats.ArchivedEntries != 2 || stats.Failed != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestFlushKeepsFailedArchives(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	archiver := &Archiver{}
+	archiver.Configure(shared.ArchiveConfig{Url: "gs://mizu", Endpoint: server.URL, IntervalSec: 3600}, "")
+	defer archiver.Configure(shared.ArchiveConfig{}, "")
+
+	for i := 0; i < maxPendingArchives+1; i++ {
+		archiver.PushEntry(fmt.Sprint(i), int64(i), []byte(`{}`))
+		if !archiver.Flush(5 * time.Second) {
+			t.Fatalf("timed out flushing the archive")
+		}
+	}
+
+	stats := archiver.GetStats()
+	if stats.Failed != maxPendingArchives+1 || stats.Dropped != 1 || stats.Archives != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,302 @@
+package chatops
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/operator"
+	"github.com/up9inc/mizu/agent/pkg/providers"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	SlackSignatureHeader = "X-Slack-Signature"
+	SlackTimestampHeader = "X-Slack-Request-Timestamp"
+
+	slackSignatureVersion = "v0"
+	// older requests are rejected so a captured request can't be replayed
+	maxRequestAge  = 5 * time.Minute
+	requestTimeout = 10 * time.Second
+	// the HAR export link holds up to this many entries
+	harLinkLimit = 10000
+
+	ResponseTypeInChannel = "in_channel"
+	ResponseTypeEphemeral = "ephemeral"
+)
+
+var ErrUnauthorized = errors.New("the command isn't signed by the configured slack app or mattermost command")
+
+// Response is the message posted back to the channel, Slack and Mattermost share its format, an in channel response
+// is shown to everyone and an ephemeral one only to the user of the command
+type Response struct {
+	ResponseType string `json:"response_type"`
+	Text         string `json:"text"`
+}
+
+// capture is the tap started by a command, it's stopped when its timer fires
+type capture struct {
+	scope        string
+	user         string
+	start        time.Time
+	duration     time.Duration
+	entriesCount int
+	responseUrl  string
+	timer        *time.Timer
+}
+
+// Bot runs the slash commands against the tap of the session, a single tap is started by the commands at a time
+type Bot struct {
+	mutex           sync.Mutex
+	config          shared.ChatOpsConfig
+	linkUrl         string
+	defaultDuration time.Duration
+	maxDuration     time.Duration
+	capture         *capture
+	client          *http.Client
+}
+
+var instance *Bot
+var once sync.Once
+
+func GetInstance() *Bot {
+	once.Do(func() {
+		instance = &Bot{}
+	})
+	return instance
+}
+
+func (bot *Bot) Configure(config shared.ChatOpsConfig) {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+
+	bot.config = config
+	bot.linkUrl = strings.TrimSuffix(config.LinkUrl, "/")
+	bot.defaultDuration, _ = time.ParseDuration(config.DefaultDuration)
+	bot.maxDuration, _ = time.ParseDuration(config.MaxDuration)
+	bot.client = &http.Client{Timeout: requestTimeout}
+
+	if config.IsEnabled() {
+		logger.Log.Infof("Accepting chatops slash commands")
+	}
+}
+
+// Verify authenticates a command, a Slack command by the signature of its body and a Mattermost one by its token
+func (bot *Bot) Verify(header http.Header, body []byte, form url.Values, now time.Time) error {
+	bot.mutex.Lock()
+	config := bot.config
+	bot.mutex.Unlock()
+
+	if signature := header.Get(SlackSignatureHeader); signature != "" {
+		if config.SlackSigningSecret == "" {
+			return ErrUnauthorized
+		}
+
+		timestamp, err := strconv.ParseInt(header.Get(SlackTimestampHeader), 10, 64)
+		if err != nil {
+			return ErrUnauthorized
+		}
+		if age := now.Sub(time.Unix(timestamp, 0)); age > maxRequestAge || age < -maxRequestAge {
+			return ErrUnauthorized
+		}
+
+		if !hmac.Equal([]byte(signature), []byte(SignSlackRequest(config.SlackSigningSecret, header.Get(SlackTimestampHeader), body))) {
+			return ErrUnauthorized
+		}
+
+		return nil
+	}
+
+	if config.MattermostToken == "" || subtle.ConstantTimeCompare([]byte(form.Get("token")), []byte(config.MattermostToken)) != 1 {
+		return ErrUnauthorized
+	}
+
+	return nil
+}
+
+// SignSlackRequest returns the signature Slack sends with a request of the app of the signing secret
+func SignSlackRequest(signingSecret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(signingSecret))
+	mac.Write([]byte(fmt.Sprintf("%s:%s:", slackSignatureVersion, timestamp)))
+	mac.Write(body)
+	return fmt.Sprintf("%s=%s", slackSignatureVersion, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Run runs the command of user, the summary of a tap that ends by itself is posted to responseUrl
+func (bot *Bot) Run(ctx context.Context, sessionTap operator.SessionTap, command *Command, user string, responseUrl string) *Response {
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+
+	switch command.Action {
+	case ActionTap:
+		return bot.tap(ctx, sessionTap, command, user, responseUrl)
+	case ActionStop:
+		return bot.stop(ctx, sessionTap, user)
+	case ActionStatus:
+		return bot.status(ctx, sessionTap)
+	default:
+		return &Response{ResponseType: ResponseTypeEphemeral, Text: usage}
+	}
+}
+
+func (bot *Bot) tap(ctx context.Context, sessionTap operator.SessionTap, command *Command, user string, responseUrl string) *Response {
+	duration := command.Duration
+	if duration == 0 {
+		duration = bot.defaultDuration
+	}
+	if duration > bot.maxDuration {
+		return &Response{ResponseType: ResponseTypeEphemeral, Text: fmt.Sprintf("Taps are limited to %v", bot.maxDuration)}
+	}
+
+	_, err := sessionTap.UpdateTap(ctx, func(spec *kubernetes.MizuTapSpec) {
+		spec.TargetNamespaces = command.Namespaces
+		spec.Targets = command.Targets
+		spec.PodRegex = ""
+		spec.PodLabelSelector = ""
+		spec.TapAnnotations = false
+		spec.Stopped = false
+	})
+	if errors.Is(err, operator.ErrTapNotFound) {
+		_, err = sessionTap.CreateTap(ctx, kubernetes.MizuTapSpec{TargetNamespaces: command.Namespaces, Targets: command.Targets})
+	}
+	if err != nil {
+		return &Response{ResponseType: ResponseTypeEphemeral, Text: fmt.Sprintf("Failed tapping %s: %v", command.scope(), err)}
+	}
+
+	if bot.capture != nil {
+		bot.capture.timer.Stop()
+	}
+
+	current := &capture{
+		scope:        command.scope(),
+		user:         user,
+		start:        time.Now(),
+		duration:     duration,
+		entriesCount: providers.GetGeneralStats().EntriesCount,
+		responseUrl:  responseUrl,
+	}
+	current.timer = time.AfterFunc(duration, func() {
+		bot.expire(sessionTap, current)
+	})
+	bot.capture = current
+
+	text := fmt.Sprintf("%s started tapping %s for %v", user, current.scope, duration)
+	if bot.linkUrl != "" {
+		text = fmt.Sprintf("%s, the traffic is shown at %s", text, bot.linkUrl)
+	}
+	return &Response{ResponseType: ResponseTypeInChannel, Text: text}
+}
+
+func (bot *Bot) stop(ctx context.Context, sessionTap operator.SessionTap, user string) *Response {
+	if _, err := sessionTap.UpdateTap(ctx, func(spec *kubernetes.MizuTapSpec) {
+		spec.Stopped = true
+	}); errors.Is(err, operator.ErrTapNotFound) {
+		return &Response{ResponseType: ResponseTypeEphemeral, Text: "Nothing is tapped"}
+	} else if err != nil {
+		return &Response{ResponseType: ResponseTypeEphemeral, Text: fmt.Sprintf("Failed stopping the tap: %v", err)}
+	}
+
+	if bot.capture == nil {
+		return &Response{ResponseType: ResponseTypeInChannel, Text: fmt.Sprintf("%s stopped the tap", user)}
+	}
+
+	current := bot.capture
+	current.timer.Stop()
+	bot.capture = nil
+
+	return &Response{ResponseType: ResponseTypeInChannel, Text: fmt.Sprintf("%s stopped tapping %s. %s", user, current.scope, bot.summarize(current, time.Now()))}
+}
+
+func (bot *Bot) status(ctx context.Context, sessionTap operator.SessionTap) *Response {
+	mizuTap, err := sessionTap.GetTap(ctx)
+	if errors.Is(err, operator.ErrTapNotFound) || (err == nil && mizuTap.Spec.Stopped) {
+		return &Response{ResponseType: ResponseTypeEphemeral, Text: "Nothing is tapped"}
+	} else if err != nil {
+		return &Response{ResponseType: ResponseTypeEphemeral, Text: fmt.Sprintf("Failed getting the tap: %v", err)}
+	}
+
+	text := fmt.Sprintf("The tap is %s, %d pods are tapped", strings.ToLower(mizuTap.Status.Phase), mizuTap.Status.TappedPods)
+	if mizuTap.Status.Message != "" {
+		text = fmt.Sprintf("%s: %s", text, mizuTap.Status.Message)
+	}
+	if current := bot.capture; current != nil {
+		remaining := current.duration - time.Since(current.start)
+		text = fmt.Sprintf("%s. %s started tapping %s, %d entries were captured, it stops in %v", text, current.user, current.scope, providers.GetGeneralStats().EntriesCount-current.entriesCount, remaining.Round(time.Second))
+	}
+
+	return &Response{ResponseType: ResponseTypeEphemeral, Text: text}
+}
+
+// expire stops the tap once its duration passed, unless another command replaced or stopped it first
+func (bot *Bot) expire(sessionTap operator.SessionTap, expired *capture) {
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+
+	bot.mutex.Lock()
+	if bot.capture != expired {
+		bot.mutex.Unlock()
+		return
+	}
+	bot.capture = nil
+
+	text := fmt.Sprintf("Stopped tapping %s after %v. %s", expired.scope, expired.duration, bot.summarize(expired, time.Now()))
+	if _, err := sessionTap.UpdateTap(ctx, func(spec *kubernetes.MizuTapSpec) {
+		spec.Stopped = true
+	}); err != nil && !errors.Is(err, operator.ErrTapNotFound) {
+		logger.Log.Errorf("Error stopping the tap of %s: %v", expired.scope, err)
+		text = fmt.Sprintf("Failed stopping the tap of %s after %v: %v", expired.scope, expired.duration, err)
+	}
+	bot.mutex.Unlock()
+
+	if expired.responseUrl != "" {
+		bot.respond(ctx, expired.responseUrl, &Response{ResponseType: ResponseTypeInChannel, Text: text})
+	}
+}
+
+// summarize counts the entries of the capture and links to them
+func (bot *Bot) summarize(summarized *capture, end time.Time) string {
+	text := fmt.Sprintf("%d entries were captured", providers.GetGeneralStats().EntriesCount-summarized.entriesCount)
+	if bot.linkUrl == "" {
+		return text
+	}
+
+	harLink := fmt.Sprintf("%s/export/har?from=%d&to=%d&limit=%d", bot.linkUrl, summarized.start.UnixNano()/int64(time.Millisecond), end.UnixNano()/int64(time.Millisecond), harLinkLimit)
+	return fmt.Sprintf("%s, the traffic is shown at %s and exported as HAR at %s", text, bot.linkUrl, harLink)
+}
+
+// respond posts a delayed response of a command, the response url of a command is valid for a while after it
+func (bot *Bot) respond(ctx context.Context, responseUrl string, response *Response) {
+	payload, err := json.Marshal(response)
+	if err != nil {
+		logger.Log.Errorf("Error marshaling the chatops response: %v", err)
+		return
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, responseUrl, bytes.NewReader(payload))
+	if err != nil {
+		logger.Log.Errorf("Error creating the chatops response: %v", err)
+		return
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	res, err := bot.client.Do(request)
+	if err != nil {
+		logger.Log.Warningf("Failed posting the chatops response: %v", err)
+		return
+	}
+	res.Body.Close()
+}
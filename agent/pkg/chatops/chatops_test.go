@@ -0,0 +1,145 @@
+package chatops
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/operator"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+)
+
+// fakeSessionTap keeps the tap in memory, like the MizuTap the operator reconciles
+type fakeSessionTap struct {
+	mizuTap *kubernetes.MizuTap
+}
+
+func (fake *fakeSessionTap) GetTap(ctx context.Context) (*kubernetes.MizuTap, error) {
+	if fake.mizuTap == nil {
+		return nil, operator.ErrTapNotFound
+	}
+	return fake.mizuTap, nil
+}
+
+func (fake *fakeSessionTap) CreateTap(ctx context.Context, spec kubernetes.MizuTapSpec) (*kubernetes.MizuTap, error) {
+	fake.mizuTap = &kubernetes.MizuTap{Spec: spec}
+	return fake.mizuTap, nil
+}
+
+func (fake *fakeSessionTap) UpdateTap(ctx context.Context, update func(spec *kubernetes.MizuTapSpec)) (*kubernetes.MizuTap, error) {
+	if fake.mizuTap == nil {
+		return nil, operator.ErrTapNotFound
+	}
+	update(&fake.mizuTap.Spec)
+	return fake.mizuTap, nil
+}
+
+func (fake *fakeSessionTap) RemoveTap(ctx context.Context) error {
+	fake.mizuTap = nil
+	return nil
+}
+
+func newTestBot() *Bot {
+	bot := &Bot{}
+	bot.Configure(shared.ChatOpsConfig{SlackSigningSecret: "secret", MattermostToken: "token", LinkUrl: "http://mizu.example.com/", DefaultDuration: "15m", MaxDuration: "1h"})
+	return bot
+}
+
+func TestParseCommand(t *testing.T) {
+	command, err := ParseCommand("tap payments 10m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := (&Command{Action: ActionTap, Namespaces: []string{"payments"}, Duration: 10 * time.Minute}); !reflect.DeepEqual(command, expected) {
+		t.Errorf("unexpected result - expected: %+v, actual: %+v", expected, command)
+	}
+
+	command, err = ParseCommand("tap shop/deployment/cart")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []kubernetes.TapTarget{{Namespace: "shop", Kind: kubernetes.TapTargetKindDeployment, Name: "cart"}}; !reflect.DeepEqual(command.Targets, expected) || command.Duration != 0 {
+		t.Errorf("unexpected result - expected: %+v, actual: %+v", expected, command)
+	}
+
+	for _, text := range []string{"tap", "tap 10m", "tap payments -5m", "tap payments shop/deployment/cart", "stop now", "capture payments"} {
+		if _, err := ParseCommand(text); err == nil {
+			t.Errorf("unexpected result - expected %q to be invalid", text)
+		}
+	}
+}
+
+func TestVerify(t *testing.T) {
+	bot := newTestBot()
+	now := time.Now()
+	body := []byte("token=token&text=tap+payments")
+	form, _ := url.ParseQuery(string(body))
+
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	slackHeader := http.Header{}
+	slackHeader.Set(SlackTimestampHeader, timestamp)
+	slackHeader.Set(SlackSignatureHeader, SignSlackRequest("secret", timestamp, body))
+	if err := bot.Verify(slackHeader, body, form, now); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := bot.Verify(slackHeader, body, form, now.Add(10*time.Minute)); err == nil {
+		t.Errorf("unexpected result - expected an old request to be rejected")
+	}
+	if err := bot.Verify(slackHeader, []byte("text=tap+shop"), form, now); err == nil {
+		t.Errorf("unexpected result - expected a changed body to be rejected")
+	}
+
+	if err := bot.Verify(http.Header{}, body, form, now); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := bot.Verify(http.Header{}, body, url.Values{"token": {"other"}}, now); err == nil {
+		t.Errorf("unexpected result - expected a wrong token to be rejected")
+	}
+}
+
+func TestRun(t *testing.T) {
+	bot := newTestBot()
+	sessionTap := &fakeSessionTap{}
+	ctx := context.Background()
+
+	response := bot.Run(ctx, sessionTap, &Command{Action: ActionTap, Namespaces: []string{"payments"}}, "alice", "")
+	if response.ResponseType != ResponseTypeInChannel || sessionTap.mizuTap == nil || !reflect.DeepEqual(sessionTap.mizuTap.Spec.TargetNamespaces, []string{"payments"}) {
+		t.Fatalf("unexpected result - expected namespace payments to be tapped, actual: %+v", response)
+	}
+	if !strings.Contains(response.Text, "15m0s") || !strings.Contains(response.Text, "http://mizu.example.com") {
+		t.Errorf("unexpected result - expected the default duration and the link, actual: %s", response.Text)
+	}
+
+	if response := bot.Run(ctx, sessionTap, &Command{Action: ActionTap, Namespaces: []string{"payments"}, Duration: 2 * time.Hour}, "alice", ""); response.ResponseType != ResponseTypeEphemeral {
+		t.Errorf("unexpected result - expected a tap longer than the max duration to be rejected, actual: %+v", response)
+	}
+
+	response = bot.Run(ctx, sessionTap, &Command{Action: ActionStop}, "bob", "")
+	if !sessionTap.mizuTap.Spec.Stopped || !strings.Contains(response.Text, "/export/har?from=") {
+		t.Errorf("unexpected result - expected the tap to be stopped with a summary, actual: %+v", response)
+	}
+
+	if response := bot.Run(ctx, sessionTap, &Command{Action: ActionStatus}, "bob", ""); response.Text != "Nothing is tapped" {
+		t.Errorf("unexpected result - expected nothing to be tapped, actual: %+v", response)
+	}
+}
+
+func TestCaptureExpires(t *testing.T) {
+	bot := newTestBot()
+	sessionTap := &fakeSessionTap{}
+
+	bot.Run(context.Background(), sessionTap, &Command{Action: ActionTap, Namespaces: []string{"payments"}, Duration: 50 * time.Millisecond}, "alice", "")
+	time.Sleep(200 * time.Millisecond)
+
+	bot.mutex.Lock()
+	defer bot.mutex.Unlock()
+	if !sessionTap.mizuTap.Spec.Stopped || bot.capture != nil {
+		t.Errorf("unexpected result - expected the tap to stop after its duration")
+	}
+}
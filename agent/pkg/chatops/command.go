@@ -0,0 +1,99 @@
+package chatops
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/up9inc/mizu/shared/kubernetes"
+)
+
+const (
+	ActionTap    = "tap"
+	ActionStop   = "stop"
+	ActionStatus = "status"
+	ActionHelp   = "help"
+)
+
+const usage = "Usage: `tap <namespace or [namespace/]kind/name>... [duration]` to tap, like `tap payments 10m`, `stop` to stop tapping and `status` to show what's tapped"
+
+// Command is the text of a slash command, like "tap payments shop/deployment/cart 10m", Duration is 0 when the
+// command doesn't set it
+type Command struct {
+	Action     string
+	Namespaces []string
+	Targets    []kubernetes.TapTarget
+	Duration   time.Duration
+}
+
+// ParseCommand parses the text following the slash command, a word with a slash is a target like the ones of mizu tap
+// --targets and the others are namespaces, the last word is the duration when it's one
+func ParseCommand(text string) (*Command, error) {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return &Command{Action: ActionHelp}, nil
+	}
+
+	command := &Command{Action: strings.ToLower(words[0])}
+	args := words[1:]
+
+	switch command.Action {
+	case ActionTap:
+		if len(args) > 0 {
+			if duration, err := time.ParseDuration(args[len(args)-1]); err == nil {
+				if duration <= 0 {
+					return nil, fmt.Errorf("the duration must be positive, like 10m")
+				}
+				command.Duration = duration
+				args = args[:len(args)-1]
+			}
+		}
+
+		if len(args) == 0 {
+			return nil, fmt.Errorf("nothing to tap. %s", usage)
+		}
+
+		for _, arg := range args {
+			if !strings.Contains(arg, "/") {
+				command.Namespaces = append(command.Namespaces, arg)
+				continue
+			}
+
+			target, err := kubernetes.ParseTapTargetReference(arg)
+			if err != nil {
+				return nil, err
+			}
+			command.Targets = append(command.Targets, target)
+		}
+
+		if len(command.Targets) > 0 && len(command.Namespaces) > 0 {
+			return nil, fmt.Errorf("either namespaces or targets can be tapped, not both")
+		}
+	case ActionStop, ActionStatus, ActionHelp:
+		if len(args) > 0 {
+			return nil, fmt.Errorf("%s takes no arguments. %s", command.Action, usage)
+		}
+	default:
+		return nil, fmt.Errorf("unknown command %s. %s", command.Action, usage)
+	}
+
+	return command, nil
+}
+
+// scope describes what the command taps, like "namespace payments" or "shop/deployment/cart"
+func (command *Command) scope() string {
+	if len(command.Targets) > 0 {
+		targets := make([]string, 0, len(command.Targets))
+		for _, target := range command.Targets {
+			// the targets without a namespace are in the namespace of the tap
+			targets = append(targets, strings.TrimPrefix(target.String(), "/"))
+		}
+		return strings.Join(targets, ", ")
+	}
+
+	if len(command.Namespaces) == 1 {
+		return fmt.Sprintf("namespace %s", command.Namespaces[0])
+	}
+
+	return fmt.Sprintf("namespaces %s", strings.Join(command.Namespaces, ", "))
+}
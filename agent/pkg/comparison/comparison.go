@@ -0,0 +1,321 @@
+package comparison
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/agent/pkg/oas"
+	"github.com/up9inc/mizu/shared"
+)
+
+const (
+	// the response fields kept per endpoint and side, the rest are left out of the schema comparison
+	maxSchemaFields = 500
+	// the json nesting followed into the response bodies
+	maxSchemaDepth = 10
+)
+
+var (
+	patNumber = regexp.MustCompile(`\d+`)
+
+	// the upper bounds of the latency histogram buckets, in milliseconds
+	latencyBuckets = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+)
+
+type sideCollector struct {
+	latencies []int64
+	errors    int
+	fields    map[string]map[string]bool
+}
+
+func newSideCollector() *sideCollector {
+	return &sideCollector{fields: make(map[string]map[string]bool)}
+}
+
+func (side *sideCollector) add(harEntry *har.Entry, collectSchema bool) {
+	side.latencies = append(side.latencies, int64(harEntry.Time))
+	if harEntry.Response.Status >= 500 {
+		side.errors++
+	}
+
+	if collectSchema {
+		collectResponseFields(&harEntry.Response, side.fields)
+	}
+}
+
+func (side *sideCollector) stats() shared.ComparisonSide {
+	stats := shared.ComparisonSide{
+		Entries: len(side.latencies),
+		Errors:  side.errors,
+		Latency: latencyStats(side.latencies),
+	}
+	if stats.Entries > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Entries)
+	}
+
+	return stats
+}
+
+// Collector contrasts the entries of the stable and the canary workloads, per endpoint
+type Collector struct {
+	stable          *sideCollector
+	canary          *sideCollector
+	stableEndpoints map[string]*sideCollector
+	canaryEndpoints map[string]*sideCollector
+}
+
+func NewCollector() *Collector {
+	return &Collector{
+		stable:          newSideCollector(),
+		canary:          newSideCollector(),
+		stableEndpoints: make(map[string]*sideCollector),
+		canaryEndpoints: make(map[string]*sideCollector),
+	}
+}
+
+func (collector *Collector) AddStable(harEntry *har.Entry) {
+	collector.add(harEntry, collector.stable, collector.stableEndpoints)
+}
+
+func (collector *Collector) AddCanary(harEntry *har.Entry) {
+	collector.add(harEntry, collector.canary, collector.canaryEndpoints)
+}
+
+func (collector *Collector) add(harEntry *har.Entry, side *sideCollector, endpoints map[string]*sideCollector) {
+	side.add(harEntry, false)
+
+	endpoint := endpointName(harEntry)
+	if _, ok := endpoints[endpoint]; !ok {
+		endpoints[endpoint] = newSideCollector()
+	}
+	endpoints[endpoint].add(harEntry, true)
+}
+
+func (collector *Collector) Compare() *shared.ComparisonResponse {
+	endpointNames := make(map[string]bool)
+	for endpoint := range collector.stableEndpoints {
+		endpointNames[endpoint] = true
+	}
+	for endpoint := range collector.canaryEndpoints {
+		endpointNames[endpoint] = true
+	}
+
+	endpoints := make([]*shared.EndpointComparison, 0, len(endpointNames))
+	for endpoint := range endpointNames {
+		stable, ok := collector.stableEndpoints[endpoint]
+		if !ok {
+			stable = newSideCollector()
+		}
+		canary, ok := collector.canaryEndpoints[endpoint]
+		if !ok {
+			canary = newSideCollector()
+		}
+
+		endpointComparison := &shared.EndpointComparison{
+			Endpoint:          endpoint,
+			Stable:            stable.stats(),
+			Canary:            canary.stats(),
+			SchemaDifferences: make([]*shared.ComparisonSchemaDifference, 0),
+		}
+		// an endpoint only one side served has no schema to contrast
+		if len(stable.latencies) > 0 && len(canary.latencies) > 0 {
+			endpointComparison.SchemaDifferences = schemaDifferences(stable.fields, canary.fields)
+		}
+
+		endpoints = append(endpoints, endpointComparison)
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		return endpoints[i].Endpoint < endpoints[j].Endpoint
+	})
+
+	return &shared.ComparisonResponse{
+		Stable:    collector.stable.stats(),
+		Canary:    collector.canary.stats(),
+		Endpoints: endpoints,
+	}
+}
+
+func endpointName(harEntry *har.Entry) string {
+	path := "/"
+	if requestUrl, err := url.Parse(harEntry.Request.URL); err == nil {
+		path = normalizePath(requestUrl.Path)
+	}
+
+	return fmt.Sprintf("%s %s", harEntry.Request.Method, path)
+}
+
+// normalizePath replaces the path segments that look like ids, so /orders/17 and /orders/42 are compared as the
+// same endpoint
+func normalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		if patNumber.FindString(segment) == segment || oas.IsGibberish(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	normalized := strings.Join(segments, "/")
+	if normalized == "" {
+		return "/"
+	}
+	return normalized
+}
+
+func latencyStats(latencies []int64) shared.ComparisonLatency {
+	stats := shared.ComparisonLatency{Histogram: make([]*shared.ComparisonHistogramBucket, 0, len(latencyBuckets)+1)}
+	for _, bound := range latencyBuckets {
+		stats.Histogram = append(stats.Histogram, &shared.ComparisonHistogramBucket{UpperBoundMs: bound})
+	}
+	overflow := &shared.ComparisonHistogramBucket{}
+	stats.Histogram = append(stats.Histogram, overflow)
+
+	if len(latencies) == 0 {
+		return stats
+	}
+
+	sorted := make([]int64, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	stats.P50 = percentile(sorted, 50)
+	stats.P90 = percentile(sorted, 90)
+	stats.P95 = percentile(sorted, 95)
+	stats.P99 = percentile(sorted, 99)
+	stats.Max = sorted[len(sorted)-1]
+
+	for _, latency := range sorted {
+		bucket := overflow
+		for _, candidate := range stats.Histogram[:len(latencyBuckets)] {
+			if latency <= candidate.UpperBoundMs {
+				bucket = candidate
+				break
+			}
+		}
+		bucket.Count++
+	}
+
+	return stats
+}
+
+// percentile uses the nearest rank method on sorted latencies
+func percentile(sorted []int64, p int) int64 {
+	rank := (p*len(sorted) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+// collectResponseFields adds the paths and types of the fields of a json response body, like items[].id number
+func collectResponseFields(response *har.Response, fields map[string]map[string]bool) {
+	if !strings.Contains(response.Content.MimeType, "json") {
+		return
+	}
+
+	isBinary, asBytes, asString := response.Content.B64Decoded()
+	if isBinary {
+		return
+	}
+	if asBytes == nil {
+		asBytes = []byte(asString)
+	}
+
+	var body interface{}
+	if err := json.Unmarshal(asBytes, &body); err != nil {
+		return
+	}
+
+	collectFields(body, "", 0, fields)
+}
+
+func collectFields(value interface{}, path string, depth int, fields map[string]map[string]bool) {
+	if depth > maxSchemaDepth {
+		return
+	}
+
+	if path != "" {
+		if _, ok := fields[path]; !ok {
+			if len(fields) >= maxSchemaFields {
+				return
+			}
+			fields[path] = make(map[string]bool)
+		}
+		fields[path][jsonType(value)] = true
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			childPath := key
+			if path != "" {
+				childPath = fmt.Sprintf("%s.%s", path, key)
+			}
+			collectFields(child, childPath, depth+1, fields)
+		}
+	case []interface{}:
+		for _, child := range typed {
+			collectFields(child, fmt.Sprintf("%s[]", path), depth+1, fields)
+		}
+	}
+}
+
+func jsonType(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	case string:
+		return "string"
+	case float64:
+		return "number"
+	case bool:
+		return "boolean"
+	default:
+		return "null"
+	}
+}
+
+func schemaDifferences(stable map[string]map[string]bool, canary map[string]map[string]bool) []*shared.ComparisonSchemaDifference {
+	paths := make(map[string]bool)
+	for path := range stable {
+		paths[path] = true
+	}
+	for path := range canary {
+		paths[path] = true
+	}
+
+	differences := make([]*shared.ComparisonSchemaDifference, 0)
+	for path := range paths {
+		stableTypes := joinTypes(stable[path])
+		canaryTypes := joinTypes(canary[path])
+		if stableTypes != canaryTypes {
+			differences = append(differences, &shared.ComparisonSchemaDifference{Field: path, StableTypes: stableTypes, CanaryTypes: canaryTypes})
+		}
+	}
+
+	sort.Slice(differences, func(i, j int) bool {
+		return differences[i].Field < differences[j].Field
+	})
+
+	return differences
+}
+
+func joinTypes(types map[string]bool) string {
+	sorted := make([]string, 0, len(types))
+	for jsonType := range types {
+		sorted = append(sorted, jsonType)
+	}
+	sort.Strings(sorted)
+
+	return strings.Join(sorted, "|")
+}
@@ -0,0 +1,71 @@
+package comparison
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/shared"
+)
+
+func newTestEntry(method string, url string, status int, time int, body string) *har.Entry {
+	return &har.Entry{
+		Time:    time,
+		Request: har.Request{Method: method, URL: url},
+		Response: har.Response{
+			Status:  status,
+			Content: har.Content{MimeType: "application/json", Text: body},
+		},
+	}
+}
+
+func TestCompare(t *testing.T) {
+	collector := NewCollector()
+	for i := 0; i < 10; i++ {
+		collector.AddStable(newTestEntry("GET", "http://orders/orders/17", 200, 10*(i+1), `{"id":17,"total":"9.99"}`))
+	}
+	collector.AddCanary(newTestEntry("GET", "http://orders/orders/42", 200, 30, `{"id":42,"total":9.99,"currency":"EUR"}`))
+	collector.AddCanary(newTestEntry("GET", "http://orders/orders/43", 503, 3000, `{"error":"unavailable"}`))
+	collector.AddCanary(newTestEntry("POST", "http://orders/orders", 201, 40, `{"id":44}`))
+
+	result := collector.Compare()
+
+	if result.Stable.Entries != 10 || result.Stable.Errors != 0 || result.Stable.Latency.P50 != 50 || result.Stable.Latency.P99 != 100 {
+		t.Errorf("unexpected result - stable: %+v", result.Stable)
+	}
+	if result.Canary.Entries != 3 || result.Canary.Errors != 1 || result.Canary.Latency.Max != 3000 {
+		t.Errorf("unexpected result - canary: %+v", result.Canary)
+	}
+
+	if len(result.Endpoints) != 2 || result.Endpoints[0].Endpoint != "GET /orders/{id}" || result.Endpoints[1].Endpoint != "POST /orders" {
+		t.Fatalf("unexpected result - endpoints: %+v", result.Endpoints)
+	}
+
+	expected := []*shared.ComparisonSchemaDifference{
+		{Field: "currency", StableTypes: "", CanaryTypes: "string"},
+		{Field: "error", StableTypes: "", CanaryTypes: "string"},
+		{Field: "total", StableTypes: "string", CanaryTypes: "number"},
+	}
+	if actual := result.Endpoints[0].SchemaDifferences; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+
+	// only the canary served the post, so there is no schema to contrast
+	if actual := result.Endpoints[1].SchemaDifferences; len(actual) != 0 {
+		t.Errorf("unexpected result - expected no schema differences, actual: %v", actual)
+	}
+}
+
+func TestLatencyStatsHistogram(t *testing.T) {
+	stats := latencyStats([]int64{3, 7, 700, 20000})
+
+	counts := make(map[int64]int)
+	for _, bucket := range stats.Histogram {
+		counts[bucket.UpperBoundMs] = bucket.Count
+	}
+
+	expected := map[int64]int{5: 1, 10: 1, 25: 0, 50: 0, 100: 0, 250: 0, 500: 0, 1000: 1, 2500: 0, 5000: 0, 10000: 0, 0: 1}
+	if !reflect.DeepEqual(counts, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, counts)
+	}
+}
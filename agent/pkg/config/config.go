@@ -10,8 +10,14 @@ import (
 
 // these values are used when the config.json file is not present
 const (
-	defaultMaxDatabaseSizeBytes int64  = 200 * 1000 * 1000
-	DefaultDatabasePath         string = "./entries"
+	defaultMaxDatabaseSizeBytes        int64  = 200 * 1000 * 1000
+	defaultMaxExportQueueDiskSizeBytes int64  = 100 * 1000 * 1000
+	DefaultDatabasePath                string = "./entries"
+	defaultQueryCacheMaxResults        int    = 128
+	defaultQueryCacheTtlSec            int    = 10
+	defaultMaxConcurrentQueries        int    = 8
+	defaultMaxQueryTimeoutSec          int    = 30
+	defaultRetentionIntervalSec        int    = 10
 )
 
 var Config *shared.MizuAgentConfig
@@ -47,7 +53,43 @@ func applyDefaultConfig() error {
 
 func getDefaultConfig() (*shared.MizuAgentConfig, error) {
 	return &shared.MizuAgentConfig{
-		MaxDBSizeBytes:    defaultMaxDatabaseSizeBytes,
-		AgentDatabasePath: DefaultDatabasePath,
+		MaxDBSizeBytes:              defaultMaxDatabaseSizeBytes,
+		AgentDatabasePath:           DefaultDatabasePath,
+		MaxExportQueueDiskSizeBytes: defaultMaxExportQueueDiskSizeBytes,
+		EntryIdScheme:               shared.EntryIdSchemeUlid,
+		Stats:                       true,
+		Metrics:                     true,
+		Insights:                    true,
+		QueryCache: shared.QueryCacheConfig{
+			MaxResults: defaultQueryCacheMaxResults,
+			TtlSec:     defaultQueryCacheTtlSec,
+		},
+		QueryLimits: shared.QueryLimitsConfig{
+			MaxConcurrent: defaultMaxConcurrentQueries,
+			MaxTimeoutSec: defaultMaxQueryTimeoutSec,
+		},
+		Retention: shared.RetentionConfig{
+			IntervalSec: defaultRetentionIntervalSec,
+		},
+		SocketKeepAlive: shared.SocketKeepAliveConfig{
+			PingIntervalSec: shared.DefaultSocketPingIntervalSec,
+			IdleTimeoutSec:  shared.DefaultSocketIdleTimeoutSec,
+		},
 	}, nil
 }
+
+// GetFeatures returns the modules that are enabled, the entries are counted and analyzed when no config was loaded,
+// like in the standalone mode
+func GetFeatures() shared.AgentFeatures {
+	if Config == nil {
+		return shared.AgentFeatures{Stats: true, Metrics: true, Insights: true}
+	}
+
+	return shared.AgentFeatures{
+		ServiceMap: Config.ServiceMap,
+		OAS:        Config.OAS,
+		Stats:      Config.Stats,
+		Metrics:    Config.Metrics,
+		Insights:   Config.Insights,
+	}
+}
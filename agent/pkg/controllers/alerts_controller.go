@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/alerts"
+	"github.com/up9inc/mizu/shared"
+)
+
+func GetAlertRules(c *gin.Context) {
+	c.JSON(http.StatusOK, alerts.GetInstance().GetRules())
+}
+
+func PostAlertRule(c *gin.Context) {
+	rule := shared.AlertRule{}
+	if err := c.Bind(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validateQuery(rule.Query); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	status, err := alerts.GetInstance().AddRule(rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func DeleteAlertRule(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	status, err := alerts.GetInstance().DeleteRule(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
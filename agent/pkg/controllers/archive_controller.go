@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	basenine "github.com/up9inc/basenine/client/go"
+	"github.com/up9inc/mizu/agent/pkg/archive"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/archiveformat"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	// the cli waits for the upload before removing the resources
+	archiveFlushTimeout = 30 * time.Second
+)
+
+// PostArchiveFlush uploads the entries since the last archive, so they aren't lost when the session stops
+func PostArchiveFlush(c *gin.Context) {
+	if !archive.GetInstance().Flush(archiveFlushTimeout) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": true,
+			"msg":   "archiving is disabled or timed out uploading the archive",
+		})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// PostArchivedEntries stores the entries of an archive of any version, so a session can be replayed from its archives.
+// The archives of version 1 were sent with a gzip content encoding, they're told apart by their content instead
+func PostArchivedEntries(c *gin.Context) {
+	reader, err := archiveformat.NewReader(c.Request.Body)
+	if errors.Is(err, archiveformat.ErrUnsupportedFormatVersion) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": true, "msg": err.Error()})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": true, "msg": err.Error()})
+		return
+	}
+	defer reader.Close()
+
+	connection, err := basenine.NewConnection(shared.BasenineHost, shared.BaseninePort)
+	if Error(c, err) {
+		return // exit
+	}
+	defer connection.Close()
+	connection.InsertMode()
+
+	entries := 0
+	skipped := 0
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": true, "msg": fmt.Sprintf("failed reading the archive after %d entries, %v", entries, err)})
+			return
+		}
+
+		if !json.Valid(entry) {
+			skipped++
+			continue
+		}
+
+		connection.SendText(string(entry))
+		entries++
+	}
+
+	if skipped > 0 {
+		logger.Log.Warningf("Skipped %d invalid lines of an archive", skipped)
+	}
+
+	c.JSON(http.StatusOK, &shared.ArchiveReplayResponse{Entries: entries, Skipped: skipped, FormatVersion: reader.Manifest().FormatVersion})
+}
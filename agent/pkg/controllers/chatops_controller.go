@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/chatops"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+// PostChatOpsCommand runs a Slack or Mattermost slash command, like "/mizu tap payments 10m", the errors of a verified
+// command are answered as messages so they're shown in the channel
+func PostChatOpsCommand(c *gin.Context) {
+	body, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bot := chatops.GetInstance()
+	if err := bot.Verify(c.Request.Header, body, form, time.Now()); err != nil {
+		c.JSON(http.StatusUnauthorized, err.Error())
+		return
+	}
+
+	command, err := chatops.ParseCommand(form.Get("text"))
+	if err != nil {
+		c.JSON(http.StatusOK, &chatops.Response{ResponseType: chatops.ResponseTypeEphemeral, Text: err.Error()})
+		return
+	}
+
+	sessionTap, err := lookupSessionTap()
+	if err != nil {
+		c.JSON(http.StatusOK, &chatops.Response{ResponseType: chatops.ResponseTypeEphemeral, Text: err.Error()})
+		return
+	}
+
+	user := form.Get("user_name")
+	logger.Log.Infof("Running chatops command %s of %s", command.Action, user)
+	c.JSON(http.StatusOK, bot.Run(c.Request.Context(), sessionTap, command, user, form.Get("response_url")))
+}
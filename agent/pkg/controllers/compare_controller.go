@@ -0,0 +1,76 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/comparison"
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/agent/pkg/models"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/validation"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const compareFetchTimeout = 10 * time.Second
+
+// GetComparison contrasts the http entries of the stable and the canary workloads over the same time range
+func GetComparison(c *gin.Context) {
+	compareRequest := &models.CompareRequest{}
+
+	if err := c.BindQuery(compareRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	if validationError := validation.Validate(compareRequest); validationError != nil {
+		c.JSON(http.StatusBadRequest, validationError)
+		return
+	}
+
+	collector := comparison.NewCollector()
+
+	stableEntries, err := fetchComparedEntries(c.Request.Context(), compareRequest.StableQuery, compareRequest)
+	if QueryError(c, err) {
+		return // exit
+	}
+	for _, harEntry := range stableEntries {
+		collector.AddStable(harEntry)
+	}
+
+	canaryEntries, err := fetchComparedEntries(c.Request.Context(), compareRequest.CanaryQuery, compareRequest)
+	if QueryError(c, err) {
+		return // exit
+	}
+	for _, harEntry := range canaryEntries {
+		collector.AddCanary(harEntry)
+	}
+
+	c.JSON(http.StatusOK, collector.Compare())
+}
+
+func fetchComparedEntries(ctx context.Context, query string, compareRequest *models.CompareRequest) ([]*har.Entry, error) {
+	query = buildHarExportQuery(query, compareRequest.From, compareRequest.To)
+	data, _, err := querylimit.GetInstance().Fetch(ctx, -1, -1, query, compareRequest.Limit, compareFetchTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	harEntries := make([]*har.Entry, 0, len(data))
+	for _, entryData := range data {
+		var entry *tapApi.Entry
+		if err := json.Unmarshal(entryData, &entry); err != nil {
+			logger.Log.Debugf("Skipping an entry that couldn't be parsed in the comparison: %v", err)
+			continue
+		}
+
+		if harEntry := newHarExportEntry(entry); harEntry != nil {
+			harEntries = append(harEntries, harEntry)
+		}
+	}
+
+	return harEntries, nil
+}
@@ -1,13 +1,24 @@
 package controllers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
+	"github.com/up9inc/mizu/agent/pkg/config"
+	"github.com/up9inc/mizu/agent/pkg/entryid"
+	"github.com/up9inc/mizu/agent/pkg/entryindex"
 	"github.com/up9inc/mizu/agent/pkg/har"
 	"github.com/up9inc/mizu/agent/pkg/models"
+	"github.com/up9inc/mizu/agent/pkg/querycache"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/summary"
+	"github.com/up9inc/mizu/agent/pkg/timequery"
 	"github.com/up9inc/mizu/agent/pkg/validation"
 
 	"github.com/gin-gonic/gin"
@@ -20,6 +31,11 @@ import (
 
 var extensionsMap map[string]*tapApi.Extension // global
 
+var (
+	timestampFormatter     *shared.TimestampFormatter
+	timestampFormatterOnce sync.Once
+)
+
 func InitExtensionsMap(ref map[string]*tapApi.Extension) {
 	extensionsMap = ref
 }
@@ -39,6 +55,27 @@ func Error(c *gin.Context, err error) bool {
 	return false // no error, can continue
 }
 
+// QueryError is Error for the errors of the limited queries, the client of a canceled query is gone
+func QueryError(c *gin.Context, err error) bool {
+	if errors.Is(err, context.Canceled) {
+		logger.Log.Debugf("Query canceled, %s %s", c.Request.Method, c.Request.URL.Path)
+		c.Abort()
+		return true
+	}
+
+	if errors.Is(err, querylimit.ErrTooManyQueries) {
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":     true,
+			"type":      "error",
+			"autoClose": "5000",
+			"msg":       err.Error(),
+		})
+		return true
+	}
+
+	return Error(c, err)
+}
+
 func GetEntries(c *gin.Context) {
 	entriesRequest := &models.EntriesRequest{}
 
@@ -54,12 +91,11 @@ func GetEntries(c *gin.Context) {
 		entriesRequest.TimeoutMs = 3000
 	}
 
-	data, meta, err := basenine.Fetch(shared.BasenineHost, shared.BaseninePort,
-		entriesRequest.LeftOff, entriesRequest.Direction, entriesRequest.Query,
-		entriesRequest.Limit, time.Duration(entriesRequest.TimeoutMs)*time.Millisecond)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, validationError)
+	result, err := fetchEntries(c.Request.Context(), entriesRequest)
+	if QueryError(c, err) {
+		return // exit
 	}
+	data, meta := result.Data, result.Meta
 
 	response := &models.EntriesResponse{}
 	var dataSlice []interface{}
@@ -77,8 +113,11 @@ func GetEntries(c *gin.Context) {
 			return // exit
 		}
 
+		timequery.GetIndex().Record(int(entry.Id), entry.Timestamp)
+
 		extension := extensionsMap[entry.Protocol.Name]
 		base := extension.Dissector.Summarize(entry)
+		summary.Apply(entry, base)
 
 		dataSlice = append(dataSlice, base)
 	}
@@ -95,6 +134,31 @@ func GetEntries(c *gin.Context) {
 	c.JSON(http.StatusOK, response)
 }
 
+// fetchEntries shares the results of the recent identical queries, the pages that reach the newest entries are
+// fetched again once an entry is stored
+func fetchEntries(ctx context.Context, entriesRequest *models.EntriesRequest) (*querycache.Result, error) {
+	key := querycache.Key(entriesRequest.Query, entriesRequest.LeftOff, entriesRequest.Direction, entriesRequest.Limit)
+
+	return querycache.GetInstance().Fetch(ctx, key, func(ctx context.Context) (*querycache.Result, error) {
+		timeout := querylimit.GetInstance().Timeout(time.Duration(entriesRequest.TimeoutMs) * time.Millisecond)
+		startTime := time.Now()
+
+		data, meta, err := querylimit.GetInstance().Fetch(ctx,
+			entriesRequest.LeftOff, entriesRequest.Direction, entriesRequest.Query,
+			entriesRequest.Limit, timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		return &querycache.Result{
+			Data:    data,
+			Meta:    meta,
+			Open:    entriesRequest.LeftOff == -1 || (entriesRequest.Direction == 1 && len(data) < entriesRequest.Limit),
+			Partial: time.Since(startTime) >= timeout,
+		}, nil
+	})
+}
+
 func GetEntry(c *gin.Context) {
 	singleEntryRequest := &models.SingleEntryRequest{}
 
@@ -106,9 +170,30 @@ func GetEntry(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, validationError)
 	}
 
-	id, _ := strconv.Atoi(c.Param("id"))
+	id, err := getEntryIndex(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":     true,
+			"type":      "error",
+			"autoClose": "5000",
+			"msg":       err.Error(),
+		})
+		return // exit
+	}
+
+	query, err := timequery.Expand(singleEntryRequest.Query, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":     true,
+			"type":      "error",
+			"autoClose": "5000",
+			"msg":       err.Error(),
+		})
+		return // exit
+	}
+
 	var entry *tapApi.Entry
-	bytes, err := basenine.Single(shared.BasenineHost, shared.BaseninePort, id, singleEntryRequest.Query)
+	bytes, err := basenine.Single(shared.BasenineHost, shared.BaseninePort, id, query.Query)
 	if Error(c, err) {
 		return // exit
 	}
@@ -125,11 +210,12 @@ func GetEntry(c *gin.Context) {
 
 	extension := extensionsMap[entry.Protocol.Name]
 	base := extension.Dissector.Summarize(entry)
+	summary.Apply(entry, base)
 	representation, bodySize, _ := extension.Dissector.Represent(entry.Request, entry.Response)
 
 	var rules []map[string]interface{}
 	var isRulesEnabled bool
-	if entry.Protocol.Name == "http" {
+	if entry.Protocol.Name == "http" && entry.HTTPPair != "" {
 		harEntry, _ := har.NewEntry(entry.Request, entry.Response, entry.StartTime, entry.ElapsedTime)
 		_, rulesMatched, _isRulesEnabled := models.RunValidationRulesState(*harEntry, entry.Destination.Name)
 		isRulesEnabled = _isRulesEnabled
@@ -140,12 +226,66 @@ func GetEntry(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, tapApi.EntryWrapper{
-		Protocol:       entry.Protocol,
-		Representation: string(representation),
-		BodySize:       bodySize,
-		Data:           entry,
-		Base:           base,
-		Rules:          rules,
-		IsRulesEnabled: isRulesEnabled,
+		Protocol:           entry.Protocol,
+		Representation:     string(representation),
+		BodySize:           bodySize,
+		Data:               entry,
+		Base:               base,
+		Rules:              rules,
+		IsRulesEnabled:     isRulesEnabled,
+		FormattedTimestamp: getTimestampFormatter().Format(entry.StartTime),
+	})
+}
+
+func getTimestampFormatter() *shared.TimestampFormatter {
+	timestampFormatterOnce.Do(func() {
+		var timestampConfig shared.TimestampConfig
+		if config.Config != nil {
+			timestampConfig = config.Config.Timestamps
+		}
+
+		var err error
+		if timestampFormatter, err = shared.NewTimestampFormatter(timestampConfig); err != nil {
+			logger.Log.Errorf("Invalid timestamps config, using the defaults: %v", err)
+			timestampFormatter, _ = shared.NewTimestampFormatter(shared.TimestampConfig{})
+		}
 	})
+
+	return timestampFormatter
+}
+
+// getEntryIndex returns the database index of an entry, entries can be referred to either by their index or by
+// their ulid which, unlike the index, stays the same when the database is recreated. The ulids are looked up in the
+// entry index
+func getEntryIndex(ctx context.Context, id string) (int, error) {
+	if !entryid.IsValid(id) {
+		return strconv.Atoi(id)
+	}
+
+	if index, ok := entryindex.GetInstance().Get(id); ok {
+		return index, nil
+	}
+
+	// only the entries the index forgot or didn't stream yet are scanned for
+	leftOff, shouldScan := entryindex.GetInstance().ScanStart(id)
+	if !shouldScan {
+		return 0, fmt.Errorf("entry %s not found", id)
+	}
+
+	query := fmt.Sprintf(`entryId == "%s"`, id)
+	data, _, err := querylimit.GetInstance().Fetch(ctx, leftOff, -1, query, 1, 3*time.Second)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(data) == 0 {
+		return 0, fmt.Errorf("entry %s not found", id)
+	}
+
+	var entry *tapApi.Entry
+	if err := json.Unmarshal(data[0], &entry); err != nil {
+		return 0, err
+	}
+
+	return int(entry.Id), nil
 }
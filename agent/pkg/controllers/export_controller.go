@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/agent/pkg/lifecycle"
+	"github.com/up9inc/mizu/agent/pkg/models"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/utils"
+	"github.com/up9inc/mizu/agent/pkg/validation"
+	"github.com/up9inc/mizu/agent/pkg/version"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	harVersion              = "1.2"
+	harCreatorName          = "mizu"
+	harExportSource         = "mizu-agent"
+	harExportDefaultTimeout = 10 * time.Second
+)
+
+// GetHarExport returns the http entries matching the query and time range as a HAR log, oldest entry first
+func GetHarExport(c *gin.Context) {
+	exportRequest := &models.HarExportRequest{}
+
+	if err := c.BindQuery(exportRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	if validationError := validation.Validate(exportRequest); validationError != nil {
+		c.JSON(http.StatusBadRequest, validationError)
+		return
+	}
+
+	timeout := harExportDefaultTimeout
+	if exportRequest.TimeoutMs > 0 {
+		timeout = time.Duration(exportRequest.TimeoutMs) * time.Millisecond
+	}
+
+	query := buildHarExportQuery(exportRequest.Query, exportRequest.From, exportRequest.To)
+	data, _, err := querylimit.GetInstance().Fetch(c.Request.Context(), -1, -1, query, exportRequest.Limit, timeout)
+	if QueryError(c, err) {
+		return // exit
+	}
+
+	harEntries := make([]*har.Entry, 0, len(data))
+
+	// the database returns the latest entries first
+	for i := len(data) - 1; i >= 0; i-- {
+		var entry *tapApi.Entry
+		if err := json.Unmarshal(data[i], &entry); err != nil {
+			logger.Log.Debugf("Skipping an entry that couldn't be parsed in the HAR export: %v", err)
+			continue
+		}
+
+		if harEntry := newHarExportEntry(entry); harEntry != nil {
+			harEntries = append(harEntries, harEntry)
+		}
+	}
+
+	lifecycle.GetInstance().Notify(shared.LifecycleEventSnapshotCreated, &lifecycle.SnapshotData{
+		Entries: len(harEntries),
+		Query:   exportRequest.Query,
+		From:    exportRequest.From,
+		To:      exportRequest.To,
+	})
+
+	source := harExportSource
+	c.JSON(http.StatusOK, models.ExtendedHAR{
+		Log: &models.ExtendedLog{
+			Version: harVersion,
+			Creator: &models.ExtendedCreator{
+				Creator: &har.Creator{
+					Name:    harCreatorName,
+					Version: version.Ver,
+				},
+				Source: &source,
+			},
+			Entries: harEntries,
+			Session: getSessionMetadata(),
+		},
+	})
+}
+
+// buildHarExportQuery narrows the user query to http entries in the requested time range
+func buildHarExportQuery(query string, from int64, to int64) string {
+	conditions := []string{`protocol.name == "http"`}
+
+	if strings.TrimSpace(query) != "" {
+		conditions = append(conditions, fmt.Sprintf("(%s)", query))
+	}
+	if from > 0 {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= %d", from))
+	}
+	if to > 0 {
+		conditions = append(conditions, fmt.Sprintf("timestamp <= %d", to))
+	}
+
+	return strings.Join(conditions, " and ")
+}
+
+// newHarExportEntry converts an http entry to HAR, the url points at the resolved destination so the archive
+// can be replayed against the service rather than the pod ip, nil is returned for entries that can't be converted
+func newHarExportEntry(entry *tapApi.Entry) *har.Entry {
+	if entry.Protocol.Name != "http" {
+		return nil
+	}
+
+	// the WebSocket frames and the protocol anomalies aren't request response pairs
+	if _, ok := entry.Request["_headers"]; !ok {
+		return nil
+	}
+
+	harEntry, err := har.NewEntry(entry.Request, entry.Response, entry.StartTime, entry.ElapsedTime)
+	if err != nil {
+		return nil
+	}
+
+	if entry.Destination != nil && entry.Destination.Name != "" {
+		harEntry.Request.URL = utils.SetHostname(harEntry.Request.URL, entry.Destination.Name)
+	}
+
+	return harEntry
+}
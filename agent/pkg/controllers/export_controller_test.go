@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+func TestBuildHarExportQuery(t *testing.T) {
+	tests := []struct {
+		query    string
+		from     int64
+		to       int64
+		expected string
+	}{
+		{query: "", expected: `protocol.name == "http"`},
+		{query: " ", from: 1000, expected: `protocol.name == "http" and timestamp >= 1000`},
+		{query: `response.status == 500 or request.path == "/a"`, from: 1000, to: 2000, expected: `protocol.name == "http" and (response.status == 500 or request.path == "/a") and timestamp >= 1000 and timestamp <= 2000`},
+		{query: "", to: 2000, expected: `protocol.name == "http" and timestamp <= 2000`},
+	}
+
+	for _, test := range tests {
+		if actual := buildHarExportQuery(test.query, test.from, test.to); actual != test.expected {
+			t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+		}
+	}
+}
+
+func TestNewHarExportEntry(t *testing.T) {
+	entry := &tapApi.Entry{
+		Protocol:    tapApi.Protocol{Name: "http"},
+		Destination: &tapApi.TCP{Name: "orders.shop", IP: "10.0.0.7", Port: "8080"},
+		StartTime:   time.Unix(1650000000, 0),
+		ElapsedTime: 12,
+		Request: map[string]interface{}{
+			"method":       "GET",
+			"url":          "/orders?id=1",
+			"httpVersion":  "HTTP/1.1",
+			"_headers":     []interface{}{map[string]interface{}{"name": "Host", "value": "10.0.0.7:8080"}},
+			"_queryString": []interface{}{map[string]interface{}{"name": "id", "value": "1"}},
+		},
+		Response: map[string]interface{}{
+			"status":      float64(200),
+			"statusText":  "OK",
+			"httpVersion": "HTTP/1.1",
+			"_headers":    []interface{}{},
+			"content":     map[string]interface{}{"mimeType": "application/json", "encoding": "", "text": "{}"},
+		},
+	}
+
+	harEntry := newHarExportEntry(entry)
+	if harEntry == nil {
+		t.Fatalf("unexpected result - expected the http entry to be converted")
+	}
+	if harEntry.Request.URL != "http://orders.shop/orders?id=1" {
+		t.Errorf("unexpected result - expected the url to point at the destination, actual: %v", harEntry.Request.URL)
+	}
+	if harEntry.Time != 12 {
+		t.Errorf("unexpected result - expected time 12, actual: %v", harEntry.Time)
+	}
+
+	entry.Protocol.Name = "amqp"
+	if newHarExportEntry(entry) != nil {
+		t.Errorf("unexpected result - expected non http entries to be skipped")
+	}
+}
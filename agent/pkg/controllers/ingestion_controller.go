@@ -0,0 +1,46 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/ingestion"
+	"github.com/up9inc/mizu/shared"
+)
+
+func GetIngestionStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, ingestion.GetInstance().GetStatus())
+}
+
+func PostIngestionMute(c *gin.Context) {
+	muteRequest := &shared.IngestionMute{}
+	if err := c.Bind(muteRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	if muteRequest.Kind == shared.IngestionMuteProtocol {
+		if _, ok := extensionsMap[muteRequest.Value]; !ok {
+			c.JSON(http.StatusBadRequest, "unknown protocol "+muteRequest.Value)
+			return
+		}
+	}
+
+	mute, err := ingestion.GetInstance().Mute(muteRequest.Kind, muteRequest.Value)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, mute)
+}
+
+func DeleteIngestionMute(c *gin.Context) {
+	mute, err := ingestion.GetInstance().Unmute(c.Param("kind"), c.Param("value"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, mute)
+}
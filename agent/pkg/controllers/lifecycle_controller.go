@@ -0,0 +1,24 @@
+package controllers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/lifecycle"
+)
+
+// the cli waits for the response before removing the resources, so the posting is bounded well below its timeout
+const sessionStoppedFlushTimeout = 5 * time.Second
+
+func PostSessionStopped(c *gin.Context) {
+	if !lifecycle.GetInstance().SessionStopped(sessionStoppedFlushTimeout) {
+		c.JSON(http.StatusGatewayTimeout, gin.H{
+			"error": true,
+			"msg":   "timed out posting the lifecycle events",
+		})
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,68 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/maintenance"
+	"github.com/up9inc/mizu/agent/pkg/models"
+)
+
+func GetMaintenanceWindows(c *gin.Context) {
+	c.JSON(http.StatusOK, maintenance.GetInstance().GetWindows())
+}
+
+func PostMaintenanceWindow(c *gin.Context) {
+	windowRequest := &models.MaintenanceWindowRequest{}
+	if err := c.Bind(windowRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	end := windowRequest.End
+	if windowRequest.Duration != "" {
+		if end != 0 {
+			c.JSON(http.StatusBadRequest, "either end or duration can be set, not both")
+			return
+		}
+
+		duration, err := time.ParseDuration(windowRequest.Duration)
+		if err != nil || duration <= 0 {
+			c.JSON(http.StatusBadRequest, "duration must be a positive duration, like 30m")
+			return
+		}
+
+		start := windowRequest.Start
+		if start == 0 {
+			start = time.Now().UnixNano() / int64(time.Millisecond)
+		}
+		windowRequest.Start = start
+		end = start + duration.Milliseconds()
+	}
+
+	window, err := maintenance.GetInstance().AddWindow(windowRequest.Start, end, windowRequest.Reason, windowRequest.Namespace)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, window)
+}
+
+func DeleteMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	window, err := maintenance.GetInstance().EndWindow(id)
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, window)
+}
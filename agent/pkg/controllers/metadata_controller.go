@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/config"
 	"github.com/up9inc/mizu/agent/pkg/version"
 	"github.com/up9inc/mizu/shared"
 )
@@ -12,3 +14,16 @@ func GetVersion(c *gin.Context) {
 	resp := shared.VersionResponse{Ver: version.Ver}
 	c.JSON(http.StatusOK, resp)
 }
+
+// GetFeatures advertises the enabled modules, so the cli and the ui skip the data of the disabled ones
+func GetFeatures(c *gin.Context) {
+	c.JSON(http.StatusOK, config.GetFeatures())
+}
+
+// featureDisabled answers the requests for the data of a disabled module
+func featureDisabled(c *gin.Context, feature string) {
+	c.JSON(http.StatusNotFound, gin.H{
+		"error": true,
+		"msg":   fmt.Sprintf("%s are disabled in the config", feature),
+	})
+}
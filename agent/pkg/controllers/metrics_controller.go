@@ -0,0 +1,21 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/metrics"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+// the content type of the Prometheus text format
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+func GetMetrics(c *gin.Context) {
+	c.Header("Content-Type", metricsContentType)
+	c.Status(http.StatusOK)
+
+	if err := metrics.GetInstance().Write(c.Writer); err != nil {
+		logger.Log.Debugf("Error writing the metrics: %v", err)
+	}
+}
@@ -0,0 +1,32 @@
+package controllers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/provenance"
+	"github.com/up9inc/mizu/shared"
+)
+
+func GetProvenanceSegments(c *gin.Context) {
+	signer := provenance.GetInstance()
+
+	segments, err := signer.GetSegments()
+	if err == provenance.ErrDisabled {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":     true,
+			"type":      "error",
+			"autoClose": "5000",
+			"msg":       err.Error(),
+		})
+		return // exit
+	}
+	if Error(c, err) {
+		return // exit
+	}
+
+	c.JSON(http.StatusOK, shared.ProvenanceSegmentsResponse{
+		Segments:      segments,
+		UnsignedCount: signer.GetUnsignedCount(),
+	})
+}
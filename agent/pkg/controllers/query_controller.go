@@ -1,13 +1,23 @@
 package controllers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	basenine "github.com/up9inc/basenine/client/go"
+	"github.com/up9inc/mizu/agent/pkg/models"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/timequery"
+	"github.com/up9inc/mizu/agent/pkg/validation"
 	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
 )
 
+const rateDefaultTimeout = 10 * time.Second
+
 type ValidateResponse struct {
 	Valid   bool   `json:"valid"`
 	Message string `json:"message"`
@@ -18,7 +28,10 @@ func PostValidate(c *gin.Context) {
 	valid := true
 	message := ""
 
-	err := basenine.Validate(shared.BasenineHost, shared.BaseninePort, query)
+	expanded, err := timequery.Expand(query, time.Now())
+	if err == nil {
+		err = basenine.Validate(shared.BasenineHost, shared.BaseninePort, expanded.Query)
+	}
 	if err != nil {
 		valid = false
 		message = err.Error()
@@ -29,3 +42,57 @@ func PostValidate(c *gin.Context) {
 		Message: message,
 	})
 }
+
+// GetRate counts the entries of a query with a rate operator in every interval of its window
+func GetRate(c *gin.Context) {
+	rateRequest := &models.RateRequest{}
+	if err := c.BindQuery(rateRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	if validationError := validation.Validate(rateRequest); validationError != nil {
+		c.JSON(http.StatusBadRequest, validationError)
+		return
+	}
+
+	expanded, err := timequery.Expand(rateRequest.Query, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+	if expanded.Rate == nil {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("the query has no %s operator, like %s(\"1m\", \"1h\")", timequery.RateOperator, timequery.RateOperator))
+		return
+	}
+
+	timeout := rateDefaultTimeout
+	if rateRequest.TimeoutMs > 0 {
+		timeout = time.Duration(rateRequest.TimeoutMs) * time.Millisecond
+	}
+
+	// the expanded query has no time operators left, so it's fetched over the same window the rate counts
+	data, _, err := querylimit.GetInstance().Fetch(c.Request.Context(), -1, -1, expanded.Query, rateRequest.Limit, timeout)
+	if QueryError(c, err) {
+		return // exit
+	}
+
+	timestamps := make([]int64, 0, len(data))
+	for _, row := range data {
+		var entry struct {
+			Timestamp int64 `json:"timestamp"`
+		}
+		if err := json.Unmarshal(row, &entry); err != nil {
+			logger.Log.Debugf("Skipping an entry that couldn't be parsed in the rate: %v", err)
+			continue
+		}
+		timestamps = append(timestamps, entry.Timestamp)
+	}
+
+	c.JSON(http.StatusOK, models.RateResponse{
+		From:      expanded.Rate.From,
+		To:        expanded.Rate.To,
+		Interval:  expanded.Rate.Interval,
+		Intervals: expanded.Rate.Count(timestamps),
+		Truncated: len(data) >= rateRequest.Limit,
+	})
+}
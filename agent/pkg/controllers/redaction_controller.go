@@ -0,0 +1,84 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/redaction"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	defaultRedactionTestLimit = 20
+	maxRedactionTestLimit     = 1000
+)
+
+// PostRedactionTest redacts the entries of a HAR log, or the latest stored http entries matching the query, with the
+// options of the request and responds with the values the redaction changes, nothing is stored
+func PostRedactionTest(c *gin.Context) {
+	testRequest := &shared.RedactionTestRequest{}
+	if err := c.Bind(testRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	for i := range testRequest.Options.RedactionRules {
+		if err := testRequest.Options.RedactionRules[i].Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	var entries []har.Entry
+	if len(testRequest.Har) > 0 {
+		var harLog har.HAR
+		if err := json.Unmarshal(testRequest.Har, &harLog); err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("har isn't a HAR log, %v", err))
+			return
+		}
+		entries = harLog.Log.Entries
+	} else {
+		if testRequest.Limit == 0 {
+			testRequest.Limit = defaultRedactionTestLimit
+		}
+		if testRequest.Limit < 0 || testRequest.Limit > maxRedactionTestLimit {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxRedactionTestLimit))
+			return
+		}
+
+		data, _, err := querylimit.GetInstance().Fetch(c.Request.Context(), -1, -1, buildHarExportQuery(testRequest.Query, 0, 0), testRequest.Limit, harExportDefaultTimeout)
+		if QueryError(c, err) {
+			return // exit
+		}
+
+		// the database returns the latest entries first
+		for i := len(data) - 1; i >= 0; i-- {
+			var entry *tapApi.Entry
+			if err := json.Unmarshal(data[i], &entry); err != nil {
+				logger.Log.Debugf("Skipping an entry that couldn't be parsed in the redaction test: %v", err)
+				continue
+			}
+
+			if harEntry := newHarExportEntry(entry); harEntry != nil {
+				entries = append(entries, *harEntry)
+			}
+		}
+	}
+
+	testResponse := &shared.RedactionTestResponse{Entries: len(entries), Results: make([]shared.RedactionTestResult, 0, len(entries))}
+	for i := range entries {
+		result := redaction.Test(&entries[i], &testRequest.Options)
+		if len(result.Values) > 0 {
+			testResponse.Redacted++
+		}
+		testResponse.Results = append(testResponse.Results, *result)
+	}
+
+	c.JSON(http.StatusOK, testResponse)
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/replayer"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	defaultReplayLimit       = 100
+	maxReplayLimit           = 1000
+	defaultReplayConcurrency = 4
+	maxReplayConcurrency     = 50
+	defaultReplayTimeout     = 10 * time.Second
+)
+
+// PostReplay re-sends the captured http requests matching the query from the API server pod, oldest first, and
+// responds once all of them were answered
+func PostReplay(c *gin.Context) {
+	replayRequest := &shared.ReplayRequest{}
+	if err := c.Bind(replayRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	target, err := url.Parse(replayRequest.TargetUrl)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		c.JSON(http.StatusBadRequest, "targetUrl must be an absolute http or https url")
+		return
+	}
+
+	if replayRequest.Limit == 0 {
+		replayRequest.Limit = defaultReplayLimit
+	}
+	if replayRequest.Limit < 0 || replayRequest.Limit > maxReplayLimit {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", maxReplayLimit))
+		return
+	}
+
+	if replayRequest.Concurrency == 0 {
+		replayRequest.Concurrency = defaultReplayConcurrency
+	}
+	if replayRequest.Concurrency < 0 || replayRequest.Concurrency > maxReplayConcurrency {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("concurrency must be between 1 and %d", maxReplayConcurrency))
+		return
+	}
+
+	timeout := defaultReplayTimeout
+	if replayRequest.TimeoutMs > 0 {
+		timeout = time.Duration(replayRequest.TimeoutMs) * time.Millisecond
+	}
+
+	data, _, err := querylimit.GetInstance().Fetch(c.Request.Context(), -1, -1, buildHarExportQuery(replayRequest.Query, 0, 0), replayRequest.Limit, harExportDefaultTimeout)
+	if QueryError(c, err) {
+		return // exit
+	}
+
+	requests := make([]*replayer.Request, 0, len(data))
+
+	// the database returns the latest entries first
+	for i := len(data) - 1; i >= 0; i-- {
+		var entry *tapApi.Entry
+		if err := json.Unmarshal(data[i], &entry); err != nil {
+			logger.Log.Debugf("Skipping an entry that couldn't be parsed in the replay: %v", err)
+			continue
+		}
+
+		// the replayed requests are captured as well when the target is tapped
+		if harEntry := newHarExportEntry(entry); harEntry != nil && !replayer.IsReplayed(&harEntry.Request) {
+			requests = append(requests, &replayer.Request{
+				EntryId:        getReplayedEntryId(entry),
+				OriginalStatus: harEntry.Response.Status,
+				Request:        &harEntry.Request,
+			})
+		}
+	}
+
+	results := replayer.Replay(c.Request.Context(), requests, target, replayRequest.Concurrency, replayRequest.Headers, timeout)
+
+	replayResponse := &shared.ReplayResponse{Entries: len(results), Results: results}
+	for _, result := range results {
+		if result.Error != "" {
+			replayResponse.Failed++
+		}
+	}
+
+	logger.Log.Infof("Replayed %d entries to %s, %d failed", replayResponse.Entries, target.Host, replayResponse.Failed)
+	c.JSON(http.StatusOK, replayResponse)
+}
+
+func getReplayedEntryId(entry *tapApi.Entry) string {
+	if entry.EntryId != "" {
+		return entry.EntryId
+	}
+
+	return strconv.FormatUint(uint64(entry.Id), 10)
+}
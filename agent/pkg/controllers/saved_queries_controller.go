@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	basenine "github.com/up9inc/basenine/client/go"
+	"github.com/up9inc/mizu/agent/pkg/savedqueries"
+	"github.com/up9inc/mizu/agent/pkg/timequery"
+	"github.com/up9inc/mizu/shared"
+)
+
+func GetSavedQueries(c *gin.Context) {
+	c.JSON(http.StatusOK, savedqueries.GetInstance().List())
+}
+
+func GetSavedQuery(c *gin.Context) {
+	savedQuery, err := savedqueries.GetInstance().Get(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, savedQuery)
+}
+
+func PostSavedQuery(c *gin.Context) {
+	savedQueryRequest := &shared.SavedQuery{}
+	if err := c.Bind(savedQueryRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validateQuery(savedQueryRequest.Query); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	savedQuery, err := savedqueries.GetInstance().Create(savedQueryRequest.Name, savedQueryRequest.Query, savedQueryRequest.Owner)
+	if errors.Is(err, savedqueries.ErrAlreadyExists) {
+		c.JSON(http.StatusConflict, err.Error())
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, savedQuery)
+}
+
+func PutSavedQuery(c *gin.Context) {
+	savedQueryRequest := &shared.SavedQuery{}
+	if err := c.Bind(savedQueryRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	if err := validateQuery(savedQueryRequest.Query); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	savedQuery, err := savedqueries.GetInstance().Update(c.Param("name"), savedQueryRequest.Query, savedQueryRequest.Owner)
+	if errors.Is(err, savedqueries.ErrNotFound) {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, savedQuery)
+}
+
+func DeleteSavedQuery(c *gin.Context) {
+	savedQuery, err := savedqueries.GetInstance().Delete(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, savedQuery)
+}
+
+// validateSavedQuery refuses the queries the database can't run, the time operators are kept unexpanded in the saved
+// query so since("1h") stays relative to when it runs
+func validateQuery(query string) error {
+	expanded, err := timequery.Expand(query, time.Now())
+	if err != nil {
+		return err
+	}
+
+	return basenine.Validate(shared.BasenineHost, shared.BaseninePort, expanded.Query)
+}
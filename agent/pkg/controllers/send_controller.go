@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/sender"
+	"github.com/up9inc/mizu/agent/pkg/summary"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	defaultCaptureTimeout = 5 * time.Second
+	captureInterval       = 500 * time.Millisecond
+)
+
+// PostSend sends a request from the API server pod and waits for the entry captured for it, the entry is only
+// captured when the target pod is tapped
+func PostSend(c *gin.Context) {
+	sendRequest := &shared.SendRequest{}
+	if err := c.Bind(sendRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	if sendRequest.Protocol == "" {
+		sendRequest.Protocol = shared.SendProtocolHttp
+	}
+	if sendRequest.Protocol != shared.SendProtocolHttp && sendRequest.Protocol != shared.SendProtocolGrpc {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("protocol must be %s or %s", shared.SendProtocolHttp, shared.SendProtocolGrpc))
+		return
+	}
+	if sendRequest.Method == "" {
+		sendRequest.Method = http.MethodGet
+	}
+	if targetUrl, err := url.Parse(sendRequest.Url); err != nil || (targetUrl.Scheme != "http" && targetUrl.Scheme != "https") || targetUrl.Host == "" {
+		c.JSON(http.StatusBadRequest, "url must be an absolute http or https url")
+		return
+	}
+
+	sendResponse, err := sender.Send(sendRequest)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, err.Error())
+		return
+	}
+
+	captureTimeout := defaultCaptureTimeout
+	if sendRequest.CaptureTimeoutMs > 0 {
+		captureTimeout = time.Duration(sendRequest.CaptureTimeoutMs) * time.Millisecond
+	}
+	if entry := fetchSentEntry(c.Request.Context(), sendResponse.SendId, captureTimeout); entry != nil {
+		sendResponse.Entry = entry
+	}
+
+	c.JSON(http.StatusOK, sendResponse)
+}
+
+// fetchSentEntry polls the database until the entry of the sent request is captured or the timeout expires
+func fetchSentEntry(ctx context.Context, sendId string, timeout time.Duration) *tapApi.BaseEntry {
+	query := fmt.Sprintf(`request.headers["%s"] == "%s"`, sender.SendIdHeaderName, sendId)
+	deadline := time.Now().Add(timeout)
+
+	for {
+		data, _, err := querylimit.GetInstance().Fetch(ctx, -1, -1, query, 1, captureInterval)
+		if err != nil {
+			logger.Log.Errorf("Error fetching the entry of sent request %s: %v", sendId, err)
+			return nil
+		}
+
+		if len(data) > 0 {
+			var entry *tapApi.Entry
+			if err := json.Unmarshal(data[0], &entry); err != nil {
+				logger.Log.Errorf("Error unmarshaling the entry of sent request %s: %v", sendId, err)
+				return nil
+			}
+
+			extension := extensionsMap[entry.Protocol.Name]
+			base := extension.Dissector.Summarize(entry)
+			summary.Apply(entry, base)
+			return base
+		}
+
+		if time.Now().After(deadline) {
+			return nil
+		}
+		time.Sleep(captureInterval)
+	}
+}
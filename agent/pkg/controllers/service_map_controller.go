@@ -2,13 +2,26 @@ package controllers
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/up9inc/mizu/agent/pkg/dependency"
 	"github.com/up9inc/mizu/agent/pkg/servicemap"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
 )
 
+// the streamed service map is sent again once it changed, at most once an interval
+const serviceMapStreamInterval = 2 * time.Second
+
+var serviceMapUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true }, // like cors for web socket
+}
+
 type ServiceMapController struct {
 	service servicemap.ServiceMap
 }
@@ -25,15 +38,60 @@ func (s *ServiceMapController) Status(c *gin.Context) {
 }
 
 func (s *ServiceMapController) Get(c *gin.Context) {
-	response := &servicemap.ServiceMapResponse{
-		Status: s.service.GetStatus(),
-		Nodes:  s.service.GetNodes(),
-		Edges:  s.service.GetEdges(),
+	c.JSON(http.StatusOK, s.getResponse())
+}
+
+// Stream sends the service map over a websocket, and again whenever entries were added to it or it was reset, until
+// the socket is closed
+func (s *ServiceMapController) Stream(c *gin.Context) {
+	connection, err := serviceMapUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		logger.Log.Errorf("Failed to upgrade the service map socket: %v", err)
+		return
+	}
+	defer connection.Close()
+
+	// the socket is only read for the close and the control messages of the client
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := connection.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(serviceMapStreamInterval)
+	defer ticker.Stop()
+
+	entriesProcessedCount := -1
+	for {
+		if response := s.getResponse(); response.Status.EntriesProcessedCount != entriesProcessedCount {
+			if err := connection.WriteJSON(response); err != nil {
+				logger.Log.Debugf("Stopped streaming the service map to %s: %v", connection.RemoteAddr(), err)
+				return
+			}
+			entriesProcessedCount = response.Status.EntriesProcessedCount
+		}
+
+		select {
+		case <-closed:
+			return
+		case <-ticker.C:
+		}
 	}
-	c.JSON(http.StatusOK, response)
 }
 
 func (s *ServiceMapController) Reset(c *gin.Context) {
 	s.service.Reset()
 	s.Status(c)
 }
+
+func (s *ServiceMapController) getResponse() *shared.ServiceMapResponse {
+	return &shared.ServiceMapResponse{
+		Status: s.service.GetStatus(),
+		Nodes:  s.service.GetNodes(),
+		Edges:  s.service.GetEdges(),
+	}
+}
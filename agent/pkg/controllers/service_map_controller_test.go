@@ -12,6 +12,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/suite"
+	"github.com/up9inc/mizu/shared"
 	tapApi "github.com/up9inc/mizu/tap/api"
 )
 
@@ -74,7 +75,7 @@ func (s *ServiceMapControllerSuite) TestGetStatus() {
 	s.c.Status(s.g)
 	assert.Equal(http.StatusOK, s.w.Code)
 
-	var status servicemap.ServiceMapStatus
+	var status shared.ServiceMapStatus
 	err := json.Unmarshal(s.w.Body.Bytes(), &status)
 	assert.NoError(err)
 	assert.Equal("enabled", status.Status)
@@ -89,7 +90,7 @@ func (s *ServiceMapControllerSuite) TestGet() {
 	s.c.Get(s.g)
 	assert.Equal(http.StatusOK, s.w.Code)
 
-	var response servicemap.ServiceMapResponse
+	var response shared.ServiceMapResponse
 	err := json.Unmarshal(s.w.Body.Bytes(), &response)
 	assert.NoError(err)
 
@@ -100,13 +101,13 @@ func (s *ServiceMapControllerSuite) TestGet() {
 	assert.Equal(1, response.Status.EdgeCount)
 
 	// response nodes
-	aNode := servicemap.ServiceMapNode{
+	aNode := shared.ServiceMapNode{
 		Id:    1,
 		Name:  TCPEntryA.Name,
 		Entry: TCPEntryA,
 		Count: 1,
 	}
-	bNode := servicemap.ServiceMapNode{
+	bNode := shared.ServiceMapNode{
 		Id:    2,
 		Name:  TCPEntryB.Name,
 		Entry: TCPEntryB,
@@ -117,7 +118,7 @@ func (s *ServiceMapControllerSuite) TestGet() {
 	assert.Len(response.Nodes, 2)
 
 	// response edges
-	assert.Equal([]servicemap.ServiceMapEdge{
+	assert.Equal([]shared.ServiceMapEdge{
 		{
 			Source:      aNode,
 			Destination: bNode,
@@ -133,7 +134,7 @@ func (s *ServiceMapControllerSuite) TestGetReset() {
 	s.c.Reset(s.g)
 	assert.Equal(http.StatusOK, s.w.Code)
 
-	var status servicemap.ServiceMapStatus
+	var status shared.ServiceMapStatus
 	err := json.Unmarshal(s.w.Body.Bytes(), &status)
 	assert.NoError(err)
 	assert.Equal("enabled", status.Status)
@@ -0,0 +1,171 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/config"
+	"github.com/up9inc/mizu/agent/pkg/dependency"
+	"github.com/up9inc/mizu/agent/pkg/operator"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+)
+
+// GetSessions returns the metadata of the sessions the stored entries were captured in, an api server holds a single
+// session
+func GetSessions(c *gin.Context) {
+	sessions := make([]*shared.SessionMetadata, 0)
+	if session := getSessionMetadata(); session != nil {
+		sessions = append(sessions, session)
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// getSessionMetadata returns nil when the agent wasn't started by the cli, like in the har reader mode
+func getSessionMetadata() *shared.SessionMetadata {
+	if config.Config == nil || config.Config.Session.StartTime == 0 {
+		return nil
+	}
+
+	session := config.Config.Session
+	return &session
+}
+
+// GetSessionTap returns the spec of the tap of the session and the status of its reconciliation
+func GetSessionTap(c *gin.Context) {
+	sessionTap, ok := getSessionTap(c)
+	if !ok {
+		return
+	}
+
+	mizuTap, err := sessionTap.GetTap(c.Request.Context())
+	if err != nil {
+		handleSessionTapError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mizuTap)
+}
+
+// PostSessionTap starts tapping the pods of the spec, the session must have no tap
+func PostSessionTap(c *gin.Context) {
+	sessionTap, ok := getSessionTap(c)
+	if !ok {
+		return
+	}
+
+	var spec kubernetes.MizuTapSpec
+	if err := c.Bind(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mizuTap, err := sessionTap.CreateTap(c.Request.Context(), spec)
+	if err != nil {
+		handleSessionTapError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, mizuTap)
+}
+
+// PutSessionTap replaces the spec of the tap, the tappers follow the pods of the new spec
+func PutSessionTap(c *gin.Context) {
+	sessionTap, ok := getSessionTap(c)
+	if !ok {
+		return
+	}
+
+	var spec kubernetes.MizuTapSpec
+	if err := c.Bind(&spec); err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return
+	}
+
+	mizuTap, err := sessionTap.UpdateTap(c.Request.Context(), func(currentSpec *kubernetes.MizuTapSpec) {
+		*currentSpec = spec
+	})
+	if err != nil {
+		handleSessionTapError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mizuTap)
+}
+
+// StopSessionTap stops the tappers and keeps the spec, so StartSessionTap taps the same pods again
+func StopSessionTap(c *gin.Context) {
+	setSessionTapStopped(c, true)
+}
+
+func StartSessionTap(c *gin.Context) {
+	setSessionTapStopped(c, false)
+}
+
+func setSessionTapStopped(c *gin.Context, stopped bool) {
+	sessionTap, ok := getSessionTap(c)
+	if !ok {
+		return
+	}
+
+	mizuTap, err := sessionTap.UpdateTap(c.Request.Context(), func(spec *kubernetes.MizuTapSpec) {
+		spec.Stopped = stopped
+	})
+	if err != nil {
+		handleSessionTapError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, mizuTap)
+}
+
+// DeleteSessionTap removes the tap, the tappers stop and the entries captured so far are kept
+func DeleteSessionTap(c *gin.Context) {
+	sessionTap, ok := getSessionTap(c)
+	if !ok {
+		return
+	}
+
+	if err := sessionTap.RemoveTap(c.Request.Context()); err != nil {
+		handleSessionTapError(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getSessionTap responds with an error when the tap of the session isn't reconciled by the api server, the cli syncs
+// the tappers itself unless mizu runs as an operator
+func getSessionTap(c *gin.Context) (operator.SessionTap, bool) {
+	sessionTap, err := lookupSessionTap()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+
+	return sessionTap, true
+}
+
+func lookupSessionTap() (operator.SessionTap, error) {
+	if config.Config == nil || !config.Config.Operator || !dependency.IsRegistered(dependency.SessionTapDependency) {
+		return nil, errors.New("the tap of the session can only be controlled when mizu runs as an operator, like with mizu tap --operator")
+	}
+
+	return dependency.GetInstance(dependency.SessionTapDependency).(operator.SessionTap), nil
+}
+
+func handleSessionTapError(c *gin.Context, err error) {
+	var invalidSpecErr *operator.InvalidTapSpecError
+	switch {
+	case errors.Is(err, operator.ErrTapNotFound):
+		c.JSON(http.StatusNotFound, err.Error())
+	case errors.Is(err, operator.ErrTapExists):
+		c.JSON(http.StatusConflict, err.Error())
+	case errors.As(err, &invalidSpecErr):
+		c.JSON(http.StatusBadRequest, err.Error())
+	default:
+		c.JSON(http.StatusInternalServerError, err.Error())
+	}
+}
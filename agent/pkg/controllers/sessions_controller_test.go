@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/config"
+	"github.com/up9inc/mizu/agent/pkg/dependency"
+	"github.com/up9inc/mizu/agent/pkg/operator"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+)
+
+// fakeSessionTap keeps the tap in memory, like the MizuTap the operator reconciles
+type fakeSessionTap struct {
+	mizuTap *kubernetes.MizuTap
+}
+
+func (fake *fakeSessionTap) GetTap(ctx context.Context) (*kubernetes.MizuTap, error) {
+	if fake.mizuTap == nil {
+		return nil, operator.ErrTapNotFound
+	}
+	return fake.mizuTap, nil
+}
+
+func (fake *fakeSessionTap) CreateTap(ctx context.Context, spec kubernetes.MizuTapSpec) (*kubernetes.MizuTap, error) {
+	if fake.mizuTap != nil {
+		return nil, operator.ErrTapExists
+	}
+	if err := operator.ValidateTapSpec(&spec); err != nil {
+		return nil, err
+	}
+	fake.mizuTap = kubernetes.NewMizuTap("mizu", "mizu-tap", spec)
+	return fake.mizuTap, nil
+}
+
+func (fake *fakeSessionTap) UpdateTap(ctx context.Context, update func(spec *kubernetes.MizuTapSpec)) (*kubernetes.MizuTap, error) {
+	if fake.mizuTap == nil {
+		return nil, operator.ErrTapNotFound
+	}
+	update(&fake.mizuTap.Spec)
+	return fake.mizuTap, nil
+}
+
+func (fake *fakeSessionTap) RemoveTap(ctx context.Context) error {
+	if fake.mizuTap == nil {
+		return operator.ErrTapNotFound
+	}
+	fake.mizuTap = nil
+	return nil
+}
+
+func serveSessionTapRequest(method string, body string, handler gin.HandlerFunc) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/sessions/tap", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	handler(c)
+	return w
+}
+
+func TestSessionTapLifecycle(t *testing.T) {
+	config.Config = &shared.MizuAgentConfig{Operator: true}
+	defer func() { config.Config = nil }()
+
+	sessionTap := &fakeSessionTap{}
+	dependency.RegisterGenerator(dependency.SessionTapDependency, func() interface{} { return sessionTap })
+
+	tests := []struct {
+		name     string
+		method   string
+		body     string
+		handler  gin.HandlerFunc
+		expected int
+	}{
+		{"get without a tap", http.MethodGet, "", GetSessionTap, http.StatusNotFound},
+		{"create with an invalid regex", http.MethodPost, `{"podRegex": "("}`, PostSessionTap, http.StatusBadRequest},
+		{"create", http.MethodPost, `{"targets": [{"namespace": "shop", "kind": "deployment", "name": "cart"}]}`, PostSessionTap, http.StatusCreated},
+		{"create again", http.MethodPost, `{}`, PostSessionTap, http.StatusConflict},
+		{"update", http.MethodPut, `{"podRegex": "^front-end"}`, PutSessionTap, http.StatusOK},
+		{"stop", http.MethodPost, "", StopSessionTap, http.StatusOK},
+		{"delete", http.MethodDelete, "", DeleteSessionTap, http.StatusNoContent},
+		{"delete again", http.MethodDelete, "", DeleteSessionTap, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		if w := serveSessionTapRequest(test.method, test.body, test.handler); w.Code != test.expected {
+			t.Errorf("unexpected result of %s - expected: %v, actual: %v %s", test.name, test.expected, w.Code, w.Body.String())
+		}
+
+		if test.name == "stop" && !sessionTap.mizuTap.Spec.Stopped {
+			t.Errorf("unexpected result - expected the tap to be stopped")
+		}
+	}
+}
+
+func TestSessionTapWithoutOperator(t *testing.T) {
+	config.Config = &shared.MizuAgentConfig{}
+	defer func() { config.Config = nil }()
+
+	if w := serveSessionTapRequest(http.MethodGet, "", GetSessionTap); w.Code != http.StatusBadRequest {
+		t.Errorf("unexpected result - expected: %v, actual: %v", http.StatusBadRequest, w.Code)
+	}
+}
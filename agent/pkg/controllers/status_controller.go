@@ -1,18 +1,43 @@
 package controllers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/op/go-logging"
 	"github.com/up9inc/mizu/agent/pkg/api"
+	"github.com/up9inc/mizu/agent/pkg/archive"
+	"github.com/up9inc/mizu/agent/pkg/config"
+	"github.com/up9inc/mizu/agent/pkg/elastic"
+	"github.com/up9inc/mizu/agent/pkg/exportqueue"
 	"github.com/up9inc/mizu/agent/pkg/holder"
+	"github.com/up9inc/mizu/agent/pkg/issues"
+	"github.com/up9inc/mizu/agent/pkg/kafka"
+	"github.com/up9inc/mizu/agent/pkg/lifecycle"
+	"github.com/up9inc/mizu/agent/pkg/markers"
+	"github.com/up9inc/mizu/agent/pkg/mirror"
 	"github.com/up9inc/mizu/agent/pkg/providers"
 	"github.com/up9inc/mizu/agent/pkg/providers/tappedPods"
 	"github.com/up9inc/mizu/agent/pkg/providers/tappers"
+	"github.com/up9inc/mizu/agent/pkg/querycache"
+	"github.com/up9inc/mizu/agent/pkg/querylimit"
+	"github.com/up9inc/mizu/agent/pkg/retention"
+	"github.com/up9inc/mizu/agent/pkg/sinks"
 	"github.com/up9inc/mizu/agent/pkg/up9"
 	"github.com/up9inc/mizu/agent/pkg/validation"
+	"github.com/up9inc/mizu/agent/pkg/watermark"
 	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/logger"
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	maxPacketDumpBytes           = 100 * 1024 * 1024
+	defaultPacketDumpDurationSec = 60
+	maxPacketDumpDurationSec     = 600
 )
 
 func HealthCheck(c *gin.Context) {
@@ -41,6 +66,19 @@ func PostTappedPods(c *gin.Context) {
 	api.BroadcastTappedPodsStatus()
 }
 
+// PostTapTargets sets the tapped pods of every node and sends them to the connected tappers
+func PostTapTargets(c *gin.Context) {
+	var nodeToTappedPodMap map[string][]corev1.Pod
+	if err := c.Bind(&nodeToTappedPodMap); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	logger.Log.Infof("[Status] POST request: tap targets of %d nodes", len(nodeToTappedPodMap))
+	tappedPods.SetTapTargets(nodeToTappedPodMap)
+	api.BroadcastTapTargets()
+}
+
 func PostTapperStatus(c *gin.Context) {
 	tapperStatus := &shared.TapperStatus{}
 	if err := c.Bind(tapperStatus); err != nil {
@@ -56,12 +94,65 @@ func PostTapperStatus(c *gin.Context) {
 	logger.Log.Infof("[Status] POST request, tapper status: %v", tapperStatus)
 	tappers.SetStatus(tapperStatus)
 	api.BroadcastTappedPodsStatus()
+
+	if tapperStatus.Status == string(corev1.PodFailed) {
+		lifecycle.GetInstance().Notify(shared.LifecycleEventTapperFailed, tapperStatus)
+	}
+}
+
+func PostTapperDebug(c *gin.Context) {
+	debugConfig := shared.TapperDebugConfig{}
+	if err := c.Bind(&debugConfig); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	if debugConfig.LogLevel != "" {
+		if _, err := logging.LogLevel(debugConfig.LogLevel); err != nil {
+			c.JSON(http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	if debugConfig.PacketDumpMaxBytes > maxPacketDumpBytes {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("packet dump is limited to %d bytes", maxPacketDumpBytes))
+		return
+	}
+	if debugConfig.PacketDumpDurationSec <= 0 {
+		debugConfig.PacketDumpDurationSec = defaultPacketDumpDurationSec
+	} else if debugConfig.PacketDumpDurationSec > maxPacketDumpDurationSec {
+		c.JSON(http.StatusBadRequest, fmt.Sprintf("packet dump is limited to %d seconds", maxPacketDumpDurationSec))
+		return
+	}
+
+	message, err := json.Marshal(shared.CreateWebSocketTapperDebugMessage(debugConfig))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, err)
+		return
+	}
+
+	nodeName := c.Param("nodeName")
+	if err := api.SendToTapper(nodeName, message); err != nil {
+		c.JSON(http.StatusNotFound, err.Error())
+		return
+	}
+
+	logger.Log.Infof("[Status] sent debug config to tapper of node %s: %+v", nodeName, debugConfig)
+	c.JSON(http.StatusOK, debugConfig)
 }
 
 func GetConnectedTappersCount(c *gin.Context) {
 	c.JSON(http.StatusOK, tappers.GetConnectedCount())
 }
 
+func GetClockSkew(c *gin.Context) {
+	c.JSON(http.StatusOK, tappers.GetClockSkews())
+}
+
+func GetTapperDrops(c *gin.Context) {
+	c.JSON(http.StatusOK, tappers.GetDrops())
+}
+
 func GetAuthStatus(c *gin.Context) {
 	authStatus, err := providers.GetAuthStatus()
 	if err != nil {
@@ -82,9 +173,115 @@ func AnalyzeInformation(c *gin.Context) {
 }
 
 func GetGeneralStats(c *gin.Context) {
+	if !config.GetFeatures().Stats {
+		featureDisabled(c, "stats")
+		return
+	}
+
 	c.JSON(http.StatusOK, providers.GetGeneralStats())
 }
 
+func GetExportQueuesStatus(c *gin.Context) {
+	exportQueuesStats := make(map[string]*exportqueue.Stats)
+	if elasticStats := elastic.GetInstance().GetExportQueueStats(); elasticStats != nil {
+		exportQueuesStats["elastic"] = elasticStats
+	}
+	if kafkaStats := kafka.GetInstance().GetExportQueueStats(); kafkaStats != nil {
+		exportQueuesStats["kafka"] = kafkaStats
+	}
+	for name, sinkStats := range sinks.GetInstance().GetExportQueuesStats() {
+		exportQueuesStats[name] = sinkStats
+	}
+
+	c.JSON(http.StatusOK, exportQueuesStats)
+}
+
+// GetSinksHealth validates every configured export destination from inside the cluster
+func GetSinksHealth(c *gin.Context) {
+	sinksHealth := make([]*shared.SinkHealth, 0)
+	if elasticHealth := elastic.GetInstance().CheckHealth(); elasticHealth != nil {
+		sinksHealth = append(sinksHealth, elasticHealth)
+	}
+	if kafkaHealth := kafka.GetInstance().CheckHealth(); kafkaHealth != nil {
+		sinksHealth = append(sinksHealth, kafkaHealth)
+	}
+	sinksHealth = append(sinksHealth, sinks.GetInstance().CheckHealth()...)
+	if archiveHealth := archive.GetInstance().CheckHealth(); archiveHealth != nil {
+		sinksHealth = append(sinksHealth, archiveHealth)
+	}
+
+	c.JSON(http.StatusOK, sinksHealth)
+}
+
+// GetSinksStatus returns the lag and the backlog of every sink of the fan-out
+func GetSinksStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, sinks.GetInstance().GetStatus())
+}
+
+// GetPressureStatus returns the memory and the disk pressure of the API server and the entries it shed
+func GetPressureStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, watermark.GetInstance().GetStatus())
+}
+
+// GetRetentionStatus returns the entries the database retains and the entries evicted from it
+func GetRetentionStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, retention.GetInstance().GetStatus())
+}
+
+func GetMirrorStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, mirror.GetInstance().GetStats())
+}
+
+func GetIssuesStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, issues.GetInstance().GetStats())
+}
+
+func GetLifecycleWebhooksStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, lifecycle.GetInstance().GetStats())
+}
+
+func GetArchiveStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, archive.GetInstance().GetStats())
+}
+
+func GetQueryCacheStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, querycache.GetInstance().GetStats())
+}
+
+func GetQueryLimitsStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, querylimit.GetInstance().GetStats())
+}
+
+func GetMarkers(c *gin.Context) {
+	from, err := strconv.ParseInt(c.DefaultQuery("from", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	to, err := strconv.ParseInt(c.DefaultQuery("to", "0"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, markers.GetInstance().GetMarkers(from, to))
+}
+
+func GetConnectionReuseStats(c *gin.Context) {
+	if !config.GetFeatures().Insights {
+		featureDisabled(c, "insights")
+		return
+	}
+
+	minRequests, err := strconv.Atoi(c.DefaultQuery("minRequests", "0"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, providers.GetConnectionReuseStats(minRequests))
+}
+
 func GetRecentTLSLinks(c *gin.Context) {
 	c.JSON(http.StatusOK, providers.GetAllRecentTLSAddresses())
 }
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/models"
+	"github.com/up9inc/mizu/agent/pkg/trends"
+	"github.com/up9inc/mizu/agent/pkg/validation"
+)
+
+func GetDailyTrends(c *gin.Context) {
+	dailyTrendsRequest := &models.DailyTrendsRequest{}
+	if err := c.BindQuery(dailyTrendsRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+	if validationError := validation.Validate(dailyTrendsRequest); validationError != nil {
+		c.JSON(http.StatusBadRequest, validationError)
+		return
+	}
+
+	c.JSON(http.StatusOK, trends.GetInstance().GetDaily(dailyTrendsRequest.Service, dailyTrendsRequest.Endpoint, dailyTrendsRequest.Days))
+}
+
+func GetWeekOverWeekTrends(c *gin.Context) {
+	weekOverWeekRequest := &models.WeekOverWeekRequest{}
+	if err := c.BindQuery(weekOverWeekRequest); err != nil {
+		c.JSON(http.StatusBadRequest, err)
+		return
+	}
+
+	end := time.Now()
+	if weekOverWeekRequest.End != "" {
+		var err error
+		if end, err = time.Parse(trends.DayFormat, weekOverWeekRequest.End); err != nil {
+			c.JSON(http.StatusBadRequest, fmt.Sprintf("invalid end %q, the end is a day like 2022-03-07", weekOverWeekRequest.End))
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, trends.GetInstance().GetWeekOverWeek(end))
+}
@@ -9,3 +9,8 @@ func RegisterGenerator(name DependencyContainerType, fn func() interface{}) {
 func GetInstance(name DependencyContainerType) interface{} {
 	return typeIntializerMap[name]()
 }
+
+func IsRegistered(name DependencyContainerType) bool {
+	_, ok := typeIntializerMap[name]
+	return ok
+}
@@ -2,22 +2,63 @@ package elastic
 
 import (
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
+	"net/url"
+	"path"
+	"regexp"
 	"sync"
 	"time"
 
 	"github.com/elastic/go-elasticsearch/v7"
+	"github.com/up9inc/mizu/agent/pkg/exportqueue"
 	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/cloudauth"
 	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/transform"
 	"github.com/up9inc/mizu/tap/api"
 )
 
+const (
+	exportQueueName     = "elastic"
+	healthCheckTimeout  = 10 * time.Second
+	healthCheckDocument = "mizu-health-check"
+	setupTimeout        = 30 * time.Second
+	bulkTimeout         = 30 * time.Second
+
+	// the first index behind the write alias, rollover increments the suffix
+	initialIndexSuffix = "-000001"
+	totalFieldsLimit   = 2000
+
+	awsSigningService = "es"
+)
+
+// the permissions of the role of an aws auth, setting up the index and indexing the entries
+var cloudAuthPermissions = []string{"es:ESHttpGet", "es:ESHttpHead", "es:ESHttpPut", "es:ESHttpPost", "es:ESHttpDelete"}
+
+// the host of an Amazon OpenSearch domain has its region, like search-mizu-abc.us-east-1.es.amazonaws.com
+var awsDomainHostPattern = regexp.MustCompile(`\.([a-z0-9-]+)\.es\.amazonaws\.com$`)
+
 type client struct {
+	name          string
 	es            *elasticsearch.Client
+	url           string
 	index         string
 	insertedCount int
+	queue         *exportqueue.Queue
+	transform     *transform.Expression
+	config        shared.ElasticConfig
+	cluster       string
+	identity      string
+
+	setupMutex sync.Mutex
+	isSetUp    bool
+	openSearch bool
 }
 
 var instance *client
@@ -30,14 +71,42 @@ func GetInstance() *client {
 	return instance
 }
 
-func (client *client) Configure(config shared.ElasticConfig) {
-	if config.Url == "" || config.User == "" || config.Password == "" {
+// New creates a client of a sink of the fan-out, its export queue is named after the sink, the client is left
+// unconfigured when the configuration is invalid
+func New(name string, config shared.ElasticConfig, cluster string, maxExportQueueDiskSizeBytes int64) *client {
+	client := newClient()
+	client.configure(name, config, cluster, maxExportQueueDiskSizeBytes)
+	return client
+}
+
+func (client *client) Configure(config shared.ElasticConfig, cluster string, maxExportQueueDiskSizeBytes int64) {
+	client.configure(exportQueueName, config, cluster, maxExportQueueDiskSizeBytes)
+}
+
+func (client *client) configure(name string, config shared.ElasticConfig, cluster string, maxExportQueueDiskSizeBytes int64) {
+	if client.queue != nil {
+		client.queue.Stop()
+		client.queue = nil
+	}
+
+	if config.Url == "" || (config.Auth == "" && (config.User == "" || config.Password == "")) {
 		if client.es != nil {
 			client.es = nil
 		}
 		logger.Log.Infof("No elastic configuration was supplied, elastic exporter disabled")
 		return
 	}
+
+	var entryTransform *transform.Expression
+	if config.Transform != "" {
+		var err error
+		if entryTransform, err = transform.Compile(config.Transform); err != nil {
+			logger.Log.Errorf("Elastic exporter disabled, %v", err)
+			client.es = nil
+			return
+		}
+	}
+
 	transport := http.DefaultTransport
 	tlsClientConfig := &tls.Config{InsecureSkipVerify: true}
 	transport.(*http.Transport).TLSClientConfig = tlsClientConfig
@@ -48,22 +117,56 @@ func (client *client) Configure(config shared.ElasticConfig) {
 		Transport: transport,
 	}
 
+	var identity string
+	if config.Auth == cloudauth.AuthAws {
+		credentials, err := cloudauth.NewAwsWebIdentityProvider()
+		if err != nil {
+			logger.Log.Errorf("Elastic exporter disabled, %v", err)
+			client.es = nil
+			return
+		}
+
+		// Amazon OpenSearch authenticates the signature of the role in place of the user and the password
+		cfg.Username, cfg.Password = "", ""
+		cfg.Transport = cloudauth.NewSigningTransport(transport, credentials, getAwsRegion(config.Url), awsSigningService)
+		identity = credentials.Identity()
+	}
+
 	es, err := elasticsearch.NewClient(cfg)
 	if err != nil {
 		logger.Log.Errorf("Failed to initialize elastic client %v", err)
+		return
 	}
 
-	// Have the client instance return a response
-	res, err := es.Info()
+	spillPath := path.Join(shared.DataDirPath, fmt.Sprintf("%s_export_queue", name))
+	queue, err := exportqueue.NewBatched(name, spillPath, exportqueue.DefaultMaxMemoryItems, maxExportQueueDiskSizeBytes, config.BulkSize, client.deliver)
 	if err != nil {
-		logger.Log.Errorf("Elastic client.Info() ERROR: %v", err)
-	} else {
-		client.es = es
-		client.index = "mizu_traffic_http_" + time.Now().Format("2006_01_02_15_04")
-		client.insertedCount = 0
-		logger.Log.Infof("Elastic client configured, index: %s, cluster info: %v", client.index, res)
+		logger.Log.Errorf("Failed to create elastic export queue %v", err)
+		return
 	}
-	defer res.Body.Close()
+
+	client.setupMutex.Lock()
+	client.isSetUp = false
+	client.setupMutex.Unlock()
+
+	client.name = name
+	client.es = es
+	client.url = config.Url
+	client.index = config.Index
+	client.insertedCount = 0
+	client.queue = queue
+	client.transform = entryTransform
+	client.config = config
+	client.cluster = cluster
+	client.identity = identity
+
+	// an unavailable elastic isn't fatal since entries are queued until it recovers, the setup is retried before
+	// they're delivered
+	if err := client.setUp(); err != nil {
+		logger.Log.Errorf("Failed setting up the elastic index %s, retrying once entries are delivered: %v", client.index, err)
+		return
+	}
+	logger.Log.Infof("Elastic client configured, index: %s, opensearch: %v", client.index, client.openSearch)
 }
 
 func newClient() *client {
@@ -74,17 +177,20 @@ func newClient() *client {
 }
 
 type httpEntry struct {
+	EntryId     string                 `json:"entryId,omitempty"`
+	Timestamp   string                 `json:"@timestamp"`
+	Cluster     string                 `json:"cluster,omitempty"`
 	Source      *api.TCP               `json:"src"`
 	Destination *api.TCP               `json:"dst"`
 	Outgoing    bool                   `json:"outgoing"`
-	CreatedAt   time.Time              `json:"createdAt"`
+	CreatedAt   string                 `json:"createdAt"`
 	Request     map[string]interface{} `json:"request"`
 	Response    map[string]interface{} `json:"response"`
 	ElapsedTime int64                  `json:"elapsedTime"`
 }
 
 func (client *client) PushEntry(entry *api.Entry) {
-	if client.es == nil {
+	if client.es == nil || client.queue == nil {
 		return
 	}
 
@@ -93,10 +199,13 @@ func (client *client) PushEntry(entry *api.Entry) {
 	}
 
 	entryToPush := httpEntry{
+		EntryId:     entry.EntryId,
+		Timestamp:   entry.StartTime.UTC().Format(time.RFC3339Nano),
+		Cluster:     client.cluster,
 		Source:      entry.Source,
 		Destination: entry.Destination,
 		Outgoing:    entry.Outgoing,
-		CreatedAt:   entry.StartTime,
+		CreatedAt:   entry.StartTime.UTC().Format(time.RFC3339),
 		Request:     entry.Request,
 		Response:    entry.Response,
 		ElapsedTime: entry.ElapsedTime,
@@ -107,10 +216,443 @@ func (client *client) PushEntry(entry *api.Entry) {
 		logger.Log.Errorf("json.Marshal ERROR: %v", err)
 		return
 	}
-	var buffer bytes.Buffer
-	buffer.WriteString(string(entryJson))
-	res, _ := client.es.Index(client.index, &buffer)
-	if res.StatusCode == 201 {
-		client.insertedCount += 1
+
+	if client.transform != nil {
+		if entryJson, err = client.transform.ApplyJson(entryJson); err != nil {
+			logger.Log.Debugf("Failed transforming entry with %s, skipping it: %v", client.transform, err)
+			return
+		}
+	}
+
+	// the queue keeps the entry while elastic is unavailable instead of blocking ingestion
+	client.queue.Push(entryJson)
+}
+
+func (client *client) GetExportQueueStats() *exportqueue.Stats {
+	if client.queue == nil {
+		return nil
+	}
+
+	stats := client.queue.GetStats()
+	return &stats
+}
+
+// getAwsRegion returns the region of the domain of the url, or the region of the pod for a domain behind a custom
+// endpoint
+func getAwsRegion(domainUrl string) string {
+	if parsedUrl, err := url.Parse(domainUrl); err == nil {
+		if match := awsDomainHostPattern.FindStringSubmatch(parsedUrl.Hostname()); match != nil {
+			return match[1]
+		}
+	}
+
+	return cloudauth.AwsRegion()
+}
+
+// CheckHealth validates that elastic is reachable, accepts the credentials and allows writing to the index, the
+// write is checked with a probe document that is deleted right after, nil is returned when elastic isn't configured
+func (client *client) CheckHealth() *shared.SinkHealth {
+	if client.es == nil {
+		return nil
+	}
+
+	health := &shared.SinkHealth{Sink: client.name, Destination: client.url}
+	if client.config.Auth != "" {
+		health.Identity = client.identity
+		health.Permissions = cloudAuthPermissions
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	status, _, err := client.request(ctx, http.MethodGet, "/", nil, "")
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	health.Connectivity = true
+
+	if status != http.StatusOK {
+		health.Error = fmt.Sprintf("unexpected elastic response status %d", status)
+		return health
+	}
+	health.Authentication = true
+
+	// writing before the write alias exists would create a plain index in its place
+	if err := client.setUp(); err != nil {
+		health.Error = fmt.Sprintf("failed setting up index %s, %v", client.index, err)
+		return health
+	}
+
+	documentPath := fmt.Sprintf("/%s/_doc/%s", client.index, healthCheckDocument)
+	status, body, err := client.request(ctx, http.MethodPut, documentPath, []byte(`{"mizuHealthCheck":true}`), "application/json")
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	if !isSuccessStatus(status) {
+		health.Error = fmt.Sprintf("failed writing to index %s, elastic response status %d: %s", client.index, status, body)
+		return health
+	}
+	health.Write = true
+
+	if status, _, err := client.request(ctx, http.MethodDelete, documentPath, nil, ""); err != nil || !isSuccessStatus(status) {
+		logger.Log.Debugf("Failed deleting the elastic health check document, status: %d, err: %v", status, err)
+	}
+
+	return health
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+func (client *client) deliver(entriesJson [][]byte) error {
+	if err := client.setUp(); err != nil {
+		return err
+	}
+
+	var body bytes.Buffer
+	for _, entryJson := range entriesJson {
+		body.WriteString(`{"index":{}}`)
+		body.WriteByte('\n')
+		body.Write(entryJson)
+		body.WriteByte('\n')
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), bulkTimeout)
+	defer cancel()
+
+	status, responseBody, err := client.request(ctx, http.MethodPost, fmt.Sprintf("/%s/_bulk", client.index), body.Bytes(), "application/x-ndjson")
+	if err != nil {
+		return err
+	}
+	if !isSuccessStatus(status) {
+		return fmt.Errorf("unexpected elastic bulk response status %d: %s", status, responseBody)
+	}
+
+	var response bulkResponse
+	if err := json.Unmarshal(responseBody, &response); err != nil {
+		return fmt.Errorf("failed parsing the elastic bulk response: %w", err)
+	}
+
+	indexed := len(entriesJson)
+	if response.Errors {
+		retryable := 0
+		var firstError json.RawMessage
+		for _, item := range response.Items {
+			for _, result := range item {
+				if isSuccessStatus(result.Status) {
+					continue
+				}
+
+				indexed--
+				if result.Status == http.StatusTooManyRequests || result.Status >= http.StatusInternalServerError {
+					retryable++
+				}
+				if firstError == nil {
+					firstError = result.Error
+				}
+			}
+		}
+
+		// the whole bulk is retried only when nothing was indexed, retrying a partial bulk would duplicate entries
+		if indexed == 0 && retryable > 0 {
+			return fmt.Errorf("elastic rejected the bulk of %d entries: %s", len(entriesJson), firstError)
+		}
+		logger.Log.Warningf("Elastic rejected %d of %d entries of a bulk: %s", len(entriesJson)-indexed, len(entriesJson), firstError)
+	}
+
+	client.insertedCount += indexed
+	return nil
+}
+
+// setUp creates the lifecycle policy, the index template and the first index behind the write alias, it's retried
+// until it succeeds once
+func (client *client) setUp() error {
+	client.setupMutex.Lock()
+	defer client.setupMutex.Unlock()
+
+	if client.isSetUp {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), setupTimeout)
+	defer cancel()
+
+	openSearch, err := client.isOpenSearch(ctx)
+	if err != nil {
+		return err
+	}
+	client.openSearch = openSearch
+
+	if err := client.putLifecyclePolicy(ctx); err != nil {
+		return err
+	}
+	if err := client.putIndexTemplate(ctx); err != nil {
+		return err
+	}
+	if err := client.createWriteIndex(ctx); err != nil {
+		return err
+	}
+
+	client.isSetUp = true
+	return nil
+}
+
+func (client *client) isOpenSearch(ctx context.Context) (bool, error) {
+	status, body, err := client.request(ctx, http.MethodGet, "/", nil, "")
+	if err != nil {
+		return false, err
+	}
+	if status != http.StatusOK {
+		return false, fmt.Errorf("unexpected elastic response status %d: %s", status, body)
+	}
+
+	var info struct {
+		Version struct {
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return false, fmt.Errorf("failed parsing the elastic info: %w", err)
+	}
+
+	return info.Version.Distribution == "opensearch", nil
+}
+
+func (client *client) policyName() string {
+	return client.index + "-policy"
+}
+
+// putLifecyclePolicy puts an ILM policy in elasticsearch and an ISM policy in opensearch, an existing ISM policy is
+// kept since it can only be updated by its sequence number
+func (client *client) putLifecyclePolicy(ctx context.Context) error {
+	var policyPath string
+	var policy map[string]interface{}
+
+	if client.openSearch {
+		policyPath = fmt.Sprintf("/_plugins/_ism/policies/%s", client.policyName())
+		policy = newIsmPolicy(client.config)
+	} else {
+		policyPath = fmt.Sprintf("/_ilm/policy/%s", client.policyName())
+		policy = newIlmPolicy(client.config)
+	}
+
+	body, err := json.Marshal(policy)
+	if err != nil {
+		return err
+	}
+
+	status, responseBody, err := client.request(ctx, http.MethodPut, policyPath, body, "application/json")
+	if err != nil {
+		return err
+	}
+	if client.openSearch && status == http.StatusConflict {
+		logger.Log.Debugf("ISM policy %s already exists, keeping it", client.policyName())
+		return nil
+	}
+	if !isSuccessStatus(status) {
+		return fmt.Errorf("failed putting lifecycle policy %s, status %d: %s", client.policyName(), status, responseBody)
+	}
+
+	return nil
+}
+
+func (client *client) putIndexTemplate(ctx context.Context) error {
+	body, err := json.Marshal(newIndexTemplate(client.index, client.policyName(), client.openSearch))
+	if err != nil {
+		return err
+	}
+
+	status, responseBody, err := client.request(ctx, http.MethodPut, fmt.Sprintf("/_index_template/%s", client.index), body, "application/json")
+	if err != nil {
+		return err
+	}
+	if !isSuccessStatus(status) {
+		return fmt.Errorf("failed putting index template %s, status %d: %s", client.index, status, responseBody)
+	}
+
+	return nil
+}
+
+// createWriteIndex creates the first index with the write alias, unless the alias already exists from a previous run
+func (client *client) createWriteIndex(ctx context.Context) error {
+	status, _, err := client.request(ctx, http.MethodHead, fmt.Sprintf("/_alias/%s", client.index), nil, "")
+	if err != nil {
+		return err
+	}
+	if status == http.StatusOK {
+		return nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"aliases": map[string]interface{}{
+			client.index: map[string]interface{}{"is_write_index": true},
+		},
+	})
+	if err != nil {
+		return err
 	}
+
+	status, responseBody, err := client.request(ctx, http.MethodPut, fmt.Sprintf("/%s%s", client.index, initialIndexSuffix), body, "application/json")
+	if err != nil {
+		return err
+	}
+	if !isSuccessStatus(status) {
+		return fmt.Errorf("failed creating index %s%s, status %d: %s", client.index, initialIndexSuffix, status, responseBody)
+	}
+
+	return nil
+}
+
+func newIlmPolicy(config shared.ElasticConfig) map[string]interface{} {
+	phases := map[string]interface{}{
+		"hot": map[string]interface{}{
+			"actions": map[string]interface{}{
+				"rollover": map[string]interface{}{
+					"max_size": config.RolloverMaxSize,
+					"max_age":  config.RolloverMaxAge,
+				},
+			},
+		},
+	}
+
+	// the min age of the phases after the hot phase counts from the rollover
+	if config.DeleteAfter != "" {
+		phases["delete"] = map[string]interface{}{
+			"min_age": config.DeleteAfter,
+			"actions": map[string]interface{}{
+				"delete": map[string]interface{}{},
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"policy": map[string]interface{}{
+			"phases": phases,
+		},
+	}
+}
+
+func newIsmPolicy(config shared.ElasticConfig) map[string]interface{} {
+	hotState := map[string]interface{}{
+		"name": "hot",
+		"actions": []interface{}{
+			map[string]interface{}{
+				"rollover": map[string]interface{}{
+					"min_size":      config.RolloverMaxSize,
+					"min_index_age": config.RolloverMaxAge,
+				},
+			},
+		},
+		"transitions": []interface{}{},
+	}
+	states := []interface{}{hotState}
+
+	if config.DeleteAfter != "" {
+		hotState["transitions"] = []interface{}{
+			map[string]interface{}{
+				"state_name": "delete",
+				"conditions": map[string]interface{}{"min_rollover_age": config.DeleteAfter},
+			},
+		}
+		states = append(states, map[string]interface{}{
+			"name":        "delete",
+			"actions":     []interface{}{map[string]interface{}{"delete": map[string]interface{}{}}},
+			"transitions": []interface{}{},
+		})
+	}
+
+	return map[string]interface{}{
+		"policy": map[string]interface{}{
+			"description":   "Rolls over the indices of the mizu entries",
+			"default_state": "hot",
+			"states":        states,
+			// attaches the policy to the indices created by the rollover
+			"ism_template": []interface{}{
+				map[string]interface{}{
+					"index_patterns": []string{config.Index + "-*"},
+					"priority":       100,
+				},
+			},
+		},
+	}
+}
+
+func newIndexTemplate(index string, policyName string, openSearch bool) map[string]interface{} {
+	settings := map[string]interface{}{
+		"index.mapping.total_fields.limit": totalFieldsLimit,
+	}
+	if openSearch {
+		settings["plugins.index_state_management.rollover_alias"] = index
+	} else {
+		settings["index.lifecycle.name"] = policyName
+		settings["index.lifecycle.rollover_alias"] = index
+	}
+
+	tcpMapping := map[string]interface{}{
+		"properties": map[string]interface{}{
+			"ip":   map[string]interface{}{"type": "keyword"},
+			"port": map[string]interface{}{"type": "keyword"},
+			"name": map[string]interface{}{"type": "keyword"},
+		},
+	}
+
+	return map[string]interface{}{
+		"index_patterns": []string{index + "-*"},
+		"priority":       100,
+		"template": map[string]interface{}{
+			"settings": settings,
+			"mappings": map[string]interface{}{
+				"properties": map[string]interface{}{
+					"@timestamp":  map[string]interface{}{"type": "date"},
+					"entryId":     map[string]interface{}{"type": "keyword"},
+					"cluster":     map[string]interface{}{"type": "keyword"},
+					"src":         tcpMapping,
+					"dst":         tcpMapping,
+					"outgoing":    map[string]interface{}{"type": "boolean"},
+					"elapsedTime": map[string]interface{}{"type": "long"},
+				},
+			},
+		},
+	}
+}
+
+// request calls the cluster through the transport of the client, which skips the product check of the elasticsearch
+// client so opensearch is served as well
+func (client *client) request(ctx context.Context, method string, path string, body []byte, contentType string) (int, []byte, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		bodyReader = bytes.NewReader(body)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, path, bodyReader)
+	if err != nil {
+		return 0, nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	res, err := client.es.Transport.Perform(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return res.StatusCode, responseBody, nil
+}
+
+func isSuccessStatus(status int) bool {
+	return status >= 200 && status <= 299
 }
@@ -0,0 +1,133 @@
+package entryid
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	ulidLength      = 26
+	randomnessBytes = 10
+	crockfordBase32 = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+)
+
+// Generator generates ULIDs, ids generated within the same millisecond are monotonically increasing so ids
+// are sortable by capture time even under high traffic
+type Generator struct {
+	mutex          sync.Mutex
+	lastTimestamp  uint64
+	lastRandomness [randomnessBytes]byte
+}
+
+func NewGenerator() *Generator {
+	return &Generator{}
+}
+
+func (g *Generator) New(t time.Time) (string, error) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	timestamp := uint64(t.UnixNano() / int64(time.Millisecond))
+	if timestamp <= g.lastTimestamp && g.lastTimestamp != 0 {
+		// keep ids increasing when the clock didn't move forward
+		timestamp = g.lastTimestamp
+		if !incrementRandomness(&g.lastRandomness) {
+			timestamp++
+			if _, err := rand.Read(g.lastRandomness[:]); err != nil {
+				return "", err
+			}
+		}
+	} else if _, err := rand.Read(g.lastRandomness[:]); err != nil {
+		return "", err
+	}
+	g.lastTimestamp = timestamp
+
+	return encode(timestamp, g.lastRandomness), nil
+}
+
+// IsValid reports whether id is a well formed ULID
+func IsValid(id string) bool {
+	if len(id) != ulidLength {
+		return false
+	}
+
+	// the first character only holds 3 bits of the 48 bit timestamp
+	if id[0] > '7' {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		if decodeChar(id[i]) < 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Time returns the time component of a ULID
+func Time(id string) (time.Time, error) {
+	if !IsValid(id) {
+		return time.Time{}, fmt.Errorf("invalid ulid %s", id)
+	}
+
+	var timestamp uint64
+	for i := 0; i < 10; i++ {
+		timestamp = timestamp<<5 | uint64(decodeChar(id[i]))
+	}
+
+	return time.Unix(0, int64(timestamp)*int64(time.Millisecond)), nil
+}
+
+func incrementRandomness(randomness *[randomnessBytes]byte) bool {
+	for i := randomnessBytes - 1; i >= 0; i-- {
+		randomness[i]++
+		if randomness[i] != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+func encode(timestamp uint64, randomness [randomnessBytes]byte) string {
+	id := make([]byte, ulidLength)
+
+	// 48 bit timestamp encoded in 10 characters
+	for i := 9; i >= 0; i-- {
+		id[i] = crockfordBase32[timestamp&0x1f]
+		timestamp >>= 5
+	}
+
+	// 80 bits of randomness encoded in 16 characters
+	var bits uint64
+	bitsCount := 0
+	index := 10
+	for _, b := range randomness {
+		bits = bits<<8 | uint64(b)
+		bitsCount += 8
+		for bitsCount >= 5 {
+			bitsCount -= 5
+			id[index] = crockfordBase32[(bits>>uint(bitsCount))&0x1f]
+			index++
+		}
+	}
+
+	return string(id)
+}
+
+func decodeChar(c byte) int {
+	if c >= 'a' && c <= 'z' {
+		c -= 'a' - 'A'
+	}
+
+	for i := 0; i < len(crockfordBase32); i++ {
+		if crockfordBase32[i] == c {
+			return i
+		}
+	}
+
+	return -1
+}
@@ -0,0 +1,66 @@
+package entryid
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewIsValidAndSortable(t *testing.T) {
+	generator := NewGenerator()
+	now := time.Now()
+
+	var previous string
+	for i := 0; i < 1000; i++ {
+		// the same timestamp on purpose, ids must still increase
+		id, err := generator.New(now)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !IsValid(id) {
+			t.Errorf("unexpected result - %s is not a valid ulid", id)
+		}
+
+		if id <= previous {
+			t.Errorf("unexpected result - %s is not greater than %s", id, previous)
+		}
+		previous = id
+	}
+}
+
+func TestTime(t *testing.T) {
+	generator := NewGenerator()
+	expected := time.Date(2022, 3, 1, 12, 30, 0, 123*int(time.Millisecond), time.UTC)
+
+	id, err := generator.New(expected)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	actual, err := Time(id)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !actual.Equal(expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestIsValid(t *testing.T) {
+	tests := map[string]bool{
+		"01FX3Q7KZ3M4E0D4G5T9V8W2RA":  true,
+		"01fx3q7kz3m4e0d4g5t9v8w2ra":  true,
+		"81FX3Q7KZ3M4E0D4G5T9V8W2RA":  false,
+		"01FX3Q7KZ3M4E0D4G5T9V8W2RU":  false,
+		"01FX3Q7KZ3M4E0D4G5T9V8W2R":   false,
+		"01FX3Q7KZ3M4E0D4G5T9V8W2RAA": false,
+		"123":                         false,
+	}
+
+	for id, expected := range tests {
+		if actual := IsValid(id); actual != expected {
+			t.Errorf("unexpected result for %s - expected: %v, actual: %v", id, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Package entryindex maps the ulids of the stored entries to their database index, so an entry that's referred to by
+// its ulid is found without scanning the database for it
+package entryindex
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	basenine "github.com/up9inc/basenine/client/go"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	maxIndexedEntries = 200000
+	reconnectInterval = 5 * time.Second
+)
+
+// Index keeps the ulids of the last maxIndexedEntries stored entries, ulids are sortable by capture time so the
+// ulids it doesn't have are told apart from the ones it forgot or didn't stream yet
+type Index struct {
+	mutex    sync.Mutex
+	indexes  map[string]int
+	entryIds []string // a ring of the indexed ulids in the order they were stored
+	next     int
+	// the newest ulid that was indexed and the newest one that was forgotten to make room for another
+	newestEntryId        string
+	newestEvictedEntryId string
+}
+
+type indexedEntry struct {
+	Id      int    `json:"id"`
+	EntryId string `json:"entryId"`
+}
+
+var instance *Index
+var once sync.Once
+
+func GetInstance() *Index {
+	once.Do(func() {
+		instance = newIndex(maxIndexedEntries)
+	})
+	return instance
+}
+
+func newIndex(maxEntries int) *Index {
+	return &Index{
+		indexes:  make(map[string]int),
+		entryIds: make([]string, maxEntries),
+	}
+}
+
+// Start streams the entries of the database into the index, the index is rebuilt whenever the stream reconnects
+// since the database may have been recreated with other indexes
+func (index *Index) Start() {
+	go func() {
+		for {
+			err := index.stream()
+			logger.Log.Warningf("Error streaming the entries of the entry id index, retrying in %v: %v", reconnectInterval, err)
+
+			time.Sleep(reconnectInterval)
+		}
+	}()
+}
+
+func (index *Index) stream() error {
+	connection, err := basenine.NewConnection(shared.BasenineHost, shared.BaseninePort)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	index.reset()
+
+	data := make(chan []byte)
+	meta := make(chan []byte)
+	go func() {
+		// the metadata of the stream isn't needed
+		for bytes := range meta {
+			if string(bytes) == basenine.CloseChannel {
+				return
+			}
+		}
+	}()
+	defer func() {
+		meta <- []byte(basenine.CloseChannel)
+	}()
+
+	connection.Query("", data, meta)
+
+	for bytes := range data {
+		if string(bytes) == basenine.CloseChannel {
+			break
+		}
+
+		var entry indexedEntry
+		if err := json.Unmarshal(bytes, &entry); err != nil {
+			logger.Log.Debugf("Error parsing an entry of the entry id index: %v", err)
+			continue
+		}
+		if entry.EntryId != "" {
+			index.Add(entry.EntryId, entry.Id)
+		}
+	}
+
+	return errors.New("the database closed the stream")
+}
+
+func (index *Index) reset() {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	index.indexes = make(map[string]int)
+	index.entryIds = make([]string, len(index.entryIds))
+	index.next = 0
+	index.newestEntryId = ""
+	index.newestEvictedEntryId = ""
+}
+
+// Add indexes a stored entry, the oldest indexed entry is forgotten once the index is full
+func (index *Index) Add(entryId string, entryIndex int) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if evicted := index.entryIds[index.next]; evicted != "" {
+		delete(index.indexes, evicted)
+		if evicted > index.newestEvictedEntryId {
+			index.newestEvictedEntryId = evicted
+		}
+	}
+
+	index.entryIds[index.next] = entryId
+	index.next = (index.next + 1) % len(index.entryIds)
+	index.indexes[entryId] = entryIndex
+	if entryId > index.newestEntryId {
+		index.newestEntryId = entryId
+	}
+}
+
+// Get returns the database index of the entry with the ulid
+func (index *Index) Get(entryId string) (int, bool) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	entryIndex, ok := index.indexes[entryId]
+	return entryIndex, ok
+}
+
+// ScanStart returns the database index a backward scan for an entry that isn't indexed starts at, -1 starts at the
+// end of the database. No scan is needed when the entry would have been indexed, since then it doesn't exist
+func (index *Index) ScanStart(entryId string) (int, bool) {
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	if entryId > index.newestEntryId {
+		// stored after the last streamed entry, it's among the last entries of the database
+		return -1, true
+	}
+
+	if entryId <= index.newestEvictedEntryId {
+		// forgotten, it's older than the oldest indexed entry which is the next one to be evicted
+		return index.indexes[index.entryIds[index.next]], true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,46 @@
+package entryindex
+
+import (
+	"testing"
+)
+
+func TestIndexGet(t *testing.T) {
+	index := newIndex(2)
+	index.Add("01G0000000000000000000000A", 1)
+	index.Add("01G0000000000000000000000B", 2)
+	index.Add("01G0000000000000000000000C", 3)
+
+	if _, ok := index.Get("01G0000000000000000000000A"); ok {
+		t.Errorf("unexpected result - expected the oldest entry to be forgotten")
+	}
+	if actual, ok := index.Get("01G0000000000000000000000C"); !ok || actual != 3 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 3, actual)
+	}
+}
+
+func TestIndexScanStart(t *testing.T) {
+	index := newIndex(2)
+	index.Add("01G0000000000000000000000A", 1)
+	index.Add("01G0000000000000000000000C", 2)
+	index.Add("01G0000000000000000000000E", 3)
+
+	tests := []struct {
+		Name       string
+		EntryId    string
+		LeftOff    int
+		ShouldScan bool
+	}{
+		{Name: "forgotten", EntryId: "01G0000000000000000000000A", LeftOff: 2, ShouldScan: true},
+		{Name: "missing", EntryId: "01G0000000000000000000000D", LeftOff: 0, ShouldScan: false},
+		{Name: "not streamed yet", EntryId: "01G0000000000000000000000F", LeftOff: -1, ShouldScan: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			leftOff, shouldScan := index.ScanStart(test.EntryId)
+			if leftOff != test.LeftOff || shouldScan != test.ShouldScan {
+				t.Errorf("unexpected result - expected: %v %v, actual: %v %v", test.LeftOff, test.ShouldScan, leftOff, shouldScan)
+			}
+		})
+	}
+}
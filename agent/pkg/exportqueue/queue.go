@@ -0,0 +1,382 @@
+package exportqueue
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	DefaultMaxMemoryItems = 1000
+
+	minRetryInterval = 1 * time.Second
+	maxRetryInterval = 30 * time.Second
+)
+
+// DeliverFunc sends a single serialized item to the sink, a non nil error means the sink is unavailable
+// and the item will be retried
+type DeliverFunc func(item []byte) error
+
+// DeliverBatchFunc sends up to the batch size of items to the sink at once, a non nil error means the sink is
+// unavailable and the whole batch will be retried
+type DeliverBatchFunc func(items [][]byte) error
+
+type Stats struct {
+	MemoryItems     int   `json:"memoryItems"`
+	SpilledItems    int   `json:"spilledItems"`
+	SpilledBytes    int64 `json:"spilledBytes"`
+	DeliveredItems  int   `json:"deliveredItems"`
+	DroppedItems    int   `json:"droppedItems"`
+	SinkUnavailable bool  `json:"sinkUnavailable"`
+}
+
+// Queue buffers items for an export sink, items are kept in memory up to maxMemoryItems and spilled to a file
+// on disk (bounded by maxSpillBytes) while the sink is unavailable. Push never blocks on the sink.
+type Queue struct {
+	name           string
+	deliver        DeliverBatchFunc
+	batchSize      int
+	maxMemoryItems int
+	maxSpillBytes  int64
+
+	mutex       sync.Mutex
+	cond        *sync.Cond
+	memory      [][]byte
+	spillPath   string
+	spillWriter *os.File
+	spillFile   *os.File
+	spillRead   *bufio.Reader
+	stats       Stats
+	stopped     bool
+	stopSignal  chan struct{}
+	done        chan struct{}
+}
+
+func New(name string, spillPath string, maxMemoryItems int, maxSpillBytes int64, deliver DeliverFunc) (*Queue, error) {
+	return NewBatched(name, spillPath, maxMemoryItems, maxSpillBytes, 1, func(items [][]byte) error {
+		return deliver(items[0])
+	})
+}
+
+// NewBatched creates a queue that delivers the items that are already waiting together, up to batchSize at once,
+// an item is never held back to fill a batch
+func NewBatched(name string, spillPath string, maxMemoryItems int, maxSpillBytes int64, batchSize int, deliver DeliverBatchFunc) (*Queue, error) {
+	if maxMemoryItems <= 0 {
+		maxMemoryItems = DefaultMaxMemoryItems
+	}
+	if batchSize <= 0 {
+		batchSize = 1
+	}
+
+	queue := &Queue{
+		name:           name,
+		deliver:        deliver,
+		batchSize:      batchSize,
+		maxMemoryItems: maxMemoryItems,
+		maxSpillBytes:  maxSpillBytes,
+		spillPath:      spillPath,
+		stopSignal:     make(chan struct{}),
+		done:           make(chan struct{}),
+	}
+	queue.cond = sync.NewCond(&queue.mutex)
+
+	if err := queue.openSpillFile(); err != nil {
+		return nil, err
+	}
+
+	go queue.run()
+
+	return queue, nil
+}
+
+// Push adds an item to the queue, when both memory and disk are full the item is dropped
+func (q *Queue) Push(item []byte) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.stopped {
+		return
+	}
+
+	// once items were spilled new items must go to disk as well, so delivery order is kept
+	if q.stats.SpilledItems == 0 && len(q.memory) < q.maxMemoryItems {
+		q.memory = append(q.memory, item)
+		q.cond.Signal()
+		return
+	}
+
+	if err := q.spill(item); err != nil {
+		q.stats.DroppedItems++
+		if q.stats.DroppedItems == 1 || q.stats.DroppedItems%1000 == 0 {
+			logger.Log.Warningf("Export queue %s is full, dropped %d items so far: %v", q.name, q.stats.DroppedItems, err)
+		}
+		return
+	}
+
+	q.cond.Signal()
+}
+
+func (q *Queue) GetStats() Stats {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	stats := q.stats
+	stats.MemoryItems = len(q.memory)
+	return stats
+}
+
+// Stop stops delivery and returns once the pending items were spilled, items that were spilled to disk remain
+// there and are delivered by the next queue created with the same spill path
+func (q *Queue) Stop() {
+	q.mutex.Lock()
+	if !q.stopped {
+		q.stopped = true
+		close(q.stopSignal)
+		q.cond.Broadcast()
+	}
+	q.mutex.Unlock()
+
+	<-q.done
+}
+
+func (q *Queue) run() {
+	defer close(q.done)
+	defer q.close()
+
+	for {
+		batch, ok := q.next()
+		if !ok {
+			return
+		}
+
+		if !q.deliverWithRetry(batch) {
+			return
+		}
+	}
+}
+
+func (q *Queue) next() ([][]byte, bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for {
+		if q.stopped {
+			return nil, false
+		}
+
+		if item, ok := q.take(); ok {
+			batch := [][]byte{item}
+			for len(batch) < q.batchSize {
+				if item, ok = q.take(); !ok {
+					break
+				}
+				batch = append(batch, item)
+			}
+			return batch, true
+		}
+
+		q.cond.Wait()
+	}
+}
+
+// take must be called while holding the mutex
+func (q *Queue) take() ([]byte, bool) {
+	if len(q.memory) > 0 {
+		item := q.memory[0]
+		q.memory[0] = nil
+		q.memory = q.memory[1:]
+		return item, true
+	}
+
+	for q.stats.SpilledItems > 0 {
+		item, err := q.unspill()
+		if err == nil {
+			return item, true
+		}
+		logger.Log.Errorf("Failed reading spilled items of export queue %s, discarding them: %v", q.name, err)
+		q.stats.DroppedItems += q.stats.SpilledItems
+		if err := q.resetSpillFile(); err != nil {
+			logger.Log.Errorf("Failed resetting spill file of export queue %s: %v", q.name, err)
+		}
+	}
+
+	return nil, false
+}
+
+func (q *Queue) deliverWithRetry(batch [][]byte) bool {
+	retryInterval := minRetryInterval
+
+	for {
+		err := q.deliver(batch)
+		if err == nil {
+			q.mutex.Lock()
+			if q.stats.SinkUnavailable {
+				logger.Log.Infof("Export queue %s sink recovered, resuming delivery", q.name)
+			}
+			q.stats.SinkUnavailable = false
+			q.stats.DeliveredItems += len(batch)
+			q.mutex.Unlock()
+			return true
+		}
+
+		q.mutex.Lock()
+		if !q.stats.SinkUnavailable {
+			logger.Log.Warningf("Export queue %s sink unavailable, buffering items until it recovers: %v", q.name, err)
+		}
+		q.stats.SinkUnavailable = true
+		q.mutex.Unlock()
+
+		select {
+		case <-q.stopSignal:
+			// keep the in flight items so they're not lost on restart
+			q.mutex.Lock()
+			for _, item := range batch {
+				_ = q.spill(item)
+			}
+			q.mutex.Unlock()
+			return false
+		case <-time.After(retryInterval):
+		}
+
+		retryInterval *= 2
+		if retryInterval > maxRetryInterval {
+			retryInterval = maxRetryInterval
+		}
+	}
+}
+
+// close spills the items still held in memory and closes the spill file, it's called by the delivery
+// goroutine once the queue is stopped
+func (q *Queue) close() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	for _, item := range q.memory {
+		if err := q.spill(item); err != nil {
+			q.stats.DroppedItems++
+		}
+	}
+	q.memory = nil
+
+	_ = q.spillWriter.Close()
+	_ = q.spillFile.Close()
+}
+
+func (q *Queue) openSpillFile() error {
+	spillWriter, err := os.OpenFile(q.spillPath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed opening spill file %s: %w", q.spillPath, err)
+	}
+
+	// a separate read handle is used since appending moves the offset of the write handle
+	spillFile, err := os.Open(q.spillPath)
+	if err != nil {
+		_ = spillWriter.Close()
+		return fmt.Errorf("failed opening spill file %s: %w", q.spillPath, err)
+	}
+
+	// items spilled by a previous run are delivered first
+	spilledItems := 0
+	var spilledBytes int64
+	reader := bufio.NewReader(spillFile)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			spilledItems++
+			spilledBytes += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			_ = spillWriter.Close()
+			_ = spillFile.Close()
+			return fmt.Errorf("failed reading spill file %s: %w", q.spillPath, err)
+		}
+	}
+
+	// drop a partially written item left by a crash, so the next append starts on a new line
+	if err := spillWriter.Truncate(spilledBytes); err != nil {
+		_ = spillWriter.Close()
+		_ = spillFile.Close()
+		return fmt.Errorf("failed truncating spill file %s: %w", q.spillPath, err)
+	}
+
+	if _, err := spillFile.Seek(0, io.SeekStart); err != nil {
+		_ = spillWriter.Close()
+		_ = spillFile.Close()
+		return fmt.Errorf("failed seeking spill file %s: %w", q.spillPath, err)
+	}
+
+	q.spillWriter = spillWriter
+	q.spillFile = spillFile
+	q.spillRead = bufio.NewReader(spillFile)
+	q.stats.SpilledItems = spilledItems
+	q.stats.SpilledBytes = spilledBytes
+
+	if spilledItems > 0 {
+		logger.Log.Infof("Export queue %s resuming delivery of %d spilled items", q.name, spilledItems)
+	}
+
+	return nil
+}
+
+// spill must be called while holding the mutex
+func (q *Queue) spill(item []byte) error {
+	if bytes.IndexByte(item, '\n') != -1 {
+		return fmt.Errorf("item contains a new line and can't be spilled")
+	}
+
+	itemSize := int64(len(item)) + 1
+	if q.stats.SpilledBytes+itemSize > q.maxSpillBytes {
+		return fmt.Errorf("spill size limit of %d bytes reached", q.maxSpillBytes)
+	}
+
+	line := make([]byte, 0, itemSize)
+	line = append(line, item...)
+	line = append(line, '\n')
+	if _, err := q.spillWriter.Write(line); err != nil {
+		return err
+	}
+
+	q.stats.SpilledItems++
+	q.stats.SpilledBytes += itemSize
+	return nil
+}
+
+// unspill must be called while holding the mutex
+func (q *Queue) unspill() ([]byte, error) {
+	line, err := q.spillRead.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	q.stats.SpilledItems--
+	if q.stats.SpilledItems == 0 {
+		if err := q.resetSpillFile(); err != nil {
+			return nil, err
+		}
+	}
+
+	return line[:len(line)-1], nil
+}
+
+// resetSpillFile must be called while holding the mutex
+func (q *Queue) resetSpillFile() error {
+	q.stats.SpilledItems = 0
+	q.stats.SpilledBytes = 0
+
+	if err := q.spillWriter.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := q.spillFile.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	q.spillRead.Reset(q.spillFile)
+	return nil
+}
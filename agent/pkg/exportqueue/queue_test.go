@@ -0,0 +1,168 @@
+package exportqueue_test
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/exportqueue"
+)
+
+type testSink struct {
+	mutex     sync.Mutex
+	available bool
+	delivered []string
+}
+
+func (s *testSink) deliver(item []byte) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if !s.available {
+		return fmt.Errorf("sink unavailable")
+	}
+	s.delivered = append(s.delivered, string(item))
+	return nil
+}
+
+func (s *testSink) setAvailable(available bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.available = available
+}
+
+func (s *testSink) getDelivered() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return append([]string{}, s.delivered...)
+}
+
+func waitForDelivered(t *testing.T, sink *testSink, expected int) []string {
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		if delivered := sink.getDelivered(); len(delivered) >= expected {
+			return delivered
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	t.Fatalf("unexpected result - expected %v delivered items, actual: %v", expected, len(sink.getDelivered()))
+	return nil
+}
+
+func TestSpillAndResumeOnRecovery(t *testing.T) {
+	sink := &testSink{available: false}
+	queue, err := exportqueue.New("test", path.Join(t.TempDir(), "spill"), 2, 1024*1024, sink.deliver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer queue.Stop()
+
+	for i := 0; i < 10; i++ {
+		queue.Push([]byte(fmt.Sprintf("item-%d", i)))
+	}
+
+	if stats := queue.GetStats(); stats.SpilledItems == 0 {
+		t.Errorf("unexpected result - expected spilled items, actual: %v", stats)
+	}
+
+	sink.setAvailable(true)
+	delivered := waitForDelivered(t, sink, 10)
+
+	for i, item := range delivered {
+		if expected := fmt.Sprintf("item-%d", i); item != expected {
+			t.Errorf("unexpected result - expected: %v, actual: %v", expected, item)
+		}
+	}
+}
+
+func TestDropWhenSpillLimitReached(t *testing.T) {
+	sink := &testSink{available: false}
+	queue, err := exportqueue.New("test", path.Join(t.TempDir(), "spill"), 1, 20, sink.deliver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer queue.Stop()
+
+	for i := 0; i < 10; i++ {
+		queue.Push([]byte(fmt.Sprintf("item-%d", i)))
+	}
+
+	if stats := queue.GetStats(); stats.DroppedItems == 0 || stats.SpilledBytes > 20 {
+		t.Errorf("unexpected result - expected dropped items and at most 20 spilled bytes, actual: %v", stats)
+	}
+}
+
+func TestSpilledItemsDeliveredAfterRestart(t *testing.T) {
+	spillPath := path.Join(t.TempDir(), "spill")
+	sink := &testSink{available: false}
+	queue, err := exportqueue.New("test", spillPath, 1, 1024*1024, sink.deliver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 5; i++ {
+		queue.Push([]byte(fmt.Sprintf("item-%d", i)))
+	}
+	queue.Stop()
+
+	sink.setAvailable(true)
+	restartedQueue, err := exportqueue.New("test", spillPath, 1, 1024*1024, sink.deliver)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer restartedQueue.Stop()
+
+	waitForDelivered(t, sink, 5)
+}
+
+func TestBatchedDelivery(t *testing.T) {
+	sink := &testSink{available: false}
+
+	var batchesMutex sync.Mutex
+	batchSizes := make([]int, 0)
+	deliverBatch := func(items [][]byte) error {
+		for _, item := range items {
+			if err := sink.deliver(item); err != nil {
+				return err
+			}
+		}
+
+		batchesMutex.Lock()
+		batchSizes = append(batchSizes, len(items))
+		batchesMutex.Unlock()
+		return nil
+	}
+
+	queue, err := exportqueue.NewBatched("test", path.Join(t.TempDir(), "spill"), 2, 1024*1024, 4, deliverBatch)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer queue.Stop()
+
+	for i := 0; i < 10; i++ {
+		queue.Push([]byte(fmt.Sprintf("item-%d", i)))
+	}
+
+	sink.setAvailable(true)
+	delivered := waitForDelivered(t, sink, 10)
+
+	for i, item := range delivered {
+		if expected := fmt.Sprintf("item-%d", i); item != expected {
+			t.Errorf("unexpected result - expected: %v, actual: %v", expected, item)
+		}
+	}
+
+	batchesMutex.Lock()
+	defer batchesMutex.Unlock()
+	for _, batchSize := range batchSizes {
+		if batchSize > 4 {
+			t.Errorf("unexpected result - expected batches of at most 4 items, actual: %v", batchSizes)
+		}
+	}
+	if len(batchSizes) == 10 {
+		t.Errorf("unexpected result - expected the waiting items to be batched, actual: %v", batchSizes)
+	}
+}
@@ -0,0 +1,126 @@
+package ingestion
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+type muteKey struct {
+	kind  string
+	value string
+}
+
+// Muter drops the entries of the muted protocols and namespaces before they're analyzed and stored, so a noisy
+// protocol can be silenced during an investigation without changing the tappers
+type Muter struct {
+	mutex sync.RWMutex
+	mutes map[muteKey]*shared.IngestionMute
+}
+
+var instance *Muter
+var once sync.Once
+
+func GetInstance() *Muter {
+	once.Do(func() {
+		instance = &Muter{mutes: make(map[muteKey]*shared.IngestionMute)}
+	})
+	return instance
+}
+
+// Mute mutes a protocol or a namespace, muting it again keeps its count
+func (muter *Muter) Mute(kind string, value string) (*shared.IngestionMute, error) {
+	if err := validateMute(kind, value); err != nil {
+		return nil, err
+	}
+
+	muter.mutex.Lock()
+	defer muter.mutex.Unlock()
+
+	key := muteKey{kind: kind, value: value}
+	mute, ok := muter.mutes[key]
+	if !ok {
+		mute = &shared.IngestionMute{Kind: kind, Value: value, Since: time.Now().UnixNano() / int64(time.Millisecond)}
+		muter.mutes[key] = mute
+	}
+
+	muteCopy := *mute
+	return &muteCopy, nil
+}
+
+// Unmute unmutes a protocol or a namespace, it returns the mute with the count of the entries it dropped
+func (muter *Muter) Unmute(kind string, value string) (*shared.IngestionMute, error) {
+	if err := validateMute(kind, value); err != nil {
+		return nil, err
+	}
+
+	muter.mutex.Lock()
+	defer muter.mutex.Unlock()
+
+	key := muteKey{kind: kind, value: value}
+	mute, ok := muter.mutes[key]
+	if !ok {
+		return nil, fmt.Errorf("%s %s isn't muted", kind, value)
+	}
+	delete(muter.mutes, key)
+
+	return mute, nil
+}
+
+// IsMuted reports whether an entry of the protocol and namespace is dropped, and counts it when it is
+func (muter *Muter) IsMuted(protocol string, namespace string) bool {
+	muter.mutex.RLock()
+	empty := len(muter.mutes) == 0
+	muter.mutex.RUnlock()
+	if empty {
+		return false
+	}
+
+	muter.mutex.Lock()
+	defer muter.mutex.Unlock()
+
+	mute, ok := muter.mutes[muteKey{kind: shared.IngestionMuteProtocol, value: protocol}]
+	if !ok && namespace != "" {
+		mute, ok = muter.mutes[muteKey{kind: shared.IngestionMuteNamespace, value: namespace}]
+	}
+	if !ok {
+		return false
+	}
+
+	mute.Dropped++
+	return true
+}
+
+// GetStatus returns the mutes, the protocols first, each sorted by value
+func (muter *Muter) GetStatus() *shared.IngestionStatus {
+	muter.mutex.RLock()
+	defer muter.mutex.RUnlock()
+
+	status := &shared.IngestionStatus{Muted: make([]*shared.IngestionMute, 0, len(muter.mutes))}
+	for _, mute := range muter.mutes {
+		muteCopy := *mute
+		status.Muted = append(status.Muted, &muteCopy)
+	}
+	sort.Slice(status.Muted, func(i, j int) bool {
+		if status.Muted[i].Kind != status.Muted[j].Kind {
+			return status.Muted[i].Kind == shared.IngestionMuteProtocol
+		}
+		return status.Muted[i].Value < status.Muted[j].Value
+	})
+
+	return status
+}
+
+func validateMute(kind string, value string) error {
+	if kind != shared.IngestionMuteProtocol && kind != shared.IngestionMuteNamespace {
+		return fmt.Errorf("unknown kind %s, either %s or %s can be muted", kind, shared.IngestionMuteProtocol, shared.IngestionMuteNamespace)
+	}
+	if value == "" {
+		return fmt.Errorf("the %s to mute is missing", kind)
+	}
+
+	return nil
+}
@@ -0,0 +1,77 @@
+package ingestion
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+func newMuter() *Muter {
+	return &Muter{mutes: make(map[muteKey]*shared.IngestionMute)}
+}
+
+func TestMuteAndUnmute(t *testing.T) {
+	muter := newMuter()
+
+	if _, err := muter.Mute(shared.IngestionMuteProtocol, "redis"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := muter.Mute(shared.IngestionMuteNamespace, "batch"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !muter.IsMuted("redis", "checkout") {
+		t.Errorf("expected the entries of a muted protocol to be dropped")
+	}
+	if !muter.IsMuted("http", "batch") {
+		t.Errorf("expected the entries of a muted namespace to be dropped")
+	}
+	if muter.IsMuted("http", "checkout") {
+		t.Errorf("expected the other entries to be stored")
+	}
+
+	mute, err := muter.Unmute(shared.IngestionMuteProtocol, "redis")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mute.Dropped != 1 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 1, mute.Dropped)
+	}
+	if muter.IsMuted("redis", "checkout") {
+		t.Errorf("expected the entries of an unmuted protocol to be stored")
+	}
+
+	if _, err := muter.Unmute(shared.IngestionMuteProtocol, "redis"); err == nil {
+		t.Errorf("expected an error unmuting a protocol that isn't muted")
+	}
+}
+
+func TestMuteInvalid(t *testing.T) {
+	muter := newMuter()
+
+	if _, err := muter.Mute("pod", "checkout"); err == nil {
+		t.Errorf("expected an error muting an unknown kind")
+	}
+	if _, err := muter.Mute(shared.IngestionMuteNamespace, ""); err == nil {
+		t.Errorf("expected an error muting an empty namespace")
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	muter := newMuter()
+	_, _ = muter.Mute(shared.IngestionMuteNamespace, "batch")
+	_, _ = muter.Mute(shared.IngestionMuteProtocol, "redis")
+	_, _ = muter.Mute(shared.IngestionMuteProtocol, "amqp")
+
+	status := muter.GetStatus()
+	actual := make([]string, 0)
+	for _, mute := range status.Muted {
+		actual = append(actual, mute.Kind+"/"+mute.Value)
+	}
+
+	expected := "[protocol/amqp protocol/redis namespace/batch]"
+	if fmtActual := fmt.Sprintf("%v", actual); fmtActual != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, fmtActual)
+	}
+}
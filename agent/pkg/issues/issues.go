@@ -0,0 +1,484 @@
+package issues
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/agent/pkg/maintenance"
+	"github.com/up9inc/mizu/agent/pkg/oas"
+	"github.com/up9inc/mizu/agent/pkg/version"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	queueSize      = 100
+	requestTimeout = 10 * time.Second
+	// the signatures seen past this count are forgotten one at a time, their next failure is forwarded again
+	maxSignatures = 10000
+	// the error bodies are fingerprinted and forwarded up to this length
+	maxBodyLength = 1024
+)
+
+var (
+	patUuid   = regexp.MustCompile(`(?i)[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`)
+	patHex    = regexp.MustCompile(`(?i)\b[0-9a-f]{8,}\b`)
+	patNumber = regexp.MustCompile(`\d+`)
+	patSpaces = regexp.MustCompile(`\s+`)
+)
+
+// Exemplar is the first captured failure of a signature, the signature groups the failures of an endpoint by
+// their error body, so the same error with other ids, timestamps or counts in its message isn't reported twice
+type Exemplar struct {
+	Signature string `json:"signature"`
+	Cluster   string `json:"cluster,omitempty"`
+	Service   string `json:"service"`
+	Namespace string `json:"namespace"`
+	Source    string `json:"source"`
+	Method    string `json:"method"`
+	Endpoint  string `json:"endpoint"`
+	Url       string `json:"url"`
+	Status    int    `json:"status"`
+	Body      string `json:"body"`
+	EntryId   string `json:"entryId"`
+	Link      string `json:"link,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+func (exemplar *Exemplar) title() string {
+	return fmt.Sprintf("%d %s %s on %s", exemplar.Status, exemplar.Method, exemplar.Endpoint, exemplar.Service)
+}
+
+type Stats struct {
+	Signatures     int `json:"signatures"`
+	Forwarded      int `json:"forwarded"`
+	SkippedRateCap int `json:"skippedRateCap"`
+	Dropped        int `json:"dropped"`
+	Failed         int `json:"failed"`
+}
+
+type Forwarder struct {
+	mutex        sync.Mutex
+	sentry       *sentryTarget
+	webhookUrl   string
+	linkUrl      string
+	cluster      string
+	maxPerMinute int
+	windowStart  time.Time
+	windowCount  int
+	signatures   map[string]bool
+	exemplars    chan *Exemplar
+	client       *http.Client
+	stats        Stats
+	stop         chan struct{}
+}
+
+var instance *Forwarder
+var once sync.Once
+
+func GetInstance() *Forwarder {
+	once.Do(func() {
+		instance = &Forwarder{}
+	})
+	return instance
+}
+
+// Configure starts forwarding to the destinations of the config, the exemplars are labeled with the cluster
+func (f *Forwarder) Configure(config shared.IssuesConfig, cluster string) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.stop != nil {
+		close(f.stop)
+		f.stop = nil
+	}
+	f.sentry = nil
+	f.webhookUrl = ""
+
+	if config.SentryDsn == "" && config.WebhookUrl == "" {
+		logger.Log.Infof("No sentry dsn or issues webhook url was supplied, forwarding of 5xx exemplars disabled")
+		return
+	}
+
+	if config.SentryDsn != "" {
+		sentry, err := parseSentryDsn(config.SentryDsn)
+		if err != nil {
+			logger.Log.Errorf("Invalid sentry dsn, forwarding of 5xx exemplars to sentry disabled: %v", err)
+		}
+		f.sentry = sentry
+	}
+	f.webhookUrl = config.WebhookUrl
+	if f.sentry == nil && f.webhookUrl == "" {
+		return
+	}
+
+	f.linkUrl = strings.TrimSuffix(config.LinkUrl, "/")
+	f.cluster = cluster
+	f.maxPerMinute = config.MaxPerMinute
+	f.signatures = make(map[string]bool)
+	f.exemplars = make(chan *Exemplar, queueSize)
+	f.client = &http.Client{Timeout: requestTimeout}
+	f.stop = make(chan struct{})
+
+	go f.send(f.exemplars, f.client, f.stop)
+
+	logger.Log.Infof("Forwarding the exemplars of new 5xx error signatures, up to %d per minute", config.MaxPerMinute)
+}
+
+// PushEntry forwards the entry when it's the first 5xx of its signature, it never blocks, exemplars are dropped
+// when the destinations can't keep up
+func (f *Forwarder) PushEntry(entry *tapApi.Entry, harEntry *har.Entry) {
+	if harEntry.Response.Status < 500 || harEntry.Response.Status > 599 {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.stop == nil {
+		return
+	}
+
+	// failures are expected during maintenance windows, they don't open issues
+	if maintenance.GetInstance().IsActive(entry.Namespace, entry.Timestamp) {
+		return
+	}
+
+	exemplar := newExemplar(entry, harEntry, f.linkUrl)
+	exemplar.Cluster = f.cluster
+	if f.signatures[exemplar.Signature] {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(f.windowStart) >= time.Minute {
+		f.windowStart = now
+		f.windowCount = 0
+	}
+	if f.windowCount >= f.maxPerMinute {
+		// the signature isn't remembered so its next failure gets another chance
+		f.stats.SkippedRateCap++
+		return
+	}
+	f.windowCount++
+
+	if len(f.signatures) >= maxSignatures {
+		for signature := range f.signatures {
+			delete(f.signatures, signature)
+			break
+		}
+	}
+	f.signatures[exemplar.Signature] = true
+	f.stats.Signatures++
+
+	select {
+	case f.exemplars <- exemplar:
+	default:
+		f.stats.Dropped++
+	}
+}
+
+func (f *Forwarder) GetStats() *Stats {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if f.stop == nil {
+		return nil
+	}
+
+	stats := f.stats
+	return &stats
+}
+
+func (f *Forwarder) send(exemplars <-chan *Exemplar, client *http.Client, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case exemplar := <-exemplars:
+			f.mutex.Lock()
+			sentry := f.sentry
+			webhookUrl := f.webhookUrl
+			f.mutex.Unlock()
+
+			var requests []*http.Request
+			if sentry != nil {
+				request, err := sentry.buildRequest(exemplar)
+				if err == nil {
+					requests = append(requests, request)
+				}
+			}
+			if webhookUrl != "" {
+				request, err := buildWebhookRequest(webhookUrl, exemplar)
+				if err == nil {
+					requests = append(requests, request)
+				}
+			}
+
+			for _, request := range requests {
+				err := doRequest(client, request)
+
+				f.mutex.Lock()
+				if err != nil {
+					f.stats.Failed++
+					if f.stats.Failed == 1 || f.stats.Failed%100 == 0 {
+						logger.Log.Warningf("Failed forwarding 5xx exemplars to %s, %d failures so far: %v", request.URL.Host, f.stats.Failed, err)
+					}
+				} else {
+					f.stats.Forwarded++
+				}
+				f.mutex.Unlock()
+			}
+		}
+	}
+}
+
+func doRequest(client *http.Client, request *http.Request) error {
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
+
+func newExemplar(entry *tapApi.Entry, harEntry *har.Entry, linkUrl string) *Exemplar {
+	exemplar := &Exemplar{
+		Service:   entry.Destination.Name,
+		Namespace: entry.Namespace,
+		Source:    entry.Source.Name,
+		Method:    harEntry.Request.Method,
+		Url:       harEntry.Request.URL,
+		Status:    harEntry.Response.Status,
+		Body:      responseBody(&harEntry.Response),
+		EntryId:   entry.EntryId,
+		Timestamp: entry.Timestamp,
+	}
+	if exemplar.Service == "" {
+		exemplar.Service = fmt.Sprintf("%s:%s", entry.Destination.IP, entry.Destination.Port)
+	}
+
+	exemplar.Endpoint = "/"
+	if capturedUrl, err := url.Parse(harEntry.Request.URL); err == nil {
+		exemplar.Endpoint = normalizePath(capturedUrl.Path)
+	}
+
+	exemplar.Signature = signature(exemplar.Service, exemplar.Method, exemplar.Endpoint, exemplar.Status, exemplar.Body)
+
+	// the entries numbered by the database get their id only once they're inserted
+	if linkUrl != "" && entry.EntryId != "" {
+		exemplar.Link = fmt.Sprintf("%s/entries/%s", linkUrl, url.PathEscape(entry.EntryId))
+	}
+
+	return exemplar
+}
+
+func responseBody(response *har.Response) string {
+	isBinary, _, body := response.Content.B64Decoded()
+	if isBinary {
+		return ""
+	}
+	if len(body) > maxBodyLength {
+		body = body[:maxBodyLength]
+	}
+	return body
+}
+
+// normalizePath replaces the path segments that look like ids, so the failures of /orders/17 and /orders/42 are
+// grouped under the same endpoint
+func normalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		if patNumber.FindString(segment) == segment || oas.IsGibberish(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	normalized := strings.Join(segments, "/")
+	if normalized == "" {
+		return "/"
+	}
+	return normalized
+}
+
+// fingerprintBody drops the parts of an error body that change between occurrences of the same error
+func fingerprintBody(body string) string {
+	body = patUuid.ReplaceAllString(body, "<uuid>")
+	body = patHex.ReplaceAllString(body, "<hex>")
+	body = patNumber.ReplaceAllString(body, "<n>")
+	return strings.TrimSpace(patSpaces.ReplaceAllString(body, " "))
+}
+
+func signature(service string, method string, endpoint string, status int, body string) string {
+	hash := sha1.Sum([]byte(fmt.Sprintf("%s\n%s\n%s\n%d\n%s", service, method, endpoint, status, fingerprintBody(body))))
+	return hex.EncodeToString(hash[:8])
+}
+
+// webhookPayload is generic, the summary and the description are ready to be mapped to the fields of a ticket
+type webhookPayload struct {
+	Summary     string    `json:"summary"`
+	Description string    `json:"description"`
+	Exemplar    *Exemplar `json:"exemplar"`
+}
+
+func buildWebhookRequest(webhookUrl string, exemplar *Exemplar) (*http.Request, error) {
+	description := fmt.Sprintf("%s %s answered %d", exemplar.Method, exemplar.Url, exemplar.Status)
+	if exemplar.Source != "" {
+		description = fmt.Sprintf("%s to %s", description, exemplar.Source)
+	}
+	if exemplar.Cluster != "" {
+		description = fmt.Sprintf("%s in cluster %s", description, exemplar.Cluster)
+	}
+	if exemplar.Link != "" {
+		description = fmt.Sprintf("%s\n\nCaptured entry: %s", description, exemplar.Link)
+	}
+	if exemplar.Body != "" {
+		description = fmt.Sprintf("%s\n\n%s", description, exemplar.Body)
+	}
+
+	payload, err := json.Marshal(&webhookPayload{
+		Summary:     exemplar.title(),
+		Description: description,
+		Exemplar:    exemplar,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, webhookUrl, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	return request, nil
+}
+
+// sentryTarget is the store endpoint of a project, parsed from a dsn like https://<key>@<host>/<project>
+type sentryTarget struct {
+	storeUrl  string
+	publicKey string
+	secretKey string
+}
+
+func parseSentryDsn(dsn string) (*sentryTarget, error) {
+	dsnUrl, err := url.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if dsnUrl.Scheme == "" || dsnUrl.Host == "" || dsnUrl.User == nil || dsnUrl.User.Username() == "" {
+		return nil, fmt.Errorf("%s is missing the scheme, the host or the public key", dsnUrl.Redacted())
+	}
+
+	path := strings.Trim(dsnUrl.Path, "/")
+	separator := strings.LastIndex(path, "/")
+	project := path[separator+1:]
+	if project == "" {
+		return nil, fmt.Errorf("%s is missing the project id", dsnUrl.Redacted())
+	}
+
+	prefix := ""
+	if separator >= 0 {
+		prefix = "/" + path[:separator]
+	}
+
+	secretKey, _ := dsnUrl.User.Password()
+	return &sentryTarget{
+		storeUrl:  fmt.Sprintf("%s://%s%s/api/%s/store/", dsnUrl.Scheme, dsnUrl.Host, prefix, project),
+		publicKey: dsnUrl.User.Username(),
+		secretKey: secretKey,
+	}, nil
+}
+
+type sentryEvent struct {
+	EventId     string                 `json:"event_id"`
+	Timestamp   float64                `json:"timestamp"`
+	Level       string                 `json:"level"`
+	Logger      string                 `json:"logger"`
+	Platform    string                 `json:"platform"`
+	Message     string                 `json:"message"`
+	Fingerprint []string               `json:"fingerprint"`
+	Tags        map[string]string      `json:"tags"`
+	Request     map[string]string      `json:"request"`
+	Extra       map[string]interface{} `json:"extra"`
+}
+
+func (target *sentryTarget) buildRequest(exemplar *Exemplar) (*http.Request, error) {
+	eventId := make([]byte, 16)
+	if _, err := rand.Read(eventId); err != nil {
+		return nil, err
+	}
+
+	extra := map[string]interface{}{
+		"entryId": exemplar.EntryId,
+		"source":  exemplar.Source,
+		"body":    exemplar.Body,
+	}
+	if exemplar.Link != "" {
+		extra["link"] = exemplar.Link
+	}
+
+	tags := map[string]string{
+		"service":   exemplar.Service,
+		"namespace": exemplar.Namespace,
+		"method":    exemplar.Method,
+		"endpoint":  exemplar.Endpoint,
+		"status":    fmt.Sprintf("%d", exemplar.Status),
+	}
+	if exemplar.Cluster != "" {
+		tags["cluster"] = exemplar.Cluster
+	}
+
+	// the fingerprint makes sentry group the events by the signature of mizu rather than by its own rules
+	payload, err := json.Marshal(&sentryEvent{
+		EventId:     hex.EncodeToString(eventId),
+		Timestamp:   float64(exemplar.Timestamp) / 1000,
+		Level:       "error",
+		Logger:      "mizu",
+		Platform:    "other",
+		Message:     exemplar.title(),
+		Fingerprint: []string{exemplar.Signature},
+		Tags:        tags,
+		Request: map[string]string{
+			"url":    exemplar.Url,
+			"method": exemplar.Method,
+		},
+		Extra: extra,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, target.storeUrl, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	auth := fmt.Sprintf("Sentry sentry_version=7, sentry_client=mizu/%s, sentry_key=%s", version.Ver, target.publicKey)
+	if target.secretKey != "" {
+		auth = fmt.Sprintf("%s, sentry_secret=%s", auth, target.secretKey)
+	}
+	request.Header.Set("X-Sentry-Auth", auth)
+	request.Header.Set("Content-Type", "application/json")
+	return request, nil
+}
@@ -0,0 +1,165 @@
+package issues
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/shared"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+func newTestEntry(entryId string, path string, body string) (*tapApi.Entry, *har.Entry) {
+	entry := &tapApi.Entry{
+		EntryId:     entryId,
+		Namespace:   "sock-shop",
+		Source:      &tapApi.TCP{Name: "front-end.sock-shop"},
+		Destination: &tapApi.TCP{Name: "orders.sock-shop", IP: "10.0.0.7", Port: "80"},
+		Timestamp:   time.Now().UnixNano() / int64(time.Millisecond),
+	}
+	harEntry := &har.Entry{
+		Request: har.Request{
+			Method: "POST",
+			URL:    "http://orders.sock-shop" + path,
+		},
+		Response: har.Response{
+			Status:  500,
+			Content: har.Content{Text: body},
+		},
+	}
+	return entry, harEntry
+}
+
+func TestNormalizePath(t *testing.T) {
+	testCases := map[string]string{
+		"":           "/",
+		"/orders/17": "/orders/{id}",
+		"/orders/3fa85f64-5717-4562-b3fc-2c963f66afa6/items": "/orders/{id}/items",
+		"/api/v2/health": "/api/v2/health",
+	}
+
+	for path, expected := range testCases {
+		if actual := normalizePath(path); actual != expected {
+			t.Errorf("unexpected endpoint of %s - expected: %v, actual: %v", path, expected, actual)
+		}
+	}
+}
+
+func TestSignatureIgnoresChangingValues(t *testing.T) {
+	entry, harEntry := newTestEntry("", "/orders/17", `{"error":"order 17 failed at 2022-03-01T10:00:00Z, trace 9f86d081884c7d65"}`)
+	first := newExemplar(entry, harEntry, "")
+
+	entry, harEntry = newTestEntry("", "/orders/42", `{"error":"order 42 failed at 2022-03-02T11:30:00Z, trace 5feceb66ffc86f38"}`)
+	second := newExemplar(entry, harEntry, "")
+
+	if first.Signature != second.Signature {
+		t.Errorf("expected the same error of the same endpoint to share a signature, actual: %v, %v", first.Signature, second.Signature)
+	}
+
+	entry, harEntry = newTestEntry("", "/orders/17", `{"error":"database unavailable"}`)
+	if other := newExemplar(entry, harEntry, ""); other.Signature == first.Signature {
+		t.Errorf("expected another error body to have another signature")
+	}
+}
+
+func TestParseSentryDsn(t *testing.T) {
+	target, err := parseSentryDsn("https://public@sentry.example.com/prefix/42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "https://sentry.example.com/prefix/api/42/store/"; target.storeUrl != expected {
+		t.Errorf("unexpected store url - expected: %v, actual: %v", expected, target.storeUrl)
+	}
+	if target.publicKey != "public" {
+		t.Errorf("unexpected public key: %v", target.publicKey)
+	}
+
+	for _, dsn := range []string{"https://sentry.example.com/42", "https://public@sentry.example.com/"} {
+		if _, err := parseSentryDsn(dsn); err == nil {
+			t.Errorf("expected %s to be rejected", dsn)
+		}
+	}
+}
+
+func TestForwardsNewSignaturesOnce(t *testing.T) {
+	var mutex sync.Mutex
+	var payloads []webhookPayload
+	var sentryAuth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		mutex.Lock()
+		defer mutex.Unlock()
+		if r.URL.Path == "/api/7/store/" {
+			sentryAuth = r.Header.Get("X-Sentry-Auth")
+			return
+		}
+		var payload webhookPayload
+		_ = json.Unmarshal(body, &payload)
+		payloads = append(payloads, payload)
+	}))
+	defer server.Close()
+
+	forwarder := &Forwarder{}
+	forwarder.Configure(shared.IssuesConfig{
+		SentryDsn:    "http://key@" + server.Listener.Addr().String() + "/7",
+		WebhookUrl:   server.URL + "/jira",
+		LinkUrl:      "http://localhost:8899/",
+		MaxPerMinute: 10,
+	}, "prod-eu")
+	defer forwarder.Configure(shared.IssuesConfig{}, "")
+
+	forwarder.PushEntry(newTestEntry("01FZ4Q4Y7M4T5A6Z8N3XJ2K9QW", "/orders/17", "order 17 failed"))
+	forwarder.PushEntry(newTestEntry("01FZ4Q4Y7M4T5A6Z8N3XJ2K9QX", "/orders/42", "order 42 failed"))
+	entry, harEntry := newTestEntry("01FZ4Q4Y7M4T5A6Z8N3XJ2K9QY", "/orders/42", "")
+	harEntry.Response.Status = 404
+	forwarder.PushEntry(entry, harEntry)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if stats := forwarder.GetStats(); stats.Forwarded == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stats := forwarder.GetStats()
+	if stats.Signatures != 1 || stats.Forwarded != 2 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(payloads) != 1 {
+		t.Fatalf("expected a single webhook call, actual: %d", len(payloads))
+	}
+	if expected := "http://localhost:8899/entries/01FZ4Q4Y7M4T5A6Z8N3XJ2K9QW"; payloads[0].Exemplar.Link != expected {
+		t.Errorf("unexpected link - expected: %v, actual: %v", expected, payloads[0].Exemplar.Link)
+	}
+	if expected := "500 POST /orders/{id} on orders.sock-shop"; payloads[0].Summary != expected {
+		t.Errorf("unexpected summary - expected: %v, actual: %v", expected, payloads[0].Summary)
+	}
+	if expected := "prod-eu"; payloads[0].Exemplar.Cluster != expected {
+		t.Errorf("unexpected cluster - expected: %v, actual: %v", expected, payloads[0].Exemplar.Cluster)
+	}
+	if sentryAuth == "" {
+		t.Errorf("expected the event to be sent to sentry")
+	}
+}
+
+func TestRateCap(t *testing.T) {
+	forwarder := &Forwarder{}
+	forwarder.Configure(shared.IssuesConfig{WebhookUrl: "http://127.0.0.1:1/jira", MaxPerMinute: 1}, "")
+	defer forwarder.Configure(shared.IssuesConfig{}, "")
+
+	forwarder.PushEntry(newTestEntry("", "/orders", "first"))
+	forwarder.PushEntry(newTestEntry("", "/carts", "second"))
+
+	if stats := forwarder.GetStats(); stats.Signatures != 1 || stats.SkippedRateCap != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
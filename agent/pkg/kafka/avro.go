@@ -0,0 +1,111 @@
+package kafka
+
+import (
+	"encoding/binary"
+	"encoding/json"
+)
+
+// AvroSchema is the schema of the avro messages in its parsing canonical form, the request and the response differ
+// per protocol so they're encoded as json strings
+const AvroSchema = `{"name":"io.mizu.Entry","type":"record","fields":[` +
+	`{"name":"entryId","type":"string"},` +
+	`{"name":"protocol","type":"string"},` +
+	`{"name":"protocolVersion","type":"string"},` +
+	`{"name":"src","type":{"name":"io.mizu.Tcp","type":"record","fields":[{"name":"ip","type":"string"},{"name":"port","type":"string"},{"name":"name","type":"string"}]}},` +
+	`{"name":"dst","type":"io.mizu.Tcp"},` +
+	`{"name":"namespace","type":"string"},` +
+	`{"name":"cluster","type":"string"},` +
+	`{"name":"outgoing","type":"boolean"},` +
+	`{"name":"timestamp","type":"long"},` +
+	`{"name":"elapsedTime","type":"long"},` +
+	`{"name":"request","type":"string"},` +
+	`{"name":"response","type":"string"}]}`
+
+// the header of the avro single object encoding, followed by the little endian fingerprint of the schema
+var avroSingleObjectMarker = []byte{0xC3, 0x01}
+
+const avroFingerprintEmpty uint64 = 0xc15d213aa4d7a795
+
+var avroFingerprintTable = newAvroFingerprintTable()
+
+var avroSchemaFingerprint = avroFingerprint([]byte(AvroSchema))
+
+func newAvroFingerprintTable() [256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		fingerprint := uint64(i)
+		for j := 0; j < 8; j++ {
+			fingerprint = (fingerprint >> 1) ^ (avroFingerprintEmpty & -(fingerprint & 1))
+		}
+		table[i] = fingerprint
+	}
+
+	return table
+}
+
+// avroFingerprint is the CRC-64-AVRO (Rabin) fingerprint of a schema in its parsing canonical form
+func avroFingerprint(schema []byte) uint64 {
+	fingerprint := avroFingerprintEmpty
+	for _, b := range schema {
+		fingerprint = (fingerprint >> 8) ^ avroFingerprintTable[byte(fingerprint)^b]
+	}
+
+	return fingerprint
+}
+
+func encodeAvroEntry(entry *kafkaEntry) ([]byte, error) {
+	request, err := json.Marshal(entry.Request)
+	if err != nil {
+		return nil, err
+	}
+	response, err := json.Marshal(entry.Response)
+	if err != nil {
+		return nil, err
+	}
+
+	data := make([]byte, 0, 10+len(request)+len(response)+256)
+	data = append(data, avroSingleObjectMarker...)
+	fingerprint := make([]byte, 8)
+	binary.LittleEndian.PutUint64(fingerprint, avroSchemaFingerprint)
+	data = append(data, fingerprint...)
+
+	data = appendAvroString(data, entry.EntryId)
+	data = appendAvroString(data, entry.Protocol)
+	data = appendAvroString(data, entry.ProtocolVersion)
+	data = appendAvroTcp(data, entry.Source)
+	data = appendAvroTcp(data, entry.Destination)
+	data = appendAvroString(data, entry.Namespace)
+	data = appendAvroString(data, entry.Cluster)
+	data = appendAvroBoolean(data, entry.Outgoing)
+	data = appendAvroLong(data, entry.Timestamp)
+	data = appendAvroLong(data, entry.ElapsedTime)
+	data = appendAvroString(data, string(request))
+	data = appendAvroString(data, string(response))
+
+	return data, nil
+}
+
+func appendAvroTcp(data []byte, tcp kafkaTcp) []byte {
+	data = appendAvroString(data, tcp.IP)
+	data = appendAvroString(data, tcp.Port)
+	return appendAvroString(data, tcp.Name)
+}
+
+// longs are zig-zag encoded variable length integers, like the varints of encoding/binary
+func appendAvroLong(data []byte, value int64) []byte {
+	buffer := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutVarint(buffer, value)
+	return append(data, buffer[:n]...)
+}
+
+func appendAvroString(data []byte, value string) []byte {
+	data = appendAvroLong(data, int64(len(value)))
+	return append(data, value...)
+}
+
+func appendAvroBoolean(data []byte, value bool) []byte {
+	if value {
+		return append(data, 1)
+	}
+	return append(data, 0)
+}
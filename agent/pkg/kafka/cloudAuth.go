@@ -0,0 +1,170 @@
+package kafka
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/up9inc/mizu/shared/cloudauth"
+)
+
+const (
+	mskIamVersion      = "2020_10_22"
+	mskIamAction       = "kafka-cluster:Connect"
+	mskIamService      = "kafka-cluster"
+	mskIamUserAgent    = "mizu"
+	mskIamSignatureTtl = 15 * time.Minute
+	credentialsTimeout = 10 * time.Second
+)
+
+// the permissions of the cloud identity of a kafka auth, describing the topic and producing to it
+var cloudAuthPermissions = map[string][]string{
+	cloudauth.AuthAws:   {"kafka-cluster:Connect", "kafka-cluster:DescribeTopic", "kafka-cluster:WriteData"},
+	cloudauth.AuthGcp:   {"managedkafka.clusters.connect", "managedkafka.topics.get"},
+	cloudauth.AuthAzure: {"Azure Event Hubs Data Sender"},
+}
+
+// the host of an MSK broker has its region, like b-1.mizu.abc123.c2.kafka.us-east-1.amazonaws.com
+var mskBrokerHostPattern = regexp.MustCompile(`\.kafka(-serverless)?\.([a-z0-9-]+)\.amazonaws\.com$`)
+
+// cloudAuthMechanism is the sasl mechanism of a cloud auth, it tells the identity it authenticates as
+type cloudAuthMechanism interface {
+	sasl.Mechanism
+	Identity() string
+}
+
+// newCloudAuthMechanism returns the sasl mechanism of the cloud auth, MSK
+// authenticates the role of the pod by AWS_MSK_IAM, the brokers of gcp and of azure take its oauth tokens by
+// OAUTHBEARER. The scope of an azure token is the namespace of the brokers, like of Event Hubs
+func newCloudAuthMechanism(auth string, brokers []string) (cloudAuthMechanism, error) {
+	switch auth {
+	case cloudauth.AuthAws:
+		credentials, err := cloudauth.NewAwsWebIdentityProvider()
+		if err != nil {
+			return nil, err
+		}
+		return &mskIamMechanism{credentials: credentials}, nil
+	case cloudauth.AuthGcp:
+		return &oauthBearerMechanism{tokens: cloudauth.NewGcpTokenProvider()}, nil
+	case cloudauth.AuthAzure:
+		host, _, err := net.SplitHostPort(brokers[0])
+		if err != nil {
+			return nil, err
+		}
+		tokens, err := cloudauth.NewAzureTokenProvider(fmt.Sprintf("https://%s/.default", host))
+		if err != nil {
+			return nil, err
+		}
+		return &oauthBearerMechanism{tokens: tokens}, nil
+	default:
+		return nil, fmt.Errorf("%s isn't a supported auth of kafka", auth)
+	}
+}
+
+// mskIamMechanism authenticates with a signature version 4 of the connect action to the broker, signed by the
+// temporary credentials of the role
+type mskIamMechanism struct {
+	credentials *cloudauth.AwsCredentialsProvider
+}
+
+func (mechanism *mskIamMechanism) Name() string {
+	return "AWS_MSK_IAM"
+}
+
+func (mechanism *mskIamMechanism) Identity() string {
+	return mechanism.credentials.Identity()
+}
+
+func (mechanism *mskIamMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	metadata := sasl.MetadataFromContext(ctx)
+	if metadata == nil {
+		return nil, nil, fmt.Errorf("the broker of the AWS_MSK_IAM handshake is unknown")
+	}
+
+	credentialsCtx, cancel := context.WithTimeout(ctx, credentialsTimeout)
+	defer cancel()
+	credentials, err := mechanism.credentials.Credentials(credentialsCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	region := cloudauth.AwsRegion()
+	if match := mskBrokerHostPattern.FindStringSubmatch(metadata.Host); match != nil {
+		region = match[2]
+	}
+
+	payload, err := getMskIamPayload(metadata.Host, credentials, region, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mechanism, payload, nil
+}
+
+// Next accepts the response of the broker to the signed payload, the broker closes the connection when it rejects it
+func (mechanism *mskIamMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	return true, nil, nil
+}
+
+// getMskIamPayload returns the json of the signed query of a presigned connect request to the broker
+func getMskIamPayload(host string, credentials *cloudauth.AwsCredentials, region string, now time.Time) ([]byte, error) {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("kafka://%s/?Action=%s", host, mskIamAction), nil)
+	if err != nil {
+		return nil, err
+	}
+	cloudauth.PresignV4(request, credentials, region, mskIamService, mskIamSignatureTtl, now)
+
+	payload := map[string]string{
+		"version":    mskIamVersion,
+		"host":       host,
+		"user-agent": mskIamUserAgent,
+		"action":     mskIamAction,
+	}
+	query := request.URL.Query()
+	for _, name := range []string{"X-Amz-Algorithm", "X-Amz-Credential", "X-Amz-Date", "X-Amz-Expires", "X-Amz-SignedHeaders", "X-Amz-Security-Token", "X-Amz-Signature"} {
+		if value := query.Get(name); value != "" {
+			payload[strings.ToLower(name)] = value
+		}
+	}
+
+	return json.Marshal(payload)
+}
+
+// oauthBearerMechanism authenticates with an oauth token of the cloud identity, as the initial response of RFC 7628
+type oauthBearerMechanism struct {
+	tokens *cloudauth.TokenProvider
+}
+
+func (mechanism *oauthBearerMechanism) Name() string {
+	return "OAUTHBEARER"
+}
+
+func (mechanism *oauthBearerMechanism) Identity() string {
+	return mechanism.tokens.Identity()
+}
+
+func (mechanism *oauthBearerMechanism) Start(ctx context.Context) (sasl.StateMachine, []byte, error) {
+	tokenCtx, cancel := context.WithTimeout(ctx, credentialsTimeout)
+	defer cancel()
+	token, err := mechanism.tokens.Token(tokenCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mechanism, []byte(fmt.Sprintf("n,,\x01auth=Bearer %s\x01\x01", token)), nil
+}
+
+// Next fails on a challenge, the broker only sends one with the error of a rejected token
+func (mechanism *oauthBearerMechanism) Next(ctx context.Context, challenge []byte) (bool, []byte, error) {
+	if len(challenge) > 0 {
+		return false, nil, fmt.Errorf("the broker rejected the token of %s: %s", mechanism.tokens.Identity(), challenge)
+	}
+
+	return true, nil, nil
+}
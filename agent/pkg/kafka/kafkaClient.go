@@ -0,0 +1,364 @@
+package kafka
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	kafkago "github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/up9inc/mizu/agent/pkg/exportqueue"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/transform"
+	"github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	exportQueueName    = "kafka"
+	healthCheckTimeout = 10 * time.Second
+	writeTimeout       = 10 * time.Second
+)
+
+type kafkaTcp struct {
+	IP   string `json:"ip"`
+	Port string `json:"port"`
+	Name string `json:"name"`
+}
+
+// kafkaEntry is the published entry, in json or in the avro schema
+type kafkaEntry struct {
+	EntryId         string                 `json:"entryId"`
+	Protocol        string                 `json:"protocol"`
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Source          kafkaTcp               `json:"src"`
+	Destination     kafkaTcp               `json:"dst"`
+	Namespace       string                 `json:"namespace"`
+	Cluster         string                 `json:"cluster"`
+	Outgoing        bool                   `json:"outgoing"`
+	Timestamp       int64                  `json:"timestamp"`
+	ElapsedTime     int64                  `json:"elapsedTime"`
+	Request         map[string]interface{} `json:"request"`
+	Response        map[string]interface{} `json:"response"`
+}
+
+type client struct {
+	mutex         sync.Mutex
+	name          string
+	writer        *kafkago.Writer
+	dialer        *kafkago.Dialer
+	brokers       []string
+	topic         string
+	format        string
+	auth          string
+	authMechanism cloudAuthMechanism
+	transform     *transform.Expression
+	cluster       string
+	queue         *exportqueue.Queue
+}
+
+var instance *client
+var once sync.Once
+
+func GetInstance() *client {
+	once.Do(func() {
+		instance = &client{}
+	})
+	return instance
+}
+
+// New creates a client of a sink of the fan-out, its export queue is named after the sink. The json messages are
+// shaped by the transform when it isn't nil
+func New(name string, config shared.KafkaConfig, entryTransform *transform.Expression, cluster string, maxExportQueueDiskSizeBytes int64) *client {
+	client := &client{}
+	client.configure(name, config, entryTransform, cluster, maxExportQueueDiskSizeBytes)
+	return client
+}
+
+func (client *client) Configure(config shared.KafkaConfig, cluster string, maxExportQueueDiskSizeBytes int64) {
+	client.configure(exportQueueName, config, nil, cluster, maxExportQueueDiskSizeBytes)
+}
+
+func (client *client) configure(name string, config shared.KafkaConfig, entryTransform *transform.Expression, cluster string, maxExportQueueDiskSizeBytes int64) {
+	// the queue is stopped without holding the mutex, stopping waits for a delivery in flight which takes it
+	client.mutex.Lock()
+	queue := client.queue
+	client.queue = nil
+	client.mutex.Unlock()
+	if queue != nil {
+		queue.Stop()
+	}
+
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.writer != nil {
+		if err := client.writer.Close(); err != nil {
+			logger.Log.Debugf("Failed closing the kafka writer: %v", err)
+		}
+		client.writer = nil
+	}
+
+	if len(config.Brokers) == 0 || config.Topic == "" {
+		logger.Log.Infof("No kafka configuration was supplied, kafka exporter disabled")
+		return
+	}
+
+	var mechanism sasl.Mechanism
+	var authMechanism cloudAuthMechanism
+	if config.Auth != "" {
+		var err error
+		if authMechanism, err = newCloudAuthMechanism(config.Auth, config.Brokers); err != nil {
+			logger.Log.Errorf("Kafka exporter disabled, %v", err)
+			return
+		}
+		mechanism = authMechanism
+	} else if config.SaslMechanism == shared.KafkaSaslMechanismPlain {
+		mechanism = plain.Mechanism{Username: config.User, Password: config.Password}
+	}
+
+	var tlsConfig *tls.Config
+	if config.Tls {
+		tlsConfig = &tls.Config{}
+	}
+
+	// a batch of a single message is produced right away, the export queue delivers the entries one by one and
+	// retries them while kafka is unavailable
+	writer := &kafkago.Writer{
+		Addr:         kafkago.TCP(config.Brokers...),
+		Topic:        config.Topic,
+		Balancer:     &kafkago.Hash{},
+		BatchSize:    1,
+		WriteTimeout: writeTimeout,
+		RequiredAcks: kafkago.RequireOne,
+		Transport: &kafkago.Transport{
+			SASL: mechanism,
+			TLS:  tlsConfig,
+		},
+	}
+
+	spillPath := path.Join(shared.DataDirPath, fmt.Sprintf("%s_export_queue", name))
+	queue, err := exportqueue.New(name, spillPath, exportqueue.DefaultMaxMemoryItems, maxExportQueueDiskSizeBytes, client.deliver)
+	if err != nil {
+		logger.Log.Errorf("Failed to create kafka export queue %v", err)
+		return
+	}
+
+	client.name = name
+	client.writer = writer
+	client.dialer = &kafkago.Dialer{Timeout: healthCheckTimeout, TLS: tlsConfig, SASLMechanism: mechanism}
+	client.brokers = config.Brokers
+	client.topic = config.Topic
+	client.format = config.Format
+	client.auth = config.Auth
+	client.authMechanism = authMechanism
+	client.transform = entryTransform
+	client.cluster = cluster
+	client.queue = queue
+	logger.Log.Infof("Kafka client configured, brokers: %s, topic: %s, format: %s", strings.Join(config.Brokers, ","), config.Topic, config.Format)
+	if config.Format == shared.KafkaFormatAvro {
+		logger.Log.Infof("Kafka avro schema: %s", AvroSchema)
+	}
+}
+
+// PushEntry queues the entry for publishing, its key is the destination service so the entries of a service keep
+// their order
+func (client *client) PushEntry(entry *api.Entry) {
+	client.mutex.Lock()
+	queue := client.queue
+	format := client.format
+	entryTransform := client.transform
+	cluster := client.cluster
+	client.mutex.Unlock()
+
+	if queue == nil {
+		return
+	}
+
+	value, err := encodeEntry(newKafkaEntry(entry, cluster), format)
+	if err != nil {
+		logger.Log.Errorf("Failed encoding entry for kafka: %v", err)
+		return
+	}
+
+	// the avro messages keep their schema, only the json messages are transformed
+	if entryTransform != nil && format != shared.KafkaFormatAvro {
+		if value, err = entryTransform.ApplyJson(value); err != nil {
+			logger.Log.Debugf("Failed transforming entry with %s, skipping it: %v", entryTransform, err)
+			return
+		}
+	}
+
+	queue.Push(newQueueItem(entryKey(entry), value))
+}
+
+func (client *client) GetExportQueueStats() *exportqueue.Stats {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+
+	if client.queue == nil {
+		return nil
+	}
+
+	stats := client.queue.GetStats()
+	return &stats
+}
+
+// CheckHealth validates that a broker is reachable, accepts the credentials and serves the topic, writing isn't
+// probed since a published message can't be deleted, nil is returned when kafka isn't configured
+func (client *client) CheckHealth() *shared.SinkHealth {
+	client.mutex.Lock()
+	name := client.name
+	dialer := client.dialer
+	brokers := client.brokers
+	topic := client.topic
+	auth := client.auth
+	authMechanism := client.authMechanism
+	configured := client.writer != nil
+	client.mutex.Unlock()
+
+	if !configured {
+		return nil
+	}
+
+	health := &shared.SinkHealth{Sink: name, Destination: fmt.Sprintf("%s/%s", strings.Join(brokers, ","), topic)}
+	if authMechanism != nil {
+		// the identity is described after dialing, the token of a gcp identity tells its service account
+		defer func() { health.Identity = authMechanism.Identity() }()
+		health.Permissions = cloudAuthPermissions[auth]
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+	defer cancel()
+
+	// the sasl handshake is part of dialing, so a failed dial can't tell connectivity and authentication apart
+	conn, err := dialer.DialContext(ctx, "tcp", brokers[0])
+	if err != nil {
+		health.Error = err.Error()
+		return health
+	}
+	defer conn.Close()
+	health.Connectivity = true
+	health.Authentication = true
+
+	partitions, err := conn.ReadPartitions(topic)
+	if err != nil {
+		health.Error = fmt.Sprintf("failed reading the partitions of topic %s, %v", topic, err)
+		return health
+	}
+	if len(partitions) == 0 {
+		health.Error = fmt.Sprintf("topic %s has no partitions", topic)
+		return health
+	}
+	health.Write = true
+
+	return health
+}
+
+func (client *client) deliver(item []byte) error {
+	client.mutex.Lock()
+	writer := client.writer
+	client.mutex.Unlock()
+
+	if writer == nil {
+		return fmt.Errorf("kafka writer closed")
+	}
+
+	key, value, err := parseQueueItem(item)
+	if err != nil {
+		// a corrupted item would be retried forever
+		logger.Log.Errorf("Dropping a kafka export queue item: %v", err)
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), writeTimeout)
+	defer cancel()
+
+	return writer.WriteMessages(ctx, kafkago.Message{Key: key, Value: value})
+}
+
+func newKafkaEntry(entry *api.Entry, cluster string) *kafkaEntry {
+	return &kafkaEntry{
+		EntryId:         entry.EntryId,
+		Protocol:        entry.Protocol.Name,
+		ProtocolVersion: entry.Protocol.Version,
+		Source:          newKafkaTcp(entry.Source),
+		Destination:     newKafkaTcp(entry.Destination),
+		Namespace:       entry.Namespace,
+		Cluster:         cluster,
+		Outgoing:        entry.Outgoing,
+		Timestamp:       entry.Timestamp,
+		ElapsedTime:     entry.ElapsedTime,
+		Request:         entry.Request,
+		Response:        entry.Response,
+	}
+}
+
+func newKafkaTcp(tcp *api.TCP) kafkaTcp {
+	if tcp == nil {
+		return kafkaTcp{}
+	}
+
+	return kafkaTcp{IP: tcp.IP, Port: tcp.Port, Name: tcp.Name}
+}
+
+func encodeEntry(entry *kafkaEntry, format string) ([]byte, error) {
+	if format == shared.KafkaFormatAvro {
+		return encodeAvroEntry(entry)
+	}
+
+	return json.Marshal(entry)
+}
+
+func entryKey(entry *api.Entry) []byte {
+	if entry.Destination == nil {
+		return nil
+	}
+	if entry.Destination.Name != "" {
+		return []byte(entry.Destination.Name)
+	}
+
+	return []byte(fmt.Sprintf("%s:%s", entry.Destination.IP, entry.Destination.Port))
+}
+
+// the export queue holds single items of a line each, the key is prefixed by its length and the item is base64
+// encoded since avro values and keys may hold new lines
+func newQueueItem(key []byte, value []byte) []byte {
+	item := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(key)+len(value))
+	n := binary.PutUvarint(item, uint64(len(key)))
+	item = append(item[:n], key...)
+	item = append(item, value...)
+
+	encoded := make([]byte, base64.StdEncoding.EncodedLen(len(item)))
+	base64.StdEncoding.Encode(encoded, item)
+	return encoded
+}
+
+func parseQueueItem(encoded []byte) ([]byte, []byte, error) {
+	item := make([]byte, base64.StdEncoding.DecodedLen(len(encoded)))
+	decodedLength, err := base64.StdEncoding.Decode(item, encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+	item = item[:decodedLength]
+
+	keyLength, n := binary.Uvarint(item)
+	if n <= 0 || uint64(len(item)-n) < keyLength {
+		return nil, nil, fmt.Errorf("invalid item of %d bytes", len(item))
+	}
+
+	key := item[n : n+int(keyLength)]
+	if len(key) == 0 {
+		key = nil
+	}
+
+	return key, item[n+int(keyLength):], nil
+}
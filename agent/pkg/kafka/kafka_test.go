@@ -0,0 +1,129 @@
+package kafka
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/shared/cloudauth"
+)
+
+func TestQueueItem(t *testing.T) {
+	for _, key := range [][]byte{nil, []byte("orders.sock-shop")} {
+		item := newQueueItem(key, []byte("{\"entryId\":\"1\"}\n"))
+		if bytes.IndexByte(item, '\n') != -1 {
+			t.Errorf("expected the item to fit a line of the spill file: %v", string(item))
+		}
+
+		key, value, err := parseQueueItem(item)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if string(value) != "{\"entryId\":\"1\"}\n" {
+			t.Errorf("unexpected result - expected: %v, actual: %v", "{\"entryId\":\"1\"}\n", string(value))
+		}
+		if len(key) != 0 && string(key) != "orders.sock-shop" {
+			t.Errorf("unexpected result - expected: %v, actual: %v", "orders.sock-shop", string(key))
+		}
+	}
+
+	if _, _, err := parseQueueItem([]byte(base64.StdEncoding.EncodeToString([]byte{20, 'a'}))); err == nil {
+		t.Errorf("expected an error for a truncated item")
+	}
+}
+
+func TestEncodeAvroEntry(t *testing.T) {
+	entry := &kafkaEntry{
+		EntryId:     "1",
+		Protocol:    "http",
+		Source:      kafkaTcp{IP: "10.0.0.1", Port: "3000", Name: "front-end"},
+		Destination: kafkaTcp{IP: "10.0.0.2", Port: "80", Name: "orders"},
+		Cluster:     "prod-eu",
+		Outgoing:    true,
+		Timestamp:   -1,
+		ElapsedTime: 64,
+		Request:     map[string]interface{}{"method": "GET"},
+	}
+
+	data, err := encodeAvroEntry(entry)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fingerprint := make([]byte, 8)
+	binary.LittleEndian.PutUint64(fingerprint, avroFingerprint([]byte(AvroSchema)))
+	header := append([]byte{0xC3, 0x01}, fingerprint...)
+	if !bytes.HasPrefix(data, header) {
+		t.Fatalf("unexpected result - expected the single object header: %v, actual: %v", header, data[:10])
+	}
+
+	expected := []byte{2, '1', 8, 'h', 't', 't', 'p', 0}
+	expected = append(expected, 16)
+	expected = append(expected, "10.0.0.1"...)
+	expected = append(expected, 8)
+	expected = append(expected, "3000"...)
+	expected = append(expected, 18)
+	expected = append(expected, "front-end"...)
+	expected = append(expected, 16)
+	expected = append(expected, "10.0.0.2"...)
+	expected = append(expected, 4)
+	expected = append(expected, "80"...)
+	expected = append(expected, 12)
+	expected = append(expected, "orders"...)
+	// an empty namespace, the cluster, outgoing, the zig-zag timestamp and elapsed time
+	expected = append(expected, 0, 14)
+	expected = append(expected, "prod-eu"...)
+	expected = append(expected, 1, 1, 0x80, 0x01)
+	expected = append(expected, 32)
+	expected = append(expected, `{"method":"GET"}`...)
+	expected = append(expected, 8)
+	expected = append(expected, "null"...)
+
+	if actual := data[len(header):]; !bytes.Equal(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestAvroFingerprint(t *testing.T) {
+	// the fingerprint of a schema that is only a primitive type, as listed in the avro specification
+	if actual := avroFingerprint([]byte(`"int"`)); actual != 0x7275d51a3f395c8f {
+		t.Errorf("unexpected result - expected: %x, actual: %x", uint64(0x7275d51a3f395c8f), actual)
+	}
+}
+
+func TestMskIamPayload(t *testing.T) {
+	credentials := &cloudauth.AwsCredentials{AccessKeyId: "ASIAEXAMPLE", SecretAccessKey: "secret", SessionToken: "session"}
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	payloadJson, err := getMskIamPayload("b-1.mizu.abc123.c2.kafka.us-east-1.amazonaws.com", credentials, "us-east-1", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var payload map[string]string
+	if err := json.Unmarshal(payloadJson, &payload); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{
+		"version":              "2020_10_22",
+		"action":               "kafka-cluster:Connect",
+		"x-amz-credential":     "ASIAEXAMPLE/20220301/us-east-1/kafka-cluster/aws4_request",
+		"x-amz-date":           "20220301T000000Z",
+		"x-amz-expires":        "900",
+		"x-amz-signedheaders":  "host",
+		"x-amz-security-token": "session",
+	}
+	for name, value := range expected {
+		if payload[name] != value {
+			t.Errorf("unexpected result - expected %s: %v, actual: %v", name, value, payload[name])
+		}
+	}
+	if len(payload["x-amz-signature"]) != 64 || strings.ToLower(payload["x-amz-signature"]) != payload["x-amz-signature"] {
+		t.Errorf("unexpected result - expected a hex signature, actual: %v", payload["x-amz-signature"])
+	}
+}
@@ -0,0 +1,64 @@
+package keepalive
+
+import (
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const pingWriteTimeout = time.Second * 5
+
+// Socket keeps a websocket open through the proxies and NATs that drop idle connections, and closes the connections
+// that silently died, whose reads would otherwise block forever
+type Socket struct {
+	connection  *websocket.Conn
+	idleTimeout time.Duration
+}
+
+// Start pings the connection every ping interval until it's closed, its reads fail once nothing was received for the
+// idle timeout, Touch must be called after every message that's read since only the pongs move the deadline by
+// themselves
+func Start(connection *websocket.Conn, config shared.SocketKeepAliveConfig) *Socket {
+	socket := &Socket{
+		connection:  connection,
+		idleTimeout: time.Duration(config.IdleTimeoutSec) * time.Second,
+	}
+
+	socket.Touch()
+	connection.SetPongHandler(func(string) error {
+		socket.Touch()
+		return nil
+	})
+
+	if config.PingIntervalSec > 0 {
+		go socket.ping(time.Duration(config.PingIntervalSec) * time.Second)
+	}
+
+	return socket
+}
+
+// Touch extends the read deadline of the connection by the idle timeout
+func (socket *Socket) Touch() {
+	if socket.idleTimeout <= 0 {
+		return
+	}
+
+	if err := socket.connection.SetReadDeadline(time.Now().Add(socket.idleTimeout)); err != nil {
+		logger.Log.Debugf("Error extending the read deadline of socket %s: %v", socket.connection.RemoteAddr(), err)
+	}
+}
+
+// ping runs until a ping can't be written, the control messages are safe to write concurrently with the messages
+func (socket *Socket) ping(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := socket.connection.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingWriteTimeout)); err != nil {
+			logger.Log.Debugf("Stopped pinging socket %s: %v", socket.connection.RemoteAddr(), err)
+			return
+		}
+	}
+}
@@ -0,0 +1,86 @@
+package keepalive
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/up9inc/mizu/shared"
+)
+
+// serveSocket keeps the sockets it accepts alive with config, the error of the first failed read is sent to readErrors
+func serveSocket(t *testing.T, config shared.SocketKeepAliveConfig, readErrors chan<- error) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+			return
+		}
+		defer connection.Close()
+
+		socket := Start(connection, config)
+		for {
+			if _, _, err := connection.ReadMessage(); err != nil {
+				readErrors <- err
+				return
+			}
+			socket.Touch()
+		}
+	}))
+}
+
+func dialSocket(t *testing.T, server *httptest.Server) *websocket.Conn {
+	connection, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the pings are answered while reading
+	go func() {
+		for {
+			if _, _, err := connection.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	return connection
+}
+
+func TestIdleSocketIsClosed(t *testing.T) {
+	readErrors := make(chan error, 1)
+	server := serveSocket(t, shared.SocketKeepAliveConfig{IdleTimeoutSec: 1}, readErrors)
+	defer server.Close()
+
+	connection := dialSocket(t, server)
+	defer connection.Close()
+
+	select {
+	case err := <-readErrors:
+		if netErr, ok := err.(net.Error); !ok || !netErr.Timeout() {
+			t.Errorf("unexpected result - expected a timeout, actual: %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Errorf("unexpected result - expected the idle socket to be closed")
+	}
+}
+
+func TestPingedSocketIsKeptOpen(t *testing.T) {
+	readErrors := make(chan error, 1)
+	server := serveSocket(t, shared.SocketKeepAliveConfig{PingIntervalSec: 1, IdleTimeoutSec: 2}, readErrors)
+	defer server.Close()
+
+	connection := dialSocket(t, server)
+	defer connection.Close()
+
+	select {
+	case err := <-readErrors:
+		t.Errorf("unexpected result - expected the socket to be kept open, actual: %v", err)
+	case <-time.After(time.Second * 4):
+	}
+}
@@ -0,0 +1,280 @@
+package lifecycle
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	queueSize      = 100
+	requestTimeout = 10 * time.Second
+)
+
+// a failed post is retried after each of these delays before the event is given up on
+var retryBackoff = []time.Duration{time.Second, 5 * time.Second}
+
+type Stats struct {
+	Sent    int `json:"sent"`
+	Failed  int `json:"failed"`
+	Dropped int `json:"dropped"`
+}
+
+// SessionData is the data of session.started and session.stopped
+type SessionData struct {
+	StartTime int64 `json:"startTime"`
+}
+
+// SnapshotData is the data of snapshot.created, the query and the time range the entries were exported with
+type SnapshotData struct {
+	Entries int    `json:"entries"`
+	Query   string `json:"query"`
+	From    int64  `json:"from"`
+	To      int64  `json:"to"`
+}
+
+// StorageThresholdData is the data of storage.threshold, the stored bytes count the inserted entries, the oldest
+// are evicted once the limit is reached
+type StorageThresholdData struct {
+	StoredBytes      int64 `json:"storedBytes"`
+	MaxBytes         int64 `json:"maxBytes"`
+	ThresholdPercent int   `json:"thresholdPercent"`
+}
+
+// Notifier posts the lifecycle events to the webhooks in the background, in the order they happened
+type Notifier struct {
+	mutex         sync.Mutex
+	config        shared.LifecycleWebhooksConfig
+	namespace     string
+	cluster       string
+	maxBytes      int64
+	storedBytes   int64
+	storageFired  bool
+	startTime     int64
+	events        chan *shared.LifecycleEvent
+	client        *http.Client
+	stats         Stats
+	stop          chan struct{}
+	pendingEvents sync.WaitGroup
+}
+
+var instance *Notifier
+var once sync.Once
+
+func GetInstance() *Notifier {
+	once.Do(func() {
+		instance = &Notifier{}
+	})
+	return instance
+}
+
+// Configure starts posting the events of the cluster, maxBytes is the database size limit the storage threshold is
+// a share of
+func (notifier *Notifier) Configure(config shared.LifecycleWebhooksConfig, namespace string, cluster string, maxBytes int64) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	if notifier.stop != nil {
+		close(notifier.stop)
+		notifier.stop = nil
+	}
+
+	if len(config.Urls) == 0 {
+		logger.Log.Infof("No lifecycle webhook urls were supplied, lifecycle webhooks disabled")
+		return
+	}
+
+	notifier.config = config
+	notifier.namespace = namespace
+	notifier.cluster = cluster
+	notifier.maxBytes = maxBytes
+	notifier.events = make(chan *shared.LifecycleEvent, queueSize)
+	notifier.client = &http.Client{Timeout: requestTimeout}
+	notifier.stop = make(chan struct{})
+
+	go notifier.send(notifier.events, notifier.client, notifier.stop)
+
+	logger.Log.Infof("Posting the lifecycle events to %d webhooks", len(config.Urls))
+}
+
+// Notify queues the event, it never blocks, events are dropped when the webhooks can't keep up
+func (notifier *Notifier) Notify(eventType string, data interface{}) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	notifier.notify(eventType, data)
+}
+
+func (notifier *Notifier) notify(eventType string, data interface{}) {
+	if notifier.stop == nil || !notifier.config.IsEnabled(eventType) {
+		return
+	}
+
+	event := &shared.LifecycleEvent{
+		Type:      eventType,
+		Timestamp: time.Now().UnixNano() / int64(time.Millisecond),
+		Namespace: notifier.namespace,
+		Cluster:   notifier.cluster,
+		Data:      data,
+	}
+
+	notifier.pendingEvents.Add(1)
+	select {
+	case notifier.events <- event:
+	default:
+		notifier.pendingEvents.Done()
+		notifier.stats.Dropped++
+	}
+}
+
+// SessionStarted notifies session.started, startTime is the unix milliseconds the api server started at
+func (notifier *Notifier) SessionStarted(startTime int64) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	notifier.startTime = startTime
+	notifier.notify(shared.LifecycleEventSessionStarted, &SessionData{StartTime: startTime})
+}
+
+// SessionStopped notifies session.stopped and waits up to timeout for the queued events to be posted, the resources
+// of the session are removed right after it
+func (notifier *Notifier) SessionStopped(timeout time.Duration) bool {
+	notifier.mutex.Lock()
+	notifier.notify(shared.LifecycleEventSessionStopped, &SessionData{StartTime: notifier.startTime})
+	notifier.mutex.Unlock()
+
+	return notifier.Flush(timeout)
+}
+
+// Flush waits up to timeout for the queued events to be posted, so an event isn't lost when the session is removed
+// right after it
+func (notifier *Notifier) Flush(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		notifier.pendingEvents.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// EntryStored fires storage.threshold once the stored entries reach the threshold of the database size limit
+func (notifier *Notifier) EntryStored(size int) {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	if notifier.stop == nil || notifier.storageFired || notifier.maxBytes <= 0 {
+		return
+	}
+
+	notifier.storedBytes += int64(size)
+	if notifier.storedBytes*100 < notifier.maxBytes*int64(notifier.config.StorageThresholdPercent) {
+		return
+	}
+
+	notifier.storageFired = true
+	notifier.notify(shared.LifecycleEventStorageThreshold, &StorageThresholdData{
+		StoredBytes:      notifier.storedBytes,
+		MaxBytes:         notifier.maxBytes,
+		ThresholdPercent: notifier.config.StorageThresholdPercent,
+	})
+}
+
+func (notifier *Notifier) GetStats() *Stats {
+	notifier.mutex.Lock()
+	defer notifier.mutex.Unlock()
+
+	if notifier.stop == nil {
+		return nil
+	}
+
+	stats := notifier.stats
+	return &stats
+}
+
+func (notifier *Notifier) send(events <-chan *shared.LifecycleEvent, client *http.Client, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case event := <-events:
+			notifier.mutex.Lock()
+			config := notifier.config
+			notifier.mutex.Unlock()
+
+			payload, err := json.Marshal(event)
+			if err != nil {
+				logger.Log.Errorf("Error marshaling the %s lifecycle event: %v", event.Type, err)
+				notifier.pendingEvents.Done()
+				continue
+			}
+
+			for _, url := range config.Urls {
+				err := postWithRetries(client, url, event.Type, payload, config.Secret)
+
+				notifier.mutex.Lock()
+				if err != nil {
+					notifier.stats.Failed++
+					logger.Log.Warningf("Failed posting the %s lifecycle event to %s: %v", event.Type, url, err)
+				} else {
+					notifier.stats.Sent++
+				}
+				notifier.mutex.Unlock()
+			}
+
+			notifier.pendingEvents.Done()
+		}
+	}
+}
+
+func postWithRetries(client *http.Client, url string, eventType string, payload []byte, secret string) error {
+	err := post(client, url, eventType, payload, secret)
+	for _, delay := range retryBackoff {
+		if err == nil {
+			return nil
+		}
+
+		time.Sleep(delay)
+		err = post(client, url, eventType, payload, secret)
+	}
+
+	return err
+}
+
+func post(client *http.Client, url string, eventType string, payload []byte, secret string) error {
+	request, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(shared.LifecycleEventHeader, eventType)
+	if secret != "" {
+		request.Header.Set(shared.LifecycleSignatureHeader, shared.SignLifecyclePayload(secret, payload))
+	}
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package lifecycle
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+type receivedEvent struct {
+	event     shared.LifecycleEvent
+	header    string
+	signature string
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, func() []receivedEvent) {
+	var mutex sync.Mutex
+	received := make([]receivedEvent, 0)
+
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		body, _ := ioutil.ReadAll(request.Body)
+
+		var event shared.LifecycleEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+		if expected := shared.SignLifecyclePayload("secret", body); request.Header.Get(shared.LifecycleSignatureHeader) != expected {
+			t.Errorf("unexpected signature - expected: %v, actual: %v", expected, request.Header.Get(shared.LifecycleSignatureHeader))
+		}
+
+		mutex.Lock()
+		received = append(received, receivedEvent{event: event, header: request.Header.Get(shared.LifecycleEventHeader)})
+		mutex.Unlock()
+	}))
+
+	return server, func() []receivedEvent {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return append([]receivedEvent{}, received...)
+	}
+}
+
+func TestNotify(t *testing.T) {
+	server, getReceived := newTestServer(t)
+	defer server.Close()
+
+	notifier := &Notifier{}
+	notifier.Configure(shared.LifecycleWebhooksConfig{
+		Urls:                    []string{server.URL},
+		Events:                  []string{shared.LifecycleEventSessionStarted, shared.LifecycleEventStorageThreshold},
+		Secret:                  "secret",
+		StorageThresholdPercent: 80,
+	}, "sock-shop", "prod-eu", 1000)
+
+	notifier.Notify(shared.LifecycleEventSessionStarted, nil)
+	notifier.Notify(shared.LifecycleEventTapperFailed, nil)
+	notifier.EntryStored(500)
+	notifier.EntryStored(300)
+	notifier.EntryStored(300)
+
+	if !notifier.Flush(5 * time.Second) {
+		t.Fatalf("timed out waiting for the events to be posted")
+	}
+
+	received := getReceived()
+	if len(received) != 2 {
+		t.Fatalf("unexpected result - expected: %v, actual: %v", 2, len(received))
+	}
+	if received[0].event.Type != shared.LifecycleEventSessionStarted || received[0].header != shared.LifecycleEventSessionStarted {
+		t.Errorf("unexpected result - expected: %v, actual: %v", shared.LifecycleEventSessionStarted, received[0].event.Type)
+	}
+	if received[0].event.Namespace != "sock-shop" {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "sock-shop", received[0].event.Namespace)
+	}
+	if received[0].event.Cluster != "prod-eu" {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "prod-eu", received[0].event.Cluster)
+	}
+	if received[1].event.Type != shared.LifecycleEventStorageThreshold {
+		t.Errorf("unexpected result - expected: %v, actual: %v", shared.LifecycleEventStorageThreshold, received[1].event.Type)
+	}
+	if data, _ := received[1].event.Data.(map[string]interface{}); data["storedBytes"] != float64(800) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 800, data["storedBytes"])
+	}
+
+	if stats := notifier.GetStats(); stats.Sent != 2 || stats.Failed != 0 || stats.Dropped != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestNotifyFailure(t *testing.T) {
+	retryBackoff = []time.Duration{time.Millisecond}
+
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		attempts++
+		writer.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	notifier := &Notifier{}
+	notifier.Configure(shared.LifecycleWebhooksConfig{Urls: []string{server.URL}}, "", "", 0)
+	notifier.Notify(shared.LifecycleEventSessionStopped, nil)
+
+	if !notifier.Flush(5 * time.Second) {
+		t.Fatalf("timed out waiting for the events to be posted")
+	}
+
+	if attempts != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 2, attempts)
+	}
+	if stats := notifier.GetStats(); stats.Sent != 0 || stats.Failed != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
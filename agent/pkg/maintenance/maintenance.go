@@ -0,0 +1,175 @@
+package maintenance
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/markers"
+)
+
+const (
+	maxWindows        = 100
+	maxWindowDuration = 7 * 24 * time.Hour
+)
+
+// Window is a declared period, like a deploy or a chaos experiment, in which the rules aren't evaluated so the
+// expected failures don't raise alerts, an empty namespace covers the whole cluster
+type Window struct {
+	Id        int    `json:"id"`
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+	Reason    string `json:"reason"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (window *Window) covers(namespace string, timestamp int64) bool {
+	if window.Namespace != "" && window.Namespace != namespace {
+		return false
+	}
+	return timestamp >= window.Start && timestamp < window.End
+}
+
+type Scheduler struct {
+	mutex   sync.Mutex
+	windows []*Window
+	lastId  int
+}
+
+var instance *Scheduler
+var once sync.Once
+
+func GetInstance() *Scheduler {
+	once.Do(func() {
+		instance = &Scheduler{}
+	})
+	return instance
+}
+
+// AddWindow declares a window between start and end (unix milliseconds), a zero start means now, the window is
+// recorded as a timeline marker
+func (s *Scheduler) AddWindow(start int64, end int64, reason string, namespace string) (*Window, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	if start == 0 {
+		start = now
+	}
+	if end <= start {
+		return nil, errors.New("the window must end after it starts")
+	}
+	if end <= now {
+		return nil, errors.New("the window already ended")
+	}
+	if time.Duration(end-start)*time.Millisecond > maxWindowDuration {
+		return nil, fmt.Errorf("the window can't be longer than %v", maxWindowDuration)
+	}
+
+	s.mutex.Lock()
+	s.removeEnded(now)
+	if len(s.windows) >= maxWindows {
+		s.mutex.Unlock()
+		return nil, fmt.Errorf("there can't be more than %d windows", maxWindows)
+	}
+	s.lastId++
+	window := &Window{Id: s.lastId, Start: start, End: end, Reason: reason, Namespace: namespace}
+	s.windows = append(s.windows, window)
+	windowCopy := *window
+	s.mutex.Unlock()
+
+	markers.GetInstance().Record(&markers.Marker{
+		Timestamp:   start,
+		Kind:        markers.KindMaintenance,
+		Namespace:   namespace,
+		Name:        fmt.Sprintf("maintenance-%d", windowCopy.Id),
+		Change:      markers.ChangeMaintenance,
+		Description: fmt.Sprintf("maintenance window until %s", time.Unix(0, end*int64(time.Millisecond)).UTC().Format(time.RFC3339)),
+		Reason:      reason,
+	})
+
+	return &windowCopy, nil
+}
+
+// EndWindow ends the window with id now, or cancels it if it didn't start yet
+func (s *Scheduler) EndWindow(id int) (*Window, error) {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+
+	s.mutex.Lock()
+	var window *Window
+	for i, candidate := range s.windows {
+		if candidate.Id != id || candidate.End <= now {
+			continue
+		}
+
+		if candidate.Start > now {
+			s.windows = append(s.windows[:i], s.windows[i+1:]...)
+		} else {
+			candidate.End = now
+		}
+		windowCopy := *candidate
+		window = &windowCopy
+		break
+	}
+	s.mutex.Unlock()
+
+	if window == nil {
+		return nil, fmt.Errorf("maintenance window %d doesn't exist or already ended", id)
+	}
+
+	if window.End == now {
+		markers.GetInstance().Record(&markers.Marker{
+			Timestamp:   now,
+			Kind:        markers.KindMaintenance,
+			Namespace:   window.Namespace,
+			Name:        fmt.Sprintf("maintenance-%d", window.Id),
+			Change:      markers.ChangeMaintenance,
+			Description: "maintenance window ended early",
+			Reason:      window.Reason,
+		})
+	}
+
+	return window, nil
+}
+
+// GetWindows returns the upcoming windows and the windows that are active or ended in the last minute
+func (s *Scheduler) GetWindows() []*Window {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.removeEnded(time.Now().UnixNano() / int64(time.Millisecond))
+
+	windows := make([]*Window, 0, len(s.windows))
+	for _, window := range s.windows {
+		windowCopy := *window
+		windows = append(windows, &windowCopy)
+	}
+	return windows
+}
+
+// IsActive reports whether the traffic of namespace at timestamp (unix milliseconds) is in a window
+func (s *Scheduler) IsActive(namespace string, timestamp int64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, window := range s.windows {
+		if window.covers(namespace, timestamp) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// removeEnded must be called while holding the mutex, windows are kept for a minute after they end since the
+// entries captured at their end may still be processed
+func (s *Scheduler) removeEnded(now int64) {
+	active := s.windows[:0]
+	for _, window := range s.windows {
+		if window.End+int64(time.Minute/time.Millisecond) > now {
+			active = append(active, window)
+		}
+	}
+	for i := len(active); i < len(s.windows); i++ {
+		s.windows[i] = nil
+	}
+	s.windows = active
+}
@@ -0,0 +1,72 @@
+package maintenance
+
+import (
+	"testing"
+	"time"
+)
+
+func nowMs() int64 {
+	return time.Now().UnixNano() / int64(time.Millisecond)
+}
+
+func TestWindowSuppressesItsNamespace(t *testing.T) {
+	scheduler := &Scheduler{}
+	now := nowMs()
+
+	window, err := scheduler.AddWindow(0, now+60000, "deploy", "checkout")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !scheduler.IsActive("checkout", now+1000) {
+		t.Errorf("expected the window to cover its namespace")
+	}
+	if scheduler.IsActive("payments", now+1000) {
+		t.Errorf("expected the window not to cover other namespaces")
+	}
+	if scheduler.IsActive("checkout", now+120000) {
+		t.Errorf("expected the window not to cover traffic after its end")
+	}
+
+	if _, err := scheduler.EndWindow(window.Id); err != nil {
+		t.Fatal(err)
+	}
+	if scheduler.IsActive("checkout", nowMs()+1000) {
+		t.Errorf("expected an ended window not to cover new traffic")
+	}
+}
+
+func TestUpcomingWindowIsCanceled(t *testing.T) {
+	scheduler := &Scheduler{}
+	now := nowMs()
+
+	window, err := scheduler.AddWindow(now+60000, now+120000, "chaos experiment", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !scheduler.IsActive("any", now+90000) {
+		t.Errorf("expected a cluster-wide window to cover every namespace")
+	}
+
+	if _, err := scheduler.EndWindow(window.Id); err != nil {
+		t.Fatal(err)
+	}
+	if windows := scheduler.GetWindows(); len(windows) != 0 {
+		t.Errorf("unexpected result - expected: %v windows, actual: %v", 0, len(windows))
+	}
+}
+
+func TestInvalidWindows(t *testing.T) {
+	scheduler := &Scheduler{}
+	now := nowMs()
+
+	if _, err := scheduler.AddWindow(now, now-1, "", ""); err == nil {
+		t.Errorf("expected an error for a window that ends before it starts")
+	}
+	if _, err := scheduler.AddWindow(now-120000, now-60000, "", ""); err == nil {
+		t.Errorf("expected an error for a window that already ended")
+	}
+	if _, err := scheduler.EndWindow(1); err == nil {
+		t.Errorf("expected an error for a window that doesn't exist")
+	}
+}
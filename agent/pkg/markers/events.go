@@ -0,0 +1,110 @@
+package markers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/up9inc/mizu/shared/logger"
+
+	corev1 "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func (w *Watcher) watchEventsWithRetry(ctx context.Context) {
+	// events that happened before mizu started are not part of the capture
+	startTime := time.Now()
+
+	for {
+		err := w.watchEvents(ctx, startTime)
+		if ctx.Err() != nil {
+			return
+		}
+		if k8serrors.IsForbidden(err) {
+			logger.Log.Infof("Not watching kubernetes events: %v", err)
+			return
+		}
+
+		logger.Log.Debugf("Kubernetes events watch stopped, retrying in %v: %v", watchRetryInterval, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func (w *Watcher) watchEvents(ctx context.Context, startTime time.Time) error {
+	// empty namespace makes the client watch all namespaces
+	watcher, err := w.clientSet.CoreV1().Events(w.namespace).Watch(ctx, metav1.ListOptions{Watch: true, FieldSelector: "type=" + corev1.EventTypeWarning})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		kubernetesEvent, ok := event.Object.(*corev1.Event)
+		if !ok {
+			return fmt.Errorf("unexpected event watch event: %v", event.Type)
+		}
+
+		if event.Type == watch.Deleted {
+			delete(w.eventCounts, kubernetesEvent.UID)
+			continue
+		}
+
+		// a recurring event is updated with a higher count, every recurrence is recorded once
+		count := kubernetesEvent.Count
+		if count == 0 {
+			count = 1
+		}
+		if previousCount, ok := w.eventCounts[kubernetesEvent.UID]; ok && previousCount >= count {
+			continue
+		}
+		w.eventCounts[kubernetesEvent.UID] = count
+
+		eventTime := getEventTime(kubernetesEvent)
+		if eventTime.Before(startTime) || !isTappedNamespace(kubernetesEvent.Namespace) {
+			continue
+		}
+
+		GetInstance().Record(eventToMarker(kubernetesEvent, eventTime))
+	}
+
+	return errors.New("event watch closed")
+}
+
+func eventToMarker(event *corev1.Event, eventTime time.Time) *Marker {
+	// events of cluster scoped objects, like nodes, are reported in the namespace of the event
+	namespace := event.InvolvedObject.Namespace
+	if namespace == "" {
+		namespace = event.Namespace
+	}
+
+	return &Marker{
+		Timestamp:   eventTime.UnixNano() / int64(time.Millisecond),
+		Kind:        event.InvolvedObject.Kind,
+		Namespace:   namespace,
+		Name:        event.InvolvedObject.Name,
+		Change:      ChangeEvent,
+		Description: event.Message,
+		Reason:      event.Reason,
+	}
+}
+
+func getEventTime(event *corev1.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+	if !event.FirstTimestamp.IsZero() {
+		return event.FirstTimestamp.Time
+	}
+
+	return time.Now()
+}
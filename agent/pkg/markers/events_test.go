@@ -0,0 +1,41 @@
+package markers
+
+import (
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestEventToMarker(t *testing.T) {
+	eventTime := time.Date(2022, 3, 1, 10, 0, 0, 0, time.UTC)
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Pod", Namespace: "default", Name: "web-1"},
+		Reason:         "Unhealthy",
+		Message:        "Readiness probe failed",
+		LastTimestamp:  metav1.NewTime(eventTime),
+	}
+
+	marker := eventToMarker(event, getEventTime(event))
+	if marker.Change != ChangeEvent || marker.Reason != "Unhealthy" || marker.Kind != "Pod" || marker.Name != "web-1" {
+		t.Errorf("unexpected result - expected Unhealthy event of Pod web-1, actual: %+v", marker)
+	}
+	if expected := eventTime.UnixNano() / int64(time.Millisecond); marker.Timestamp != expected {
+		t.Errorf("unexpected result - expected timestamp: %v, actual: %v", expected, marker.Timestamp)
+	}
+}
+
+func TestEventOfClusterScopedObject(t *testing.T) {
+	event := &corev1.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "default"},
+		InvolvedObject: corev1.ObjectReference{Kind: "Node", Name: "node-1"},
+		Reason:         "SystemOOM",
+		EventTime:      metav1.NewMicroTime(time.Now()),
+	}
+
+	if marker := eventToMarker(event, getEventTime(event)); marker.Namespace != "default" {
+		t.Errorf("unexpected result - expected namespace: default, actual: %v", marker.Namespace)
+	}
+}
@@ -0,0 +1,159 @@
+package markers
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/entryid"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/tap/api"
+
+	basenine "github.com/up9inc/basenine/client/go"
+)
+
+const (
+	ChangeImage       = "image"
+	ChangeReplicas    = "replicas"
+	ChangeEvent       = "event"
+	ChangeMaintenance = "maintenance"
+
+	KindDeployment  = "Deployment"
+	KindRollout     = "Rollout"
+	KindMaintenance = "MaintenanceWindow"
+
+	maxRecentMarkers = 1000
+)
+
+// Marker is a change of a workload that may explain a change in the captured traffic, like a new image
+// being rolled out, a deployment being scaled or a warning event such as a failed probe
+type Marker struct {
+	EntryId     string `json:"entryId,omitempty"`
+	Timestamp   int64  `json:"timestamp"`
+	Kind        string `json:"kind"`
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Change      string `json:"change"`
+	Description string `json:"description"`
+	Old         string `json:"old"`
+	New         string `json:"new"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// Recorder keeps the recent markers for the stats api and inserts every marker into the entries database
+type Recorder struct {
+	mutex       sync.Mutex
+	markers     []*Marker
+	connection  *basenine.Connection
+	idGenerator *entryid.Generator
+}
+
+var instance *Recorder
+var once sync.Once
+
+func GetInstance() *Recorder {
+	once.Do(func() {
+		instance = &Recorder{}
+	})
+	return instance
+}
+
+// SetEntryIdScheme must be called before markers are recorded, markers get the same kind of ids as the entries
+func (r *Recorder) SetEntryIdScheme(entryIdScheme string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if entryIdScheme == shared.EntryIdSchemeIndex {
+		r.idGenerator = nil
+	} else {
+		r.idGenerator = entryid.NewGenerator()
+	}
+}
+
+func (r *Recorder) Record(marker *Marker) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.idGenerator != nil {
+		if entryId, err := r.idGenerator.New(time.Unix(0, marker.Timestamp*int64(time.Millisecond))); err != nil {
+			logger.Log.Errorf("Failed generating marker entry id: %v", err)
+		} else {
+			marker.EntryId = entryId
+		}
+	}
+
+	r.markers = append(r.markers, marker)
+	if len(r.markers) > maxRecentMarkers {
+		r.markers[0] = nil
+		r.markers = r.markers[1:]
+	}
+
+	logger.Log.Infof("%s %s/%s %s", marker.Kind, marker.Namespace, marker.Name, marker.Description)
+
+	if err := r.insert(marker); err != nil {
+		logger.Log.Errorf("Failed inserting marker of %s %s/%s: %v", marker.Kind, marker.Namespace, marker.Name, err)
+	}
+}
+
+// GetMarkers returns the recent markers between from and to (unix milliseconds, inclusive), zero means unbounded
+func (r *Recorder) GetMarkers(from int64, to int64) []*Marker {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	markers := make([]*Marker, 0)
+	for _, marker := range r.markers {
+		if from > 0 && marker.Timestamp < from {
+			continue
+		}
+		if to > 0 && marker.Timestamp > to {
+			continue
+		}
+		markers = append(markers, marker)
+	}
+
+	return markers
+}
+
+// insert must be called while holding the mutex
+func (r *Recorder) insert(marker *Marker) error {
+	if r.connection == nil {
+		connection, err := basenine.NewConnection(shared.BasenineHost, shared.BaseninePort)
+		if err != nil {
+			return err
+		}
+		connection.InsertMode()
+		r.connection = connection
+	}
+
+	data, err := json.Marshal(toEntry(marker))
+	if err != nil {
+		return err
+	}
+
+	r.connection.SendText(string(data))
+	return nil
+}
+
+func toEntry(marker *Marker) *api.Entry {
+	return &api.Entry{
+		EntryId:     marker.EntryId,
+		Protocol:    Protocol,
+		Source:      &api.TCP{Name: "kubernetes"},
+		Destination: &api.TCP{Name: marker.Name},
+		Namespace:   marker.Namespace,
+		Timestamp:   marker.Timestamp,
+		StartTime:   time.Unix(0, marker.Timestamp*int64(time.Millisecond)),
+		Request: map[string]interface{}{
+			"kind":        marker.Kind,
+			"name":        marker.Name,
+			"namespace":   marker.Namespace,
+			"change":      marker.Change,
+			"description": marker.Description,
+			"old":         marker.Old,
+			"new":         marker.New,
+			"reason":      marker.Reason,
+		},
+		Response: map[string]interface{}{},
+	}
+}
@@ -0,0 +1,123 @@
+package markers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// Protocol is the pseudo protocol of the marker entries, markers aren't dissected from traffic but are inserted
+// into the database next to the captured entries so they show up in the same timeline
+var Protocol = api.Protocol{
+	Name:            "marker",
+	LongName:        "Kubernetes Workload Marker",
+	Abbreviation:    "K8S",
+	Macro:           "marker",
+	Version:         "1",
+	BackgroundColor: "#326ce5",
+	ForegroundColor: "#ffffff",
+	FontSize:        11,
+	ReferenceLink:   "https://kubernetes.io/docs/concepts/workloads/controllers/deployment/",
+	Ports:           []string{},
+	Priority:        255,
+}
+
+type dissecting string
+
+func (d dissecting) Register(extension *api.Extension) {
+	extension.Protocol = &Protocol
+}
+
+func (d dissecting) Ping() {
+	log.Printf("pong %s", Protocol.Name)
+}
+
+func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, superIdentifier *api.SuperIdentifier, emitter api.Emitter, options *api.TrafficFilteringOptions, reqResMatcher api.RequestResponseMatcher) error {
+	return fmt.Errorf("%s entries are not dissected from traffic", Protocol.Name)
+}
+
+func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	return nil
+}
+
+func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
+	method := ""
+	methodQuery := ""
+	if reason, ok := entry.Request["reason"].(string); ok && reason != "" {
+		method = reason
+		methodQuery = fmt.Sprintf(`request.reason == "%s"`, reason)
+	} else if change, ok := entry.Request["change"].(string); ok {
+		method = change
+		methodQuery = fmt.Sprintf(`request.change == "%s"`, change)
+	}
+
+	summary := ""
+	summaryQuery := ""
+	if name, ok := entry.Request["name"].(string); ok {
+		summary = fmt.Sprintf("%v/%s", entry.Request["kind"], name)
+		summaryQuery = fmt.Sprintf(`request.name == "%s"`, name)
+	}
+
+	return &api.BaseEntry{
+		Id:           entry.Id,
+		EntryId:      entry.EntryId,
+		Protocol:     entry.Protocol,
+		Summary:      summary,
+		SummaryQuery: summaryQuery,
+		Method:       method,
+		MethodQuery:  methodQuery,
+		Timestamp:    entry.Timestamp,
+		Source:       entry.Source,
+		Destination:  entry.Destination,
+		IsOutgoing:   entry.Outgoing,
+	}
+}
+
+func (d dissecting) Represent(request map[string]interface{}, response map[string]interface{}) (object []byte, bodySize int64, err error) {
+	var details []byte
+	details, err = json.Marshal([]api.TableData{
+		{Name: "Kind", Value: request["kind"], Selector: "request.kind"},
+		{Name: "Name", Value: request["name"], Selector: "request.name"},
+		{Name: "Namespace", Value: request["namespace"], Selector: "request.namespace"},
+		{Name: "Change", Value: request["change"], Selector: "request.change"},
+		{Name: "Reason", Value: request["reason"], Selector: "request.reason"},
+		{Name: "Description", Value: request["description"], Selector: "request.description"},
+		{Name: "Previous", Value: request["old"], Selector: "request.old"},
+		{Name: "Current", Value: request["new"], Selector: "request.new"},
+	})
+	if err != nil {
+		return
+	}
+
+	representation := map[string]interface{}{
+		"request": []api.SectionData{
+			{
+				Type:  api.TABLE,
+				Title: "Details",
+				Data:  string(details),
+			},
+		},
+		"response": []api.SectionData{},
+	}
+	object, err = json.Marshal(representation)
+	return
+}
+
+func (d dissecting) Macros() map[string]string {
+	return map[string]string{
+		Protocol.Macro: fmt.Sprintf(`proto.name == "%s"`, Protocol.Name),
+	}
+}
+
+func (d dissecting) NewResponseRequestMatcher() api.RequestResponseMatcher {
+	return nil
+}
+
+var Dissector dissecting
+
+func NewDissector() api.Dissector {
+	return Dissector
+}
@@ -0,0 +1,250 @@
+package markers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/providers/tappedPods"
+	"github.com/up9inc/mizu/shared/logger"
+
+	appsv1 "k8s.io/api/apps/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	restclient "k8s.io/client-go/rest"
+)
+
+const watchRetryInterval = 5 * time.Second
+
+var rolloutsResource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "rollouts"}
+
+type workloadState struct {
+	images   map[string]string
+	replicas int64
+}
+
+// Watcher watches the deployments (and argo rollouts, when installed) of the tapped namespaces and records a
+// marker whenever their images or replica count change, it can also record the warning events of the tapped
+// namespaces
+type Watcher struct {
+	clientSet     *kubernetes.Clientset
+	dynamicClient dynamic.Interface
+	namespace     string
+	states        map[string]*workloadState
+	eventCounts   map[types.UID]int32
+}
+
+func NewFromInCluster(namespace string) (*Watcher, error) {
+	config, err := restclient.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Watcher{clientSet: clientSet, dynamicClient: dynamicClient, namespace: namespace, states: make(map[string]*workloadState), eventCounts: make(map[types.UID]int32)}, nil
+}
+
+func (w *Watcher) Start(ctx context.Context, deploymentMarkers bool, kubernetesEvents bool) {
+	if deploymentMarkers {
+		events := make(chan workloadEvent)
+		go w.watchWithRetry(ctx, KindDeployment, w.watchDeployments, events)
+		go w.watchWithRetry(ctx, KindRollout, w.watchRollouts, events)
+		go w.handleEvents(ctx, events)
+	}
+
+	if kubernetesEvents {
+		go w.watchEventsWithRetry(ctx)
+	}
+}
+
+type workloadEvent struct {
+	kind      string
+	eventType watch.EventType
+	namespace string
+	name      string
+	state     *workloadState
+}
+
+func (w *Watcher) watchWithRetry(ctx context.Context, kind string, watchFunc func(context.Context, chan<- workloadEvent) error, events chan<- workloadEvent) {
+	for {
+		err := watchFunc(ctx, events)
+		if ctx.Err() != nil {
+			return
+		}
+		if k8serrors.IsNotFound(err) || k8serrors.IsForbidden(err) {
+			logger.Log.Infof("Not watching %s markers: %v", kind, err)
+			return
+		}
+
+		logger.Log.Debugf("%s markers watch stopped, retrying in %v: %v", kind, watchRetryInterval, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func (w *Watcher) watchDeployments(ctx context.Context, events chan<- workloadEvent) error {
+	// empty namespace makes the client watch all namespaces
+	watcher, err := w.clientSet.AppsV1().Deployments(w.namespace).Watch(ctx, metav1.ListOptions{Watch: true})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		deployment, ok := event.Object.(*appsv1.Deployment)
+		if !ok {
+			return fmt.Errorf("unexpected deployment watch event: %v", event.Type)
+		}
+
+		state := &workloadState{images: make(map[string]string)}
+		if deployment.Spec.Replicas != nil {
+			state.replicas = int64(*deployment.Spec.Replicas)
+		}
+		for _, container := range deployment.Spec.Template.Spec.Containers {
+			state.images[container.Name] = container.Image
+		}
+
+		events <- workloadEvent{kind: KindDeployment, eventType: event.Type, namespace: deployment.Namespace, name: deployment.Name, state: state}
+	}
+
+	return errors.New("deployment watch closed")
+}
+
+func (w *Watcher) watchRollouts(ctx context.Context, events chan<- workloadEvent) error {
+	watcher, err := w.dynamicClient.Resource(rolloutsResource).Namespace(w.namespace).Watch(ctx, metav1.ListOptions{Watch: true})
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		rollout, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected rollout watch event: %v", event.Type)
+		}
+
+		state := &workloadState{images: make(map[string]string)}
+		state.replicas, _, _ = unstructured.NestedInt64(rollout.Object, "spec", "replicas")
+		containers, _, _ := unstructured.NestedSlice(rollout.Object, "spec", "template", "spec", "containers")
+		for _, container := range containers {
+			if containerMap, ok := container.(map[string]interface{}); ok {
+				name, _ := containerMap["name"].(string)
+				image, _ := containerMap["image"].(string)
+				state.images[name] = image
+			}
+		}
+
+		events <- workloadEvent{kind: KindRollout, eventType: event.Type, namespace: rollout.GetNamespace(), name: rollout.GetName(), state: state}
+	}
+
+	return errors.New("rollout watch closed")
+}
+
+func (w *Watcher) handleEvents(ctx context.Context, events <-chan workloadEvent) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-events:
+			key := fmt.Sprintf("%s/%s/%s", event.kind, event.namespace, event.name)
+			if event.eventType == watch.Deleted {
+				delete(w.states, key)
+				continue
+			}
+
+			// the first event of every workload only describes its current state
+			previous, isKnown := w.states[key]
+			w.states[key] = event.state
+			if !isKnown || !isTappedNamespace(event.namespace) {
+				continue
+			}
+
+			for _, marker := range diffWorkloads(event.kind, event.namespace, event.name, previous, event.state) {
+				GetInstance().Record(marker)
+			}
+		}
+	}
+}
+
+func diffWorkloads(kind string, namespace string, name string, previous *workloadState, current *workloadState) []*Marker {
+	timestamp := time.Now().UnixNano() / int64(time.Millisecond)
+	markers := make([]*Marker, 0)
+
+	containerNames := make([]string, 0, len(current.images))
+	for containerName := range current.images {
+		containerNames = append(containerNames, containerName)
+	}
+	sort.Strings(containerNames)
+
+	for _, containerName := range containerNames {
+		previousImage, ok := previous.images[containerName]
+		if !ok || previousImage == current.images[containerName] {
+			continue
+		}
+
+		markers = append(markers, &Marker{
+			Timestamp:   timestamp,
+			Kind:        kind,
+			Namespace:   namespace,
+			Name:        name,
+			Change:      ChangeImage,
+			Description: fmt.Sprintf("image of container %s changed", containerName),
+			Old:         previousImage,
+			New:         current.images[containerName],
+		})
+	}
+
+	if previous.replicas != current.replicas {
+		verb := "scaled up"
+		if current.replicas < previous.replicas {
+			verb = "scaled down"
+		}
+
+		markers = append(markers, &Marker{
+			Timestamp:   timestamp,
+			Kind:        kind,
+			Namespace:   namespace,
+			Name:        name,
+			Change:      ChangeReplicas,
+			Description: fmt.Sprintf("%s from %d to %d replicas", verb, previous.replicas, current.replicas),
+			Old:         fmt.Sprintf("%d", previous.replicas),
+			New:         fmt.Sprintf("%d", current.replicas),
+		})
+	}
+
+	return markers
+}
+
+func isTappedNamespace(namespace string) bool {
+	pods := tappedPods.Get()
+	// until the tapped pods are known every namespace the agent can see is relevant
+	if len(pods) == 0 {
+		return true
+	}
+
+	for _, pod := range pods {
+		if pod.Namespace == namespace {
+			return true
+		}
+	}
+
+	return false
+}
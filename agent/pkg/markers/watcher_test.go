@@ -0,0 +1,44 @@
+package markers
+
+import (
+	"testing"
+)
+
+func TestDiffWorkloadsImageChange(t *testing.T) {
+	previous := &workloadState{images: map[string]string{"app": "app:1", "sidecar": "proxy:1"}, replicas: 2}
+	current := &workloadState{images: map[string]string{"app": "app:2", "sidecar": "proxy:1"}, replicas: 2}
+
+	markers := diffWorkloads(KindDeployment, "default", "web", previous, current)
+	if len(markers) != 1 {
+		t.Fatalf("unexpected result - expected 1 marker, actual: %v", len(markers))
+	}
+
+	marker := markers[0]
+	if marker.Change != ChangeImage || marker.Old != "app:1" || marker.New != "app:2" {
+		t.Errorf("unexpected result - expected image change from app:1 to app:2, actual: %+v", marker)
+	}
+}
+
+func TestDiffWorkloadsScaled(t *testing.T) {
+	previous := &workloadState{images: map[string]string{"app": "app:1"}, replicas: 3}
+	current := &workloadState{images: map[string]string{"app": "app:1"}, replicas: 1}
+
+	markers := diffWorkloads(KindRollout, "default", "web", previous, current)
+	if len(markers) != 1 {
+		t.Fatalf("unexpected result - expected 1 marker, actual: %v", len(markers))
+	}
+
+	marker := markers[0]
+	if marker.Change != ChangeReplicas || marker.Old != "3" || marker.New != "1" || marker.Kind != KindRollout {
+		t.Errorf("unexpected result - expected rollout scaled from 3 to 1, actual: %+v", marker)
+	}
+}
+
+func TestDiffWorkloadsUnchanged(t *testing.T) {
+	previous := &workloadState{images: map[string]string{"app": "app:1"}, replicas: 1}
+	current := &workloadState{images: map[string]string{"app": "app:1", "added": "added:1"}, replicas: 1}
+
+	if markers := diffWorkloads(KindDeployment, "default", "web", previous, current); len(markers) != 0 {
+		t.Errorf("unexpected result - expected no markers, actual: %v", markers)
+	}
+}
@@ -0,0 +1,277 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+const (
+	// the services seen past this count are summed up under otherService, to bound the number of series
+	maxServices  = 1000
+	otherService = "other"
+
+	// entries with a status from this one on are counted as errors, like the 5xx of http
+	errorStatus = 500
+)
+
+// the upper bounds of the latency histogram buckets, in milliseconds
+var latencyBuckets = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000, 10000}
+
+type serviceKey struct {
+	service  string
+	protocol string
+}
+
+type serviceMetrics struct {
+	requests      uint64
+	errors        uint64
+	latencyCounts []uint64
+	latencySum    int64
+}
+
+// Collector counts the stored entries and the tapper drops, and renders them in the Prometheus text format
+type Collector struct {
+	mutex        sync.Mutex
+	entries      map[string]uint64
+	services     map[serviceKey]*serviceMetrics
+	serviceNames map[string]bool
+	tapperDrops  map[string]shared.TapperDrops
+	sinkEntries  map[string]uint64
+	sinkLags     map[string]int64
+	storeEntries int64
+	storeBytes   int64
+	evicted      map[string]uint64
+	cluster      string
+}
+
+var instance *Collector
+var once sync.Once
+
+func GetInstance() *Collector {
+	once.Do(func() {
+		instance = newCollector()
+	})
+	return instance
+}
+
+func newCollector() *Collector {
+	return &Collector{
+		entries:      make(map[string]uint64),
+		services:     make(map[serviceKey]*serviceMetrics),
+		serviceNames: make(map[string]bool),
+		tapperDrops:  make(map[string]shared.TapperDrops),
+		sinkEntries:  make(map[string]uint64),
+		sinkLags:     make(map[string]int64),
+		evicted:      make(map[string]uint64),
+	}
+}
+
+// PushEntry counts a stored entry of the protocol, the service is the resolved destination of the entry
+func (collector *Collector) PushEntry(protocol string, service string, status int, latencyMs int64) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.entries[protocol]++
+
+	if !collector.serviceNames[service] {
+		if len(collector.serviceNames) >= maxServices {
+			service = otherService
+		}
+		collector.serviceNames[service] = true
+	}
+
+	key := serviceKey{service: service, protocol: protocol}
+	metrics, ok := collector.services[key]
+	if !ok {
+		metrics = &serviceMetrics{latencyCounts: make([]uint64, len(latencyBuckets))}
+		collector.services[key] = metrics
+	}
+
+	metrics.requests++
+	if status >= errorStatus {
+		metrics.errors++
+	}
+
+	// the buckets are cumulative, like prometheus expects them
+	for i, bound := range latencyBuckets {
+		if latencyMs <= bound {
+			metrics.latencyCounts[i]++
+		}
+	}
+	metrics.latencySum += latencyMs
+}
+
+// SetTapperDrops records the tcp streams and the entries a tapper dropped since it started
+func (collector *Collector) SetTapperDrops(nodeName string, drops shared.TapperDrops) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.tapperDrops[nodeName] = drops
+}
+
+// SetSink records the entries a sink of the fan-out streamed and how long after its capture the last one reached it
+func (collector *Collector) SetSink(sink string, entries uint64, lagMs int64) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.sinkEntries[sink] = entries
+	collector.sinkLags[sink] = lagMs
+}
+
+// SetStore records the entries the database retains and the entries evicted from it since the API server started, by
+// the bound they were over
+func (collector *Collector) SetStore(entries int64, bytes int64, evicted map[string]uint64) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.storeEntries = entries
+	collector.storeBytes = bytes
+	collector.evicted = evicted
+}
+
+// SetCluster labels every series with the cluster, so the metrics of several clusters can be told apart
+func (collector *Collector) SetCluster(cluster string) {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	collector.cluster = cluster
+}
+
+func (collector *Collector) Write(writer io.Writer) error {
+	collector.mutex.Lock()
+	defer collector.mutex.Unlock()
+
+	buffered := bufio.NewWriter(writer)
+
+	writeHeader(buffered, "mizu_captured_entries_total", "counter", "The stored entries, by protocol.")
+	for _, protocol := range sortedKeys(collector.entries) {
+		fmt.Fprintf(buffered, "mizu_captured_entries_total%s %d\n", collector.labels("protocol="+quote(protocol)), collector.entries[protocol])
+	}
+
+	keys := make([]serviceKey, 0, len(collector.services))
+	for key := range collector.services {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].service != keys[j].service {
+			return keys[i].service < keys[j].service
+		}
+		return keys[i].protocol < keys[j].protocol
+	})
+
+	writeHeader(buffered, "mizu_service_requests_total", "counter", "The stored entries, by destination service and protocol.")
+	for _, key := range keys {
+		fmt.Fprintf(buffered, "mizu_service_requests_total%s %d\n", collector.labels(key.labels()), collector.services[key].requests)
+	}
+
+	writeHeader(buffered, "mizu_service_errors_total", "counter", "The stored entries with a status of 500 and above, by destination service and protocol.")
+	for _, key := range keys {
+		fmt.Fprintf(buffered, "mizu_service_errors_total%s %d\n", collector.labels(key.labels()), collector.services[key].errors)
+	}
+
+	writeHeader(buffered, "mizu_service_latency_milliseconds", "histogram", "The latency of the stored entries, by destination service and protocol, use histogram_quantile for the percentiles.")
+	for _, key := range keys {
+		metrics := collector.services[key]
+		for i, bound := range latencyBuckets {
+			fmt.Fprintf(buffered, "mizu_service_latency_milliseconds_bucket%s %d\n", collector.labels(fmt.Sprintf("%s,le=\"%d\"", key.labels(), bound)), metrics.latencyCounts[i])
+		}
+		fmt.Fprintf(buffered, "mizu_service_latency_milliseconds_bucket%s %d\n", collector.labels(key.labels()+",le=\"+Inf\""), metrics.requests)
+		fmt.Fprintf(buffered, "mizu_service_latency_milliseconds_sum%s %d\n", collector.labels(key.labels()), metrics.latencySum)
+		fmt.Fprintf(buffered, "mizu_service_latency_milliseconds_count%s %d\n", collector.labels(key.labels()), metrics.requests)
+	}
+
+	nodeNames := make([]string, 0, len(collector.tapperDrops))
+	for nodeName := range collector.tapperDrops {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	writeHeader(buffered, "mizu_tapper_dropped_tcp_streams_total", "counter", "The tcp streams a tapper dropped since it started, by node.")
+	for _, nodeName := range nodeNames {
+		fmt.Fprintf(buffered, "mizu_tapper_dropped_tcp_streams_total%s %d\n", collector.labels("node="+quote(nodeName)), collector.tapperDrops[nodeName].DroppedTcpStreams)
+	}
+	writeHeader(buffered, "mizu_tapper_sampled_out_entries_total", "counter", "The entries a tapper left out by sampling the connections since it started, by node.")
+	for _, nodeName := range nodeNames {
+		fmt.Fprintf(buffered, "mizu_tapper_sampled_out_entries_total%s %d\n", collector.labels("node="+quote(nodeName)), collector.tapperDrops[nodeName].SampledOutEntries)
+	}
+	writeHeader(buffered, "mizu_tapper_rate_limited_entries_total", "counter", "The entries a tapper dropped over the max entries per second of a pod since it started, by node.")
+	for _, nodeName := range nodeNames {
+		fmt.Fprintf(buffered, "mizu_tapper_rate_limited_entries_total%s %d\n", collector.labels("node="+quote(nodeName)), collector.tapperDrops[nodeName].RateLimitedEntries)
+	}
+
+	writeHeader(buffered, "mizu_sink_entries_total", "counter", "The entries streamed to a sink of the fan-out, by sink.")
+	for _, sink := range sortedKeys(collector.sinkEntries) {
+		fmt.Fprintf(buffered, "mizu_sink_entries_total%s %d\n", collector.labels("sink="+quote(sink)), collector.sinkEntries[sink])
+	}
+	writeHeader(buffered, "mizu_sink_lag_milliseconds", "gauge", "How long after its capture the last entry reached a sink of the fan-out, by sink.")
+	for _, sink := range sortedKeys(collector.sinkEntries) {
+		fmt.Fprintf(buffered, "mizu_sink_lag_milliseconds%s %d\n", collector.labels("sink="+quote(sink)), collector.sinkLags[sink])
+	}
+
+	writeHeader(buffered, "mizu_store_entries", "gauge", "The entries the database retains.")
+	fmt.Fprintf(buffered, "mizu_store_entries%s %d\n", collector.labels(""), collector.storeEntries)
+	writeHeader(buffered, "mizu_store_bytes", "gauge", "The bytes of the entries the database retains.")
+	fmt.Fprintf(buffered, "mizu_store_bytes%s %d\n", collector.labels(""), collector.storeBytes)
+	writeHeader(buffered, "mizu_store_evicted_entries_total", "counter", "The entries evicted from the database, by the bound they were over, bytes, entries or age.")
+	for _, reason := range sortedKeys(collector.evicted) {
+		fmt.Fprintf(buffered, "mizu_store_evicted_entries_total%s %d\n", collector.labels("reason="+quote(reason)), collector.evicted[reason])
+	}
+
+	memStats := runtime.MemStats{}
+	runtime.ReadMemStats(&memStats)
+
+	writeHeader(buffered, "mizu_agent_memory_heap_alloc_bytes", "gauge", "The bytes of the allocated heap objects of the API server.")
+	fmt.Fprintf(buffered, "mizu_agent_memory_heap_alloc_bytes%s %d\n", collector.labels(""), memStats.HeapAlloc)
+	writeHeader(buffered, "mizu_agent_memory_sys_bytes", "gauge", "The bytes of memory the API server obtained from the OS.")
+	fmt.Fprintf(buffered, "mizu_agent_memory_sys_bytes%s %d\n", collector.labels(""), memStats.Sys)
+	writeHeader(buffered, "mizu_agent_goroutines", "gauge", "The goroutines of the API server.")
+	fmt.Fprintf(buffered, "mizu_agent_goroutines%s %d\n", collector.labels(""), runtime.NumGoroutine())
+
+	return buffered.Flush()
+}
+
+// labels renders the labels of a series, prefixed by the cluster label when there's a cluster
+func (collector *Collector) labels(labels string) string {
+	if collector.cluster != "" {
+		if labels == "" {
+			labels = "cluster=" + quote(collector.cluster)
+		} else {
+			labels = fmt.Sprintf("cluster=%s,%s", quote(collector.cluster), labels)
+		}
+	}
+	if labels == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("{%s}", labels)
+}
+
+func (key serviceKey) labels() string {
+	return fmt.Sprintf("service=%s,protocol=%s", quote(key.service), quote(key.protocol))
+}
+
+func writeHeader(writer io.Writer, name string, metricType string, help string) {
+	fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// quote escapes a label value as the text format requires
+func quote(value string) string {
+	return fmt.Sprintf("\"%s\"", strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(value))
+}
+
+func sortedKeys(values map[string]uint64) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
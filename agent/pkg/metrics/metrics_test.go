@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+func TestWrite(t *testing.T) {
+	collector := newCollector()
+	collector.PushEntry("http", "orders.shop", 200, 20)
+	collector.PushEntry("http", "orders.shop", 503, 700)
+	collector.PushEntry("redis", "cache.shop", 0, 3)
+	collector.SetTapperDrops("node-1", shared.TapperDrops{DroppedTcpStreams: 7, SampledOutEntries: 90, RateLimitedEntries: 3})
+	collector.SetSink("payments-es", 12, 350)
+	collector.SetStore(40, 8000, map[string]uint64{shared.EvictionReasonAge: 25})
+
+	var buffer bytes.Buffer
+	if err := collector.Write(&buffer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exposition := buffer.String()
+	for _, expected := range []string{
+		"# TYPE mizu_captured_entries_total counter\n",
+		`mizu_captured_entries_total{protocol="http"} 2` + "\n",
+		`mizu_service_requests_total{service="orders.shop",protocol="http"} 2` + "\n",
+		`mizu_service_errors_total{service="orders.shop",protocol="http"} 1` + "\n",
+		`mizu_service_errors_total{service="cache.shop",protocol="redis"} 0` + "\n",
+		`mizu_service_latency_milliseconds_bucket{service="orders.shop",protocol="http",le="25"} 1` + "\n",
+		`mizu_service_latency_milliseconds_bucket{service="orders.shop",protocol="http",le="1000"} 2` + "\n",
+		`mizu_service_latency_milliseconds_bucket{service="orders.shop",protocol="http",le="+Inf"} 2` + "\n",
+		`mizu_service_latency_milliseconds_sum{service="orders.shop",protocol="http"} 720` + "\n",
+		`mizu_tapper_dropped_tcp_streams_total{node="node-1"} 7` + "\n",
+		`mizu_tapper_sampled_out_entries_total{node="node-1"} 90` + "\n",
+		`mizu_tapper_rate_limited_entries_total{node="node-1"} 3` + "\n",
+		`mizu_sink_entries_total{sink="payments-es"} 12` + "\n",
+		`mizu_sink_lag_milliseconds{sink="payments-es"} 350` + "\n",
+		"mizu_store_entries 40\n",
+		"mizu_store_bytes 8000\n",
+		`mizu_store_evicted_entries_total{reason="age"} 25` + "\n",
+		"mizu_agent_goroutines ",
+	} {
+		if !strings.Contains(exposition, expected) {
+			t.Errorf("unexpected result - expected %q in: %s", expected, exposition)
+		}
+	}
+}
+
+func TestWriteLabelsCluster(t *testing.T) {
+	collector := newCollector()
+	collector.SetCluster("prod-eu")
+	collector.PushEntry("http", "orders.shop", 200, 20)
+
+	var buffer bytes.Buffer
+	if err := collector.Write(&buffer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	exposition := buffer.String()
+	for _, expected := range []string{
+		`mizu_captured_entries_total{cluster="prod-eu",protocol="http"} 1` + "\n",
+		`mizu_service_latency_milliseconds_bucket{cluster="prod-eu",service="orders.shop",protocol="http",le="+Inf"} 1` + "\n",
+		`mizu_agent_goroutines{cluster="prod-eu"} `,
+	} {
+		if !strings.Contains(exposition, expected) {
+			t.Errorf("unexpected result - expected %q in: %s", expected, exposition)
+		}
+	}
+}
+
+func TestPushEntryBoundsServices(t *testing.T) {
+	collector := newCollector()
+	for i := 0; i < maxServices+10; i++ {
+		collector.PushEntry("http", fmt.Sprintf("service-%d", i), 200, 1)
+	}
+
+	if actual := collector.services[serviceKey{service: otherService, protocol: "http"}]; actual == nil || actual.requests != 10 {
+		t.Errorf("unexpected result - expected the services past the limit to be counted as %s, actual: %+v", otherService, actual)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	if actual, expected := quote("a\"b\\c\nd"), `"a\"b\\c\nd"`; actual != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
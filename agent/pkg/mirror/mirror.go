@@ -0,0 +1,254 @@
+package mirror
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	// MirroredHeaderName marks mirrored requests, requests carrying it are never mirrored again so tapping the
+	// staging environment as well doesn't create a loop
+	MirroredHeaderName = "X-Mizu-Mirrored"
+
+	queueSize      = 100
+	requestTimeout = 5 * time.Second
+)
+
+// headers that are never forwarded to the staging service, in addition to the redaction done by the tappers
+var strippedHeaders = map[string]bool{
+	"authorization":       true,
+	"proxy-authorization": true,
+	"cookie":              true,
+	"x-api-key":           true,
+	"host":                true,
+	"content-length":      true,
+	"connection":          true,
+	"transfer-encoding":   true,
+	"upgrade":             true,
+	"keep-alive":          true,
+}
+
+type Stats struct {
+	Mirrored        int `json:"mirrored"`
+	SkippedSampling int `json:"skippedSampling"`
+	SkippedRateCap  int `json:"skippedRateCap"`
+	Dropped         int `json:"dropped"`
+	Failed          int `json:"failed"`
+}
+
+type Mirror struct {
+	mutex      sync.Mutex
+	target     *url.URL
+	sampleRate float64
+	limiter    *rateLimiter
+	requests   chan *http.Request
+	client     *http.Client
+	stats      Stats
+	stop       chan struct{}
+}
+
+var instance *Mirror
+var once sync.Once
+
+func GetInstance() *Mirror {
+	once.Do(func() {
+		instance = &Mirror{}
+	})
+	return instance
+}
+
+func (m *Mirror) Configure(config shared.MirrorConfig) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+	m.target = nil
+
+	if config.Url == "" {
+		logger.Log.Infof("No mirror url was supplied, traffic mirroring disabled")
+		return
+	}
+
+	target, err := url.Parse(config.Url)
+	if err != nil || target.Scheme == "" || target.Host == "" {
+		logger.Log.Errorf("Invalid mirror url %s, traffic mirroring disabled", config.Url)
+		return
+	}
+
+	m.target = target
+	m.sampleRate = config.SampleRate
+	m.limiter = newRateLimiter(config.MaxRequestsPerSec)
+	m.requests = make(chan *http.Request, queueSize)
+	m.client = &http.Client{
+		Timeout: requestTimeout,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	m.stop = make(chan struct{})
+
+	go m.send(m.requests, m.client, m.stop)
+
+	logger.Log.Infof("Mirroring %v%% of the http traffic to %s, up to %d requests per second", config.SampleRate*100, target.Host, config.MaxRequestsPerSec)
+}
+
+// PushEntry mirrors a captured http request, it never blocks, requests are dropped when the staging service
+// can't keep up
+func (m *Mirror) PushEntry(request *har.Request) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.target == nil || isMirrored(request) {
+		return
+	}
+
+	if rand.Float64() >= m.sampleRate {
+		m.stats.SkippedSampling++
+		return
+	}
+
+	if !m.limiter.allow(time.Now()) {
+		m.stats.SkippedRateCap++
+		return
+	}
+
+	mirroredRequest, err := buildRequest(m.target, request)
+	if err != nil {
+		logger.Log.Debugf("Failed building mirrored request: %v", err)
+		m.stats.Failed++
+		return
+	}
+
+	select {
+	case m.requests <- mirroredRequest:
+	default:
+		m.stats.Dropped++
+	}
+}
+
+func (m *Mirror) GetStats() *Stats {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	if m.target == nil {
+		return nil
+	}
+
+	stats := m.stats
+	return &stats
+}
+
+func (m *Mirror) send(requests <-chan *http.Request, client *http.Client, stop <-chan struct{}) {
+	for {
+		select {
+		case <-stop:
+			return
+		case request := <-requests:
+			response, err := client.Do(request)
+
+			m.mutex.Lock()
+			if err != nil {
+				m.stats.Failed++
+				if m.stats.Failed == 1 || m.stats.Failed%100 == 0 {
+					logger.Log.Warningf("Failed mirroring requests to %s, %d failures so far: %v", request.URL.Host, m.stats.Failed, err)
+				}
+			} else {
+				m.stats.Mirrored++
+			}
+			m.mutex.Unlock()
+
+			if response != nil {
+				_, _ = io.Copy(ioutil.Discard, response.Body)
+				response.Body.Close()
+			}
+		}
+	}
+}
+
+func isMirrored(request *har.Request) bool {
+	for _, header := range request.Headers {
+		if strings.EqualFold(header.Name, MirroredHeaderName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func buildRequest(target *url.URL, request *har.Request) (*http.Request, error) {
+	capturedUrl, err := url.Parse(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	mirroredUrl := *target
+	mirroredUrl.Path = strings.TrimSuffix(target.Path, "/") + capturedUrl.Path
+	mirroredUrl.RawQuery = capturedUrl.RawQuery
+
+	var body io.Reader
+	if request.PostData.Text != "" {
+		body = bytes.NewBufferString(request.PostData.Text)
+	}
+
+	mirroredRequest, err := http.NewRequest(request.Method, mirroredUrl.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range request.Headers {
+		name := strings.ToLower(header.Name)
+		if strippedHeaders[name] || strings.HasPrefix(name, ":") {
+			continue
+		}
+		mirroredRequest.Header.Add(header.Name, header.Value)
+	}
+	mirroredRequest.Header.Set(MirroredHeaderName, "1")
+
+	if capturedUrl.Host != "" {
+		mirroredRequest.Header.Set("X-Forwarded-Host", capturedUrl.Host)
+	}
+
+	return mirroredRequest, nil
+}
+
+// rateLimiter is a token bucket refilled at maxPerSec tokens per second, with a burst of maxPerSec
+type rateLimiter struct {
+	maxPerSec  float64
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newRateLimiter(maxPerSec int) *rateLimiter {
+	return &rateLimiter{maxPerSec: float64(maxPerSec), tokens: float64(maxPerSec)}
+}
+
+func (l *rateLimiter) allow(now time.Time) bool {
+	if !l.lastRefill.IsZero() {
+		l.tokens += now.Sub(l.lastRefill).Seconds() * l.maxPerSec
+		if l.tokens > l.maxPerSec {
+			l.tokens = l.maxPerSec
+		}
+	}
+	l.lastRefill = now
+
+	if l.tokens < 1 {
+		return false
+	}
+
+	l.tokens--
+	return true
+}
@@ -0,0 +1,114 @@
+package mirror
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/shared"
+)
+
+func newTestRequest() *har.Request {
+	return &har.Request{
+		Method: "POST",
+		URL:    "http://front-end.sock-shop/orders?page=2",
+		Headers: []har.Header{
+			{Name: "Host", Value: "front-end.sock-shop"},
+			{Name: "Authorization", Value: "Bearer secret"},
+			{Name: "Cookie", Value: "session=secret"},
+			{Name: "Content-Type", Value: "application/json"},
+			{Name: "X-Request-Id", Value: "abc"},
+		},
+		PostData: har.PostData{Text: `{"item":"socks"}`},
+	}
+}
+
+func TestBuildRequest(t *testing.T) {
+	target, _ := url.Parse("http://staging-gateway:8080/shadow/")
+
+	request, err := buildRequest(target, newTestRequest())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "http://staging-gateway:8080/shadow/orders?page=2"; request.URL.String() != expected {
+		t.Errorf("unexpected url - expected: %v, actual: %v", expected, request.URL.String())
+	}
+
+	for _, header := range []string{"Authorization", "Cookie"} {
+		if value := request.Header.Get(header); value != "" {
+			t.Errorf("unexpected result - expected %s header to be stripped, actual: %v", header, value)
+		}
+	}
+
+	if request.Header.Get("X-Request-Id") != "abc" || request.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected result - expected other headers to be kept, actual: %v", request.Header)
+	}
+
+	if request.Header.Get(MirroredHeaderName) != "1" {
+		t.Errorf("unexpected result - expected %s header, actual: %v", MirroredHeaderName, request.Header)
+	}
+
+	body, _ := ioutil.ReadAll(request.Body)
+	if string(body) != `{"item":"socks"}` {
+		t.Errorf("unexpected body: %s", body)
+	}
+}
+
+func TestMirroredRequestsAreNotMirroredAgain(t *testing.T) {
+	request := newTestRequest()
+	request.Headers = append(request.Headers, har.Header{Name: "x-mizu-mirrored", Value: "1"})
+
+	if !isMirrored(request) {
+		t.Errorf("unexpected result - expected request to be detected as mirrored")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := newRateLimiter(2)
+	now := time.Now()
+
+	if !limiter.allow(now) || !limiter.allow(now) {
+		t.Errorf("unexpected result - expected the burst to be allowed")
+	}
+
+	if limiter.allow(now) {
+		t.Errorf("unexpected result - expected the rate cap to be reached")
+	}
+
+	if !limiter.allow(now.Add(500 * time.Millisecond)) {
+		t.Errorf("unexpected result - expected a request to be allowed after refill")
+	}
+}
+
+func TestPushEntry(t *testing.T) {
+	received := make(chan *http.Request, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r
+	}))
+	defer server.Close()
+
+	mirror := &Mirror{}
+	mirror.Configure(shared.MirrorConfig{Url: server.URL, SampleRate: 1, MaxRequestsPerSec: 1})
+	defer mirror.Configure(shared.MirrorConfig{})
+
+	mirror.PushEntry(newTestRequest())
+	mirror.PushEntry(newTestRequest())
+
+	select {
+	case request := <-received:
+		if request.URL.Path != "/orders" {
+			t.Errorf("unexpected path: %v", request.URL.Path)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("mirrored request wasn't received")
+	}
+
+	if stats := mirror.GetStats(); stats.SkippedRateCap != 1 {
+		t.Errorf("unexpected result - expected 1 request skipped by the rate cap, actual: %v", stats)
+	}
+}
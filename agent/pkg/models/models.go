@@ -5,6 +5,7 @@ import (
 
 	"github.com/up9inc/mizu/agent/pkg/har"
 	"github.com/up9inc/mizu/agent/pkg/rules"
+	"github.com/up9inc/mizu/agent/pkg/timequery"
 	tapApi "github.com/up9inc/mizu/tap/api"
 
 	basenine "github.com/up9inc/basenine/client/go"
@@ -13,13 +14,74 @@ import (
 )
 
 type EntriesRequest struct {
-	LeftOff   int    `form:"leftOff" validate:"required,min=-1"`
+	LeftOff   int    `form:"leftOff" validate:"min=-1"`
 	Direction int    `form:"direction" validate:"required,oneof='1' '-1'"`
 	Query     string `form:"query"`
 	Limit     int    `form:"limit" validate:"required,min=1"`
 	TimeoutMs int    `form:"timeoutMs" validate:"min=1"`
 }
 
+// MaintenanceWindowRequest declares a maintenance window, its end is either absolute (unix milliseconds) or a
+// duration from its start, like 30m
+type MaintenanceWindowRequest struct {
+	Start     int64  `json:"start"`
+	End       int64  `json:"end"`
+	Duration  string `json:"duration"`
+	Reason    string `json:"reason"`
+	Namespace string `json:"namespace"`
+}
+
+// HarExportRequest selects the entries exported as a HAR log, From and To are unix milliseconds and 0 leaves
+// that side of the time range open
+type HarExportRequest struct {
+	Query     string `form:"query"`
+	Limit     int    `form:"limit" validate:"required,min=1"`
+	From      int64  `form:"from" validate:"min=0"`
+	To        int64  `form:"to" validate:"min=0"`
+	TimeoutMs int    `form:"timeoutMs" validate:"min=0"`
+}
+
+// RateRequest counts the entries of a query with a rate operator, like http and rate("1m", "1h"), the window is
+// counted from its Limit latest entries at most
+type RateRequest struct {
+	Query     string `form:"query" validate:"required"`
+	Limit     int    `form:"limit" validate:"required,min=1"`
+	TimeoutMs int    `form:"timeoutMs" validate:"min=0"`
+}
+
+// RateResponse is the count of the entries in every interval of the window of a rate query, Truncated is set when
+// the window has more entries than the limit of the request, the oldest of them aren't counted
+type RateResponse struct {
+	From      int64                    `json:"from"`
+	To        int64                    `json:"to"`
+	Interval  int64                    `json:"interval"`
+	Intervals []timequery.RateInterval `json:"intervals"`
+	Truncated bool                     `json:"truncated"`
+}
+
+// CompareRequest selects the entries of the stable and the canary workloads over the same time range, From and To
+// are unix milliseconds and 0 leaves that side of the time range open
+type CompareRequest struct {
+	StableQuery string `form:"stableQuery" validate:"required"`
+	CanaryQuery string `form:"canaryQuery" validate:"required"`
+	Limit       int    `form:"limit" validate:"required,min=1"`
+	From        int64  `form:"from" validate:"min=0"`
+	To          int64  `form:"to" validate:"min=0"`
+}
+
+// DailyTrendsRequest selects the daily aggregates of the last Days days, of the service and of the endpoint, like
+// "GET /users/{id}", when they're set
+type DailyTrendsRequest struct {
+	Service  string `form:"service"`
+	Endpoint string `form:"endpoint"`
+	Days     int    `form:"days" validate:"required,min=1,max=56"`
+}
+
+// WeekOverWeekRequest selects the week ending at End, a day like 2022-03-07, today when it's empty
+type WeekOverWeekRequest struct {
+	End string `form:"end"`
+}
+
 type SingleEntryRequest struct {
 	Query string `form:"query"`
 }
@@ -70,6 +132,19 @@ type WebSocketQueryMetadataMessage struct {
 	Data *basenine.Metadata `json:"data,omitempty"`
 }
 
+// StreamConfig is the negotiated streaming of a browser socket, MaxBytesPerSec is 0 when unlimited, and Adaptive
+// streams the summaries of the entries instead of the full entries once they don't fit the bandwidth
+type StreamConfig struct {
+	MaxBytesPerSec int64 `json:"maxBytesPerSec"`
+	FullEntries    bool  `json:"fullEntries"`
+	Adaptive       bool  `json:"adaptive"`
+}
+
+type WebSocketStreamConfigMessage struct {
+	*shared.WebSocketMessageMetadata
+	Data *StreamConfig `json:"data,omitempty"`
+}
+
 type WebSocketStartTimeMessage struct {
 	*shared.WebSocketMessageMetadata
 	Data int64 `json:"data"`
@@ -135,6 +210,16 @@ func CreateWebsocketStartTimeMessage(base int64) ([]byte, error) {
 	return json.Marshal(message)
 }
 
+func CreateWebsocketStreamConfigMessage(base *StreamConfig) ([]byte, error) {
+	message := &WebSocketStreamConfigMessage{
+		WebSocketMessageMetadata: &shared.WebSocketMessageMetadata{
+			MessageType: shared.WebSocketMessageTypeStreamConfig,
+		},
+		Data: base,
+	}
+	return json.Marshal(message)
+}
+
 // ExtendedHAR is the top level object of a HAR log.
 type ExtendedHAR struct {
 	Log *ExtendedLog `json:"log"`
@@ -148,6 +233,8 @@ type ExtendedLog struct {
 	Creator *ExtendedCreator `json:"creator"`
 	// Entries is a list containing requests and responses.
 	Entries []*har.Entry `json:"entries"`
+	// Session holds who started the session the entries were captured in, for auditing.
+	Session *shared.SessionMetadata `json:"_session,omitempty"`
 }
 
 type ExtendedCreator struct {
@@ -0,0 +1,382 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/api"
+	"github.com/up9inc/mizu/agent/pkg/lifecycle"
+	"github.com/up9inc/mizu/agent/pkg/providers/tappedPods"
+	"github.com/up9inc/mizu/agent/pkg/providers/tappers"
+	"github.com/up9inc/mizu/agent/pkg/retention"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/units"
+
+	core "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+const (
+	watchRetryInterval  = 5 * time.Second
+	syncerRetryInterval = 30 * time.Second
+	statusTimeout       = 10 * time.Second
+)
+
+// Operator reconciles the tap of the session of the api server, it runs the tapper syncer the cli runs otherwise and
+// restarts it whenever the spec of the tap changes, so the tappers keep following the pods without the cli
+type Operator struct {
+	provider      *kubernetes.Provider
+	agentConfig   *shared.MizuAgentConfig
+	resourceNames kubernetes.ResourceNames
+	current       *kubernetes.MizuTap // the tap the syncer runs for, nil when there's none
+	cancelSyncer  context.CancelFunc
+}
+
+type tapEvent struct {
+	eventType watch.EventType
+	mizuTap   *kubernetes.MizuTap
+}
+
+type syncerFailure struct {
+	generation int64
+	err        error
+}
+
+func NewFromInCluster(agentConfig *shared.MizuAgentConfig) (*Operator, error) {
+	provider, err := kubernetes.NewProviderInCluster()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Operator{
+		provider:      provider,
+		agentConfig:   agentConfig,
+		resourceNames: kubernetes.GetResourceNames(agentConfig.Session.Id),
+	}, nil
+}
+
+func (operator *Operator) Start(ctx context.Context) {
+	events := make(chan tapEvent)
+	go operator.watchWithRetry(ctx, events)
+	go operator.reconcile(ctx, events)
+}
+
+func (operator *Operator) watchWithRetry(ctx context.Context, events chan<- tapEvent) {
+	for {
+		err := operator.watch(ctx, events)
+		if ctx.Err() != nil {
+			return
+		}
+		if k8serrors.IsForbidden(err) {
+			logger.Log.Errorf("Not reconciling the %s taps, the api server isn't allowed to watch them: %v", operator.agentConfig.MizuResourcesNamespace, err)
+			return
+		}
+
+		// the custom resource definition might only be created after the api server
+		logger.Log.Debugf("Tap watch stopped, retrying in %v: %v", watchRetryInterval, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
+
+func (operator *Operator) watch(ctx context.Context, events chan<- tapEvent) error {
+	watcher, err := operator.provider.WatchMizuTaps(ctx, operator.agentConfig.MizuResourcesNamespace)
+	if err != nil {
+		return err
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		object, ok := event.Object.(*unstructured.Unstructured)
+		if !ok {
+			return fmt.Errorf("unexpected tap watch event: %v", event.Type)
+		}
+
+		// the taps of the other sessions of the namespace are reconciled by their own api servers
+		if object.GetName() != operator.resourceNames.MizuTapName {
+			continue
+		}
+
+		mizuTap, err := kubernetes.MizuTapFromUnstructured(object)
+		if err != nil {
+			logger.Log.Errorf("Error parsing tap %s: %v", object.GetName(), err)
+			continue
+		}
+
+		select {
+		case events <- tapEvent{eventType: event.Type, mizuTap: mizuTap}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	return errors.New("tap watch closed")
+}
+
+func (operator *Operator) reconcile(ctx context.Context, events <-chan tapEvent) {
+	failures := make(chan syncerFailure)
+	var retry <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			operator.stopSyncer()
+			return
+
+		case event := <-events:
+			switch event.eventType {
+			case watch.Added, watch.Modified:
+				// the status updates don't change the generation, neither do the events of a restarted watch
+				if operator.current != nil && operator.current.Generation == event.mizuTap.Generation {
+					continue
+				}
+
+				retry = operator.startTap(ctx, event.mizuTap, failures)
+			case watch.Deleted:
+				retry = nil
+				operator.stopTap(ctx)
+			}
+
+		case failure := <-failures:
+			if operator.current == nil || operator.current.Generation != failure.generation {
+				continue
+			}
+
+			retry = operator.failTap(ctx, failure.err)
+
+		case <-retry:
+			if operator.current != nil {
+				retry = operator.startTap(ctx, operator.current, failures)
+			}
+		}
+	}
+}
+
+// startTap (re)starts the syncer of the tap, it returns when to retry when it failed
+func (operator *Operator) startTap(ctx context.Context, mizuTap *kubernetes.MizuTap, failures chan<- syncerFailure) <-chan time.Time {
+	operator.stopSyncer()
+	operator.current = mizuTap
+	logger.Log.Infof("Reconciling tap %s, generation %d", mizuTap.Name, mizuTap.Generation)
+
+	if mizuTap.Spec.Stopped {
+		logger.Log.Infof("Tap %s is stopped, stopping the tappers", mizuTap.Name)
+		operator.resetTappers(ctx)
+		operator.patchStatus(ctx, mizuTap, kubernetes.MizuTapStatus{
+			Phase:              kubernetes.MizuTapPhaseStopped,
+			ObservedGeneration: mizuTap.Generation,
+		})
+		return nil
+	}
+
+	syncerConfig, err := getTapperSyncerConfig(&mizuTap.Spec, operator.agentConfig, operator.resourceNames)
+	if err != nil {
+		return operator.failTap(ctx, err)
+	}
+
+	if err := api.SetRecordedProtocols(mizuTap.Spec.Protocols); err != nil {
+		return operator.failTap(ctx, err)
+	}
+
+	if mizuTap.Spec.MaxEntriesDBSize != "" {
+		maxEntriesDBSizeBytes, err := units.HumanReadableToBytes(mizuTap.Spec.MaxEntriesDBSize)
+		if err != nil {
+			return operator.failTap(ctx, fmt.Errorf("invalid max entries db size %s: %w", mizuTap.Spec.MaxEntriesDBSize, err))
+		}
+
+		if err := retention.GetInstance().SetMaxBytes(maxEntriesDBSizeBytes); err != nil {
+			return operator.failTap(ctx, fmt.Errorf("failed limiting the entries db size: %w", err))
+		}
+	}
+
+	syncerCtx, cancel := context.WithCancel(ctx)
+	tapperSyncer, err := kubernetes.CreateAndStartMizuTapperSyncer(syncerCtx, operator.provider, *syncerConfig, time.Now())
+	if err != nil {
+		cancel()
+		return operator.failTap(ctx, err)
+	}
+	operator.cancelSyncer = cancel
+
+	go operator.handleSyncerEvents(syncerCtx, tapperSyncer, mizuTap, failures)
+
+	operator.patchStatus(ctx, mizuTap, kubernetes.MizuTapStatus{
+		Phase:              kubernetes.MizuTapPhaseTapping,
+		TappedPods:         len(tapperSyncer.CurrentlyTappedPods),
+		ObservedGeneration: mizuTap.Generation,
+	})
+
+	return nil
+}
+
+// failTap stops the syncer of the current tap and reports the error in its status, the tap is retried unless its spec
+// changes first
+func (operator *Operator) failTap(ctx context.Context, err error) <-chan time.Time {
+	operator.stopSyncer()
+	logger.Log.Errorf("Error reconciling tap %s, retrying in %v: %v", operator.current.Name, syncerRetryInterval, err)
+
+	operator.patchStatus(ctx, operator.current, kubernetes.MizuTapStatus{
+		Phase:              kubernetes.MizuTapPhaseFailed,
+		Message:            err.Error(),
+		ObservedGeneration: operator.current.Generation,
+	})
+
+	return time.After(syncerRetryInterval)
+}
+
+// stopTap stops tapping once the tap is deleted, the tapper daemon set is kept without pods like when mizu starts
+func (operator *Operator) stopTap(ctx context.Context) {
+	operator.stopSyncer()
+	if operator.current == nil {
+		return
+	}
+
+	logger.Log.Infof("Tap %s was deleted, stopping the tappers", operator.current.Name)
+	operator.current = nil
+	operator.resetTappers(ctx)
+}
+
+// resetTappers removes the pods of the tapper daemon set and forgets the tapped pods
+func (operator *Operator) resetTappers(ctx context.Context) {
+	if err := operator.provider.ResetMizuTapperDaemonSet(ctx, operator.agentConfig.MizuResourcesNamespace, operator.resourceNames.TapperDaemonSetName, operator.agentConfig.AgentImage, operator.resourceNames.TapperPodName); err != nil {
+		logger.Log.Errorf("Error resetting the tapper daemon set: %v", err)
+	}
+
+	if err := api.SetRecordedProtocols(nil); err != nil {
+		logger.Log.Errorf("Error resetting the recorded protocols: %v", err)
+	}
+
+	tappedPods.Set([]*shared.PodInfo{})
+	api.BroadcastTappedPodsStatus()
+}
+
+func (operator *Operator) stopSyncer() {
+	if operator.cancelSyncer != nil {
+		operator.cancelSyncer()
+		operator.cancelSyncer = nil
+	}
+}
+
+// handleSyncerEvents reports the changes of the syncer like the cli reports them to the status routes
+func (operator *Operator) handleSyncerEvents(ctx context.Context, tapperSyncer *kubernetes.MizuTapperSyncer, mizuTap *kubernetes.MizuTap, failures chan<- syncerFailure) {
+	for {
+		select {
+		case syncerErr, ok := <-tapperSyncer.ErrorOut:
+			if !ok {
+				return
+			}
+
+			select {
+			case failures <- syncerFailure{generation: mizuTap.Generation, err: syncerErr.OriginalError}:
+			case <-ctx.Done():
+			}
+			return
+
+		case _, ok := <-tapperSyncer.TapPodChangesOut:
+			if !ok {
+				return
+			}
+
+			tappedPods.Set(kubernetes.GetPodInfosForPods(tapperSyncer.CurrentlyTappedPods))
+			tappedPods.SetTapTargets(tapperSyncer.GetNodeToTappedPodMap())
+			api.BroadcastTappedPodsStatus()
+			api.BroadcastTapTargets()
+
+			operator.patchStatus(ctx, mizuTap, kubernetes.MizuTapStatus{
+				Phase:              kubernetes.MizuTapPhaseTapping,
+				TappedPods:         len(tapperSyncer.CurrentlyTappedPods),
+				ObservedGeneration: mizuTap.Generation,
+			})
+
+		case tapperStatus, ok := <-tapperSyncer.TapperStatusChangedOut:
+			if !ok {
+				return
+			}
+
+			tappers.SetStatus(&tapperStatus)
+			api.BroadcastTappedPodsStatus()
+
+			if tapperStatus.Status == string(core.PodFailed) {
+				lifecycle.GetInstance().Notify(shared.LifecycleEventTapperFailed, &tapperStatus)
+			}
+
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (operator *Operator) patchStatus(ctx context.Context, mizuTap *kubernetes.MizuTap, status kubernetes.MizuTapStatus) {
+	ctx, cancel := context.WithTimeout(ctx, statusTimeout)
+	defer cancel()
+
+	if err := operator.provider.PatchMizuTapStatus(ctx, mizuTap.Namespace, mizuTap.Name, status); err != nil {
+		logger.Log.Errorf("Error updating the status of tap %s: %v", mizuTap.Name, err)
+	}
+}
+
+// getTapperSyncerConfig takes the pods to tap from the spec and runs the tappers like the api server was started
+func getTapperSyncerConfig(spec *kubernetes.MizuTapSpec, agentConfig *shared.MizuAgentConfig, resourceNames kubernetes.ResourceNames) (*kubernetes.TapperSyncerConfig, error) {
+	podRegexStr := spec.PodRegex
+	if podRegexStr == "" {
+		podRegexStr = ".*"
+	}
+
+	podRegex, err := regexp.Compile(podRegexStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod regex %s: %w", podRegexStr, err)
+	}
+
+	podLabelSelector, err := labels.Parse(spec.PodLabelSelector)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pod label selector %s: %w", spec.PodLabelSelector, err)
+	}
+
+	targetNamespaces := spec.TargetNamespaces
+	if len(targetNamespaces) == 0 {
+		targetNamespaces = []string{kubernetes.K8sAllNamespaces}
+	}
+
+	targets, err := kubernetes.NormalizeTapTargets(spec.Targets)
+	if err != nil {
+		return nil, fmt.Errorf("invalid targets: %w", err)
+	}
+
+	// the targets without a namespace are in the namespace of the tap
+	targets = kubernetes.ResolveTapTargetsNamespace(targets, agentConfig.MizuResourcesNamespace)
+	if len(targets) > 0 {
+		targetNamespaces = kubernetes.GetTapTargetsNamespaces(targets)
+	}
+
+	return &kubernetes.TapperSyncerConfig{
+		TargetNamespaces:         targetNamespaces,
+		PodFilterRegex:           *podRegex,
+		PodFilterLabelSelector:   podLabelSelector,
+		TapAnnotations:           spec.TapAnnotations,
+		Targets:                  targets,
+		MizuResourcesNamespace:   agentConfig.MizuResourcesNamespace,
+		ResourceNames:            resourceNames,
+		AgentImage:               agentConfig.AgentImage,
+		TapperResources:          agentConfig.TapperResources,
+		ImagePullPolicy:          core.PullPolicy(agentConfig.PullPolicy),
+		LogLevel:                 agentConfig.LogLevel,
+		IgnoredUserAgents:        spec.TrafficFilteringOptions.IgnoredUserAgents,
+		MizuApiFilteringOptions:  spec.TrafficFilteringOptions,
+		MizuServiceAccountExists: true,
+		ServiceMesh:              spec.ServiceMesh,
+		Tls:                      spec.Tls,
+		TapperAuthentication:     agentConfig.TapperAuthentication,
+		TapperScheduling:         spec.TapperScheduling,
+		SocketKeepAlive:          agentConfig.SocketKeepAlive,
+		Relay:                    spec.Relay,
+	}, nil
+}
@@ -0,0 +1,97 @@
+package operator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+)
+
+func TestGetTapperSyncerConfigDefaults(t *testing.T) {
+	agentConfig := &shared.MizuAgentConfig{MizuResourcesNamespace: "mizu", AgentImage: "mizu:latest", PullPolicy: "Always"}
+
+	syncerConfig, err := getTapperSyncerConfig(&kubernetes.MizuTapSpec{}, agentConfig, kubernetes.GetResourceNames(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := []string{kubernetes.K8sAllNamespaces}; !reflect.DeepEqual(syncerConfig.TargetNamespaces, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, syncerConfig.TargetNamespaces)
+	}
+	if !syncerConfig.PodFilterRegex.MatchString("any-pod") {
+		t.Errorf("unexpected result - expected the default regex to match every pod, actual: %v", syncerConfig.PodFilterRegex.String())
+	}
+	if syncerConfig.MizuResourcesNamespace != "mizu" || syncerConfig.AgentImage != "mizu:latest" || syncerConfig.ImagePullPolicy != "Always" {
+		t.Errorf("unexpected result - expected the tappers of the api server config, actual: %+v", syncerConfig)
+	}
+}
+
+func TestGetTapperSyncerConfig(t *testing.T) {
+	spec := &kubernetes.MizuTapSpec{TargetNamespaces: []string{"default", "shop"}, PodRegex: "^front-end", PodLabelSelector: "app=frontend", ServiceMesh: true}
+
+	syncerConfig, err := getTapperSyncerConfig(spec, &shared.MizuAgentConfig{}, kubernetes.GetResourceNames("ab12"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(syncerConfig.TargetNamespaces, spec.TargetNamespaces) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", spec.TargetNamespaces, syncerConfig.TargetNamespaces)
+	}
+	if syncerConfig.PodFilterRegex.MatchString("back-end") || !syncerConfig.PodFilterRegex.MatchString("front-end-7d9f") {
+		t.Errorf("unexpected result - expected regex ^front-end, actual: %v", syncerConfig.PodFilterRegex.String())
+	}
+	if syncerConfig.PodFilterLabelSelector.String() != spec.PodLabelSelector {
+		t.Errorf("unexpected result - expected: %v, actual: %v", spec.PodLabelSelector, syncerConfig.PodFilterLabelSelector.String())
+	}
+	if !syncerConfig.ServiceMesh || syncerConfig.ResourceNames.TapperDaemonSetName != "mizu-tapper-daemon-set-ab12" {
+		t.Errorf("unexpected result - expected the spec and the session of the tap, actual: %+v", syncerConfig)
+	}
+}
+
+func TestGetTapperSyncerConfigInvalidRegex(t *testing.T) {
+	if _, err := getTapperSyncerConfig(&kubernetes.MizuTapSpec{PodRegex: "("}, &shared.MizuAgentConfig{}, kubernetes.GetResourceNames("")); err == nil {
+		t.Errorf("unexpected result - expected an invalid regex error")
+	}
+}
+
+func TestGetTapperSyncerConfigTargets(t *testing.T) {
+	spec := &kubernetes.MizuTapSpec{
+		TargetNamespaces: []string{"default"},
+		Targets:          []kubernetes.TapTarget{{Name: "front-end-7d9f"}, {Namespace: "shop", Kind: "deployment", Name: "cart"}},
+	}
+
+	syncerConfig, err := getTapperSyncerConfig(spec, &shared.MizuAgentConfig{MizuResourcesNamespace: "mizu"}, kubernetes.GetResourceNames(""))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedTargets := []kubernetes.TapTarget{{Namespace: "mizu", Kind: kubernetes.TapTargetKindPod, Name: "front-end-7d9f"}, {Namespace: "shop", Kind: kubernetes.TapTargetKindDeployment, Name: "cart"}}
+	if !reflect.DeepEqual(syncerConfig.Targets, expectedTargets) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expectedTargets, syncerConfig.Targets)
+	}
+	if expected := []string{"mizu", "shop"}; !reflect.DeepEqual(syncerConfig.TargetNamespaces, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, syncerConfig.TargetNamespaces)
+	}
+}
+
+func TestGetTapperSyncerConfigInvalidTargets(t *testing.T) {
+	spec := &kubernetes.MizuTapSpec{Targets: []kubernetes.TapTarget{{Kind: "CronJob", Name: "nightly"}}}
+	if _, err := getTapperSyncerConfig(spec, &shared.MizuAgentConfig{}, kubernetes.GetResourceNames("")); err == nil {
+		t.Errorf("unexpected result - expected an invalid targets error")
+	}
+}
+
+func TestValidateTapSpec(t *testing.T) {
+	if err := ValidateTapSpec(&kubernetes.MizuTapSpec{PodRegex: "^front-end", MaxEntriesDBSize: "200MB"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	for _, spec := range []kubernetes.MizuTapSpec{{PodRegex: "("}, {PodLabelSelector: "app in frontend"}, {MaxEntriesDBSize: "lots"}, {Targets: []kubernetes.TapTarget{{Kind: "deployment"}}}} {
+		var invalidSpecErr *InvalidTapSpecError
+		if err := ValidateTapSpec(&spec); !errors.As(err, &invalidSpecErr) {
+			t.Errorf("unexpected result - expected an invalid spec error for %+v, actual: %v", spec, err)
+		}
+	}
+}
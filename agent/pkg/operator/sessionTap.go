@@ -0,0 +1,105 @@
+package operator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/units"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+var (
+	ErrTapNotFound = errors.New("the session has no tap")
+	ErrTapExists   = errors.New("the session already has a tap")
+)
+
+// SessionTap creates, changes and removes the MizuTap of the session, the operator reconciles the changes like the
+// ones of mizu tap --operator, so the session can be driven without the cli
+type SessionTap interface {
+	GetTap(ctx context.Context) (*kubernetes.MizuTap, error)
+	CreateTap(ctx context.Context, spec kubernetes.MizuTapSpec) (*kubernetes.MizuTap, error)
+	UpdateTap(ctx context.Context, update func(spec *kubernetes.MizuTapSpec)) (*kubernetes.MizuTap, error)
+	RemoveTap(ctx context.Context) error
+}
+
+func (operator *Operator) GetTap(ctx context.Context) (*kubernetes.MizuTap, error) {
+	mizuTap, err := operator.provider.GetMizuTap(ctx, operator.agentConfig.MizuResourcesNamespace, operator.resourceNames.MizuTapName)
+	if k8serrors.IsNotFound(err) {
+		return nil, ErrTapNotFound
+	}
+
+	return mizuTap, err
+}
+
+func (operator *Operator) CreateTap(ctx context.Context, spec kubernetes.MizuTapSpec) (*kubernetes.MizuTap, error) {
+	if _, err := operator.GetTap(ctx); err == nil {
+		return nil, ErrTapExists
+	} else if !errors.Is(err, ErrTapNotFound) {
+		return nil, err
+	}
+
+	return operator.applyTap(ctx, spec)
+}
+
+// UpdateTap changes the spec of the tap with update, like stopping it
+func (operator *Operator) UpdateTap(ctx context.Context, update func(spec *kubernetes.MizuTapSpec)) (*kubernetes.MizuTap, error) {
+	mizuTap, err := operator.GetTap(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update(&mizuTap.Spec)
+	return operator.applyTap(ctx, mizuTap.Spec)
+}
+
+// RemoveTap stops the tappers for good, the entries of the session are kept
+func (operator *Operator) RemoveTap(ctx context.Context) error {
+	if _, err := operator.GetTap(ctx); err != nil {
+		return err
+	}
+
+	return operator.provider.RemoveMizuTap(ctx, operator.agentConfig.MizuResourcesNamespace, operator.resourceNames.MizuTapName)
+}
+
+func (operator *Operator) applyTap(ctx context.Context, spec kubernetes.MizuTapSpec) (*kubernetes.MizuTap, error) {
+	if err := ValidateTapSpec(&spec); err != nil {
+		return nil, err
+	}
+
+	if err := operator.provider.ApplyMizuTap(ctx, operator.agentConfig.MizuResourcesNamespace, operator.resourceNames.MizuTapName, spec); err != nil {
+		return nil, err
+	}
+
+	return operator.GetTap(ctx)
+}
+
+// InvalidTapSpecError is the error of a spec the operator would fail to reconcile
+type InvalidTapSpecError struct {
+	err error
+}
+
+func (e *InvalidTapSpecError) Error() string {
+	return e.err.Error()
+}
+
+func (e *InvalidTapSpecError) Unwrap() error {
+	return e.err
+}
+
+// ValidateTapSpec rejects the specs that would fail when they're reconciled, instead of reporting them in the status
+func ValidateTapSpec(spec *kubernetes.MizuTapSpec) error {
+	if _, err := getTapperSyncerConfig(spec, &shared.MizuAgentConfig{}, kubernetes.GetResourceNames("")); err != nil {
+		return &InvalidTapSpecError{err: err}
+	}
+
+	if spec.MaxEntriesDBSize != "" {
+		if _, err := units.HumanReadableToBytes(spec.MaxEntriesDBSize); err != nil {
+			return &InvalidTapSpecError{err: fmt.Errorf("invalid max entries db size %s: %w", spec.MaxEntriesDBSize, err)}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,257 @@
+package provenance
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+// the open segment is signed at this interval even when it isn't full, so entries don't stay unsigned for long
+const sealInterval = time.Minute
+
+var ErrDisabled = errors.New("entry provenance is disabled")
+
+// Signer hash chains the stored entries in their order of insertion and signs the chain every segment, the signed
+// segments are appended to a file so the chain continues across restarts of the api server
+type Signer struct {
+	mutex        sync.Mutex
+	privateKey   ed25519.PrivateKey
+	segmentSize  int
+	segmentsPath string
+	nextIndex    int
+	previousHash string
+	hash         []byte
+	entryIds     []string
+	stop         chan struct{}
+}
+
+var instance *Signer
+var once sync.Once
+
+func GetInstance() *Signer {
+	once.Do(func() {
+		instance = &Signer{}
+	})
+	return instance
+}
+
+func (s *Signer) Configure(config shared.ProvenanceConfig) {
+	if config.SecretName == "" {
+		logger.Log.Infof("No provenance secret was supplied, signing of the stored entries disabled")
+		return
+	}
+
+	keyPath := shared.ProvenanceKeyDirPath + shared.ProvenanceKeyFileName
+	keyPem, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		logger.Log.Errorf("Failed reading the provenance key %s, signing of the stored entries disabled: %v", keyPath, err)
+		return
+	}
+
+	privateKey, err := shared.ParseProvenancePrivateKey(keyPem)
+	if err != nil {
+		logger.Log.Errorf("Invalid provenance key in secret %s, signing of the stored entries disabled: %v", config.SecretName, err)
+		return
+	}
+
+	if err := s.start(privateKey, config.SegmentSize, shared.DataDirPath+shared.ProvenanceSegmentsFileName); err != nil {
+		logger.Log.Errorf("Failed loading the provenance segments, signing of the stored entries disabled: %v", err)
+		return
+	}
+
+	logger.Log.Infof("Signing the stored entries every %d entries, continuing the chain at segment %d", config.SegmentSize, s.nextIndex)
+}
+
+func (s *Signer) start(privateKey ed25519.PrivateKey, segmentSize int, segmentsPath string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	segments, err := readSegments(segmentsPath)
+	if err != nil {
+		return err
+	}
+
+	s.previousHash = shared.ProvenanceGenesisHash
+	s.nextIndex = 0
+	if len(segments) > 0 {
+		last := segments[len(segments)-1]
+		s.previousHash = last.Hash
+		s.nextIndex = last.Index + 1
+	}
+
+	if s.hash, err = hex.DecodeString(s.previousHash); err != nil {
+		return err
+	}
+
+	s.privateKey = privateKey
+	s.segmentSize = segmentSize
+	s.segmentsPath = segmentsPath
+	s.entryIds = make([]string, 0, segmentSize)
+	s.stop = make(chan struct{})
+
+	go s.sealPeriodically(s.stop)
+
+	return nil
+}
+
+// PushEntry adds the entry to the chain, entryJson must be the entry as it was inserted into the database
+func (s *Signer) PushEntry(entryId string, entryJson []byte) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stop == nil {
+		return
+	}
+
+	// entries are looked up by their id when the chain is verified
+	if entryId == "" {
+		logger.Log.Debugf("Leaving an entry without an id out of the provenance chain")
+		return
+	}
+
+	entryHash, err := shared.ProvenanceEntryHash(entryJson)
+	if err != nil {
+		logger.Log.Errorf("Failed hashing entry %s for the provenance chain: %v", entryId, err)
+		return
+	}
+
+	s.hash = shared.ProvenanceChain(s.hash, entryHash)
+	s.entryIds = append(s.entryIds, entryId)
+
+	if len(s.entryIds) >= s.segmentSize {
+		s.seal()
+	}
+}
+
+func (s *Signer) sealPeriodically(stop chan struct{}) {
+	ticker := time.NewTicker(sealInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			s.mutex.Lock()
+			if len(s.entryIds) > 0 {
+				s.seal()
+			}
+			s.mutex.Unlock()
+		}
+	}
+}
+
+// seal signs the open segment and appends it to the segments file, the caller must hold the mutex
+func (s *Signer) seal() {
+	segment := &shared.ProvenanceSegment{
+		Index:        s.nextIndex,
+		PreviousHash: s.previousHash,
+		Hash:         hex.EncodeToString(s.hash),
+		EntryIds:     s.entryIds,
+		SignedAt:     time.Now().UnixMilli(),
+	}
+	segment.Sign(s.privateKey)
+
+	if err := appendSegment(s.segmentsPath, segment); err != nil {
+		// the entries stay in the open segment and are signed with the next one
+		logger.Log.Errorf("Failed storing provenance segment %d: %v", segment.Index, err)
+		return
+	}
+
+	s.nextIndex++
+	s.previousHash = segment.Hash
+	s.entryIds = make([]string, 0, s.segmentSize)
+}
+
+// GetSegments returns the signed segments, oldest first
+func (s *Signer) GetSegments() ([]*shared.ProvenanceSegment, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stop == nil {
+		return nil, ErrDisabled
+	}
+
+	return readSegments(s.segmentsPath)
+}
+
+// GetUnsignedCount returns the number of chained entries that weren't signed yet
+func (s *Signer) GetUnsignedCount() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.entryIds)
+}
+
+func (s *Signer) Close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.stop == nil {
+		return
+	}
+
+	if len(s.entryIds) > 0 {
+		s.seal()
+	}
+	close(s.stop)
+	s.stop = nil
+}
+
+func readSegments(segmentsPath string) ([]*shared.ProvenanceSegment, error) {
+	file, err := os.Open(segmentsPath)
+	if os.IsNotExist(err) {
+		return make([]*shared.ProvenanceSegment, 0), nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	segments := make([]*shared.ProvenanceSegment, 0)
+	scanner := bufio.NewScanner(file)
+	// a segment lists the ids of all of its entries
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var segment *shared.ProvenanceSegment
+		if err := json.Unmarshal(scanner.Bytes(), &segment); err != nil {
+			return nil, fmt.Errorf("invalid provenance segment after segment %d: %w", len(segments), err)
+		}
+		segments = append(segments, segment)
+	}
+
+	return segments, scanner.Err()
+}
+
+func appendSegment(segmentsPath string, segment *shared.ProvenanceSegment) error {
+	line, err := json.Marshal(segment)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(segmentsPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
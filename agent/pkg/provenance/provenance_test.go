@@ -0,0 +1,93 @@
+package provenance
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+func newTestEntry(i int) (string, []byte) {
+	entryId := fmt.Sprintf("01G0Z9K3V2Q8%04d", i)
+	return entryId, []byte(fmt.Sprintf(`{"id":0,"entryId":"%s","timestamp":%d}`, entryId, 1650000000000+i))
+}
+
+func verifyChain(t *testing.T, segments []*shared.ProvenanceSegment, publicKey ed25519.PublicKey, entries map[string][]byte) {
+	previousHash := shared.ProvenanceGenesisHash
+	for i, segment := range segments {
+		if segment.Index != i || segment.PreviousHash != previousHash {
+			t.Fatalf("unexpected result - segment %d doesn't continue the chain", i)
+		}
+		if !segment.VerifySignature(publicKey) {
+			t.Fatalf("unexpected result - invalid signature of segment %d", i)
+		}
+
+		hash, _ := hex.DecodeString(segment.PreviousHash)
+		for _, entryId := range segment.EntryIds {
+			entryHash, err := shared.ProvenanceEntryHash(entries[entryId])
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			hash = shared.ProvenanceChain(hash, entryHash)
+		}
+		if hex.EncodeToString(hash) != segment.Hash {
+			t.Fatalf("unexpected result - the entries of segment %d don't match its hash", i)
+		}
+
+		previousHash = segment.Hash
+	}
+}
+
+func TestSignerChainsSegments(t *testing.T) {
+	publicKey, privateKey, _ := ed25519.GenerateKey(rand.Reader)
+	segmentsPath := path.Join(t.TempDir(), shared.ProvenanceSegmentsFileName)
+	entries := make(map[string][]byte)
+
+	signer := &Signer{}
+	if err := signer.start(privateKey, 3, segmentsPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i := 0; i < 7; i++ {
+		entryId, entryJson := newTestEntry(i)
+		entries[entryId] = entryJson
+		signer.PushEntry(entryId, entryJson)
+	}
+
+	if unsigned := signer.GetUnsignedCount(); unsigned != 1 {
+		t.Errorf("unexpected result - expected 1 unsigned entry, actual: %v", unsigned)
+	}
+
+	// the open segment is signed on close and a restarted signer continues the chain
+	signer.Close()
+	signer = &Signer{}
+	if err := signer.start(privateKey, 3, segmentsPath); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entryId, entryJson := newTestEntry(7)
+	entries[entryId] = entryJson
+	signer.PushEntry(entryId, entryJson)
+	signer.Close()
+
+	segments, err := readSegments(segmentsPath)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(segments) != 4 {
+		t.Fatalf("unexpected result - expected 4 segments, actual: %v", len(segments))
+	}
+
+	verifyChain(t, segments, publicKey, entries)
+}
+
+func TestSignerDisabled(t *testing.T) {
+	signer := &Signer{}
+	signer.PushEntry(newTestEntry(0))
+
+	if _, err := signer.GetSegments(); err != ErrDisabled {
+		t.Errorf("unexpected result - expected %v, actual: %v", ErrDisabled, err)
+	}
+}
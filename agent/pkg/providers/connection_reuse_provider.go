@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+// connections that didn't carry a request for this long are assumed closed, they are kept only in the totals
+const connectionIdleTimeout = 5 * time.Minute
+
+// ConnectionReuseStats describes how well the clients of a client-service pair reuse their connections, a low
+// reuse ratio means a new connection is opened for (almost) every request
+type ConnectionReuseStats struct {
+	Source                       string  `json:"source"`
+	Destination                  string  `json:"destination"`
+	Connections                  int     `json:"connections"`
+	Requests                     int     `json:"requests"`
+	SingleRequestConnections     int     `json:"singleRequestConnections"`
+	AverageRequestsPerConnection float64 `json:"averageRequestsPerConnection"`
+	ReuseRatio                   float64 `json:"reuseRatio"`
+}
+
+type connectionPairStats struct {
+	source                   string
+	destination              string
+	connections              int
+	requests                 int
+	singleRequestConnections int
+	openConnections          map[string]*openConnection
+}
+
+type openConnection struct {
+	requests int
+	lastSeen time.Time
+}
+
+var (
+	lockConnectionReuse = &sync.Mutex{}
+	connectionPairs     = make(map[string]*connectionPairStats)
+	lastIdlePrune       time.Time
+)
+
+// ConnectionRequestAdded counts an entry on the connection between source and destination, entries whose
+// client port is unknown (e.g. rewritten from X-Forwarded-For) can't be attributed to a connection and are ignored
+func ConnectionRequestAdded(source *tapApi.TCP, destination *tapApi.TCP, timestamp time.Time) {
+	if source == nil || destination == nil || source.Port == "" {
+		return
+	}
+
+	lockConnectionReuse.Lock()
+	defer lockConnectionReuse.Unlock()
+
+	sourceName := getConnectionEndpointName(source, false)
+	destinationName := getConnectionEndpointName(destination, true)
+	pairKey := fmt.Sprintf("%s->%s", sourceName, destinationName)
+	pair, ok := connectionPairs[pairKey]
+	if !ok {
+		pair = &connectionPairStats{source: sourceName, destination: destinationName, openConnections: make(map[string]*openConnection)}
+		connectionPairs[pairKey] = pair
+	}
+
+	connectionKey := fmt.Sprintf("%s:%s->%s:%s", source.IP, source.Port, destination.IP, destination.Port)
+	connection, ok := pair.openConnections[connectionKey]
+	if !ok {
+		connection = &openConnection{}
+		pair.openConnections[connectionKey] = connection
+		pair.connections++
+		pair.singleRequestConnections++
+	} else if connection.requests == 1 {
+		pair.singleRequestConnections--
+	}
+
+	connection.requests++
+	if timestamp.After(connection.lastSeen) {
+		connection.lastSeen = timestamp
+	}
+	pair.requests++
+
+	if time.Since(lastIdlePrune) > connectionIdleTimeout {
+		pruneIdleConnections(time.Now().Add(-connectionIdleTimeout))
+		lastIdlePrune = time.Now()
+	}
+}
+
+// GetConnectionReuseStats returns the pairs with at least minRequests requests, the pairs that reuse their
+// connections the least come first
+func GetConnectionReuseStats(minRequests int) []*ConnectionReuseStats {
+	lockConnectionReuse.Lock()
+	defer lockConnectionReuse.Unlock()
+
+	stats := make([]*ConnectionReuseStats, 0)
+	for _, pair := range connectionPairs {
+		if pair.requests < minRequests || pair.connections == 0 {
+			continue
+		}
+
+		stats = append(stats, &ConnectionReuseStats{
+			Source:                       pair.source,
+			Destination:                  pair.destination,
+			Connections:                  pair.connections,
+			Requests:                     pair.requests,
+			SingleRequestConnections:     pair.singleRequestConnections,
+			AverageRequestsPerConnection: float64(pair.requests) / float64(pair.connections),
+			ReuseRatio:                   1 - float64(pair.connections)/float64(pair.requests),
+		})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].ReuseRatio != stats[j].ReuseRatio {
+			return stats[i].ReuseRatio < stats[j].ReuseRatio
+		}
+		return stats[i].Requests > stats[j].Requests
+	})
+
+	return stats
+}
+
+func ResetConnectionReuseStats() {
+	lockConnectionReuse.Lock()
+	defer lockConnectionReuse.Unlock()
+
+	connectionPairs = make(map[string]*connectionPairStats)
+}
+
+// pruneIdleConnections must be called while holding the lock
+func pruneIdleConnections(idleSince time.Time) {
+	for _, pair := range connectionPairs {
+		for connectionKey, connection := range pair.openConnections {
+			if connection.lastSeen.Before(idleSince) {
+				delete(pair.openConnections, connectionKey)
+			}
+		}
+	}
+}
+
+// getConnectionEndpointName prefers the resolved name, the client port is left out of unresolved sources since
+// every connection of a client has a different one
+func getConnectionEndpointName(endpoint *tapApi.TCP, withPort bool) string {
+	if endpoint.Name != "" {
+		return endpoint.Name
+	}
+	if withPort {
+		return fmt.Sprintf("%s:%s", endpoint.IP, endpoint.Port)
+	}
+	return endpoint.IP
+}
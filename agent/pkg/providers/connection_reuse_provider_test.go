@@ -0,0 +1,61 @@
+package providers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/providers"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+func TestConnectionReuseStats(t *testing.T) {
+	providers.ResetConnectionReuseStats()
+	now := time.Now()
+	service := &tapApi.TCP{Name: "service.default", IP: "10.0.0.1", Port: "80"}
+
+	// the first client reuses a single connection, the second opens a connection per request
+	for i := 0; i < 4; i++ {
+		providers.ConnectionRequestAdded(&tapApi.TCP{Name: "reusing.default", IP: "10.0.0.2", Port: "40000"}, service, now)
+	}
+	for _, port := range []string{"40001", "40002", "40003", "40004"} {
+		providers.ConnectionRequestAdded(&tapApi.TCP{Name: "hammering.default", IP: "10.0.0.3", Port: port}, service, now)
+	}
+
+	stats := providers.GetConnectionReuseStats(0)
+	if len(stats) != 2 {
+		t.Fatalf("unexpected result - expected: %v pairs, actual: %v", 2, len(stats))
+	}
+
+	hammering := stats[0]
+	if hammering.Source != "hammering.default" || hammering.Connections != 4 || hammering.SingleRequestConnections != 4 || hammering.ReuseRatio != 0 {
+		t.Errorf("unexpected stats of the hammering client: %+v", hammering)
+	}
+
+	reusing := stats[1]
+	if reusing.Source != "reusing.default" || reusing.Connections != 1 || reusing.SingleRequestConnections != 0 || reusing.AverageRequestsPerConnection != 4 || reusing.ReuseRatio != 0.75 {
+		t.Errorf("unexpected stats of the reusing client: %+v", reusing)
+	}
+}
+
+func TestConnectionReuseStatsMinRequests(t *testing.T) {
+	providers.ResetConnectionReuseStats()
+	providers.ConnectionRequestAdded(&tapApi.TCP{IP: "10.0.0.2", Port: "40000"}, &tapApi.TCP{IP: "10.0.0.1", Port: "80"}, time.Now())
+
+	if stats := providers.GetConnectionReuseStats(2); len(stats) != 0 {
+		t.Errorf("unexpected result - expected: %v pairs, actual: %v", 0, len(stats))
+	}
+
+	stats := providers.GetConnectionReuseStats(1)
+	if len(stats) != 1 || stats[0].Source != "10.0.0.2" || stats[0].Destination != "10.0.0.1:80" {
+		t.Errorf("unexpected result: %+v", stats)
+	}
+}
+
+func TestConnectionReuseStatsUnknownClientPort(t *testing.T) {
+	providers.ResetConnectionReuseStats()
+	providers.ConnectionRequestAdded(&tapApi.TCP{IP: "10.0.0.2"}, &tapApi.TCP{IP: "10.0.0.1", Port: "80"}, time.Now())
+
+	if stats := providers.GetConnectionReuseStats(0); len(stats) != 0 {
+		t.Errorf("unexpected result - expected: %v pairs, actual: %v", 0, len(stats))
+	}
+}
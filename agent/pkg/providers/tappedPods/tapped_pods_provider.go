@@ -9,6 +9,7 @@ import (
 	"github.com/up9inc/mizu/agent/pkg/utils"
 	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/logger"
+	core "k8s.io/api/core/v1"
 )
 
 const FilePath = shared.DataDirPath + "tapped-pods.json"
@@ -17,6 +18,7 @@ var (
 	lock       = &sync.Mutex{}
 	syncOnce   sync.Once
 	tappedPods []*shared.PodInfo
+	tapTargets map[string][]core.Pod // the tapped pods of every node, nil until the tapper syncer reports them
 )
 
 func Get() []*shared.PodInfo {
@@ -41,6 +43,23 @@ func Set(tappedPodsToSet []*shared.PodInfo) {
 	}
 }
 
+// SetTapTargets sets the pods the tapper of every node captures, with their addresses
+func SetTapTargets(nodeToTappedPodMap map[string][]core.Pod) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	tapTargets = nodeToTappedPodMap
+}
+
+// GetTapTargets returns the pods the tapper of nodeName captures, false when the node has no tapped pods
+func GetTapTargets(nodeName string) ([]core.Pod, bool) {
+	lock.Lock()
+	defer lock.Unlock()
+
+	pods, ok := tapTargets[nodeName]
+	return pods, ok
+}
+
 func GetTappedPodsStatus() []shared.TappedPodStatus {
 	tappedPodsStatus := make([]shared.TappedPodStatus, 0)
 	for _, pod := range Get() {
@@ -0,0 +1,126 @@
+package tappers
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	// ClockSkewThreshold is the skew above which a node is flagged and the timestamps of its entries are corrected,
+	// smaller differences are within the noise of the heartbeat latency
+	ClockSkewThreshold = 100 * time.Millisecond
+
+	clockSkewSamples = 10
+)
+
+type clockSkewSamplesWindow struct {
+	offsets       []int64
+	lastHeartbeat int64
+	isSkewed      bool
+}
+
+var (
+	lockClockSkew = &sync.Mutex{}
+	clockSkews    = make(map[string]*clockSkewSamplesWindow)
+)
+
+// HeartbeatReceived records the difference between the clock of a tapper and the clock of the api server, both
+// timestamps are unix milliseconds
+func HeartbeatReceived(nodeName string, tapperTimestamp int64, receivedTimestamp int64) {
+	lockClockSkew.Lock()
+	defer lockClockSkew.Unlock()
+
+	window, ok := clockSkews[nodeName]
+	if !ok {
+		window = &clockSkewSamplesWindow{}
+		clockSkews[nodeName] = window
+	}
+
+	window.offsets = append(window.offsets, tapperTimestamp-receivedTimestamp)
+	if len(window.offsets) > clockSkewSamples {
+		window.offsets = window.offsets[1:]
+	}
+	window.lastHeartbeat = receivedTimestamp
+
+	skew := time.Duration(maxOffset(window.offsets)) * time.Millisecond
+	if isSkewed(skew) != window.isSkewed {
+		window.isSkewed = isSkewed(skew)
+		if window.isSkewed {
+			logger.Log.Warningf("Clock of node %s is skewed by %v, correcting the timestamps of its entries", nodeName, skew)
+		} else {
+			logger.Log.Infof("Clock of node %s is no longer skewed", nodeName)
+		}
+	}
+}
+
+// GetClockSkew returns how far ahead the clock of the tapper is, the offset of every heartbeat also includes its
+// latency, which only makes the offset smaller, so the largest offset is the closest to the actual skew
+func GetClockSkew(nodeName string) time.Duration {
+	lockClockSkew.Lock()
+	defer lockClockSkew.Unlock()
+
+	window, ok := clockSkews[nodeName]
+	if !ok || len(window.offsets) == 0 {
+		return 0
+	}
+
+	return time.Duration(maxOffset(window.offsets)) * time.Millisecond
+}
+
+// GetClockSkewCorrection returns the duration that should be subtracted from the timestamps captured by the tapper,
+// it's zero unless the node is skewed
+func GetClockSkewCorrection(nodeName string) time.Duration {
+	skew := GetClockSkew(nodeName)
+	if isSkewed(skew) {
+		return skew
+	}
+
+	return 0
+}
+
+func GetClockSkews() []*shared.TapperClockSkew {
+	lockClockSkew.Lock()
+	nodeNames := make([]string, 0, len(clockSkews))
+	for nodeName := range clockSkews {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	lockClockSkew.Unlock()
+	sort.Strings(nodeNames)
+
+	tapperClockSkews := make([]*shared.TapperClockSkew, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		skew := GetClockSkew(nodeName)
+
+		lockClockSkew.Lock()
+		lastHeartbeat := clockSkews[nodeName].lastHeartbeat
+		lockClockSkew.Unlock()
+
+		tapperClockSkews = append(tapperClockSkews, &shared.TapperClockSkew{
+			NodeName:      nodeName,
+			SkewMs:        skew.Milliseconds(),
+			IsSkewed:      isSkewed(skew),
+			LastHeartbeat: lastHeartbeat,
+		})
+	}
+
+	return tapperClockSkews
+}
+
+func isSkewed(skew time.Duration) bool {
+	return skew >= ClockSkewThreshold || skew <= -ClockSkewThreshold
+}
+
+func maxOffset(offsets []int64) int64 {
+	max := offsets[0]
+	for _, offset := range offsets[1:] {
+		if offset > max {
+			max = offset
+		}
+	}
+
+	return max
+}
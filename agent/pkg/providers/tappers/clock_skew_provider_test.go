@@ -0,0 +1,44 @@
+package tappers_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/providers/tappers"
+)
+
+func TestClockSkewIgnoresLatency(t *testing.T) {
+	// the tapper clock is 500ms ahead, every heartbeat takes a different time to arrive
+	for _, latency := range []int64{30, 2, 10, 80} {
+		tappers.HeartbeatReceived("skewed-node", 1000500, 1000000+latency)
+	}
+
+	if skew := tappers.GetClockSkew("skewed-node"); skew != 498*time.Millisecond {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 498*time.Millisecond, skew)
+	}
+	if correction := tappers.GetClockSkewCorrection("skewed-node"); correction != 498*time.Millisecond {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 498*time.Millisecond, correction)
+	}
+}
+
+func TestClockSkewBelowThreshold(t *testing.T) {
+	tappers.HeartbeatReceived("synced-node", 1000000, 1000005)
+
+	if correction := tappers.GetClockSkewCorrection("synced-node"); correction != 0 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 0, correction)
+	}
+
+	for _, clockSkew := range tappers.GetClockSkews() {
+		if clockSkew.NodeName == "synced-node" && clockSkew.IsSkewed {
+			t.Errorf("unexpected result - expected synced-node not to be skewed, actual: %+v", clockSkew)
+		}
+	}
+}
+
+func TestClockSkewBehind(t *testing.T) {
+	tappers.HeartbeatReceived("behind-node", 1000000, 1002000)
+
+	if correction := tappers.GetClockSkewCorrection("behind-node"); correction != -2000*time.Millisecond {
+		t.Errorf("unexpected result - expected: %v, actual: %v", -2000*time.Millisecond, correction)
+	}
+}
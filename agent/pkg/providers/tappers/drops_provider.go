@@ -0,0 +1,42 @@
+package tappers
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+var (
+	lockDrops = &sync.Mutex{}
+	drops     = make(map[string]shared.TapperDrops)
+)
+
+// SetDrops records the drops of the tapper of the node, the counters of its latest heartbeat
+func SetDrops(nodeName string, tapperDrops shared.TapperDrops) {
+	lockDrops.Lock()
+	defer lockDrops.Unlock()
+
+	drops[nodeName] = tapperDrops
+}
+
+func GetDrops() []*shared.TapperDropsStatus {
+	lockDrops.Lock()
+	defer lockDrops.Unlock()
+
+	nodeNames := make([]string, 0, len(drops))
+	for nodeName := range drops {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	tapperDrops := make([]*shared.TapperDropsStatus, 0, len(nodeNames))
+	for _, nodeName := range nodeNames {
+		tapperDrops = append(tapperDrops, &shared.TapperDropsStatus{
+			NodeName:    nodeName,
+			TapperDrops: drops[nodeName],
+		})
+	}
+
+	return tapperDrops
+}
@@ -0,0 +1,226 @@
+package querycache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+// Result is the result of a query, Open is true when entries stored later may change it, like a page of the newest
+// entries, and a Partial result, like of a timed out query, is returned but not cached
+type Result struct {
+	Data    [][]byte
+	Meta    []byte
+	Open    bool
+	Partial bool
+}
+
+type Stats struct {
+	Results     int `json:"results"`
+	Hits        int `json:"hits"`
+	Misses      int `json:"misses"`
+	Shared      int `json:"shared"`
+	Invalidated int `json:"invalidated"`
+}
+
+type cachedResult struct {
+	key        string
+	result     *Result
+	generation uint64
+	storedAt   time.Time
+}
+
+type call struct {
+	key     string
+	done    chan struct{}
+	result  *Result
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Cache keeps the results of the recent queries so refreshes and viewers of the same page don't scan the database
+// again. Every stored entry may match a query, so it invalidates the open results, the results of older pages stay
+// until their ttl, which covers the entries the database drops once it's full
+type Cache struct {
+	mutex      sync.Mutex
+	maxResults int
+	ttl        time.Duration
+	generation uint64
+	results    map[string]*list.Element
+	lru        *list.List
+	calls      map[string]*call
+	stats      Stats
+}
+
+var instance *Cache
+var once sync.Once
+
+func GetInstance() *Cache {
+	once.Do(func() {
+		instance = &Cache{}
+	})
+	return instance
+}
+
+// Configure clears the cache, a config without max results disables caching
+func (cache *Cache) Configure(config shared.QueryCacheConfig) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	cache.maxResults = config.MaxResults
+	cache.ttl = time.Duration(config.TtlSec) * time.Second
+	cache.results = make(map[string]*list.Element)
+	cache.lru = list.New()
+	cache.calls = make(map[string]*call)
+	cache.stats = Stats{}
+
+	if cache.maxResults <= 0 {
+		logger.Log.Infof("Query cache disabled")
+		return
+	}
+
+	logger.Log.Infof("Caching the results of up to %d queries for %v", cache.maxResults, cache.ttl)
+}
+
+// Key is the key of a page of the results of a query
+func Key(query string, leftOff int, direction int, limit int) string {
+	return fmt.Sprintf("%d:%d:%d:%s", leftOff, direction, limit, query)
+}
+
+// EntryAdded invalidates the open results
+func (cache *Cache) EntryAdded() {
+	cache.mutex.Lock()
+	cache.generation++
+	cache.mutex.Unlock()
+}
+
+// Fetch returns the cached result of the key or else the result of fetch, calls of the same key at the same time
+// share a single fetch which is canceled once all of them are canceled
+func (cache *Cache) Fetch(ctx context.Context, key string, fetch func(ctx context.Context) (*Result, error)) (*Result, error) {
+	cache.mutex.Lock()
+
+	if cache.maxResults <= 0 {
+		cache.mutex.Unlock()
+		return fetch(ctx)
+	}
+
+	if result := cache.get(key); result != nil {
+		cache.stats.Hits++
+		cache.mutex.Unlock()
+		return result, nil
+	}
+
+	if existingCall, ok := cache.calls[key]; ok {
+		cache.stats.Shared++
+		existingCall.waiters++
+		cache.mutex.Unlock()
+		return cache.wait(ctx, existingCall)
+	}
+
+	cache.stats.Misses++
+	fetchCtx, cancel := context.WithCancel(context.Background())
+	newCall := &call{key: key, done: make(chan struct{}), waiters: 1, cancel: cancel}
+	cache.calls[key] = newCall
+	generation := cache.generation
+	calls := cache.calls
+	cache.mutex.Unlock()
+
+	go func() {
+		newCall.result, newCall.err = fetch(fetchCtx)
+		cancel()
+
+		cache.mutex.Lock()
+		// the cache may have been configured again during the fetch
+		if calls[key] == newCall {
+			delete(calls, key)
+		}
+		if newCall.err == nil && !newCall.result.Partial && cache.maxResults > 0 {
+			// the result is stored with the generation it started at, so entries stored during the fetch invalidate it
+			cache.put(&cachedResult{key: key, result: newCall.result, generation: generation, storedAt: time.Now()})
+		}
+		cache.mutex.Unlock()
+
+		close(newCall.done)
+	}()
+
+	return cache.wait(ctx, newCall)
+}
+
+// wait returns the result of the call unless ctx is canceled first, the last caller to be canceled cancels the call
+func (cache *Cache) wait(ctx context.Context, c *call) (*Result, error) {
+	select {
+	case <-c.done:
+		return c.result, c.err
+	case <-ctx.Done():
+		cache.mutex.Lock()
+		c.waiters--
+		if c.waiters == 0 {
+			c.cancel()
+			// the callers that come next start a new fetch
+			if cache.calls[c.key] == c {
+				delete(cache.calls, c.key)
+			}
+		}
+		cache.mutex.Unlock()
+		return nil, ctx.Err()
+	}
+}
+
+func (cache *Cache) GetStats() *Stats {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	if cache.maxResults <= 0 {
+		return nil
+	}
+
+	stats := cache.stats
+	stats.Results = cache.lru.Len()
+	return &stats
+}
+
+// get must be called while holding the mutex
+func (cache *Cache) get(key string) *Result {
+	element, ok := cache.results[key]
+	if !ok {
+		return nil
+	}
+
+	cached := element.Value.(*cachedResult)
+	if cached.result.Open && cached.generation != cache.generation {
+		cache.stats.Invalidated++
+		cache.remove(element)
+		return nil
+	}
+	if time.Since(cached.storedAt) > cache.ttl {
+		cache.remove(element)
+		return nil
+	}
+
+	cache.lru.MoveToFront(element)
+	return cached.result
+}
+
+// put must be called while holding the mutex
+func (cache *Cache) put(cached *cachedResult) {
+	if element, ok := cache.results[cached.key]; ok {
+		cache.remove(element)
+	}
+
+	cache.results[cached.key] = cache.lru.PushFront(cached)
+	for cache.lru.Len() > cache.maxResults {
+		cache.remove(cache.lru.Back())
+	}
+}
+
+// remove must be called while holding the mutex
+func (cache *Cache) remove(element *list.Element) {
+	cache.lru.Remove(element)
+	delete(cache.results, element.Value.(*cachedResult).key)
+}
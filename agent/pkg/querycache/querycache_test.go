@@ -0,0 +1,220 @@
+package querycache
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+func newCache(maxResults int) *Cache {
+	cache := &Cache{}
+	cache.Configure(shared.QueryCacheConfig{MaxResults: maxResults, TtlSec: 60})
+	return cache
+}
+
+// waitFor waits for the stats of the cache to meet the condition, like for callers to reach the cache
+func waitFor(t *testing.T, cache *Cache, condition func(stats *Stats) bool) {
+	for start := time.Now(); !condition(cache.GetStats()); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("timed out waiting for the cache, stats: %+v", cache.GetStats())
+		}
+	}
+}
+
+func countingFetch(fetches *int, result Result) func(ctx context.Context) (*Result, error) {
+	return func(ctx context.Context) (*Result, error) {
+		*fetches++
+		fetched := result
+		return &fetched, nil
+	}
+}
+
+func TestFetchCachesClosedResults(t *testing.T) {
+	cache := newCache(10)
+	fetches := 0
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.Fetch(context.Background(), "closed", countingFetch(&fetches, Result{})); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		cache.EntryAdded()
+	}
+
+	if fetches != 1 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 1, fetches)
+	}
+	if stats := cache.GetStats(); stats.Hits != 2 || stats.Misses != 1 || stats.Results != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestFetchInvalidatesOpenResults(t *testing.T) {
+	cache := newCache(10)
+	fetches := 0
+	fetch := countingFetch(&fetches, Result{Open: true})
+
+	_, _ = cache.Fetch(context.Background(), "open", fetch)
+	_, _ = cache.Fetch(context.Background(), "open", fetch)
+	if fetches != 1 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 1, fetches)
+	}
+
+	cache.EntryAdded()
+	_, _ = cache.Fetch(context.Background(), "open", fetch)
+	if fetches != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 2, fetches)
+	}
+	if stats := cache.GetStats(); stats.Invalidated != 1 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestFetchSkipsPartialResults(t *testing.T) {
+	cache := newCache(10)
+	fetches := 0
+	fetch := countingFetch(&fetches, Result{Partial: true})
+
+	_, _ = cache.Fetch(context.Background(), "partial", fetch)
+	_, _ = cache.Fetch(context.Background(), "partial", fetch)
+	if fetches != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 2, fetches)
+	}
+}
+
+func TestFetchEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newCache(2)
+	fetches := 0
+	fetch := countingFetch(&fetches, Result{})
+
+	_, _ = cache.Fetch(context.Background(), "a", fetch)
+	_, _ = cache.Fetch(context.Background(), "b", fetch)
+	_, _ = cache.Fetch(context.Background(), "a", fetch)
+	_, _ = cache.Fetch(context.Background(), "c", fetch)
+	_, _ = cache.Fetch(context.Background(), "a", fetch)
+	if fetches != 3 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 3, fetches)
+	}
+
+	_, _ = cache.Fetch(context.Background(), "b", fetch)
+	if fetches != 4 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 4, fetches)
+	}
+}
+
+func TestFetchSharesConcurrentFetches(t *testing.T) {
+	cache := newCache(10)
+	var mutex sync.Mutex
+	fetches := 0
+	release := make(chan struct{})
+
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			_, _ = cache.Fetch(context.Background(), "shared", func(ctx context.Context) (*Result, error) {
+				mutex.Lock()
+				fetches++
+				mutex.Unlock()
+				<-release
+				return &Result{}, nil
+			})
+		}()
+	}
+
+	waitFor(t, cache, func(stats *Stats) bool { return stats.Misses+stats.Shared == 5 })
+	close(release)
+	waitGroup.Wait()
+
+	if fetches != 1 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 1, fetches)
+	}
+}
+
+func TestFetchDisabled(t *testing.T) {
+	cache := newCache(0)
+	fetches := 0
+
+	for i := 0; i < 2; i++ {
+		if _, err := cache.Fetch(context.Background(), "disabled", countingFetch(&fetches, Result{})); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if fetches != 2 || cache.GetStats() != nil {
+		t.Errorf("unexpected result - expected every query to be fetched, actual fetches: %v", fetches)
+	}
+}
+
+func TestFetchCancelsAbandonedFetches(t *testing.T) {
+	cache := newCache(10)
+	canceled := make(chan struct{})
+	fetch := func(ctx context.Context) (*Result, error) {
+		<-ctx.Done()
+		close(canceled)
+		return nil, ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		_, err := cache.Fetch(ctx, "abandoned", fetch)
+		errs <- err
+	}()
+
+	waitFor(t, cache, func(stats *Stats) bool { return stats.Misses == 1 })
+	cancel()
+
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("unexpected result - expected: %v, actual: %v", context.Canceled, err)
+	}
+
+	select {
+	case <-canceled:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the fetch to be canceled")
+	}
+}
+
+func TestFetchKeepsFetchesOfOtherCallers(t *testing.T) {
+	cache := newCache(10)
+	release := make(chan struct{})
+	fetch := func(ctx context.Context) (*Result, error) {
+		select {
+		case <-release:
+			return &Result{}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceledErrs := make(chan error)
+	go func() {
+		_, err := cache.Fetch(ctx, "kept", fetch)
+		canceledErrs <- err
+	}()
+
+	waitFor(t, cache, func(stats *Stats) bool { return stats.Misses == 1 })
+
+	results := make(chan error)
+	go func() {
+		_, err := cache.Fetch(context.Background(), "kept", fetch)
+		results <- err
+	}()
+
+	waitFor(t, cache, func(stats *Stats) bool { return stats.Shared == 1 })
+
+	cancel()
+	if err := <-canceledErrs; err != context.Canceled {
+		t.Errorf("unexpected result - expected: %v, actual: %v", context.Canceled, err)
+	}
+
+	close(release)
+	if err := <-results; err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
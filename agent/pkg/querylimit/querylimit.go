@@ -0,0 +1,198 @@
+package querylimit
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	basenine "github.com/up9inc/basenine/client/go"
+	"github.com/up9inc/mizu/agent/pkg/timequery"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var ErrTooManyQueries = errors.New("too many queries are running, try again later")
+
+type Stats struct {
+	MaxConcurrent int `json:"maxConcurrent"`
+	MaxTimeoutSec int `json:"maxTimeoutSec"`
+	Running       int `json:"running"`
+	Waiting       int `json:"waiting"`
+	Queries       int `json:"queries"`
+	Rejected      int `json:"rejected"`
+	Canceled      int `json:"canceled"`
+	TimedOut      int `json:"timedOut"`
+}
+
+// Limiter runs the queries of the database in a limited number of slots, so expensive queries can't starve the
+// inserts of the entries and the queries of the other viewers
+type Limiter struct {
+	mutex      sync.Mutex
+	slots      chan struct{}
+	maxTimeout time.Duration
+	stats      Stats
+}
+
+var instance *Limiter
+var once sync.Once
+
+func GetInstance() *Limiter {
+	once.Do(func() {
+		instance = &Limiter{}
+	})
+	return instance
+}
+
+// Configure sets the limits, 0 concurrent queries or 0 timeout don't limit them
+func (limiter *Limiter) Configure(config shared.QueryLimitsConfig) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	limiter.slots = nil
+	if config.MaxConcurrent > 0 {
+		limiter.slots = make(chan struct{}, config.MaxConcurrent)
+	}
+	limiter.maxTimeout = time.Duration(config.MaxTimeoutSec) * time.Second
+	limiter.stats = Stats{MaxConcurrent: config.MaxConcurrent, MaxTimeoutSec: config.MaxTimeoutSec}
+
+	logger.Log.Infof("Limiting the queries to %d concurrent queries of up to %ds, 0 is unlimited", config.MaxConcurrent, config.MaxTimeoutSec)
+}
+
+// Timeout returns the timeout of a query that requested the timeout
+func (limiter *Limiter) Timeout(requested time.Duration) time.Duration {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if limiter.maxTimeout > 0 && (requested <= 0 || requested > limiter.maxTimeout) {
+		return limiter.maxTimeout
+	}
+	return requested
+}
+
+// Run runs the query with the rest of its timeout once a slot is free, it returns when the query is done or when ctx
+// is canceled, like when the client disconnects. The database can't abort a running query, so the query keeps its
+// slot until it's done and its timeout bounds it
+func (limiter *Limiter) Run(ctx context.Context, timeout time.Duration, query func(timeout time.Duration) error) error {
+	timeout = limiter.Timeout(timeout)
+	startTime := time.Now()
+
+	limiter.mutex.Lock()
+	slots := limiter.slots
+	limiter.stats.Waiting++
+	limiter.mutex.Unlock()
+
+	err := limiter.acquire(ctx, slots, timeout)
+
+	limiter.mutex.Lock()
+	limiter.stats.Waiting--
+	switch {
+	case errors.Is(err, ErrTooManyQueries):
+		limiter.stats.Rejected++
+	case err != nil:
+		limiter.stats.Canceled++
+	default:
+		limiter.stats.Running++
+		limiter.stats.Queries++
+	}
+	limiter.mutex.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		remaining := timeout
+		if timeout > 0 {
+			remaining -= time.Since(startTime)
+		}
+		queryErr := query(remaining)
+
+		limiter.mutex.Lock()
+		limiter.stats.Running--
+		if timeout > 0 && time.Since(startTime) >= timeout {
+			limiter.stats.TimedOut++
+		}
+		limiter.mutex.Unlock()
+
+		if slots != nil {
+			<-slots
+		}
+		done <- queryErr
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		limiter.mutex.Lock()
+		limiter.stats.Canceled++
+		limiter.mutex.Unlock()
+		return ctx.Err()
+	}
+}
+
+// acquire waits for a slot up to the timeout, a query that can't start in time would have no time to run
+func (limiter *Limiter) acquire(ctx context.Context, slots chan struct{}, timeout time.Duration) error {
+	if slots == nil {
+		return ctx.Err()
+	}
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	select {
+	case slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-expired:
+		return ErrTooManyQueries
+	}
+}
+
+// Fetch fetches the entries of the query from the database in a slot, the time operators of the query are expanded
+// and a forward scan of a time window starts at the window
+func (limiter *Limiter) Fetch(ctx context.Context, leftOff int, direction int, query string, limit int, timeout time.Duration) ([][]byte, []byte, error) {
+	expanded, err := timequery.Expand(query, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	windowLeftOff := leftOff
+	if direction == 1 && expanded.From > 0 {
+		if indexed := timequery.GetIndex().LeftOff(expanded.From); indexed > windowLeftOff {
+			windowLeftOff = indexed
+		}
+	}
+
+	var data [][]byte
+	var meta []byte
+
+	err = limiter.Run(ctx, timeout, func(timeout time.Duration) error {
+		var fetchErr error
+		data, meta, fetchErr = basenine.Fetch(shared.BasenineHost, shared.BaseninePort, windowLeftOff, direction, expanded.Query, limit, timeout)
+		// the index may be ahead of a database that restarted, the scan starts over without it
+		if fetchErr == nil && len(data) == 0 && windowLeftOff != leftOff {
+			data, meta, fetchErr = basenine.Fetch(shared.BasenineHost, shared.BaseninePort, leftOff, direction, expanded.Query, limit, timeout)
+		}
+		return fetchErr
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data, meta, nil
+}
+
+func (limiter *Limiter) GetStats() Stats {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	return limiter.stats
+}
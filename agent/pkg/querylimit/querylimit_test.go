@@ -0,0 +1,97 @@
+package querylimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+func newLimiter(maxConcurrent int, maxTimeoutSec int) *Limiter {
+	limiter := &Limiter{}
+	limiter.Configure(shared.QueryLimitsConfig{MaxConcurrent: maxConcurrent, MaxTimeoutSec: maxTimeoutSec})
+	return limiter
+}
+
+func TestTimeout(t *testing.T) {
+	limiter := newLimiter(1, 30)
+
+	tests := []struct {
+		requested time.Duration
+		expected  time.Duration
+	}{
+		{0, 30 * time.Second},
+		{3 * time.Second, 3 * time.Second},
+		{time.Minute, 30 * time.Second},
+	}
+
+	for _, test := range tests {
+		if actual := limiter.Timeout(test.requested); actual != test.expected {
+			t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+		}
+	}
+
+	if actual := newLimiter(1, 0).Timeout(time.Minute); actual != time.Minute {
+		t.Errorf("unexpected result - expected: %v, actual: %v", time.Minute, actual)
+	}
+}
+
+func TestRunRejectsQueriesOverTheLimit(t *testing.T) {
+	limiter := newLimiter(1, 30)
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	go func() {
+		_ = limiter.Run(context.Background(), time.Second, func(timeout time.Duration) error {
+			close(started)
+			<-release
+			return nil
+		})
+	}()
+	<-started
+
+	err := limiter.Run(context.Background(), 50*time.Millisecond, func(timeout time.Duration) error {
+		t.Errorf("unexpected query over the limit")
+		return nil
+	})
+	if err != ErrTooManyQueries {
+		t.Errorf("unexpected result - expected: %v, actual: %v", ErrTooManyQueries, err)
+	}
+
+	close(release)
+
+	if err := limiter.Run(context.Background(), time.Second, func(timeout time.Duration) error { return nil }); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if stats := limiter.GetStats(); stats.Queries != 2 || stats.Rejected != 1 || stats.Running != 0 {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestRunReturnsWhenCanceled(t *testing.T) {
+	limiter := newLimiter(1, 30)
+	release := make(chan struct{})
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errs := make(chan error)
+	go func() {
+		errs <- limiter.Run(ctx, 10*time.Second, func(timeout time.Duration) error {
+			<-release
+			return nil
+		})
+	}()
+
+	cancel()
+
+	select {
+	case err := <-errs:
+		if err != context.Canceled {
+			t.Errorf("unexpected result - expected: %v, actual: %v", context.Canceled, err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for the canceled query to return")
+	}
+}
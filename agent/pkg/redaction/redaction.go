@@ -0,0 +1,171 @@
+package redaction
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/shared"
+	tapApi "github.com/up9inc/mizu/tap/api"
+	httpExt "github.com/up9inc/mizu/tap/extensions/http"
+)
+
+const (
+	messageRequest  = "request"
+	messageResponse = "response"
+)
+
+// Test redacts an http entry of a HAR log like the tappers would with the options, and returns the values the
+// redaction changes
+func Test(entry *har.Entry, options *tapApi.TrafficFilteringOptions) *shared.RedactionTestResult {
+	result := &shared.RedactionTestResult{
+		Method: entry.Request.Method,
+		Url:    entry.Request.URL,
+		Status: entry.Response.Status,
+		Values: make([]shared.RedactedValue, 0),
+	}
+
+	_, _, requestText := entry.Request.PostData.B64Decoded()
+	requestBody := []byte(requestText)
+	request, err := http.NewRequest(entry.Request.Method, entry.Request.URL, bytes.NewReader(requestBody))
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	for _, header := range entry.Request.Headers {
+		request.Header.Add(header.Name, header.Value)
+	}
+
+	_, _, responseText := entry.Response.Content.B64Decoded()
+	responseBody := []byte(responseText)
+	response := &http.Response{
+		StatusCode: entry.Response.Status,
+		Header:     make(http.Header),
+		Body:       ioutil.NopCloser(bytes.NewReader(responseBody)),
+	}
+	for _, header := range entry.Response.Headers {
+		response.Header.Add(header.Name, header.Value)
+	}
+
+	requestHeaders := request.Header.Clone()
+	query := request.URL.Query()
+	responseHeaders := response.Header.Clone()
+
+	httpExt.Redact(&tapApi.OutputChannelItem{
+		Protocol: tapApi.Protocol{Name: "http"},
+		Pair: &tapApi.RequestResponsePair{
+			Request:  tapApi.GenericMessage{IsRequest: true, Payload: tapApi.HTTPPayload{Type: tapApi.TypeHttpRequest, Data: request}},
+			Response: tapApi.GenericMessage{Payload: tapApi.HTTPPayload{Type: tapApi.TypeHttpResponse, Data: response}},
+		},
+	}, options)
+
+	redactedRequestBody, _ := ioutil.ReadAll(request.Body)
+	redactedResponseBody, _ := ioutil.ReadAll(response.Body)
+
+	result.Values = append(result.Values, diffValues(messageRequest, tapApi.RedactionInHeader, requestHeaders, request.Header)...)
+	result.Values = append(result.Values, diffValues(messageRequest, tapApi.RedactionInQuery, query, request.URL.Query())...)
+	result.Values = append(result.Values, diffBodies(messageRequest, requestBody, redactedRequestBody)...)
+	result.Values = append(result.Values, diffValues(messageResponse, tapApi.RedactionInHeader, responseHeaders, response.Header)...)
+	result.Values = append(result.Values, diffBodies(messageResponse, responseBody, redactedResponseBody)...)
+
+	return result
+}
+
+// diffValues compares the headers or the query params before and after the redaction, by name and position
+func diffValues(message string, in string, before map[string][]string, after map[string][]string) []shared.RedactedValue {
+	names := make([]string, 0, len(before))
+	for name := range before {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make([]shared.RedactedValue, 0)
+	for _, name := range names {
+		redactedValues := after[name]
+		for i, value := range before[name] {
+			if i >= len(redactedValues) {
+				values = append(values, shared.RedactedValue{Message: message, In: in, Name: name, Value: value, Removed: true})
+			} else if redactedValues[i] != value {
+				values = append(values, shared.RedactedValue{Message: message, In: in, Name: name, Value: value, Redacted: redactedValues[i]})
+			}
+		}
+	}
+
+	return values
+}
+
+// diffBodies compares the fields of JSON bodies, the redaction rewrites them so their text can change without any
+// value changing, the other bodies are compared as a whole
+func diffBodies(message string, before []byte, after []byte) []shared.RedactedValue {
+	if bytes.Equal(before, after) {
+		return nil
+	}
+
+	var beforeValue, afterValue interface{}
+	if json.Unmarshal(before, &beforeValue) == nil && json.Unmarshal(after, &afterValue) == nil {
+		values := make([]shared.RedactedValue, 0)
+		diffJson(message, "$", beforeValue, afterValue, &values)
+		return values
+	}
+
+	return []shared.RedactedValue{{Message: message, In: tapApi.RedactionInBody, Value: string(before), Redacted: string(after)}}
+}
+
+func diffJson(message string, path string, before interface{}, after interface{}, values *[]shared.RedactedValue) {
+	switch typedBefore := before.(type) {
+	case map[string]interface{}:
+		if typedAfter, ok := after.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(typedBefore))
+			for key := range typedBefore {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				childPath := jsonPathField(path, key)
+				if childAfter, ok := typedAfter[key]; ok {
+					diffJson(message, childPath, typedBefore[key], childAfter, values)
+				} else {
+					*values = append(*values, shared.RedactedValue{Message: message, In: tapApi.RedactionInBody, Name: childPath, Value: jsonText(typedBefore[key]), Removed: true})
+				}
+			}
+			return
+		}
+	case []interface{}:
+		if typedAfter, ok := after.([]interface{}); ok && len(typedAfter) == len(typedBefore) {
+			for i := range typedBefore {
+				diffJson(message, path+"["+strconv.Itoa(i)+"]", typedBefore[i], typedAfter[i], values)
+			}
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(before, after) {
+		*values = append(*values, shared.RedactedValue{Message: message, In: tapApi.RedactionInBody, Name: path, Value: jsonText(before), Redacted: jsonText(after)})
+	}
+}
+
+// jsonPathField is the path of a field in the syntax of the body redaction rules
+func jsonPathField(path string, key string) string {
+	if key != "" && !strings.ContainsAny(key, ".[]'\" ") {
+		return path + "." + key
+	}
+
+	return path + "['" + key + "']"
+}
+
+// jsonText is a string as it is and any other value as JSON
+func jsonText(value interface{}) string {
+	if text, ok := value.(string); ok {
+		return text
+	}
+
+	text, _ := json.Marshal(value)
+	return string(text)
+}
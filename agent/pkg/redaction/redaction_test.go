@@ -0,0 +1,63 @@
+package redaction
+
+import (
+	"testing"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/shared"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+func TestRedaction(t *testing.T) {
+	entry := &har.Entry{
+		Request: har.Request{
+			Method: "POST",
+			URL:    "http://catalogue/items?token=abc&page=2",
+			Headers: []har.Header{
+				{Name: "Authorization", Value: "Bearer abc"},
+				{Name: "Cookie", Value: "session=abc"},
+				{Name: "Content-Type", Value: "application/json"},
+			},
+			PostData: har.PostData{MimeType: "application/json", Text: `{"password": "hunter2", "name": "bob"}`},
+		},
+		Response: har.Response{
+			Status:  200,
+			Headers: []har.Header{{Name: "Content-Type", Value: "application/json"}},
+			Content: har.Content{MimeType: "application/json", Text: `{"items": [1, 2]}`},
+		},
+	}
+
+	result := Test(entry, &tapApi.TrafficFilteringOptions{})
+
+	expected := []shared.RedactedValue{
+		{Message: messageRequest, In: tapApi.RedactionInHeader, Name: "Authorization", Value: "Bearer abc", Redacted: "[REDACTED]"},
+		{Message: messageRequest, In: tapApi.RedactionInHeader, Name: "Cookie", Value: "session=abc", Removed: true},
+		{Message: messageRequest, In: tapApi.RedactionInQuery, Name: "token", Value: "abc", Redacted: "[REDACTED]"},
+		{Message: messageRequest, In: tapApi.RedactionInBody, Name: "$.password", Value: "hunter2", Redacted: "[REDACTED]"},
+	}
+	if result.Error != "" || len(result.Values) != len(expected) {
+		t.Fatalf("unexpected result - expected: %v, actual: %+v", expected, result)
+	}
+	for i := range expected {
+		if result.Values[i] != expected[i] {
+			t.Errorf("unexpected result - expected: %+v, actual: %+v", expected[i], result.Values[i])
+		}
+	}
+}
+
+func TestDiffBodies(t *testing.T) {
+	// the fields are compared, not the text
+	if values := diffBodies(messageResponse, []byte(`{"b": 1, "a": [true]}`), []byte(`{"a":[true],"b":1}`)); len(values) != 0 {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "no values", values)
+	}
+
+	values := diffBodies(messageResponse, []byte(`{"card": {"number": "4111"}, "a b": 1}`), []byte(`{"card": "[REDACTED]"}`))
+	if len(values) != 2 || values[0].Name != "$['a b']" || !values[0].Removed || values[1].Name != "$.card" || values[1].Value != `{"number":"4111"}` {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "a removed field and a masked object", values)
+	}
+
+	values = diffBodies(messageResponse, []byte("call +1 555 0100"), []byte("call [REDACTED]"))
+	if len(values) != 1 || values[0].Name != "" || values[0].Redacted != "call [REDACTED]" {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "the whole body", values)
+	}
+}
@@ -0,0 +1,110 @@
+package relay
+
+import (
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/up9inc/mizu/agent/pkg/keepalive"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const upstreamHandshakeTimeout = time.Second * 5
+
+var websocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// Routes forwards the sockets of the tappers of a node pool to the api server, every tapper socket gets its own
+// socket to the api server, with the query and the headers of the tapper, so the api server tells the tappers apart
+// by their node name and authenticates them by their token as if they were connected directly
+func Routes(app *gin.Engine, apiServerAddress string, socketKeepAlive shared.SocketKeepAliveConfig) {
+	app.GET("/echo", func(c *gin.Context) {
+		c.JSON(http.StatusOK, "Here is Mizu relay")
+	})
+
+	app.GET("/wsTapper", func(c *gin.Context) {
+		relayTapperSocket(c.Writer, c.Request, apiServerAddress, socketKeepAlive)
+	})
+}
+
+func relayTapperSocket(w http.ResponseWriter, r *http.Request, apiServerAddress string, socketKeepAlive shared.SocketKeepAliveConfig) {
+	upstreamAddress, err := url.Parse(apiServerAddress)
+	if err != nil {
+		logger.Log.Errorf("Invalid api server address %s: %v", apiServerAddress, err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	upstreamAddress.RawQuery = r.URL.RawQuery
+
+	// the socket to the api server is watermarked like the tapper sockets, the relay may run on a tapped node
+	header := http.Header{}
+	header.Set(tapApi.MizuTrafficHeaderName, tapApi.MizuTrafficTapper)
+	if authorization := r.Header.Get("Authorization"); authorization != "" {
+		header.Set("Authorization", authorization)
+	}
+
+	dialer := &websocket.Dialer{
+		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout: upstreamHandshakeTimeout,
+	}
+	upstream, response, err := dialer.Dial(upstreamAddress.String(), header)
+	if err != nil {
+		logger.Log.Warningf("Couldn't relay the tapper of %s to %s: %v", r.RemoteAddr, upstreamAddress.Host, err)
+		// the tapper retries like it does when the api server refuses it
+		status := http.StatusBadGateway
+		if response != nil {
+			status = response.StatusCode
+		}
+		w.WriteHeader(status)
+		return
+	}
+
+	downstream, err := websocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Log.Errorf("Failed to set websocket upgrade: %v", err)
+		_ = upstream.Close()
+		return
+	}
+
+	nodeName := r.URL.Query().Get(shared.TapperNodeNameQueryParam)
+	logger.Log.Infof("Relaying the tapper of node %s to %s", nodeName, upstreamAddress.Host)
+
+	done := make(chan struct{}, 2)
+	go pipe(downstream, upstream, keepalive.Start(downstream, socketKeepAlive), done)
+	go pipe(upstream, downstream, keepalive.Start(upstream, socketKeepAlive), done)
+
+	// a side that is closed makes the other one closed as well, so the tapper reconnects through the relay
+	<-done
+	_ = downstream.Close()
+	_ = upstream.Close()
+	<-done
+
+	logger.Log.Infof("Stopped relaying the tapper of node %s", nodeName)
+}
+
+// pipe is the only writer of the messages of to, the keep alive pings are control messages that are safe to write
+// concurrently
+func pipe(from *websocket.Conn, to *websocket.Conn, fromKeepAlive *keepalive.Socket, done chan<- struct{}) {
+	defer func() { done <- struct{}{} }()
+
+	for {
+		messageType, message, err := from.ReadMessage()
+		if err != nil {
+			logger.Log.Debugf("Error reading from socket %s: %v", from.RemoteAddr(), err)
+			return
+		}
+		fromKeepAlive.Touch()
+
+		if err := to.WriteMessage(messageType, message); err != nil {
+			logger.Log.Debugf("Error writing to socket %s: %v", to.RemoteAddr(), err)
+			return
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package replayer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+	"github.com/up9inc/mizu/shared"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+// ReplayedHeaderName marks the replayed requests, the tappers drop them so replaying doesn't add entries when the
+// target is tapped
+const ReplayedHeaderName = tapApi.MizuReplayHeaderName
+
+// headers of the captured connection that don't apply to the replayed request
+var strippedHeaders = map[string]bool{
+	"host":              true,
+	"content-length":    true,
+	"connection":        true,
+	"transfer-encoding": true,
+	"upgrade":           true,
+	"keep-alive":        true,
+	"te":                true,
+}
+
+// Request is a captured request to replay along with the status it was answered with
+type Request struct {
+	EntryId        string
+	OriginalStatus int
+	Request        *har.Request
+}
+
+// Replay sends the requests to the same paths of target, up to concurrency of them at a time, the results are in the
+// order of the requests, headers are set on each request and an empty value removes the header
+func Replay(ctx context.Context, requests []*Request, target *url.URL, concurrency int, headers map[string]string, timeout time.Duration) []shared.ReplayResult {
+	client := &http.Client{
+		Timeout: timeout,
+		// the redirects are returned as is, like the captured responses
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	results := make([]shared.ReplayResult, len(requests))
+	indexes := make(chan int)
+
+	var workers sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for index := range indexes {
+				results[index] = replay(ctx, client, requests[index], target, headers)
+			}
+		}()
+	}
+
+	for index := range requests {
+		indexes <- index
+	}
+	close(indexes)
+	workers.Wait()
+
+	return results
+}
+
+func IsReplayed(request *har.Request) bool {
+	for _, header := range request.Headers {
+		if strings.EqualFold(header.Name, ReplayedHeaderName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func replay(ctx context.Context, client *http.Client, request *Request, target *url.URL, headers map[string]string) shared.ReplayResult {
+	result := shared.ReplayResult{
+		EntryId:        request.EntryId,
+		Method:         request.Request.Method,
+		OriginalStatus: request.OriginalStatus,
+	}
+
+	if ctx.Err() != nil {
+		result.Error = ctx.Err().Error()
+		return result
+	}
+
+	replayedRequest, err := buildRequest(ctx, target, request.Request, headers)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Url = replayedRequest.URL.String()
+
+	start := time.Now()
+	response, err := client.Do(replayedRequest)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	result.Status = response.StatusCode
+	result.ElapsedTime = time.Since(start).Milliseconds()
+	return result
+}
+
+func buildRequest(ctx context.Context, target *url.URL, request *har.Request, headers map[string]string) (*http.Request, error) {
+	capturedUrl, err := url.Parse(request.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	replayedUrl := *target
+	replayedUrl.Path = strings.TrimSuffix(target.Path, "/") + capturedUrl.Path
+	replayedUrl.RawQuery = capturedUrl.RawQuery
+
+	var body io.Reader
+	if request.PostData.Text != "" {
+		body = bytes.NewBufferString(request.PostData.Text)
+	}
+
+	replayedRequest, err := http.NewRequestWithContext(ctx, request.Method, replayedUrl.String(), body)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, header := range request.Headers {
+		name := strings.ToLower(header.Name)
+		if strippedHeaders[name] || strings.HasPrefix(name, ":") {
+			continue
+		}
+		replayedRequest.Header.Add(header.Name, header.Value)
+	}
+
+	for name, value := range headers {
+		if value == "" {
+			replayedRequest.Header.Del(name)
+		} else {
+			replayedRequest.Header.Set(name, value)
+		}
+	}
+	replayedRequest.Header.Set(ReplayedHeaderName, "1")
+
+	return replayedRequest, nil
+}
@@ -0,0 +1,93 @@
+package replayer
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/har"
+)
+
+func TestBuildRequest(t *testing.T) {
+	target, _ := url.Parse("http://staging-gateway:8080/replay/")
+	captured := &har.Request{
+		Method: "POST",
+		URL:    "http://front-end.sock-shop/orders?page=2",
+		Headers: []har.Header{
+			{Name: "Host", Value: "front-end.sock-shop"},
+			{Name: "Authorization", Value: "Bearer [REDACTED]"},
+			{Name: "Content-Type", Value: "application/json"},
+			{Name: "X-Request-Id", Value: "abc"},
+		},
+		PostData: har.PostData{Text: `{"item":"socks"}`},
+	}
+
+	request, err := buildRequest(context.Background(), target, captured, map[string]string{"Authorization": "Bearer staging", "X-Request-Id": ""})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if expected := "http://staging-gateway:8080/replay/orders?page=2"; request.URL.String() != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, request.URL.String())
+	}
+	if request.Header.Get("Authorization") != "Bearer staging" || request.Header.Get("X-Request-Id") != "" || request.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("unexpected result - expected the rewritten headers, actual: %v", request.Header)
+	}
+	if request.Header.Get(ReplayedHeaderName) != "1" {
+		t.Errorf("unexpected result - expected %s header, actual: %v", ReplayedHeaderName, request.Header)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	var mutex sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mutex.Unlock()
+
+		time.Sleep(20 * time.Millisecond)
+
+		mutex.Lock()
+		inFlight--
+		mutex.Unlock()
+
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer server.Close()
+	target, _ := url.Parse(server.URL)
+
+	requests := make([]*Request, 0)
+	for i := 0; i < 10; i++ {
+		path := "/ok"
+		if i == 3 {
+			path = "/fail"
+		}
+		requests = append(requests, &Request{EntryId: fmt.Sprint(i), OriginalStatus: 200, Request: &har.Request{Method: "GET", URL: "http://front-end" + path}})
+	}
+
+	results := Replay(context.Background(), requests, target, 3, nil, time.Second)
+
+	for i, result := range results {
+		expectedStatus := http.StatusOK
+		if i == 3 {
+			expectedStatus = http.StatusInternalServerError
+		}
+		if result.EntryId != fmt.Sprint(i) || result.Status != expectedStatus || result.Error != "" {
+			t.Errorf("unexpected result - expected entry %d with status %d, actual: %+v", i, expectedStatus, result)
+		}
+	}
+	if maxInFlight > 3 {
+		t.Errorf("unexpected result - expected up to 3 concurrent requests, actual: %d", maxInFlight)
+	}
+}
@@ -0,0 +1,251 @@
+package resolver
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	cmap "github.com/orcaman/concurrent-map"
+	"github.com/up9inc/mizu/shared/logger"
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	resolvConfPath = "/etc/resolv.conf"
+
+	dnsLookupTimeout   = 2 * time.Second
+	dnsMinTtl          = 30 * time.Second
+	dnsMaxTtl          = time.Hour
+	dnsNegativeTtl     = 5 * time.Minute
+	dnsDefaultTtl      = 5 * time.Minute
+	dnsQueueSize       = 1000
+	dnsWorkers         = 4
+	maxDnsCacheEntries = 100000
+)
+
+var errNoPtrRecord = errors.New("no PTR record")
+
+type dnsCacheEntry struct {
+	hostname  string
+	expiresAt time.Time
+}
+
+// DnsResolver resolves IPs to hostnames with reverse (PTR) lookups, the lookups run in the background and the
+// results are cached for the TTL of the record, so resolving never blocks the caller, an IP that isn't cached
+// yet resolves to an empty name until its lookup completes
+type DnsResolver struct {
+	nameserver string
+	cache      cmap.ConcurrentMap
+	pending    cmap.ConcurrentMap
+	queue      chan string
+}
+
+func NewDnsResolver() *DnsResolver {
+	return &DnsResolver{
+		nameserver: getNameserver(resolvConfPath),
+		cache:      cmap.New(),
+		pending:    cmap.New(),
+		queue:      make(chan string, dnsQueueSize),
+	}
+}
+
+func (resolver *DnsResolver) Start(ctx context.Context) {
+	for i := 0; i < dnsWorkers; i++ {
+		go resolver.lookupWorker(ctx)
+	}
+}
+
+// Resolve returns the cached hostname of ip, a lookup is queued when it isn't cached or its record expired
+func (resolver *DnsResolver) Resolve(ip string) string {
+	if net.ParseIP(ip) == nil {
+		return ""
+	}
+
+	hostname := ""
+	if cached, ok := resolver.cache.Get(ip); ok {
+		entry := cached.(*dnsCacheEntry)
+		if time.Now().Before(entry.expiresAt) {
+			return entry.hostname
+		}
+		// an expired name is still better than none until the lookup completes
+		hostname = entry.hostname
+	}
+
+	if resolver.pending.SetIfAbsent(ip, true) {
+		select {
+		case resolver.queue <- ip:
+		default:
+			// the queue is full, the ip is queued again by one of its next entries
+			resolver.pending.Remove(ip)
+		}
+	}
+
+	return hostname
+}
+
+func (resolver *DnsResolver) lookupWorker(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ip := <-resolver.queue:
+			hostname, ttl, err := resolver.lookup(ctx, ip)
+			if err != nil {
+				if !errors.Is(err, errNoPtrRecord) {
+					logger.Log.Debugf("Failed reverse lookup of %s: %v", ip, err)
+				}
+				hostname, ttl = "", dnsNegativeTtl
+			}
+
+			resolver.store(ip, hostname, ttl)
+			resolver.pending.Remove(ip)
+		}
+	}
+}
+
+func (resolver *DnsResolver) store(ip string, hostname string, ttl time.Duration) {
+	if ttl < dnsMinTtl {
+		ttl = dnsMinTtl
+	} else if ttl > dnsMaxTtl {
+		ttl = dnsMaxTtl
+	}
+
+	if resolver.cache.Count() >= maxDnsCacheEntries && !resolver.cache.Has(ip) {
+		resolver.removeExpired()
+		if resolver.cache.Count() >= maxDnsCacheEntries {
+			return
+		}
+	}
+
+	resolver.cache.Set(ip, &dnsCacheEntry{hostname: hostname, expiresAt: time.Now().Add(ttl)})
+}
+
+func (resolver *DnsResolver) removeExpired() {
+	now := time.Now()
+	for item := range resolver.cache.IterBuffered() {
+		if now.After(item.Val.(*dnsCacheEntry).expiresAt) {
+			resolver.cache.Remove(item.Key)
+		}
+	}
+}
+
+func (resolver *DnsResolver) lookup(ctx context.Context, ip string) (string, time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, dnsLookupTimeout)
+	defer cancel()
+
+	// the system resolver doesn't expose the TTL of the records
+	if resolver.nameserver == "" {
+		names, err := net.DefaultResolver.LookupAddr(ctx, ip)
+		if err != nil || len(names) == 0 {
+			return "", 0, errNoPtrRecord
+		}
+		return strings.TrimSuffix(names[0], "."), dnsDefaultTtl, nil
+	}
+
+	return queryPtr(ctx, resolver.nameserver, ip)
+}
+
+func queryPtr(ctx context.Context, nameserver string, ip string) (string, time.Duration, error) {
+	arpa, err := reverseAddress(ip)
+	if err != nil {
+		return "", 0, err
+	}
+	name, err := dnsmessage.NewName(arpa)
+	if err != nil {
+		return "", 0, err
+	}
+
+	id := uint16(rand.Intn(1 << 16))
+	query := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: name, Type: dnsmessage.TypePTR, Class: dnsmessage.ClassINET}},
+	}
+	packed, err := query.Pack()
+	if err != nil {
+		return "", 0, err
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "udp", nameserver)
+	if err != nil {
+		return "", 0, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Write(packed); err != nil {
+		return "", 0, err
+	}
+
+	buffer := make([]byte, 1500)
+	for {
+		n, err := conn.Read(buffer)
+		if err != nil {
+			return "", 0, err
+		}
+
+		var response dnsmessage.Message
+		if err := response.Unpack(buffer[:n]); err != nil || response.ID != id {
+			// not the answer to this query, keep waiting until the deadline
+			continue
+		}
+
+		if response.RCode != dnsmessage.RCodeSuccess && response.RCode != dnsmessage.RCodeNameError {
+			return "", 0, fmt.Errorf("reverse lookup failed with %v", response.RCode)
+		}
+
+		for _, answer := range response.Answers {
+			if ptr, ok := answer.Body.(*dnsmessage.PTRResource); ok {
+				return strings.TrimSuffix(ptr.PTR.String(), "."), time.Duration(answer.Header.TTL) * time.Second, nil
+			}
+		}
+
+		return "", 0, errNoPtrRecord
+	}
+}
+
+// reverseAddress returns the in-addr.arpa or ip6.arpa name of ip
+func reverseAddress(ip string) (string, error) {
+	parsedIp := net.ParseIP(ip)
+	if parsedIp == nil {
+		return "", fmt.Errorf("invalid ip %s", ip)
+	}
+
+	if ipv4 := parsedIp.To4(); ipv4 != nil {
+		return fmt.Sprintf("%d.%d.%d.%d.in-addr.arpa.", ipv4[3], ipv4[2], ipv4[1], ipv4[0]), nil
+	}
+
+	var builder strings.Builder
+	for i := len(parsedIp) - 1; i >= 0; i-- {
+		fmt.Fprintf(&builder, "%x.%x.", parsedIp[i]&0xf, parsedIp[i]>>4)
+	}
+	builder.WriteString("ip6.arpa.")
+	return builder.String(), nil
+}
+
+// getNameserver returns the first nameserver of the resolv.conf file, or an empty string to use the system resolver
+func getNameserver(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" && net.ParseIP(fields[1]) != nil {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+
+	return ""
+}
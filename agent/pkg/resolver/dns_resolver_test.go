@@ -0,0 +1,70 @@
+package resolver
+
+import (
+	"os"
+	"path"
+	"testing"
+	"time"
+)
+
+func TestReverseAddress(t *testing.T) {
+	tests := map[string]string{
+		"10.1.2.3":    "3.2.1.10.in-addr.arpa.",
+		"2001:db8::1": "1.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.0.8.b.d.0.1.0.0.2.ip6.arpa.",
+	}
+
+	for ip, expected := range tests {
+		t.Run(ip, func(t *testing.T) {
+			actual, err := reverseAddress(ip)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if actual != expected {
+				t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+			}
+		})
+	}
+
+	if _, err := reverseAddress("not-an-ip"); err == nil {
+		t.Errorf("expected an error for an invalid ip")
+	}
+}
+
+func TestGetNameserver(t *testing.T) {
+	resolvConf := path.Join(t.TempDir(), "resolv.conf")
+	content := "search default.svc.cluster.local svc.cluster.local\nnameserver 10.96.0.10\nnameserver 10.96.0.11\noptions ndots:5\n"
+	if err := os.WriteFile(resolvConf, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if nameserver := getNameserver(resolvConf); nameserver != "10.96.0.10:53" {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "10.96.0.10:53", nameserver)
+	}
+
+	if nameserver := getNameserver(path.Join(t.TempDir(), "missing")); nameserver != "" {
+		t.Errorf("unexpected result - expected an empty nameserver, actual: %v", nameserver)
+	}
+}
+
+func TestResolveDoesNotBlock(t *testing.T) {
+	dnsResolver := NewDnsResolver()
+
+	// no workers are started, so the lookup stays queued
+	if hostname := dnsResolver.Resolve("10.1.2.3"); hostname != "" {
+		t.Errorf("unexpected result - expected an empty hostname, actual: %v", hostname)
+	}
+	if len(dnsResolver.queue) != 1 {
+		t.Errorf("unexpected result - expected: %v queued lookups, actual: %v", 1, len(dnsResolver.queue))
+	}
+
+	// a pending lookup isn't queued twice
+	dnsResolver.Resolve("10.1.2.3")
+	if len(dnsResolver.queue) != 1 {
+		t.Errorf("unexpected result - expected: %v queued lookups, actual: %v", 1, len(dnsResolver.queue))
+	}
+
+	dnsResolver.store("10.1.2.4", "example.com", time.Minute)
+	if hostname := dnsResolver.Resolve("10.1.2.4"); hostname != "example.com" {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "example.com", hostname)
+	}
+}
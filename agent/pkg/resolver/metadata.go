@@ -0,0 +1,97 @@
+package resolver
+
+import (
+	cmap "github.com/orcaman/concurrent-map"
+	"github.com/up9inc/mizu/shared"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type objectMetadata struct {
+	namespace string
+	values    map[string]string
+}
+
+// workloadMetadata keeps the allowlisted labels and annotations of the pods and services by their ip and of the
+// namespaces by their name
+type workloadMetadata struct {
+	labels      []string
+	annotations []string
+	byIp        cmap.ConcurrentMap
+	byNamespace cmap.ConcurrentMap
+}
+
+func newWorkloadMetadata(config shared.EnrichmentConfig) *workloadMetadata {
+	if len(config.Labels) == 0 && len(config.Annotations) == 0 {
+		return nil
+	}
+
+	return &workloadMetadata{
+		labels:      config.Labels,
+		annotations: config.Annotations,
+		byIp:        cmap.New(),
+		byNamespace: cmap.New(),
+	}
+}
+
+func (metadata *workloadMetadata) filter(objectMeta *metav1.ObjectMeta) map[string]string {
+	values := make(map[string]string)
+	for _, label := range metadata.labels {
+		if value, ok := objectMeta.Labels[label]; ok {
+			values[label] = value
+		}
+	}
+	for _, annotation := range metadata.annotations {
+		if value, ok := objectMeta.Annotations[annotation]; ok {
+			values[annotation] = value
+		}
+	}
+
+	return values
+}
+
+func (metadata *workloadMetadata) saveIp(ip string, objectMeta *metav1.ObjectMeta, eventType watch.EventType) {
+	if ip == "" || ip == kubClientNullString {
+		return
+	}
+
+	if eventType == watch.Deleted {
+		metadata.byIp.Remove(ip)
+		return
+	}
+
+	metadata.byIp.Set(ip, &objectMetadata{namespace: objectMeta.Namespace, values: metadata.filter(objectMeta)})
+}
+
+func (metadata *workloadMetadata) saveNamespace(objectMeta *metav1.ObjectMeta, eventType watch.EventType) {
+	if eventType == watch.Deleted {
+		metadata.byNamespace.Remove(objectMeta.Name)
+		return
+	}
+
+	metadata.byNamespace.Set(objectMeta.Name, metadata.filter(objectMeta))
+}
+
+// get returns the metadata of the pod or service of the ip, its values take precedence over those of its namespace
+func (metadata *workloadMetadata) get(ip string) map[string]string {
+	object, ok := metadata.byIp.Get(ip)
+	if !ok {
+		return nil
+	}
+
+	values := make(map[string]string)
+	if namespaceValues, ok := metadata.byNamespace.Get(object.(*objectMetadata).namespace); ok {
+		for key, value := range namespaceValues.(map[string]string) {
+			values[key] = value
+		}
+	}
+	for key, value := range object.(*objectMetadata).values {
+		values[key] = value
+	}
+
+	if len(values) == 0 {
+		return nil
+	}
+
+	return values
+}
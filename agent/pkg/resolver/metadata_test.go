@@ -0,0 +1,54 @@
+package resolver
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func TestWorkloadMetadata(t *testing.T) {
+	metadata := newWorkloadMetadata(shared.EnrichmentConfig{
+		Labels:      []string{"version", "team"},
+		Annotations: []string{"example.com/cost-center"},
+	})
+
+	metadata.saveNamespace(&metav1.ObjectMeta{
+		Name:        "shop",
+		Labels:      map[string]string{"team": "payments", "kubernetes.io/metadata.name": "shop"},
+		Annotations: map[string]string{"example.com/cost-center": "cc-100"},
+	}, watch.Added)
+	metadata.saveIp("10.0.0.7", &metav1.ObjectMeta{
+		Name:        "orders-7d9f",
+		Namespace:   "shop",
+		Labels:      map[string]string{"version": "v2", "pod-template-hash": "7d9f"},
+		Annotations: map[string]string{"example.com/cost-center": "cc-200"},
+	}, watch.Added)
+
+	expected := map[string]string{"version": "v2", "team": "payments", "example.com/cost-center": "cc-200"}
+	if actual := metadata.get("10.0.0.7"); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+
+	if actual := metadata.get("10.0.0.8"); actual != nil {
+		t.Errorf("unexpected result - expected no metadata for an unknown ip, actual: %v", actual)
+	}
+
+	metadata.saveIp("10.0.0.7", &metav1.ObjectMeta{Name: "orders-7d9f", Namespace: "shop"}, watch.Deleted)
+	if actual := metadata.get("10.0.0.7"); actual != nil {
+		t.Errorf("unexpected result - expected no metadata for a deleted pod, actual: %v", actual)
+	}
+}
+
+func TestWorkloadMetadataDisabled(t *testing.T) {
+	if metadata := newWorkloadMetadata(shared.EnrichmentConfig{}); metadata != nil {
+		t.Errorf("unexpected result - expected no metadata without allowlisted keys")
+	}
+
+	resolver := &Resolver{}
+	if actual := resolver.GetMetadata("10.0.0.7"); actual != nil {
+		t.Errorf("unexpected result - expected no metadata, actual: %v", actual)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/logger"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 
@@ -28,6 +29,7 @@ type Resolver struct {
 	isStarted    bool
 	errOut       chan error
 	namespace    string
+	metadata     *workloadMetadata
 }
 
 type ResolvedObjectInfo struct {
@@ -42,7 +44,27 @@ func (resolver *Resolver) Start(ctx context.Context) {
 		go resolver.infiniteErrorHandleRetryFunc(ctx, resolver.watchServices)
 		go resolver.infiniteErrorHandleRetryFunc(ctx, resolver.watchEndpoints)
 		go resolver.infiniteErrorHandleRetryFunc(ctx, resolver.watchPods)
+		// namespaces can't be watched with the role of the namespace restricted mode
+		if resolver.metadata != nil && resolver.namespace == "" {
+			go resolver.infiniteErrorHandleRetryFunc(ctx, resolver.watchNamespaces)
+		}
+	}
+}
+
+// EnableEnrichment keeps the allowlisted labels and annotations of the pods, services and namespaces, it must be
+// called before Start
+func (resolver *Resolver) EnableEnrichment(config shared.EnrichmentConfig) {
+	resolver.metadata = newWorkloadMetadata(config)
+}
+
+// GetMetadata returns the allowlisted labels and annotations of the pod or service of the ip and of its namespace,
+// nil when there are none
+func (resolver *Resolver) GetMetadata(ip string) map[string]string {
+	if resolver.metadata == nil {
+		return nil
 	}
+
+	return resolver.metadata.get(ip)
 }
 
 func (resolver *Resolver) Resolve(name string) *ResolvedObjectInfo {
@@ -74,10 +96,17 @@ func (resolver *Resolver) watchPods(ctx context.Context) error {
 			if event.Object == nil {
 				return errors.New("error in kubectl pod watch")
 			}
+			pod, ok := event.Object.(*corev1.Pod)
+			if !ok {
+				continue
+			}
 			if event.Type == watch.Deleted {
-				pod := event.Object.(*corev1.Pod)
 				resolver.saveResolvedName(pod.Status.PodIP, "", pod.Namespace, event.Type)
 			}
+			// pods on the host network share the ip of their node
+			if resolver.metadata != nil && !pod.Spec.HostNetwork {
+				resolver.metadata.saveIp(pod.Status.PodIP, &pod.ObjectMeta, event.Type)
+			}
 		case <-ctx.Done():
 			watcher.Stop()
 			return nil
@@ -153,6 +182,9 @@ func (resolver *Resolver) watchServices(ctx context.Context) error {
 					}
 				}
 				resolver.saveServiceIP(service.Spec.ClusterIP, serviceHostname, service.Namespace, event.Type)
+				if resolver.metadata != nil {
+					resolver.metadata.saveIp(service.Spec.ClusterIP, &service.ObjectMeta, event.Type)
+				}
 			}
 			if service.Status.LoadBalancer.Ingress != nil {
 				for _, ingress := range service.Status.LoadBalancer.Ingress {
@@ -166,6 +198,30 @@ func (resolver *Resolver) watchServices(ctx context.Context) error {
 	}
 }
 
+func (resolver *Resolver) watchNamespaces(ctx context.Context) error {
+	watcher, err := resolver.clientSet.CoreV1().Namespaces().Watch(ctx, metav1.ListOptions{Watch: true})
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case event := <-watcher.ResultChan():
+			if event.Object == nil {
+				return errors.New("error in kubectl namespace watch")
+			}
+
+			namespace, ok := event.Object.(*corev1.Namespace)
+			if !ok {
+				continue
+			}
+			resolver.metadata.saveNamespace(&namespace.ObjectMeta, event.Type)
+		case <-ctx.Done():
+			watcher.Stop()
+			return nil
+		}
+	}
+}
+
 func (resolver *Resolver) saveResolvedName(key string, resolved string, namespace string, eventType watch.EventType) {
 	if eventType == watch.Deleted {
 		resolver.nameMap.Remove(resolved)
@@ -0,0 +1,249 @@
+package retention
+
+import (
+	"sync"
+	"time"
+
+	basenine "github.com/up9inc/basenine/client/go"
+	"github.com/up9inc/mizu/agent/pkg/metrics"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	// the stored entries are counted in buckets of this many seconds, so the buckets of a long max age stay few
+	bucketSeconds = 10
+
+	// a limit of 0 doesn't limit the database, so the limit isn't lowered under this one
+	minLimitBytes int64 = 1000 * 1000
+)
+
+type bucket struct {
+	start   int64
+	entries int64
+	bytes   int64
+}
+
+// Enforcer bounds the stored entries by count, by size and by age. The database only evicts its oldest entries once
+// it's over its size limit, so the entries over the count and the age bounds are evicted by lowering the limit to
+// the size of the entries that are kept
+type Enforcer struct {
+	mutex       sync.Mutex
+	config      shared.RetentionConfig
+	maxBytes    int64
+	limitBytes  int64
+	limit       func(bytes int64) error
+	now         func() time.Time
+	buckets     []bucket
+	entries     int64
+	bytes       int64
+	recentBytes int64
+	evicted     map[string]uint64
+	stop        chan struct{}
+}
+
+var instance *Enforcer
+var once sync.Once
+
+func GetInstance() *Enforcer {
+	once.Do(func() {
+		instance = newEnforcer(func(bytes int64) error {
+			return basenine.Limit(shared.BasenineHost, shared.BaseninePort, bytes)
+		}, time.Now)
+	})
+	return instance
+}
+
+func newEnforcer(limit func(bytes int64) error, now func() time.Time) *Enforcer {
+	return &Enforcer{limit: limit, now: now, evicted: make(map[string]uint64)}
+}
+
+// Configure starts evicting the entries over the bounds every interval, maxBytes is the database size limit the
+// database was started with
+func (enforcer *Enforcer) Configure(config shared.RetentionConfig, maxBytes int64) {
+	enforcer.mutex.Lock()
+	defer enforcer.mutex.Unlock()
+
+	if enforcer.stop != nil {
+		close(enforcer.stop)
+		enforcer.stop = nil
+	}
+
+	enforcer.config = config
+	enforcer.maxBytes = maxBytes
+	enforcer.limitBytes = maxBytes
+	if config.IntervalSec <= 0 {
+		logger.Log.Infof("No retention interval was supplied, evicting the entries by count and by age disabled")
+		return
+	}
+
+	enforcer.stop = make(chan struct{})
+	go enforcer.watch(enforcer.stop, time.Duration(config.IntervalSec)*time.Second)
+	logger.Log.Infof("Retaining up to %d bytes, %d entries and %d seconds of entries, 0 is unbounded", maxBytes, config.MaxEntries, config.MaxAgeSec)
+}
+
+func (enforcer *Enforcer) watch(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := enforcer.enforce(); err != nil {
+				logger.Log.Errorf("Failed limiting the entries db size: %v", err)
+			}
+		}
+	}
+}
+
+// SetMaxBytes changes the database size limit, like a tap of the operator does
+func (enforcer *Enforcer) SetMaxBytes(maxBytes int64) error {
+	enforcer.mutex.Lock()
+	enforcer.maxBytes = maxBytes
+	enforcer.mutex.Unlock()
+
+	return enforcer.enforce()
+}
+
+// EntryStored counts an entry inserted into the database, by the time it was inserted since the database evicts the
+// entries in the order they were inserted
+func (enforcer *Enforcer) EntryStored(size int) {
+	enforcer.mutex.Lock()
+	defer enforcer.mutex.Unlock()
+
+	start := enforcer.now().Unix() / bucketSeconds * bucketSeconds
+	if last := len(enforcer.buckets) - 1; last < 0 || enforcer.buckets[last].start != start {
+		enforcer.buckets = append(enforcer.buckets, bucket{start: start})
+	}
+
+	newest := &enforcer.buckets[len(enforcer.buckets)-1]
+	newest.entries++
+	newest.bytes += int64(size)
+	enforcer.entries++
+	enforcer.bytes += int64(size)
+	enforcer.recentBytes += int64(size)
+}
+
+// enforce evicts the entries over the bounds and applies the limit of the entries that are kept
+func (enforcer *Enforcer) enforce() error {
+	enforcer.mutex.Lock()
+	enforcer.evict(enforcer.now())
+	limit := enforcer.nextLimit()
+	enforcer.recentBytes = 0
+	isChanged := limit != enforcer.limitBytes
+	status := enforcer.getStatus()
+	enforcer.mutex.Unlock()
+
+	metrics.GetInstance().SetStore(status.Entries, status.Bytes, status.Evicted)
+
+	if !isChanged {
+		return nil
+	}
+
+	// the limit is applied outside the lock, the entries keep being stored while the database is called
+	if err := enforcer.limit(limit); err != nil {
+		return err
+	}
+
+	enforcer.mutex.Lock()
+	enforcer.limitBytes = limit
+	enforcer.mutex.Unlock()
+	return nil
+}
+
+// evict drops the oldest entries over the bounds from the count, an entry over several bounds is counted as evicted
+// by the size, then by the count. The entries over the size limit are evicted by the database on its own, they're
+// only counted
+func (enforcer *Enforcer) evict(now time.Time) {
+	for len(enforcer.buckets) > 0 {
+		oldest := &enforcer.buckets[0]
+
+		var reason string
+		var count int64
+		switch {
+		case enforcer.maxBytes > 0 && enforcer.bytes > enforcer.maxBytes:
+			// the entries that make up the excess by the average size of the entries of the bucket
+			reason = shared.EvictionReasonBytes
+			count = oldest.entries
+			if oldest.bytes > 0 {
+				count = ((enforcer.bytes-enforcer.maxBytes)*oldest.entries + oldest.bytes - 1) / oldest.bytes
+			}
+		case enforcer.config.MaxEntries > 0 && enforcer.entries > enforcer.config.MaxEntries:
+			reason = shared.EvictionReasonEntries
+			count = enforcer.entries - enforcer.config.MaxEntries
+		case enforcer.config.MaxAgeSec > 0 && oldest.start+bucketSeconds <= now.Unix()-enforcer.config.MaxAgeSec:
+			reason = shared.EvictionReasonAge
+			count = oldest.entries
+		default:
+			return
+		}
+
+		if count > oldest.entries {
+			count = oldest.entries
+		}
+		evictedBytes := oldest.bytes
+		if count < oldest.entries {
+			evictedBytes = oldest.bytes * count / oldest.entries
+		}
+
+		oldest.entries -= count
+		oldest.bytes -= evictedBytes
+		enforcer.entries -= count
+		enforcer.bytes -= evictedBytes
+		enforcer.evicted[reason] += uint64(count)
+
+		if oldest.entries == 0 {
+			enforcer.buckets = enforcer.buckets[1:]
+		}
+	}
+}
+
+// nextLimit is the size of the kept entries with room for the entries stored until the next interval, like the ones
+// stored during the last one, it's the database size limit when the entries aren't bounded by count or by age
+func (enforcer *Enforcer) nextLimit() int64 {
+	if enforcer.config.MaxEntries <= 0 && enforcer.config.MaxAgeSec <= 0 {
+		return enforcer.maxBytes
+	}
+
+	limit := enforcer.bytes + enforcer.recentBytes
+	if limit < minLimitBytes {
+		limit = minLimitBytes
+	}
+	if enforcer.maxBytes > 0 && limit > enforcer.maxBytes {
+		limit = enforcer.maxBytes
+	}
+
+	return limit
+}
+
+func (enforcer *Enforcer) GetStatus() *shared.RetentionStatus {
+	enforcer.mutex.Lock()
+	defer enforcer.mutex.Unlock()
+
+	return enforcer.getStatus()
+}
+
+func (enforcer *Enforcer) getStatus() *shared.RetentionStatus {
+	evicted := make(map[string]uint64, len(enforcer.evicted))
+	for reason, count := range enforcer.evicted {
+		evicted[reason] = count
+	}
+
+	var oldestTime int64
+	if len(enforcer.buckets) > 0 {
+		oldestTime = enforcer.buckets[0].start * 1000
+	}
+
+	return &shared.RetentionStatus{
+		MaxEntries: enforcer.config.MaxEntries,
+		MaxAgeSec:  enforcer.config.MaxAgeSec,
+		MaxBytes:   enforcer.maxBytes,
+		LimitBytes: enforcer.limitBytes,
+		Entries:    enforcer.entries,
+		Bytes:      enforcer.bytes,
+		OldestTime: oldestTime,
+		Evicted:    evicted,
+	}
+}
@@ -0,0 +1,135 @@
+package retention
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+type testClock struct {
+	now time.Time
+}
+
+func (clock *testClock) Now() time.Time {
+	return clock.now
+}
+
+func newTestEnforcer(config shared.RetentionConfig, maxBytes int64) (*Enforcer, *testClock, *[]int64) {
+	clock := &testClock{now: time.Unix(1000, 0)}
+	limits := make([]int64, 0)
+	enforcer := newEnforcer(func(bytes int64) error {
+		limits = append(limits, bytes)
+		return nil
+	}, clock.Now)
+	enforcer.config = config
+	enforcer.maxBytes = maxBytes
+	enforcer.limitBytes = maxBytes
+
+	return enforcer, clock, &limits
+}
+
+func TestEvictByEntries(t *testing.T) {
+	enforcer, clock, _ := newTestEnforcer(shared.RetentionConfig{MaxEntries: 3}, 0)
+	for i := 0; i < 5; i++ {
+		enforcer.EntryStored(100)
+		clock.now = clock.now.Add(bucketSeconds * time.Second)
+	}
+
+	enforcer.evict(clock.now)
+
+	status := enforcer.getStatus()
+	if status.Entries != 3 || status.Bytes != 300 || status.OldestTime != 1020*1000 {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "3 entries of 300 bytes from 1020", status)
+	}
+	if !reflect.DeepEqual(status.Evicted, map[string]uint64{shared.EvictionReasonEntries: 2}) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "2 evicted by entries", status.Evicted)
+	}
+}
+
+func TestEvictByAge(t *testing.T) {
+	enforcer, clock, _ := newTestEnforcer(shared.RetentionConfig{MaxAgeSec: 60}, 0)
+	for i := 0; i < 4; i++ {
+		enforcer.EntryStored(10)
+		enforcer.EntryStored(10)
+		clock.now = clock.now.Add(30 * time.Second)
+	}
+
+	// the buckets of 1000 and 1030 end over 60 seconds before 1120
+	enforcer.evict(clock.now)
+
+	status := enforcer.getStatus()
+	if status.Entries != 4 || status.Bytes != 40 {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "4 entries of 40 bytes", status)
+	}
+	if status.Evicted[shared.EvictionReasonAge] != 4 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 4, status.Evicted)
+	}
+}
+
+func TestEvictByBytes(t *testing.T) {
+	enforcer, clock, _ := newTestEnforcer(shared.RetentionConfig{}, 250)
+	for i := 0; i < 4; i++ {
+		enforcer.EntryStored(100)
+	}
+	clock.now = clock.now.Add(time.Minute)
+
+	// the oldest entries of the bucket are evicted by their average size, not the whole bucket
+	enforcer.evict(clock.now)
+
+	status := enforcer.getStatus()
+	if status.Entries != 2 || status.Bytes != 200 || status.Evicted[shared.EvictionReasonBytes] != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "2 entries of 200 bytes and 2 evicted by bytes", status)
+	}
+}
+
+func TestEnforceLimit(t *testing.T) {
+	enforcer, clock, limits := newTestEnforcer(shared.RetentionConfig{MaxEntries: 1000}, 20*minLimitBytes)
+	for i := 0; i < 1200; i++ {
+		enforcer.EntryStored(5000)
+	}
+	clock.now = clock.now.Add(time.Minute)
+
+	if err := enforcer.enforce(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// the kept entries and the ones stored during the interval, under the database size limit
+	expected := []int64{1000*5000 + 1200*5000}
+	if !reflect.DeepEqual(*limits, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, *limits)
+	}
+
+	// nothing was stored since, the limit is lowered to the kept entries
+	if err := enforcer.enforce(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := enforcer.enforce(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = append(expected, 1000*5000)
+	if !reflect.DeepEqual(*limits, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, *limits)
+	}
+
+	if err := enforcer.SetMaxBytes(2 * minLimitBytes); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = append(expected, 2*minLimitBytes)
+	if !reflect.DeepEqual(*limits, expected) || enforcer.GetStatus().LimitBytes != 2*minLimitBytes {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, *limits)
+	}
+}
+
+func TestEnforceWithoutBounds(t *testing.T) {
+	enforcer, _, limits := newTestEnforcer(shared.RetentionConfig{}, 10*minLimitBytes)
+	enforcer.EntryStored(5000)
+
+	if err := enforcer.enforce(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(*limits) != 0 {
+		t.Errorf("unexpected result - expected the database size limit to stay, actual: %v", *limits)
+	}
+}
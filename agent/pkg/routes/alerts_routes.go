@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// AlertsRoutes defines the group of alert rule routes, the rules notify webhooks when the traffic breaches them
+func AlertsRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/alerts")
+
+	routeGroup.GET("/rules", controllers.GetAlertRules)          // get the rules with the counts of their windows
+	routeGroup.POST("/rules", controllers.PostAlertRule)         // add a rule
+	routeGroup.DELETE("/rules/:id", controllers.DeleteAlertRule) // remove a rule
+}
@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// ArchiveRoutes defines the group of the routes of the bucket archives.
+func ArchiveRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/archive")
+
+	routeGroup.POST("/flush", controllers.PostArchiveFlush)      // upload the entries since the last archive
+	routeGroup.POST("/entries", controllers.PostArchivedEntries) // store the entries of an archive, to replay it
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// ChatOpsRoutes defines the group of routes receiving the slash commands of Slack and Mattermost
+func ChatOpsRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/chatops")
+
+	routeGroup.POST("/command", controllers.PostChatOpsCommand)
+}
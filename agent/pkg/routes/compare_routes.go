@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// CompareRoutes defines the group of workload comparison routes.
+func CompareRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/compare")
+
+	routeGroup.GET("/", controllers.GetComparison) // error rates, latencies and response schemas of a stable and a canary workload
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// ExportRoutes defines the group of bulk entries export routes.
+func ExportRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/export")
+
+	routeGroup.GET("/har", controllers.GetHarExport) // http entries matching a query and time range as a HAR log
+}
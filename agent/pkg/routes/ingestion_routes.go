@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// IngestionRoutes defines the group of ingestion routes, the entries of the muted protocols and namespaces aren't stored
+func IngestionRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/ingestion")
+
+	routeGroup.GET("/", controllers.GetIngestionStatus)                       // get the muted protocols and namespaces
+	routeGroup.POST("/mutes", controllers.PostIngestionMute)                  // mute a protocol or a namespace
+	routeGroup.DELETE("/mutes/:kind/:value", controllers.DeleteIngestionMute) // unmute a protocol or a namespace
+}
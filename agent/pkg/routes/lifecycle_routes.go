@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// LifecycleRoutes defines the group of lifecycle routes, the events the api server can't observe itself are
+// reported here
+func LifecycleRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/lifecycle")
+
+	routeGroup.POST("/sessionStopped", controllers.PostSessionStopped) // posts session.stopped before the cli removes the resources
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// MaintenanceRoutes defines the group of maintenance window routes, the rules aren't evaluated during the windows
+func MaintenanceRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/maintenance")
+
+	routeGroup.GET("/", controllers.GetMaintenanceWindows)         // get the upcoming and active windows
+	routeGroup.POST("/", controllers.PostMaintenanceWindow)        // declare a window
+	routeGroup.DELETE("/:id", controllers.DeleteMaintenanceWindow) // end a window now
+}
@@ -11,4 +11,5 @@ func MetadataRoutes(app *gin.Engine) {
 	routeGroup := app.Group("/metadata")
 
 	routeGroup.GET("/version", controllers.GetVersion)
+	routeGroup.GET("/features", controllers.GetFeatures) // the modules that are enabled, disabled ones serve no data
 }
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// MetricsRoutes exposes the metrics of the API server to Prometheus.
+func MetricsRoutes(ginApp *gin.Engine) {
+	ginApp.GET("/metrics", controllers.GetMetrics) // entries, error rates and latencies per service, tapper drops and memory
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// ProvenanceRoutes defines the group of signed entry provenance routes.
+func ProvenanceRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/provenance")
+
+	routeGroup.GET("/segments", controllers.GetProvenanceSegments) // signed segments of the stored entries chain
+}
@@ -9,4 +9,5 @@ func QueryRoutes(ginApp *gin.Engine) {
 	routeGroup := ginApp.Group("/query")
 
 	routeGroup.POST("/validate", controllers.PostValidate)
+	routeGroup.GET("/rate", controllers.GetRate) // entries per interval of a query with a rate operator
 }
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// RedactionRoutes defines the group of routes trying out the redaction of the tappers
+func RedactionRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/redaction")
+
+	routeGroup.POST("/test", controllers.PostRedactionTest)
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// ReplayRoutes defines the group of routes re-sending the captured requests from inside the cluster
+func ReplayRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/replay")
+
+	routeGroup.POST("/", controllers.PostReplay)
+}
@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// SavedQueriesRoutes defines the group of the saved queries routes, the named queries the team shares
+func SavedQueriesRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/savedQueries")
+
+	routeGroup.GET("/", controllers.GetSavedQueries)
+	routeGroup.GET("/:name", controllers.GetSavedQuery)
+	routeGroup.POST("/", controllers.PostSavedQuery)    // save a new query
+	routeGroup.PUT("/:name", controllers.PutSavedQuery) // change the query string of a saved query
+	routeGroup.DELETE("/:name", controllers.DeleteSavedQuery)
+}
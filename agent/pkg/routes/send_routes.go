@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// SendRoutes defines the group of routes sending requests from inside the cluster
+func SendRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/send")
+
+	routeGroup.POST("/", controllers.PostSend)
+}
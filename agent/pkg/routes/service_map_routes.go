@@ -12,5 +12,6 @@ func ServiceMapRoutes(ginApp *gin.Engine) {
 
 	routeGroup.GET("/status", controller.Status)
 	routeGroup.GET("/get", controller.Get)
+	routeGroup.GET("/ws", controller.Stream)
 	routeGroup.GET("/reset", controller.Reset)
 }
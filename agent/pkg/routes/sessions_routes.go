@@ -0,0 +1,21 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// SessionsRoutes defines the group of sessions routes, who started the session and with what flags, and the control
+// of its tap when mizu runs as an operator
+func SessionsRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/sessions")
+
+	routeGroup.GET("/", controllers.GetSessions)
+
+	routeGroup.GET("/tap", controllers.GetSessionTap)
+	routeGroup.POST("/tap", controllers.PostSessionTap)        // start tapping the pods of a spec
+	routeGroup.PUT("/tap", controllers.PutSessionTap)          // replace the spec
+	routeGroup.POST("/tap/stop", controllers.StopSessionTap)   // stop the tappers, the spec is kept
+	routeGroup.POST("/tap/start", controllers.StartSessionTap) // tap the pods of the kept spec again
+	routeGroup.DELETE("/tap", controllers.DeleteSessionTap)    // stop the tappers and remove the spec
+}
@@ -11,8 +11,12 @@ func StatusRoutes(ginApp *gin.Engine) {
 	routeGroup.GET("/health", controllers.HealthCheck)
 
 	routeGroup.POST("/tappedPods", controllers.PostTappedPods)
+	routeGroup.POST("/tapTargets", controllers.PostTapTargets) // update the pods the running tappers capture, without restarting them
 	routeGroup.POST("/tapperStatus", controllers.PostTapperStatus)
+	routeGroup.POST("/tapperDebug/:nodeName", controllers.PostTapperDebug) // change the log level or dump packets of a single tapper
 	routeGroup.GET("/connectedTappersCount", controllers.GetConnectedTappersCount)
+	routeGroup.GET("/clockSkew", controllers.GetClockSkew) // get the clock skew of every tapper node
+	routeGroup.GET("/drops", controllers.GetTapperDrops)   // get the tcp streams and the entries every tapper dropped, sampled out or rate limited
 	routeGroup.GET("/tap", controllers.GetTappingStatus)
 
 	routeGroup.GET("/auth", controllers.GetAuthStatus)
@@ -21,6 +25,28 @@ func StatusRoutes(ginApp *gin.Engine) {
 
 	routeGroup.GET("/general", controllers.GetGeneralStats) // get general stats about entries in DB
 
+	routeGroup.GET("/connectionReuse", controllers.GetConnectionReuseStats) // get requests per connection of every client-service pair
+
+	routeGroup.GET("/exportQueues", controllers.GetExportQueuesStatus)
+	routeGroup.GET("/sinks", controllers.GetSinksHealth)     // check connectivity, auth and write permission of every export destination
+	routeGroup.GET("/sinks/lag", controllers.GetSinksStatus) // get the lag and the backlog of every sink of the fan-out
+
+	routeGroup.GET("/pressure", controllers.GetPressureStatus)   // get the memory and disk pressure and the entries shed under it
+	routeGroup.GET("/retention", controllers.GetRetentionStatus) // get the retained entries and the entries evicted by size, count and age
+
+	routeGroup.GET("/mirror", controllers.GetMirrorStatus)
+
+	routeGroup.GET("/issues", controllers.GetIssuesStatus)
+
+	routeGroup.GET("/lifecycleWebhooks", controllers.GetLifecycleWebhooksStatus)
+
+	routeGroup.GET("/archive", controllers.GetArchiveStatus)
+
+	routeGroup.GET("/queryCache", controllers.GetQueryCacheStatus)   // get the hits and misses of the cache of the entries queries
+	routeGroup.GET("/queryLimits", controllers.GetQueryLimitsStatus) // get the running, waiting and rejected queries
+
+	routeGroup.GET("/markers", controllers.GetMarkers) // get deployment markers, optionally between from and to (unix ms)
+
 	routeGroup.GET("/recentTLSLinks", controllers.GetRecentTLSLinks)
 
 	routeGroup.GET("/resolving", controllers.GetCurrentResolvingInformation)
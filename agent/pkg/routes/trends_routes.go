@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/up9inc/mizu/agent/pkg/controllers"
+)
+
+// TrendsRoutes defines the group of the endpoint trends routes, the daily aggregates kept after the entries expire
+func TrendsRoutes(ginApp *gin.Engine) {
+	routeGroup := ginApp.Group("/trends")
+
+	routeGroup.GET("/daily", controllers.GetDailyTrends)               // volume, error rate and p95 of the endpoints per day
+	routeGroup.GET("/weekOverWeek", controllers.GetWeekOverWeekTrends) // a week of the endpoints contrasted with the week before
+}
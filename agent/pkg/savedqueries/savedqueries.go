@@ -0,0 +1,182 @@
+package savedqueries
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/utils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	FilePath   = shared.DataDirPath + shared.SavedQueriesFileName
+	maxQueries = 1000
+)
+
+var (
+	ErrNotFound      = errors.New("saved query not found")
+	ErrAlreadyExists = errors.New("a saved query with this name already exists")
+
+	// the names are used in urls and on the command line
+	namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,99}$`)
+)
+
+// Store keeps the saved queries of the team in a file of the data directory, so they're kept across restarts of the
+// API server
+type Store struct {
+	mutex    sync.Mutex
+	queries  map[string]*shared.SavedQuery
+	filePath string
+}
+
+var instance *Store
+var once sync.Once
+
+func GetInstance() *Store {
+	once.Do(func() {
+		instance = newStore(FilePath)
+	})
+	return instance
+}
+
+func newStore(filePath string) *Store {
+	store := &Store{queries: make(map[string]*shared.SavedQuery), filePath: filePath}
+
+	var queries []*shared.SavedQuery
+	if err := utils.ReadJsonFile(filePath, &queries); err != nil {
+		if !os.IsNotExist(err) {
+			logger.Log.Errorf("Error reading the saved queries from file, err: %v", err)
+		}
+		return store
+	}
+
+	for _, query := range queries {
+		store.queries[query.Name] = query
+	}
+	return store
+}
+
+// List returns the saved queries sorted by name
+func (store *Store) List() []*shared.SavedQuery {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	return store.sorted()
+}
+
+func (store *Store) Get(name string) (*shared.SavedQuery, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	query, ok := store.queries[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	queryCopy := *query
+	return &queryCopy, nil
+}
+
+// Create saves a new query, its name must be free
+func (store *Store) Create(name string, query string, owner string) (*shared.SavedQuery, error) {
+	if err := validate(name, query); err != nil {
+		return nil, err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	if _, ok := store.queries[name]; ok {
+		return nil, ErrAlreadyExists
+	}
+	if len(store.queries) >= maxQueries {
+		return nil, fmt.Errorf("there can't be more than %d saved queries", maxQueries)
+	}
+
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	savedQuery := &shared.SavedQuery{Name: name, Query: query, Owner: owner, CreatedAt: now, UpdatedAt: now}
+	store.queries[name] = savedQuery
+	store.save()
+
+	queryCopy := *savedQuery
+	return &queryCopy, nil
+}
+
+// Update changes the query string of a saved query, and its owner when owner isn't empty
+func (store *Store) Update(name string, query string, owner string) (*shared.SavedQuery, error) {
+	if err := validate(name, query); err != nil {
+		return nil, err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	savedQuery, ok := store.queries[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+
+	savedQuery.Query = query
+	if owner != "" {
+		savedQuery.Owner = owner
+	}
+	savedQuery.UpdatedAt = time.Now().UnixNano() / int64(time.Millisecond)
+	store.save()
+
+	queryCopy := *savedQuery
+	return &queryCopy, nil
+}
+
+func (store *Store) Delete(name string) (*shared.SavedQuery, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	savedQuery, ok := store.queries[name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(store.queries, name)
+	store.save()
+
+	return savedQuery, nil
+}
+
+func (store *Store) sorted() []*shared.SavedQuery {
+	queries := make([]*shared.SavedQuery, 0, len(store.queries))
+	for _, query := range store.queries {
+		queryCopy := *query
+		queries = append(queries, &queryCopy)
+	}
+	sort.Slice(queries, func(i, j int) bool {
+		return queries[i].Name < queries[j].Name
+	})
+
+	return queries
+}
+
+func (store *Store) save() {
+	if store.filePath == "" {
+		return
+	}
+
+	if err := utils.SaveJsonFile(store.filePath, store.sorted()); err != nil {
+		logger.Log.Errorf("Error saving the saved queries, err: %v", err)
+	}
+}
+
+func validate(name string, query string) error {
+	if !namePattern.MatchString(name) {
+		return fmt.Errorf("invalid name %q, a name is up to 100 letters, digits, dots, dashes and underscores", name)
+	}
+	if query == "" {
+		return errors.New("the query is missing")
+	}
+
+	return nil
+}
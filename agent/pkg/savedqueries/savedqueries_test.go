@@ -0,0 +1,57 @@
+package savedqueries
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestCreateUpdateDelete(t *testing.T) {
+	store := newStore("")
+
+	if _, err := store.Create("checkout-5xx", `http and response.status >= 500`, "jane"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Create("checkout-5xx", `http`, "john"); !errors.Is(err, ErrAlreadyExists) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", ErrAlreadyExists, err)
+	}
+
+	updated, err := store.Update("checkout-5xx", `http and response.status >= 500 and dst.name == "checkout"`, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Owner != "jane" || updated.Query != `http and response.status >= 500 and dst.name == "checkout"` {
+		t.Errorf("unexpected result: %+v", updated)
+	}
+
+	if _, err := store.Delete("checkout-5xx"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Get("checkout-5xx"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", ErrNotFound, err)
+	}
+}
+
+func TestInvalidName(t *testing.T) {
+	store := newStore("")
+
+	for _, name := range []string{"", "has space", "slash/name", "-dash-first"} {
+		if _, err := store.Create(name, `http`, ""); err == nil {
+			t.Errorf("expected an error for the name %q", name)
+		}
+	}
+}
+
+func TestPersisted(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), "saved-queries.json")
+
+	store := newStore(filePath)
+	_, _ = store.Create("slow", `elapsedTime > 1000`, "")
+	_, _ = store.Create("errors", `response.status >= 500`, "")
+
+	reloaded := newStore(filePath)
+	queries := reloaded.List()
+	if len(queries) != 2 || queries[0].Name != "errors" || queries[1].Query != `elapsedTime > 1000` {
+		t.Errorf("unexpected result: %+v", queries)
+	}
+}
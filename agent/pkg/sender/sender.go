@@ -0,0 +1,189 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/up9inc/mizu/shared"
+	"golang.org/x/net/http2"
+)
+
+const (
+	// SendIdHeaderName marks the requests sent by the API server so the entry captured for them can be found
+	SendIdHeaderName = "x-mizu-send-id"
+
+	defaultTimeout = 10 * time.Second
+	maxBodySize    = 1024 * 1024
+	// the compression flag and the length of the message precede each gRPC message
+	grpcPrefixLength = 5
+)
+
+var httpClient = &http.Client{
+	// the redirects are returned as is, following them would hide the response of the target
+	CheckRedirect: func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+var grpcClient = &http.Client{
+	Transport: &http2.Transport{
+		// gRPC services in the cluster are mostly served over cleartext HTTP/2
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	},
+}
+
+// Send sends the request to the target and returns its response, the request is marked with a send id
+func Send(sendRequest *shared.SendRequest) (*shared.SendResponse, error) {
+	timeout := defaultTimeout
+	if sendRequest.TimeoutMs > 0 {
+		timeout = time.Duration(sendRequest.TimeoutMs) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var req *http.Request
+	var err error
+	if sendRequest.Protocol == shared.SendProtocolGrpc {
+		req, err = newGrpcRequest(ctx, sendRequest)
+	} else {
+		req, err = http.NewRequestWithContext(ctx, sendRequest.Method, sendRequest.Url, strings.NewReader(sendRequest.Body))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for name, value := range sendRequest.Headers {
+		req.Header.Set(name, value)
+	}
+	sendId := uuid.NewString()
+	// set as is so the HTTP/2 and HTTP/1 entries have the same header name
+	req.Header[SendIdHeaderName] = []string{sendId}
+
+	client := httpClient
+	if sendRequest.Protocol == shared.SendProtocolGrpc {
+		client = grpcClient
+	}
+
+	start := time.Now()
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	sendResponse := &shared.SendResponse{
+		SendId:      sendId,
+		Status:      res.StatusCode,
+		StatusText:  http.StatusText(res.StatusCode),
+		Headers:     flattenHeader(res.Header),
+		Trailers:    flattenHeader(res.Trailer),
+		ElapsedTime: time.Since(start).Milliseconds(),
+	}
+
+	if sendRequest.Protocol == shared.SendProtocolGrpc {
+		if err := setGrpcResponse(sendResponse, res, body); err != nil {
+			return nil, err
+		}
+	} else if utf8.Valid(body) {
+		sendResponse.Body = string(body)
+	} else {
+		sendResponse.Body = base64.StdEncoding.EncodeToString(body)
+		sendResponse.BodyEncoding = shared.SendBodyEncodingBase64
+	}
+
+	return sendResponse, nil
+}
+
+func newGrpcRequest(ctx context.Context, sendRequest *shared.SendRequest) (*http.Request, error) {
+	message, err := base64.StdEncoding.DecodeString(sendRequest.Body)
+	if err != nil {
+		return nil, fmt.Errorf("the body of a gRPC request must be a base64 encoded protobuf message: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sendRequest.Url, bytes.NewReader(frameGrpcMessage(message)))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("content-type", "application/grpc")
+	req.Header.Set("te", "trailers")
+
+	return req, nil
+}
+
+func frameGrpcMessage(message []byte) []byte {
+	framed := make([]byte, grpcPrefixLength+len(message))
+	binary.BigEndian.PutUint32(framed[1:grpcPrefixLength], uint32(len(message)))
+	copy(framed[grpcPrefixLength:], message)
+	return framed
+}
+
+func parseGrpcMessages(body []byte) ([][]byte, error) {
+	messages := make([][]byte, 0)
+	for len(body) > 0 {
+		if len(body) < grpcPrefixLength {
+			return nil, fmt.Errorf("truncated gRPC message prefix")
+		}
+		length := int(binary.BigEndian.Uint32(body[1:grpcPrefixLength]))
+		if len(body) < grpcPrefixLength+length {
+			return nil, fmt.Errorf("truncated gRPC message, expected %d bytes", length)
+		}
+		messages = append(messages, body[grpcPrefixLength:grpcPrefixLength+length])
+		body = body[grpcPrefixLength+length:]
+	}
+	return messages, nil
+}
+
+func setGrpcResponse(sendResponse *shared.SendResponse, res *http.Response, body []byte) error {
+	messages, err := parseGrpcMessages(body)
+	if err != nil {
+		return err
+	}
+	for _, message := range messages {
+		sendResponse.GrpcMessages = append(sendResponse.GrpcMessages, base64.StdEncoding.EncodeToString(message))
+	}
+
+	// a response without messages carries the status in its headers
+	status := res.Trailer.Get("grpc-status")
+	sendResponse.GrpcMessage = res.Trailer.Get("grpc-message")
+	if status == "" {
+		status = res.Header.Get("grpc-status")
+		sendResponse.GrpcMessage = res.Header.Get("grpc-message")
+	}
+	if status != "" {
+		grpcStatus, err := strconv.Atoi(status)
+		if err != nil {
+			return fmt.Errorf("invalid grpc-status %q", status)
+		}
+		sendResponse.GrpcStatus = &grpcStatus
+	}
+
+	return nil
+}
+
+func flattenHeader(header http.Header) map[string]string {
+	flattened := make(map[string]string, len(header))
+	for name, values := range header {
+		flattened[name] = strings.Join(values, ", ")
+	}
+	return flattened
+}
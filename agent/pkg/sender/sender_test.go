@@ -0,0 +1,78 @@
+package sender
+
+import (
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+func TestSendHttp(t *testing.T) {
+	var received *http.Request
+	var receivedBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ := ioutil.ReadAll(r.Body)
+		receivedBody = string(body)
+		w.Header().Set("Location", "/elsewhere")
+		w.WriteHeader(http.StatusFound)
+		_, _ = w.Write([]byte("moved"))
+	}))
+	defer server.Close()
+
+	sendResponse, err := Send(&shared.SendRequest{
+		Method:  http.MethodPost,
+		Url:     server.URL + "/orders",
+		Headers: map[string]string{"Content-Type": "application/json"},
+		Body:    `{"item":"socks"}`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if received.Method != http.MethodPost || received.URL.Path != "/orders" || receivedBody != `{"item":"socks"}` {
+		t.Errorf("unexpected request %s %s %s", received.Method, received.URL.Path, receivedBody)
+	}
+	if received.Header.Get(SendIdHeaderName) != sendResponse.SendId || sendResponse.SendId == "" {
+		t.Errorf("expected the send id %q, got %q", sendResponse.SendId, received.Header.Get(SendIdHeaderName))
+	}
+	// the redirect isn't followed
+	if sendResponse.Status != http.StatusFound || sendResponse.Headers["Location"] != "/elsewhere" || sendResponse.Body != "moved" {
+		t.Errorf("unexpected response %+v", sendResponse)
+	}
+}
+
+func TestSendHttpBinaryBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte{0xff, 0x00, 0xfe})
+	}))
+	defer server.Close()
+
+	sendResponse, err := Send(&shared.SendRequest{Method: http.MethodGet, Url: server.URL})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if sendResponse.BodyEncoding != shared.SendBodyEncodingBase64 || sendResponse.Body != base64.StdEncoding.EncodeToString([]byte{0xff, 0x00, 0xfe}) {
+		t.Errorf("expected a base64 encoded body, got %q %q", sendResponse.BodyEncoding, sendResponse.Body)
+	}
+}
+
+func TestGrpcMessages(t *testing.T) {
+	body := append(frameGrpcMessage([]byte("first")), frameGrpcMessage([]byte{})...)
+
+	messages, err := parseGrpcMessages(body)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(messages) != 2 || string(messages[0]) != "first" || len(messages[1]) != 0 {
+		t.Errorf("unexpected messages %q", messages)
+	}
+
+	if _, err := parseGrpcMessages(body[:7]); err == nil {
+		t.Error("expected an error for a truncated message")
+	}
+}
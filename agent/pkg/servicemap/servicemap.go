@@ -3,6 +3,7 @@ package servicemap
 import (
 	"sync"
 
+	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/logger"
 	tapApi "github.com/up9inc/mizu/tap/api"
 )
@@ -11,6 +12,9 @@ const (
 	ServiceMapEnabled  = "enabled"
 	ServiceMapDisabled = "disabled"
 	UnresolvedNodeName = "unresolved"
+
+	// entries with a status from this one on are counted as errors of their edge, like the 5xx of http
+	errorStatus = 500
 )
 
 var instance *defaultServiceMap
@@ -25,6 +29,8 @@ func GetDefaultServiceMapInstance() *defaultServiceMap {
 }
 
 type defaultServiceMap struct {
+	// the entries are added by the entries handler while the map is read by the requests
+	lock             sync.RWMutex
 	enabled          bool
 	graph            *graph
 	entriesProcessed int
@@ -32,15 +38,17 @@ type defaultServiceMap struct {
 
 type ServiceMapSink interface {
 	NewTCPEntry(source *tapApi.TCP, destination *tapApi.TCP, protocol *tapApi.Protocol)
+	// NewTCPEntryWithStatus is NewTCPEntry of an entry whose status counts it as an error of its edge from 500 on
+	NewTCPEntryWithStatus(source *tapApi.TCP, destination *tapApi.TCP, protocol *tapApi.Protocol, status int)
 }
 
 type ServiceMap interface {
 	Enable()
 	Disable()
 	IsEnabled() bool
-	GetStatus() ServiceMapStatus
-	GetNodes() []ServiceMapNode
-	GetEdges() []ServiceMapEdge
+	GetStatus() shared.ServiceMapStatus
+	GetNodes() []shared.ServiceMapNode
+	GetEdges() []shared.ServiceMapEdge
 	GetEntriesProcessedCount() int
 	GetNodesCount() int
 	GetEdgesCount() int
@@ -71,6 +79,7 @@ type nodeData struct {
 type edgeProtocol struct {
 	protocol *tapApi.Protocol
 	count    int
+	errors   int
 }
 
 type edgeData struct {
@@ -97,12 +106,13 @@ func newNodeData(id int, e *tapApi.TCP) *nodeData {
 	}
 }
 
-func newEdgeData(p *tapApi.Protocol) *edgeData {
+func newEdgeData(p *tapApi.Protocol, errors int) *edgeData {
 	return &edgeData{
 		data: map[key]*edgeProtocol{
 			key(p.Name): {
 				protocol: p,
 				count:    1,
+				errors:   errors,
 			},
 		},
 	}
@@ -122,7 +132,7 @@ func (s *defaultServiceMap) addNode(k key, e *tapApi.TCP) (*nodeData, bool) {
 	return nd, false
 }
 
-func (s *defaultServiceMap) addEdge(u, v *entryData, p *tapApi.Protocol) {
+func (s *defaultServiceMap) addEdge(u, v *entryData, p *tapApi.Protocol, failed bool) {
 	if n, ok := s.addNode(u.key, u.entry); !ok {
 		n.count++
 	}
@@ -134,6 +144,11 @@ func (s *defaultServiceMap) addEdge(u, v *entryData, p *tapApi.Protocol) {
 		s.graph.Edges[u.key] = make(map[key]*edgeData)
 	}
 
+	errors := 0
+	if failed {
+		errors = 1
+	}
+
 	// new edge u -> v pair
 	// protocol is the same for u and v
 	if e, ok := s.graph.Edges[u.key][v.key]; ok {
@@ -142,38 +157,60 @@ func (s *defaultServiceMap) addEdge(u, v *entryData, p *tapApi.Protocol) {
 
 		k := key(p.Name)
 		if pd, pOk := e.data[k]; pOk {
-			// protocol key already exists, just increment the count
+			// protocol key already exists, just increment the counts
 			pd.count++
+			pd.errors += errors
 		} else {
 			// new protocol key
 			e.data[k] = &edgeProtocol{
 				protocol: p,
 				count:    1,
+				errors:   errors,
 			}
 		}
 	} else {
 		// new edge data for u -> v pair
-		s.graph.Edges[u.key][v.key] = newEdgeData(p)
+		s.graph.Edges[u.key][v.key] = newEdgeData(p, errors)
 	}
 
 	s.entriesProcessed++
 }
 
 func (s *defaultServiceMap) Enable() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
 	s.enabled = true
 }
 
 func (s *defaultServiceMap) Disable() {
-	s.Reset()
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.reset()
 	s.enabled = false
 }
 
 func (s *defaultServiceMap) IsEnabled() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
 	return s.enabled
 }
 
 func (s *defaultServiceMap) NewTCPEntry(src *tapApi.TCP, dst *tapApi.TCP, p *tapApi.Protocol) {
-	if !s.IsEnabled() {
+	s.newTCPEntry(src, dst, p, false)
+}
+
+func (s *defaultServiceMap) NewTCPEntryWithStatus(src *tapApi.TCP, dst *tapApi.TCP, p *tapApi.Protocol, status int) {
+	s.newTCPEntry(src, dst, p, status >= errorStatus)
+}
+
+func (s *defaultServiceMap) newTCPEntry(src *tapApi.TCP, dst *tapApi.TCP, p *tapApi.Protocol, failed bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if !s.enabled {
 		return
 	}
 
@@ -206,27 +243,33 @@ func (s *defaultServiceMap) NewTCPEntry(src *tapApi.TCP, dst *tapApi.TCP, p *tap
 		}
 	}
 
-	s.addEdge(srcEntry, dstEntry, p)
+	s.addEdge(srcEntry, dstEntry, p, failed)
 }
 
-func (s *defaultServiceMap) GetStatus() ServiceMapStatus {
+func (s *defaultServiceMap) GetStatus() shared.ServiceMapStatus {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
 	status := ServiceMapDisabled
-	if s.IsEnabled() {
+	if s.enabled {
 		status = ServiceMapEnabled
 	}
 
-	return ServiceMapStatus{
+	return shared.ServiceMapStatus{
 		Status:                status,
 		EntriesProcessedCount: s.entriesProcessed,
-		NodeCount:             s.GetNodesCount(),
-		EdgeCount:             s.GetEdgesCount(),
+		NodeCount:             s.nodesCount(),
+		EdgeCount:             s.edgesCount(),
 	}
 }
 
-func (s *defaultServiceMap) GetNodes() []ServiceMapNode {
-	var nodes []ServiceMapNode
+func (s *defaultServiceMap) GetNodes() []shared.ServiceMapNode {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var nodes []shared.ServiceMapNode
 	for i, n := range s.graph.Nodes {
-		nodes = append(nodes, ServiceMapNode{
+		nodes = append(nodes, shared.ServiceMapNode{
 			Id:    n.id,
 			Name:  string(i),
 			Entry: n.entry,
@@ -236,26 +279,31 @@ func (s *defaultServiceMap) GetNodes() []ServiceMapNode {
 	return nodes
 }
 
-func (s *defaultServiceMap) GetEdges() []ServiceMapEdge {
-	var edges []ServiceMapEdge
+func (s *defaultServiceMap) GetEdges() []shared.ServiceMapEdge {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var edges []shared.ServiceMapEdge
 	for u, m := range s.graph.Edges {
 		for v := range m {
 			for _, p := range s.graph.Edges[u][v].data {
-				edges = append(edges, ServiceMapEdge{
-					Source: ServiceMapNode{
+				edges = append(edges, shared.ServiceMapEdge{
+					Source: shared.ServiceMapNode{
 						Id:    s.graph.Nodes[u].id,
 						Name:  string(u),
 						Entry: s.graph.Nodes[u].entry,
 						Count: s.graph.Nodes[u].count,
 					},
-					Destination: ServiceMapNode{
+					Destination: shared.ServiceMapNode{
 						Id:    s.graph.Nodes[v].id,
 						Name:  string(v),
 						Entry: s.graph.Nodes[v].entry,
 						Count: s.graph.Nodes[v].count,
 					},
-					Count:    p.count,
-					Protocol: p.protocol,
+					Count:     p.count,
+					Errors:    p.errors,
+					ErrorRate: float64(p.errors) / float64(p.count),
+					Protocol:  p.protocol,
 				})
 			}
 		}
@@ -264,14 +312,31 @@ func (s *defaultServiceMap) GetEdges() []ServiceMapEdge {
 }
 
 func (s *defaultServiceMap) GetEntriesProcessedCount() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
 	return s.entriesProcessed
 }
 
 func (s *defaultServiceMap) GetNodesCount() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.nodesCount()
+}
+
+func (s *defaultServiceMap) nodesCount() int {
 	return len(s.graph.Nodes)
 }
 
 func (s *defaultServiceMap) GetEdgesCount() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.edgesCount()
+}
+
+func (s *defaultServiceMap) edgesCount() int {
 	var count int
 	for u, m := range s.graph.Edges {
 		for v := range m {
@@ -284,6 +349,13 @@ func (s *defaultServiceMap) GetEdgesCount() int {
 }
 
 func (s *defaultServiceMap) Reset() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.reset()
+}
+
+func (s *defaultServiceMap) reset() {
 	s.entriesProcessed = 0
 	s.graph = newDirectedGraph()
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/suite"
+	"github.com/up9inc/mizu/shared"
 	tapApi "github.com/up9inc/mizu/tap/api"
 )
 
@@ -259,7 +260,7 @@ func (s *ServiceMapEnabledSuite) TestServiceMap() {
 	dNode := -1
 	unresolvedNode := -1
 	unresolvedNode2 := -1
-	var validateNode = func(node ServiceMapNode, entryName string, count int) int {
+	var validateNode = func(node shared.ServiceMapNode, entryName string, count int) int {
 		// id
 		assert.GreaterOrEqual(node.Id, 1)
 		assert.LessOrEqual(node.Id, expectedNodeCount)
@@ -321,7 +322,7 @@ func (s *ServiceMapEnabledSuite) TestServiceMap() {
 	buEdge := -1
 	cdEdge := -1
 	acEdge := -1
-	var validateEdge = func(edge ServiceMapEdge, sourceEntryName string, destEntryName string, protocolName string, protocolCount int) {
+	var validateEdge = func(edge shared.ServiceMapEdge, sourceEntryName string, destEntryName string, protocolName string, protocolCount int) {
 		// source node
 		assert.Contains(nodeIds, edge.Source.Id)
 		assert.LessOrEqual(edge.Source.Id, expectedNodeCount)
@@ -403,10 +404,31 @@ func (s *ServiceMapEnabledSuite) TestServiceMap() {
 	assert.Equal(0, status.EdgeCount)
 
 	// Nodes after reset
-	assert.Equal([]ServiceMapNode(nil), nodes)
+	assert.Equal([]shared.ServiceMapNode(nil), nodes)
 
 	// Edges after reset
-	assert.Equal([]ServiceMapEdge(nil), edges)
+	assert.Equal([]shared.ServiceMapEdge(nil), edges)
+}
+
+func (s *ServiceMapEnabledSuite) TestServiceMapErrors() {
+	assert := s.Assert()
+
+	s.instance.Reset()
+
+	// A -> B - HTTP, one of the four entries failed
+	s.instance.NewTCPEntryWithStatus(TCPEntryA, TCPEntryB, ProtocolHttp, 200)
+	s.instance.NewTCPEntryWithStatus(TCPEntryA, TCPEntryB, ProtocolHttp, 404)
+	s.instance.NewTCPEntryWithStatus(TCPEntryA, TCPEntryB, ProtocolHttp, 503)
+	s.instance.NewTCPEntry(TCPEntryA, TCPEntryB, ProtocolHttp)
+
+	edges := s.instance.GetEdges()
+
+	assert.Equal(1, len(edges))
+	assert.Equal(4, edges[0].Count)
+	assert.Equal(1, edges[0].Errors)
+	assert.Equal(0.25, edges[0].ErrorRate)
+
+	s.instance.Reset()
 }
 
 func TestServiceMapSuite(t *testing.T) {
@@ -0,0 +1,303 @@
+package sinks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	basenine "github.com/up9inc/basenine/client/go"
+	"github.com/up9inc/mizu/agent/pkg/elastic"
+	"github.com/up9inc/mizu/agent/pkg/exportqueue"
+	"github.com/up9inc/mizu/agent/pkg/kafka"
+	"github.com/up9inc/mizu/agent/pkg/metrics"
+	"github.com/up9inc/mizu/agent/pkg/timequery"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/transform"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const reconnectInterval = 10 * time.Second
+
+// destination is where a sink delivers its entries, through an export queue of its own
+type destination interface {
+	PushEntry(entry *tapApi.Entry)
+	GetExportQueueStats() *exportqueue.Stats
+	CheckHealth() *shared.SinkHealth
+}
+
+type sink struct {
+	config      shared.SinkConfig
+	destination destination
+	entries     uint64
+	lastId      uint
+	lastEntryAt int64 // the newest timestamp streamed, the stream is opened again from it
+	lagMs       int64
+	err         string
+}
+
+// streamQuery is the query of the entries of the sink stored from the timestamp on
+func (sink *sink) streamQuery(from int64, now time.Time) (string, error) {
+	window := fmt.Sprintf("timestamp >= %d", from)
+	if sink.config.Query == "" {
+		return window, nil
+	}
+
+	expanded, err := timequery.Expand(sink.config.Query, now)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("(%s) and %s", expanded.Query, window), nil
+}
+
+// observe counts an entry streamed at now, it returns false for the entries a reconnected stream sends again. The ids
+// of a stream only grow, they start over when the database restarts but the entries stored since are newer
+func (sink *sink) observe(entry *tapApi.Entry, now int64) bool {
+	if entry.Id <= sink.lastId && entry.Timestamp <= sink.lastEntryAt {
+		return false
+	}
+
+	sink.entries++
+	sink.lastId = entry.Id
+	if entry.Timestamp > sink.lastEntryAt {
+		sink.lastEntryAt = entry.Timestamp
+	}
+	sink.lagMs = now - entry.Timestamp
+	return true
+}
+
+func (sink *sink) status() *shared.SinkStatus {
+	status := &shared.SinkStatus{
+		Name:        sink.config.Name,
+		Type:        sink.config.Type,
+		Query:       sink.config.Query,
+		Delivery:    sink.config.Delivery,
+		Entries:     sink.entries,
+		LastEntryAt: sink.lastEntryAt,
+		LagMs:       sink.lagMs,
+		Error:       sink.err,
+	}
+
+	if stats := sink.destination.GetExportQueueStats(); stats != nil {
+		status.Backlog = stats.MemoryItems + stats.SpilledItems
+		status.Delivered = stats.DeliveredItems
+		status.Dropped = stats.DroppedItems
+		status.Unavailable = stats.SinkUnavailable
+	}
+
+	return status
+}
+
+// FanOut delivers the stored entries to several sinks at once, every sink streams the entries matching its query from
+// the database and delivers them through its own export queue, so the sinks don't wait for one another
+type FanOut struct {
+	mutex sync.Mutex
+	sinks []*sink
+}
+
+var instance *FanOut
+var once sync.Once
+
+func GetInstance() *FanOut {
+	once.Do(func() {
+		instance = &FanOut{}
+	})
+	return instance
+}
+
+// Configure starts the sinks, the entries stored before are not delivered
+func (fanOut *FanOut) Configure(configs []shared.SinkConfig, cluster string, maxExportQueueDiskSizeBytes int64) {
+	fanOut.mutex.Lock()
+	defer fanOut.mutex.Unlock()
+
+	from := time.Now().UnixNano() / int64(time.Millisecond)
+	for _, config := range configs {
+		destination, err := newDestination(config, cluster, maxExportQueueDiskSizeBytes)
+		if err != nil {
+			logger.Log.Errorf("Sink %s disabled, %v", config.Name, err)
+			continue
+		}
+
+		sink := &sink{config: config, destination: destination}
+		fanOut.sinks = append(fanOut.sinks, sink)
+		go fanOut.watch(sink, from)
+	}
+
+	if len(fanOut.sinks) > 0 {
+		logger.Log.Infof("Delivering the entries to %d sinks", len(fanOut.sinks))
+	}
+}
+
+func (fanOut *FanOut) GetStatus() []*shared.SinkStatus {
+	fanOut.mutex.Lock()
+	defer fanOut.mutex.Unlock()
+
+	statuses := make([]*shared.SinkStatus, 0, len(fanOut.sinks))
+	for _, sink := range fanOut.sinks {
+		statuses = append(statuses, sink.status())
+	}
+
+	return statuses
+}
+
+// GetExportQueuesStats returns the export queue of every sink by its name
+func (fanOut *FanOut) GetExportQueuesStats() map[string]*exportqueue.Stats {
+	fanOut.mutex.Lock()
+	defer fanOut.mutex.Unlock()
+
+	queuesStats := make(map[string]*exportqueue.Stats)
+	for _, sink := range fanOut.sinks {
+		if stats := sink.destination.GetExportQueueStats(); stats != nil {
+			queuesStats[sinkLabel(sink.config)] = stats
+		}
+	}
+
+	return queuesStats
+}
+
+// CheckHealth validates the destination of every sink that can be probed
+func (fanOut *FanOut) CheckHealth() []*shared.SinkHealth {
+	fanOut.mutex.Lock()
+	sinks := make([]*sink, len(fanOut.sinks))
+	copy(sinks, fanOut.sinks)
+	fanOut.mutex.Unlock()
+
+	sinksHealth := make([]*shared.SinkHealth, 0, len(sinks))
+	for _, sink := range sinks {
+		if health := sink.destination.CheckHealth(); health != nil {
+			health.Sink = sinkLabel(sink.config)
+			sinksHealth = append(sinksHealth, health)
+		}
+	}
+
+	return sinksHealth
+}
+
+// watch streams the entries of the sink as long as the API server runs, the stream is opened again from the last
+// streamed entry when it fails
+func (fanOut *FanOut) watch(sink *sink, from int64) {
+	for {
+		err := fanOut.stream(sink, from)
+
+		fanOut.mutex.Lock()
+		sink.err = err.Error()
+		if sink.lastEntryAt > 0 {
+			from = sink.lastEntryAt
+		}
+		fanOut.mutex.Unlock()
+		logger.Log.Warningf("Error streaming the entries of sink %s, retrying in %v: %v", sink.config.Name, reconnectInterval, err)
+
+		time.Sleep(reconnectInterval)
+	}
+}
+
+func (fanOut *FanOut) stream(sink *sink, from int64) error {
+	query, err := sink.streamQuery(from, time.Now())
+	if err != nil {
+		return err
+	}
+
+	connection, err := basenine.NewConnection(shared.BasenineHost, shared.BaseninePort)
+	if err != nil {
+		return err
+	}
+	defer connection.Close()
+
+	fanOut.mutex.Lock()
+	sink.err = ""
+	fanOut.mutex.Unlock()
+
+	data := make(chan []byte)
+	meta := make(chan []byte)
+	go func() {
+		// the metadata of the stream isn't needed
+		for bytes := range meta {
+			if string(bytes) == basenine.CloseChannel {
+				return
+			}
+		}
+	}()
+	defer func() {
+		meta <- []byte(basenine.CloseChannel)
+	}()
+
+	connection.Query(query, data, meta)
+
+	for bytes := range data {
+		if string(bytes) == basenine.CloseChannel {
+			break
+		}
+		fanOut.entryStored(sink, bytes)
+	}
+
+	return errors.New("the database closed the stream")
+}
+
+func (fanOut *FanOut) entryStored(sink *sink, data []byte) {
+	var entry *tapApi.Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		logger.Log.Debugf("Error parsing an entry of sink %s: %v", sink.config.Name, err)
+		return
+	}
+
+	fanOut.mutex.Lock()
+	observed := sink.observe(entry, time.Now().UnixNano()/int64(time.Millisecond))
+	entries, lagMs := sink.entries, sink.lagMs
+	fanOut.mutex.Unlock()
+
+	if !observed {
+		return
+	}
+
+	metrics.GetInstance().SetSink(sink.config.Name, entries, lagMs)
+	sink.destination.PushEntry(entry)
+}
+
+func newDestination(config shared.SinkConfig, cluster string, maxExportQueueDiskSizeBytes int64) (destination, error) {
+	var entryTransform *transform.Expression
+	if config.Transform != "" {
+		var err error
+		if entryTransform, err = transform.Compile(config.Transform); err != nil {
+			return nil, err
+		}
+	}
+
+	// the best-effort sinks keep their queue in memory only
+	if config.Delivery == shared.SinkDeliveryBestEffort {
+		maxExportQueueDiskSizeBytes = 0
+	}
+
+	queueName := fmt.Sprintf("sink_%s", config.Name)
+	var destination destination
+	switch config.Type {
+	case shared.SinkTypeElastic:
+		if config.Transform != "" {
+			config.Elastic.Transform = config.Transform
+		}
+		destination = elastic.New(queueName, config.Elastic, cluster, maxExportQueueDiskSizeBytes)
+	case shared.SinkTypeKafka:
+		destination = kafka.New(queueName, config.Kafka, entryTransform, cluster, maxExportQueueDiskSizeBytes)
+	case shared.SinkTypeWebhook, shared.SinkTypeSlack:
+		webhook, err := newWebhook(queueName, config, entryTransform, cluster, maxExportQueueDiskSizeBytes)
+		if err != nil {
+			return nil, err
+		}
+		destination = webhook
+	default:
+		return nil, fmt.Errorf("unknown sink type %s", config.Type)
+	}
+
+	// the clients log why they're left unconfigured
+	if destination.GetExportQueueStats() == nil {
+		return nil, fmt.Errorf("the %s destination isn't configured", config.Type)
+	}
+
+	return destination, nil
+}
+
+func sinkLabel(config shared.SinkConfig) string {
+	return fmt.Sprintf("%s sink %s", config.Type, config.Name)
+}
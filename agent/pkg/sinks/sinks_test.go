@@ -0,0 +1,154 @@
+package sinks
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/transform"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+func TestStreamQuery(t *testing.T) {
+	now := time.Unix(1646128800, 0)
+	tests := map[string]string{
+		``:                              `timestamp >= 1646128000000`,
+		`response.status >= 500`:        `(response.status >= 500) and timestamp >= 1646128000000`,
+		`http and since("1h") or redis`: `(http and timestamp >= 1646125200000 or redis) and timestamp >= 1646128000000`,
+	}
+
+	for query, expected := range tests {
+		sink := &sink{config: shared.SinkConfig{Query: query}}
+		actual, err := sink.streamQuery(1646128000000, now)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", query, err)
+			continue
+		}
+		if actual != expected {
+			t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+		}
+	}
+}
+
+func TestObserveSkipsStreamedEntries(t *testing.T) {
+	sink := &sink{}
+	streamed := []*tapApi.Entry{
+		{Id: 1, Timestamp: 1000},
+		{Id: 2, Timestamp: 1200},
+		{Id: 3, Timestamp: 1100},
+	}
+	for _, entry := range streamed {
+		if !sink.observe(entry, 1500) {
+			t.Errorf("expected entry %d to be delivered", entry.Id)
+		}
+	}
+	if sink.lastEntryAt != 1200 || sink.lagMs != 400 {
+		t.Errorf("unexpected result - expected: %v %v, actual: %v %v", 1200, 400, sink.lastEntryAt, sink.lagMs)
+	}
+
+	// the reconnected stream starts from the newest timestamp
+	if sink.observe(&tapApi.Entry{Id: 2, Timestamp: 1200}, 2000) {
+		t.Errorf("expected a streamed entry to be skipped")
+	}
+	if !sink.observe(&tapApi.Entry{Id: 4, Timestamp: 1200}, 2000) {
+		t.Errorf("expected a new entry to be delivered")
+	}
+
+	// the ids start over when the database restarts
+	if !sink.observe(&tapApi.Entry{Id: 1, Timestamp: 1800}, 2000) {
+		t.Errorf("expected an entry of a restarted database to be delivered")
+	}
+	if sink.entries != 5 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 5, sink.entries)
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+	}))
+	defer server.Close()
+
+	entryTransform, err := transform.Compile(`{id: .entryId, status: .response.status}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	webhook := &webhook{url: server.URL, batch: true, transform: entryTransform, client: server.Client()}
+	items := make([][]byte, 0)
+	for _, entryId := range []string{"a", "b"} {
+		item, err := webhook.newItem(&tapApi.Entry{EntryId: entryId, Response: map[string]interface{}{"status": 503}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		items = append(items, item)
+	}
+
+	if err := webhook.deliver(items); err != nil {
+		t.Fatal(err)
+	}
+
+	var posted []map[string]interface{}
+	if err := json.Unmarshal([]byte(bodies[0]), &posted); err != nil {
+		t.Fatalf("unexpected body %s: %v", bodies[0], err)
+	}
+	if len(posted) != 2 || posted[1]["id"] != "b" || posted[1]["status"] != float64(503) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "2 transformed entries", bodies[0])
+	}
+}
+
+func TestWebhookDeliverEntry(t *testing.T) {
+	var body string
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := ioutil.ReadAll(r.Body)
+		body = string(raw)
+		authorization = r.Header.Get("Authorization")
+	}))
+	defer server.Close()
+
+	webhook := &webhook{url: server.URL, headers: map[string]string{"Authorization": "Bearer token"}, client: server.Client()}
+	item, err := webhook.newItem(&tapApi.Entry{EntryId: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := webhook.deliver([][]byte{item}); err != nil {
+		t.Fatal(err)
+	}
+
+	var posted map[string]interface{}
+	if err := json.Unmarshal([]byte(body), &posted); err != nil || posted["entryId"] != "a" {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "the entry as a json object", body)
+	}
+	if authorization != "Bearer token" {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "Bearer token", authorization)
+	}
+}
+
+func TestSlackItem(t *testing.T) {
+	webhook := &webhook{slack: true, cluster: "prod-eu"}
+	entry := &tapApi.Entry{
+		EntryId:     "01FXYZ",
+		Protocol:    tapApi.Protocol{Abbreviation: "HTTP"},
+		Source:      &tapApi.TCP{IP: "10.0.0.1", Port: "5000"},
+		Destination: &tapApi.TCP{Name: "payments.shop"},
+		Timestamp:   1646128800000,
+	}
+
+	item, err := webhook.newItem(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"text":"*HTTP* entry from 10.0.0.1:5000 to payments.shop at 2022-03-01T10:00:00Z, on prod-eu (01FXYZ)"}`
+	if string(item) != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, string(item))
+	}
+}
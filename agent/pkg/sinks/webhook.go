@@ -0,0 +1,189 @@
+package sinks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"path"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/exportqueue"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/transform"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const requestTimeout = 10 * time.Second
+
+// webhook posts the entries of a sink as a json object each or in json arrays, or to slack as a message each, the
+// export queue retries them with a backoff while the webhook is unavailable
+type webhook struct {
+	url       string
+	headers   map[string]string
+	slack     bool
+	batch     bool
+	transform *transform.Expression
+	cluster   string
+	client    *http.Client
+	queue     *exportqueue.Queue
+}
+
+func newWebhook(name string, config shared.SinkConfig, entryTransform *transform.Expression, cluster string, maxExportQueueDiskSizeBytes int64) (*webhook, error) {
+	if config.WebhookUrl == "" {
+		return nil, fmt.Errorf("the webhook url is missing")
+	}
+
+	slack := config.Type == shared.SinkTypeSlack
+	batchSize := config.WebhookBatchSize
+	if slack || batchSize < 1 {
+		batchSize = 1
+	}
+
+	webhook := &webhook{
+		url:       config.WebhookUrl,
+		headers:   config.WebhookHeaders,
+		slack:     slack,
+		batch:     batchSize > 1,
+		transform: entryTransform,
+		cluster:   cluster,
+		client:    &http.Client{Timeout: requestTimeout},
+	}
+
+	spillPath := path.Join(shared.DataDirPath, fmt.Sprintf("%s_export_queue", name))
+	queue, err := exportqueue.NewBatched(name, spillPath, exportqueue.DefaultMaxMemoryItems, maxExportQueueDiskSizeBytes, batchSize, webhook.deliver)
+	if err != nil {
+		return nil, err
+	}
+	webhook.queue = queue
+
+	return webhook, nil
+}
+
+func (webhook *webhook) PushEntry(entry *tapApi.Entry) {
+	item, err := webhook.newItem(entry)
+	if err != nil {
+		logger.Log.Debugf("Failed preparing an entry for %s, skipping it: %v", webhook.url, err)
+		return
+	}
+
+	webhook.queue.Push(item)
+}
+
+func (webhook *webhook) GetExportQueueStats() *exportqueue.Stats {
+	stats := webhook.queue.GetStats()
+	return &stats
+}
+
+// CheckHealth returns nil, a webhook can't be probed without posting to it
+func (webhook *webhook) CheckHealth() *shared.SinkHealth {
+	return nil
+}
+
+// newItem is the posted json of the entry, the slack message of the entry for slack
+func (webhook *webhook) newItem(entry *tapApi.Entry) ([]byte, error) {
+	if entry.Cluster == "" {
+		entry.Cluster = webhook.cluster
+	}
+
+	if !webhook.slack {
+		entryJson, err := json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+		if webhook.transform == nil {
+			return entryJson, nil
+		}
+		return webhook.transform.ApplyJson(entryJson)
+	}
+
+	text := slackText(entry)
+	if webhook.transform != nil {
+		var err error
+		if text, err = webhook.transformText(entry); err != nil {
+			return nil, err
+		}
+	}
+
+	return json.Marshal(map[string]string{"text": text})
+}
+
+// transformText is the text the transform of a slack sink yields, a value that isn't a string is posted as json
+func (webhook *webhook) transformText(entry *tapApi.Entry) (string, error) {
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		return "", err
+	}
+
+	transformed, err := webhook.transform.ApplyJson(entryJson)
+	if err != nil {
+		return "", err
+	}
+
+	var text string
+	if err := json.Unmarshal(transformed, &text); err != nil {
+		return string(transformed), nil
+	}
+	return text, nil
+}
+
+func (webhook *webhook) deliver(items [][]byte) error {
+	body := items[0]
+	if webhook.batch {
+		body = append([]byte{'['}, bytes.Join(items, []byte{','})...)
+		body = append(body, ']')
+	}
+
+	request, err := http.NewRequest(http.MethodPost, webhook.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for key, value := range webhook.headers {
+		request.Header.Set(key, value)
+	}
+
+	response, err := webhook.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode == http.StatusTooManyRequests || response.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+	// a rejected batch would be rejected again, retrying it would hold back the entries after it
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		logger.Log.Warningf("Webhook %s rejected %d entries, status %s", webhook.url, len(items), response.Status)
+	}
+
+	return nil
+}
+
+func slackText(entry *tapApi.Entry) string {
+	text := fmt.Sprintf("*%s* entry from %s to %s at %s", entry.Protocol.Abbreviation, endpointName(entry.Source),
+		endpointName(entry.Destination), time.Unix(0, entry.Timestamp*int64(time.Millisecond)).UTC().Format(time.RFC3339))
+	if entry.Cluster != "" {
+		text = fmt.Sprintf("%s, on %s", text, entry.Cluster)
+	}
+	if entry.EntryId != "" {
+		text = fmt.Sprintf("%s (%s)", text, entry.EntryId)
+	}
+
+	return text
+}
+
+func endpointName(endpoint *tapApi.TCP) string {
+	if endpoint == nil {
+		return "unknown"
+	}
+	if endpoint.Name != "" {
+		return endpoint.Name
+	}
+
+	return fmt.Sprintf("%s:%s", endpoint.IP, endpoint.Port)
+}
@@ -0,0 +1,127 @@
+package summary
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/transform"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+// matches the fields that are plain paths, only those can be turned into a query
+var plainPathRegex = regexp.MustCompile(`^(\.[A-Za-z_][A-Za-z0-9_]*(\[[0-9]+\])*)+$`)
+
+type summaryField struct {
+	expression *transform.Expression
+	queryPath  string
+	maxLength  int
+}
+
+var (
+	lockSummary      = &sync.RWMutex{}
+	fieldsByProtocol = make(map[string][]*summaryField)
+)
+
+func Configure(config shared.SummaryConfig) error {
+	configuredFields := make(map[string][]*summaryField)
+	for protocol, fields := range config {
+		for _, field := range fields {
+			expression, err := transform.Compile(field.Field)
+			if err != nil {
+				return fmt.Errorf("invalid %s summary field, err: %w", protocol, err)
+			}
+
+			compiledField := &summaryField{expression: expression, maxLength: field.MaxLength}
+			if plainPathRegex.MatchString(field.Field) {
+				compiledField.queryPath = strings.TrimPrefix(field.Field, ".")
+			}
+			configuredFields[protocol] = append(configuredFields[protocol], compiledField)
+		}
+	}
+
+	lockSummary.Lock()
+	defer lockSummary.Unlock()
+
+	fieldsByProtocol = configuredFields
+	return nil
+}
+
+// Apply replaces the summary of base with the configured fields of the entry's protocol, entries of protocols
+// without configured fields keep the summary of their dissector, as do entries whose fields are all missing
+func Apply(entry *tapApi.Entry, base *tapApi.BaseEntry) {
+	lockSummary.RLock()
+	fields := fieldsByProtocol[entry.Protocol.Name]
+	lockSummary.RUnlock()
+
+	if len(fields) == 0 {
+		return
+	}
+
+	// the fields are evaluated on the entry the way it's stored and exported
+	entryJson, err := json.Marshal(entry)
+	if err != nil {
+		return
+	}
+	var document interface{}
+	if err := json.Unmarshal(entryJson, &document); err != nil {
+		return
+	}
+
+	var values []string
+	var queries []string
+	for _, field := range fields {
+		result, err := field.expression.Apply(document)
+		if err != nil || result == nil {
+			continue
+		}
+
+		value, ok := formatValue(result)
+		if !ok {
+			continue
+		}
+
+		truncated := false
+		if field.maxLength > 0 && len([]rune(value)) > field.maxLength {
+			value = string([]rune(value)[:field.maxLength])
+			truncated = true
+		}
+		values = append(values, value)
+
+		if field.queryPath == "" {
+			continue
+		}
+		if truncated {
+			queries = append(queries, fmt.Sprintf("%s.startsWith(%s)", field.queryPath, strconv.Quote(value)))
+		} else if _, isString := result.(string); isString {
+			queries = append(queries, fmt.Sprintf("%s == %s", field.queryPath, strconv.Quote(value)))
+		} else {
+			queries = append(queries, fmt.Sprintf("%s == %s", field.queryPath, value))
+		}
+	}
+
+	if len(values) == 0 {
+		return
+	}
+
+	base.Summary = strings.Join(values, " ")
+	base.SummaryQuery = strings.Join(queries, " and ")
+}
+
+// formatValue formats scalars as they're written in queries, objects and arrays aren't summarized
+func formatValue(value interface{}) (string, bool) {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue, true
+	case float64:
+		return strconv.FormatFloat(typedValue, 'f', -1, 64), true
+	case bool:
+		return strconv.FormatBool(typedValue), true
+	default:
+		return "", false
+	}
+}
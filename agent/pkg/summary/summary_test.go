@@ -0,0 +1,66 @@
+package summary
+
+import (
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+func TestApply(t *testing.T) {
+	if err := Configure(shared.SummaryConfig{
+		"redis": {{Field: ".request.command"}, {Field: ".request.key", MaxLength: 5}},
+		"kafka": {{Field: ".request.payload.topic"}, {Field: ".request.payload.partition"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = Configure(nil) }()
+
+	tests := []struct {
+		entry                *tapApi.Entry
+		expectedSummary      string
+		expectedSummaryQuery string
+	}{
+		{
+			entry:                &tapApi.Entry{Protocol: tapApi.Protocol{Name: "redis"}, Request: map[string]interface{}{"command": "GET", "key": "session:1234"}},
+			expectedSummary:      "GET sessi",
+			expectedSummaryQuery: `request.command == "GET" and request.key.startsWith("sessi")`,
+		},
+		{
+			entry:                &tapApi.Entry{Protocol: tapApi.Protocol{Name: "kafka"}, Request: map[string]interface{}{"payload": map[string]interface{}{"topic": "orders", "partition": 3}}},
+			expectedSummary:      "orders 3",
+			expectedSummaryQuery: `request.payload.topic == "orders" and request.payload.partition == 3`,
+		},
+		{
+			// the fields are missing, the dissector's summary is kept
+			entry:                &tapApi.Entry{Protocol: tapApi.Protocol{Name: "kafka"}, Request: map[string]interface{}{}},
+			expectedSummary:      "dissector summary",
+			expectedSummaryQuery: "dissector query",
+		},
+		{
+			entry:                &tapApi.Entry{Protocol: tapApi.Protocol{Name: "http"}},
+			expectedSummary:      "dissector summary",
+			expectedSummaryQuery: "dissector query",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.entry.Protocol.Name, func(t *testing.T) {
+			base := &tapApi.BaseEntry{Summary: "dissector summary", SummaryQuery: "dissector query"}
+			Apply(test.entry, base)
+
+			if base.Summary != test.expectedSummary {
+				t.Errorf("unexpected summary - expected: %v, actual: %v", test.expectedSummary, base.Summary)
+			}
+			if base.SummaryQuery != test.expectedSummaryQuery {
+				t.Errorf("unexpected summary query - expected: %v, actual: %v", test.expectedSummaryQuery, base.SummaryQuery)
+			}
+		})
+	}
+}
+
+func TestConfigureInvalidField(t *testing.T) {
+	if err := Configure(shared.SummaryConfig{"redis": {{Field: ".request.("}}}); err == nil {
+		t.Errorf("expected an error for an invalid field")
+	}
+}
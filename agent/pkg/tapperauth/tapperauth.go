@@ -0,0 +1,175 @@
+package tapperauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	authenticationv1client "k8s.io/client-go/kubernetes/typed/authentication/v1"
+	restclient "k8s.io/client-go/rest"
+)
+
+const (
+	reviewTimeout = 10 * time.Second
+	// a reviewed token is trusted for this long before it is reviewed again, tappers only reconnect on failures so
+	// the cache mostly saves reviews when all the tappers reconnect together after an api server restart
+	reviewCacheTtl = time.Minute
+)
+
+var ErrMissingToken = errors.New("no bearer token in the authorization header")
+
+// Authenticator validates the projected service account tokens the tappers send when they connect, tokens are
+// accepted when the kubernetes api server reviews them as issued for the mizu agent audience to a service account
+// of the mizu resources namespace, so pods elsewhere in the cluster can't send entries to the api server
+type Authenticator struct {
+	tokenReviews authenticationv1client.TokenReviewInterface
+	namespace    string
+	mutex        sync.Mutex
+	reviewed     map[string]*reviewedToken
+}
+
+type reviewedToken struct {
+	userName   string
+	reviewedAt time.Time
+}
+
+func NewFromInCluster(namespace string) (*Authenticator, error) {
+	config, err := restclient.InClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	clientSet, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAuthenticator(clientSet.AuthenticationV1().TokenReviews(), namespace), nil
+}
+
+func newAuthenticator(tokenReviews authenticationv1client.TokenReviewInterface, namespace string) *Authenticator {
+	return &Authenticator{tokenReviews: tokenReviews, namespace: namespace, reviewed: make(map[string]*reviewedToken)}
+}
+
+// Authenticate returns the service account user name of the tapper that sent the request
+func (a *Authenticator) Authenticate(request *http.Request) (string, error) {
+	token := getBearerToken(request)
+	if token == "" {
+		return "", ErrMissingToken
+	}
+
+	key := tokenKey(token)
+	if userName, ok := a.getReviewed(key); ok {
+		return userName, nil
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), reviewTimeout)
+	defer cancel()
+
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{
+			Token:     token,
+			Audiences: []string{shared.TapperTokenAudience},
+		},
+	}
+	result, err := a.tokenReviews.Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		if k8serrors.IsForbidden(err) {
+			return "", fmt.Errorf("the api server isn't allowed to review tokens, run mizu clean to recreate the mizu cluster role: %w", err)
+		}
+		return "", fmt.Errorf("failed reviewing token: %w", err)
+	}
+
+	userName, err := a.validate(result.Status)
+	if err != nil {
+		return userName, err
+	}
+
+	a.markReviewed(key, userName)
+	return userName, nil
+}
+
+func (a *Authenticator) validate(status authenticationv1.TokenReviewStatus) (string, error) {
+	if !status.Authenticated {
+		if status.Error != "" {
+			return "", fmt.Errorf("token isn't authenticated: %s", status.Error)
+		}
+		return "", errors.New("token isn't authenticated")
+	}
+
+	userName := status.User.Username
+	if !containsAudience(status.Audiences, shared.TapperTokenAudience) {
+		return userName, fmt.Errorf("token of %s isn't issued for the %s audience", userName, shared.TapperTokenAudience)
+	}
+
+	serviceAccountPrefix := fmt.Sprintf("system:serviceaccount:%s:", a.namespace)
+	if !strings.HasPrefix(userName, serviceAccountPrefix) {
+		return userName, fmt.Errorf("%s isn't a service account of the %s namespace", userName, a.namespace)
+	}
+
+	return userName, nil
+}
+
+func (a *Authenticator) getReviewed(key string) (string, bool) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	reviewed, ok := a.reviewed[key]
+	if !ok {
+		return "", false
+	}
+	if time.Since(reviewed.reviewedAt) > reviewCacheTtl {
+		delete(a.reviewed, key)
+		return "", false
+	}
+
+	return reviewed.userName, true
+}
+
+func (a *Authenticator) markReviewed(key string, userName string) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for reviewedKey, reviewed := range a.reviewed {
+		if time.Since(reviewed.reviewedAt) > reviewCacheTtl {
+			delete(a.reviewed, reviewedKey)
+		}
+	}
+	a.reviewed[key] = &reviewedToken{userName: userName, reviewedAt: time.Now()}
+}
+
+func getBearerToken(request *http.Request) string {
+	authorization := request.Header.Get("Authorization")
+	if len(authorization) < len("Bearer ") || !strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return ""
+	}
+
+	return strings.TrimSpace(authorization[len("Bearer "):])
+}
+
+// the tokens are kept hashed so a memory dump of the api server doesn't leak them
+func tokenKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func containsAudience(audiences []string, audience string) bool {
+	for _, candidate := range audiences {
+		if candidate == audience {
+			return true
+		}
+	}
+
+	return false
+}
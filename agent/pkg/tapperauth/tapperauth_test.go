@@ -0,0 +1,116 @@
+package tapperauth
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newTestAuthenticator(status authenticationv1.TokenReviewStatus, reviews *int) *Authenticator {
+	clientSet := fake.NewSimpleClientset()
+	clientSet.PrependReactor("create", "tokenreviews", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		*reviews++
+		review := action.(k8stesting.CreateAction).GetObject().(*authenticationv1.TokenReview)
+		review.Status = status
+		return true, review, nil
+	})
+
+	return newAuthenticator(clientSet.AuthenticationV1().TokenReviews(), "mizu")
+}
+
+func newTapperRequest(authorization string) *http.Request {
+	request, _ := http.NewRequest(http.MethodGet, "http://mizu-api-server/wsTapper", nil)
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	return request
+}
+
+func TestAuthenticateTapperToken(t *testing.T) {
+	reviews := 0
+	authenticator := newTestAuthenticator(authenticationv1.TokenReviewStatus{
+		Authenticated: true,
+		User:          authenticationv1.UserInfo{Username: "system:serviceaccount:mizu:mizu-service-account"},
+		Audiences:     []string{shared.TapperTokenAudience},
+	}, &reviews)
+
+	for i := 0; i < 2; i++ {
+		userName, err := authenticator.Authenticate(newTapperRequest("Bearer tapper-token"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if userName != "system:serviceaccount:mizu:mizu-service-account" {
+			t.Errorf("unexpected result - expected the tapper service account, actual: %v", userName)
+		}
+	}
+
+	if reviews != 1 {
+		t.Errorf("unexpected result - expected the second connection to use the cached review, actual reviews: %v", reviews)
+	}
+}
+
+func TestAuthenticateRejectsTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		status authenticationv1.TokenReviewStatus
+	}{
+		{
+			name:   "unauthenticated",
+			status: authenticationv1.TokenReviewStatus{Authenticated: false, Error: "token expired"},
+		},
+		{
+			name: "other namespace",
+			status: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "system:serviceaccount:default:default"},
+				Audiences:     []string{shared.TapperTokenAudience},
+			},
+		},
+		{
+			name: "other audience",
+			status: authenticationv1.TokenReviewStatus{
+				Authenticated: true,
+				User:          authenticationv1.UserInfo{Username: "system:serviceaccount:mizu:mizu-service-account"},
+				Audiences:     []string{"https://kubernetes.default.svc"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			reviews := 0
+			authenticator := newTestAuthenticator(test.status, &reviews)
+
+			for i := 0; i < 2; i++ {
+				if _, err := authenticator.Authenticate(newTapperRequest("Bearer rogue-token")); err == nil {
+					t.Fatalf("unexpected result - expected the token to be rejected")
+				}
+			}
+
+			if reviews != 2 {
+				t.Errorf("unexpected result - expected rejected tokens not to be cached, actual reviews: %v", reviews)
+			}
+		})
+	}
+}
+
+func TestAuthenticateMissingToken(t *testing.T) {
+	reviews := 0
+	authenticator := newTestAuthenticator(authenticationv1.TokenReviewStatus{Authenticated: true}, &reviews)
+
+	for _, authorization := range []string{"", "Basic dXNlcjpwYXNz", "Bearer "} {
+		if _, err := authenticator.Authenticate(newTapperRequest(authorization)); err != ErrMissingToken {
+			t.Errorf("unexpected result - expected %v for %q, actual: %v", ErrMissingToken, authorization, err)
+		}
+	}
+
+	if reviews != 0 {
+		t.Errorf("unexpected result - expected no reviews, actual: %v", reviews)
+	}
+}
@@ -0,0 +1,93 @@
+package timequery
+
+import (
+	"sync"
+)
+
+const (
+	bucketMilliseconds = int64(60 * 1000)
+	// a week of minutes
+	maxBuckets = 7 * 24 * 60
+	// an entry is stored once its response is captured, so the entries aren't stored exactly in the order of their
+	// timestamps, the index only skips the entries that are older than the margin
+	marginMilliseconds = 5 * bucketMilliseconds
+)
+
+// Index maps the minutes of the timestamps to the database indices of the entries, so the queries of a time window
+// that scan forward start at the window instead of at the first entry. It learns the indices from the entries the
+// queries read
+type Index struct {
+	mutex       sync.Mutex
+	buckets     map[int64]int
+	maxId       int
+	maxIdBucket int64
+}
+
+var instance *Index
+var once sync.Once
+
+func GetIndex() *Index {
+	once.Do(func() {
+		instance = &Index{buckets: make(map[int64]int)}
+	})
+	return instance
+}
+
+// Record notes the database index of an entry and its timestamp in unix milliseconds
+func (index *Index) Record(id int, timestamp int64) {
+	if timestamp <= 0 {
+		return
+	}
+
+	bucket := timestamp / bucketMilliseconds
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	// the indices start over when the database restarts empty, the entries it had are gone
+	if id < index.maxId && bucket > index.maxIdBucket+marginMilliseconds/bucketMilliseconds {
+		index.buckets = make(map[int64]int)
+		index.maxId = 0
+	}
+	if id >= index.maxId {
+		index.maxId = id
+		index.maxIdBucket = bucket
+	}
+
+	if last, ok := index.buckets[bucket]; ok && last >= id {
+		return
+	}
+	index.buckets[bucket] = id
+
+	if len(index.buckets) > maxBuckets {
+		index.evictOldest()
+	}
+}
+
+// LeftOff returns the database index to start a forward scan for the entries at or after from, it's 0 when no entry
+// old enough was recorded
+func (index *Index) LeftOff(from int64) int {
+	before := (from - marginMilliseconds) / bucketMilliseconds
+
+	index.mutex.Lock()
+	defer index.mutex.Unlock()
+
+	leftOff := 0
+	for bucket, id := range index.buckets {
+		if bucket < before && id > leftOff {
+			leftOff = id
+		}
+	}
+
+	return leftOff
+}
+
+func (index *Index) evictOldest() {
+	oldest := int64(-1)
+	for bucket := range index.buckets {
+		if oldest == -1 || bucket < oldest {
+			oldest = bucket
+		}
+	}
+	delete(index.buckets, oldest)
+}
@@ -0,0 +1,378 @@
+// Package timequery adds the time window operators to the queries of the database, since("5m") matches the entries
+// of the last 5 minutes and between(t1, t2) the entries from t1 to t2. rate("1m", "1h") counts the entries of the
+// query per minute over the last hour, as a filter it matches the entries of the last hour like since("1h"). The
+// database doesn't know them, so they're expanded to comparisons of the timestamp before a query reaches it.
+package timequery
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const (
+	SinceOperator   = "since"
+	BetweenOperator = "between"
+	RateOperator    = "rate"
+
+	maxRateIntervals = 10000
+)
+
+// Expanded is a query with its time operators expanded, From is the unix milliseconds that every matching entry is at
+// or after, or 0 when the query doesn't bound its entries by time. Rate is the window of the rate operator of the
+// query, nil when it has none
+type Expanded struct {
+	Query string
+	From  int64
+	Rate  *Rate
+}
+
+// Rate is the window of a rate operator, from From to To in unix milliseconds, cut in intervals of Interval
+// milliseconds
+type Rate struct {
+	From     int64
+	To       int64
+	Interval int64
+}
+
+// RateInterval is the count of the entries of a rate interval that starts at Start, in unix milliseconds
+type RateInterval struct {
+	Start     int64   `json:"start"`
+	Count     int     `json:"count"`
+	PerSecond float64 `json:"perSecond"`
+}
+
+type call struct {
+	start int
+	end   int
+	args  []string
+	// a call at the top level of the query that isn't negated bounds the entries of the whole query
+	bounding bool
+}
+
+// Expand replaces the time operators of the query, relative times are relative to now. A query without time
+// operators is returned as is
+func Expand(query string, now time.Time) (*Expanded, error) {
+	calls, hasTopLevelOr, err := findCalls(query)
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := &Expanded{Query: query}
+	if len(calls) == 0 {
+		return expanded, nil
+	}
+
+	var builder strings.Builder
+	last := 0
+	for _, call := range calls {
+		text := query[call.start:call.end]
+		from, to, interval, err := callWindow(text, call.args, now)
+		if err != nil {
+			return nil, err
+		}
+
+		if interval > 0 {
+			if !call.bounding || hasTopLevelOr {
+				return nil, fmt.Errorf("%s has to be at the top level of the query, and-ed with its filters", text)
+			}
+			if expanded.Rate != nil {
+				return nil, fmt.Errorf("a query has one %s operator at most", RateOperator)
+			}
+			expanded.Rate = &Rate{From: from, To: now.UnixNano() / int64(time.Millisecond), Interval: interval}
+		}
+
+		builder.WriteString(query[last:call.start])
+		if to == 0 {
+			builder.WriteString(fmt.Sprintf("timestamp >= %d", from))
+		} else {
+			builder.WriteString(fmt.Sprintf("(timestamp >= %d and timestamp <= %d)", from, to))
+		}
+		last = call.end
+
+		if call.bounding && !hasTopLevelOr && from > expanded.From {
+			expanded.From = from
+		}
+	}
+	builder.WriteString(query[last:])
+	expanded.Query = builder.String()
+
+	return expanded, nil
+}
+
+// callWindow returns the window of a call, to is 0 for a window that doesn't end and interval is the interval of
+// a rate call, 0 for the other operators
+func callWindow(text string, args []string, now time.Time) (int64, int64, int64, error) {
+	name := strings.TrimSpace(text[:strings.IndexByte(text, '(')])
+	switch name {
+	case SinceOperator:
+		if len(args) != 1 {
+			return 0, 0, 0, fmt.Errorf("%s takes a duration, like %s(\"5m\")", text, SinceOperator)
+		}
+		from, err := parseTime(args[0], now)
+		return from, 0, 0, err
+	case RateOperator:
+		if len(args) != 2 {
+			return 0, 0, 0, fmt.Errorf("%s takes an interval and a window, like %s(\"1m\", \"1h\")", text, RateOperator)
+		}
+		interval, err := parseInterval(args[0])
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		from, err := parseTime(args[1], now)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		nowMs := now.UnixNano() / int64(time.Millisecond)
+		if from >= nowMs {
+			return 0, 0, 0, fmt.Errorf("%s has an empty window", text)
+		}
+		if (nowMs-from)/interval >= maxRateIntervals {
+			return 0, 0, 0, fmt.Errorf("%s has more than %d intervals, use a longer interval", text, maxRateIntervals)
+		}
+		return from, 0, interval, nil
+	default:
+		if len(args) != 2 {
+			return 0, 0, 0, fmt.Errorf("%s takes two times, like %s(\"2022-03-01T10:00:00Z\", \"1h\")", text, BetweenOperator)
+		}
+		from, err := parseTime(args[0], now)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		to, err := parseTime(args[1], now)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		if from > to {
+			return 0, 0, 0, fmt.Errorf("%s starts after it ends", text)
+		}
+		return from, to, 0, nil
+	}
+}
+
+// parseInterval parses the milliseconds of the interval of a rate, a duration in quotes like "1m"
+func parseInterval(arg string) (int64, error) {
+	value, err := strconv.Unquote(arg)
+	if err != nil {
+		return 0, fmt.Errorf("%s isn't an interval, use a duration in quotes like \"1m\"", arg)
+	}
+
+	duration, err := parseDuration(value)
+	if err != nil || duration < time.Second {
+		return 0, fmt.Errorf("%s isn't an interval, use a duration of a second or more like \"1m\"", arg)
+	}
+
+	return int64(duration / time.Millisecond), nil
+}
+
+// Count counts the timestamps, in unix milliseconds, in the intervals of the window, the last interval ends at To and
+// may be shorter than the others. The timestamps outside of the window aren't counted
+func (rate *Rate) Count(timestamps []int64) []RateInterval {
+	intervals := make([]RateInterval, 0, (rate.To-rate.From)/rate.Interval+1)
+	for start := rate.From; start < rate.To; start += rate.Interval {
+		intervals = append(intervals, RateInterval{Start: start})
+	}
+
+	for _, timestamp := range timestamps {
+		if timestamp < rate.From || timestamp > rate.To {
+			continue
+		}
+		i := int((timestamp - rate.From) / rate.Interval)
+		if i == len(intervals) {
+			// the end of the window is part of its last interval
+			i--
+		}
+		intervals[i].Count++
+	}
+
+	for i := range intervals {
+		length := rate.Interval
+		if end := intervals[i].Start + rate.Interval; end > rate.To {
+			length = rate.To - intervals[i].Start
+		}
+		intervals[i].PerSecond = float64(intervals[i].Count) * 1000 / float64(length)
+	}
+
+	return intervals
+}
+
+// parseTime parses the unix milliseconds of a time, an RFC 3339 time or a duration before now like "90s", "1h30m"
+// or "2d"
+func parseTime(arg string, now time.Time) (int64, error) {
+	if !strings.HasPrefix(arg, `"`) {
+		milliseconds, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%s isn't a time, use unix milliseconds, an RFC 3339 time or a duration in quotes", arg)
+		}
+		return milliseconds, nil
+	}
+
+	value, err := strconv.Unquote(arg)
+	if err != nil {
+		return 0, fmt.Errorf("invalid string %s", arg)
+	}
+
+	if parsed, err := time.Parse(time.RFC3339Nano, value); err == nil {
+		return parsed.UnixNano() / int64(time.Millisecond), nil
+	}
+
+	duration, err := parseDuration(value)
+	if err != nil || duration < 0 {
+		return 0, fmt.Errorf("%s isn't a time, use unix milliseconds, an RFC 3339 time or a duration like \"5m\"", arg)
+	}
+
+	return now.Add(-duration).UnixNano() / int64(time.Millisecond), nil
+}
+
+func parseDuration(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+
+	return time.ParseDuration(value)
+}
+
+// findCalls finds the calls of the time operators outside of the strings of the query, and whether the top level of
+// the query has an or that makes the calls not bound its entries
+func findCalls(query string) ([]*call, bool, error) {
+	calls := make([]*call, 0)
+	hasTopLevelOr := false
+	depth := 0
+
+	for i := 0; i < len(query); {
+		char := query[i]
+		switch {
+		case char == '"':
+			end, err := skipString(query, i)
+			if err != nil {
+				return nil, false, err
+			}
+			i = end
+		case char == '(':
+			depth++
+			i++
+		case char == ')':
+			depth--
+			i++
+		case char == '|' && strings.HasPrefix(query[i:], "||") && depth == 0:
+			hasTopLevelOr = true
+			i += 2
+		case isIdentifierChar(char):
+			start := i
+			for i < len(query) && isIdentifierChar(query[i]) {
+				i++
+			}
+			word := query[start:i]
+
+			if word == "or" && depth == 0 {
+				hasTopLevelOr = true
+			}
+			if word != SinceOperator && word != BetweenOperator && word != RateOperator || isMethodCall(query, start) {
+				continue
+			}
+
+			open := skipSpaces(query, i)
+			if open >= len(query) || query[open] != '(' {
+				continue
+			}
+
+			args, end, err := parseArgs(query, open)
+			if err != nil {
+				return nil, false, fmt.Errorf("invalid %s, %w", word, err)
+			}
+			calls = append(calls, &call{start: start, end: end, args: args, bounding: depth == 0 && !isNegated(query, start)})
+			i = end
+		default:
+			i++
+		}
+	}
+
+	return calls, hasTopLevelOr, nil
+}
+
+// parseArgs parses the arguments from the opening parenthesis, they're strings or integers
+func parseArgs(query string, open int) ([]string, int, error) {
+	args := make([]string, 0)
+	i := skipSpaces(query, open+1)
+	if i < len(query) && query[i] == ')' {
+		return args, i + 1, nil
+	}
+
+	for i < len(query) {
+		start := i
+		if query[i] == '"' {
+			end, err := skipString(query, i)
+			if err != nil {
+				return nil, 0, err
+			}
+			i = end
+		} else {
+			for i < len(query) && (query[i] == '-' || unicode.IsDigit(rune(query[i]))) {
+				i++
+			}
+			if i == start {
+				return nil, 0, fmt.Errorf("unexpected %q, the arguments are strings or integers", query[i])
+			}
+		}
+		args = append(args, query[start:i])
+
+		i = skipSpaces(query, i)
+		if i >= len(query) {
+			break
+		}
+		switch query[i] {
+		case ')':
+			return args, i + 1, nil
+		case ',':
+			i = skipSpaces(query, i+1)
+		default:
+			return nil, 0, fmt.Errorf("unexpected %q after an argument", query[i])
+		}
+	}
+
+	return nil, 0, fmt.Errorf("missing closing parenthesis")
+}
+
+// skipString returns the index after the string that starts at start
+func skipString(query string, start int) (int, error) {
+	for i := start + 1; i < len(query); i++ {
+		switch query[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1, nil
+		}
+	}
+
+	return 0, fmt.Errorf("unterminated string at %d", start)
+}
+
+func skipSpaces(query string, i int) int {
+	for i < len(query) && unicode.IsSpace(rune(query[i])) {
+		i++
+	}
+	return i
+}
+
+func isIdentifierChar(char byte) bool {
+	return char == '_' || unicode.IsLetter(rune(char)) || unicode.IsDigit(rune(char))
+}
+
+// isMethodCall is true for the helpers of fields like request.path.since(...), they aren't the time operators
+func isMethodCall(query string, start int) bool {
+	return start > 0 && query[start-1] == '.'
+}
+
+func isNegated(query string, start int) bool {
+	i := start - 1
+	for i >= 0 && unicode.IsSpace(rune(query[i])) {
+		i--
+	}
+	return i >= 0 && query[i] == '!'
+}
@@ -0,0 +1,139 @@
+package timequery
+
+import (
+	"testing"
+	"time"
+)
+
+var now = time.Unix(1646128800, 0)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		query    string
+		expected string
+		from     int64
+	}{
+		{`http and response.status >= 500`, `http and response.status >= 500`, 0},
+		{`http and since("5m")`, `http and timestamp >= 1646128500000`, 1646128500000},
+		{`since("2d")`, `timestamp >= 1645956000000`, 1645956000000},
+		{`between("2022-03-01T09:00:00Z", "1h")`, `(timestamp >= 1646125200000 and timestamp <= 1646125200000)`, 1646125200000},
+		{`between(1646120000000, 1646121000000) and grpc`, `(timestamp >= 1646120000000 and timestamp <= 1646121000000) and grpc`, 1646120000000},
+		{`since("1h") or http`, `timestamp >= 1646125200000 or http`, 0},
+		{`!since("1h")`, `!timestamp >= 1646125200000`, 0},
+		{`(since("1h") or http) and since("5m")`, `(timestamp >= 1646125200000 or http) and timestamp >= 1646128500000`, 1646128500000},
+		{`request.path == "since(\"5m\")"`, `request.path == "since(\"5m\")"`, 0},
+		{`request.path.since("5m")`, `request.path.since("5m")`, 0},
+		{`http and rate("1m", "1h")`, `http and timestamp >= 1646125200000`, 1646125200000},
+	}
+
+	for _, test := range tests {
+		expanded, err := Expand(test.query, now)
+		if err != nil {
+			t.Errorf("unexpected error for %s: %v", test.query, err)
+			continue
+		}
+
+		if expanded.Query != test.expected || expanded.From != test.from {
+			t.Errorf("unexpected result for %s - expected: %v %v, actual: %v %v", test.query, test.expected, test.from, expanded.Query, expanded.From)
+		}
+	}
+}
+
+func TestExpandInvalid(t *testing.T) {
+	queries := []string{
+		`since()`,
+		`since("5m", "1m")`,
+		`since("yesterday")`,
+		`since("5m"`,
+		`between("1h")`,
+		`between("5m", "1h")`,
+		`since(http)`,
+		`rate("1m")`,
+		`rate("1ms", "1h")`,
+		`rate("1s", "7d")`,
+		`rate(60000, "1h")`,
+		`rate("1m", "1h") or http`,
+		`!rate("1m", "1h")`,
+		`(http and rate("1m", "1h"))`,
+		`rate("1m", "1h") and rate("5m", "1h")`,
+	}
+
+	for _, query := range queries {
+		if _, err := Expand(query, now); err == nil {
+			t.Errorf("expected an error for %s", query)
+		}
+	}
+}
+
+func TestExpandRate(t *testing.T) {
+	expanded, err := Expand(`grpc and rate("5m", "1h")`, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := Rate{From: 1646125200000, To: 1646128800000, Interval: 300000}
+	if expanded.Rate == nil || *expanded.Rate != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, expanded.Rate)
+	}
+
+	if expanded, _ := Expand(`grpc and since("1h")`, now); expanded.Rate != nil {
+		t.Errorf("unexpected result - expected: %v, actual: %v", nil, expanded.Rate)
+	}
+}
+
+func TestRateCount(t *testing.T) {
+	rate := &Rate{From: 1000, To: 4500, Interval: 1000}
+	timestamps := []int64{999, 1000, 1500, 1999, 2000, 3999, 4000, 4500, 4501}
+
+	expected := []RateInterval{
+		{Start: 1000, Count: 3, PerSecond: 3},
+		{Start: 2000, Count: 1, PerSecond: 1},
+		{Start: 3000, Count: 1, PerSecond: 1},
+		{Start: 4000, Count: 2, PerSecond: 4},
+	}
+
+	actual := rate.Count(timestamps)
+	if len(actual) != len(expected) {
+		t.Fatalf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+	for i := range expected {
+		if actual[i] != expected[i] {
+			t.Errorf("unexpected result - expected: %v, actual: %v", expected[i], actual[i])
+		}
+	}
+}
+
+func TestIndexLeftOff(t *testing.T) {
+	index := &Index{buckets: make(map[int64]int)}
+	minute := bucketMilliseconds
+	start := int64(1646128800000)
+
+	for i := 0; i < 20; i++ {
+		index.Record(i*100, start+int64(i)*minute)
+	}
+
+	tests := map[int64]int{
+		start - 60*minute:  0,
+		start + 5*minute:   0,
+		start + 10*minute:  400,
+		start + 100*minute: 1900,
+	}
+
+	for from, expected := range tests {
+		if actual := index.LeftOff(from); actual != expected {
+			t.Errorf("unexpected result for %d - expected: %v, actual: %v", from, expected, actual)
+		}
+	}
+}
+
+func TestIndexDatabaseRestart(t *testing.T) {
+	index := &Index{buckets: make(map[int64]int)}
+	start := int64(1646128800000)
+
+	index.Record(5000, start)
+	index.Record(3, start+60*bucketMilliseconds)
+
+	if actual := index.LeftOff(start + 120*bucketMilliseconds); actual != 3 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 3, actual)
+	}
+}
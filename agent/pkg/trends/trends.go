@@ -0,0 +1,443 @@
+package trends
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/agent/pkg/oas"
+	"github.com/up9inc/mizu/agent/pkg/utils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	FilePath  = shared.DataDirPath + shared.EndpointTrendsFileName
+	DayFormat = "2006-01-02"
+	// the days the aggregates are kept, long after the database evicted their entries
+	RetentionDays = 56
+	// the endpoints seen past this count in a day are summed up under otherEndpoint, to bound the size of the file
+	maxEndpointsPerDay = 1000
+	otherEndpoint      = "other"
+	saveInterval       = time.Minute
+	// entries with a status from this one on are counted as errors, like the 5xx of http
+	errorStatus = 500
+	weekDays    = 7
+)
+
+var (
+	patNumber = regexp.MustCompile(`\d+`)
+
+	// the upper bounds of the latency histogram buckets, in milliseconds, they're finer than the buckets of the
+	// metrics since the p95 is interpolated within them
+	latencyBuckets = []int64{1, 2, 5, 10, 15, 25, 40, 60, 80, 100, 150, 200, 300, 400, 600, 800, 1000, 1500, 2000, 3000, 5000, 10000, 30000}
+)
+
+type endpointKey struct {
+	service  string
+	endpoint string
+}
+
+// aggregate sums up the entries of an endpoint for a day, Latencies counts the entries of every bucket and the last
+// count is of the latencies above the last bound
+type aggregate struct {
+	Day       string  `json:"day"`
+	Service   string  `json:"service"`
+	Endpoint  string  `json:"endpoint"`
+	Entries   int64   `json:"entries"`
+	Errors    int64   `json:"errors"`
+	Latencies []int64 `json:"latencies"`
+}
+
+func newAggregate(day string, key endpointKey) *aggregate {
+	return &aggregate{Day: day, Service: key.service, Endpoint: key.endpoint, Latencies: make([]int64, len(latencyBuckets)+1)}
+}
+
+func (aggregate *aggregate) add(other *aggregate) {
+	aggregate.Entries += other.Entries
+	aggregate.Errors += other.Errors
+	for i, count := range other.Latencies {
+		aggregate.Latencies[i] += count
+	}
+}
+
+func (aggregate *aggregate) stats() shared.EndpointTrendStats {
+	stats := shared.EndpointTrendStats{
+		Entries: aggregate.Entries,
+		Errors:  aggregate.Errors,
+		P95Ms:   percentile(aggregate.Latencies, 0.95),
+	}
+	if stats.Entries > 0 {
+		stats.ErrorRate = float64(stats.Errors) / float64(stats.Entries)
+	}
+
+	return stats
+}
+
+// Store aggregates the http entries per endpoint and day, the aggregates are kept in a file of the data directory
+// for RetentionDays, independently of the entries the database retains, so the trends outlive the entries
+type Store struct {
+	mutex    sync.Mutex
+	days     map[string]map[endpointKey]*aggregate
+	filePath string
+	dirty    bool
+	now      func() time.Time
+	stop     chan struct{}
+}
+
+var instance *Store
+var once sync.Once
+
+func GetInstance() *Store {
+	once.Do(func() {
+		instance = newStore()
+	})
+	return instance
+}
+
+func newStore() *Store {
+	return &Store{days: make(map[string]map[endpointKey]*aggregate), now: time.Now}
+}
+
+// Configure reads the saved aggregates and saves them every minute they change
+func (store *Store) Configure() {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	store.filePath = FilePath
+	store.load()
+
+	if store.stop == nil {
+		store.stop = make(chan struct{})
+		go store.saveLoop(store.stop)
+	}
+}
+
+func (store *Store) load() {
+	var aggregates []*aggregate
+	if err := utils.ReadJsonFile(store.filePath, &aggregates); err != nil {
+		if !os.IsNotExist(err) {
+			logger.Log.Errorf("Error reading the endpoint trends from file, err: %v", err)
+		}
+		return
+	}
+
+	oldestDay := store.oldestDay()
+	for _, savedAggregate := range aggregates {
+		if savedAggregate.Day < oldestDay {
+			continue
+		}
+		// the counts of other buckets can't be mapped to the current ones, the volume and the errors are still kept
+		if len(savedAggregate.Latencies) != len(latencyBuckets)+1 {
+			savedAggregate.Latencies = make([]int64, len(latencyBuckets)+1)
+		}
+
+		if _, ok := store.days[savedAggregate.Day]; !ok {
+			store.days[savedAggregate.Day] = make(map[endpointKey]*aggregate)
+		}
+		store.days[savedAggregate.Day][endpointKey{service: savedAggregate.Service, endpoint: savedAggregate.Endpoint}] = savedAggregate
+	}
+	logger.Log.Infof("Loaded the endpoint trends of %d days", len(store.days))
+}
+
+// PushEntry counts an http entry of the service in the aggregate of the day of its timestamp, in milliseconds
+func (store *Store) PushEntry(service string, method string, requestUrl string, status int, latencyMs int64, timestamp int64) {
+	day := time.Unix(0, timestamp*int64(time.Millisecond)).UTC().Format(DayFormat)
+	key := endpointKey{service: service, endpoint: endpointName(method, requestUrl)}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	endpoints, ok := store.days[day]
+	if !ok {
+		if day < store.oldestDay() {
+			return
+		}
+		endpoints = make(map[endpointKey]*aggregate)
+		store.days[day] = endpoints
+		store.prune()
+	}
+
+	dayAggregate, ok := endpoints[key]
+	if !ok {
+		if len(endpoints) >= maxEndpointsPerDay {
+			key = endpointKey{service: otherEndpoint, endpoint: otherEndpoint}
+		}
+		if dayAggregate, ok = endpoints[key]; !ok {
+			dayAggregate = newAggregate(day, key)
+			endpoints[key] = dayAggregate
+		}
+	}
+
+	dayAggregate.Entries++
+	if status >= errorStatus {
+		dayAggregate.Errors++
+	}
+	dayAggregate.Latencies[latencyBucket(latencyMs)]++
+	store.dirty = true
+}
+
+// GetDaily returns the aggregates of the days up to today, of the service and of the endpoint when they're set,
+// sorted by day, service and endpoint
+func (store *Store) GetDaily(service string, endpoint string, days int) []*shared.EndpointDailyTrend {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+
+	firstDay := store.now().UTC().AddDate(0, 0, 1-days).Format(DayFormat)
+	trends := make([]*shared.EndpointDailyTrend, 0)
+	for day, endpoints := range store.days {
+		if day < firstDay {
+			continue
+		}
+
+		for key, dayAggregate := range endpoints {
+			if (service != "" && key.service != service) || (endpoint != "" && key.endpoint != endpoint) {
+				continue
+			}
+
+			trends = append(trends, &shared.EndpointDailyTrend{
+				Day:                day,
+				Service:            key.service,
+				Endpoint:           key.endpoint,
+				EndpointTrendStats: dayAggregate.stats(),
+			})
+		}
+	}
+
+	sort.Slice(trends, func(i, j int) bool {
+		if trends[i].Day != trends[j].Day {
+			return trends[i].Day < trends[j].Day
+		}
+		if trends[i].Service != trends[j].Service {
+			return trends[i].Service < trends[j].Service
+		}
+		return trends[i].Endpoint < trends[j].Endpoint
+	})
+
+	return trends
+}
+
+// GetWeekOverWeek contrasts the endpoints over the 7 days ending at end with the 7 days before them, the weekly p95 is
+// interpolated within the histograms of the days summed up
+func (store *Store) GetWeekOverWeek(end time.Time) *shared.WeekOverWeekResponse {
+	end = end.UTC()
+	response := &shared.WeekOverWeekResponse{
+		Start:         end.AddDate(0, 0, 1-weekDays).Format(DayFormat),
+		End:           end.Format(DayFormat),
+		LastWeekStart: end.AddDate(0, 0, 1-2*weekDays).Format(DayFormat),
+		LastWeekEnd:   end.AddDate(0, 0, -weekDays).Format(DayFormat),
+		Endpoints:     make([]*shared.EndpointWeekOverWeek, 0),
+	}
+
+	store.mutex.Lock()
+	thisWeek := store.sumDays(response.Start, response.End)
+	lastWeek := store.sumDays(response.LastWeekStart, response.LastWeekEnd)
+	store.mutex.Unlock()
+
+	keys := make(map[endpointKey]bool)
+	for key := range thisWeek {
+		keys[key] = true
+	}
+	for key := range lastWeek {
+		keys[key] = true
+	}
+
+	for key := range keys {
+		thisWeekAggregate, ok := thisWeek[key]
+		if !ok {
+			thisWeekAggregate = newAggregate("", key)
+		}
+		lastWeekAggregate, ok := lastWeek[key]
+		if !ok {
+			lastWeekAggregate = newAggregate("", key)
+		}
+
+		comparison := &shared.EndpointWeekOverWeek{
+			Service:  key.service,
+			Endpoint: key.endpoint,
+			ThisWeek: thisWeekAggregate.stats(),
+			LastWeek: lastWeekAggregate.stats(),
+		}
+		comparison.ErrorRateChange = comparison.ThisWeek.ErrorRate - comparison.LastWeek.ErrorRate
+		if comparison.LastWeek.Entries > 0 {
+			volumeChange := float64(comparison.ThisWeek.Entries-comparison.LastWeek.Entries) / float64(comparison.LastWeek.Entries)
+			comparison.VolumeChange = &volumeChange
+		}
+		if comparison.LastWeek.P95Ms > 0 && comparison.ThisWeek.Entries > 0 {
+			p95Change := (comparison.ThisWeek.P95Ms - comparison.LastWeek.P95Ms) / comparison.LastWeek.P95Ms
+			comparison.P95Change = &p95Change
+		}
+
+		response.Endpoints = append(response.Endpoints, comparison)
+	}
+
+	sort.Slice(response.Endpoints, func(i, j int) bool {
+		if response.Endpoints[i].Service != response.Endpoints[j].Service {
+			return response.Endpoints[i].Service < response.Endpoints[j].Service
+		}
+		return response.Endpoints[i].Endpoint < response.Endpoints[j].Endpoint
+	})
+
+	return response
+}
+
+// sumDays sums up the aggregates of every endpoint from the first day to the last one, both included
+func (store *Store) sumDays(firstDay string, lastDay string) map[endpointKey]*aggregate {
+	sums := make(map[endpointKey]*aggregate)
+	for day, endpoints := range store.days {
+		if day < firstDay || day > lastDay {
+			continue
+		}
+
+		for key, dayAggregate := range endpoints {
+			if _, ok := sums[key]; !ok {
+				sums[key] = newAggregate("", key)
+			}
+			sums[key].add(dayAggregate)
+		}
+	}
+
+	return sums
+}
+
+func (store *Store) oldestDay() string {
+	return store.now().UTC().AddDate(0, 0, 1-RetentionDays).Format(DayFormat)
+}
+
+// prune forgets the days past the retention, it runs as a day starts
+func (store *Store) prune() {
+	oldestDay := store.oldestDay()
+	for day := range store.days {
+		if day < oldestDay {
+			delete(store.days, day)
+		}
+	}
+}
+
+func (store *Store) saveLoop(stop chan struct{}) {
+	ticker := time.NewTicker(saveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			store.save()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// save writes a copy of the aggregates, the entries aren't held back while the file is written
+func (store *Store) save() {
+	store.mutex.Lock()
+	if !store.dirty || store.filePath == "" {
+		store.mutex.Unlock()
+		return
+	}
+	aggregates := store.snapshot()
+	filePath := store.filePath
+	store.dirty = false
+	store.mutex.Unlock()
+
+	if err := utils.SaveJsonFile(filePath, aggregates); err != nil {
+		logger.Log.Errorf("Error saving the endpoint trends, err: %v", err)
+	}
+}
+
+func (store *Store) snapshot() []*aggregate {
+	aggregates := make([]*aggregate, 0)
+	for _, endpoints := range store.days {
+		for _, dayAggregate := range endpoints {
+			aggregateCopy := *dayAggregate
+			aggregateCopy.Latencies = append([]int64(nil), dayAggregate.Latencies...)
+			aggregates = append(aggregates, &aggregateCopy)
+		}
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		if aggregates[i].Day != aggregates[j].Day {
+			return aggregates[i].Day < aggregates[j].Day
+		}
+		if aggregates[i].Service != aggregates[j].Service {
+			return aggregates[i].Service < aggregates[j].Service
+		}
+		return aggregates[i].Endpoint < aggregates[j].Endpoint
+	})
+
+	return aggregates
+}
+
+func latencyBucket(latencyMs int64) int {
+	for i, bound := range latencyBuckets {
+		if latencyMs <= bound {
+			return i
+		}
+	}
+
+	return len(latencyBuckets)
+}
+
+// percentile interpolates the latency of the quantile within its bucket, like histogram_quantile of prometheus does,
+// the latencies above the last bound are reported as the last bound
+func percentile(latencies []int64, quantile float64) float64 {
+	var total int64
+	for _, count := range latencies {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	rank := quantile * float64(total)
+	var cumulative int64
+	for i, count := range latencies {
+		if count == 0 || float64(cumulative+count) < rank {
+			cumulative += count
+			continue
+		}
+		if i == len(latencyBuckets) {
+			break
+		}
+
+		lowerBound := int64(0)
+		if i > 0 {
+			lowerBound = latencyBuckets[i-1]
+		}
+		return float64(lowerBound) + float64(latencyBuckets[i]-lowerBound)*(rank-float64(cumulative))/float64(count)
+	}
+
+	return float64(latencyBuckets[len(latencyBuckets)-1])
+}
+
+func endpointName(method string, requestUrl string) string {
+	path := "/"
+	if parsedUrl, err := url.Parse(requestUrl); err == nil {
+		path = normalizePath(parsedUrl.Path)
+	}
+
+	return fmt.Sprintf("%s %s", method, path)
+}
+
+// normalizePath replaces the ids in the path, so the requests of an endpoint are aggregated together
+func normalizePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if segment == "" {
+			continue
+		}
+		if patNumber.FindString(segment) == segment || oas.IsGibberish(segment) {
+			segments[i] = "{id}"
+		}
+	}
+
+	normalized := strings.Join(segments, "/")
+	if normalized == "" {
+		return "/"
+	}
+	return normalized
+}
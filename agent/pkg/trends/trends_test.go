@@ -0,0 +1,100 @@
+package trends
+
+import (
+	"fmt"
+	"path"
+	"testing"
+	"time"
+)
+
+func dayMs(day int, hour int) int64 {
+	return time.Date(2022, 3, day, hour, 0, 0, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+}
+
+func newTestStore() *Store {
+	store := newStore()
+	store.now = func() time.Time { return time.Date(2022, 3, 14, 12, 0, 0, 0, time.UTC) }
+	return store
+}
+
+func TestPercentile(t *testing.T) {
+	latencies := make([]int64, len(latencyBuckets)+1)
+	for i := 0; i < 100; i++ {
+		latencies[latencyBucket(int64(i))]++
+	}
+
+	// the 95th latency is in the bucket of 80 to 100ms, which has 20 of the latencies
+	if p95 := percentile(latencies, 0.95); p95 < 90 || p95 > 100 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "between 90 and 100", p95)
+	}
+
+	if p95 := percentile(make([]int64, len(latencyBuckets)+1), 0.95); p95 != 0 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 0, p95)
+	}
+}
+
+func TestWeekOverWeek(t *testing.T) {
+	store := newTestStore()
+
+	for i := 0; i < 10; i++ {
+		store.PushEntry("users", "GET", "http://users/users/1", 200, 20, dayMs(2, 10))
+	}
+	for i := 0; i < 20; i++ {
+		status := 200
+		if i%4 == 0 {
+			status = 500
+		}
+		store.PushEntry("users", "GET", fmt.Sprintf("http://users/users/%d", i), status, 40, dayMs(10, 10))
+	}
+
+	response := store.GetWeekOverWeek(time.Date(2022, 3, 14, 0, 0, 0, 0, time.UTC))
+	if response.Start != "2022-03-08" || response.LastWeekStart != "2022-03-01" || response.LastWeekEnd != "2022-03-07" {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "the weeks ending on 2022-03-14 and 2022-03-07", response)
+	}
+	if len(response.Endpoints) != 1 {
+		t.Fatalf("unexpected result - expected: %v, actual: %v", 1, len(response.Endpoints))
+	}
+
+	endpoint := response.Endpoints[0]
+	if endpoint.Endpoint != "GET /users/{id}" || endpoint.ThisWeek.Entries != 20 || endpoint.LastWeek.Entries != 10 {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "20 entries of GET /users/{id} against 10", endpoint)
+	}
+	if endpoint.VolumeChange == nil || *endpoint.VolumeChange != 1 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 1, endpoint.VolumeChange)
+	}
+	if endpoint.ErrorRateChange != 0.25 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 0.25, endpoint.ErrorRateChange)
+	}
+	if endpoint.P95Change == nil || *endpoint.P95Change <= 0 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "a p95 increase", endpoint.P95Change)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	store := newTestStore()
+
+	store.PushEntry("users", "GET", "http://users/", 200, 5, dayMs(14, 0)-int64(RetentionDays)*24*int64(time.Hour/time.Millisecond))
+	store.PushEntry("users", "GET", "http://users/", 200, 5, dayMs(14, 1))
+
+	if daily := store.GetDaily("", "", RetentionDays); len(daily) != 1 || daily[0].Day != "2022-03-14" {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "only the aggregate of 2022-03-14", daily)
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "trends.json")
+
+	store := newTestStore()
+	store.filePath = filePath
+	store.PushEntry("users", "POST", "http://users/users", 201, 12, dayMs(13, 8))
+	store.save()
+
+	loaded := newTestStore()
+	loaded.filePath = filePath
+	loaded.load()
+
+	daily := loaded.GetDaily("users", "POST /users", 7)
+	if len(daily) != 1 || daily[0].Entries != 1 || daily[0].P95Ms == 0 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "the saved aggregate", daily)
+	}
+}
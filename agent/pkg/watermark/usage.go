@@ -0,0 +1,144 @@
+package watermark
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+const cgroupRoot = "/sys/fs/cgroup"
+
+// a cgroup v1 without a limit reports the largest page aligned int64
+const unlimitedMemory = int64(1) << 62
+
+var errNoMemoryLimit = errors.New("the container has no memory limit")
+
+// memoryPercent is the used percent of the memory limit of the container, the used memory is its working set like
+// kubelet counts it for evicting and OOM killing, the inactive file cache is reclaimed before that happens
+func memoryPercent(root string) (int, error) {
+	used, limit, err := readCgroupV2Memory(root)
+	if os.IsNotExist(err) {
+		used, limit, err = readCgroupV1Memory(path.Join(root, "memory"))
+	}
+	if err != nil {
+		return -1, err
+	}
+
+	return percent(used, limit), nil
+}
+
+func readCgroupV2Memory(root string) (int64, int64, error) {
+	rawLimit, err := readCgroupFile(path.Join(root, "memory.max"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if rawLimit == "max" {
+		return 0, 0, errNoMemoryLimit
+	}
+	limit, err := strconv.ParseInt(rawLimit, 10, 64)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	usage, err := readCgroupInt(path.Join(root, "memory.current"))
+	if err != nil {
+		return 0, 0, err
+	}
+	inactiveFile, err := readMemoryStat(path.Join(root, "memory.stat"), "inactive_file")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return workingSet(usage, inactiveFile), limit, nil
+}
+
+func readCgroupV1Memory(root string) (int64, int64, error) {
+	limit, err := readCgroupInt(path.Join(root, "memory.limit_in_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	if limit >= unlimitedMemory {
+		return 0, 0, errNoMemoryLimit
+	}
+
+	usage, err := readCgroupInt(path.Join(root, "memory.usage_in_bytes"))
+	if err != nil {
+		return 0, 0, err
+	}
+	inactiveFile, err := readMemoryStat(path.Join(root, "memory.stat"), "total_inactive_file")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return workingSet(usage, inactiveFile), limit, nil
+}
+
+// diskPercent is the used percent of the volume of the directory, like df counts it the blocks reserved for root
+// aren't available
+func diskPercent(dir string) (int, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return -1, err
+	}
+
+	used := (int64(stat.Blocks) - int64(stat.Bfree)) * int64(stat.Bsize)
+	available := int64(stat.Bavail) * int64(stat.Bsize)
+	return percent(used, used+available), nil
+}
+
+func workingSet(usage int64, inactiveFile int64) int64 {
+	if inactiveFile > usage {
+		return 0
+	}
+	return usage - inactiveFile
+}
+
+func percent(used int64, total int64) int {
+	if total <= 0 {
+		return -1
+	}
+	return int(used * 100 / total)
+}
+
+func readCgroupFile(filePath string) (string, error) {
+	data, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func readCgroupInt(filePath string) (int64, error) {
+	value, err := readCgroupFile(filePath)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func readMemoryStat(filePath string, key string) (int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			return strconv.ParseInt(fields[1], 10, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+
+	return 0, fmt.Errorf("%s is missing from %s", key, filePath)
+}
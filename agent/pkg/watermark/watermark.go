@@ -0,0 +1,193 @@
+package watermark
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+// Guard sheds the ingestion as the memory or the disk of the API server run out, so it keeps serving the API and
+// reports the pressure instead of being OOM killed or filling its volume
+type Guard struct {
+	mutex         sync.RWMutex
+	config        shared.WatermarksConfig
+	level         string
+	since         int64
+	memoryPercent int
+	diskPercent   int
+	sampled       uint64
+	shedBodies    uint64
+	paused        uint64
+	stop          chan struct{}
+}
+
+var instance *Guard
+var once sync.Once
+
+func GetInstance() *Guard {
+	once.Do(func() {
+		instance = &Guard{level: shared.PressureLevelNormal, memoryPercent: -1, diskPercent: -1}
+	})
+	return instance
+}
+
+// Configure starts measuring the pressure every interval
+func (guard *Guard) Configure(config shared.WatermarksConfig) {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	if guard.stop != nil {
+		close(guard.stop)
+		guard.stop = nil
+	}
+
+	guard.config = config
+	if config.IntervalSec <= 0 {
+		logger.Log.Infof("No watermarks interval was supplied, shedding the ingestion disabled")
+		return
+	}
+
+	guard.stop = make(chan struct{})
+	go guard.watch(guard.stop, time.Duration(config.IntervalSec)*time.Second)
+	logger.Log.Infof("Shedding the ingestion from %d%% of the memory or the disk, pausing it from %d%%", config.HighPercent, config.CriticalPercent)
+}
+
+func (guard *Guard) watch(stop chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		memory, err := memoryPercent(cgroupRoot)
+		if err != nil && err != errNoMemoryLimit {
+			logger.Log.Debugf("Failed reading the memory usage: %v", err)
+		}
+		disk, err := diskPercent(shared.DataDirPath)
+		if err != nil {
+			logger.Log.Debugf("Failed reading the disk usage: %v", err)
+		}
+		guard.update(memory, disk, time.Now())
+
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// update moves to the level of the highest of the percents, the levels are left below a lower watermark than they're
+// reached at so the shedding doesn't flap
+func (guard *Guard) update(memoryPercent int, diskPercent int, now time.Time) {
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	guard.memoryPercent = memoryPercent
+	guard.diskPercent = diskPercent
+
+	pressure := memoryPercent
+	if diskPercent > pressure {
+		pressure = diskPercent
+	}
+
+	level := nextLevel(guard.level, pressure, guard.config)
+	if level == guard.level {
+		return
+	}
+
+	switch level {
+	case shared.PressureLevelCritical:
+		logger.Log.Errorf("Critical pressure, memory %d%%, disk %d%%, dropping the entries of the tappers", memoryPercent, diskPercent)
+	case shared.PressureLevelHigh:
+		logger.Log.Warningf("High pressure, memory %d%%, disk %d%%, dropping the bodies of the entries and keeping %v%% of them", memoryPercent, diskPercent, guard.config.SampleRate*100)
+	default:
+		logger.Log.Infof("The pressure is back to normal, memory %d%%, disk %d%%", memoryPercent, diskPercent)
+	}
+
+	guard.level = level
+	guard.since = now.UnixNano() / int64(time.Millisecond)
+}
+
+func nextLevel(level string, pressure int, config shared.WatermarksConfig) string {
+	switch {
+	case pressure >= config.CriticalPercent:
+		return shared.PressureLevelCritical
+	case level == shared.PressureLevelCritical && pressure >= config.HighPercent:
+		return shared.PressureLevelCritical
+	case pressure >= config.HighPercent:
+		return shared.PressureLevelHigh
+	case level != shared.PressureLevelNormal && pressure >= config.LowPercent:
+		return shared.PressureLevelHigh
+	default:
+		return shared.PressureLevelNormal
+	}
+}
+
+// AdmitTapped reports whether an entry of a tapper is taken in, the entries are dropped before they're parsed while
+// the pressure is critical
+func (guard *Guard) AdmitTapped() bool {
+	guard.mutex.RLock()
+	critical := guard.level == shared.PressureLevelCritical
+	guard.mutex.RUnlock()
+	if !critical {
+		return true
+	}
+
+	guard.mutex.Lock()
+	guard.paused++
+	guard.mutex.Unlock()
+	return false
+}
+
+// Admit samples an entry under high pressure, it returns whether the entry is kept and whether its bodies are dropped
+func (guard *Guard) Admit() (keep bool, shedBodies bool) {
+	guard.mutex.RLock()
+	level := guard.level
+	sampleRate := guard.config.SampleRate
+	guard.mutex.RUnlock()
+	if level == shared.PressureLevelNormal {
+		return true, false
+	}
+
+	guard.mutex.Lock()
+	defer guard.mutex.Unlock()
+
+	if rand.Float64() >= sampleRate {
+		guard.sampled++
+		return false, false
+	}
+
+	guard.shedBodies++
+	return true, true
+}
+
+func (guard *Guard) GetStatus() *shared.PressureStatus {
+	guard.mutex.RLock()
+	defer guard.mutex.RUnlock()
+
+	return &shared.PressureStatus{
+		Level:         guard.level,
+		Since:         guard.since,
+		MemoryPercent: guard.memoryPercent,
+		DiskPercent:   guard.diskPercent,
+		Sampled:       guard.sampled,
+		ShedBodies:    guard.shedBodies,
+		Paused:        guard.paused,
+	}
+}
+
+// ShedBodies drops the bodies of an http entry and its raw pair, so the contract and the rules aren't checked either
+func ShedBodies(entry *tapApi.Entry) {
+	entry.HTTPPair = ""
+
+	if postData, ok := entry.Request["postData"].(map[string]interface{}); ok {
+		delete(postData, "text")
+		delete(postData, "params")
+	}
+	if content, ok := entry.Response["content"].(map[string]interface{}); ok {
+		delete(content, "text")
+	}
+}
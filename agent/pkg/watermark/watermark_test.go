@@ -0,0 +1,130 @@
+package watermark
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+var config = shared.WatermarksConfig{LowPercent: 70, HighPercent: 80, CriticalPercent: 90, SampleRate: 0, IntervalSec: 5}
+
+func TestNextLevel(t *testing.T) {
+	tests := []struct {
+		level    string
+		pressure int
+		expected string
+	}{
+		{shared.PressureLevelNormal, 75, shared.PressureLevelNormal},
+		{shared.PressureLevelNormal, 80, shared.PressureLevelHigh},
+		{shared.PressureLevelNormal, 95, shared.PressureLevelCritical},
+		{shared.PressureLevelHigh, 72, shared.PressureLevelHigh},
+		{shared.PressureLevelHigh, 69, shared.PressureLevelNormal},
+		{shared.PressureLevelCritical, 85, shared.PressureLevelCritical},
+		{shared.PressureLevelCritical, 79, shared.PressureLevelHigh},
+		{shared.PressureLevelCritical, 10, shared.PressureLevelNormal},
+		{shared.PressureLevelNormal, -1, shared.PressureLevelNormal},
+	}
+
+	for _, test := range tests {
+		if actual := nextLevel(test.level, test.pressure, config); actual != test.expected {
+			t.Errorf("unexpected result for %s at %d%% - expected: %v, actual: %v", test.level, test.pressure, test.expected, actual)
+		}
+	}
+}
+
+func TestShedding(t *testing.T) {
+	guard := &Guard{level: shared.PressureLevelNormal, config: config}
+
+	if keep, shedBodies := guard.Admit(); !keep || shedBodies || !guard.AdmitTapped() {
+		t.Errorf("expected the entries to be taken in under normal pressure")
+	}
+
+	// the disk counts like the memory, and a memory without a limit doesn't count
+	guard.update(-1, 85, time.Now())
+	if keep, _ := guard.Admit(); keep {
+		t.Errorf("expected the entries to be sampled out under high pressure")
+	}
+	if !guard.AdmitTapped() {
+		t.Errorf("expected the entries of the tappers to be taken in under high pressure")
+	}
+
+	guard.update(92, 85, time.Now())
+	if guard.AdmitTapped() {
+		t.Errorf("expected the entries of the tappers to be dropped under critical pressure")
+	}
+
+	status := guard.GetStatus()
+	if status.Level != shared.PressureLevelCritical || status.MemoryPercent != 92 || status.Sampled != 1 || status.Paused != 1 {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "critical with 1 sampled and 1 paused", status)
+	}
+}
+
+func TestShedBodies(t *testing.T) {
+	entry := &tapApi.Entry{
+		HTTPPair: `{"request":{}}`,
+		Request:  map[string]interface{}{"postData": map[string]interface{}{"mimeType": "application/json", "text": "{}"}},
+		Response: map[string]interface{}{"content": map[string]interface{}{"mimeType": "text/html", "text": "<html>"}, "bodySize": 6},
+	}
+
+	ShedBodies(entry)
+
+	postData := entry.Request["postData"].(map[string]interface{})
+	content := entry.Response["content"].(map[string]interface{})
+	if entry.HTTPPair != "" || postData["text"] != nil || content["text"] != nil || content["mimeType"] != "text/html" {
+		t.Errorf("unexpected result - expected: %v, actual: %v %v %v", "no bodies", entry.HTTPPair, postData, content)
+	}
+}
+
+func TestMemoryPercent(t *testing.T) {
+	root, err := ioutil.TempDir("", "cgroup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(root)
+
+	if _, err := memoryPercent(root); err == nil {
+		t.Errorf("expected an error without a cgroup")
+	}
+
+	// cgroup v1
+	v1 := path.Join(root, "memory")
+	writeFiles(t, v1, map[string]string{
+		"memory.limit_in_bytes": "1000\n",
+		"memory.usage_in_bytes": "900\n",
+		"memory.stat":           "cache 300\ntotal_inactive_file 200\n",
+	})
+	if actual, err := memoryPercent(root); err != nil || actual != 70 {
+		t.Errorf("unexpected result - expected: %v, actual: %v %v", 70, actual, err)
+	}
+
+	// cgroup v2 is read first
+	writeFiles(t, root, map[string]string{
+		"memory.max":     "2000\n",
+		"memory.current": "1900\n",
+		"memory.stat":    "anon 1500\ninactive_file 100\n",
+	})
+	if actual, err := memoryPercent(root); err != nil || actual != 90 {
+		t.Errorf("unexpected result - expected: %v, actual: %v %v", 90, actual, err)
+	}
+
+	writeFiles(t, root, map[string]string{"memory.max": "max\n"})
+	if _, err := memoryPercent(root); err != errNoMemoryLimit {
+		t.Errorf("unexpected result - expected: %v, actual: %v", errNoMemoryLimit, err)
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(path.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
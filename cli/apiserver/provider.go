@@ -15,6 +15,7 @@ import (
 	"github.com/up9inc/mizu/cli/config"
 	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/tap/api"
 	core "k8s.io/api/core/v1"
 )
 
@@ -27,12 +28,18 @@ type Provider struct {
 const DefaultRetries = 3
 const DefaultTimeout = 2 * time.Second
 
+const sessionStoppedTimeout = 10 * time.Second
+
+// the api server uploads the archive before responding
+const archiveFlushTimeout = 40 * time.Second
+
 func NewProvider(url string, retries int, timeout time.Duration) *Provider {
 	return &Provider{
 		url:     url,
 		retries: config.GetIntEnvConfig(config.ApiServerRetries, retries),
 		client: &http.Client{
-			Timeout: timeout,
+			Timeout:   timeout,
+			Transport: &api.MizuTrafficTransport{Source: api.MizuTrafficCli},
 		},
 	}
 }
@@ -97,6 +104,77 @@ func (provider *Provider) ReportTappedPods(pods []core.Pod) error {
 	}
 }
 
+// ReportTapTargets sends the tapped pods of every node to the API server, which updates the running tappers with them
+func (provider *Provider) ReportTapTargets(nodeToTappedPodMap map[string][]core.Pod) error {
+	tapTargetsUrl := fmt.Sprintf("%s/status/tapTargets", provider.url)
+
+	if jsonValue, err := json.Marshal(nodeToTappedPodMap); err != nil {
+		return fmt.Errorf("failed Marshal the tap targets %w", err)
+	} else {
+		if _, err := utils.Post(tapTargetsUrl, "application/json", bytes.NewBuffer(jsonValue), provider.client); err != nil {
+			return fmt.Errorf("failed sending to API server the tap targets %w", err)
+		} else {
+			logger.Log.Debugf("Reported to server API about the tap targets of %d nodes successfully", len(nodeToTappedPodMap))
+			return nil
+		}
+	}
+}
+
+// NotifySessionStopped has the api server post session.stopped to the lifecycle webhooks, it returns once the events
+// were posted so the resources can be removed
+func (provider *Provider) NotifySessionStopped() error {
+	sessionStoppedUrl := fmt.Sprintf("%s/lifecycle/sessionStopped", provider.url)
+
+	// the api server waits for the webhooks, longer than the default timeout allows
+	client := &http.Client{Timeout: sessionStoppedTimeout, Transport: provider.client.Transport}
+	if _, err := utils.Post(sessionStoppedUrl, "application/json", nil, client); err != nil {
+		return fmt.Errorf("failed notifying the API server the session stopped %w", err)
+	}
+
+	return nil
+}
+
+// FlushArchive has the api server upload the entries since its last archive, so they're archived before the resources
+// are removed
+func (provider *Provider) FlushArchive() error {
+	flushUrl := fmt.Sprintf("%s/archive/flush", provider.url)
+
+	client := &http.Client{Timeout: archiveFlushTimeout, Transport: provider.client.Transport}
+	response, err := utils.Post(flushUrl, "application/json", nil, client)
+	if err != nil {
+		return fmt.Errorf("failed flushing the archive %w", err)
+	}
+	response.Body.Close()
+
+	return nil
+}
+
+// PostArchivedEntries stores the entries of an archive of any format version in the api server
+func (provider *Provider) PostArchivedEntries(archive []byte) (*shared.ArchiveReplayResponse, error) {
+	entriesUrl := fmt.Sprintf("%s/archive/entries", provider.url)
+
+	request, err := http.NewRequest(http.MethodPost, entriesUrl, bytes.NewReader(archive))
+	if err != nil {
+		return nil, err
+	}
+	// the api server tells the format versions apart by their content
+	request.Header.Set("Content-Type", "application/octet-stream")
+
+	response, requestErr := utils.Do(request, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to store the archived entries, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	replayResponse := &shared.ArchiveReplayResponse{}
+	if err := json.NewDecoder(response.Body).Decode(replayResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse the stored archive response, err: %w", err)
+	}
+
+	return replayResponse, nil
+}
+
 func (provider *Provider) GetGeneralStats() (map[string]interface{}, error) {
 	generalStatsUrl := fmt.Sprintf("%s/status/general", provider.url)
 
@@ -119,6 +197,43 @@ func (provider *Provider) GetGeneralStats() (map[string]interface{}, error) {
 	return generalStats, nil
 }
 
+// GetSinksHealth asks the API server to validate every configured export destination, an empty list is returned
+// when none is configured
+func (provider *Provider) GetSinksHealth() ([]*shared.SinkHealth, error) {
+	sinksUrl := fmt.Sprintf("%s/status/sinks", provider.url)
+
+	response, requestErr := utils.Get(sinksUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get sinks health, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var sinksHealth []*shared.SinkHealth
+	if parseErr := json.NewDecoder(response.Body).Decode(&sinksHealth); parseErr != nil {
+		return nil, fmt.Errorf("failed to parse sinks health, err: %v", parseErr)
+	}
+	return sinksHealth, nil
+}
+
+// GetPressureStatus returns the memory and disk pressure of the API server and the entries it shed under it
+func (provider *Provider) GetPressureStatus() (*shared.PressureStatus, error) {
+	pressureUrl := fmt.Sprintf("%s/status/pressure", provider.url)
+
+	response, requestErr := utils.Get(pressureUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get pressure status, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var pressureStatus *shared.PressureStatus
+	if parseErr := json.NewDecoder(response.Body).Decode(&pressureStatus); parseErr != nil {
+		return nil, fmt.Errorf("failed to parse pressure status, err: %v", parseErr)
+	}
+	return pressureStatus, nil
+}
+
 func (provider *Provider) GetVersion() (string, error) {
 	versionUrl, _ := url.Parse(fmt.Sprintf("%s/metadata/version", provider.url))
 	req := &http.Request{
@@ -138,3 +253,463 @@ func (provider *Provider) GetVersion() (string, error) {
 
 	return versionResponse.Ver, nil
 }
+
+// GetFeatures returns the modules that are enabled in the API server
+func (provider *Provider) GetFeatures() (*shared.AgentFeatures, error) {
+	featuresUrl := fmt.Sprintf("%s/metadata/features", provider.url)
+
+	response, requestErr := utils.Get(featuresUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get features, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var features shared.AgentFeatures
+	if parseErr := json.NewDecoder(response.Body).Decode(&features); parseErr != nil {
+		return nil, fmt.Errorf("failed to parse features, err: %v", parseErr)
+	}
+	return &features, nil
+}
+
+// EntriesMetadata is the part of the query metadata returned with the entries that the cli uses
+type EntriesMetadata struct {
+	Total   int `json:"total"`
+	LeftOff int `json:"leftOff"`
+}
+
+func (provider *Provider) GetEntries(query string, limit int) ([]map[string]interface{}, error) {
+	entries, _, err := provider.getEntries(query, -1, -1, limit)
+	return entries, err
+}
+
+// GetEntriesAfter returns up to limit entries matching query, scanning forward starting at the database index leftOff,
+// the entries are returned oldest first
+func (provider *Provider) GetEntriesAfter(query string, leftOff int, limit int) ([]map[string]interface{}, *EntriesMetadata, error) {
+	return provider.getEntries(query, leftOff, 1, limit)
+}
+
+func (provider *Provider) getEntries(query string, leftOff int, direction int, limit int) ([]map[string]interface{}, *EntriesMetadata, error) {
+	entriesUrl, _ := url.Parse(fmt.Sprintf("%s/entries", provider.url))
+	queryParams := entriesUrl.Query()
+	queryParams.Set("leftOff", fmt.Sprintf("%d", leftOff))
+	queryParams.Set("direction", fmt.Sprintf("%d", direction))
+	queryParams.Set("query", query)
+	queryParams.Set("limit", fmt.Sprintf("%d", limit))
+	entriesUrl.RawQuery = queryParams.Encode()
+
+	response, requestErr := utils.Get(entriesUrl.String(), provider.client)
+	if requestErr != nil {
+		return nil, nil, fmt.Errorf("failed to get entries, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var entriesResponse struct {
+		Data []map[string]interface{} `json:"data"`
+		Meta *EntriesMetadata         `json:"meta"`
+	}
+	if err := json.NewDecoder(response.Body).Decode(&entriesResponse); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse entries, err: %w", err)
+	}
+
+	if entriesResponse.Meta == nil {
+		entriesResponse.Meta = &EntriesMetadata{}
+	}
+
+	return entriesResponse.Data, entriesResponse.Meta, nil
+}
+
+func (provider *Provider) GetEntry(id string) (map[string]interface{}, error) {
+	entryUrl := fmt.Sprintf("%s/entries/%s", provider.url, url.PathEscape(id))
+
+	response, requestErr := utils.Get(entryUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get entry %s, err: %w", id, requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var entry map[string]interface{}
+	if err := json.NewDecoder(response.Body).Decode(&entry); err != nil {
+		return nil, fmt.Errorf("failed to parse entry %s, err: %w", id, err)
+	}
+
+	return entry, nil
+}
+
+// GetHar returns the http entries matching the query as a HAR log, from and to are unix milliseconds bounding the
+// entries timestamps and 0 leaves that side of the range open
+func (provider *Provider) GetHar(query string, limit int, from int64, to int64) ([]byte, error) {
+	harUrl, _ := url.Parse(fmt.Sprintf("%s/export/har", provider.url))
+	queryParams := harUrl.Query()
+	queryParams.Set("query", query)
+	queryParams.Set("limit", fmt.Sprintf("%d", limit))
+	queryParams.Set("from", fmt.Sprintf("%d", from))
+	queryParams.Set("to", fmt.Sprintf("%d", to))
+	harUrl.RawQuery = queryParams.Encode()
+
+	response, requestErr := utils.Get(harUrl.String(), provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to export entries, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	data, readErr := ioutil.ReadAll(response.Body)
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read exported entries, err: %w", readErr)
+	}
+
+	return data, nil
+}
+
+// GetComparison contrasts the http entries matching the stable and the canary queries, from and to are unix
+// milliseconds bounding the entries timestamps and 0 leaves that side of the range open
+func (provider *Provider) GetComparison(stableQuery string, canaryQuery string, limit int, from int64, to int64) (*shared.ComparisonResponse, error) {
+	compareUrl, _ := url.Parse(fmt.Sprintf("%s/compare/", provider.url))
+	queryParams := compareUrl.Query()
+	queryParams.Set("stableQuery", stableQuery)
+	queryParams.Set("canaryQuery", canaryQuery)
+	queryParams.Set("limit", fmt.Sprintf("%d", limit))
+	queryParams.Set("from", fmt.Sprintf("%d", from))
+	queryParams.Set("to", fmt.Sprintf("%d", to))
+	compareUrl.RawQuery = queryParams.Encode()
+
+	response, requestErr := utils.Get(compareUrl.String(), provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to compare entries, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	comparison := &shared.ComparisonResponse{}
+	if err := json.NewDecoder(response.Body).Decode(comparison); err != nil {
+		return nil, fmt.Errorf("failed to parse the comparison, err: %w", err)
+	}
+
+	return comparison, nil
+}
+
+// GetServiceMap returns the graph of the services that called each other in the stored entries
+func (provider *Provider) GetServiceMap() (*shared.ServiceMapResponse, error) {
+	serviceMapUrl := fmt.Sprintf("%s/servicemap/get", provider.url)
+
+	response, requestErr := utils.Get(serviceMapUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get the service map, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	serviceMap := &shared.ServiceMapResponse{}
+	if err := json.NewDecoder(response.Body).Decode(serviceMap); err != nil {
+		return nil, fmt.Errorf("failed to parse the service map, err: %w", err)
+	}
+
+	return serviceMap, nil
+}
+
+// GetProvenanceSegments returns the signed segments of the stored entries chain, oldest first
+func (provider *Provider) GetProvenanceSegments() (*shared.ProvenanceSegmentsResponse, error) {
+	segmentsUrl := fmt.Sprintf("%s/provenance/segments", provider.url)
+
+	response, requestErr := utils.Get(segmentsUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get provenance segments, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var segmentsResponse *shared.ProvenanceSegmentsResponse
+	if err := json.NewDecoder(response.Body).Decode(&segmentsResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance segments, err: %w", err)
+	}
+
+	return segmentsResponse, nil
+}
+
+func (provider *Provider) Send(sendRequest *shared.SendRequest) (*shared.SendResponse, error) {
+	sendUrl := fmt.Sprintf("%s/send/", provider.url)
+
+	jsonValue, err := json.Marshal(sendRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the send request, err: %w", err)
+	}
+
+	response, requestErr := utils.Post(sendUrl, "application/json", bytes.NewBuffer(jsonValue), provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to send request to %s, err: %w", sendRequest.Url, requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var sendResponse *shared.SendResponse
+	if err := json.NewDecoder(response.Body).Decode(&sendResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse the send response, err: %w", err)
+	}
+
+	return sendResponse, nil
+}
+
+func (provider *Provider) Replay(replayRequest *shared.ReplayRequest) (*shared.ReplayResponse, error) {
+	replayUrl := fmt.Sprintf("%s/replay/", provider.url)
+
+	jsonValue, err := json.Marshal(replayRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the replay request, err: %w", err)
+	}
+
+	response, requestErr := utils.Post(replayUrl, "application/json", bytes.NewBuffer(jsonValue), provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to replay the entries to %s, err: %w", replayRequest.TargetUrl, requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var replayResponse *shared.ReplayResponse
+	if err := json.NewDecoder(response.Body).Decode(&replayResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse the replay response, err: %w", err)
+	}
+
+	return replayResponse, nil
+}
+
+// TestRedaction redacts the entries of the HAR log of the request, or the latest stored http entries matching its
+// query, with the options of the request and returns the values the redaction changes
+func (provider *Provider) TestRedaction(testRequest *shared.RedactionTestRequest) (*shared.RedactionTestResponse, error) {
+	testUrl := fmt.Sprintf("%s/redaction/test", provider.url)
+
+	jsonValue, err := json.Marshal(testRequest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the redaction test request, err: %w", err)
+	}
+
+	response, requestErr := utils.Post(testUrl, "application/json", bytes.NewBuffer(jsonValue), provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to test the redaction, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var testResponse *shared.RedactionTestResponse
+	if err := json.NewDecoder(response.Body).Decode(&testResponse); err != nil {
+		return nil, fmt.Errorf("failed to parse the redaction test response, err: %w", err)
+	}
+
+	return testResponse, nil
+}
+
+// GetIngestionStatus returns the protocols and namespaces whose entries the API server doesn't store
+func (provider *Provider) GetIngestionStatus() (*shared.IngestionStatus, error) {
+	ingestionUrl := fmt.Sprintf("%s/ingestion/", provider.url)
+
+	response, requestErr := utils.Get(ingestionUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get the ingestion status, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var ingestionStatus shared.IngestionStatus
+	if parseErr := json.NewDecoder(response.Body).Decode(&ingestionStatus); parseErr != nil {
+		return nil, fmt.Errorf("failed to parse the ingestion status, err: %v", parseErr)
+	}
+	return &ingestionStatus, nil
+}
+
+// MuteIngestion stops storing the entries of a protocol or of a namespace, kind is shared.IngestionMuteProtocol or
+// shared.IngestionMuteNamespace
+func (provider *Provider) MuteIngestion(kind string, value string) (*shared.IngestionMute, error) {
+	muteUrl := fmt.Sprintf("%s/ingestion/mutes", provider.url)
+
+	jsonValue, err := json.Marshal(&shared.IngestionMute{Kind: kind, Value: value})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the mute request, err: %w", err)
+	}
+
+	response, requestErr := utils.Post(muteUrl, "application/json", bytes.NewBuffer(jsonValue), provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to mute %s %s, err: %w", kind, value, requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var mute *shared.IngestionMute
+	if err := json.NewDecoder(response.Body).Decode(&mute); err != nil {
+		return nil, fmt.Errorf("failed to parse the mute response, err: %w", err)
+	}
+
+	return mute, nil
+}
+
+// UnmuteIngestion resumes storing the entries of a protocol or of a namespace, the returned mute counts the entries
+// that weren't stored
+func (provider *Provider) UnmuteIngestion(kind string, value string) (*shared.IngestionMute, error) {
+	unmuteUrl := fmt.Sprintf("%s/ingestion/mutes/%s/%s", provider.url, url.PathEscape(kind), url.PathEscape(value))
+
+	request, err := http.NewRequest(http.MethodDelete, unmuteUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, requestErr := utils.Do(request, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to unmute %s %s, err: %w", kind, value, requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var mute *shared.IngestionMute
+	if err := json.NewDecoder(response.Body).Decode(&mute); err != nil {
+		return nil, fmt.Errorf("failed to parse the unmute response, err: %w", err)
+	}
+
+	return mute, nil
+}
+
+func (provider *Provider) GetSavedQueries() ([]*shared.SavedQuery, error) {
+	savedQueriesUrl := fmt.Sprintf("%s/savedQueries/", provider.url)
+
+	response, requestErr := utils.Get(savedQueriesUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get the saved queries, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var savedQueries []*shared.SavedQuery
+	if parseErr := json.NewDecoder(response.Body).Decode(&savedQueries); parseErr != nil {
+		return nil, fmt.Errorf("failed to parse the saved queries, err: %v", parseErr)
+	}
+	return savedQueries, nil
+}
+
+func (provider *Provider) GetSavedQuery(name string) (*shared.SavedQuery, error) {
+	savedQueryUrl := fmt.Sprintf("%s/savedQueries/%s", provider.url, url.PathEscape(name))
+
+	response, requestErr := utils.Get(savedQueryUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get the saved query %s, err: %w", name, requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var savedQuery *shared.SavedQuery
+	if parseErr := json.NewDecoder(response.Body).Decode(&savedQuery); parseErr != nil {
+		return nil, fmt.Errorf("failed to parse the saved query, err: %v", parseErr)
+	}
+	return savedQuery, nil
+}
+
+// SaveQuery saves a new query, or changes the saved query of the same name when overwrite is set
+func (provider *Provider) SaveQuery(savedQuery *shared.SavedQuery, overwrite bool) (*shared.SavedQuery, error) {
+	jsonValue, err := json.Marshal(savedQuery)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the saved query, err: %w", err)
+	}
+
+	method := http.MethodPost
+	savedQueryUrl := fmt.Sprintf("%s/savedQueries/", provider.url)
+	if overwrite {
+		if _, err := provider.GetSavedQuery(savedQuery.Name); err == nil {
+			method = http.MethodPut
+			savedQueryUrl = fmt.Sprintf("%s/savedQueries/%s", provider.url, url.PathEscape(savedQuery.Name))
+		}
+	}
+
+	request, err := http.NewRequest(method, savedQueryUrl, bytes.NewBuffer(jsonValue))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, requestErr := utils.Do(request, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to save the query %s, err: %w", savedQuery.Name, requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var saved *shared.SavedQuery
+	if err := json.NewDecoder(response.Body).Decode(&saved); err != nil {
+		return nil, fmt.Errorf("failed to parse the saved query, err: %w", err)
+	}
+
+	return saved, nil
+}
+
+func (provider *Provider) DeleteSavedQuery(name string) error {
+	savedQueryUrl := fmt.Sprintf("%s/savedQueries/%s", provider.url, url.PathEscape(name))
+
+	request, err := http.NewRequest(http.MethodDelete, savedQueryUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	response, requestErr := utils.Do(request, provider.client)
+	if requestErr != nil {
+		return fmt.Errorf("failed to delete the saved query %s, err: %w", name, requestErr)
+	}
+	response.Body.Close()
+
+	return nil
+}
+
+// GetAlertRules returns the alert rules with the counts of their current windows
+func (provider *Provider) GetAlertRules() ([]*shared.AlertRuleStatus, error) {
+	rulesUrl := fmt.Sprintf("%s/alerts/rules", provider.url)
+
+	response, requestErr := utils.Get(rulesUrl, provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to get the alert rules, err: %w", requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var rules []*shared.AlertRuleStatus
+	if parseErr := json.NewDecoder(response.Body).Decode(&rules); parseErr != nil {
+		return nil, fmt.Errorf("failed to parse the alert rules, err: %v", parseErr)
+	}
+	return rules, nil
+}
+
+func (provider *Provider) AddAlertRule(rule *shared.AlertRule) (*shared.AlertRuleStatus, error) {
+	rulesUrl := fmt.Sprintf("%s/alerts/rules", provider.url)
+
+	jsonValue, err := json.Marshal(rule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal the alert rule, err: %w", err)
+	}
+
+	response, requestErr := utils.Post(rulesUrl, "application/json", bytes.NewBuffer(jsonValue), provider.client)
+	if requestErr != nil {
+		return nil, fmt.Errorf("failed to add the alert rule %s, err: %w", rule.Name, requestErr)
+	}
+
+	defer response.Body.Close()
+
+	var status *shared.AlertRuleStatus
+	if err := json.NewDecoder(response.Body).Decode(&status); err != nil {
+		return nil, fmt.Errorf("failed to parse the alert rule, err: %w", err)
+	}
+
+	return status, nil
+}
+
+func (provider *Provider) DeleteAlertRule(id int) error {
+	ruleUrl := fmt.Sprintf("%s/alerts/rules/%d", provider.url, id)
+
+	request, err := http.NewRequest(http.MethodDelete, ruleUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	response, requestErr := utils.Do(request, provider.client)
+	if requestErr != nil {
+		return fmt.Errorf("failed to delete the alert rule %d, err: %w", id, requestErr)
+	}
+	response.Body.Close()
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package ci
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// TestCase is a single assertion of a ci report, it failed when Failure is set
+type TestCase struct {
+	Suite   string
+	Name    string
+	Failure string
+	Details string
+}
+
+// Report is rendered as JUnit XML with a test suite per distinct TestCase.Suite, in the order they first appear
+type Report struct {
+	Name  string
+	Cases []*TestCase
+}
+
+func (report *Report) AddPassed(suite string, name string) {
+	report.Cases = append(report.Cases, &TestCase{Suite: suite, Name: name})
+}
+
+func (report *Report) AddFailed(suite string, name string, failure string, details string) {
+	report.Cases = append(report.Cases, &TestCase{Suite: suite, Name: name, Failure: failure, Details: details})
+}
+
+func (report *Report) Failures() int {
+	failures := 0
+	for _, testCase := range report.Cases {
+		if testCase.Failure != "" {
+			failures++
+		}
+	}
+
+	return failures
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitTestSuite struct {
+	Name      string           `xml:"name,attr"`
+	Tests     int              `xml:"tests,attr"`
+	Failures  int              `xml:"failures,attr"`
+	TestCases []*junitTestCase `xml:"testcase"`
+}
+
+type junitTestSuites struct {
+	XMLName    xml.Name          `xml:"testsuites"`
+	Name       string            `xml:"name,attr"`
+	Tests      int               `xml:"tests,attr"`
+	Failures   int               `xml:"failures,attr"`
+	TestSuites []*junitTestSuite `xml:"testsuite"`
+}
+
+func (report *Report) JUnit() ([]byte, error) {
+	testSuites := &junitTestSuites{Name: report.Name, Tests: len(report.Cases), Failures: report.Failures()}
+	suitesByName := make(map[string]*junitTestSuite)
+
+	for _, testCase := range report.Cases {
+		testSuite, ok := suitesByName[testCase.Suite]
+		if !ok {
+			testSuite = &junitTestSuite{Name: testCase.Suite}
+			suitesByName[testCase.Suite] = testSuite
+			testSuites.TestSuites = append(testSuites.TestSuites, testSuite)
+		}
+
+		junitCase := &junitTestCase{Name: testCase.Name, ClassName: fmt.Sprintf("%s.%s", report.Name, testCase.Suite)}
+		if testCase.Failure != "" {
+			junitCase.Failure = &junitFailure{Message: testCase.Failure, Type: testCase.Suite, Text: testCase.Details}
+			testSuite.Failures++
+		}
+		testSuite.Tests++
+		testSuite.TestCases = append(testSuite.TestCases, junitCase)
+	}
+
+	data, err := xml.MarshalIndent(testSuites, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), data...), nil
+}
+
+func (report *Report) WriteJUnit(path string) error {
+	data, err := report.JUnit()
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// WriteGitHubAnnotations writes an error workflow command per failed case, GitHub Actions shows them on the
+// summary of the run
+func (report *Report) WriteGitHubAnnotations(writer io.Writer) error {
+	for _, testCase := range report.Cases {
+		if testCase.Failure == "" {
+			continue
+		}
+
+		message := fmt.Sprintf("%s: %s", testCase.Name, testCase.Failure)
+		if testCase.Details != "" {
+			message = fmt.Sprintf("%s\n%s", message, testCase.Details)
+		}
+
+		title := fmt.Sprintf("%s %s", report.Name, testCase.Suite)
+		if _, err := fmt.Fprintf(writer, "::error title=%s::%s\n", escapeAnnotationProperty(title), escapeAnnotationData(message)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func escapeAnnotationData(data string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(data)
+}
+
+func escapeAnnotationProperty(property string) string {
+	return strings.NewReplacer(":", "%3A", ",", "%2C").Replace(escapeAnnotationData(property))
+}
@@ -0,0 +1,51 @@
+package ci
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func newTestReport() *Report {
+	report := &Report{Name: "check"}
+	report.AddPassed("kubernetes-api", "can query the Kubernetes API")
+	report.AddFailed("k8s-components", "'mizu-api-server' pod running", "pod not running", "check the pod events")
+	report.AddPassed("k8s-components", "'mizu-tapper-daemon-set' pods running")
+	return report
+}
+
+func TestJUnit(t *testing.T) {
+	data, err := newTestReport().JUnit()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	junit := string(data)
+	for _, expected := range []string{
+		`<testsuites name="check" tests="3" failures="1">`,
+		`<testsuite name="kubernetes-api" tests="1" failures="0">`,
+		`<testsuite name="k8s-components" tests="2" failures="1">`,
+		`<testcase name="&#39;mizu-api-server&#39; pod running" classname="check.k8s-components">`,
+		`<failure message="pod not running" type="k8s-components">check the pod events</failure>`,
+	} {
+		if !strings.Contains(junit, expected) {
+			t.Errorf("unexpected result - expected %s in: %s", expected, junit)
+		}
+	}
+}
+
+func TestWriteGitHubAnnotations(t *testing.T) {
+	report := newTestReport()
+	report.AddFailed("contract", "GET /orders/{id}", "100% of 3 entries failed", "response: status 500\nbody: missing")
+
+	var buffer bytes.Buffer
+	if err := report.WriteGitHubAnnotations(&buffer); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "::error title=check k8s-components::'mizu-api-server' pod running: pod not running%0Acheck the pod events\n" +
+		"::error title=check contract::GET /orders/{id}: 100%25 of 3 entries failed%0Aresponse: status 500%0Abody: missing\n"
+	if actual := buffer.String(); actual != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var alertsCmd = &cobra.Command{
+	Use:   "alerts list|add|delete [NAME QUERY | ID]",
+	Short: "Manage the alert rules evaluated on the live traffic",
+	Long: `Manage the alert rules of the API server, a rule fires when at least --threshold entries matching its query are captured within --window, and again every --cooldown while it stays breached.
+mizu alerts add payments-5xx 'response.status >= 500 and dst.name == "payments"' --threshold 10 --window 5m --slack-url https://hooks.slack.com/services/... adds a rule.
+The alerts are posted to --webhook-url as JSON and to --slack-url, a Slack incoming webhook, as a message. The rules aren't evaluated during maintenance windows.
+mizu alerts list prints the rules with the entries of their current window.
+mizu alerts delete 1 removes a rule.`,
+	ValidArgs:    []string{configStructs.AlertsActionList, configStructs.AlertsActionAdd, configStructs.AlertsActionDelete},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("alerts", config.Config.Alerts)
+		return runMizuAlerts()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("an action is required, one of %s", strings.Join(cmd.ValidArgs, ", "))
+		}
+		config.Config.Alerts.Action = args[0]
+
+		switch args[0] {
+		case configStructs.AlertsActionList:
+			if len(args) != 1 {
+				return fmt.Errorf("unexpected number of arguments, list takes none")
+			}
+		case configStructs.AlertsActionAdd:
+			if len(args) < 3 {
+				return fmt.Errorf("unexpected number of arguments, a name and a query are required")
+			}
+			config.Config.Alerts.Name = args[1]
+			config.Config.Alerts.Query = strings.Join(args[2:], " ")
+		case configStructs.AlertsActionDelete:
+			if len(args) != 2 {
+				return fmt.Errorf("unexpected number of arguments, the id of a rule is required")
+			}
+			ruleId, err := strconv.Atoi(args[1])
+			if err != nil {
+				return fmt.Errorf("invalid rule id %s", args[1])
+			}
+			config.Config.Alerts.RuleId = ruleId
+		default:
+			return fmt.Errorf("unknown action %s, one of %s", args[0], strings.Join(cmd.ValidArgs, ", "))
+		}
+
+		if err := config.Config.Alerts.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(alertsCmd)
+
+	defaultAlertsConfig := configStructs.AlertsConfig{}
+	if err := defaults.Set(&defaultAlertsConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	alertsCmd.Flags().Int(configStructs.ThresholdAlertsName, defaultAlertsConfig.Threshold, "The number of matching entries within the window that fires the rule")
+	alertsCmd.Flags().String(configStructs.WindowAlertsName, defaultAlertsConfig.Window, "The window the matching entries are counted in, like 5m")
+	alertsCmd.Flags().String(configStructs.CooldownAlertsName, defaultAlertsConfig.Cooldown, "How long a fired rule waits before firing again, the window by default")
+	alertsCmd.Flags().String(configStructs.WebhookUrlAlertsName, defaultAlertsConfig.WebhookUrl, "Post the alerts of the rule as JSON to this url")
+	alertsCmd.Flags().String(configStructs.SlackUrlAlertsName, defaultAlertsConfig.SlackUrl, "Post the alerts of the rule to this Slack incoming webhook")
+	alertsCmd.Flags().Uint16P(configStructs.GuiPortAlertsName, "p", defaultAlertsConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	alertsCmd.Flags().StringP(configStructs.UrlAlertsName, "u", defaultAlertsConfig.Url, "Provide a custom host")
+
+	if err := alertsCmd.Flags().MarkHidden(configStructs.UrlAlertsName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
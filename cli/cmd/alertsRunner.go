@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+func runMizuAlerts() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, apiServerProvider, err := connectToApiServer(ctx, cancel, config.Config.Alerts.Url, config.Config.Alerts.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	switch config.Config.Alerts.Action {
+	case configStructs.AlertsActionAdd:
+		status, err := apiServerProvider.AddAlertRule(&shared.AlertRule{
+			Name:        config.Config.Alerts.Name,
+			Query:       config.Config.Alerts.Query,
+			Threshold:   config.Config.Alerts.Threshold,
+			WindowSec:   int(config.Config.Alerts.WindowDuration().Seconds()),
+			CooldownSec: int(config.Config.Alerts.CooldownDuration().Seconds()),
+			WebhookUrl:  config.Config.Alerts.WebhookUrl,
+			SlackUrl:    config.Config.Alerts.SlackUrl,
+		})
+		if err != nil {
+			return err
+		}
+		logger.Log.Infof("Added the alert rule %s with id %d", status.Name, status.Id)
+		return nil
+	case configStructs.AlertsActionDelete:
+		if err := apiServerProvider.DeleteAlertRule(config.Config.Alerts.RuleId); err != nil {
+			return err
+		}
+		logger.Log.Infof("Deleted the alert rule %d", config.Config.Alerts.RuleId)
+		return nil
+	default:
+		rules, err := apiServerProvider.GetAlertRules()
+		if err != nil {
+			return err
+		}
+		return printAlertRules(rules)
+	}
+}
+
+func printAlertRules(rules []*shared.AlertRuleStatus) error {
+	if len(rules) == 0 {
+		logger.Log.Infof("There are no alert rules, add one with mizu alerts add")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tNAME\tCOUNT\tTHRESHOLD\tWINDOW\tSTATE\tFIRED\tQUERY")
+	for _, rule := range rules {
+		state := "ok"
+		if rule.Error != "" {
+			state = "error: " + rule.Error
+		} else if rule.Firing {
+			state = "firing"
+		}
+
+		fmt.Fprintf(writer, "%d\t%s\t%d\t%d\t%v\t%s\t%d\t%s\n", rule.Id, rule.Name, rule.Count, rule.Threshold,
+			time.Duration(rule.WindowSec)*time.Second, state, rule.Fired, rule.Query)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,34 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach",
+	Short: "Reconnect to a tap left running by mizu tap --daemon",
+	Long: `Reconnect to a tap left running by mizu tap --daemon.
+The GUI is opened and the changes of the tap status are printed until Ctrl+C, which detaches without stopping the tap,
+mizu clean stops it and removes mizu.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("attach", config.Config.Attach)
+		runMizuAttach()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+
+	defaultAttachConfig := configStructs.AttachConfig{}
+	if err := defaults.Set(&defaultAttachConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	attachCmd.Flags().Uint16P(configStructs.GuiPortAttachName, "p", defaultAttachConfig.GuiPort, "Provide a custom port for the web interface webserver")
+}
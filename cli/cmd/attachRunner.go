@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/cli/utils"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/logger"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func runMizuAttach() {
+	kubernetesProvider, err := getKubernetesProviderForCli()
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resourceNames := getSessionResourceNames()
+	mizuTap, err := kubernetesProvider.GetMizuTap(ctx, config.Config.MizuResourcesNamespace, resourceNames.MizuTapName)
+	if err != nil {
+		if k8serrors.IsNotFound(err) {
+			logger.Log.Infof("No tap is running in namespace %s, run `mizu tap --daemon` first", config.Config.MizuResourcesNamespace)
+		} else {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error getting tap %s: %v", resourceNames.MizuTapName, errormessage.FormatError(err)))
+		}
+		return
+	}
+	printMizuTapStatus(mizuTap)
+
+	exists, err := kubernetesProvider.DoesServiceExist(ctx, config.Config.MizuResourcesNamespace, resourceNames.ApiServerPodName)
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error checking for the %s service: %v", resourceNames.ApiServerPodName, errormessage.FormatError(err)))
+		return
+	}
+	if !exists {
+		logger.Log.Infof("%s service not found, run `mizu clean` and `mizu tap --daemon` again", resourceNames.ApiServerPodName)
+		return
+	}
+
+	logger.Log.Infof("Establishing connection to k8s cluster...")
+	startProxyReportErrorIfAny(kubernetesProvider, ctx, cancel, config.Config.Attach.GuiPort, resourceNames)
+	if ctx.Err() != nil {
+		return
+	}
+
+	url := GetApiServerUrl(config.Config.Attach.GuiPort)
+	logger.Log.Infof("Mizu is available at %s, Ctrl+C detaches and leaves the tap running", url)
+	if !config.Config.HeadlessMode {
+		uiUtils.OpenBrowser(url)
+	}
+
+	go watchMizuTapStatus(ctx, kubernetesProvider, resourceNames.MizuTapName, mizuTap.Status)
+
+	utils.WaitForFinish(ctx, cancel)
+	logger.Log.Infof("Detached, the tap keeps running until `mizu clean`")
+}
+
+func printMizuTapStatus(mizuTap *kubernetes.MizuTap) {
+	switch mizuTap.Status.Phase {
+	case kubernetes.MizuTapPhaseTapping:
+		logger.Log.Infof("Tap %s is tapping %d pods", mizuTap.Name, mizuTap.Status.TappedPods)
+	case kubernetes.MizuTapPhaseFailed:
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Tap %s failed, the api server keeps retrying it: %s", mizuTap.Name, mizuTap.Status.Message))
+	case kubernetes.MizuTapPhaseStopped:
+		logger.Log.Infof("Tap %s is stopped", mizuTap.Name)
+	default:
+		logger.Log.Infof("Tap %s isn't reconciled by the api server yet", mizuTap.Name)
+	}
+}
+
+// watchMizuTapStatus prints the status of the tap whenever it changes, like when the pods it taps come and go, and
+// detaches when the tap is removed
+func watchMizuTapStatus(ctx context.Context, kubernetesProvider *kubernetes.Provider, name string, status kubernetes.MizuTapStatus) {
+	watcher, err := kubernetesProvider.WatchMizuTaps(ctx, config.Config.MizuResourcesNamespace)
+	if err != nil {
+		logger.Log.Debugf("Error watching tap %s: %v", name, err)
+		return
+	}
+	defer watcher.Stop()
+
+	for event := range watcher.ResultChan() {
+		object, ok := event.Object.(*unstructured.Unstructured)
+		if !ok || object.GetName() != name {
+			continue
+		}
+
+		if event.Type == kubernetes.EventDeleted {
+			logger.Log.Infof("Tap %s was removed", name)
+			return
+		}
+
+		mizuTap, err := kubernetes.MizuTapFromUnstructured(object)
+		if err != nil {
+			logger.Log.Debugf("Error parsing tap %s: %v", name, err)
+			continue
+		}
+
+		if mizuTap.Status.Phase != status.Phase || mizuTap.Status.TappedPods != status.TappedPods || mizuTap.Status.Message != status.Message {
+			status = mizuTap.Status
+			printMizuTapStatus(mizuTap)
+		}
+	}
+}
@@ -3,7 +3,9 @@ package cmd
 import (
 	"github.com/creasty/defaults"
 	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
 	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
 	"github.com/up9inc/mizu/cli/telemetry"
 	"github.com/up9inc/mizu/shared/logger"
 )
@@ -11,10 +13,18 @@ import (
 var checkCmd = &cobra.Command{
 	Use:   "check",
 	Short: "Check the Mizu installation for potential problems",
+	// the failed checks are returned as an error with --ci
+	SilenceUsage: true,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Check.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		go telemetry.ReportRun("check", nil)
-		runMizuCheck()
-		return nil
+		return runMizuCheck()
 	},
 }
 
@@ -27,4 +37,12 @@ func init() {
 	}
 
 	checkCmd.Flags().Bool(configStructs.PreTapCheckName, defaultCheckConfig.PreTap, "Check pre-tap Mizu installation for potential problems")
+	checkCmd.Flags().Bool(configStructs.JsonCheckName, defaultCheckConfig.Json, "Print the check results as a json report")
+	checkCmd.Flags().Bool(configStructs.FixCheckName, defaultCheckConfig.Fix, "Recreate the missing mizu resources and delete the tapper pods that aren't running")
+	checkCmd.Flags().Bool(configStructs.CiCheckName, defaultCheckConfig.Ci, "Write a JUnit report and GitHub annotations, and exit non-zero when a check fails")
+	checkCmd.Flags().String(configStructs.JunitFileCheckName, defaultCheckConfig.JunitFile, "Path of the JUnit report written with --ci")
+	checkCmd.Flags().String(configStructs.AsCheckName, defaultCheckConfig.As, "Check the tap permissions of another user or service account (system:serviceaccount:<namespace>:<name>) instead of yours, with --pre-tap")
+	checkCmd.Flags().StringSlice(configStructs.AsGroupCheckName, defaultCheckConfig.AsGroups, "Groups of the user checked with --as, can be repeated")
+	checkCmd.Flags().Bool(configStructs.ReportCheckName, defaultCheckConfig.Report, "Write a support bundle with the cluster version, the nodes, the mizu pods, the RBAC review and the image pull events to attach to issues")
+	checkCmd.Flags().String(configStructs.ReportFileCheckName, defaultCheckConfig.ReportFile, "Path of the support bundle tarball written with --report")
 }
@@ -3,18 +3,26 @@ package cmd
 import (
 	"context"
 	"embed"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	core "k8s.io/api/core/v1"
 	rbac "k8s.io/api/rbac/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/ci"
 	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/resources"
 	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/kubernetes"
 	"github.com/up9inc/mizu/shared/logger"
 	"github.com/up9inc/mizu/shared/semver"
@@ -25,110 +33,409 @@ var (
 	embedFS embed.FS
 )
 
-func runMizuCheck() {
-	logger.Log.Infof("Mizu checks\n===================")
+const (
+	checkStatusPassed  = "passed"
+	checkStatusFailed  = "failed"
+	checkStatusWarning = "warning"
 
+	// the API server checks the export destinations one by one, each with its own timeout
+	sinksHealthTimeout = time.Minute
+)
+
+// checkResult is the outcome of a single check, the results are collected before rendering so they can be
+// printed either as log lines or as a json report
+type checkResult struct {
+	Check       string `json:"check"`
+	Status      string `json:"status"`
+	Message     string `json:"message"`
+	Error       string `json:"error,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+	Fixed       bool   `json:"fixed,omitempty"`
+	FixError    string `json:"fixError,omitempty"`
+	// fix repairs the failure with --fix, it's nil for failures that can't be repaired automatically
+	fix func(ctx context.Context) error
+}
+
+type checkReport struct {
+	Passed  bool           `json:"passed"`
+	Results []*checkResult `json:"results"`
+}
+
+func (report *checkReport) addPassed(check string, message string) {
+	report.Results = append(report.Results, &checkResult{Check: check, Status: checkStatusPassed, Message: message})
+}
+
+func (report *checkReport) addFailed(check string, message string, err error, remediation string) {
+	result := &checkResult{Check: check, Status: checkStatusFailed, Message: message, Remediation: remediation}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	report.Results = append(report.Results, result)
+}
+
+// addWarning records a finding that doesn't fail the checks, since it may still work on some clusters
+func (report *checkReport) addWarning(check string, message string, err error, remediation string) {
+	result := &checkResult{Check: check, Status: checkStatusWarning, Message: message, Remediation: remediation}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	report.Results = append(report.Results, result)
+}
+
+func (report *checkReport) addFixableFailed(check string, message string, err error, remediation string, fix func(ctx context.Context) error) {
+	report.addFailed(check, message, err, remediation)
+	report.Results[len(report.Results)-1].fix = fix
+}
+
+// fix runs the fixes of the failed checks in the order the checks ran, so a namespace is created before the
+// resources in it
+func (report *checkReport) fix(ctx context.Context) {
+	for _, result := range report.Results {
+		if result.Status != checkStatusFailed || result.fix == nil {
+			continue
+		}
+
+		if err := result.fix(ctx); err != nil {
+			result.FixError = err.Error()
+		} else {
+			result.Fixed = true
+		}
+	}
+}
+
+func runMizuCheck() error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel() // cancel will be called when this function exits
 
-	kubernetesProvider, kubernetesVersion, checkPassed := checkKubernetesApi()
+	report := &checkReport{Results: make([]*checkResult, 0)}
+
+	kubernetesProvider, kubernetesVersion, checkPassed := checkKubernetesApi(report)
 
 	if checkPassed {
-		checkPassed = checkKubernetesVersion(kubernetesVersion)
+		checkPassed = checkKubernetesVersion(report, kubernetesVersion)
 	}
 
 	if config.Config.Check.PreTap {
 		if checkPassed {
-			checkPassed = checkK8sTapPermissions(ctx, kubernetesProvider)
+			checkPassed = checkK8sTapPermissions(ctx, report, kubernetesProvider)
 		}
 
 		if checkPassed {
-			checkPassed = checkImagePullInCluster(ctx, kubernetesProvider)
+			checkPassed = checkNetwork(ctx, report, kubernetesProvider)
+		}
+
+		if checkPassed {
+			checkPassed = checkTargetNodes(ctx, report, kubernetesProvider)
+		}
+
+		if checkPassed {
+			checkPassed = checkImagePullInCluster(ctx, report, kubernetesProvider)
 		}
 	} else {
 		if checkPassed {
-			checkPassed = checkK8sResources(ctx, kubernetesProvider)
+			checkPassed = checkK8sResources(ctx, report, kubernetesProvider)
+		}
+
+		if checkPassed {
+			checkPassed = checkNetwork(ctx, report, kubernetesProvider)
 		}
 
 		if checkPassed {
-			checkPassed = checkServerConnection(kubernetesProvider)
+			checkPassed = checkServerConnection(report, kubernetesProvider)
+		}
+
+		if checkPassed {
+			checkPassed = checkCloudIdentity(ctx, report, kubernetesProvider)
+		}
+
+		if checkPassed {
+			checkPassed = checkSinks(report, kubernetesProvider)
 		}
 	}
 
-	if checkPassed {
-		logger.Log.Infof("\nStatus check results are %v", fmt.Sprintf(uiUtils.Green, "√"))
+	report.Passed = checkPassed
+
+	if !checkPassed && config.Config.Check.Fix {
+		report.fix(ctx)
+	}
+
+	if config.Config.Check.Json {
+		if err := printCheckReportJson(report); err != nil {
+			return err
+		}
 	} else {
-		logger.Log.Errorf("\nStatus check results are %v", fmt.Sprintf(uiUtils.Red, "✗"))
+		printCheckReport(report, "Mizu checks", "Status check")
+	}
+
+	if config.Config.Check.Report {
+		if err := writeCheckSupportBundle(ctx, report, kubernetesProvider, kubernetesVersion, config.Config.Check.ReportFile); err != nil {
+			return fmt.Errorf("failed to write the support bundle, err: %w", err)
+		}
+		// the json report is printed alone so it can be piped
+		if !config.Config.Check.Json {
+			logger.Log.Infof("Wrote the support bundle to %s, attach it to the issue", config.Config.Check.ReportFile)
+		}
+	}
+
+	if config.Config.Check.Ci {
+		return finishCiReport(report.ciReport(), config.Config.Check.JunitFile)
+	}
+
+	return nil
+}
+
+// ciReport has a test case per check result, a warning passes and a fixed failure is still reported as failed since
+// the fix is only verified by the next run
+func (report *checkReport) ciReport() *ci.Report {
+	ciReport := &ci.Report{Name: "check"}
+	for _, result := range report.Results {
+		if result.Status == checkStatusPassed || result.Status == checkStatusWarning {
+			ciReport.AddPassed(result.Check, result.Message)
+			continue
+		}
+
+		failure := result.Error
+		if failure == "" {
+			failure = checkStatusFailed
+		}
+		ciReport.AddFailed(result.Check, result.Message, failure, result.Remediation)
 	}
+
+	return ciReport
+}
+
+func printCheckReportJson(report *checkReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+	return nil
 }
 
-func checkKubernetesApi() (*kubernetes.Provider, *semver.SemVersion, bool) {
-	logger.Log.Infof("\nkubernetes-api\n--------------------")
+func printCheckReport(report *checkReport, title string, resultsTitle string) {
+	logger.Log.Infof("%s\n===================", title)
+
+	lastCheck := ""
+	for _, result := range report.Results {
+		if result.Check != lastCheck {
+			logger.Log.Infof("\n%s\n--------------------", result.Check)
+			lastCheck = result.Check
+		}
+
+		if result.Status == checkStatusPassed {
+			logger.Log.Infof("%v %s", fmt.Sprintf(uiUtils.Green, "√"), result.Message)
+			continue
+		}
+
+		if result.Status == checkStatusWarning {
+			if result.Error != "" {
+				logger.Log.Warningf("%v %s, err: %s", fmt.Sprintf(uiUtils.Yellow, "!"), result.Message, result.Error)
+			} else {
+				logger.Log.Warningf("%v %s", fmt.Sprintf(uiUtils.Yellow, "!"), result.Message)
+			}
+			if result.Remediation != "" {
+				logger.Log.Infof("  %s", result.Remediation)
+			}
+			continue
+		}
+
+		if result.Error != "" {
+			logger.Log.Errorf("%v %s, err: %s", fmt.Sprintf(uiUtils.Red, "✗"), result.Message, result.Error)
+		} else {
+			logger.Log.Errorf("%v %s", fmt.Sprintf(uiUtils.Red, "✗"), result.Message)
+		}
+		if result.Fixed {
+			logger.Log.Infof("  %v fixed", fmt.Sprintf(uiUtils.Green, "√"))
+		} else if result.FixError != "" {
+			logger.Log.Errorf("  %v couldn't fix, err: %s", fmt.Sprintf(uiUtils.Red, "✗"), result.FixError)
+		} else if result.Remediation != "" {
+			logger.Log.Infof("  %s", result.Remediation)
+		}
+	}
+
+	if report.Passed {
+		logger.Log.Infof("\n%s results are %v", resultsTitle, fmt.Sprintf(uiUtils.Green, "√"))
+	} else {
+		logger.Log.Errorf("\n%s results are %v", resultsTitle, fmt.Sprintf(uiUtils.Red, "✗"))
+	}
+
+	if config.Config.Check.Fix && !report.Passed {
+		logger.Log.Infof("Run mizu check again to verify the fixes")
+	}
+}
+
+func checkKubernetesApi(report *checkReport) (*kubernetes.Provider, *semver.SemVersion, bool) {
+	const check = "kubernetes-api"
 
 	kubernetesProvider, err := kubernetes.NewProvider(config.Config.KubeConfigPath(), config.Config.KubeContext)
 	if err != nil {
-		logger.Log.Errorf("%v can't initialize the client, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(check, "can't initialize the client", err, "make sure the kubeconfig file and context are valid, they can be set with --kube-config-path and --kube-context")
 		return nil, nil, false
 	}
-	logger.Log.Infof("%v can initialize the client", fmt.Sprintf(uiUtils.Green, "√"))
+	report.addPassed(check, "can initialize the client")
 
 	kubernetesVersion, err := kubernetesProvider.GetKubernetesVersion()
 	if err != nil {
-		logger.Log.Errorf("%v can't query the Kubernetes API, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(check, "can't query the Kubernetes API", err, "make sure the cluster is reachable and the credentials of the kubeconfig context didn't expire")
 		return nil, nil, false
 	}
-	logger.Log.Infof("%v can query the Kubernetes API", fmt.Sprintf(uiUtils.Green, "√"))
+	report.addPassed(check, "can query the Kubernetes API")
 
 	return kubernetesProvider, kubernetesVersion, true
 }
 
-func checkKubernetesVersion(kubernetesVersion *semver.SemVersion) bool {
-	logger.Log.Infof("\nkubernetes-version\n--------------------")
+func checkKubernetesVersion(report *checkReport, kubernetesVersion *semver.SemVersion) bool {
+	const check = "kubernetes-version"
 
 	if err := kubernetes.ValidateKubernetesVersion(kubernetesVersion); err != nil {
-		logger.Log.Errorf("%v not running the minimum Kubernetes API version, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(check, "not running the minimum Kubernetes API version", err, "upgrade the cluster to a supported Kubernetes version")
 		return false
 	}
 
-	logger.Log.Infof("%v is running the minimum Kubernetes API version", fmt.Sprintf(uiUtils.Green, "√"))
+	report.addPassed(check, "is running the minimum Kubernetes API version")
 	return true
 }
 
-func checkServerConnection(kubernetesProvider *kubernetes.Provider) bool {
-	logger.Log.Infof("\nAPI-server-connectivity\n--------------------")
+func checkServerConnection(report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
+	const check = "API-server-connectivity"
+	const remediation = "make sure the API server pod is running and nothing else listens on the gui port, which can be set with --gui-port"
 
 	serverUrl := GetApiServerUrl(config.Config.Tap.GuiPort)
 
 	apiServerProvider := apiserver.NewProvider(serverUrl, 1, apiserver.DefaultTimeout)
 	if err := apiServerProvider.TestConnection(); err == nil {
-		logger.Log.Infof("%v found Mizu server tunnel available and connected successfully to API server", fmt.Sprintf(uiUtils.Green, "√"))
+		report.addPassed(check, "found Mizu server tunnel available and connected successfully to API server")
 		return true
 	}
 
 	connectedToApiServer := false
 
 	if err := checkProxy(serverUrl, kubernetesProvider); err != nil {
-		logger.Log.Errorf("%v couldn't connect to API server using proxy, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(check, "couldn't connect to API server using proxy", err, remediation)
 	} else {
 		connectedToApiServer = true
-		logger.Log.Infof("%v connected successfully to API server using proxy", fmt.Sprintf(uiUtils.Green, "√"))
+		report.addPassed(check, "connected successfully to API server using proxy")
 	}
 
 	if err := checkPortForward(serverUrl, kubernetesProvider); err != nil {
-		logger.Log.Errorf("%v couldn't connect to API server using port-forward, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(check, "couldn't connect to API server using port-forward", err, remediation)
 	} else {
 		connectedToApiServer = true
-		logger.Log.Infof("%v connected successfully to API server using port-forward", fmt.Sprintf(uiUtils.Green, "√"))
+		report.addPassed(check, "connected successfully to API server using port-forward")
 	}
 
 	return connectedToApiServer
 }
 
+// checkCloudIdentity validates that the service account of the API server is bound to the configured cloud identity,
+// the identity webhooks only inject it into the pods created after the service account was annotated
+func checkCloudIdentity(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
+	const check = "cloud-identity"
+
+	expectedAnnotations := kubernetes.GetCloudIdentityAnnotations(config.Config.CloudIdentity)
+	if len(expectedAnnotations) == 0 {
+		return true
+	}
+
+	annotations, err := kubernetesProvider.GetServiceAccountAnnotations(ctx, config.Config.MizuResourcesNamespace, kubernetes.ServiceAccountName)
+	if err != nil {
+		report.addFailed(check, fmt.Sprintf("couldn't get the '%s' service account", kubernetes.ServiceAccountName), err, "")
+		return false
+	}
+
+	allPassed := true
+	for annotation, value := range expectedAnnotations {
+		if annotations[annotation] == value {
+			report.addPassed(check, fmt.Sprintf("'%s' service account is bound to %s", kubernetes.ServiceAccountName, value))
+			continue
+		}
+
+		annotation, value := annotation, value
+		report.addFixableFailed(check, fmt.Sprintf("'%s' service account isn't bound to %s, its %s annotation is '%s'", kubernetes.ServiceAccountName, value, annotation, annotations[annotation]), nil,
+			"annotate the service account and restart mizu, the identity is injected into the pods when they're created", func(ctx context.Context) error {
+				return kubernetesProvider.AnnotateServiceAccount(ctx, config.Config.MizuResourcesNamespace, kubernetes.ServiceAccountName, map[string]string{annotation: value})
+			})
+		allPassed = false
+	}
+
+	return allPassed
+}
+
+// checkSinks validates the export destinations from the API server, since that's where the entries are sent from
+func checkSinks(report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
+	const check = "export-destinations"
+	const remediation = "make sure the destination is reachable from the cluster and the configured user is allowed to write to it"
+
+	serverUrl := GetApiServerUrl(config.Config.Tap.GuiPort)
+
+	apiServerProvider := apiserver.NewProvider(serverUrl, 1, sinksHealthTimeout)
+	if err := apiServerProvider.TestConnection(); err != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		httpServer, err := kubernetes.StartProxy(kubernetesProvider, config.Config.Tap.ProxyHost, config.Config.Tap.GuiPort, config.Config.MizuResourcesNamespace, getSessionResourceNames().ApiServerPodName, cancel)
+		if err != nil {
+			report.addFailed(check, "couldn't connect to API server to check the export destinations", err, "")
+			return false
+		}
+		defer func() {
+			if err := httpServer.Shutdown(ctx); err != nil {
+				logger.Log.Debugf("Error occurred while stopping proxy, err: %v", err)
+			}
+		}()
+
+		apiServerProvider = apiserver.NewProvider(serverUrl, apiserver.DefaultRetries, sinksHealthTimeout)
+	}
+
+	sinksHealth, err := apiServerProvider.GetSinksHealth()
+	if err != nil {
+		report.addFailed(check, "couldn't check the export destinations", err, "")
+		return false
+	}
+
+	if len(sinksHealth) == 0 {
+		report.addPassed(check, "no export destinations are configured")
+		return true
+	}
+
+	allPassed := true
+	for _, health := range sinksHealth {
+		var healthErr error
+		if health.Error != "" {
+			healthErr = errors.New(health.Error)
+		}
+
+		destination := fmt.Sprintf("%s destination '%s'", health.Sink, health.Destination)
+		if !health.Connectivity {
+			report.addFailed(check, fmt.Sprintf("%s isn't reachable from the cluster", destination), healthErr, remediation)
+		} else if !health.Authentication && health.Identity != "" {
+			report.addFailed(check, fmt.Sprintf("%s couldn't authenticate as %s", destination, health.Identity), healthErr, "make sure the cloud identity trusts the mizu service account and its workload identity is enabled in the cluster")
+		} else if !health.Authentication {
+			report.addFailed(check, fmt.Sprintf("%s rejected the credentials", destination), healthErr, "make sure the configured credentials are valid")
+		} else if !health.Write && len(health.Permissions) > 0 {
+			report.addFailed(check, fmt.Sprintf("%s doesn't allow %s to write", destination, health.Identity), healthErr, fmt.Sprintf("grant %s the permissions %s on the destination", health.Identity, strings.Join(health.Permissions, ", ")))
+		} else if !health.Write {
+			report.addFailed(check, fmt.Sprintf("%s doesn't allow writing", destination), healthErr, remediation)
+		} else if health.Identity != "" {
+			report.addPassed(check, fmt.Sprintf("%s is reachable and writable as %s", destination, health.Identity))
+			continue
+		} else {
+			report.addPassed(check, fmt.Sprintf("%s is reachable and writable", destination))
+			continue
+		}
+		allPassed = false
+	}
+
+	return allPassed
+}
+
 func checkProxy(serverUrl string, kubernetesProvider *kubernetes.Provider) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	httpServer, err := kubernetes.StartProxy(kubernetesProvider, config.Config.Tap.ProxyHost, config.Config.Tap.GuiPort, config.Config.MizuResourcesNamespace, kubernetes.ApiServerPodName, cancel)
+	httpServer, err := kubernetes.StartProxy(kubernetesProvider, config.Config.Tap.ProxyHost, config.Config.Tap.GuiPort, config.Config.MizuResourcesNamespace, getSessionResourceNames().ApiServerPodName, cancel)
 	if err != nil {
 		return err
 	}
@@ -149,7 +456,7 @@ func checkPortForward(serverUrl string, kubernetesProvider *kubernetes.Provider)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	podRegex, _ := regexp.Compile(kubernetes.ApiServerPodName)
+	podRegex, _ := regexp.Compile(fmt.Sprintf("^%s$", getSessionResourceNames().ApiServerPodName))
 	forwarder, err := kubernetes.NewPortForward(kubernetesProvider, config.Config.MizuResourcesNamespace, podRegex, config.Config.Tap.GuiPort, ctx, cancel)
 	if err != nil {
 		return err
@@ -165,56 +472,100 @@ func checkPortForward(serverUrl string, kubernetesProvider *kubernetes.Provider)
 	return nil
 }
 
-func checkK8sResources(ctx context.Context, kubernetesProvider *kubernetes.Provider) bool {
-	logger.Log.Infof("\nk8s-components\n--------------------")
+func checkK8sResources(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
+	resourceNames := getSessionResourceNames()
+	namespace := config.Config.MizuResourcesNamespace
+	isNsRestrictedMode := config.Config.IsNsRestrictedMode()
+
+	fixRBAC := func(ctx context.Context) error {
+		return resources.CreateMizuRBAC(ctx, kubernetesProvider, isNsRestrictedMode, namespace)
+	}
 
-	exist, err := kubernetesProvider.DoesNamespaceExist(ctx, config.Config.MizuResourcesNamespace)
-	allResourcesExist := checkResourceExist(config.Config.MizuResourcesNamespace, "namespace", exist, err)
+	exist, err := kubernetesProvider.DoesNamespaceExist(ctx, namespace)
+	allResourcesExist := checkResourceExist(report, namespace, "namespace", exist, err, func(ctx context.Context) error {
+		_, err := kubernetesProvider.CreateNamespace(ctx, namespace)
+		return err
+	})
 
-	exist, err = kubernetesProvider.DoesConfigMapExist(ctx, config.Config.MizuResourcesNamespace, kubernetes.ConfigMapName)
-	allResourcesExist = checkResourceExist(kubernetes.ConfigMapName, "config map", exist, err) && allResourcesExist
+	exist, err = kubernetesProvider.DoesConfigMapExist(ctx, namespace, resourceNames.ConfigMapName)
+	allResourcesExist = checkResourceExist(report, resourceNames.ConfigMapName, "config map", exist, err, func(ctx context.Context) error {
+		return createConfigMapFromConfig(ctx, kubernetesProvider, resourceNames.ConfigMapName)
+	}) && allResourcesExist
 
-	exist, err = kubernetesProvider.DoesServiceAccountExist(ctx, config.Config.MizuResourcesNamespace, kubernetes.ServiceAccountName)
-	allResourcesExist = checkResourceExist(kubernetes.ServiceAccountName, "service account", exist, err) && allResourcesExist
+	exist, err = kubernetesProvider.DoesServiceAccountExist(ctx, namespace, kubernetes.ServiceAccountName)
+	allResourcesExist = checkResourceExist(report, kubernetes.ServiceAccountName, "service account", exist, err, fixRBAC) && allResourcesExist
 
-	if config.Config.IsNsRestrictedMode() {
-		exist, err = kubernetesProvider.DoesRoleExist(ctx, config.Config.MizuResourcesNamespace, kubernetes.RoleName)
-		allResourcesExist = checkResourceExist(kubernetes.RoleName, "role", exist, err) && allResourcesExist
+	if isNsRestrictedMode {
+		exist, err = kubernetesProvider.DoesRoleExist(ctx, namespace, kubernetes.RoleName)
+		allResourcesExist = checkResourceExist(report, kubernetes.RoleName, "role", exist, err, fixRBAC) && allResourcesExist
 
-		exist, err = kubernetesProvider.DoesRoleBindingExist(ctx, config.Config.MizuResourcesNamespace, kubernetes.RoleBindingName)
-		allResourcesExist = checkResourceExist(kubernetes.RoleBindingName, "role binding", exist, err) && allResourcesExist
+		exist, err = kubernetesProvider.DoesRoleBindingExist(ctx, namespace, kubernetes.RoleBindingName)
+		allResourcesExist = checkResourceExist(report, kubernetes.RoleBindingName, "role binding", exist, err, fixRBAC) && allResourcesExist
 	} else {
 		exist, err = kubernetesProvider.DoesClusterRoleExist(ctx, kubernetes.ClusterRoleName)
-		allResourcesExist = checkResourceExist(kubernetes.ClusterRoleName, "cluster role", exist, err) && allResourcesExist
+		allResourcesExist = checkResourceExist(report, kubernetes.ClusterRoleName, "cluster role", exist, err, fixRBAC) && allResourcesExist
 
 		exist, err = kubernetesProvider.DoesClusterRoleBindingExist(ctx, kubernetes.ClusterRoleBindingName)
-		allResourcesExist = checkResourceExist(kubernetes.ClusterRoleBindingName, "cluster role binding", exist, err) && allResourcesExist
+		allResourcesExist = checkResourceExist(report, kubernetes.ClusterRoleBindingName, "cluster role binding", exist, err, fixRBAC) && allResourcesExist
 	}
 
-	exist, err = kubernetesProvider.DoesServiceExist(ctx, config.Config.MizuResourcesNamespace, kubernetes.ApiServerPodName)
-	allResourcesExist = checkResourceExist(kubernetes.ApiServerPodName, "service", exist, err) && allResourcesExist
+	exist, err = kubernetesProvider.DoesServiceExist(ctx, namespace, resourceNames.ApiServerPodName)
+	allResourcesExist = checkResourceExist(report, resourceNames.ApiServerPodName, "service", exist, err, func(ctx context.Context) error {
+		_, err := kubernetesProvider.CreateService(ctx, namespace, resourceNames.ApiServerPodName, resourceNames.ApiServerPodName)
+		return err
+	}) && allResourcesExist
 
-	allResourcesExist = checkPodResourcesExist(ctx, kubernetesProvider) && allResourcesExist
+	allResourcesExist = checkPodResourcesExist(ctx, report, kubernetesProvider, resourceNames) && allResourcesExist
 
 	return allResourcesExist
 }
 
-func checkPodResourcesExist(ctx context.Context, kubernetesProvider *kubernetes.Provider) bool {
-	if pods, err := kubernetesProvider.ListPodsByAppLabel(ctx, config.Config.MizuResourcesNamespace, kubernetes.ApiServerPodName); err != nil {
-		logger.Log.Errorf("%v error checking if '%v' pod is running, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), kubernetes.ApiServerPodName, err)
+// createConfigMapFromConfig recreates the config map of the api server from the current config, like tap does
+func createConfigMapFromConfig(ctx context.Context, kubernetesProvider *kubernetes.Provider, configMapName string) error {
+	var serializedValidationRules string
+	if config.Config.Tap.EnforcePolicyFile != "" {
+		var err error
+		if serializedValidationRules, err = readValidationRules(config.Config.Tap.EnforcePolicyFile); err != nil {
+			return err
+		}
+	}
+
+	var serializedContract string
+	if config.Config.Tap.ContractFile != "" {
+		bytes, err := ioutil.ReadFile(config.Config.Tap.ContractFile)
+		if err != nil {
+			return err
+		}
+		serializedContract = string(bytes)
+	}
+
+	serializedMizuConfig, err := getSerializedMizuAgentConfig(getTapMizuAgentConfig())
+	if err != nil {
+		return err
+	}
+
+	return kubernetesProvider.CreateConfigMap(ctx, config.Config.MizuResourcesNamespace, configMapName, serializedValidationRules, serializedContract, serializedMizuConfig)
+}
+
+const k8sComponentsCheck = "k8s-components"
+const k8sComponentsRemediation = "run mizu clean and tap again to recreate the mizu resources"
+
+func checkPodResourcesExist(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider, resourceNames kubernetes.ResourceNames) bool {
+	if pods, err := kubernetesProvider.ListPodsByAppLabel(ctx, config.Config.MizuResourcesNamespace, resourceNames.ApiServerPodName); err != nil {
+		report.addFailed(k8sComponentsCheck, fmt.Sprintf("error checking if '%v' pod is running", resourceNames.ApiServerPodName), err, "")
 		return false
 	} else if len(pods) == 0 {
-		logger.Log.Errorf("%v '%v' pod doesn't exist", fmt.Sprintf(uiUtils.Red, "✗"), kubernetes.ApiServerPodName)
+		report.addFailed(k8sComponentsCheck, fmt.Sprintf("'%v' pod doesn't exist", resourceNames.ApiServerPodName), nil, k8sComponentsRemediation)
 		return false
 	} else if !kubernetes.IsPodRunning(&pods[0]) {
-		logger.Log.Errorf("%v '%v' pod not running", fmt.Sprintf(uiUtils.Red, "✗"), kubernetes.ApiServerPodName)
+		report.addFailed(k8sComponentsCheck, fmt.Sprintf("'%v' pod not running", resourceNames.ApiServerPodName), nil, "check the pod events and logs with kubectl describe or mizu logs")
 		return false
 	}
 
-	logger.Log.Infof("%v '%v' pod running", fmt.Sprintf(uiUtils.Green, "√"), kubernetes.ApiServerPodName)
+	report.addPassed(k8sComponentsCheck, fmt.Sprintf("'%v' pod running", resourceNames.ApiServerPodName))
 
-	if pods, err := kubernetesProvider.ListPodsByAppLabel(ctx, config.Config.MizuResourcesNamespace, kubernetes.TapperPodName); err != nil {
-		logger.Log.Errorf("%v error checking if '%v' pods are running, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), kubernetes.TapperPodName, err)
+	if pods, err := kubernetesProvider.ListPodsByAppLabel(ctx, config.Config.MizuResourcesNamespace, resourceNames.TapperPodName); err != nil {
+		report.addFailed(k8sComponentsCheck, fmt.Sprintf("error checking if '%v' pods are running", resourceNames.TapperPodName), err, "")
 		return false
 	} else {
 		tappers := 0
@@ -228,31 +579,42 @@ func checkPodResourcesExist(ctx context.Context, kubernetesProvider *kubernetes.
 		}
 
 		if notRunningTappers > 0 {
-			logger.Log.Errorf("%v '%v' %v/%v pods are not running", fmt.Sprintf(uiUtils.Red, "✗"), kubernetes.TapperPodName, notRunningTappers, tappers)
+			// the tapper daemon set recreates the deleted pods
+			report.addFixableFailed(k8sComponentsCheck, fmt.Sprintf("'%v' %v/%v pods are not running", resourceNames.TapperPodName, notRunningTappers, tappers), nil, "check the pod events and logs with kubectl describe or mizu logs", func(ctx context.Context) error {
+				for _, pod := range pods {
+					if kubernetes.IsPodRunning(&pod) {
+						continue
+					}
+					if err := kubernetesProvider.RemovePod(ctx, pod.Namespace, pod.Name); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
 			return false
 		}
 
-		logger.Log.Infof("%v '%v' %v pods running", fmt.Sprintf(uiUtils.Green, "√"), kubernetes.TapperPodName, tappers)
+		report.addPassed(k8sComponentsCheck, fmt.Sprintf("'%v' %v pods running", resourceNames.TapperPodName, tappers))
 		return true
 	}
 }
 
-func checkResourceExist(resourceName string, resourceType string, exist bool, err error) bool {
+func checkResourceExist(report *checkReport, resourceName string, resourceType string, exist bool, err error, fix func(ctx context.Context) error) bool {
 	if err != nil {
-		logger.Log.Errorf("%v error checking if '%v' %v exists, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), resourceName, resourceType, err)
+		report.addFailed(k8sComponentsCheck, fmt.Sprintf("error checking if '%v' %v exists", resourceName, resourceType), err, "")
 		return false
 	} else if !exist {
-		logger.Log.Errorf("%v '%v' %v doesn't exist", fmt.Sprintf(uiUtils.Red, "✗"), resourceName, resourceType)
+		report.addFixableFailed(k8sComponentsCheck, fmt.Sprintf("'%v' %v doesn't exist", resourceName, resourceType), nil, k8sComponentsRemediation, fix)
 		return false
 	}
 
-	logger.Log.Infof("%v '%v' %v exists", fmt.Sprintf(uiUtils.Green, "√"), resourceName, resourceType)
+	report.addPassed(k8sComponentsCheck, fmt.Sprintf("'%v' %v exists", resourceName, resourceType))
 	return true
 }
 
-func checkK8sTapPermissions(ctx context.Context, kubernetesProvider *kubernetes.Provider) bool {
-	logger.Log.Infof("\nkubernetes-permissions\n--------------------")
+const kubernetesPermissionsCheck = "kubernetes-permissions"
 
+func checkK8sTapPermissions(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
 	var filePath string
 	if config.Config.IsNsRestrictedMode() {
 		filePath = "permissionFiles/permissions-ns-tap.yaml"
@@ -262,13 +624,13 @@ func checkK8sTapPermissions(ctx context.Context, kubernetesProvider *kubernetes.
 
 	data, err := embedFS.ReadFile(filePath)
 	if err != nil {
-		logger.Log.Errorf("%v error while checking kubernetes permissions, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(kubernetesPermissionsCheck, "error while checking kubernetes permissions", err, "")
 		return false
 	}
 
 	obj, err := getDecodedObject(data)
 	if err != nil {
-		logger.Log.Errorf("%v error while checking kubernetes permissions, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(kubernetesPermissionsCheck, "error while checking kubernetes permissions", err, "")
 		return false
 	}
 
@@ -279,7 +641,7 @@ func checkK8sTapPermissions(ctx context.Context, kubernetesProvider *kubernetes.
 		rules = obj.(*rbac.ClusterRole).Rules
 	}
 
-	return checkPermissions(ctx, kubernetesProvider, rules)
+	return checkPermissions(ctx, report, kubernetesProvider, rules, apiServerWatchRules)
 }
 
 func getDecodedObject(data []byte) (runtime.Object, error) {
@@ -293,53 +655,128 @@ func getDecodedObject(data []byte) (runtime.Object, error) {
 	return obj, nil
 }
 
-func checkPermissions(ctx context.Context, kubernetesProvider *kubernetes.Provider, rules []rbac.PolicyRule) bool {
+// the api server watches the pods, services, endpoints, deployments, events and rollouts of the target namespaces, the
+// tap grants it the permissions only if the user holds them and without them the ips aren't resolved to names
+var apiServerWatchRules = kubernetes.GetApiServerWatchRules(kubernetes.ApiServerNamespacedResources)
+
+// the tap lists and watches the pods of the target namespaces and the api server the resources it watches there, the
+// other namespaced permissions are used in the mizu namespace
+var targetNamespacesPermissions = getRulesVerbs(apiServerWatchRules)
+
+// getRulesVerbs maps each resource of the rules to the verbs they grant on it
+func getRulesVerbs(rules []rbac.PolicyRule) map[string][]string {
+	resourcesVerbs := make(map[string][]string)
+	for _, rule := range rules {
+		for _, resource := range rule.Resources {
+			resourcesVerbs[resource] = shared.Unique(append(resourcesVerbs[resource], rule.Verbs...))
+		}
+	}
+
+	return resourcesVerbs
+}
+
+// checkPermissions fails on the missing permissions of the rules and warns about the missing ones of the optional rules
+func checkPermissions(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider, rules []rbac.PolicyRule, optionalRules []rbac.PolicyRule) bool {
+	canI := kubernetesProvider.CanI
+	asUser := ""
+	if config.Config.Check.As != "" {
+		// the access of another user is reviewed on its behalf, which needs a permission of its own
+		reviewAccess := kubernetes.ResourceAccess{Group: "authorization.k8s.io", Resource: "subjectaccessreviews", Verb: "create"}
+		exist, err := kubernetesProvider.CanI(ctx, reviewAccess)
+		if err != nil || !exist {
+			report.addFailed(kubernetesPermissionsCheck, fmt.Sprintf("can't check the permissions of %v, creating subjectaccessreviews in group 'authorization.k8s.io' isn't allowed", config.Config.Check.As), err, "run the check as a cluster admin")
+			return false
+		}
+
+		canI = func(ctx context.Context, access kubernetes.ResourceAccess) (bool, error) {
+			return kubernetesProvider.CanUser(ctx, config.Config.Check.As, config.Config.Check.AsGroups, access)
+		}
+		asUser = fmt.Sprintf(" as %v", config.Config.Check.As)
+	}
+
+	resourceScopes, err := kubernetesProvider.GetResourceScopes()
+	if err != nil {
+		report.addWarning(kubernetesPermissionsCheck, "can't discover which resources are cluster scoped, the permissions of every resource are checked as namespaced", err, "")
+	}
+
+	targetNamespaces := getNamespaces(kubernetesProvider)
+	namespacesOf := func(resource string, verb string) []string {
+		if shared.Contains(targetNamespacesPermissions[resource], verb) {
+			return targetNamespaces
+		}
+
+		return []string{config.Config.MizuResourcesNamespace}
+	}
+
 	permissionsExist := true
+	checkedAccesses := make(map[kubernetes.ResourceAccess]bool)
 
 	for _, rule := range rules {
-		for _, group := range rule.APIGroups {
-			for _, resource := range rule.Resources {
-				for _, verb := range rule.Verbs {
-					exist, err := kubernetesProvider.CanI(ctx, config.Config.MizuResourcesNamespace, resource, verb, group)
-					permissionsExist = checkPermissionExist(group, resource, verb, exist, err) && permissionsExist
-				}
+		for _, access := range kubernetes.GetRuleAccesses(rule, resourceScopes, namespacesOf) {
+			checkedAccesses[access] = true
+			exist, err := canI(ctx, access)
+			permissionsExist = checkPermissionExist(report, asUser, access, exist, err) && permissionsExist
+		}
+	}
+
+	for _, rule := range optionalRules {
+		for _, access := range kubernetes.GetRuleAccesses(rule, resourceScopes, namespacesOf) {
+			if checkedAccesses[access] {
+				continue
 			}
+			checkedAccesses[access] = true
+			exist, err := canI(ctx, access)
+			checkOptionalPermissionExist(report, asUser, access, exist, err)
 		}
 	}
 
 	return permissionsExist
 }
 
-func checkPermissionExist(group string, resource string, verb string, exist bool, err error) bool {
+// checkPermissionExist reports a permission, asUser names the user it was checked for and is empty for the current one
+func checkPermissionExist(report *checkReport, asUser string, access kubernetes.ResourceAccess, exist bool, err error) bool {
 	if err != nil {
-		logger.Log.Errorf("%v error checking permission for %v %v in group '%v', err: %v", fmt.Sprintf(uiUtils.Red, "✗"), verb, resource, group, err)
+		report.addFailed(kubernetesPermissionsCheck, fmt.Sprintf("error checking permission for %v%v", access, asUser), err, "")
 		return false
 	} else if !exist {
-		logger.Log.Errorf("%v can't %v %v in group '%v'", fmt.Sprintf(uiUtils.Red, "✗"), verb, resource, group)
+		report.addFailed(kubernetesPermissionsCheck, fmt.Sprintf("can't %v%v", access, asUser), nil, "ask a cluster admin to grant the permissions listed in the mizu permission files")
 		return false
 	}
 
-	logger.Log.Infof("%v can %v %v in group '%v'", fmt.Sprintf(uiUtils.Green, "√"), verb, resource, group)
+	report.addPassed(kubernetesPermissionsCheck, fmt.Sprintf("can %v%v", access, asUser))
 	return true
 }
 
-func checkImagePullInCluster(ctx context.Context, kubernetesProvider *kubernetes.Provider) bool {
-	logger.Log.Infof("\nimage-pull-in-cluster\n--------------------")
+// checkOptionalPermissionExist warns about an optional permission, the tap runs without it
+func checkOptionalPermissionExist(report *checkReport, asUser string, access kubernetes.ResourceAccess, exist bool, err error) {
+	if err != nil {
+		report.addWarning(kubernetesPermissionsCheck, fmt.Sprintf("error checking permission for %v%v", access, asUser), err, "")
+		return
+	} else if !exist {
+		report.addWarning(kubernetesPermissionsCheck, fmt.Sprintf("can't %v%v, mizu won't resolve the ips to names", access, asUser), nil, "ask a cluster admin to grant the permissions listed in the ip-resolution-optional mizu permission file")
+		return
+	}
+
+	report.addPassed(kubernetesPermissionsCheck, fmt.Sprintf("can %v%v", access, asUser))
+}
+
+func checkImagePullInCluster(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
+	const check = "image-pull-in-cluster"
 
 	podName := "image-pull-in-cluster"
 
 	defer removeImagePullInClusterResources(ctx, kubernetesProvider, podName)
 	if err := createImagePullInClusterResources(ctx, kubernetesProvider, podName); err != nil {
-		logger.Log.Errorf("%v error while creating image pull in cluster resources, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(check, "error while creating image pull in cluster resources", err, "")
 		return false
 	}
 
 	if err := checkImagePulled(ctx, kubernetesProvider, podName); err != nil {
-		logger.Log.Errorf("%v cluster is not able to pull mizu containers from docker hub, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		report.addFailed(check, "cluster is not able to pull mizu containers from docker hub", err, "allow the cluster nodes to pull images from docker hub, or mirror the mizu images to a reachable registry")
 		return false
 	}
 
-	logger.Log.Infof("%v cluster is able to pull mizu containers from docker hub", fmt.Sprintf(uiUtils.Green, "√"))
+	report.addPassed(check, "cluster is able to pull mizu containers from docker hub")
 	return true
 }
 
@@ -423,3 +860,197 @@ func createImagePullInClusterResources(ctx context.Context, kubernetesProvider *
 
 	return nil
 }
+
+const networkCheck = "network"
+
+// checkNetwork detects the CNI, verifies the tappers are admitted with the host network and their capabilities and
+// warns about the network policies that keep the tappers from reaching the API server, the most common reasons a tap
+// silently captures nothing
+func checkNetwork(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
+	checkCni(ctx, report, kubernetesProvider)
+
+	nodes, err := kubernetesProvider.ListNodes(ctx)
+	if err != nil {
+		report.addWarning(networkCheck, "can't list the nodes", err, "the tappers run on every linux node, ask a cluster admin to check the nodes")
+	} else {
+		checkNodesOs(report, nodes)
+	}
+
+	if !checkTapperAdmission(ctx, report, kubernetesProvider) {
+		return false
+	}
+
+	if nodes != nil {
+		checkNetworkPolicies(ctx, report, kubernetesProvider, nodes)
+	}
+
+	return true
+}
+
+func checkCni(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) {
+	daemonSets, err := kubernetesProvider.ListDaemonSets(ctx, kubernetes.K8sAllNamespaces)
+	if err != nil {
+		report.addWarning(networkCheck, "can't list the daemon sets to detect the CNI", err, "")
+		return
+	}
+
+	cnis := kubernetes.DetectCni(daemonSets)
+	if len(cnis) == 0 {
+		report.addWarning(networkCheck, "can't detect the CNI", nil, "mizu taps the pod traffic on the node network interfaces, CNIs that bypass them, like ones with eBPF host routing, may hide the traffic from the tappers")
+		return
+	}
+
+	report.addPassed(networkCheck, fmt.Sprintf("detected the %s CNI", strings.Join(cnis, ", ")))
+}
+
+func checkNodesOs(report *checkReport, nodes []core.Node) {
+	var windowsNodes []string
+	for _, node := range nodes {
+		if node.Labels[core.LabelOSStable] == "windows" {
+			windowsNodes = append(windowsNodes, node.Name)
+		}
+	}
+
+	if len(windowsNodes) > 0 {
+		report.addWarning(networkCheck, fmt.Sprintf("%d of %d nodes run windows, their pods can't be tapped: %s", len(windowsNodes), len(nodes), strings.Join(windowsNodes, ", ")), nil, "the tappers run only on linux nodes")
+		return
+	}
+
+	report.addPassed(networkCheck, fmt.Sprintf("all %d nodes run linux", len(nodes)))
+}
+
+// checkTapperAdmission dry runs a pod with the host network and the capabilities of a tapper, so pod security
+// admission, pod security policies and policy webhooks reject it without creating anything
+func checkTapperAdmission(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
+	capabilities := []core.Capability{"NET_RAW", "NET_ADMIN"}
+	if config.Config.Tap.ServiceMesh || config.Config.Tap.Tls {
+		capabilities = append(capabilities, "SYS_ADMIN", "SYS_PTRACE")
+	}
+
+	var zero int64
+	pod := &core.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "mizu-network-check",
+		},
+		Spec: core.PodSpec{
+			HostNetwork: true,
+			Containers: []core.Container{
+				{
+					Name:  "probe",
+					Image: "up9inc/busybox",
+					SecurityContext: &core.SecurityContext{
+						Capabilities: &core.Capabilities{Add: capabilities, Drop: []core.Capability{"ALL"}},
+					},
+				},
+			},
+			TerminationGracePeriodSeconds: &zero,
+		},
+	}
+
+	// the namespace is created by the tap, until then the namespace default gets the same cluster wide admission
+	namespace := config.Config.MizuResourcesNamespace
+	err := kubernetesProvider.DryRunCreatePod(ctx, namespace, pod)
+	if k8serrors.IsNotFound(err) && !config.Config.IsNsRestrictedMode() {
+		namespace = "default"
+		err = kubernetesProvider.DryRunCreatePod(ctx, namespace, pod)
+	}
+
+	if err != nil {
+		report.addFailed(networkCheck, fmt.Sprintf("pods with the host network and %v can't run in namespace %s", capabilities, namespace), err, "allow the host network and the capabilities in the mizu namespace, like with the pod-security.kubernetes.io/enforce=privileged label or an exception of the policy engine")
+		return false
+	}
+
+	report.addPassed(networkCheck, fmt.Sprintf("pods with the host network and %v can run in namespace %s", capabilities, namespace))
+	return true
+}
+
+// checkNetworkPolicies warns about the network policies of the mizu namespace that isolate the API server from the
+// tappers, which connect from the node ips since they run in the host network
+func checkNetworkPolicies(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider, nodes []core.Node) {
+	networkPolicies, err := kubernetesProvider.ListNetworkPolicies(ctx, config.Config.MizuResourcesNamespace)
+	if err != nil {
+		report.addWarning(networkCheck, fmt.Sprintf("can't list the network policies of namespace %s", config.Config.MizuResourcesNamespace), err, "")
+		return
+	}
+
+	apiServerPodName := getSessionResourceNames().ApiServerPodName
+	apiServerPodLabels := map[string]string{"app": apiServerPodName}
+	if apiServerPod, err := kubernetesProvider.GetPod(ctx, config.Config.MizuResourcesNamespace, apiServerPodName); err == nil {
+		apiServerPodLabels = apiServerPod.Labels
+	}
+
+	blockingPolicies := kubernetes.GetBlockingNetworkPolicies(networkPolicies, apiServerPodLabels, shared.DefaultApiServerPort, kubernetes.GetNodeInternalIps(nodes))
+	if len(blockingPolicies) > 0 {
+		report.addWarning(networkCheck, fmt.Sprintf("network policies %s may block the tappers from connecting to the API server", strings.Join(blockingPolicies, ", ")), nil, fmt.Sprintf("allow ingress to port %d of the API server from the node ips, the tappers run in the host network", shared.DefaultApiServerPort))
+		return
+	}
+
+	report.addPassed(networkCheck, "no network policy blocks the tappers from connecting to the API server")
+}
+
+const targetNodesCheck = "target-nodes"
+
+// checkTargetNodes verifies a tapper can run on every node of the target pods, with the tolerations, the node selector
+// and the architectures of the tappers
+func checkTargetNodes(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) bool {
+	const remediation = "add the tolerations or the node selector under tap.tapper-scheduling in the config, or set tap.tapper-scheduling.auto-tolerate to tolerate the taints of the nodes of the target pods"
+
+	targetPods, err := kubernetesProvider.ListAllRunningTapTargetPods(ctx, config.Config.Tap.PodRegex(), config.Config.Tap.PodLabelSelector(), getNamespaces(kubernetesProvider), config.Config.Tap.Annotations, getTapTargets(kubernetesProvider))
+	if err != nil {
+		report.addFailed(targetNodesCheck, "can't list the target pods", err, "")
+		return false
+	}
+
+	nodeToTargetPods := kubernetes.GetNodeHostToTappedPodsMap(kubernetes.ExcludeMizuPods(targetPods))
+	if len(nodeToTargetPods) == 0 {
+		report.addWarning(targetNodesCheck, "no running pods match the tap regex and namespaces", nil, "")
+		return true
+	}
+
+	nodes, err := kubernetesProvider.ListNodes(ctx)
+	if err != nil {
+		report.addFailed(targetNodesCheck, "can't list the nodes of the target pods", err, "")
+		return false
+	}
+
+	var targetNodes []core.Node
+	for _, node := range nodes {
+		if _, ok := nodeToTargetPods[node.Name]; ok {
+			targetNodes = append(targetNodes, node)
+		}
+	}
+
+	tapperScheduling := config.Config.Tap.TapperScheduling
+	tolerations := kubernetes.GetTapperTolerations(tapperScheduling, targetNodes)
+
+	checkPassed := true
+	for _, node := range targetNodes {
+		targetPodsCount := len(nodeToTargetPods[node.Name])
+
+		if untoleratedTaints := kubernetes.GetUntoleratedTaints(node, tolerations); len(untoleratedTaints) > 0 {
+			taints := make([]string, 0, len(untoleratedTaints))
+			for _, taint := range untoleratedTaints {
+				taints = append(taints, taint.ToString())
+			}
+			report.addFailed(targetNodesCheck, fmt.Sprintf("the tapper won't tolerate the taints %s of node %s, %d target pods won't be tapped", strings.Join(taints, ", "), node.Name, targetPodsCount), nil, remediation)
+			checkPassed = false
+			continue
+		}
+
+		if !kubernetes.MatchesNodeSelector(node, tapperScheduling.NodeSelector) {
+			report.addFailed(targetNodesCheck, fmt.Sprintf("node %s doesn't match the node selector of the tapper, %d target pods won't be tapped", node.Name, targetPodsCount), nil, remediation)
+			checkPassed = false
+			continue
+		}
+
+		if !kubernetes.IsTapperArchitectureSupported(node) {
+			report.addFailed(targetNodesCheck, fmt.Sprintf("node %s runs %s, the mizu image is published for %s only, %d target pods won't be tapped", node.Name, node.Labels[core.LabelArchStable], strings.Join(kubernetes.SupportedTapperArchitectures, ", "), targetPodsCount), nil, "move the target pods to nodes of a supported architecture")
+			checkPassed = false
+			continue
+		}
+
+		report.addPassed(targetNodesCheck, fmt.Sprintf("a tapper can run on node %s (%s) of %d target pods", node.Name, node.Labels[core.LabelArchStable], targetPodsCount))
+	}
+
+	return checkPassed
+}
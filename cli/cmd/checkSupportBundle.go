@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/mizu"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/semver"
+	core "k8s.io/api/core/v1"
+)
+
+// the reasons of the kubelet events about pulling images, Failed and BackOff are also used for other failures so
+// those are picked by their message
+var imagePullEventReasons = []string{"Pulling", "Pulled", "ErrImagePull", "ImagePullBackOff", "ErrImageNeverPull", "InspectFailed"}
+
+type checkBundleCluster struct {
+	CreatedAt         time.Time `json:"createdAt"`
+	CliVersion        string    `json:"cliVersion"`
+	KubeContext       string    `json:"kubeContext,omitempty"`
+	KubernetesVersion string    `json:"kubernetesVersion,omitempty"`
+	MizuNamespace     string    `json:"mizuNamespace"`
+	PreTap            bool      `json:"preTap"`
+}
+
+type checkBundleNode struct {
+	Name                    string            `json:"name"`
+	Ready                   bool              `json:"ready"`
+	Unschedulable           bool              `json:"unschedulable"`
+	Os                      string            `json:"os"`
+	Arch                    string            `json:"arch"`
+	OsImage                 string            `json:"osImage"`
+	KernelVersion           string            `json:"kernelVersion"`
+	KubeletVersion          string            `json:"kubeletVersion"`
+	ContainerRuntimeVersion string            `json:"containerRuntimeVersion"`
+	Taints                  []core.Taint      `json:"taints,omitempty"`
+	Allocatable             core.ResourceList `json:"allocatable"`
+}
+
+// checkSupportBundle is a tar.gz of the check results and the cluster state behind them, the parts that can't be
+// collected are listed in its errors.txt instead of failing the bundle
+type checkSupportBundle struct {
+	tarWriter *tar.Writer
+	errors    []string
+}
+
+func writeCheckSupportBundle(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider, kubernetesVersion *semver.SemVersion, filePath string) error {
+	file, err := os.Create(filePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	bundle := &checkSupportBundle{tarWriter: tar.NewWriter(gzipWriter)}
+
+	bundle.addJson("check-report.json", report)
+
+	cluster := checkBundleCluster{
+		CreatedAt:     time.Now().UTC(),
+		CliVersion:    mizu.Ver,
+		KubeContext:   config.Config.KubeContext,
+		MizuNamespace: config.Config.MizuResourcesNamespace,
+		PreTap:        config.Config.Check.PreTap,
+	}
+	if kubernetesVersion != nil {
+		cluster.KubernetesVersion = string(*kubernetesVersion)
+	}
+	bundle.addJson("cluster.json", cluster)
+
+	// the kubernetes api isn't reachable, the check report says why
+	if kubernetesProvider != nil {
+		bundle.addNodes(ctx, kubernetesProvider)
+		bundle.addRbacReview(ctx, report, kubernetesProvider)
+		bundle.addMizuPods(ctx, kubernetesProvider)
+		bundle.addEvents(ctx, kubernetesProvider)
+	}
+
+	if len(bundle.errors) > 0 {
+		bundle.addFile("errors.txt", []byte(strings.Join(bundle.errors, "\n")+"\n"))
+	}
+
+	if err := bundle.tarWriter.Close(); err != nil {
+		return err
+	}
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+func (bundle *checkSupportBundle) addNodes(ctx context.Context, kubernetesProvider *kubernetes.Provider) {
+	nodes, err := kubernetesProvider.ListNodes(ctx)
+	if err != nil {
+		bundle.addError("can't list the nodes: %v", err)
+		return
+	}
+
+	bundleNodes := make([]checkBundleNode, 0, len(nodes))
+	for _, node := range nodes {
+		bundleNode := checkBundleNode{
+			Name:                    node.Name,
+			Unschedulable:           node.Spec.Unschedulable,
+			Os:                      node.Labels[core.LabelOSStable],
+			Arch:                    node.Labels[core.LabelArchStable],
+			OsImage:                 node.Status.NodeInfo.OSImage,
+			KernelVersion:           node.Status.NodeInfo.KernelVersion,
+			KubeletVersion:          node.Status.NodeInfo.KubeletVersion,
+			ContainerRuntimeVersion: node.Status.NodeInfo.ContainerRuntimeVersion,
+			Taints:                  node.Spec.Taints,
+			Allocatable:             node.Status.Allocatable,
+		}
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == core.NodeReady {
+				bundleNode.Ready = condition.Status == core.ConditionTrue
+			}
+		}
+		bundleNodes = append(bundleNodes, bundleNode)
+	}
+
+	bundle.addJson("nodes.json", bundleNodes)
+}
+
+// addRbacReview adds the results of the tap permissions check, which runs for the bundle when the checks didn't run it
+func (bundle *checkSupportBundle) addRbacReview(ctx context.Context, report *checkReport, kubernetesProvider *kubernetes.Provider) {
+	rbacReview := make([]*checkResult, 0)
+	for _, result := range report.Results {
+		if result.Check == kubernetesPermissionsCheck {
+			rbacReview = append(rbacReview, result)
+		}
+	}
+
+	if len(rbacReview) == 0 {
+		permissionsReport := &checkReport{Results: make([]*checkResult, 0)}
+		permissionsReport.Passed = checkK8sTapPermissions(ctx, permissionsReport, kubernetesProvider)
+		rbacReview = permissionsReport.Results
+	}
+
+	bundle.addJson("rbac-review.json", rbacReview)
+}
+
+func (bundle *checkSupportBundle) addMizuPods(ctx context.Context, kubernetesProvider *kubernetes.Provider) {
+	podRegex := regexp.MustCompile("^" + kubernetes.MizuResourcesPrefix)
+	pods, err := kubernetesProvider.ListAllPodsMatchingRegex(ctx, podRegex, []string{config.Config.MizuResourcesNamespace})
+	if err != nil {
+		bundle.addError("can't list the mizu pods: %v", err)
+		return
+	}
+
+	for _, pod := range pods {
+		pod.ManagedFields = nil
+		bundle.addJson(fmt.Sprintf("pods/%s.json", pod.Name), pod)
+	}
+}
+
+func (bundle *checkSupportBundle) addEvents(ctx context.Context, kubernetesProvider *kubernetes.Provider) {
+	events, err := kubernetesProvider.ListEvents(ctx, config.Config.MizuResourcesNamespace)
+	if err != nil {
+		bundle.addError("can't list the events of the mizu namespace: %v", err)
+		return
+	}
+
+	imagePullEvents := make([]core.Event, 0)
+	for _, event := range events {
+		if isImagePullEvent(&event) {
+			imagePullEvents = append(imagePullEvents, event)
+		}
+	}
+
+	bundle.addFile("events.log", []byte(kubernetes.FormatEvents(events)))
+	bundle.addFile("image-pull-events.log", []byte(kubernetes.FormatEvents(imagePullEvents)))
+}
+
+func isImagePullEvent(event *core.Event) bool {
+	for _, reason := range imagePullEventReasons {
+		if event.Reason == reason {
+			return true
+		}
+	}
+
+	return (event.Reason == "Failed" || event.Reason == "BackOff") && strings.Contains(strings.ToLower(event.Message), "image")
+}
+
+func (bundle *checkSupportBundle) addJson(name string, value interface{}) {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		bundle.addError("can't serialize %s: %v", name, err)
+		return
+	}
+
+	bundle.addFile(name, data)
+}
+
+func (bundle *checkSupportBundle) addFile(name string, data []byte) {
+	header := &tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := bundle.tarWriter.WriteHeader(header); err != nil {
+		bundle.addError("can't add %s: %v", name, err)
+		return
+	}
+	if _, err := bundle.tarWriter.Write(data); err != nil {
+		bundle.addError("can't add %s: %v", name, err)
+	}
+}
+
+func (bundle *checkSupportBundle) addError(format string, args ...interface{}) {
+	bundle.errors = append(bundle.errors, fmt.Sprintf(format, args...))
+}
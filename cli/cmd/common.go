@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
+	"os/user"
 	"path"
 	"regexp"
 	"time"
 
 	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/ci"
 	"github.com/up9inc/mizu/cli/config/configStructs"
 	"github.com/up9inc/mizu/cli/errormessage"
 	"github.com/up9inc/mizu/cli/mizu"
@@ -23,12 +26,73 @@ import (
 	"github.com/up9inc/mizu/shared/logger"
 )
 
+// getSessionMetadata records who started the session and with what flags, without a kube config, like with docker,
+// the user is the local user and the cluster is only known when it's named in the config
+func getSessionMetadata(kubernetesProvider *kubernetes.Provider, command string, sessionId string, startTime time.Time) shared.SessionMetadata {
+	sessionMetadata := shared.SessionMetadata{
+		Id:            sessionId,
+		CliVersion:    mizu.Ver,
+		CliCommitHash: mizu.GitCommitHash,
+		StartTime:     startTime.UnixNano() / int64(time.Millisecond),
+		Command:       command,
+		Flags:         config.GetCommandFlags(),
+	}
+
+	sessionMetadata.Cluster = config.Config.ClusterName
+	if kubernetesProvider != nil {
+		if sessionMetadata.Cluster == "" {
+			sessionMetadata.Cluster = kubernetesProvider.ClusterFingerprint()
+		}
+
+		kubeUser, err := kubernetesProvider.CurrentUser()
+		if err != nil {
+			logger.Log.Debugf("Failed getting the kube config user, err: %v", err)
+		}
+		sessionMetadata.User = kubeUser
+	}
+
+	if sessionMetadata.User == "" {
+		if localUser, err := user.Current(); err != nil {
+			logger.Log.Debugf("Failed getting the local user, err: %v", err)
+		} else {
+			sessionMetadata.User = localUser.Username
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Log.Debugf("Failed getting the hostname, err: %v", err)
+	}
+	sessionMetadata.Hostname = hostname
+
+	return sessionMetadata
+}
+
+// finishCiReport writes the JUnit XML of the report to junitFile and its GitHub annotations to stdout, the failures
+// are returned as an error so the command exits non-zero
+func finishCiReport(report *ci.Report, junitFile string) error {
+	if err := report.WriteJUnit(junitFile); err != nil {
+		return fmt.Errorf("failed to write the JUnit report, err: %w", err)
+	}
+	logger.Log.Debugf("Wrote the JUnit report to %s", junitFile)
+
+	if err := report.WriteGitHubAnnotations(os.Stdout); err != nil {
+		return err
+	}
+
+	if failures := report.Failures(); failures > 0 {
+		return fmt.Errorf("%d of %d %s assertions failed", failures, len(report.Cases), report.Name)
+	}
+
+	return nil
+}
+
 func GetApiServerUrl(port uint16) string {
 	return fmt.Sprintf("http://%s", kubernetes.GetMizuApiServerProxiedHostAndPath(port))
 }
 
-func startProxyReportErrorIfAny(kubernetesProvider *kubernetes.Provider, ctx context.Context, cancel context.CancelFunc, port uint16) {
-	httpServer, err := kubernetes.StartProxy(kubernetesProvider, config.Config.Tap.ProxyHost, port, config.Config.MizuResourcesNamespace, kubernetes.ApiServerPodName, cancel)
+func startProxyReportErrorIfAny(kubernetesProvider *kubernetes.Provider, ctx context.Context, cancel context.CancelFunc, port uint16, resourceNames kubernetes.ResourceNames) {
+	httpServer, err := kubernetes.StartProxy(kubernetesProvider, config.Config.Tap.ProxyHost, port, config.Config.MizuResourcesNamespace, resourceNames.ApiServerPodName, cancel)
 	if err != nil {
 		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error occured while running k8s proxy %v\n"+
 			"Try setting different port by using --%s", errormessage.FormatError(err), configStructs.GuiPortTapName))
@@ -43,7 +107,7 @@ func startProxyReportErrorIfAny(kubernetesProvider *kubernetes.Provider, ctx con
 			logger.Log.Debugf("Error occurred while stopping proxy %v", errormessage.FormatError(err))
 		}
 
-		podRegex, _ := regexp.Compile(kubernetes.ApiServerPodName)
+		podRegex, _ := regexp.Compile(fmt.Sprintf("^%s$", resourceNames.ApiServerPodName))
 		if _, err := kubernetes.NewPortForward(kubernetesProvider, config.Config.MizuResourcesNamespace, podRegex, port, ctx, cancel); err != nil {
 			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error occured while running port forward %v\n"+
 				"Try setting different port by using --%s", errormessage.FormatError(err), configStructs.GuiPortTapName))
@@ -60,8 +124,38 @@ func startProxyReportErrorIfAny(kubernetesProvider *kubernetes.Provider, ctx con
 	}
 }
 
+// connectToApiServer connects to the API server at apiServerUrl, or when it's empty through a local port, reusing
+// a running tap or view connection if there is one and starting a proxy otherwise
+func connectToApiServer(ctx context.Context, cancel context.CancelFunc, apiServerUrl string, port uint16) (string, *apiserver.Provider, error) {
+	if apiServerUrl == "" {
+		apiServerUrl = GetApiServerUrl(port)
+
+		if err := apiserver.NewProvider(apiServerUrl, 1, apiserver.DefaultTimeout).TestConnection(); err != nil {
+			kubernetesProvider, err := getKubernetesProviderForCli()
+			if err != nil {
+				return "", nil, err
+			}
+
+			logger.Log.Debugf("Establishing connection to k8s cluster...")
+			startProxyReportErrorIfAny(kubernetesProvider, ctx, cancel, port, getSessionResourceNames())
+		}
+	}
+
+	apiServerProvider := apiserver.NewProvider(apiServerUrl, apiserver.DefaultRetries, apiserver.DefaultTimeout)
+	if err := apiServerProvider.TestConnection(); err != nil {
+		return "", nil, fmt.Errorf("couldn't connect to API server, for more info check logs at %s", fsUtils.GetLogFilePath())
+	}
+
+	return apiServerUrl, apiServerProvider, nil
+}
+
 func getKubernetesProviderForCli() (*kubernetes.Provider, error) {
-	kubernetesProvider, err := kubernetes.NewProvider(config.Config.KubeConfigPath(), config.Config.KubeContext)
+	return getKubernetesProviderForContext(config.Config.KubeContext)
+}
+
+// getKubernetesProviderForContext returns a provider of the cluster of kubeContext, the current context when it's empty
+func getKubernetesProviderForContext(kubeContext string) (*kubernetes.Provider, error) {
+	kubernetesProvider, err := kubernetes.NewProvider(config.Config.KubeConfigPath(), kubeContext)
 	if err != nil {
 		handleKubernetesProviderError(err)
 		return nil, err
@@ -95,11 +189,57 @@ func handleKubernetesProviderError(err error) {
 	}
 }
 
-func finishMizuExecution(kubernetesProvider *kubernetes.Provider, isNsRestrictedMode bool, mizuResourcesNamespace string) {
+// finishMizuExecution removes the resources of the session, with keepEntriesClaim the claim of the entries and the
+// session are kept so the next tap with persistent storage shows the entries again
+func finishMizuExecution(kubernetesProvider *kubernetes.Provider, isNsRestrictedMode bool, mizuResourcesNamespace string, resourceNames kubernetes.ResourceNames, keepEntriesClaim bool) {
 	removalCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
 	defer cancel()
 	dumpLogsIfNeeded(removalCtx, kubernetesProvider)
-	resources.CleanUpMizuResources(removalCtx, cancel, kubernetesProvider, isNsRestrictedMode, mizuResourcesNamespace)
+	resources.CleanUpMizuResources(removalCtx, cancel, kubernetesProvider, isNsRestrictedMode, mizuResourcesNamespace, resourceNames, keepEntriesClaim)
+
+	if keepEntriesClaim {
+		logger.Log.Infof("The entries are kept on persistent volume claim %s in namespace %s, run `mizu tap --%s` to show them again or `mizu clean` to remove them", resourceNames.EntriesClaimName, mizuResourcesNamespace, configStructs.PersistentStorageTapName)
+		return
+	}
+
+	if err := fsUtils.RemoveSessionId(config.Config.KubeContext, mizuResourcesNamespace); err != nil {
+		logger.Log.Debugf("Failed removing session state, err: %v", err)
+	}
+}
+
+// getSessionResourceNames returns the names of the resources of the session recorded for the mizu resources
+// namespace, sessions are used only in namespace restricted mode where several users may share a namespace
+func getSessionResourceNames() kubernetes.ResourceNames {
+	if !config.Config.IsNsRestrictedMode() {
+		return kubernetes.GetResourceNames("")
+	}
+
+	sessionId, err := fsUtils.GetSessionId(config.Config.KubeContext, config.Config.MizuResourcesNamespace)
+	if err != nil {
+		logger.Log.Debugf("Failed reading session state, err: %v", err)
+	}
+
+	return kubernetes.GetResourceNames(sessionId)
+}
+
+// startSession records a new session for the mizu resources namespace, unless one is already recorded
+func startSession() (kubernetes.ResourceNames, error) {
+	resourceNames := getSessionResourceNames()
+	if !config.Config.IsNsRestrictedMode() || resourceNames.SessionId != "" {
+		return resourceNames, nil
+	}
+
+	sessionId, err := kubernetes.GenerateSessionId()
+	if err != nil {
+		return resourceNames, err
+	}
+
+	if err := fsUtils.SaveSessionId(config.Config.KubeContext, config.Config.MizuResourcesNamespace, sessionId); err != nil {
+		return resourceNames, err
+	}
+
+	logger.Log.Debugf("Started mizu session %s", sessionId)
+	return kubernetes.GetResourceNames(sessionId), nil
 }
 
 func dumpLogsIfNeeded(ctx context.Context, kubernetesProvider *kubernetes.Provider) {
@@ -121,3 +261,20 @@ func getSerializedMizuAgentConfig(mizuAgentConfig *shared.MizuAgentConfig) (stri
 
 	return string(serializedConfig), nil
 }
+
+// renderEntryTimestamps replaces the start time of a fetched entry with its configured display format
+func renderEntryTimestamps(entryData interface{}, formatter *shared.TimestampFormatter) {
+	entryMap, ok := entryData.(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	startTime, ok := entryMap["startTime"].(string)
+	if !ok {
+		return
+	}
+
+	if parsedTime, err := time.Parse(time.RFC3339Nano, startTime); err == nil {
+		entryMap["startTime"] = formatter.Format(parsedTime)
+	}
+}
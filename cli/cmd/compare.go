@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare",
+	Short: "Compare the traffic of a stable and a canary workload",
+	Long: `Compare the error rates, latency distributions and response schemas of the http entries of a stable and a canary workload over the same time range, to validate a canary rollout with real traffic.
+The workloads are selected with queries, e.g. --stable 'dst.name == "orders"' --canary 'dst.name == "orders-canary"', or with labels copied by the enrichment, e.g. --canary 'dst.metadata["version"] == "v2"'.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("compare", config.Config.Compare)
+		return runMizuCompare()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Compare.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(compareCmd)
+
+	defaultCompareConfig := configStructs.CompareConfig{}
+	if err := defaults.Set(&defaultCompareConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	compareCmd.Flags().String(configStructs.StableCompareName, defaultCompareConfig.Stable, "Query selecting the entries of the stable workload")
+	compareCmd.Flags().String(configStructs.CanaryCompareName, defaultCompareConfig.Canary, "Query selecting the entries of the canary workload")
+	compareCmd.Flags().IntP(configStructs.LimitCompareName, "l", defaultCompareConfig.Limit, "Maximum number of entries compared per workload, the latest are kept")
+	compareCmd.Flags().String(configStructs.FromCompareName, defaultCompareConfig.From, "Compare only entries captured after this time")
+	compareCmd.Flags().String(configStructs.ToCompareName, defaultCompareConfig.To, "Compare only entries captured before this time")
+	compareCmd.Flags().Bool(configStructs.JsonCompareName, defaultCompareConfig.Json, "Print the comparison as json")
+	compareCmd.Flags().Uint16P(configStructs.GuiPortCompareName, "p", defaultCompareConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	compareCmd.Flags().StringP(configStructs.UrlCompareName, "u", defaultCompareConfig.Url, "Provide a custom host")
+
+	if err := compareCmd.Flags().MarkHidden(configStructs.UrlCompareName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/shared"
+)
+
+// the api server fetches and compares the entries of both workloads before responding
+const compareTimeout = 30 * time.Second
+
+func runMizuCompare() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	from, to, err := config.Config.Compare.TimeRange(time.Now())
+	if err != nil {
+		return err
+	}
+
+	apiServerUrl, _, err := connectToApiServer(ctx, cancel, config.Config.Compare.Url, config.Config.Compare.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	apiServerProvider := apiserver.NewProvider(apiServerUrl, apiserver.DefaultRetries, compareTimeout)
+	comparison, err := apiServerProvider.GetComparison(config.Config.Compare.Stable, config.Config.Compare.Canary, config.Config.Compare.Limit, from, to)
+	if err != nil {
+		return err
+	}
+
+	if config.Config.Compare.Json {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(comparison)
+	}
+
+	return printComparison(comparison)
+}
+
+func printComparison(comparison *shared.ComparisonResponse) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ENDPOINT\tSIDE\tENTRIES\tERROR RATE\tP50\tP95\tP99\tMAX")
+	printComparisonSide(writer, "all", "stable", &comparison.Stable)
+	printComparisonSide(writer, "", "canary", &comparison.Canary)
+	for _, endpoint := range comparison.Endpoints {
+		printComparisonSide(writer, endpoint.Endpoint, "stable", &endpoint.Stable)
+		printComparisonSide(writer, "", "canary", &endpoint.Canary)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	schemaDifferences := 0
+	for _, endpoint := range comparison.Endpoints {
+		schemaDifferences += len(endpoint.SchemaDifferences)
+	}
+	if schemaDifferences == 0 {
+		return nil
+	}
+
+	fmt.Println()
+	writer = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ENDPOINT\tRESPONSE FIELD\tSTABLE\tCANARY")
+	for _, endpoint := range comparison.Endpoints {
+		for _, difference := range endpoint.SchemaDifferences {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", endpoint.Endpoint, difference.Field, schemaTypesOrMissing(difference.StableTypes), schemaTypesOrMissing(difference.CanaryTypes))
+		}
+	}
+
+	return writer.Flush()
+}
+
+func printComparisonSide(writer *tabwriter.Writer, endpoint string, side string, stats *shared.ComparisonSide) {
+	if stats.Entries == 0 {
+		fmt.Fprintf(writer, "%s\t%s\t0\t-\t-\t-\t-\t-\n", endpoint, side)
+		return
+	}
+
+	fmt.Fprintf(writer, "%s\t%s\t%d\t%.2f%%\t%dms\t%dms\t%dms\t%dms\n", endpoint, side, stats.Entries, stats.ErrorRate*100,
+		stats.Latency.P50, stats.Latency.P95, stats.Latency.P99, stats.Latency.Max)
+}
+
+func schemaTypesOrMissing(types string) string {
+	if types == "" {
+		return "missing"
+	}
+
+	return types
+}
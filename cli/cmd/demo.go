@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var demoCmd = &cobra.Command{
+	Use:   "demo",
+	Short: "Serve a sample capture in the local UI, without connecting to a cluster",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("demo", config.Config.Demo)
+		runMizuDemo()
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(demoCmd)
+
+	defaultDemoConfig := configStructs.DemoConfig{}
+	if err := defaults.Set(&defaultDemoConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	demoCmd.Flags().Uint16P(configStructs.GuiPortDemoName, "p", defaultDemoConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	demoCmd.Flags().String(configStructs.CapturePathDemoName, defaultDemoConfig.CapturePath, "Serve entries from a capture file instead of the bundled sample")
+	demoCmd.Flags().String(configStructs.UiPathDemoName, defaultDemoConfig.UiPath, "Serve a built UI from this directory instead of the one bundled into the CLI")
+}
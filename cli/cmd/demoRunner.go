@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/demo"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/mizu"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/cli/utils"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+func runMizuDemo() {
+	entries, err := demo.LoadCapture(config.Config.Demo.CapturePath)
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error loading capture: %v", errormessage.FormatError(err)))
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	server := demo.NewServer(entries, mizu.Ver, config.Config.Demo.GuiPort, config.Config.Demo.UiPath)
+	go func() {
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error serving demo: %v", errormessage.FormatError(err)))
+			cancel()
+		}
+	}()
+	defer server.Close()
+
+	url := GetApiServerUrl(config.Config.Demo.GuiPort)
+	logger.Log.Infof("Mizu demo is serving %d sample entries at %s, no cluster is used", len(entries), url)
+
+	if config.Config.Demo.UiPath == "" && !demo.HasBundledUi() {
+		logger.Log.Warningf(uiUtils.Warning, fmt.Sprintf("This build of mizu has no bundled UI, serve a built one with --%s", configStructs.UiPathDemoName))
+	}
+
+	if !config.Config.HeadlessMode {
+		uiUtils.OpenBrowser(url)
+	}
+
+	utils.WaitForFinish(ctx, cancel)
+}
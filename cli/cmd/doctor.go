@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the local environment of the Mizu CLI for potential problems",
+	Long: `Check the local environment of the Mizu CLI for potential problems.
+Unlike check it doesn't reach the cluster, it verifies the kubeconfig, the auth plugins it runs, the proxy environment
+variables, the mizu config directory and the gui port.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("doctor", nil)
+		return runMizuDoctor()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+
+	defaultDoctorConfig := configStructs.DoctorConfig{}
+	if err := defaults.Set(&defaultDoctorConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	doctorCmd.Flags().Bool(configStructs.JsonDoctorName, defaultDoctorConfig.Json, "Print the doctor results as a json report")
+}
@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/mizu"
+	"github.com/up9inc/mizu/shared/logger"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+// the proxy variables are read in both cases, the upper case ones take precedence
+var proxyEnvVars = [][]string{
+	{"HTTPS_PROXY", "https_proxy"},
+	{"HTTP_PROXY", "http_proxy"},
+	{"NO_PROXY", "no_proxy"},
+}
+
+// runMizuDoctor checks the environment the CLI runs in without reaching the cluster, so it also explains
+// failures that happen before check can report anything
+func runMizuDoctor() error {
+	report := &checkReport{Results: make([]*checkResult, 0)}
+
+	checkPassed := true
+	kubeConfig, contextName, kubeConfigPassed := checkKubeConfig(report)
+	checkPassed = checkPassed && kubeConfigPassed
+
+	var server string
+	if kubeConfigPassed {
+		checkPassed = checkAuthPlugins(report, kubeConfig, contextName) && checkPassed
+		server = kubeConfig.Clusters[kubeConfig.Contexts[contextName].Cluster].Server
+	}
+
+	checkPassed = checkProxyEnv(report, server) && checkPassed
+	checkPassed = checkConfigDir(report) && checkPassed
+	checkPassed = checkGuiPorts(report) && checkPassed
+
+	report.Passed = checkPassed
+
+	if config.Config.Doctor.Json {
+		return printCheckReportJson(report)
+	}
+
+	printCheckReport(report, "Mizu doctor", "Doctor")
+	return nil
+}
+
+func checkKubeConfig(report *checkReport) (*clientcmdapi.Config, string, bool) {
+	const check = "kubeconfig"
+	const remediation = "the kubeconfig path can be set with --set kube-config-path=<path> or the KUBECONFIG environment variable"
+
+	kubeConfigPath := config.Config.KubeConfigPath()
+	readable := true
+	for _, path := range filepath.SplitList(kubeConfigPath) {
+		if _, err := os.ReadFile(path); err != nil {
+			report.addFailed(check, fmt.Sprintf("can't read %s", path), err, remediation)
+			readable = false
+			continue
+		}
+		report.addPassed(check, fmt.Sprintf("can read %s", path))
+	}
+	if !readable {
+		return nil, "", false
+	}
+
+	kubeConfig, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+		&clientcmd.ClientConfigLoadingRules{Precedence: filepath.SplitList(kubeConfigPath)},
+		&clientcmd.ConfigOverrides{},
+	).RawConfig()
+	if err != nil {
+		report.addFailed(check, "can't parse the kubeconfig", err, "make sure the kubeconfig is valid, e.g. with kubectl config view")
+		return nil, "", false
+	}
+
+	contextName := config.Config.KubeContext
+	if contextName == "" {
+		contextName = kubeConfig.CurrentContext
+	}
+	if contextName == "" {
+		report.addFailed(check, "no context is selected", nil, "select a context with kubectl config use-context or --set kube-context=<context>")
+		return nil, "", false
+	}
+
+	context, ok := kubeConfig.Contexts[contextName]
+	if !ok {
+		report.addFailed(check, fmt.Sprintf("context %s doesn't exist", contextName), nil, "list the available contexts with kubectl config get-contexts")
+		return nil, "", false
+	}
+	if _, ok := kubeConfig.Clusters[context.Cluster]; !ok {
+		report.addFailed(check, fmt.Sprintf("cluster %s of context %s doesn't exist", context.Cluster, contextName), nil, "fix the context with kubectl config set-context")
+		return nil, "", false
+	}
+	report.addPassed(check, fmt.Sprintf("context %s refers to cluster %s", contextName, context.Cluster))
+
+	return &kubeConfig, contextName, true
+}
+
+func checkAuthPlugins(report *checkReport, kubeConfig *clientcmdapi.Config, contextName string) bool {
+	const check = "auth-plugins"
+
+	authInfoName := kubeConfig.Contexts[contextName].AuthInfo
+	authInfo, ok := kubeConfig.AuthInfos[authInfoName]
+	if !ok {
+		report.addFailed(check, fmt.Sprintf("user %s of context %s doesn't exist", authInfoName, contextName), nil, "fix the context with kubectl config set-context")
+		return false
+	}
+
+	if authInfo.Exec == nil {
+		if authInfo.AuthProvider != nil {
+			report.addPassed(check, fmt.Sprintf("user %s uses the built-in %s auth provider", authInfoName, authInfo.AuthProvider.Name))
+		} else {
+			report.addPassed(check, fmt.Sprintf("user %s doesn't run an auth plugin", authInfoName))
+		}
+		return true
+	}
+
+	path, err := exec.LookPath(authInfo.Exec.Command)
+	if err != nil {
+		remediation := fmt.Sprintf("install %s and make sure it's in the PATH of the shell running mizu", authInfo.Exec.Command)
+		if authInfo.Exec.InstallHint != "" {
+			remediation = strings.TrimSpace(authInfo.Exec.InstallHint)
+		}
+		report.addFailed(check, fmt.Sprintf("auth plugin %s of user %s isn't found", authInfo.Exec.Command, authInfoName), err, remediation)
+		return false
+	}
+
+	report.addPassed(check, fmt.Sprintf("auth plugin %s of user %s is found at %s", authInfo.Exec.Command, authInfoName, path))
+	return true
+}
+
+// checkProxyEnv validates the proxy variables the same way the Kubernetes client reads them
+func checkProxyEnv(report *checkReport, server string) bool {
+	const check = "proxy-env"
+
+	checkPassed := true
+	proxySet := false
+	for _, names := range proxyEnvVars {
+		upper, lower := os.Getenv(names[0]), os.Getenv(names[1])
+		if upper != "" || lower != "" {
+			proxySet = true
+		}
+		if upper != "" && lower != "" && upper != lower {
+			report.addFailed(check, fmt.Sprintf("%s and %s are set to different values", names[0], names[1]), nil, fmt.Sprintf("%s is used, unset one of them to avoid surprises", names[0]))
+			checkPassed = false
+		}
+	}
+
+	if !proxySet {
+		report.addPassed(check, "no proxy is configured")
+		return checkPassed
+	}
+
+	if server == "" {
+		return checkPassed
+	}
+
+	request, err := http.NewRequest(http.MethodGet, server, nil)
+	if err != nil {
+		report.addFailed(check, fmt.Sprintf("can't parse the cluster server url %s", server), err, "fix the server of the cluster with kubectl config set-cluster")
+		return false
+	}
+
+	proxyUrl, err := http.ProxyFromEnvironment(request)
+	if err != nil {
+		report.addFailed(check, "the proxy url is invalid", err, "set the proxy variables to urls such as http://proxy.example.com:3128")
+		return false
+	}
+
+	if proxyUrl == nil {
+		report.addPassed(check, fmt.Sprintf("the cluster server %s is reached without a proxy", request.URL.Host))
+	} else {
+		report.addPassed(check, fmt.Sprintf("the cluster server %s is reached through the proxy %s", request.URL.Host, proxyUrl.Host))
+	}
+	return checkPassed
+}
+
+func checkConfigDir(report *checkReport) bool {
+	const check = "config-dir"
+
+	mizuFolderPath := mizu.GetMizuFolderPath()
+	if mizuFolderPath == "" {
+		report.addFailed(check, "can't find the home directory", nil, "set the HOME environment variable")
+		return false
+	}
+
+	file, err := os.CreateTemp(mizuFolderPath, ".doctor-")
+	if err != nil {
+		report.addFailed(check, fmt.Sprintf("can't write to %s", mizuFolderPath), err, fmt.Sprintf("make sure %s is a directory owned by the user running mizu", mizuFolderPath))
+		return false
+	}
+	_ = file.Close()
+	if err := os.Remove(file.Name()); err != nil {
+		logger.Log.Debugf("error while removing %s, err: %v", file.Name(), err)
+	}
+	report.addPassed(check, fmt.Sprintf("can write to %s", mizuFolderPath))
+
+	if _, err := os.Stat(config.Config.ConfigFilePath); err == nil {
+		report.addPassed(check, fmt.Sprintf("config file %s is loaded", config.Config.ConfigFilePath))
+	} else if os.IsNotExist(err) {
+		report.addPassed(check, fmt.Sprintf("config file %s doesn't exist, the defaults are used", config.Config.ConfigFilePath))
+	}
+
+	return true
+}
+
+func checkGuiPorts(report *checkReport) bool {
+	const check = "ports"
+
+	ports := []uint16{config.Config.Tap.GuiPort}
+	if config.Config.View.GuiPort != config.Config.Tap.GuiPort {
+		ports = append(ports, config.Config.View.GuiPort)
+	}
+
+	checkPassed := true
+	for _, port := range ports {
+		address := net.JoinHostPort(config.Config.Tap.ProxyHost, fmt.Sprintf("%d", port))
+		listener, err := net.Listen("tcp", address)
+		if err != nil {
+			report.addFailed(check, fmt.Sprintf("%s is in use", address), err, "it's expected while mizu tap or view is running, otherwise stop the process using it or pick another port with --gui-port")
+			checkPassed = false
+			continue
+		}
+		_ = listener.Close()
+		report.addPassed(check, fmt.Sprintf("%s is available", address))
+	}
+
+	return checkPassed
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export captured traffic as a HAR archive",
+	Long: `Export the captured http entries matching a query as a HAR archive, which can be imported into browser devtools and other HAR tools.
+Use --from and --to with an RFC 3339 time or a duration back from now, e.g. --from 1h --to 30m, to export a time range.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("export", config.Config.Export)
+		return runMizuExport()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Export.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(exportCmd)
+
+	defaultExportConfig := configStructs.ExportConfig{}
+	if err := defaults.Set(&defaultExportConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	exportCmd.Flags().String(configStructs.FormatExportName, defaultExportConfig.Format, "Format of the exported archive, only har is supported")
+	exportCmd.Flags().StringP(configStructs.OutExportName, "o", defaultExportConfig.Out, "Write the archive to this file instead of stdout")
+	exportCmd.Flags().StringP(configStructs.QueryExportName, "q", defaultExportConfig.Query, "Export only entries matching this query")
+	exportCmd.Flags().IntP(configStructs.LimitExportName, "l", defaultExportConfig.Limit, "Maximum number of entries to export, the latest are kept")
+	exportCmd.Flags().String(configStructs.FromExportName, defaultExportConfig.From, "Export only entries captured after this time")
+	exportCmd.Flags().String(configStructs.ToExportName, defaultExportConfig.To, "Export only entries captured before this time")
+	exportCmd.Flags().Uint16P(configStructs.GuiPortExportName, "p", defaultExportConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	exportCmd.Flags().StringP(configStructs.UrlExportName, "u", defaultExportConfig.Url, "Provide a custom host")
+
+	if err := exportCmd.Flags().MarkHidden(configStructs.UrlExportName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+// the api server collects the whole archive before responding
+const exportTimeout = 30 * time.Second
+
+func runMizuExport() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	from, to, err := config.Config.Export.TimeRange(time.Now())
+	if err != nil {
+		return err
+	}
+
+	apiServerUrl, _, err := connectToApiServer(ctx, cancel, config.Config.Export.Url, config.Config.Export.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	apiServerProvider := apiserver.NewProvider(apiServerUrl, apiserver.DefaultRetries, exportTimeout)
+	harData, err := apiServerProvider.GetHar(config.Config.Export.Query, config.Config.Export.Limit, from, to)
+	if err != nil {
+		return err
+	}
+
+	var harLog struct {
+		Log struct {
+			Entries []json.RawMessage `json:"entries"`
+		} `json:"log"`
+	}
+	if err := json.Unmarshal(harData, &harLog); err != nil {
+		return fmt.Errorf("failed to parse the exported archive, err: %w", err)
+	}
+
+	if config.Config.Export.Out == "" {
+		_, err := os.Stdout.Write(harData)
+		return err
+	}
+
+	if err := ioutil.WriteFile(config.Config.Export.Out, harData, 0644); err != nil {
+		return err
+	}
+
+	logger.Log.Infof("Exported %d entries to %s", len(harLog.Log.Entries), config.Config.Export.Out)
+
+	return nil
+}
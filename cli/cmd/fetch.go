@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var fetchCmd = &cobra.Command{
+	Use:   "fetch",
+	Short: "Download captured entries",
+	Long: `Download captured entries matching a query as JSON lines.
+Use --transform with a jq-like expression to keep only the fields you need, e.g. '{id: .entryId, path: .request.path, status: .response.status}'.
+Use --follow to keep writing new entries to --output until interrupted, the output is rotated by --max-file-size and a restarted follow resumes where the previous one stopped.
+Use --since-cursor with a file to fetch only the entries added since the previous fetch with the same file, the file is created by the first fetch, e.g. for a periodic sync. The entries are appended to --output.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("fetch", config.Config.Fetch)
+		return runMizuFetch()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Fetch.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(fetchCmd)
+
+	defaultFetchConfig := configStructs.FetchConfig{}
+	if err := defaults.Set(&defaultFetchConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	fetchCmd.Flags().StringP(configStructs.QueryFetchName, "q", defaultFetchConfig.Query, "Fetch only entries matching this query")
+	fetchCmd.Flags().IntP(configStructs.LimitFetchName, "l", defaultFetchConfig.Limit, "Maximum number of entries to fetch")
+	fetchCmd.Flags().StringP(configStructs.OutputFetchName, "o", defaultFetchConfig.Output, "Write the entries to this file instead of stdout")
+	fetchCmd.Flags().StringP(configStructs.TransformFetchName, "t", defaultFetchConfig.Transform, "Transform each entry with a jq-like expression before writing it")
+	fetchCmd.Flags().BoolP(configStructs.FollowFetchName, "f", defaultFetchConfig.Follow, "Keep writing new entries to the output file until interrupted")
+	fetchCmd.Flags().String(configStructs.SinceCursorFetchName, defaultFetchConfig.SinceCursor, "Fetch only the entries added since the previous fetch with this cursor file, and update it")
+	fetchCmd.Flags().String(configStructs.HumanMaxFileSizeFetchName, defaultFetchConfig.HumanMaxFileSize, "Rotate the output file when it reaches this size, with --follow")
+	fetchCmd.Flags().Int(configStructs.MaxFilesFetchName, defaultFetchConfig.MaxFiles, "Number of rotated output files to keep, with --follow")
+	fetchCmd.Flags().Uint16P(configStructs.GuiPortFetchName, "p", defaultFetchConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	fetchCmd.Flags().StringP(configStructs.UrlFetchName, "u", defaultFetchConfig.Url, "Provide a custom host")
+
+	if err := fetchCmd.Flags().MarkHidden(configStructs.UrlFetchName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,366 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/utils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/transform"
+)
+
+const (
+	followPollInterval     = time.Second
+	followMaxRetryInterval = 30 * time.Second
+)
+
+func runMizuFetch() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var entryTransform *transform.Expression
+	if config.Config.Fetch.Transform != "" {
+		var err error
+		if entryTransform, err = transform.Compile(config.Config.Fetch.Transform); err != nil {
+			return err
+		}
+	}
+
+	timestampFormatter, err := shared.NewTimestampFormatter(config.Config.Timestamps)
+	if err != nil {
+		return err
+	}
+
+	_, apiServerProvider, err := connectToApiServer(ctx, cancel, config.Config.Fetch.Url, config.Config.Fetch.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	if config.Config.Fetch.Follow {
+		return followEntries(ctx, cancel, apiServerProvider, entryTransform, timestampFormatter)
+	}
+
+	if config.Config.Fetch.SinceCursor != "" {
+		return fetchEntriesSinceCursor(apiServerProvider, entryTransform, timestampFormatter)
+	}
+
+	baseEntries, err := apiServerProvider.GetEntries(config.Config.Fetch.Query, config.Config.Fetch.Limit)
+	if err != nil {
+		return err
+	}
+
+	out, closeOut, err := openFetchOutput(false)
+	if err != nil {
+		return err
+	}
+	defer closeOut()
+
+	writer := bufio.NewWriter(out)
+	defer writer.Flush()
+
+	written := 0
+	for _, baseEntry := range baseEntries {
+		entry, err := apiServerProvider.GetEntry(getEntryIdForFetch(baseEntry))
+		if err != nil {
+			logger.Log.Debugf("Failed fetching entry, skipping it: %v", err)
+			continue
+		}
+
+		line, err := renderEntryLine(entry, entryTransform, timestampFormatter)
+		if err != nil {
+			return err
+		}
+
+		if _, err := writer.Write(line); err != nil {
+			return err
+		}
+		written++
+	}
+
+	if config.Config.Fetch.Output != "" {
+		logger.Log.Infof("Wrote %d entries to %s", written, config.Config.Fetch.Output)
+	}
+
+	return nil
+}
+
+// fetchEntriesSinceCursor writes the entries matching the query that were added after the entry of the cursor file,
+// oldest first, and moves the cursor to the last written entry. Without a cursor file it fetches from the first entry
+func fetchEntriesSinceCursor(apiServerProvider *apiserver.Provider, entryTransform *transform.Expression, timestampFormatter *shared.TimestampFormatter) error {
+	cursorPath := config.Config.Fetch.SinceCursor
+	cursor, resumed := readFetchCursor(cursorPath)
+	if resumed {
+		logger.Log.Debugf("Fetching the entries after entry %d", cursor)
+	}
+
+	baseEntries, metadata, err := apiServerProvider.GetEntriesAfter(config.Config.Fetch.Query, cursor+1, config.Config.Fetch.Limit)
+	if err != nil {
+		return err
+	}
+
+	if resumed && len(baseEntries) == 0 && metadata.Total <= cursor {
+		// the api server restarted with an empty database
+		logger.Log.Infof("The entries database was reset, fetching it from its start")
+		cursor = -1
+		if baseEntries, _, err = apiServerProvider.GetEntriesAfter(config.Config.Fetch.Query, 0, config.Config.Fetch.Limit); err != nil {
+			return err
+		}
+	}
+
+	out, closeOut, err := openFetchOutput(true)
+	if err != nil {
+		return err
+	}
+	defer closeOut()
+
+	writer := bufio.NewWriter(out)
+
+	written := 0
+	lastIndex := cursor
+	for _, baseEntry := range baseEntries {
+		index, ok := getEntryIndex(baseEntry)
+		if !ok || index <= cursor {
+			continue
+		}
+
+		// an entry that fails isn't skipped, the next fetch retries it
+		var entry map[string]interface{}
+		if entry, err = apiServerProvider.GetEntry(getEntryIdForFetch(baseEntry)); err != nil {
+			break
+		}
+
+		line, renderErr := renderEntryLine(entry, entryTransform, timestampFormatter)
+		if renderErr != nil {
+			err = renderErr
+			break
+		}
+
+		if _, err = writer.Write(line); err != nil {
+			break
+		}
+		written++
+		lastIndex = index
+	}
+
+	// the cursor is moved only past the entries that reached the output
+	if flushErr := writer.Flush(); flushErr != nil {
+		return flushErr
+	}
+	if lastIndex > cursor {
+		if cursorErr := writeFetchCursor(cursorPath, lastIndex); cursorErr != nil {
+			return fmt.Errorf("failed saving the fetch cursor %s, err: %w", cursorPath, cursorErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if written == config.Config.Fetch.Limit {
+		logger.Log.Infof("Fetched the --%s of %d entries, fetch again for the rest", configStructs.LimitFetchName, written)
+	}
+	if config.Config.Fetch.Output != "" {
+		logger.Log.Infof("Wrote %d entries to %s", written, config.Config.Fetch.Output)
+	}
+
+	return nil
+}
+
+// openFetchOutput opens the output file, or stdout without one
+func openFetchOutput(appendOutput bool) (io.Writer, func(), error) {
+	if config.Config.Fetch.Output == "" {
+		return os.Stdout, func() {}, nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if appendOutput {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_APPEND
+	}
+
+	file, err := os.OpenFile(config.Config.Fetch.Output, flags, 0666)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return file, func() { file.Close() }, nil
+}
+
+// followEntries writes the entries matching the query to a rotating output file until interrupted, the index of
+// the last written entry is kept in a cursor file next to the output so connection errors and restarts resume
+// from it instead of skipping or repeating entries
+func followEntries(ctx context.Context, cancel context.CancelFunc, apiServerProvider *apiserver.Provider, entryTransform *transform.Expression, timestampFormatter *shared.TimestampFormatter) error {
+	out, err := utils.NewRotatingFile(config.Config.Fetch.Output, config.Config.Fetch.MaxFileSizeBytes(), config.Config.Fetch.MaxFiles)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	cursorPath := fmt.Sprintf("%s.cursor", config.Config.Fetch.Output)
+	cursor, resumed := readFetchCursor(cursorPath)
+	if resumed {
+		logger.Log.Infof("Resuming after entry %d", cursor)
+	}
+
+	go utils.WaitForFinish(ctx, cancel)
+
+	logger.Log.Infof("Writing entries to %s, press Ctrl+C to stop", config.Config.Fetch.Output)
+
+	written := 0
+	fetchedLatest := resumed
+	retryInterval := followPollInterval
+	for ctx.Err() == nil {
+		var baseEntries []map[string]interface{}
+		var metadata *apiserver.EntriesMetadata
+		if !fetchedLatest {
+			// like tail -f, start with the latest entries, they come newest first and are written oldest first
+			baseEntries, err = apiServerProvider.GetEntries(config.Config.Fetch.Query, config.Config.Fetch.Limit)
+			for i, j := 0, len(baseEntries)-1; i < j; i, j = i+1, j-1 {
+				baseEntries[i], baseEntries[j] = baseEntries[j], baseEntries[i]
+			}
+		} else {
+			baseEntries, metadata, err = apiServerProvider.GetEntriesAfter(config.Config.Fetch.Query, cursor+1, config.Config.Fetch.Limit)
+		}
+
+		if err == nil && metadata != nil && len(baseEntries) == 0 && metadata.Total <= cursor {
+			// the api server restarted with an empty database
+			logger.Log.Infof("The entries database was reset, following it from its start")
+			cursor = -1
+			continue
+		}
+
+		for _, baseEntry := range baseEntries {
+			if err != nil {
+				break
+			}
+
+			index, ok := getEntryIndex(baseEntry)
+			if !ok || index <= cursor {
+				continue
+			}
+
+			var entry map[string]interface{}
+			if entry, err = apiServerProvider.GetEntry(getEntryIdForFetch(baseEntry)); err != nil {
+				break
+			}
+
+			line, renderErr := renderEntryLine(entry, entryTransform, timestampFormatter)
+			if renderErr != nil {
+				return renderErr
+			}
+
+			if _, writeErr := out.Write(line); writeErr != nil {
+				return writeErr
+			}
+			written++
+			cursor = index
+		}
+
+		if cursor >= 0 {
+			if cursorErr := writeFetchCursor(cursorPath, cursor); cursorErr != nil {
+				logger.Log.Debugf("Failed saving the follow cursor: %v", cursorErr)
+			}
+		}
+
+		if err != nil {
+			logger.Log.Warningf("Failed fetching entries, retrying in %v: %v", retryInterval, err)
+			sleepUnlessDone(ctx, retryInterval)
+			if retryInterval *= 2; retryInterval > followMaxRetryInterval {
+				retryInterval = followMaxRetryInterval
+			}
+			continue
+		}
+		retryInterval = followPollInterval
+		fetchedLatest = true
+
+		if len(baseEntries) < config.Config.Fetch.Limit {
+			sleepUnlessDone(ctx, followPollInterval)
+		}
+	}
+
+	logger.Log.Infof("Wrote %d entries to %s", written, config.Config.Fetch.Output)
+
+	return nil
+}
+
+func renderEntryLine(entry map[string]interface{}, entryTransform *transform.Expression, timestampFormatter *shared.TimestampFormatter) ([]byte, error) {
+	renderEntryTimestamps(entry["data"], timestampFormatter)
+
+	var result interface{} = entry["data"]
+	if entryTransform != nil {
+		var err error
+		if result, err = entryTransform.Apply(result); err != nil {
+			return nil, fmt.Errorf("failed transforming entry with %s, err: %w", entryTransform, err)
+		}
+	}
+
+	line, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(line, '\n'), nil
+}
+
+func readFetchCursor(cursorPath string) (int, bool) {
+	content, err := ioutil.ReadFile(cursorPath)
+	if err != nil {
+		return -1, false
+	}
+
+	cursor, err := strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || cursor < 0 {
+		logger.Log.Debugf("Ignoring invalid cursor %s", cursorPath)
+		return -1, false
+	}
+
+	return cursor, true
+}
+
+func writeFetchCursor(cursorPath string, cursor int) error {
+	// write and rename, an interrupted write mustn't leave a truncated cursor behind
+	tempPath := fmt.Sprintf("%s.tmp", cursorPath)
+	if err := ioutil.WriteFile(tempPath, []byte(strconv.Itoa(cursor)), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, cursorPath)
+}
+
+func sleepUnlessDone(ctx context.Context, duration time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+}
+
+// getEntryIndex returns the database index of the entry, the fetch cursors are kept in it
+func getEntryIndex(baseEntry map[string]interface{}) (int, bool) {
+	id, ok := baseEntry["id"].(float64)
+	return int(id), ok
+}
+
+// getEntryIdForFetch prefers the stable entry id, falling back to the database index for entries captured
+// with the index scheme
+func getEntryIdForFetch(baseEntry map[string]interface{}) string {
+	if entryId, ok := baseEntry["entryId"].(string); ok && entryId != "" {
+		return entryId
+	}
+
+	// json numbers are decoded as floats, %v would print large indices in exponent notation
+	if id, ok := baseEntry["id"].(float64); ok {
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	}
+
+	return fmt.Sprintf("%v", baseEntry["id"])
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph",
+	Short: "Print the dependency map of the tapped services",
+	Long: `Print the services that called each other in the captured entries, with the calls, the error rates and the protocols of every source and destination pair.
+The map is printed as a Graphviz DOT graph by default, e.g. mizu graph | dot -Tsvg > services.svg, or as json with -o json.
+The map of a running tap is streamed live by the websocket of the /servicemap/ws API server endpoint.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("graph", config.Config.Graph)
+		return runMizuGraph()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Graph.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+
+	defaultGraphConfig := configStructs.GraphConfig{}
+	if err := defaults.Set(&defaultGraphConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	graphCmd.Flags().StringP(configStructs.OutputGraphName, "o", defaultGraphConfig.Output, "The format of the map, dot or json")
+	graphCmd.Flags().StringP(configStructs.FileGraphName, "f", defaultGraphConfig.File, "Write the map to this file instead of stdout")
+	graphCmd.Flags().Uint16P(configStructs.GuiPortGraphName, "p", defaultGraphConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	graphCmd.Flags().StringP(configStructs.UrlGraphName, "u", defaultGraphConfig.Url, "Provide a custom host")
+
+	if err := graphCmd.Flags().MarkHidden(configStructs.UrlGraphName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/shared"
+)
+
+// the edges that failed at least this often are drawn in red
+const graphErrorRateThreshold = 0.05
+
+func runMizuGraph() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, apiServerProvider, err := connectToApiServer(ctx, cancel, config.Config.Graph.Url, config.Config.Graph.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	serviceMap, err := apiServerProvider.GetServiceMap()
+	if err != nil {
+		return err
+	}
+
+	if serviceMap.Status.Status != "enabled" {
+		return errors.New("the service map is disabled, tap with --set service-map=true to build it")
+	}
+
+	var out io.Writer = os.Stdout
+	if config.Config.Graph.File != "" {
+		file, err := os.Create(config.Config.Graph.File)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+
+	writer := bufio.NewWriter(out)
+	defer writer.Flush()
+
+	if config.Config.Graph.Output == configStructs.GraphOutputJson {
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(serviceMap)
+	}
+
+	return writeServiceMapDot(writer, serviceMap)
+}
+
+// writeServiceMapDot writes the map as a Graphviz digraph, sorted so the same map is always written the same
+func writeServiceMapDot(writer io.Writer, serviceMap *shared.ServiceMapResponse) error {
+	nodeNames := make([]string, 0, len(serviceMap.Nodes))
+	for _, node := range serviceMap.Nodes {
+		nodeNames = append(nodeNames, node.Name)
+	}
+	sort.Strings(nodeNames)
+
+	edges := append([]shared.ServiceMapEdge{}, serviceMap.Edges...)
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].Source.Name != edges[j].Source.Name {
+			return edges[i].Source.Name < edges[j].Source.Name
+		}
+		if edges[i].Destination.Name != edges[j].Destination.Name {
+			return edges[i].Destination.Name < edges[j].Destination.Name
+		}
+		return getServiceMapEdgeProtocol(edges[i]) < getServiceMapEdgeProtocol(edges[j])
+	})
+
+	lines := []string{
+		"digraph mizu {",
+		"  rankdir=LR;",
+		"  node [shape=box, style=rounded];",
+	}
+	for _, nodeName := range nodeNames {
+		lines = append(lines, fmt.Sprintf("  %s;", quoteDot(nodeName)))
+	}
+	for _, edge := range edges {
+		attributes := []string{fmt.Sprintf("label=%s", quoteDot(fmt.Sprintf("%s %d calls, %.1f%% errors", getServiceMapEdgeProtocol(edge), edge.Count, edge.ErrorRate*100)))}
+		if edge.ErrorRate >= graphErrorRateThreshold {
+			attributes = append(attributes, "color=red", "fontcolor=red")
+		}
+		lines = append(lines, fmt.Sprintf("  %s -> %s [%s];", quoteDot(edge.Source.Name), quoteDot(edge.Destination.Name), strings.Join(attributes, ", ")))
+	}
+	lines = append(lines, "}")
+
+	_, err := fmt.Fprintln(writer, strings.Join(lines, "\n"))
+	return err
+}
+
+func getServiceMapEdgeProtocol(edge shared.ServiceMapEdge) string {
+	if edge.Protocol == nil {
+		return ""
+	}
+
+	return edge.Protocol.Abbreviation
+}
+
+func quoteDot(value string) string {
+	return fmt.Sprintf("\"%s\"", strings.NewReplacer("\\", "\\\\", "\"", "\\\"").Replace(value))
+}
@@ -16,6 +16,10 @@ import (
 var logsCmd = &cobra.Command{
 	Use:   "logs",
 	Short: "Create a zip file with logs for Github issue or troubleshoot",
+	Long: `Create a zip file with logs for Github issue or troubleshoot.
+The zip file holds the logs of the api server and tapper pods, including the logs of the previous run of restarted
+containers, the kubernetes events of the mizu namespace, the agent config and the effective cli config with their
+secrets redacted, the cli log file and a manifest.json that lists the pods, the files and what couldn't be collected.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		go telemetry.ReportRun("logs", config.Config.Logs)
 
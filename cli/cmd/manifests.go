@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var manifestsCmd = &cobra.Command{
+	Use:   "manifests [POD REGEX | KIND/NAME...]",
+	Short: "Render the kubernetes resources of mizu as YAML or as a Helm chart",
+	Long: `Render the kubernetes resources mizu tap creates, to deploy mizu declaratively.
+The pods are selected by the regex, or by the workloads like deployment/cart, and by the tap config, like the namespaces of tap.namespaces. The tapper daemon set runs on the nodes of the pods that match when rendering, render the resources again to tap pods that were scheduled on other nodes.
+The YAML is printed to stdout, or written to a file per resource with --dir. The Helm chart is written to --dir.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("manifests", config.Config.Manifests)
+		return runMizuManifests()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := readTapArgs(args); err != nil {
+			return err
+		}
+
+		if err := config.Config.Tap.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		if err := config.Config.Manifests.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		var err error
+		if state.targets, err = readTapTargets(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(manifestsCmd)
+
+	defaultManifestsConfig := configStructs.ManifestsConfig{}
+	if err := defaults.Set(&defaultManifestsConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	manifestsCmd.Flags().StringP(configStructs.OutputManifestsName, "o", defaultManifestsConfig.Output, "The format of the resources, yaml or helm")
+	manifestsCmd.Flags().String(configStructs.DirManifestsName, defaultManifestsConfig.Dir, "The directory to write the resources to instead of stdout")
+}
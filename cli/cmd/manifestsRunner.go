@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/mizu"
+	"github.com/up9inc/mizu/cli/resources"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/logger"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	applyconfapp "k8s.io/client-go/applyconfigurations/apps/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const manifestsHelmChartName = "mizu"
+
+type manifest struct {
+	kind   string
+	name   string
+	object interface{}
+}
+
+// fileName keeps the files in the order the resources depend on each other, kubectl apply -f applies them by name
+func (m *manifest) fileName(index int) string {
+	return fmt.Sprintf("%02d-%s-%s.yaml", index, strings.ToLower(m.kind), m.name)
+}
+
+func runMizuManifests() error {
+	serializedValidationRules, serializedContract, ok := readTapPolicyFiles()
+	if !ok {
+		return errors.New("failed reading the policy files")
+	}
+
+	kubernetesProvider, err := getKubernetesProviderForCli()
+	if err != nil {
+		return err
+	}
+
+	manifests, err := getMizuManifests(context.Background(), kubernetesProvider, serializedValidationRules, serializedContract)
+	if err != nil {
+		return err
+	}
+
+	if config.Config.Manifests.Output == configStructs.ManifestsOutputHelm {
+		return writeHelmChart(manifests, config.Config.Manifests.Dir)
+	}
+
+	if config.Config.Manifests.Dir != "" {
+		return writeManifests(manifests, config.Config.Manifests.Dir)
+	}
+
+	for _, m := range manifests {
+		data, err := yaml.Marshal(m.object)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("---\n%s", data)
+	}
+
+	return nil
+}
+
+// getMizuManifests returns the resources mizu tap creates, in the order they're created
+func getMizuManifests(ctx context.Context, kubernetesProvider *kubernetes.Provider, serializedValidationRules string, serializedContract string) ([]manifest, error) {
+	namespace := config.Config.MizuResourcesNamespace
+	resourceNames := kubernetes.GetResourceNames("")
+	manifests := make([]manifest, 0)
+
+	if !config.Config.IsNsRestrictedMode() {
+		manifests = append(manifests, manifest{kind: "Namespace", name: namespace, object: kubernetesProvider.GetNamespaceObject(namespace)})
+	}
+
+	mizuAgentConfig := getTapMizuAgentConfig()
+	mizuAgentConfig.Session = getSessionMetadata(kubernetesProvider, "manifests", "", time.Now())
+	mizuAgentConfig.Cluster = mizuAgentConfig.Session.Cluster
+	serializedMizuConfig, err := getSerializedMizuAgentConfig(mizuAgentConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	configMap := kubernetesProvider.GetConfigMapObject(resourceNames.ConfigMapName, serializedValidationRules, serializedContract, serializedMizuConfig)
+	configMap.Namespace = namespace
+	manifests = append(manifests, manifest{kind: "ConfigMap", name: configMap.Name, object: configMap})
+
+	for _, rbacObject := range resources.GetMizuRBACObjects(kubernetesProvider, config.Config.IsNsRestrictedMode(), namespace, config.Config.CloudIdentity) {
+		kind := rbacObject.(runtime.Object).GetObjectKind().GroupVersionKind().Kind
+		manifests = append(manifests, manifest{kind: kind, name: rbacObject.(metav1.Object).GetName(), object: rbacObject})
+	}
+
+	// the provenance key isn't copied, it would be committed with the resources
+	var provenanceSecretName string
+	if config.Config.Provenance.SecretName != "" {
+		provenanceSecretName = resourceNames.ProvenanceSecretName
+		logger.Log.Warningf(uiUtils.Warning, fmt.Sprintf("The provenance key isn't rendered, copy the %s key of secret %s to a secret %s in namespace %s", shared.ProvenanceKeyFileName, config.Config.Provenance.SecretName, provenanceSecretName, namespace))
+	}
+
+	var entriesClaimName string
+	if config.Config.Tap.PersistentStorage {
+		entriesClaimName = resourceNames.EntriesClaimName
+		claimSizeBytes := config.Config.Tap.MaxEntriesDBSizeBytes() + mizu.InstallModePersistentVolumeSizeBufferBytes
+		claim := kubernetesProvider.GetPersistentVolumeClaimObject(entriesClaimName, claimSizeBytes, config.Config.Tap.StorageClass)
+		claim.Namespace = namespace
+		manifests = append(manifests, manifest{kind: "PersistentVolumeClaim", name: claim.Name, object: claim})
+	}
+
+	apiServerPod, err := kubernetesProvider.GetMizuApiServerPodObject(&kubernetes.ApiServerOptions{
+		Namespace:             namespace,
+		PodName:               resourceNames.ApiServerPodName,
+		ConfigMapName:         resourceNames.ConfigMapName,
+		PodImage:              config.Config.AgentImage,
+		ServiceAccountName:    kubernetes.ServiceAccountName,
+		IsNamespaceRestricted: config.Config.IsNsRestrictedMode(),
+		SyncEntriesConfig:     getSyncEntriesConfig(),
+		MaxEntriesDBSizeBytes: config.Config.Tap.MaxEntriesDBSizeBytes(),
+		Resources:             config.Config.Tap.ApiServerResources,
+		ImagePullPolicy:       config.Config.ImagePullPolicy(),
+		LogLevel:              config.Config.LogLevel(),
+		ProvenanceSecretName:  provenanceSecretName,
+		CloudIdentity:         config.Config.CloudIdentity,
+	}, entriesClaimName != "", entriesClaimName, false)
+	if err != nil {
+		return nil, err
+	}
+	manifests = append(manifests, manifest{kind: "Deployment", name: apiServerPod.Name, object: getApiServerDeploymentObject(apiServerPod, namespace)})
+
+	service := kubernetesProvider.GetServiceObject(resourceNames.ApiServerPodName, resourceNames.ApiServerPodName)
+	service.Namespace = namespace
+	manifests = append(manifests, manifest{kind: "Service", name: service.Name, object: service})
+
+	tapperDaemonSet, err := getTapperDaemonSetObject(ctx, kubernetesProvider, namespace, resourceNames)
+	if err != nil {
+		return nil, err
+	}
+	if tapperDaemonSet != nil {
+		manifests = append(manifests, manifest{kind: "DaemonSet", name: resourceNames.TapperDaemonSetName, object: tapperDaemonSet})
+	}
+
+	return manifests, nil
+}
+
+// getApiServerDeploymentObject runs the api server pod as a deployment, so it's recreated when its node is drained
+func getApiServerDeploymentObject(pod *core.Pod, namespace string) *apps.Deployment {
+	replicas := int32(1)
+	return &apps.Deployment{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Deployment",
+			APIVersion: "apps/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      pod.Name,
+			Namespace: namespace,
+			Labels:    pod.Labels,
+		},
+		Spec: apps.DeploymentSpec{
+			Replicas: &replicas,
+			Selector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{"app": pod.Labels["app"]},
+			},
+			// the entries database of the old pod is its own, there's no reason to keep it running
+			Strategy: apps.DeploymentStrategy{Type: apps.RecreateDeploymentStrategyType},
+			Template: core.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels:      pod.Labels,
+					Annotations: pod.Annotations,
+				},
+				Spec: pod.Spec,
+			},
+		},
+	}
+}
+
+// getTapperDaemonSetObject returns the tapper daemon set of the pods that match now, nil when none does
+func getTapperDaemonSetObject(ctx context.Context, kubernetesProvider *kubernetes.Provider, namespace string, resourceNames kubernetes.ResourceNames) (*applyconfapp.DaemonSetApplyConfiguration, error) {
+	targetNamespaces := getNamespaces(kubernetesProvider)
+	matchingPods, err := kubernetesProvider.ListAllRunningTapTargetPods(ctx, config.Config.Tap.PodRegex(), config.Config.Tap.PodLabelSelector(), targetNamespaces, config.Config.Tap.Annotations, getTapTargets(kubernetesProvider))
+	if err != nil {
+		return nil, err
+	}
+
+	tappedPods := kubernetes.ExcludeMizuPods(matchingPods)
+	if len(tappedPods) == 0 {
+		logger.Log.Warningf(uiUtils.Warning, "No running pods match the regex, the tapper daemon set isn't rendered")
+		return nil, nil
+	}
+
+	nodeToTappedPodMap := kubernetes.GetNodeHostToTappedPodsMap(tappedPods)
+	logger.Log.Infof("Rendering tappers for %d pods on %d nodes", len(tappedPods), len(nodeToTappedPodMap))
+
+	var tappedNodes []core.Node
+	if config.Config.Tap.TapperScheduling.AutoTolerate {
+		nodes, err := kubernetesProvider.ListNodes(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range nodes {
+			if _, ok := nodeToTappedPodMap[node.Name]; ok {
+				tappedNodes = append(tappedNodes, node)
+			}
+		}
+	}
+
+	mizuApiFilteringOptions, err := getMizuApiFilteringOptions()
+	if err != nil {
+		return nil, err
+	}
+
+	return kubernetesProvider.GetMizuTapperDaemonSetObject(
+		namespace,
+		resourceNames.TapperDaemonSetName,
+		config.Config.AgentImage,
+		resourceNames.TapperPodName,
+		fmt.Sprintf("%s.%s.svc.cluster.local", resourceNames.ApiServerPodName, namespace),
+		nodeToTappedPodMap,
+		nil, // the relays are set up by mizu tap from the pools of the live nodes
+		kubernetes.ServiceAccountName,
+		config.Config.Tap.TapperResources,
+		config.Config.ImagePullPolicy(),
+		*mizuApiFilteringOptions,
+		config.Config.LogLevel(),
+		config.Config.Tap.ServiceMesh,
+		config.Config.Tap.Tls,
+		isTapperAuthenticationEnabled(),
+		config.Config.SocketKeepAlive,
+		kubernetes.GetTapperTolerations(config.Config.Tap.TapperScheduling, tappedNodes),
+		config.Config.Tap.TapperScheduling.NodeSelector)
+}
+
+func writeManifests(manifests []manifest, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	for index, m := range manifests {
+		data, err := yaml.Marshal(m.object)
+		if err != nil {
+			return err
+		}
+
+		if err := ioutil.WriteFile(path.Join(dir, m.fileName(index)), data, 0644); err != nil {
+			return err
+		}
+	}
+
+	logger.Log.Infof("Wrote %d resources to %s, deploy them with: kubectl apply -f %s", len(manifests), dir, dir)
+	return nil
+}
+
+// writeHelmChart writes the resources as the templates of a chart, helm creates the namespace of the release so the
+// namespace isn't a template
+func writeHelmChart(manifests []manifest, dir string) error {
+	templatesDir := path.Join(dir, "templates")
+	if err := os.MkdirAll(templatesDir, 0755); err != nil {
+		return err
+	}
+
+	chart := fmt.Sprintf(`apiVersion: v2
+name: %s
+description: Mizu API traffic viewer for Kubernetes, rendered by mizu manifests
+type: application
+version: %s
+appVersion: "%s"
+`, manifestsHelmChartName, mizu.Ver, mizu.Ver)
+	if err := ioutil.WriteFile(path.Join(dir, "Chart.yaml"), []byte(chart), 0644); err != nil {
+		return err
+	}
+
+	values := "# the resources are rendered with the mizu config, run mizu manifests again to change them\n"
+	if err := ioutil.WriteFile(path.Join(dir, "values.yaml"), []byte(values), 0644); err != nil {
+		return err
+	}
+
+	templates := 0
+	for index, m := range manifests {
+		if m.kind == "Namespace" {
+			continue
+		}
+
+		data, err := yaml.Marshal(m.object)
+		if err != nil {
+			return err
+		}
+
+		// helm would evaluate the braces of the config, like of the regexes, as actions
+		template := strings.ReplaceAll(string(data), "{{", `{{"{{"}}`)
+		if err := ioutil.WriteFile(path.Join(templatesDir, m.fileName(index)), []byte(template), 0644); err != nil {
+			return err
+		}
+		templates++
+	}
+
+	logger.Log.Infof("Wrote a chart of %d resources to %s, install it with: helm install %s %s --namespace %s --create-namespace", templates, dir, manifestsHelmChartName, dir, config.Config.MizuResourcesNamespace)
+	return nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var muteCmd = &cobra.Command{
+	Use:   "mute",
+	Short: "Stop storing the entries of a protocol or a namespace",
+	Long: `Stop storing the entries of the --protocol and --namespace until they're unmuted with --unmute, e.g. to silence a noisy protocol during an investigation.
+The tappers keep running, the API server drops the entries of the muted protocols and namespaces and counts them.
+Without a --protocol or a --namespace the muted protocols and namespaces are printed.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("mute", config.Config.Mute)
+		return runMizuMute()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Mute.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(muteCmd)
+
+	defaultMuteConfig := configStructs.MuteConfig{}
+	if err := defaults.Set(&defaultMuteConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	muteCmd.Flags().StringArrayP(configStructs.ProtocolMuteName, "P", defaultMuteConfig.Protocols, "A protocol to mute, like redis, can be repeated")
+	muteCmd.Flags().StringArrayP(configStructs.NamespaceMuteName, "n", defaultMuteConfig.Namespaces, "A namespace to mute, can be repeated")
+	muteCmd.Flags().Bool(configStructs.UnmuteMuteName, defaultMuteConfig.Unmute, "Unmute the protocols and namespaces instead")
+	muteCmd.Flags().Uint16P(configStructs.GuiPortMuteName, "p", defaultMuteConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	muteCmd.Flags().StringP(configStructs.UrlMuteName, "u", defaultMuteConfig.Url, "Provide a custom host")
+
+	if err := muteCmd.Flags().MarkHidden(configStructs.UrlMuteName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+func runMizuMute() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, apiServerProvider, err := connectToApiServer(ctx, cancel, config.Config.Mute.Url, config.Config.Mute.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	if err := updateMutes(apiServerProvider, shared.IngestionMuteProtocol, config.Config.Mute.Protocols); err != nil {
+		return err
+	}
+	if err := updateMutes(apiServerProvider, shared.IngestionMuteNamespace, config.Config.Mute.Namespaces); err != nil {
+		return err
+	}
+
+	ingestionStatus, err := apiServerProvider.GetIngestionStatus()
+	if err != nil {
+		return err
+	}
+
+	return printIngestionStatus(ingestionStatus)
+}
+
+func updateMutes(apiServerProvider *apiserver.Provider, kind string, values []string) error {
+	for _, value := range values {
+		if config.Config.Mute.Unmute {
+			mute, err := apiServerProvider.UnmuteIngestion(kind, value)
+			if err != nil {
+				return err
+			}
+			logger.Log.Infof("Unmuted %s %s, %d entries weren't stored", kind, value, mute.Dropped)
+		} else {
+			if _, err := apiServerProvider.MuteIngestion(kind, value); err != nil {
+				return err
+			}
+			logger.Log.Infof("Muted %s %s", kind, value)
+		}
+	}
+
+	return nil
+}
+
+func printIngestionStatus(ingestionStatus *shared.IngestionStatus) error {
+	if len(ingestionStatus.Muted) == 0 {
+		logger.Log.Infof("Nothing is muted, the entries of every protocol and namespace are stored")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "KIND\tVALUE\tMUTED SINCE\tDROPPED")
+	for _, mute := range ingestionStatus.Muted {
+		since := time.Unix(0, mute.Since*int64(time.Millisecond)).Format(time.RFC3339)
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\n", mute.Kind, mute.Value, since, mute.Dropped)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var queryCmd = &cobra.Command{
+	Use:   "query save|list|run|delete [NAME] [QUERY]",
+	Short: "Save, list and run the queries shared by the team",
+	Long: `Manage the saved queries of the API server, named queries the team shares like "all 5xx from checkout".
+mizu query save checkout-5xx 'http and response.status >= 500 and dst.name == "checkout"' saves a query, --overwrite changes a saved query of the same name.
+mizu query list prints the saved queries.
+mizu query run checkout-5xx downloads the entries matching a saved query as JSON lines, like mizu fetch.
+mizu query delete checkout-5xx removes a saved query.`,
+	ValidArgs:    []string{configStructs.QueryActionSave, configStructs.QueryActionList, configStructs.QueryActionRun, configStructs.QueryActionDelete},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("query", config.Config.Query)
+		return runMizuQuery()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("an action is required, one of %s", strings.Join(cmd.ValidArgs, ", "))
+		}
+		config.Config.Query.Action = args[0]
+
+		switch args[0] {
+		case configStructs.QueryActionSave:
+			if len(args) < 3 {
+				return fmt.Errorf("unexpected number of arguments, a name and a query are required")
+			}
+			config.Config.Query.Name = args[1]
+			config.Config.Query.Query = strings.Join(args[2:], " ")
+		case configStructs.QueryActionList:
+			if len(args) != 1 {
+				return fmt.Errorf("unexpected number of arguments, list takes none")
+			}
+		case configStructs.QueryActionRun, configStructs.QueryActionDelete:
+			if len(args) != 2 {
+				return fmt.Errorf("unexpected number of arguments, a name is required")
+			}
+			config.Config.Query.Name = args[1]
+		default:
+			return fmt.Errorf("unknown action %s, one of %s", args[0], strings.Join(cmd.ValidArgs, ", "))
+		}
+
+		if err := config.Config.Query.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(queryCmd)
+
+	defaultQueryConfig := configStructs.QueryConfig{}
+	if err := defaults.Set(&defaultQueryConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	queryCmd.Flags().String(configStructs.OwnerQueryName, defaultQueryConfig.Owner, "The owner of a saved query, the current user by default")
+	queryCmd.Flags().Bool(configStructs.OverwriteQueryName, defaultQueryConfig.Overwrite, "Change the saved query of the same name")
+	queryCmd.Flags().IntP(configStructs.LimitQueryName, "l", defaultQueryConfig.Limit, "Maximum number of entries to run a saved query for")
+	queryCmd.Flags().StringP(configStructs.TransformQueryName, "t", defaultQueryConfig.Transform, "Transform each entry of a run with a jq-like expression")
+	queryCmd.Flags().Uint16P(configStructs.GuiPortQueryName, "p", defaultQueryConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	queryCmd.Flags().StringP(configStructs.UrlQueryName, "u", defaultQueryConfig.Url, "Provide a custom host")
+
+	if err := queryCmd.Flags().MarkHidden(configStructs.UrlQueryName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
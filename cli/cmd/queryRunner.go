@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/transform"
+)
+
+func runMizuQuery() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, apiServerProvider, err := connectToApiServer(ctx, cancel, config.Config.Query.Url, config.Config.Query.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	switch config.Config.Query.Action {
+	case configStructs.QueryActionSave:
+		savedQuery, err := apiServerProvider.SaveQuery(&shared.SavedQuery{
+			Name:  config.Config.Query.Name,
+			Query: config.Config.Query.Query,
+			Owner: config.Config.Query.OwnerOrCurrentUser(),
+		}, config.Config.Query.Overwrite)
+		if err != nil {
+			return err
+		}
+		logger.Log.Infof("Saved the query %s", savedQuery.Name)
+		return nil
+	case configStructs.QueryActionList:
+		savedQueries, err := apiServerProvider.GetSavedQueries()
+		if err != nil {
+			return err
+		}
+		return printSavedQueries(savedQueries)
+	case configStructs.QueryActionRun:
+		return runSavedQuery(apiServerProvider)
+	default:
+		if err := apiServerProvider.DeleteSavedQuery(config.Config.Query.Name); err != nil {
+			return err
+		}
+		logger.Log.Infof("Deleted the query %s", config.Config.Query.Name)
+		return nil
+	}
+}
+
+func printSavedQueries(savedQueries []*shared.SavedQuery) error {
+	if len(savedQueries) == 0 {
+		logger.Log.Infof("There are no saved queries, save one with mizu query save")
+		return nil
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "NAME\tOWNER\tQUERY")
+	for _, savedQuery := range savedQueries {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", savedQuery.Name, savedQuery.Owner, savedQuery.Query)
+	}
+
+	return writer.Flush()
+}
+
+// runSavedQuery writes the entries matching a saved query to stdout as JSON lines, like mizu fetch
+func runSavedQuery(apiServerProvider *apiserver.Provider) error {
+	savedQuery, err := apiServerProvider.GetSavedQuery(config.Config.Query.Name)
+	if err != nil {
+		return err
+	}
+
+	var entryTransform *transform.Expression
+	if config.Config.Query.Transform != "" {
+		if entryTransform, err = transform.Compile(config.Config.Query.Transform); err != nil {
+			return err
+		}
+	}
+
+	timestampFormatter, err := shared.NewTimestampFormatter(config.Config.Timestamps)
+	if err != nil {
+		return err
+	}
+
+	logger.Log.Debugf("Running the query %s: %s", savedQuery.Name, savedQuery.Query)
+	baseEntries, err := apiServerProvider.GetEntries(savedQuery.Query, config.Config.Query.Limit)
+	if err != nil {
+		return err
+	}
+
+	writer := bufio.NewWriter(os.Stdout)
+	defer writer.Flush()
+
+	for _, baseEntry := range baseEntries {
+		entry, err := apiServerProvider.GetEntry(getEntryIdForFetch(baseEntry))
+		if err != nil {
+			logger.Log.Debugf("Failed fetching entry, skipping it: %v", err)
+			continue
+		}
+
+		line, err := renderEntryLine(entry, entryTransform, timestampFormatter)
+		if err != nil {
+			return err
+		}
+
+		if _, err := writer.Write(line); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
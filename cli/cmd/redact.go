@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var redactCmd = &cobra.Command{
+	Use:   "redact test",
+	Short: "Show what the redaction of mizu tap removes from sample entries",
+	Long: `Run the redaction of the tap config, the built-in redaction unless tap.no-redact is set, tap.regex-masking, the tap.redaction rules and tap.pii-patterns with tap.detect-pii, on sample http entries and print every value it would mask or remove, so a policy can be checked before capturing production traffic.
+mizu redact test --file sample.har redacts the entries of a HAR file, like one written by mizu export.
+mizu redact test --query 'request.path == "/login"' redacts the latest stored entries matching the query, these were already redacted with the options mizu tap ran with.
+The rules are read from the config file, or set with --set, like --set tap.detect-pii=true. The entries are redacted by the API server, nothing is stored.`,
+	ValidArgs:    []string{configStructs.RedactActionTest},
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("redact", config.Config.Redact)
+		return runMizuRedact()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 || args[0] != configStructs.RedactActionTest {
+			return fmt.Errorf("an action is required, one of %s", strings.Join(cmd.ValidArgs, ", "))
+		}
+		config.Config.Redact.Action = args[0]
+
+		if err := config.Config.Redact.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(redactCmd)
+
+	defaultRedactConfig := configStructs.RedactConfig{}
+	if err := defaults.Set(&defaultRedactConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	redactCmd.Flags().StringP(configStructs.FileRedactName, "f", defaultRedactConfig.File, "A HAR file of the sample entries, instead of the stored entries")
+	redactCmd.Flags().StringP(configStructs.QueryRedactName, "q", defaultRedactConfig.Query, "The query of the stored entries to redact")
+	redactCmd.Flags().IntP(configStructs.LimitRedactName, "l", defaultRedactConfig.Limit, "The maximum number of stored entries to redact, the latest ones")
+	redactCmd.Flags().Bool(configStructs.JsonRedactName, defaultRedactConfig.Json, "Print the results as JSON")
+	redactCmd.Flags().Uint16P(configStructs.GuiPortRedactName, "p", defaultRedactConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	redactCmd.Flags().StringP(configStructs.UrlRedactName, "u", defaultRedactConfig.Url, "Provide a custom host")
+
+	if err := redactCmd.Flags().MarkHidden(configStructs.UrlRedactName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+// the longer values, like whole bodies, are cut when printed, --json prints them in full
+const maxPrintedRedactedValueLength = 120
+
+func runMizuRedact() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	options, err := getMizuApiFilteringOptions()
+	if err != nil {
+		return err
+	}
+
+	redactConfig := config.Config.Redact
+	testRequest := &shared.RedactionTestRequest{
+		Options: *options,
+		Query:   redactConfig.Query,
+		Limit:   redactConfig.Limit,
+	}
+
+	if redactConfig.File != "" {
+		data, err := ioutil.ReadFile(redactConfig.File)
+		if err != nil {
+			return fmt.Errorf("failed to read %s, err: %w", redactConfig.File, err)
+		}
+		if !json.Valid(data) {
+			return fmt.Errorf("%s isn't a HAR file", redactConfig.File)
+		}
+		testRequest.Har = data
+	}
+
+	_, apiServerProvider, err := connectToApiServer(ctx, cancel, redactConfig.Url, redactConfig.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	testResponse, err := apiServerProvider.TestRedaction(testRequest)
+	if err != nil {
+		return err
+	}
+
+	if redactConfig.Json {
+		data, err := json.MarshalIndent(testResponse, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if testResponse.Entries == 0 {
+		logger.Log.Infof("No http entries to redact")
+		return nil
+	}
+
+	for _, result := range testResponse.Results {
+		if result.Error != "" {
+			fmt.Printf("%v %s %s: %s\n", fmt.Sprintf(uiUtils.Red, "✗"), result.Method, result.Url, result.Error)
+			continue
+		}
+		if len(result.Values) == 0 {
+			fmt.Printf("%v %s %s %d, nothing redacted\n", fmt.Sprintf(uiUtils.Green, "√"), result.Method, result.Url, result.Status)
+			continue
+		}
+
+		fmt.Printf("%v %s %s %d, %d values redacted\n", fmt.Sprintf(uiUtils.Yellow, "!"), result.Method, result.Url, result.Status, len(result.Values))
+		for _, value := range result.Values {
+			location := fmt.Sprintf("%s %s", value.Message, value.In)
+			if value.Name != "" {
+				location = fmt.Sprintf("%s %s", location, value.Name)
+			}
+
+			if value.Removed {
+				fmt.Printf("    %s: %s removed\n", location, formatRedactedValue(value.Value))
+			} else {
+				fmt.Printf("    %s: %s -> %s\n", location, formatRedactedValue(value.Value), formatRedactedValue(value.Redacted))
+			}
+		}
+	}
+
+	logger.Log.Infof("The redaction changes %d of %d entries", testResponse.Redacted, testResponse.Entries)
+	return nil
+}
+
+func formatRedactedValue(value string) string {
+	if len(value) > maxPrintedRedactedValueLength {
+		value = value[:maxPrintedRedactedValueLength] + "..."
+	}
+
+	return fmt.Sprintf("%q", value)
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var replayCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Load archived traffic into the GUI, or re-send captured requests",
+	Long: `Load the entries archived to a bucket back into a running mizu and open the GUI.
+--from is an archive or a prefix of archives, like s3://bucket/prefix or gs://bucket/prefix, the bucket is accessed with the endpoint, region and keys of the archive config, or with the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables.
+
+With --target the captured http requests matching --query are re-sent to the same paths of the target url instead, from the API server pod so they originate inside the cluster, oldest first:
+  mizu replay --target http://catalogue.staging --query 'response.status == 500' --header "Authorization: Bearer token" --header "Cookie:"
+A --header with no value removes the header from the requests. The replayed requests carry an X-Mizu-Replay header, the tappers don't record them when the target is tapped.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("replay", config.Config.Replay)
+		return runMizuReplay()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Replay.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(replayCmd)
+
+	defaultReplayConfig := configStructs.ReplayConfig{}
+	if err := defaults.Set(&defaultReplayConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	replayCmd.Flags().String(configStructs.FromReplayName, defaultReplayConfig.From, "The archive or the prefix of the archives to load, like s3://bucket/prefix")
+	replayCmd.Flags().String(configStructs.TargetReplayName, defaultReplayConfig.Target, "The base url the captured requests are re-sent to, instead of loading archives")
+	replayCmd.Flags().String(configStructs.QueryReplayName, defaultReplayConfig.Query, "The query of the entries to re-send")
+	replayCmd.Flags().Int(configStructs.LimitReplayName, defaultReplayConfig.Limit, "The maximum number of entries to re-send, the latest ones")
+	replayCmd.Flags().Int(configStructs.ConcurrencyReplayName, defaultReplayConfig.Concurrency, "How many requests are sent at a time")
+	replayCmd.Flags().StringArrayP(configStructs.HeaderReplayName, "H", defaultReplayConfig.Headers, "A header to set on the requests, like \"Name: value\", can be repeated")
+	replayCmd.Flags().String(configStructs.TimeoutReplayName, defaultReplayConfig.Timeout, "How long to wait for the response of each request")
+	replayCmd.Flags().Bool(configStructs.JsonReplayName, defaultReplayConfig.Json, "Print the results as JSON")
+	replayCmd.Flags().Uint16P(configStructs.GuiPortReplayName, "p", defaultReplayConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	replayCmd.Flags().StringP(configStructs.UrlReplayName, "u", defaultReplayConfig.Url, "Provide a custom host")
+
+	if err := replayCmd.Flags().MarkHidden(configStructs.UrlReplayName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
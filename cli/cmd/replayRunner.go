@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/cli/utils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/archiveformat"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/shared/objectstorage"
+)
+
+// an archive holds the entries of an archive interval, downloading and storing it takes longer than the default timeout
+const replayTimeout = 60 * time.Second
+
+func runMizuReplay() error {
+	if config.Config.Replay.Target != "" {
+		return runMizuReplayTraffic()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	location, err := objectstorage.ParseUrl(config.Config.Replay.From)
+	if err != nil {
+		return err
+	}
+
+	archiveConfig := config.Config.Archive
+	accessKeyId, secretAccessKey := archiveConfig.AccessKeyId, archiveConfig.SecretAccessKey
+	if accessKeyId == "" && secretAccessKey == "" {
+		accessKeyId, secretAccessKey = os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
+
+	client, err := objectstorage.NewClient(location, archiveConfig.Endpoint, archiveConfig.Region, accessKeyId, secretAccessKey, replayTimeout)
+	if err != nil {
+		return err
+	}
+
+	keys, err := getArchiveKeys(ctx, client, location.Key)
+	if err != nil {
+		return err
+	}
+	if len(keys) == 0 {
+		return fmt.Errorf("no archives were found at %s", location)
+	}
+
+	apiServerUrl, _, err := connectToApiServer(ctx, cancel, config.Config.Replay.Url, config.Config.Replay.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	apiServerProvider := apiserver.NewProvider(apiServerUrl, apiserver.DefaultRetries, replayTimeout)
+	entries := 0
+	for _, key := range keys {
+		archive, err := client.GetObject(ctx, key)
+		if err != nil {
+			return err
+		}
+
+		replayResponse, err := apiServerProvider.PostArchivedEntries(archive)
+		if err != nil {
+			return err
+		}
+		if replayResponse.FormatVersion < archiveformat.FormatVersion {
+			logger.Log.Debugf("%s is an archive of format version %d", key, replayResponse.FormatVersion)
+		}
+		if replayResponse.Skipped > 0 {
+			logger.Log.Warningf("Skipped %d invalid lines of %s", replayResponse.Skipped, key)
+		}
+
+		entries += replayResponse.Entries
+		logger.Log.Debugf("Replayed %d entries of %s", replayResponse.Entries, key)
+	}
+
+	logger.Log.Infof("Replayed %d entries of %d archives from %s", entries, len(keys), location)
+	logger.Log.Infof("Mizu is available at %s", apiServerUrl)
+
+	if !config.Config.HeadlessMode {
+		uiUtils.OpenBrowser(apiServerUrl)
+	}
+
+	utils.WaitForFinish(ctx, cancel)
+
+	return nil
+}
+
+// getArchiveKeys returns the key when it's an archive, or else the archives under it as a prefix, oldest first
+func getArchiveKeys(ctx context.Context, client *objectstorage.Client, key string) ([]string, error) {
+	if archiveformat.IsArchiveKey(key) {
+		return []string{key}, nil
+	}
+
+	objects, err := client.ListObjects(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(objects))
+	for _, object := range objects {
+		if archiveformat.IsArchiveKey(object.Key) {
+			keys = append(keys, object.Key)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// runMizuReplayTraffic re-sends the captured requests matching the query to the target from the api server pod, the
+// call returns once every request was answered or timed out
+func runMizuReplayTraffic() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	apiServerUrl, _, err := connectToApiServer(ctx, cancel, config.Config.Replay.Url, config.Config.Replay.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	replayConfig := config.Config.Replay
+	replayRequest := &shared.ReplayRequest{
+		Query:       replayConfig.Query,
+		TargetUrl:   replayConfig.Target,
+		Limit:       replayConfig.Limit,
+		Concurrency: replayConfig.Concurrency,
+		Headers:     replayConfig.ParsedHeaders(),
+		TimeoutMs:   int(replayConfig.TimeoutDuration().Milliseconds()),
+	}
+
+	// the requests are sent in rounds of concurrency requests, each round takes up to the timeout
+	rounds := (replayConfig.Limit + replayConfig.Concurrency - 1) / replayConfig.Concurrency
+	timeout := time.Duration(rounds)*replayConfig.TimeoutDuration() + replayTimeout
+	replayResponse, err := apiserver.NewProvider(apiServerUrl, 1, timeout).Replay(replayRequest)
+	if err != nil {
+		return err
+	}
+
+	if replayConfig.Json {
+		data, err := json.MarshalIndent(replayResponse, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	if replayResponse.Entries == 0 {
+		logger.Log.Infof("No http entries match the query, nothing was replayed")
+		return nil
+	}
+
+	for _, result := range replayResponse.Results {
+		if result.Error != "" {
+			fmt.Printf("%v %s %s %s: %s\n", fmt.Sprintf(uiUtils.Red, "✗"), result.EntryId, result.Method, result.Url, result.Error)
+		} else if result.Status != result.OriginalStatus {
+			fmt.Printf("%v %s %s %s %d (captured %d, %dms)\n", fmt.Sprintf(uiUtils.Yellow, "!"), result.EntryId, result.Method, result.Url, result.Status, result.OriginalStatus, result.ElapsedTime)
+		} else {
+			fmt.Printf("%v %s %s %s %d (%dms)\n", fmt.Sprintf(uiUtils.Green, "√"), result.EntryId, result.Method, result.Url, result.Status, result.ElapsedTime)
+		}
+	}
+
+	logger.Log.Infof("Replayed %d entries to %s, %d failed", replayResponse.Entries, replayConfig.Target, replayResponse.Failed)
+	return nil
+}
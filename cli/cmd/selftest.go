@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var selftestCmd = &cobra.Command{
+	Use:          "selftest",
+	Short:        "Deploy sample HTTP, gRPC and Redis workloads, tap them and verify their traffic is captured",
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("selftest", config.Config.Selftest)
+
+		if err := config.Config.Selftest.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return runMizuSelftest()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(selftestCmd)
+
+	defaultSelftestConfig := configStructs.SelftestConfig{}
+	if err := defaults.Set(&defaultSelftestConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	selftestCmd.Flags().String(configStructs.NamespaceSelftestName, defaultSelftestConfig.Namespace, "Namespace to deploy the sample workloads to")
+	selftestCmd.Flags().Uint16P(configStructs.GuiPortSelftestName, "p", defaultSelftestConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	selftestCmd.Flags().Int(configStructs.TimeoutSecSelftestName, defaultSelftestConfig.TimeoutSec, "Seconds to wait for the expected traffic to be captured")
+	selftestCmd.Flags().Bool(configStructs.KeepResourcesSelftestName, defaultSelftestConfig.KeepResources, "Don't remove the sample workloads when done")
+	selftestCmd.Flags().Bool(configStructs.StartupSelftestName, defaultSelftestConfig.Startup, "Start a pod while tapping and verify none of the requests of its init container and of its startup are missed")
+}
@@ -0,0 +1,446 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/mizu/fsUtils"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	selftestPodsPrefix      = "mizu-selftest-"
+	selftestHttpbinName     = selftestPodsPrefix + "httpbin"
+	selftestRedisName       = selftestPodsPrefix + "redis"
+	selftestGrpcbinName     = selftestPodsPrefix + "grpcbin"
+	selftestClientName      = selftestPodsPrefix + "client"
+	selftestStartupName     = selftestPodsPrefix + "startup"
+	selftestTrafficKey      = "mizu-selftest"
+	selftestPollingInterval = 3 * time.Second
+
+	// selftestStartupRequests are sent by the init container of the startup pod, and as many by its container
+	selftestStartupRequests = 10
+
+	selftestGrpcbinPort   = 9000
+	selftestGrpcbinMethod = "grpcbin.GRPCBin/DummyUnary"
+)
+
+var (
+	selftestPodNames     = []string{selftestHttpbinName, selftestRedisName, selftestGrpcbinName, selftestClientName}
+	selftestServiceNames = []string{selftestHttpbinName, selftestRedisName, selftestGrpcbinName}
+)
+
+type selftestExpectation struct {
+	Protocol string
+	Query    string
+}
+
+// every sample workload generates traffic that should be matched by one of these queries
+var selftestExpectations = []selftestExpectation{
+	{Protocol: "http", Query: fmt.Sprintf(`http and request.path == "/anything/%s"`, selftestTrafficKey)},
+	{Protocol: "redis", Query: fmt.Sprintf(`redis and request.key == "%s"`, selftestTrafficKey)},
+	{Protocol: "grpc", Query: fmt.Sprintf(`grpc and request.path == "/%s"`, selftestGrpcbinMethod)},
+}
+
+func runMizuSelftest() error {
+	kubernetesProvider, err := getKubernetesProviderForCli()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	logger.Log.Infof("Mizu selftest\n===================")
+
+	logger.Log.Infof("\nsample-workloads\n--------------------")
+	createdNamespace, err := createSelftestWorkloads(ctx, kubernetesProvider)
+	if !config.Config.Selftest.KeepResources {
+		defer removeSelftestWorkloads(kubernetesProvider, createdNamespace)
+	}
+	if err != nil {
+		logger.Log.Errorf("%v error while creating sample workloads, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		return fmt.Errorf("selftest failed")
+	}
+
+	if err := waitForSelftestWorkloads(ctx, kubernetesProvider); err != nil {
+		logger.Log.Errorf("%v sample workloads are not running, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		return fmt.Errorf("selftest failed")
+	}
+	logger.Log.Infof("%v sample workloads are running in namespace %s", fmt.Sprintf(uiUtils.Green, "√"), config.Config.Selftest.Namespace)
+
+	logger.Log.Infof("\ntap\n--------------------")
+	tapProcess, err := startSelftestTap(ctx)
+	if err != nil {
+		logger.Log.Errorf("%v error while starting tap, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		return fmt.Errorf("selftest failed")
+	}
+	defer stopSelftestTap(tapProcess)
+
+	timeoutSec := config.Config.Selftest.TimeoutSec
+	apiServerProvider := apiserver.NewProvider(GetApiServerUrl(config.Config.Selftest.GuiPort), timeoutSec, apiserver.DefaultTimeout)
+	if err := apiServerProvider.TestConnection(); err != nil {
+		logger.Log.Errorf("%v couldn't connect to API server, for more info check logs at %s", fmt.Sprintf(uiUtils.Red, "✗"), fsUtils.GetLogFilePath())
+		return fmt.Errorf("selftest failed")
+	}
+	logger.Log.Infof("%v tap is running", fmt.Sprintf(uiUtils.Green, "√"))
+
+	logger.Log.Infof("\ncaptured-traffic\n--------------------")
+	if !checkSelftestExpectations(apiServerProvider, time.Duration(timeoutSec)*time.Second) {
+		logger.Log.Errorf("\nSelftest results are %v, for more info check logs at %s", fmt.Sprintf(uiUtils.Red, "✗"), fsUtils.GetLogFilePath())
+		return fmt.Errorf("selftest failed")
+	}
+
+	if config.Config.Selftest.Startup {
+		logger.Log.Infof("\nstartup-traffic\n--------------------")
+		if !checkSelftestStartupTraffic(ctx, kubernetesProvider, apiServerProvider, time.Duration(timeoutSec)*time.Second) {
+			logger.Log.Errorf("\nSelftest results are %v, for more info check logs at %s", fmt.Sprintf(uiUtils.Red, "✗"), fsUtils.GetLogFilePath())
+			return fmt.Errorf("selftest failed")
+		}
+	}
+
+	logger.Log.Infof("\nSelftest results are %v", fmt.Sprintf(uiUtils.Green, "√"))
+	return nil
+}
+
+// checkSelftestStartupTraffic starts a pod while tapping, its init container and its container send numbered requests
+// as soon as they start and exit, every request has to be captured even though the pod is tapped only once it starts
+func checkSelftestStartupTraffic(ctx context.Context, kubernetesProvider *kubernetes.Provider, apiServerProvider *apiserver.Provider, timeout time.Duration) bool {
+	namespace := config.Config.Selftest.Namespace
+	// the pods of previous runs may still be terminating
+	podName := fmt.Sprintf("%s-%d", selftestStartupName, time.Now().Unix())
+	pathPrefix := fmt.Sprintf("/anything/%s-", podName)
+
+	if _, err := kubernetesProvider.CreatePod(ctx, namespace, getSelftestStartupPod(podName, pathPrefix)); err != nil {
+		logger.Log.Errorf("%v error while creating the startup pod, err: %v", fmt.Sprintf(uiUtils.Red, "✗"), err)
+		return false
+	}
+	if !config.Config.Selftest.KeepResources {
+		defer func() {
+			removeCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+			defer cancel()
+			if err := kubernetesProvider.RemovePod(removeCtx, namespace, podName); err != nil {
+				logger.Log.Debugf("error while removing selftest pod %s, err: %v", podName, err)
+			}
+		}()
+	}
+
+	pending := make(map[string]bool)
+	for i := 1; i <= 2*selftestStartupRequests; i++ {
+		pending[fmt.Sprintf("%s%d", pathPrefix, i)] = true
+	}
+
+	query := fmt.Sprintf(`http and request.path.startsWith("%s")`, pathPrefix)
+	deadline := time.Now().Add(timeout)
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		time.Sleep(selftestPollingInterval)
+
+		entries, err := apiServerProvider.GetEntries(query, 4*selftestStartupRequests)
+		if err != nil {
+			logger.Log.Debugf("error while fetching the startup entries, err: %v", err)
+			continue
+		}
+
+		for _, entry := range entries {
+			if path, ok := entry["summary"].(string); ok {
+				delete(pending, path)
+			}
+		}
+	}
+
+	if len(pending) > 0 {
+		missed := make([]string, 0, len(pending))
+		for path := range pending {
+			missed = append(missed, path)
+		}
+		sort.Strings(missed)
+		logger.Log.Errorf("%v %d of the %d startup requests weren't captured after %v: %s", fmt.Sprintf(uiUtils.Red, "✗"), len(missed), 2*selftestStartupRequests, timeout, strings.Join(missed, ", "))
+		return false
+	}
+
+	logger.Log.Infof("%v the requests of the init container and of the startup of pod %s are captured", fmt.Sprintf(uiUtils.Green, "√"), podName)
+	return true
+}
+
+// getSelftestStartupPod sends the requests 1 to selftestStartupRequests from the init container, like a migration,
+// and the rest from the container right as it starts
+func getSelftestStartupPod(podName string, pathPrefix string) *core.Pod {
+	requestsCommand := func(first int) string {
+		return fmt.Sprintf(
+			"for i in $(seq %d %d); do wget -q -O /dev/null http://%s%s$i || exit 1; done",
+			first, first+selftestStartupRequests-1, selftestHttpbinName, pathPrefix,
+		)
+	}
+
+	var zero int64
+	return &core.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: podName,
+		},
+		Spec: core.PodSpec{
+			InitContainers: []core.Container{
+				{
+					Name:    "migrate",
+					Image:   "redis:6-alpine",
+					Command: []string{"sh", "-c"},
+					Args:    []string{requestsCommand(1)},
+				},
+			},
+			Containers: []core.Container{
+				{
+					Name:    "startup",
+					Image:   "redis:6-alpine",
+					Command: []string{"sh", "-c"},
+					Args:    []string{requestsCommand(selftestStartupRequests + 1)},
+				},
+			},
+			RestartPolicy:                 core.RestartPolicyNever,
+			TerminationGracePeriodSeconds: &zero,
+		},
+	}
+}
+
+func checkSelftestExpectations(apiServerProvider *apiserver.Provider, timeout time.Duration) bool {
+	pending := make(map[string]selftestExpectation)
+	for _, expectation := range selftestExpectations {
+		pending[expectation.Protocol] = expectation
+	}
+
+	deadline := time.Now().Add(timeout)
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		for protocol, expectation := range pending {
+			entries, err := apiServerProvider.GetEntries(expectation.Query, 1)
+			if err != nil {
+				logger.Log.Debugf("error while fetching %s entries, err: %v", protocol, err)
+				continue
+			}
+
+			if len(entries) > 0 {
+				logger.Log.Infof("%v %s traffic is captured", fmt.Sprintf(uiUtils.Green, "√"), protocol)
+				delete(pending, protocol)
+			}
+		}
+
+		if len(pending) > 0 {
+			time.Sleep(selftestPollingInterval)
+		}
+	}
+
+	for protocol := range pending {
+		logger.Log.Errorf("%v %s traffic wasn't captured after %v", fmt.Sprintf(uiUtils.Red, "✗"), protocol, timeout)
+	}
+
+	return len(pending) == 0
+}
+
+func startSelftestTap(ctx context.Context) (*exec.Cmd, error) {
+	executablePath, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	tapCmd := exec.CommandContext(ctx, executablePath,
+		"tap", fmt.Sprintf("^%s", selftestPodsPrefix),
+		"--namespaces", config.Config.Selftest.Namespace,
+		"--gui-port", fmt.Sprintf("%d", config.Config.Selftest.GuiPort),
+		"--config-path", config.Config.ConfigFilePath,
+		"--set", "headless=true",
+	)
+
+	logger.Log.Debugf("Starting tap: %v", tapCmd.Args)
+	if err := tapCmd.Start(); err != nil {
+		return nil, err
+	}
+
+	return tapCmd, nil
+}
+
+func stopSelftestTap(tapProcess *exec.Cmd) {
+	// interrupting lets tap clean up its own resources
+	if err := tapProcess.Process.Signal(os.Interrupt); err != nil {
+		logger.Log.Debugf("error while interrupting tap, killing it, err: %v", err)
+		_ = tapProcess.Process.Kill()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- tapProcess.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(cleanupTimeout):
+		logger.Log.Debugf("tap didn't exit in time, killing it")
+		_ = tapProcess.Process.Kill()
+	}
+}
+
+func createSelftestWorkloads(ctx context.Context, kubernetesProvider *kubernetes.Provider) (bool, error) {
+	namespace := config.Config.Selftest.Namespace
+	createdNamespace := true
+	if _, err := kubernetesProvider.CreateNamespace(ctx, namespace); err != nil {
+		if !k8serrors.IsAlreadyExists(err) {
+			return false, err
+		}
+		createdNamespace = false
+	}
+
+	var zero int64
+	pods := []*core.Pod{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   selftestHttpbinName,
+				Labels: map[string]string{"app": selftestHttpbinName},
+			},
+			Spec: core.PodSpec{
+				Containers: []core.Container{
+					{
+						Name:  "httpbin",
+						Image: "kennethreitz/httpbin",
+						Ports: []core.ContainerPort{{ContainerPort: 80}},
+					},
+				},
+				TerminationGracePeriodSeconds: &zero,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   selftestRedisName,
+				Labels: map[string]string{"app": selftestRedisName},
+			},
+			Spec: core.PodSpec{
+				Containers: []core.Container{
+					{
+						Name:  "redis",
+						Image: "redis:6-alpine",
+						Ports: []core.ContainerPort{{ContainerPort: 6379}},
+					},
+				},
+				TerminationGracePeriodSeconds: &zero,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   selftestGrpcbinName,
+				Labels: map[string]string{"app": selftestGrpcbinName},
+			},
+			Spec: core.PodSpec{
+				Containers: []core.Container{
+					{
+						Name:  "grpcbin",
+						Image: "moul/grpcbin",
+						Ports: []core.ContainerPort{{ContainerPort: selftestGrpcbinPort}},
+					},
+				},
+				TerminationGracePeriodSeconds: &zero,
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: selftestClientName,
+			},
+			Spec: core.PodSpec{
+				Containers: []core.Container{
+					{
+						Name:    "client",
+						Image:   "redis:6-alpine",
+						Command: []string{"sh", "-c"},
+						Args: []string{fmt.Sprintf(
+							"while true; do wget -q -O /dev/null http://%s/anything/%s; redis-cli -h %s set %s %s; redis-cli -h %s get %s; sleep 1; done",
+							selftestHttpbinName, selftestTrafficKey, selftestRedisName, selftestTrafficKey, selftestTrafficKey, selftestRedisName, selftestTrafficKey,
+						)},
+					},
+					{
+						Name:    "grpc-client",
+						Image:   "fullstorydev/grpcurl:latest-alpine",
+						Command: []string{"sh", "-c"},
+						Args: []string{fmt.Sprintf(
+							`while true; do grpcurl -plaintext -d '{"f_string": "%s"}' %s:%d %s; sleep 1; done`,
+							selftestTrafficKey, selftestGrpcbinName, selftestGrpcbinPort, selftestGrpcbinMethod,
+						)},
+					},
+				},
+				TerminationGracePeriodSeconds: &zero,
+			},
+		},
+	}
+
+	for _, pod := range pods {
+		if _, err := kubernetesProvider.CreatePod(ctx, namespace, pod); err != nil && !k8serrors.IsAlreadyExists(err) {
+			return createdNamespace, fmt.Errorf("failed to create pod %s, err: %w", pod.Name, err)
+		}
+	}
+
+	if _, err := kubernetesProvider.CreateServiceForApp(ctx, namespace, selftestHttpbinName, selftestHttpbinName, 80); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return createdNamespace, fmt.Errorf("failed to create service %s, err: %w", selftestHttpbinName, err)
+	}
+
+	if _, err := kubernetesProvider.CreateServiceForApp(ctx, namespace, selftestRedisName, selftestRedisName, 6379); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return createdNamespace, fmt.Errorf("failed to create service %s, err: %w", selftestRedisName, err)
+	}
+
+	if _, err := kubernetesProvider.CreateServiceForApp(ctx, namespace, selftestGrpcbinName, selftestGrpcbinName, selftestGrpcbinPort); err != nil && !k8serrors.IsAlreadyExists(err) {
+		return createdNamespace, fmt.Errorf("failed to create service %s, err: %w", selftestGrpcbinName, err)
+	}
+
+	return createdNamespace, nil
+}
+
+func waitForSelftestWorkloads(ctx context.Context, kubernetesProvider *kubernetes.Provider) error {
+	podsRegex := regexp.MustCompile(fmt.Sprintf("^%s", selftestPodsPrefix))
+	namespaces := []string{config.Config.Selftest.Namespace}
+	deadline := time.Now().Add(time.Duration(config.Config.Selftest.TimeoutSec) * time.Second)
+
+	for time.Now().Before(deadline) {
+		runningPods, err := kubernetesProvider.ListAllRunningPodsMatchingRegex(ctx, podsRegex, namespaces)
+		if err != nil {
+			return err
+		}
+
+		if len(runningPods) == len(selftestPodNames) {
+			return nil
+		}
+
+		time.Sleep(selftestPollingInterval)
+	}
+
+	return fmt.Errorf("pods didn't reach running state in time")
+}
+
+func removeSelftestWorkloads(kubernetesProvider *kubernetes.Provider, removeNamespace bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	namespace := config.Config.Selftest.Namespace
+	for _, podName := range selftestPodNames {
+		if err := kubernetesProvider.RemovePod(ctx, namespace, podName); err != nil {
+			logger.Log.Debugf("error while removing selftest pod %s, err: %v", podName, err)
+		}
+	}
+
+	for _, serviceName := range selftestServiceNames {
+		if err := kubernetesProvider.RemoveService(ctx, namespace, serviceName); err != nil {
+			logger.Log.Debugf("error while removing selftest service %s, err: %v", serviceName, err)
+		}
+	}
+
+	if removeNamespace {
+		if err := kubernetesProvider.RemoveNamespace(ctx, namespace); err != nil {
+			logger.Log.Debugf("error while removing selftest namespace, err: %v", err)
+		}
+	}
+}
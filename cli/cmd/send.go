@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var sendCmd = &cobra.Command{
+	Use:   "send [URL]",
+	Short: "Send a request from inside the cluster",
+	Long: `Send an HTTP or gRPC request from the API server pod and print the response along with the entry captured for it.
+The entry is only captured when the pod of the target service is tapped.
+Use --grpc to call a gRPC method, e.g. http://payments.shop:50051/payments.Payments/Charge, the --data of a gRPC request is the base64 encoded protobuf message.
+A --data starting with @ is read from a file, e.g. @order.json.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("send", config.Config.Send)
+		return runMizuSend()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("unexpected number of arguments, a url is required")
+		}
+		config.Config.Send.TargetUrl = args[0]
+
+		if err := config.Config.Send.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sendCmd)
+
+	defaultSendConfig := configStructs.SendConfig{}
+	if err := defaults.Set(&defaultSendConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	sendCmd.Flags().StringP(configStructs.MethodSendName, "X", defaultSendConfig.Method, "The request method, GET or POST when --data is set")
+	sendCmd.Flags().StringArrayP(configStructs.HeaderSendName, "H", defaultSendConfig.Headers, "A request header in the form \"Name: value\", can be repeated")
+	sendCmd.Flags().StringP(configStructs.DataSendName, "d", defaultSendConfig.Data, "The request body, or @file to read it from a file")
+	sendCmd.Flags().Bool(configStructs.GrpcSendName, defaultSendConfig.Grpc, "Send a gRPC request")
+	sendCmd.Flags().String(configStructs.TimeoutSendName, defaultSendConfig.Timeout, "How long to wait for the response")
+	sendCmd.Flags().String(configStructs.CaptureTimeoutSendName, defaultSendConfig.CaptureTimeout, "How long to wait for the entry to be captured")
+	sendCmd.Flags().Bool(configStructs.JsonSendName, defaultSendConfig.Json, "Print the response and the entry as JSON")
+	sendCmd.Flags().Uint16P(configStructs.GuiPortSendName, "p", defaultSendConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	sendCmd.Flags().StringP(configStructs.UrlSendName, "u", defaultSendConfig.Url, "Provide a custom host")
+
+	if err := sendCmd.Flags().MarkHidden(configStructs.UrlSendName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared"
+)
+
+// the API server waits for the response and then for the entry, on top of that the request has to reach it
+const sendOverheadTimeout = 5 * time.Second
+
+func runMizuSend() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	apiServerUrl, _, err := connectToApiServer(ctx, cancel, config.Config.Send.Url, config.Config.Send.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	sendRequest, err := getSendRequest()
+	if err != nil {
+		return err
+	}
+
+	timeout := config.Config.Send.TimeoutDuration() + config.Config.Send.CaptureTimeoutDuration() + sendOverheadTimeout
+	sendResponse, err := apiserver.NewProvider(apiServerUrl, 1, timeout).Send(sendRequest)
+	if err != nil {
+		return err
+	}
+
+	if config.Config.Send.Json {
+		data, err := json.MarshalIndent(sendResponse, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	printSendResponse(sendResponse)
+	printSentEntry(sendResponse, apiServerUrl)
+	return nil
+}
+
+func getSendRequest() (*shared.SendRequest, error) {
+	sendConfig := config.Config.Send
+
+	data := sendConfig.Data
+	if strings.HasPrefix(data, "@") {
+		fileData, err := os.ReadFile(strings.TrimPrefix(data, "@"))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the request body, err: %w", err)
+		}
+		data = string(fileData)
+		if sendConfig.Grpc {
+			// a file holds the protobuf message itself
+			data = base64.StdEncoding.EncodeToString(fileData)
+		}
+	}
+
+	sendRequest := &shared.SendRequest{
+		Protocol:         shared.SendProtocolHttp,
+		Method:           strings.ToUpper(sendConfig.Method),
+		Url:              sendConfig.TargetUrl,
+		Headers:          sendConfig.ParsedHeaders(),
+		Body:             data,
+		TimeoutMs:        int(sendConfig.TimeoutDuration().Milliseconds()),
+		CaptureTimeoutMs: int(sendConfig.CaptureTimeoutDuration().Milliseconds()),
+	}
+
+	if sendConfig.Grpc {
+		sendRequest.Protocol = shared.SendProtocolGrpc
+		sendRequest.Method = http.MethodPost
+	} else if sendRequest.Method == "" {
+		sendRequest.Method = http.MethodGet
+		if data != "" {
+			sendRequest.Method = http.MethodPost
+		}
+	}
+
+	return sendRequest, nil
+}
+
+func printSendResponse(sendResponse *shared.SendResponse) {
+	fmt.Printf("%d %s (%dms)\n", sendResponse.Status, sendResponse.StatusText, sendResponse.ElapsedTime)
+	printSendHeaders(sendResponse.Headers)
+
+	if sendResponse.GrpcStatus != nil {
+		printSendHeaders(sendResponse.Trailers)
+		fmt.Printf("\ngRPC status %d %s\n", *sendResponse.GrpcStatus, sendResponse.GrpcMessage)
+		for i, message := range sendResponse.GrpcMessages {
+			fmt.Printf("message %d (base64): %s\n", i+1, message)
+		}
+		return
+	}
+
+	if sendResponse.Body != "" {
+		if sendResponse.BodyEncoding == shared.SendBodyEncodingBase64 {
+			fmt.Println("\nbody (base64):")
+		} else {
+			fmt.Println()
+		}
+		fmt.Println(sendResponse.Body)
+	}
+}
+
+func printSendHeaders(headers map[string]string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Printf("%s: %s\n", name, headers[name])
+	}
+}
+
+func printSentEntry(sendResponse *shared.SendResponse, apiServerUrl string) {
+	entry, ok := sendResponse.Entry.(map[string]interface{})
+	if !ok {
+		fmt.Printf("\n%v no entry was captured, make sure the pod of the target service is tapped\n", fmt.Sprintf(uiUtils.Yellow, "!"))
+		return
+	}
+
+	id := fmt.Sprintf("%v", entry["id"])
+	if entryId, ok := entry["entryId"].(string); ok && entryId != "" {
+		id = entryId
+	}
+	fmt.Printf("\n%v captured entry %s: %v %v %v\n", fmt.Sprintf(uiUtils.Green, "√"), id, entry["method"], entry["summary"], entry["status"])
+	fmt.Printf("%s/entries/%s\n", apiServerUrl, url.PathEscape(id))
+}
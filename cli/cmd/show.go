@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var showCmd = &cobra.Command{
+	Use:          "show [ENTRY ID]",
+	Short:        "Print a captured entry",
+	Long:         `Print a captured entry by its id, as shown in the UI and in exports.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("show", config.Config.Show)
+		return runMizuShow()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 1 {
+			return errors.New("unexpected number of arguments, an entry id is required")
+		}
+		config.Config.Show.EntryId = args[0]
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+
+	defaultShowConfig := configStructs.ShowConfig{}
+	if err := defaults.Set(&defaultShowConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	showCmd.Flags().Uint16P(configStructs.GuiPortShowName, "p", defaultShowConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	showCmd.Flags().StringP(configStructs.UrlShowName, "u", defaultShowConfig.Url, "Provide a custom host")
+
+	if err := showCmd.Flags().MarkHidden(configStructs.UrlShowName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+func runMizuShow() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	apiServerUrl, apiServerProvider, err := connectToApiServer(ctx, cancel, config.Config.Show.Url, config.Config.Show.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	timestampFormatter, err := shared.NewTimestampFormatter(config.Config.Timestamps)
+	if err != nil {
+		return err
+	}
+
+	entry, err := apiServerProvider.GetEntry(config.Config.Show.EntryId)
+	if err != nil {
+		return err
+	}
+
+	renderEntryTimestamps(entry["data"], timestampFormatter)
+
+	data, err := json.MarshalIndent(entry["data"], "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(string(data))
+	logger.Log.Debugf("Entry permalink: %s/entries/%s", apiServerUrl, url.PathEscape(config.Config.Show.EntryId))
+
+	return nil
+}
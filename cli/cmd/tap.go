@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/up9inc/mizu/cli/up9"
 
@@ -21,25 +22,93 @@ import (
 const uploadTrafficMessageToConfirm = `NOTE: running mizu with --%s flag will upload recorded traffic for further analysis and enriched presentation options.`
 
 var tapCmd = &cobra.Command{
-	Use:   "tap [POD REGEX]",
+	Use:   "tap [POD REGEX | KIND/NAME...]",
 	Short: "Record ingoing traffic of a kubernetes pod",
 	Long: `Record the ingoing traffic of a kubernetes pod.
-Supported protocols are HTTP and gRPC.`,
+Supported protocols are HTTP and gRPC.
+With --docker the regex selects the local Docker containers to record instead, no cluster is used.
+With --docker --interface the traffic of a network interface of the Docker host is recorded instead of containers, like
+lo for the processes running on the host, or docker0 for the containers of the default bridge network.
+
+With --selector the pods matching the regex are narrowed down by their labels, with the label selector syntax of kubectl
+like app=frontend,tier in (web, edge). The pods that start matching, like the new replicas of a rollout, are tapped as
+they come up, and the ones whose labels stop matching are no longer tapped.
+
+With --daemon the tap keeps running in the cluster after the cli exits, the api server follows the matching pods itself
+like with --operator, without a port-forward. mizu attach reconnects to it, mizu view only opens the GUI and mizu clean
+stops it and removes mizu.
+
+The arguments are either a pod regex, or workloads like deployment/cart, statefulset/db or service/front-end whose pods
+are tapped, the ones of a deployment through its replica sets and the ones of a service through its selector. The pods
+a rollout replaces are followed, the new pods are tapped as they come up: mizu tap deployment/cart service/front-end
+
+With --targets-file or --targets the pods are listed explicitly instead of matched by the regex, like pods listed by
+other tools. The targets file is YAML, the namespace defaults to the namespace of the kube context and the kind to Pod:
+
+  targets:
+    - name: front-end-7d9f8b6c5-x2x4z
+    - namespace: shop
+      kind: Deployment # or Pod, ReplicaSet, StatefulSet, DaemonSet, Job, Service
+      name: cart
+
+--targets takes references like kubectl get -o name prints them, [[namespace/]kind/]name, and reads them from stdin
+for the - value: kubectl get pods -l app=cart -o name | mizu tap --targets -
+
+The tap.redaction section of the config file masks values before the entries are stored, even with --no-redact. A rule
+masks a header or a query param by name, or the fields of JSON bodies by JSON path, a regex masks only the matching parts
+of the values and a rule with a regex but no name applies to every value of its kind:
+
+  tap:
+    redaction:
+      - in: header # or query, body
+        name: X-Tenant-Id
+      - in: body
+        name: $.card.number # $.items[*].price or $..ssn at any depth
+        regex: "\\d{12}"
+      - in: body # any body, JSON or not
+        regex: "[\\w.]+@[\\w.]+"
+
+The cluster admin restricts what the tappers capture, whatever the tap options, with a mizu-capture-policy config map in
+the kube-system namespace, which mizu never creates or changes. The tappers drop the packets of the denied hosts, or of
+the hosts that aren't allowed when there's an allowlist, before they're dissected. The admin publishes the policy by
+letting the service accounts read it, from then on the tappers capture nothing while the config map is missing. The
+hosts are ips, cidrs or host names:
+
+  kubectl create configmap mizu-capture-policy -n kube-system --from-file=capture-policy.yaml
+  kubectl create role mizu-capture-policy-reader -n kube-system --verb=get --resource=configmaps --resource-name=mizu-capture-policy
+  kubectl create rolebinding mizu-capture-policy-reader -n kube-system --role=mizu-capture-policy-reader --group=system:serviceaccounts
+
+  allowed-hosts: [] # empty allows every host
+  denied-hosts:
+    - sso.corp.example
+    - 10.20.0.0/16`,
 	RunE: func(cmd *cobra.Command, args []string) error {
-		RunMizuTap()
+		if config.Config.Tap.Docker {
+			RunMizuTapDocker()
+		} else {
+			RunMizuTap()
+		}
 		return nil
 	},
 	PreRunE: func(cmd *cobra.Command, args []string) error {
-		if len(args) == 1 {
-			config.Config.Tap.PodRegexStr = args[0]
-		} else if len(args) > 1 {
-			return errors.New("unexpected number of arguments")
+		if err := readTapArgs(args); err != nil {
+			return err
 		}
 
 		if err := config.Config.Tap.Validate(); err != nil {
 			return errormessage.FormatError(err)
 		}
 
+		var err error
+		if state.targets, err = readTapTargets(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		// the first context is the one the gui is opened for, the entries of the other clusters are forwarded to it
+		if len(config.Config.Tap.KubeContexts) > 0 {
+			config.Config.KubeContext = config.Config.Tap.KubeContexts[0]
+		}
+
 		if config.Config.Tap.Workspace != "" {
 			askConfirmation(configStructs.WorkspaceTapName)
 
@@ -101,6 +170,25 @@ func askConfirmation(flagName string) {
 	}
 }
 
+// readTapArgs sets the pod regex, or the targets when the arguments are references like deployment/cart, the names of
+// pods can't contain a slash so the two don't mix up
+func readTapArgs(args []string) error {
+	isTargets := len(args) > 0
+	for _, arg := range args {
+		isTargets = isTargets && strings.Contains(arg, "/")
+	}
+
+	if isTargets {
+		config.Config.Tap.Targets = append(config.Config.Tap.Targets, args...)
+	} else if len(args) == 1 {
+		config.Config.Tap.PodRegexStr = args[0]
+	} else if len(args) > 1 {
+		return errors.New("unexpected number of arguments")
+	}
+
+	return nil
+}
+
 func init() {
 	rootCmd.AddCommand(tapCmd)
 
@@ -111,16 +199,41 @@ func init() {
 
 	tapCmd.Flags().Uint16P(configStructs.GuiPortTapName, "p", defaultTapConfig.GuiPort, "Provide a custom port for the web interface webserver")
 	tapCmd.Flags().StringSliceP(configStructs.NamespacesTapName, "n", defaultTapConfig.Namespaces, "Namespaces selector")
+	tapCmd.Flags().StringP(configStructs.SelectorTapName, "l", defaultTapConfig.PodLabelSelectorStr, "Tap only the pods matching the regex that also match this label selector, like app=frontend,tier=web")
 	tapCmd.Flags().Bool(configStructs.AnalysisTapName, defaultTapConfig.Analysis, "Uploads traffic to UP9 for further analysis (Beta)")
 	tapCmd.Flags().BoolP(configStructs.AllNamespacesTapName, "A", defaultTapConfig.AllNamespaces, "Tap all namespaces")
 	tapCmd.Flags().StringSliceP(configStructs.PlainTextFilterRegexesTapName, "r", defaultTapConfig.PlainTextFilterRegexes, "List of regex expressions that are used to filter matching values from text/plain http bodies")
 	tapCmd.Flags().Bool(configStructs.DisableRedactionTapName, defaultTapConfig.DisableRedaction, "Disables redaction of potentially sensitive request/response headers and body values")
+	tapCmd.Flags().Bool(configStructs.DetectPiiTapName, defaultTapConfig.DetectPii, "Mask the emails, credit card numbers, phone numbers and JWTs found in the text bodies and count them per entry, the patterns are toggled by the tap.pii-patterns section of the config file")
 	tapCmd.Flags().String(configStructs.HumanMaxEntriesDBSizeTapName, defaultTapConfig.HumanMaxEntriesDBSize, "Override the default max entries db size")
+	tapCmd.Flags().String(configStructs.HumanMaxStreamBandwidthName, defaultTapConfig.HumanMaxStreamBandwidth, "Cap the bandwidth of every GUI streaming the entries per second, like 256KB, unlimited by default")
+	tapCmd.Flags().Float64(configStructs.SampleRateTapName, defaultTapConfig.SampleRate, "Record only this fraction of the connections, like 0.1, the requests of a recorded connection are all recorded")
+	tapCmd.Flags().Int(configStructs.MaxEntriesPerSecTapName, defaultTapConfig.MaxEntriesPerSec, "Cap the entries recorded for every pod each second, the tappers count the entries they drop, unlimited by default")
 	tapCmd.Flags().String(configStructs.InsertionFilterName, defaultTapConfig.InsertionFilter, "Set the insertion filter. Accepts string or a file path.")
+	tapCmd.Flags().String(configStructs.TargetsFileTapName, defaultTapConfig.TargetsFile, "YAML file listing the pods and workloads to tap instead of the pods matching the regex")
+	tapCmd.Flags().StringSlice(configStructs.TargetsTapName, defaultTapConfig.Targets, "Pods and workloads to tap instead of the pods matching the regex, as [[namespace/]kind/]name, - reads them from stdin")
 	tapCmd.Flags().Bool(configStructs.DryRunTapName, defaultTapConfig.DryRun, "Preview of all pods matching the regex, without tapping them")
+	tapCmd.Flags().Bool(configStructs.ShowTargetsTapName, defaultTapConfig.ShowTargets, "List the pods matching the regex with their nodes and IPs, and the nodes tappers would run on, without deploying anything")
 	tapCmd.Flags().StringP(configStructs.WorkspaceTapName, "w", defaultTapConfig.Workspace, "Uploads traffic to your UP9 workspace for further analysis (requires auth)")
 	tapCmd.Flags().String(configStructs.EnforcePolicyFile, defaultTapConfig.EnforcePolicyFile, "Yaml file path with policy rules")
 	tapCmd.Flags().String(configStructs.ContractFile, defaultTapConfig.ContractFile, "OAS/Swagger file to validate to monitor the contracts")
 	tapCmd.Flags().Bool(configStructs.ServiceMeshName, defaultTapConfig.ServiceMesh, "Record decrypted traffic if the cluster is configured with a service mesh and with mtls")
 	tapCmd.Flags().Bool(configStructs.TlsName, defaultTapConfig.Tls, "Record tls traffic")
+	tapCmd.Flags().Bool(configStructs.DeploymentMarkersName, defaultTapConfig.DeploymentMarkers, "Add markers to the entries timeline when deployments in the tapped namespaces change image or replica count")
+	tapCmd.Flags().Bool(configStructs.KubernetesEventsName, defaultTapConfig.KubernetesEvents, "Add the warning events of the tapped namespaces (failed probes, evictions, OOM kills) to the entries timeline")
+	tapCmd.Flags().Bool(configStructs.RawHeadersName, defaultTapConfig.RawHeaders, "Keep the raw HTTP/1.x header bytes (ordering, duplicates, casing) next to the parsed headers")
+	tapCmd.Flags().Bool(configStructs.DnsResolutionName, defaultTapConfig.DnsResolution, "Name the destinations outside the cluster by the reverse DNS lookup of their IP")
+	tapCmd.Flags().String(configStructs.TagHeaderTapName, defaultTapConfig.TagHeader, "Tag the http entries by the value of this request header, so requests marked by their sender are found by the tag == \"value\" query, empty to not tag")
+	tapCmd.Flags().Bool(configStructs.RelayTapName, defaultTapConfig.Relay, "Connect the tappers of the node pools other than the pool of the api server through a relay pod of their own pool, for clusters whose network blocks the traffic between the pools")
+	tapCmd.Flags().String(configStructs.RelayPoolLabelTapName, defaultTapConfig.RelayPoolLabel, "The node label that tells the pool of a node apart for --relay, the node pool label of GKE, EKS or AKS by default")
+	tapCmd.Flags().Bool(configStructs.PersistentStorageTapName, defaultTapConfig.PersistentStorage, "Store the entries on a persistent volume claim that outlives the api server pod and the session, the next tap with --persistent-storage shows them again and mizu clean removes the claim")
+	tapCmd.Flags().String(configStructs.StorageClassTapName, defaultTapConfig.StorageClass, "The storage class of the persistent volume claim of --persistent-storage, the default storage class of the cluster by default")
+	tapCmd.Flags().Bool(configStructs.AnnotationsTapName, defaultTapConfig.Annotations, "Honor the mizu.io/tap annotation of namespaces and pods, namespaces and pods annotated \"true\" are tapped and the ones annotated \"false\" are skipped regardless of the regex")
+	tapCmd.Flags().Bool(configStructs.TapperAuthenticationName, defaultTapConfig.TapperAuthentication, "Authenticate the tappers to the api server with projected service account tokens, so other pods can't send it entries (requires kubernetes 1.20 or later, ignored in namespace restricted mode)")
+	tapCmd.Flags().Bool(configStructs.OperatorTapName, defaultTapConfig.Operator, "Declare the tap as a MizuTap resource that the api server reconciles, so the pods are tapped after the cli exits, running again updates the tap and mizu clean removes it")
+	tapCmd.Flags().Bool(configStructs.DaemonTapName, defaultTapConfig.Daemon, "Leave the tap running in the cluster and exit once it's tapping, like --operator, mizu attach reconnects to it later and mizu clean removes it")
+	tapCmd.Flags().StringSlice(configStructs.ProtocolsTapName, defaultTapConfig.Protocols, "Record only the entries of these protocols, like http and kafka, all of them by default (requires --operator or --daemon)")
+	tapCmd.Flags().StringSlice(configStructs.KubeContextsTapName, defaultTapConfig.KubeContexts, "Tap the clusters of these kube contexts at once, the entries of all of them are shown by the api server of the first one, labeled by the context they were captured in")
+	tapCmd.Flags().Bool(configStructs.DockerTapName, defaultTapConfig.Docker, "Record the traffic of the local Docker containers (Docker Desktop or docker-compose) instead of a kubernetes cluster")
+	tapCmd.Flags().String(configStructs.InterfaceTapName, defaultTapConfig.Interface, "Record all the traffic of this network interface of the Docker host instead of the containers matching the regex (requires --docker)")
 }
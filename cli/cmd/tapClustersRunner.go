@@ -0,0 +1,235 @@
+package cmd
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/cmd/goUtils"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/mizu"
+	"github.com/up9inc/mizu/cli/mizu/fsUtils"
+	"github.com/up9inc/mizu/cli/resources"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/logger"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// the entries of a cluster are forwarded in batches of up to this many entries
+const clusterForwardBatchSize = 100
+
+// tappedCluster is one of the other clusters of a multi-cluster tap, its api server applies the tappers itself like
+// in operator mode and the cli forwards its entries to the api server of the first kube context, through a proxy on
+// its own local port
+type tappedCluster struct {
+	kubeContext        string
+	kubernetesProvider *kubernetes.Provider
+	port               uint16
+}
+
+func isMultiClusterTap() bool {
+	return len(config.Config.Tap.KubeContexts) > 1
+}
+
+// startTappedClusters taps the clusters of the other kube contexts and forwards their entries until ctx is done, a
+// cluster that can't be tapped is reported and skipped so it doesn't stop the tap of the others
+func startTappedClusters(ctx context.Context, cancel context.CancelFunc, serializedValidationRules string, serializedContract string) []*tappedCluster {
+	clusters := make([]*tappedCluster, 0)
+	for index, kubeContext := range config.Config.Tap.KubeContexts[1:] {
+		kubernetesProvider, err := getKubernetesProviderForContext(kubeContext)
+		if err != nil {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Skipping cluster %s, its kube context isn't usable", kubeContext))
+			continue
+		}
+
+		cluster := &tappedCluster{
+			kubeContext:        kubeContext,
+			kubernetesProvider: kubernetesProvider,
+			port:               config.Config.Tap.GuiPort + uint16(index) + 1,
+		}
+
+		logger.Log.Infof("Tapping cluster %s...", kubeContext)
+		if err := createTappedClusterResources(ctx, cluster, serializedValidationRules, serializedContract); err != nil {
+			var statusError *k8serrors.StatusError
+			if errors.As(err, &statusError) && (statusError.ErrStatus.Reason == metav1.StatusReasonAlreadyExists) {
+				logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Skipping cluster %s, mizu is already running in it, run `mizu clean --set kube-context=%s` to remove it", kubeContext, kubeContext))
+			} else {
+				logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Skipping cluster %s, error creating its resources: %v", kubeContext, errormessage.FormatError(err)))
+				cleanUpTappedCluster(cluster)
+			}
+			continue
+		}
+
+		clusters = append(clusters, cluster)
+		go goUtils.HandleExcWrapper(forwardClusterEntries, ctx, cancel, cluster)
+	}
+
+	return clusters
+}
+
+// createTappedClusterResources creates the resources of mizu tap in the cluster, with a MizuTap of the same pods that
+// its api server reconciles, the entries are labeled with the kube context
+func createTappedClusterResources(ctx context.Context, cluster *tappedCluster, serializedValidationRules string, serializedContract string) error {
+	if err := applyMizuTapCrd(ctx, cluster.kubernetesProvider); err != nil {
+		return err
+	}
+
+	mizuAgentConfig := getTapMizuAgentConfig()
+	mizuAgentConfig.Operator = true
+	mizuAgentConfig.Session = getSessionMetadata(cluster.kubernetesProvider, "tap", state.resourceNames.SessionId, state.startTime)
+	mizuAgentConfig.Session.Cluster = cluster.kubeContext
+	mizuAgentConfig.Cluster = cluster.kubeContext
+	serializedMizuConfig, err := getSerializedMizuAgentConfig(mizuAgentConfig)
+	if err != nil {
+		return err
+	}
+
+	// clean finds the resources of the session by the kube context
+	if state.resourceNames.SessionId != "" {
+		if err := fsUtils.SaveSessionId(cluster.kubeContext, config.Config.MizuResourcesNamespace, state.resourceNames.SessionId); err != nil {
+			return err
+		}
+	}
+
+	if _, err := resources.CreateTapMizuResources(ctx, cluster.kubernetesProvider, serializedValidationRules, serializedContract, serializedMizuConfig, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, state.resourceNames, config.Config.AgentImage, getSyncEntriesConfig(), config.Config.Tap.MaxEntriesDBSizeBytes(), config.Config.Tap.PersistentStorage, config.Config.Tap.StorageClass, config.Config.Tap.ApiServerResources, config.Config.ImagePullPolicy(), config.Config.LogLevel(), config.Config.Provenance, config.Config.CloudIdentity); err != nil {
+		return err
+	}
+
+	if err := cluster.kubernetesProvider.CreateMizuOperatorRBAC(ctx, config.Config.MizuResourcesNamespace, kubernetes.ServiceAccountName, kubernetes.OperatorRoleName, kubernetes.OperatorRoleBindingName, mizu.RBACVersion); err != nil {
+		return err
+	}
+
+	mizuApiFilteringOptions, err := getMizuApiFilteringOptions()
+	if err != nil {
+		return err
+	}
+
+	return cluster.kubernetesProvider.ApplyMizuTap(ctx, config.Config.MizuResourcesNamespace, state.resourceNames.MizuTapName, getMizuTapSpec(getNamespaces(cluster.kubernetesProvider), getTapTargets(cluster.kubernetesProvider), *mizuApiFilteringOptions))
+}
+
+// forwardClusterEntries polls the api server of the cluster for new entries, like fetch --follow, and stores them in
+// the api server of the first kube context until ctx is done
+func forwardClusterEntries(ctx context.Context, cancel context.CancelFunc, cluster *tappedCluster) {
+	if _, err := kubernetes.StartProxy(cluster.kubernetesProvider, config.Config.Tap.ProxyHost, cluster.port, config.Config.MizuResourcesNamespace, state.resourceNames.ApiServerPodName, cancel); err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error proxying cluster %s on port %d, its entries aren't forwarded: %v", cluster.kubeContext, cluster.port, errormessage.FormatError(err)))
+		return
+	}
+
+	clusterApiProvider := apiserver.NewProvider(GetApiServerUrl(cluster.port), 1, apiserver.DefaultTimeout)
+
+	cursor := -1
+	forwarding := false
+	retryInterval := followPollInterval
+	for ctx.Err() == nil {
+		forwarded, nextCursor, err := forwardClusterEntriesBatch(clusterApiProvider, cursor)
+		if err != nil {
+			// the api servers take a while to start
+			logger.Log.Debugf("Failed forwarding the entries of cluster %s, retrying in %v: %v", cluster.kubeContext, retryInterval, err)
+			sleepUnlessDone(ctx, retryInterval)
+			if retryInterval *= 2; retryInterval > followMaxRetryInterval {
+				retryInterval = followMaxRetryInterval
+			}
+			continue
+		}
+		retryInterval = followPollInterval
+
+		if !forwarding {
+			forwarding = true
+			logger.Log.Infof("Forwarding the entries of cluster %s", cluster.kubeContext)
+		}
+
+		if nextCursor < cursor {
+			logger.Log.Infof("The entries database of cluster %s was reset, forwarding it from its start", cluster.kubeContext)
+		}
+		cursor = nextCursor
+
+		if forwarded < clusterForwardBatchSize {
+			sleepUnlessDone(ctx, followPollInterval)
+		}
+	}
+}
+
+// forwardClusterEntriesBatch forwards the entries of the cluster after the database index cursor, it returns how many
+// were forwarded and the cursor to continue from, the batch is retried from the same cursor when it fails
+func forwardClusterEntriesBatch(clusterApiProvider *apiserver.Provider, cursor int) (int, int, error) {
+	baseEntries, metadata, err := clusterApiProvider.GetEntriesAfter("", cursor+1, clusterForwardBatchSize)
+	if err != nil {
+		return 0, cursor, err
+	}
+
+	if len(baseEntries) == 0 && metadata.Total <= cursor {
+		// the api server restarted with an empty database
+		return 0, -1, nil
+	}
+
+	var archive bytes.Buffer
+	gzipWriter := gzip.NewWriter(&archive)
+
+	forwarded := 0
+	nextCursor := cursor
+	for _, baseEntry := range baseEntries {
+		index, ok := getEntryIndex(baseEntry)
+		if !ok || index <= cursor {
+			continue
+		}
+
+		entry, err := clusterApiProvider.GetEntry(getEntryIdForFetch(baseEntry))
+		if err != nil {
+			return 0, cursor, err
+		}
+
+		line, err := json.Marshal(entry["data"])
+		if err != nil {
+			return 0, cursor, err
+		}
+
+		if _, err := gzipWriter.Write(append(line, '\n')); err != nil {
+			return 0, cursor, err
+		}
+		forwarded++
+		nextCursor = index
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return 0, cursor, err
+	}
+
+	if forwarded == 0 {
+		return 0, cursor, nil
+	}
+
+	if _, err := apiProvider.PostArchivedEntries(archive.Bytes()); err != nil {
+		return 0, cursor, err
+	}
+
+	return forwarded, nextCursor, nil
+}
+
+// finishTappedClusters removes the resources of the clusters, the api server of the first kube context is removed by
+// finishTapExecution
+func finishTappedClusters(clusters []*tappedCluster) {
+	for _, cluster := range clusters {
+		logger.Log.Infof("Removing the mizu resources of cluster %s...", cluster.kubeContext)
+		cleanUpTappedCluster(cluster)
+	}
+}
+
+func cleanUpTappedCluster(cluster *tappedCluster) {
+	removalCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+	resources.CleanUpMizuResources(removalCtx, cancel, cluster.kubernetesProvider, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, state.resourceNames, config.Config.Tap.PersistentStorage)
+	if config.Config.Tap.PersistentStorage {
+		return
+	}
+
+	if err := fsUtils.RemoveSessionId(cluster.kubeContext, config.Config.MizuResourcesNamespace); err != nil {
+		logger.Log.Debugf("Failed removing the session state of cluster %s, err: %v", cluster.kubeContext, err)
+	}
+}
@@ -0,0 +1,367 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"reflect"
+	"strings"
+	"time"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/docker"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/cli/utils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	dockerApiServerContainerName = kubernetes.ApiServerPodName
+	dockerBasenineContainerName  = kubernetes.MizuResourcesPrefix + "basenine"
+	dockerTapperContainerName    = kubernetes.TapperPodName
+	// the tapper runs in the network namespace of the docker host, which is the VM of Docker Desktop on Windows and macOS
+	dockerHostNetwork   = "host"
+	dockerNodeName      = "docker"
+	dockerApiServerPort = 8080
+
+	dockerContainersSyncInterval = 5 * time.Second
+)
+
+var dockerContainerNames = []string{dockerTapperContainerName, dockerBasenineContainerName, dockerApiServerContainerName}
+
+// RunMizuTapDocker taps the containers of the local docker daemon, the agent runs in containers next to them
+// and is reached on the gui port like the port-forwarded agent of a cluster
+func RunMizuTapDocker() {
+	state.startTime = time.Now()
+
+	apiProvider = apiserver.NewProvider(GetApiServerUrl(config.Config.Tap.GuiPort), apiserver.DefaultRetries, apiserver.DefaultTimeout)
+
+	serializedValidationRules, serializedContract, ok := readTapPolicyFiles()
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dockerProvider, err := docker.NewProvider(ctx)
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error connecting to Docker: %v", errormessage.FormatError(err)))
+		return
+	}
+
+	var containers []docker.Container
+	if config.Config.Tap.Interface != "" {
+		logger.Log.Infof("Tapping the %s interface of the Docker host", config.Config.Tap.Interface)
+	} else {
+		containers, err = dockerProvider.ListRunningContainers(ctx, config.Config.Tap.PodRegex())
+		if err != nil {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error listing containers: %v", errormessage.FormatError(err)))
+			return
+		}
+
+		logger.Log.Infof("Tapping local Docker containers")
+		if len(containers) == 0 {
+			logger.Log.Warningf(uiUtils.Warning, "Did not find any currently running containers that match the regex argument, mizu will automatically tap matching containers if any are started later")
+		}
+		for _, container := range containers {
+			logger.Log.Infof(uiUtils.Green, fmt.Sprintf("+%s", container.Name))
+		}
+	}
+
+	if config.Config.Tap.DryRun {
+		return
+	}
+
+	for _, containerName := range dockerContainerNames {
+		if exists, err := dockerProvider.ContainerExists(ctx, containerName); err != nil {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error listing containers: %v", errormessage.FormatError(err)))
+			return
+		} else if exists {
+			logger.Log.Infof("Mizu is already running in Docker, stop the other `mizu tap --%s` or remove the %s container", configStructs.DockerTapName, containerName)
+			return
+		}
+	}
+
+	mizuAgentConfig := getTapMizuAgentConfig()
+	// there's no cluster to watch for deployments and events
+	mizuAgentConfig.DeploymentMarkers = false
+	mizuAgentConfig.KubernetesEvents = false
+	// nor tokens to review
+	mizuAgentConfig.TapperAuthentication = false
+	// nor secrets to mount the provenance key from
+	mizuAgentConfig.Provenance.SecretName = ""
+	mizuAgentConfig.Session = getSessionMetadata(nil, "tap", "", state.startTime)
+	mizuAgentConfig.Cluster = mizuAgentConfig.Session.Cluster
+	serializedMizuConfig, err := getSerializedMizuAgentConfig(mizuAgentConfig)
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error serializing mizu config: %v", errormessage.FormatError(err)))
+		return
+	}
+
+	defer finishDockerTapExecution(dockerProvider)
+
+	logger.Log.Infof("Waiting for Mizu Agent to start...")
+	if err := createDockerApiServer(ctx, dockerProvider, serializedValidationRules, serializedContract, serializedMizuConfig); err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error creating containers: %v", errormessage.FormatError(err)))
+		return
+	}
+
+	apiServerTimeoutSec := config.GetIntEnvConfig(config.ApiServerTimeoutSec, 120)
+	if err := apiserver.NewProvider(GetApiServerUrl(config.Config.Tap.GuiPort), apiServerTimeoutSec, apiserver.DefaultTimeout).TestConnection(); err != nil {
+		logger.Log.Errorf(uiUtils.Error, "Mizu API server was not ready in time")
+		return
+	}
+
+	if err := applyDockerTapper(ctx, dockerProvider, containers); err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error starting mizu tapper: %v", errormessage.FormatError(err)))
+		return
+	}
+
+	if config.Config.Tap.Interface == "" {
+		go syncDockerTapper(ctx, dockerProvider, containers)
+	}
+
+	url := GetApiServerUrl(config.Config.Tap.GuiPort)
+	logger.Log.Infof("Mizu is available at %s", url)
+	if !config.Config.HeadlessMode {
+		uiUtils.OpenBrowser(url)
+	}
+
+	// block until exit signal or error
+	utils.WaitForFinish(ctx, cancel)
+}
+
+func createDockerApiServer(ctx context.Context, dockerProvider *docker.Provider, serializedValidationRules string, serializedContract string, serializedMizuConfig string) error {
+	configDir, err := ioutil.TempDir("", "mizu-config")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(configDir)
+
+	// the same files the config map holds in a cluster
+	configFiles := map[string]string{
+		shared.ConfigFileName:          serializedMizuConfig,
+		shared.ValidationRulesFileName: serializedValidationRules,
+		shared.ContractFileName:        serializedContract,
+	}
+	for fileName, content := range configFiles {
+		if content == "" {
+			continue
+		}
+		if err := ioutil.WriteFile(path.Join(configDir, fileName), []byte(content), 0644); err != nil {
+			return err
+		}
+	}
+
+	env := map[string]string{
+		shared.LogLevelEnvVar: config.Config.LogLevel().String(),
+	}
+	if syncEntriesConfig := getSyncEntriesConfig(); syncEntriesConfig != nil {
+		marshaledSyncEntriesConfig, err := json.Marshal(syncEntriesConfig)
+		if err != nil {
+			return err
+		}
+		env[shared.SyncEntriesConfigEnvVar] = string(marshaledSyncEntriesConfig)
+	}
+
+	if err := dockerProvider.CreateContainer(ctx, &docker.ContainerOptions{
+		Name:         dockerApiServerContainerName,
+		Image:        config.Config.AgentImage,
+		PullAlways:   config.Config.ImagePullPolicy() == core.PullAlways,
+		PublishPorts: []string{fmt.Sprintf("%s:%d:%d", config.Config.Tap.ProxyHost, config.Config.Tap.GuiPort, dockerApiServerPort)},
+		Env:          env,
+		Args:         []string{"--api-server"},
+	}); err != nil {
+		return err
+	}
+	if err := dockerProvider.CopyDirToContainer(ctx, dockerApiServerContainerName, configDir, strings.TrimSuffix(shared.ConfigDirPath, "/")); err != nil {
+		return err
+	}
+	if err := dockerProvider.StartContainer(ctx, dockerApiServerContainerName); err != nil {
+		return err
+	}
+
+	// the api server reaches basenine on localhost, as the containers of its pod do
+	if err := dockerProvider.CreateContainer(ctx, &docker.ContainerOptions{
+		Name:       dockerBasenineContainerName,
+		Image:      config.Config.AgentImage,
+		Entrypoint: "basenine",
+		WorkingDir: shared.DataDirPath,
+		Network:    fmt.Sprintf("container:%s", dockerApiServerContainerName),
+		Args:       []string{"-addr", "0.0.0.0", "-port", shared.BaseninePort, "-persistent"},
+	}); err != nil {
+		return err
+	}
+	return dockerProvider.StartContainer(ctx, dockerBasenineContainerName)
+}
+
+// applyDockerTapper (re)creates the tapper with the containers as its targets, like the daemon set is updated
+// with the tapped pods in a cluster, or with the interface of --interface
+func applyDockerTapper(ctx context.Context, dockerProvider *docker.Provider, containers []docker.Container) error {
+	tappedPods := getDockerTappedPods(containers)
+	if err := apiProvider.ReportTappedPods(tappedPods); err != nil {
+		logger.Log.Debugf("[Error] failed update tapped pods %v", err)
+	}
+
+	if err := dockerProvider.RemoveContainer(ctx, dockerTapperContainerName); err != nil {
+		logger.Log.Debugf("Failed removing the previous tapper container, err: %v", err)
+	}
+
+	apiServerIp, err := dockerProvider.GetContainerIP(ctx, dockerApiServerContainerName)
+	if err != nil {
+		return err
+	}
+
+	mizuApiFilteringOptions, err := getMizuApiFilteringOptions()
+	if err != nil {
+		return err
+	}
+	mizuApiFilteringOptionsJson, err := json.Marshal(mizuApiFilteringOptions)
+	if err != nil {
+		return err
+	}
+	socketKeepAliveJson, err := json.Marshal(config.Config.SocketKeepAlive)
+	if err != nil {
+		return err
+	}
+
+	env := map[string]string{
+		shared.LogLevelEnvVar:             config.Config.LogLevel().String(),
+		shared.GoGCEnvVar:                 "12800",
+		shared.MizuFilteringOptionsEnvVar: string(mizuApiFilteringOptionsJson),
+		shared.SocketKeepAliveEnvVar:      string(socketKeepAliveJson),
+	}
+
+	// without host mode the tapper records all the traffic of its interface
+	tappedInterface := config.Config.Tap.Interface
+	if tappedInterface == "" {
+		tappedInterface = "any"
+
+		nodeToTappedPodMap := map[string][]core.Pod{dockerNodeName: tappedPods}
+		nodeToTappedPodMapJson, err := json.Marshal(nodeToTappedPodMap)
+		if err != nil {
+			return err
+		}
+
+		env[shared.HostModeEnvVar] = "1"
+		env[shared.NodeNameEnvVar] = dockerNodeName
+		env[shared.TappedAddressesPerNodeDictEnvVar] = string(nodeToTappedPodMapJson)
+	}
+
+	if err := dockerProvider.CreateContainer(ctx, &docker.ContainerOptions{
+		Name:    dockerTapperContainerName,
+		Image:   config.Config.AgentImage,
+		Network: dockerHostNetwork,
+		CapAdd:  []string{"NET_RAW", "NET_ADMIN"},
+		Env:     env,
+		Args:    []string{"-i", tappedInterface, "--tap", "--api-server-address", fmt.Sprintf("ws://%s:%d/wsTapper", apiServerIp, dockerApiServerPort), "--nodefrag"},
+	}); err != nil {
+		return err
+	}
+	return dockerProvider.StartContainer(ctx, dockerTapperContainerName)
+}
+
+// getDockerTappedPods describes the containers as the pods the tapper and the api server expect, a container
+// attached to several networks is listed once per address
+func getDockerTappedPods(containers []docker.Container) []core.Pod {
+	pods := make([]core.Pod, 0)
+	for _, container := range containers {
+		namespace := container.Project
+		if namespace == "" {
+			namespace = dockerNodeName
+		}
+
+		for _, ip := range container.IPs {
+			pods = append(pods, core.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      container.Name,
+					Namespace: namespace,
+				},
+				Spec: core.PodSpec{
+					NodeName: dockerNodeName,
+				},
+				Status: core.PodStatus{
+					PodIP: ip,
+					Phase: core.PodRunning,
+				},
+			})
+		}
+	}
+	return pods
+}
+
+// syncDockerTapper polls the running containers, docker has no watch that's cheaper for the few containers of
+// a developer machine
+func syncDockerTapper(ctx context.Context, dockerProvider *docker.Provider, containers []docker.Container) {
+	ticker := time.NewTicker(dockerContainersSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			currentContainers, err := dockerProvider.ListRunningContainers(ctx, config.Config.Tap.PodRegex())
+			if err != nil {
+				logger.Log.Debugf("[Error] failed listing containers %v", err)
+				continue
+			}
+			if reflect.DeepEqual(containers, currentContainers) {
+				continue
+			}
+
+			logDockerContainerChanges(containers, currentContainers)
+			if err := applyDockerTapper(ctx, dockerProvider, currentContainers); err != nil {
+				logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error updating mizu tapper: %v", errormessage.FormatError(err)))
+				continue
+			}
+			containers = currentContainers
+		case <-ctx.Done():
+			logger.Log.Debugf("Docker containers sync loop, ctx done")
+			return
+		}
+	}
+}
+
+func logDockerContainerChanges(previous []docker.Container, current []docker.Container) {
+	previousNames := make(map[string]bool)
+	for _, container := range previous {
+		previousNames[container.Name] = true
+	}
+	currentNames := make(map[string]bool)
+	for _, container := range current {
+		currentNames[container.Name] = true
+		if !previousNames[container.Name] {
+			logger.Log.Infof(uiUtils.Green, fmt.Sprintf("+%s", container.Name))
+		}
+	}
+	for _, container := range previous {
+		if !currentNames[container.Name] {
+			logger.Log.Infof(uiUtils.Red, fmt.Sprintf("-%s", container.Name))
+		}
+	}
+}
+
+func finishDockerTapExecution(dockerProvider *docker.Provider) {
+	telemetry.ReportTapTelemetry(apiProvider, config.Config.Tap, state.startTime)
+
+	removalCtx, cancel := context.WithTimeout(context.Background(), cleanupTimeout)
+	defer cancel()
+
+	for _, containerName := range dockerContainerNames {
+		if err := dockerProvider.RemoveContainer(removalCtx, containerName); err != nil {
+			logger.Log.Debugf("Failed removing container %s, err: %v", containerName, err)
+		}
+	}
+}
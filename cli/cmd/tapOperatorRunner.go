@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/mizu"
+	"github.com/up9inc/mizu/cli/resources"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/kubernetes"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/tap/api"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+const reconcileCheckInterval = 2 * time.Second
+
+// runMizuTapOperator declares the tap as a MizuTap that the api server reconciles, the first run creates the mizu
+// resources and the later ones only change the tap, the cli exits without removing anything, a daemon tap exits once the
+// tap is reconciled
+func runMizuTapOperator(ctx context.Context, kubernetesProvider *kubernetes.Provider, serializedValidationRules string, serializedContract string) {
+	if err := applyMizuTapCrd(ctx, kubernetesProvider); err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error creating the %s resource definition: %v", kubernetes.MizuTapKind, errormessage.FormatError(err)))
+		return
+	}
+
+	isRunning, err := kubernetesProvider.DoesServiceExist(ctx, config.Config.MizuResourcesNamespace, state.resourceNames.ApiServerPodName)
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error checking for a running mizu: %v", errormessage.FormatError(err)))
+		return
+	}
+
+	if isRunning {
+		logger.Log.Infof("Mizu is already running in namespace %s, updating its tap, the other options apply once it's cleaned and tapped again", config.Config.MizuResourcesNamespace)
+	} else if !createMizuOperatorResources(ctx, kubernetesProvider, serializedValidationRules, serializedContract) {
+		return
+	}
+
+	mizuApiFilteringOptions, err := getMizuApiFilteringOptions()
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error parsing the regex masking: %v", errormessage.FormatError(err)))
+		return
+	}
+
+	if err := kubernetesProvider.ApplyMizuTap(ctx, config.Config.MizuResourcesNamespace, state.resourceNames.MizuTapName, getMizuTapSpec(state.targetNamespaces, getTapTargets(kubernetesProvider), *mizuApiFilteringOptions)); err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error applying tap %s: %v", state.resourceNames.MizuTapName, errormessage.FormatError(err)))
+		return
+	}
+
+	if config.Config.Tap.Daemon {
+		detachMizuTap(ctx, kubernetesProvider)
+		return
+	}
+
+	logger.Log.Infof("Tap %s is applied, the api server keeps tapping the matching pods until `mizu clean`", state.resourceNames.MizuTapName)
+	logger.Log.Infof("Run `mizu view` to open the GUI, the tap status is shown by: kubectl get %s -n %s", kubernetes.MizuTapPlural, config.Config.MizuResourcesNamespace)
+}
+
+// detachMizuTap waits for the api server to reconcile the applied tap before the cli exits, so a daemon tap that
+// can't start is reported instead of being left behind silently
+func detachMizuTap(ctx context.Context, kubernetesProvider *kubernetes.Provider) {
+	logger.Log.Infof("Waiting for the api server to start tapping...")
+	mizuTap, err := waitForMizuTapReconciled(ctx, kubernetesProvider)
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error waiting for tap %s: %v", state.resourceNames.MizuTapName, errormessage.FormatError(err)))
+		logger.Log.Infof("The tap is left in namespace %s, run `mizu clean` to remove it", config.Config.MizuResourcesNamespace)
+		return
+	}
+
+	if mizuTap.Status.Phase == kubernetes.MizuTapPhaseFailed {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Tap %s failed, the api server keeps retrying it: %s", state.resourceNames.MizuTapName, mizuTap.Status.Message))
+		logger.Log.Infof("Run `mizu attach` to follow it or `mizu clean` to remove it")
+		return
+	}
+
+	logger.Log.Infof(uiUtils.Green, fmt.Sprintf("Mizu is tapping %d pods in the background, it keeps capturing after the cli exits", mizuTap.Status.TappedPods))
+	logger.Log.Infof("Run `mizu attach` to reconnect, `mizu view` to only open the GUI and `mizu clean` to stop tapping and remove mizu")
+}
+
+// waitForMizuTapReconciled polls the tap until the api server reports the outcome of its current spec, it's
+// reconciled once the api server is running, which takes as long as the api server takes to start
+func waitForMizuTapReconciled(ctx context.Context, kubernetesProvider *kubernetes.Provider) (*kubernetes.MizuTap, error) {
+	apiServerTimeoutSec := config.GetIntEnvConfig(config.ApiServerTimeoutSec, 120)
+	timeoutCtx, cancel := context.WithTimeout(ctx, time.Duration(apiServerTimeoutSec)*time.Second)
+	defer cancel()
+
+	ticker := time.NewTicker(reconcileCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		mizuTap, err := kubernetesProvider.GetMizuTap(timeoutCtx, config.Config.MizuResourcesNamespace, state.resourceNames.MizuTapName)
+		if err != nil && timeoutCtx.Err() == nil {
+			return nil, err
+		}
+		if err == nil && mizuTap.Status.Phase != "" && mizuTap.Status.ObservedGeneration >= mizuTap.Generation {
+			return mizuTap, nil
+		}
+
+		select {
+		case <-ticker.C:
+		case <-timeoutCtx.Done():
+			return nil, fmt.Errorf("the api server didn't reconcile the tap in %d seconds", apiServerTimeoutSec)
+		}
+	}
+}
+
+// createMizuOperatorResources creates the resources of mizu tap with the permissions the api server needs to apply
+// the tapper daemon set itself, they're removed when they aren't all created
+func createMizuOperatorResources(ctx context.Context, kubernetesProvider *kubernetes.Provider, serializedValidationRules string, serializedContract string) bool {
+	mizuAgentConfig := getTapMizuAgentConfig()
+	mizuAgentConfig.Session = getSessionMetadata(kubernetesProvider, "tap", state.resourceNames.SessionId, state.startTime)
+	mizuAgentConfig.Cluster = mizuAgentConfig.Session.Cluster
+	serializedMizuConfig, err := getSerializedMizuAgentConfig(mizuAgentConfig)
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error serializing mizu config: %v", errormessage.FormatError(err)))
+		return false
+	}
+
+	logger.Log.Infof("Creating the Mizu Agent...")
+	if state.mizuServiceAccountExists, err = resources.CreateTapMizuResources(ctx, kubernetesProvider, serializedValidationRules, serializedContract, serializedMizuConfig, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, state.resourceNames, config.Config.AgentImage, getSyncEntriesConfig(), config.Config.Tap.MaxEntriesDBSizeBytes(), config.Config.Tap.PersistentStorage, config.Config.Tap.StorageClass, config.Config.Tap.ApiServerResources, config.Config.ImagePullPolicy(), config.Config.LogLevel(), config.Config.Provenance, config.Config.CloudIdentity); err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error creating resources: %v", errormessage.FormatError(err)))
+		finishMizuExecution(kubernetesProvider, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, state.resourceNames, config.Config.Tap.PersistentStorage)
+		return false
+	}
+
+	if err := kubernetesProvider.CreateMizuOperatorRBAC(ctx, config.Config.MizuResourcesNamespace, kubernetes.ServiceAccountName, kubernetes.OperatorRoleName, kubernetes.OperatorRoleBindingName, mizu.RBACVersion); err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error creating the permissions of the api server: %v", errormessage.FormatError(err)))
+		finishMizuExecution(kubernetesProvider, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, state.resourceNames, config.Config.Tap.PersistentStorage)
+		return false
+	}
+
+	return true
+}
+
+// applyMizuTapCrd applies the MizuTap resource definition, in namespace-restricted mode the definition, which is
+// clusterwide, is expected to be installed already when it can't be applied
+func applyMizuTapCrd(ctx context.Context, kubernetesProvider *kubernetes.Provider) error {
+	err := kubernetesProvider.ApplyMizuTapCrd(ctx)
+	if err != nil && k8serrors.IsForbidden(err) && config.Config.IsNsRestrictedMode() {
+		logger.Log.Debugf("Not allowed to apply the %s resource definition, assuming it's installed: %v", kubernetes.MizuTapKind, err)
+		return nil
+	}
+
+	return err
+}
+
+// getMizuTapSpec declares the pods mizu tap would tap in targetNamespaces, or the targets when there are any, with the
+// options of the tappers the api server applies for it
+func getMizuTapSpec(targetNamespaces []string, targets []kubernetes.TapTarget, mizuApiFilteringOptions api.TrafficFilteringOptions) kubernetes.MizuTapSpec {
+	if shared.Contains(targetNamespaces, kubernetes.K8sAllNamespaces) {
+		targetNamespaces = nil
+	}
+
+	return kubernetes.MizuTapSpec{
+		TargetNamespaces:        targetNamespaces,
+		PodRegex:                config.Config.Tap.PodRegexStr,
+		PodLabelSelector:        config.Config.Tap.PodLabelSelectorStr,
+		TapAnnotations:          config.Config.Tap.Annotations,
+		Targets:                 targets,
+		Protocols:               config.Config.Tap.Protocols,
+		MaxEntriesDBSize:        config.Config.Tap.HumanMaxEntriesDBSize,
+		ServiceMesh:             config.Config.Tap.ServiceMesh,
+		Tls:                     config.Config.Tap.Tls,
+		TapperScheduling:        config.Config.Tap.TapperScheduling,
+		Relay:                   config.Config.Tap.RelayConfig(),
+		TrafficFilteringOptions: mizuApiFilteringOptions,
+	}
+}
@@ -5,8 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
 	"regexp"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/up9inc/mizu/cli/resources"
@@ -31,12 +34,17 @@ import (
 	"github.com/up9inc/mizu/tap/api"
 )
 
-const cleanupTimeout = time.Minute
+const (
+	cleanupTimeout        = time.Minute
+	pressureCheckInterval = 15 * time.Second
+)
 
 type tapState struct {
 	startTime                time.Time
 	targetNamespaces         []string
+	targets                  []kubernetes.TapTarget
 	mizuServiceAccountExists bool
+	resourceNames            kubernetes.ResourceNames
 }
 
 var state tapState
@@ -47,38 +55,9 @@ func RunMizuTap() {
 
 	apiProvider = apiserver.NewProvider(GetApiServerUrl(config.Config.Tap.GuiPort), apiserver.DefaultRetries, apiserver.DefaultTimeout)
 
-	var err error
-	var serializedValidationRules string
-	if config.Config.Tap.EnforcePolicyFile != "" {
-		serializedValidationRules, err = readValidationRules(config.Config.Tap.EnforcePolicyFile)
-		if err != nil {
-			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error reading policy file: %v", errormessage.FormatError(err)))
-			return
-		}
-	}
-
-	// Read and validate the OAS file
-	var serializedContract string
-	if config.Config.Tap.ContractFile != "" {
-		bytes, err := ioutil.ReadFile(config.Config.Tap.ContractFile)
-		if err != nil {
-			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error reading contract file: %v", errormessage.FormatError(err)))
-			return
-		}
-		serializedContract = string(bytes)
-
-		ctx := context.Background()
-		loader := &openapi3.Loader{Context: ctx}
-		doc, err := loader.LoadFromData(bytes)
-		if err != nil {
-			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error loading contract file: %v", errormessage.FormatError(err)))
-			return
-		}
-		err = doc.Validate(ctx)
-		if err != nil {
-			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error validating contract file: %v", errormessage.FormatError(err)))
-			return
-		}
+	serializedValidationRules, serializedContract, ok := readTapPolicyFiles()
+	if !ok {
+		return
 	}
 
 	kubernetesProvider, err := getKubernetesProviderForCli()
@@ -91,19 +70,17 @@ func RunMizuTap() {
 
 	state.targetNamespaces = getNamespaces(kubernetesProvider)
 
-	mizuAgentConfig := getTapMizuAgentConfig()
-	serializedMizuConfig, err := getSerializedMizuAgentConfig(mizuAgentConfig)
-	if err != nil {
-		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error serializing mizu config: %v", errormessage.FormatError(err)))
-		return
-	}
-
 	if config.Config.IsNsRestrictedMode() {
 		if len(state.targetNamespaces) != 1 || !shared.Contains(state.targetNamespaces, config.Config.MizuResourcesNamespace) {
 			logger.Log.Errorf("Not supported mode. Mizu can't resolve IPs in other namespaces when running in namespace restricted mode.\n"+
 				"You can use the same namespace for --%s and --%s", configStructs.NamespacesTapName, config.MizuResourcesNamespaceConfigName)
 			return
 		}
+
+		if config.Config.Tap.Annotations {
+			logger.Log.Errorf("Not supported mode. Mizu can't discover annotated namespaces when running in namespace restricted mode, run without --%s", configStructs.AnnotationsTapName)
+			return
+		}
 	}
 
 	var namespacesStr string
@@ -112,8 +89,22 @@ func RunMizuTap() {
 	} else {
 		namespacesStr = "all namespaces"
 	}
+	if config.Config.Tap.Annotations && !shared.Contains(state.targetNamespaces, kubernetes.K8sAllNamespaces) {
+		namespacesStr = fmt.Sprintf("%s and the namespaces annotated %s: \"%s\"", namespacesStr, kubernetes.AnnotationTap, kubernetes.AnnotationTapOptIn)
+	}
+
+	if config.Config.Tap.ShowTargets {
+		if err := printTapTargets(ctx, kubernetesProvider, state.targetNamespaces); err != nil {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error listing pods: %v", errormessage.FormatError(err)))
+		}
+		return
+	}
 
-	logger.Log.Infof("Tapping pods in %s", namespacesStr)
+	if len(state.targets) > 0 {
+		logger.Log.Infof("Tapping %d targets in %s", len(state.targets), namespacesStr)
+	} else {
+		logger.Log.Infof("Tapping pods in %s", namespacesStr)
+	}
 
 	if err := printTappedPodsPreview(ctx, kubernetesProvider, state.targetNamespaces); err != nil {
 		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error listing pods: %v", errormessage.FormatError(err)))
@@ -123,13 +114,36 @@ func RunMizuTap() {
 		return
 	}
 
+	if state.resourceNames, err = startSession(); err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error starting mizu session: %v", errormessage.FormatError(err)))
+		return
+	}
+
+	if config.Config.Tap.IsOperatorTap() {
+		runMizuTapOperator(ctx, kubernetesProvider, serializedValidationRules, serializedContract)
+		return
+	}
+
+	mizuAgentConfig := getTapMizuAgentConfig()
+	mizuAgentConfig.Session = getSessionMetadata(kubernetesProvider, "tap", state.resourceNames.SessionId, state.startTime)
+	if isMultiClusterTap() {
+		// the entries of all the clusters are stored together, labeled by their kube context
+		mizuAgentConfig.Session.Cluster = config.Config.KubeContext
+	}
+	mizuAgentConfig.Cluster = mizuAgentConfig.Session.Cluster
+	serializedMizuConfig, err := getSerializedMizuAgentConfig(mizuAgentConfig)
+	if err != nil {
+		logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error serializing mizu config: %v", errormessage.FormatError(err)))
+		return
+	}
+
 	logger.Log.Infof("Waiting for Mizu Agent to start...")
-	if state.mizuServiceAccountExists, err = resources.CreateTapMizuResources(ctx, kubernetesProvider, serializedValidationRules, serializedContract, serializedMizuConfig, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, config.Config.AgentImage, getSyncEntriesConfig(), config.Config.Tap.MaxEntriesDBSizeBytes(), config.Config.Tap.ApiServerResources, config.Config.ImagePullPolicy(), config.Config.LogLevel()); err != nil {
+	if state.mizuServiceAccountExists, err = resources.CreateTapMizuResources(ctx, kubernetesProvider, serializedValidationRules, serializedContract, serializedMizuConfig, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, state.resourceNames, config.Config.AgentImage, getSyncEntriesConfig(), config.Config.Tap.MaxEntriesDBSizeBytes(), config.Config.Tap.PersistentStorage, config.Config.Tap.StorageClass, config.Config.Tap.ApiServerResources, config.Config.ImagePullPolicy(), config.Config.LogLevel(), config.Config.Provenance, config.Config.CloudIdentity); err != nil {
 		var statusError *k8serrors.StatusError
 		if errors.As(err, &statusError) && (statusError.ErrStatus.Reason == metav1.StatusReasonAlreadyExists) {
 			logger.Log.Info("Mizu is already running in this namespace, change the `mizu-resources-namespace` configuration or run `mizu clean` to remove the currently running Mizu instance")
 		} else {
-			defer resources.CleanUpMizuResources(ctx, cancel, kubernetesProvider, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace)
+			defer resources.CleanUpMizuResources(ctx, cancel, kubernetesProvider, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, state.resourceNames, config.Config.Tap.PersistentStorage)
 			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error creating resources: %v", errormessage.FormatError(err)))
 		}
 
@@ -141,42 +155,189 @@ func RunMizuTap() {
 	go goUtils.HandleExcWrapper(watchApiServerEvents, ctx, kubernetesProvider, cancel)
 	go goUtils.HandleExcWrapper(watchApiServerPod, ctx, kubernetesProvider, cancel)
 
+	if isMultiClusterTap() {
+		tappedClusters := startTappedClusters(ctx, cancel, serializedValidationRules, serializedContract)
+		defer finishTappedClusters(tappedClusters)
+	}
+
 	// block until exit signal or error
 	utils.WaitForFinish(ctx, cancel)
 }
 
+// readTapPolicyFiles reads the validation rules and the contract, the errors are logged and reported as not ok
+// readTapTargets reads the targets of --targets-file and --targets, the - target reads more targets from stdin, there
+// are no targets when the pods are matched by the regex
+func readTapTargets() ([]kubernetes.TapTarget, error) {
+	if !config.Config.Tap.HasTargets() {
+		return nil, nil
+	}
+
+	targets := make([]kubernetes.TapTarget, 0)
+	if config.Config.Tap.TargetsFile != "" {
+		data, err := ioutil.ReadFile(config.Config.Tap.TargetsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading the targets file: %w", err)
+		}
+
+		fileTargets, err := kubernetes.ParseTapTargetsFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid targets file %s: %w", config.Config.Tap.TargetsFile, err)
+		}
+		targets = append(targets, fileTargets...)
+	}
+
+	for _, reference := range config.Config.Tap.Targets {
+		if reference != configStructs.StdinTarget {
+			target, err := kubernetes.ParseTapTargetReference(reference)
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, target)
+			continue
+		}
+
+		data, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading the targets from stdin: %w", err)
+		}
+
+		stdinTargets, err := kubernetes.ParseTapTargetReferences(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid targets in stdin: %w", err)
+		}
+		targets = append(targets, stdinTargets...)
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets were given by --%s or --%s", configStructs.TargetsFileTapName, configStructs.TargetsTapName)
+	}
+
+	return targets, nil
+}
+
+func readTapPolicyFiles() (serializedValidationRules string, serializedContract string, ok bool) {
+	var err error
+	if config.Config.Tap.EnforcePolicyFile != "" {
+		serializedValidationRules, err = readValidationRules(config.Config.Tap.EnforcePolicyFile)
+		if err != nil {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error reading policy file: %v", errormessage.FormatError(err)))
+			return "", "", false
+		}
+	}
+
+	// Read and validate the OAS file
+	if config.Config.Tap.ContractFile != "" {
+		bytes, err := ioutil.ReadFile(config.Config.Tap.ContractFile)
+		if err != nil {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error reading contract file: %v", errormessage.FormatError(err)))
+			return "", "", false
+		}
+		serializedContract = string(bytes)
+
+		ctx := context.Background()
+		loader := &openapi3.Loader{Context: ctx}
+		doc, err := loader.LoadFromData(bytes)
+		if err != nil {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error loading contract file: %v", errormessage.FormatError(err)))
+			return "", "", false
+		}
+		err = doc.Validate(ctx)
+		if err != nil {
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Error validating contract file: %v", errormessage.FormatError(err)))
+			return "", "", false
+		}
+	}
+
+	return serializedValidationRules, serializedContract, true
+}
+
 func finishTapExecution(kubernetesProvider *kubernetes.Provider) {
 	telemetry.ReportTapTelemetry(apiProvider, config.Config.Tap, state.startTime)
 
-	finishMizuExecution(kubernetesProvider, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace)
+	if len(config.Config.LifecycleWebhooks.Urls) > 0 {
+		if err := apiProvider.NotifySessionStopped(); err != nil {
+			logger.Log.Debugf("[Error] failed notifying the lifecycle webhooks %v", err)
+		}
+	}
+
+	if config.Config.Archive.Url != "" {
+		if err := apiProvider.FlushArchive(); err != nil {
+			logger.Log.Debugf("[Error] failed flushing the archive %v", err)
+		}
+	}
+
+	finishMizuExecution(kubernetesProvider, config.Config.IsNsRestrictedMode(), config.Config.MizuResourcesNamespace, state.resourceNames, config.Config.Tap.PersistentStorage)
 }
 
 func getTapMizuAgentConfig() *shared.MizuAgentConfig {
 	mizuAgentConfig := shared.MizuAgentConfig{
-		MaxDBSizeBytes:         config.Config.Tap.MaxEntriesDBSizeBytes(),
-		InsertionFilter:        config.Config.Tap.GetInsertionFilter(),
-		AgentImage:             config.Config.AgentImage,
-		PullPolicy:             config.Config.ImagePullPolicyStr,
-		LogLevel:               config.Config.LogLevel(),
-		TapperResources:        config.Config.Tap.TapperResources,
-		MizuResourcesNamespace: config.Config.MizuResourcesNamespace,
-		AgentDatabasePath:      shared.DataDirPath,
-		ServiceMap:             config.Config.ServiceMap,
-		OAS:                    config.Config.OAS,
-		Telemetry:              config.Config.Telemetry,
-		Elastic:                config.Config.Elastic,
+		MaxDBSizeBytes:              config.Config.Tap.MaxEntriesDBSizeBytes(),
+		InsertionFilter:             config.Config.Tap.GetInsertionFilter(),
+		AgentImage:                  config.Config.AgentImage,
+		PullPolicy:                  config.Config.ImagePullPolicyStr,
+		LogLevel:                    config.Config.LogLevel(),
+		TapperResources:             config.Config.Tap.TapperResources,
+		MizuResourcesNamespace:      config.Config.MizuResourcesNamespace,
+		AgentDatabasePath:           shared.DataDirPath,
+		ServiceMap:                  config.Config.ServiceMap,
+		OAS:                         config.Config.OAS,
+		Stats:                       config.Config.Stats,
+		Metrics:                     config.Config.Metrics,
+		Insights:                    config.Config.Insights,
+		Telemetry:                   config.Config.Telemetry,
+		Elastic:                     config.Config.Elastic,
+		Kafka:                       config.Config.Kafka,
+		Sinks:                       config.Config.Sinks,
+		Archive:                     config.Config.Archive,
+		QueryCache:                  config.Config.QueryCache,
+		QueryLimits:                 config.Config.QueryLimits,
+		SocketKeepAlive:             config.Config.SocketKeepAlive,
+		MaxExportQueueDiskSizeBytes: config.Config.Tap.MaxExportQueueDiskSizeBytes(),
+		MaxStreamBytesPerSec:        config.Config.Tap.MaxStreamBytesPerSec(),
+		EntryIdScheme:               config.Config.Tap.EntryIdScheme,
+		Mirror:                      config.Config.Mirror,
+		Issues:                      getIssuesConfig(),
+		DeploymentMarkers:           config.Config.Tap.DeploymentMarkers,
+		KubernetesEvents:            config.Config.Tap.KubernetesEvents,
+		DnsResolution:               config.Config.Tap.DnsResolution,
+		TagHeader:                   config.Config.Tap.TagHeader,
+		Timestamps:                  config.Config.Timestamps,
+		Summary:                     config.Config.Summary,
+		TapperAuthentication:        isTapperAuthenticationEnabled(),
+		Operator:                    config.Config.Tap.IsOperatorTap(),
+		Provenance:                  config.Config.Provenance,
+		Enrichment:                  config.Config.Enrichment,
+		LifecycleWebhooks:           config.Config.LifecycleWebhooks,
+		ChatOps:                     config.Config.ChatOps,
+		Watermarks:                  config.Config.Watermarks,
+		Retention:                   config.Config.Retention,
 	}
 
 	return &mizuAgentConfig
 }
 
+// isTapperAuthenticationEnabled is false in namespace restricted mode since token reviews can only be granted by a cluster role
+func isTapperAuthenticationEnabled() bool {
+	return config.Config.Tap.TapperAuthentication && !config.Config.IsNsRestrictedMode()
+}
+
+// getIssuesConfig links the issues back to the entries through the local proxy unless another address was given
+func getIssuesConfig() shared.IssuesConfig {
+	issuesConfig := config.Config.Issues
+	if issuesConfig.LinkUrl == "" {
+		issuesConfig.LinkUrl = GetApiServerUrl(config.Config.Tap.GuiPort)
+	}
+
+	return issuesConfig
+}
+
 /*
 this function is a bit problematic as it might be detached from the actual pods the mizu api server will tap.
 The alternative would be to wait for api server to be ready and then query it for the pods it listens to, this has
 the arguably worse drawback of taking a relatively very long time before the user sees which pods are targeted, if any.
 */
 func printTappedPodsPreview(ctx context.Context, kubernetesProvider *kubernetes.Provider, namespaces []string) error {
-	if matchingPods, err := kubernetesProvider.ListAllRunningPodsMatchingRegex(ctx, config.Config.Tap.PodRegex(), namespaces); err != nil {
+	if matchingPods, err := kubernetesProvider.ListAllRunningTapTargetPods(ctx, config.Config.Tap.PodRegex(), config.Config.Tap.PodLabelSelector(), namespaces, config.Config.Tap.Annotations, getTapTargets(kubernetesProvider)); err != nil {
 		return err
 	} else {
 		if len(matchingPods) == 0 {
@@ -189,11 +350,64 @@ func printTappedPodsPreview(ctx context.Context, kubernetesProvider *kubernetes.
 	}
 }
 
+// printTapTargets lists the pods the tapper syncer would pick and the nodes it would start tappers on
+func printTapTargets(ctx context.Context, kubernetesProvider *kubernetes.Provider, namespaces []string) error {
+	matchingPods, err := kubernetesProvider.ListAllTapTargetPods(ctx, config.Config.Tap.PodRegex(), config.Config.Tap.PodLabelSelector(), namespaces, config.Config.Tap.Annotations, getTapTargets(kubernetesProvider))
+	if err != nil {
+		return err
+	}
+
+	runningPods := make([]core.Pod, 0)
+	pendingPods := 0
+	for _, pod := range kubernetes.ExcludeMizuPods(matchingPods) {
+		if kubernetes.IsPodRunning(&pod) {
+			runningPods = append(runningPods, pod)
+		} else {
+			pendingPods++
+		}
+	}
+
+	if len(runningPods) == 0 {
+		printNoPodsFoundSuggestion(namespaces)
+	} else {
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "NAMESPACE\tNAME\tNODE\tPOD IP\tHOST IP")
+		for _, pod := range runningPods {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\n", pod.Namespace, pod.Name, pod.Spec.NodeName, pod.Status.PodIP, pod.Status.HostIP)
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+
+	if pendingPods > 0 {
+		logger.Log.Infof("%d more matching pods aren't running yet, they will be tapped once they are", pendingPods)
+	}
+
+	nodeToTappedPodMap := kubernetes.GetNodeHostToTappedPodsMap(runningPods)
+	nodeNames := make([]string, 0, len(nodeToTappedPodMap))
+	for nodeName := range nodeToTappedPodMap {
+		nodeNames = append(nodeNames, nodeName)
+	}
+	sort.Strings(nodeNames)
+
+	logger.Log.Infof("Tappers would run on %d nodes", len(nodeNames))
+	for _, nodeName := range nodeNames {
+		logger.Log.Infof(uiUtils.Green, fmt.Sprintf("+%s (%d pods)", nodeName, len(nodeToTappedPodMap[nodeName])))
+	}
+
+	return nil
+}
+
 func startTapperSyncer(ctx context.Context, cancel context.CancelFunc, provider *kubernetes.Provider, targetNamespaces []string, mizuApiFilteringOptions api.TrafficFilteringOptions, startTime time.Time) error {
 	tapperSyncer, err := kubernetes.CreateAndStartMizuTapperSyncer(ctx, provider, kubernetes.TapperSyncerConfig{
 		TargetNamespaces:         targetNamespaces,
 		PodFilterRegex:           *config.Config.Tap.PodRegex(),
+		PodFilterLabelSelector:   config.Config.Tap.PodLabelSelector(),
+		TapAnnotations:           config.Config.Tap.Annotations,
+		Targets:                  getTapTargets(provider),
 		MizuResourcesNamespace:   config.Config.MizuResourcesNamespace,
+		ResourceNames:            state.resourceNames,
 		AgentImage:               config.Config.AgentImage,
 		TapperResources:          config.Config.Tap.TapperResources,
 		ImagePullPolicy:          config.Config.ImagePullPolicy(),
@@ -203,6 +417,10 @@ func startTapperSyncer(ctx context.Context, cancel context.CancelFunc, provider
 		MizuServiceAccountExists: state.mizuServiceAccountExists,
 		ServiceMesh:              config.Config.Tap.ServiceMesh,
 		Tls:                      config.Config.Tap.Tls,
+		TapperAuthentication:     isTapperAuthenticationEnabled(),
+		TapperScheduling:         config.Config.Tap.TapperScheduling,
+		SocketKeepAlive:          config.Config.SocketKeepAlive,
+		Relay:                    config.Config.Tap.RelayConfig(),
 	}, startTime)
 
 	if err != nil {
@@ -227,6 +445,9 @@ func startTapperSyncer(ctx context.Context, cancel context.CancelFunc, provider
 				if err := apiProvider.ReportTappedPods(tapperSyncer.CurrentlyTappedPods); err != nil {
 					logger.Log.Debugf("[Error] failed update tapped pods %v", err)
 				}
+				if err := apiProvider.ReportTapTargets(tapperSyncer.GetNodeToTappedPodMap()); err != nil {
+					logger.Log.Debugf("[Error] failed update tap targets %v", err)
+				}
 			case tapperStatus, ok := <-tapperSyncer.TapperStatusChangedOut:
 				if !ok {
 					logger.Log.Debug("mizuTapperSyncer tapper status changed channel closed, ending listener loop")
@@ -246,11 +467,20 @@ func startTapperSyncer(ctx context.Context, cancel context.CancelFunc, provider
 }
 
 func printNoPodsFoundSuggestion(targetNamespaces []string) {
+	if len(state.targets) > 0 {
+		logger.Log.Warningf(uiUtils.Warning, "Did not find any currently running pods of the targets, mizu will automatically tap their pods if any are created later")
+		return
+	}
+
 	var suggestionStr string
 	if !shared.Contains(targetNamespaces, kubernetes.K8sAllNamespaces) {
 		suggestionStr = ". You can also try selecting a different namespace with -n or tap all namespaces with -A"
 	}
-	logger.Log.Warningf(uiUtils.Warning, fmt.Sprintf("Did not find any currently running pods that match the regex argument, mizu will automatically tap matching pods if any are created later%s", suggestionStr))
+	matchStr := "the regex argument"
+	if config.Config.Tap.PodLabelSelectorStr != "" {
+		matchStr = fmt.Sprintf("the regex argument and the label selector %s", config.Config.Tap.PodLabelSelectorStr)
+	}
+	logger.Log.Warningf(uiUtils.Warning, fmt.Sprintf("Did not find any currently running pods that match %s, mizu will automatically tap matching pods if any are created later%s", matchStr, suggestionStr))
 }
 
 func getErrorDisplayTextForK8sTapManagerError(err kubernetes.K8sTapManagerError) string {
@@ -289,10 +519,20 @@ func getMizuApiFilteringOptions() (*api.TrafficFilteringOptions, error) {
 		}
 	}
 
+	redactionRules, err := config.Config.Tap.RedactionRules()
+	if err != nil {
+		return nil, err
+	}
+
 	return &api.TrafficFilteringOptions{
-		PlainTextMaskingRegexes: compiledRegexSlice,
-		IgnoredUserAgents:       config.Config.Tap.IgnoredUserAgents,
-		DisableRedaction:        config.Config.Tap.DisableRedaction,
+		PlainTextMaskingRegexes:   compiledRegexSlice,
+		IgnoredUserAgents:         config.Config.Tap.IgnoredUserAgents,
+		DisableRedaction:          config.Config.Tap.DisableRedaction,
+		PreserveRawHeaders:        config.Config.Tap.RawHeaders,
+		RedactionRules:            redactionRules,
+		PiiPatterns:               config.Config.Tap.EnabledPiiPatterns(),
+		SampleRate:                config.Config.Tap.SampleRate,
+		MaxEntriesPerSecondPerPod: config.Config.Tap.MaxEntriesPerSec,
 	}, nil
 }
 
@@ -310,7 +550,7 @@ func getSyncEntriesConfig() *shared.SyncEntriesConfig {
 }
 
 func watchApiServerPod(ctx context.Context, kubernetesProvider *kubernetes.Provider, cancel context.CancelFunc) {
-	podExactRegex := regexp.MustCompile(fmt.Sprintf("^%s$", kubernetes.ApiServerPodName))
+	podExactRegex := regexp.MustCompile(fmt.Sprintf("^%s$", state.resourceNames.ApiServerPodName))
 	podWatchHelper := kubernetes.NewPodWatchHelper(kubernetesProvider, podExactRegex)
 	eventChan, errorChan := kubernetes.FilteredWatch(ctx, podWatchHelper, []string{config.Config.MizuResourcesNamespace}, podWatchHelper)
 	isPodReady := false
@@ -329,7 +569,7 @@ func watchApiServerPod(ctx context.Context, kubernetesProvider *kubernetes.Provi
 			case kubernetes.EventAdded:
 				logger.Log.Debugf("Watching API Server pod loop, added")
 			case kubernetes.EventDeleted:
-				logger.Log.Infof("%s removed", kubernetes.ApiServerPodName)
+				logger.Log.Infof("%s removed", state.resourceNames.ApiServerPodName)
 				cancel()
 				return
 			case kubernetes.EventModified:
@@ -373,7 +613,7 @@ func watchApiServerPod(ctx context.Context, kubernetesProvider *kubernetes.Provi
 }
 
 func watchApiServerEvents(ctx context.Context, kubernetesProvider *kubernetes.Provider, cancel context.CancelFunc) {
-	podExactRegex := regexp.MustCompile(fmt.Sprintf("^%s", kubernetes.ApiServerPodName))
+	podExactRegex := regexp.MustCompile(fmt.Sprintf("^%s", state.resourceNames.ApiServerPodName))
 	eventWatchHelper := kubernetes.NewEventWatchHelper(kubernetesProvider, podExactRegex, "pod")
 	eventChan, errorChan := kubernetes.FilteredWatch(ctx, eventWatchHelper, []string{config.Config.MizuResourcesNamespace}, eventWatchHelper)
 	for {
@@ -424,7 +664,7 @@ func watchApiServerEvents(ctx context.Context, kubernetesProvider *kubernetes.Pr
 }
 
 func postApiServerStarted(ctx context.Context, kubernetesProvider *kubernetes.Provider, cancel context.CancelFunc) {
-	startProxyReportErrorIfAny(kubernetesProvider, ctx, cancel, config.Config.Tap.GuiPort)
+	startProxyReportErrorIfAny(kubernetesProvider, ctx, cancel, config.Config.Tap.GuiPort, state.resourceNames)
 
 	options, _ := getMizuApiFilteringOptions()
 	if err := startTapperSyncer(ctx, cancel, kubernetesProvider, state.targetNamespaces, *options, state.startTime); err != nil {
@@ -437,10 +677,58 @@ func postApiServerStarted(ctx context.Context, kubernetesProvider *kubernetes.Pr
 	if !config.Config.HeadlessMode {
 		uiUtils.OpenBrowser(url)
 	}
+
+	go watchApiServerPressure(ctx)
+}
+
+// watchApiServerPressure reports when the API server starts and stops shedding the ingestion under memory or disk
+// pressure, so a tap losing entries doesn't go unnoticed
+func watchApiServerPressure(ctx context.Context) {
+	level := shared.PressureLevelNormal
+	ticker := time.NewTicker(pressureCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Log.Debugf("Watching API server pressure loop, ctx done")
+			return
+		case <-ticker.C:
+		}
+
+		status, err := apiProvider.GetPressureStatus()
+		if err != nil {
+			logger.Log.Debugf("[Error] Watching API server pressure loop, error: %v", err)
+			continue
+		}
+		if status.Level == level {
+			continue
+		}
+
+		usage := fmt.Sprintf("memory %s, disk %s", formatPressurePercent(status.MemoryPercent), formatPressurePercent(status.DiskPercent))
+		switch status.Level {
+		case shared.PressureLevelCritical:
+			logger.Log.Errorf(uiUtils.Error, fmt.Sprintf("Mizu API server is under critical pressure (%s), the captured traffic is dropped until it goes down", usage))
+		case shared.PressureLevelHigh:
+			logger.Log.Warningf(uiUtils.Warning, fmt.Sprintf("Mizu API server is under high pressure (%s), the bodies are dropped and %v%% of the traffic is kept", usage, config.Config.Watermarks.SampleRate*100))
+		default:
+			logger.Log.Infof("Mizu API server pressure is back to normal (%s), %d entries were sampled out, %d stored without bodies and %d dropped", usage, status.Sampled, status.ShedBodies, status.Paused)
+		}
+		level = status.Level
+	}
+}
+
+func formatPressurePercent(percent int) string {
+	if percent < 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d%%", percent)
 }
 
 func getNamespaces(kubernetesProvider *kubernetes.Provider) []string {
-	if config.Config.Tap.AllNamespaces {
+	if len(state.targets) > 0 {
+		return kubernetes.GetTapTargetsNamespaces(getTapTargets(kubernetesProvider))
+	} else if config.Config.Tap.AllNamespaces {
 		return []string{kubernetes.K8sAllNamespaces}
 	} else if len(config.Config.Tap.Namespaces) > 0 {
 		return shared.Unique(config.Config.Tap.Namespaces)
@@ -452,3 +740,17 @@ func getNamespaces(kubernetesProvider *kubernetes.Provider) []string {
 		return []string{currentNamespace}
 	}
 }
+
+// getTapTargets returns the targets of the tap, the targets without a namespace are in the namespace of the kube
+// context of kubernetesProvider
+func getTapTargets(kubernetesProvider *kubernetes.Provider) []kubernetes.TapTarget {
+	if len(state.targets) == 0 {
+		return nil
+	}
+
+	currentNamespace, err := kubernetesProvider.CurrentNamespace()
+	if err != nil {
+		logger.Log.Fatalf(uiUtils.Red, fmt.Sprintf("error getting current namespace: %+v", err))
+	}
+	return kubernetes.ResolveTapTargetsNamespace(state.targets, currentNamespace)
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Validate the captured traffic against the contract and the rules",
+	Long: `Report the endpoints whose captured http entries failed the contract (--contract of tap) or the traffic validation rules (--traffic-validation-file of tap).
+Use --ci in integration environments to write a JUnit report and GitHub annotations, and to exit non-zero when an endpoint failed.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("validate", config.Config.Validate)
+		return runMizuValidate()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Validate.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+
+	defaultValidateConfig := configStructs.ValidateConfig{}
+	if err := defaults.Set(&defaultValidateConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	validateCmd.Flags().Bool(configStructs.ContractsValidateName, defaultValidateConfig.Contracts, "Validate the entries against the contract")
+	validateCmd.Flags().Bool(configStructs.RulesValidateName, defaultValidateConfig.Rules, "Validate the entries against the validation rules")
+	validateCmd.Flags().StringP(configStructs.QueryValidateName, "q", defaultValidateConfig.Query, "Validate only entries matching this query")
+	validateCmd.Flags().IntP(configStructs.LimitValidateName, "l", defaultValidateConfig.Limit, "Maximum number of entries validated, the latest are kept")
+	validateCmd.Flags().String(configStructs.FromValidateName, defaultValidateConfig.From, "Validate only entries captured after this time")
+	validateCmd.Flags().String(configStructs.ToValidateName, defaultValidateConfig.To, "Validate only entries captured before this time")
+	validateCmd.Flags().Bool(configStructs.CiValidateName, defaultValidateConfig.Ci, "Write a JUnit report and GitHub annotations, and exit non-zero when an endpoint failed")
+	validateCmd.Flags().String(configStructs.JunitFileValidateName, defaultValidateConfig.JunitFile, "Path of the JUnit report written with --ci")
+	validateCmd.Flags().Uint16P(configStructs.GuiPortValidateName, "p", defaultValidateConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	validateCmd.Flags().StringP(configStructs.UrlValidateName, "u", defaultValidateConfig.Url, "Provide a custom host")
+
+	if err := validateCmd.Flags().MarkHidden(configStructs.UrlValidateName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
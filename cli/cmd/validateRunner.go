@@ -0,0 +1,248 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/ci"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared/logger"
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	// the contract status the api server sets on the entries that failed the contract
+	validateContractFailed tapApi.ContractStatus = 2
+
+	validateContractQuery = "contractStatus > 0"
+	validateRulesQuery    = "rules.numberOfRules > 0"
+)
+
+// validatedEndpoint collects the entries of an endpoint, the latest failed entry is fetched to explain the failure
+type validatedEndpoint struct {
+	name          string
+	entries       int
+	failed        int
+	latestFailure map[string]interface{}
+}
+
+func runMizuValidate() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	from, to, err := config.Config.Validate.TimeRange(time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, apiServerProvider, err := connectToApiServer(ctx, cancel, config.Config.Validate.Url, config.Config.Validate.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	report := &ci.Report{Name: "validate"}
+
+	if config.Config.Validate.Contracts {
+		endpoints, err := getValidatedEndpoints(apiServerProvider, validateContractQuery, from, to, func(baseEntry *tapApi.BaseEntry) bool {
+			return baseEntry.ContractStatus == validateContractFailed
+		})
+		if err != nil {
+			return err
+		}
+
+		addValidatedEndpoints(report, "contract", endpoints, contractFailureDetails)
+	}
+
+	if config.Config.Validate.Rules {
+		endpoints, err := getValidatedEndpoints(apiServerProvider, validateRulesQuery, from, to, func(baseEntry *tapApi.BaseEntry) bool {
+			return !baseEntry.Rules.Status
+		})
+		if err != nil {
+			return err
+		}
+
+		addValidatedEndpoints(report, "rules", endpoints, rulesFailureDetails)
+	}
+
+	printValidateReport(report)
+
+	if config.Config.Validate.Ci {
+		return finishCiReport(report, config.Config.Validate.JunitFile)
+	}
+
+	return nil
+}
+
+// getValidatedEndpoints groups the entries the contract or the rules apply to by their endpoint, sorted by name
+func getValidatedEndpoints(apiServerProvider *apiserver.Provider, applicableQuery string, from int64, to int64, isFailed func(baseEntry *tapApi.BaseEntry) bool) ([]*validatedEndpoint, error) {
+	baseEntries, err := apiServerProvider.GetEntries(buildValidateQuery(applicableQuery, config.Config.Validate.Query, from, to), config.Config.Validate.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	endpointsByName := make(map[string]*validatedEndpoint)
+	names := make([]string, 0)
+
+	// the entries are returned latest first
+	for _, baseEntryMap := range baseEntries {
+		baseEntry, err := toBaseEntry(baseEntryMap)
+		if err != nil {
+			logger.Log.Debugf("Skipping an entry that couldn't be parsed: %v", err)
+			continue
+		}
+
+		name := validatedEndpointName(baseEntry)
+		endpoint, ok := endpointsByName[name]
+		if !ok {
+			endpoint = &validatedEndpoint{name: name}
+			endpointsByName[name] = endpoint
+			names = append(names, name)
+		}
+
+		endpoint.entries++
+		if !isFailed(baseEntry) {
+			continue
+		}
+
+		endpoint.failed++
+		if endpoint.latestFailure == nil {
+			if endpoint.latestFailure, err = apiServerProvider.GetEntry(getEntryIdForFetch(baseEntryMap)); err != nil {
+				logger.Log.Debugf("Failed fetching the failed entry of %s: %v", name, err)
+			}
+		}
+	}
+
+	sort.Strings(names)
+	endpoints := make([]*validatedEndpoint, 0, len(names))
+	for _, name := range names {
+		endpoints = append(endpoints, endpointsByName[name])
+	}
+
+	return endpoints, nil
+}
+
+func buildValidateQuery(applicableQuery string, query string, from int64, to int64) string {
+	conditions := []string{applicableQuery}
+
+	if strings.TrimSpace(query) != "" {
+		conditions = append(conditions, fmt.Sprintf("(%s)", query))
+	}
+	if from > 0 {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= %d", from))
+	}
+	if to > 0 {
+		conditions = append(conditions, fmt.Sprintf("timestamp <= %d", to))
+	}
+
+	return strings.Join(conditions, " and ")
+}
+
+func toBaseEntry(baseEntryMap map[string]interface{}) (*tapApi.BaseEntry, error) {
+	data, err := json.Marshal(baseEntryMap)
+	if err != nil {
+		return nil, err
+	}
+
+	var baseEntry *tapApi.BaseEntry
+	if err := json.Unmarshal(data, &baseEntry); err != nil {
+		return nil, err
+	}
+
+	return baseEntry, nil
+}
+
+func validatedEndpointName(baseEntry *tapApi.BaseEntry) string {
+	destination := ""
+	if baseEntry.Destination != nil {
+		destination = baseEntry.Destination.Name
+		if destination == "" {
+			destination = fmt.Sprintf("%s:%s", baseEntry.Destination.IP, baseEntry.Destination.Port)
+		}
+	}
+
+	return fmt.Sprintf("%s %s %s", destination, baseEntry.Method, baseEntry.Summary)
+}
+
+func addValidatedEndpoints(report *ci.Report, suite string, endpoints []*validatedEndpoint, failureDetails func(entry map[string]interface{}) string) {
+	for _, endpoint := range endpoints {
+		if endpoint.failed == 0 {
+			report.AddPassed(suite, endpoint.name)
+			continue
+		}
+
+		details := ""
+		if endpoint.latestFailure != nil {
+			details = failureDetails(endpoint.latestFailure)
+		}
+		report.AddFailed(suite, endpoint.name, fmt.Sprintf("%d of %d entries failed", endpoint.failed, endpoint.entries), details)
+	}
+}
+
+func contractFailureDetails(entry map[string]interface{}) string {
+	data, _ := entry["data"].(map[string]interface{})
+
+	reasons := make([]string, 0)
+	if reason, ok := data["contractRequestReason"].(string); ok && reason != "" {
+		reasons = append(reasons, fmt.Sprintf("request: %s", reason))
+	}
+	if reason, ok := data["contractResponseReason"].(string); ok && reason != "" {
+		reasons = append(reasons, fmt.Sprintf("response: %s", reason))
+	}
+
+	return strings.Join(reasons, "\n")
+}
+
+func rulesFailureDetails(entry map[string]interface{}) string {
+	rulesMatched, _ := entry["rulesMatched"].([]interface{})
+
+	failedRules := make([]string, 0)
+	for _, ruleMatched := range rulesMatched {
+		ruleMatchedMap, _ := ruleMatched.(map[string]interface{})
+		if matched, _ := ruleMatchedMap["matched"].(bool); matched {
+			continue
+		}
+
+		rule, _ := ruleMatchedMap["rule"].(map[string]interface{})
+		failedRules = append(failedRules, fmt.Sprintf("failed rule: %v", rule["Name"]))
+	}
+
+	return strings.Join(failedRules, "\n")
+}
+
+func printValidateReport(report *ci.Report) {
+	logger.Log.Infof("Mizu validate\n===================")
+
+	lastSuite := ""
+	for _, testCase := range report.Cases {
+		if testCase.Suite != lastSuite {
+			logger.Log.Infof("\n%s\n--------------------", testCase.Suite)
+			lastSuite = testCase.Suite
+		}
+
+		if testCase.Failure == "" {
+			logger.Log.Infof("%v %s", fmt.Sprintf(uiUtils.Green, "√"), testCase.Name)
+			continue
+		}
+
+		logger.Log.Errorf("%v %s, %s", fmt.Sprintf(uiUtils.Red, "✗"), testCase.Name, testCase.Failure)
+		for _, line := range strings.Split(testCase.Details, "\n") {
+			if line != "" {
+				logger.Log.Infof("  %s", line)
+			}
+		}
+	}
+
+	if len(report.Cases) == 0 {
+		logger.Log.Infof("\nNo captured entries were validated, make sure tap runs with a contract or traffic validation rules")
+	} else if report.Failures() == 0 {
+		logger.Log.Infof("\nValidation results are %v", fmt.Sprintf(uiUtils.Green, "√"))
+	} else {
+		logger.Log.Errorf("\nValidation results are %v", fmt.Sprintf(uiUtils.Red, "✗"))
+	}
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/creasty/defaults"
+	"github.com/spf13/cobra"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
+	"github.com/up9inc/mizu/cli/telemetry"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: "Verify the signed provenance of the captured entries",
+	Long: `Verify that the captured entries weren't changed, removed or reordered since they were stored.
+Requires a tap started with --set provenance.secret-name=<secret>, the secret holds an ed25519 private key under ed25519.key, e.g. created with
+  openssl genpkey -algorithm ed25519 -out ed25519.key
+  kubectl create secret generic mizu-provenance-key --from-file=ed25519.key
+The signatures are checked with --public-key, e.g. created with openssl pkey -in ed25519.key -pubout -out ed25519.pub`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		go telemetry.ReportRun("verify", config.Config.Verify)
+		return runMizuVerify()
+	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.Verify.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+
+	defaultVerifyConfig := configStructs.VerifyConfig{}
+	if err := defaults.Set(&defaultVerifyConfig); err != nil {
+		logger.Log.Debug(err)
+	}
+
+	verifyCmd.Flags().String(configStructs.PublicKeyVerifyName, defaultVerifyConfig.PublicKey, "PEM file of the ed25519 public key the entries are verified with")
+	verifyCmd.Flags().Uint16P(configStructs.GuiPortVerifyName, "p", defaultVerifyConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	verifyCmd.Flags().StringP(configStructs.UrlVerifyName, "u", defaultVerifyConfig.Url, "Provide a custom host")
+
+	if err := verifyCmd.Flags().MarkHidden(configStructs.UrlVerifyName); err != nil {
+		logger.Log.Debug(err)
+	}
+}
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/up9inc/mizu/cli/apiserver"
+	"github.com/up9inc/mizu/cli/config"
+	"github.com/up9inc/mizu/cli/uiUtils"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+func runMizuVerify() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	publicKeyPem, err := ioutil.ReadFile(config.Config.Verify.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := shared.ParseProvenancePublicKey(publicKeyPem)
+	if err != nil {
+		return fmt.Errorf("invalid public key %s, err: %w", config.Config.Verify.PublicKey, err)
+	}
+
+	_, apiServerProvider, err := connectToApiServer(ctx, cancel, config.Config.Verify.Url, config.Config.Verify.GuiPort)
+	if err != nil {
+		return err
+	}
+
+	segmentsResponse, err := apiServerProvider.GetProvenanceSegments()
+	if err != nil {
+		return err
+	}
+
+	if len(segmentsResponse.Segments) == 0 {
+		logger.Log.Infof("No signed segments yet, %d entries are waiting to be signed", segmentsResponse.UnsignedCount)
+		return nil
+	}
+
+	previousHash := shared.ProvenanceGenesisHash
+	failed := 0
+	entries := 0
+	for _, segment := range segmentsResponse.Segments {
+		if err := verifySegment(apiServerProvider, segment, previousHash, publicKey); err != nil {
+			logger.Log.Infof(uiUtils.Red, fmt.Sprintf("Segment %d failed verification: %v", segment.Index, err))
+			failed++
+		}
+
+		previousHash = segment.Hash
+		entries += len(segment.EntryIds)
+	}
+
+	first := segmentsResponse.Segments[0]
+	last := segmentsResponse.Segments[len(segmentsResponse.Segments)-1]
+	logger.Log.Infof("Checked %d entries in %d segments, signed from %s to %s", entries, len(segmentsResponse.Segments),
+		time.UnixMilli(first.SignedAt).UTC().Format(time.RFC3339), time.UnixMilli(last.SignedAt).UTC().Format(time.RFC3339))
+	if segmentsResponse.UnsignedCount > 0 {
+		logger.Log.Infof("%d entries captured after the last segment aren't signed yet", segmentsResponse.UnsignedCount)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d segments failed verification", failed, len(segmentsResponse.Segments))
+	}
+
+	logger.Log.Infof(uiUtils.Green, "All the signed entries are intact")
+	return nil
+}
+
+// verifySegment checks the segment continues the chain and is signed, then recomputes its hash from the entries
+// the api server returns now
+func verifySegment(apiServerProvider *apiserver.Provider, segment *shared.ProvenanceSegment, previousHash string, publicKey ed25519.PublicKey) error {
+	if segment.PreviousHash != previousHash {
+		return fmt.Errorf("it doesn't continue the chain of the previous segment, segments are missing or were replaced")
+	}
+
+	if !segment.VerifySignature(publicKey) {
+		return fmt.Errorf("invalid signature")
+	}
+
+	hash, err := hex.DecodeString(segment.PreviousHash)
+	if err != nil {
+		return err
+	}
+
+	for _, entryId := range segment.EntryIds {
+		entry, err := apiServerProvider.GetEntry(entryId)
+		if err != nil {
+			return fmt.Errorf("entry %s is missing, it was removed or evicted by the database size limit: %v", entryId, err)
+		}
+
+		entryJson, err := json.Marshal(entry["data"])
+		if err != nil {
+			return err
+		}
+
+		entryHash, err := shared.ProvenanceEntryHash(entryJson)
+		if err != nil {
+			return fmt.Errorf("entry %s can't be hashed: %v", entryId, err)
+		}
+
+		hash = shared.ProvenanceChain(hash, entryHash)
+	}
+
+	if hex.EncodeToString(hash) != segment.Hash {
+		return fmt.Errorf("the entries don't match the signed hash, an entry was changed or the entries were reordered")
+	}
+
+	return nil
+}
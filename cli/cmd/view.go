@@ -5,6 +5,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/up9inc/mizu/cli/config"
 	"github.com/up9inc/mizu/cli/config/configStructs"
+	"github.com/up9inc/mizu/cli/errormessage"
 	"github.com/up9inc/mizu/cli/telemetry"
 	"github.com/up9inc/mizu/shared/logger"
 )
@@ -17,6 +18,13 @@ var viewCmd = &cobra.Command{
 		runMizuView()
 		return nil
 	},
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := config.Config.View.Validate(); err != nil {
+			return errormessage.FormatError(err)
+		}
+
+		return nil
+	},
 }
 
 func init() {
@@ -28,6 +36,7 @@ func init() {
 	}
 
 	viewCmd.Flags().Uint16P(configStructs.GuiPortViewName, "p", defaultViewConfig.GuiPort, "Provide a custom port for the web interface webserver")
+	viewCmd.Flags().String(configStructs.MaxBandwidthViewName, defaultViewConfig.HumanMaxBandwidth, "Cap the bandwidth of streaming the entries to the GUI per second, like 256KB, for slow links")
 	viewCmd.Flags().StringP(configStructs.UrlViewName, "u", defaultViewConfig.Url, "Provide a custom host")
 
 	if err := viewCmd.Flags().MarkHidden(configStructs.UrlViewName); err != nil {
@@ -11,10 +11,11 @@ import (
 	"github.com/up9inc/mizu/cli/config"
 	"github.com/up9inc/mizu/cli/mizu/fsUtils"
 	"github.com/up9inc/mizu/cli/uiUtils"
-	"github.com/up9inc/mizu/shared/kubernetes"
 	"github.com/up9inc/mizu/shared/logger"
 )
 
+const maxBandwidthUiQueryParam = "maxBandwidth"
+
 func runMizuView() {
 	kubernetesProvider, err := getKubernetesProviderForCli()
 	if err != nil {
@@ -27,14 +28,15 @@ func runMizuView() {
 	url := config.Config.View.Url
 
 	if url == "" {
-		exists, err := kubernetesProvider.DoesServiceExist(ctx, config.Config.MizuResourcesNamespace, kubernetes.ApiServerPodName)
+		resourceNames := getSessionResourceNames()
+		exists, err := kubernetesProvider.DoesServiceExist(ctx, config.Config.MizuResourcesNamespace, resourceNames.ApiServerPodName)
 		if err != nil {
 			logger.Log.Errorf("Failed to found mizu service %v", err)
 			cancel()
 			return
 		}
 		if !exists {
-			logger.Log.Infof("%s service not found, you should run `mizu tap` command first", kubernetes.ApiServerPodName)
+			logger.Log.Infof("%s service not found, you should run `mizu tap` command first", resourceNames.ApiServerPodName)
 			cancel()
 			return
 		}
@@ -43,11 +45,11 @@ func runMizuView() {
 
 		response, err := http.Get(fmt.Sprintf("%s/", url))
 		if err == nil && response.StatusCode == 200 {
-			logger.Log.Infof("Found a running service %s and open port %d", kubernetes.ApiServerPodName, config.Config.View.GuiPort)
+			logger.Log.Infof("Found a running service %s and open port %d", resourceNames.ApiServerPodName, config.Config.View.GuiPort)
 			return
 		}
 		logger.Log.Infof("Establishing connection to k8s cluster...")
-		startProxyReportErrorIfAny(kubernetesProvider, ctx, cancel, config.Config.View.GuiPort)
+		startProxyReportErrorIfAny(kubernetesProvider, ctx, cancel, config.Config.View.GuiPort, resourceNames)
 	}
 
 	apiServerProvider := apiserver.NewProvider(url, apiserver.DefaultRetries, apiserver.DefaultTimeout)
@@ -59,8 +61,17 @@ func runMizuView() {
 	logger.Log.Infof("Mizu is available at %s", url)
 
 	if !config.Config.HeadlessMode {
-		uiUtils.OpenBrowser(url)
+		uiUtils.OpenBrowser(getViewUiUrl(url))
 	}
 
 	utils.WaitForFinish(ctx, cancel)
 }
+
+// getViewUiUrl passes the bandwidth of the view to the GUI, which asks for it when it streams the entries
+func getViewUiUrl(url string) string {
+	if maxBytesPerSec := config.Config.View.MaxBytesPerSec(); maxBytesPerSec > 0 {
+		return fmt.Sprintf("%s/?%s=%d", url, maxBandwidthUiQueryParam, maxBytesPerSec)
+	}
+
+	return url
+}
@@ -24,11 +24,21 @@ const (
 	SetCommandName = "set"
 	FieldNameTag   = "yaml"
 	ReadonlyTag    = "readonly"
+
+	redactedFlagValue = "[REDACTED]"
 )
 
+// the values of flags with any of these in their name are redacted from the recorded command flags
+var sensitiveFlagNames = []string{"secret", "password", "token", "dsn", "key"}
+
+// the values of config fields with any of these in their name are redacted from the dumped configs, on top of the
+// sensitive flag names, the webhook urls embed their credentials in the url itself
+var sensitiveFieldNames = append([]string{"auth", "webhookurl", "slackurl"}, sensitiveFlagNames...)
+
 var (
-	Config  = ConfigStruct{}
-	cmdName string
+	Config       = ConfigStruct{}
+	cmdName      string
+	commandFlags map[string]string
 )
 
 func InitConfig(cmd *cobra.Command) error {
@@ -47,6 +57,15 @@ func InitConfig(cmd *cobra.Command) error {
 		}
 	}
 
+	// the sinks of the config file are created by the yaml parser, after the defaults were set
+	for i := range Config.Sinks {
+		if err := defaults.Set(&Config.Sinks[i]); err != nil {
+			return err
+		}
+	}
+
+	commandFlags = make(map[string]string)
+	cmd.Flags().Visit(recordFlag)
 	cmd.Flags().Visit(initFlag)
 
 	if err := Config.validate(); err != nil {
@@ -59,6 +78,106 @@ func InitConfig(cmd *cobra.Command) error {
 	return nil
 }
 
+// GetCommandFlags returns the flags the command was run with, the values of sensitive flags are redacted
+func GetCommandFlags() map[string]string {
+	return commandFlags
+}
+
+func recordFlag(f *pflag.Flag) {
+	sliceValue, isSliceValue := f.Value.(pflag.SliceValue)
+	if !isSliceValue {
+		commandFlags[f.Name] = redactFlagValue(f.Name, f.Value.String())
+		return
+	}
+
+	values := make([]string, 0)
+	for _, value := range sliceValue.GetSlice() {
+		if f.Name == SetCommandName && strings.Contains(value, Separator) {
+			split := strings.SplitN(value, Separator, 2)
+			value = split[0] + Separator + redactFlagValue(split[0], split[1])
+		} else {
+			value = redactFlagValue(f.Name, value)
+		}
+		values = append(values, value)
+	}
+
+	commandFlags[f.Name] = strings.Join(values, ",")
+}
+
+func redactFlagValue(name string, value string) string {
+	for _, sensitiveFlagName := range sensitiveFlagNames {
+		if strings.Contains(strings.ToLower(name), sensitiveFlagName) {
+			return redactedFlagValue
+		}
+	}
+
+	return value
+}
+
+// GetRedactedConfig renders the config as yaml with the values of the sensitive fields redacted, for attaching it to
+// bug reports
+func GetRedactedConfig(config *ConfigStruct) (string, error) {
+	configBytes, err := yaml.Marshal(config)
+	if err != nil {
+		return "", err
+	}
+
+	var document interface{}
+	if err := yaml.Unmarshal(configBytes, &document); err != nil {
+		return "", err
+	}
+
+	return uiUtils.PrettyYaml(RedactSensitiveFields(document))
+}
+
+// RedactSensitiveFields redacts in place the non empty string values of the sensitive fields of a decoded yaml or json
+// document, empty values are kept so it's still visible that a field isn't set
+func RedactSensitiveFields(document interface{}) interface{} {
+	switch value := document.(type) {
+	case map[string]interface{}:
+		for fieldName, fieldValue := range value {
+			if isSensitiveFieldName(fieldName) && isRedactableValue(fieldValue) {
+				value[fieldName] = redactedFlagValue
+			} else {
+				value[fieldName] = RedactSensitiveFields(fieldValue)
+			}
+		}
+	case []interface{}:
+		for i, item := range value {
+			value[i] = RedactSensitiveFields(item)
+		}
+	}
+
+	return document
+}
+
+func isSensitiveFieldName(name string) bool {
+	normalizedName := strings.NewReplacer("-", "", "_", "").Replace(strings.ToLower(name))
+	for _, sensitiveFieldName := range sensitiveFieldNames {
+		if strings.Contains(normalizedName, sensitiveFieldName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func isRedactableValue(value interface{}) bool {
+	switch typedValue := value.(type) {
+	case string:
+		return typedValue != ""
+	case []interface{}:
+		for _, item := range typedValue {
+			if !isRedactableValue(item) {
+				return false
+			}
+		}
+		return len(typedValue) > 0
+	default:
+		return false
+	}
+}
+
 func GetConfigWithDefaults() (*ConfigStruct, error) {
 	defaultConf := ConfigStruct{}
 	if err := defaults.Set(&defaultConf); err != nil {
@@ -348,6 +467,20 @@ func getParsedValue(kind reflect.Kind, value string) (reflect.Value, error) {
 		}
 
 		return reflect.ValueOf(uintArgumentValue), nil
+	case reflect.Float32:
+		floatArgumentValue, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			break
+		}
+
+		return reflect.ValueOf(float32(floatArgumentValue)), nil
+	case reflect.Float64:
+		floatArgumentValue, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			break
+		}
+
+		return reflect.ValueOf(floatArgumentValue), nil
 	}
 
 	return reflect.ValueOf(nil), errors.New("value to parse does not match type")
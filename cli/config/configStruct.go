@@ -2,14 +2,21 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path"
 	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
 
 	"github.com/op/go-logging"
 	"github.com/up9inc/mizu/cli/config/configStructs"
 	"github.com/up9inc/mizu/cli/mizu"
 	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/cloudauth"
+	"github.com/up9inc/mizu/shared/objectstorage"
+	"github.com/up9inc/mizu/shared/transform"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/util/homedir"
 )
@@ -21,27 +28,66 @@ const (
 )
 
 type ConfigStruct struct {
-	Tap                    configStructs.TapConfig     `yaml:"tap"`
-	Check                  configStructs.CheckConfig   `yaml:"check"`
-	Install                configStructs.InstallConfig `yaml:"install"`
-	Version                configStructs.VersionConfig `yaml:"version"`
-	View                   configStructs.ViewConfig    `yaml:"view"`
-	Logs                   configStructs.LogsConfig    `yaml:"logs"`
-	Auth                   configStructs.AuthConfig    `yaml:"auth"`
-	Config                 configStructs.ConfigConfig  `yaml:"config,omitempty"`
-	AgentImage             string                      `yaml:"agent-image,omitempty" readonly:""`
-	ImagePullPolicyStr     string                      `yaml:"image-pull-policy" default:"Always"`
-	MizuResourcesNamespace string                      `yaml:"mizu-resources-namespace" default:"mizu"`
-	Telemetry              bool                        `yaml:"telemetry" default:"true"`
-	DumpLogs               bool                        `yaml:"dump-logs" default:"false"`
-	KubeConfigPathStr      string                      `yaml:"kube-config-path"`
-	KubeContext            string                      `yaml:"kube-context"`
-	ConfigFilePath         string                      `yaml:"config-path,omitempty" readonly:""`
-	HeadlessMode           bool                        `yaml:"headless" default:"false"`
-	LogLevelStr            string                      `yaml:"log-level,omitempty" default:"INFO" readonly:""`
-	ServiceMap             bool                        `yaml:"service-map" default:"true"`
-	OAS                    bool                        `yaml:"oas,omitempty" default:"false" readonly:""`
-	Elastic                shared.ElasticConfig        `yaml:"elastic"`
+	Tap                    configStructs.TapConfig        `yaml:"tap"`
+	Check                  configStructs.CheckConfig      `yaml:"check"`
+	Doctor                 configStructs.DoctorConfig     `yaml:"doctor"`
+	Install                configStructs.InstallConfig    `yaml:"install"`
+	Version                configStructs.VersionConfig    `yaml:"version"`
+	View                   configStructs.ViewConfig       `yaml:"view"`
+	Attach                 configStructs.AttachConfig     `yaml:"attach"`
+	Logs                   configStructs.LogsConfig       `yaml:"logs"`
+	Selftest               configStructs.SelftestConfig   `yaml:"selftest"`
+	Demo                   configStructs.DemoConfig       `yaml:"demo"`
+	Show                   configStructs.ShowConfig       `yaml:"show"`
+	Send                   configStructs.SendConfig       `yaml:"send"`
+	Fetch                  configStructs.FetchConfig      `yaml:"fetch"`
+	Export                 configStructs.ExportConfig     `yaml:"export"`
+	Replay                 configStructs.ReplayConfig     `yaml:"replay"`
+	Manifests              configStructs.ManifestsConfig  `yaml:"manifests"`
+	Verify                 configStructs.VerifyConfig     `yaml:"verify"`
+	Compare                configStructs.CompareConfig    `yaml:"compare"`
+	Graph                  configStructs.GraphConfig      `yaml:"graph"`
+	Validate               configStructs.ValidateConfig   `yaml:"validate"`
+	Mute                   configStructs.MuteConfig       `yaml:"mute"`
+	Query                  configStructs.QueryConfig      `yaml:"query"`
+	Alerts                 configStructs.AlertsConfig     `yaml:"alerts"`
+	Redact                 configStructs.RedactConfig     `yaml:"redact"`
+	Auth                   configStructs.AuthConfig       `yaml:"auth"`
+	Config                 configStructs.ConfigConfig     `yaml:"config,omitempty"`
+	AgentImage             string                         `yaml:"agent-image,omitempty" readonly:""`
+	ImagePullPolicyStr     string                         `yaml:"image-pull-policy" default:"Always"`
+	MizuResourcesNamespace string                         `yaml:"mizu-resources-namespace" default:"mizu"`
+	Telemetry              bool                           `yaml:"telemetry" default:"true"`
+	DumpLogs               bool                           `yaml:"dump-logs" default:"false"`
+	KubeConfigPathStr      string                         `yaml:"kube-config-path"`
+	KubeContext            string                         `yaml:"kube-context"`
+	ClusterName            string                         `yaml:"cluster-name"`
+	ConfigFilePath         string                         `yaml:"config-path,omitempty" readonly:""`
+	HeadlessMode           bool                           `yaml:"headless" default:"false"`
+	LogLevelStr            string                         `yaml:"log-level,omitempty" default:"INFO" readonly:""`
+	ServiceMap             bool                           `yaml:"service-map" default:"true"`
+	OAS                    bool                           `yaml:"oas,omitempty" default:"false" readonly:""`
+	Stats                  bool                           `yaml:"stats" default:"true"`
+	Metrics                bool                           `yaml:"metrics" default:"true"`
+	Insights               bool                           `yaml:"insights" default:"true"`
+	Elastic                shared.ElasticConfig           `yaml:"elastic"`
+	Kafka                  shared.KafkaConfig             `yaml:"kafka"`
+	Sinks                  []shared.SinkConfig            `yaml:"sinks"`
+	Archive                shared.ArchiveConfig           `yaml:"archive"`
+	QueryCache             shared.QueryCacheConfig        `yaml:"query-cache"`
+	QueryLimits            shared.QueryLimitsConfig       `yaml:"query-limits"`
+	SocketKeepAlive        shared.SocketKeepAliveConfig   `yaml:"socket-keepalive"`
+	Mirror                 shared.MirrorConfig            `yaml:"mirror"`
+	Issues                 shared.IssuesConfig            `yaml:"issues"`
+	Provenance             shared.ProvenanceConfig        `yaml:"provenance"`
+	Enrichment             shared.EnrichmentConfig        `yaml:"enrichment"`
+	Timestamps             shared.TimestampConfig         `yaml:"timestamps"`
+	Summary                shared.SummaryConfig           `yaml:"summary"`
+	LifecycleWebhooks      shared.LifecycleWebhooksConfig `yaml:"lifecycle-webhooks"`
+	ChatOps                shared.ChatOpsConfig           `yaml:"chatops"`
+	Watermarks             shared.WatermarksConfig        `yaml:"watermarks"`
+	Retention              shared.RetentionConfig         `yaml:"retention"`
+	CloudIdentity          shared.CloudIdentityConfig     `yaml:"cloud-identity"`
 }
 
 func (config *ConfigStruct) validate() error {
@@ -49,6 +95,338 @@ func (config *ConfigStruct) validate() error {
 		return fmt.Errorf("%s is not a valid log level, err: %v", config.LogLevelStr, err)
 	}
 
+	if config.Elastic.Transform != "" {
+		if _, err := transform.Compile(config.Elastic.Transform); err != nil {
+			return fmt.Errorf("elastic transform is invalid, err: %v", err)
+		}
+	}
+
+	if config.Elastic.Url != "" {
+		if config.Elastic.Index == "" || config.Elastic.Index != strings.ToLower(config.Elastic.Index) {
+			return fmt.Errorf("%s is not a valid elastic index, it must be a non empty lowercase name", config.Elastic.Index)
+		}
+
+		if config.Elastic.BulkSize <= 0 {
+			return fmt.Errorf("elastic bulk size must be greater than 0")
+		}
+
+		if err := cloudauth.Validate(config.Elastic.Auth, "elastic", cloudauth.AuthAws); err != nil {
+			return err
+		}
+	}
+
+	if _, err := shared.NewTimestampFormatter(config.Timestamps); err != nil {
+		return err
+	}
+
+	for protocol, fields := range config.Summary {
+		for _, field := range fields {
+			if _, err := transform.Compile(field.Field); err != nil {
+				return fmt.Errorf("%s summary field is invalid, err: %v", protocol, err)
+			}
+			if field.MaxLength < 0 {
+				return fmt.Errorf("%s summary field %s max length must not be negative", protocol, field.Field)
+			}
+		}
+	}
+
+	if config.Mirror.Url != "" {
+		if mirrorUrl, err := url.Parse(config.Mirror.Url); err != nil || mirrorUrl.Scheme == "" || mirrorUrl.Host == "" {
+			return fmt.Errorf("%s is not a valid mirror url", config.Mirror.Url)
+		}
+
+		if config.Mirror.SampleRate <= 0 || config.Mirror.SampleRate > 1 {
+			return fmt.Errorf("mirror sample rate must be greater than 0 and at most 1")
+		}
+
+		if config.Mirror.MaxRequestsPerSec <= 0 {
+			return fmt.Errorf("mirror max requests per second must be greater than 0")
+		}
+	}
+
+	if len(config.Kafka.Brokers) > 0 {
+		if config.Kafka.Topic == "" {
+			return fmt.Errorf("kafka topic is required when kafka brokers are set")
+		}
+
+		if config.Kafka.Format != shared.KafkaFormatJson && config.Kafka.Format != shared.KafkaFormatAvro {
+			return fmt.Errorf("%s is not a valid kafka format, the formats are %s and %s", config.Kafka.Format, shared.KafkaFormatJson, shared.KafkaFormatAvro)
+		}
+
+		if config.Kafka.SaslMechanism != "" && config.Kafka.SaslMechanism != shared.KafkaSaslMechanismPlain {
+			return fmt.Errorf("%s is not a supported kafka sasl mechanism, the supported mechanism is %s", config.Kafka.SaslMechanism, shared.KafkaSaslMechanismPlain)
+		}
+
+		if config.Kafka.SaslMechanism != "" && (config.Kafka.User == "" || config.Kafka.Password == "") {
+			return fmt.Errorf("kafka user and password are required with a sasl mechanism")
+		}
+
+		if err := validateKafkaAuth(config.Kafka, "kafka"); err != nil {
+			return err
+		}
+	}
+
+	if err := config.validateSinks(); err != nil {
+		return err
+	}
+
+	if err := config.validateCloudIdentity(); err != nil {
+		return err
+	}
+
+	if config.Archive.Url != "" {
+		if _, err := objectstorage.ParseUrl(config.Archive.Url); err != nil {
+			return fmt.Errorf("invalid archive url, %w", err)
+		}
+
+		if config.Archive.IntervalSec <= 0 {
+			return fmt.Errorf("archive interval must be greater than 0")
+		}
+
+		if config.Archive.RetentionDays < 0 {
+			return fmt.Errorf("archive retention days can't be negative")
+		}
+
+		if err := validateArchiveAuth(config.Archive); err != nil {
+			return err
+		}
+	}
+
+	if config.QueryCache.MaxResults < 0 {
+		return fmt.Errorf("query cache max results can't be negative")
+	}
+
+	if config.QueryCache.MaxResults > 0 && config.QueryCache.TtlSec <= 0 {
+		return fmt.Errorf("query cache ttl must be greater than 0")
+	}
+
+	if config.QueryLimits.MaxConcurrent < 0 || config.QueryLimits.MaxTimeoutSec < 0 {
+		return fmt.Errorf("query limits can't be negative")
+	}
+
+	if config.SocketKeepAlive.PingIntervalSec < 0 || config.SocketKeepAlive.IdleTimeoutSec < 0 {
+		return fmt.Errorf("socket keepalive can't be negative")
+	}
+
+	// the pongs have to arrive within the idle timeout, with some slack for slow links
+	if config.SocketKeepAlive.IdleTimeoutSec > 0 && config.SocketKeepAlive.PingIntervalSec > 0 && config.SocketKeepAlive.IdleTimeoutSec <= config.SocketKeepAlive.PingIntervalSec {
+		return fmt.Errorf("socket keepalive idle timeout must be greater than its ping interval")
+	}
+
+	if config.Issues.SentryDsn != "" {
+		if dsn, err := url.Parse(config.Issues.SentryDsn); err != nil || dsn.Scheme == "" || dsn.Host == "" || dsn.User == nil {
+			return fmt.Errorf("%s is not a valid sentry dsn", config.Issues.SentryDsn)
+		}
+	}
+
+	if config.Issues.WebhookUrl != "" {
+		if webhookUrl, err := url.Parse(config.Issues.WebhookUrl); err != nil || webhookUrl.Scheme == "" || webhookUrl.Host == "" {
+			return fmt.Errorf("%s is not a valid issues webhook url", config.Issues.WebhookUrl)
+		}
+	}
+
+	if (config.Issues.SentryDsn != "" || config.Issues.WebhookUrl != "") && config.Issues.MaxPerMinute <= 0 {
+		return fmt.Errorf("issues max per minute must be greater than 0")
+	}
+
+	for _, webhookUrl := range config.LifecycleWebhooks.Urls {
+		if parsedUrl, err := url.Parse(webhookUrl); err != nil || parsedUrl.Scheme == "" || parsedUrl.Host == "" {
+			return fmt.Errorf("%s is not a valid lifecycle webhook url", webhookUrl)
+		}
+	}
+
+	for _, event := range config.LifecycleWebhooks.Events {
+		if !shared.Contains(shared.LifecycleEvents, event) {
+			return fmt.Errorf("%s is not a lifecycle event, the events are %s", event, strings.Join(shared.LifecycleEvents, ", "))
+		}
+	}
+
+	if config.LifecycleWebhooks.StorageThresholdPercent < 1 || config.LifecycleWebhooks.StorageThresholdPercent > 100 {
+		return fmt.Errorf("lifecycle webhooks storage threshold percent must be between 1 and 100")
+	}
+
+	if config.ChatOps.LinkUrl != "" {
+		if linkUrl, err := url.Parse(config.ChatOps.LinkUrl); err != nil || linkUrl.Scheme == "" || linkUrl.Host == "" {
+			return fmt.Errorf("%s is not a valid chatops link url", config.ChatOps.LinkUrl)
+		}
+	}
+
+	defaultDuration, err := time.ParseDuration(config.ChatOps.DefaultDuration)
+	if err != nil || defaultDuration <= 0 {
+		return fmt.Errorf("chatops default duration must be a positive duration, like 15m")
+	}
+
+	if maxDuration, err := time.ParseDuration(config.ChatOps.MaxDuration); err != nil || maxDuration < defaultDuration {
+		return fmt.Errorf("chatops max duration must be a duration of at least the default duration, like 2h")
+	}
+
+	if config.Watermarks.LowPercent <= 0 || config.Watermarks.LowPercent >= config.Watermarks.HighPercent ||
+		config.Watermarks.HighPercent >= config.Watermarks.CriticalPercent || config.Watermarks.CriticalPercent > 100 {
+		return fmt.Errorf("watermarks must grow from the low percent to the high percent to the critical percent, between 1 and 100")
+	}
+
+	if config.Watermarks.SampleRate <= 0 || config.Watermarks.SampleRate > 1 {
+		return fmt.Errorf("watermarks sample rate must be greater than 0 and at most 1")
+	}
+
+	if config.Watermarks.IntervalSec < 0 {
+		return fmt.Errorf("watermarks interval can't be negative")
+	}
+
+	if config.Retention.MaxEntries < 0 || config.Retention.MaxAgeSec < 0 || config.Retention.IntervalSec < 0 {
+		return fmt.Errorf("retention max entries, max age and interval can't be negative")
+	}
+
+	if (config.Retention.MaxEntries > 0 || config.Retention.MaxAgeSec > 0) && config.Retention.IntervalSec == 0 {
+		return fmt.Errorf("retention interval must be greater than 0 to bound the entries by count or by age")
+	}
+
+	if config.Provenance.SecretName != "" {
+		if config.Provenance.SegmentSize <= 0 {
+			return fmt.Errorf("provenance segment size must be greater than 0")
+		}
+
+		// the chain is verified by looking up the entries by their ulid, as they were inserted
+		if config.Tap.EntryIdScheme == shared.EntryIdSchemeIndex {
+			return fmt.Errorf("provenance requires the %s entry id scheme", shared.EntryIdSchemeUlid)
+		}
+
+		if config.Tap.InsertionFilter != "" {
+			return fmt.Errorf("provenance can't be combined with an insertion filter, entries dropped or redacted by the filter fail the verification")
+		}
+	}
+
+	return nil
+}
+
+// a larger batch of entries would be posted as a body of tens of megabytes
+const maxWebhookBatchSize = 1000
+
+var sinkNamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+func (config *ConfigStruct) validateSinks() error {
+	names := make(map[string]bool)
+	for _, sink := range config.Sinks {
+		// the name of a sink names its export queue file
+		if !sinkNamePattern.MatchString(sink.Name) {
+			return fmt.Errorf("%s is not a valid sink name, it must be a lowercase name of letters, digits and dashes", sink.Name)
+		}
+		if names[sink.Name] {
+			return fmt.Errorf("there are several sinks named %s", sink.Name)
+		}
+		names[sink.Name] = true
+
+		if sink.Delivery != shared.SinkDeliveryAtLeastOnce && sink.Delivery != shared.SinkDeliveryBestEffort {
+			return fmt.Errorf("%s is not a valid delivery of sink %s, the deliveries are %s and %s", sink.Delivery, sink.Name, shared.SinkDeliveryAtLeastOnce, shared.SinkDeliveryBestEffort)
+		}
+
+		if sink.Transform != "" {
+			if _, err := transform.Compile(sink.Transform); err != nil {
+				return fmt.Errorf("transform of sink %s is invalid, err: %v", sink.Name, err)
+			}
+		}
+
+		switch sink.Type {
+		case shared.SinkTypeElastic:
+			if sink.Elastic.Url == "" || (sink.Elastic.Auth == "" && (sink.Elastic.User == "" || sink.Elastic.Password == "")) {
+				return fmt.Errorf("elastic url, user and password are required by sink %s, or an elastic url with an auth", sink.Name)
+			}
+			if err := cloudauth.Validate(sink.Elastic.Auth, fmt.Sprintf("the elastic of sink %s", sink.Name), cloudauth.AuthAws); err != nil {
+				return err
+			}
+			if sink.Elastic.Index == "" || sink.Elastic.Index != strings.ToLower(sink.Elastic.Index) {
+				return fmt.Errorf("%s is not a valid elastic index of sink %s, it must be a non empty lowercase name", sink.Elastic.Index, sink.Name)
+			}
+			if sink.Elastic.BulkSize <= 0 {
+				return fmt.Errorf("elastic bulk size of sink %s must be greater than 0", sink.Name)
+			}
+		case shared.SinkTypeKafka:
+			if len(sink.Kafka.Brokers) == 0 || sink.Kafka.Topic == "" {
+				return fmt.Errorf("kafka brokers and topic are required by sink %s", sink.Name)
+			}
+			if sink.Kafka.Format != shared.KafkaFormatJson && sink.Kafka.Format != shared.KafkaFormatAvro {
+				return fmt.Errorf("%s is not a valid kafka format of sink %s, the formats are %s and %s", sink.Kafka.Format, sink.Name, shared.KafkaFormatJson, shared.KafkaFormatAvro)
+			}
+			if sink.Kafka.Format == shared.KafkaFormatAvro && sink.Transform != "" {
+				return fmt.Errorf("sink %s can't transform the avro messages, their schema is fixed", sink.Name)
+			}
+			if sink.Kafka.SaslMechanism != "" && (sink.Kafka.SaslMechanism != shared.KafkaSaslMechanismPlain || sink.Kafka.User == "" || sink.Kafka.Password == "") {
+				return fmt.Errorf("kafka sasl mechanism of sink %s must be %s, with a user and a password", sink.Name, shared.KafkaSaslMechanismPlain)
+			}
+			if err := validateKafkaAuth(sink.Kafka, fmt.Sprintf("the kafka of sink %s", sink.Name)); err != nil {
+				return err
+			}
+		case shared.SinkTypeWebhook, shared.SinkTypeSlack:
+			if webhookUrl, err := url.Parse(sink.WebhookUrl); err != nil || webhookUrl.Scheme == "" || webhookUrl.Host == "" {
+				return fmt.Errorf("%s is not a valid webhook url of sink %s", sink.WebhookUrl, sink.Name)
+			}
+			if sink.WebhookBatchSize < 1 || sink.WebhookBatchSize > maxWebhookBatchSize {
+				return fmt.Errorf("the webhook batch size of sink %s must be between 1 and %d", sink.Name, maxWebhookBatchSize)
+			}
+		default:
+			return fmt.Errorf("%s is not a valid type of sink %s, the types are %s", sink.Type, sink.Name,
+				strings.Join([]string{shared.SinkTypeElastic, shared.SinkTypeKafka, shared.SinkTypeWebhook, shared.SinkTypeSlack}, ", "))
+		}
+	}
+
+	return nil
+}
+
+func validateKafkaAuth(kafka shared.KafkaConfig, destination string) error {
+	if err := cloudauth.Validate(kafka.Auth, destination, cloudauth.AuthAws, cloudauth.AuthGcp, cloudauth.AuthAzure); err != nil {
+		return err
+	}
+
+	if kafka.Auth != "" && kafka.SaslMechanism != "" {
+		return fmt.Errorf("%s can't have both an auth and a sasl mechanism, the auth brings its own mechanism", destination)
+	}
+
+	return nil
+}
+
+func validateArchiveAuth(archive shared.ArchiveConfig) error {
+	if archive.Auth == "" {
+		return nil
+	}
+
+	location, err := objectstorage.ParseUrl(archive.Url)
+	if err != nil {
+		return err
+	}
+
+	supportedAuth := cloudauth.AuthAws
+	if location.Scheme == objectstorage.SchemeGcs {
+		supportedAuth = cloudauth.AuthGcp
+	}
+
+	return cloudauth.Validate(archive.Auth, fmt.Sprintf("%s archives", location.Scheme), supportedAuth)
+}
+
+// validateCloudIdentity makes sure the service account of the api server is bound to a cloud identity of every cloud
+// auth of the destinations, the destinations would fail authenticating without it
+func (config *ConfigStruct) validateCloudIdentity() error {
+	auths := []string{config.Archive.Auth}
+	if config.Elastic.Url != "" {
+		auths = append(auths, config.Elastic.Auth)
+	}
+	if len(config.Kafka.Brokers) > 0 {
+		auths = append(auths, config.Kafka.Auth)
+	}
+	for _, sink := range config.Sinks {
+		auths = append(auths, sink.Elastic.Auth, sink.Kafka.Auth)
+	}
+
+	for _, auth := range auths {
+		switch {
+		case auth == cloudauth.AuthAws && config.CloudIdentity.AwsRoleArn == "":
+			return fmt.Errorf("the %s auth requires the cloud-identity.aws-role-arn of the role to authenticate as", auth)
+		case auth == cloudauth.AuthGcp && config.CloudIdentity.GcpServiceAccount == "":
+			return fmt.Errorf("the %s auth requires the cloud-identity.gcp-service-account to authenticate as", auth)
+		case auth == cloudauth.AuthAzure && config.CloudIdentity.AzureClientId == "":
+			return fmt.Errorf("the %s auth requires the cloud-identity.azure-client-id of the managed identity to authenticate as", auth)
+		}
+	}
+
 	return nil
 }
 
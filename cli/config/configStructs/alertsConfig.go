@@ -0,0 +1,74 @@
+package configStructs
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	AlertsActionList   = "list"
+	AlertsActionAdd    = "add"
+	AlertsActionDelete = "delete"
+)
+
+const (
+	ThresholdAlertsName  = "threshold"
+	WindowAlertsName     = "window"
+	CooldownAlertsName   = "cooldown"
+	WebhookUrlAlertsName = "webhook-url"
+	SlackUrlAlertsName   = "slack-url"
+	GuiPortAlertsName    = "gui-port"
+	UrlAlertsName        = "url"
+)
+
+type AlertsConfig struct {
+	Action    string `yaml:"-"`
+	Name      string `yaml:"-"`
+	Query     string `yaml:"-"`
+	RuleId    int    `yaml:"-"`
+	Threshold int    `yaml:"threshold" default:"1"`
+	Window    string `yaml:"window" default:"1m"`
+	Cooldown  string `yaml:"cooldown"`
+	// the urls are kept out of the telemetry, a slack webhook url is a secret
+	WebhookUrl string `yaml:"webhook-url" json:"-"`
+	SlackUrl   string `yaml:"slack-url" json:"-"`
+	GuiPort    uint16 `yaml:"gui-port" default:"8899"`
+	Url        string `yaml:"url,omitempty" readonly:""`
+}
+
+func (config *AlertsConfig) WindowDuration() time.Duration {
+	window, _ := time.ParseDuration(config.Window)
+	return window
+}
+
+// CooldownDuration is 0 when no cooldown is set, the API server uses the window then
+func (config *AlertsConfig) CooldownDuration() time.Duration {
+	cooldown, _ := time.ParseDuration(config.Cooldown)
+	return cooldown
+}
+
+func (config *AlertsConfig) Validate() error {
+	if config.Action != AlertsActionAdd {
+		return nil
+	}
+
+	if config.Threshold <= 0 {
+		return fmt.Errorf("--%s must be greater than 0", ThresholdAlertsName)
+	}
+
+	if window, err := time.ParseDuration(config.Window); err != nil || window < time.Second {
+		return fmt.Errorf("--%s must be a duration of at least 1s, like 5m", WindowAlertsName)
+	}
+
+	if config.Cooldown != "" {
+		if cooldown, err := time.ParseDuration(config.Cooldown); err != nil || cooldown < 0 {
+			return fmt.Errorf("--%s must be a duration, like 30m", CooldownAlertsName)
+		}
+	}
+
+	if config.WebhookUrl == "" && config.SlackUrl == "" {
+		return fmt.Errorf("--%s or --%s is required to send the alerts to", WebhookUrlAlertsName, SlackUrlAlertsName)
+	}
+
+	return nil
+}
@@ -0,0 +1,9 @@
+package configStructs
+
+const (
+	GuiPortAttachName = "gui-port"
+)
+
+type AttachConfig struct {
+	GuiPort uint16 `yaml:"gui-port" default:"8899"`
+}
@@ -1,9 +1,40 @@
 package configStructs
 
+import "fmt"
+
 const (
-	PreTapCheckName = "pre-tap"
+	PreTapCheckName     = "pre-tap"
+	JsonCheckName       = "json"
+	FixCheckName        = "fix"
+	CiCheckName         = "ci"
+	JunitFileCheckName  = "junit-file"
+	AsCheckName         = "as"
+	AsGroupCheckName    = "as-group"
+	ReportCheckName     = "report"
+	ReportFileCheckName = "report-file"
 )
 
 type CheckConfig struct {
-	PreTap bool `yaml:"pre-tap"`
+	PreTap     bool     `yaml:"pre-tap"`
+	Json       bool     `yaml:"json"`
+	Fix        bool     `yaml:"fix"`
+	Ci         bool     `yaml:"ci"`
+	JunitFile  string   `yaml:"junit-file" default:"mizu-check-junit.xml"`
+	As         string   `yaml:"as"`
+	AsGroups   []string `yaml:"as-group"`
+	Report     bool     `yaml:"report"`
+	ReportFile string   `yaml:"report-file" default:"mizu-check-report.tar.gz"`
+}
+
+func (config *CheckConfig) Validate() error {
+	// the permissions of another user are only checked before tapping
+	if config.As != "" && !config.PreTap {
+		return fmt.Errorf("--%s requires --%s", AsCheckName, PreTapCheckName)
+	}
+
+	if len(config.AsGroups) > 0 && config.As == "" {
+		return fmt.Errorf("--%s requires --%s", AsGroupCheckName, AsCheckName)
+	}
+
+	return nil
 }
@@ -0,0 +1,64 @@
+package configStructs
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	StableCompareName  = "stable"
+	CanaryCompareName  = "canary"
+	LimitCompareName   = "limit"
+	FromCompareName    = "from"
+	ToCompareName      = "to"
+	JsonCompareName    = "json"
+	GuiPortCompareName = "gui-port"
+	UrlCompareName     = "url"
+)
+
+type CompareConfig struct {
+	Stable  string `yaml:"stable"`
+	Canary  string `yaml:"canary"`
+	Limit   int    `yaml:"limit" default:"10000"`
+	From    string `yaml:"from"`
+	To      string `yaml:"to"`
+	Json    bool   `yaml:"json"`
+	GuiPort uint16 `yaml:"gui-port" default:"8899"`
+	Url     string `yaml:"url,omitempty" readonly:""`
+}
+
+// TimeRange returns the unix milliseconds of --from and --to, 0 when not set, in the format of the export command
+func (config *CompareConfig) TimeRange(now time.Time) (int64, int64, error) {
+	from, err := parseExportTime(config.From, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --%s value %s, expected an RFC 3339 time or a duration like 30m", FromCompareName, config.From)
+	}
+
+	to, err := parseExportTime(config.To, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --%s value %s, expected an RFC 3339 time or a duration like 30m", ToCompareName, config.To)
+	}
+
+	return from, to, nil
+}
+
+func (config *CompareConfig) Validate() error {
+	if config.Stable == "" || config.Canary == "" {
+		return fmt.Errorf("both --%s and --%s queries are required", StableCompareName, CanaryCompareName)
+	}
+
+	if config.Limit <= 0 {
+		return fmt.Errorf("--%s must be greater than 0", LimitCompareName)
+	}
+
+	from, to, err := config.TimeRange(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if from > 0 && to > 0 && from > to {
+		return fmt.Errorf("--%s must be before --%s", FromCompareName, ToCompareName)
+	}
+
+	return nil
+}
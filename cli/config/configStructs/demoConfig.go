@@ -0,0 +1,13 @@
+package configStructs
+
+const (
+	GuiPortDemoName     = "gui-port"
+	CapturePathDemoName = "capture"
+	UiPathDemoName      = "ui-path"
+)
+
+type DemoConfig struct {
+	GuiPort     uint16 `yaml:"gui-port" default:"8899"`
+	CapturePath string `yaml:"capture"`
+	UiPath      string `yaml:"ui-path"`
+}
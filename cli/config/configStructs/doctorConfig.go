@@ -0,0 +1,9 @@
+package configStructs
+
+const (
+	JsonDoctorName = "json"
+)
+
+type DoctorConfig struct {
+	Json bool `yaml:"json"`
+}
@@ -0,0 +1,84 @@
+package configStructs
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	FormatExportName  = "format"
+	OutExportName     = "out"
+	QueryExportName   = "query"
+	LimitExportName   = "limit"
+	FromExportName    = "from"
+	ToExportName      = "to"
+	GuiPortExportName = "gui-port"
+	UrlExportName     = "url"
+
+	HarExportFormat = "har"
+)
+
+type ExportConfig struct {
+	Format  string `yaml:"format" default:"har"`
+	Out     string `yaml:"out"`
+	Query   string `yaml:"query"`
+	Limit   int    `yaml:"limit" default:"1000"`
+	From    string `yaml:"from"`
+	To      string `yaml:"to"`
+	GuiPort uint16 `yaml:"gui-port" default:"8899"`
+	Url     string `yaml:"url,omitempty" readonly:""`
+}
+
+// TimeRange returns the unix milliseconds of --from and --to, 0 when not set. Both accept either an RFC 3339
+// timestamp or a duration back from now, like 30m
+func (config *ExportConfig) TimeRange(now time.Time) (int64, int64, error) {
+	from, err := parseExportTime(config.From, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --%s value %s, expected an RFC 3339 time or a duration like 30m", FromExportName, config.From)
+	}
+
+	to, err := parseExportTime(config.To, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --%s value %s, expected an RFC 3339 time or a duration like 30m", ToExportName, config.To)
+	}
+
+	return from, to, nil
+}
+
+func (config *ExportConfig) Validate() error {
+	if config.Format != HarExportFormat {
+		return fmt.Errorf("unsupported --%s %s, supported formats: %s", FormatExportName, config.Format, HarExportFormat)
+	}
+
+	if config.Limit <= 0 {
+		return fmt.Errorf("--%s must be greater than 0", LimitExportName)
+	}
+
+	from, to, err := config.TimeRange(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if from > 0 && to > 0 && from > to {
+		return fmt.Errorf("--%s must be before --%s", FromExportName, ToExportName)
+	}
+
+	return nil
+}
+
+func parseExportTime(value string, now time.Time) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	if duration, err := time.ParseDuration(value); err == nil {
+		return now.Add(-duration).UnixMilli(), nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return 0, err
+	}
+
+	return parsed.UnixMilli(), nil
+}
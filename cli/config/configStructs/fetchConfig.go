@@ -0,0 +1,71 @@
+package configStructs
+
+import (
+	"fmt"
+
+	"github.com/up9inc/mizu/shared/transform"
+	"github.com/up9inc/mizu/shared/units"
+)
+
+const (
+	QueryFetchName            = "query"
+	LimitFetchName            = "limit"
+	OutputFetchName           = "output"
+	TransformFetchName        = "transform"
+	FollowFetchName           = "follow"
+	SinceCursorFetchName      = "since-cursor"
+	HumanMaxFileSizeFetchName = "max-file-size"
+	MaxFilesFetchName         = "max-files"
+	GuiPortFetchName          = "gui-port"
+	UrlFetchName              = "url"
+)
+
+type FetchConfig struct {
+	Query            string `yaml:"query"`
+	Limit            int    `yaml:"limit" default:"100"`
+	Output           string `yaml:"output"`
+	Transform        string `yaml:"transform"`
+	Follow           bool   `yaml:"follow"`
+	SinceCursor      string `yaml:"since-cursor"`
+	HumanMaxFileSize string `yaml:"max-file-size" default:"100MB"`
+	MaxFiles         int    `yaml:"max-files" default:"5"`
+	GuiPort          uint16 `yaml:"gui-port" default:"8899"`
+	Url              string `yaml:"url,omitempty" readonly:""`
+}
+
+func (config *FetchConfig) MaxFileSizeBytes() int64 {
+	maxFileSizeBytes, _ := units.HumanReadableToBytes(config.HumanMaxFileSize)
+	return maxFileSizeBytes
+}
+
+func (config *FetchConfig) Validate() error {
+	if config.Limit <= 0 {
+		return fmt.Errorf("--%s must be greater than 0", LimitFetchName)
+	}
+
+	if config.Transform != "" {
+		if _, err := transform.Compile(config.Transform); err != nil {
+			return err
+		}
+	}
+
+	if config.Follow && config.SinceCursor != "" {
+		return fmt.Errorf("--%s can't be used with --%s, a follow keeps its cursor next to the output", SinceCursorFetchName, FollowFetchName)
+	}
+
+	if config.Follow {
+		if config.Output == "" {
+			return fmt.Errorf("--%s requires --%s", FollowFetchName, OutputFetchName)
+		}
+
+		if maxFileSize, err := units.HumanReadableToBytes(config.HumanMaxFileSize); err != nil || maxFileSize <= 0 {
+			return fmt.Errorf("Could not parse --%s value %s", HumanMaxFileSizeFetchName, config.HumanMaxFileSize)
+		}
+
+		if config.MaxFiles <= 0 {
+			return fmt.Errorf("--%s must be greater than 0", MaxFilesFetchName)
+		}
+	}
+
+	return nil
+}
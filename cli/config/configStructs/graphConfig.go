@@ -0,0 +1,28 @@
+package configStructs
+
+import "fmt"
+
+const (
+	OutputGraphName  = "output"
+	FileGraphName    = "file"
+	GuiPortGraphName = "gui-port"
+	UrlGraphName     = "url"
+
+	GraphOutputDot  = "dot"
+	GraphOutputJson = "json"
+)
+
+type GraphConfig struct {
+	Output  string `yaml:"output" default:"dot"`
+	File    string `yaml:"file"`
+	GuiPort uint16 `yaml:"gui-port" default:"8899"`
+	Url     string `yaml:"url,omitempty" readonly:""`
+}
+
+func (config *GraphConfig) Validate() error {
+	if config.Output != GraphOutputDot && config.Output != GraphOutputJson {
+		return fmt.Errorf("%s is not a valid --%s, the outputs are %s and %s", config.Output, OutputGraphName, GraphOutputDot, GraphOutputJson)
+	}
+
+	return nil
+}
@@ -0,0 +1,34 @@
+package configStructs
+
+import (
+	"fmt"
+)
+
+const (
+	OutputManifestsName = "output"
+	DirManifestsName    = "dir"
+)
+
+const (
+	ManifestsOutputYaml = "yaml"
+	ManifestsOutputHelm = "helm"
+)
+
+type ManifestsConfig struct {
+	Output string `yaml:"output" default:"yaml"`
+	Dir    string `yaml:"dir"`
+}
+
+func (config *ManifestsConfig) Validate() error {
+	switch config.Output {
+	case ManifestsOutputYaml:
+	case ManifestsOutputHelm:
+		if config.Dir == "" {
+			return fmt.Errorf("--%s is required with --%s %s", DirManifestsName, OutputManifestsName, ManifestsOutputHelm)
+		}
+	default:
+		return fmt.Errorf("--%s must be %s or %s", OutputManifestsName, ManifestsOutputYaml, ManifestsOutputHelm)
+	}
+
+	return nil
+}
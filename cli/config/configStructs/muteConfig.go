@@ -0,0 +1,29 @@
+package configStructs
+
+import (
+	"fmt"
+)
+
+const (
+	ProtocolMuteName  = "protocol"
+	NamespaceMuteName = "namespace"
+	UnmuteMuteName    = "unmute"
+	GuiPortMuteName   = "gui-port"
+	UrlMuteName       = "url"
+)
+
+type MuteConfig struct {
+	Protocols  []string `yaml:"protocol"`
+	Namespaces []string `yaml:"namespace"`
+	Unmute     bool     `yaml:"unmute"`
+	GuiPort    uint16   `yaml:"gui-port" default:"8899"`
+	Url        string   `yaml:"url,omitempty" readonly:""`
+}
+
+func (config *MuteConfig) Validate() error {
+	if config.Unmute && len(config.Protocols) == 0 && len(config.Namespaces) == 0 {
+		return fmt.Errorf("--%s requires a --%s or a --%s", UnmuteMuteName, ProtocolMuteName, NamespaceMuteName)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package configStructs
+
+import (
+	"fmt"
+	"os/user"
+
+	"github.com/up9inc/mizu/shared/transform"
+)
+
+const (
+	QueryActionSave   = "save"
+	QueryActionList   = "list"
+	QueryActionRun    = "run"
+	QueryActionDelete = "delete"
+)
+
+const (
+	OwnerQueryName     = "owner"
+	OverwriteQueryName = "overwrite"
+	LimitQueryName     = "limit"
+	TransformQueryName = "transform"
+	GuiPortQueryName   = "gui-port"
+	UrlQueryName       = "url"
+)
+
+type QueryConfig struct {
+	Action    string `yaml:"-"`
+	Name      string `yaml:"-"`
+	Query     string `yaml:"-"`
+	Owner     string `yaml:"owner"`
+	Overwrite bool   `yaml:"overwrite"`
+	Limit     int    `yaml:"limit" default:"100"`
+	Transform string `yaml:"transform"`
+	GuiPort   uint16 `yaml:"gui-port" default:"8899"`
+	Url       string `yaml:"url,omitempty" readonly:""`
+}
+
+// OwnerOrCurrentUser is the owner of the saved queries, the user running the cli when it isn't set
+func (config *QueryConfig) OwnerOrCurrentUser() string {
+	if config.Owner != "" {
+		return config.Owner
+	}
+
+	if currentUser, err := user.Current(); err == nil {
+		return currentUser.Username
+	}
+	return ""
+}
+
+func (config *QueryConfig) Validate() error {
+	if config.Action == QueryActionRun && config.Limit <= 0 {
+		return fmt.Errorf("--%s must be greater than 0", LimitQueryName)
+	}
+
+	if config.Transform != "" {
+		if _, err := transform.Compile(config.Transform); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
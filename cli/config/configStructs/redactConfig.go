@@ -0,0 +1,38 @@
+package configStructs
+
+import (
+	"fmt"
+)
+
+const RedactActionTest = "test"
+
+const (
+	FileRedactName    = "file"
+	QueryRedactName   = "query"
+	LimitRedactName   = "limit"
+	JsonRedactName    = "json"
+	GuiPortRedactName = "gui-port"
+	UrlRedactName     = "url"
+)
+
+type RedactConfig struct {
+	Action  string `yaml:"-"`
+	File    string `yaml:"file"`
+	Query   string `yaml:"query"`
+	Limit   int    `yaml:"limit" default:"20"`
+	Json    bool   `yaml:"json"`
+	GuiPort uint16 `yaml:"gui-port" default:"8899"`
+	Url     string `yaml:"url,omitempty" readonly:""`
+}
+
+func (config *RedactConfig) Validate() error {
+	if config.File != "" && config.Query != "" {
+		return fmt.Errorf("--%s and --%s can't be used together, the query selects stored entries", FileRedactName, QueryRedactName)
+	}
+
+	if config.Limit <= 0 {
+		return fmt.Errorf("--%s must be positive", LimitRedactName)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package configStructs
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/up9inc/mizu/shared/objectstorage"
+)
+
+const (
+	FromReplayName        = "from"
+	TargetReplayName      = "target"
+	QueryReplayName       = "query"
+	LimitReplayName       = "limit"
+	ConcurrencyReplayName = "concurrency"
+	HeaderReplayName      = "header"
+	TimeoutReplayName     = "timeout"
+	JsonReplayName        = "json"
+	GuiPortReplayName     = "gui-port"
+	UrlReplayName         = "url"
+)
+
+type ReplayConfig struct {
+	From        string   `yaml:"from"`
+	Target      string   `yaml:"target"`
+	Query       string   `yaml:"query"`
+	Limit       int      `yaml:"limit" default:"100"`
+	Concurrency int      `yaml:"concurrency" default:"4"`
+	Headers     []string `yaml:"header"`
+	Timeout     string   `yaml:"timeout" default:"10s"`
+	Json        bool     `yaml:"json"`
+	GuiPort     uint16   `yaml:"gui-port" default:"8899"`
+	Url         string   `yaml:"url,omitempty" readonly:""`
+}
+
+func (config *ReplayConfig) TimeoutDuration() time.Duration {
+	timeout, _ := time.ParseDuration(config.Timeout)
+	return timeout
+}
+
+// ParsedHeaders returns the headers given as "Name: value", an empty value removes the header from the requests
+func (config *ReplayConfig) ParsedHeaders() map[string]string {
+	headers := make(map[string]string, len(config.Headers))
+	for _, header := range config.Headers {
+		name, value, _ := cutHeader(header)
+		headers[name] = value
+	}
+	return headers
+}
+
+func (config *ReplayConfig) Validate() error {
+	if (config.From == "") == (config.Target == "") {
+		return fmt.Errorf("either --%s or --%s is required, like --%s s3://bucket/prefix or --%s http://catalogue.staging", FromReplayName, TargetReplayName, FromReplayName, TargetReplayName)
+	}
+
+	if config.From != "" {
+		if _, err := objectstorage.ParseUrl(config.From); err != nil {
+			return fmt.Errorf("invalid --%s, %w", FromReplayName, err)
+		}
+
+		return nil
+	}
+
+	if target, err := url.Parse(config.Target); err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fmt.Errorf("--%s %s isn't an absolute http or https url, e.g. http://catalogue.staging", TargetReplayName, config.Target)
+	}
+
+	if config.Limit <= 0 {
+		return fmt.Errorf("--%s must be positive", LimitReplayName)
+	}
+
+	if config.Concurrency <= 0 {
+		return fmt.Errorf("--%s must be positive", ConcurrencyReplayName)
+	}
+
+	for _, header := range config.Headers {
+		if _, _, ok := cutHeader(header); !ok {
+			return fmt.Errorf("--%s %q must be in the form \"Name: value\"", HeaderReplayName, header)
+		}
+	}
+
+	if timeout, err := time.ParseDuration(config.Timeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("--%s must be a positive duration, like 10s", TimeoutReplayName)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package configStructs
+
+import (
+	"fmt"
+)
+
+const (
+	NamespaceSelftestName     = "namespace"
+	GuiPortSelftestName       = "gui-port"
+	TimeoutSecSelftestName    = "timeout"
+	KeepResourcesSelftestName = "keep-resources"
+	StartupSelftestName       = "startup"
+)
+
+type SelftestConfig struct {
+	Namespace     string `yaml:"namespace" default:"mizu-selftest"`
+	GuiPort       uint16 `yaml:"gui-port" default:"8899"`
+	TimeoutSec    int    `yaml:"timeout" default:"300"`
+	KeepResources bool   `yaml:"keep-resources" default:"false"`
+	Startup       bool   `yaml:"startup" default:"false"`
+}
+
+func (config *SelftestConfig) Validate() error {
+	if config.Namespace == "" {
+		return fmt.Errorf("--%s can't be empty", NamespaceSelftestName)
+	}
+
+	if config.TimeoutSec <= 0 {
+		return fmt.Errorf("--%s must be a positive number of seconds", TimeoutSecSelftestName)
+	}
+
+	return nil
+}
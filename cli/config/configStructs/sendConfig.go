@@ -0,0 +1,87 @@
+package configStructs
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	MethodSendName         = "method"
+	HeaderSendName         = "header"
+	DataSendName           = "data"
+	GrpcSendName           = "grpc"
+	TimeoutSendName        = "timeout"
+	CaptureTimeoutSendName = "capture-timeout"
+	JsonSendName           = "json"
+	GuiPortSendName        = "gui-port"
+	UrlSendName            = "url"
+)
+
+type SendConfig struct {
+	TargetUrl      string   `yaml:"-"`
+	Method         string   `yaml:"method"`
+	Headers        []string `yaml:"header"`
+	Data           string   `yaml:"data"`
+	Grpc           bool     `yaml:"grpc"`
+	Timeout        string   `yaml:"timeout" default:"10s"`
+	CaptureTimeout string   `yaml:"capture-timeout" default:"5s"`
+	Json           bool     `yaml:"json"`
+	GuiPort        uint16   `yaml:"gui-port" default:"8899"`
+	Url            string   `yaml:"url,omitempty" readonly:""`
+}
+
+func (config *SendConfig) TimeoutDuration() time.Duration {
+	timeout, _ := time.ParseDuration(config.Timeout)
+	return timeout
+}
+
+func (config *SendConfig) CaptureTimeoutDuration() time.Duration {
+	captureTimeout, _ := time.ParseDuration(config.CaptureTimeout)
+	return captureTimeout
+}
+
+// ParsedHeaders returns the headers given as "Name: value", like curl does
+func (config *SendConfig) ParsedHeaders() map[string]string {
+	headers := make(map[string]string, len(config.Headers))
+	for _, header := range config.Headers {
+		name, value, _ := cutHeader(header)
+		headers[name] = value
+	}
+	return headers
+}
+
+func (config *SendConfig) Validate() error {
+	if targetUrl, err := url.Parse(config.TargetUrl); err != nil || (targetUrl.Scheme != "http" && targetUrl.Scheme != "https") || targetUrl.Host == "" {
+		return fmt.Errorf("%s isn't an absolute http or https url, e.g. http://catalogue.sock-shop/catalogue", config.TargetUrl)
+	}
+
+	if config.Grpc && config.Method != "" && !strings.EqualFold(config.Method, "POST") {
+		return fmt.Errorf("--%s can't be used with --%s, gRPC requests are always POSTed", MethodSendName, GrpcSendName)
+	}
+
+	for _, header := range config.Headers {
+		if _, _, ok := cutHeader(header); !ok {
+			return fmt.Errorf("--%s %q must be in the form \"Name: value\"", HeaderSendName, header)
+		}
+	}
+
+	if timeout, err := time.ParseDuration(config.Timeout); err != nil || timeout <= 0 {
+		return fmt.Errorf("--%s must be a positive duration, like 10s", TimeoutSendName)
+	}
+
+	if captureTimeout, err := time.ParseDuration(config.CaptureTimeout); err != nil || captureTimeout <= 0 {
+		return fmt.Errorf("--%s must be a positive duration, like 5s", CaptureTimeoutSendName)
+	}
+
+	return nil
+}
+
+func cutHeader(header string) (string, string, bool) {
+	i := strings.Index(header, ":")
+	if i <= 0 {
+		return "", "", false
+	}
+	return strings.TrimSpace(header[:i]), strings.TrimSpace(header[i+1:]), true
+}
@@ -0,0 +1,12 @@
+package configStructs
+
+const (
+	GuiPortShowName = "gui-port"
+	UrlShowName     = "url"
+)
+
+type ShowConfig struct {
+	EntryId string `yaml:"-"`
+	GuiPort uint16 `yaml:"gui-port" default:"8899"`
+	Url     string `yaml:"url,omitempty" readonly:""`
+}
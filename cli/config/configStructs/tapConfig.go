@@ -14,6 +14,9 @@ import (
 	basenine "github.com/up9inc/basenine/server/lib"
 	"github.com/up9inc/mizu/shared/logger"
 	"github.com/up9inc/mizu/shared/units"
+	"github.com/up9inc/mizu/tap/api"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 const (
@@ -26,35 +29,174 @@ const (
 	HumanMaxEntriesDBSizeTapName  = "max-entries-db-size"
 	InsertionFilterName           = "insertion-filter"
 	DryRunTapName                 = "dry-run"
+	ShowTargetsTapName            = "show-targets"
 	WorkspaceTapName              = "workspace"
 	EnforcePolicyFile             = "traffic-validation-file"
 	ContractFile                  = "contract"
 	ServiceMeshName               = "service-mesh"
 	TlsName                       = "tls"
+	EntryIdSchemeName             = "entry-id-scheme"
+	DeploymentMarkersName         = "deployment-markers"
+	KubernetesEventsName          = "kubernetes-events"
+	RawHeadersName                = "raw-headers"
+	DnsResolutionName             = "dns-resolution"
+	TagHeaderTapName              = "tag-header"
+	DockerTapName                 = "docker"
+	AnnotationsTapName            = "annotations"
+	TapperAuthenticationName      = "tapper-authentication"
+	HumanMaxStreamBandwidthName   = "max-stream-bandwidth"
+	OperatorTapName               = "operator"
+	DaemonTapName                 = "daemon"
+	ProtocolsTapName              = "protocols"
+	KubeContextsTapName           = "kube-context"
+	TargetsFileTapName            = "targets-file"
+	TargetsTapName                = "targets"
+	DetectPiiTapName              = "detect-pii"
+	InterfaceTapName              = "interface"
+	SelectorTapName               = "selector"
+	SampleRateTapName             = "sample-rate"
+	MaxEntriesPerSecTapName       = "max-entries-per-sec"
+	RelayTapName                  = "relay"
+	RelayPoolLabelTapName         = "relay-pool-label"
+	PersistentStorageTapName      = "persistent-storage"
+	StorageClassTapName           = "storage-class"
 )
 
 type TapConfig struct {
-	UploadIntervalSec      int              `yaml:"upload-interval" default:"10"`
-	PodRegexStr            string           `yaml:"regex" default:".*"`
-	GuiPort                uint16           `yaml:"gui-port" default:"8899"`
-	ProxyHost              string           `yaml:"proxy-host" default:"127.0.0.1"`
-	Namespaces             []string         `yaml:"namespaces"`
-	Analysis               bool             `yaml:"analysis" default:"false"`
-	AllNamespaces          bool             `yaml:"all-namespaces" default:"false"`
-	PlainTextFilterRegexes []string         `yaml:"regex-masking"`
-	IgnoredUserAgents      []string         `yaml:"ignored-user-agents"`
-	DisableRedaction       bool             `yaml:"no-redact" default:"false"`
-	HumanMaxEntriesDBSize  string           `yaml:"max-entries-db-size" default:"200MB"`
-	InsertionFilter        string           `yaml:"insertion-filter" default:""`
-	DryRun                 bool             `yaml:"dry-run" default:"false"`
-	Workspace              string           `yaml:"workspace"`
-	EnforcePolicyFile      string           `yaml:"traffic-validation-file"`
-	ContractFile           string           `yaml:"contract"`
-	AskUploadConfirmation  bool             `yaml:"ask-upload-confirmation" default:"true"`
-	ApiServerResources     shared.Resources `yaml:"api-server-resources"`
-	TapperResources        shared.Resources `yaml:"tapper-resources"`
-	ServiceMesh            bool             `yaml:"service-mesh" default:"false"`
-	Tls                    bool             `yaml:"tls" default:"false"`
+	UploadIntervalSec           int                           `yaml:"upload-interval" default:"10"`
+	PodRegexStr                 string                        `yaml:"regex" default:".*"`
+	PodLabelSelectorStr         string                        `yaml:"selector"`
+	GuiPort                     uint16                        `yaml:"gui-port" default:"8899"`
+	ProxyHost                   string                        `yaml:"proxy-host" default:"127.0.0.1"`
+	Namespaces                  []string                      `yaml:"namespaces"`
+	Analysis                    bool                          `yaml:"analysis" default:"false"`
+	AllNamespaces               bool                          `yaml:"all-namespaces" default:"false"`
+	PlainTextFilterRegexes      []string                      `yaml:"regex-masking"`
+	IgnoredUserAgents           []string                      `yaml:"ignored-user-agents"`
+	DisableRedaction            bool                          `yaml:"no-redact" default:"false"`
+	HumanMaxEntriesDBSize       string                        `yaml:"max-entries-db-size" default:"200MB"`
+	InsertionFilter             string                        `yaml:"insertion-filter" default:""`
+	HumanMaxExportQueueDiskSize string                        `yaml:"max-export-queue-disk-size" default:"100MB"`
+	HumanMaxStreamBandwidth     string                        `yaml:"max-stream-bandwidth"`
+	SampleRate                  float64                       `yaml:"sample-rate" default:"1"`
+	MaxEntriesPerSec            int                           `yaml:"max-entries-per-sec" default:"0"`
+	DryRun                      bool                          `yaml:"dry-run" default:"false"`
+	ShowTargets                 bool                          `yaml:"show-targets" default:"false"`
+	Workspace                   string                        `yaml:"workspace"`
+	EnforcePolicyFile           string                        `yaml:"traffic-validation-file"`
+	ContractFile                string                        `yaml:"contract"`
+	AskUploadConfirmation       bool                          `yaml:"ask-upload-confirmation" default:"true"`
+	ApiServerResources          shared.Resources              `yaml:"api-server-resources"`
+	TapperResources             shared.Resources              `yaml:"tapper-resources"`
+	ServiceMesh                 bool                          `yaml:"service-mesh" default:"false"`
+	Tls                         bool                          `yaml:"tls" default:"false"`
+	EntryIdScheme               string                        `yaml:"entry-id-scheme" default:"ulid"`
+	DeploymentMarkers           bool                          `yaml:"deployment-markers" default:"true"`
+	KubernetesEvents            bool                          `yaml:"kubernetes-events" default:"false"`
+	RawHeaders                  bool                          `yaml:"raw-headers" default:"false"`
+	DnsResolution               bool                          `yaml:"dns-resolution" default:"true"`
+	TagHeader                   string                        `yaml:"tag-header" default:"X-Debug-Tag"`
+	Docker                      bool                          `yaml:"docker" default:"false"`
+	Interface                   string                        `yaml:"interface"`
+	Annotations                 bool                          `yaml:"annotations" default:"false"`
+	TapperAuthentication        bool                          `yaml:"tapper-authentication" default:"true"`
+	TapperScheduling            shared.TapperSchedulingConfig `yaml:"tapper-scheduling"`
+	Relay                       bool                          `yaml:"relay" default:"false"`
+	RelayPoolLabel              string                        `yaml:"relay-pool-label"`
+	PersistentStorage           bool                          `yaml:"persistent-storage" default:"false"`
+	StorageClass                string                        `yaml:"storage-class"`
+	Operator                    bool                          `yaml:"operator" default:"false"`
+	Daemon                      bool                          `yaml:"daemon" default:"false"`
+	Protocols                   []string                      `yaml:"protocols"`
+	KubeContexts                []string                      `yaml:"kube-context"`
+	TargetsFile                 string                        `yaml:"targets-file"`
+	Targets                     []string                      `yaml:"targets"`
+	Redaction                   []RedactionRuleConfig         `yaml:"redaction"`
+	DetectPii                   bool                          `yaml:"detect-pii" default:"false"`
+	PiiPatterns                 PiiPatternsConfig             `yaml:"pii-patterns"`
+}
+
+func (config *TapConfig) RelayConfig() shared.RelayConfig {
+	return shared.RelayConfig{
+		Enabled:   config.Relay,
+		PoolLabel: config.RelayPoolLabel,
+	}
+}
+
+// PiiPatternsConfig toggles the PII patterns masked with --detect-pii
+type PiiPatternsConfig struct {
+	Email       bool `yaml:"email" default:"true"`
+	CreditCard  bool `yaml:"credit-card" default:"true"`
+	PhoneNumber bool `yaml:"phone-number" default:"true"`
+	Jwt         bool `yaml:"jwt" default:"true"`
+}
+
+// RedactionRuleConfig is a rule of the redaction section of the config file, like
+// {in: body, name: $.card.number, regex: "\\d{12}"}, see api.RedactionRule
+type RedactionRuleConfig struct {
+	In    string `yaml:"in"`
+	Name  string `yaml:"name"`
+	Regex string `yaml:"regex"`
+}
+
+// StdinTarget is the --targets value that reads the targets from stdin
+const StdinTarget = "-"
+
+// HasTargets is true when the pods to tap are listed explicitly instead of matched by the regex
+func (config *TapConfig) HasTargets() bool {
+	return config.TargetsFile != "" || len(config.Targets) > 0
+}
+
+// IsOperatorTap is true when the tap is declared as a MizuTap that the api server reconciles, which a daemon tap
+// is since nothing syncs the tappers after the cli detaches
+func (config *TapConfig) IsOperatorTap() bool {
+	return config.Operator || config.Daemon
+}
+
+// RedactionRules compiles the rules of the redaction section for the tappers
+func (config *TapConfig) RedactionRules() ([]api.RedactionRule, error) {
+	rules := make([]api.RedactionRule, 0, len(config.Redaction))
+	for _, ruleConfig := range config.Redaction {
+		rule := api.RedactionRule{In: ruleConfig.In, Name: ruleConfig.Name}
+		if ruleConfig.Regex != "" {
+			regex, err := api.CompileRegexToSerializableRegexp(ruleConfig.Regex)
+			if err != nil {
+				return nil, fmt.Errorf("%s is not a valid redaction regex %s", ruleConfig.Regex, err)
+			}
+			rule.Regex = regex
+		}
+
+		if err := rule.Validate(); err != nil {
+			return nil, err
+		}
+
+		rules = append(rules, rule)
+	}
+
+	return rules, nil
+}
+
+// EnabledPiiPatterns are the PII patterns the tappers mask, none without --detect-pii
+func (config *TapConfig) EnabledPiiPatterns() []string {
+	if !config.DetectPii {
+		return nil
+	}
+
+	toggles := map[string]bool{
+		api.PiiEmail:       config.PiiPatterns.Email,
+		api.PiiCreditCard:  config.PiiPatterns.CreditCard,
+		api.PiiPhoneNumber: config.PiiPatterns.PhoneNumber,
+		api.PiiJwt:         config.PiiPatterns.Jwt,
+	}
+
+	patterns := make([]string, 0)
+	for _, pattern := range api.PiiPatterns {
+		if toggles[pattern] {
+			patterns = append(patterns, pattern)
+		}
+	}
+
+	return patterns
 }
 
 func (config *TapConfig) PodRegex() *regexp.Regexp {
@@ -62,11 +204,35 @@ func (config *TapConfig) PodRegex() *regexp.Regexp {
 	return podRegex
 }
 
+// PodLabelSelector selects the pods matching the regex by their labels, every pod when there's no selector
+func (config *TapConfig) PodLabelSelector() labels.Selector {
+	podLabelSelector, err := labels.Parse(config.PodLabelSelectorStr)
+	if err != nil {
+		return labels.Everything()
+	}
+	return podLabelSelector
+}
+
 func (config *TapConfig) MaxEntriesDBSizeBytes() int64 {
 	maxEntriesDBSizeBytes, _ := units.HumanReadableToBytes(config.HumanMaxEntriesDBSize)
 	return maxEntriesDBSizeBytes
 }
 
+func (config *TapConfig) MaxExportQueueDiskSizeBytes() int64 {
+	maxExportQueueDiskSizeBytes, _ := units.HumanReadableToBytes(config.HumanMaxExportQueueDiskSize)
+	return maxExportQueueDiskSizeBytes
+}
+
+// MaxStreamBytesPerSec is the bandwidth of every browser socket, 0 when unlimited
+func (config *TapConfig) MaxStreamBytesPerSec() int64 {
+	if config.HumanMaxStreamBandwidth == "" {
+		return 0
+	}
+
+	maxStreamBytesPerSec, _ := units.HumanReadableToBytes(config.HumanMaxStreamBandwidth)
+	return maxStreamBytesPerSec
+}
+
 func (config *TapConfig) GetInsertionFilter() string {
 	insertionFilter := config.InsertionFilter
 	if fs.ValidPath(insertionFilter) {
@@ -92,11 +258,55 @@ func (config *TapConfig) Validate() error {
 		return fmt.Errorf("%s is not a valid regex %s", config.PodRegexStr, compileErr)
 	}
 
+	if _, err := labels.Parse(config.PodLabelSelectorStr); err != nil {
+		return fmt.Errorf("%s is not a valid label selector %s", config.PodLabelSelectorStr, err)
+	}
+
 	_, parseHumanDataSizeErr := units.HumanReadableToBytes(config.HumanMaxEntriesDBSize)
 	if parseHumanDataSizeErr != nil {
 		return fmt.Errorf("Could not parse --%s value %s", HumanMaxEntriesDBSizeTapName, config.HumanMaxEntriesDBSize)
 	}
 
+	if _, err := units.HumanReadableToBytes(config.HumanMaxExportQueueDiskSize); err != nil {
+		return fmt.Errorf("Could not parse max-export-queue-disk-size value %s", config.HumanMaxExportQueueDiskSize)
+	}
+
+	if config.HumanMaxStreamBandwidth != "" {
+		if _, err := units.HumanReadableToBytes(config.HumanMaxStreamBandwidth); err != nil {
+			return fmt.Errorf("Could not parse --%s value %s", HumanMaxStreamBandwidthName, config.HumanMaxStreamBandwidth)
+		}
+	}
+
+	if config.SampleRate <= 0 || config.SampleRate > 1 {
+		return fmt.Errorf("--%s must be greater than 0 and at most 1, got %v", SampleRateTapName, config.SampleRate)
+	}
+
+	if config.MaxEntriesPerSec < 0 {
+		return fmt.Errorf("--%s can't be negative, got %d", MaxEntriesPerSecTapName, config.MaxEntriesPerSec)
+	}
+
+	for _, toleration := range config.TapperScheduling.Tolerations {
+		if toleration.Operator != "" && toleration.Operator != string(core.TolerationOpEqual) && toleration.Operator != string(core.TolerationOpExists) {
+			return fmt.Errorf("%s is not a valid toleration operator, the operators are %s and %s", toleration.Operator, core.TolerationOpEqual, core.TolerationOpExists)
+		}
+
+		if toleration.Operator == string(core.TolerationOpExists) && toleration.Value != "" {
+			return fmt.Errorf("the toleration of %s can't have a value with the %s operator", toleration.Key, core.TolerationOpExists)
+		}
+
+		if toleration.Key == "" && toleration.Operator != string(core.TolerationOpExists) {
+			return fmt.Errorf("a toleration without a key must have the %s operator", core.TolerationOpExists)
+		}
+
+		if toleration.Effect != "" && toleration.Effect != string(core.TaintEffectNoSchedule) && toleration.Effect != string(core.TaintEffectPreferNoSchedule) && toleration.Effect != string(core.TaintEffectNoExecute) {
+			return fmt.Errorf("%s is not a valid toleration effect, the effects are %s, %s and %s", toleration.Effect, core.TaintEffectNoSchedule, core.TaintEffectPreferNoSchedule, core.TaintEffectNoExecute)
+		}
+	}
+
+	if config.EntryIdScheme != shared.EntryIdSchemeUlid && config.EntryIdScheme != shared.EntryIdSchemeIndex {
+		return fmt.Errorf("%s is not a valid %s, supported schemes are %s and %s", config.EntryIdScheme, EntryIdSchemeName, shared.EntryIdSchemeUlid, shared.EntryIdSchemeIndex)
+	}
+
 	if config.Workspace != "" {
 		workspaceRegex, _ := regexp.Compile("[A-Za-z0-9][-A-Za-z0-9_.]*[A-Za-z0-9]+$")
 		if len(config.Workspace) > 63 || !workspaceRegex.MatchString(config.Workspace) {
@@ -104,9 +314,92 @@ func (config *TapConfig) Validate() error {
 		}
 	}
 
+	if config.TagHeader != "" {
+		headerNameRegex, _ := regexp.Compile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+		if !headerNameRegex.MatchString(config.TagHeader) {
+			return fmt.Errorf("--%s %q is not a valid header name", TagHeaderTapName, config.TagHeader)
+		}
+	}
+
 	if config.Analysis && config.Workspace != "" {
 		return fmt.Errorf("Can't run with both --%s and --%s flags", AnalysisTapName, WorkspaceTapName)
 	}
 
+	if config.Docker && (config.ServiceMesh || config.Tls) {
+		return fmt.Errorf("Can't run with --%s together with --%s or --%s", DockerTapName, ServiceMeshName, TlsName)
+	}
+
+	if config.Docker && config.Annotations {
+		return fmt.Errorf("Can't run with both --%s and --%s flags", DockerTapName, AnnotationsTapName)
+	}
+
+	if config.Docker && config.IsOperatorTap() {
+		return fmt.Errorf("Can't run with --%s together with --%s or --%s", DockerTapName, OperatorTapName, DaemonTapName)
+	}
+
+	if config.Docker && config.PodLabelSelectorStr != "" {
+		return fmt.Errorf("Can't run with both --%s and --%s flags", DockerTapName, SelectorTapName)
+	}
+
+	if config.Interface != "" && !config.Docker {
+		return fmt.Errorf("--%s is only supported with --%s", InterfaceTapName, DockerTapName)
+	}
+
+	if config.Interface != "" && config.PodRegexStr != ".*" {
+		return fmt.Errorf("--%s records all the traffic of the interface, it can't be combined with a regex", InterfaceTapName)
+	}
+
+	if len(config.KubeContexts) > 1 && (config.Docker || config.IsOperatorTap()) {
+		return fmt.Errorf("Can't tap several clusters with --%s together with --%s, --%s or --%s", KubeContextsTapName, DockerTapName, OperatorTapName, DaemonTapName)
+	}
+
+	if len(shared.Unique(config.KubeContexts)) != len(config.KubeContexts) {
+		return fmt.Errorf("--%s can't list a context more than once", KubeContextsTapName)
+	}
+
+	if len(config.Protocols) > 0 && !config.IsOperatorTap() {
+		return fmt.Errorf("--%s is only supported with --%s or --%s", ProtocolsTapName, OperatorTapName, DaemonTapName)
+	}
+
+	if config.HasTargets() {
+		if config.Docker {
+			return fmt.Errorf("Can't run with --%s or --%s together with --%s", TargetsFileTapName, TargetsTapName, DockerTapName)
+		}
+
+		if config.PodRegexStr != ".*" || config.PodLabelSelectorStr != "" || len(config.Namespaces) > 0 || config.AllNamespaces || config.Annotations {
+			return fmt.Errorf("--%s and --%s replace the pod regex, they can't be combined with it or with --%s, --%s, --%s or --%s", TargetsFileTapName, TargetsTapName, SelectorTapName, NamespacesTapName, AllNamespacesTapName, AnnotationsTapName)
+		}
+	}
+
+	stdinTargets := 0
+	for _, target := range config.Targets {
+		if target == StdinTarget {
+			stdinTargets++
+		}
+	}
+	if stdinTargets > 1 {
+		return fmt.Errorf("--%s can read the targets from stdin only once", TargetsTapName)
+	}
+
+	if _, err := config.RedactionRules(); err != nil {
+		return err
+	}
+
+	if config.DetectPii && len(config.EnabledPiiPatterns()) == 0 {
+		return fmt.Errorf("--%s is set but every pattern of pii-patterns is off", DetectPiiTapName)
+	}
+
+	if config.Docker && config.PersistentStorage {
+		return fmt.Errorf("Can't run with both --%s and --%s flags", DockerTapName, PersistentStorageTapName)
+	}
+
+	if config.StorageClass != "" && !config.PersistentStorage {
+		return fmt.Errorf("--%s is only supported with --%s", StorageClassTapName, PersistentStorageTapName)
+	}
+
+	if config.Docker && config.ShowTargets {
+		return fmt.Errorf("Can't run with both --%s and --%s flags, use --%s to list the matching containers", DockerTapName, ShowTargetsTapName, DryRunTapName)
+	}
+
 	return nil
 }
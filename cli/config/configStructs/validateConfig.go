@@ -0,0 +1,68 @@
+package configStructs
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	ContractsValidateName = "contracts"
+	RulesValidateName     = "rules"
+	QueryValidateName     = "query"
+	LimitValidateName     = "limit"
+	FromValidateName      = "from"
+	ToValidateName        = "to"
+	CiValidateName        = "ci"
+	JunitFileValidateName = "junit-file"
+	GuiPortValidateName   = "gui-port"
+	UrlValidateName       = "url"
+)
+
+type ValidateConfig struct {
+	Contracts bool   `yaml:"contracts" default:"true"`
+	Rules     bool   `yaml:"rules" default:"true"`
+	Query     string `yaml:"query"`
+	Limit     int    `yaml:"limit" default:"10000"`
+	From      string `yaml:"from"`
+	To        string `yaml:"to"`
+	Ci        bool   `yaml:"ci"`
+	JunitFile string `yaml:"junit-file" default:"mizu-validate-junit.xml"`
+	GuiPort   uint16 `yaml:"gui-port" default:"8899"`
+	Url       string `yaml:"url,omitempty" readonly:""`
+}
+
+// TimeRange returns the unix milliseconds of --from and --to, 0 when not set, in the format of the export command
+func (config *ValidateConfig) TimeRange(now time.Time) (int64, int64, error) {
+	from, err := parseExportTime(config.From, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --%s value %s, expected an RFC 3339 time or a duration like 30m", FromValidateName, config.From)
+	}
+
+	to, err := parseExportTime(config.To, now)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid --%s value %s, expected an RFC 3339 time or a duration like 30m", ToValidateName, config.To)
+	}
+
+	return from, to, nil
+}
+
+func (config *ValidateConfig) Validate() error {
+	if !config.Contracts && !config.Rules {
+		return fmt.Errorf("nothing to validate, enable --%s or --%s", ContractsValidateName, RulesValidateName)
+	}
+
+	if config.Limit <= 0 {
+		return fmt.Errorf("--%s must be greater than 0", LimitValidateName)
+	}
+
+	from, to, err := config.TimeRange(time.Now())
+	if err != nil {
+		return err
+	}
+
+	if from > 0 && to > 0 && from > to {
+		return fmt.Errorf("--%s must be before --%s", FromValidateName, ToValidateName)
+	}
+
+	return nil
+}
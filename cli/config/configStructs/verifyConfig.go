@@ -0,0 +1,23 @@
+package configStructs
+
+import "fmt"
+
+const (
+	PublicKeyVerifyName = "public-key"
+	GuiPortVerifyName   = "gui-port"
+	UrlVerifyName       = "url"
+)
+
+type VerifyConfig struct {
+	PublicKey string `yaml:"public-key"`
+	GuiPort   uint16 `yaml:"gui-port" default:"8899"`
+	Url       string `yaml:"url,omitempty" readonly:""`
+}
+
+func (config *VerifyConfig) Validate() error {
+	if config.PublicKey == "" {
+		return fmt.Errorf("--%s is required", PublicKeyVerifyName)
+	}
+
+	return nil
+}
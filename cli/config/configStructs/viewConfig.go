@@ -1,11 +1,41 @@
 package configStructs
 
+import (
+	"fmt"
+
+	"github.com/up9inc/mizu/shared/units"
+)
+
 const (
-	GuiPortViewName = "gui-port"
-	UrlViewName     = "url"
+	GuiPortViewName      = "gui-port"
+	UrlViewName          = "url"
+	MaxBandwidthViewName = "max-bandwidth"
 )
 
 type ViewConfig struct {
-	GuiPort uint16 `yaml:"gui-port" default:"8899"`
-	Url     string `yaml:"url,omitempty" readonly:""`
+	GuiPort           uint16 `yaml:"gui-port" default:"8899"`
+	Url               string `yaml:"url,omitempty" readonly:""`
+	HumanMaxBandwidth string `yaml:"max-bandwidth"`
+}
+
+// MaxBytesPerSec is the bandwidth the GUI asks for when it streams the entries, 0 when unlimited
+func (config *ViewConfig) MaxBytesPerSec() int64 {
+	if config.HumanMaxBandwidth == "" {
+		return 0
+	}
+
+	maxBytesPerSec, _ := units.HumanReadableToBytes(config.HumanMaxBandwidth)
+	return maxBytesPerSec
+}
+
+func (config *ViewConfig) Validate() error {
+	if config.HumanMaxBandwidth == "" {
+		return nil
+	}
+
+	if _, err := units.HumanReadableToBytes(config.HumanMaxBandwidth); err != nil {
+		return fmt.Errorf("Could not parse --%s value %s", MaxBandwidthViewName, config.HumanMaxBandwidth)
+	}
+
+	return nil
 }
@@ -1,9 +1,12 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"testing"
+
+	"github.com/spf13/pflag"
 )
 
 type ConfigMock struct {
@@ -321,6 +324,9 @@ func TestGetParsedValueValidValue(t *testing.T) {
 		{StringValue: "66", Kind: reflect.Uint32, ActualValue: uint32(66)},
 		{StringValue: "6", Kind: reflect.Uint64, ActualValue: uint64(6)},
 		{StringValue: "66", Kind: reflect.Uint64, ActualValue: uint64(66)},
+		{StringValue: "0.5", Kind: reflect.Float32, ActualValue: float32(0.5)},
+		{StringValue: "0.1", Kind: reflect.Float64, ActualValue: 0.1},
+		{StringValue: "1", Kind: reflect.Float64, ActualValue: float64(1)},
 	}
 
 	for _, test := range tests {
@@ -366,6 +372,8 @@ func TestGetParsedValueInvalidValue(t *testing.T) {
 		{StringValue: "-6", Kind: reflect.Uint32},
 		{StringValue: "test", Kind: reflect.Uint64},
 		{StringValue: "-6", Kind: reflect.Uint64},
+		{StringValue: "test", Kind: reflect.Float32},
+		{StringValue: "true", Kind: reflect.Float64},
 	}
 
 	for _, test := range tests {
@@ -383,3 +391,42 @@ func TestGetParsedValueInvalidValue(t *testing.T) {
 		})
 	}
 }
+
+func TestRecordFlagRedactsSensitiveValues(t *testing.T) {
+	flagSet := pflag.NewFlagSet("tap", pflag.ContinueOnError)
+	flagSet.StringSlice(SetCommandName, []string{}, "")
+	flagSet.String("namespaces", "", "")
+	flagSet.String("auth-token", "", "")
+
+	if err := flagSet.Parse([]string{"--set", "tap.regex=front.*", "--set", "lifecycle-webhooks.secret=s3cr3t", "--namespaces", "sock-shop", "--auth-token", "abc"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	commandFlags = make(map[string]string)
+	flagSet.Visit(recordFlag)
+
+	expected := map[string]string{
+		SetCommandName: "tap.regex=front.*,lifecycle-webhooks.secret=[REDACTED]",
+		"namespaces":   "sock-shop",
+		"auth-token":   "[REDACTED]",
+	}
+	if !reflect.DeepEqual(GetCommandFlags(), expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, GetCommandFlags())
+	}
+}
+
+func TestRedactSensitiveFields(t *testing.T) {
+	var document interface{}
+	if err := json.Unmarshal([]byte(`{"sentryDsn":"https://key@sentry.io/1","tap":{"webhook-url":"https://hooks.slack.com/T/B/x","regex":"front.*","auth":""},"lifecycleWebhooks":{"urls":["https://ci"],"secret":"s3cr3t"}}`), &document); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]interface{}{
+		"sentryDsn":         "[REDACTED]",
+		"tap":               map[string]interface{}{"webhook-url": "[REDACTED]", "regex": "front.*", "auth": ""},
+		"lifecycleWebhooks": map[string]interface{}{"urls": []interface{}{"https://ci"}, "secret": "[REDACTED]"},
+	}
+	if redacted := RedactSensitiveFields(document); !reflect.DeepEqual(redacted, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, redacted)
+	}
+}
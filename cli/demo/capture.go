@@ -0,0 +1,38 @@
+package demo
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+//go:embed sampleCapture.json
+var sampleCapture []byte
+
+// LoadCapture reads a capture of entries in the format returned by the agent's /entries/:id endpoint,
+// the bundled sample capture is used when no path is given
+func LoadCapture(capturePath string) ([]*api.EntryWrapper, error) {
+	captureBytes := sampleCapture
+	if capturePath != "" {
+		var err error
+		if captureBytes, err = ioutil.ReadFile(capturePath); err != nil {
+			return nil, fmt.Errorf("failed to read capture file, err: %w", err)
+		}
+	}
+
+	var entries []*api.EntryWrapper
+	if err := json.Unmarshal(captureBytes, &entries); err != nil {
+		return nil, fmt.Errorf("failed to parse capture, err: %w", err)
+	}
+
+	for i, entry := range entries {
+		if entry.Data == nil || entry.Base == nil {
+			return nil, fmt.Errorf("capture entry %d is missing its data or base fields", i)
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,100 @@
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+var (
+	comparisonRegex = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_.\[\]"-]*)\s*(==|!=)\s*(.+)$`)
+	selectorRegex   = regexp.MustCompile(`\.?([A-Za-z_][A-Za-z0-9_]*)|\["([^"]*)"\]`)
+)
+
+type entryFilter func(entry map[string]interface{}) bool
+
+// newQueryFilter supports the subset of the query language the UI generates on its own, which is protocol macros
+// and equality comparisons joined by "and", full queries are only available when connected to an agent
+func newQueryFilter(query string, macros map[string]string) (entryFilter, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return func(entry map[string]interface{}) bool { return true }, nil
+	}
+
+	var filters []entryFilter
+	for _, clause := range strings.Split(query, " and ") {
+		clause = strings.TrimSpace(clause)
+		if expanded, ok := macros[clause]; ok {
+			clause = expanded
+		}
+
+		filter, err := newComparisonFilter(clause)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+
+	return func(entry map[string]interface{}) bool {
+		for _, filter := range filters {
+			if !filter(entry) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
+func newComparisonFilter(clause string) (entryFilter, error) {
+	match := comparisonRegex.FindStringSubmatch(clause)
+	if match == nil {
+		return nil, fmt.Errorf("unsupported expression in demo mode: %s", clause)
+	}
+
+	selector, operator, literal := match[1], match[2], strings.TrimSpace(match[3])
+
+	var expected interface{}
+	if err := json.Unmarshal([]byte(literal), &expected); err != nil {
+		return nil, fmt.Errorf("unsupported value in demo mode: %s", literal)
+	}
+
+	return func(entry map[string]interface{}) bool {
+		actual, found := selectValue(entry, selector)
+		equal := found && reflect.DeepEqual(actual, expected)
+		if operator == "!=" {
+			return !equal
+		}
+		return equal
+	}, nil
+}
+
+func selectValue(entry map[string]interface{}, selector string) (interface{}, bool) {
+	var current interface{} = entry
+	for _, match := range selectorRegex.FindAllStringSubmatch(selector, -1) {
+		key := match[1]
+		if key == "" {
+			key = match[2]
+		}
+
+		object, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if current, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func entryToMap(entry *api.Entry) map[string]interface{} {
+	entryMap := make(map[string]interface{})
+	entryBytes, _ := json.Marshal(entry)
+	_ = json.Unmarshal(entryBytes, &entryMap)
+	return entryMap
+}
@@ -0,0 +1,62 @@
+package demo
+
+import (
+	"testing"
+)
+
+func TestQueryFilter(t *testing.T) {
+	entry := map[string]interface{}{
+		"proto": map[string]interface{}{"name": "http"},
+		"request": map[string]interface{}{
+			"method":  "GET",
+			"path":    "/catalogue",
+			"headers": map[string]interface{}{"Host": "catalogue.sock-shop"},
+		},
+		"response": map[string]interface{}{"status": float64(200)},
+	}
+	macros := map[string]string{"http": `proto.name == "http"`, "redis": `proto.name == "redis"`}
+
+	tests := []struct {
+		Query    string
+		Expected bool
+	}{
+		{Query: "", Expected: true},
+		{Query: "http", Expected: true},
+		{Query: "redis", Expected: false},
+		{Query: `request.path == "/catalogue"`, Expected: true},
+		{Query: `request.path != "/catalogue"`, Expected: false},
+		{Query: `http and response.status == 200`, Expected: true},
+		{Query: `http and response.status == 500`, Expected: false},
+		{Query: `request.headers["Host"] == "catalogue.sock-shop"`, Expected: true},
+		{Query: `request.missing == "value"`, Expected: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Query, func(t *testing.T) {
+			filter, err := newQueryFilter(test.Query, macros)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if actual := filter(entry); actual != test.Expected {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.Expected, actual)
+			}
+		})
+	}
+}
+
+func TestQueryFilterUnsupported(t *testing.T) {
+	tests := []string{
+		`request.path.startsWith("/cat")`,
+		`response.status > 200`,
+		`http or redis`,
+	}
+
+	for _, query := range tests {
+		t.Run(query, func(t *testing.T) {
+			if _, err := newQueryFilter(query, map[string]string{}); err == nil {
+				t.Errorf("expected an error for unsupported query %s", query)
+			}
+		})
+	}
+}
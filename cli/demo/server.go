@@ -0,0 +1,325 @@
+package demo
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// Server serves a recorded capture through the same API the agent exposes, so the UI and the CLI can be used
+// without a cluster
+type Server struct {
+	entries    []*api.EntryWrapper
+	entryMaps  []map[string]interface{}
+	macros     map[string]string
+	version    string
+	startTime  int64
+	httpServer *http.Server
+}
+
+type queryMetadata struct {
+	Current            int   `json:"current"`
+	Total              int   `json:"total"`
+	NumberOfWritten    int   `json:"numberOfWritten"`
+	LeftOff            int   `json:"leftOff"`
+	TruncatedTimestamp int64 `json:"truncatedTimestamp"`
+}
+
+var websocketUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true }, // the ui may be served by a dev server of its own
+}
+
+type webSocketParams struct {
+	Query             string `json:"query"`
+	EnableFullEntries bool   `json:"enableFullEntries"`
+}
+
+// NewServer serves the entries and the UI of uiPath, or the UI bundled into the cli when uiPath is empty
+func NewServer(entries []*api.EntryWrapper, version string, port uint16, uiPath string) *Server {
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Data.Timestamp < entries[j].Data.Timestamp
+	})
+
+	server := &Server{
+		entries: entries,
+		macros:  make(map[string]string),
+		version: version,
+	}
+
+	for _, entry := range entries {
+		server.entryMaps = append(server.entryMaps, entryToMap(entry.Data))
+		server.macros[entry.Protocol.Macro] = fmt.Sprintf(`proto.name == "%s"`, entry.Protocol.Name)
+	}
+
+	if len(entries) > 0 {
+		server.startTime = entries[0].Data.Timestamp
+	} else {
+		server.startTime = time.Now().UnixNano() / int64(time.Millisecond)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/echo", server.echo)
+	mux.HandleFunc("/metadata/version", server.getVersion)
+	mux.HandleFunc("/status/health", server.getHealth)
+	mux.HandleFunc("/status/tap", server.getTappingStatus)
+	mux.HandleFunc("/status/general", server.getGeneralStats)
+	mux.HandleFunc("/status/auth", server.getEmptyObject)
+	mux.HandleFunc("/status/analyze", server.getEmptyObject)
+	mux.HandleFunc("/status/recentTLSLinks", server.getEmptyList)
+	mux.HandleFunc("/query/validate", server.postValidate)
+	mux.HandleFunc("/entries", server.getEntries)
+	mux.HandleFunc("/entries/", server.getEntriesOrEntry)
+	mux.HandleFunc("/ws", server.serveWebsocket)
+	if uiPath != "" {
+		mux.Handle("/", http.FileServer(http.Dir(uiPath)))
+	} else {
+		mux.Handle("/", bundledUiHandler())
+	}
+
+	server.httpServer = &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: mux,
+	}
+
+	return server
+}
+
+func (s *Server) ListenAndServe() error {
+	return s.httpServer.ListenAndServe()
+}
+
+func (s *Server) Close() error {
+	return s.httpServer.Close()
+}
+
+func (s *Server) echo(w http.ResponseWriter, _ *http.Request) {
+	_, _ = w.Write([]byte("Here is Mizu agent"))
+}
+
+func (s *Server) getVersion(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, shared.VersionResponse{Ver: s.version})
+}
+
+func (s *Server) getHealth(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, shared.HealthResponse{
+		TappedPods:            s.tappedPods(),
+		ConnectedTappersCount: 0,
+		TappersStatus:         []*shared.TapperStatus{},
+	})
+}
+
+func (s *Server) getTappingStatus(w http.ResponseWriter, _ *http.Request) {
+	tappedPodsStatus := make([]shared.TappedPodStatus, 0)
+	for _, pod := range s.tappedPods() {
+		tappedPodsStatus = append(tappedPodsStatus, shared.TappedPodStatus{Name: pod.Name, Namespace: pod.Namespace, IsTapped: true})
+	}
+
+	writeJson(w, http.StatusOK, tappedPodsStatus)
+}
+
+func (s *Server) getGeneralStats(w http.ResponseWriter, _ *http.Request) {
+	generalStats := map[string]interface{}{
+		"EntriesCount":        len(s.entries),
+		"EntriesVolumeInGB":   0,
+		"FirstEntryTimestamp": 0,
+		"LastEntryTimestamp":  0,
+	}
+	if len(s.entries) > 0 {
+		generalStats["FirstEntryTimestamp"] = s.entries[0].Data.Timestamp
+		generalStats["LastEntryTimestamp"] = s.entries[len(s.entries)-1].Data.Timestamp
+	}
+
+	writeJson(w, http.StatusOK, generalStats)
+}
+
+func (s *Server) getEmptyObject(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, map[string]interface{}{})
+}
+
+func (s *Server) getEmptyList(w http.ResponseWriter, _ *http.Request) {
+	writeJson(w, http.StatusOK, []interface{}{})
+}
+
+func (s *Server) postValidate(w http.ResponseWriter, r *http.Request) {
+	_, err := newQueryFilter(r.FormValue("query"), s.macros)
+
+	response := map[string]interface{}{"valid": err == nil, "message": ""}
+	if err != nil {
+		response["message"] = err.Error()
+	}
+
+	writeJson(w, http.StatusOK, response)
+}
+
+func (s *Server) getEntriesOrEntry(w http.ResponseWriter, r *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/entries/"), "/")
+	if id == "" {
+		s.getEntries(w, r)
+		return
+	}
+
+	s.getEntry(w, r, id)
+}
+
+func (s *Server) getEntries(w http.ResponseWriter, r *http.Request) {
+	filter, err := newQueryFilter(r.URL.Query().Get("query"), s.macros)
+	if err != nil {
+		writeJson(w, http.StatusBadRequest, map[string]interface{}{"error": true, "msg": err.Error()})
+		return
+	}
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+
+	data := make([]*api.BaseEntry, 0)
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(data) >= limit {
+			break
+		}
+		if filter(s.entryMaps[i]) {
+			data = append(data, s.entries[i].Base)
+		}
+	}
+
+	// entries are returned oldest first, like the agent does
+	for i, j := 0, len(data)-1; i < j; i, j = i+1, j-1 {
+		data[i], data[j] = data[j], data[i]
+	}
+
+	writeJson(w, http.StatusOK, map[string]interface{}{
+		"data": data,
+		"meta": queryMetadata{Current: len(data), Total: len(s.entries), NumberOfWritten: len(s.entries), LeftOff: 0},
+	})
+}
+
+func (s *Server) getEntry(w http.ResponseWriter, r *http.Request, id string) {
+	for _, entry := range s.entries {
+		if fmt.Sprintf("%d", entry.Data.Id) == id || (entry.Data.EntryId != "" && entry.Data.EntryId == id) {
+			writeJson(w, http.StatusOK, entry)
+			return
+		}
+	}
+
+	writeJson(w, http.StatusNotFound, map[string]interface{}{"error": true, "msg": fmt.Sprintf("entry %s not found", id)})
+}
+
+func (s *Server) serveWebsocket(w http.ResponseWriter, r *http.Request) {
+	ws, err := websocketUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		logger.Log.Debugf("Failed to upgrade websocket: %v", err)
+		return
+	}
+	defer ws.Close()
+
+	if err := s.writeWebsocketMessage(ws, shared.WebSocketMessageTypeStartTime, s.startTime); err != nil {
+		return
+	}
+
+	_, message, err := ws.ReadMessage()
+	if err != nil {
+		return
+	}
+
+	var params webSocketParams
+	if err := json.Unmarshal(message, &params); err != nil {
+		logger.Log.Debugf("Failed to parse websocket params: %v", err)
+		return
+	}
+
+	filter, err := newQueryFilter(params.Query, s.macros)
+	if err != nil {
+		_ = s.writeWebsocketMessage(ws, shared.WebSocketMessageTypeToast, map[string]interface{}{
+			"type":      "error",
+			"autoClose": 5000,
+			"text":      err.Error(),
+		})
+		return
+	}
+
+	written := 0
+	for i, entry := range s.entries {
+		if !filter(s.entryMaps[i]) {
+			continue
+		}
+
+		if params.EnableFullEntries {
+			err = s.writeWebsocketMessage(ws, shared.WebSocketMessageTypeFullEntry, entry.Data)
+		} else {
+			err = s.writeWebsocketMessage(ws, shared.WebSocketMessageTypeEntry, entry.Base)
+		}
+		if err != nil {
+			return
+		}
+		written++
+
+		if err := s.writeWebsocketMessage(ws, shared.WebSocketMessageTypeQueryMetadata, queryMetadata{
+			Current:         i + 1,
+			Total:           len(s.entries),
+			NumberOfWritten: written,
+			LeftOff:         i,
+		}); err != nil {
+			return
+		}
+	}
+
+	// the capture is static, keep the socket open until the UI closes it
+	for {
+		if _, _, err := ws.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
+func (s *Server) writeWebsocketMessage(ws *websocket.Conn, messageType shared.WebSocketMessageType, data interface{}) error {
+	message, err := json.Marshal(map[string]interface{}{
+		"messageType": messageType,
+		"data":        data,
+	})
+	if err != nil {
+		return err
+	}
+
+	return ws.WriteMessage(websocket.TextMessage, message)
+}
+
+func (s *Server) tappedPods() []*shared.PodInfo {
+	podsByName := make(map[string]*shared.PodInfo)
+	for _, entry := range s.entries {
+		if entry.Data.Destination == nil || entry.Data.Destination.Name == "" {
+			continue
+		}
+		podsByName[entry.Data.Destination.Name] = &shared.PodInfo{
+			Name:      strings.Split(entry.Data.Destination.Name, ".")[0],
+			Namespace: entry.Data.Namespace,
+		}
+	}
+
+	pods := make([]*shared.PodInfo, 0, len(podsByName))
+	for _, pod := range podsByName {
+		pods = append(pods, pod)
+	}
+	sort.Slice(pods, func(i, j int) bool {
+		return pods[i].Name < pods[j].Name
+	})
+
+	return pods
+}
+
+func writeJson(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		logger.Log.Debugf("Failed to write response: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package demo
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const (
+	uiIndexFileName       = "index.html"
+	uiPlaceholderFileName = "placeholder.html"
+)
+
+// the UI copied by make demo-ui before the cli is built, or only a placeholder page without it
+//
+//go:embed site
+var siteFiles embed.FS
+
+// HasBundledUi tells if the UI was bundled into the cli when it was built
+func HasBundledUi() bool {
+	_, err := fs.Stat(siteFiles, "site/"+uiIndexFileName)
+	return err == nil
+}
+
+func bundledUiHandler() http.Handler {
+	site, err := fs.Sub(siteFiles, "site")
+	if err != nil {
+		logger.Log.Debugf("Failed to open the bundled UI: %v", err)
+		return http.NotFoundHandler()
+	}
+
+	if HasBundledUi() {
+		return http.FileServer(http.FS(site))
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		placeholder, err := fs.ReadFile(site, uiPlaceholderFileName)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write(placeholder)
+	})
+}
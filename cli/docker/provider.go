@@ -0,0 +1,223 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/up9inc/mizu/shared/kubernetes"
+)
+
+const composeProjectLabel = "com.docker.compose.project"
+
+// Provider runs the docker CLI, so the local mode works with whatever daemon the CLI is configured for
+// (Docker Desktop on Windows and macOS, the native daemon on Linux)
+type Provider struct {
+	binary string
+}
+
+type Container struct {
+	Name    string
+	Project string
+	IPs     []string
+}
+
+type ContainerOptions struct {
+	Name         string
+	Image        string
+	PullAlways   bool
+	Entrypoint   string
+	WorkingDir   string
+	Network      string
+	PublishPorts []string
+	CapAdd       []string
+	Env          map[string]string
+	Args         []string
+}
+
+type inspectedContainer struct {
+	Name   string `json:"Name"`
+	Config struct {
+		Labels map[string]string `json:"Labels"`
+	} `json:"Config"`
+	NetworkSettings struct {
+		Networks map[string]struct {
+			IPAddress string `json:"IPAddress"`
+		} `json:"Networks"`
+	} `json:"NetworkSettings"`
+}
+
+func NewProvider(ctx context.Context) (*Provider, error) {
+	binary, err := exec.LookPath("docker")
+	if err != nil {
+		return nil, errors.New("the docker CLI wasn't found in PATH")
+	}
+
+	provider := &Provider{binary: binary}
+	if _, err := provider.run(ctx, "version", "--format", "{{.Server.Version}}"); err != nil {
+		return nil, fmt.Errorf("can't reach the Docker daemon, make sure Docker Desktop or the docker service is running: %w", err)
+	}
+
+	return provider, nil
+}
+
+// ListRunningContainers returns the running containers whose name matches the regex, except the ones of mizu
+func (provider *Provider) ListRunningContainers(ctx context.Context, regex *regexp.Regexp) ([]Container, error) {
+	ids, err := provider.run(ctx, "ps", "--quiet", "--no-trunc")
+	if err != nil {
+		return nil, err
+	}
+	if ids == "" {
+		return []Container{}, nil
+	}
+
+	output, err := provider.run(ctx, append([]string{"inspect"}, strings.Fields(ids)...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	containers, err := parseInspectOutput([]byte(output))
+	if err != nil {
+		return nil, err
+	}
+
+	matchingContainers := make([]Container, 0)
+	for _, container := range containers {
+		if regex.MatchString(container.Name) {
+			matchingContainers = append(matchingContainers, container)
+		}
+	}
+	return matchingContainers, nil
+}
+
+func parseInspectOutput(output []byte) ([]Container, error) {
+	var inspected []inspectedContainer
+	if err := json.Unmarshal(output, &inspected); err != nil {
+		return nil, err
+	}
+
+	containers := make([]Container, 0, len(inspected))
+	for _, item := range inspected {
+		if item.Config.Labels[kubernetes.LabelManagedBy] == kubernetes.LabelValueMizu {
+			continue
+		}
+
+		container := Container{
+			Name:    strings.TrimPrefix(item.Name, "/"),
+			Project: item.Config.Labels[composeProjectLabel],
+			IPs:     make([]string, 0),
+		}
+		for _, network := range item.NetworkSettings.Networks {
+			if network.IPAddress != "" {
+				container.IPs = append(container.IPs, network.IPAddress)
+			}
+		}
+		sort.Strings(container.IPs)
+		containers = append(containers, container)
+	}
+
+	sort.Slice(containers, func(i, j int) bool {
+		return containers[i].Name < containers[j].Name
+	})
+	return containers, nil
+}
+
+func (provider *Provider) CreateContainer(ctx context.Context, opts *ContainerOptions) error {
+	args := []string{"create", "--name", opts.Name, "--label", fmt.Sprintf("%s=%s", kubernetes.LabelManagedBy, kubernetes.LabelValueMizu)}
+	if opts.PullAlways {
+		args = append(args, "--pull", "always")
+	}
+	if opts.Entrypoint != "" {
+		args = append(args, "--entrypoint", opts.Entrypoint)
+	}
+	if opts.WorkingDir != "" {
+		args = append(args, "--workdir", opts.WorkingDir)
+	}
+	if opts.Network != "" {
+		args = append(args, "--network", opts.Network)
+	}
+	for _, port := range opts.PublishPorts {
+		args = append(args, "--publish", port)
+	}
+	for _, capability := range opts.CapAdd {
+		args = append(args, "--cap-add", capability)
+	}
+
+	envNames := make([]string, 0, len(opts.Env))
+	for name := range opts.Env {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+	for _, name := range envNames {
+		args = append(args, "--env", fmt.Sprintf("%s=%s", name, opts.Env[name]))
+	}
+
+	args = append(args, opts.Image)
+	args = append(args, opts.Args...)
+
+	_, err := provider.run(ctx, args...)
+	return err
+}
+
+// CopyDirToContainer copies the contents of a local directory into a created container, unlike a bind mount it
+// doesn't depend on the file sharing settings of Docker Desktop
+func (provider *Provider) CopyDirToContainer(ctx context.Context, containerName string, srcDir string, destDir string) error {
+	// the trailing "." copies the contents of the directory rather than the directory itself
+	src := strings.TrimRight(srcDir, string(filepath.Separator)) + string(filepath.Separator) + "."
+	_, err := provider.run(ctx, "cp", src, fmt.Sprintf("%s:%s", containerName, destDir))
+	return err
+}
+
+func (provider *Provider) StartContainer(ctx context.Context, containerName string) error {
+	_, err := provider.run(ctx, "start", containerName)
+	return err
+}
+
+func (provider *Provider) RemoveContainer(ctx context.Context, containerName string) error {
+	_, err := provider.run(ctx, "rm", "--force", "--volumes", containerName)
+	return err
+}
+
+func (provider *Provider) ContainerExists(ctx context.Context, containerName string) (bool, error) {
+	output, err := provider.run(ctx, "ps", "--all", "--quiet", "--filter", fmt.Sprintf("name=^/%s$", containerName))
+	if err != nil {
+		return false, err
+	}
+	return output != "", nil
+}
+
+func (provider *Provider) GetContainerIP(ctx context.Context, containerName string) (string, error) {
+	output, err := provider.run(ctx, "inspect", "--format", "{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}", containerName)
+	if err != nil {
+		return "", err
+	}
+
+	ips := strings.Fields(output)
+	if len(ips) == 0 {
+		return "", fmt.Errorf("container %s has no IP address", containerName)
+	}
+	return ips[0], nil
+}
+
+func (provider *Provider) run(ctx context.Context, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, provider.binary, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return "", fmt.Errorf("docker %s: %s", args[0], message)
+		}
+		return "", fmt.Errorf("docker %s: %w", args[0], err)
+	}
+
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,39 @@
+package docker
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseInspectOutput(t *testing.T) {
+	output := []byte(`[
+		{
+			"Name": "/shop-web-1",
+			"Config": {"Labels": {"com.docker.compose.project": "shop"}},
+			"NetworkSettings": {"Networks": {"shop_default": {"IPAddress": "172.18.0.3"}, "shop_backend": {"IPAddress": "172.19.0.2"}}}
+		},
+		{
+			"Name": "/mizu-api-server",
+			"Config": {"Labels": {"app.kubernetes.io/managed-by": "mizu"}},
+			"NetworkSettings": {"Networks": {"bridge": {"IPAddress": "172.17.0.2"}}}
+		},
+		{
+			"Name": "/cache",
+			"Config": {"Labels": null},
+			"NetworkSettings": {"Networks": {"host": {"IPAddress": ""}}}
+		}
+	]`)
+
+	containers, err := parseInspectOutput(output)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []Container{
+		{Name: "cache", IPs: []string{}},
+		{Name: "shop-web-1", Project: "shop", IPs: []string{"172.18.0.3", "172.19.0.2"}},
+	}
+	if !reflect.DeepEqual(expected, containers) {
+		t.Errorf("expected %+v, got %+v", expected, containers)
+	}
+}
@@ -3,21 +3,74 @@ package fsUtils
 import (
 	"archive/zip"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path"
 	"regexp"
+	"runtime"
+	"time"
 
 	"github.com/up9inc/mizu/cli/config"
 	"github.com/up9inc/mizu/cli/mizu"
+	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/kubernetes"
 	"github.com/up9inc/mizu/shared/logger"
+	core "k8s.io/api/core/v1"
 )
 
+const (
+	manifestFileName        = "manifest.json"
+	effectiveConfigFileName = "effective-config.yaml"
+)
+
+// logsManifest describes the content of the logs zip file and the environment it was created in, so a bug report
+// can be understood without going back and forth with the reporter
+type logsManifest struct {
+	CreatedAt         time.Time      `json:"createdAt"`
+	CliVersion        string         `json:"cliVersion"`
+	CliGitCommitHash  string         `json:"cliGitCommitHash"`
+	CliPlatform       string         `json:"cliPlatform"`
+	KubeContext       string         `json:"kubeContext,omitempty"`
+	KubernetesVersion string         `json:"kubernetesVersion,omitempty"`
+	Namespace         string         `json:"namespace"`
+	Pods              []manifestPod  `json:"pods"`
+	Files             []manifestFile `json:"files"`
+	Errors            []string       `json:"errors,omitempty"`
+}
+
+type manifestPod struct {
+	Name       string              `json:"name"`
+	Node       string              `json:"node"`
+	Phase      string              `json:"phase"`
+	Containers []manifestContainer `json:"containers"`
+}
+
+type manifestContainer struct {
+	Name         string `json:"name"`
+	Image        string `json:"image"`
+	Ready        bool   `json:"ready"`
+	RestartCount int32  `json:"restartCount"`
+}
+
+type manifestFile struct {
+	Name string `json:"name"`
+	Size int    `json:"size"`
+}
+
+type logsBundle struct {
+	zipWriter *zip.Writer
+	manifest  logsManifest
+}
+
 func GetLogFilePath() string {
 	return path.Join(mizu.GetMizuFolderPath(), "mizu_cli.log")
 }
 
+// DumpLogs creates a zip file with the logs of the api server and the tappers, including the logs of the previous run
+// of restarted containers, the events of the mizu namespace, the config of the agent and of the cli with their secrets
+// redacted, the cli log file and a manifest that lists them all
 func DumpLogs(ctx context.Context, provider *kubernetes.Provider, filePath string) error {
 	podExactRegex := regexp.MustCompile("^" + kubernetes.MizuResourcesPrefix)
 	pods, err := provider.ListAllPodsMatchingRegex(ctx, podExactRegex, []string{config.Config.MizuResourcesNamespace})
@@ -25,10 +78,6 @@ func DumpLogs(ctx context.Context, provider *kubernetes.Provider, filePath strin
 		return err
 	}
 
-	if len(pods) == 0 {
-		return fmt.Errorf("no mizu pods found in namespace %s", config.Config.MizuResourcesNamespace)
-	}
-
 	newZipFile, err := os.Create(filePath)
 	if err != nil {
 		return err
@@ -37,49 +86,157 @@ func DumpLogs(ctx context.Context, provider *kubernetes.Provider, filePath strin
 	zipWriter := zip.NewWriter(newZipFile)
 	defer zipWriter.Close()
 
+	bundle := &logsBundle{
+		zipWriter: zipWriter,
+		manifest: logsManifest{
+			CreatedAt:        time.Now().UTC(),
+			CliVersion:       mizu.Ver,
+			CliGitCommitHash: mizu.GitCommitHash,
+			CliPlatform:      fmt.Sprintf("%s/%s", runtime.GOOS, runtime.GOARCH),
+			KubeContext:      config.Config.KubeContext,
+			Namespace:        config.Config.MizuResourcesNamespace,
+			Pods:             []manifestPod{},
+			Files:            []manifestFile{},
+		},
+	}
+
+	if kubernetesVersion, err := provider.GetKubernetesVersion(); err != nil {
+		bundle.addError("Failed to get kubernetes version, %v", err)
+	} else {
+		bundle.manifest.KubernetesVersion = string(*kubernetesVersion)
+	}
+
+	if len(pods) == 0 {
+		bundle.addError("No mizu pods found in namespace %s", config.Config.MizuResourcesNamespace)
+	}
+
+	configMapNames := make(map[string]bool)
 	for _, pod := range pods {
-		for _, container := range pod.Spec.Containers {
-			logs, err := provider.GetPodLogs(ctx, pod.Namespace, pod.Name, container.Name)
-			if err != nil {
-				logger.Log.Errorf("Failed to get logs, %v", err)
-				continue
-			} else {
-				logger.Log.Debugf("Successfully read log length %d for pod: %s.%s.%s", len(logs), pod.Namespace, pod.Name, container.Name)
-			}
+		bundle.addPod(ctx, provider, &pod)
 
-			if err := AddStrToZip(zipWriter, logs, fmt.Sprintf("%s.%s.%s.log", pod.Namespace, pod.Name, container.Name)); err != nil {
-				logger.Log.Errorf("Failed write logs, %v", err)
-			} else {
-				logger.Log.Debugf("Successfully added log length %d from pod: %s.%s.%s", len(logs), pod.Namespace, pod.Name, container.Name)
+		for _, volume := range pod.Spec.Volumes {
+			if volume.ConfigMap != nil {
+				configMapNames[volume.ConfigMap.Name] = true
 			}
 		}
 	}
 
+	for configMapName := range configMapNames {
+		bundle.addAgentConfig(ctx, provider, configMapName)
+	}
+
 	events, err := provider.GetNamespaceEvents(ctx, config.Config.MizuResourcesNamespace)
 	if err != nil {
-		logger.Log.Debugf("Failed to get k8b events, %v", err)
+		bundle.addError("Failed to get k8b events, %v", err)
 	} else {
-		logger.Log.Debugf("Successfully read events for k8b namespace: %s", config.Config.MizuResourcesNamespace)
+		bundle.addStr(events, fmt.Sprintf("%s_events.log", config.Config.MizuResourcesNamespace))
 	}
 
-	if err := AddStrToZip(zipWriter, events, fmt.Sprintf("%s_events.log", config.Config.MizuResourcesNamespace)); err != nil {
-		logger.Log.Debugf("Failed write logs, %v", err)
+	if effectiveConfig, err := config.GetRedactedConfig(&config.Config); err != nil {
+		bundle.addError("Failed to render the effective config, %v", err)
 	} else {
-		logger.Log.Debugf("Successfully added events for k8b namespace: %s", config.Config.MizuResourcesNamespace)
+		bundle.addStr(effectiveConfig, effectiveConfigFileName)
 	}
 
-	if err := AddFileToZip(zipWriter, config.Config.ConfigFilePath); err != nil {
-		logger.Log.Debugf("Failed write file, %v", err)
+	if cliLogs, err := ioutil.ReadFile(GetLogFilePath()); err != nil {
+		bundle.addError("Failed to read file %s, %v", GetLogFilePath(), err)
 	} else {
-		logger.Log.Debugf("Successfully added file %s", config.Config.ConfigFilePath)
+		bundle.addStr(string(cliLogs), path.Base(GetLogFilePath()))
 	}
 
-	if err := AddFileToZip(zipWriter, GetLogFilePath()); err != nil {
-		logger.Log.Debugf("Failed write file, %v", err)
-	} else {
-		logger.Log.Debugf("Successfully added file %s", GetLogFilePath())
+	manifest, err := json.MarshalIndent(bundle.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := AddStrToZip(zipWriter, string(manifest), manifestFileName); err != nil {
+		return err
 	}
 
 	logger.Log.Infof("You can find the zip file with all logs in %s", filePath)
 	return nil
 }
+
+func (bundle *logsBundle) addPod(ctx context.Context, provider *kubernetes.Provider, pod *core.Pod) {
+	podManifest := manifestPod{
+		Name:       pod.Name,
+		Node:       pod.Spec.NodeName,
+		Phase:      string(pod.Status.Phase),
+		Containers: []manifestContainer{},
+	}
+
+	for _, container := range pod.Spec.Containers {
+		containerManifest := manifestContainer{Name: container.Name, Image: container.Image}
+		for _, containerStatus := range pod.Status.ContainerStatuses {
+			if containerStatus.Name == container.Name {
+				containerManifest.Ready = containerStatus.Ready
+				containerManifest.RestartCount = containerStatus.RestartCount
+			}
+		}
+		podManifest.Containers = append(podManifest.Containers, containerManifest)
+
+		logs, err := provider.GetPodLogs(ctx, pod.Namespace, pod.Name, container.Name)
+		if err != nil {
+			bundle.addError("Failed to get logs, %v", err)
+		} else {
+			logger.Log.Debugf("Successfully read log length %d for pod: %s.%s.%s", len(logs), pod.Namespace, pod.Name, container.Name)
+			bundle.addStr(logs, fmt.Sprintf("%s.%s.%s.log", pod.Namespace, pod.Name, container.Name))
+		}
+
+		if containerManifest.RestartCount == 0 {
+			continue
+		}
+
+		previousLogs, err := provider.GetPreviousPodLogs(ctx, pod.Namespace, pod.Name, container.Name)
+		if err != nil {
+			bundle.addError("Failed to get previous logs, %v", err)
+		} else {
+			bundle.addStr(previousLogs, fmt.Sprintf("%s.%s.%s.previous.log", pod.Namespace, pod.Name, container.Name))
+		}
+	}
+
+	bundle.manifest.Pods = append(bundle.manifest.Pods, podManifest)
+}
+
+func (bundle *logsBundle) addAgentConfig(ctx context.Context, provider *kubernetes.Provider, configMapName string) {
+	configMap, err := provider.GetConfigMap(ctx, config.Config.MizuResourcesNamespace, configMapName)
+	if err != nil {
+		bundle.addError("Failed to get config map %s, %v", configMapName, err)
+		return
+	}
+
+	agentConfig, ok := configMap.Data[shared.ConfigFileName]
+	if !ok {
+		return
+	}
+
+	var document interface{}
+	if err := json.Unmarshal([]byte(agentConfig), &document); err != nil {
+		bundle.addError("Failed to parse the agent config of config map %s, %v", configMapName, err)
+		return
+	}
+
+	redactedAgentConfig, err := json.MarshalIndent(config.RedactSensitiveFields(document), "", "  ")
+	if err != nil {
+		bundle.addError("Failed to render the agent config of config map %s, %v", configMapName, err)
+		return
+	}
+
+	bundle.addStr(string(redactedAgentConfig), fmt.Sprintf("%s.%s.%s", config.Config.MizuResourcesNamespace, configMapName, shared.ConfigFileName))
+}
+
+func (bundle *logsBundle) addStr(content string, fileName string) {
+	if err := AddStrToZip(bundle.zipWriter, content, fileName); err != nil {
+		bundle.addError("Failed write logs, %v", err)
+		return
+	}
+
+	logger.Log.Debugf("Successfully added %s of length %d", fileName, len(content))
+	bundle.manifest.Files = append(bundle.manifest.Files, manifestFile{Name: fileName, Size: len(content)})
+}
+
+// addError records what couldn't be collected in the manifest instead of failing, a partial bundle still helps
+func (bundle *logsBundle) addError(format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	logger.Log.Debug(message)
+	bundle.manifest.Errors = append(bundle.manifest.Errors, message)
+}
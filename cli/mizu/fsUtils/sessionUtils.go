@@ -0,0 +1,88 @@
+package fsUtils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/up9inc/mizu/cli/mizu"
+)
+
+const sessionsFileName = "sessions.json"
+
+// sessions are keyed by kube context and namespace, a session id is recorded while the session's resources exist
+type sessionsState map[string]string
+
+func getSessionsFilePath() string {
+	return path.Join(mizu.GetMizuFolderPath(), sessionsFileName)
+}
+
+func getSessionKey(kubeContext string, namespace string) string {
+	return fmt.Sprintf("%s/%s", kubeContext, namespace)
+}
+
+func GetSessionId(kubeContext string, namespace string) (string, error) {
+	sessions, err := readSessionsState()
+	if err != nil {
+		return "", err
+	}
+
+	return sessions[getSessionKey(kubeContext, namespace)], nil
+}
+
+func SaveSessionId(kubeContext string, namespace string, sessionId string) error {
+	sessions, err := readSessionsState()
+	if err != nil {
+		return err
+	}
+
+	sessions[getSessionKey(kubeContext, namespace)] = sessionId
+	return writeSessionsState(sessions)
+}
+
+func RemoveSessionId(kubeContext string, namespace string) error {
+	sessions, err := readSessionsState()
+	if err != nil {
+		return err
+	}
+
+	sessionKey := getSessionKey(kubeContext, namespace)
+	if _, ok := sessions[sessionKey]; !ok {
+		return nil
+	}
+
+	delete(sessions, sessionKey)
+	return writeSessionsState(sessions)
+}
+
+func readSessionsState() (sessionsState, error) {
+	sessions := sessionsState{}
+
+	data, err := ioutil.ReadFile(getSessionsFilePath())
+	if os.IsNotExist(err) {
+		return sessions, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("invalid sessions file %s, err: %v", getSessionsFilePath(), err)
+	}
+
+	return sessions, nil
+}
+
+func writeSessionsState(sessions sessionsState) error {
+	if err := EnsureDir(mizu.GetMizuFolderPath()); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(getSessionsFilePath(), data, 0600)
+}
@@ -12,13 +12,18 @@ import (
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
-func CleanUpMizuResources(ctx context.Context, cancel context.CancelFunc, kubernetesProvider *kubernetes.Provider, isNsRestrictedMode bool, mizuResourcesNamespace string) {
+// CleanUpMizuResources removes the resources of the session, the persistent volume claim of the entries is kept with
+// keepEntriesClaim, and so is the namespace in the non restricted mode since the claim goes with it
+func CleanUpMizuResources(ctx context.Context, cancel context.CancelFunc, kubernetesProvider *kubernetes.Provider, isNsRestrictedMode bool, mizuResourcesNamespace string, resourceNames kubernetes.ResourceNames, keepEntriesClaim bool) {
 	logger.Log.Infof("\nRemoving mizu resources")
 
 	var leftoverResources []string
 
 	if isNsRestrictedMode {
-		leftoverResources = cleanUpRestrictedMode(ctx, kubernetesProvider, mizuResourcesNamespace)
+		leftoverResources = cleanUpRestrictedMode(ctx, kubernetesProvider, mizuResourcesNamespace, resourceNames, keepEntriesClaim)
+	} else if keepEntriesClaim {
+		leftoverResources = cleanUpRestrictedMode(ctx, kubernetesProvider, mizuResourcesNamespace, resourceNames, keepEntriesClaim)
+		leftoverResources = append(leftoverResources, cleanUpClusterRBAC(ctx, kubernetesProvider)...)
 	} else {
 		leftoverResources = cleanUpNonRestrictedMode(ctx, cancel, kubernetesProvider, mizuResourcesNamespace)
 	}
@@ -42,6 +47,12 @@ func cleanUpNonRestrictedMode(ctx context.Context, cancel context.CancelFunc, ku
 		defer waitUntilNamespaceDeleted(ctx, cancel, kubernetesProvider, mizuResourcesNamespace)
 	}
 
+	return append(leftoverResources, cleanUpClusterRBAC(ctx, kubernetesProvider)...)
+}
+
+func cleanUpClusterRBAC(ctx context.Context, kubernetesProvider *kubernetes.Provider) []string {
+	leftoverResources := make([]string, 0)
+
 	if resources, err := kubernetesProvider.ListManagedClusterRoles(ctx); err != nil {
 		resourceDesc := "ClusterRoles"
 		handleDeletionError(err, resourceDesc, &leftoverResources)
@@ -88,24 +99,60 @@ func waitUntilNamespaceDeleted(ctx context.Context, cancel context.CancelFunc, k
 	}
 }
 
-func cleanUpRestrictedMode(ctx context.Context, kubernetesProvider *kubernetes.Provider, mizuResourcesNamespace string) []string {
+func cleanUpRestrictedMode(ctx context.Context, kubernetesProvider *kubernetes.Provider, mizuResourcesNamespace string, resourceNames kubernetes.ResourceNames, keepEntriesClaim bool) []string {
 	leftoverResources := make([]string, 0)
 
-	if err := kubernetesProvider.RemoveService(ctx, mizuResourcesNamespace, kubernetes.ApiServerPodName); err != nil {
-		resourceDesc := fmt.Sprintf("Service %s in namespace %s", kubernetes.ApiServerPodName, mizuResourcesNamespace)
+	// the tap goes first so the api server doesn't apply the tapper daemon set again
+	if err := kubernetesProvider.RemoveMizuTap(ctx, mizuResourcesNamespace, resourceNames.MizuTapName); err != nil {
+		resourceDesc := fmt.Sprintf("MizuTap %s in namespace %s", resourceNames.MizuTapName, mizuResourcesNamespace)
+		handleDeletionError(err, resourceDesc, &leftoverResources)
+	}
+
+	if err := kubernetesProvider.RemoveService(ctx, mizuResourcesNamespace, resourceNames.ApiServerPodName); err != nil {
+		resourceDesc := fmt.Sprintf("Service %s in namespace %s", resourceNames.ApiServerPodName, mizuResourcesNamespace)
+		handleDeletionError(err, resourceDesc, &leftoverResources)
+	}
+
+	if err := kubernetesProvider.RemoveDaemonSet(ctx, mizuResourcesNamespace, resourceNames.TapperDaemonSetName); err != nil {
+		resourceDesc := fmt.Sprintf("DaemonSet %s in namespace %s", resourceNames.TapperDaemonSetName, mizuResourcesNamespace)
+		handleDeletionError(err, resourceDesc, &leftoverResources)
+	}
+
+	if err := kubernetesProvider.RemoveMizuRelays(ctx, mizuResourcesNamespace, resourceNames.RelayName); err != nil {
+		resourceDesc := fmt.Sprintf("Relays %s in namespace %s", resourceNames.RelayName, mizuResourcesNamespace)
 		handleDeletionError(err, resourceDesc, &leftoverResources)
 	}
 
-	if err := kubernetesProvider.RemoveDaemonSet(ctx, mizuResourcesNamespace, kubernetes.TapperDaemonSetName); err != nil {
-		resourceDesc := fmt.Sprintf("DaemonSet %s in namespace %s", kubernetes.TapperDaemonSetName, mizuResourcesNamespace)
+	if err := kubernetesProvider.RemoveConfigMap(ctx, mizuResourcesNamespace, resourceNames.ConfigMapName); err != nil {
+		resourceDesc := fmt.Sprintf("ConfigMap %s in namespace %s", resourceNames.ConfigMapName, mizuResourcesNamespace)
 		handleDeletionError(err, resourceDesc, &leftoverResources)
 	}
 
-	if err := kubernetesProvider.RemoveConfigMap(ctx, mizuResourcesNamespace, kubernetes.ConfigMapName); err != nil {
-		resourceDesc := fmt.Sprintf("ConfigMap %s in namespace %s", kubernetes.ConfigMapName, mizuResourcesNamespace)
+	if err := kubernetesProvider.RemoveSecret(ctx, mizuResourcesNamespace, resourceNames.ProvenanceSecretName); err != nil {
+		resourceDesc := fmt.Sprintf("Secret %s in namespace %s", resourceNames.ProvenanceSecretName, mizuResourcesNamespace)
 		handleDeletionError(err, resourceDesc, &leftoverResources)
 	}
 
+	if err := kubernetesProvider.RemovePod(ctx, mizuResourcesNamespace, resourceNames.ApiServerPodName); err != nil {
+		resourceDesc := fmt.Sprintf("Pod %s in namespace %s", resourceNames.ApiServerPodName, mizuResourcesNamespace)
+		handleDeletionError(err, resourceDesc, &leftoverResources)
+	}
+
+	if !keepEntriesClaim {
+		if err := kubernetesProvider.RemovePersistentVolumeClaim(ctx, mizuResourcesNamespace, resourceNames.EntriesClaimName); err != nil {
+			resourceDesc := fmt.Sprintf("PersistentVolumeClaim %s in namespace %s", resourceNames.EntriesClaimName, mizuResourcesNamespace)
+			handleDeletionError(err, resourceDesc, &leftoverResources)
+		}
+	}
+
+	// the service account and its role are shared by all the sessions in the namespace
+	if otherSessionsExist, err := doOtherSessionsExist(ctx, kubernetesProvider, mizuResourcesNamespace, resourceNames.SessionId); err != nil {
+		logger.Log.Debugf("Error checking for other mizu sessions in namespace %s: %v", mizuResourcesNamespace, errormessage.FormatError(err))
+	} else if otherSessionsExist {
+		logger.Log.Debugf("Other mizu sessions are running in namespace %s, keeping the shared resources", mizuResourcesNamespace)
+		return leftoverResources
+	}
+
 	if resources, err := kubernetesProvider.ListManagedServiceAccounts(ctx, mizuResourcesNamespace); err != nil {
 		resourceDesc := fmt.Sprintf("ServiceAccounts in namespace %s", mizuResourcesNamespace)
 		handleDeletionError(err, resourceDesc, &leftoverResources)
@@ -142,12 +189,17 @@ func cleanUpRestrictedMode(ctx context.Context, kubernetesProvider *kubernetes.P
 		}
 	}
 
-	if err := kubernetesProvider.RemovePod(ctx, mizuResourcesNamespace, kubernetes.ApiServerPodName); err != nil {
-		resourceDesc := fmt.Sprintf("Pod %s in namespace %s", kubernetes.ApiServerPodName, mizuResourcesNamespace)
-		handleDeletionError(err, resourceDesc, &leftoverResources)
+	return leftoverResources
+}
+
+func doOtherSessionsExist(ctx context.Context, kubernetesProvider *kubernetes.Provider, mizuResourcesNamespace string, sessionId string) (bool, error) {
+	labelSelector := fmt.Sprintf("%s,%s!=%s", kubernetes.LabelSession, kubernetes.LabelSession, sessionId)
+	pods, err := kubernetesProvider.ListPodsByLabelSelector(ctx, mizuResourcesNamespace, labelSelector)
+	if err != nil {
+		return false, err
 	}
 
-	return leftoverResources
+	return len(pods) > 0, nil
 }
 
 func handleDeletionError(err error, resourceDesc string, leftoverResources *[]string) {
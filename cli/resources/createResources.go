@@ -12,20 +12,26 @@ import (
 	"github.com/up9inc/mizu/shared/kubernetes"
 	"github.com/up9inc/mizu/shared/logger"
 	core "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 )
 
-func CreateTapMizuResources(ctx context.Context, kubernetesProvider *kubernetes.Provider, serializedValidationRules string, serializedContract string, serializedMizuConfig string, isNsRestrictedMode bool, mizuResourcesNamespace string, agentImage string, syncEntriesConfig *shared.SyncEntriesConfig, maxEntriesDBSizeBytes int64, apiServerResources shared.Resources, imagePullPolicy core.PullPolicy, logLevel logging.Level) (bool, error) {
+// the resources of the core and apps groups the api server watches to resolve ips to names, to enrich the entries and to
+// record markers, the argo rollouts are granted by a rule of their own
+var rbacResources = append([]string{"namespaces", "nodes"}, kubernetes.ApiServerNamespacedResources...)
+
+func CreateTapMizuResources(ctx context.Context, kubernetesProvider *kubernetes.Provider, serializedValidationRules string, serializedContract string, serializedMizuConfig string, isNsRestrictedMode bool, mizuResourcesNamespace string, resourceNames kubernetes.ResourceNames, agentImage string, syncEntriesConfig *shared.SyncEntriesConfig, maxEntriesDBSizeBytes int64, persistentStorage bool, storageClass string, apiServerResources shared.Resources, imagePullPolicy core.PullPolicy, logLevel logging.Level, provenanceConfig shared.ProvenanceConfig, cloudIdentity shared.CloudIdentityConfig) (bool, error) {
 	if !isNsRestrictedMode {
-		if err := createMizuNamespace(ctx, kubernetesProvider, mizuResourcesNamespace); err != nil {
+		// the namespace of a former session is kept with the claim of its entries
+		if err := createMizuNamespace(ctx, kubernetesProvider, mizuResourcesNamespace); err != nil && !(persistentStorage && k8serrors.IsAlreadyExists(err)) {
 			return false, err
 		}
 	}
 
-	if err := createMizuConfigmap(ctx, kubernetesProvider, serializedValidationRules, serializedContract, serializedMizuConfig, mizuResourcesNamespace); err != nil {
+	if err := createMizuConfigmap(ctx, kubernetesProvider, serializedValidationRules, serializedContract, serializedMizuConfig, mizuResourcesNamespace, resourceNames.ConfigMapName); err != nil {
 		return false, err
 	}
 
-	mizuServiceAccountExists, err := createRBACIfNecessary(ctx, kubernetesProvider, isNsRestrictedMode, mizuResourcesNamespace, []string{"pods", "services", "endpoints"})
+	mizuServiceAccountExists, err := createRBACIfNecessary(ctx, kubernetesProvider, isNsRestrictedMode, mizuResourcesNamespace, rbacResources)
 	if err != nil {
 		logger.Log.Warningf(uiUtils.Warning, fmt.Sprintf("Failed to ensure the resources required for IP resolving. Mizu will not resolve target IPs to names. error: %v", errormessage.FormatError(err)))
 	}
@@ -37,9 +43,28 @@ func CreateTapMizuResources(ctx context.Context, kubernetesProvider *kubernetes.
 		serviceAccountName = ""
 	}
 
+	if annotations := kubernetes.GetCloudIdentityAnnotations(cloudIdentity); len(annotations) > 0 {
+		if !mizuServiceAccountExists {
+			return mizuServiceAccountExists, fmt.Errorf("the cloud identity can't be bound without the service account %s", kubernetes.ServiceAccountName)
+		}
+		if err := kubernetesProvider.AnnotateServiceAccount(ctx, mizuResourcesNamespace, kubernetes.ServiceAccountName, annotations); err != nil {
+			return mizuServiceAccountExists, fmt.Errorf("failed binding the cloud identity to the service account %s, err: %w", kubernetes.ServiceAccountName, err)
+		}
+	}
+
+	var provenanceSecretName string
+	if provenanceConfig.SecretName != "" {
+		if err := kubernetesProvider.CopyProvenanceSecret(ctx, provenanceConfig.SecretNamespace, provenanceConfig.SecretName, mizuResourcesNamespace, resourceNames.ProvenanceSecretName); err != nil {
+			return mizuServiceAccountExists, fmt.Errorf("failed copying the provenance secret %s, err: %w", provenanceConfig.SecretName, err)
+		}
+		provenanceSecretName = resourceNames.ProvenanceSecretName
+	}
+
 	opts := &kubernetes.ApiServerOptions{
 		Namespace:             mizuResourcesNamespace,
-		PodName:               kubernetes.ApiServerPodName,
+		PodName:               resourceNames.ApiServerPodName,
+		ConfigMapName:         resourceNames.ConfigMapName,
+		SessionId:             resourceNames.SessionId,
 		PodImage:              agentImage,
 		KratosImage:           "",
 		KetoImage:             "",
@@ -50,18 +75,33 @@ func CreateTapMizuResources(ctx context.Context, kubernetesProvider *kubernetes.
 		Resources:             apiServerResources,
 		ImagePullPolicy:       imagePullPolicy,
 		LogLevel:              logLevel,
+		ProvenanceSecretName:  provenanceSecretName,
+		CloudIdentity:         cloudIdentity,
+	}
+
+	var entriesClaimName string
+	if persistentStorage {
+		claimSizeBytes := maxEntriesDBSizeBytes + mizu.InstallModePersistentVolumeSizeBufferBytes
+		claimExists, err := kubernetesProvider.CreatePersistentVolumeClaimIfMissing(ctx, mizuResourcesNamespace, resourceNames.EntriesClaimName, claimSizeBytes, storageClass)
+		if err != nil {
+			return mizuServiceAccountExists, fmt.Errorf("failed creating the persistent volume claim %s, err: %w", resourceNames.EntriesClaimName, err)
+		}
+		if claimExists {
+			logger.Log.Infof("Showing the entries the former sessions stored on persistent volume claim %s", resourceNames.EntriesClaimName)
+		}
+		entriesClaimName = resourceNames.EntriesClaimName
 	}
 
-	if err := createMizuApiServerPod(ctx, kubernetesProvider, opts); err != nil {
+	if err := createMizuApiServerPod(ctx, kubernetesProvider, opts, entriesClaimName); err != nil {
 		return mizuServiceAccountExists, err
 	}
 
-	_, err = kubernetesProvider.CreateService(ctx, mizuResourcesNamespace, kubernetes.ApiServerPodName, kubernetes.ApiServerPodName)
+	_, err = kubernetesProvider.CreateService(ctx, mizuResourcesNamespace, resourceNames.ApiServerPodName, resourceNames.ApiServerPodName)
 	if err != nil {
 		return mizuServiceAccountExists, err
 	}
 
-	logger.Log.Debugf("Successfully created service: %s", kubernetes.ApiServerPodName)
+	logger.Log.Debugf("Successfully created service: %s", resourceNames.ApiServerPodName)
 
 	return mizuServiceAccountExists, nil
 }
@@ -71,11 +111,40 @@ func createMizuNamespace(ctx context.Context, kubernetesProvider *kubernetes.Pro
 	return err
 }
 
-func createMizuConfigmap(ctx context.Context, kubernetesProvider *kubernetes.Provider, serializedValidationRules string, serializedContract string, serializedMizuConfig string, mizuResourcesNamespace string) error {
-	err := kubernetesProvider.CreateConfigMap(ctx, mizuResourcesNamespace, kubernetes.ConfigMapName, serializedValidationRules, serializedContract, serializedMizuConfig)
+func createMizuConfigmap(ctx context.Context, kubernetesProvider *kubernetes.Provider, serializedValidationRules string, serializedContract string, serializedMizuConfig string, mizuResourcesNamespace string, configMapName string) error {
+	err := kubernetesProvider.CreateConfigMap(ctx, mizuResourcesNamespace, configMapName, serializedValidationRules, serializedContract, serializedMizuConfig)
+	return err
+}
+
+// CreateMizuRBAC creates the service account of the api server with its role and binding, existing ones are kept
+func CreateMizuRBAC(ctx context.Context, kubernetesProvider *kubernetes.Provider, isNsRestrictedMode bool, mizuResourcesNamespace string) error {
+	_, err := createRBACIfNecessary(ctx, kubernetesProvider, isNsRestrictedMode, mizuResourcesNamespace, rbacResources)
 	return err
 }
 
+// GetMizuRBACObjects returns the service account of the api server with its role and binding in the namespace, the
+// service account is annotated with the cloud identity
+func GetMizuRBACObjects(kubernetesProvider *kubernetes.Provider, isNsRestrictedMode bool, mizuResourcesNamespace string, cloudIdentity shared.CloudIdentityConfig) []interface{} {
+	var annotations map[string]string
+	if cloudIdentityAnnotations := kubernetes.GetCloudIdentityAnnotations(cloudIdentity); len(cloudIdentityAnnotations) > 0 {
+		annotations = cloudIdentityAnnotations
+	}
+
+	if !isNsRestrictedMode {
+		serviceAccount, clusterRole, clusterRoleBinding := kubernetesProvider.GetMizuRBACObjects(mizuResourcesNamespace, kubernetes.ServiceAccountName, kubernetes.ClusterRoleName, kubernetes.ClusterRoleBindingName, mizu.RBACVersion, rbacResources)
+		serviceAccount.Namespace = mizuResourcesNamespace
+		serviceAccount.Annotations = annotations
+		return []interface{}{serviceAccount, clusterRole, clusterRoleBinding}
+	}
+
+	serviceAccount, role, roleBinding := kubernetesProvider.GetMizuRBACNamespaceRestrictedObjects(mizuResourcesNamespace, kubernetes.ServiceAccountName, kubernetes.RoleName, kubernetes.RoleBindingName, mizu.RBACVersion)
+	serviceAccount.Namespace = mizuResourcesNamespace
+	serviceAccount.Annotations = annotations
+	role.Namespace = mizuResourcesNamespace
+	roleBinding.Namespace = mizuResourcesNamespace
+	return []interface{}{serviceAccount, role, roleBinding}
+}
+
 func createRBACIfNecessary(ctx context.Context, kubernetesProvider *kubernetes.Provider, isNsRestrictedMode bool, mizuResourcesNamespace string, resources []string) (bool, error) {
 	if !isNsRestrictedMode {
 		if err := kubernetesProvider.CreateMizuRBAC(ctx, mizuResourcesNamespace, kubernetes.ServiceAccountName, kubernetes.ClusterRoleName, kubernetes.ClusterRoleBindingName, mizu.RBACVersion, resources); err != nil {
@@ -90,14 +159,15 @@ func createRBACIfNecessary(ctx context.Context, kubernetesProvider *kubernetes.P
 	return true, nil
 }
 
-func createMizuApiServerPod(ctx context.Context, kubernetesProvider *kubernetes.Provider, opts *kubernetes.ApiServerOptions) error {
-	pod, err := kubernetesProvider.GetMizuApiServerPodObject(opts, false, "", false)
+// createMizuApiServerPod creates the api server pod, the entries are stored on the claim when there's one
+func createMizuApiServerPod(ctx context.Context, kubernetesProvider *kubernetes.Provider, opts *kubernetes.ApiServerOptions, entriesClaimName string) error {
+	pod, err := kubernetesProvider.GetMizuApiServerPodObject(opts, entriesClaimName != "", entriesClaimName, false)
 	if err != nil {
 		return err
 	}
 	if _, err = kubernetesProvider.CreatePod(ctx, opts.Namespace, pod); err != nil {
 		return err
 	}
-	logger.Log.Debugf("Successfully created API server pod: %s", kubernetes.ApiServerPodName)
+	logger.Log.Debugf("Successfully created API server pod: %s", opts.PodName)
 	return nil
 }
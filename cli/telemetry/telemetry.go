@@ -42,18 +42,22 @@ func ReportTapTelemetry(apiProvider *apiserver.Provider, args interface{}, start
 		return
 	}
 
-	generalStats, err := apiProvider.GetGeneralStats()
-	if err != nil {
-		logger.Log.Debugf("[ERROR] failed to get general stats from api server %v", err)
-		return
-	}
 	argsBytes, _ := json.Marshal(args)
 	argsMap := map[string]interface{}{
 		"cmd":                    "tap",
 		"args":                   string(argsBytes),
 		"executionTimeInSeconds": int(time.Since(startTime).Seconds()),
-		"apiCallsCount":          generalStats["EntriesCount"],
-		"trafficVolumeInGB":      generalStats["EntriesVolumeInGB"],
+	}
+
+	// the entries aren't counted when the stats are disabled
+	if features, err := apiProvider.GetFeatures(); err != nil || features.Stats {
+		generalStats, err := apiProvider.GetGeneralStats()
+		if err != nil {
+			logger.Log.Debugf("[ERROR] failed to get general stats from api server %v", err)
+			return
+		}
+		argsMap["apiCallsCount"] = generalStats["EntriesCount"]
+		argsMap["trafficVolumeInGB"] = generalStats["EntriesVolumeInGB"]
 	}
 
 	if err := sendTelemetry(argsMap); err != nil {
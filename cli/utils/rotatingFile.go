@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+)
+
+// RotatingFile is a file that's rotated once writing to it would grow it beyond maxSize, the rotated files are
+// renamed to path.1, path.2 and so on, the newest first, and only maxBackups of them are kept
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxBackups int
+	file       *os.File
+	size       int64
+}
+
+// NewRotatingFile opens path for appending, so a restarted writer continues the file it left
+func NewRotatingFile(path string, maxSize int64, maxBackups int) (*RotatingFile, error) {
+	rotatingFile := &RotatingFile{path: path, maxSize: maxSize, maxBackups: maxBackups}
+	if err := rotatingFile.open(); err != nil {
+		return nil, err
+	}
+
+	return rotatingFile, nil
+}
+
+// Write writes p to the current file, p is never split between files so it should hold whole records
+func (rotatingFile *RotatingFile) Write(p []byte) (int, error) {
+	if rotatingFile.size > 0 && rotatingFile.size+int64(len(p)) > rotatingFile.maxSize {
+		if err := rotatingFile.rotate(); err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := rotatingFile.file.Write(p)
+	rotatingFile.size += int64(n)
+	return n, err
+}
+
+func (rotatingFile *RotatingFile) Close() error {
+	return rotatingFile.file.Close()
+}
+
+func (rotatingFile *RotatingFile) open() error {
+	file, err := os.OpenFile(rotatingFile.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	rotatingFile.file = file
+	rotatingFile.size = info.Size()
+	return nil
+}
+
+func (rotatingFile *RotatingFile) rotate() error {
+	if err := rotatingFile.file.Close(); err != nil {
+		return err
+	}
+
+	if rotatingFile.maxBackups > 0 {
+		for i := rotatingFile.maxBackups - 1; i > 0; i-- {
+			if err := os.Rename(rotatingFile.backupPath(i), rotatingFile.backupPath(i+1)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		if err := os.Rename(rotatingFile.path, rotatingFile.backupPath(1)); err != nil {
+			return err
+		}
+	} else if err := os.Remove(rotatingFile.path); err != nil {
+		return err
+	}
+
+	return rotatingFile.open()
+}
+
+func (rotatingFile *RotatingFile) backupPath(index int) string {
+	return fmt.Sprintf("%s.%d", rotatingFile.path, index)
+}
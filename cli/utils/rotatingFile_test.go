@@ -0,0 +1,66 @@
+package utils
+
+import (
+	"os"
+	"path"
+	"testing"
+)
+
+func TestRotatingFile(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "entries.jsonl")
+
+	rotatingFile, err := NewRotatingFile(filePath, 10, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{"first\n", "second\n", "third\n", "fourth\n"} {
+		if _, err := rotatingFile.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rotatingFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		filePath:        "fourth\n",
+		filePath + ".1": "third\n",
+		filePath + ".2": "second\n",
+	}
+	for file, expectedContent := range expected {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(content) != expectedContent {
+			t.Errorf("unexpected content of %s - expected: %q, actual: %q", file, expectedContent, string(content))
+		}
+	}
+
+	if _, err := os.Stat(filePath + ".3"); !os.IsNotExist(err) {
+		t.Errorf("expected only %d backups to be kept", 2)
+	}
+}
+
+func TestRotatingFileAppends(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "entries.jsonl")
+	if err := os.WriteFile(filePath, []byte("first\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	rotatingFile, err := NewRotatingFile(filePath, 100, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotatingFile.Write([]byte("second\n")); err != nil {
+		t.Fatal(err)
+	}
+	if err := rotatingFile.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if content, _ := os.ReadFile(filePath); string(content) != "first\nsecond\n" {
+		t.Errorf("unexpected content - expected: %q, actual: %q", "first\nsecond\n", string(content))
+	}
+}
@@ -0,0 +1,38 @@
+package shared
+
+// AlertRule fires when at least Threshold entries matching Query are stored within WindowSec, and again every
+// CooldownSec while it stays breached. The alerts are posted to WebhookUrl as an Alert, and to SlackUrl, a Slack
+// incoming webhook, as a message
+type AlertRule struct {
+	Id          int    `json:"id"`
+	Name        string `json:"name"`
+	Query       string `json:"query"`
+	Threshold   int    `json:"threshold"`
+	WindowSec   int    `json:"windowSec"`
+	CooldownSec int    `json:"cooldownSec"`
+	WebhookUrl  string `json:"webhookUrl,omitempty"`
+	SlackUrl    string `json:"slackUrl,omitempty"`
+}
+
+// AlertRuleStatus is a rule with the count of the entries of its current window, Error is set while its query can't
+// run
+type AlertRuleStatus struct {
+	AlertRule
+	Count       int    `json:"count"`
+	Firing      bool   `json:"firing"`
+	Fired       int    `json:"fired"`
+	LastFiredAt int64  `json:"lastFiredAt,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// Alert is posted to the webhook of a rule when it fires, Timestamp is the unix milliseconds it fired
+type Alert struct {
+	Rule        string `json:"rule"`
+	Query       string `json:"query"`
+	Count       int    `json:"count"`
+	Threshold   int    `json:"threshold"`
+	WindowSec   int    `json:"windowSec"`
+	Cluster     string `json:"cluster,omitempty"`
+	LastEntryId string `json:"lastEntryId,omitempty"`
+	Timestamp   int64  `json:"timestamp"`
+}
@@ -0,0 +1,184 @@
+package archiveformat
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+var writerTime = time.Unix(1646128800, 0)
+
+func readAll(t *testing.T, reader *Reader) []string {
+	entries := make([]string, 0)
+	for {
+		entry, err := reader.Next()
+		if err == io.EOF {
+			return entries
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		entries = append(entries, string(entry))
+	}
+}
+
+func TestWriteAndRead(t *testing.T) {
+	writer := NewWriter("mizu-agent 0.0.1", "prod-eu", 2)
+	for i, timestamp := range []int64{1000, 3000, 2000} {
+		if err := writer.Add(fmt.Sprintf("entry-%d", i), timestamp, []byte(fmt.Sprintf(`{"id":%d}`, i+1))); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	var archive bytes.Buffer
+	if err := writer.WriteArchive(&archive); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	reader, err := NewReader(&archive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	manifest := reader.Manifest()
+	if manifest.FormatVersion != FormatVersion || manifest.Entries != 3 || manifest.FirstTimestamp != 1000 || manifest.LastTimestamp != 3000 || manifest.Cluster != "prod-eu" {
+		t.Errorf("unexpected manifest: %+v", manifest)
+	}
+
+	expectedChunks := []Chunk{
+		{Name: "chunks/000000.ndjson.gz", Entries: 2, FirstTimestamp: 1000, LastTimestamp: 3000},
+		{Name: "chunks/000001.ndjson.gz", Entries: 1, FirstTimestamp: 2000, LastTimestamp: 2000},
+	}
+	if !reflect.DeepEqual(manifest.Chunks, expectedChunks) {
+		t.Errorf("unexpected result - expected: %+v, actual: %+v", expectedChunks, manifest.Chunks)
+	}
+
+	expected := []string{`{"id":1}`, `{"id":2}`, `{"id":3}`}
+	if actual := readAll(t, reader); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestWriteIndex(t *testing.T) {
+	writer := NewWriter("", "", 1)
+	_ = writer.Add("first", 1000, []byte(`{}`))
+	_ = writer.Add("second", 2000, []byte(`{}`))
+
+	var archive bytes.Buffer
+	if err := writer.WriteArchive(&archive); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tarReader := tar.NewReader(&archive)
+	names := make([]string, 0)
+	var index string
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		names = append(names, header.Name)
+		if header.Name == IndexName {
+			data, _ := ioutil.ReadAll(tarReader)
+			index = string(data)
+		}
+	}
+
+	expectedNames := []string{ManifestName, IndexName, "chunks/000000.ndjson.gz", "chunks/000001.ndjson.gz"}
+	if !reflect.DeepEqual(names, expectedNames) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expectedNames, names)
+	}
+
+	expectedIndex := `{"entryId":"first","timestamp":1000,"chunk":0,"line":0}` + "\n" + `{"entryId":"second","timestamp":2000,"chunk":1,"line":0}` + "\n"
+	if index != expectedIndex {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expectedIndex, index)
+	}
+}
+
+func TestReadLegacy(t *testing.T) {
+	var gzipped bytes.Buffer
+	gzipWriter := gzip.NewWriter(&gzipped)
+	_, _ = gzipWriter.Write([]byte("{\"id\":1}\n\n{\"id\":2}\n"))
+	_ = gzipWriter.Close()
+
+	tests := map[string]io.Reader{
+		"gzip":  &gzipped,
+		"plain": strings.NewReader("{\"id\":1}\n{\"id\":2}"),
+	}
+
+	for name, archive := range tests {
+		reader, err := NewReader(archive)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+
+		if reader.Manifest().FormatVersion != LegacyFormatVersion {
+			t.Errorf("%s: unexpected result - expected version: %v, actual: %v", name, LegacyFormatVersion, reader.Manifest().FormatVersion)
+		}
+
+		expected := []string{`{"id":1}`, `{"id":2}`}
+		if actual := readAll(t, reader); !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: unexpected result - expected: %v, actual: %v", name, expected, actual)
+		}
+	}
+}
+
+func TestReadNewerVersion(t *testing.T) {
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+	_ = writeFile(tarWriter, ManifestName, []byte(`{"formatVersion":3}`), writerTime)
+	_ = tarWriter.Close()
+
+	if _, err := NewReader(&archive); !errors.Is(err, ErrUnsupportedFormatVersion) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", ErrUnsupportedFormatVersion, err)
+	}
+}
+
+func TestReadIgnoresUnknownFiles(t *testing.T) {
+	var chunk bytes.Buffer
+	gzipWriter := gzip.NewWriter(&chunk)
+	_, _ = gzipWriter.Write([]byte("{\"id\":1}\n"))
+	_ = gzipWriter.Close()
+
+	var archive bytes.Buffer
+	tarWriter := tar.NewWriter(&archive)
+	_ = writeFile(tarWriter, ManifestName, []byte(`{"formatVersion":2,"entries":1,"newField":true}`), writerTime)
+	_ = writeFile(tarWriter, "signatures.json", []byte(`{}`), writerTime)
+	_ = writeFile(tarWriter, "chunks/000000.ndjson.gz", chunk.Bytes(), writerTime)
+	_ = tarWriter.Close()
+
+	reader, err := NewReader(&archive)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []string{`{"id":1}`}
+	if actual := readAll(t, reader); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
+
+func TestIsArchiveKey(t *testing.T) {
+	tests := map[string]bool{
+		"prod/2022/03/01/1-2.mizu.tar":  true,
+		"prod/2022/03/01/1-2.ndjson.gz": true,
+		"prod/notes.txt":                false,
+	}
+
+	for key, expected := range tests {
+		if actual := IsArchiveKey(key); actual != expected {
+			t.Errorf("unexpected result for %s - expected: %v, actual: %v", key, expected, actual)
+		}
+	}
+}
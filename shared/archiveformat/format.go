@@ -0,0 +1,87 @@
+// Package archiveformat reads and writes the archives of the captured entries, the objects mizu uploads to a bucket
+// and replays with mizu replay --from.
+//
+// An archive of version 2 is an uncompressed tar, suffixed .mizu.tar, holding in this order:
+//
+//	manifest.json            the Manifest, always the first file
+//	index.ndjson             an IndexEntry of every entry on a line of its own, in the order of the entries
+//	chunks/000000.ndjson.gz  the entries, gzipped JSON on a line each, up to a chunk size of entries a chunk
+//	chunks/000001.ndjson.gz  ...
+//
+// The entries are the JSON documents mizu stores, like the ones of the entries API. The index locates an entry by its
+// chunk and the line in it, so a tool can read a single chunk instead of the whole archive.
+//
+// The format version only changes when the readers of the previous version can't read the archives anymore, new
+// fields of the manifest and of the index, and new files, are added without changing it, so the readers ignore the
+// fields and the files they don't know. The readers refuse the versions newer than the one they know.
+//
+// Version 1 is the archive of the earlier releases, suffixed .ndjson.gz, the gzipped entries on a line each without a
+// manifest. The readers keep reading it, as well as plain NDJSON.
+package archiveformat
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	FormatVersion       = 2
+	LegacyFormatVersion = 1
+
+	ObjectSuffix       = ".mizu.tar"
+	LegacyObjectSuffix = ".ndjson.gz"
+
+	ManifestName = "manifest.json"
+	IndexName    = "index.ndjson"
+	chunksDir    = "chunks/"
+	chunkSuffix  = ".ndjson.gz"
+
+	DefaultChunkEntries = 1000
+	// an entry is a line of a chunk, a single entry may hold a large body
+	MaxEntryBytes = 64 * 1024 * 1024
+)
+
+var ErrUnsupportedFormatVersion = errors.New("unsupported archive format version")
+
+// Manifest describes an archive, the timestamps are the unix milliseconds of the entries
+type Manifest struct {
+	FormatVersion  int       `json:"formatVersion"`
+	CreatedAt      time.Time `json:"createdAt"`
+	Producer       string    `json:"producer,omitempty"`
+	Cluster        string    `json:"cluster,omitempty"`
+	Entries        int       `json:"entries"`
+	FirstTimestamp int64     `json:"firstTimestamp"`
+	LastTimestamp  int64     `json:"lastTimestamp"`
+	Chunks         []Chunk   `json:"chunks"`
+}
+
+type Chunk struct {
+	Name           string `json:"name"`
+	Entries        int    `json:"entries"`
+	FirstTimestamp int64  `json:"firstTimestamp"`
+	LastTimestamp  int64  `json:"lastTimestamp"`
+}
+
+// IndexEntry locates an entry, Chunk is the index of its chunk in the manifest and Line its line in the chunk, both
+// from 0
+type IndexEntry struct {
+	EntryId   string `json:"entryId"`
+	Timestamp int64  `json:"timestamp"`
+	Chunk     int    `json:"chunk"`
+	Line      int    `json:"line"`
+}
+
+// IsArchiveKey is true for the objects of the archives of every version
+func IsArchiveKey(key string) bool {
+	return strings.HasSuffix(key, ObjectSuffix) || strings.HasSuffix(key, LegacyObjectSuffix)
+}
+
+func chunkName(chunk int) string {
+	return fmt.Sprintf("%s%06d%s", chunksDir, chunk, chunkSuffix)
+}
+
+func isChunkName(name string) bool {
+	return strings.HasPrefix(name, chunksDir) && strings.HasSuffix(name, chunkSuffix)
+}
@@ -0,0 +1,129 @@
+package archiveformat
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+const (
+	// the magic of a tar header, at the same offset in the ustar, pax and gnu formats
+	tarMagicOffset = 257
+	tarMagic       = "ustar"
+)
+
+// Reader reads the entries of an archive of any version, in the order they were archived
+type Reader struct {
+	manifest  *Manifest
+	tarReader *tar.Reader
+	lines     *bufio.Scanner
+	chunk     io.Closer
+}
+
+// NewReader reads the manifest of an archive, an archive of version 1 or plain NDJSON has a manifest with only
+// its FormatVersion set. The error wraps ErrUnsupportedFormatVersion for the archives of later versions
+func NewReader(r io.Reader) (*Reader, error) {
+	buffered := bufio.NewReader(r)
+	// a short archive is detected with what there is of it
+	header, _ := buffered.Peek(tarMagicOffset + len(tarMagic))
+
+	if len(header) >= 2 && header[0] == 0x1f && header[1] == 0x8b {
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gzip archive, %w", err)
+		}
+		return &Reader{manifest: &Manifest{FormatVersion: LegacyFormatVersion}, lines: newLineScanner(gzipReader), chunk: gzipReader}, nil
+	}
+
+	if len(header) < tarMagicOffset+len(tarMagic) || string(header[tarMagicOffset:]) != tarMagic {
+		return &Reader{manifest: &Manifest{FormatVersion: LegacyFormatVersion}, lines: newLineScanner(buffered)}, nil
+	}
+
+	tarReader := tar.NewReader(buffered)
+	manifestHeader, err := tarReader.Next()
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive, %w", err)
+	}
+	if manifestHeader.Name != ManifestName {
+		return nil, fmt.Errorf("invalid archive, it starts with %s instead of %s", manifestHeader.Name, ManifestName)
+	}
+
+	manifest := &Manifest{}
+	if err := json.NewDecoder(tarReader).Decode(manifest); err != nil {
+		return nil, fmt.Errorf("invalid archive manifest, %w", err)
+	}
+	if manifest.FormatVersion > FormatVersion {
+		return nil, fmt.Errorf("%w, the archive is of version %d and this mizu reads up to version %d, upgrade mizu to read it", ErrUnsupportedFormatVersion, manifest.FormatVersion, FormatVersion)
+	}
+	if manifest.FormatVersion <= LegacyFormatVersion {
+		return nil, fmt.Errorf("invalid archive manifest, format version %d", manifest.FormatVersion)
+	}
+
+	return &Reader{manifest: manifest, tarReader: tarReader}, nil
+}
+
+func (reader *Reader) Manifest() *Manifest {
+	return reader.manifest
+}
+
+// Next returns the json of the next entry, it's valid until the next call. io.EOF is returned after the last entry
+func (reader *Reader) Next() ([]byte, error) {
+	for {
+		if reader.lines != nil {
+			for reader.lines.Scan() {
+				if line := reader.lines.Bytes(); len(bytes.TrimSpace(line)) > 0 {
+					return line, nil
+				}
+			}
+			if err := reader.lines.Err(); err != nil {
+				return nil, err
+			}
+
+			reader.lines = nil
+			if err := reader.Close(); err != nil {
+				return nil, err
+			}
+		}
+
+		if reader.tarReader == nil {
+			return nil, io.EOF
+		}
+
+		header, err := reader.tarReader.Next()
+		if err != nil {
+			return nil, err
+		}
+		// the index and the files of later releases aren't entries
+		if !isChunkName(header.Name) {
+			continue
+		}
+
+		gzipReader, err := gzip.NewReader(reader.tarReader)
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive chunk %s, %w", header.Name, err)
+		}
+		reader.lines = newLineScanner(gzipReader)
+		reader.chunk = gzipReader
+	}
+}
+
+// Close releases the chunk being read, the archive itself is closed by the caller
+func (reader *Reader) Close() error {
+	if reader.chunk == nil {
+		return nil
+	}
+
+	chunk := reader.chunk
+	reader.chunk = nil
+	return chunk.Close()
+}
+
+func newLineScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), MaxEntryBytes)
+	return scanner
+}
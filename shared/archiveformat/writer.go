@@ -0,0 +1,174 @@
+package archiveformat
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Writer builds an archive in memory, the entries are added in the order they're read back
+type Writer struct {
+	manifest     Manifest
+	chunkEntries int
+	chunks       [][]byte
+	chunk        *bytes.Buffer
+	gzipWriter   *gzip.Writer
+	index        *bytes.Buffer
+}
+
+// NewWriter starts an archive of the producer, like "mizu-agent 0.0.1", chunkEntries of 0 is DefaultChunkEntries
+func NewWriter(producer string, cluster string, chunkEntries int) *Writer {
+	if chunkEntries <= 0 {
+		chunkEntries = DefaultChunkEntries
+	}
+
+	return &Writer{
+		manifest: Manifest{
+			FormatVersion: FormatVersion,
+			Producer:      producer,
+			Cluster:       cluster,
+			Chunks:        make([]Chunk, 0),
+		},
+		chunkEntries: chunkEntries,
+		index:        &bytes.Buffer{},
+	}
+}
+
+// Add appends the json of an entry, timestamp is the unix milliseconds of the entry
+func (writer *Writer) Add(entryId string, timestamp int64, data []byte) error {
+	if bytes.IndexByte(data, '\n') >= 0 {
+		return fmt.Errorf("the json of entry %s isn't on a single line", entryId)
+	}
+
+	if writer.gzipWriter == nil {
+		writer.chunk = &bytes.Buffer{}
+		writer.gzipWriter = gzip.NewWriter(writer.chunk)
+		writer.manifest.Chunks = append(writer.manifest.Chunks, Chunk{Name: chunkName(len(writer.manifest.Chunks))})
+	}
+
+	if _, err := writer.gzipWriter.Write(data); err != nil {
+		return err
+	}
+	if _, err := writer.gzipWriter.Write([]byte{'\n'}); err != nil {
+		return err
+	}
+
+	chunkIndex := len(writer.manifest.Chunks) - 1
+	chunk := &writer.manifest.Chunks[chunkIndex]
+	indexEntry, err := json.Marshal(&IndexEntry{EntryId: entryId, Timestamp: timestamp, Chunk: chunkIndex, Line: chunk.Entries})
+	if err != nil {
+		return err
+	}
+	writer.index.Write(indexEntry)
+	writer.index.WriteByte('\n')
+
+	updateTimestamps(&chunk.FirstTimestamp, &chunk.LastTimestamp, chunk.Entries, timestamp)
+	chunk.Entries++
+	updateTimestamps(&writer.manifest.FirstTimestamp, &writer.manifest.LastTimestamp, writer.manifest.Entries, timestamp)
+	writer.manifest.Entries++
+
+	if chunk.Entries >= writer.chunkEntries {
+		return writer.closeChunk()
+	}
+
+	return nil
+}
+
+func updateTimestamps(first *int64, last *int64, entries int, timestamp int64) {
+	if entries == 0 || timestamp < *first {
+		*first = timestamp
+	}
+	if entries == 0 || timestamp > *last {
+		*last = timestamp
+	}
+}
+
+func (writer *Writer) Entries() int {
+	return writer.manifest.Entries
+}
+
+func (writer *Writer) FirstTimestamp() int64 {
+	return writer.manifest.FirstTimestamp
+}
+
+func (writer *Writer) LastTimestamp() int64 {
+	return writer.manifest.LastTimestamp
+}
+
+// Size is about the size of the archive so far, the entries still buffered by the compression aren't counted
+func (writer *Writer) Size() int {
+	size := writer.index.Len()
+	for _, chunk := range writer.chunks {
+		size += len(chunk)
+	}
+	if writer.chunk != nil {
+		size += writer.chunk.Len()
+	}
+
+	return size
+}
+
+// WriteArchive writes the archive to out, the writer can't be added to afterwards
+func (writer *Writer) WriteArchive(out io.Writer) error {
+	if err := writer.closeChunk(); err != nil {
+		return err
+	}
+
+	writer.manifest.CreatedAt = time.Now().UTC()
+	manifest, err := json.MarshalIndent(&writer.manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tarWriter := tar.NewWriter(out)
+	if err := writeFile(tarWriter, ManifestName, manifest, writer.manifest.CreatedAt); err != nil {
+		return err
+	}
+	if err := writeFile(tarWriter, IndexName, writer.index.Bytes(), writer.manifest.CreatedAt); err != nil {
+		return err
+	}
+	for i, chunk := range writer.chunks {
+		if err := writeFile(tarWriter, writer.manifest.Chunks[i].Name, chunk, writer.manifest.CreatedAt); err != nil {
+			return err
+		}
+	}
+
+	return tarWriter.Close()
+}
+
+func (writer *Writer) closeChunk() error {
+	if writer.gzipWriter == nil {
+		return nil
+	}
+
+	if err := writer.gzipWriter.Close(); err != nil {
+		return err
+	}
+
+	writer.chunks = append(writer.chunks, writer.chunk.Bytes())
+	writer.chunk = nil
+	writer.gzipWriter = nil
+	return nil
+}
+
+func writeFile(tarWriter *tar.Writer, name string, data []byte, modTime time.Time) error {
+	header := &tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  modTime,
+		// the readers tell the archives from the legacy ones by the ustar magic
+		Format: tar.FormatUSTAR,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := tarWriter.Write(data)
+	return err
+}
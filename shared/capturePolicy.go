@@ -0,0 +1,135 @@
+package shared
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+var captureHostNameRegex = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*\.?$`)
+
+// CapturePolicy is the cluster admin's allowlist and denylist of the hosts whose traffic the tappers capture, read from
+// the mizu-capture-policy config map of the kube-system namespace. Mizu never writes that config map, so the policy
+// holds whatever the tap options are. A host is an ip, a cidr or a host name, the denylist wins over the allowlist and
+// an empty allowlist allows every host
+type CapturePolicy struct {
+	AllowedHosts []string `yaml:"allowed-hosts" json:"allowedHosts"`
+	DeniedHosts  []string `yaml:"denied-hosts" json:"deniedHosts"`
+}
+
+func ParseCapturePolicy(content []byte) (*CapturePolicy, error) {
+	policy := &CapturePolicy{}
+	if err := yaml.Unmarshal(content, policy); err != nil {
+		return nil, err
+	}
+
+	for _, host := range append(append([]string{}, policy.AllowedHosts...), policy.DeniedHosts...) {
+		if !isValidCaptureHost(host) {
+			return nil, fmt.Errorf("host %q isn't an ip, a cidr or a host name", host)
+		}
+	}
+
+	return policy, nil
+}
+
+func isValidCaptureHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	if _, _, err := net.ParseCIDR(host); err == nil {
+		return true
+	}
+
+	return len(host) <= 253 && captureHostNameRegex.MatchString(host)
+}
+
+// CapturePolicyMatcher matches the addresses of the captured packets against a capture policy, a nil matcher allows
+// every address
+type CapturePolicyMatcher struct {
+	denyAll      bool
+	hasAllowlist bool
+	allowed      *captureHostSet
+	denied       *captureHostSet
+}
+
+type captureHostSet struct {
+	ips      map[string]bool
+	networks []*net.IPNet
+}
+
+// NewCapturePolicyMatcher resolves the host names of the policy with lookupHost, the ones that don't resolve match no
+// address, an allowlist of only such host names allows nothing
+func NewCapturePolicyMatcher(policy *CapturePolicy, lookupHost func(host string) ([]string, error)) *CapturePolicyMatcher {
+	return &CapturePolicyMatcher{
+		hasAllowlist: len(policy.AllowedHosts) > 0,
+		allowed:      newCaptureHostSet(policy.AllowedHosts, lookupHost),
+		denied:       newCaptureHostSet(policy.DeniedHosts, lookupHost),
+	}
+}
+
+// NewDenyAllCapturePolicyMatcher doesn't allow any address, it stands in for a policy that can't be read so a broken
+// policy doesn't capture the hosts it's meant to deny
+func NewDenyAllCapturePolicyMatcher() *CapturePolicyMatcher {
+	return &CapturePolicyMatcher{denyAll: true}
+}
+
+func newCaptureHostSet(hosts []string, lookupHost func(host string) ([]string, error)) *captureHostSet {
+	hostSet := &captureHostSet{ips: make(map[string]bool)}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			hostSet.ips[ip.String()] = true
+		} else if _, network, err := net.ParseCIDR(host); err == nil {
+			hostSet.networks = append(hostSet.networks, network)
+		} else if addresses, err := lookupHost(host); err == nil {
+			for _, address := range addresses {
+				if ip := net.ParseIP(address); ip != nil {
+					hostSet.ips[ip.String()] = true
+				}
+			}
+		}
+	}
+
+	return hostSet
+}
+
+func (hostSet *captureHostSet) contains(ip net.IP) bool {
+	if hostSet.ips[ip.String()] {
+		return true
+	}
+	for _, network := range hostSet.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Allows reports whether the traffic between the addresses may be captured, none of them may be denied and one of
+// them must be allowed when there's an allowlist. The addresses that aren't ips are ignored
+func (matcher *CapturePolicyMatcher) Allows(addresses ...string) bool {
+	if matcher == nil {
+		return true
+	}
+	if matcher.denyAll {
+		return false
+	}
+
+	isAllowed := !matcher.hasAllowlist
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			continue
+		}
+		if matcher.denied.contains(ip) {
+			return false
+		}
+		if !isAllowed && matcher.allowed.contains(ip) {
+			isAllowed = true
+		}
+	}
+
+	return isAllowed
+}
@@ -0,0 +1,53 @@
+package shared_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+func lookupTestHost(host string) ([]string, error) {
+	if host == "sso.corp.example" {
+		return []string{"10.1.2.3", "fd00::3"}, nil
+	}
+
+	return nil, fmt.Errorf("no such host %s", host)
+}
+
+func TestCapturePolicyMatcherAllows(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Policy    *shared.CapturePolicy
+		Addresses []string
+		Expected  bool
+	}{
+		{Name: "no policy", Policy: &shared.CapturePolicy{}, Addresses: []string{"10.1.2.3", "10.0.0.1"}, Expected: true},
+		{Name: "denied ip", Policy: &shared.CapturePolicy{DeniedHosts: []string{"10.0.0.1"}}, Addresses: []string{"10.0.0.2", "10.0.0.1"}, Expected: false},
+		{Name: "denied cidr", Policy: &shared.CapturePolicy{DeniedHosts: []string{"10.0.0.0/24"}}, Addresses: []string{"10.0.0.200"}, Expected: false},
+		{Name: "denied host name", Policy: &shared.CapturePolicy{DeniedHosts: []string{"sso.corp.example"}}, Addresses: []string{"10.0.0.2", "fd00:0::3"}, Expected: false},
+		{Name: "allowed ip", Policy: &shared.CapturePolicy{AllowedHosts: []string{"10.0.0.1"}}, Addresses: []string{"10.0.0.2", "10.0.0.1"}, Expected: true},
+		{Name: "not allowed ip", Policy: &shared.CapturePolicy{AllowedHosts: []string{"10.0.0.1"}}, Addresses: []string{"10.0.0.2", "10.0.0.3"}, Expected: false},
+		{Name: "denied wins", Policy: &shared.CapturePolicy{AllowedHosts: []string{"10.0.0.0/8"}, DeniedHosts: []string{"sso.corp.example"}}, Addresses: []string{"10.0.0.2", "10.1.2.3"}, Expected: false},
+		{Name: "unresolved allowlist", Policy: &shared.CapturePolicy{AllowedHosts: []string{"unknown.example"}}, Addresses: []string{"10.0.0.2"}, Expected: false},
+		{Name: "unknown addresses", Policy: &shared.CapturePolicy{DeniedHosts: []string{"10.0.0.1"}}, Addresses: []string{"unknown"}, Expected: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			actual := shared.NewCapturePolicyMatcher(test.Policy, lookupTestHost).Allows(test.Addresses...)
+			if actual != test.Expected {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.Expected, actual)
+			}
+		})
+	}
+
+	if shared.NewDenyAllCapturePolicyMatcher().Allows("10.0.0.2") {
+		t.Errorf("unexpected result - expected: %v, actual: %v", false, true)
+	}
+
+	var noMatcher *shared.CapturePolicyMatcher
+	if !noMatcher.Allows("10.0.0.2") {
+		t.Errorf("unexpected result - expected: %v, actual: %v", true, false)
+	}
+}
@@ -0,0 +1,16 @@
+package shared
+
+// ChatOpsConfig accepts the slash commands of Slack, verified with the signing secret of the Slack app, and of
+// Mattermost, verified with the token of the slash command, so the incident channel can start and stop the tap.
+// LinkUrl is the address the links back to the GUI start with and MaxDuration caps the duration of a tap command
+type ChatOpsConfig struct {
+	SlackSigningSecret string `yaml:"slack-signing-secret,omitempty" json:"slackSigningSecret"`
+	MattermostToken    string `yaml:"mattermost-token,omitempty" json:"mattermostToken"`
+	LinkUrl            string `yaml:"link-url,omitempty" json:"linkUrl"`
+	DefaultDuration    string `yaml:"default-duration" json:"defaultDuration" default:"15m"`
+	MaxDuration        string `yaml:"max-duration" json:"maxDuration" default:"2h"`
+}
+
+func (config *ChatOpsConfig) IsEnabled() bool {
+	return config.SlackSigningSecret != "" || config.MattermostToken != ""
+}
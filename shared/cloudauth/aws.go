@@ -0,0 +1,167 @@
+package cloudauth
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the env vars the pod identity webhook of EKS sets in the pods of a service account annotated with a role
+const (
+	awsRoleArnEnvVar              = "AWS_ROLE_ARN"
+	awsWebIdentityTokenFileEnvVar = "AWS_WEB_IDENTITY_TOKEN_FILE"
+	awsRegionEnvVar               = "AWS_REGION"
+)
+
+const (
+	defaultStsUrl  = "https://sts.amazonaws.com/"
+	stsApiVersion  = "2011-06-15"
+	roleSessionTag = "mizu"
+)
+
+// AwsCredentials sign the requests to aws, SessionToken is only set for the temporary credentials of a role
+type AwsCredentials struct {
+	AccessKeyId     string
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      time.Time
+}
+
+// AwsCredentialsProvider returns the static credentials of a config, or the temporary credentials of the role that
+// is bound to the service account of the pod by IRSA, which are renewed shortly before they expire
+type AwsCredentialsProvider struct {
+	mutex       sync.Mutex
+	roleArn     string
+	tokenFile   string
+	stsUrl      string
+	httpClient  *http.Client
+	now         func() time.Time
+	credentials *AwsCredentials
+}
+
+func NewStaticAwsCredentialsProvider(accessKeyId string, secretAccessKey string) *AwsCredentialsProvider {
+	return &AwsCredentialsProvider{
+		now:         time.Now,
+		credentials: &AwsCredentials{AccessKeyId: accessKeyId, SecretAccessKey: secretAccessKey},
+	}
+}
+
+// NewAwsWebIdentityProvider assumes the role of the service account of the pod with its projected token, like the
+// aws sdks do, the role and the token file are set in the pod by EKS
+func NewAwsWebIdentityProvider() (*AwsCredentialsProvider, error) {
+	roleArn := os.Getenv(awsRoleArnEnvVar)
+	tokenFile := os.Getenv(awsWebIdentityTokenFileEnvVar)
+	if roleArn == "" || tokenFile == "" {
+		return nil, fmt.Errorf("%s and %s aren't set, the service account of the pod isn't annotated with an IAM role", awsRoleArnEnvVar, awsWebIdentityTokenFileEnvVar)
+	}
+
+	// the regional endpoint is used when the region is known, it's reachable from private subnets with an endpoint
+	stsUrl := defaultStsUrl
+	if region := AwsRegion(); region != "" {
+		stsUrl = fmt.Sprintf("https://sts.%s.amazonaws.com/", region)
+	}
+
+	return &AwsCredentialsProvider{
+		roleArn:    roleArn,
+		tokenFile:  tokenFile,
+		stsUrl:     stsUrl,
+		httpClient: &http.Client{Timeout: requestTimeout},
+		now:        time.Now,
+	}, nil
+}
+
+// AwsRegion is the region of the pod, set by EKS along with the role
+func AwsRegion() string {
+	return os.Getenv(awsRegionEnvVar)
+}
+
+// Identity describes the credentials, for the health of the destinations
+func (provider *AwsCredentialsProvider) Identity() string {
+	if provider.roleArn != "" {
+		return fmt.Sprintf("aws role %s", provider.roleArn)
+	}
+
+	return fmt.Sprintf("aws access key %s", provider.credentials.AccessKeyId)
+}
+
+func (provider *AwsCredentialsProvider) Credentials(ctx context.Context) (*AwsCredentials, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if provider.credentials != nil && (provider.roleArn == "" || provider.now().Add(expiryMargin).Before(provider.credentials.Expiration)) {
+		return provider.credentials, nil
+	}
+
+	credentials, err := provider.assumeRoleWithWebIdentity(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed assuming role %s: %w", provider.roleArn, err)
+	}
+
+	provider.credentials = credentials
+	return credentials, nil
+}
+
+type assumeRoleWithWebIdentityResponse struct {
+	AccessKeyId     string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>AccessKeyId"`
+	SecretAccessKey string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>SecretAccessKey"`
+	SessionToken    string    `xml:"AssumeRoleWithWebIdentityResult>Credentials>SessionToken"`
+	Expiration      time.Time `xml:"AssumeRoleWithWebIdentityResult>Credentials>Expiration"`
+}
+
+// assumeRoleWithWebIdentity isn't signed, STS authenticates the call by the token of the service account, which is
+// read on every call since the kubelet rotates it
+func (provider *AwsCredentialsProvider) assumeRoleWithWebIdentity(ctx context.Context) (*AwsCredentials, error) {
+	token, err := ioutil.ReadFile(provider.tokenFile)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("Action", "AssumeRoleWithWebIdentity")
+	form.Set("Version", stsApiVersion)
+	form.Set("RoleArn", provider.roleArn)
+	form.Set("RoleSessionName", fmt.Sprintf("%s-%d", roleSessionTag, provider.now().Unix()))
+	form.Set("WebIdentityToken", strings.TrimSpace(string(token)))
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, provider.stsUrl, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	response, err := provider.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("sts responded with status %d: %s", response.StatusCode, body)
+	}
+
+	var result assumeRoleWithWebIdentityResponse
+	if err := xml.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed parsing the sts response: %w", err)
+	}
+	if result.AccessKeyId == "" || result.SecretAccessKey == "" {
+		return nil, fmt.Errorf("the sts response has no credentials")
+	}
+
+	return &AwsCredentials{
+		AccessKeyId:     result.AccessKeyId,
+		SecretAccessKey: result.SecretAccessKey,
+		SessionToken:    result.SessionToken,
+		Expiration:      result.Expiration,
+	}, nil
+}
@@ -0,0 +1,105 @@
+package cloudauth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// the auths of a destination, an empty auth is the static credentials of its config
+const (
+	AuthAws   = "aws"
+	AuthGcp   = "gcp"
+	AuthAzure = "azure"
+)
+
+const (
+	// the credentials are renewed this long before they expire, so a request doesn't go out with expired ones
+	expiryMargin = 5 * time.Minute
+
+	requestTimeout = 10 * time.Second
+)
+
+// Validate errors when the auth isn't one of the supported auths of a destination
+func Validate(auth string, destination string, supported ...string) error {
+	if auth == "" {
+		return nil
+	}
+
+	for _, supportedAuth := range supported {
+		if auth == supportedAuth {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s isn't a supported auth of %s, the supported auths are %s", auth, destination, strings.Join(supported, ", "))
+}
+
+type oauthToken struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// TokenProvider returns the oauth access tokens of the cloud identity of the pod, a token is kept until shortly
+// before it expires
+type TokenProvider struct {
+	mutex    sync.Mutex
+	identity string
+	fetch    func(ctx context.Context) (*oauthToken, error)
+	now      func() time.Time
+	token    string
+	expiry   time.Time
+}
+
+func (provider *TokenProvider) Token(ctx context.Context) (string, error) {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	if provider.token != "" && provider.now().Add(expiryMargin).Before(provider.expiry) {
+		return provider.token, nil
+	}
+
+	token, err := provider.fetch(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed getting a token of %s: %w", provider.identity, err)
+	}
+
+	provider.token = token.AccessToken
+	provider.expiry = provider.now().Add(time.Duration(token.ExpiresIn) * time.Second)
+	return provider.token, nil
+}
+
+// Identity describes the cloud identity the tokens are of, for the health of the destinations
+func (provider *TokenProvider) Identity() string {
+	provider.mutex.Lock()
+	defer provider.mutex.Unlock()
+
+	return provider.identity
+}
+
+func readTokenResponse(response *http.Response) (*oauthToken, error) {
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token request failed with status %d: %s", response.StatusCode, body)
+	}
+
+	var token oauthToken
+	if err := json.Unmarshal(body, &token); err != nil {
+		return nil, fmt.Errorf("failed parsing the token response: %w", err)
+	}
+	if token.AccessToken == "" {
+		return nil, fmt.Errorf("the token response has no access token")
+	}
+
+	return &token, nil
+}
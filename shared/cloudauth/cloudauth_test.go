@@ -0,0 +1,131 @@
+package cloudauth
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strings"
+	"testing"
+	"time"
+)
+
+const assumeRoleResponse = `<AssumeRoleWithWebIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleWithWebIdentityResult>
+    <Credentials>
+      <AccessKeyId>ASIAEXAMPLE</AccessKeyId>
+      <SecretAccessKey>secret</SecretAccessKey>
+      <SessionToken>session</SessionToken>
+      <Expiration>2022-03-01T01:00:00Z</Expiration>
+    </Credentials>
+  </AssumeRoleWithWebIdentityResult>
+</AssumeRoleWithWebIdentityResponse>`
+
+func TestAssumeRoleWithWebIdentity(t *testing.T) {
+	tokenFile := path.Join(t.TempDir(), "token")
+	if err := ioutil.WriteFile(tokenFile, []byte("service-account-token\n"), 0600); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if err := r.ParseForm(); err != nil || r.Form.Get("WebIdentityToken") != "service-account-token" || r.Form.Get("RoleArn") != "arn:aws:iam::123456789012:role/mizu" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		_, _ = w.Write([]byte(assumeRoleResponse))
+	}))
+	defer server.Close()
+
+	now := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	provider := &AwsCredentialsProvider{
+		roleArn:    "arn:aws:iam::123456789012:role/mizu",
+		tokenFile:  tokenFile,
+		stsUrl:     server.URL,
+		httpClient: server.Client(),
+		now:        func() time.Time { return now },
+	}
+
+	for i := 0; i < 2; i++ {
+		credentials, err := provider.Credentials(context.Background())
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if credentials.AccessKeyId != "ASIAEXAMPLE" || credentials.SessionToken != "session" {
+			t.Errorf("unexpected result - expected: %v, actual: %+v", "the credentials of the role", credentials)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 1, calls)
+	}
+
+	// the credentials are renewed once they're about to expire
+	now = now.Add(58 * time.Minute)
+	if _, err := provider.Credentials(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 2, calls)
+	}
+}
+
+func TestTokenProvider(t *testing.T) {
+	now := time.Unix(1000, 0)
+	fetches := 0
+	provider := &TokenProvider{
+		identity: "test identity",
+		now:      func() time.Time { return now },
+		fetch: func(ctx context.Context) (*oauthToken, error) {
+			fetches++
+			return &oauthToken{AccessToken: "token", ExpiresIn: 3600}, nil
+		},
+	}
+
+	for _, elapsed := range []time.Duration{0, 30 * time.Minute, 56 * time.Minute} {
+		now = time.Unix(1000, 0).Add(elapsed)
+		if token, err := provider.Token(context.Background()); err != nil || token != "token" {
+			t.Fatalf("unexpected result - expected: %v, actual: %v, %v", "token", token, err)
+		}
+	}
+
+	if fetches != 2 {
+		t.Errorf("unexpected result - expected: %v, actual: %v", 2, fetches)
+	}
+}
+
+func TestSigningTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		authorization := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authorization, signingAlgorithm+" Credential=AKIDEXAMPLE/") || !strings.Contains(authorization, "/us-east-1/es/aws4_request") || string(body) != `{"a":1}` {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	credentials := NewStaticAwsCredentialsProvider("AKIDEXAMPLE", "secret")
+	client := &http.Client{Transport: NewSigningTransport(http.DefaultTransport, credentials, "us-east-1", "es")}
+
+	response, err := client.Post(server.URL+"/index/_doc", "application/json", strings.NewReader(`{"a":1}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		t.Errorf("unexpected result - expected: %v, actual: %v", http.StatusOK, response.StatusCode)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate("", "elastic", AuthAws); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := Validate(AuthGcp, "elastic", AuthAws); err == nil {
+		t.Errorf("unexpected result - expected an error for an unsupported auth")
+	}
+}
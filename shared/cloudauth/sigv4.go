@@ -0,0 +1,202 @@
+package cloudauth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	signingAlgorithm = "AWS4-HMAC-SHA256"
+	amzDateFormat    = "20060102T150405Z"
+	amzDayFormat     = "20060102"
+)
+
+// SignV4 adds the signature version 4 authorization of the request to the service of the region, signing the host,
+// the content type and the amz headers, the session token of temporary credentials is signed with them
+func SignV4(request *http.Request, body []byte, credentials *AwsCredentials, region string, service string, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.UTC().Format(amzDateFormat)
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if credentials.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+
+	signedHeaders := map[string]string{"host": request.URL.Host}
+	for name, values := range request.Header {
+		lowerName := strings.ToLower(name)
+		if strings.HasPrefix(lowerName, "x-amz-") || lowerName == "content-type" || lowerName == "range" {
+			signedHeaders[lowerName] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+
+	names := make([]string, 0, len(signedHeaders))
+	for name := range signedHeaders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(fmt.Sprintf("%s:%s\n", name, signedHeaders[name]))
+	}
+	signedHeaderNames := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalPath(request.URL),
+		request.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaderNames,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+
+	scope, signature := sign(canonicalRequest, credentials, region, service, now)
+	request.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s", signingAlgorithm, credentials.AccessKeyId, scope, signedHeaderNames, signature))
+}
+
+// PresignV4 adds the signature version 4 of the request to its query, signing only the host, like the urls that are
+// handed over to be called by another party
+func PresignV4(request *http.Request, credentials *AwsCredentials, region string, service string, expires time.Duration, now time.Time) {
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", now.UTC().Format(amzDayFormat), region, service)
+
+	query := request.URL.Query()
+	query.Set("X-Amz-Algorithm", signingAlgorithm)
+	query.Set("X-Amz-Credential", fmt.Sprintf("%s/%s", credentials.AccessKeyId, scope))
+	query.Set("X-Amz-Date", now.UTC().Format(amzDateFormat))
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", int64(expires.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+	if credentials.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", credentials.SessionToken)
+	}
+	canonicalQuery := EncodeQuery(query)
+
+	emptyPayloadHash := sha256.Sum256(nil)
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		canonicalPath(request.URL),
+		canonicalQuery,
+		fmt.Sprintf("host:%s\n", request.URL.Host),
+		"host",
+		hex.EncodeToString(emptyPayloadHash[:]),
+	}, "\n")
+
+	_, signature := sign(canonicalRequest, credentials, region, service, now)
+	request.URL.RawQuery = fmt.Sprintf("%s&X-Amz-Signature=%s", canonicalQuery, signature)
+}
+
+func canonicalPath(requestUrl *url.URL) string {
+	if escapedPath := requestUrl.EscapedPath(); escapedPath != "" {
+		return escapedPath
+	}
+
+	return "/"
+}
+
+func sign(canonicalRequest string, credentials *AwsCredentials, region string, service string, now time.Time) (string, string) {
+	day := now.UTC().Format(amzDayFormat)
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", day, region, service)
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{signingAlgorithm, now.UTC().Format(amzDateFormat), scope, hex.EncodeToString(canonicalRequestHash[:])}, "\n")
+
+	signingKey := hmacSha256([]byte("AWS4"+credentials.SecretAccessKey), day)
+	signingKey = hmacSha256(signingKey, region)
+	signingKey = hmacSha256(signingKey, service)
+	signingKey = hmacSha256(signingKey, "aws4_request")
+
+	return scope, hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// SigningTransport signs the requests of an http client by signature version 4, like the requests to an Amazon
+// OpenSearch domain, with the credentials of the provider
+type SigningTransport struct {
+	base        http.RoundTripper
+	credentials *AwsCredentialsProvider
+	region      string
+	service     string
+}
+
+func NewSigningTransport(base http.RoundTripper, credentials *AwsCredentialsProvider, region string, service string) *SigningTransport {
+	return &SigningTransport{base: base, credentials: credentials, region: region, service: service}
+}
+
+func (transport *SigningTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	credentials, err := transport.credentials.Credentials(request.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	// the request of the caller isn't modified, the body is read to be hashed and set again on the signed copy
+	var body []byte
+	if request.Body != nil {
+		body, err = ioutil.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	signedRequest := request.Clone(request.Context())
+	signedRequest.Body = ioutil.NopCloser(bytes.NewReader(body))
+	signedRequest.ContentLength = int64(len(body))
+	SignV4(signedRequest, body, credentials, transport.region, transport.service, time.Now())
+
+	return transport.base.RoundTrip(signedRequest)
+}
+
+// EncodePath escapes every byte of the path but the unreserved characters and the slashes, like signature version 4
+// expects
+func EncodePath(objectPath string) string {
+	segments := strings.Split(objectPath, "/")
+	for i, segment := range segments {
+		segments[i] = encodeUri(segment)
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// EncodeQuery sorts the query by its names and escapes them like EncodePath
+func EncodeQuery(query url.Values) string {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		for _, value := range query[name] {
+			pairs = append(pairs, fmt.Sprintf("%s=%s", encodeUri(name), encodeUri(value)))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
+
+func encodeUri(value string) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') || b == '-' || b == '_' || b == '.' || b == '~' {
+			encoded.WriteByte(b)
+		} else {
+			encoded.WriteString(fmt.Sprintf("%%%02X", b))
+		}
+	}
+
+	return encoded.String()
+}
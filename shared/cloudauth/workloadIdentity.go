@@ -0,0 +1,119 @@
+package cloudauth
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	gcpMetadataUrl          = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default"
+	gcpMetadataFlavorHeader = "Metadata-Flavor"
+	gcpMetadataFlavor       = "Google"
+)
+
+// the env vars the workload identity webhook of AKS sets in the pods that are labeled to use a workload identity
+const (
+	azureClientIdEnvVar           = "AZURE_CLIENT_ID"
+	azureTenantIdEnvVar           = "AZURE_TENANT_ID"
+	azureFederatedTokenFileEnvVar = "AZURE_FEDERATED_TOKEN_FILE"
+	azureAuthorityHostEnvVar      = "AZURE_AUTHORITY_HOST"
+
+	defaultAzureAuthorityHost = "https://login.microsoftonline.com/"
+)
+
+// NewGcpTokenProvider returns the tokens of the google service account that GKE workload identity binds to the
+// service account of the pod, from the metadata server of the node
+func NewGcpTokenProvider() *TokenProvider {
+	httpClient := &http.Client{Timeout: requestTimeout}
+	provider := &TokenProvider{identity: "the gcp service account of the pod", now: time.Now}
+
+	// the token provider is locked while fetching, so the identity is safe to set
+	provider.fetch = func(ctx context.Context) (*oauthToken, error) {
+		response, err := getGcpMetadata(ctx, httpClient, "token")
+		if err != nil {
+			return nil, err
+		}
+
+		token, err := readTokenResponse(response)
+		if err != nil {
+			return nil, err
+		}
+
+		if response, err := getGcpMetadata(ctx, httpClient, "email"); err == nil {
+			email, _ := ioutil.ReadAll(response.Body)
+			response.Body.Close()
+			if response.StatusCode == http.StatusOK && len(email) > 0 {
+				provider.identity = fmt.Sprintf("gcp service account %s", email)
+			}
+		}
+
+		return token, nil
+	}
+
+	return provider
+}
+
+func getGcpMetadata(ctx context.Context, httpClient *http.Client, name string) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s", gcpMetadataUrl, name), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set(gcpMetadataFlavorHeader, gcpMetadataFlavor)
+
+	return httpClient.Do(request)
+}
+
+// NewAzureTokenProvider returns the tokens of the managed identity that AKS workload identity federates with the
+// service account of the pod, for the scope, like https://<namespace>.servicebus.windows.net/.default. The token of
+// the service account is exchanged for them as a client assertion
+func NewAzureTokenProvider(scope string) (*TokenProvider, error) {
+	clientId := os.Getenv(azureClientIdEnvVar)
+	tenantId := os.Getenv(azureTenantIdEnvVar)
+	tokenFile := os.Getenv(azureFederatedTokenFileEnvVar)
+	if clientId == "" || tenantId == "" || tokenFile == "" {
+		return nil, fmt.Errorf("%s, %s and %s aren't set, the pod doesn't use an azure workload identity", azureClientIdEnvVar, azureTenantIdEnvVar, azureFederatedTokenFileEnvVar)
+	}
+
+	authorityHost := os.Getenv(azureAuthorityHostEnvVar)
+	if authorityHost == "" {
+		authorityHost = defaultAzureAuthorityHost
+	}
+	tokenUrl := fmt.Sprintf("%s/%s/oauth2/v2.0/token", strings.TrimSuffix(authorityHost, "/"), tenantId)
+
+	httpClient := &http.Client{Timeout: requestTimeout}
+	fetch := func(ctx context.Context) (*oauthToken, error) {
+		// the kubelet rotates the token, so it's read on every exchange
+		assertion, err := ioutil.ReadFile(tokenFile)
+		if err != nil {
+			return nil, err
+		}
+
+		form := url.Values{}
+		form.Set("grant_type", "client_credentials")
+		form.Set("client_id", clientId)
+		form.Set("scope", scope)
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenUrl, strings.NewReader(form.Encode()))
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		response, err := httpClient.Do(request)
+		if err != nil {
+			return nil, err
+		}
+
+		return readTokenResponse(response)
+	}
+
+	return &TokenProvider{identity: fmt.Sprintf("azure client id %s", clientId), fetch: fetch, now: time.Now}, nil
+}
@@ -0,0 +1,47 @@
+package shared
+
+type ComparisonHistogramBucket struct {
+	// 0 is the bucket of the latencies above the last bound
+	UpperBoundMs int64 `json:"le"`
+	Count        int   `json:"count"`
+}
+
+type ComparisonLatency struct {
+	P50       int64                        `json:"p50"`
+	P90       int64                        `json:"p90"`
+	P95       int64                        `json:"p95"`
+	P99       int64                        `json:"p99"`
+	Max       int64                        `json:"max"`
+	Histogram []*ComparisonHistogramBucket `json:"histogram"`
+}
+
+// ComparisonSide sums up the entries of one of the compared workloads, errors are the 5xx responses
+type ComparisonSide struct {
+	Entries   int               `json:"entries"`
+	Errors    int               `json:"errors"`
+	ErrorRate float64           `json:"errorRate"`
+	Latency   ComparisonLatency `json:"latency"`
+}
+
+// ComparisonSchemaDifference is a response field that only one of the workloads returned, or that they returned
+// with other types, the types are empty on the side the field is missing from
+type ComparisonSchemaDifference struct {
+	Field       string `json:"field"`
+	StableTypes string `json:"stableTypes"`
+	CanaryTypes string `json:"canaryTypes"`
+}
+
+type EndpointComparison struct {
+	Endpoint          string                        `json:"endpoint"`
+	Stable            ComparisonSide                `json:"stable"`
+	Canary            ComparisonSide                `json:"canary"`
+	SchemaDifferences []*ComparisonSchemaDifference `json:"schemaDifferences"`
+}
+
+// ComparisonResponse contrasts a stable and a canary workload over the same time range, endpoints are sorted by
+// their method and path
+type ComparisonResponse struct {
+	Stable    ComparisonSide        `json:"stable"`
+	Canary    ComparisonSide        `json:"canary"`
+	Endpoints []*EndpointComparison `json:"endpoints"`
+}
@@ -6,6 +6,7 @@ const (
 	HostModeEnvVar                   = "HOST_MODE"
 	NodeNameEnvVar                   = "NODE_NAME"
 	TappedAddressesPerNodeDictEnvVar = "TAPPED_ADDRESSES_PER_HOST"
+	RelayAddressesPerNodeEnvVar      = "RELAY_ADDRESSES_PER_NODE"
 	ConfigDirPath                    = "/app/config/"
 	DataDirPath                      = "/app/data/"
 	ValidationRulesFileName          = "validation-rules.yaml"
@@ -14,7 +15,31 @@ const (
 	GoGCEnvVar                       = "GOGC"
 	DefaultApiServerPort             = 8899
 	LogLevelEnvVar                   = "LOG_LEVEL"
+	SocketKeepAliveEnvVar            = "SOCKET_KEEPALIVE"
 	MizuAgentImageRepo               = "docker.io/up9inc/mizu"
 	BasenineHost                     = "127.0.0.1"
 	BaseninePort                     = "9099"
+	TapperNodeNameQueryParam         = "nodeName"
+	TapperTokenAudience              = "mizu-agent"
+	TapperTokenDirPath               = "/var/run/secrets/mizu/"
+	TapperTokenFileName              = "token"
+	ProvenanceKeyDirPath             = "/app/provenance/"
+	CapturePolicyNamespace           = "kube-system"
+	CapturePolicyConfigMapName       = "mizu-capture-policy"
+	CapturePolicyFileName            = "capture-policy.yaml"
+	ProvenanceKeyFileName            = "ed25519.key"
+	ProvenanceSegmentsFileName       = "provenance-segments.jsonl"
+	SavedQueriesFileName             = "saved-queries.json"
+	AlertRulesFileName               = "alert-rules.json"
+	EndpointTrendsFileName           = "endpoint-trends.json"
+)
+
+const (
+	DefaultSocketPingIntervalSec = 20
+	DefaultSocketIdleTimeoutSec  = 60
+)
+
+const (
+	EntryIdSchemeUlid  = "ulid"
+	EntryIdSchemeIndex = "index"
 )
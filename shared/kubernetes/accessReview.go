@@ -0,0 +1,109 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+
+	auth "k8s.io/api/authorization/v1"
+	rbac "k8s.io/api/rbac/v1"
+)
+
+// ResourceAccess is the access an access review checks. A resource like pods/log names its subresource after a
+// slash, an empty Name is the access to every object of the resource and an empty Namespace is the access in every
+// namespace, or to a cluster scoped resource when it isn't Namespaced
+type ResourceAccess struct {
+	Namespace  string
+	Namespaced bool
+	Group      string
+	Resource   string
+	Name       string
+	Verb       string
+}
+
+// ResourceScopes tells if the resources the api server serves are namespaced, by group/resource
+type ResourceScopes map[string]bool
+
+func (access ResourceAccess) resourceAttributes() *auth.ResourceAttributes {
+	resource, subresource := splitSubresource(access.Resource)
+	return &auth.ResourceAttributes{
+		Namespace:   access.Namespace,
+		Verb:        access.Verb,
+		Group:       access.Group,
+		Resource:    resource,
+		Subresource: subresource,
+		Name:        access.Name,
+	}
+}
+
+func (access ResourceAccess) String() string {
+	description := fmt.Sprintf("%v %v", access.Verb, access.Resource)
+	if access.Name != "" {
+		description += fmt.Sprintf(" named %v", access.Name)
+	}
+	description += fmt.Sprintf(" in group '%v'", access.Group)
+
+	if !access.Namespaced {
+		return description + " at the cluster scope"
+	} else if access.Namespace == K8sAllNamespaces {
+		return description + " in all namespaces"
+	}
+
+	return description + fmt.Sprintf(" in namespace %v", access.Namespace)
+}
+
+// IsNamespaced tells if the resource is namespaced, the unknown ones, like the resources of a definition that isn't
+// installed yet, are taken as namespaced like most resources are
+func (scopes ResourceScopes) IsNamespaced(group string, resource string) bool {
+	resource, _ = splitSubresource(resource)
+	if namespaced, ok := scopes[group+"/"+resource]; ok {
+		return namespaced
+	}
+
+	return true
+}
+
+// GetRuleAccesses expands a policy rule to the accesses it grants, every verb on every resource of every group, of
+// every one of its resource names. The cluster scoped resources are reviewed at the cluster scope and the namespaced
+// ones in each of the namespaces namespacesOf returns for them
+func GetRuleAccesses(rule rbac.PolicyRule, scopes ResourceScopes, namespacesOf func(resource string, verb string) []string) []ResourceAccess {
+	names := rule.ResourceNames
+	if len(names) == 0 {
+		names = []string{""}
+	}
+
+	accesses := make([]ResourceAccess, 0)
+	for _, group := range rule.APIGroups {
+		for _, resource := range rule.Resources {
+			namespaced := scopes.IsNamespaced(group, resource)
+			for _, verb := range rule.Verbs {
+				namespaces := []string{K8sAllNamespaces}
+				if namespaced {
+					namespaces = namespacesOf(resource, verb)
+				}
+
+				for _, namespace := range namespaces {
+					for _, name := range names {
+						accesses = append(accesses, ResourceAccess{
+							Namespace:  namespace,
+							Namespaced: namespaced,
+							Group:      group,
+							Resource:   resource,
+							Name:       name,
+							Verb:       verb,
+						})
+					}
+				}
+			}
+		}
+	}
+
+	return accesses
+}
+
+func splitSubresource(resource string) (string, string) {
+	if split := strings.SplitN(resource, "/", 2); len(split) == 2 {
+		return split[0], split[1]
+	}
+
+	return resource, ""
+}
@@ -0,0 +1,74 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	rbac "k8s.io/api/rbac/v1"
+)
+
+func TestGetRuleAccesses(t *testing.T) {
+	scopes := ResourceScopes{"/pods": true, "/namespaces": false, "/secrets": true}
+	namespacesOf := func(resource string, verb string) []string {
+		if resource == "pods" && verb == "list" {
+			return []string{"shop", "payments"}
+		}
+		return []string{"mizu"}
+	}
+
+	tests := []struct {
+		name     string
+		rule     rbac.PolicyRule
+		expected []ResourceAccess
+	}{
+		{
+			"cluster scoped",
+			rbac.PolicyRule{APIGroups: []string{""}, Resources: []string{"namespaces"}, Verbs: []string{"list"}},
+			[]ResourceAccess{{Namespace: "", Namespaced: false, Group: "", Resource: "namespaces", Verb: "list"}},
+		},
+		{
+			"target namespaces",
+			rbac.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"list", "create"}},
+			[]ResourceAccess{
+				{Namespace: "shop", Namespaced: true, Group: "", Resource: "pods", Verb: "list"},
+				{Namespace: "payments", Namespaced: true, Group: "", Resource: "pods", Verb: "list"},
+				{Namespace: "mizu", Namespaced: true, Group: "", Resource: "pods", Verb: "create"},
+			},
+		},
+		{
+			"subresource and resource names",
+			rbac.PolicyRule{APIGroups: []string{""}, Resources: []string{"pods/log", "secrets"}, ResourceNames: []string{"mizu-key"}, Verbs: []string{"get"}},
+			[]ResourceAccess{
+				{Namespace: "mizu", Namespaced: true, Group: "", Resource: "pods/log", Name: "mizu-key", Verb: "get"},
+				{Namespace: "mizu", Namespaced: true, Group: "", Resource: "secrets", Name: "mizu-key", Verb: "get"},
+			},
+		},
+		{
+			"unknown resource",
+			rbac.PolicyRule{APIGroups: []string{"mizu.io"}, Resources: []string{"mizutaps"}, Verbs: []string{"create"}},
+			[]ResourceAccess{{Namespace: "mizu", Namespaced: true, Group: "mizu.io", Resource: "mizutaps", Verb: "create"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := GetRuleAccesses(test.rule, scopes, namespacesOf); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestResourceAccessAttributes(t *testing.T) {
+	access := ResourceAccess{Namespace: "mizu", Namespaced: true, Group: "", Resource: "services/proxy", Name: "mizu-api-server", Verb: "get"}
+
+	attributes := access.resourceAttributes()
+	if attributes.Resource != "services" || attributes.Subresource != "proxy" || attributes.Name != "mizu-api-server" || attributes.Namespace != "mizu" {
+		t.Errorf("unexpected result - expected: %v, actual: %+v", "the proxy subresource of service mizu-api-server in namespace mizu", attributes)
+	}
+
+	expected := "get services/proxy named mizu-api-server in group '' in namespace mizu"
+	if actual := access.String(); actual != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/up9inc/mizu/shared"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// the annotations that bind a service account to a cloud identity on EKS, GKE and AKS, and the label of the pods that
+// AKS injects the workload identity into
+const (
+	AwsRoleArnAnnotation        = "eks.amazonaws.com/role-arn"
+	GcpServiceAccountAnnotation = "iam.gke.io/gcp-service-account"
+	AzureClientIdAnnotation     = "azure.workload.identity/client-id"
+	AzureUseWorkloadIdentity    = "azure.workload.identity/use"
+)
+
+// GetCloudIdentityAnnotations returns the annotations of the service account of the api server that bind it to the
+// cloud identity of the config
+func GetCloudIdentityAnnotations(cloudIdentity shared.CloudIdentityConfig) map[string]string {
+	annotations := make(map[string]string)
+	if cloudIdentity.AwsRoleArn != "" {
+		annotations[AwsRoleArnAnnotation] = cloudIdentity.AwsRoleArn
+	}
+	if cloudIdentity.GcpServiceAccount != "" {
+		annotations[GcpServiceAccountAnnotation] = cloudIdentity.GcpServiceAccount
+	}
+	if cloudIdentity.AzureClientId != "" {
+		annotations[AzureClientIdAnnotation] = cloudIdentity.AzureClientId
+	}
+
+	return annotations
+}
+
+// AnnotateServiceAccount merges the annotations into the ones of the service account, the identity webhooks read them
+// when a pod of the service account is created, so the pods have to be created after it
+func (provider *Provider) AnnotateServiceAccount(ctx context.Context, namespace string, name string, annotations map[string]string) error {
+	patch, err := json.Marshal(map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": annotations},
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = provider.clientSet.CoreV1().ServiceAccounts(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+func (provider *Provider) GetServiceAccountAnnotations(ctx context.Context, namespace string, name string) (map[string]string, error) {
+	serviceAccount, err := provider.clientSet.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return serviceAccount.Annotations, nil
+}
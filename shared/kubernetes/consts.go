@@ -9,9 +9,15 @@ const (
 	RoleBindingName            = MizuResourcesPrefix + "role-binding"
 	RoleName                   = MizuResourcesPrefix + "role"
 	ServiceAccountName         = MizuResourcesPrefix + "service-account"
+	OperatorRoleBindingName    = MizuResourcesPrefix + "operator-role-binding"
+	OperatorRoleName           = MizuResourcesPrefix + "operator-role"
 	TapperDaemonSetName        = MizuResourcesPrefix + "tapper-daemon-set"
 	TapperPodName              = MizuResourcesPrefix + "tapper"
 	ConfigMapName              = MizuResourcesPrefix + "config"
+	MizuTapName                = MizuResourcesPrefix + "tap"
+	ProvenanceSecretName       = MizuResourcesPrefix + "provenance"
+	RelayName                  = MizuResourcesPrefix + "relay"
+	EntriesClaimName           = MizuResourcesPrefix + "entries"
 	MinKubernetesServerVersion = "1.16.0"
 )
 
@@ -19,7 +25,16 @@ const (
 	LabelPrefixApp      = "app.kubernetes.io/"
 	LabelManagedBy      = LabelPrefixApp + "managed-by"
 	LabelCreatedBy      = LabelPrefixApp + "created-by"
+	LabelSession        = LabelPrefixApp + "instance"
 	LabelValueMizu      = "mizu"
 	LabelValueMizuCLI   = "mizu-cli"
 	LabelValueMizuAgent = "mizu-agent"
+	// LabelRelay is the relay name prefix of the session on the relays, the name of a relay is suffixed by its pool
+	LabelRelay = "mizu.io/relay"
+)
+
+const (
+	AnnotationTap       = "mizu.io/tap"
+	AnnotationTapOptIn  = "true"
+	AnnotationTapOptOut = "false"
 )
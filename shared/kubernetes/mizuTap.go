@@ -0,0 +1,203 @@
+package kubernetes
+
+import (
+	"encoding/json"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/tap/api"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	MizuTapGroup    = "mizu.io"
+	MizuTapVersion  = "v1alpha1"
+	MizuTapKind     = "MizuTap"
+	MizuTapPlural   = "mizutaps"
+	MizuTapCrdName  = MizuTapPlural + "." + MizuTapGroup
+	mizuTapSingular = "mizutap"
+)
+
+const (
+	MizuTapPhaseTapping = "Tapping"
+	MizuTapPhaseFailed  = "Failed"
+	MizuTapPhaseStopped = "Stopped"
+)
+
+var MizuTapResource = schema.GroupVersionResource{Group: MizuTapGroup, Version: MizuTapVersion, Resource: MizuTapPlural}
+
+var customResourceDefinitionsResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// MizuTap declares a tap that the api server of its namespace reconciles, the tappers follow the matching pods for as
+// long as it exists, independently of the cli that created it
+type MizuTap struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   MizuTapSpec   `json:"spec"`
+	Status MizuTapStatus `json:"status,omitempty"`
+}
+
+// MizuTapSpec selects the pods to tap like mizu tap does, no TargetNamespaces taps all namespaces, PodLabelSelector
+// narrows the pods matching the regex down by their labels, Targets replace the namespaces, the regex and the selector,
+// no Protocols records all protocols and MaxEntriesDBSize, like "200MB", is the retention of the entries database,
+// Stopped keeps the tap without tapping until it's cleared
+type MizuTapSpec struct {
+	TargetNamespaces        []string                      `json:"targetNamespaces,omitempty"`
+	PodRegex                string                        `json:"podRegex,omitempty"`
+	PodLabelSelector        string                        `json:"podLabelSelector,omitempty"`
+	TapAnnotations          bool                          `json:"tapAnnotations,omitempty"`
+	Targets                 []TapTarget                   `json:"targets,omitempty"`
+	Protocols               []string                      `json:"protocols,omitempty"`
+	MaxEntriesDBSize        string                        `json:"maxEntriesDBSize,omitempty"`
+	ServiceMesh             bool                          `json:"serviceMesh,omitempty"`
+	Tls                     bool                          `json:"tls,omitempty"`
+	TapperScheduling        shared.TapperSchedulingConfig `json:"tapperScheduling"`
+	Relay                   shared.RelayConfig            `json:"relay"`
+	TrafficFilteringOptions api.TrafficFilteringOptions   `json:"trafficFilteringOptions"`
+	Stopped                 bool                          `json:"stopped,omitempty"`
+}
+
+// MizuTapStatus is the outcome of reconciling the generation ObservedGeneration of the spec
+type MizuTapStatus struct {
+	Phase              string `json:"phase,omitempty"`
+	Message            string `json:"message,omitempty"`
+	TappedPods         int    `json:"tappedPods"`
+	ObservedGeneration int64  `json:"observedGeneration,omitempty"`
+}
+
+func NewMizuTap(namespace string, name string, spec MizuTapSpec) *MizuTap {
+	return &MizuTap{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       MizuTapKind,
+			APIVersion: MizuTapGroup + "/" + MizuTapVersion,
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: namespace,
+			Labels: map[string]string{
+				LabelManagedBy: LabelValueMizu,
+			},
+		},
+		Spec: spec,
+	}
+}
+
+func MizuTapFromUnstructured(object *unstructured.Unstructured) (*MizuTap, error) {
+	data, err := json.Marshal(object.Object)
+	if err != nil {
+		return nil, err
+	}
+
+	var mizuTap MizuTap
+	if err := json.Unmarshal(data, &mizuTap); err != nil {
+		return nil, err
+	}
+
+	return &mizuTap, nil
+}
+
+func (mizuTap *MizuTap) ToUnstructured() (*unstructured.Unstructured, error) {
+	data, err := json.Marshal(mizuTap)
+	if err != nil {
+		return nil, err
+	}
+
+	object := &unstructured.Unstructured{}
+	if err := object.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+// GetMizuTapCrdObject returns the custom resource definition of the MizuTap, the nested options of the spec are kept
+// as they are so the definition doesn't have to follow the options of the cli
+func GetMizuTapCrdObject() *unstructured.Unstructured {
+	stringSchema := map[string]interface{}{"type": "string"}
+	stringArraySchema := map[string]interface{}{"type": "array", "items": stringSchema}
+	booleanSchema := map[string]interface{}{"type": "boolean"}
+	integerSchema := map[string]interface{}{"type": "integer"}
+	preservedObjectSchema := map[string]interface{}{"type": "object", "x-kubernetes-preserve-unknown-fields": true}
+	targetsSchema := map[string]interface{}{"type": "array", "items": map[string]interface{}{
+		"type":     "object",
+		"required": []interface{}{"name"},
+		"properties": map[string]interface{}{
+			"namespace": stringSchema,
+			"kind":      stringSchema,
+			"name":      stringSchema,
+		},
+	}}
+
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "apiextensions.k8s.io/v1",
+		"kind":       "CustomResourceDefinition",
+		"metadata": map[string]interface{}{
+			"name": MizuTapCrdName,
+			"labels": map[string]interface{}{
+				LabelManagedBy: LabelValueMizu,
+			},
+		},
+		"spec": map[string]interface{}{
+			"group": MizuTapGroup,
+			"scope": "Namespaced",
+			"names": map[string]interface{}{
+				"kind":     MizuTapKind,
+				"plural":   MizuTapPlural,
+				"singular": mizuTapSingular,
+			},
+			"versions": []interface{}{
+				map[string]interface{}{
+					"name":    MizuTapVersion,
+					"served":  true,
+					"storage": true,
+					"schema": map[string]interface{}{
+						"openAPIV3Schema": map[string]interface{}{
+							"type": "object",
+							"properties": map[string]interface{}{
+								"spec": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"targetNamespaces":        stringArraySchema,
+										"podRegex":                stringSchema,
+										"podLabelSelector":        stringSchema,
+										"tapAnnotations":          booleanSchema,
+										"targets":                 targetsSchema,
+										"protocols":               stringArraySchema,
+										"maxEntriesDBSize":        stringSchema,
+										"serviceMesh":             booleanSchema,
+										"tls":                     booleanSchema,
+										"tapperScheduling":        preservedObjectSchema,
+										"relay":                   preservedObjectSchema,
+										"trafficFilteringOptions": preservedObjectSchema,
+										"stopped":                 booleanSchema,
+									},
+								},
+								"status": map[string]interface{}{
+									"type": "object",
+									"properties": map[string]interface{}{
+										"phase":              stringSchema,
+										"message":            stringSchema,
+										"tappedPods":         integerSchema,
+										"observedGeneration": integerSchema,
+									},
+								},
+							},
+						},
+					},
+					"subresources": map[string]interface{}{
+						"status": map[string]interface{}{},
+					},
+					"additionalPrinterColumns": []interface{}{
+						map[string]interface{}{"name": "Regex", "type": "string", "jsonPath": ".spec.podRegex"},
+						map[string]interface{}{"name": "Selector", "type": "string", "jsonPath": ".spec.podLabelSelector"},
+						map[string]interface{}{"name": "Phase", "type": "string", "jsonPath": ".status.phase"},
+						map[string]interface{}{"name": "Tapped Pods", "type": "integer", "jsonPath": ".status.tappedPods"},
+						map[string]interface{}{"name": "Age", "type": "date", "jsonPath": ".metadata.creationTimestamp"},
+					},
+				},
+			},
+		},
+	}}
+}
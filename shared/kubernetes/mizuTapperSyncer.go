@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sync"
 	"time"
 
 	"github.com/op/go-logging"
@@ -12,13 +13,21 @@ import (
 	"github.com/up9inc/mizu/shared/logger"
 	"github.com/up9inc/mizu/tap/api"
 	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
-const updateTappersDelay = 5 * time.Second
+// updateTappersDelay is short so the pods of jobs, which may live only seconds, are tapped before they end, the tappers
+// are restarted only when the tapped nodes change
+const updateTappersDelay = time.Second
+
+// updateStartingPodsDelay is shorter, the init containers of a pod run as soon as it's given an address and the tapper
+// holds their traffic only until it's sent the address
+const updateStartingPodsDelay = 100 * time.Millisecond
 
 type TappedPodChangeEvent struct {
 	Added   []core.Pod
 	Removed []core.Pod
+	Updated []core.Pod
 }
 
 // MizuTapperSyncer uses a k8s pod watch to update tapper daemonsets when targeted pods are removed or created
@@ -37,7 +46,11 @@ type MizuTapperSyncer struct {
 type TapperSyncerConfig struct {
 	TargetNamespaces         []string
 	PodFilterRegex           regexp.Regexp
+	PodFilterLabelSelector   labels.Selector
+	TapAnnotations           bool
+	Targets                  []TapTarget
 	MizuResourcesNamespace   string
+	ResourceNames            ResourceNames
 	AgentImage               string
 	TapperResources          shared.Resources
 	ImagePullPolicy          core.PullPolicy
@@ -47,9 +60,17 @@ type TapperSyncerConfig struct {
 	MizuServiceAccountExists bool
 	ServiceMesh              bool
 	Tls                      bool
+	TapperAuthentication     bool
+	TapperScheduling         shared.TapperSchedulingConfig
+	SocketKeepAlive          shared.SocketKeepAliveConfig
+	Relay                    shared.RelayConfig
 }
 
 func CreateAndStartMizuTapperSyncer(ctx context.Context, kubernetesProvider *Provider, config TapperSyncerConfig, startTime time.Time) (*MizuTapperSyncer, error) {
+	if config.PodFilterLabelSelector == nil {
+		config.PodFilterLabelSelector = labels.Everything()
+	}
+
 	syncer := &MizuTapperSyncer{
 		startTime:              startTime.Truncate(time.Second), // Round down because k8s CreationTimestamp is given in 1 sec resolution.
 		context:                ctx,
@@ -76,7 +97,7 @@ func CreateAndStartMizuTapperSyncer(ctx context.Context, kubernetesProvider *Pro
 }
 
 func (tapperSyncer *MizuTapperSyncer) watchTapperPods() {
-	mizuResourceRegex := regexp.MustCompile(fmt.Sprintf("^%s.*", TapperPodName))
+	mizuResourceRegex := regexp.MustCompile(fmt.Sprintf("^%s.*", tapperSyncer.config.ResourceNames.TapperPodName))
 	podWatchHelper := NewPodWatchHelper(tapperSyncer.kubernetesProvider, mizuResourceRegex)
 	eventChan, errorChan := FilteredWatch(tapperSyncer.context, podWatchHelper, []string{tapperSyncer.config.MizuResourcesNamespace}, podWatchHelper)
 
@@ -119,7 +140,7 @@ func (tapperSyncer *MizuTapperSyncer) watchTapperPods() {
 }
 
 func (tapperSyncer *MizuTapperSyncer) watchTapperEvents() {
-	mizuResourceRegex := regexp.MustCompile(fmt.Sprintf("^%s.*", TapperPodName))
+	mizuResourceRegex := regexp.MustCompile(fmt.Sprintf("^%s.*", tapperSyncer.config.ResourceNames.TapperPodName))
 	eventWatchHelper := NewEventWatchHelper(tapperSyncer.kubernetesProvider, mizuResourceRegex, "pod")
 	eventChan, errorChan := FilteredWatch(tapperSyncer.context, eventWatchHelper, []string{tapperSyncer.config.MizuResourcesNamespace}, eventWatchHelper)
 
@@ -183,9 +204,28 @@ func (tapperSyncer *MizuTapperSyncer) watchTapperEvents() {
 
 func (tapperSyncer *MizuTapperSyncer) watchPodsForTapping() {
 	podWatchHelper := NewPodWatchHelper(tapperSyncer.kubernetesProvider, &tapperSyncer.config.PodFilterRegex)
-	eventChan, errorChan := FilteredWatch(tapperSyncer.context, podWatchHelper, tapperSyncer.config.TargetNamespaces, podWatchHelper)
+	podWatchHelper.LabelSelector = tapperSyncer.config.PodFilterLabelSelector
+	watchedNamespaces := tapperSyncer.config.TargetNamespaces
+
+	var namespaceEventChan <-chan *WatchEvent
+	var namespaceErrorChan <-chan error
+	if tapperSyncer.config.TapAnnotations {
+		// Namespaces can opt in at any time, so the pods of all of them are watched
+		podWatchHelper.PassTapAnnotated = true
+		watchedNamespaces = []string{K8sAllNamespaces}
+
+		namespaceWatchHelper := NewNamespaceWatchHelper(tapperSyncer.kubernetesProvider)
+		namespaceEventChan, namespaceErrorChan = FilteredWatch(tapperSyncer.context, namespaceWatchHelper, []string{K8sAllNamespaces}, namespaceWatchHelper)
+	}
+
+	eventChan, errorChan := FilteredWatch(tapperSyncer.context, podWatchHelper, watchedNamespaces, podWatchHelper)
 
+	var restartTappersLock sync.Mutex
 	restartTappers := func() {
+		restartTappersLock.Lock()
+		defer restartTappersLock.Unlock()
+
+		previousNodeToTappedPodMap := tapperSyncer.nodeToTappedPodMap
 		err, changeFound := tapperSyncer.updateCurrentlyTappedPods()
 		if err != nil {
 			tapperSyncer.ErrorOut <- K8sTapManagerError{
@@ -198,6 +238,11 @@ func (tapperSyncer *MizuTapperSyncer) watchPodsForTapping() {
 			logger.Log.Debugf("Nothing changed update tappers not needed")
 			return
 		}
+		if !isTappedNodesChanged(previousNodeToTappedPodMap, tapperSyncer.nodeToTappedPodMap) {
+			// the running tappers are sent their new targets through the API server, restarting them would lose traffic
+			logger.Log.Debugf("The tapped nodes didn't change update tappers not needed")
+			return
+		}
 		if err := tapperSyncer.updateMizuTappers(); err != nil {
 			tapperSyncer.ErrorOut <- K8sTapManagerError{
 				OriginalError:    err,
@@ -206,6 +251,7 @@ func (tapperSyncer *MizuTapperSyncer) watchPodsForTapping() {
 		}
 	}
 	restartTappersDebouncer := debounce.NewDebouncer(updateTappersDelay, restartTappers)
+	startingPodsDebouncer := debounce.NewDebouncer(updateStartingPodsDelay, restartTappers)
 
 	for {
 		select {
@@ -233,14 +279,21 @@ func (tapperSyncer *MizuTapperSyncer) watchPodsForTapping() {
 					logger.Log.Error(err)
 				}
 			case EventModified:
-				logger.Log.Debugf("Modified matching pod %s, ns: %s, phase: %s, ip: %s", pod.Name, pod.Namespace, pod.Status.Phase, pod.Status.PodIP)
-				// Act only if the modified pod has already obtained an IP address.
-				// After filtering for IPs, on a normal pod restart this includes the following events:
+				logger.Log.Debugf("Modified matching pod %s, ns: %s, phase: %s, node: %s, ip: %s", pod.Name, pod.Namespace, pod.Status.Phase, pod.Spec.NodeName, pod.Status.PodIP)
+				// Act only if the modified pod has already been scheduled on a node.
+				// After filtering for nodes, on a normal pod restart this includes the following events:
+				// - Pod scheduled, its tapper is started before the pod runs
+				// - Pod obtains an IP address
 				// - Pod deletion
 				// - Pod reaches start state
 				// - Pod reaches ready state
 				// Ready/unready transitions might also trigger this event.
-				if pod.Status.PodIP != "" {
+				if pod.Spec.NodeName != "" && pod.Status.PodIP != "" && pod.Status.Phase == core.PodPending {
+					// the pod is starting, its init containers may already be running
+					if err := startingPodsDebouncer.SetOn(); err != nil {
+						logger.Log.Error(err)
+					}
+				} else if pod.Spec.NodeName != "" {
 					if err := restartTappersDebouncer.SetOn(); err != nil {
 						logger.Log.Error(err)
 					}
@@ -259,9 +312,38 @@ func (tapperSyncer *MizuTapperSyncer) watchPodsForTapping() {
 			tapperSyncer.handleErrorInWatchLoop(err, restartTappersDebouncer)
 			continue
 
+		case wEvent, ok := <-namespaceEventChan:
+			if !ok {
+				namespaceEventChan = nil
+				continue
+			}
+
+			namespace, err := wEvent.ToNamespace()
+			if err != nil {
+				tapperSyncer.handleErrorInWatchLoop(err, restartTappersDebouncer)
+				continue
+			}
+
+			switch wEvent.Type {
+			case EventAdded, EventModified, EventDeleted:
+				logger.Log.Debugf("Namespace %s changed, tap annotation: %q", namespace.Name, GetTapAnnotation(namespace.ObjectMeta))
+				if err := restartTappersDebouncer.SetOn(); err != nil {
+					logger.Log.Error(err)
+				}
+			}
+		case err, ok := <-namespaceErrorChan:
+			if !ok {
+				namespaceErrorChan = nil
+				continue
+			}
+
+			tapperSyncer.handleErrorInWatchLoop(err, restartTappersDebouncer)
+			continue
+
 		case <-tapperSyncer.context.Done():
 			logger.Log.Debugf("Watching pods loop, context done, stopping `restart tappers debouncer`")
 			restartTappersDebouncer.Cancel()
+			startingPodsDebouncer.Cancel()
 			// TODO: Does this also perform cleanup?
 			return
 		}
@@ -278,23 +360,28 @@ func (tapperSyncer *MizuTapperSyncer) handleErrorInWatchLoop(err error, restartT
 }
 
 func (tapperSyncer *MizuTapperSyncer) updateCurrentlyTappedPods() (err error, changesFound bool) {
-	if matchingPods, err := tapperSyncer.kubernetesProvider.ListAllRunningPodsMatchingRegex(tapperSyncer.context, &tapperSyncer.config.PodFilterRegex, tapperSyncer.config.TargetNamespaces); err != nil {
+	if matchingPods, err := tapperSyncer.kubernetesProvider.ListAllTappableTapTargetPods(tapperSyncer.context, &tapperSyncer.config.PodFilterRegex, tapperSyncer.config.PodFilterLabelSelector, tapperSyncer.config.TargetNamespaces, tapperSyncer.config.TapAnnotations, tapperSyncer.config.Targets); err != nil {
 		return err, false
 	} else {
-		podsToTap := excludeMizuPods(matchingPods)
+		podsToTap := ExcludeMizuPods(matchingPods)
 		addedPods, removedPods := getPodArrayDiff(tapperSyncer.CurrentlyTappedPods, podsToTap)
+		updatedPods := getUpdatedPods(tapperSyncer.CurrentlyTappedPods, podsToTap)
 		for _, addedPod := range addedPods {
-			logger.Log.Debugf("tapping new pod %s", addedPod.Name)
+			logger.Log.Debugf("tapping new pod %s, phase: %s", addedPod.Name, addedPod.Status.Phase)
 		}
 		for _, removedPod := range removedPods {
 			logger.Log.Debugf("pod %s is no longer running, tapping for it stopped", removedPod.Name)
 		}
-		if len(addedPods) > 0 || len(removedPods) > 0 {
+		for _, updatedPod := range updatedPods {
+			logger.Log.Debugf("pod %s changed, phase: %s, ip: %s", updatedPod.Name, updatedPod.Status.Phase, updatedPod.Status.PodIP)
+		}
+		if len(addedPods) > 0 || len(removedPods) > 0 || len(updatedPods) > 0 {
 			tapperSyncer.CurrentlyTappedPods = podsToTap
 			tapperSyncer.nodeToTappedPodMap = GetNodeHostToTappedPodsMap(tapperSyncer.CurrentlyTappedPods)
 			tapperSyncer.TapPodChangesOut <- TappedPodChangeEvent{
 				Added:   addedPods,
 				Removed: removedPods,
+				Updated: updatedPods,
 			}
 			return nil, true
 		}
@@ -302,6 +389,26 @@ func (tapperSyncer *MizuTapperSyncer) updateCurrentlyTappedPods() (err error, ch
 	}
 }
 
+// GetNodeToTappedPodMap returns the tapped pods of every node, the pods the tapper of the node captures
+func (tapperSyncer *MizuTapperSyncer) GetNodeToTappedPodMap() map[string][]core.Pod {
+	return tapperSyncer.nodeToTappedPodMap
+}
+
+// isTappedNodesChanged decides if the tapper daemon set has to be updated, the tappers run on the tapped nodes only
+func isTappedNodesChanged(previousNodeToTappedPodMap map[string][]core.Pod, nodeToTappedPodMap map[string][]core.Pod) bool {
+	if len(previousNodeToTappedPodMap) != len(nodeToTappedPodMap) {
+		return true
+	}
+
+	for nodeName := range nodeToTappedPodMap {
+		if _, ok := previousNodeToTappedPodMap[nodeName]; !ok {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (tapperSyncer *MizuTapperSyncer) updateMizuTappers() error {
 	if len(tapperSyncer.nodeToTappedPodMap) > 0 {
 		var serviceAccountName string
@@ -311,21 +418,36 @@ func (tapperSyncer *MizuTapperSyncer) updateMizuTappers() error {
 			serviceAccountName = ""
 		}
 
+		tolerations, err := tapperSyncer.getTapperTolerations()
+		if err != nil {
+			return err
+		}
+
+		relayAddresses, err := tapperSyncer.updateRelays(tolerations)
+		if err != nil {
+			return err
+		}
+
 		if err := tapperSyncer.kubernetesProvider.ApplyMizuTapperDaemonSet(
 			tapperSyncer.context,
 			tapperSyncer.config.MizuResourcesNamespace,
-			TapperDaemonSetName,
+			tapperSyncer.config.ResourceNames.TapperDaemonSetName,
 			tapperSyncer.config.AgentImage,
-			TapperPodName,
-			fmt.Sprintf("%s.%s.svc.cluster.local", ApiServerPodName, tapperSyncer.config.MizuResourcesNamespace),
+			tapperSyncer.config.ResourceNames.TapperPodName,
+			tapperSyncer.getApiServerHost(),
 			tapperSyncer.nodeToTappedPodMap,
+			relayAddresses,
 			serviceAccountName,
 			tapperSyncer.config.TapperResources,
 			tapperSyncer.config.ImagePullPolicy,
 			tapperSyncer.config.MizuApiFilteringOptions,
 			tapperSyncer.config.LogLevel,
 			tapperSyncer.config.ServiceMesh,
-			tapperSyncer.config.Tls); err != nil {
+			tapperSyncer.config.Tls,
+			tapperSyncer.config.TapperAuthentication,
+			tapperSyncer.config.SocketKeepAlive,
+			tolerations,
+			tapperSyncer.config.TapperScheduling.NodeSelector); err != nil {
 			return err
 		}
 
@@ -334,14 +456,94 @@ func (tapperSyncer *MizuTapperSyncer) updateMizuTappers() error {
 		if err := tapperSyncer.kubernetesProvider.ResetMizuTapperDaemonSet(
 			tapperSyncer.context,
 			tapperSyncer.config.MizuResourcesNamespace,
-			TapperDaemonSetName,
+			tapperSyncer.config.ResourceNames.TapperDaemonSetName,
 			tapperSyncer.config.AgentImage,
-			TapperPodName); err != nil {
+			tapperSyncer.config.ResourceNames.TapperPodName); err != nil {
 			return err
 		}
 
 		logger.Log.Debugf("Successfully reset tapper daemon set")
+
+		if _, err := tapperSyncer.updateRelays(nil); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
+
+func (tapperSyncer *MizuTapperSyncer) getApiServerHost() string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", tapperSyncer.config.ResourceNames.ApiServerPodName, tapperSyncer.config.MizuResourcesNamespace)
+}
+
+// updateRelays applies the relays of the pools of the tapped nodes that can't connect to the api server directly, and
+// removes the relays of the pools that aren't tapped anymore, it returns the relay address of every relayed node
+func (tapperSyncer *MizuTapperSyncer) updateRelays(tolerations []core.Toleration) (map[string]string, error) {
+	if !tapperSyncer.config.Relay.Enabled {
+		return nil, nil
+	}
+
+	relays := make(map[string]nodePool)
+	relayAddresses := make(map[string]string)
+	if len(tapperSyncer.nodeToTappedPodMap) > 0 {
+		nodes, err := tapperSyncer.kubernetesProvider.ListNodes(tapperSyncer.context)
+		if err != nil {
+			return nil, err
+		}
+
+		apiServerPod, err := tapperSyncer.kubernetesProvider.GetPod(tapperSyncer.context, tapperSyncer.config.MizuResourcesNamespace, tapperSyncer.config.ResourceNames.ApiServerPodName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the node of the api server, %w", err)
+		}
+
+		relays, relayAddresses = getRelays(nodes, tapperSyncer.nodeToTappedPodMap, apiServerPod.Spec.NodeName, tapperSyncer.config.Relay.PoolLabel, tapperSyncer.config.ResourceNames.RelayName, tapperSyncer.config.MizuResourcesNamespace)
+	}
+
+	relayNames := make([]string, 0, len(relays))
+	for relayName, pool := range relays {
+		if err := tapperSyncer.kubernetesProvider.ApplyMizuRelay(
+			tapperSyncer.context,
+			tapperSyncer.config.MizuResourcesNamespace,
+			relayName,
+			tapperSyncer.config.ResourceNames.RelayName,
+			pool.label,
+			pool.name,
+			tapperSyncer.config.AgentImage,
+			tapperSyncer.config.ImagePullPolicy,
+			fmt.Sprintf("ws://%s/wsTapper", tapperSyncer.getApiServerHost()),
+			tapperSyncer.config.TapperResources,
+			tapperSyncer.config.LogLevel,
+			tapperSyncer.config.SocketKeepAlive,
+			tolerations); err != nil {
+			return nil, err
+		}
+
+		logger.Log.Debugf("Relaying the tappers of node pool %s through %s", pool.name, relayName)
+		relayNames = append(relayNames, relayName)
+	}
+
+	if err := tapperSyncer.kubernetesProvider.RemoveMizuRelays(tapperSyncer.context, tapperSyncer.config.MizuResourcesNamespace, tapperSyncer.config.ResourceNames.RelayName, relayNames...); err != nil {
+		return nil, err
+	}
+
+	return relayAddresses, nil
+}
+
+// getTapperTolerations lists the nodes of the tapped pods only when their taints are tolerated automatically
+func (tapperSyncer *MizuTapperSyncer) getTapperTolerations() ([]core.Toleration, error) {
+	var tappedNodes []core.Node
+	if tapperSyncer.config.TapperScheduling.AutoTolerate {
+		nodes, err := tapperSyncer.kubernetesProvider.ListNodes(tapperSyncer.context)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, node := range nodes {
+			if _, ok := tapperSyncer.nodeToTappedPodMap[node.Name]; ok {
+				tappedNodes = append(tappedNodes, node)
+			}
+		}
+	}
+
+	return GetTapperTolerations(tapperSyncer.config.TapperScheduling, tappedNodes), nil
+}
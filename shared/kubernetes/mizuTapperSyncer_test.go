@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newTappedPod(uid string, nodeName string, phase core.PodPhase, podIP string) core.Pod {
+	return core.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: uid, UID: types.UID(uid)},
+		Spec:       core.PodSpec{NodeName: nodeName},
+		Status:     core.PodStatus{Phase: phase, PodIP: podIP},
+	}
+}
+
+func TestGetUpdatedPods(t *testing.T) {
+	oldPods := []core.Pod{newTappedPod("job", "node-a", core.PodPending, ""), newTappedPod("web", "node-a", core.PodRunning, "10.0.0.2")}
+	newPods := []core.Pod{newTappedPod("job", "node-a", core.PodPending, "10.0.0.3"), newTappedPod("web", "node-a", core.PodRunning, "10.0.0.2"), newTappedPod("db", "node-b", core.PodRunning, "10.0.0.4")}
+
+	updatedPods := getUpdatedPods(oldPods, newPods)
+	if len(updatedPods) != 1 || updatedPods[0].Name != "job" {
+		t.Errorf("unexpected result - expected: [job], actual: %v", updatedPods)
+	}
+}
+
+func TestIsPodTappable(t *testing.T) {
+	tests := []struct {
+		pod      core.Pod
+		expected bool
+	}{
+		{newTappedPod("running", "node-a", core.PodRunning, "10.0.0.2"), true},
+		{newTappedPod("scheduled", "node-a", core.PodPending, ""), true},
+		{newTappedPod("unscheduled", "", core.PodPending, ""), false},
+		{newTappedPod("succeeded", "node-a", core.PodSucceeded, "10.0.0.2"), false},
+	}
+
+	for _, test := range tests {
+		if actual := IsPodTappable(&test.pod); actual != test.expected {
+			t.Errorf("unexpected result for %s - expected: %v, actual: %v", test.pod.Name, test.expected, actual)
+		}
+	}
+}
+
+func TestIsTappedNodesChanged(t *testing.T) {
+	nodeToTappedPodMap := map[string][]core.Pod{"node-a": {newTappedPod("web", "node-a", core.PodRunning, "10.0.0.2")}}
+
+	tests := []struct {
+		name     string
+		previous map[string][]core.Pod
+		expected bool
+	}{
+		{"same nodes with other pods", map[string][]core.Pod{"node-a": {}}, false},
+		{"another node", map[string][]core.Pod{"node-b": {}}, true},
+		{"more nodes", map[string][]core.Pod{"node-a": {}, "node-b": {}}, true},
+		{"no nodes", nil, true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := isTappedNodesChanged(test.previous, nodeToTappedPodMap); actual != test.expected {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+			}
+		})
+	}
+}
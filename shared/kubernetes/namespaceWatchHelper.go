@@ -0,0 +1,37 @@
+package kubernetes
+
+import (
+	"context"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+type NamespaceWatchHelper struct {
+	kubernetesProvider *Provider
+}
+
+func NewNamespaceWatchHelper(kubernetesProvider *Provider) *NamespaceWatchHelper {
+	return &NamespaceWatchHelper{
+		kubernetesProvider: kubernetesProvider,
+	}
+}
+
+// Implements the EventFilterer Interface
+func (wh *NamespaceWatchHelper) Filter(wEvent *WatchEvent) (bool, error) {
+	if _, err := wEvent.ToNamespace(); err != nil {
+		return false, nil
+	}
+
+	return true, nil
+}
+
+// Implements the WatchCreator Interface, namespaces are cluster scoped so the namespace argument is ignored
+func (wh *NamespaceWatchHelper) NewWatcher(ctx context.Context, namespace string) (watch.Interface, error) {
+	watcher, err := wh.kubernetesProvider.clientSet.CoreV1().Namespaces().Watch(ctx, metav1.ListOptions{Watch: true})
+	if err != nil {
+		return nil, err
+	}
+
+	return watcher, nil
+}
@@ -0,0 +1,182 @@
+package kubernetes
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// the CNIs by the prefixes of the names of their node daemon sets, the more specific names come first since canal
+// runs calico and flannel and gke dataplane v2 runs cilium
+var cniDaemonSetPrefixes = []struct {
+	prefix string
+	cni    string
+}{
+	{"canal", "Canal"},
+	{"calico-node", "Calico"},
+	{"anetd", "Cilium (GKE Dataplane V2)"},
+	{"cilium", "Cilium"},
+	{"kube-flannel", "Flannel"},
+	{"flannel", "Flannel"},
+	{"weave-net", "Weave Net"},
+	{"aws-node", "Amazon VPC CNI"},
+	{"azure-cns", "Azure CNI"},
+	{"azure-npm", "Azure CNI"},
+	{"antrea-agent", "Antrea"},
+	{"kube-router", "kube-router"},
+	{"ovnkube-node", "OVN-Kubernetes"},
+	{"sdn", "OpenShift SDN"},
+}
+
+// DetectCni returns the names of the CNIs whose node daemon sets are deployed, empty when none is known
+func DetectCni(daemonSets []apps.DaemonSet) []string {
+	detected := make(map[string]bool)
+	for _, daemonSet := range daemonSets {
+		for _, cniDaemonSetPrefix := range cniDaemonSetPrefixes {
+			if strings.HasPrefix(daemonSet.Name, cniDaemonSetPrefix.prefix) {
+				detected[cniDaemonSetPrefix.cni] = true
+				break
+			}
+		}
+	}
+
+	cnis := make([]string, 0, len(detected))
+	for cni := range detected {
+		cnis = append(cnis, cni)
+	}
+	sort.Strings(cnis)
+
+	return cnis
+}
+
+// GetNodeInternalIps returns the internal ips of the nodes, the tappers run in the host network so their traffic
+// comes from these ips
+func GetNodeInternalIps(nodes []core.Node) []net.IP {
+	ips := make([]net.IP, 0, len(nodes))
+	for _, node := range nodes {
+		for _, address := range node.Status.Addresses {
+			if address.Type != core.NodeInternalIP {
+				continue
+			}
+
+			if ip := net.ParseIP(address.Address); ip != nil {
+				ips = append(ips, ip)
+			}
+		}
+	}
+
+	return ips
+}
+
+// GetBlockingNetworkPolicies returns the names of the network policies that isolate the ingress of a pod with the
+// labels when none of them allows the port from all of the source ips, empty when the traffic is allowed
+func GetBlockingNetworkPolicies(policies []networking.NetworkPolicy, podLabels map[string]string, port int32, sourceIps []net.IP) []string {
+	isolating := make([]string, 0)
+
+	for _, policy := range policies {
+		if !isIngressPolicy(&policy) {
+			continue
+		}
+
+		selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.PodSelector)
+		if err != nil || !selector.Matches(labels.Set(podLabels)) {
+			continue
+		}
+
+		for _, rule := range policy.Spec.Ingress {
+			if ingressRuleAllows(&rule, port, sourceIps) {
+				return []string{}
+			}
+		}
+
+		isolating = append(isolating, policy.Name)
+	}
+
+	return isolating
+}
+
+func isIngressPolicy(policy *networking.NetworkPolicy) bool {
+	// a policy without policy types always isolates the ingress
+	if len(policy.Spec.PolicyTypes) == 0 {
+		return true
+	}
+
+	for _, policyType := range policy.Spec.PolicyTypes {
+		if policyType == networking.PolicyTypeIngress {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ingressRuleAllows checks the ports and the ip blocks of the rule, the pod and namespace selectors don't select the
+// traffic of the host network on most CNIs so they aren't considered
+func ingressRuleAllows(rule *networking.NetworkPolicyIngressRule, port int32, sourceIps []net.IP) bool {
+	if !ingressRuleAllowsPort(rule, port) {
+		return false
+	}
+
+	if len(rule.From) == 0 {
+		return true
+	}
+
+	for _, peer := range rule.From {
+		if peer.IPBlock != nil && ipBlockContainsAll(peer.IPBlock, sourceIps) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ingressRuleAllowsPort(rule *networking.NetworkPolicyIngressRule, port int32) bool {
+	if len(rule.Ports) == 0 {
+		return true
+	}
+
+	for _, rulePort := range rule.Ports {
+		// named ports can't be resolved without the pod, so they're assumed to match
+		if rulePort.Port == nil || rulePort.Port.Type == intstr.String {
+			return true
+		}
+
+		endPort := rulePort.Port.IntVal
+		if rulePort.EndPort != nil {
+			endPort = *rulePort.EndPort
+		}
+		if port >= rulePort.Port.IntVal && port <= endPort {
+			return true
+		}
+	}
+
+	return false
+}
+
+func ipBlockContainsAll(ipBlock *networking.IPBlock, ips []net.IP) bool {
+	_, cidr, err := net.ParseCIDR(ipBlock.CIDR)
+	if err != nil {
+		return false
+	}
+
+	for _, ip := range ips {
+		if !cidr.Contains(ip) {
+			return false
+		}
+
+		for _, except := range ipBlock.Except {
+			if _, exceptCidr, err := net.ParseCIDR(except); err == nil && exceptCidr.Contains(ip) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
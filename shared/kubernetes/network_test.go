@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"net"
+	"reflect"
+	"testing"
+
+	apps "k8s.io/api/apps/v1"
+	networking "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func TestDetectCni(t *testing.T) {
+	daemonSets := []apps.DaemonSet{
+		{ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "canal"}},
+		{ObjectMeta: metav1.ObjectMeta{Name: "cilium"}},
+	}
+
+	if actual, expected := DetectCni(daemonSets), []string{"Canal", "Cilium"}; !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+
+	if actual := DetectCni(daemonSets[:1]); len(actual) != 0 {
+		t.Errorf("unexpected result - expected no cni, actual: %v", actual)
+	}
+}
+
+func newNetworkPolicy(name string, ingress ...networking.NetworkPolicyIngressRule) networking.NetworkPolicy {
+	return networking.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec: networking.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: map[string]string{"app": "mizu-api-server"}},
+			PolicyTypes: []networking.PolicyType{networking.PolicyTypeIngress},
+			Ingress:     ingress,
+		},
+	}
+}
+
+func TestGetBlockingNetworkPolicies(t *testing.T) {
+	podLabels := map[string]string{"app": "mizu-api-server"}
+	nodeIps := []net.IP{net.ParseIP("10.0.1.5"), net.ParseIP("10.0.2.7")}
+	port := intstr.FromInt(8899)
+	otherPort := intstr.FromInt(443)
+
+	tests := []struct {
+		name     string
+		policies []networking.NetworkPolicy
+		expected []string
+	}{
+		{"no policies", nil, []string{}},
+		{"deny all", []networking.NetworkPolicy{newNetworkPolicy("deny-all")}, []string{"deny-all"}},
+		{"allow all", []networking.NetworkPolicy{newNetworkPolicy("deny-all"), newNetworkPolicy("allow-all", networking.NetworkPolicyIngressRule{})}, []string{}},
+		{"pod selector peers only", []networking.NetworkPolicy{newNetworkPolicy("same-namespace", networking.NetworkPolicyIngressRule{
+			From: []networking.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{}}},
+		})}, []string{"same-namespace"}},
+		{"node ip block", []networking.NetworkPolicy{newNetworkPolicy("nodes", networking.NetworkPolicyIngressRule{
+			From:  []networking.NetworkPolicyPeer{{IPBlock: &networking.IPBlock{CIDR: "10.0.0.0/16"}}},
+			Ports: []networking.NetworkPolicyPort{{Port: &port}},
+		})}, []string{}},
+		{"ip block excepting a node", []networking.NetworkPolicy{newNetworkPolicy("nodes", networking.NetworkPolicyIngressRule{
+			From: []networking.NetworkPolicyPeer{{IPBlock: &networking.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.2.0/24"}}}},
+		})}, []string{"nodes"}},
+		{"other port", []networking.NetworkPolicy{newNetworkPolicy("https", networking.NetworkPolicyIngressRule{
+			Ports: []networking.NetworkPolicyPort{{Port: &otherPort}},
+		})}, []string{"https"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := GetBlockingNetworkPolicies(test.policies, podLabels, 8899, nodeIps); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+			}
+		})
+	}
+
+	egressPolicy := newNetworkPolicy("egress")
+	egressPolicy.Spec.PolicyTypes = []networking.PolicyType{networking.PolicyTypeEgress}
+	if actual := GetBlockingNetworkPolicies([]networking.NetworkPolicy{egressPolicy}, podLabels, 8899, nodeIps); len(actual) != 0 {
+		t.Errorf("unexpected result - expected an egress policy not to block, actual: %v", actual)
+	}
+
+	otherPodPolicy := newNetworkPolicy("other-pod")
+	otherPodPolicy.Spec.PodSelector.MatchLabels = map[string]string{"app": "front-end"}
+	if actual := GetBlockingNetworkPolicies([]networking.NetworkPolicy{otherPodPolicy}, podLabels, 8899, nodeIps); len(actual) != 0 {
+		t.Errorf("unexpected result - expected a policy of another pod not to block, actual: %v", actual)
+	}
+}
@@ -0,0 +1,60 @@
+package kubernetes
+
+import (
+	"context"
+
+	core "k8s.io/api/core/v1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetPersistentVolumeClaimObject returns the claim of the volume the api server stores the entries on, the default
+// storage class of the cluster is used when there's no storage class
+func (provider *Provider) GetPersistentVolumeClaimObject(claimName string, sizeBytes int64, storageClass string) *core.PersistentVolumeClaim {
+	claim := &core.PersistentVolumeClaim{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "PersistentVolumeClaim",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: claimName,
+			Labels: map[string]string{
+				LabelManagedBy: provider.managedBy,
+				LabelCreatedBy: provider.createdBy,
+			},
+		},
+		Spec: core.PersistentVolumeClaimSpec{
+			AccessModes: []core.PersistentVolumeAccessMode{core.ReadWriteOnce},
+			Resources: core.ResourceRequirements{
+				Requests: core.ResourceList{
+					core.ResourceStorage: *resource.NewQuantity(sizeBytes, resource.DecimalSI),
+				},
+			},
+		},
+	}
+	if storageClass != "" {
+		claim.Spec.StorageClassName = &storageClass
+	}
+
+	return claim
+}
+
+// CreatePersistentVolumeClaimIfMissing creates the claim of the entries, an existing claim is kept with the entries
+// of the former sessions on it. It returns whether the claim existed
+func (provider *Provider) CreatePersistentVolumeClaimIfMissing(ctx context.Context, namespace string, claimName string, sizeBytes int64, storageClass string) (bool, error) {
+	if _, err := provider.clientSet.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, claimName, metav1.GetOptions{}); err == nil {
+		return true, nil
+	} else if !k8serrors.IsNotFound(err) {
+		return false, err
+	}
+
+	claim := provider.GetPersistentVolumeClaimObject(claimName, sizeBytes, storageClass)
+	_, err := provider.clientSet.CoreV1().PersistentVolumeClaims(namespace).Create(ctx, claim, metav1.CreateOptions{})
+	return false, err
+}
+
+func (provider *Provider) RemovePersistentVolumeClaim(ctx context.Context, namespace string, claimName string) error {
+	err := provider.clientSet.CoreV1().PersistentVolumeClaims(namespace).Delete(ctx, claimName, metav1.DeleteOptions{})
+	return provider.handleRemovalError(err)
+}
@@ -5,12 +5,18 @@ import (
 	"regexp"
 
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/watch"
 )
 
 type PodWatchHelper struct {
 	kubernetesProvider *Provider
 	NameRegexFilter    *regexp.Regexp
+	// PassTapAnnotated also passes the pods that don't match the regex but have a mizu.io/tap annotation
+	PassTapAnnotated bool
+	// LabelSelector is applied by the api server, which sends a deleted event for a pod whose labels stop matching, it's
+	// ignored with PassTapAnnotated since the annotated pods are tapped regardless of their labels
+	LabelSelector labels.Selector
 }
 
 func NewPodWatchHelper(kubernetesProvider *Provider, NameRegexFilter *regexp.Regexp) *PodWatchHelper {
@@ -27,7 +33,7 @@ func (wh *PodWatchHelper) Filter(wEvent *WatchEvent) (bool, error) {
 		return false, nil
 	}
 
-	if !wh.NameRegexFilter.MatchString(pod.Name) {
+	if !wh.NameRegexFilter.MatchString(pod.Name) && !(wh.PassTapAnnotated && GetTapAnnotation(pod.ObjectMeta) != "") {
 		return false, nil
 	}
 
@@ -36,7 +42,12 @@ func (wh *PodWatchHelper) Filter(wEvent *WatchEvent) (bool, error) {
 
 // Implements the WatchCreator Interface
 func (wh *PodWatchHelper) NewWatcher(ctx context.Context, namespace string) (watch.Interface, error) {
-	watcher, err := wh.kubernetesProvider.clientSet.CoreV1().Pods(namespace).Watch(ctx, metav1.ListOptions{Watch: true})
+	listOptions := metav1.ListOptions{Watch: true}
+	if wh.LabelSelector != nil && !wh.PassTapAnnotated {
+		listOptions.LabelSelector = wh.LabelSelector.String()
+	}
+
+	watcher, err := wh.kubernetesProvider.clientSet.CoreV1().Pods(namespace).Watch(ctx, listOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -3,6 +3,8 @@ package kubernetes
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -10,25 +12,35 @@ import (
 	"net/url"
 	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/op/go-logging"
 	"github.com/up9inc/mizu/shared"
 	"github.com/up9inc/mizu/shared/logger"
 	"github.com/up9inc/mizu/shared/semver"
 	"github.com/up9inc/mizu/tap/api"
+	apps "k8s.io/api/apps/v1"
 	auth "k8s.io/api/authorization/v1"
 	core "k8s.io/api/core/v1"
+	networking "k8s.io/api/networking/v1"
 	rbac "k8s.io/api/rbac/v1"
 	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	"k8s.io/apimachinery/pkg/version"
 	"k8s.io/apimachinery/pkg/watch"
 	applyconfapp "k8s.io/client-go/applyconfigurations/apps/v1"
 	applyconfcore "k8s.io/client-go/applyconfigurations/core/v1"
 	applyconfmeta "k8s.io/client-go/applyconfigurations/meta/v1"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/rest"
@@ -40,18 +52,24 @@ import (
 
 type Provider struct {
 	clientSet        *kubernetes.Clientset
+	dynamicClient    dynamic.Interface
 	kubernetesConfig clientcmd.ClientConfig
 	clientConfig     restclient.Config
+	contextName      string
 	managedBy        string
 	createdBy        string
 }
 
 const (
-	fieldManagerName = "mizu-manager"
-	procfsVolumeName = "proc"
-	procfsMountPath  = "/hostproc"
-	sysfsVolumeName  = "sys"
-	sysfsMountPath   = "/sys"
+	fieldManagerName     = "mizu-manager"
+	procfsVolumeName     = "proc"
+	procfsMountPath      = "/hostproc"
+	sysfsVolumeName      = "sys"
+	sysfsMountPath       = "/sys"
+	tokenVolumeName      = "mizu-token"
+	provenanceVolumeName = "mizu-provenance"
+	// the kubelet rotates projected tokens once 80% of their lifetime passed
+	tapperTokenExpirationSeconds = 3600
 )
 
 func NewProvider(kubeConfigPath string, contextName string) (*Provider, error) {
@@ -77,12 +95,20 @@ func NewProvider(kubeConfigPath string, contextName string) (*Provider, error) {
 			"you can set alternative kube config file path by adding the kube-config-path field to the mizu config file, err:  %w", kubeConfigPath, err)
 	}
 
+	dynamicClient, err := dynamic.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, fmt.Errorf("error while using kube config (%s)\n"+
+			"you can set alternative kube config file path by adding the kube-config-path field to the mizu config file, err:  %w", kubeConfigPath, err)
+	}
+
 	logger.Log.Debugf("K8s client config, host: %s, api path: %s, user agent: %s", restClientConfig.Host, restClientConfig.APIPath, restClientConfig.UserAgent)
 
 	return &Provider{
 		clientSet:        clientSet,
+		dynamicClient:    dynamicClient,
 		kubernetesConfig: kubernetesConfig,
 		clientConfig:     *restClientConfig,
+		contextName:      contextName,
 		managedBy:        LabelValueMizu,
 		createdBy:        LabelValueMizuCLI,
 	}, nil
@@ -97,9 +123,14 @@ func NewProviderInCluster() (*Provider, error) {
 	if err != nil {
 		return nil, err
 	}
+	dynamicClient, err := dynamic.NewForConfig(restClientConfig)
+	if err != nil {
+		return nil, err
+	}
 
 	return &Provider{
 		clientSet:        clientSet,
+		dynamicClient:    dynamicClient,
 		kubernetesConfig: nil, // not relevant in cluster
 		clientConfig:     *restClientConfig,
 		managedBy:        LabelValueMizu,
@@ -115,6 +146,38 @@ func (provider *Provider) CurrentNamespace() (string, error) {
 	return ns, err
 }
 
+// CurrentUser returns the user of the kube config context, the authenticated username would take a SelfSubjectReview,
+// which is served only from Kubernetes 1.26
+func (provider *Provider) CurrentUser() (string, error) {
+	if provider.kubernetesConfig == nil {
+		return "", errors.New("kubernetesConfig is nil, mizu cli will not work with in-cluster kubernetes config, use a kubeconfig file when initializing the Provider")
+	}
+
+	rawConfig, err := provider.kubernetesConfig.RawConfig()
+	if err != nil {
+		return "", err
+	}
+
+	contextName := provider.contextName
+	if contextName == "" {
+		contextName = rawConfig.CurrentContext
+	}
+
+	kubeContext, ok := rawConfig.Contexts[contextName]
+	if !ok {
+		return "", fmt.Errorf("context %s not found in the kube config", contextName)
+	}
+
+	return kubeContext.AuthInfo, nil
+}
+
+// ClusterFingerprint identifies the cluster by a hash of its API server url, so the outputs of clusters can be told
+// apart when they aren't given a name
+func (provider *Provider) ClusterFingerprint() string {
+	hash := sha256.Sum256([]byte(provider.clientConfig.Host))
+	return fmt.Sprintf("cluster-%s", hex.EncodeToString(hash[:6]))
+}
+
 func (provider *Provider) WaitUtilNamespaceDeleted(ctx context.Context, name string) error {
 	fieldSelector := fmt.Sprintf("metadata.name=%s", name)
 	var limit int64 = 1
@@ -156,7 +219,15 @@ func (provider *Provider) WaitUtilNamespaceDeleted(ctx context.Context, name str
 }
 
 func (provider *Provider) CreateNamespace(ctx context.Context, name string) (*core.Namespace, error) {
-	namespaceSpec := &core.Namespace{
+	return provider.clientSet.CoreV1().Namespaces().Create(ctx, provider.GetNamespaceObject(name), metav1.CreateOptions{})
+}
+
+func (provider *Provider) GetNamespaceObject(name string) *core.Namespace {
+	return &core.Namespace{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Namespace",
+			APIVersion: "v1",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: name,
 			Labels: map[string]string{
@@ -165,12 +236,13 @@ func (provider *Provider) CreateNamespace(ctx context.Context, name string) (*co
 			},
 		},
 	}
-	return provider.clientSet.CoreV1().Namespaces().Create(ctx, namespaceSpec, metav1.CreateOptions{})
 }
 
 type ApiServerOptions struct {
 	Namespace             string
 	PodName               string
+	ConfigMapName         string
+	SessionId             string
 	PodImage              string
 	KratosImage           string
 	KetoImage             string
@@ -181,6 +253,8 @@ type ApiServerOptions struct {
 	Resources             shared.Resources
 	ImagePullPolicy       core.PullPolicy
 	LogLevel              logging.Level
+	ProvenanceSecretName  string
+	CloudIdentity         shared.CloudIdentityConfig
 }
 
 func (provider *Provider) GetMizuApiServerPodObject(opts *ApiServerOptions, mountVolumeClaim bool, volumeClaimName string, createAuthContainer bool) (*core.Pod, error) {
@@ -193,7 +267,7 @@ func (provider *Provider) GetMizuApiServerPodObject(opts *ApiServerOptions, moun
 	}
 
 	configMapVolume := &core.ConfigMapVolumeSource{}
-	configMapVolume.Name = ConfigMapName
+	configMapVolume.Name = opts.ConfigMapName
 
 	cpuLimit, err := resource.ParseQuantity(opts.Resources.CpuLimit)
 	if err != nil {
@@ -219,13 +293,13 @@ func (provider *Provider) GetMizuApiServerPodObject(opts *ApiServerOptions, moun
 
 	volumeMounts := []core.VolumeMount{
 		{
-			Name:      ConfigMapName,
+			Name:      opts.ConfigMapName,
 			MountPath: shared.ConfigDirPath,
 		},
 	}
 	volumes := []core.Volume{
 		{
-			Name: ConfigMapName,
+			Name: opts.ConfigMapName,
 			VolumeSource: core.VolumeSource{
 				ConfigMap: configMapVolume,
 			},
@@ -247,12 +321,30 @@ func (provider *Provider) GetMizuApiServerPodObject(opts *ApiServerOptions, moun
 		})
 	}
 
+	// only the api server reads the provenance key
+	apiServerVolumeMounts := volumeMounts
+	if opts.ProvenanceSecretName != "" {
+		volumes = append(volumes, core.Volume{
+			Name: provenanceVolumeName,
+			VolumeSource: core.VolumeSource{
+				Secret: &core.SecretVolumeSource{
+					SecretName: opts.ProvenanceSecretName,
+				},
+			},
+		})
+		apiServerVolumeMounts = append(append([]core.VolumeMount{}, volumeMounts...), core.VolumeMount{
+			Name:      provenanceVolumeName,
+			MountPath: shared.ProvenanceKeyDirPath,
+			ReadOnly:  true,
+		})
+	}
+
 	containers := []core.Container{
 		{
 			Name:            opts.PodName,
 			Image:           opts.PodImage,
 			ImagePullPolicy: opts.ImagePullPolicy,
-			VolumeMounts:    volumeMounts,
+			VolumeMounts:    apiServerVolumeMounts,
 			Command:         command,
 			Env: []core.EnvVar{
 				{
@@ -317,9 +409,10 @@ func (provider *Provider) GetMizuApiServerPodObject(opts *ApiServerOptions, moun
 				FailureThreshold: 3,
 				ProbeHandler: core.ProbeHandler{
 					HTTPGet: &core.HTTPGetAction{
-						Path:   "/health/ready",
-						Port:   intstr.FromInt(4433),
-						Scheme: core.URISchemeHTTP,
+						Path:        "/health/ready",
+						Port:        intstr.FromInt(4433),
+						Scheme:      core.URISchemeHTTP,
+						HTTPHeaders: mizuHealthCheckHeaders(),
 					},
 				},
 				PeriodSeconds:    1,
@@ -347,9 +440,10 @@ func (provider *Provider) GetMizuApiServerPodObject(opts *ApiServerOptions, moun
 				FailureThreshold: 3,
 				ProbeHandler: core.ProbeHandler{
 					HTTPGet: &core.HTTPGetAction{
-						Path:   "/health/ready",
-						Port:   intstr.FromInt(4466),
-						Scheme: core.URISchemeHTTP,
+						Path:        "/health/ready",
+						Port:        intstr.FromInt(4466),
+						Scheme:      core.URISchemeHTTP,
+						HTTPHeaders: mizuHealthCheckHeaders(),
 					},
 				},
 				PeriodSeconds:    1,
@@ -369,13 +463,27 @@ func (provider *Provider) GetMizuApiServerPodObject(opts *ApiServerOptions, moun
 		})
 	}
 
+	labels := map[string]string{
+		"app":          opts.PodName,
+		LabelManagedBy: provider.managedBy,
+		LabelCreatedBy: provider.createdBy,
+	}
+	if opts.SessionId != "" {
+		labels[LabelSession] = opts.SessionId
+	}
+	if opts.CloudIdentity.AzureClientId != "" {
+		labels[AzureUseWorkloadIdentity] = "true"
+	}
+
 	pod := &core.Pod{
 		ObjectMeta: metav1.ObjectMeta{
-			Name: opts.PodName,
-			Labels: map[string]string{
-				"app":          opts.PodName,
-				LabelManagedBy: provider.managedBy,
-				LabelCreatedBy: provider.createdBy,
+			Name:   opts.PodName,
+			Labels: labels,
+			// the annotations Prometheus scrape configs commonly discover the pods to scrape by
+			Annotations: map[string]string{
+				"prometheus.io/scrape": "true",
+				"prometheus.io/port":   fmt.Sprintf("%d", shared.DefaultApiServerPort),
+				"prometheus.io/path":   "/metrics",
 			},
 		},
 		Spec: core.PodSpec{
@@ -398,7 +506,15 @@ func (provider *Provider) CreatePod(ctx context.Context, namespace string, podSp
 }
 
 func (provider *Provider) CreateService(ctx context.Context, namespace string, serviceName string, appLabelValue string) (*core.Service, error) {
-	service := core.Service{
+	return provider.clientSet.CoreV1().Services(namespace).Create(ctx, provider.GetServiceObject(serviceName, appLabelValue), metav1.CreateOptions{})
+}
+
+func (provider *Provider) GetServiceObject(serviceName string, appLabelValue string) *core.Service {
+	return &core.Service{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Service",
+			APIVersion: "v1",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: serviceName,
 			Labels: map[string]string{
@@ -412,18 +528,30 @@ func (provider *Provider) CreateService(ctx context.Context, namespace string, s
 			Selector: map[string]string{"app": appLabelValue},
 		},
 	}
+}
+
+func (provider *Provider) CreateServiceForApp(ctx context.Context, namespace string, serviceName string, appLabelValue string, port int32) (*core.Service, error) {
+	service := core.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: serviceName,
+			Labels: map[string]string{
+				LabelManagedBy: provider.managedBy,
+				LabelCreatedBy: provider.createdBy,
+			},
+		},
+		Spec: core.ServiceSpec{
+			Ports:    []core.ServicePort{{TargetPort: intstr.FromInt(int(port)), Port: port}},
+			Type:     core.ServiceTypeClusterIP,
+			Selector: map[string]string{"app": appLabelValue},
+		},
+	}
 	return provider.clientSet.CoreV1().Services(namespace).Create(ctx, &service, metav1.CreateOptions{})
 }
 
-func (provider *Provider) CanI(ctx context.Context, namespace string, resource string, verb string, group string) (bool, error) {
+func (provider *Provider) CanI(ctx context.Context, access ResourceAccess) (bool, error) {
 	selfSubjectAccessReview := &auth.SelfSubjectAccessReview{
 		Spec: auth.SelfSubjectAccessReviewSpec{
-			ResourceAttributes: &auth.ResourceAttributes{
-				Namespace: namespace,
-				Resource:  resource,
-				Verb:      verb,
-				Group:     group,
-			},
+			ResourceAttributes: access.resourceAttributes(),
 		},
 	}
 
@@ -435,6 +563,48 @@ func (provider *Provider) CanI(ctx context.Context, namespace string, resource s
 	return response.Status.Allowed, nil
 }
 
+// CanUser is CanI for another user and groups, like kubectl auth can-i --as, creating the review needs permission to
+// create subjectaccessreviews
+func (provider *Provider) CanUser(ctx context.Context, user string, groups []string, access ResourceAccess) (bool, error) {
+	subjectAccessReview := &auth.SubjectAccessReview{
+		Spec: auth.SubjectAccessReviewSpec{
+			User:               user,
+			Groups:             GetSubjectGroups(user, groups),
+			ResourceAttributes: access.resourceAttributes(),
+		},
+	}
+
+	response, err := provider.clientSet.AuthorizationV1().SubjectAccessReviews().Create(ctx, subjectAccessReview, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	return response.Status.Allowed, nil
+}
+
+// GetResourceScopes discovers which resources of the cluster are namespaced, the api groups that fail the discovery,
+// like the ones of an unavailable aggregated api server, are left out
+func (provider *Provider) GetResourceScopes() (ResourceScopes, error) {
+	resourceLists, err := provider.clientSet.Discovery().ServerPreferredResources()
+	if err != nil && len(resourceLists) == 0 {
+		return nil, err
+	}
+
+	scopes := ResourceScopes{}
+	for _, resourceList := range resourceLists {
+		groupVersion, err := schema.ParseGroupVersion(resourceList.GroupVersion)
+		if err != nil {
+			continue
+		}
+
+		for _, resource := range resourceList.APIResources {
+			scopes[groupVersion.Group+"/"+resource.Name] = resource.Namespaced
+		}
+	}
+
+	return scopes, nil
+}
+
 func (provider *Provider) DoesNamespaceExist(ctx context.Context, name string) (bool, error) {
 	namespaceResource, err := provider.clientSet.CoreV1().Namespaces().Get(ctx, name, metav1.GetOptions{})
 	return provider.doesResourceExist(namespaceResource, err)
@@ -445,6 +615,10 @@ func (provider *Provider) DoesConfigMapExist(ctx context.Context, namespace stri
 	return provider.doesResourceExist(configMapResource, err)
 }
 
+func (provider *Provider) GetConfigMap(ctx context.Context, namespace string, name string) (*core.ConfigMap, error) {
+	return provider.clientSet.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
 func (provider *Provider) DoesServiceAccountExist(ctx context.Context, namespace string, name string) (bool, error) {
 	serviceAccountResource, err := provider.clientSet.CoreV1().ServiceAccounts(namespace).Get(ctx, name, metav1.GetOptions{})
 	return provider.doesResourceExist(serviceAccountResource, err)
@@ -488,8 +662,50 @@ func (provider *Provider) doesResourceExist(resource interface{}, err error) (bo
 	return resource != nil, nil
 }
 
+// ApiServerNamespacedResources are the namespaced resources the api server watches in the namespaces it resolves, to
+// resolve ips to names and to record the deployment and event markers
+var ApiServerNamespacedResources = []string{"pods", "services", "endpoints", "deployments", "events"}
+
+// GetApiServerWatchRules returns the rules that let the api server list and watch the resources of the core and apps
+// groups and the argo rollouts, the watch of the rollouts gives up when they aren't installed
+func GetApiServerWatchRules(resources []string) []rbac.PolicyRule {
+	return []rbac.PolicyRule{
+		{
+			APIGroups: []string{"", "extensions", "apps"},
+			Resources: resources,
+			Verbs:     []string{"list", "get", "watch"},
+		},
+		{
+			APIGroups: []string{"argoproj.io"},
+			Resources: []string{"rollouts"},
+			Verbs:     []string{"list", "get", "watch"},
+		},
+	}
+}
+
 func (provider *Provider) CreateMizuRBAC(ctx context.Context, namespace string, serviceAccountName string, clusterRoleName string, clusterRoleBindingName string, version string, resources []string) error {
+	serviceAccount, clusterRole, clusterRoleBinding := provider.GetMizuRBACObjects(namespace, serviceAccountName, clusterRoleName, clusterRoleBindingName, version, resources)
+	_, err := provider.clientSet.CoreV1().ServiceAccounts(namespace).Create(ctx, serviceAccount, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	_, err = provider.clientSet.RbacV1().ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	_, err = provider.clientSet.RbacV1().ClusterRoleBindings().Create(ctx, clusterRoleBinding, metav1.CreateOptions{})
+	if err != nil && !k8serrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
+
+func (provider *Provider) GetMizuRBACObjects(namespace string, serviceAccountName string, clusterRoleName string, clusterRoleBindingName string, version string, resources []string) (*core.ServiceAccount, *rbac.ClusterRole, *rbac.ClusterRoleBinding) {
 	serviceAccount := &core.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ServiceAccount",
+			APIVersion: "v1",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: serviceAccountName,
 			Labels: map[string]string{
@@ -500,6 +716,10 @@ func (provider *Provider) CreateMizuRBAC(ctx context.Context, namespace string,
 		},
 	}
 	clusterRole := &rbac.ClusterRole{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterRole",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: clusterRoleName,
 			Labels: map[string]string{
@@ -508,15 +728,18 @@ func (provider *Provider) CreateMizuRBAC(ctx context.Context, namespace string,
 				LabelCreatedBy:     provider.createdBy,
 			},
 		},
-		Rules: []rbac.PolicyRule{
-			{
-				APIGroups: []string{"", "extensions", "apps"},
-				Resources: resources,
-				Verbs:     []string{"list", "get", "watch"},
-			},
-		},
+		Rules: append(GetApiServerWatchRules(resources), rbac.PolicyRule{
+			// to authenticate the tappers by their service account tokens
+			APIGroups: []string{"authentication.k8s.io"},
+			Resources: []string{"tokenreviews"},
+			Verbs:     []string{"create"},
+		}),
 	}
 	clusterRoleBinding := &rbac.ClusterRoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ClusterRoleBinding",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: clusterRoleBindingName,
 			Labels: map[string]string{
@@ -538,23 +761,32 @@ func (provider *Provider) CreateMizuRBAC(ctx context.Context, namespace string,
 			},
 		},
 	}
+	return serviceAccount, clusterRole, clusterRoleBinding
+}
+
+func (provider *Provider) CreateMizuRBACNamespaceRestricted(ctx context.Context, namespace string, serviceAccountName string, roleName string, roleBindingName string, version string) error {
+	serviceAccount, role, roleBinding := provider.GetMizuRBACNamespaceRestrictedObjects(namespace, serviceAccountName, roleName, roleBindingName, version)
 	_, err := provider.clientSet.CoreV1().ServiceAccounts(namespace).Create(ctx, serviceAccount, metav1.CreateOptions{})
 	if err != nil && !k8serrors.IsAlreadyExists(err) {
 		return err
 	}
-	_, err = provider.clientSet.RbacV1().ClusterRoles().Create(ctx, clusterRole, metav1.CreateOptions{})
+	_, err = provider.clientSet.RbacV1().Roles(namespace).Create(ctx, role, metav1.CreateOptions{})
 	if err != nil && !k8serrors.IsAlreadyExists(err) {
 		return err
 	}
-	_, err = provider.clientSet.RbacV1().ClusterRoleBindings().Create(ctx, clusterRoleBinding, metav1.CreateOptions{})
+	_, err = provider.clientSet.RbacV1().RoleBindings(namespace).Create(ctx, roleBinding, metav1.CreateOptions{})
 	if err != nil && !k8serrors.IsAlreadyExists(err) {
 		return err
 	}
 	return nil
 }
 
-func (provider *Provider) CreateMizuRBACNamespaceRestricted(ctx context.Context, namespace string, serviceAccountName string, roleName string, roleBindingName string, version string) error {
+func (provider *Provider) GetMizuRBACNamespaceRestrictedObjects(namespace string, serviceAccountName string, roleName string, roleBindingName string, version string) (*core.ServiceAccount, *rbac.Role, *rbac.RoleBinding) {
 	serviceAccount := &core.ServiceAccount{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "ServiceAccount",
+			APIVersion: "v1",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: serviceAccountName,
 			Labels: map[string]string{
@@ -565,6 +797,10 @@ func (provider *Provider) CreateMizuRBACNamespaceRestricted(ctx context.Context,
 		},
 	}
 	role := &rbac.Role{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Role",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: roleName,
 			Labels: map[string]string{
@@ -573,15 +809,13 @@ func (provider *Provider) CreateMizuRBACNamespaceRestricted(ctx context.Context,
 				LabelCreatedBy:     provider.createdBy,
 			},
 		},
-		Rules: []rbac.PolicyRule{
-			{
-				APIGroups: []string{"", "extensions", "apps"},
-				Resources: []string{"pods", "services", "endpoints"},
-				Verbs:     []string{"list", "get", "watch"},
-			},
-		},
+		Rules: GetApiServerWatchRules(ApiServerNamespacedResources),
 	}
 	roleBinding := &rbac.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "RoleBinding",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
 		ObjectMeta: metav1.ObjectMeta{
 			Name: roleBindingName,
 			Labels: map[string]string{
@@ -603,11 +837,14 @@ func (provider *Provider) CreateMizuRBACNamespaceRestricted(ctx context.Context,
 			},
 		},
 	}
-	_, err := provider.clientSet.CoreV1().ServiceAccounts(namespace).Create(ctx, serviceAccount, metav1.CreateOptions{})
-	if err != nil && !k8serrors.IsAlreadyExists(err) {
-		return err
-	}
-	_, err = provider.clientSet.RbacV1().Roles(namespace).Create(ctx, role, metav1.CreateOptions{})
+	return serviceAccount, role, roleBinding
+}
+
+// CreateMizuOperatorRBAC lets the service account of the api server reconcile the taps of its namespace, it applies
+// the tapper daemon set itself instead of the cli
+func (provider *Provider) CreateMizuOperatorRBAC(ctx context.Context, namespace string, serviceAccountName string, roleName string, roleBindingName string, version string) error {
+	role, roleBinding := provider.GetMizuOperatorRBACObjects(namespace, serviceAccountName, roleName, roleBindingName, version)
+	_, err := provider.clientSet.RbacV1().Roles(namespace).Create(ctx, role, metav1.CreateOptions{})
 	if err != nil && !k8serrors.IsAlreadyExists(err) {
 		return err
 	}
@@ -618,6 +855,80 @@ func (provider *Provider) CreateMizuRBACNamespaceRestricted(ctx context.Context,
 	return nil
 }
 
+func (provider *Provider) GetMizuOperatorRBACObjects(namespace string, serviceAccountName string, roleName string, roleBindingName string, version string) (*rbac.Role, *rbac.RoleBinding) {
+	role := &rbac.Role{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Role",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      roleName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"mizu-cli-version": version,
+				LabelManagedBy:     provider.managedBy,
+				LabelCreatedBy:     provider.createdBy,
+			},
+		},
+		Rules: []rbac.PolicyRule{
+			{
+				APIGroups: []string{"apps"},
+				Resources: []string{"daemonsets"},
+				Verbs:     []string{"get", "list", "watch", "create", "update", "patch"},
+			},
+			{
+				// the relays of the node pools
+				APIGroups: []string{"apps"},
+				Resources: []string{"deployments"},
+				Verbs:     []string{"get", "list", "create", "update", "patch", "delete"},
+			},
+			{
+				APIGroups: []string{""},
+				Resources: []string{"services"},
+				Verbs:     []string{"get", "list", "create", "update", "patch", "delete"},
+			},
+			{
+				APIGroups: []string{MizuTapGroup},
+				Resources: []string{MizuTapPlural},
+				Verbs:     []string{"get", "list", "watch", "create", "update", "patch", "delete"},
+			},
+			{
+				APIGroups: []string{MizuTapGroup},
+				Resources: []string{MizuTapPlural + "/status"},
+				Verbs:     []string{"get", "update", "patch"},
+			},
+		},
+	}
+	roleBinding := &rbac.RoleBinding{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "RoleBinding",
+			APIVersion: "rbac.authorization.k8s.io/v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      roleBindingName,
+			Namespace: namespace,
+			Labels: map[string]string{
+				"mizu-cli-version": version,
+				LabelManagedBy:     provider.managedBy,
+				LabelCreatedBy:     provider.createdBy,
+			},
+		},
+		RoleRef: rbac.RoleRef{
+			Name:     roleName,
+			Kind:     "Role",
+			APIGroup: "rbac.authorization.k8s.io",
+		},
+		Subjects: []rbac.Subject{
+			{
+				Kind:      "ServiceAccount",
+				Name:      serviceAccountName,
+				Namespace: namespace,
+			},
+		},
+	}
+	return role, roleBinding
+}
+
 func (provider *Provider) RemoveNamespace(ctx context.Context, name string) error {
 	err := provider.clientSet.CoreV1().Namespaces().Delete(ctx, name, metav1.DeleteOptions{})
 	return provider.handleRemovalError(err)
@@ -658,6 +969,11 @@ func (provider *Provider) RemoveConfigMap(ctx context.Context, namespace string,
 	return provider.handleRemovalError(err)
 }
 
+func (provider *Provider) RemoveSecret(ctx context.Context, namespace string, secretName string) error {
+	err := provider.clientSet.CoreV1().Secrets(namespace).Delete(ctx, secretName, metav1.DeleteOptions{})
+	return provider.handleRemovalError(err)
+}
+
 func (provider *Provider) RemoveService(ctx context.Context, namespace string, serviceName string) error {
 	err := provider.clientSet.CoreV1().Services(namespace).Delete(ctx, serviceName, metav1.DeleteOptions{})
 	return provider.handleRemovalError(err)
@@ -679,6 +995,14 @@ func (provider *Provider) handleRemovalError(err error) error {
 }
 
 func (provider *Provider) CreateConfigMap(ctx context.Context, namespace string, configMapName string, serializedValidationRules string, serializedContract string, serializedMizuConfig string) error {
+	configMap := provider.GetConfigMapObject(configMapName, serializedValidationRules, serializedContract, serializedMizuConfig)
+	if _, err := provider.clientSet.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (provider *Provider) GetConfigMapObject(configMapName string, serializedValidationRules string, serializedContract string, serializedMizuConfig string) *core.ConfigMap {
 	configMapData := make(map[string]string)
 	if serializedValidationRules != "" {
 		configMapData[shared.ValidationRulesFileName] = serializedValidationRules
@@ -688,7 +1012,7 @@ func (provider *Provider) CreateConfigMap(ctx context.Context, namespace string,
 	}
 	configMapData[shared.ConfigFileName] = serializedMizuConfig
 
-	configMap := &core.ConfigMap{
+	return &core.ConfigMap{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "ConfigMap",
 			APIVersion: "v1",
@@ -702,27 +1026,78 @@ func (provider *Provider) CreateConfigMap(ctx context.Context, namespace string,
 		},
 		Data: configMapData,
 	}
-	if _, err := provider.clientSet.CoreV1().ConfigMaps(namespace).Create(ctx, configMap, metav1.CreateOptions{}); err != nil {
+}
+
+// CopyProvenanceSecret copies the provenance key of the source secret to a secret of the mizu resources namespace,
+// so the key can be kept outside of the namespace mizu deletes when it's done
+func (provider *Provider) CopyProvenanceSecret(ctx context.Context, sourceNamespace string, sourceSecretName string, namespace string, secretName string) error {
+	sourceSecret, err := provider.clientSet.CoreV1().Secrets(sourceNamespace).Get(ctx, sourceSecretName, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+
+	key, ok := sourceSecret.Data[shared.ProvenanceKeyFileName]
+	if !ok {
+		return fmt.Errorf("secret %s in namespace %s has no %s key", sourceSecretName, sourceNamespace, shared.ProvenanceKeyFileName)
+	}
+
+	secret := &core.Secret{
+		TypeMeta: metav1.TypeMeta{
+			Kind:       "Secret",
+			APIVersion: "v1",
+		},
+		ObjectMeta: metav1.ObjectMeta{
+			Name: secretName,
+			Labels: map[string]string{
+				LabelManagedBy: provider.managedBy,
+				LabelCreatedBy: provider.createdBy,
+			},
+		},
+		Data: map[string][]byte{
+			shared.ProvenanceKeyFileName: key,
+		},
+	}
+	if _, err := provider.clientSet.CoreV1().Secrets(namespace).Create(ctx, secret, metav1.CreateOptions{}); err != nil {
 		return err
 	}
 	return nil
 }
 
-func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespace string, daemonSetName string, podImage string, tapperPodName string, apiServerPodIp string, nodeToTappedPodMap map[string][]core.Pod, serviceAccountName string, resources shared.Resources, imagePullPolicy core.PullPolicy, mizuApiFilteringOptions api.TrafficFilteringOptions, logLevel logging.Level, serviceMesh bool, tls bool) error {
+func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespace string, daemonSetName string, podImage string, tapperPodName string, apiServerPodIp string, nodeToTappedPodMap map[string][]core.Pod, relayAddresses map[string]string, serviceAccountName string, resources shared.Resources, imagePullPolicy core.PullPolicy, mizuApiFilteringOptions api.TrafficFilteringOptions, logLevel logging.Level, serviceMesh bool, tls bool, tapperAuthentication bool, socketKeepAlive shared.SocketKeepAliveConfig, tolerations []core.Toleration, nodeSelector map[string]string) error {
 	logger.Log.Debugf("Applying %d tapper daemon sets, ns: %s, daemonSetName: %s, podImage: %s, tapperPodName: %s", len(nodeToTappedPodMap), namespace, daemonSetName, podImage, tapperPodName)
 
+	daemonSet, err := provider.GetMizuTapperDaemonSetObject(namespace, daemonSetName, podImage, tapperPodName, apiServerPodIp, nodeToTappedPodMap, relayAddresses, serviceAccountName, resources, imagePullPolicy, mizuApiFilteringOptions, logLevel, serviceMesh, tls, tapperAuthentication, socketKeepAlive, tolerations, nodeSelector)
+	if err != nil {
+		return err
+	}
+
+	applyOptions := metav1.ApplyOptions{
+		Force:        true,
+		FieldManager: fieldManagerName,
+	}
+
+	_, err = provider.clientSet.AppsV1().DaemonSets(namespace).Apply(ctx, daemonSet, applyOptions)
+	return err
+}
+
+func (provider *Provider) GetMizuTapperDaemonSetObject(namespace string, daemonSetName string, podImage string, tapperPodName string, apiServerPodIp string, nodeToTappedPodMap map[string][]core.Pod, relayAddresses map[string]string, serviceAccountName string, resources shared.Resources, imagePullPolicy core.PullPolicy, mizuApiFilteringOptions api.TrafficFilteringOptions, logLevel logging.Level, serviceMesh bool, tls bool, tapperAuthentication bool, socketKeepAlive shared.SocketKeepAliveConfig, tolerations []core.Toleration, nodeSelector map[string]string) (*applyconfapp.DaemonSetApplyConfiguration, error) {
 	if len(nodeToTappedPodMap) == 0 {
-		return fmt.Errorf("daemon set %s must tap at least 1 pod", daemonSetName)
+		return nil, fmt.Errorf("daemon set %s must tap at least 1 pod", daemonSetName)
 	}
 
 	nodeToTappedPodMapJsonStr, err := json.Marshal(nodeToTappedPodMap)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	mizuApiFilteringOptionsJsonStr, err := json.Marshal(mizuApiFilteringOptions)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	socketKeepAliveJsonStr, err := json.Marshal(socketKeepAlive)
+	if err != nil {
+		return nil, err
 	}
 
 	mizuCmd := []string{
@@ -776,7 +1151,15 @@ func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespac
 		applyconfcore.EnvVar().WithName(shared.TappedAddressesPerNodeDictEnvVar).WithValue(string(nodeToTappedPodMapJsonStr)),
 		applyconfcore.EnvVar().WithName(shared.GoGCEnvVar).WithValue("12800"),
 		applyconfcore.EnvVar().WithName(shared.MizuFilteringOptionsEnvVar).WithValue(string(mizuApiFilteringOptionsJsonStr)),
+		applyconfcore.EnvVar().WithName(shared.SocketKeepAliveEnvVar).WithValue(string(socketKeepAliveJsonStr)),
 	)
+	if len(relayAddresses) > 0 {
+		relayAddressesJsonStr, err := json.Marshal(relayAddresses)
+		if err != nil {
+			return nil, err
+		}
+		agentContainer.WithEnv(applyconfcore.EnvVar().WithName(shared.RelayAddressesPerNodeEnvVar).WithValue(string(relayAddressesJsonStr)))
+	}
 	agentContainer.WithEnv(
 		applyconfcore.EnvVar().WithName(shared.NodeNameEnvVar).WithValueFrom(
 			applyconfcore.EnvVarSource().WithFieldRef(
@@ -786,19 +1169,19 @@ func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespac
 	)
 	cpuLimit, err := resource.ParseQuantity(resources.CpuLimit)
 	if err != nil {
-		return fmt.Errorf("invalid cpu limit for %s container", tapperPodName)
+		return nil, fmt.Errorf("invalid cpu limit for %s container", tapperPodName)
 	}
 	memLimit, err := resource.ParseQuantity(resources.MemoryLimit)
 	if err != nil {
-		return fmt.Errorf("invalid memory limit for %s container", tapperPodName)
+		return nil, fmt.Errorf("invalid memory limit for %s container", tapperPodName)
 	}
 	cpuRequests, err := resource.ParseQuantity(resources.CpuRequests)
 	if err != nil {
-		return fmt.Errorf("invalid cpu request for %s container", tapperPodName)
+		return nil, fmt.Errorf("invalid cpu request for %s container", tapperPodName)
 	}
 	memRequests, err := resource.ParseQuantity(resources.MemoryRequests)
 	if err != nil {
-		return fmt.Errorf("invalid memory request for %s container", tapperPodName)
+		return nil, fmt.Errorf("invalid memory request for %s container", tapperPodName)
 	}
 	agentResourceLimits := core.ResourceList{
 		"cpu":    cpuLimit,
@@ -819,22 +1202,20 @@ func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespac
 	nodeSelectorRequirement.WithKey("kubernetes.io/hostname")
 	nodeSelectorRequirement.WithOperator(core.NodeSelectorOpIn)
 	nodeSelectorRequirement.WithValues(nodeNames...)
+	// a tapper on a node of another architecture would crash loop on the exec format of the image
+	architectureRequirement := applyconfcore.NodeSelectorRequirement()
+	architectureRequirement.WithKey(core.LabelArchStable)
+	architectureRequirement.WithOperator(core.NodeSelectorOpIn)
+	architectureRequirement.WithValues(SupportedTapperArchitectures...)
 	nodeSelectorTerm := applyconfcore.NodeSelectorTerm()
-	nodeSelectorTerm.WithMatchExpressions(nodeSelectorRequirement)
-	nodeSelector := applyconfcore.NodeSelector()
-	nodeSelector.WithNodeSelectorTerms(nodeSelectorTerm)
+	nodeSelectorTerm.WithMatchExpressions(nodeSelectorRequirement, architectureRequirement)
+	nodeAffinitySelector := applyconfcore.NodeSelector()
+	nodeAffinitySelector.WithNodeSelectorTerms(nodeSelectorTerm)
 	nodeAffinity := applyconfcore.NodeAffinity()
-	nodeAffinity.WithRequiredDuringSchedulingIgnoredDuringExecution(nodeSelector)
+	nodeAffinity.WithRequiredDuringSchedulingIgnoredDuringExecution(nodeAffinitySelector)
 	affinity := applyconfcore.Affinity()
 	affinity.WithNodeAffinity(nodeAffinity)
 
-	noExecuteToleration := applyconfcore.Toleration()
-	noExecuteToleration.WithOperator(core.TolerationOpExists)
-	noExecuteToleration.WithEffect(core.TaintEffectNoExecute)
-	noScheduleToleration := applyconfcore.Toleration()
-	noScheduleToleration.WithOperator(core.TolerationOpExists)
-	noScheduleToleration.WithEffect(core.TaintEffectNoSchedule)
-
 	// Host procfs is needed inside the container because we need access to
 	//	the network namespaces of processes on the machine.
 	//
@@ -850,6 +1231,24 @@ func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespac
 	sysfsVolumeMount := applyconfcore.VolumeMount().WithName(sysfsVolumeName).WithMountPath(sysfsMountPath).WithReadOnly(true)
 	agentContainer.WithVolumeMounts(sysfsVolumeMount)
 
+	volumes := []*applyconfcore.VolumeApplyConfiguration{procfsVolume, sysfsVolume}
+
+	// The api server reviews this token to make sure entries are sent by the tappers
+	if tapperAuthentication {
+		tokenVolume := applyconfcore.Volume()
+		tokenVolume.WithName(tokenVolumeName).WithProjected(applyconfcore.ProjectedVolumeSource().WithSources(
+			applyconfcore.VolumeProjection().WithServiceAccountToken(
+				applyconfcore.ServiceAccountTokenProjection().
+					WithAudience(shared.TapperTokenAudience).
+					WithExpirationSeconds(tapperTokenExpirationSeconds).
+					WithPath(shared.TapperTokenFileName),
+			),
+		))
+		tokenVolumeMount := applyconfcore.VolumeMount().WithName(tokenVolumeName).WithMountPath(shared.TapperTokenDirPath).WithReadOnly(true)
+		agentContainer.WithVolumeMounts(tokenVolumeMount)
+		volumes = append(volumes, tokenVolume)
+	}
+
 	podSpec := applyconfcore.PodSpec()
 	podSpec.WithHostNetwork(true)
 	podSpec.WithDNSPolicy(core.DNSClusterFirstWithHostNet)
@@ -859,8 +1258,11 @@ func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespac
 	}
 	podSpec.WithContainers(agentContainer)
 	podSpec.WithAffinity(affinity)
-	podSpec.WithTolerations(noExecuteToleration, noScheduleToleration)
-	podSpec.WithVolumes(procfsVolume, sysfsVolume)
+	podSpec.WithTolerations(getTolerationApplyConfigurations(tolerations)...)
+	if len(nodeSelector) > 0 {
+		podSpec.WithNodeSelector(nodeSelector)
+	}
+	podSpec.WithVolumes(volumes...)
 
 	podTemplate := applyconfcore.PodTemplateSpec()
 	podTemplate.WithLabels(map[string]string{
@@ -873,11 +1275,6 @@ func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespac
 	labelSelector := applyconfmeta.LabelSelector()
 	labelSelector.WithMatchLabels(map[string]string{"app": tapperPodName})
 
-	applyOptions := metav1.ApplyOptions{
-		Force:        true,
-		FieldManager: fieldManagerName,
-	}
-
 	daemonSet := applyconfapp.DaemonSet(daemonSetName, namespace)
 	daemonSet.
 		WithLabels(map[string]string{
@@ -886,8 +1283,27 @@ func (provider *Provider) ApplyMizuTapperDaemonSet(ctx context.Context, namespac
 		}).
 		WithSpec(applyconfapp.DaemonSetSpec().WithSelector(labelSelector).WithTemplate(podTemplate))
 
-	_, err = provider.clientSet.AppsV1().DaemonSets(namespace).Apply(ctx, daemonSet, applyOptions)
-	return err
+	return daemonSet, nil
+}
+
+func getTolerationApplyConfigurations(tolerations []core.Toleration) []*applyconfcore.TolerationApplyConfiguration {
+	tolerationApplyConfigurations := make([]*applyconfcore.TolerationApplyConfiguration, 0, len(tolerations))
+	for _, toleration := range tolerations {
+		tolerationApplyConfiguration := applyconfcore.Toleration()
+		if toleration.Key != "" {
+			tolerationApplyConfiguration.WithKey(toleration.Key)
+		}
+		tolerationApplyConfiguration.WithOperator(toleration.Operator)
+		if toleration.Value != "" {
+			tolerationApplyConfiguration.WithValue(toleration.Value)
+		}
+		if toleration.Effect != "" {
+			tolerationApplyConfiguration.WithEffect(toleration.Effect)
+		}
+		tolerationApplyConfigurations = append(tolerationApplyConfigurations, tolerationApplyConfiguration)
+	}
+
+	return tolerationApplyConfigurations
 }
 
 func (provider *Provider) ResetMizuTapperDaemonSet(ctx context.Context, namespace string, daemonSetName string, podImage string, tapperPodName string) error {
@@ -939,6 +1355,69 @@ func (provider *Provider) ResetMizuTapperDaemonSet(ctx context.Context, namespac
 	return err
 }
 
+// ApplyMizuTapCrd creates or updates the custom resource definition of the MizuTap, it's cluster scoped so it's kept
+// when mizu is cleaned, removing it would remove the taps of every namespace
+func (provider *Provider) ApplyMizuTapCrd(ctx context.Context) error {
+	data, err := GetMizuTapCrdObject().MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	force := true
+	_, err = provider.dynamicClient.Resource(customResourceDefinitionsResource).Patch(ctx, MizuTapCrdName, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: fieldManagerName, Force: &force})
+	return err
+}
+
+// ApplyMizuTap creates the tap or replaces its spec, the status is left to the api server that reconciles it
+func (provider *Provider) ApplyMizuTap(ctx context.Context, namespace string, name string, spec MizuTapSpec) error {
+	mizuTap := NewMizuTap(namespace, name, spec)
+	mizuTap.Labels[LabelCreatedBy] = provider.createdBy
+	object, err := mizuTap.ToUnstructured()
+	if err != nil {
+		return err
+	}
+	unstructured.RemoveNestedField(object.Object, "status")
+	unstructured.RemoveNestedField(object.Object, "metadata", "creationTimestamp")
+
+	data, err := object.MarshalJSON()
+	if err != nil {
+		return err
+	}
+
+	force := true
+	_, err = provider.dynamicClient.Resource(MizuTapResource).Namespace(namespace).Patch(ctx, name, types.ApplyPatchType, data, metav1.PatchOptions{FieldManager: fieldManagerName, Force: &force})
+	return err
+}
+
+func (provider *Provider) GetMizuTap(ctx context.Context, namespace string, name string) (*MizuTap, error) {
+	object, err := provider.dynamicClient.Resource(MizuTapResource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return MizuTapFromUnstructured(object)
+}
+
+func (provider *Provider) WatchMizuTaps(ctx context.Context, namespace string) (watch.Interface, error) {
+	return provider.dynamicClient.Resource(MizuTapResource).Namespace(namespace).Watch(ctx, metav1.ListOptions{Watch: true})
+}
+
+// PatchMizuTapStatus replaces the status of the tap, a patch doesn't conflict with the changes of its spec
+func (provider *Provider) PatchMizuTapStatus(ctx context.Context, namespace string, name string, status MizuTapStatus) error {
+	data, err := json.Marshal(map[string]interface{}{"status": status})
+	if err != nil {
+		return err
+	}
+
+	_, err = provider.dynamicClient.Resource(MizuTapResource).Namespace(namespace).Patch(ctx, name, types.MergePatchType, data, metav1.PatchOptions{}, "status")
+	return err
+}
+
+func (provider *Provider) RemoveMizuTap(ctx context.Context, namespace string, name string) error {
+	err := provider.dynamicClient.Resource(MizuTapResource).Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	return provider.handleRemovalError(err)
+}
+
 func (provider *Provider) listPodsImpl(ctx context.Context, regex *regexp.Regexp, namespaces []string, listOptions metav1.ListOptions) ([]core.Pod, error) {
 	var pods []core.Pod
 	for _, namespace := range namespaces {
@@ -982,6 +1461,125 @@ func (provider *Provider) ListAllRunningPodsMatchingRegex(ctx context.Context, r
 	return matchingPods, nil
 }
 
+// ListAllRunningTapTargetPods lists the running pods to tap, when honorTapAnnotations is set the mizu.io/tap annotations of namespaces and pods are honored in addition to the regex
+func (provider *Provider) ListAllRunningTapTargetPods(ctx context.Context, regex *regexp.Regexp, labelSelector labels.Selector, namespaces []string, honorTapAnnotations bool, targets []TapTarget) ([]core.Pod, error) {
+	pods, err := provider.ListAllTapTargetPods(ctx, regex, labelSelector, namespaces, honorTapAnnotations, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	runningPods := make([]core.Pod, 0)
+	for _, pod := range pods {
+		if IsPodRunning(&pod) {
+			runningPods = append(runningPods, pod)
+		}
+	}
+	return runningPods, nil
+}
+
+// ListAllTappableTapTargetPods is ListAllRunningTapTargetPods with the pending pods that were scheduled on a node
+func (provider *Provider) ListAllTappableTapTargetPods(ctx context.Context, regex *regexp.Regexp, labelSelector labels.Selector, namespaces []string, honorTapAnnotations bool, targets []TapTarget) ([]core.Pod, error) {
+	pods, err := provider.ListAllTapTargetPods(ctx, regex, labelSelector, namespaces, honorTapAnnotations, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	tappablePods := make([]core.Pod, 0)
+	for _, pod := range pods {
+		if IsPodTappable(&pod) {
+			tappablePods = append(tappablePods, pod)
+		}
+	}
+	return tappablePods, nil
+}
+
+// ListAllTapTargetPods lists the pods to tap, the pods matching both the regex and the label selector, when honorTapAnnotations is set the namespaces
+// annotated with mizu.io/tap "true" are listed as well and when there are targets only their pods are listed, instead of the pods matching the regex
+func (provider *Provider) ListAllTapTargetPods(ctx context.Context, regex *regexp.Regexp, labelSelector labels.Selector, namespaces []string, honorTapAnnotations bool, targets []TapTarget) ([]core.Pod, error) {
+	if len(targets) > 0 {
+		return provider.listTapTargetsPods(ctx, namespaces, targets)
+	}
+
+	if !honorTapAnnotations {
+		return provider.listPodsImpl(ctx, regex, namespaces, metav1.ListOptions{LabelSelector: labelSelector.String()})
+	}
+
+	namespaceAnnotations, err := provider.GetNamespacesTapAnnotations(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get namespaces tap annotations, %w", err)
+	}
+
+	pods, err := provider.listPodsImpl(ctx, regexp.MustCompile(".*"), GetTapAnnotationsNamespaces(namespaces, namespaceAnnotations), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	matchingPods := make([]core.Pod, 0)
+	for _, pod := range pods {
+		if IsTapTarget(&pod, namespaceAnnotations[pod.Namespace], regex, labelSelector) {
+			matchingPods = append(matchingPods, pod)
+		}
+	}
+	return matchingPods, nil
+}
+
+func (provider *Provider) listTapTargetsPods(ctx context.Context, namespaces []string, targets []TapTarget) ([]core.Pod, error) {
+	targets, err := provider.resolveServiceTapTargets(ctx, targets)
+	if err != nil {
+		return nil, err
+	}
+
+	pods, err := provider.listPodsImpl(ctx, regexp.MustCompile(".*"), namespaces, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	matchingPods := make([]core.Pod, 0)
+	for _, pod := range pods {
+		if IsTapTargetsMatch(&pod, targets) {
+			matchingPods = append(matchingPods, pod)
+		}
+	}
+	return matchingPods, nil
+}
+
+// resolveServiceTapTargets sets the selectors of the service targets, they're read on every listing so a change of a
+// service selector is followed like a rollout, a service that doesn't exist (yet) has no pods
+func (provider *Provider) resolveServiceTapTargets(ctx context.Context, targets []TapTarget) ([]TapTarget, error) {
+	resolvedTargets := make([]TapTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.Kind == TapTargetKindService {
+			service, err := provider.clientSet.CoreV1().Services(target.Namespace).Get(ctx, target.Name, metav1.GetOptions{})
+			if k8serrors.IsNotFound(err) {
+				target = target.withServicePodSelector(nil)
+			} else if err != nil {
+				return nil, fmt.Errorf("failed to get the service of target %s, %w", target, err)
+			} else {
+				target = target.withServicePodSelector(service)
+			}
+		}
+		resolvedTargets = append(resolvedTargets, target)
+	}
+
+	return resolvedTargets, nil
+}
+
+// GetNamespacesTapAnnotations maps the namespaces that have a mizu.io/tap annotation to its value
+func (provider *Provider) GetNamespacesTapAnnotations(ctx context.Context) (map[string]string, error) {
+	namespaces, err := provider.ListAllNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	namespaceAnnotations := make(map[string]string)
+	for _, namespace := range namespaces {
+		if annotation := GetTapAnnotation(namespace.ObjectMeta); annotation != "" {
+			namespaceAnnotations[namespace.Name] = annotation
+		}
+	}
+	return namespaceAnnotations, nil
+}
+
 func (provider *Provider) ListPodsByAppLabel(ctx context.Context, namespaces string, labelName string) ([]core.Pod, error) {
 	pods, err := provider.clientSet.CoreV1().Pods(namespaces).List(ctx, metav1.ListOptions{LabelSelector: fmt.Sprintf("app=%s", labelName)})
 	if err != nil {
@@ -991,6 +1589,15 @@ func (provider *Provider) ListPodsByAppLabel(ctx context.Context, namespaces str
 	return pods.Items, err
 }
 
+func (provider *Provider) ListPodsByLabelSelector(ctx context.Context, namespace string, labelSelector string) ([]core.Pod, error) {
+	pods, err := provider.clientSet.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, err
+	}
+
+	return pods.Items, err
+}
+
 func (provider *Provider) ListAllNamespaces(ctx context.Context) ([]core.Namespace, error) {
 	namespaces, err := provider.clientSet.CoreV1().Namespaces().List(ctx, metav1.ListOptions{})
 	if err != nil {
@@ -1000,8 +1607,52 @@ func (provider *Provider) ListAllNamespaces(ctx context.Context) ([]core.Namespa
 	return namespaces.Items, err
 }
 
+func (provider *Provider) ListNodes(ctx context.Context) ([]core.Node, error) {
+	nodes, err := provider.clientSet.CoreV1().Nodes().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return nodes.Items, err
+}
+
+func (provider *Provider) ListDaemonSets(ctx context.Context, namespace string) ([]apps.DaemonSet, error) {
+	daemonSets, err := provider.clientSet.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return daemonSets.Items, err
+}
+
+func (provider *Provider) ListNetworkPolicies(ctx context.Context, namespace string) ([]networking.NetworkPolicy, error) {
+	networkPolicies, err := provider.clientSet.NetworkingV1().NetworkPolicies(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	return networkPolicies.Items, err
+}
+
+// DryRunCreatePod sends the pod through the admission of the namespace without creating it, so pod security and
+// policy webhooks reject it like they'd reject the real pod
+func (provider *Provider) DryRunCreatePod(ctx context.Context, namespace string, podSpec *core.Pod) error {
+	_, err := provider.clientSet.CoreV1().Pods(namespace).Create(ctx, podSpec, metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}})
+	return err
+}
+
 func (provider *Provider) GetPodLogs(ctx context.Context, namespace string, podName string, containerName string) (string, error) {
-	podLogOpts := core.PodLogOptions{Container: containerName}
+	return provider.getPodLogs(ctx, namespace, podName, containerName, false)
+}
+
+// GetPreviousPodLogs returns the logs of the previous run of a container that restarted, which usually hold the reason
+// it crashed
+func (provider *Provider) GetPreviousPodLogs(ctx context.Context, namespace string, podName string, containerName string) (string, error) {
+	return provider.getPodLogs(ctx, namespace, podName, containerName, true)
+}
+
+func (provider *Provider) getPodLogs(ctx context.Context, namespace string, podName string, containerName string, previous bool) (string, error) {
+	podLogOpts := core.PodLogOptions{Container: containerName, Previous: previous}
 	req := provider.clientSet.CoreV1().Pods(namespace).GetLogs(podName, &podLogOpts)
 	podLogs, err := req.Stream(ctx)
 	if err != nil {
@@ -1016,13 +1667,56 @@ func (provider *Provider) GetPodLogs(ctx context.Context, namespace string, podN
 	return str, nil
 }
 
+// GetNamespaceEvents returns the events of the namespace one per line, oldest first
 func (provider *Provider) GetNamespaceEvents(ctx context.Context, namespace string) (string, error) {
+	events, err := provider.ListEvents(ctx, namespace)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatEvents(events), nil
+}
+
+// ListEvents returns the events of the namespace oldest first
+func (provider *Provider) ListEvents(ctx context.Context, namespace string) ([]core.Event, error) {
 	eventList, err := provider.clientSet.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
 	if err != nil {
-		return "", fmt.Errorf("error getting events on ns: %s, %w", namespace, err)
+		return nil, fmt.Errorf("error getting events on ns: %s, %w", namespace, err)
 	}
 
-	return eventList.String(), nil
+	events := eventList.Items
+	sort.SliceStable(events, func(i, j int) bool {
+		return getEventTime(&events[i]).Before(getEventTime(&events[j]))
+	})
+
+	return events, nil
+}
+
+// FormatEvents renders the events one per line with their time, type, reason, object, count and message
+func FormatEvents(events []core.Event) string {
+	var buf bytes.Buffer
+	for _, event := range events {
+		buf.WriteString(fmt.Sprintf("%s\t%s\t%s\t%s/%s\tx%d\t%s\n",
+			getEventTime(&event).UTC().Format(time.RFC3339), event.Type, event.Reason,
+			strings.ToLower(event.InvolvedObject.Kind), event.InvolvedObject.Name, event.Count, strings.TrimSpace(event.Message)))
+	}
+
+	return buf.String()
+}
+
+func getEventTime(event *core.Event) time.Time {
+	if !event.LastTimestamp.IsZero() {
+		return event.LastTimestamp.Time
+	}
+	if !event.EventTime.IsZero() {
+		return event.EventTime.Time
+	}
+
+	return event.CreationTimestamp.Time
+}
+
+func (provider *Provider) GetConfigMap(ctx context.Context, namespace string, name string) (*core.ConfigMap, error) {
+	return provider.clientSet.CoreV1().ConfigMaps(namespace).Get(ctx, name, metav1.GetOptions{})
 }
 
 func (provider *Provider) ListManagedServiceAccounts(ctx context.Context, namespace string) (*core.ServiceAccountList, error) {
@@ -1138,3 +1832,15 @@ func loadKubernetesConfiguration(kubeConfigPath string, context string) clientcm
 func IsPodRunning(pod *core.Pod) bool {
 	return pod.Status.Phase == core.PodRunning
 }
+
+// IsPodTappable is true for the running pods and for the pending pods that were already scheduled, the tapper of their
+// node is started before their containers so the pods of jobs are tapped from their first packet
+func IsPodTappable(pod *core.Pod) bool {
+	return IsPodRunning(pod) || (pod.Status.Phase == core.PodPending && pod.Spec.NodeName != "")
+}
+
+// mizuHealthCheckHeaders watermarks the probes of the mizu containers, so the tappers don't capture them when the
+// namespace of mizu is tapped
+func mizuHealthCheckHeaders() []core.HTTPHeader {
+	return []core.HTTPHeader{{Name: api.MizuTrafficHeaderName, Value: api.MizuTrafficHealthCheck}}
+}
@@ -0,0 +1,219 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/op/go-logging"
+	"github.com/up9inc/mizu/shared"
+	core "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	applyconfapp "k8s.io/client-go/applyconfigurations/apps/v1"
+	applyconfcore "k8s.io/client-go/applyconfigurations/core/v1"
+	applyconfmeta "k8s.io/client-go/applyconfigurations/meta/v1"
+)
+
+// the node pool labels of GKE, EKS and AKS, the pool of a node is the first of them it has
+var nodePoolLabels = []string{"cloud.google.com/gke-nodepool", "eks.amazonaws.com/nodegroup", "kubernetes.azure.com/agentpool", "agentpool"}
+
+var invalidRelayNameCharacters = regexp.MustCompile("[^a-z0-9-]+")
+
+const maxRelayNameLength = 63
+
+type nodePool struct {
+	label string
+	name  string
+}
+
+// getNodePool returns the pool of the node by the pool label, or by the node pool labels of the cloud providers when
+// there's no pool label
+func getNodePool(node core.Node, poolLabel string) (nodePool, bool) {
+	labels := nodePoolLabels
+	if poolLabel != "" {
+		labels = []string{poolLabel}
+	}
+
+	for _, label := range labels {
+		if name, ok := node.Labels[label]; ok && name != "" {
+			return nodePool{label: label, name: name}, true
+		}
+	}
+
+	return nodePool{}, false
+}
+
+// GetRelayName names the relay of the pool after it, a relay name is also the host name of its service so it's cut to
+// the length of a DNS label
+func GetRelayName(relayNamePrefix string, pool string) string {
+	name := fmt.Sprintf("%s-%s", relayNamePrefix, invalidRelayNameCharacters.ReplaceAllString(strings.ToLower(pool), "-"))
+	if len(name) > maxRelayNameLength {
+		name = name[:maxRelayNameLength]
+	}
+
+	return strings.TrimRight(name, "-")
+}
+
+func getRelayAddress(relayName string, namespace string) string {
+	return fmt.Sprintf("ws://%s.%s.svc.cluster.local/wsTapper", relayName, namespace)
+}
+
+// getRelays returns the pools of the tapped nodes outside the pool of the api server node by the name of their relay,
+// and the address of the relay of every tapped node in them. The tappers of the nodes in the pool of the api server and
+// of the nodes without a pool connect to the api server directly
+func getRelays(nodes []core.Node, nodeToTappedPodMap map[string][]core.Pod, apiServerNodeName string, poolLabel string, relayNamePrefix string, namespace string) (map[string]nodePool, map[string]string) {
+	relays := make(map[string]nodePool)
+	relayAddresses := make(map[string]string)
+
+	var apiServerPool *nodePool
+	for _, node := range nodes {
+		if node.Name == apiServerNodeName {
+			if pool, ok := getNodePool(node, poolLabel); ok {
+				apiServerPool = &pool
+			}
+		}
+	}
+
+	for _, node := range nodes {
+		if _, ok := nodeToTappedPodMap[node.Name]; !ok {
+			continue
+		}
+
+		pool, ok := getNodePool(node, poolLabel)
+		if !ok || (apiServerPool != nil && pool == *apiServerPool) {
+			continue
+		}
+
+		relayName := GetRelayName(relayNamePrefix, pool.name)
+		relays[relayName] = pool
+		relayAddresses[node.Name] = getRelayAddress(relayName, namespace)
+	}
+
+	return relays, relayAddresses
+}
+
+// ApplyMizuRelay applies the deployment of the relay of the pool and its service, the relay runs on a node of the pool
+// and forwards the sockets of the tappers of the pool to the api server
+func (provider *Provider) ApplyMizuRelay(ctx context.Context, namespace string, relayName string, relayNamePrefix string, poolLabel string, poolName string, podImage string, imagePullPolicy core.PullPolicy, apiServerAddress string, resources shared.Resources, logLevel logging.Level, socketKeepAlive shared.SocketKeepAliveConfig, tolerations []core.Toleration) error {
+	socketKeepAliveJsonStr, err := json.Marshal(socketKeepAlive)
+	if err != nil {
+		return err
+	}
+
+	cpuLimit, err := resource.ParseQuantity(resources.CpuLimit)
+	if err != nil {
+		return fmt.Errorf("invalid cpu limit for %s container", relayName)
+	}
+	memLimit, err := resource.ParseQuantity(resources.MemoryLimit)
+	if err != nil {
+		return fmt.Errorf("invalid memory limit for %s container", relayName)
+	}
+	cpuRequests, err := resource.ParseQuantity(resources.CpuRequests)
+	if err != nil {
+		return fmt.Errorf("invalid cpu request for %s container", relayName)
+	}
+	memRequests, err := resource.ParseQuantity(resources.MemoryRequests)
+	if err != nil {
+		return fmt.Errorf("invalid memory request for %s container", relayName)
+	}
+
+	relayContainer := applyconfcore.Container()
+	relayContainer.WithName(relayName)
+	relayContainer.WithImage(podImage)
+	relayContainer.WithImagePullPolicy(imagePullPolicy)
+	relayContainer.WithCommand("./mizuagent", "--relay", "--api-server-address", apiServerAddress)
+	relayContainer.WithEnv(
+		applyconfcore.EnvVar().WithName(shared.LogLevelEnvVar).WithValue(logLevel.String()),
+		applyconfcore.EnvVar().WithName(shared.SocketKeepAliveEnvVar).WithValue(string(socketKeepAliveJsonStr)),
+	)
+	relayContainer.WithPorts(applyconfcore.ContainerPort().WithContainerPort(shared.DefaultApiServerPort))
+	relayContainer.WithReadinessProbe(applyconfcore.Probe().
+		WithHTTPGet(applyconfcore.HTTPGetAction().WithPath("/echo").WithPort(intstr.FromInt(shared.DefaultApiServerPort))).
+		WithPeriodSeconds(1))
+	relayContainer.WithResources(applyconfcore.ResourceRequirements().
+		WithRequests(core.ResourceList{"cpu": cpuRequests, "memory": memRequests}).
+		WithLimits(core.ResourceList{"cpu": cpuLimit, "memory": memLimit}))
+
+	podSpec := applyconfcore.PodSpec()
+	podSpec.WithContainers(relayContainer)
+	podSpec.WithNodeSelector(map[string]string{poolLabel: poolName})
+	podSpec.WithTolerations(getTolerationApplyConfigurations(tolerations)...)
+	podSpec.WithAutomountServiceAccountToken(false)
+	podSpec.WithTerminationGracePeriodSeconds(0)
+
+	labels := map[string]string{
+		"app":          relayName,
+		LabelRelay:     relayNamePrefix,
+		LabelManagedBy: provider.managedBy,
+		LabelCreatedBy: provider.createdBy,
+	}
+
+	podTemplate := applyconfcore.PodTemplateSpec()
+	podTemplate.WithLabels(labels)
+	podTemplate.WithSpec(podSpec)
+
+	deployment := applyconfapp.Deployment(relayName, namespace)
+	deployment.
+		WithLabels(labels).
+		WithSpec(applyconfapp.DeploymentSpec().
+			WithReplicas(1).
+			WithSelector(applyconfmeta.LabelSelector().WithMatchLabels(map[string]string{"app": relayName})).
+			WithTemplate(podTemplate))
+
+	service := applyconfcore.Service(relayName, namespace)
+	service.
+		WithLabels(labels).
+		WithSpec(applyconfcore.ServiceSpec().
+			WithType(core.ServiceTypeClusterIP).
+			WithSelector(map[string]string{"app": relayName}).
+			WithPorts(applyconfcore.ServicePort().WithName("relay").WithPort(80).WithTargetPort(intstr.FromInt(shared.DefaultApiServerPort))))
+
+	applyOptions := metav1.ApplyOptions{
+		Force:        true,
+		FieldManager: fieldManagerName,
+	}
+
+	if _, err := provider.clientSet.AppsV1().Deployments(namespace).Apply(ctx, deployment, applyOptions); err != nil {
+		return err
+	}
+
+	_, err = provider.clientSet.CoreV1().Services(namespace).Apply(ctx, service, applyOptions)
+	return err
+}
+
+// RemoveMizuRelays removes the deployments and the services of the relays named by the prefix, except for the kept ones
+func (provider *Provider) RemoveMizuRelays(ctx context.Context, namespace string, relayNamePrefix string, keptRelayNames ...string) error {
+	listOptions := metav1.ListOptions{LabelSelector: fmt.Sprintf("%s=%s", LabelRelay, relayNamePrefix)}
+
+	deployments, err := provider.clientSet.AppsV1().Deployments(namespace).List(ctx, listOptions)
+	if err != nil {
+		return err
+	}
+	for _, deployment := range deployments.Items {
+		if shared.Contains(keptRelayNames, deployment.Name) {
+			continue
+		}
+		if err := provider.handleRemovalError(provider.clientSet.AppsV1().Deployments(namespace).Delete(ctx, deployment.Name, metav1.DeleteOptions{})); err != nil {
+			return err
+		}
+	}
+
+	services, err := provider.clientSet.CoreV1().Services(namespace).List(ctx, listOptions)
+	if err != nil {
+		return err
+	}
+	for _, service := range services.Items {
+		if shared.Contains(keptRelayNames, service.Name) {
+			continue
+		}
+		if err := provider.RemoveService(ctx, namespace, service.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
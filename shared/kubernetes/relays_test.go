@@ -0,0 +1,73 @@
+package kubernetes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newPoolNode(name string, labels map[string]string) core.Node {
+	return core.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestGetRelays(t *testing.T) {
+	nodes := []core.Node{
+		newPoolNode("default-1", map[string]string{"cloud.google.com/gke-nodepool": "default"}),
+		newPoolNode("default-2", map[string]string{"cloud.google.com/gke-nodepool": "default"}),
+		newPoolNode("gpu-1", map[string]string{"cloud.google.com/gke-nodepool": "GPU_Pool"}),
+		newPoolNode("gpu-2", map[string]string{"cloud.google.com/gke-nodepool": "GPU_Pool"}),
+		newPoolNode("batch-1", map[string]string{"cloud.google.com/gke-nodepool": "batch"}),
+		newPoolNode("bare", map[string]string{}),
+	}
+	nodeToTappedPodMap := map[string][]core.Pod{"default-2": {}, "gpu-1": {}, "gpu-2": {}, "bare": {}}
+
+	relays, relayAddresses := getRelays(nodes, nodeToTappedPodMap, "default-1", "", "mizu-relay", "mizu")
+
+	expectedRelays := map[string]nodePool{"mizu-relay-gpu-pool": {label: "cloud.google.com/gke-nodepool", name: "GPU_Pool"}}
+	if !reflect.DeepEqual(relays, expectedRelays) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expectedRelays, relays)
+	}
+
+	expectedAddresses := map[string]string{
+		"gpu-1": "ws://mizu-relay-gpu-pool.mizu.svc.cluster.local/wsTapper",
+		"gpu-2": "ws://mizu-relay-gpu-pool.mizu.svc.cluster.local/wsTapper",
+	}
+	if !reflect.DeepEqual(relayAddresses, expectedAddresses) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expectedAddresses, relayAddresses)
+	}
+}
+
+func TestGetRelaysPoolLabel(t *testing.T) {
+	nodes := []core.Node{
+		newPoolNode("a", map[string]string{"zone": "east", "eks.amazonaws.com/nodegroup": "workers"}),
+		newPoolNode("b", map[string]string{"zone": "west", "eks.amazonaws.com/nodegroup": "workers"}),
+	}
+	nodeToTappedPodMap := map[string][]core.Pod{"a": {}, "b": {}}
+
+	if relays, _ := getRelays(nodes, nodeToTappedPodMap, "a", "", "mizu-relay", "mizu"); len(relays) != 0 {
+		t.Errorf("unexpected result - expected no relays for a single pool, actual: %v", relays)
+	}
+
+	relays, relayAddresses := getRelays(nodes, nodeToTappedPodMap, "a", "zone", "mizu-relay", "mizu")
+	expectedRelays := map[string]nodePool{"mizu-relay-west": {label: "zone", name: "west"}}
+	if !reflect.DeepEqual(relays, expectedRelays) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expectedRelays, relays)
+	}
+	if _, ok := relayAddresses["a"]; ok {
+		t.Errorf("unexpected result - expected the node of the api server to connect directly, actual: %v", relayAddresses)
+	}
+}
+
+func TestGetRelayName(t *testing.T) {
+	if name := GetRelayName("mizu-relay-ab12", "pool.1"); name != "mizu-relay-ab12-pool-1" {
+		t.Errorf("unexpected result - expected: %v, actual: %v", "mizu-relay-ab12-pool-1", name)
+	}
+
+	name := GetRelayName("mizu-relay", strings.Repeat("a", 51)+"-b")
+	if len(name) > maxRelayNameLength || strings.HasSuffix(name, "-") {
+		t.Errorf("unexpected result - expected a DNS label, actual: %v", name)
+	}
+}
@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+const sessionIdBytes = 2
+
+// ResourceNames are the names of the resources created for a single mizu session, sessions with an id get
+// suffixed names so several sessions can share a namespace without clobbering each other's resources
+type ResourceNames struct {
+	SessionId            string
+	ApiServerPodName     string
+	ConfigMapName        string
+	TapperDaemonSetName  string
+	TapperPodName        string
+	ProvenanceSecretName string
+	MizuTapName          string
+	RelayName            string
+	EntriesClaimName     string
+}
+
+func GetResourceNames(sessionId string) ResourceNames {
+	if sessionId == "" {
+		return ResourceNames{
+			ApiServerPodName:     ApiServerPodName,
+			ConfigMapName:        ConfigMapName,
+			TapperDaemonSetName:  TapperDaemonSetName,
+			TapperPodName:        TapperPodName,
+			ProvenanceSecretName: ProvenanceSecretName,
+			MizuTapName:          MizuTapName,
+			RelayName:            RelayName,
+			EntriesClaimName:     EntriesClaimName,
+		}
+	}
+
+	return ResourceNames{
+		SessionId:            sessionId,
+		ApiServerPodName:     fmt.Sprintf("%s-%s", ApiServerPodName, sessionId),
+		ConfigMapName:        fmt.Sprintf("%s-%s", ConfigMapName, sessionId),
+		TapperDaemonSetName:  fmt.Sprintf("%s-%s", TapperDaemonSetName, sessionId),
+		TapperPodName:        fmt.Sprintf("%s-%s", TapperPodName, sessionId),
+		ProvenanceSecretName: fmt.Sprintf("%s-%s", ProvenanceSecretName, sessionId),
+		MizuTapName:          fmt.Sprintf("%s-%s", MizuTapName, sessionId),
+		RelayName:            fmt.Sprintf("%s-%s", RelayName, sessionId),
+		EntriesClaimName:     fmt.Sprintf("%s-%s", EntriesClaimName, sessionId),
+	}
+}
+
+func GenerateSessionId() (string, error) {
+	randomBytes := make([]byte, sessionIdBytes)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(randomBytes), nil
+}
@@ -0,0 +1,51 @@
+package kubernetes
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestGetResourceNamesWithoutSession(t *testing.T) {
+	resourceNames := GetResourceNames("")
+
+	if resourceNames.ApiServerPodName != ApiServerPodName || resourceNames.ConfigMapName != ConfigMapName ||
+		resourceNames.TapperDaemonSetName != TapperDaemonSetName || resourceNames.TapperPodName != TapperPodName ||
+		resourceNames.ProvenanceSecretName != ProvenanceSecretName || resourceNames.MizuTapName != MizuTapName ||
+		resourceNames.RelayName != RelayName || resourceNames.EntriesClaimName != EntriesClaimName {
+		t.Errorf("unexpected result - expected default names, actual: %v", resourceNames)
+	}
+}
+
+func TestGetResourceNamesWithSession(t *testing.T) {
+	resourceNames := GetResourceNames("ab12")
+
+	expected := ResourceNames{
+		SessionId:            "ab12",
+		ApiServerPodName:     "mizu-api-server-ab12",
+		ConfigMapName:        "mizu-config-ab12",
+		TapperDaemonSetName:  "mizu-tapper-daemon-set-ab12",
+		TapperPodName:        "mizu-tapper-ab12",
+		ProvenanceSecretName: "mizu-provenance-ab12",
+		MizuTapName:          "mizu-tap-ab12",
+		RelayName:            "mizu-relay-ab12",
+		EntriesClaimName:     "mizu-entries-ab12",
+	}
+	if resourceNames != expected {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, resourceNames)
+	}
+}
+
+func TestGenerateSessionId(t *testing.T) {
+	sessionIdRegex := regexp.MustCompile("^[0-9a-f]{4}$")
+
+	for i := 0; i < 10; i++ {
+		sessionId, err := GenerateSessionId()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if !sessionIdRegex.MatchString(sessionId) {
+			t.Errorf("unexpected result - session id %s doesn't match %s", sessionId, sessionIdRegex)
+		}
+	}
+}
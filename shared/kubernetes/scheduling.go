@@ -0,0 +1,94 @@
+package kubernetes
+
+import (
+	"github.com/up9inc/mizu/shared"
+	core "k8s.io/api/core/v1"
+)
+
+// SupportedTapperArchitectures are the node architectures the mizu image is published for
+var SupportedTapperArchitectures = []string{"amd64", "arm64"}
+
+// GetTapperTolerations returns the tolerations of the tappers by the scheduling config, nodes are the nodes of the
+// tapped pods, whose taints are tolerated with AutoTolerate
+func GetTapperTolerations(config shared.TapperSchedulingConfig, nodes []core.Node) []core.Toleration {
+	tolerations := make([]core.Toleration, 0)
+
+	if config.TolerateAll {
+		tolerations = append(tolerations,
+			core.Toleration{Operator: core.TolerationOpExists, Effect: core.TaintEffectNoExecute},
+			core.Toleration{Operator: core.TolerationOpExists, Effect: core.TaintEffectNoSchedule},
+		)
+	}
+
+	for _, toleration := range config.Tolerations {
+		operator := core.TolerationOperator(toleration.Operator)
+		if operator == "" {
+			operator = core.TolerationOpEqual
+		}
+
+		tolerations = append(tolerations, core.Toleration{
+			Key:      toleration.Key,
+			Operator: operator,
+			Value:    toleration.Value,
+			Effect:   core.TaintEffect(toleration.Effect),
+		})
+	}
+
+	if config.AutoTolerate {
+		for _, node := range nodes {
+			for _, taint := range GetUntoleratedTaints(node, tolerations) {
+				tolerations = append(tolerations, core.Toleration{
+					Key:      taint.Key,
+					Operator: core.TolerationOpEqual,
+					Value:    taint.Value,
+					Effect:   taint.Effect,
+				})
+			}
+		}
+	}
+
+	return tolerations
+}
+
+// GetUntoleratedTaints returns the taints of the node that keep pods with the tolerations off it, PreferNoSchedule
+// taints only prefer other nodes so they're left out
+func GetUntoleratedTaints(node core.Node, tolerations []core.Toleration) []core.Taint {
+	untolerated := make([]core.Taint, 0)
+
+	for i := range node.Spec.Taints {
+		taint := &node.Spec.Taints[i]
+		if taint.Effect == core.TaintEffectPreferNoSchedule {
+			continue
+		}
+
+		tolerated := false
+		for j := range tolerations {
+			if tolerations[j].ToleratesTaint(taint) {
+				tolerated = true
+				break
+			}
+		}
+
+		if !tolerated {
+			untolerated = append(untolerated, *taint)
+		}
+	}
+
+	return untolerated
+}
+
+// IsTapperArchitectureSupported checks the architecture label of the node, which the kubelet sets
+func IsTapperArchitectureSupported(node core.Node) bool {
+	return shared.Contains(SupportedTapperArchitectures, node.Labels[core.LabelArchStable])
+}
+
+// MatchesNodeSelector checks the node has every label of the node selector
+func MatchesNodeSelector(node core.Node, nodeSelector map[string]string) bool {
+	for key, value := range nodeSelector {
+		if nodeValue, ok := node.Labels[key]; !ok || nodeValue != value {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,68 @@
+package kubernetes
+
+import (
+	"testing"
+
+	"github.com/up9inc/mizu/shared"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTaintedNode(name string, arch string, taints ...core.Taint) core.Node {
+	return core.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{core.LabelArchStable: arch}},
+		Spec:       core.NodeSpec{Taints: taints},
+	}
+}
+
+func TestGetTapperTolerations(t *testing.T) {
+	gpuTaint := core.Taint{Key: "nvidia.com/gpu", Value: "present", Effect: core.TaintEffectNoSchedule}
+	spotTaint := core.Taint{Key: "spot", Value: "true", Effect: core.TaintEffectNoExecute}
+	preferTaint := core.Taint{Key: "batch", Value: "true", Effect: core.TaintEffectPreferNoSchedule}
+	nodes := []core.Node{newTaintedNode("gpu", "amd64", gpuTaint, preferTaint), newTaintedNode("spot", "arm64", spotTaint)}
+
+	tolerations := GetTapperTolerations(shared.TapperSchedulingConfig{TolerateAll: true}, nil)
+	for _, node := range nodes {
+		if untolerated := GetUntoleratedTaints(node, tolerations); len(untolerated) != 0 {
+			t.Errorf("unexpected result - expected every taint of %s to be tolerated, actual: %v", node.Name, untolerated)
+		}
+	}
+
+	tolerations = GetTapperTolerations(shared.TapperSchedulingConfig{Tolerations: []shared.TapperToleration{{Key: "spot", Value: "true"}}}, nil)
+	if untolerated := GetUntoleratedTaints(nodes[0], tolerations); len(untolerated) != 1 || untolerated[0].Key != gpuTaint.Key {
+		t.Errorf("unexpected result - expected: %v, actual: %v", []core.Taint{gpuTaint}, untolerated)
+	}
+	if untolerated := GetUntoleratedTaints(nodes[1], tolerations); len(untolerated) != 0 {
+		t.Errorf("unexpected result - expected the configured toleration to tolerate %v, actual: %v", spotTaint, untolerated)
+	}
+
+	tolerations = GetTapperTolerations(shared.TapperSchedulingConfig{AutoTolerate: true}, nodes)
+	if len(tolerations) != 2 {
+		t.Errorf("unexpected result - expected a toleration per taint, actual: %v", tolerations)
+	}
+	for _, node := range nodes {
+		if untolerated := GetUntoleratedTaints(node, tolerations); len(untolerated) != 0 {
+			t.Errorf("unexpected result - expected every taint of %s to be tolerated, actual: %v", node.Name, untolerated)
+		}
+	}
+}
+
+func TestIsTapperArchitectureSupported(t *testing.T) {
+	if !IsTapperArchitectureSupported(newTaintedNode("arm", "arm64")) {
+		t.Errorf("expected arm64 to be supported")
+	}
+	if IsTapperArchitectureSupported(newTaintedNode("ibm", "s390x")) {
+		t.Errorf("expected s390x not to be supported")
+	}
+}
+
+func TestMatchesNodeSelector(t *testing.T) {
+	node := newTaintedNode("node", "amd64")
+
+	if !MatchesNodeSelector(node, nil) || !MatchesNodeSelector(node, map[string]string{core.LabelArchStable: "amd64"}) {
+		t.Errorf("expected the node to match")
+	}
+	if MatchesNodeSelector(node, map[string]string{core.LabelArchStable: "arm64"}) || MatchesNodeSelector(node, map[string]string{"pool": "tap"}) {
+		t.Errorf("expected the node not to match")
+	}
+}
@@ -0,0 +1,41 @@
+package kubernetes
+
+import (
+	"strings"
+
+	"github.com/up9inc/mizu/shared"
+)
+
+const (
+	authenticatedGroup             = "system:authenticated"
+	serviceAccountUserPrefix       = "system:serviceaccount:"
+	serviceAccountsGroup           = "system:serviceaccounts"
+	serviceAccountsNamespacePrefix = "system:serviceaccounts:"
+)
+
+// GetSubjectGroups returns the groups the api server would authenticate user with, besides groups, a subject access
+// review only matches the bindings of the groups it's given, so the implicit ones are added like the authenticator does
+func GetSubjectGroups(user string, groups []string) []string {
+	subjectGroups := make([]string, 0)
+	addGroup := func(group string) {
+		if group != "" && !shared.Contains(subjectGroups, group) {
+			subjectGroups = append(subjectGroups, group)
+		}
+	}
+
+	for _, group := range groups {
+		addGroup(group)
+	}
+
+	addGroup(authenticatedGroup)
+
+	if strings.HasPrefix(user, serviceAccountUserPrefix) {
+		serviceAccount := strings.Split(strings.TrimPrefix(user, serviceAccountUserPrefix), ":")
+		if len(serviceAccount) == 2 && serviceAccount[0] != "" {
+			addGroup(serviceAccountsGroup)
+			addGroup(serviceAccountsNamespacePrefix + serviceAccount[0])
+		}
+	}
+
+	return subjectGroups
+}
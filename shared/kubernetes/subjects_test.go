@@ -0,0 +1,29 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetSubjectGroups(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		groups   []string
+		expected []string
+	}{
+		{"user", "jane", nil, []string{"system:authenticated"}},
+		{"user with groups", "jane", []string{"dev", "ops"}, []string{"dev", "ops", "system:authenticated"}},
+		{"duplicate groups", "jane", []string{"dev", "dev", "system:authenticated"}, []string{"dev", "system:authenticated"}},
+		{"service account", "system:serviceaccount:shop:deployer", nil, []string{"system:authenticated", "system:serviceaccounts", "system:serviceaccounts:shop"}},
+		{"malformed service account", "system:serviceaccount:deployer", nil, []string{"system:authenticated"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := GetSubjectGroups(test.user, test.groups); !reflect.DeepEqual(actual, test.expected) {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+			}
+		})
+	}
+}
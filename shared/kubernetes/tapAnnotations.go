@@ -0,0 +1,52 @@
+package kubernetes
+
+import (
+	"regexp"
+	"sort"
+
+	"github.com/up9inc/mizu/shared"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// GetTapAnnotation returns the mizu.io/tap value of the object, empty when it's missing or neither "true" nor "false"
+func GetTapAnnotation(objectMeta metav1.ObjectMeta) string {
+	switch value := objectMeta.Annotations[AnnotationTap]; value {
+	case AnnotationTapOptIn, AnnotationTapOptOut:
+		return value
+	default:
+		return ""
+	}
+}
+
+// IsTapTarget decides if a pod is tapped, the annotation of the pod wins over the annotation of its namespace and both win over the regex
+// and the label selector
+func IsTapTarget(pod *core.Pod, namespaceAnnotation string, podRegex *regexp.Regexp, labelSelector labels.Selector) bool {
+	if podAnnotation := GetTapAnnotation(pod.ObjectMeta); podAnnotation != "" {
+		return podAnnotation == AnnotationTapOptIn
+	}
+
+	if namespaceAnnotation == AnnotationTapOptOut {
+		return false
+	}
+
+	return podRegex.MatchString(pod.Name) && labelSelector.Matches(labels.Set(pod.Labels))
+}
+
+// GetTapAnnotationsNamespaces returns the namespaces to list for tapping, the target namespaces together with the namespaces that opted in
+func GetTapAnnotationsNamespaces(targetNamespaces []string, namespaceAnnotations map[string]string) []string {
+	if shared.Contains(targetNamespaces, K8sAllNamespaces) {
+		return []string{K8sAllNamespaces}
+	}
+
+	optedInNamespaces := make([]string, 0)
+	for namespace, annotation := range namespaceAnnotations {
+		if annotation == AnnotationTapOptIn && !shared.Contains(targetNamespaces, namespace) {
+			optedInNamespaces = append(optedInNamespaces, namespace)
+		}
+	}
+	sort.Strings(optedInNamespaces)
+
+	return append(append([]string{}, targetNamespaces...), optedInNamespaces...)
+}
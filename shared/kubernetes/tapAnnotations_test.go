@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+func newAnnotatedPod(name string, tapAnnotation string) *core.Pod {
+	pod := &core.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}}
+	if tapAnnotation != "" {
+		pod.Annotations = map[string]string{AnnotationTap: tapAnnotation}
+	}
+	return pod
+}
+
+func TestIsTapTarget(t *testing.T) {
+	podRegex := regexp.MustCompile("^front")
+
+	tests := []struct {
+		name                string
+		pod                 *core.Pod
+		namespaceAnnotation string
+		expected            bool
+	}{
+		{"regex match", newAnnotatedPod("front-1", ""), "", true},
+		{"regex mismatch", newAnnotatedPod("back-1", ""), "", false},
+		{"pod opt in", newAnnotatedPod("back-1", AnnotationTapOptIn), "", true},
+		{"pod opt out", newAnnotatedPod("front-1", AnnotationTapOptOut), "", false},
+		{"namespace opt out", newAnnotatedPod("front-1", ""), AnnotationTapOptOut, false},
+		{"pod opt in wins over namespace opt out", newAnnotatedPod("back-1", AnnotationTapOptIn), AnnotationTapOptOut, true},
+		{"pod opt out wins over namespace opt in", newAnnotatedPod("front-1", AnnotationTapOptOut), AnnotationTapOptIn, false},
+		{"namespace opt in keeps the regex", newAnnotatedPod("back-1", ""), AnnotationTapOptIn, false},
+		{"invalid annotation is ignored", newAnnotatedPod("back-1", "yes"), "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := IsTapTarget(test.pod, test.namespaceAnnotation, podRegex, labels.Everything()); actual != test.expected {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestIsTapTargetLabelSelector(t *testing.T) {
+	podRegex := regexp.MustCompile(".*")
+	labelSelector, _ := labels.Parse("app=frontend,tier in (web, edge)")
+
+	frontEnd := newAnnotatedPod("front-end-7d9f8b6c5-x2x4z", "")
+	frontEnd.Labels = map[string]string{"app": "frontend", "tier": "web"}
+	if !IsTapTarget(frontEnd, "", podRegex, labelSelector) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", true, false)
+	}
+
+	cart := newAnnotatedPod("cart-5c6d7-k2j4h", "")
+	cart.Labels = map[string]string{"app": "cart", "tier": "web"}
+	if IsTapTarget(cart, "", podRegex, labelSelector) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", false, true)
+	}
+
+	// the annotation wins over the label selector like over the regex
+	cart.Annotations = map[string]string{AnnotationTap: AnnotationTapOptIn}
+	if !IsTapTarget(cart, "", podRegex, labelSelector) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", true, false)
+	}
+}
+
+func TestGetTapAnnotationsNamespaces(t *testing.T) {
+	namespaceAnnotations := map[string]string{
+		"payments": AnnotationTapOptIn,
+		"default":  AnnotationTapOptIn,
+		"billing":  AnnotationTapOptIn,
+		"internal": AnnotationTapOptOut,
+	}
+
+	actual := GetTapAnnotationsNamespaces([]string{"default"}, namespaceAnnotations)
+	expected := []string{"default", "billing", "payments"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+
+	actual = GetTapAnnotationsNamespaces([]string{K8sAllNamespaces}, namespaceAnnotations)
+	expected = []string{K8sAllNamespaces}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
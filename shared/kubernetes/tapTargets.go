@@ -0,0 +1,221 @@
+package kubernetes
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/up9inc/mizu/shared"
+	"gopkg.in/yaml.v3"
+	apps "k8s.io/api/apps/v1"
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	TapTargetKindPod         = "Pod"
+	TapTargetKindDeployment  = "Deployment"
+	TapTargetKindReplicaSet  = "ReplicaSet"
+	TapTargetKindStatefulSet = "StatefulSet"
+	TapTargetKindDaemonSet   = "DaemonSet"
+	TapTargetKindJob         = "Job"
+	TapTargetKindService     = "Service"
+)
+
+// tapTargetKinds maps the kinds and the resource names kubectl accepts, like "deploy" or "deployment.apps" from
+// kubectl get -o name, to the kinds of the targets
+var tapTargetKinds = map[string]string{
+	"pod": TapTargetKindPod, "pods": TapTargetKindPod, "po": TapTargetKindPod,
+	"deployment": TapTargetKindDeployment, "deployments": TapTargetKindDeployment, "deploy": TapTargetKindDeployment, "deployment.apps": TapTargetKindDeployment,
+	"replicaset": TapTargetKindReplicaSet, "replicasets": TapTargetKindReplicaSet, "rs": TapTargetKindReplicaSet, "replicaset.apps": TapTargetKindReplicaSet,
+	"statefulset": TapTargetKindStatefulSet, "statefulsets": TapTargetKindStatefulSet, "sts": TapTargetKindStatefulSet, "statefulset.apps": TapTargetKindStatefulSet,
+	"daemonset": TapTargetKindDaemonSet, "daemonsets": TapTargetKindDaemonSet, "ds": TapTargetKindDaemonSet, "daemonset.apps": TapTargetKindDaemonSet,
+	"job": TapTargetKindJob, "jobs": TapTargetKindJob, "job.batch": TapTargetKindJob,
+	"service": TapTargetKindService, "services": TapTargetKindService, "svc": TapTargetKindService,
+}
+
+// TapTarget is a pod, or the workload whose pods are tapped, listed explicitly instead of matched by the regex, no
+// Namespace is the namespace of the kube context and no Kind is a pod
+type TapTarget struct {
+	Namespace string `yaml:"namespace,omitempty" json:"namespace,omitempty"`
+	Kind      string `yaml:"kind,omitempty" json:"kind,omitempty"`
+	Name      string `yaml:"name" json:"name"`
+
+	// podSelector is the selector of a service target, it's resolved by the provider every time the pods are listed
+	podSelector labels.Selector
+}
+
+// tapTargetsFile is the format of --targets-file:
+//
+//	targets:
+//	  - name: front-end-7d9f8b6c5-x2x4z
+//	  - namespace: shop
+//	    kind: Deployment
+//	    name: cart
+type tapTargetsFile struct {
+	Targets []TapTarget `yaml:"targets"`
+}
+
+func (target TapTarget) String() string {
+	return fmt.Sprintf("%s/%s/%s", target.Namespace, strings.ToLower(target.Kind), target.Name)
+}
+
+// Matches decides if pod belongs to the target, the pods of a deployment are matched by the name of their replica set,
+// which is the name of the deployment followed by the pod template hash, and the pods of a service by its selector
+func (target TapTarget) Matches(pod *core.Pod) bool {
+	if pod.Namespace != target.Namespace {
+		return false
+	}
+
+	if target.Kind == TapTargetKindPod {
+		return pod.Name == target.Name
+	}
+
+	if target.Kind == TapTargetKindService {
+		return target.podSelector != nil && target.podSelector.Matches(labels.Set(pod.Labels))
+	}
+
+	owner := metav1.GetControllerOf(pod)
+	if owner == nil {
+		return false
+	}
+
+	if target.Kind == TapTargetKindDeployment {
+		podTemplateHash, ok := pod.Labels[apps.DefaultDeploymentUniqueLabelKey]
+		return ok && owner.Kind == TapTargetKindReplicaSet && owner.Name == fmt.Sprintf("%s-%s", target.Name, podTemplateHash)
+	}
+
+	return owner.Kind == target.Kind && owner.Name == target.Name
+}
+
+func (target TapTarget) normalized() (TapTarget, error) {
+	if target.Name == "" {
+		return target, errors.New("a target has no name")
+	}
+
+	if target.Kind == "" {
+		target.Kind = TapTargetKindPod
+	} else if kind, ok := tapTargetKinds[strings.ToLower(target.Kind)]; ok {
+		target.Kind = kind
+	} else {
+		return target, fmt.Errorf("target %s has an unsupported kind %s, the kinds are %s, %s, %s, %s, %s, %s and %s", target.Name, target.Kind, TapTargetKindPod, TapTargetKindDeployment, TapTargetKindReplicaSet, TapTargetKindStatefulSet, TapTargetKindDaemonSet, TapTargetKindJob, TapTargetKindService)
+	}
+
+	return target, nil
+}
+
+// ParseTapTargetsFile parses the targets of a --targets-file, the unknown fields are rejected so a typo doesn't tap a
+// pod of the wrong namespace
+func ParseTapTargetsFile(data []byte) ([]TapTarget, error) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var targetsFile tapTargetsFile
+	if err := decoder.Decode(&targetsFile); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	return NormalizeTapTargets(targetsFile.Targets)
+}
+
+// NormalizeTapTargets validates the targets and returns them with the kinds Matches expects, like Pod for no kind
+func NormalizeTapTargets(targets []TapTarget) ([]TapTarget, error) {
+	normalizedTargets := make([]TapTarget, 0, len(targets))
+	for _, target := range targets {
+		normalizedTarget, err := target.normalized()
+		if err != nil {
+			return nil, err
+		}
+		normalizedTargets = append(normalizedTargets, normalizedTarget)
+	}
+
+	return normalizedTargets, nil
+}
+
+// ParseTapTargetReference parses a target like "front-end-7d9f", "deployment/cart" or "shop/deployment.apps/cart", the
+// output of kubectl get -o name is a list of such references
+func ParseTapTargetReference(reference string) (TapTarget, error) {
+	var target TapTarget
+	switch parts := strings.Split(reference, "/"); len(parts) {
+	case 1:
+		target = TapTarget{Name: parts[0]}
+	case 2:
+		target = TapTarget{Kind: parts[0], Name: parts[1]}
+	case 3:
+		target = TapTarget{Namespace: parts[0], Kind: parts[1], Name: parts[2]}
+	default:
+		return target, fmt.Errorf("%s isn't a target, the targets are [[namespace/]kind/]name", reference)
+	}
+
+	if target.Kind == "" && strings.Contains(reference, "/") {
+		return target, fmt.Errorf("%s isn't a target, the targets are [[namespace/]kind/]name", reference)
+	}
+
+	return target.normalized()
+}
+
+// ParseTapTargetReferences parses the references separated by whitespace, like the lines other tools print
+func ParseTapTargetReferences(data []byte) ([]TapTarget, error) {
+	targets := make([]TapTarget, 0)
+	for _, reference := range strings.Fields(string(data)) {
+		target, err := ParseTapTargetReference(reference)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	return targets, nil
+}
+
+// ResolveTapTargetsNamespace returns the targets with namespace set for the ones that have none
+func ResolveTapTargetsNamespace(targets []TapTarget, namespace string) []TapTarget {
+	resolvedTargets := make([]TapTarget, 0, len(targets))
+	for _, target := range targets {
+		if target.Namespace == "" {
+			target.Namespace = namespace
+		}
+		resolvedTargets = append(resolvedTargets, target)
+	}
+
+	return resolvedTargets
+}
+
+// GetTapTargetsNamespaces returns the namespaces of the targets, which are the namespaces to list for tapping
+func GetTapTargetsNamespaces(targets []TapTarget) []string {
+	namespaces := make([]string, 0)
+	for _, target := range targets {
+		namespaces = append(namespaces, target.Namespace)
+	}
+
+	namespaces = shared.Unique(namespaces)
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// withServicePodSelector returns the target with the selector of its service, a service without a selector, like
+// the ones of external endpoints, has no pods
+func (target TapTarget) withServicePodSelector(service *core.Service) TapTarget {
+	if service == nil || len(service.Spec.Selector) == 0 {
+		target.podSelector = labels.Nothing()
+	} else {
+		target.podSelector = labels.SelectorFromSet(service.Spec.Selector)
+	}
+
+	return target
+}
+
+// IsTapTargetsMatch decides if a pod belongs to any of the targets
+func IsTapTargetsMatch(pod *core.Pod, targets []TapTarget) bool {
+	for _, target := range targets {
+		if target.Matches(pod) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,123 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+
+	core "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newOwnedPod(namespace string, name string, ownerKind string, ownerName string, labels map[string]string) *core.Pod {
+	isController := true
+	return &core.Pod{ObjectMeta: metav1.ObjectMeta{
+		Namespace:       namespace,
+		Name:            name,
+		Labels:          labels,
+		OwnerReferences: []metav1.OwnerReference{{Kind: ownerKind, Name: ownerName, Controller: &isController}},
+	}}
+}
+
+func TestParseTapTargetsFile(t *testing.T) {
+	data := []byte(`
+targets:
+  - name: front-end-7d9f
+  - namespace: shop
+    kind: deployment
+    name: cart
+`)
+
+	targets, err := ParseTapTargetsFile(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []TapTarget{{Kind: TapTargetKindPod, Name: "front-end-7d9f"}, {Namespace: "shop", Kind: TapTargetKindDeployment, Name: "cart"}}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, targets)
+	}
+}
+
+func TestParseTapTargetsFileInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"unknown field", "targets:\n  - namespce: shop\n    name: cart\n"},
+		{"missing name", "targets:\n  - namespace: shop\n"},
+		{"unsupported kind", "targets:\n  - kind: CronJob\n    name: nightly\n"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := ParseTapTargetsFile([]byte(test.data)); err == nil {
+				t.Errorf("unexpected result - expected an invalid targets file error")
+			}
+		})
+	}
+}
+
+func TestParseTapTargetReferences(t *testing.T) {
+	targets, err := ParseTapTargetReferences([]byte("front-end-7d9f\npod/checkout-5c4b\ndeployment.apps/cart\nshop/sts/db\nsvc/cart\n"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []TapTarget{
+		{Kind: TapTargetKindPod, Name: "front-end-7d9f"},
+		{Kind: TapTargetKindPod, Name: "checkout-5c4b"},
+		{Kind: TapTargetKindDeployment, Name: "cart"},
+		{Namespace: "shop", Kind: TapTargetKindStatefulSet, Name: "db"},
+		{Kind: TapTargetKindService, Name: "cart"},
+	}
+	if !reflect.DeepEqual(targets, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, targets)
+	}
+
+	for _, reference := range []string{"a/b/c/d", "/cart", "cronjob/cart"} {
+		if _, err := ParseTapTargetReference(reference); err == nil {
+			t.Errorf("unexpected result - expected %s to be an invalid target", reference)
+		}
+	}
+}
+
+func TestTapTargetMatches(t *testing.T) {
+	deploymentPod := newOwnedPod("shop", "cart-6b7c8d9f-x2x4z", "ReplicaSet", "cart-6b7c8d9f", map[string]string{"pod-template-hash": "6b7c8d9f"})
+	statefulSetPod := newOwnedPod("shop", "db-0", "StatefulSet", "db", nil)
+	service := &core.Service{Spec: core.ServiceSpec{Selector: map[string]string{"pod-template-hash": "6b7c8d9f"}}}
+
+	tests := []struct {
+		name     string
+		target   TapTarget
+		pod      *core.Pod
+		expected bool
+	}{
+		{"pod", TapTarget{Namespace: "shop", Kind: TapTargetKindPod, Name: "db-0"}, statefulSetPod, true},
+		{"pod of another namespace", TapTarget{Namespace: "default", Kind: TapTargetKindPod, Name: "db-0"}, statefulSetPod, false},
+		{"deployment", TapTarget{Namespace: "shop", Kind: TapTargetKindDeployment, Name: "cart"}, deploymentPod, true},
+		{"deployment with a longer name", TapTarget{Namespace: "shop", Kind: TapTargetKindDeployment, Name: "cart-6b7c"}, deploymentPod, false},
+		{"replica set", TapTarget{Namespace: "shop", Kind: TapTargetKindReplicaSet, Name: "cart-6b7c8d9f"}, deploymentPod, true},
+		{"stateful set", TapTarget{Namespace: "shop", Kind: TapTargetKindStatefulSet, Name: "db"}, statefulSetPod, true},
+		{"another kind", TapTarget{Namespace: "shop", Kind: TapTargetKindDaemonSet, Name: "db"}, statefulSetPod, false},
+		{"service", TapTarget{Namespace: "shop", Kind: TapTargetKindService, Name: "cart"}.withServicePodSelector(service), deploymentPod, true},
+		{"service selecting other pods", TapTarget{Namespace: "shop", Kind: TapTargetKindService, Name: "cart"}.withServicePodSelector(service), statefulSetPod, false},
+		{"service without a selector", TapTarget{Namespace: "shop", Kind: TapTargetKindService, Name: "cart"}.withServicePodSelector(nil), deploymentPod, false},
+		{"unresolved service", TapTarget{Namespace: "shop", Kind: TapTargetKindService, Name: "cart"}, deploymentPod, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := test.target.Matches(test.pod); actual != test.expected {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGetTapTargetsNamespaces(t *testing.T) {
+	targets := ResolveTapTargetsNamespace([]TapTarget{{Name: "front-end"}, {Namespace: "shop", Name: "cart"}, {Namespace: "shop", Name: "db"}}, "default")
+
+	if expected, actual := []string{"default", "shop"}, GetTapTargetsNamespaces(targets); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("unexpected result - expected: %v, actual: %v", expected, actual)
+	}
+}
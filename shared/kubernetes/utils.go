@@ -1,6 +1,7 @@
 package kubernetes
 
 import (
+	"reflect"
 	"regexp"
 
 	"github.com/up9inc/mizu/shared"
@@ -48,7 +49,7 @@ func getMinimizedContainerStatuses(fullPod core.Pod) []v1.ContainerStatus {
 	return result
 }
 
-func excludeMizuPods(pods []core.Pod) []core.Pod {
+func ExcludeMizuPods(pods []core.Pod) []core.Pod {
 	mizuPrefixRegex := regexp.MustCompile("^" + MizuResourcesPrefix)
 
 	nonMizuPods := make([]core.Pod, 0)
@@ -68,6 +69,23 @@ func getPodArrayDiff(oldPods []core.Pod, newPods []core.Pod) (added []core.Pod,
 	return added, removed
 }
 
+// getUpdatedPods returns the pods of newPods whose address or containers changed since oldPods, like a pending pod that
+// was given an ip address, the tappers need them to capture the pod
+func getUpdatedPods(oldPods []core.Pod, newPods []core.Pod) []core.Pod {
+	updatedPods := make([]core.Pod, 0)
+	for _, newPod := range newPods {
+		for _, oldPod := range oldPods {
+			if newPod.UID == oldPod.UID {
+				if !reflect.DeepEqual(getMinimizedPod(newPod), getMinimizedPod(oldPod)) {
+					updatedPods = append(updatedPods, newPod)
+				}
+				break
+			}
+		}
+	}
+	return updatedPods
+}
+
 //returns pods present in pods1 array and missing in pods2 array
 func getMissingPods(pods1 []core.Pod, pods2 []core.Pod) []core.Pod {
 	missingPods := make([]core.Pod, 0)
@@ -38,6 +38,15 @@ func (we *WatchEvent) ToPod() (*corev1.Pod, error) {
 	return pod, nil
 }
 
+func (we *WatchEvent) ToNamespace() (*corev1.Namespace, error) {
+	namespace, ok := we.Object.(*corev1.Namespace)
+	if !ok {
+		return nil, &InvalidObjectType{RequestedType: reflect.TypeOf(namespace)}
+	}
+
+	return namespace, nil
+}
+
 func (we *WatchEvent) ToEvent() (*eventsv1.Event, error) {
 	event, ok := we.Object.(*eventsv1.Event)
 	if !ok {
@@ -0,0 +1,61 @@
+package shared
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+const (
+	LifecycleEventSessionStarted   = "session.started"
+	LifecycleEventSessionStopped   = "session.stopped"
+	LifecycleEventSnapshotCreated  = "snapshot.created"
+	LifecycleEventStorageThreshold = "storage.threshold"
+	LifecycleEventTapperFailed     = "tapper.failed"
+
+	// LifecycleSignatureHeader holds the hex HMAC-SHA256 of the payload, keyed by the secret of the webhooks
+	LifecycleSignatureHeader = "X-Mizu-Signature"
+	LifecycleEventHeader     = "X-Mizu-Event"
+)
+
+var LifecycleEvents = []string{
+	LifecycleEventSessionStarted,
+	LifecycleEventSessionStopped,
+	LifecycleEventSnapshotCreated,
+	LifecycleEventStorageThreshold,
+	LifecycleEventTapperFailed,
+}
+
+// LifecycleWebhooksConfig posts the lifecycle events of mizu to the webhook urls, like a session starting or a tapper
+// failing, unlike the sinks it's never called per entry. Events limits the posted event types, all are posted when
+// it's empty, and StorageThresholdPercent is the share of the database size limit that fires storage.threshold
+type LifecycleWebhooksConfig struct {
+	Urls                    []string `yaml:"urls" json:"urls"`
+	Events                  []string `yaml:"events" json:"events"`
+	Secret                  string   `yaml:"secret,omitempty" json:"secret"`
+	StorageThresholdPercent int      `yaml:"storage-threshold-percent" json:"storageThresholdPercent" default:"80"`
+}
+
+func (config *LifecycleWebhooksConfig) IsEnabled(eventType string) bool {
+	if len(config.Urls) == 0 {
+		return false
+	}
+
+	return len(config.Events) == 0 || Contains(config.Events, eventType)
+}
+
+// LifecycleEvent is the payload posted to the webhooks, Data depends on the event type
+type LifecycleEvent struct {
+	Type      string      `json:"type"`
+	Timestamp int64       `json:"timestamp"`
+	Namespace string      `json:"namespace"`
+	Cluster   string      `json:"cluster,omitempty"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// SignLifecyclePayload returns the value of the signature header of a payload
+func SignLifecyclePayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -24,6 +24,9 @@ const (
 	WebSocketMessageTypeQueryMetadata WebSocketMessageType = "queryMetadata"
 	WebSocketMessageTypeStartTime     WebSocketMessageType = "startTime"
 	WebSocketMessageTypeTapConfig     WebSocketMessageType = "tapConfig"
+	WebSocketMessageTypeTapperDebug   WebSocketMessageType = "tapperDebug"
+	WebSocketMessageTypeHeartbeat     WebSocketMessageType = "heartbeat"
+	WebSocketMessageTypeStreamConfig  WebSocketMessageType = "streamConfig"
 )
 
 type Resources struct {
@@ -33,25 +36,249 @@ type Resources struct {
 	MemoryRequests string `yaml:"memory-requests" default:"50Mi"`
 }
 
+// TapperSchedulingConfig schedules the tappers on the nodes of the tapped pods, by default they tolerate every
+// NoSchedule and NoExecute taint. With TolerateAll false they tolerate the Tolerations, and with AutoTolerate also the
+// taints of the nodes of the tapped pods. The tappers run only on the nodes matching the NodeSelector
+type TapperSchedulingConfig struct {
+	TolerateAll  bool               `yaml:"tolerate-all" json:"tolerateAll" default:"true"`
+	AutoTolerate bool               `yaml:"auto-tolerate" json:"autoTolerate" default:"false"`
+	Tolerations  []TapperToleration `yaml:"tolerations" json:"tolerations,omitempty"`
+	NodeSelector map[string]string  `yaml:"node-selector" json:"nodeSelector,omitempty"`
+}
+
+// RelayConfig makes the tappers of the node pools other than the pool of the api server connect to it through a relay
+// pod of their own pool, for clusters whose network doesn't let the nodes of a pool reach the pods of another, only the
+// relay pods need a route to the api server. The pool of a node is its PoolLabel, or the node pool label of the cloud
+// provider when it's empty
+type RelayConfig struct {
+	Enabled   bool   `yaml:"enabled" json:"enabled" default:"false"`
+	PoolLabel string `yaml:"pool-label" json:"poolLabel,omitempty"`
+}
+
+// TapperToleration is a toleration of the tappers, Operator is Equal, the default, or Exists and an empty Effect
+// tolerates every effect of the taint
+type TapperToleration struct {
+	Key      string `yaml:"key" json:"key"`
+	Operator string `yaml:"operator" json:"operator,omitempty"`
+	Value    string `yaml:"value" json:"value,omitempty"`
+	Effect   string `yaml:"effect" json:"effect,omitempty"`
+}
+
 type MizuAgentConfig struct {
-	MaxDBSizeBytes         int64         `json:"maxDBSizeBytes"`
-	InsertionFilter        string        `json:"insertionFilter"`
-	AgentImage             string        `json:"agentImage"`
-	PullPolicy             string        `json:"pullPolicy"`
-	LogLevel               logging.Level `json:"logLevel"`
-	TapperResources        Resources     `json:"tapperResources"`
-	MizuResourcesNamespace string        `json:"mizuResourceNamespace"`
-	AgentDatabasePath      string        `json:"agentDatabasePath"`
-	ServiceMap             bool          `json:"serviceMap"`
-	OAS                    bool          `json:"oas"`
-	Telemetry              bool          `json:"telemetry"`
-	Elastic                ElasticConfig `json:"elastic"`
+	MaxDBSizeBytes              int64                   `json:"maxDBSizeBytes"`
+	InsertionFilter             string                  `json:"insertionFilter"`
+	AgentImage                  string                  `json:"agentImage"`
+	PullPolicy                  string                  `json:"pullPolicy"`
+	LogLevel                    logging.Level           `json:"logLevel"`
+	TapperResources             Resources               `json:"tapperResources"`
+	MizuResourcesNamespace      string                  `json:"mizuResourceNamespace"`
+	AgentDatabasePath           string                  `json:"agentDatabasePath"`
+	ServiceMap                  bool                    `json:"serviceMap"`
+	OAS                         bool                    `json:"oas"`
+	Stats                       bool                    `json:"stats"`
+	Metrics                     bool                    `json:"metrics"`
+	Insights                    bool                    `json:"insights"`
+	Telemetry                   bool                    `json:"telemetry"`
+	Elastic                     ElasticConfig           `json:"elastic"`
+	Kafka                       KafkaConfig             `json:"kafka"`
+	Sinks                       []SinkConfig            `json:"sinks"`
+	Archive                     ArchiveConfig           `json:"archive"`
+	QueryCache                  QueryCacheConfig        `json:"queryCache"`
+	QueryLimits                 QueryLimitsConfig       `json:"queryLimits"`
+	SocketKeepAlive             SocketKeepAliveConfig   `json:"socketKeepAlive"`
+	MaxExportQueueDiskSizeBytes int64                   `json:"maxExportQueueDiskSizeBytes"`
+	MaxStreamBytesPerSec        int64                   `json:"maxStreamBytesPerSec"`
+	EntryIdScheme               string                  `json:"entryIdScheme"`
+	Mirror                      MirrorConfig            `json:"mirror"`
+	Issues                      IssuesConfig            `json:"issues"`
+	DeploymentMarkers           bool                    `json:"deploymentMarkers"`
+	KubernetesEvents            bool                    `json:"kubernetesEvents"`
+	Timestamps                  TimestampConfig         `json:"timestamps"`
+	DnsResolution               bool                    `json:"dnsResolution"`
+	TagHeader                   string                  `json:"tagHeader"`
+	Summary                     SummaryConfig           `json:"summary"`
+	TapperAuthentication        bool                    `json:"tapperAuthentication"`
+	Operator                    bool                    `json:"operator"`
+	Provenance                  ProvenanceConfig        `json:"provenance"`
+	Enrichment                  EnrichmentConfig        `json:"enrichment"`
+	LifecycleWebhooks           LifecycleWebhooksConfig `json:"lifecycleWebhooks"`
+	ChatOps                     ChatOpsConfig           `json:"chatOps"`
+	Watermarks                  WatermarksConfig        `json:"watermarks"`
+	Retention                   RetentionConfig         `json:"retention"`
+	Session                     SessionMetadata         `json:"session"`
+	Cluster                     string                  `json:"cluster"`
 }
 
+// SessionMetadata records who started the session and how, for auditing the captured traffic, User is the user of
+// the kube config context, Cluster is the configured cluster name or the fingerprint of the cluster and StartTime is
+// in unix milliseconds
+type SessionMetadata struct {
+	Id            string            `json:"id,omitempty"`
+	Cluster       string            `json:"cluster"`
+	User          string            `json:"user"`
+	Hostname      string            `json:"hostname"`
+	CliVersion    string            `json:"cliVersion"`
+	CliCommitHash string            `json:"cliCommitHash"`
+	StartTime     int64             `json:"startTime"`
+	Command       string            `json:"command"`
+	Flags         map[string]string `json:"flags"`
+}
+
+// ElasticConfig configures bulk indexing the http entries into Elasticsearch or OpenSearch. Index is the write alias,
+// the indices behind it share an index template and roll over by the lifecycle policy once they reach
+// RolloverMaxSize or RolloverMaxAge, and are deleted DeleteAfter they rolled over when it's set, like "30d". An Auth of
+// aws signs the requests with the role of the service account of the api server, like Amazon OpenSearch expects, in
+// place of the User and the Password
 type ElasticConfig struct {
-	User     string `yaml:"user,omitempty" default:"" readonly:""`
-	Password string `yaml:"password,omitempty" default:"" readonly:""`
-	Url      string `yaml:"url,omitempty" default:"" readonly:""`
+	User            string `yaml:"user,omitempty" default:"" readonly:""`
+	Password        string `yaml:"password,omitempty" default:"" readonly:""`
+	Url             string `yaml:"url,omitempty" default:"" readonly:""`
+	Auth            string `yaml:"auth,omitempty" default:""`
+	Transform       string `yaml:"transform,omitempty" default:""`
+	Index           string `yaml:"index" default:"mizu-traffic"`
+	BulkSize        int    `yaml:"bulk-size" default:"500"`
+	RolloverMaxSize string `yaml:"rollover-max-size" default:"10gb"`
+	RolloverMaxAge  string `yaml:"rollover-max-age" default:"1d"`
+	DeleteAfter     string `yaml:"delete-after,omitempty" default:""`
+}
+
+const (
+	KafkaFormatJson = "json"
+	KafkaFormatAvro = "avro"
+
+	KafkaSaslMechanismPlain = "plain"
+)
+
+// KafkaConfig configures publishing every stored entry to a kafka topic, keyed by the destination service. Format is
+// json or avro, the avro messages use the single object encoding so consumers can resolve the schema by its fingerprint.
+// An Auth authenticates with the cloud identity of the service account of the api server in place of the sasl
+// mechanism, the role by the AWS_MSK_IAM mechanism of MSK for aws, or its oauth tokens by the OAUTHBEARER mechanism for
+// gcp and azure, like of Event Hubs
+type KafkaConfig struct {
+	Brokers       []string `yaml:"brokers" json:"brokers"`
+	Topic         string   `yaml:"topic,omitempty" json:"topic"`
+	Format        string   `yaml:"format" json:"format" default:"json"`
+	SaslMechanism string   `yaml:"sasl-mechanism,omitempty" json:"saslMechanism"`
+	User          string   `yaml:"user,omitempty" json:"user" readonly:""`
+	Password      string   `yaml:"password,omitempty" json:"password" readonly:""`
+	Tls           bool     `yaml:"tls" json:"tls" default:"false"`
+	Auth          string   `yaml:"auth,omitempty" json:"auth"`
+}
+
+const (
+	SinkTypeElastic = "elastic"
+	SinkTypeKafka   = "kafka"
+	SinkTypeWebhook = "webhook"
+	SinkTypeSlack   = "slack"
+
+	SinkDeliveryAtLeastOnce = "at-least-once"
+	SinkDeliveryBestEffort  = "best-effort"
+)
+
+// SinkConfig configures a sink of the fan-out, every sink streams the stored entries matching its Query on its own, so
+// a slow or unavailable destination doesn't hold back the others. The entries are shaped by Transform before they're
+// delivered, for a slack sink it yields the text of the message. The at-least-once sinks spill the entries to disk
+// while their destination is down, the best-effort sinks drop them once their memory queue is full. A webhook sink
+// posts every entry as a json object to WebhookUrl with the WebhookHeaders, like the token of a SIEM, or up to
+// WebhookBatchSize waiting entries at once as a json array when it's greater than 1
+type SinkConfig struct {
+	Name             string            `yaml:"name" json:"name"`
+	Type             string            `yaml:"type" json:"type"`
+	Query            string            `yaml:"query,omitempty" json:"query"`
+	Transform        string            `yaml:"transform,omitempty" json:"transform"`
+	Delivery         string            `yaml:"delivery" json:"delivery" default:"at-least-once"`
+	Elastic          ElasticConfig     `yaml:"elastic,omitempty" json:"elastic"`
+	Kafka            KafkaConfig       `yaml:"kafka,omitempty" json:"kafka"`
+	WebhookUrl       string            `yaml:"webhook-url,omitempty" json:"webhookUrl"`
+	WebhookHeaders   map[string]string `yaml:"webhook-headers,omitempty" json:"webhookHeaders"`
+	WebhookBatchSize int               `yaml:"webhook-batch-size" json:"webhookBatchSize" default:"1"`
+}
+
+// ArchiveConfig configures archiving the stored entries to a bucket every IntervalSec, as archives of the
+// archiveformat package under the prefix of Url, like s3://bucket/prefix or gs://bucket/prefix. Endpoint is for S3
+// compatible storages like minio, gcs is called with the HMAC keys of a service account. An Auth of aws or gcp
+// authenticates with the cloud identity of the service account of the api server in place of the keys. Archives
+// older than RetentionDays are deleted, 0 keeps them
+type ArchiveConfig struct {
+	Url             string `yaml:"url,omitempty" json:"url"`
+	Endpoint        string `yaml:"endpoint,omitempty" json:"endpoint"`
+	Region          string `yaml:"region,omitempty" json:"region"`
+	Auth            string `yaml:"auth,omitempty" json:"auth"`
+	AccessKeyId     string `yaml:"access-key-id,omitempty" json:"accessKeyId" readonly:""`
+	SecretAccessKey string `yaml:"secret-access-key,omitempty" json:"secretAccessKey" readonly:""`
+	IntervalSec     int    `yaml:"interval-sec" json:"intervalSec" default:"300"`
+	RetentionDays   int    `yaml:"retention-days" json:"retentionDays" default:"0"`
+}
+
+// CloudIdentityConfig binds the service account of the api server to a cloud identity, which the destinations with a
+// cloud auth are authenticated as: an IAM role by IRSA on EKS, a google service account by workload identity on GKE or
+// the client id of a managed identity by workload identity on AKS. The identity has to trust the service account
+type CloudIdentityConfig struct {
+	AwsRoleArn        string `yaml:"aws-role-arn,omitempty" json:"awsRoleArn"`
+	GcpServiceAccount string `yaml:"gcp-service-account,omitempty" json:"gcpServiceAccount"`
+	AzureClientId     string `yaml:"azure-client-id,omitempty" json:"azureClientId"`
+}
+
+// QueryCacheConfig configures caching the results of the recent queries of the entries, up to MaxResults pages for
+// TtlSec each, a MaxResults of 0 disables the cache
+type QueryCacheConfig struct {
+	MaxResults int `yaml:"max-results" json:"maxResults" default:"128"`
+	TtlSec     int `yaml:"ttl-sec" json:"ttlSec" default:"10"`
+}
+
+// QueryLimitsConfig limits the queries of the entries to MaxConcurrent queries at a time, of up to MaxTimeoutSec
+// each, 0 doesn't limit them
+type QueryLimitsConfig struct {
+	MaxConcurrent int `yaml:"max-concurrent" json:"maxConcurrent" default:"8"`
+	MaxTimeoutSec int `yaml:"max-timeout-sec" json:"maxTimeoutSec" default:"30"`
+}
+
+// SocketKeepAliveConfig keeps the idle websockets of the tappers and of the browsers open through proxies and NATs,
+// they're pinged every PingIntervalSec and closed when nothing, not even a pong, was received for IdleTimeoutSec, 0
+// disables either
+type SocketKeepAliveConfig struct {
+	PingIntervalSec int `yaml:"ping-interval-sec" json:"pingIntervalSec" default:"20"`
+	IdleTimeoutSec  int `yaml:"idle-timeout-sec" json:"idleTimeoutSec" default:"60"`
+}
+
+// ArchiveReplayResponse is the count of the entries of an archive the API server stored, and of the invalid lines it
+// skipped, FormatVersion is the version of the archive
+type ArchiveReplayResponse struct {
+	Entries       int `json:"entries"`
+	Skipped       int `json:"skipped"`
+	FormatVersion int `json:"formatVersion"`
+}
+
+// MirrorConfig configures forwarding a sampled copy of the captured http requests to a staging service
+type MirrorConfig struct {
+	Url               string  `yaml:"url,omitempty" json:"url"`
+	SampleRate        float64 `yaml:"sample-rate" json:"sampleRate" default:"0.1"`
+	MaxRequestsPerSec int     `yaml:"max-requests-per-second" json:"maxRequestsPerSec" default:"10"`
+}
+
+// IssuesConfig configures forwarding an exemplar of every new 5xx error signature to Sentry or to a webhook, like
+// the incoming webhooks of Jira automation, LinkUrl is the address the links back to the entries start with
+type IssuesConfig struct {
+	SentryDsn    string `yaml:"sentry-dsn,omitempty" json:"sentryDsn"`
+	WebhookUrl   string `yaml:"webhook-url,omitempty" json:"webhookUrl"`
+	LinkUrl      string `yaml:"link-url,omitempty" json:"linkUrl"`
+	MaxPerMinute int    `yaml:"max-per-minute" json:"maxPerMinute" default:"10"`
+}
+
+// EnrichmentConfig lists the labels and annotations copied from the pods, services and namespaces of the traffic
+// onto the metadata of the source and destination of its entries, like dst.metadata["version"]
+type EnrichmentConfig struct {
+	Labels      []string `yaml:"labels" json:"labels"`
+	Annotations []string `yaml:"annotations" json:"annotations"`
+}
+
+// SummaryConfig lists the fields shown in the summary of the entries of each protocol, by protocol name
+type SummaryConfig map[string][]SummaryField
+
+// SummaryField is a jq-like path of an entry field, values longer than MaxLength are cut to their prefix
+type SummaryField struct {
+	Field     string `yaml:"field" json:"field"`
+	MaxLength int    `yaml:"max-length,omitempty" json:"maxLength,omitempty"`
 }
 
 type WebSocketMessageMetadata struct {
@@ -80,12 +307,84 @@ type WebSocketTapConfigMessage struct {
 	TapTargets []v1.Pod `json:"pods"`
 }
 
+type WebSocketTapperDebugMessage struct {
+	*WebSocketMessageMetadata
+	Config TapperDebugConfig `json:"config"`
+}
+
+// TapperDebugConfig changes the debug settings of a single running tapper, a packet dump is started when
+// PacketDumpMaxBytes is positive and it stops by itself after PacketDumpDurationSec
+type TapperDebugConfig struct {
+	LogLevel              string `json:"logLevel,omitempty"`
+	PacketDumpMaxBytes    int64  `json:"packetDumpMaxBytes,omitempty"`
+	PacketDumpDurationSec int    `json:"packetDumpDurationSec,omitempty"`
+	StopPacketDump        bool   `json:"stopPacketDump,omitempty"`
+}
+
+// WebSocketHeartbeatMessage is sent periodically by every tapper, Timestamp is the tapper clock in unix milliseconds
+type WebSocketHeartbeatMessage struct {
+	*WebSocketMessageMetadata
+	Timestamp int64 `json:"timestamp"`
+	TapperDrops
+}
+
+// TapperDrops counts what a tapper didn't send to the API server since it started, the tcp streams it couldn't keep
+// up with and the entries left out by the sampling and by the cap on the entries per second of every pod
+type TapperDrops struct {
+	DroppedTcpStreams  uint64 `json:"droppedTcpStreams"`
+	SampledOutEntries  uint64 `json:"sampledOutEntries"`
+	RateLimitedEntries uint64 `json:"rateLimitedEntries"`
+}
+
+type TapperDropsStatus struct {
+	NodeName string `json:"nodeName"`
+	TapperDrops
+}
+
+type TapperClockSkew struct {
+	NodeName      string `json:"nodeName"`
+	SkewMs        int64  `json:"skewMs"`
+	IsSkewed      bool   `json:"isSkewed"`
+	LastHeartbeat int64  `json:"lastHeartbeat"`
+}
+
 type TapperStatus struct {
 	TapperName string `json:"tapperName"`
 	NodeName   string `json:"nodeName"`
 	Status     string `json:"status"`
 }
 
+// SinkHealth is the outcome of validating an export destination from the API server, the steps run in order and
+// stop at the first one that fails. Identity is the cloud identity the destination is authenticated as and Permissions
+// are the ones it needs on the destination, they're only set for a destination with a cloud auth
+type SinkHealth struct {
+	Sink           string   `json:"sink"`
+	Destination    string   `json:"destination"`
+	Identity       string   `json:"identity,omitempty"`
+	Permissions    []string `json:"permissions,omitempty"`
+	Connectivity   bool     `json:"connectivity"`
+	Authentication bool     `json:"authentication"`
+	Write          bool     `json:"write"`
+	Error          string   `json:"error,omitempty"`
+}
+
+// SinkStatus is the progress of a sink of the fan-out, LagMs is how long after its capture the last entry reached the
+// sink and Backlog is the entries waiting for the destination
+type SinkStatus struct {
+	Name        string `json:"name"`
+	Type        string `json:"type"`
+	Query       string `json:"query"`
+	Delivery    string `json:"delivery"`
+	Entries     uint64 `json:"entries"`
+	LastEntryAt int64  `json:"lastEntryAt"`
+	LagMs       int64  `json:"lagMs"`
+	Backlog     int    `json:"backlog"`
+	Delivered   int    `json:"delivered"`
+	Dropped     int    `json:"dropped"`
+	Unavailable bool   `json:"unavailable"`
+	Error       string `json:"error,omitempty"`
+}
+
 type TappedPodStatus struct {
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
@@ -130,6 +429,34 @@ func CreateWebSocketMessageTypeAnalyzeStatus(analyzeStatus AnalyzeStatus) WebSoc
 	}
 }
 
+func CreateWebSocketTapConfigMessage(tapTargets []v1.Pod) WebSocketTapConfigMessage {
+	return WebSocketTapConfigMessage{
+		WebSocketMessageMetadata: &WebSocketMessageMetadata{
+			MessageType: WebSocketMessageTypeTapConfig,
+		},
+		TapTargets: tapTargets,
+	}
+}
+
+func CreateWebSocketTapperDebugMessage(config TapperDebugConfig) WebSocketTapperDebugMessage {
+	return WebSocketTapperDebugMessage{
+		WebSocketMessageMetadata: &WebSocketMessageMetadata{
+			MessageType: WebSocketMessageTypeTapperDebug,
+		},
+		Config: config,
+	}
+}
+
+func CreateWebSocketHeartbeatMessage(timestamp int64, drops TapperDrops) WebSocketHeartbeatMessage {
+	return WebSocketHeartbeatMessage{
+		WebSocketMessageMetadata: &WebSocketMessageMetadata{
+			MessageType: WebSocketMessageTypeHeartbeat,
+		},
+		Timestamp:   timestamp,
+		TapperDrops: drops,
+	}
+}
+
 type HealthResponse struct {
 	TappedPods            []*PodInfo      `json:"tappedPods"`
 	ConnectedTappersCount int             `json:"connectedTappersCount"`
@@ -140,6 +467,17 @@ type VersionResponse struct {
 	Ver string `json:"ver"`
 }
 
+// AgentFeatures are the modules the API server runs, the disabled ones neither process the entries nor serve their
+// data. Stats are the counts and volume of the entries, Metrics are the Prometheus metrics and Insights are the
+// requests per connection of every client-service pair
+type AgentFeatures struct {
+	ServiceMap bool `json:"serviceMap"`
+	OAS        bool `json:"oas"`
+	Stats      bool `json:"stats"`
+	Metrics    bool `json:"metrics"`
+	Insights   bool `json:"insights"`
+}
+
 type RulesPolicy struct {
 	Rules []RulePolicy `yaml:"rules"`
 }
@@ -219,3 +557,96 @@ func DecodeEnforcePolicy(path string) (RulesPolicy, error) {
 	}
 	return enforcePolicy, nil
 }
+
+const (
+	SendProtocolHttp = "http"
+	SendProtocolGrpc = "grpc"
+
+	SendBodyEncodingBase64 = "base64"
+)
+
+// SendRequest is a request the API server sends from inside the cluster, the body of a gRPC request is the
+// base64 encoded protobuf message
+type SendRequest struct {
+	Protocol         string            `json:"protocol"`
+	Method           string            `json:"method"`
+	Url              string            `json:"url"`
+	Headers          map[string]string `json:"headers"`
+	Body             string            `json:"body"`
+	TimeoutMs        int               `json:"timeoutMs"`
+	CaptureTimeoutMs int               `json:"captureTimeoutMs"`
+}
+
+// SendResponse is the response to a SendRequest along with the entry captured when the target is tapped,
+// a body that isn't valid UTF-8 is base64 encoded
+type SendResponse struct {
+	SendId       string            `json:"sendId"`
+	Status       int               `json:"status"`
+	StatusText   string            `json:"statusText"`
+	Headers      map[string]string `json:"headers"`
+	Trailers     map[string]string `json:"trailers,omitempty"`
+	Body         string            `json:"body"`
+	BodyEncoding string            `json:"bodyEncoding,omitempty"`
+	GrpcStatus   *int              `json:"grpcStatus,omitempty"`
+	GrpcMessage  string            `json:"grpcMessage,omitempty"`
+	GrpcMessages []string          `json:"grpcMessages,omitempty"`
+	ElapsedTime  int64             `json:"elapsedTime"`
+	Entry        interface{}       `json:"entry,omitempty"`
+}
+
+// ReplayRequest re-sends the captured http requests matching Query, up to Limit of them, to the same paths of
+// TargetUrl from inside the cluster, Headers are set on each request and an empty value removes the header
+type ReplayRequest struct {
+	Query       string            `json:"query"`
+	TargetUrl   string            `json:"targetUrl"`
+	Limit       int               `json:"limit"`
+	Concurrency int               `json:"concurrency"`
+	Headers     map[string]string `json:"headers"`
+	TimeoutMs   int               `json:"timeoutMs"`
+}
+
+// ReplayResult is the outcome of replaying an entry, Error is set instead of Status when no response was received
+type ReplayResult struct {
+	EntryId        string `json:"entryId"`
+	Method         string `json:"method"`
+	Url            string `json:"url"`
+	OriginalStatus int    `json:"originalStatus"`
+	Status         int    `json:"status,omitempty"`
+	Error          string `json:"error,omitempty"`
+	ElapsedTime    int64  `json:"elapsedTime"`
+}
+
+// ReplayResponse holds the results in the order the entries were captured, oldest first
+type ReplayResponse struct {
+	Entries int            `json:"entries"`
+	Failed  int            `json:"failed"`
+	Results []ReplayResult `json:"results"`
+}
+
+const (
+	IngestionMuteProtocol  = "protocol"
+	IngestionMuteNamespace = "namespace"
+)
+
+// IngestionMute stops storing the entries of a protocol or of a namespace until it's removed, the tappers keep
+// capturing them. Since is the unix milliseconds it was added and Dropped the entries it dropped since
+type IngestionMute struct {
+	Kind    string `json:"kind"`
+	Value   string `json:"value"`
+	Since   int64  `json:"since"`
+	Dropped int    `json:"dropped"`
+}
+
+type IngestionStatus struct {
+	Muted []*IngestionMute `json:"muted"`
+}
+
+// SavedQuery is a named query the API server keeps for the team, like "all 5xx from checkout", the times are unix
+// milliseconds
+type SavedQuery struct {
+	Name      string `json:"name"`
+	Query     string `json:"query"`
+	Owner     string `json:"owner,omitempty"`
+	CreatedAt int64  `json:"createdAt"`
+	UpdatedAt int64  `json:"updatedAt"`
+}
@@ -0,0 +1,326 @@
+package objectstorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/up9inc/mizu/shared/cloudauth"
+)
+
+const (
+	SchemeS3  = "s3"
+	SchemeGcs = "gs"
+
+	defaultS3Region  = "us-east-1"
+	defaultGcsRegion = "auto"
+	gcsEndpoint      = "https://storage.googleapis.com"
+)
+
+// Location is a bucket and the key or prefix in it, parsed from a url like s3://bucket/prefix or gs://bucket/prefix
+type Location struct {
+	Scheme string
+	Bucket string
+	Key    string
+}
+
+func ParseUrl(rawUrl string) (*Location, error) {
+	parsedUrl, err := url.Parse(rawUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	if parsedUrl.Scheme != SchemeS3 && parsedUrl.Scheme != SchemeGcs {
+		return nil, fmt.Errorf("%s isn't a bucket url, expected %s://bucket/prefix or %s://bucket/prefix", rawUrl, SchemeS3, SchemeGcs)
+	}
+	if parsedUrl.Host == "" {
+		return nil, fmt.Errorf("%s is missing the bucket", rawUrl)
+	}
+
+	return &Location{
+		Scheme: parsedUrl.Scheme,
+		Bucket: parsedUrl.Host,
+		Key:    strings.TrimPrefix(parsedUrl.Path, "/"),
+	}, nil
+}
+
+func (location *Location) String() string {
+	return fmt.Sprintf("%s://%s/%s", location.Scheme, location.Bucket, location.Key)
+}
+
+// ResponseError is a request that the storage responded to with a failure status
+type ResponseError struct {
+	StatusCode int
+	message    string
+}
+
+func (err *ResponseError) Error() string {
+	return err.message
+}
+
+// IsAuthenticationFailure tells a request whose credentials were rejected from a request that isn't allowed, S3 and gcs
+// respond to both with a forbidden status and tell them apart by the error code
+func (err *ResponseError) IsAuthenticationFailure() bool {
+	if err.StatusCode == http.StatusUnauthorized {
+		return true
+	}
+
+	for _, code := range []string{"InvalidAccessKeyId", "SignatureDoesNotMatch", "InvalidToken", "ExpiredToken", "AuthenticationRequired"} {
+		if strings.Contains(err.message, fmt.Sprintf("<Code>%s</Code>", code)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Object is an object of a bucket listing
+type Object struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// Client calls the S3 api with requests signed by signature version 4, gcs buckets are called through their S3
+// compatible api, which accepts the HMAC keys of a service account, or the oauth tokens of a service account in place
+// of the signature
+type Client struct {
+	bucket        string
+	baseUrl       *url.URL
+	virtualHosted bool
+	region        string
+	credentials   *cloudauth.AwsCredentialsProvider
+	tokens        *cloudauth.TokenProvider
+	httpClient    *http.Client
+}
+
+// NewClient returns a client of the bucket of the location, an empty endpoint is the endpoint of aws or of gcs by the
+// scheme of the location, any other endpoint, like of minio, is called with path style urls
+func NewClient(location *Location, endpoint string, region string, accessKeyId string, secretAccessKey string, timeout time.Duration) (*Client, error) {
+	return NewClientWithAuth(location, endpoint, region, "", accessKeyId, secretAccessKey, timeout)
+}
+
+// NewClientWithAuth returns a client like NewClient that authenticates by the auth, the static keys when it's empty,
+// the role of the service account of the pod on aws or the google service account of the pod on gcs
+func NewClientWithAuth(location *Location, endpoint string, region string, auth string, accessKeyId string, secretAccessKey string, timeout time.Duration) (*Client, error) {
+	if err := cloudauth.Validate(auth, fmt.Sprintf("%s buckets", location.Scheme), supportedAuth(location.Scheme)); err != nil {
+		return nil, err
+	}
+
+	var credentials *cloudauth.AwsCredentialsProvider
+	var tokens *cloudauth.TokenProvider
+	switch auth {
+	case cloudauth.AuthAws:
+		var err error
+		if credentials, err = cloudauth.NewAwsWebIdentityProvider(); err != nil {
+			return nil, err
+		}
+		if region == "" {
+			region = cloudauth.AwsRegion()
+		}
+	case cloudauth.AuthGcp:
+		tokens = cloudauth.NewGcpTokenProvider()
+	default:
+		credentials = cloudauth.NewStaticAwsCredentialsProvider(accessKeyId, secretAccessKey)
+	}
+
+	virtualHosted := false
+	if region == "" {
+		region = defaultS3Region
+		if location.Scheme == SchemeGcs {
+			region = defaultGcsRegion
+		}
+	}
+
+	if endpoint == "" {
+		if location.Scheme == SchemeGcs {
+			endpoint = gcsEndpoint
+		} else {
+			endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", location.Bucket, region)
+			virtualHosted = true
+		}
+	}
+
+	baseUrl, err := url.Parse(strings.TrimSuffix(endpoint, "/"))
+	if err != nil {
+		return nil, err
+	}
+	if baseUrl.Scheme == "" || baseUrl.Host == "" {
+		return nil, fmt.Errorf("%s isn't a valid endpoint", endpoint)
+	}
+
+	return &Client{
+		bucket:        location.Bucket,
+		baseUrl:       baseUrl,
+		virtualHosted: virtualHosted,
+		region:        region,
+		credentials:   credentials,
+		tokens:        tokens,
+		httpClient:    &http.Client{Timeout: timeout},
+	}, nil
+}
+
+// supportedAuth is the cloud auth of the buckets of the scheme
+func supportedAuth(scheme string) string {
+	if scheme == SchemeGcs {
+		return cloudauth.AuthGcp
+	}
+
+	return cloudauth.AuthAws
+}
+
+// Authenticate gets the credentials of the requests, the temporary credentials of a role or the token of a service
+// account are requested from the cloud, the static keys are only verified by the bucket
+func (client *Client) Authenticate(ctx context.Context) error {
+	if client.tokens != nil {
+		_, err := client.tokens.Token(ctx)
+		return err
+	}
+
+	_, err := client.credentials.Credentials(ctx)
+	return err
+}
+
+// Identity describes who the requests are authenticated as
+func (client *Client) Identity() string {
+	if client.tokens != nil {
+		return client.tokens.Identity()
+	}
+
+	return client.credentials.Identity()
+}
+
+func (client *Client) PutObject(ctx context.Context, key string, body []byte, contentType string) error {
+	headers := http.Header{}
+	headers.Set("Content-Type", contentType)
+
+	_, err := client.do(ctx, http.MethodPut, key, nil, headers, body)
+	return err
+}
+
+func (client *Client) GetObject(ctx context.Context, key string) ([]byte, error) {
+	return client.do(ctx, http.MethodGet, key, nil, nil, nil)
+}
+
+func (client *Client) DeleteObject(ctx context.Context, key string) error {
+	_, err := client.do(ctx, http.MethodDelete, key, nil, nil, nil)
+	return err
+}
+
+type listBucketResult struct {
+	Contents []struct {
+		Key          string    `xml:"Key"`
+		Size         int64     `xml:"Size"`
+		LastModified time.Time `xml:"LastModified"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// ListObjects returns every object whose key starts with the prefix, ordered by key
+func (client *Client) ListObjects(ctx context.Context, prefix string) ([]Object, error) {
+	objects := make([]Object, 0)
+	continuationToken := ""
+
+	for {
+		query := url.Values{}
+		query.Set("list-type", "2")
+		query.Set("prefix", prefix)
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		body, err := client.do(ctx, http.MethodGet, "", query, nil, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+		if err := xml.Unmarshal(body, &result); err != nil {
+			return nil, fmt.Errorf("failed parsing the listing of bucket %s: %w", client.bucket, err)
+		}
+
+		for _, content := range result.Contents {
+			objects = append(objects, Object{Key: content.Key, Size: content.Size, LastModified: content.LastModified})
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			break
+		}
+		continuationToken = result.NextContinuationToken
+	}
+
+	return objects, nil
+}
+
+func (client *Client) do(ctx context.Context, method string, key string, query url.Values, headers http.Header, body []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, method, client.objectUrl(key, query), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range headers {
+		request.Header[name] = values
+	}
+
+	if client.tokens != nil {
+		token, err := client.tokens.Token(ctx)
+		if err != nil {
+			return nil, err
+		}
+		request.Header.Set("Authorization", "Bearer "+token)
+	} else if err := client.sign(request, body, time.Now()); err != nil {
+		return nil, err
+	}
+
+	response, err := client.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	responseBody, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, &ResponseError{
+			StatusCode: response.StatusCode,
+			message:    fmt.Sprintf("%s %s/%s failed with status %d: %s", method, client.bucket, key, response.StatusCode, responseBody),
+		}
+	}
+
+	return responseBody, nil
+}
+
+func (client *Client) objectUrl(key string, query url.Values) string {
+	objectPath := "/" + key
+	if !client.virtualHosted {
+		objectPath = strings.TrimSuffix(fmt.Sprintf("/%s/%s", client.bucket, key), "/")
+	}
+
+	objectUrl := *client.baseUrl
+	objectUrl.Path = objectUrl.Path + objectPath
+	objectUrl.RawPath = client.baseUrl.EscapedPath() + cloudauth.EncodePath(objectPath)
+	objectUrl.RawQuery = cloudauth.EncodeQuery(query)
+
+	return objectUrl.String()
+}
+
+// sign adds the signature version 4 authorization of the request, with the static credentials of the config or the
+// temporary credentials of the role of the service account
+func (client *Client) sign(request *http.Request, body []byte, now time.Time) error {
+	credentials, err := client.credentials.Credentials(request.Context())
+	if err != nil {
+		return err
+	}
+
+	cloudauth.SignV4(request, body, credentials, client.region, "s3", now)
+	return nil
+}
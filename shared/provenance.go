@@ -0,0 +1,127 @@
+package shared
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// ProvenanceGenesisHash is the previous hash of the first segment of a chain
+var ProvenanceGenesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+// ProvenanceConfig enables hash chaining and signing the stored entries with the ed25519 private key in the named
+// secret, the chain is signed every SegmentSize entries and at least once a minute
+type ProvenanceConfig struct {
+	SecretName      string `yaml:"secret-name,omitempty" json:"secretName"`
+	SecretNamespace string `yaml:"secret-namespace" json:"secretNamespace" default:"default"`
+	SegmentSize     int    `yaml:"segment-size" json:"segmentSize" default:"1000"`
+}
+
+// ProvenanceSegment is a signed link of the chain, Hash is the chain hash of PreviousHash followed by the entries
+// of EntryIds in their order of insertion
+type ProvenanceSegment struct {
+	Index        int      `json:"index"`
+	PreviousHash string   `json:"previousHash"`
+	Hash         string   `json:"hash"`
+	EntryIds     []string `json:"entryIds"`
+	SignedAt     int64    `json:"signedAt"`
+	Signature    string   `json:"signature"`
+}
+
+// ProvenanceSegmentsResponse lists the signed segments oldest first, UnsignedCount entries were chained after the
+// last segment and are signed with the next one
+type ProvenanceSegmentsResponse struct {
+	Segments      []*ProvenanceSegment `json:"segments"`
+	UnsignedCount int                  `json:"unsignedCount"`
+}
+
+func (segment *ProvenanceSegment) signedPayload() []byte {
+	return []byte(fmt.Sprintf("mizu-provenance-v1\n%d\n%s\n%s\n%d", segment.Index, segment.PreviousHash, segment.Hash, segment.SignedAt))
+}
+
+func (segment *ProvenanceSegment) Sign(privateKey ed25519.PrivateKey) {
+	segment.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, segment.signedPayload()))
+}
+
+func (segment *ProvenanceSegment) VerifySignature(publicKey ed25519.PublicKey) bool {
+	signature, err := base64.StdEncoding.DecodeString(segment.Signature)
+	if err != nil {
+		return false
+	}
+
+	return ed25519.Verify(publicKey, segment.signedPayload(), signature)
+}
+
+// ProvenanceEntryHash hashes the entry the way it's returned by the api server, the database index is left out
+// since it changes when the database is recreated and isn't known when the entry is inserted
+func ProvenanceEntryHash(entryJson []byte) ([]byte, error) {
+	var entry map[string]interface{}
+	if err := json.Unmarshal(entryJson, &entry); err != nil {
+		return nil, err
+	}
+	delete(entry, "id")
+
+	// maps are marshaled with sorted keys
+	canonicalJson, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.Sum256(canonicalJson)
+	return hash[:], nil
+}
+
+// ProvenanceChain returns the next hash of the chain
+func ProvenanceChain(previousHash []byte, entryHash []byte) []byte {
+	hash := sha256.Sum256(bytes.Join([][]byte{previousHash, entryHash}, nil))
+	return hash[:]
+}
+
+// ParseProvenancePrivateKey parses a PEM encoded PKCS #8 ed25519 private key, like the one created by
+// openssl genpkey -algorithm ed25519
+func ParseProvenancePrivateKey(pemData []byte) (ed25519.PrivateKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ed25519 private key, got %T", key)
+	}
+
+	return privateKey, nil
+}
+
+// ParseProvenancePublicKey parses a PEM encoded PKIX ed25519 public key, like the one created by
+// openssl pkey -pubout
+func ParseProvenancePublicKey(pemData []byte) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(pemData)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	publicKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected an ed25519 public key, got %T", key)
+	}
+
+	return publicKey, nil
+}
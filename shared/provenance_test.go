@@ -0,0 +1,90 @@
+package shared
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+)
+
+func TestProvenanceEntryHashIgnoresIndexAndKeyOrder(t *testing.T) {
+	stored := []byte(`{"id":17,"entryId":"01G0Z9K3V2Q8","proto":{"name":"http"},"timestamp":1650000000123,"request":{"path":"/a","method":"GET"}}`)
+	served := []byte(`{"request":{"method":"GET","path":"/a"},"timestamp":1650000000123,"proto":{"name":"http"},"entryId":"01G0Z9K3V2Q8","id":3}`)
+	tampered := []byte(`{"id":17,"entryId":"01G0Z9K3V2Q8","proto":{"name":"http"},"timestamp":1650000000123,"request":{"path":"/b","method":"GET"}}`)
+
+	storedHash, err := ProvenanceEntryHash(stored)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	servedHash, err := ProvenanceEntryHash(served)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tamperedHash, err := ProvenanceEntryHash(tampered)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !bytes.Equal(storedHash, servedHash) {
+		t.Errorf("unexpected result - expected the stored and the served entry to have the same hash")
+	}
+	if bytes.Equal(storedHash, tamperedHash) {
+		t.Errorf("unexpected result - expected the tampered entry to have another hash")
+	}
+}
+
+func TestProvenanceSegmentSignature(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	genesis, _ := hex.DecodeString(ProvenanceGenesisHash)
+	segment := &ProvenanceSegment{
+		Index:        0,
+		PreviousHash: ProvenanceGenesisHash,
+		Hash:         hex.EncodeToString(ProvenanceChain(genesis, []byte("entry"))),
+		EntryIds:     []string{"01G0Z9K3V2Q8"},
+		SignedAt:     1650000000123,
+	}
+	segment.Sign(privateKey)
+
+	if !segment.VerifySignature(publicKey) {
+		t.Errorf("unexpected result - expected the signature to be valid")
+	}
+
+	segment.Hash = ProvenanceGenesisHash
+	if segment.VerifySignature(publicKey) {
+		t.Errorf("unexpected result - expected the signature of the modified segment to be invalid")
+	}
+}
+
+func TestParseProvenanceKeys(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	privateDer, _ := x509.MarshalPKCS8PrivateKey(privateKey)
+	publicDer, _ := x509.MarshalPKIXPublicKey(publicKey)
+
+	parsedPrivateKey, err := ParseProvenancePrivateKey(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDer}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsedPublicKey, err := ParseProvenancePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDer}))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !parsedPrivateKey.Equal(privateKey) || !parsedPublicKey.Equal(publicKey) {
+		t.Errorf("unexpected result - expected the parsed keys to equal the generated keys")
+	}
+
+	if _, err := ParseProvenancePrivateKey([]byte("not a key")); err == nil {
+		t.Errorf("unexpected result - expected an error for data that isn't PEM")
+	}
+}
@@ -0,0 +1,47 @@
+package shared
+
+import (
+	"encoding/json"
+
+	tapApi "github.com/up9inc/mizu/tap/api"
+)
+
+// RedactionTestRequest runs the redaction of the tappers with Options on the http entries of Har, a HAR log, or on
+// the latest Limit stored http entries matching Query when there's no Har. The stored entries were already redacted
+// with the options mizu tap ran with, only what Options removes on top of that shows
+type RedactionTestRequest struct {
+	Options tapApi.TrafficFilteringOptions `json:"options"`
+	Har     json.RawMessage                `json:"har,omitempty"`
+	Query   string                         `json:"query"`
+	Limit   int                            `json:"limit"`
+}
+
+// RedactedValue is a value the redaction changes, In is the header, the query or the body of the request or the
+// response, Name is the name of the header or the query param or the JSON path of a body field, no Name is the whole
+// body. Removed is set for the values that are dropped instead of masked, like the cookies
+type RedactedValue struct {
+	Message  string `json:"message"`
+	In       string `json:"in"`
+	Name     string `json:"name,omitempty"`
+	Value    string `json:"value"`
+	Redacted string `json:"redacted"`
+	Removed  bool   `json:"removed,omitempty"`
+}
+
+// RedactionTestResult holds the values the redaction changes in an entry, Error is set for an entry that couldn't
+// be redacted
+type RedactionTestResult struct {
+	Method string          `json:"method"`
+	Url    string          `json:"url"`
+	Status int             `json:"status"`
+	Values []RedactedValue `json:"values"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// RedactionTestResponse holds the results in the order of the entries, oldest first, Redacted counts the entries
+// with any changed value
+type RedactionTestResponse struct {
+	Entries  int                   `json:"entries"`
+	Redacted int                   `json:"redacted"`
+	Results  []RedactionTestResult `json:"results"`
+}
@@ -0,0 +1,28 @@
+package shared
+
+const (
+	EvictionReasonBytes   = "bytes"
+	EvictionReasonEntries = "entries"
+	EvictionReasonAge     = "age"
+)
+
+// RetentionConfig bounds the stored entries by count and by age on top of the database size limit, the oldest entries
+// are evicted first. A max of 0 doesn't bound the entries by it, the entries over the bounds are evicted every interval
+type RetentionConfig struct {
+	MaxEntries  int64 `yaml:"max-entries" json:"maxEntries" default:"0"`
+	MaxAgeSec   int64 `yaml:"max-age-sec" json:"maxAgeSec" default:"0"`
+	IntervalSec int   `yaml:"interval-sec" json:"intervalSec" default:"10"`
+}
+
+// RetentionStatus is the retained share of the stored entries, LimitBytes is the database size limit the retention
+// applied and Evicted counts the evicted entries by the bound they were over
+type RetentionStatus struct {
+	MaxEntries int64             `json:"maxEntries"`
+	MaxAgeSec  int64             `json:"maxAgeSec"`
+	MaxBytes   int64             `json:"maxBytes"`
+	LimitBytes int64             `json:"limitBytes"`
+	Entries    int64             `json:"entries"`
+	Bytes      int64             `json:"bytes"`
+	OldestTime int64             `json:"oldestTime"`
+	Evicted    map[string]uint64 `json:"evicted"`
+}
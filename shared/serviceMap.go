@@ -1,4 +1,4 @@
-package servicemap
+package shared
 
 import (
 	tapApi "github.com/up9inc/mizu/tap/api"
@@ -24,9 +24,13 @@ type ServiceMapNode struct {
 	Count int         `json:"count"`
 }
 
+// ServiceMapEdge counts the calls of a protocol from the source to the destination, errors are the calls with a
+// status of 500 and above, like the 5xx of http
 type ServiceMapEdge struct {
 	Source      ServiceMapNode   `json:"source"`
 	Destination ServiceMapNode   `json:"destination"`
 	Count       int              `json:"count"`
+	Errors      int              `json:"errors"`
+	ErrorRate   float64          `json:"errorRate"`
 	Protocol    *tapApi.Protocol `json:"protocol"`
 }
@@ -0,0 +1,67 @@
+package shared
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	// the agent image and some cli platforms don't ship the timezone database
+	_ "time/tzdata"
+)
+
+const (
+	TimestampFormatRfc3339 = "rfc3339"
+	TimestampFormatEpoch   = "epoch"
+	TimestampFormatLocal   = "local"
+
+	localTimestampLayout = "2006-01-02 15:04:05.000 MST"
+)
+
+// TimestampConfig controls how timestamps are rendered for people by the UI and the CLI, numeric timestamps (unix
+// milliseconds) that are part of the entries are kept as is and the exports always use RFC3339 in UTC
+type TimestampConfig struct {
+	Format   string `yaml:"format" json:"format" default:"rfc3339"`
+	Timezone string `yaml:"timezone" json:"timezone" default:"UTC"`
+}
+
+type TimestampFormatter struct {
+	format   string
+	location *time.Location
+}
+
+func NewTimestampFormatter(config TimestampConfig) (*TimestampFormatter, error) {
+	format := strings.ToLower(config.Format)
+	switch format {
+	case "":
+		format = TimestampFormatRfc3339
+	case TimestampFormatRfc3339, TimestampFormatEpoch, TimestampFormatLocal:
+	default:
+		return nil, fmt.Errorf("%s is not a valid timestamp format, supported formats: %s, %s, %s", config.Format, TimestampFormatRfc3339, TimestampFormatEpoch, TimestampFormatLocal)
+	}
+
+	location := time.UTC
+	if config.Timezone != "" {
+		var err error
+		if location, err = time.LoadLocation(config.Timezone); err != nil {
+			return nil, fmt.Errorf("%s is not a valid timezone, err: %v", config.Timezone, err)
+		}
+	}
+
+	return &TimestampFormatter{format: format, location: location}, nil
+}
+
+// Format returns unix milliseconds for the epoch format and a string for the others
+func (formatter *TimestampFormatter) Format(t time.Time) interface{} {
+	switch formatter.format {
+	case TimestampFormatEpoch:
+		return t.UnixNano() / int64(time.Millisecond)
+	case TimestampFormatLocal:
+		return t.In(formatter.location).Format(localTimestampLayout)
+	default:
+		return t.In(formatter.location).Format(time.RFC3339Nano)
+	}
+}
+
+func (formatter *TimestampFormatter) FormatMillis(timestamp int64) interface{} {
+	return formatter.Format(time.Unix(0, timestamp*int64(time.Millisecond)))
+}
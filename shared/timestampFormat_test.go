@@ -0,0 +1,41 @@
+package shared
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimestampFormats(t *testing.T) {
+	timestamp := time.Date(2022, 3, 1, 10, 30, 0, 250*int(time.Millisecond), time.UTC)
+
+	tests := []struct {
+		config   TimestampConfig
+		expected interface{}
+	}{
+		{config: TimestampConfig{}, expected: "2022-03-01T10:30:00.25Z"},
+		{config: TimestampConfig{Format: TimestampFormatRfc3339, Timezone: "Asia/Jerusalem"}, expected: "2022-03-01T12:30:00.25+02:00"},
+		{config: TimestampConfig{Format: TimestampFormatEpoch, Timezone: "America/New_York"}, expected: int64(1646130600250)},
+		{config: TimestampConfig{Format: TimestampFormatLocal, Timezone: "UTC"}, expected: "2022-03-01 10:30:00.250 UTC"},
+	}
+
+	for _, test := range tests {
+		formatter, err := NewTimestampFormatter(test.config)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if actual := formatter.Format(timestamp); actual != test.expected {
+			t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, actual)
+		}
+	}
+}
+
+func TestInvalidTimestampConfig(t *testing.T) {
+	if _, err := NewTimestampFormatter(TimestampConfig{Format: "iso"}); err == nil {
+		t.Errorf("unexpected result - expected an error for an unknown format")
+	}
+
+	if _, err := NewTimestampFormatter(TimestampConfig{Timezone: "Mars/Olympus"}); err == nil {
+		t.Errorf("unexpected result - expected an error for an unknown timezone")
+	}
+}
@@ -0,0 +1,142 @@
+package transform
+
+import "fmt"
+
+type node interface {
+	eval(input interface{}) (interface{}, error)
+}
+
+type identityNode struct{}
+
+func (identityNode) eval(input interface{}) (interface{}, error) {
+	return input, nil
+}
+
+type fieldNode struct {
+	target node
+	name   string
+}
+
+func (n *fieldNode) eval(input interface{}) (interface{}, error) {
+	value, err := n.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := value.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return typed[n.name], nil
+	default:
+		return nil, fmt.Errorf("cannot index %s with %q", typeName(value), n.name)
+	}
+}
+
+type indexNode struct {
+	target node
+	index  int
+}
+
+func (n *indexNode) eval(input interface{}) (interface{}, error) {
+	value, err := n.target.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	switch typed := value.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		index := n.index
+		if index < 0 {
+			index += len(typed)
+		}
+		if index < 0 || index >= len(typed) {
+			return nil, nil
+		}
+		return typed[index], nil
+	default:
+		return nil, fmt.Errorf("cannot index %s with a number", typeName(value))
+	}
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(_ interface{}) (interface{}, error) {
+	return n.value, nil
+}
+
+type objectField struct {
+	key   string
+	value node
+}
+
+type objectNode struct {
+	fields []objectField
+}
+
+func (n *objectNode) eval(input interface{}) (interface{}, error) {
+	result := make(map[string]interface{}, len(n.fields))
+	for _, field := range n.fields {
+		value, err := field.value.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		result[field.key] = value
+	}
+
+	return result, nil
+}
+
+type arrayNode struct {
+	elements []node
+}
+
+func (n *arrayNode) eval(input interface{}) (interface{}, error) {
+	result := make([]interface{}, 0, len(n.elements))
+	for _, element := range n.elements {
+		value, err := element.eval(input)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, value)
+	}
+
+	return result, nil
+}
+
+type pipeNode struct {
+	left  node
+	right node
+}
+
+func (n *pipeNode) eval(input interface{}) (interface{}, error) {
+	value, err := n.left.eval(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return n.right.eval(value)
+}
+
+func typeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64, int, int64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
@@ -0,0 +1,318 @@
+package transform
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type parser struct {
+	input string
+	pos   int
+}
+
+func (p *parser) parse() (node, error) {
+	root, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos])
+	}
+
+	return root, nil
+}
+
+func (p *parser) parsePipe() (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.consume('|') {
+		right, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		left = &pipeNode{left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *parser) parseTerm() (node, error) {
+	p.skipSpaces()
+	if p.pos >= len(p.input) {
+		return nil, p.errorf("unexpected end of expression")
+	}
+
+	switch c := p.input[p.pos]; {
+	case c == '.':
+		return p.parsePath()
+	case c == '{':
+		return p.parseObject()
+	case c == '[':
+		return p.parseArray()
+	case c == '"':
+		value, err := p.parseString()
+		if err != nil {
+			return nil, err
+		}
+		return &literalNode{value: value}, nil
+	case c == '-' || isDigit(c):
+		return p.parseNumber()
+	case isIdentStart(c):
+		switch ident := p.parseIdent(); ident {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		default:
+			return nil, p.errorf("unsupported function or keyword %q", ident)
+		}
+	default:
+		return nil, p.errorf("unexpected %q", c)
+	}
+}
+
+func (p *parser) parsePath() (node, error) {
+	var current node = identityNode{}
+
+	// the leading dot may be directly followed by a field name, a quoted field name or an index
+	p.pos++
+	if p.pos < len(p.input) {
+		switch c := p.input[p.pos]; {
+		case isIdentStart(c):
+			current = &fieldNode{target: current, name: p.parseIdent()}
+		case c == '"':
+			name, err := p.parseString()
+			if err != nil {
+				return nil, err
+			}
+			current = &fieldNode{target: current, name: name}
+		}
+	}
+
+	for p.pos < len(p.input) {
+		switch p.input[p.pos] {
+		case '.':
+			p.pos++
+			if p.pos < len(p.input) && p.input[p.pos] == '"' {
+				name, err := p.parseString()
+				if err != nil {
+					return nil, err
+				}
+				current = &fieldNode{target: current, name: name}
+			} else if p.pos < len(p.input) && isIdentStart(p.input[p.pos]) {
+				current = &fieldNode{target: current, name: p.parseIdent()}
+			} else {
+				return nil, p.errorf("expected a field name after '.'")
+			}
+		case '[':
+			p.pos++
+			p.skipSpaces()
+			if p.pos < len(p.input) && p.input[p.pos] == '"' {
+				name, err := p.parseString()
+				if err != nil {
+					return nil, err
+				}
+				current = &fieldNode{target: current, name: name}
+			} else {
+				start := p.pos
+				if p.pos < len(p.input) && p.input[p.pos] == '-' {
+					p.pos++
+				}
+				for p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+					p.pos++
+				}
+				index, err := strconv.Atoi(p.input[start:p.pos])
+				if err != nil {
+					return nil, p.errorf("expected an index")
+				}
+				current = &indexNode{target: current, index: index}
+			}
+			if !p.consume(']') {
+				return nil, p.errorf("expected ']'")
+			}
+		default:
+			return current, nil
+		}
+	}
+
+	return current, nil
+}
+
+func (p *parser) parseObject() (node, error) {
+	p.pos++
+	object := &objectNode{}
+
+	p.skipSpaces()
+	if p.consume('}') {
+		return object, nil
+	}
+
+	for {
+		p.skipSpaces()
+		if p.pos >= len(p.input) {
+			return nil, p.errorf("expected '}'")
+		}
+
+		var key string
+		shorthandAllowed := false
+		if p.input[p.pos] == '"' {
+			var err error
+			if key, err = p.parseString(); err != nil {
+				return nil, err
+			}
+		} else if isIdentStart(p.input[p.pos]) {
+			key = p.parseIdent()
+			shorthandAllowed = true
+		} else {
+			return nil, p.errorf("expected an object key")
+		}
+
+		var value node
+		if p.consume(':') {
+			var err error
+			if value, err = p.parseObjectValue(); err != nil {
+				return nil, err
+			}
+		} else if shorthandAllowed {
+			value = &fieldNode{target: identityNode{}, name: key}
+		} else {
+			return nil, p.errorf("expected ':' after object key %q", key)
+		}
+		object.fields = append(object.fields, objectField{key: key, value: value})
+
+		if p.consume(',') {
+			continue
+		}
+		if p.consume('}') {
+			return object, nil
+		}
+		return nil, p.errorf("expected ',' or '}'")
+	}
+}
+
+// parseObjectValue parses an object value, like jq pipes are allowed in values only inside parentheses
+func (p *parser) parseObjectValue() (node, error) {
+	if p.consume('(') {
+		value, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if !p.consume(')') {
+			return nil, p.errorf("expected ')'")
+		}
+		return value, nil
+	}
+
+	return p.parseTerm()
+}
+
+func (p *parser) parseArray() (node, error) {
+	p.pos++
+	array := &arrayNode{}
+
+	p.skipSpaces()
+	if p.consume(']') {
+		return array, nil
+	}
+
+	for {
+		element, err := p.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		array.elements = append(array.elements, element)
+
+		if p.consume(',') {
+			continue
+		}
+		if p.consume(']') {
+			return array, nil
+		}
+		return nil, p.errorf("expected ',' or ']'")
+	}
+}
+
+func (p *parser) parseString() (string, error) {
+	start := p.pos
+	p.pos++
+	for p.pos < len(p.input) {
+		switch p.input[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			value, err := strconv.Unquote(p.input[start:p.pos])
+			if err != nil {
+				return "", p.errorf("invalid string %s", p.input[start:p.pos])
+			}
+			return value, nil
+		default:
+			p.pos++
+		}
+	}
+
+	return "", p.errorf("unterminated string")
+}
+
+func (p *parser) parseNumber() (node, error) {
+	start := p.pos
+	if p.input[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.input) && (isDigit(p.input[p.pos]) || strings.IndexByte(".eE+-", p.input[p.pos]) != -1) {
+		p.pos++
+	}
+
+	value, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return nil, p.errorf("invalid number %s", p.input[start:p.pos])
+	}
+
+	return &literalNode{value: value}, nil
+}
+
+func (p *parser) parseIdent() string {
+	start := p.pos
+	for p.pos < len(p.input) && (isIdentStart(p.input[p.pos]) || isDigit(p.input[p.pos])) {
+		p.pos++
+	}
+
+	return p.input[start:p.pos]
+}
+
+func (p *parser) consume(c byte) bool {
+	p.skipSpaces()
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+
+	return false
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("%s at position %d", fmt.Sprintf(format, args...), p.pos)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
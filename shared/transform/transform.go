@@ -0,0 +1,56 @@
+// Package transform implements a subset of jq expressions, used to project entries before they're written by
+// exports and by `mizu fetch`.
+//
+// Supported syntax:
+//
+//	.                      the input itself
+//	.a.b, ."a-b", .a[0]    object fields and array elements, missing fields evaluate to null
+//	{a: .x, b, "c-d": .y}  object construction, {b} is short for {b: .b}
+//	[.a, .b]               array construction
+//	"str", 1, true, null   literals
+//	.a | .b                pipes
+package transform
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+type Expression struct {
+	source string
+	root   node
+}
+
+func Compile(expression string) (*Expression, error) {
+	p := &parser{input: expression}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid transform expression %q: %w", expression, err)
+	}
+
+	return &Expression{source: expression, root: root}, nil
+}
+
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Apply evaluates the expression on a value decoded from json
+func (e *Expression) Apply(value interface{}) (interface{}, error) {
+	return e.root.eval(value)
+}
+
+// ApplyJson evaluates the expression on a json document and returns the result as json
+func (e *Expression) ApplyJson(data []byte) ([]byte, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+
+	result, err := e.Apply(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(result)
+}
@@ -0,0 +1,84 @@
+package transform
+
+import (
+	"testing"
+)
+
+const testEntry = `{
+	"id": 7,
+	"proto": {"name": "http"},
+	"src": {"ip": "10.0.0.1", "name": "front-end"},
+	"request": {"method": "GET", "path": "/catalogue", "headers": {"x-request-id": "abc"}},
+	"response": {"status": 200, "content": {"items": [{"id": 1}, {"id": 2}]}}
+}`
+
+func TestApplyJson(t *testing.T) {
+	tests := []struct {
+		expression string
+		expected   string
+	}{
+		{`.`, `{"id":7,"proto":{"name":"http"},"request":{"headers":{"x-request-id":"abc"},"method":"GET","path":"/catalogue"},"response":{"content":{"items":[{"id":1},{"id":2}]},"status":200},"src":{"ip":"10.0.0.1","name":"front-end"}}`},
+		{`.request.path`, `"/catalogue"`},
+		{`.request.headers."x-request-id"`, `"abc"`},
+		{`.request.headers["x-request-id"]`, `"abc"`},
+		{`.response.content.items[1].id`, `2`},
+		{`.response.content.items[-1]`, `{"id":2}`},
+		{`.response.content.items[5]`, `null`},
+		{`.missing.field`, `null`},
+		{`{id, method: .request.method, status: .response.status}`, `{"id":7,"method":"GET","status":200}`},
+		{`{"source-ip": .src.ip}`, `{"source-ip":"10.0.0.1"}`},
+		{`{path: (.request | .path)}`, `{"path":"/catalogue"}`},
+		{`[.proto.name, .response.status]`, `["http",200]`},
+		{`.request | {method, path}`, `{"method":"GET","path":"/catalogue"}`},
+		{`{kind: "entry", sampled: true, weight: 1.5, extra: null}`, `{"extra":null,"kind":"entry","sampled":true,"weight":1.5}`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.expression, func(t *testing.T) {
+			expression, err := Compile(test.expression)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			actual, err := expression.ApplyJson([]byte(testEntry))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if string(actual) != test.expected {
+				t.Errorf("unexpected result - expected: %v, actual: %v", test.expected, string(actual))
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	expressions := []string{
+		``,
+		`.request.`,
+		`{method`,
+		`{"method"}`,
+		`[.a, .b`,
+		`.a[x]`,
+		`select(.a)`,
+		`.a .b`,
+		`"unterminated`,
+	}
+
+	for _, expression := range expressions {
+		if _, err := Compile(expression); err == nil {
+			t.Errorf("unexpected result - expected an error for %q", expression)
+		}
+	}
+}
+
+func TestApplyErrors(t *testing.T) {
+	expression, err := Compile(`.request.path.length`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := expression.ApplyJson([]byte(testEntry)); err == nil {
+		t.Errorf("unexpected result - expected an error indexing a string")
+	}
+}
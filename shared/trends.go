@@ -0,0 +1,41 @@
+package shared
+
+// EndpointTrendStats sums up the entries of an endpoint of a service over a day, or over a week, errors are the 5xx
+// responses and the p95 latency is interpolated within the latency histogram of the entries
+type EndpointTrendStats struct {
+	Entries   int64   `json:"entries"`
+	Errors    int64   `json:"errors"`
+	ErrorRate float64 `json:"errorRate"`
+	P95Ms     float64 `json:"p95Ms"`
+}
+
+// EndpointDailyTrend is the aggregate of an endpoint for a day, in UTC, like 2022-03-01
+type EndpointDailyTrend struct {
+	Day      string `json:"day"`
+	Service  string `json:"service"`
+	Endpoint string `json:"endpoint"`
+	EndpointTrendStats
+}
+
+// EndpointWeekOverWeek contrasts the week of an endpoint with the week before it, the changes are relative, like 0.25
+// for 25% more, and they're left out when the previous week has nothing to compare to. The error rate change is the
+// difference of the rates
+type EndpointWeekOverWeek struct {
+	Service         string             `json:"service"`
+	Endpoint        string             `json:"endpoint"`
+	ThisWeek        EndpointTrendStats `json:"thisWeek"`
+	LastWeek        EndpointTrendStats `json:"lastWeek"`
+	VolumeChange    *float64           `json:"volumeChange,omitempty"`
+	ErrorRateChange float64            `json:"errorRateChange"`
+	P95Change       *float64           `json:"p95Change,omitempty"`
+}
+
+// WeekOverWeekResponse contrasts the 7 days ending at End with the 7 days before them, endpoints are sorted by
+// service and endpoint
+type WeekOverWeekResponse struct {
+	Start         string                  `json:"start"`
+	End           string                  `json:"end"`
+	LastWeekStart string                  `json:"lastWeekStart"`
+	LastWeekEnd   string                  `json:"lastWeekEnd"`
+	Endpoints     []*EndpointWeekOverWeek `json:"endpoints"`
+}
@@ -0,0 +1,33 @@
+package shared
+
+const (
+	PressureLevelNormal   = "normal"
+	PressureLevelHigh     = "high"
+	PressureLevelCritical = "critical"
+)
+
+// WatermarksConfig sheds the ingestion of the API server before it runs out of memory or disk, the pressure is the
+// highest of the used percent of the memory limit of its container and of the volume of its data directory. From
+// HighPercent on the bodies of the entries are dropped and SampleRate of the entries are kept, from CriticalPercent
+// on the entries of the tappers are dropped as they arrive. The pressure has to go back under HighPercent to leave
+// critical and under LowPercent to stop shedding
+type WatermarksConfig struct {
+	LowPercent      int     `yaml:"low-percent" json:"lowPercent" default:"70"`
+	HighPercent     int     `yaml:"high-percent" json:"highPercent" default:"80"`
+	CriticalPercent int     `yaml:"critical-percent" json:"criticalPercent" default:"90"`
+	SampleRate      float64 `yaml:"sample-rate" json:"sampleRate" default:"0.25"`
+	IntervalSec     int     `yaml:"interval-sec" json:"intervalSec" default:"5"`
+}
+
+// PressureStatus is the shedding of the API server, a percent is -1 when it can't be read, like the memory of a
+// container without a memory limit. Sampled, ShedBodies and Paused count the entries dropped by sampling, the entries
+// stored without their bodies and the entries dropped while critical
+type PressureStatus struct {
+	Level         string `json:"level"`
+	Since         int64  `json:"since"`
+	MemoryPercent int    `json:"memoryPercent"`
+	DiskPercent   int    `json:"diskPercent"`
+	Sampled       uint64 `json:"sampled"`
+	ShedBodies    uint64 `json:"shedBodies"`
+	Paused        uint64 `json:"paused"`
+}
@@ -37,9 +37,10 @@ type Protocol struct {
 }
 
 type TCP struct {
-	IP   string `json:"ip"`
-	Port string `json:"port"`
-	Name string `json:"name"`
+	IP       string            `json:"ip"`
+	Port     string            `json:"port"`
+	Name     string            `json:"name"`
+	Metadata map[string]string `json:"metadata,omitempty"`
 }
 
 type Extension struct {
@@ -118,6 +119,7 @@ type RequestResponseMatcher interface {
 type Emitting struct {
 	AppStats      *AppStats
 	OutputChannel chan *OutputChannelItem
+	Limiter       *EntryLimiter // nil when every entry is emitted
 }
 
 type Emitter interface {
@@ -125,16 +127,22 @@ type Emitter interface {
 }
 
 func (e *Emitting) Emit(item *OutputChannelItem) {
+	if e.Limiter != nil && !e.Limiter.Admit(item, e.AppStats) {
+		return
+	}
+
 	e.OutputChannel <- item
 	e.AppStats.IncMatchedPairs()
 }
 
 type Entry struct {
 	Id                     uint                   `json:"id"`
+	EntryId                string                 `json:"entryId,omitempty"`
 	Protocol               Protocol               `json:"proto"`
 	Source                 *TCP                   `json:"src"`
 	Destination            *TCP                   `json:"dst"`
 	Namespace              string                 `json:"namespace,omitempty"`
+	Cluster                string                 `json:"cluster,omitempty"`
 	Outgoing               bool                   `json:"outgoing"`
 	Timestamp              int64                  `json:"timestamp"`
 	StartTime              time.Time              `json:"startTime"`
@@ -147,20 +155,24 @@ type Entry struct {
 	ContractResponseReason string                 `json:"contractResponseReason,omitempty"`
 	ContractContent        string                 `json:"contractContent,omitempty"`
 	HTTPPair               string                 `json:"httpPair,omitempty"`
+	GraphQL                *GraphQL               `json:"graphql,omitempty"`
+	Tag                    string                 `json:"tag,omitempty"`
 }
 
 type EntryWrapper struct {
-	Protocol       Protocol                 `json:"protocol"`
-	Representation string                   `json:"representation"`
-	BodySize       int64                    `json:"bodySize"`
-	Data           *Entry                   `json:"data"`
-	Base           *BaseEntry               `json:"base"`
-	Rules          []map[string]interface{} `json:"rulesMatched,omitempty"`
-	IsRulesEnabled bool                     `json:"isRulesEnabled"`
+	Protocol           Protocol                 `json:"protocol"`
+	Representation     string                   `json:"representation"`
+	BodySize           int64                    `json:"bodySize"`
+	Data               *Entry                   `json:"data"`
+	Base               *BaseEntry               `json:"base"`
+	Rules              []map[string]interface{} `json:"rulesMatched,omitempty"`
+	IsRulesEnabled     bool                     `json:"isRulesEnabled"`
+	FormattedTimestamp interface{}              `json:"formattedTimestamp,omitempty"`
 }
 
 type BaseEntry struct {
 	Id             uint            `json:"id"`
+	EntryId        string          `json:"entryId,omitempty"`
 	Protocol       Protocol        `json:"proto,omitempty"`
 	Summary        string          `json:"summary,omitempty"`
 	SummaryQuery   string          `json:"summaryQuery,omitempty"`
@@ -175,6 +187,7 @@ type BaseEntry struct {
 	Latency        int64           `json:"latency"`
 	Rules          ApplicableRules `json:"rules,omitempty"`
 	ContractStatus ContractStatus  `json:"contractStatus"`
+	Tag            string          `json:"tag,omitempty"`
 }
 
 type ApplicableRules struct {
@@ -218,8 +231,32 @@ const (
 )
 
 type HTTPPayload struct {
-	Type uint8
-	Data interface{}
+	Type             uint8
+	Data             interface{}
+	RawHeaders       *RawHeaders
+	InterimResponses []*http.Response
+	GrpcMessages     []*GrpcMessage
+	// WebSocketId is set on the requests upgrading the connection to WebSocket, the frames that follow carry it
+	WebSocketId string
+	// MaskedPii counts the values of each PII pattern that were masked in the body
+	MaskedPii map[string]int
+}
+
+// RawHeaders is the lossless form of an HTTP/1.x header section, it keeps the order, the duplicates and the
+// casing that are lost when the headers are parsed into a map
+type RawHeaders struct {
+	Headers []RawHeader `json:"headers"`
+	// Block holds the exact bytes of the header lines, up to and including the empty line that ends them
+	Block []byte `json:"block"`
+	// Redacted is set when sensitive values were replaced, Block is then no longer byte exact
+	Redacted bool `json:"redacted"`
+}
+
+type RawHeader struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Raw holds the exact bytes of the header line when they aren't valid UTF-8, Name and Value are lossy then
+	Raw []byte `json:"raw,omitempty"`
 }
 
 type HTTPPayloader interface {
@@ -227,11 +264,51 @@ type HTTPPayloader interface {
 }
 
 type HTTPWrapper struct {
-	Method      string               `json:"method"`
-	Url         string               `json:"url"`
-	Details     interface{}          `json:"details"`
-	RawRequest  *HTTPRequestWrapper  `json:"rawRequest"`
-	RawResponse *HTTPResponseWrapper `json:"rawResponse"`
+	Method           string                 `json:"method"`
+	Url              string                 `json:"url"`
+	Details          interface{}            `json:"details"`
+	RawRequest       *HTTPRequestWrapper    `json:"rawRequest"`
+	RawResponse      *HTTPResponseWrapper   `json:"rawResponse"`
+	RawHeaders       *RawHeaders            `json:"rawHeaders,omitempty"`
+	Trailers         []har.Header           `json:"trailers,omitempty"`
+	InterimResponses []*HTTPInterimResponse `json:"interimResponses,omitempty"`
+	GrpcMessages     []*GrpcMessage         `json:"grpcMessages,omitempty"`
+	WebSocketId      string                 `json:"websocketId,omitempty"`
+	MaskedPii        map[string]int         `json:"maskedPii,omitempty"`
+}
+
+// HTTPInterimResponse is a 1xx response, like 100 Continue or 103 Early Hints, that was sent before the final
+// response of a request
+type HTTPInterimResponse struct {
+	Status     int          `json:"status"`
+	StatusText string       `json:"statusText"`
+	Headers    []har.Header `json:"headers"`
+}
+
+// GrpcMessage is a length-prefixed message of a gRPC stream, its protobuf fields are decoded on a best-effort basis
+// and keyed by their field numbers since the schema isn't known
+type GrpcMessage struct {
+	Compressed bool                   `json:"compressed"`
+	Size       int                    `json:"size"`
+	Fields     map[string]interface{} `json:"fields,omitempty"`
+}
+
+func headersToHar(header http.Header) []har.Header {
+	headers := make([]har.Header, 0)
+	for name, values := range header {
+		for _, value := range values {
+			headers = append(headers, har.Header{Name: name, Value: value})
+		}
+	}
+
+	sort.Slice(headers, func(i, j int) bool {
+		if headers[i].Name != headers[j].Name {
+			return headers[i].Name < headers[j].Name
+		}
+		return headers[i].Value < headers[j].Value
+	})
+
+	return headers
 }
 
 func (h HTTPPayload) MarshalJSON() ([]byte, error) {
@@ -279,9 +356,14 @@ func (h HTTPPayload) MarshalJSON() ([]byte, error) {
 			reqWrapper = &HTTPRequestWrapper{Request: h.Data.(*http.Request)}
 		}
 		return json.Marshal(&HTTPWrapper{
-			Method:     harRequest.Method,
-			Details:    harRequest,
-			RawRequest: reqWrapper,
+			Method:       harRequest.Method,
+			Details:      harRequest,
+			RawRequest:   reqWrapper,
+			RawHeaders:   h.RawHeaders,
+			Trailers:     headersToHar(h.Data.(*http.Request).Trailer),
+			GrpcMessages: h.GrpcMessages,
+			WebSocketId:  h.WebSocketId,
+			MaskedPii:    h.MaskedPii,
 		})
 	case TypeHttpResponse:
 		harResponse, err := har.NewResponse(h.Data.(*http.Response), true)
@@ -310,11 +392,24 @@ func (h HTTPPayload) MarshalJSON() ([]byte, error) {
 		if !testEnvEnabled {
 			resWrapper = &HTTPResponseWrapper{Response: h.Data.(*http.Response)}
 		}
+		interimResponses := make([]*HTTPInterimResponse, 0, len(h.InterimResponses))
+		for _, interimResponse := range h.InterimResponses {
+			interimResponses = append(interimResponses, &HTTPInterimResponse{
+				Status:     interimResponse.StatusCode,
+				StatusText: http.StatusText(interimResponse.StatusCode),
+				Headers:    headersToHar(interimResponse.Header),
+			})
+		}
 		return json.Marshal(&HTTPWrapper{
-			Method:      "",
-			Url:         "",
-			Details:     harResponse,
-			RawResponse: resWrapper,
+			Method:           "",
+			Url:              "",
+			Details:          harResponse,
+			RawResponse:      resWrapper,
+			RawHeaders:       h.RawHeaders,
+			Trailers:         headersToHar(h.Data.(*http.Response).Trailer),
+			InterimResponses: interimResponses,
+			GrpcMessages:     h.GrpcMessages,
+			MaskedPii:        h.MaskedPii,
 		})
 	default:
 		panic(fmt.Sprintf("HTTP payload cannot be marshaled: %v", h.Type))
@@ -322,11 +417,17 @@ func (h HTTPPayload) MarshalJSON() ([]byte, error) {
 }
 
 type HTTPWrapperTricky struct {
-	Method      string         `json:"method"`
-	Url         string         `json:"url"`
-	Details     interface{}    `json:"details"`
-	RawRequest  *http.Request  `json:"rawRequest"`
-	RawResponse *http.Response `json:"rawResponse"`
+	Method           string                 `json:"method"`
+	Url              string                 `json:"url"`
+	Details          interface{}            `json:"details"`
+	RawRequest       *http.Request          `json:"rawRequest"`
+	RawResponse      *http.Response         `json:"rawResponse"`
+	RawHeaders       *RawHeaders            `json:"rawHeaders,omitempty"`
+	Trailers         []har.Header           `json:"trailers,omitempty"`
+	InterimResponses []*HTTPInterimResponse `json:"interimResponses,omitempty"`
+	GrpcMessages     []*GrpcMessage         `json:"grpcMessages,omitempty"`
+	WebSocketId      string                 `json:"websocketId,omitempty"`
+	MaskedPii        map[string]int         `json:"maskedPii,omitempty"`
 }
 
 type HTTPMessage struct {
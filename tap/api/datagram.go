@@ -0,0 +1,10 @@
+package api
+
+import "time"
+
+// DatagramDissector is implemented next to Dissector by the extensions of the protocols carried over UDP,
+// every datagram holds whole messages so no stream is reassembled. The tapper keeps a single long-lived
+// matcher per extension for all the datagrams, the dissector returns an error if the payload isn't its protocol
+type DatagramDissector interface {
+	DissectDatagram(payload []byte, tcpID *TcpID, captureTime time.Time, emitter Emitter, options *TrafficFilteringOptions, reqResMatcher RequestResponseMatcher) error
+}
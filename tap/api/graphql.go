@@ -0,0 +1,17 @@
+package api
+
+const (
+	GraphQLQuery        = "query"
+	GraphQLMutation     = "mutation"
+	GraphQLSubscription = "subscription"
+)
+
+// GraphQL is the operation of an HTTP entry that carries a GraphQL request, it's kept at the top level of the entry
+// so the queries can filter on e.g. graphql.operation == "getUser" without looking into the body
+type GraphQL struct {
+	Operation string                 `json:"operation"`
+	Type      string                 `json:"type"`
+	Variables map[string]interface{} `json:"variables"`
+	// the number of operations of a batched request, only the first one is described
+	BatchSize int `json:"batchSize,omitempty"`
+}
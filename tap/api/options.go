@@ -4,4 +4,11 @@ type TrafficFilteringOptions struct {
 	IgnoredUserAgents       []string
 	PlainTextMaskingRegexes []*SerializableRegexp
 	DisableRedaction        bool
+	PreserveRawHeaders      bool
+	RedactionRules          []RedactionRule
+	PiiPatterns             []string
+	// SampleRate is the fraction of the connections that are recorded, 0 records all of them like 1
+	SampleRate float64
+	// MaxEntriesPerSecondPerPod caps the entries a tapper records for a pod every second, 0 doesn't cap them
+	MaxEntriesPerSecondPerPod int
 }
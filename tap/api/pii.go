@@ -0,0 +1,25 @@
+package api
+
+import "fmt"
+
+// The PII patterns the tappers detect in the bodies, see TrafficFilteringOptions.PiiPatterns
+const (
+	PiiEmail       = "email"
+	PiiCreditCard  = "credit-card"
+	PiiPhoneNumber = "phone-number"
+	PiiJwt         = "jwt"
+)
+
+// PiiPatterns lists the patterns in the order they're detected, a JWT is masked before the digits in it are taken for
+// a card or a phone number
+var PiiPatterns = []string{PiiJwt, PiiCreditCard, PiiEmail, PiiPhoneNumber}
+
+func ValidatePiiPattern(pattern string) error {
+	for _, known := range PiiPatterns {
+		if pattern == known {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%s is not a PII pattern, the patterns are %v", pattern, PiiPatterns)
+}
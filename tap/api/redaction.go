@@ -0,0 +1,183 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	RedactionInHeader = "header"
+	RedactionInQuery  = "query"
+	RedactionInBody   = "body"
+)
+
+// RedactionRule masks the values of the http entries before they leave the tapper, even with redaction disabled. In
+// is where the values are, the headers or the query params named Name, case insensitively, or the fields of the JSON
+// bodies at the JSON path Name, like $.card.number, $.items[*].price or $..ssn. No Name is all the values of In, the
+// whole body for a body rule, and Regex masks only the matching parts of the values instead of the whole values
+type RedactionRule struct {
+	In    string              `json:"in"`
+	Name  string              `json:"name,omitempty"`
+	Regex *SerializableRegexp `json:"regex,omitempty"`
+}
+
+func (rule *RedactionRule) Validate() error {
+	switch rule.In {
+	case RedactionInHeader, RedactionInQuery:
+	case RedactionInBody:
+		if rule.Name != "" {
+			if _, err := ParseJsonPath(rule.Name); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("a redaction rule is in %q, the rules are in %s, %s or %s", rule.In, RedactionInHeader, RedactionInQuery, RedactionInBody)
+	}
+
+	if rule.Name == "" && rule.Regex == nil {
+		return fmt.Errorf("a %s redaction rule has neither a name nor a regex, it would mask every value", rule.In)
+	}
+
+	return nil
+}
+
+// MatchesName decides if the header or the query param named name is masked by the rule
+func (rule *RedactionRule) MatchesName(name string) bool {
+	return rule.Name == "" || strings.EqualFold(rule.Name, name)
+}
+
+// JsonPathSegment is a field, an index, or a wildcard of an array, Recursive segments match at any depth below the
+// previous segment, like ..ssn
+type JsonPathSegment struct {
+	Field     string
+	Index     int
+	Wildcard  bool
+	Recursive bool
+}
+
+// ParseJsonPath parses the subset of JSONPath the redaction rules use, fields like $.a.b or $['a b'], indexes and
+// wildcards like $.a[0] or $.a[*], and recursive descent like $..a
+func ParseJsonPath(path string) ([]JsonPathSegment, error) {
+	if !strings.HasPrefix(path, "$") {
+		return nil, fmt.Errorf("JSON path %s must start with $", path)
+	}
+
+	segments := make([]JsonPathSegment, 0)
+	rest := path[1:]
+	for rest != "" {
+		var segment JsonPathSegment
+		switch {
+		case strings.HasPrefix(rest, ".."):
+			segment.Recursive = true
+			rest = rest[2:]
+		case strings.HasPrefix(rest, "."):
+			rest = rest[1:]
+		case strings.HasPrefix(rest, "["):
+		default:
+			return nil, fmt.Errorf("invalid JSON path %s at %s", path, rest)
+		}
+
+		if strings.HasPrefix(rest, "[") {
+			end := strings.Index(rest, "]")
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %s, a [ isn't closed", path)
+			}
+
+			selector := rest[1:end]
+			rest = rest[end+1:]
+			if selector == "*" {
+				segment.Wildcard = true
+			} else if quoted, err := strconv.Unquote(strings.ReplaceAll(selector, "'", "\"")); err == nil && quoted != "" {
+				segment.Field = quoted
+			} else if index, err := strconv.Atoi(selector); err == nil && index >= 0 {
+				segment.Index = index
+			} else {
+				return nil, fmt.Errorf("invalid JSON path %s, %s isn't an index, a quoted field or *", path, selector)
+			}
+		} else {
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+
+			segment.Field = rest[:end]
+			rest = rest[end:]
+			if segment.Field == "" {
+				return nil, fmt.Errorf("invalid JSON path %s, a field has no name", path)
+			}
+			if segment.Field == "*" {
+				segment.Field = ""
+				segment.Wildcard = true
+			}
+		}
+
+		segments = append(segments, segment)
+	}
+
+	if len(segments) == 0 {
+		return nil, fmt.Errorf("JSON path %s selects the whole body, a body rule without a name masks it", path)
+	}
+
+	return segments, nil
+}
+
+// ReplaceJsonPath replaces the values at the path of the decoded JSON value with replace, it returns the changed
+// value and whether any value was found
+func ReplaceJsonPath(value interface{}, segments []JsonPathSegment, replace func(interface{}) interface{}) (interface{}, bool) {
+	if len(segments) == 0 {
+		return replace(value), true
+	}
+
+	segment, rest := segments[0], segments[1:]
+	replaced := false
+
+	if segment.Recursive {
+		// the segment applies to the value itself, and then to every value below it
+		nonRecursive := segment
+		nonRecursive.Recursive = false
+		var found bool
+		value, found = ReplaceJsonPath(value, append([]JsonPathSegment{nonRecursive}, rest...), replace)
+		replaced = replaced || found
+
+		switch typed := value.(type) {
+		case map[string]interface{}:
+			for key, child := range typed {
+				if typed[key], found = ReplaceJsonPath(child, segments, replace); found {
+					replaced = true
+				}
+			}
+		case []interface{}:
+			for i, child := range typed {
+				if typed[i], found = ReplaceJsonPath(child, segments, replace); found {
+					replaced = true
+				}
+			}
+		}
+
+		return value, replaced
+	}
+
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			if segment.Wildcard || (segment.Field != "" && key == segment.Field) {
+				var found bool
+				if typed[key], found = ReplaceJsonPath(child, rest, replace); found {
+					replaced = true
+				}
+			}
+		}
+	case []interface{}:
+		for i, child := range typed {
+			if segment.Wildcard || (segment.Field == "" && i == segment.Index) {
+				var found bool
+				if typed[i], found = ReplaceJsonPath(child, rest, replace); found {
+					replaced = true
+				}
+			}
+		}
+	}
+
+	return value, replaced
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+	"time"
+)
+
+// EntryLimiter samples the connections and caps the entries per second of every tapped pod, the entries it drops are
+// never sent to the API server. A connection is either recorded or dropped as a whole, so the requests of a sampled
+// connection keep their responses.
+type EntryLimiter struct {
+	sampleThreshold           uint32 // the connections whose hash is below it are recorded
+	maxEntriesPerSecondPerPod int
+	now                       func() time.Time
+
+	mutex        sync.Mutex
+	windowSecond int64
+	podEntries   map[string]int
+}
+
+// NewEntryLimiter returns nil when every entry is recorded, a sample rate of 0 or 1 records every connection and a
+// max of 0 entries per second doesn't cap the pods
+func NewEntryLimiter(sampleRate float64, maxEntriesPerSecondPerPod int) *EntryLimiter {
+	isSampled := sampleRate > 0 && sampleRate < 1
+	if !isSampled && maxEntriesPerSecondPerPod <= 0 {
+		return nil
+	}
+
+	limiter := &EntryLimiter{
+		sampleThreshold:           math.MaxUint32,
+		maxEntriesPerSecondPerPod: maxEntriesPerSecondPerPod,
+		now:                       time.Now,
+		podEntries:                make(map[string]int),
+	}
+	if isSampled {
+		limiter.sampleThreshold = uint32(sampleRate * math.MaxUint32)
+	}
+
+	return limiter
+}
+
+// Admit decides if the entry is recorded, the dropped entries are counted in stats
+func (limiter *EntryLimiter) Admit(item *OutputChannelItem, stats *AppStats) bool {
+	if item.ConnectionInfo == nil {
+		return true
+	}
+
+	if !limiter.isSampled(item.ConnectionInfo) {
+		stats.IncSampledOutEntries()
+		return false
+	}
+
+	if !limiter.isUnderPodLimit(item.ConnectionInfo) {
+		stats.IncRateLimitedEntries()
+		return false
+	}
+
+	return true
+}
+
+func (limiter *EntryLimiter) isSampled(connectionInfo *ConnectionInfo) bool {
+	if limiter.sampleThreshold == math.MaxUint32 {
+		return true
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(connectionInfo.ClientIP + ":" + connectionInfo.ClientPort + "-" + connectionInfo.ServerIP + ":" + connectionInfo.ServerPort))
+	return hash.Sum32() < limiter.sampleThreshold
+}
+
+// isUnderPodLimit counts the entry for the tapped pod, the server of the incoming entries and the client of the
+// outgoing ones
+func (limiter *EntryLimiter) isUnderPodLimit(connectionInfo *ConnectionInfo) bool {
+	if limiter.maxEntriesPerSecondPerPod <= 0 {
+		return true
+	}
+
+	pod := connectionInfo.ServerIP
+	if connectionInfo.IsOutgoing {
+		pod = connectionInfo.ClientIP
+	}
+
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+
+	if second := limiter.now().Unix(); second != limiter.windowSecond {
+		limiter.windowSecond = second
+		limiter.podEntries = make(map[string]int)
+	}
+
+	if limiter.podEntries[pod] >= limiter.maxEntriesPerSecondPerPod {
+		return false
+	}
+
+	limiter.podEntries[pod]++
+	return true
+}
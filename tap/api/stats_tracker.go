@@ -14,6 +14,12 @@ type AppStats struct {
 	TlsConnectionsCount         uint64    `json:"tlsConnectionsCount"`
 	MatchedPairs                uint64    `json:"matchedPairs"`
 	DroppedTcpStreams           uint64    `json:"droppedTcpStreams"`
+	SampledOutEntries           uint64    `json:"sampledOutEntries"`
+	RateLimitedEntries          uint64    `json:"rateLimitedEntries"`
+	// unlike the counters above they aren't reset by DumpStats
+	droppedTcpStreamsTotal  uint64
+	sampledOutEntriesTotal  uint64
+	rateLimitedEntriesTotal uint64
 }
 
 func (as *AppStats) IncMatchedPairs() {
@@ -22,6 +28,32 @@ func (as *AppStats) IncMatchedPairs() {
 
 func (as *AppStats) IncDroppedTcpStreams() {
 	atomic.AddUint64(&as.DroppedTcpStreams, 1)
+	atomic.AddUint64(&as.droppedTcpStreamsTotal, 1)
+}
+
+// DroppedTcpStreamsTotal returns the tcp streams dropped since the tapper started
+func (as *AppStats) DroppedTcpStreamsTotal() uint64 {
+	return atomic.LoadUint64(&as.droppedTcpStreamsTotal)
+}
+
+func (as *AppStats) IncSampledOutEntries() {
+	atomic.AddUint64(&as.SampledOutEntries, 1)
+	atomic.AddUint64(&as.sampledOutEntriesTotal, 1)
+}
+
+// SampledOutEntriesTotal returns the entries of the connections left out by the sampling since the tapper started
+func (as *AppStats) SampledOutEntriesTotal() uint64 {
+	return atomic.LoadUint64(&as.sampledOutEntriesTotal)
+}
+
+func (as *AppStats) IncRateLimitedEntries() {
+	atomic.AddUint64(&as.RateLimitedEntries, 1)
+	atomic.AddUint64(&as.rateLimitedEntriesTotal, 1)
+}
+
+// RateLimitedEntriesTotal returns the entries dropped over the entries per second of their pod since the tapper started
+func (as *AppStats) RateLimitedEntriesTotal() uint64 {
+	return atomic.LoadUint64(&as.rateLimitedEntriesTotal)
 }
 
 func (as *AppStats) IncPacketsCount() uint64 {
@@ -59,6 +91,8 @@ func (as *AppStats) DumpStats() *AppStats {
 	currentAppStats.TlsConnectionsCount = resetUint64(&as.TlsConnectionsCount)
 	currentAppStats.MatchedPairs = resetUint64(&as.MatchedPairs)
 	currentAppStats.DroppedTcpStreams = resetUint64(&as.DroppedTcpStreams)
+	currentAppStats.SampledOutEntries = resetUint64(&as.SampledOutEntries)
+	currentAppStats.RateLimitedEntries = resetUint64(&as.RateLimitedEntries)
 
 	return currentAppStats
 }
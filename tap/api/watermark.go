@@ -0,0 +1,40 @@
+package api
+
+import "net/http"
+
+// MizuTrafficHeaderName watermarks the requests mizu sends itself, the tappers drop them, their responses and the
+// frames of their WebSocket connections, so they never show up in the entries, the stats or the service map. The
+// value is the component that sent the request
+const MizuTrafficHeaderName = "X-Mizu-Traffic"
+
+// MizuReplayHeaderName marks the requests re-sent by mizu replay, they're dropped like the watermarked ones
+const MizuReplayHeaderName = "X-Mizu-Replay"
+
+const (
+	MizuTrafficTapper      = "tapper"
+	MizuTrafficCli         = "cli"
+	MizuTrafficHealthCheck = "health-check"
+)
+
+func IsMizuTraffic(header http.Header) bool {
+	return header.Get(MizuTrafficHeaderName) != "" || header.Get(MizuReplayHeaderName) != ""
+}
+
+// MizuTrafficTransport watermarks the requests of a client with Source, Base sends them, http.DefaultTransport when
+// it's nil
+type MizuTrafficTransport struct {
+	Source string
+	Base   http.RoundTripper
+}
+
+func (transport *MizuTrafficTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	// a RoundTripper must not modify the request it's given
+	watermarked := request.Clone(request.Context())
+	watermarked.Header.Set(MizuTrafficHeaderName, transport.Source)
+
+	base := transport.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(watermarked)
+}
@@ -0,0 +1,118 @@
+// Package capturepolicy applies the cluster admin's capture policy to the packets of the tapper before they're dumped
+// or dissected. The policy is read from a source every refreshInterval, so the admin's changes apply without
+// restarting the tappers
+package capturepolicy
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/up9inc/mizu/shared"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+const refreshInterval = 30 * time.Second
+
+var (
+	// ErrNotPublished is returned by a source when the cluster admin didn't publish a capture policy
+	ErrNotPublished = errors.New("no capture policy was published")
+	// ErrMissing is returned by a source when the cluster admin published a capture policy that no longer exists
+	ErrMissing = errors.New("the published capture policy is missing")
+)
+
+// Source reads the content of the capture policy
+type Source func() ([]byte, error)
+
+var (
+	matcher atomic.Value // *shared.CapturePolicyMatcher
+	// the last successful resolution of every host name, so a failing dns lookup doesn't drop a denied host
+	resolvedHosts = make(map[string][]string)
+	description   string
+	isRead        bool
+)
+
+func init() {
+	matcher.Store((*shared.CapturePolicyMatcher)(nil))
+}
+
+// Start reads the policy of the source and keeps reading it in the background, no source allows every host
+func Start(source Source) {
+	if source == nil {
+		store(nil, "No capture policy source, capturing the traffic of every host")
+		return
+	}
+
+	refresh(source)
+
+	go func() {
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			refresh(source)
+		}
+	}()
+}
+
+// Allows reports whether the traffic between the addresses may be captured
+func Allows(addresses ...string) bool {
+	return matcher.Load().(*shared.CapturePolicyMatcher).Allows(addresses...)
+}
+
+func refresh(source Source) {
+	content, err := source()
+	if errors.Is(err, ErrNotPublished) {
+		isRead = true
+		store(nil, "No capture policy, capturing the traffic of every host")
+		return
+	} else if errors.Is(err, ErrMissing) {
+		isRead = true
+		store(shared.NewDenyAllCapturePolicyMatcher(), "The capture policy of the cluster admin is missing, capturing nothing until it's restored")
+		return
+	} else if err != nil {
+		// a failing read keeps the last policy, and captures nothing while no policy was ever read
+		if !isRead {
+			store(shared.NewDenyAllCapturePolicyMatcher(), fmt.Sprintf("Failed reading the capture policy, capturing nothing until it's read: %v", err))
+		} else {
+			logger.Log.Warningf("Failed reading the capture policy, keeping the last one: %v", err)
+		}
+		return
+	}
+
+	isRead = true
+	policy, err := shared.ParseCapturePolicy(content)
+	if err != nil {
+		store(shared.NewDenyAllCapturePolicyMatcher(), fmt.Sprintf("Capture policy is invalid, capturing nothing until it's fixed: %v", err))
+		return
+	}
+
+	store(shared.NewCapturePolicyMatcher(policy, lookupHost), fmt.Sprintf("Capture policy allows the hosts %v, denies the hosts %v", policy.AllowedHosts, policy.DeniedHosts))
+}
+
+func store(policyMatcher *shared.CapturePolicyMatcher, policyDescription string) {
+	matcher.Store(policyMatcher)
+
+	if policyDescription != description {
+		description = policyDescription
+		logger.Log.Info(policyDescription)
+	}
+}
+
+func lookupHost(host string) ([]string, error) {
+	addresses, err := net.LookupHost(host)
+	if err != nil {
+		if resolved, ok := resolvedHosts[host]; ok {
+			logger.Log.Debugf("Failed resolving the capture policy host %s, keeping its last addresses %v: %v", host, resolved, err)
+			return resolved, nil
+		}
+
+		logger.Log.Debugf("Failed resolving the capture policy host %s: %v", host, err)
+		return nil, err
+	}
+
+	resolvedHosts[host] = addresses
+	return addresses, nil
+}
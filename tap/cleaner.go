@@ -23,6 +23,7 @@ type Cleaner struct {
 	stats             CleanerStats
 	statsMutex        sync.Mutex
 	streamsMap        *tcpStreamMap
+	datagramMatchers  []api.RequestResponseMatcher
 }
 
 func (cl *Cleaner) clean() {
@@ -43,6 +44,11 @@ func (cl *Cleaner) clean() {
 		return true
 	})
 
+	// the datagram matchers outlive the connections, their unanswered messages are dropped at the same age
+	for _, reqResMatcher := range cl.datagramMatchers {
+		cl.stats.deleted += deleteOlderThan(reqResMatcher.GetMap(), startCleanTime.Add(-cl.connectionTimeout))
+	}
+
 	cl.statsMutex.Lock()
 	logger.Log.Debugf("Assembler Stats after cleaning %s", cl.assembler.Dump())
 	cl.stats.flushed += flushed
@@ -282,6 +282,7 @@ func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
 
 	return &api.BaseEntry{
 		Id:             entry.Id,
+		EntryId:        entry.EntryId,
 		Protocol:       entry.Protocol,
 		Summary:        summary,
 		SummaryQuery:   summaryQuery,
@@ -0,0 +1,55 @@
+package cassandra
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// the stream id pairs the frames, the responses to the requests sent concurrently on a connection come in any order
+func handleRequest(tcpID *api.TcpID, captureTime time.Time, emitter api.Emitter, request *CqlRequest, reqResMatcher *requestResponseMatcher) {
+	ident := fmt.Sprintf(
+		"%s_%s_%s_%s_%d",
+		tcpID.SrcIP,
+		tcpID.DstIP,
+		tcpID.SrcPort,
+		tcpID.DstPort,
+		request.Stream,
+	)
+
+	item := reqResMatcher.registerRequest(ident, request, captureTime)
+	if item != nil {
+		item.ConnectionInfo = &api.ConnectionInfo{
+			ClientIP:   tcpID.SrcIP,
+			ClientPort: tcpID.SrcPort,
+			ServerIP:   tcpID.DstIP,
+			ServerPort: tcpID.DstPort,
+			IsOutgoing: true,
+		}
+		emitter.Emit(item)
+	}
+}
+
+func handleResponse(tcpID *api.TcpID, captureTime time.Time, emitter api.Emitter, stream int16, response *CqlResponse, reqResMatcher *requestResponseMatcher) {
+	ident := fmt.Sprintf(
+		"%s_%s_%s_%s_%d",
+		tcpID.DstIP,
+		tcpID.SrcIP,
+		tcpID.DstPort,
+		tcpID.SrcPort,
+		stream,
+	)
+
+	item := reqResMatcher.registerResponse(ident, response, captureTime)
+	if item != nil {
+		item.ConnectionInfo = &api.ConnectionInfo{
+			ClientIP:   tcpID.DstIP,
+			ClientPort: tcpID.DstPort,
+			ServerIP:   tcpID.SrcIP,
+			ServerPort: tcpID.SrcPort,
+			IsOutgoing: false,
+		}
+		emitter.Emit(item)
+	}
+}
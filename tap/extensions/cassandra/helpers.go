@@ -0,0 +1,221 @@
+package cassandra
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type CqlPayload struct {
+	Data interface{}
+}
+
+type CqlPayloader interface {
+	MarshalJSON() ([]byte, error)
+}
+
+func (h CqlPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Data)
+}
+
+type CqlWrapper struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Details interface{} `json:"details"`
+}
+
+func representRequest(request map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Opcode",
+			Value:    request["opcode"].(string),
+			Selector: `request.opcode`,
+		},
+		{
+			Name:     "Protocol Version",
+			Value:    fmt.Sprintf("%v", request["version"]),
+			Selector: `request.version`,
+		},
+		{
+			Name:     "Stream",
+			Value:    fmt.Sprintf("%v", request["stream"]),
+			Selector: `request.stream`,
+		},
+	}
+	details = appendOptionalTableData(details, request, "Consistency", "consistency", `request.`)
+	details = appendOptionalTableData(details, request, "Serial Consistency", "serialConsistency", `request.`)
+	details = appendOptionalTableData(details, request, "Prepared Id", "preparedId", `request.`)
+	details = appendOptionalTableData(details, request, "Keyspace", "keyspace", `request.`)
+	details = appendOptionalTableData(details, request, "Batch Type", "batchType", `request.`)
+	if values, ok := request["values"].(float64); ok && values > 0 {
+		details = append(details, api.TableData{
+			Name:     "Values",
+			Value:    fmt.Sprintf("%v", values),
+			Selector: `request.values`,
+		})
+	}
+	if pageSize, ok := request["pageSize"].(float64); ok {
+		details = append(details, api.TableData{
+			Name:     "Page Size",
+			Value:    fmt.Sprintf("%v", pageSize),
+			Selector: `request.pageSize`,
+		})
+	}
+	details = append(details, api.TableData{
+		Name:     "Tracing",
+		Value:    fmt.Sprintf("%v", request["tracing"]),
+		Selector: `request.tracing`,
+	})
+	if compressed, ok := request["compressed"].(bool); ok && compressed {
+		details = append(details, api.TableData{
+			Name:     "Compressed",
+			Value:    "true",
+			Selector: `request.compressed`,
+		})
+	}
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if options, ok := request["options"].(map[string]interface{}); ok && len(options) > 0 {
+		rows := make([]api.TableData, 0, len(options))
+		for name, value := range options {
+			rows = append(rows, api.TableData{
+				Name:     name,
+				Value:    fmt.Sprintf("%v", value),
+				Selector: fmt.Sprintf(`request.options["%s"]`, name),
+			})
+		}
+		optionsJson, _ := json.Marshal(rows)
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Options",
+			Data:  string(optionsJson),
+		})
+	}
+
+	if statements, ok := request["statements"].([]interface{}); ok && len(statements) > 0 {
+		rows := make([]api.TableData, 0, len(statements))
+		for i, item := range statements {
+			statement := item.(map[string]interface{})
+			rows = append(rows, api.TableData{
+				Name:     fmt.Sprintf("%d", i),
+				Value:    statement["query"].(string),
+				Selector: fmt.Sprintf(`request.statements[%d].query`, i),
+			})
+		}
+		statementsJson, _ := json.Marshal(rows)
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Statements",
+			Data:  string(statementsJson),
+		})
+	} else if query, ok := request["query"].(string); ok && query != "" {
+		representation = append(representation, api.SectionData{
+			Type:     api.BODY,
+			Title:    "Query",
+			Data:     query,
+			Selector: `request.query`,
+		})
+	}
+
+	return
+}
+
+func representResponse(response map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Opcode",
+			Value:    response["opcode"].(string),
+			Selector: `response.opcode`,
+		},
+		{
+			Name:     "Status",
+			Value:    response["status"].(string),
+			Selector: `response.status`,
+		},
+	}
+	details = appendOptionalTableData(details, response, "Kind", "kind", `response.`)
+	if response["kind"] == resultKinds[resultRows] {
+		columns := make([]string, 0)
+		if values, ok := response["columns"].([]interface{}); ok {
+			for _, value := range values {
+				columns = append(columns, fmt.Sprintf("%v", value))
+			}
+		}
+		details = append(details, []api.TableData{
+			{
+				Name:     "Rows",
+				Value:    fmt.Sprintf("%v", response["rows"]),
+				Selector: `response.rows`,
+			},
+			{
+				Name:     "Columns",
+				Value:    strings.Join(columns, ", "),
+				Selector: `response.columns`,
+			},
+			{
+				Name:     "Has More Pages",
+				Value:    fmt.Sprintf("%v", response["hasMorePages"]),
+				Selector: `response.hasMorePages`,
+			},
+		}...)
+	}
+	details = appendOptionalTableData(details, response, "Keyspace", "keyspace", `response.`)
+	details = appendOptionalTableData(details, response, "Prepared Id", "preparedId", `response.`)
+	details = appendOptionalTableData(details, response, "Schema Change", "schemaChange", `response.`)
+	details = appendOptionalTableData(details, response, "Tracing Id", "tracingId", `response.`)
+	if warnings, ok := response["warnings"].([]interface{}); ok {
+		for i, warning := range warnings {
+			details = append(details, api.TableData{
+				Name:     "Warning",
+				Value:    fmt.Sprintf("%v", warning),
+				Selector: fmt.Sprintf(`response.warnings[%d]`, i),
+			})
+		}
+	}
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if cqlError, ok := response["error"].(map[string]interface{}); ok {
+		rows := []api.TableData{
+			{
+				Name:     "Code",
+				Value:    fmt.Sprintf("%v", cqlError["code"]),
+				Selector: `response.error.code`,
+			},
+		}
+		rows = appendOptionalTableData(rows, cqlError, "Name", "name", `response.error.`)
+		rows = appendOptionalTableData(rows, cqlError, "Message", "message", `response.error.`)
+		errorJson, _ := json.Marshal(rows)
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Error",
+			Data:  string(errorJson),
+		})
+	}
+
+	return
+}
+
+func appendOptionalTableData(rows []api.TableData, generic map[string]interface{}, name string, key string, selectorPrefix string) []api.TableData {
+	value, ok := generic[key].(string)
+	if !ok || value == "" {
+		return rows
+	}
+
+	return append(rows, api.TableData{
+		Name:     name,
+		Value:    value,
+		Selector: fmt.Sprintf("%s%s", selectorPrefix, key),
+	})
+}
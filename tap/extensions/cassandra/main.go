@@ -0,0 +1,206 @@
+package cassandra
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+var protocol api.Protocol = api.Protocol{
+	Name:            "cql",
+	LongName:        "Cassandra Query Language Native Protocol",
+	Abbreviation:    "CQL",
+	Macro:           "cql",
+	Version:         "4",
+	BackgroundColor: "#1287b1",
+	ForegroundColor: "#ffffff",
+	FontSize:        12,
+	ReferenceLink:   "https://github.com/apache/cassandra/blob/trunk/doc/native_protocol_v4.spec",
+	Ports:           []string{"9042"},
+	Priority:        10,
+}
+
+type dissecting string
+
+func (d dissecting) Register(extension *api.Extension) {
+	extension.Protocol = &protocol
+}
+
+func (d dissecting) Ping() {
+	log.Printf("pong %s", protocol.Name)
+}
+
+// Dissect reads the versions 3 to 5 of the protocol, a connection of the version 5 is only followed from its start
+func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, superIdentifier *api.SuperIdentifier, emitter api.Emitter, options *api.TrafficFilteringOptions, _reqResMatcher api.RequestResponseMatcher) error {
+	reqResMatcher := _reqResMatcher.(*requestResponseMatcher)
+	reader := newFrameReader(b, isClient)
+
+	// a CQL frame header is too generic to be trusted off port 9042, a connection elsewhere must start with its STARTUP
+	identified := isCqlPort(tcpID.SrcPort) || isCqlPort(tcpID.DstPort)
+
+	for {
+		if superIdentifier.Protocol != nil && superIdentifier.Protocol != &protocol {
+			return errors.New("Identified by another protocol")
+		}
+
+		f, err := reader.next()
+		if err != nil {
+			return err
+		}
+
+		if !identified {
+			if !isConnectionStart(f) {
+				return errors.New("Not a CQL connection")
+			}
+			identified = true
+		}
+
+		if isClient {
+			request, err := parseRequest(f)
+			if err != nil {
+				return err
+			}
+			superIdentifier.Protocol = &protocol
+			handleRequest(tcpID, superTimer.CaptureTime, emitter, request, reqResMatcher)
+			continue
+		}
+
+		// the events pushed by the server aren't answering any request
+		if f.opcode == opcodeEvent {
+			superIdentifier.Protocol = &protocol
+			continue
+		}
+		response, err := parseResponse(f)
+		if err != nil {
+			return err
+		}
+		superIdentifier.Protocol = &protocol
+		handleResponse(tcpID, superTimer.CaptureTime, emitter, f.stream, response, reqResMatcher)
+	}
+}
+
+func isConnectionStart(f *frame) bool {
+	switch f.opcode {
+	case opcodeOptions, opcodeStartup, opcodeSupported, opcodeReady, opcodeAuthenticate:
+		return true
+	default:
+		return false
+	}
+}
+
+func isCqlPort(port string) bool {
+	for _, cqlPort := range protocol.Ports {
+		if port == cqlPort {
+			return true
+		}
+	}
+	return false
+}
+
+func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	request := item.Pair.Request.Payload.(map[string]interface{})
+	response := item.Pair.Response.Payload.(map[string]interface{})
+	reqDetails := request["details"].(map[string]interface{})
+	resDetails := response["details"].(map[string]interface{})
+
+	elapsedTime := item.Pair.Response.CaptureTime.Sub(item.Pair.Request.CaptureTime).Round(time.Millisecond).Milliseconds()
+	if elapsedTime < 0 {
+		elapsedTime = 0
+	}
+	return &api.Entry{
+		Protocol: protocol,
+		Source: &api.TCP{
+			Name: resolvedSource,
+			IP:   item.ConnectionInfo.ClientIP,
+			Port: item.ConnectionInfo.ClientPort,
+		},
+		Destination: &api.TCP{
+			Name: resolvedDestination,
+			IP:   item.ConnectionInfo.ServerIP,
+			Port: item.ConnectionInfo.ServerPort,
+		},
+		Namespace:   namespace,
+		Outgoing:    item.ConnectionInfo.IsOutgoing,
+		Request:     reqDetails,
+		Response:    resDetails,
+		Timestamp:   item.Timestamp,
+		StartTime:   item.Pair.Request.CaptureTime,
+		ElapsedTime: elapsedTime,
+	}
+
+}
+
+func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
+	status := 0
+	statusQuery := ""
+
+	method := entry.Request["opcode"].(string)
+	methodQuery := fmt.Sprintf(`request.opcode == "%s"`, method)
+
+	summary := ""
+	summaryQuery := ""
+	if query, ok := entry.Request["query"].(string); ok && query != "" {
+		summary = query
+		summaryQuery = fmt.Sprintf(`request.query == %s`, strconv.Quote(summary))
+	}
+
+	if cqlError, ok := entry.Response["error"].(map[string]interface{}); ok {
+		if summary == "" {
+			summary = cqlError["name"].(string)
+		} else {
+			summary = fmt.Sprintf("%s (%s)", summary, cqlError["name"])
+		}
+	}
+
+	return &api.BaseEntry{
+		Id:             entry.Id,
+		EntryId:        entry.EntryId,
+		Protocol:       entry.Protocol,
+		Summary:        summary,
+		SummaryQuery:   summaryQuery,
+		Status:         status,
+		StatusQuery:    statusQuery,
+		Method:         method,
+		MethodQuery:    methodQuery,
+		Timestamp:      entry.Timestamp,
+		Source:         entry.Source,
+		Destination:    entry.Destination,
+		IsOutgoing:     entry.Outgoing,
+		Latency:        entry.ElapsedTime,
+		Rules:          entry.Rules,
+		ContractStatus: entry.ContractStatus,
+	}
+}
+
+func (d dissecting) Represent(request map[string]interface{}, response map[string]interface{}) (object []byte, bodySize int64, err error) {
+	bodySize = 0
+	representation := make(map[string]interface{})
+	repRequest := representRequest(request)
+	repResponse := representResponse(response)
+	representation["request"] = repRequest
+	representation["response"] = repResponse
+	object, err = json.Marshal(representation)
+	return
+}
+
+func (d dissecting) Macros() map[string]string {
+	return map[string]string{
+		`cql`: fmt.Sprintf(`proto.name == "%s"`, protocol.Name),
+	}
+}
+
+func (d dissecting) NewResponseRequestMatcher() api.RequestResponseMatcher {
+	return createResponseRequestMatcher()
+}
+
+var Dissector dissecting
+
+func NewDissector() api.Dissector {
+	return Dissector
+}
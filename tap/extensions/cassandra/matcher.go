@@ -0,0 +1,128 @@
+package cassandra
+
+import (
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// Key is `{client_ip}_{server_ip}_{client_port}_{server_port}_{stream}`
+type requestResponseMatcher struct {
+	openMessagesMap *sync.Map
+	// the queries of the statements prepared on the connection, by prepared id
+	statements     map[string]string
+	statementsLock sync.Mutex
+}
+
+func createResponseRequestMatcher() api.RequestResponseMatcher {
+	return &requestResponseMatcher{
+		openMessagesMap: &sync.Map{},
+		statements:      make(map[string]string),
+	}
+}
+
+func (matcher *requestResponseMatcher) GetMap() *sync.Map {
+	return matcher.openMessagesMap
+}
+func (matcher *requestResponseMatcher) SetMaxTry(value int) {
+}
+
+func (matcher *requestResponseMatcher) registerRequest(ident string, request *CqlRequest, captureTime time.Time) *api.OutputChannelItem {
+	requestCqlMessage := api.GenericMessage{
+		IsRequest:   true,
+		CaptureTime: captureTime,
+		Payload: CqlPayload{
+			Data: &CqlWrapper{
+				Method:  request.Opcode,
+				Url:     "",
+				Details: request,
+			},
+		},
+	}
+
+	if response, found := matcher.openMessagesMap.Load(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		responseCqlMessage := response.(*api.GenericMessage)
+		if !responseCqlMessage.IsRequest {
+			matcher.openMessagesMap.Delete(ident)
+			return matcher.preparePair(&requestCqlMessage, responseCqlMessage)
+		}
+	}
+
+	matcher.openMessagesMap.Store(ident, &requestCqlMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) registerResponse(ident string, response *CqlResponse, captureTime time.Time) *api.OutputChannelItem {
+	responseCqlMessage := api.GenericMessage{
+		IsRequest:   false,
+		CaptureTime: captureTime,
+		Payload: CqlPayload{
+			Data: &CqlWrapper{
+				Method:  response.Status,
+				Url:     "",
+				Details: response,
+			},
+		},
+	}
+
+	if request, found := matcher.openMessagesMap.Load(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		requestCqlMessage := request.(*api.GenericMessage)
+		if requestCqlMessage.IsRequest {
+			matcher.openMessagesMap.Delete(ident)
+			return matcher.preparePair(requestCqlMessage, &responseCqlMessage)
+		}
+	}
+
+	matcher.openMessagesMap.Store(ident, &responseCqlMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) preparePair(requestCqlMessage *api.GenericMessage, responseCqlMessage *api.GenericMessage) *api.OutputChannelItem {
+	request := requestCqlMessage.Payload.(CqlPayload).Data.(*CqlWrapper).Details.(*CqlRequest)
+	response := responseCqlMessage.Payload.(CqlPayload).Data.(*CqlWrapper).Details.(*CqlResponse)
+	matcher.resolveStatement(request, response)
+
+	return &api.OutputChannelItem{
+		Protocol:       protocol,
+		Timestamp:      requestCqlMessage.CaptureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: nil,
+		Pair: &api.RequestResponsePair{
+			Request:  *requestCqlMessage,
+			Response: *responseCqlMessage,
+		},
+	}
+}
+
+// resolveStatement remembers the prepared statements and fills in the query of the executions referring to them,
+// a statement executed on another connection than the one preparing it is left without its query
+func (matcher *requestResponseMatcher) resolveStatement(request *CqlRequest, response *CqlResponse) {
+	matcher.statementsLock.Lock()
+	defer matcher.statementsLock.Unlock()
+
+	if request.Opcode == requestOpcodes[opcodePrepare] {
+		if response.PreparedId == "" {
+			return
+		}
+		if _, found := matcher.statements[response.PreparedId]; !found && len(matcher.statements) >= maxStatements {
+			// the statements are never closed, they're evicted only to make room
+			for preparedId := range matcher.statements {
+				delete(matcher.statements, preparedId)
+				break
+			}
+		}
+		matcher.statements[response.PreparedId] = request.Query
+		return
+	}
+
+	for i := range request.Statements {
+		if request.Statements[i].PreparedId != "" && request.Statements[i].Query == "" {
+			request.Statements[i].Query = matcher.statements[request.Statements[i].PreparedId]
+		}
+	}
+	if request.PreparedId != "" && request.Query == "" {
+		request.Query = matcher.statements[request.PreparedId]
+	}
+}
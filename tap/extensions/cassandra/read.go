@@ -0,0 +1,714 @@
+package cassandra
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var errMalformedFrame = errors.New("malformed frame")
+
+type frame struct {
+	version byte
+	flags   byte
+	stream  int16
+	opcode  byte
+	body    []byte
+	// the body was longer than maxRetainedLength, its parsing may run out of bytes
+	truncated bool
+}
+
+// segmentReader strips the segments of the version 5 framing, the frames are read across them as a single stream
+type segmentReader struct {
+	b         *bufio.Reader
+	remaining int
+	started   bool
+}
+
+// Read doesn't check the CRCs, a compressed segment has a longer header and is rejected by the checks of the frame it carries
+func (s *segmentReader) Read(p []byte) (int, error) {
+	for s.remaining == 0 {
+		if s.started {
+			if _, err := s.b.Discard(segmentTrailerLength); err != nil {
+				return 0, err
+			}
+		}
+		header := make([]byte, segmentHeaderLength)
+		if _, err := io.ReadFull(s.b, header); err != nil {
+			return 0, err
+		}
+		s.remaining = int(uint32(header[0])|uint32(header[1])<<8|uint32(header[2])<<16) & segmentLengthMask
+		s.started = true
+	}
+
+	if len(p) > s.remaining {
+		p = p[:s.remaining]
+	}
+	n, err := s.b.Read(p)
+	s.remaining -= n
+	return n, err
+}
+
+type frameReader struct {
+	b        *bufio.Reader
+	isClient bool
+	// set once the connection switched to the segments of the version 5
+	segments *segmentReader
+}
+
+func newFrameReader(b *bufio.Reader, isClient bool) *frameReader {
+	return &frameReader{b: b, isClient: isClient}
+}
+
+func (r *frameReader) source() io.Reader {
+	if r.segments != nil {
+		return r.segments
+	}
+	return r.b
+}
+
+func (r *frameReader) next() (*frame, error) {
+	src := r.source()
+
+	header := make([]byte, frameHeaderLength)
+	if _, err := io.ReadFull(src, header); err != nil {
+		return nil, err
+	}
+
+	f := &frame{
+		version: header[0] & versionMask,
+		flags:   header[1],
+		stream:  int16(binary.BigEndian.Uint16(header[2:4])),
+		opcode:  header[4],
+	}
+	length := binary.BigEndian.Uint32(header[5:9])
+
+	isResponse := header[0]&directionResponse != 0
+	if f.version < minVersion || f.version > maxVersion || isResponse == r.isClient || f.flags&0xe0 != 0 || length > maxFrameLength {
+		return nil, errMalformedFrame
+	}
+	if r.isClient {
+		if _, ok := requestOpcodes[f.opcode]; !ok {
+			return nil, errMalformedFrame
+		}
+	} else if _, ok := responseOpcodes[f.opcode]; !ok {
+		return nil, errMalformedFrame
+	}
+
+	retained := length
+	if retained > maxRetainedLength {
+		retained = maxRetainedLength
+		f.truncated = true
+	}
+	f.body = make([]byte, retained)
+	if _, err := io.ReadFull(src, f.body); err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, src, int64(length-retained)); err != nil {
+		return nil, err
+	}
+
+	// the version 5 switches to the segments right after the STARTUP and its answer, each side on its own
+	if f.version == 5 && r.segments == nil {
+		if (r.isClient && f.opcode == opcodeStartup) || (!r.isClient && (f.opcode == opcodeReady || f.opcode == opcodeAuthenticate)) {
+			r.segments = &segmentReader{b: r.b}
+		}
+	}
+
+	return f, nil
+}
+
+// bodyReader reads the notations of the specification, running out of bytes is io.ErrUnexpectedEOF
+type bodyReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *bodyReader) take(length int) ([]byte, error) {
+	if length < 0 || r.offset+length > len(r.data) {
+		return nil, io.ErrUnexpectedEOF
+	}
+	value := r.data[r.offset : r.offset+length]
+	r.offset += length
+	return value, nil
+}
+
+func (r *bodyReader) byte() (byte, error) {
+	data, err := r.take(1)
+	if err != nil {
+		return 0, err
+	}
+	return data[0], nil
+}
+
+func (r *bodyReader) short() (uint16, error) {
+	data, err := r.take(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(data), nil
+}
+
+func (r *bodyReader) int() (int32, error) {
+	data, err := r.take(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(data)), nil
+}
+
+func (r *bodyReader) string() (string, error) {
+	length, err := r.short()
+	if err != nil {
+		return "", err
+	}
+	data, err := r.take(int(length))
+	return string(data), err
+}
+
+func (r *bodyReader) longString() (string, error) {
+	length, err := r.int()
+	if err != nil {
+		return "", err
+	}
+	data, err := r.take(int(length))
+	return string(data), err
+}
+
+// bytes returns nil for the null and the unset values, their lengths are negative
+func (r *bodyReader) bytes() ([]byte, error) {
+	length, err := r.int()
+	if err != nil || length < 0 {
+		return nil, err
+	}
+	return r.take(int(length))
+}
+
+func (r *bodyReader) shortBytes() ([]byte, error) {
+	length, err := r.short()
+	if err != nil {
+		return nil, err
+	}
+	return r.take(int(length))
+}
+
+func (r *bodyReader) stringList() ([]string, error) {
+	count, err := r.short()
+	if err != nil {
+		return nil, err
+	}
+	values := make([]string, 0)
+	for i := 0; i < int(count); i++ {
+		value, err := r.string()
+		if err != nil {
+			return values, err
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+func (r *bodyReader) stringMap() (map[string]string, error) {
+	count, err := r.short()
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string)
+	for i := 0; i < int(count); i++ {
+		key, err := r.string()
+		if err != nil {
+			return values, err
+		}
+		value, err := r.string()
+		if err != nil {
+			return values, err
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// skipBytesMap skips the custom payload, its values are opaque to the protocol
+func (r *bodyReader) skipBytesMap() error {
+	count, err := r.short()
+	if err != nil {
+		return err
+	}
+	for i := 0; i < int(count); i++ {
+		if _, err := r.string(); err != nil {
+			return err
+		}
+		if _, err := r.bytes(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *bodyReader) consistency() (string, error) {
+	value, err := r.short()
+	if err != nil {
+		return "", err
+	}
+	return consistencyName(value), nil
+}
+
+// flags reads the flags of the query parameters and the batches, they grew from a byte to an int in the version 5
+func (r *bodyReader) flags(version byte) (int32, error) {
+	if version >= 5 {
+		return r.int()
+	}
+	value, err := r.byte()
+	return int32(value), err
+}
+
+// columnType reads a type option, the collections, UDTs and tuples nest more options
+func (r *bodyReader) columnType(depth int) (string, error) {
+	if depth > maxTypeDepth {
+		return "", errMalformedFrame
+	}
+
+	id, err := r.short()
+	if err != nil {
+		return "", err
+	}
+	switch id {
+	case columnTypeCustom:
+		return r.string()
+	case columnTypeList, columnTypeSet:
+		element, err := r.columnType(depth + 1)
+		return fmt.Sprintf("%s<%s>", columnTypes[id], element), err
+	case columnTypeMap:
+		key, err := r.columnType(depth + 1)
+		if err != nil {
+			return "", err
+		}
+		value, err := r.columnType(depth + 1)
+		return fmt.Sprintf("map<%s, %s>", key, value), err
+	case columnTypeUdt:
+		if _, err := r.string(); err != nil {
+			return "", err
+		}
+		name, err := r.string()
+		if err != nil {
+			return "", err
+		}
+		count, err := r.short()
+		if err != nil {
+			return "", err
+		}
+		for i := 0; i < int(count); i++ {
+			if _, err := r.string(); err != nil {
+				return "", err
+			}
+			if _, err := r.columnType(depth + 1); err != nil {
+				return "", err
+			}
+		}
+		return name, nil
+	case columnTypeTuple:
+		count, err := r.short()
+		if err != nil {
+			return "", err
+		}
+		elements := make([]string, 0)
+		for i := 0; i < int(count); i++ {
+			element, err := r.columnType(depth + 1)
+			if err != nil {
+				return "", err
+			}
+			elements = append(elements, element)
+		}
+		return fmt.Sprintf("tuple<%s>", strings.Join(elements, ", ")), nil
+	default:
+		if name, ok := columnTypes[id]; ok {
+			return name, nil
+		}
+		return "", errMalformedFrame
+	}
+}
+
+func formatUuid(data []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:16])
+}
+
+// isTolerated accepts running out of the retained bytes of a truncated body, what was parsed until then is kept
+func isTolerated(f *frame, err error) bool {
+	return err == nil || (f.truncated && errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func parseRequest(f *frame) (*CqlRequest, error) {
+	request := &CqlRequest{
+		Opcode:     opcodeName(f.opcode),
+		Version:    int(f.version),
+		Stream:     f.stream,
+		Tracing:    f.flags&flagTracing != 0,
+		Compressed: f.version < 5 && f.flags&flagCompression != 0,
+	}
+	// the algorithm is negotiated by the STARTUP, the compressed bodies are left out
+	if request.Compressed {
+		return request, nil
+	}
+
+	err := readRequestBody(&bodyReader{data: f.body}, f, request)
+	if !isTolerated(f, err) {
+		return nil, err
+	}
+	return request, nil
+}
+
+func readRequestBody(r *bodyReader, f *frame, request *CqlRequest) (err error) {
+	if f.flags&flagCustomPayload != 0 {
+		if err := r.skipBytesMap(); err != nil {
+			return err
+		}
+	}
+
+	switch f.opcode {
+	case opcodeStartup:
+		request.Options, err = r.stringMap()
+		return err
+
+	case opcodeQuery:
+		if request.Query, err = r.longString(); err != nil {
+			return err
+		}
+		return readQueryParameters(r, f.version, request)
+
+	case opcodePrepare:
+		if request.Query, err = r.longString(); err != nil {
+			return err
+		}
+		if f.version >= 5 {
+			flags, err := r.int()
+			if err != nil {
+				return err
+			}
+			if flags&0x01 != 0 {
+				request.Keyspace, err = r.string()
+				return err
+			}
+		}
+		return nil
+
+	case opcodeExecute:
+		id, err := r.shortBytes()
+		if err != nil {
+			return err
+		}
+		request.PreparedId = hex.EncodeToString(id)
+		if f.version >= 5 {
+			if _, err := r.shortBytes(); err != nil {
+				return err
+			}
+		}
+		return readQueryParameters(r, f.version, request)
+
+	case opcodeBatch:
+		return readBatch(r, f.version, request)
+
+	default:
+		// the credentials of AUTH_RESPONSE aren't kept, OPTIONS and REGISTER carry nothing worth showing
+		return nil
+	}
+}
+
+func readQueryParameters(r *bodyReader, version byte, request *CqlRequest) (err error) {
+	if request.Consistency, err = r.consistency(); err != nil {
+		return err
+	}
+	flags, err := r.flags(version)
+	if err != nil {
+		return err
+	}
+
+	if flags&queryFlagValues != 0 {
+		count, err := r.short()
+		if err != nil {
+			return err
+		}
+		request.Values = int(count)
+		for i := 0; i < int(count); i++ {
+			if flags&queryFlagValueNames != 0 {
+				if _, err := r.string(); err != nil {
+					return err
+				}
+			}
+			if _, err := r.bytes(); err != nil {
+				return err
+			}
+		}
+	}
+	if flags&queryFlagPageSize != 0 {
+		if request.PageSize, err = r.int(); err != nil {
+			return err
+		}
+	}
+	if flags&queryFlagPagingState != 0 {
+		if _, err := r.bytes(); err != nil {
+			return err
+		}
+	}
+	if flags&queryFlagSerialConsistency != 0 {
+		if request.SerialConsistency, err = r.consistency(); err != nil {
+			return err
+		}
+	}
+	if flags&queryFlagDefaultTimestamp != 0 {
+		if _, err := r.take(8); err != nil {
+			return err
+		}
+	}
+	if version >= 5 && flags&queryFlagKeyspace != 0 {
+		if request.Keyspace, err = r.string(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readBatch reads the statements of a batch, the query of the batch is the first of them
+func readBatch(r *bodyReader, version byte, request *CqlRequest) error {
+	batchType, err := r.byte()
+	if err != nil {
+		return err
+	}
+	request.BatchType = batchTypes[batchType]
+
+	count, err := r.short()
+	if err != nil {
+		return err
+	}
+	request.Statements = make([]CqlStatement, 0)
+	for i := 0; i < int(count); i++ {
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+
+		statement := CqlStatement{}
+		switch kind {
+		case 0:
+			if statement.Query, err = r.longString(); err != nil {
+				return err
+			}
+		case 1:
+			id, err := r.shortBytes()
+			if err != nil {
+				return err
+			}
+			statement.PreparedId = hex.EncodeToString(id)
+		default:
+			return errMalformedFrame
+		}
+
+		values, err := r.short()
+		if err != nil {
+			return err
+		}
+		statement.Values = int(values)
+		for j := 0; j < int(values); j++ {
+			if _, err := r.bytes(); err != nil {
+				return err
+			}
+		}
+		request.Statements = append(request.Statements, statement)
+	}
+
+	if len(request.Statements) > 0 {
+		request.Query = request.Statements[0].Query
+		request.PreparedId = request.Statements[0].PreparedId
+	}
+
+	if request.Consistency, err = r.consistency(); err != nil {
+		return err
+	}
+	flags, err := r.flags(version)
+	if err != nil {
+		return err
+	}
+	if flags&queryFlagSerialConsistency != 0 {
+		if request.SerialConsistency, err = r.consistency(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func parseResponse(f *frame) (*CqlResponse, error) {
+	response := &CqlResponse{
+		Opcode:     opcodeName(f.opcode),
+		Status:     StatusOk,
+		Columns:    make([]string, 0),
+		Warnings:   make([]string, 0),
+		Compressed: f.version < 5 && f.flags&flagCompression != 0,
+	}
+	if f.opcode == opcodeError {
+		response.Status = StatusError
+	}
+	if response.Compressed {
+		return response, nil
+	}
+
+	err := readResponseBody(&bodyReader{data: f.body}, f, response)
+	if !isTolerated(f, err) {
+		return nil, err
+	}
+	return response, nil
+}
+
+func readResponseBody(r *bodyReader, f *frame, response *CqlResponse) (err error) {
+	if f.flags&flagTracing != 0 {
+		id, err := r.take(16)
+		if err != nil {
+			return err
+		}
+		response.TracingId = formatUuid(id)
+	}
+	if f.flags&flagWarning != 0 {
+		if response.Warnings, err = r.stringList(); err != nil {
+			return err
+		}
+	}
+	if f.flags&flagCustomPayload != 0 {
+		if err := r.skipBytesMap(); err != nil {
+			return err
+		}
+	}
+
+	switch f.opcode {
+	case opcodeError:
+		code, err := r.int()
+		if err != nil {
+			return err
+		}
+		response.Error = &CqlError{Code: code, Name: errorCodeName(code)}
+		response.Error.Message, err = r.string()
+		return err
+
+	case opcodeResult:
+		return readResult(r, f.version, response)
+
+	default:
+		return nil
+	}
+}
+
+func readResult(r *bodyReader, version byte, response *CqlResponse) (err error) {
+	kind, err := r.int()
+	if err != nil {
+		return err
+	}
+	response.Kind = resultKindName(kind)
+
+	switch kind {
+	case resultRows:
+		if err := readRowsMetadata(r, version, response); err != nil {
+			return err
+		}
+		response.Rows, err = r.int()
+		return err
+
+	case resultSetKeyspace:
+		response.Keyspace, err = r.string()
+		return err
+
+	case resultPrepared:
+		// the metadata of the bound variables and of the result follow, the id is all that's needed to resolve the executions
+		id, err := r.shortBytes()
+		if err != nil {
+			return err
+		}
+		response.PreparedId = hex.EncodeToString(id)
+		return nil
+
+	case resultSchemaChange:
+		changeType, err := r.string()
+		if err != nil {
+			return err
+		}
+		target, err := r.string()
+		if err != nil {
+			return err
+		}
+		name, err := r.string()
+		if err != nil {
+			return err
+		}
+		if target != "KEYSPACE" {
+			object, err := r.string()
+			if err != nil {
+				return err
+			}
+			name = fmt.Sprintf("%s.%s", name, object)
+		}
+		response.SchemaChange = fmt.Sprintf("%s %s %s", changeType, target, name)
+		return nil
+
+	default:
+		return nil
+	}
+}
+
+func readRowsMetadata(r *bodyReader, version byte, response *CqlResponse) error {
+	flags, err := r.int()
+	if err != nil {
+		return err
+	}
+	count, err := r.int()
+	if err != nil {
+		return err
+	}
+	if count < 0 || count > maxColumns {
+		return errMalformedFrame
+	}
+
+	if flags&metadataHasMorePages != 0 {
+		response.HasMorePages = true
+		if _, err := r.bytes(); err != nil {
+			return err
+		}
+	}
+	if version >= 5 && flags&metadataChanged != 0 {
+		if _, err := r.shortBytes(); err != nil {
+			return err
+		}
+	}
+	if flags&metadataNoMetadata != 0 {
+		return nil
+	}
+
+	global := flags&metadataGlobalTablesSpec != 0
+	if global {
+		if _, err := r.string(); err != nil {
+			return err
+		}
+		if _, err := r.string(); err != nil {
+			return err
+		}
+	}
+	for i := 0; i < int(count); i++ {
+		if !global {
+			if _, err := r.string(); err != nil {
+				return err
+			}
+			if _, err := r.string(); err != nil {
+				return err
+			}
+		}
+		name, err := r.string()
+		if err != nil {
+			return err
+		}
+		columnType, err := r.columnType(0)
+		if err != nil {
+			return err
+		}
+		response.Columns = append(response.Columns, fmt.Sprintf("%s %s", name, columnType))
+	}
+	return nil
+}
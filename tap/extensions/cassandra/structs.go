@@ -0,0 +1,273 @@
+package cassandra
+
+import "fmt"
+
+const (
+	frameHeaderLength = 9
+	maxFrameLength    = 256 * 1024 * 1024
+	// the bodies are parsed from their first bytes, the rows past them aren't needed
+	maxRetainedLength = 64 * 1024
+	maxStatements     = 1000
+	maxColumns        = 1024
+	maxTypeDepth      = 16
+
+	// the version of the responses has this bit set
+	directionResponse = 0x80
+	versionMask       = 0x7f
+	minVersion        = 3
+	maxVersion        = 5
+
+	// from the version 5 the frames are carried by segments once the connection is started
+	segmentHeaderLength  = 6
+	segmentTrailerLength = 4
+	segmentLengthMask    = 0x1ffff
+
+	StatusOk    = "OK"
+	StatusError = "ERROR"
+)
+
+// frame flags
+const (
+	flagCompression   = 0x01
+	flagTracing       = 0x02
+	flagCustomPayload = 0x04
+	flagWarning       = 0x08
+)
+
+const (
+	opcodeError         = 0x00
+	opcodeStartup       = 0x01
+	opcodeReady         = 0x02
+	opcodeAuthenticate  = 0x03
+	opcodeOptions       = 0x05
+	opcodeSupported     = 0x06
+	opcodeQuery         = 0x07
+	opcodeResult        = 0x08
+	opcodePrepare       = 0x09
+	opcodeExecute       = 0x0a
+	opcodeRegister      = 0x0b
+	opcodeEvent         = 0x0c
+	opcodeBatch         = 0x0d
+	opcodeAuthChallenge = 0x0e
+	opcodeAuthResponse  = 0x0f
+	opcodeAuthSuccess   = 0x10
+)
+
+var requestOpcodes = map[byte]string{
+	opcodeStartup:      "STARTUP",
+	opcodeOptions:      "OPTIONS",
+	opcodeQuery:        "QUERY",
+	opcodePrepare:      "PREPARE",
+	opcodeExecute:      "EXECUTE",
+	opcodeRegister:     "REGISTER",
+	opcodeBatch:        "BATCH",
+	opcodeAuthResponse: "AUTH_RESPONSE",
+}
+
+var responseOpcodes = map[byte]string{
+	opcodeError:         "ERROR",
+	opcodeReady:         "READY",
+	opcodeAuthenticate:  "AUTHENTICATE",
+	opcodeSupported:     "SUPPORTED",
+	opcodeResult:        "RESULT",
+	opcodeEvent:         "EVENT",
+	opcodeAuthChallenge: "AUTH_CHALLENGE",
+	opcodeAuthSuccess:   "AUTH_SUCCESS",
+}
+
+// query parameter flags, a byte up to the version 4 and an int from the version 5
+const (
+	queryFlagValues            = 0x01
+	queryFlagSkipMetadata      = 0x02
+	queryFlagPageSize          = 0x04
+	queryFlagPagingState       = 0x08
+	queryFlagSerialConsistency = 0x10
+	queryFlagDefaultTimestamp  = 0x20
+	queryFlagValueNames        = 0x40
+	queryFlagKeyspace          = 0x80
+	queryFlagNowInSeconds      = 0x100
+)
+
+var consistencies = map[uint16]string{
+	0x0000: "ANY",
+	0x0001: "ONE",
+	0x0002: "TWO",
+	0x0003: "THREE",
+	0x0004: "QUORUM",
+	0x0005: "ALL",
+	0x0006: "LOCAL_QUORUM",
+	0x0007: "EACH_QUORUM",
+	0x0008: "SERIAL",
+	0x0009: "LOCAL_SERIAL",
+	0x000a: "LOCAL_ONE",
+}
+
+var batchTypes = map[byte]string{
+	0: "LOGGED",
+	1: "UNLOGGED",
+	2: "COUNTER",
+}
+
+const (
+	resultVoid         = 0x0001
+	resultRows         = 0x0002
+	resultSetKeyspace  = 0x0003
+	resultPrepared     = 0x0004
+	resultSchemaChange = 0x0005
+)
+
+var resultKinds = map[int32]string{
+	resultVoid:         "VOID",
+	resultRows:         "ROWS",
+	resultSetKeyspace:  "SET_KEYSPACE",
+	resultPrepared:     "PREPARED",
+	resultSchemaChange: "SCHEMA_CHANGE",
+}
+
+// rows metadata flags
+const (
+	metadataGlobalTablesSpec = 0x01
+	metadataHasMorePages     = 0x02
+	metadataNoMetadata       = 0x04
+	metadataChanged          = 0x08
+)
+
+var errorCodes = map[int32]string{
+	0x0000: "SERVER_ERROR",
+	0x000a: "PROTOCOL_ERROR",
+	0x0100: "BAD_CREDENTIALS",
+	0x1000: "UNAVAILABLE",
+	0x1001: "OVERLOADED",
+	0x1002: "IS_BOOTSTRAPPING",
+	0x1003: "TRUNCATE_ERROR",
+	0x1100: "WRITE_TIMEOUT",
+	0x1200: "READ_TIMEOUT",
+	0x1300: "READ_FAILURE",
+	0x1400: "FUNCTION_FAILURE",
+	0x1500: "WRITE_FAILURE",
+	0x1600: "CDC_WRITE_FAILURE",
+	0x1700: "CAS_WRITE_UNKNOWN",
+	0x2000: "SYNTAX_ERROR",
+	0x2100: "UNAUTHORIZED",
+	0x2200: "INVALID",
+	0x2300: "CONFIG_ERROR",
+	0x2400: "ALREADY_EXISTS",
+	0x2500: "UNPREPARED",
+}
+
+// the ids of the column types, the custom, collection, UDT and tuple types are followed by more options
+var columnTypes = map[uint16]string{
+	0x0000: "custom",
+	0x0001: "ascii",
+	0x0002: "bigint",
+	0x0003: "blob",
+	0x0004: "boolean",
+	0x0005: "counter",
+	0x0006: "decimal",
+	0x0007: "double",
+	0x0008: "float",
+	0x0009: "int",
+	0x000b: "timestamp",
+	0x000c: "uuid",
+	0x000d: "varchar",
+	0x000e: "varint",
+	0x000f: "timeuuid",
+	0x0010: "inet",
+	0x0011: "date",
+	0x0012: "time",
+	0x0013: "smallint",
+	0x0014: "tinyint",
+	0x0015: "duration",
+	0x0020: "list",
+	0x0021: "map",
+	0x0022: "set",
+	0x0030: "udt",
+	0x0031: "tuple",
+}
+
+const (
+	columnTypeCustom = 0x0000
+	columnTypeList   = 0x0020
+	columnTypeMap    = 0x0021
+	columnTypeSet    = 0x0022
+	columnTypeUdt    = 0x0030
+	columnTypeTuple  = 0x0031
+)
+
+func opcodeName(opcode byte) string {
+	if name, ok := requestOpcodes[opcode]; ok {
+		return name
+	}
+	if name, ok := responseOpcodes[opcode]; ok {
+		return name
+	}
+	return fmt.Sprintf("OPCODE%d", opcode)
+}
+
+func consistencyName(value uint16) string {
+	if name, ok := consistencies[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("CONSISTENCY%d", value)
+}
+
+func resultKindName(value int32) string {
+	if name, ok := resultKinds[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("KIND%d", value)
+}
+
+func errorCodeName(value int32) string {
+	if name, ok := errorCodes[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("ERROR%d", value)
+}
+
+type CqlRequest struct {
+	Opcode            string            `json:"opcode"`
+	Version           int               `json:"version"`
+	Stream            int16             `json:"stream"`
+	Query             string            `json:"query"`
+	Consistency       string            `json:"consistency,omitempty"`
+	SerialConsistency string            `json:"serialConsistency,omitempty"`
+	PreparedId        string            `json:"preparedId,omitempty"`
+	Keyspace          string            `json:"keyspace,omitempty"`
+	Values            int               `json:"values"`
+	PageSize          int32             `json:"pageSize,omitempty"`
+	BatchType         string            `json:"batchType,omitempty"`
+	Statements        []CqlStatement    `json:"statements,omitempty"`
+	Options           map[string]string `json:"options,omitempty"`
+	Tracing           bool              `json:"tracing"`
+	Compressed        bool              `json:"compressed"`
+}
+
+// CqlStatement is one of the statements of a batch, either a query or a prepared statement
+type CqlStatement struct {
+	Query      string `json:"query"`
+	PreparedId string `json:"preparedId,omitempty"`
+	Values     int    `json:"values"`
+}
+
+type CqlResponse struct {
+	Opcode       string    `json:"opcode"`
+	Status       string    `json:"status"`
+	Kind         string    `json:"kind,omitempty"`
+	Rows         int32     `json:"rows"`
+	Columns      []string  `json:"columns"`
+	HasMorePages bool      `json:"hasMorePages"`
+	Keyspace     string    `json:"keyspace,omitempty"`
+	PreparedId   string    `json:"preparedId,omitempty"`
+	SchemaChange string    `json:"schemaChange,omitempty"`
+	TracingId    string    `json:"tracingId,omitempty"`
+	Warnings     []string  `json:"warnings"`
+	Compressed   bool      `json:"compressed"`
+	Error        *CqlError `json:"error,omitempty"`
+}
+
+type CqlError struct {
+	Code    int32  `json:"code"`
+	Name    string `json:"name"`
+	Message string `json:"message"`
+}
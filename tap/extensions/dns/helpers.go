@@ -0,0 +1,160 @@
+package dns
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type DnsPayload struct {
+	Data interface{}
+}
+
+type DnsPayloader interface {
+	MarshalJSON() ([]byte, error)
+}
+
+func (h DnsPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Data)
+}
+
+type DnsWrapper struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Details interface{} `json:"details"`
+}
+
+func representRequest(request map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Name",
+			Value:    request["name"].(string),
+			Selector: `request.name`,
+		},
+		{
+			Name:     "Type",
+			Value:    request["type"].(string),
+			Selector: `request.type`,
+		},
+		{
+			Name:     "Transaction Id",
+			Value:    fmt.Sprintf("%v", request["id"]),
+			Selector: `request.id`,
+		},
+		{
+			Name:     "Opcode",
+			Value:    request["opcode"].(string),
+			Selector: `request.opcode`,
+		},
+		{
+			Name:     "Transport",
+			Value:    request["transport"].(string),
+			Selector: `request.transport`,
+		},
+		{
+			Name:     "Recursion Desired",
+			Value:    fmt.Sprintf("%v", request["recursionDesired"]),
+			Selector: `request.recursionDesired`,
+		},
+	}
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if questions, ok := request["questions"].([]interface{}); ok && len(questions) > 1 {
+		rows := make([]api.TableData, 0, len(questions))
+		for i, item := range questions {
+			question := item.(map[string]interface{})
+			rows = append(rows, api.TableData{
+				Name:     fmt.Sprintf("%v", question["name"]),
+				Value:    fmt.Sprintf("%v %v", question["class"], question["type"]),
+				Selector: fmt.Sprintf(`request.questions[%d].name`, i),
+			})
+		}
+		questionsJson, _ := json.Marshal(rows)
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Questions",
+			Data:  string(questionsJson),
+		})
+	}
+
+	return
+}
+
+func representResponse(response map[string]interface{}) (representation []interface{}) {
+	addresses := make([]string, 0)
+	if values, ok := response["addresses"].([]interface{}); ok {
+		for _, value := range values {
+			addresses = append(addresses, fmt.Sprintf("%v", value))
+		}
+	}
+
+	details := []api.TableData{
+		{
+			Name:     "Response Code",
+			Value:    response["rcode"].(string),
+			Selector: `response.rcode`,
+		},
+		{
+			Name:     "Addresses",
+			Value:    strings.Join(addresses, ", "),
+			Selector: `response.addresses`,
+		},
+		{
+			Name:     "Authoritative",
+			Value:    fmt.Sprintf("%v", response["authoritative"]),
+			Selector: `response.authoritative`,
+		},
+		{
+			Name:     "Truncated",
+			Value:    fmt.Sprintf("%v", response["truncated"]),
+			Selector: `response.truncated`,
+		},
+		{
+			Name:     "Recursion Available",
+			Value:    fmt.Sprintf("%v", response["recursionAvailable"]),
+			Selector: `response.recursionAvailable`,
+		},
+	}
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	representation = appendRecordsSection(representation, response, "Answers", "answers")
+	representation = appendRecordsSection(representation, response, "Authorities", "authorities")
+	representation = appendRecordsSection(representation, response, "Additionals", "additionals")
+
+	return
+}
+
+func appendRecordsSection(representation []interface{}, response map[string]interface{}, title string, key string) []interface{} {
+	records, ok := response[key].([]interface{})
+	if !ok || len(records) == 0 {
+		return representation
+	}
+
+	rows := make([]api.TableData, 0, len(records))
+	for i, item := range records {
+		record := item.(map[string]interface{})
+		rows = append(rows, api.TableData{
+			Name:     fmt.Sprintf("%v", record["name"]),
+			Value:    fmt.Sprintf("%v %v %v %v", record["ttl"], record["class"], record["type"], record["data"]),
+			Selector: fmt.Sprintf(`response.%s[%d].data`, key, i),
+		})
+	}
+	recordsJson, _ := json.Marshal(rows)
+	return append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: title,
+		Data:  string(recordsJson),
+	})
+}
@@ -0,0 +1,196 @@
+package dns
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+var protocol api.Protocol = api.Protocol{
+	Name:            "dns",
+	LongName:        "Domain Name System",
+	Abbreviation:    "DNS",
+	Macro:           "dns",
+	Version:         "1035",
+	BackgroundColor: "#5b6abf",
+	ForegroundColor: "#ffffff",
+	FontSize:        12,
+	ReferenceLink:   "https://datatracker.ietf.org/doc/html/rfc1035",
+	Ports:           []string{"53"},
+	Priority:        7,
+}
+
+var errNotDnsPort = errors.New("Not a DNS port")
+
+type dissecting string
+
+func (d dissecting) Register(extension *api.Extension) {
+	extension.Protocol = &protocol
+}
+
+func (d dissecting) Ping() {
+	log.Printf("pong %s", protocol.Name)
+}
+
+// Dissect reads DNS over TCP, used for the responses too large for a datagram and the zone transfers
+func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, superIdentifier *api.SuperIdentifier, emitter api.Emitter, options *api.TrafficFilteringOptions, _reqResMatcher api.RequestResponseMatcher) error {
+	reqResMatcher := _reqResMatcher.(*requestResponseMatcher)
+
+	// the messages have too little structure to tell DNS apart from the other protocols on any port
+	if !isDnsPort(tcpID.SrcPort) && !isDnsPort(tcpID.DstPort) {
+		return errNotDnsPort
+	}
+
+	for {
+		if superIdentifier.Protocol != nil && superIdentifier.Protocol != &protocol {
+			return errors.New("Identified by another protocol")
+		}
+
+		message, err := readTcpMessage(b)
+		if err != nil {
+			return err
+		}
+
+		request, response, err := parseMessage(message, TransportTcp)
+		if err != nil {
+			return err
+		}
+		superIdentifier.Protocol = &protocol
+
+		if request != nil {
+			handleRequest(tcpID, superTimer.CaptureTime, emitter, request, reqResMatcher)
+		} else {
+			handleResponse(tcpID, superTimer.CaptureTime, emitter, response, reqResMatcher)
+		}
+	}
+}
+
+// DissectDatagram reads DNS over UDP, where every datagram carries a single message
+func (d dissecting) DissectDatagram(payload []byte, tcpID *api.TcpID, captureTime time.Time, emitter api.Emitter, options *api.TrafficFilteringOptions, _reqResMatcher api.RequestResponseMatcher) error {
+	reqResMatcher := _reqResMatcher.(*requestResponseMatcher)
+
+	if !isDnsPort(tcpID.SrcPort) && !isDnsPort(tcpID.DstPort) {
+		return errNotDnsPort
+	}
+
+	request, response, err := parseMessage(payload, TransportUdp)
+	if err != nil {
+		return err
+	}
+
+	if request != nil {
+		handleRequest(tcpID, captureTime, emitter, request, reqResMatcher)
+	} else {
+		handleResponse(tcpID, captureTime, emitter, response, reqResMatcher)
+	}
+	return nil
+}
+
+func isDnsPort(port string) bool {
+	for _, dnsPort := range protocol.Ports {
+		if port == dnsPort {
+			return true
+		}
+	}
+	return false
+}
+
+func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	request := item.Pair.Request.Payload.(map[string]interface{})
+	response := item.Pair.Response.Payload.(map[string]interface{})
+	reqDetails := request["details"].(map[string]interface{})
+	resDetails := response["details"].(map[string]interface{})
+
+	elapsedTime := item.Pair.Response.CaptureTime.Sub(item.Pair.Request.CaptureTime).Round(time.Millisecond).Milliseconds()
+	if elapsedTime < 0 {
+		elapsedTime = 0
+	}
+	return &api.Entry{
+		Protocol: protocol,
+		Source: &api.TCP{
+			Name: resolvedSource,
+			IP:   item.ConnectionInfo.ClientIP,
+			Port: item.ConnectionInfo.ClientPort,
+		},
+		Destination: &api.TCP{
+			Name: resolvedDestination,
+			IP:   item.ConnectionInfo.ServerIP,
+			Port: item.ConnectionInfo.ServerPort,
+		},
+		Namespace:   namespace,
+		Outgoing:    item.ConnectionInfo.IsOutgoing,
+		Request:     reqDetails,
+		Response:    resDetails,
+		Timestamp:   item.Timestamp,
+		StartTime:   item.Pair.Request.CaptureTime,
+		ElapsedTime: elapsedTime,
+	}
+
+}
+
+func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
+	status := 0
+	statusQuery := ""
+
+	method := entry.Request["type"].(string)
+	methodQuery := fmt.Sprintf(`request.type == "%s"`, method)
+
+	summary := entry.Request["name"].(string)
+	summaryQuery := fmt.Sprintf(`request.name == %s`, strconv.Quote(summary))
+
+	if rcode, ok := entry.Response["rcode"].(string); ok && rcode != rcodes[0] {
+		summary = fmt.Sprintf("%s (%s)", summary, rcode)
+	}
+
+	return &api.BaseEntry{
+		Id:             entry.Id,
+		EntryId:        entry.EntryId,
+		Protocol:       entry.Protocol,
+		Summary:        summary,
+		SummaryQuery:   summaryQuery,
+		Status:         status,
+		StatusQuery:    statusQuery,
+		Method:         method,
+		MethodQuery:    methodQuery,
+		Timestamp:      entry.Timestamp,
+		Source:         entry.Source,
+		Destination:    entry.Destination,
+		IsOutgoing:     entry.Outgoing,
+		Latency:        entry.ElapsedTime,
+		Rules:          entry.Rules,
+		ContractStatus: entry.ContractStatus,
+	}
+}
+
+func (d dissecting) Represent(request map[string]interface{}, response map[string]interface{}) (object []byte, bodySize int64, err error) {
+	bodySize = 0
+	representation := make(map[string]interface{})
+	repRequest := representRequest(request)
+	repResponse := representResponse(response)
+	representation["request"] = repRequest
+	representation["response"] = repResponse
+	object, err = json.Marshal(representation)
+	return
+}
+
+func (d dissecting) Macros() map[string]string {
+	return map[string]string{
+		`dns`: fmt.Sprintf(`proto.name == "%s"`, protocol.Name),
+	}
+}
+
+func (d dissecting) NewResponseRequestMatcher() api.RequestResponseMatcher {
+	return createResponseRequestMatcher()
+}
+
+var Dissector dissecting
+
+func NewDissector() api.Dissector {
+	return Dissector
+}
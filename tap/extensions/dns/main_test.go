@@ -0,0 +1,538 @@
+package dns
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	binDir          = "bin"
+	patternBin      = "*_req.bin"
+	patternExpect   = "*.json"
+	msgDissecting   = "Dissecting:"
+	msgAnalyzing    = "Analyzing:"
+	msgSummarizing  = "Summarizing:"
+	msgRepresenting = "Representing:"
+	respSuffix      = "_res.bin"
+	expectDir       = "expect"
+	dissectDir      = "dissect"
+	analyzeDir      = "analyze"
+	summarizeDir    = "summarize"
+	representDir    = "represent"
+	testUpdate      = "TEST_UPDATE"
+)
+
+func TestRegister(t *testing.T) {
+	dissector := NewDissector()
+	extension := &api.Extension{}
+	dissector.Register(extension)
+	assert.Equal(t, "dns", extension.Protocol.Name)
+}
+
+func TestMacros(t *testing.T) {
+	expectedMacros := map[string]string{
+		"dns": `proto.name == "dns"`,
+	}
+	dissector := NewDissector()
+	macros := dissector.Macros()
+	assert.Equal(t, expectedMacros, macros)
+}
+
+func TestPing(t *testing.T) {
+	dissector := NewDissector()
+	dissector.Ping()
+}
+
+func TestDissect(t *testing.T) {
+	_, testUpdateEnabled := os.LookupEnv(testUpdate)
+
+	expectDirDissect := path.Join(expectDir, dissectDir)
+
+	if testUpdateEnabled {
+		os.RemoveAll(expectDirDissect)
+		err := os.MkdirAll(expectDirDissect, 0775)
+		assert.Nil(t, err)
+	}
+
+	dissector := NewDissector()
+	paths, err := filepath.Glob(path.Join(binDir, patternBin))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	options := &api.TrafficFilteringOptions{
+		IgnoredUserAgents: []string{},
+	}
+
+	for _, _path := range paths {
+		basePath := _path[:len(_path)-8]
+
+		// Channel to verify the output
+		itemChannel := make(chan *api.OutputChannelItem)
+		var emitter api.Emitter = &api.Emitting{
+			AppStats:      &api.AppStats{},
+			OutputChannel: itemChannel,
+		}
+
+		var items []*api.OutputChannelItem
+		stop := make(chan bool)
+
+		go func() {
+			for {
+				select {
+				case <-stop:
+					return
+				case item := <-itemChannel:
+					items = append(items, item)
+				}
+			}
+		}()
+
+		// Stream level
+		counterPair := &api.CounterPair{
+			Request:  0,
+			Response: 0,
+		}
+		superIdentifier := &api.SuperIdentifier{}
+
+		// Request
+		pathClient := _path
+		fmt.Printf("%s %s\n", msgDissecting, pathClient)
+		fileClient, err := os.Open(pathClient)
+		assert.Nil(t, err)
+
+		bufferClient := bufio.NewReader(fileClient)
+		tcpIDClient := &api.TcpID{
+			SrcIP:   "1",
+			DstIP:   "2",
+			SrcPort: "1",
+			DstPort: "53",
+		}
+		reqResMatcher := dissector.NewResponseRequestMatcher()
+		err = dissector.Dissect(bufferClient, true, tcpIDClient, counterPair, &api.SuperTimer{}, superIdentifier, emitter, options, reqResMatcher)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			log.Println(err)
+		}
+
+		// Response
+		pathServer := basePath + respSuffix
+		fmt.Printf("%s %s\n", msgDissecting, pathServer)
+		fileServer, err := os.Open(pathServer)
+		assert.Nil(t, err)
+
+		bufferServer := bufio.NewReader(fileServer)
+		tcpIDServer := &api.TcpID{
+			SrcIP:   "2",
+			DstIP:   "1",
+			SrcPort: "53",
+			DstPort: "1",
+		}
+		err = dissector.Dissect(bufferServer, false, tcpIDServer, counterPair, &api.SuperTimer{}, superIdentifier, emitter, options, reqResMatcher)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			log.Println(err)
+		}
+
+		fileClient.Close()
+		fileServer.Close()
+
+		pathExpect := path.Join(expectDirDissect, fmt.Sprintf("%s.json", basePath[4:]))
+
+		time.Sleep(10 * time.Millisecond)
+
+		stop <- true
+
+		marshaled, err := json.Marshal(items)
+		assert.Nil(t, err)
+
+		if testUpdateEnabled {
+			if len(items) > 0 {
+				err = os.WriteFile(pathExpect, marshaled, 0644)
+				assert.Nil(t, err)
+			}
+		} else {
+			if _, err := os.Stat(pathExpect); errors.Is(err, os.ErrNotExist) {
+				assert.Len(t, items, 0)
+			} else {
+				expectedBytes, err := ioutil.ReadFile(pathExpect)
+				assert.Nil(t, err)
+
+				assert.JSONEq(t, string(expectedBytes), string(marshaled))
+			}
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	_, testUpdateEnabled := os.LookupEnv(testUpdate)
+
+	expectDirDissect := path.Join(expectDir, dissectDir)
+	expectDirAnalyze := path.Join(expectDir, analyzeDir)
+
+	if testUpdateEnabled {
+		os.RemoveAll(expectDirAnalyze)
+		err := os.MkdirAll(expectDirAnalyze, 0775)
+		assert.Nil(t, err)
+	}
+
+	dissector := NewDissector()
+	paths, err := filepath.Glob(path.Join(expectDirDissect, patternExpect))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, _path := range paths {
+		fmt.Printf("%s %s\n", msgAnalyzing, _path)
+
+		bytes, err := ioutil.ReadFile(_path)
+		assert.Nil(t, err)
+
+		var items []*api.OutputChannelItem
+		err = json.Unmarshal(bytes, &items)
+		assert.Nil(t, err)
+
+		var entries []*api.Entry
+		for _, item := range items {
+			entry := dissector.Analyze(item, "", "", "")
+			entries = append(entries, entry)
+		}
+
+		pathExpect := path.Join(expectDirAnalyze, filepath.Base(_path))
+
+		marshaled, err := json.Marshal(entries)
+		assert.Nil(t, err)
+
+		if testUpdateEnabled {
+			if len(entries) > 0 {
+				err = os.WriteFile(pathExpect, marshaled, 0644)
+				assert.Nil(t, err)
+			}
+		} else {
+			if _, err := os.Stat(pathExpect); errors.Is(err, os.ErrNotExist) {
+				assert.Len(t, items, 0)
+			} else {
+				expectedBytes, err := ioutil.ReadFile(pathExpect)
+				assert.Nil(t, err)
+
+				assert.JSONEq(t, string(expectedBytes), string(marshaled))
+			}
+		}
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	_, testUpdateEnabled := os.LookupEnv(testUpdate)
+
+	expectDirAnalyze := path.Join(expectDir, analyzeDir)
+	expectDirSummarize := path.Join(expectDir, summarizeDir)
+
+	if testUpdateEnabled {
+		os.RemoveAll(expectDirSummarize)
+		err := os.MkdirAll(expectDirSummarize, 0775)
+		assert.Nil(t, err)
+	}
+
+	dissector := NewDissector()
+	paths, err := filepath.Glob(path.Join(expectDirAnalyze, patternExpect))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, _path := range paths {
+		fmt.Printf("%s %s\n", msgSummarizing, _path)
+
+		bytes, err := ioutil.ReadFile(_path)
+		assert.Nil(t, err)
+
+		var entries []*api.Entry
+		err = json.Unmarshal(bytes, &entries)
+		assert.Nil(t, err)
+
+		var baseEntries []*api.BaseEntry
+		for _, entry := range entries {
+			baseEntry := dissector.Summarize(entry)
+			baseEntries = append(baseEntries, baseEntry)
+		}
+
+		pathExpect := path.Join(expectDirSummarize, filepath.Base(_path))
+
+		marshaled, err := json.Marshal(baseEntries)
+		assert.Nil(t, err)
+
+		if testUpdateEnabled {
+			if len(baseEntries) > 0 {
+				err = os.WriteFile(pathExpect, marshaled, 0644)
+				assert.Nil(t, err)
+			}
+		} else {
+			if _, err := os.Stat(pathExpect); errors.Is(err, os.ErrNotExist) {
+				assert.Len(t, entries, 0)
+			} else {
+				expectedBytes, err := ioutil.ReadFile(pathExpect)
+				assert.Nil(t, err)
+
+				assert.JSONEq(t, string(expectedBytes), string(marshaled))
+			}
+		}
+	}
+}
+
+func TestRepresent(t *testing.T) {
+	_, testUpdateEnabled := os.LookupEnv(testUpdate)
+
+	expectDirAnalyze := path.Join(expectDir, analyzeDir)
+	expectDirRepresent := path.Join(expectDir, representDir)
+
+	if testUpdateEnabled {
+		os.RemoveAll(expectDirRepresent)
+		err := os.MkdirAll(expectDirRepresent, 0775)
+		assert.Nil(t, err)
+	}
+
+	dissector := NewDissector()
+	paths, err := filepath.Glob(path.Join(expectDirAnalyze, patternExpect))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, _path := range paths {
+		fmt.Printf("%s %s\n", msgRepresenting, _path)
+
+		bytes, err := ioutil.ReadFile(_path)
+		assert.Nil(t, err)
+
+		var entries []*api.Entry
+		err = json.Unmarshal(bytes, &entries)
+		assert.Nil(t, err)
+
+		var objects []string
+		for _, entry := range entries {
+			object, _, err := dissector.Represent(entry.Request, entry.Response)
+			assert.Nil(t, err)
+			objects = append(objects, string(object))
+		}
+
+		pathExpect := path.Join(expectDirRepresent, filepath.Base(_path))
+
+		marshaled, err := json.Marshal(objects)
+		assert.Nil(t, err)
+
+		if testUpdateEnabled {
+			if len(objects) > 0 {
+				err = os.WriteFile(pathExpect, marshaled, 0644)
+				assert.Nil(t, err)
+			}
+		} else {
+			if _, err := os.Stat(pathExpect); errors.Is(err, os.ErrNotExist) {
+				assert.Len(t, objects, 0)
+			} else {
+				expectedBytes, err := ioutil.ReadFile(pathExpect)
+				assert.Nil(t, err)
+
+				assert.JSONEq(t, string(expectedBytes), string(marshaled))
+			}
+		}
+	}
+}
+
+func uint16Bytes(value uint16) []byte {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, value)
+	return b
+}
+
+func uint32Bytes(value uint32) []byte {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, value)
+	return b
+}
+
+func nameBytes(labels ...string) []byte {
+	b := make([]byte, 0)
+	for _, label := range labels {
+		b = append(b, byte(len(label)))
+		b = append(b, label...)
+	}
+	return append(b, 0)
+}
+
+func header(id uint16, flags uint16, questions uint16, answers uint16, authorities uint16, additionals uint16) []byte {
+	return bytes.Join([][]byte{uint16Bytes(id), uint16Bytes(flags), uint16Bytes(questions), uint16Bytes(answers), uint16Bytes(authorities), uint16Bytes(additionals)}, nil)
+}
+
+func question(name []byte, qtype uint16) []byte {
+	return bytes.Join([][]byte{name, uint16Bytes(qtype), uint16Bytes(1)}, nil)
+}
+
+func record(name []byte, rtype uint16, ttl uint32, data []byte) []byte {
+	return bytes.Join([][]byte{name, uint16Bytes(rtype), uint16Bytes(1), uint32Bytes(ttl), uint16Bytes(uint16(len(data))), data}, nil)
+}
+
+// the name of the first question always starts right after the header
+var questionPointer = []byte{0xc0, headerLength}
+
+func query(id uint16, qtype uint16, labels ...string) []byte {
+	return append(header(id, flagRecursionDesired, 1, 0, 0, 0), question(nameBytes(labels...), qtype)...)
+}
+
+func tcpMessage(message []byte) []byte {
+	return append(uint16Bytes(uint16(len(message))), message...)
+}
+
+// analyze passes the item through JSON first, as the agent does before analyzing it
+func analyze(t *testing.T, item *api.OutputChannelItem) *api.Entry {
+	marshaled, err := json.Marshal(item)
+	assert.Nil(t, err)
+	var unmarshaled api.OutputChannelItem
+	assert.Nil(t, json.Unmarshal(marshaled, &unmarshaled))
+	return NewDissector().Analyze(&unmarshaled, "", "", "")
+}
+
+func TestDissectDatagram(t *testing.T) {
+	dissector := NewDissector().(api.DatagramDissector)
+	reqResMatcher := NewDissector().NewResponseRequestMatcher()
+	itemChannel := make(chan *api.OutputChannelItem, 1)
+	emitter := &api.Emitting{AppStats: &api.AppStats{}, OutputChannel: itemChannel}
+	options := &api.TrafficFilteringOptions{}
+	clientTcpID := &api.TcpID{SrcIP: "10.0.0.5", DstIP: "10.96.0.10", SrcPort: "40000", DstPort: "53"}
+	serverTcpID := &api.TcpID{SrcIP: "10.96.0.10", DstIP: "10.0.0.5", SrcPort: "53", DstPort: "40000"}
+	captureTime := time.Now()
+
+	response := bytes.Join([][]byte{
+		header(0x1234, flagResponse|flagRecursionDesired|flagRecursionAvailable, 1, 3, 0, 1),
+		question(nameBytes("shop", "default", "svc", "cluster", "local"), typeA),
+		record(questionPointer, typeCNAME, 30, append([]byte{3}, append([]byte("web"), 0xc0, headerLength)...)),
+		record(questionPointer, typeA, 30, []byte{10, 96, 12, 7}),
+		record(questionPointer, typeA, 30, []byte{10, 96, 12, 8}),
+		// the OPT pseudo-record of EDNS
+		[]byte{0}, uint16Bytes(typeOPT), uint16Bytes(1232), uint32Bytes(0), uint16Bytes(0),
+	}, nil)
+
+	assert.Nil(t, dissector.DissectDatagram(query(0x1234, typeA, "shop", "default", "svc", "cluster", "local"), clientTcpID, captureTime, emitter, options, reqResMatcher))
+	assert.Nil(t, dissector.DissectDatagram(response, serverTcpID, captureTime.Add(3*time.Millisecond), emitter, options, reqResMatcher))
+	assert.Len(t, itemChannel, 1)
+
+	item := <-itemChannel
+	assert.Equal(t, "10.0.0.5", item.ConnectionInfo.ClientIP)
+	assert.Equal(t, "53", item.ConnectionInfo.ServerPort)
+
+	entry := analyze(t, item)
+	assert.Equal(t, int64(3), entry.ElapsedTime)
+
+	request := entry.Request
+	assert.Equal(t, "shop.default.svc.cluster.local", request["name"])
+	assert.Equal(t, "A", request["type"])
+	assert.Equal(t, "QUERY", request["opcode"])
+	assert.Equal(t, TransportUdp, request["transport"])
+	assert.Equal(t, true, request["recursionDesired"])
+
+	details := entry.Response
+	assert.Equal(t, "NOERROR", details["rcode"])
+	assert.Equal(t, []interface{}{"10.96.12.7", "10.96.12.8"}, details["addresses"])
+	answers := details["answers"].([]interface{})
+	assert.Len(t, answers, 3)
+	assert.Equal(t, "web.shop.default.svc.cluster.local", answers[0].(map[string]interface{})["data"])
+	assert.Equal(t, float64(30), answers[1].(map[string]interface{})["ttl"])
+	assert.Len(t, details["additionals"], 0)
+}
+
+func TestDissectDatagramRetransmission(t *testing.T) {
+	dissector := NewDissector().(api.DatagramDissector)
+	reqResMatcher := NewDissector().NewResponseRequestMatcher()
+	itemChannel := make(chan *api.OutputChannelItem, 1)
+	emitter := &api.Emitting{AppStats: &api.AppStats{}, OutputChannel: itemChannel}
+	options := &api.TrafficFilteringOptions{}
+	clientTcpID := &api.TcpID{SrcIP: "1", DstIP: "2", SrcPort: "40000", DstPort: "53"}
+	serverTcpID := &api.TcpID{SrcIP: "2", DstIP: "1", SrcPort: "53", DstPort: "40000"}
+	captureTime := time.Now()
+
+	request := query(7, typeAAAA, "missing", "example")
+	response := bytes.Join([][]byte{
+		header(7, flagResponse|flagAuthoritative|3, 1, 0, 1, 0),
+		question(nameBytes("missing", "example"), typeAAAA),
+		record(nameBytes("example"), typeSOA, 3600, bytes.Join([][]byte{nameBytes("ns", "example"), nameBytes("admin", "example"), uint32Bytes(1), uint32Bytes(7200), uint32Bytes(900), uint32Bytes(1209600), uint32Bytes(300)}, nil)),
+	}, nil)
+
+	assert.Nil(t, dissector.DissectDatagram(request, clientTcpID, captureTime, emitter, options, reqResMatcher))
+	assert.Nil(t, dissector.DissectDatagram(request, clientTcpID, captureTime.Add(time.Second), emitter, options, reqResMatcher))
+	assert.Nil(t, dissector.DissectDatagram(response, serverTcpID, captureTime.Add(time.Second+5*time.Millisecond), emitter, options, reqResMatcher))
+	assert.Len(t, itemChannel, 1)
+
+	entry := analyze(t, <-itemChannel)
+	assert.Equal(t, int64(5), entry.ElapsedTime)
+	assert.Equal(t, "NXDOMAIN", entry.Response["rcode"])
+	assert.Equal(t, true, entry.Response["authoritative"])
+	authority := entry.Response["authorities"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "ns.example admin.example 1 7200 900 1209600 300", authority["data"])
+
+	baseEntry := NewDissector().Summarize(entry)
+	assert.Equal(t, "AAAA", baseEntry.Method)
+	assert.Equal(t, "missing.example (NXDOMAIN)", baseEntry.Summary)
+	assert.Equal(t, `request.name == "missing.example"`, baseEntry.SummaryQuery)
+
+	_, _, err := NewDissector().Represent(entry.Request, entry.Response)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, countOpenMessages(reqResMatcher))
+}
+
+func countOpenMessages(reqResMatcher api.RequestResponseMatcher) int {
+	count := 0
+	reqResMatcher.GetMap().Range(func(key, value interface{}) bool {
+		count++
+		return true
+	})
+	return count
+}
+
+func TestDissectDatagramRejectsOtherTraffic(t *testing.T) {
+	dissector := NewDissector().(api.DatagramDissector)
+	reqResMatcher := NewDissector().NewResponseRequestMatcher()
+	itemChannel := make(chan *api.OutputChannelItem, 1)
+	emitter := &api.Emitting{AppStats: &api.AppStats{}, OutputChannel: itemChannel}
+	options := &api.TrafficFilteringOptions{}
+
+	tcpID := &api.TcpID{SrcIP: "1", DstIP: "2", SrcPort: "40000", DstPort: "8125"}
+	assert.NotNil(t, dissector.DissectDatagram(query(1, typeA, "example"), tcpID, time.Now(), emitter, options, reqResMatcher))
+
+	tcpID = &api.TcpID{SrcIP: "1", DstIP: "2", SrcPort: "40000", DstPort: "53"}
+	assert.NotNil(t, dissector.DissectDatagram([]byte("not a dns message"), tcpID, time.Now(), emitter, options, reqResMatcher))
+	// a loop of compression pointers
+	assert.NotNil(t, dissector.DissectDatagram(append(header(1, 0, 1, 0, 0, 0), 0xc0, headerLength, 0, 1, 0, 1), tcpID, time.Now(), emitter, options, reqResMatcher))
+	assert.Len(t, itemChannel, 0)
+}
+
+func TestDissectTcpRejectsOtherPorts(t *testing.T) {
+	dissector := NewDissector()
+	reader := bufio.NewReader(bytes.NewReader(tcpMessage(query(1, typeA, "example"))))
+	tcpID := &api.TcpID{SrcPort: "40000", DstPort: "8080"}
+	err := dissector.Dissect(reader, true, tcpID, &api.CounterPair{}, &api.SuperTimer{}, &api.SuperIdentifier{}, &api.Emitting{AppStats: &api.AppStats{}, OutputChannel: make(chan *api.OutputChannelItem, 1)}, &api.TrafficFilteringOptions{}, dissector.NewResponseRequestMatcher())
+	assert.Equal(t, errNotDnsPort, err)
+}
+
+func TestRecordData(t *testing.T) {
+	txt, err := recordData([]byte("\x05hello\x05world"), 0, 12, typeTXT)
+	assert.Nil(t, err)
+	assert.Equal(t, `"hello" "world"`, txt)
+
+	aaaa, err := recordData(append(make([]byte, 15), 1), 0, 16, typeAAAA)
+	assert.Nil(t, err)
+	assert.Equal(t, "::1", aaaa)
+
+	unknown, err := recordData([]byte{0xca, 0xfe}, 0, 2, 99)
+	assert.Nil(t, err)
+	assert.Equal(t, `\# 2 cafe`, unknown)
+}
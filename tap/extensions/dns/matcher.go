@@ -0,0 +1,88 @@
+package dns
+
+import (
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// Key is `{client_ip}_{server_ip}_{client_port}_{server_port}_{transaction_id}`
+type requestResponseMatcher struct {
+	openMessagesMap *sync.Map
+}
+
+func createResponseRequestMatcher() api.RequestResponseMatcher {
+	return &requestResponseMatcher{openMessagesMap: &sync.Map{}}
+}
+
+func (matcher *requestResponseMatcher) GetMap() *sync.Map {
+	return matcher.openMessagesMap
+}
+func (matcher *requestResponseMatcher) SetMaxTry(value int) {
+}
+
+func (matcher *requestResponseMatcher) registerRequest(ident string, request *DnsRequest, captureTime time.Time) *api.OutputChannelItem {
+	requestDnsMessage := api.GenericMessage{
+		IsRequest:   true,
+		CaptureTime: captureTime,
+		Payload: DnsPayload{
+			Data: &DnsWrapper{
+				Method:  request.Type,
+				Url:     request.Name,
+				Details: request,
+			},
+		},
+	}
+
+	if response, found := matcher.openMessagesMap.Load(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		responseDnsMessage := response.(*api.GenericMessage)
+		if !responseDnsMessage.IsRequest {
+			matcher.openMessagesMap.Delete(ident)
+			return matcher.preparePair(&requestDnsMessage, responseDnsMessage)
+		}
+	}
+
+	// a retransmitted query replaces the unanswered one, its response is timed against the last attempt
+	matcher.openMessagesMap.Store(ident, &requestDnsMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) registerResponse(ident string, response *DnsResponse, captureTime time.Time) *api.OutputChannelItem {
+	responseDnsMessage := api.GenericMessage{
+		IsRequest:   false,
+		CaptureTime: captureTime,
+		Payload: DnsPayload{
+			Data: &DnsWrapper{
+				Method:  response.Rcode,
+				Url:     "",
+				Details: response,
+			},
+		},
+	}
+
+	if request, found := matcher.openMessagesMap.Load(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		requestDnsMessage := request.(*api.GenericMessage)
+		if requestDnsMessage.IsRequest {
+			matcher.openMessagesMap.Delete(ident)
+			return matcher.preparePair(requestDnsMessage, &responseDnsMessage)
+		}
+	}
+
+	matcher.openMessagesMap.Store(ident, &responseDnsMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) preparePair(requestDnsMessage *api.GenericMessage, responseDnsMessage *api.GenericMessage) *api.OutputChannelItem {
+	return &api.OutputChannelItem{
+		Protocol:       protocol,
+		Timestamp:      requestDnsMessage.CaptureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: nil,
+		Pair: &api.RequestResponsePair{
+			Request:  *requestDnsMessage,
+			Response: *responseDnsMessage,
+		},
+	}
+}
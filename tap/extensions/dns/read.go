@@ -0,0 +1,339 @@
+package dns
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+)
+
+// readTcpMessage reads a message of DNS over TCP, which is prefixed by its two bytes length
+func readTcpMessage(b *bufio.Reader) ([]byte, error) {
+	prefix := make([]byte, 2)
+	if _, err := io.ReadFull(b, prefix); err != nil {
+		return nil, err
+	}
+
+	length := int(binary.BigEndian.Uint16(prefix))
+	if length < headerLength {
+		return nil, fmt.Errorf("invalid DNS message length: %d", length)
+	}
+
+	message := make([]byte, length)
+	if _, err := io.ReadFull(b, message); err != nil {
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return message, nil
+}
+
+type messageReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *messageReader) uint16() (uint16, error) {
+	if r.offset+2 > len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	value := binary.BigEndian.Uint16(r.data[r.offset:])
+	r.offset += 2
+	return value, nil
+}
+
+func (r *messageReader) uint32() (uint32, error) {
+	if r.offset+4 > len(r.data) {
+		return 0, io.ErrUnexpectedEOF
+	}
+	value := binary.BigEndian.Uint32(r.data[r.offset:])
+	r.offset += 4
+	return value, nil
+}
+
+func (r *messageReader) name() (string, error) {
+	name, next, err := readName(r.data, r.offset)
+	if err != nil {
+		return "", err
+	}
+	r.offset = next
+	return name, nil
+}
+
+// readName reads the name at the offset following the compression pointers,
+// it returns the offset right after the name as it appears at the offset
+func readName(data []byte, offset int) (string, int, error) {
+	labels := make([]string, 0)
+	length := 0
+	pointers := 0
+	next := -1
+
+	for {
+		if offset >= len(data) {
+			return "", 0, io.ErrUnexpectedEOF
+		}
+		labelLength := int(data[offset])
+
+		switch labelLength & 0xc0 {
+		case 0x00:
+			if labelLength == 0 {
+				if next < 0 {
+					next = offset + 1
+				}
+				if len(labels) == 0 {
+					return ".", next, nil
+				}
+				return strings.Join(labels, "."), next, nil
+			}
+			if offset+1+labelLength > len(data) {
+				return "", 0, io.ErrUnexpectedEOF
+			}
+			length += labelLength + 1
+			if length > maxNameLength {
+				return "", 0, errors.New("DNS name is too long")
+			}
+			labels = append(labels, string(data[offset+1:offset+1+labelLength]))
+			offset += 1 + labelLength
+		case 0xc0:
+			if offset+2 > len(data) {
+				return "", 0, io.ErrUnexpectedEOF
+			}
+			pointers++
+			if pointers > maxPointers {
+				return "", 0, errors.New("too many DNS compression pointers")
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(data[offset:]) & 0x3fff)
+		default:
+			return "", 0, fmt.Errorf("invalid DNS label type: %#x", labelLength&0xc0)
+		}
+	}
+}
+
+// parseMessage parses a DNS message, exactly one of the returned request and response is set
+func parseMessage(data []byte, transport string) (*DnsRequest, *DnsResponse, error) {
+	reader := &messageReader{data: data}
+	id, err := reader.uint16()
+	if err != nil {
+		return nil, nil, err
+	}
+	flags, err := reader.uint16()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	counts := make([]uint16, 4)
+	for i := range counts {
+		if counts[i], err = reader.uint16(); err != nil {
+			return nil, nil, err
+		}
+	}
+	questionCount := counts[0]
+	if questionCount > maxQuestions {
+		return nil, nil, fmt.Errorf("invalid DNS question count: %d", questionCount)
+	}
+	if int(counts[1])+int(counts[2])+int(counts[3]) > maxRecords {
+		return nil, nil, errors.New("invalid DNS record count")
+	}
+
+	questions := make([]DnsQuestion, 0, questionCount)
+	for i := 0; i < int(questionCount); i++ {
+		question, err := readQuestion(reader)
+		if err != nil {
+			return nil, nil, err
+		}
+		questions = append(questions, question)
+	}
+
+	if flags&flagResponse == 0 {
+		if questionCount == 0 {
+			return nil, nil, errors.New("DNS query without a question")
+		}
+		request := &DnsRequest{
+			Id:               id,
+			Opcode:           opcodeName((flags >> opcodeShift) & opcodeMask),
+			Transport:        transport,
+			RecursionDesired: flags&flagRecursionDesired != 0,
+			Name:             questions[0].Name,
+			Type:             questions[0].Type,
+			Questions:        questions,
+		}
+		return request, nil, nil
+	}
+
+	response := &DnsResponse{
+		Id:                 id,
+		Rcode:              rcodeName(flags & rcodeMask),
+		Authoritative:      flags&flagAuthoritative != 0,
+		Truncated:          flags&flagTruncated != 0,
+		RecursionAvailable: flags&flagRecursionAvailable != 0,
+		Addresses:          make([]string, 0),
+	}
+	if response.Answers, err = readRecords(reader, counts[1]); err != nil {
+		return nil, nil, err
+	}
+	if response.Authorities, err = readRecords(reader, counts[2]); err != nil {
+		return nil, nil, err
+	}
+	if response.Additionals, err = readRecords(reader, counts[3]); err != nil {
+		// a truncated response may end anywhere, what was read before is still meaningful
+		if !response.Truncated {
+			return nil, nil, err
+		}
+	}
+
+	for _, answer := range response.Answers {
+		if answer.Type == types[typeA] || answer.Type == types[typeAAAA] {
+			response.Addresses = append(response.Addresses, answer.Data)
+		}
+	}
+	return nil, response, nil
+}
+
+func readQuestion(reader *messageReader) (question DnsQuestion, err error) {
+	if question.Name, err = reader.name(); err != nil {
+		return
+	}
+	var qtype, qclass uint16
+	if qtype, err = reader.uint16(); err != nil {
+		return
+	}
+	if qclass, err = reader.uint16(); err != nil {
+		return
+	}
+	question.Type = typeName(qtype)
+	question.Class = className(qclass)
+	return
+}
+
+func readRecords(reader *messageReader, count uint16) ([]DnsRecord, error) {
+	records := make([]DnsRecord, 0, count)
+	for i := 0; i < int(count); i++ {
+		name, err := reader.name()
+		if err != nil {
+			return records, err
+		}
+		rtype, err := reader.uint16()
+		if err != nil {
+			return records, err
+		}
+		rclass, err := reader.uint16()
+		if err != nil {
+			return records, err
+		}
+		ttl, err := reader.uint32()
+		if err != nil {
+			return records, err
+		}
+		dataLength, err := reader.uint16()
+		if err != nil {
+			return records, err
+		}
+		dataOffset := reader.offset
+		if dataOffset+int(dataLength) > len(reader.data) {
+			return records, io.ErrUnexpectedEOF
+		}
+		reader.offset += int(dataLength)
+
+		// the OPT pseudo-record of EDNS carries options of the message rather than data of the name
+		if rtype == typeOPT {
+			continue
+		}
+
+		data, err := recordData(reader.data, dataOffset, int(dataLength), rtype)
+		if err != nil {
+			return records, err
+		}
+		records = append(records, DnsRecord{
+			Name:  name,
+			Type:  typeName(rtype),
+			Class: className(rclass),
+			Ttl:   ttl,
+			Data:  data,
+		})
+	}
+	return records, nil
+}
+
+// recordData formats the data of a record in the presentation format of the zone files
+func recordData(data []byte, offset int, length int, rtype uint16) (string, error) {
+	rdata := data[offset : offset+length]
+	reader := &messageReader{data: data[:offset+length], offset: offset}
+
+	switch rtype {
+	case typeA:
+		if length == net.IPv4len {
+			return net.IP(rdata).String(), nil
+		}
+	case typeAAAA:
+		if length == net.IPv6len {
+			return net.IP(rdata).String(), nil
+		}
+	case typeCNAME, typeNS, typePTR:
+		// the compression pointers may refer to the whole message
+		name, _, err := readName(data, offset)
+		return name, err
+	case typeMX:
+		preference, err := reader.uint16()
+		if err != nil {
+			return "", err
+		}
+		exchange, _, err := readName(data, reader.offset)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %s", preference, exchange), nil
+	case typeSRV:
+		values := make([]uint16, 3)
+		for i := range values {
+			value, err := reader.uint16()
+			if err != nil {
+				return "", err
+			}
+			values[i] = value
+		}
+		target, _, err := readName(data, reader.offset)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%d %d %d %s", values[0], values[1], values[2], target), nil
+	case typeSOA:
+		mname, err := reader.name()
+		if err != nil {
+			return "", err
+		}
+		rname, err := reader.name()
+		if err != nil {
+			return "", err
+		}
+		values := make([]uint32, 5)
+		for i := range values {
+			value, err := reader.uint32()
+			if err != nil {
+				return "", err
+			}
+			values[i] = value
+		}
+		return fmt.Sprintf("%s %s %d %d %d %d %d", mname, rname, values[0], values[1], values[2], values[3], values[4]), nil
+	case typeTXT:
+		texts := make([]string, 0)
+		for i := 0; i < length; {
+			textLength := int(rdata[i])
+			if i+1+textLength > length {
+				return "", io.ErrUnexpectedEOF
+			}
+			texts = append(texts, fmt.Sprintf("%q", rdata[i+1:i+1+textLength]))
+			i += 1 + textLength
+		}
+		return strings.Join(texts, " "), nil
+	}
+
+	// the generic format of RFC 3597 for the types that aren't decoded
+	return fmt.Sprintf(`\# %d %x`, length, rdata), nil
+}
@@ -0,0 +1,158 @@
+package dns
+
+import "fmt"
+
+const (
+	headerLength = 12
+	// the longest message a TCP length prefix can announce
+	maxMessageLength = 0xffff
+	maxNameLength    = 255
+	// bounds the compression pointers followed while reading a name, a loop of pointers is malformed
+	maxPointers  = 64
+	maxQuestions = 16
+	maxRecords   = 256
+
+	TransportUdp = "udp"
+	TransportTcp = "tcp"
+)
+
+// header flags
+const (
+	flagResponse           = 0x8000
+	flagAuthoritative      = 0x0400
+	flagTruncated          = 0x0200
+	flagRecursionDesired   = 0x0100
+	flagRecursionAvailable = 0x0080
+
+	opcodeShift = 11
+	opcodeMask  = 0x0f
+	rcodeMask   = 0x0f
+)
+
+const (
+	typeA     = 1
+	typeNS    = 2
+	typeCNAME = 5
+	typeSOA   = 6
+	typePTR   = 12
+	typeMX    = 15
+	typeTXT   = 16
+	typeAAAA  = 28
+	typeSRV   = 33
+	typeOPT   = 41
+)
+
+var types = map[uint16]string{
+	typeA:     "A",
+	typeNS:    "NS",
+	typeCNAME: "CNAME",
+	typeSOA:   "SOA",
+	typePTR:   "PTR",
+	typeMX:    "MX",
+	typeTXT:   "TXT",
+	typeAAAA:  "AAAA",
+	typeSRV:   "SRV",
+	typeOPT:   "OPT",
+	64:        "SVCB",
+	65:        "HTTPS",
+	252:       "AXFR",
+	255:       "ANY",
+	257:       "CAA",
+}
+
+var classes = map[uint16]string{
+	1:   "IN",
+	3:   "CH",
+	4:   "HS",
+	254: "NONE",
+	255: "ANY",
+}
+
+var opcodes = map[uint16]string{
+	0: "QUERY",
+	1: "IQUERY",
+	2: "STATUS",
+	4: "NOTIFY",
+	5: "UPDATE",
+}
+
+var rcodes = map[uint16]string{
+	0:  "NOERROR",
+	1:  "FORMERR",
+	2:  "SERVFAIL",
+	3:  "NXDOMAIN",
+	4:  "NOTIMP",
+	5:  "REFUSED",
+	6:  "YXDOMAIN",
+	7:  "YXRRSET",
+	8:  "NXRRSET",
+	9:  "NOTAUTH",
+	10: "NOTZONE",
+}
+
+// the numeric fallbacks follow the presentation format of RFC 3597
+func typeName(value uint16) string {
+	if name, ok := types[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", value)
+}
+
+func className(value uint16) string {
+	// the top bit is the unicast-response/cache-flush bit of mDNS
+	value &= 0x7fff
+	if name, ok := classes[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("CLASS%d", value)
+}
+
+func opcodeName(value uint16) string {
+	if name, ok := opcodes[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("OPCODE%d", value)
+}
+
+func rcodeName(value uint16) string {
+	if name, ok := rcodes[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("RCODE%d", value)
+}
+
+type DnsQuestion struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Class string `json:"class"`
+}
+
+type DnsRecord struct {
+	Name  string `json:"name"`
+	Type  string `json:"type"`
+	Class string `json:"class"`
+	Ttl   uint32 `json:"ttl"`
+	Data  string `json:"data"`
+}
+
+type DnsRequest struct {
+	Id               uint16        `json:"id"`
+	Opcode           string        `json:"opcode"`
+	Transport        string        `json:"transport"`
+	RecursionDesired bool          `json:"recursionDesired"`
+	Name             string        `json:"name"`
+	Type             string        `json:"type"`
+	Questions        []DnsQuestion `json:"questions"`
+}
+
+type DnsResponse struct {
+	Id                 uint16      `json:"id"`
+	Rcode              string      `json:"rcode"`
+	Authoritative      bool        `json:"authoritative"`
+	Truncated          bool        `json:"truncated"`
+	RecursionAvailable bool        `json:"recursionAvailable"`
+	Addresses          []string    `json:"addresses"`
+	Answers            []DnsRecord `json:"answers"`
+	Authorities        []DnsRecord `json:"authorities"`
+	Additionals        []DnsRecord `json:"additionals"`
+}
@@ -0,0 +1,375 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+var anomalyProtocol api.Protocol = api.Protocol{
+	Name:            "http",
+	LongName:        "Hypertext Transfer Protocol -- HTTP/1.1 [ Protocol Anomaly ]",
+	Abbreviation:    "ANOMALY",
+	Macro:           "anomaly",
+	Version:         "7230",
+	BackgroundColor: "#d9534f",
+	ForegroundColor: "#ffffff",
+	FontSize:        10,
+	ReferenceLink:   "https://datatracker.ietf.org/doc/html/rfc7230#section-9.5",
+	Ports:           []string{"80", "443", "8080"},
+	Priority:        0,
+}
+
+const (
+	anomalyMessageRequest  = "request"
+	anomalyMessageResponse = "response"
+
+	anomalyContentLengthAndTransferEncoding = "both Content-Length and Transfer-Encoding"
+	anomalyConflictingContentLength         = "conflicting Content-Length"
+	anomalyInvalidContentLength             = "invalid Content-Length"
+	anomalyUnsupportedTransferEncoding      = "unsupported Transfer-Encoding"
+	anomalyWhitespaceBeforeColon            = "whitespace before the colon of a header"
+	anomalyMissingColon                     = "header line without a colon"
+	anomalyObsoleteLineFolding              = "obsolete line folding"
+	anomalyBareLineFeed                     = "line ending without a carriage return"
+	anomalyMalformedBody                    = "malformed body"
+	anomalyMalformedRequest                 = "malformed request"
+	anomalyMalformedResponse                = "malformed response"
+)
+
+var (
+	requestLinePattern  = regexp.MustCompile(`^[A-Z]+ \S+ HTTP/1\.[01]\r?\n$`)
+	responseLinePattern = regexp.MustCompile(`^HTTP/1\.[01] `)
+)
+
+// ProtocolAnomaly is an HTTP/1.x message whose framing is ambiguous or that couldn't be parsed, proxies and servers
+// that disagree on where such a message ends can be smuggled requests through. Raw holds the start line and the
+// header lines as they were sent, with the sensitive values masked, the body isn't kept since its bounds can't be
+// trusted. Parsed is set when the message was parsed anyway, its entry is recorded too
+type ProtocolAnomaly struct {
+	Message   string   `json:"message"`
+	Reasons   []string `json:"reasons"`
+	StartLine string   `json:"startLine"`
+	Raw       string   `json:"raw"`
+	Encoding  string   `json:"encoding,omitempty"`
+	Parsed    bool     `json:"parsed"`
+}
+
+type ProtocolAnomalyPayload struct {
+	Anomaly *ProtocolAnomaly
+}
+
+func (p ProtocolAnomalyPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Anomaly)
+}
+
+// peekHTTPMessageHead returns the head of the next message when its start line is an HTTP/1.x one, the anomalies
+// are only looked for on the streams that carry HTTP
+func peekHTTPMessageHead(b *bufio.Reader, isRequest bool) []byte {
+	startLine := peekStartLine(b)
+	if startLine == nil {
+		return nil
+	}
+
+	if isRequest && !requestLinePattern.Match(startLine) || !isRequest && !responseLinePattern.Match(startLine) {
+		return nil
+	}
+
+	if head := peekMessageHead(b); head != nil {
+		return head
+	}
+
+	return startLine
+}
+
+// framingAnomalies lists what makes the framing of a message ambiguous in its head, these are the differences
+// request smuggling relies on, see RFC 7230 section 3.3.3
+func framingAnomalies(head []byte) []string {
+	reasons := make([]string, 0)
+	addReason := func(reason string) {
+		for _, existing := range reasons {
+			if existing == reason {
+				return
+			}
+		}
+		reasons = append(reasons, reason)
+	}
+
+	var contentLengths, transferEncodings []string
+	lines := bytes.SplitAfter(head, []byte("\n"))
+	for i, line := range lines {
+		if len(line) == 0 {
+			continue
+		}
+		if !bytes.HasSuffix(line, []byte("\r\n")) {
+			addReason(anomalyBareLineFeed)
+		}
+
+		content := strings.TrimRight(string(line), "\r\n")
+		if i == 0 || len(content) == 0 {
+			continue
+		}
+
+		if content[0] == ' ' || content[0] == '\t' {
+			addReason(anomalyObsoleteLineFolding)
+			continue
+		}
+
+		colon := strings.IndexByte(content, ':')
+		if colon < 0 {
+			addReason(anomalyMissingColon)
+			continue
+		}
+
+		name := content[:colon]
+		if strings.TrimRight(name, " \t") != name {
+			addReason(anomalyWhitespaceBeforeColon)
+		}
+
+		value := strings.Trim(content[colon+1:], " \t")
+		switch strings.ToLower(strings.TrimRight(name, " \t")) {
+		case "content-length":
+			for _, contentLength := range strings.Split(value, ",") {
+				contentLengths = append(contentLengths, strings.TrimSpace(contentLength))
+			}
+		case "transfer-encoding":
+			transferEncodings = append(transferEncodings, value)
+		}
+	}
+
+	if len(contentLengths) > 0 && len(transferEncodings) > 0 {
+		addReason(anomalyContentLengthAndTransferEncoding)
+	}
+
+	for _, contentLength := range contentLengths {
+		if _, err := strconv.ParseUint(contentLength, 10, 63); err != nil {
+			addReason(anomalyInvalidContentLength)
+		} else if contentLength != contentLengths[0] {
+			addReason(anomalyConflictingContentLength)
+		}
+	}
+
+	// chunked is the only coding the parser knows how to frame
+	if len(transferEncodings) > 1 || len(transferEncodings) == 1 && !strings.EqualFold(transferEncodings[0], "chunked") {
+		addReason(anomalyUnsupportedTransferEncoding)
+	}
+
+	return reasons
+}
+
+// handleAnomaly emits an anomaly for a message that couldn't be parsed, or for a parsed one when its framing is
+// ambiguous, parseErr is the error of the parser
+func handleAnomaly(head []byte, parseErr error, isRequest bool, tcpID *api.TcpID, superTimer *api.SuperTimer, emitter api.Emitter, options *api.TrafficFilteringOptions) {
+	if head == nil {
+		return
+	}
+
+	reasons := framingAnomalies(head)
+	if parseErr != nil && len(reasons) == 0 {
+		if isRequest {
+			reasons = append(reasons, anomalyMalformedRequest)
+		} else {
+			reasons = append(reasons, anomalyMalformedResponse)
+		}
+	}
+	if len(reasons) == 0 {
+		return
+	}
+
+	emitAnomaly(head, reasons, parseErr == nil, isRequest, tcpID, superTimer, emitter, options)
+}
+
+func emitAnomaly(head []byte, reasons []string, parsed bool, isRequest bool, tcpID *api.TcpID, superTimer *api.SuperTimer, emitter api.Emitter, options *api.TrafficFilteringOptions) {
+	start, _ := headerSectionBounds(head)
+	if start == 0 {
+		start = len(head)
+	}
+
+	startLine := string(head[:start])
+	if isRequest {
+		startLine = redactRequestLine(startLine, options)
+	}
+	raw := append([]byte(startLine), parseRawHeaders(head[start:], !options.DisableRedaction, options.RedactionRules).Block...)
+
+	anomaly := &ProtocolAnomaly{
+		Reasons:   reasons,
+		StartLine: strings.TrimRight(startLine, "\r\n"),
+		Parsed:    parsed,
+	}
+	if utf8.Valid(raw) {
+		anomaly.Raw = string(raw)
+	} else {
+		anomaly.Raw = base64.StdEncoding.EncodeToString(raw)
+		anomaly.Encoding = "base64"
+	}
+
+	connectionInfo := &api.ConnectionInfo{
+		ClientIP:   tcpID.SrcIP,
+		ClientPort: tcpID.SrcPort,
+		ServerIP:   tcpID.DstIP,
+		ServerPort: tcpID.DstPort,
+		IsOutgoing: true,
+	}
+	anomaly.Message = anomalyMessageRequest
+	if !isRequest {
+		connectionInfo = &api.ConnectionInfo{
+			ClientIP:   tcpID.DstIP,
+			ClientPort: tcpID.DstPort,
+			ServerIP:   tcpID.SrcIP,
+			ServerPort: tcpID.SrcPort,
+			IsOutgoing: false,
+		}
+		anomaly.Message = anomalyMessageResponse
+	}
+
+	emitter.Emit(&api.OutputChannelItem{
+		Protocol:       anomalyProtocol,
+		Timestamp:      superTimer.CaptureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: connectionInfo,
+		Pair: &api.RequestResponsePair{
+			Request: api.GenericMessage{
+				IsRequest:   true,
+				CaptureTime: superTimer.CaptureTime,
+				Payload:     ProtocolAnomalyPayload{Anomaly: anomaly},
+			},
+			Response: api.GenericMessage{},
+		},
+	})
+}
+
+// redactRequestLine masks the query params of the request target like the ones of the parsed requests
+func redactRequestLine(requestLine string, options *api.TrafficFilteringOptions) string {
+	parts := strings.SplitN(requestLine, " ", 3)
+	if len(parts) != 3 || !strings.Contains(parts[1], "?") {
+		return requestLine
+	}
+
+	target, err := url.ParseRequestURI(parts[1])
+	if err != nil {
+		return requestLine
+	}
+
+	if !options.DisableRedaction {
+		filterUrl(target)
+	}
+	for i := range options.RedactionRules {
+		if options.RedactionRules[i].In == api.RedactionInQuery {
+			redactQuery(target, &options.RedactionRules[i])
+		}
+	}
+
+	return parts[0] + " " + target.String() + " " + parts[2]
+}
+
+func analyzeAnomaly(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	request := item.Pair.Request.Payload.(map[string]interface{})
+
+	return &api.Entry{
+		Protocol: item.Protocol,
+		Source: &api.TCP{
+			Name: resolvedSource,
+			IP:   item.ConnectionInfo.ClientIP,
+			Port: item.ConnectionInfo.ClientPort,
+		},
+		Destination: &api.TCP{
+			Name: resolvedDestination,
+			IP:   item.ConnectionInfo.ServerIP,
+			Port: item.ConnectionInfo.ServerPort,
+		},
+		Namespace:   namespace,
+		Outgoing:    item.ConnectionInfo.IsOutgoing,
+		Request:     request,
+		Response:    make(map[string]interface{}),
+		Timestamp:   item.Timestamp,
+		StartTime:   item.Pair.Request.CaptureTime,
+		ElapsedTime: 0,
+	}
+}
+
+func summarizeAnomaly(entry *api.Entry) *api.BaseEntry {
+	reasons, _ := entry.Request["reasons"].([]interface{})
+	summary := ""
+	if len(reasons) > 0 {
+		summary, _ = reasons[0].(string)
+	}
+	summaryQuery := fmt.Sprintf(`request.reasons[0] == "%s"`, summary)
+	method, _ := entry.Request["message"].(string)
+	methodQuery := fmt.Sprintf(`request.message == "%s"`, method)
+
+	return &api.BaseEntry{
+		Id:             entry.Id,
+		EntryId:        entry.EntryId,
+		Protocol:       entry.Protocol,
+		Summary:        summary,
+		SummaryQuery:   summaryQuery,
+		Status:         0,
+		StatusQuery:    "",
+		Method:         method,
+		MethodQuery:    methodQuery,
+		Timestamp:      entry.Timestamp,
+		Source:         entry.Source,
+		Destination:    entry.Destination,
+		IsOutgoing:     entry.Outgoing,
+		Latency:        entry.ElapsedTime,
+		Rules:          entry.Rules,
+		ContractStatus: entry.ContractStatus,
+	}
+}
+
+func representAnomaly(request map[string]interface{}) (repRequest []interface{}, bodySize int64) {
+	details, _ := json.Marshal([]api.TableData{
+		{
+			Name:     "Message",
+			Value:    request["message"].(string),
+			Selector: `request.message`,
+		},
+		{
+			Name:     "Start Line",
+			Value:    request["startLine"].(string),
+			Selector: `request.startLine`,
+		},
+		{
+			Name:     "Parsed",
+			Value:    request["parsed"].(bool),
+			Selector: `request.parsed`,
+		},
+	})
+	repRequest = append(repRequest, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(details),
+	})
+
+	repRequest = append(repRequest, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Reasons",
+		Data:  representSliceAsTable(request["reasons"].([]interface{}), `request.reasons`),
+	})
+
+	encoding, _ := request["encoding"].(string)
+	mimeType := "text/plain"
+	if encoding != "" {
+		mimeType = "application/octet-stream"
+	}
+	raw := request["raw"].(string)
+	repRequest = append(repRequest, api.SectionData{
+		Type:     api.BODY,
+		Title:    "Raw Message Head",
+		Encoding: encoding,
+		MimeType: mimeType,
+		Data:     raw,
+		Selector: `request.raw`,
+	})
+
+	return repRequest, int64(len(raw))
+}
@@ -0,0 +1,96 @@
+package http
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/up9inc/mizu/tap/api"
+)
+
+func TestFramingAnomalies(t *testing.T) {
+	tests := map[string][]string{
+		"POST / HTTP/1.1\r\nHost: a\r\nContent-Length: 4\r\n\r\n":                                 {},
+		"POST / HTTP/1.1\r\nContent-Length: 4\r\nTransfer-Encoding: chunked\r\n\r\n":              {anomalyContentLengthAndTransferEncoding},
+		"POST / HTTP/1.1\r\nContent-Length: 4\r\nContent-Length: 5\r\n\r\n":                       {anomalyConflictingContentLength},
+		"POST / HTTP/1.1\r\nContent-Length: 4, 4\r\n\r\n":                                         {},
+		"POST / HTTP/1.1\r\nContent-Length: -1\r\n\r\n":                                           {anomalyInvalidContentLength},
+		"POST / HTTP/1.1\r\nTransfer-Encoding: xchunked\r\n\r\n":                                  {anomalyUnsupportedTransferEncoding},
+		"POST / HTTP/1.1\r\nTransfer-Encoding: chunked\r\nTransfer-Encoding: identity\r\n\r\n":    {anomalyUnsupportedTransferEncoding},
+		"POST / HTTP/1.1\r\nTransfer-Encoding : chunked\r\n\r\n":                                  {anomalyWhitespaceBeforeColon},
+		"POST / HTTP/1.1\r\nTransfer-Encoding:\r\n chunked\r\n\r\n":                               {anomalyObsoleteLineFolding, anomalyUnsupportedTransferEncoding},
+		"POST / HTTP/1.1\nContent-Length: 4\n\n":                                                  {anomalyBareLineFeed},
+		"POST / HTTP/1.1\r\nHost a\r\n\r\n":                                                       {anomalyMissingColon},
+		"HTTP/1.1 200 OK\r\nContent-Length: 2\r\nTransfer-Encoding: chunked\r\nServer: a\r\n\r\n": {anomalyContentLengthAndTransferEncoding},
+	}
+
+	for head, expected := range tests {
+		reasons := framingAnomalies([]byte(head))
+		if len(expected) == 0 {
+			assert.Empty(t, reasons, head)
+		} else {
+			assert.Equal(t, expected, reasons, head)
+		}
+	}
+}
+
+func TestPeekHTTPMessageHead(t *testing.T) {
+	b := bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: a\r\n\r\nbody"))
+	assert.Equal(t, "GET / HTTP/1.1\r\nHost: a\r\n\r\n", string(peekHTTPMessageHead(b, true)))
+	// nothing is consumed
+	assert.Equal(t, 4+len("GET / HTTP/1.1\r\nHost: a\r\n\r\n"), b.Buffered())
+
+	assert.Nil(t, peekHTTPMessageHead(bufio.NewReader(strings.NewReader("SSH-2.0-OpenSSH_8.9\r\n")), true))
+	assert.Nil(t, peekHTTPMessageHead(bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: a\r\n\r\n")), false))
+	assert.NotNil(t, peekHTTPMessageHead(bufio.NewReader(strings.NewReader("HTTP/1.1 200 OK\r\n\r\n")), false))
+}
+
+func TestDissectAnomaly(t *testing.T) {
+	client := "POST /cart?token=abc HTTP/1.1\r\nHost: cart\r\nAuthorization: Bearer abc\r\nContent-Length: 4\r\nTransfer-Encoding: chunked\r\n\r\n" +
+		"0\r\n\r\n" +
+		"POST /cart HTTP/1.1\r\nHost: cart\r\nContent-Length: 4\r\nContent-Length: 5\r\n\r\n"
+
+	dissector := NewDissector()
+	emitter := &collectingEmitter{}
+	clientID := &api.TcpID{SrcIP: "1.1.1.1", DstIP: "2.2.2.2", SrcPort: "40000", DstPort: "80"}
+	_ = dissector.Dissect(bufio.NewReader(strings.NewReader(client)), true, clientID, &api.CounterPair{}, &api.SuperTimer{}, &api.SuperIdentifier{}, emitter, &api.TrafficFilteringOptions{}, dissector.NewResponseRequestMatcher())
+
+	assert.Len(t, emitter.items, 2)
+
+	// the first request is parsed, the parser ignores its Content-Length
+	smuggled := roundTrip(t, emitter.items[0])
+	assert.Equal(t, "anomaly", smuggled.Protocol.Macro)
+	entry := dissector.Analyze(smuggled, "", "", "")
+	assert.Equal(t, anomalyMessageRequest, entry.Request["message"])
+	assert.Equal(t, []interface{}{anomalyContentLengthAndTransferEncoding}, entry.Request["reasons"])
+	assert.Equal(t, true, entry.Request["parsed"])
+	assert.Equal(t, "POST /cart?token=[REDACTED] HTTP/1.1", entry.Request["startLine"])
+	assert.Contains(t, entry.Request["raw"], "Transfer-Encoding: chunked\r\n")
+	assert.NotContains(t, entry.Request["raw"], "Bearer abc")
+	assert.Equal(t, "2.2.2.2", entry.Destination.IP)
+
+	summary := dissector.Summarize(entry)
+	assert.Equal(t, anomalyContentLengthAndTransferEncoding, summary.Summary)
+	assert.Equal(t, anomalyMessageRequest, summary.Method)
+
+	// the second one is rejected by the parser
+	entry = dissector.Analyze(roundTrip(t, emitter.items[1]), "", "", "")
+	assert.Equal(t, []interface{}{anomalyConflictingContentLength}, entry.Request["reasons"])
+	assert.Equal(t, false, entry.Request["parsed"])
+
+	representation, headSize, err := dissector.Represent(entry.Request, entry.Response)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(len(entry.Request["raw"].(string))), headSize)
+	assert.Contains(t, string(representation), "Raw Message Head")
+}
+
+func TestDissectAnomalyNotHTTP(t *testing.T) {
+	dissector := NewDissector()
+	emitter := &collectingEmitter{}
+	clientID := &api.TcpID{SrcIP: "1.1.1.1", DstIP: "2.2.2.2", SrcPort: "40000", DstPort: "22"}
+	_ = dissector.Dissect(bufio.NewReader(strings.NewReader("SSH-2.0-OpenSSH_8.9\r\n\x00\x00\x01\x02")), true, clientID, &api.CounterPair{}, &api.SuperTimer{}, &api.SuperIdentifier{}, emitter, &api.TrafficFilteringOptions{}, dissector.NewResponseRequestMatcher())
+
+	// the streams of the other protocols aren't anomalies
+	assert.Len(t, emitter.items, 0)
+}
@@ -0,0 +1,213 @@
+package http
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type graphQLRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+	Extensions    map[string]interface{} `json:"extensions"`
+}
+
+type graphQLOperation struct {
+	kind string
+	name string
+}
+
+// parseGraphQL recognizes the GraphQL requests sent over HTTP, either POSTed as JSON (possibly batched) or as
+// application/graphql, or sent in the query string of a GET
+func parseGraphQL(reqDetails map[string]interface{}) *api.GraphQL {
+	requests := make([]graphQLRequest, 0)
+
+	method, _ := reqDetails["method"].(string)
+	postData, _ := reqDetails["postData"].(map[string]interface{})
+	text, _ := postData["text"].(string)
+	mimeType, _ := postData["mimeType"].(string)
+
+	switch {
+	case method == "GET":
+		queryString, _ := reqDetails["queryString"].(map[string]interface{})
+		query, _ := queryString["query"].(string)
+		request := graphQLRequest{Query: query}
+		request.OperationName, _ = queryString["operationName"].(string)
+		if variables, ok := queryString["variables"].(string); ok {
+			_ = json.Unmarshal([]byte(variables), &request.Variables)
+		}
+		if extensions, ok := queryString["extensions"].(string); ok {
+			_ = json.Unmarshal([]byte(extensions), &request.Extensions)
+		}
+		requests = append(requests, request)
+	case strings.HasPrefix(strings.ToLower(mimeType), "application/graphql"):
+		requests = append(requests, graphQLRequest{Query: text})
+	default:
+		trimmed := strings.TrimSpace(text)
+		// the other bodies aren't unmarshaled
+		if !strings.Contains(trimmed, `"query"`) && !strings.Contains(trimmed, `"persistedQuery"`) {
+			return nil
+		}
+		if strings.HasPrefix(trimmed, "[") {
+			if err := json.Unmarshal([]byte(trimmed), &requests); err != nil {
+				return nil
+			}
+		} else if strings.HasPrefix(trimmed, "{") {
+			var request graphQLRequest
+			if err := json.Unmarshal([]byte(trimmed), &request); err != nil {
+				return nil
+			}
+			requests = append(requests, request)
+		}
+	}
+
+	if len(requests) == 0 {
+		return nil
+	}
+
+	graphQL := describeGraphQLRequest(requests[0])
+	if graphQL != nil && len(requests) > 1 {
+		graphQL.BatchSize = len(requests)
+	}
+	return graphQL
+}
+
+func describeGraphQLRequest(request graphQLRequest) *api.GraphQL {
+	variables := request.Variables
+	if variables == nil {
+		variables = make(map[string]interface{})
+	}
+
+	if request.Query == "" {
+		// an automatic persisted query sends only the hash of the document once the server knows it
+		if _, ok := request.Extensions["persistedQuery"]; ok && request.OperationName != "" {
+			return &api.GraphQL{Operation: request.OperationName, Variables: variables}
+		}
+		return nil
+	}
+
+	operations := scanGraphQLOperations(request.Query)
+	for _, operation := range operations {
+		if request.OperationName == "" || operation.name == request.OperationName {
+			return &api.GraphQL{
+				Operation: operation.name,
+				Type:      operation.kind,
+				Variables: variables,
+			}
+		}
+	}
+	return nil
+}
+
+// scanGraphQLOperations lists the operations defined in a GraphQL document, it only tokenizes the top level of
+// the definitions so the selection sets are never parsed
+func scanGraphQLOperations(document string) []graphQLOperation {
+	operations := make([]graphQLOperation, 0)
+	depth := 0
+	definitionStart := true
+	// the index of the operation whose name is the next name token
+	named := -1
+
+	for i := 0; i < len(document); {
+		c := document[i]
+		switch {
+		case c == '#':
+			for i < len(document) && document[i] != '\n' && document[i] != '\r' {
+				i++
+			}
+			continue
+		case c == '"':
+			i = skipGraphQLString(document, i)
+			named = -1
+			continue
+		case c == '{' || c == '(' || c == '[':
+			if depth == 0 && c == '{' && definitionStart {
+				// the query shorthand, an anonymous query made of a selection set only
+				operations = append(operations, graphQLOperation{kind: api.GraphQLQuery})
+			}
+			if depth == 0 {
+				definitionStart = false
+				named = -1
+			}
+			depth++
+		case c == '}' || c == ')' || c == ']':
+			if depth > 0 {
+				depth--
+			}
+			if depth == 0 && c == '}' {
+				definitionStart = true
+			}
+		case isGraphQLNameStart(c):
+			start := i
+			for i < len(document) && isGraphQLNameContinue(document[i]) {
+				i++
+			}
+			if depth > 0 {
+				continue
+			}
+
+			name := document[start:i]
+			if named >= 0 {
+				operations[named].name = name
+				named = -1
+			} else if definitionStart && (name == api.GraphQLQuery || name == api.GraphQLMutation || name == api.GraphQLSubscription) {
+				operations = append(operations, graphQLOperation{kind: name})
+				named = len(operations) - 1
+			} else {
+				named = -1
+			}
+			definitionStart = false
+			continue
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',' || c == 0xef || c == 0xbb || c == 0xbf:
+			// the insignificant characters, including the bytes of the unicode BOM
+		default:
+			if depth == 0 {
+				named = -1
+			}
+		}
+		i++
+	}
+
+	return operations
+}
+
+// skipGraphQLString returns the offset right after the string or block string starting at the offset
+func skipGraphQLString(document string, i int) int {
+	if strings.HasPrefix(document[i:], `"""`) {
+		i += 3
+		for i < len(document) {
+			if strings.HasPrefix(document[i:], `\"""`) {
+				i += 4
+				continue
+			}
+			if strings.HasPrefix(document[i:], `"""`) {
+				return i + 3
+			}
+			i++
+		}
+		return i
+	}
+
+	i++
+	for i < len(document) {
+		switch document[i] {
+		case '\\':
+			i += 2
+			continue
+		case '"', '\n':
+			return i + 1
+		}
+		i++
+	}
+	return i
+}
+
+func isGraphQLNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isGraphQLNameContinue(c byte) bool {
+	return isGraphQLNameStart(c) || (c >= '0' && c <= '9')
+}
@@ -0,0 +1,90 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/up9inc/mizu/tap/api"
+)
+
+func postDetails(mimeType string, text string) map[string]interface{} {
+	return map[string]interface{}{
+		"method": "POST",
+		"postData": map[string]interface{}{
+			"mimeType": mimeType,
+			"text":     text,
+		},
+	}
+}
+
+func TestParseGraphQL(t *testing.T) {
+	body := `{
+		"query": "# the user page\nfragment userFields on User { id name }\nquery getUser($id: ID!) { user(id: $id) { ...userFields } }",
+		"operationName": "getUser",
+		"variables": {"id": "42"}
+	}`
+
+	graphQL := parseGraphQL(postDetails("application/json", body))
+	assert.Equal(t, &api.GraphQL{
+		Operation: "getUser",
+		Type:      api.GraphQLQuery,
+		Variables: map[string]interface{}{"id": "42"},
+	}, graphQL)
+}
+
+func TestParseGraphQLOperationName(t *testing.T) {
+	body := `{"query": "query list { users { id } } mutation rename($name: String = \"mizu\") @audit { rename(name: $name) { id } }", "operationName": "rename"}`
+
+	graphQL := parseGraphQL(postDetails("application/json", body))
+	assert.Equal(t, "rename", graphQL.Operation)
+	assert.Equal(t, api.GraphQLMutation, graphQL.Type)
+	assert.Equal(t, map[string]interface{}{}, graphQL.Variables)
+
+	// an operation name that isn't in the document
+	body = `{"query": "query list { users { id } }", "operationName": "missing"}`
+	assert.Nil(t, parseGraphQL(postDetails("application/json", body)))
+}
+
+func TestParseGraphQLShorthand(t *testing.T) {
+	graphQL := parseGraphQL(postDetails("application/graphql", `{ users(filter: "query { }") { id } }`))
+	assert.Equal(t, "", graphQL.Operation)
+	assert.Equal(t, api.GraphQLQuery, graphQL.Type)
+}
+
+func TestParseGraphQLBatch(t *testing.T) {
+	body := `[{"query": "subscription onMessage { message { text } }"}, {"query": "query me { me { id } }"}]`
+
+	graphQL := parseGraphQL(postDetails("application/json", body))
+	assert.Equal(t, "onMessage", graphQL.Operation)
+	assert.Equal(t, api.GraphQLSubscription, graphQL.Type)
+	assert.Equal(t, 2, graphQL.BatchSize)
+}
+
+func TestParseGraphQLGet(t *testing.T) {
+	reqDetails := map[string]interface{}{
+		"method": "GET",
+		"queryString": map[string]interface{}{
+			"query":     `query getUser($id: ID!) { user(id: $id) { name } }`,
+			"variables": `{"id": 7}`,
+		},
+	}
+
+	graphQL := parseGraphQL(reqDetails)
+	assert.Equal(t, "getUser", graphQL.Operation)
+	assert.Equal(t, map[string]interface{}{"id": float64(7)}, graphQL.Variables)
+}
+
+func TestParseGraphQLPersistedQuery(t *testing.T) {
+	body := `{"operationName": "getUser", "variables": {"id": "42"}, "extensions": {"persistedQuery": {"version": 1, "sha256Hash": "ecf4edb46db40b5132295c0291d62fb65d6759a9eedfa4d5d612dd5ec54a6b38"}}}`
+
+	graphQL := parseGraphQL(postDetails("application/json", body))
+	assert.Equal(t, "getUser", graphQL.Operation)
+	assert.Equal(t, "", graphQL.Type)
+}
+
+func TestParseGraphQLOtherRequests(t *testing.T) {
+	assert.Nil(t, parseGraphQL(postDetails("application/json", `{"name": "mizu"}`)))
+	assert.Nil(t, parseGraphQL(postDetails("application/json", `{"query": 42}`)))
+	assert.Nil(t, parseGraphQL(postDetails("text/plain", `query`)))
+	assert.Nil(t, parseGraphQL(map[string]interface{}{"method": "GET", "queryString": map[string]interface{}{"q": "mizu"}}))
+}
@@ -0,0 +1,194 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+const (
+	grpcMessageHeaderLength = 5
+	// nested messages deeper than this are kept as bytes
+	maxProtobufDepth = 16
+	// limits the memory a malicious compressed message can take
+	maxDecompressedGrpcMessageSize = 4 * 1024 * 1024
+)
+
+const (
+	protobufWireVarint          = 0
+	protobufWireFixed64         = 1
+	protobufWireLengthDelimited = 2
+	protobufWireFixed32         = 5
+)
+
+// getGrpcMessages decodes the messages of a gRPC request or response from its body, which the HTTP/2 assembler
+// encodes as base64, the body is rewound so it can still be converted to HAR
+func getGrpcMessages(body *io.ReadCloser, encoding string) []*api.GrpcMessage {
+	if *body == nil {
+		return nil
+	}
+
+	encoded, err := ioutil.ReadAll(*body)
+	*body = io.NopCloser(bytes.NewReader(encoded))
+	if err != nil {
+		return nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil
+	}
+
+	return decodeGrpcMessages(data, encoding)
+}
+
+// decodeGrpcMessages splits data into its length-prefixed messages, a truncated last message is dropped
+func decodeGrpcMessages(data []byte, encoding string) []*api.GrpcMessage {
+	var messages []*api.GrpcMessage
+	for len(data) >= grpcMessageHeaderLength {
+		compressed := data[0] == 1
+		length := binary.BigEndian.Uint32(data[1:grpcMessageHeaderLength])
+		data = data[grpcMessageHeaderLength:]
+		if uint64(length) > uint64(len(data)) {
+			break
+		}
+
+		payload := data[:length]
+		data = data[length:]
+
+		message := &api.GrpcMessage{Compressed: compressed, Size: int(length)}
+		if compressed {
+			// only gzip is decompressed, the other encodings are rarely used
+			if encoding != "gzip" {
+				messages = append(messages, message)
+				continue
+			}
+			if payload = gunzipGrpcMessage(payload); payload == nil {
+				messages = append(messages, message)
+				continue
+			}
+		}
+
+		if fields, ok := decodeProtobuf(payload, 0); ok {
+			message.Fields = fields
+		}
+		messages = append(messages, message)
+	}
+
+	return messages
+}
+
+func gunzipGrpcMessage(payload []byte) []byte {
+	reader, err := gzip.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return nil
+	}
+	defer reader.Close()
+
+	decompressed, err := ioutil.ReadAll(io.LimitReader(reader, maxDecompressedGrpcMessageSize+1))
+	if err != nil || len(decompressed) > maxDecompressedGrpcMessageSize {
+		return nil
+	}
+
+	return decompressed
+}
+
+// decodeProtobuf decodes a protobuf message without its schema, the fields are keyed by their numbers and repeated
+// fields become lists, ok is false when data isn't a valid protobuf message
+func decodeProtobuf(data []byte, depth int) (fields map[string]interface{}, ok bool) {
+	fields = make(map[string]interface{})
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, false
+		}
+		data = data[n:]
+
+		fieldNumber := key >> 3
+		if fieldNumber == 0 {
+			return nil, false
+		}
+
+		var value interface{}
+		switch key & 7 {
+		case protobufWireVarint:
+			varint, n := binary.Uvarint(data)
+			if n <= 0 {
+				return nil, false
+			}
+			value = varint
+			data = data[n:]
+		case protobufWireFixed64:
+			if len(data) < 8 {
+				return nil, false
+			}
+			value = binary.LittleEndian.Uint64(data)
+			data = data[8:]
+		case protobufWireLengthDelimited:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || length > uint64(len(data)-n) {
+				return nil, false
+			}
+			value = decodeLengthDelimited(data[n:n+int(length)], depth)
+			data = data[n+int(length):]
+		case protobufWireFixed32:
+			if len(data) < 4 {
+				return nil, false
+			}
+			value = binary.LittleEndian.Uint32(data)
+			data = data[4:]
+		default:
+			// groups are deprecated, they're treated as invalid
+			return nil, false
+		}
+
+		name := strconv.FormatUint(fieldNumber, 10)
+		if existing, found := fields[name]; !found {
+			fields[name] = value
+		} else if list, isList := existing.([]interface{}); isList {
+			fields[name] = append(list, value)
+		} else {
+			fields[name] = []interface{}{existing, value}
+		}
+	}
+
+	return fields, true
+}
+
+// decodeLengthDelimited guesses what a length-delimited field holds, printable text is assumed to be a string,
+// otherwise a nested message and bytes when it doesn't decode as one
+func decodeLengthDelimited(data []byte, depth int) interface{} {
+	if isPrintableText(data) {
+		return string(data)
+	}
+
+	if depth < maxProtobufDepth {
+		if fields, ok := decodeProtobuf(data, depth+1); ok {
+			return fields
+		}
+	}
+
+	return base64.StdEncoding.EncodeToString(data)
+}
+
+func isPrintableText(data []byte) bool {
+	if !utf8.Valid(data) {
+		return false
+	}
+
+	for _, r := range string(data) {
+		if !unicode.IsPrint(r) && r != '\n' && r != '\r' && r != '\t' {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func grpcFrame(compressed bool, payload []byte) []byte {
+	frame := make([]byte, grpcMessageHeaderLength, grpcMessageHeaderLength+len(payload))
+	if compressed {
+		frame[0] = 1
+	}
+	binary.BigEndian.PutUint32(frame[1:], uint32(len(payload)))
+	return append(frame, payload...)
+}
+
+// a message with name = "mizu" (1), id = 150 (2) and a nested message with name = "tap" (3)
+var helloRequest = []byte{0x0a, 0x04, 'm', 'i', 'z', 'u', 0x10, 0x96, 0x01, 0x1a, 0x05, 0x0a, 0x03, 't', 'a', 'p'}
+
+func TestDecodeGrpcMessages(t *testing.T) {
+	data := append(grpcFrame(false, helloRequest), grpcFrame(false, []byte{0x10, 0x01, 0x10, 0x02})...)
+
+	messages := decodeGrpcMessages(data, "")
+	assert.Len(t, messages, 2)
+
+	assert.Equal(t, len(helloRequest), messages[0].Size)
+	assert.Equal(t, "mizu", messages[0].Fields["1"])
+	assert.Equal(t, uint64(150), messages[0].Fields["2"])
+	assert.Equal(t, map[string]interface{}{"1": "tap"}, messages[0].Fields["3"])
+
+	// a repeated field becomes a list
+	assert.Equal(t, []interface{}{uint64(1), uint64(2)}, messages[1].Fields["2"])
+}
+
+func TestDecodeGrpcMessagesCompressed(t *testing.T) {
+	var compressed bytes.Buffer
+	writer := gzip.NewWriter(&compressed)
+	_, _ = writer.Write(helloRequest)
+	_ = writer.Close()
+
+	messages := decodeGrpcMessages(grpcFrame(true, compressed.Bytes()), "gzip")
+	assert.Len(t, messages, 1)
+	assert.True(t, messages[0].Compressed)
+	assert.Equal(t, "mizu", messages[0].Fields["1"])
+
+	// unsupported encodings are kept undecoded
+	messages = decodeGrpcMessages(grpcFrame(true, compressed.Bytes()), "snappy")
+	assert.Len(t, messages, 1)
+	assert.Nil(t, messages[0].Fields)
+}
+
+func TestDecodeGrpcMessagesInvalid(t *testing.T) {
+	// a truncated message is dropped
+	assert.Empty(t, decodeGrpcMessages(grpcFrame(false, helloRequest)[:10], ""))
+
+	// a payload that isn't protobuf is kept without fields
+	messages := decodeGrpcMessages(grpcFrame(false, []byte{0xff, 0xff}), "")
+	assert.Len(t, messages, 1)
+	assert.Nil(t, messages[0].Fields)
+}
@@ -13,17 +13,37 @@ import (
 )
 
 func filterAndEmit(item *api.OutputChannelItem, emitter api.Emitter, options *api.TrafficFilteringOptions) {
-	if IsIgnoredUserAgent(item, options) {
+	if IsIgnoredUserAgent(item, options) || isMizuTraffic(item) {
 		return
 	}
 
+	Redact(item, options)
+	replaceForwardedFor(item)
+
+	emitter.Emit(item)
+}
+
+// Redact masks the sensitive values of an http item like the tappers do before emitting it, with the built-in
+// redaction, the redaction rules and the PII patterns of the options
+func Redact(item *api.OutputChannelItem, options *api.TrafficFilteringOptions) {
 	if !options.DisableRedaction {
 		FilterSensitiveData(item, options)
 	}
 
-	replaceForwardedFor(item)
+	if len(options.RedactionRules) > 0 {
+		ApplyRedactionRules(item, options.RedactionRules)
+	}
 
-	emitter.Emit(item)
+	if len(options.PiiPatterns) > 0 {
+		MaskPii(item, options.PiiPatterns)
+	}
+}
+
+// isMizuTraffic is true for the pairs of the requests mizu sent itself, like the connections of the tappers to the api
+// server or the replayed requests
+func isMizuTraffic(item *api.OutputChannelItem) bool {
+	request := item.Pair.Request.Payload.(api.HTTPPayload).Data.(*http.Request)
+	return api.IsMizuTraffic(request.Header)
 }
 
 func replaceForwardedFor(item *api.OutputChannelItem) {
@@ -48,7 +68,7 @@ func replaceForwardedFor(item *api.OutputChannelItem) {
 }
 
 func handleHTTP2Stream(http2Assembler *Http2Assembler, tcpID *api.TcpID, superTimer *api.SuperTimer, emitter api.Emitter, options *api.TrafficFilteringOptions, reqResMatcher *requestResponseMatcher) error {
-	streamID, messageHTTP1, isGrpc, err := http2Assembler.readMessage()
+	streamID, messageHTTP1, interimResponses, isGrpc, err := http2Assembler.readMessage()
 	if err != nil {
 		return err
 	}
@@ -66,7 +86,11 @@ func handleHTTP2Stream(http2Assembler *Http2Assembler, tcpID *api.TcpID, superTi
 			streamID,
 			"HTTP2",
 		)
-		item = reqResMatcher.registerRequest(ident, &messageHTTP1, superTimer.CaptureTime, messageHTTP1.ProtoMinor)
+		var grpcMessages []*api.GrpcMessage
+		if isGrpc {
+			grpcMessages = getGrpcMessages(&messageHTTP1.Body, messageHTTP1.Header.Get("Grpc-Encoding"))
+		}
+		item = reqResMatcher.registerRequest(ident, &messageHTTP1, nil, grpcMessages, "", superTimer.CaptureTime, messageHTTP1.ProtoMinor)
 		if item != nil {
 			item.ConnectionInfo = &api.ConnectionInfo{
 				ClientIP:   tcpID.SrcIP,
@@ -86,7 +110,11 @@ func handleHTTP2Stream(http2Assembler *Http2Assembler, tcpID *api.TcpID, superTi
 			streamID,
 			"HTTP2",
 		)
-		item = reqResMatcher.registerResponse(ident, &messageHTTP1, superTimer.CaptureTime, messageHTTP1.ProtoMinor)
+		var grpcMessages []*api.GrpcMessage
+		if isGrpc {
+			grpcMessages = getGrpcMessages(&messageHTTP1.Body, messageHTTP1.Header.Get("Grpc-Encoding"))
+		}
+		item = reqResMatcher.registerResponse(ident, &messageHTTP1, nil, interimResponses, grpcMessages, superTimer.CaptureTime, messageHTTP1.ProtoMinor)
 		if item != nil {
 			item.ConnectionInfo = &api.ConnectionInfo{
 				ClientIP:   tcpID.DstIP,
@@ -110,11 +138,17 @@ func handleHTTP2Stream(http2Assembler *Http2Assembler, tcpID *api.TcpID, superTi
 	return nil
 }
 
-func handleHTTP1ClientStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, emitter api.Emitter, options *api.TrafficFilteringOptions, reqResMatcher *requestResponseMatcher) (switchingProtocolsHTTP2 bool, req *http.Request, err error) {
+func handleHTTP1ClientStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, emitter api.Emitter, options *api.TrafficFilteringOptions, reqResMatcher *requestResponseMatcher) (switchingProtocolsHTTP2 bool, webSocketId string, req *http.Request, err error) {
+	head := peekHTTPMessageHead(b, true)
+	rawHeaders := getRawHeaders(b, options)
 	req, err = http.ReadRequest(b)
 	if err != nil {
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			handleAnomaly(head, err, true, tcpID, superTimer, emitter, options)
+		}
 		return
 	}
+	handleAnomaly(head, nil, true, tcpID, superTimer, emitter, options)
 	counterPair.Lock()
 	counterPair.Request++
 	requestCounter := counterPair.Request
@@ -125,9 +159,16 @@ func handleHTTP1ClientStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api
 		switchingProtocolsHTTP2 = true
 	}
 
+	if isWebSocketUpgrade(req.Header) {
+		webSocketId = getWebSocketId(tcpID.SrcIP, tcpID.DstIP, tcpID.SrcPort, tcpID.DstPort, requestCounter)
+	}
+
 	var body []byte
 	body, err = ioutil.ReadAll(req.Body)
 	req.Body = io.NopCloser(bytes.NewBuffer(body)) // rewind
+	if err != nil && err != io.ErrUnexpectedEOF && head != nil {
+		emitAnomaly(head, []string{anomalyMalformedBody}, true, true, tcpID, superTimer, emitter, options)
+	}
 
 	ident := fmt.Sprintf(
 		"%s_%s_%s_%s_%d_%s",
@@ -138,7 +179,7 @@ func handleHTTP1ClientStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api
 		requestCounter,
 		"HTTP1",
 	)
-	item := reqResMatcher.registerRequest(ident, req, superTimer.CaptureTime, req.ProtoMinor)
+	item := reqResMatcher.registerRequest(ident, req, rawHeaders, nil, webSocketId, superTimer.CaptureTime, req.ProtoMinor)
 	if item != nil {
 		item.ConnectionInfo = &api.ConnectionInfo{
 			ClientIP:   tcpID.SrcIP,
@@ -152,11 +193,28 @@ func handleHTTP1ClientStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api
 	return
 }
 
-func handleHTTP1ServerStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, emitter api.Emitter, options *api.TrafficFilteringOptions, reqResMatcher *requestResponseMatcher) (switchingProtocolsHTTP2 bool, err error) {
+func handleHTTP1ServerStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, emitter api.Emitter, options *api.TrafficFilteringOptions, reqResMatcher *requestResponseMatcher) (switchingProtocolsHTTP2 bool, webSocketId string, err error) {
+	var rawHeaders *api.RawHeaders
 	var res *http.Response
-	res, err = http.ReadResponse(b, nil)
-	if err != nil {
-		return
+	var interimResponses []*http.Response
+	var head []byte
+	for {
+		head = peekHTTPMessageHead(b, false)
+		rawHeaders = getRawHeaders(b, options)
+		res, err = http.ReadResponse(b, nil)
+		if err != nil {
+			if err != io.EOF && err != io.ErrUnexpectedEOF {
+				handleAnomaly(head, err, false, tcpID, superTimer, emitter, options)
+			}
+			return
+		}
+		handleAnomaly(head, nil, false, tcpID, superTimer, emitter, options)
+
+		// interim responses belong to the request of the final response that follows them
+		if !isInterimResponse(res) {
+			break
+		}
+		interimResponses = append(interimResponses, res)
 	}
 	counterPair.Lock()
 	counterPair.Response++
@@ -168,9 +226,16 @@ func handleHTTP1ServerStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api
 		switchingProtocolsHTTP2 = true
 	}
 
+	if res.StatusCode == http.StatusSwitchingProtocols && isWebSocketUpgrade(res.Header) {
+		webSocketId = getWebSocketId(tcpID.DstIP, tcpID.SrcIP, tcpID.DstPort, tcpID.SrcPort, responseCounter)
+	}
+
 	var body []byte
 	body, err = ioutil.ReadAll(res.Body)
 	res.Body = io.NopCloser(bytes.NewBuffer(body)) // rewind
+	if err != nil && err != io.ErrUnexpectedEOF && head != nil {
+		emitAnomaly(head, []string{anomalyMalformedBody}, true, false, tcpID, superTimer, emitter, options)
+	}
 
 	ident := fmt.Sprintf(
 		"%s_%s_%s_%s_%d_%s",
@@ -181,7 +246,7 @@ func handleHTTP1ServerStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api
 		responseCounter,
 		"HTTP1",
 	)
-	item := reqResMatcher.registerResponse(ident, res, superTimer.CaptureTime, res.ProtoMinor)
+	item := reqResMatcher.registerResponse(ident, res, rawHeaders, interimResponses, nil, superTimer.CaptureTime, res.ProtoMinor)
 	if item != nil {
 		item.ConnectionInfo = &api.ConnectionInfo{
 			ClientIP:   tcpID.DstIP,
@@ -194,3 +259,22 @@ func handleHTTP1ServerStream(b *bufio.Reader, tcpID *api.TcpID, counterPair *api
 	}
 	return
 }
+
+func getRawHeaders(b *bufio.Reader, options *api.TrafficFilteringOptions) *api.RawHeaders {
+	if !options.PreserveRawHeaders {
+		return nil
+	}
+
+	block := peekRawHeaders(b)
+	if block == nil {
+		return nil
+	}
+
+	return parseRawHeaders(block, !options.DisableRedaction, options.RedactionRules)
+}
+
+// isInterimResponse reports whether the response is a 1xx informational response, 101 Switching Protocols is
+// final since the connection changes protocol after it
+func isInterimResponse(res *http.Response) bool {
+	return res.StatusCode >= 100 && res.StatusCode < 200 && res.StatusCode != http.StatusSwitchingProtocols
+}
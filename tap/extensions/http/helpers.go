@@ -95,3 +95,33 @@ func representSliceAsTable(slice []interface{}, selectorPrefix string) (represen
 	representation = string(obj)
 	return
 }
+
+// representHeaderListAsTable keeps the order and the duplicates of the headers, unlike representMapSliceAsTable
+func representHeaderListAsTable(headers []interface{}, selectorPrefix string) (representation string) {
+	table := make([]api.TableData, 0)
+	for i, item := range headers {
+		h := item.(map[string]interface{})
+		table = append(table, api.TableData{
+			Name:     fmt.Sprintf("%v", h["name"]),
+			Value:    h["value"],
+			Selector: fmt.Sprintf("%s[%d].value", selectorPrefix, i),
+		})
+	}
+
+	obj, _ := json.Marshal(table)
+	representation = string(obj)
+	return
+}
+
+// representGrpcMessages shows the decoded messages as json, the protobuf schema is unknown so a table of the
+// nested fields wouldn't be any easier to read
+func representGrpcMessages(grpcMessages interface{}, selector string) api.SectionData {
+	obj, _ := json.MarshalIndent(grpcMessages, "", "  ")
+	return api.SectionData{
+		Type:     api.BODY,
+		Title:    "gRPC Messages",
+		MimeType: "application/json",
+		Data:     string(obj),
+		Selector: selector,
+	}
+}
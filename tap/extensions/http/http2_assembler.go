@@ -49,8 +49,10 @@ var grpcStatusCodes = []string{
 }
 
 type messageFragment struct {
-	headers []hpack.HeaderField
-	data    []byte
+	headers  []hpack.HeaderField
+	trailers []hpack.HeaderField
+	interim  [][]hpack.HeaderField
+	data     []byte
 }
 
 type fragmentsByStream map[uint32]*messageFragment
@@ -59,7 +61,17 @@ func (fbs *fragmentsByStream) appendFrame(streamID uint32, frame http2.Frame) {
 	switch frame := frame.(type) {
 	case *http2.MetaHeadersFrame:
 		if existingFragment, ok := (*fbs)[streamID]; ok {
-			existingFragment.headers = append(existingFragment.headers, frame.Fields...)
+			switch {
+			case len(existingFragment.headers) == 0:
+				existingFragment.headers = frame.Fields
+			case isInterimStatus(existingFragment.headers) && len(existingFragment.data) == 0:
+				// a 1xx response (100-continue, 103 Early Hints) is followed by the final response headers
+				existingFragment.interim = append(existingFragment.interim, existingFragment.headers)
+				existingFragment.headers = frame.Fields
+			default:
+				// a header block after the first one carries the trailers
+				existingFragment.trailers = append(existingFragment.trailers, frame.Fields...)
+			}
 		} else {
 			// new fragment
 			(*fbs)[streamID] = &messageFragment{headers: frame.Fields}
@@ -84,12 +96,32 @@ func (fbs *fragmentsByStream) appendFrame(streamID uint32, frame http2.Frame) {
 	}
 }
 
-func (fbs *fragmentsByStream) pop(streamID uint32) ([]hpack.HeaderField, []byte) {
-	headers := (*fbs)[streamID].headers
-	data := (*fbs)[streamID].data
+func (fbs *fragmentsByStream) pop(streamID uint32) *messageFragment {
+	fragment := (*fbs)[streamID]
 	delete(*fbs, streamID)
 
-	return headers, data
+	return fragment
+}
+
+func isInterimStatus(headers []hpack.HeaderField) bool {
+	for _, header := range headers {
+		if header.Name == ":status" {
+			return len(header.Value) == 3 && header.Value[0] == '1'
+		}
+	}
+
+	return false
+}
+
+func headerFieldsToHTTP1(headers []hpack.HeaderField) http.Header {
+	// Note: header keys are converted by http.Header.Add to canonical names, e.g. content-type -> Content-Type.
+	// By converting the keys we violate the HTTP/2 specification, which state that all headers must be lowercase.
+	headersHTTP1 := make(http.Header)
+	for _, header := range headers {
+		headersHTTP1.Add(header.Name, header.Value)
+	}
+
+	return headersHTTP1
 }
 
 func createHTTP2Assembler(b *bufio.Reader) *Http2Assembler {
@@ -107,7 +139,7 @@ type Http2Assembler struct {
 	framer            *http2.Framer
 }
 
-func (ga *Http2Assembler) readMessage() (streamID uint32, messageHTTP1 interface{}, isGrpc bool, err error) {
+func (ga *Http2Assembler) readMessage() (streamID uint32, messageHTTP1 interface{}, interimResponses []*http.Response, isGrpc bool, err error) {
 	// Exactly one Framer is used for each half connection.
 	// (Instead of creating a new Framer for each ReadFrame operation)
 	// This is needed in order to decompress the headers,
@@ -126,22 +158,24 @@ func (ga *Http2Assembler) readMessage() (streamID uint32, messageHTTP1 interface
 		return
 	}
 
-	headers, data := ga.fragmentsByStream.pop(streamID)
+	fragment := ga.fragmentsByStream.pop(streamID)
 
-	// Note: header keys are converted by http.Header.Set to canonical names, e.g. content-type -> Content-Type.
-	// By converting the keys we violate the HTTP/2 specification, which state that all headers must be lowercase.
-	headersHTTP1 := make(http.Header)
-	for _, header := range headers {
-		headersHTTP1.Add(header.Name, header.Value)
+	headersHTTP1 := headerFieldsToHTTP1(fragment.headers)
+	var trailersHTTP1 http.Header
+	if len(fragment.trailers) > 0 {
+		trailersHTTP1 = headerFieldsToHTTP1(fragment.trailers)
 	}
-	dataString := base64.StdEncoding.EncodeToString(data)
+	dataString := base64.StdEncoding.EncodeToString(fragment.data)
 
 	// Use http1 types only because they are expected in http_matcher.
 	method := headersHTTP1.Get(":method")
 	status := headersHTTP1.Get(":status")
 
-	// gRPC detection
+	// gRPC detection, the status is sent in the trailers unless the response has no body
 	grpcStatus := headersHTTP1.Get("Grpc-Status")
+	if grpcStatus == "" {
+		grpcStatus = trailersHTTP1.Get("Grpc-Status")
+	}
 	if grpcStatus != "" {
 		isGrpc = true
 		status = grpcStatus
@@ -166,6 +200,7 @@ func (ga *Http2Assembler) readMessage() (streamID uint32, messageHTTP1 interface
 			ProtoMinor:    protoMinorHTTP2,
 			Body:          io.NopCloser(strings.NewReader(dataString)),
 			ContentLength: int64(len(dataString)),
+			Trailer:       trailersHTTP1,
 		}
 	} else if status != "" {
 		var statusCode int
@@ -183,6 +218,19 @@ func (ga *Http2Assembler) readMessage() (streamID uint32, messageHTTP1 interface
 			ProtoMinor:    protoMinorHTTP2,
 			Body:          io.NopCloser(strings.NewReader(dataString)),
 			ContentLength: int64(len(dataString)),
+			Trailer:       trailersHTTP1,
+		}
+
+		for _, interimHeaders := range fragment.interim {
+			interimHeadersHTTP1 := headerFieldsToHTTP1(interimHeaders)
+			interimStatusCode, _ := strconv.Atoi(interimHeadersHTTP1.Get(":status"))
+			interimResponses = append(interimResponses, &http.Response{
+				StatusCode: interimStatusCode,
+				Header:     interimHeadersHTTP1,
+				Proto:      protoHTTP2,
+				ProtoMajor: protoMajorHTTP2,
+				ProtoMinor: protoMinorHTTP2,
+			})
 		}
 	} else {
 		err = errors.New("failed to assemble stream: neither a request nor a message")
@@ -0,0 +1,33 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func headersFrame(fields ...hpack.HeaderField) *http2.MetaHeadersFrame {
+	return &http2.MetaHeadersFrame{HeadersFrame: &http2.HeadersFrame{}, Fields: fields}
+}
+
+func TestAppendFrameSeparatesInterimAndTrailers(t *testing.T) {
+	fbs := make(fragmentsByStream)
+	fbs.appendFrame(1, headersFrame(hpack.HeaderField{Name: ":status", Value: "103"}, hpack.HeaderField{Name: "link", Value: "</style.css>; rel=preload"}))
+	fbs.appendFrame(1, headersFrame(hpack.HeaderField{Name: ":status", Value: "200"}))
+	fbs.appendFrame(1, headersFrame(hpack.HeaderField{Name: "grpc-status", Value: "0"}))
+
+	fragment := fbs.pop(1)
+	assert.Equal(t, []hpack.HeaderField{{Name: ":status", Value: "200"}}, fragment.headers)
+	assert.Equal(t, []hpack.HeaderField{{Name: "grpc-status", Value: "0"}}, fragment.trailers)
+	assert.Len(t, fragment.interim, 1)
+	assert.Equal(t, "103", fragment.interim[0][0].Value)
+	assert.Empty(t, fbs)
+}
+
+func TestIsInterimStatus(t *testing.T) {
+	assert.True(t, isInterimStatus([]hpack.HeaderField{{Name: ":status", Value: "100"}}))
+	assert.False(t, isInterimStatus([]hpack.HeaderField{{Name: ":status", Value: "200"}}))
+	assert.False(t, isInterimStatus([]hpack.HeaderField{{Name: ":method", Value: "GET"}}))
+}
@@ -102,6 +102,8 @@ func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, co
 	}
 
 	switchingProtocolsHTTP2 := false
+	// set once the connection is upgraded to WebSocket, the frames that follow are dissected instead of HTTP
+	webSocketId := ""
 	for {
 		if switchingProtocolsHTTP2 {
 			switchingProtocolsHTTP2 = false
@@ -128,15 +130,27 @@ func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, co
 				continue
 			}
 			superIdentifier.Protocol = &http11protocol
+		} else if webSocketId != "" && (!isClient || peekWebSocketClientFrame(b)) {
+			// the client keeps sending requests when the server refuses the upgrade
+			err = handleWebSocketFrame(b, isClient, webSocketId, tcpID, superTimer, emitter, options, reqResMatcher)
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			} else if err != nil {
+				continue
+			}
 		} else if isClient {
 			var req *http.Request
-			switchingProtocolsHTTP2, req, err = handleHTTP1ClientStream(b, tcpID, counterPair, superTimer, emitter, options, reqResMatcher)
+			var upgradeId string
+			switchingProtocolsHTTP2, upgradeId, req, err = handleHTTP1ClientStream(b, tcpID, counterPair, superTimer, emitter, options, reqResMatcher)
 			if err == io.EOF || err == io.ErrUnexpectedEOF {
 				break
 			} else if err != nil {
 				continue
 			}
 			superIdentifier.Protocol = &http11protocol
+			if upgradeId != "" {
+				webSocketId = upgradeId
+			}
 
 			// In case of an HTTP2 upgrade, duplicate the HTTP1 request into HTTP2 with stream ID 1
 			if switchingProtocolsHTTP2 {
@@ -148,7 +162,7 @@ func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, co
 					tcpID.DstPort,
 					"HTTP2",
 				)
-				item := reqResMatcher.registerRequest(ident, req, superTimer.CaptureTime, req.ProtoMinor)
+				item := reqResMatcher.registerRequest(ident, req, nil, nil, "", superTimer.CaptureTime, req.ProtoMinor)
 				if item != nil {
 					item.ConnectionInfo = &api.ConnectionInfo{
 						ClientIP:   tcpID.SrcIP,
@@ -161,7 +175,7 @@ func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, co
 				}
 			}
 		} else {
-			switchingProtocolsHTTP2, err = handleHTTP1ServerStream(b, tcpID, counterPair, superTimer, emitter, options, reqResMatcher)
+			switchingProtocolsHTTP2, webSocketId, err = handleHTTP1ServerStream(b, tcpID, counterPair, superTimer, emitter, options, reqResMatcher)
 			if err == io.EOF || err == io.ErrUnexpectedEOF {
 				break
 			} else if err != nil {
@@ -179,6 +193,13 @@ func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, co
 }
 
 func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	if item.Protocol.Macro == webSocketProtocol.Macro {
+		return analyzeWebSocketFrame(item, resolvedSource, resolvedDestination, namespace)
+	}
+	if item.Protocol.Macro == anomalyProtocol.Macro {
+		return analyzeAnomaly(item, resolvedSource, resolvedDestination, namespace)
+	}
+
 	var host, authority, path string
 
 	request := item.Pair.Request.Payload.(map[string]interface{})
@@ -247,8 +268,45 @@ func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string,
 	reqDetails["_queryStringMerged"] = mapSliceMergeRepeatedKeys(reqDetails["_queryString"].([]interface{}))
 	reqDetails["queryString"] = mapSliceRebuildAsMap(reqDetails["_queryStringMerged"].([]interface{}))
 
+	if trailers, ok := request["trailers"].([]interface{}); ok {
+		reqDetails["_trailers"] = trailers
+		reqDetails["trailers"] = mapSliceRebuildAsMap(trailers)
+	}
+	if trailers, ok := response["trailers"].([]interface{}); ok {
+		resDetails["_trailers"] = trailers
+		resDetails["trailers"] = mapSliceRebuildAsMap(trailers)
+	}
+	if interimResponses, ok := response["interimResponses"]; ok {
+		resDetails["interimResponses"] = interimResponses
+	}
+
+	if rawHeaders, ok := request["rawHeaders"]; ok {
+		reqDetails["rawHeaders"] = rawHeaders
+	}
+	if rawHeaders, ok := response["rawHeaders"]; ok {
+		resDetails["rawHeaders"] = rawHeaders
+	}
+
+	if grpcMessages, ok := request["grpcMessages"]; ok {
+		reqDetails["grpcMessages"] = grpcMessages
+	}
+	if grpcMessages, ok := response["grpcMessages"]; ok {
+		resDetails["grpcMessages"] = grpcMessages
+	}
+
+	if maskedPii, ok := request["maskedPii"]; ok {
+		reqDetails["maskedPii"] = maskedPii
+	}
+	if maskedPii, ok := response["maskedPii"]; ok {
+		resDetails["maskedPii"] = maskedPii
+	}
+
 	statusCode := int(resDetails["status"].(float64))
-	if item.Protocol.Abbreviation == "gRPC" {
+	// the frames of the connection refer to the upgrade request by its id
+	if webSocketId, ok := request["websocketId"]; ok && statusCode == http.StatusSwitchingProtocols {
+		reqDetails["websocketId"] = webSocketId
+	}
+	if item.Protocol.Abbreviation == "gRPC" && statusCode >= 0 && statusCode < len(grpcStatusCodes) {
 		resDetails["statusText"] = grpcStatusCodes[statusCode]
 	}
 
@@ -277,10 +335,18 @@ func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string,
 		StartTime:   item.Pair.Request.CaptureTime,
 		ElapsedTime: elapsedTime,
 		HTTPPair:    string(httpPair),
+		GraphQL:     parseGraphQL(reqDetails),
 	}
 }
 
 func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
+	if entry.Protocol.Macro == webSocketProtocol.Macro {
+		return summarizeWebSocketFrame(entry)
+	}
+	if entry.Protocol.Macro == anomalyProtocol.Macro {
+		return summarizeAnomaly(entry)
+	}
+
 	summary := entry.Request["path"].(string)
 	summaryQuery := fmt.Sprintf(`request.path == "%s"`, summary)
 	method := entry.Request["method"].(string)
@@ -290,6 +356,7 @@ func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
 
 	return &api.BaseEntry{
 		Id:             entry.Id,
+		EntryId:        entry.EntryId,
 		Protocol:       entry.Protocol,
 		Summary:        summary,
 		SummaryQuery:   summaryQuery,
@@ -304,11 +371,12 @@ func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
 		Latency:        entry.ElapsedTime,
 		Rules:          entry.Rules,
 		ContractStatus: entry.ContractStatus,
+		Tag:            entry.Tag,
 	}
 }
 
 func representRequest(request map[string]interface{}) (repRequest []interface{}) {
-	details, _ := json.Marshal([]api.TableData{
+	detailsData := []api.TableData{
 		{
 			Name:     "Method",
 			Value:    request["method"].(string),
@@ -329,7 +397,15 @@ func representRequest(request map[string]interface{}) (repRequest []interface{})
 			Value:    int64(request["bodySize"].(float64)),
 			Selector: `request.bodySize`,
 		},
-	})
+	}
+	if webSocketId, ok := request["websocketId"].(string); ok {
+		detailsData = append(detailsData, api.TableData{
+			Name:     "WebSocket Id",
+			Value:    webSocketId,
+			Selector: `request.websocketId`,
+		})
+	}
+	details, _ := json.Marshal(detailsData)
 	repRequest = append(repRequest, api.SectionData{
 		Type:  api.TABLE,
 		Title: "Details",
@@ -357,6 +433,27 @@ func representRequest(request map[string]interface{}) (repRequest []interface{})
 		Data:  representMapSliceAsTable(request["_cookies"].([]interface{}), `request.cookies`),
 	})
 
+	if trailers, ok := request["_trailers"].([]interface{}); ok {
+		repRequest = append(repRequest, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Trailers",
+			Data:  representMapSliceAsTable(trailers, `request.trailers`),
+		})
+	}
+
+	rawHeaders, _ := request["rawHeaders"].(map[string]interface{})
+	if headers, ok := rawHeaders["headers"].([]interface{}); ok {
+		repRequest = append(repRequest, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Raw Headers",
+			Data:  representHeaderListAsTable(headers, `request.rawHeaders.headers`),
+		})
+	}
+
+	if grpcMessages, ok := request["grpcMessages"]; ok {
+		repRequest = append(repRequest, representGrpcMessages(grpcMessages, `request.grpcMessages`))
+	}
+
 	repRequest = append(repRequest, api.SectionData{
 		Type:  api.TABLE,
 		Title: "Query String",
@@ -447,6 +544,38 @@ func representResponse(response map[string]interface{}) (repResponse []interface
 		Data:  representMapSliceAsTable(response["_cookies"].([]interface{}), `response.cookies`),
 	})
 
+	if trailers, ok := response["_trailers"].([]interface{}); ok {
+		repResponse = append(repResponse, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Trailers",
+			Data:  representMapSliceAsTable(trailers, `response.trailers`),
+		})
+	}
+
+	interimResponses, _ := response["interimResponses"].([]interface{})
+	for i, item := range interimResponses {
+		interimResponse := item.(map[string]interface{})
+		headers, _ := interimResponse["headers"].([]interface{})
+		repResponse = append(repResponse, api.SectionData{
+			Type:  api.TABLE,
+			Title: fmt.Sprintf("Interim Response %v %v", interimResponse["status"], interimResponse["statusText"]),
+			Data:  representHeaderListAsTable(headers, fmt.Sprintf(`response.interimResponses[%d].headers`, i)),
+		})
+	}
+
+	rawHeaders, _ := response["rawHeaders"].(map[string]interface{})
+	if headers, ok := rawHeaders["headers"].([]interface{}); ok {
+		repResponse = append(repResponse, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Raw Headers",
+			Data:  representHeaderListAsTable(headers, `response.rawHeaders.headers`),
+		})
+	}
+
+	if grpcMessages, ok := response["grpcMessages"]; ok {
+		repResponse = append(repResponse, representGrpcMessages(grpcMessages, `response.grpcMessages`))
+	}
+
 	content, _ := response["content"].(map[string]interface{})
 	mimeType := content["mimeType"]
 	if mimeType == nil || len(mimeType.(string)) == 0 {
@@ -470,6 +599,23 @@ func representResponse(response map[string]interface{}) (repResponse []interface
 
 func (d dissecting) Represent(request map[string]interface{}, response map[string]interface{}) (object []byte, bodySize int64, err error) {
 	representation := make(map[string]interface{})
+	// only the frames have an opcode
+	if _, ok := request["opcode"]; ok {
+		repRequest, frameSize := representWebSocketFrame(request)
+		representation["request"] = repRequest
+		representation["response"] = make([]interface{}, 0)
+		object, err = json.Marshal(representation)
+		return object, frameSize, err
+	}
+	// only the anomalies have reasons
+	if _, ok := request["reasons"]; ok {
+		repRequest, headSize := representAnomaly(request)
+		representation["request"] = repRequest
+		representation["response"] = make([]interface{}, 0)
+		object, err = json.Marshal(representation)
+		return object, headSize, err
+	}
+
 	repRequest := representRequest(request)
 	repResponse, bodySize := representResponse(response)
 	representation["request"] = repRequest
@@ -480,9 +626,11 @@ func (d dissecting) Represent(request map[string]interface{}, response map[strin
 
 func (d dissecting) Macros() map[string]string {
 	return map[string]string{
-		`http`:  fmt.Sprintf(`proto.name == "%s" and proto.version.startsWith("%c")`, http11protocol.Name, http11protocol.Version[0]),
-		`http2`: fmt.Sprintf(`proto.name == "%s" and proto.version == "%s"`, http11protocol.Name, http2Protocol.Version),
-		`grpc`:  fmt.Sprintf(`proto.name == "%s" and proto.version == "%s" and proto.macro == "%s"`, http11protocol.Name, grpcProtocol.Version, grpcProtocol.Macro),
+		`http`:      fmt.Sprintf(`proto.name == "%s" and proto.version.startsWith("%c")`, http11protocol.Name, http11protocol.Version[0]),
+		`http2`:     fmt.Sprintf(`proto.name == "%s" and proto.version == "%s"`, http11protocol.Name, http2Protocol.Version),
+		`grpc`:      fmt.Sprintf(`proto.name == "%s" and proto.version == "%s" and proto.macro == "%s"`, http11protocol.Name, grpcProtocol.Version, grpcProtocol.Macro),
+		`websocket`: fmt.Sprintf(`proto.name == "%s" and proto.macro == "%s"`, http11protocol.Name, webSocketProtocol.Macro),
+		`anomaly`:   fmt.Sprintf(`proto.name == "%s" and proto.macro == "%s"`, http11protocol.Name, anomalyProtocol.Macro),
 	}
 }
 
@@ -44,9 +44,11 @@ func TestRegister(t *testing.T) {
 
 func TestMacros(t *testing.T) {
 	expectedMacros := map[string]string{
-		"http":  `proto.name == "http" and proto.version.startsWith("1")`,
-		"http2": `proto.name == "http" and proto.version == "2.0"`,
-		"grpc":  `proto.name == "http" and proto.version == "2.0" and proto.macro == "grpc"`,
+		"http":      `proto.name == "http" and proto.version.startsWith("1")`,
+		"http2":     `proto.name == "http" and proto.version == "2.0"`,
+		"grpc":      `proto.name == "http" and proto.version == "2.0" and proto.macro == "grpc"`,
+		"websocket": `proto.name == "http" and proto.macro == "websocket"`,
+		"anomaly":   `proto.name == "http" and proto.macro == "anomaly"`,
 	}
 	dissector := NewDissector()
 	macros := dissector.Macros()
@@ -11,10 +11,14 @@ import (
 // Key is {client_addr}_{client_port}_{dest_addr}_{dest_port}_{incremental_counter}_{proto_ident}
 type requestResponseMatcher struct {
 	openMessagesMap *sync.Map
+	// the paths of the connections upgraded to WebSocket, keyed by their WebSocket id
+	webSocketPaths *sync.Map
+	// the WebSocket ids of the connections mizu opened itself, their frames are dropped
+	mizuWebSockets *sync.Map
 }
 
 func createResponseRequestMatcher() api.RequestResponseMatcher {
-	return &requestResponseMatcher{openMessagesMap: &sync.Map{}}
+	return &requestResponseMatcher{openMessagesMap: &sync.Map{}, webSocketPaths: &sync.Map{}, mizuWebSockets: &sync.Map{}}
 }
 
 func (matcher *requestResponseMatcher) GetMap() *sync.Map {
@@ -24,16 +28,26 @@ func (matcher *requestResponseMatcher) GetMap() *sync.Map {
 func (matcher *requestResponseMatcher) SetMaxTry(value int) {
 }
 
-func (matcher *requestResponseMatcher) registerRequest(ident string, request *http.Request, captureTime time.Time, protoMinor int) *api.OutputChannelItem {
+func (matcher *requestResponseMatcher) registerRequest(ident string, request *http.Request, rawHeaders *api.RawHeaders, grpcMessages []*api.GrpcMessage, webSocketId string, captureTime time.Time, protoMinor int) *api.OutputChannelItem {
 	requestHTTPMessage := api.GenericMessage{
 		IsRequest:   true,
 		CaptureTime: captureTime,
 		Payload: api.HTTPPayload{
-			Type: TypeHttpRequest,
-			Data: request,
+			Type:         TypeHttpRequest,
+			Data:         request,
+			RawHeaders:   rawHeaders,
+			GrpcMessages: grpcMessages,
+			WebSocketId:  webSocketId,
 		},
 	}
 
+	if webSocketId != "" {
+		matcher.webSocketPaths.Store(webSocketId, request.URL.Path)
+		if api.IsMizuTraffic(request.Header) {
+			matcher.mizuWebSockets.Store(webSocketId, true)
+		}
+	}
+
 	if response, found := matcher.openMessagesMap.LoadAndDelete(ident); found {
 		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
 		responseHTTPMessage := response.(*api.GenericMessage)
@@ -47,13 +61,16 @@ func (matcher *requestResponseMatcher) registerRequest(ident string, request *ht
 	return nil
 }
 
-func (matcher *requestResponseMatcher) registerResponse(ident string, response *http.Response, captureTime time.Time, protoMinor int) *api.OutputChannelItem {
+func (matcher *requestResponseMatcher) registerResponse(ident string, response *http.Response, rawHeaders *api.RawHeaders, interimResponses []*http.Response, grpcMessages []*api.GrpcMessage, captureTime time.Time, protoMinor int) *api.OutputChannelItem {
 	responseHTTPMessage := api.GenericMessage{
 		IsRequest:   false,
 		CaptureTime: captureTime,
 		Payload: api.HTTPPayload{
-			Type: TypeHttpResponse,
-			Data: response,
+			Type:             TypeHttpResponse,
+			Data:             response,
+			RawHeaders:       rawHeaders,
+			InterimResponses: interimResponses,
+			GrpcMessages:     grpcMessages,
 		},
 	}
 
@@ -70,6 +87,22 @@ func (matcher *requestResponseMatcher) registerResponse(ident string, response *
 	return nil
 }
 
+// getWebSocketPath returns the path of the upgrade request, it's empty for the first frames of the server when
+// they're read before the request
+func (matcher *requestResponseMatcher) getWebSocketPath(webSocketId string) string {
+	if path, ok := matcher.webSocketPaths.Load(webSocketId); ok {
+		return path.(string)
+	}
+	return ""
+}
+
+// isMizuWebSocket is true for the connections upgraded by a request of mizu, like the ones of the tappers, the first
+// frames of the server can't be told apart when they're read before the request
+func (matcher *requestResponseMatcher) isMizuWebSocket(webSocketId string) bool {
+	_, ok := matcher.mizuWebSockets.Load(webSocketId)
+	return ok
+}
+
 func (matcher *requestResponseMatcher) preparePair(requestHTTPMessage *api.GenericMessage, responseHTTPMessage *api.GenericMessage, protoMinor int) *api.OutputChannelItem {
 	protocol := http11protocol
 	if protoMinor == 0 {
@@ -0,0 +1,181 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// the patterns don't match quotes or angle brackets, so masking them in a JSON or an XML body keeps it valid as long
+// as the placeholder replaces a string
+var piiRegexes = map[string]*regexp.Regexp{
+	// the header and the payload of a JWT are base64 encoded JSON objects, which start with eyJ
+	api.PiiJwt:        regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`),
+	api.PiiCreditCard: regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+	api.PiiEmail:      regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}\b`),
+	// the numbers need a country code or separators, so ids and timestamps aren't taken for phone numbers
+	api.PiiPhoneNumber: regexp.MustCompile(`\+\d{1,3}(?:[ .-]?\d{2,4}){2,5}\b|\(\d{3}\) ?\d{3}[ .-]\d{4}\b|\b\d{3}[.-]\d{3}[.-]\d{4}\b`),
+}
+
+// MaskPii masks the PII patterns in the text bodies of the item, the count of the masked values of each pattern is
+// reported in the payloads of the request and the response
+func MaskPii(item *api.OutputChannelItem, patterns []string) {
+	requestPayload := item.Pair.Request.Payload.(api.HTTPPayload)
+	request := requestPayload.Data.(*http.Request)
+	if body, err := ioutil.ReadAll(request.Body); err == nil {
+		var maskedBody []byte
+		maskedBody, requestPayload.MaskedPii = maskPiiBody(body, getContentTypeHeaderValue(request.Header), patterns)
+		request.Body = ioutil.NopCloser(bytes.NewBuffer(maskedBody))
+	}
+	item.Pair.Request.Payload = requestPayload
+
+	responsePayload := item.Pair.Response.Payload.(api.HTTPPayload)
+	response := responsePayload.Data.(*http.Response)
+	if body, err := ioutil.ReadAll(response.Body); err == nil {
+		var maskedBody []byte
+		maskedBody, responsePayload.MaskedPii = maskPiiBody(body, getContentTypeHeaderValue(response.Header), patterns)
+		response.Body = ioutil.NopCloser(bytes.NewBuffer(maskedBody))
+	}
+	item.Pair.Response.Payload = responsePayload
+}
+
+// maskPiiBody masks the patterns in a text body, the counts are nil when nothing was masked
+func maskPiiBody(body []byte, contentType string, patterns []string) ([]byte, map[string]int) {
+	if len(body) == 0 || !isTextContentType(contentType) {
+		return body, nil
+	}
+
+	isJson := isJsonContentType(contentType)
+	var counts map[string]int
+	for _, pattern := range api.PiiPatterns {
+		if !containsPattern(patterns, pattern) {
+			continue
+		}
+
+		var count int
+		body, count = maskPiiMatches(body, piiRegexes[pattern], isJson, func(match []byte) bool {
+			return pattern != api.PiiCreditCard || isLuhnValid(match)
+		})
+
+		if count > 0 {
+			if counts == nil {
+				counts = make(map[string]int)
+			}
+			counts[pattern] = count
+		}
+	}
+
+	return body, counts
+}
+
+// maskPiiMatches masks the matches of the regex that shouldMask accepts, a match outside of the strings of a JSON
+// body (e.g. a card number sent as a number) is masked with a quoted placeholder so the body stays valid
+func maskPiiMatches(body []byte, regex *regexp.Regexp, isJson bool, shouldMask func(match []byte) bool) ([]byte, int) {
+	var masked []byte
+	count := 0
+	last := 0
+	scanner := &jsonStringScanner{}
+	for _, match := range regex.FindAllIndex(body, -1) {
+		if !shouldMask(body[match[0]:match[1]]) {
+			continue
+		}
+
+		masked = append(masked, body[last:match[0]]...)
+		if isJson && !scanner.isInString(body, match[0]) {
+			masked = append(masked, '"')
+			masked = append(masked, maskedFieldPlaceholderValue...)
+			masked = append(masked, '"')
+		} else {
+			masked = append(masked, maskedFieldPlaceholderValue...)
+		}
+		last = match[1]
+		count++
+	}
+
+	if count == 0 {
+		return body, 0
+	}
+
+	return append(masked, body[last:]...), count
+}
+
+// jsonStringScanner tells whether the offsets of a JSON document are inside a string, the offsets it's asked about
+// must not decrease
+type jsonStringScanner struct {
+	offset   int
+	inString bool
+	escaped  bool
+}
+
+func (scanner *jsonStringScanner) isInString(body []byte, offset int) bool {
+	for ; scanner.offset < offset; scanner.offset++ {
+		switch c := body[scanner.offset]; {
+		case scanner.escaped:
+			scanner.escaped = false
+		case scanner.inString && c == '\\':
+			scanner.escaped = true
+		case c == '"':
+			scanner.inString = !scanner.inString
+		}
+	}
+
+	return scanner.inString
+}
+
+func containsPattern(patterns []string, pattern string) bool {
+	for _, enabled := range patterns {
+		if enabled == pattern {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isTextContentType is true for the bodies the patterns are looked for in, a body without a content type is
+// looked into too
+func isTextContentType(contentType string) bool {
+	mimeType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	switch {
+	case mimeType == "", strings.HasPrefix(mimeType, "text/"):
+		return true
+	case mimeType == "application/x-www-form-urlencoded", mimeType == "application/graphql":
+		return true
+	case strings.HasSuffix(mimeType, "json"), strings.HasSuffix(mimeType, "xml"):
+		return true
+	}
+
+	return false
+}
+
+func isJsonContentType(contentType string) bool {
+	mimeType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return strings.HasSuffix(mimeType, "json")
+}
+
+// isLuhnValid checks the digits of a card number with the Luhn checksum, which most other numbers fail
+func isLuhnValid(number []byte) bool {
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		if number[i] < '0' || number[i] > '9' {
+			continue
+		}
+
+		digit := int(number[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+
+	return sum%10 == 0
+}
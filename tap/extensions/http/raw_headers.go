@@ -0,0 +1,175 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// peekRawHeaders returns the exact bytes of the header lines of the next HTTP/1.x message without consuming
+// them, nil is returned when the header section doesn't fit into the reader's buffer
+func peekRawHeaders(b *bufio.Reader) []byte {
+	head := peekMessageHead(b)
+	if head == nil {
+		return nil
+	}
+
+	start, _ := headerSectionBounds(head)
+	return head[start:]
+}
+
+// peekMessageHead returns the start line and the header lines of the next HTTP/1.x message without consuming
+// them, nil is returned when they don't fit into the reader's buffer
+func peekMessageHead(b *bufio.Reader) []byte {
+	if _, err := b.Peek(1); err != nil {
+		return nil
+	}
+
+	for {
+		peeked, _ := b.Peek(b.Buffered())
+		if _, end := headerSectionBounds(peeked); end >= 0 {
+			return append([]byte(nil), peeked[:end]...)
+		}
+
+		// the header section isn't complete yet, wait for more data like the parser would
+		if _, err := b.Peek(len(peeked) + 1); err != nil {
+			return nil
+		}
+	}
+}
+
+// peekStartLine returns the first line of the next message without consuming it, nil is returned when the line
+// doesn't fit into the reader's buffer
+func peekStartLine(b *bufio.Reader) []byte {
+	if _, err := b.Peek(1); err != nil {
+		return nil
+	}
+
+	for {
+		peeked, _ := b.Peek(b.Buffered())
+		if end := bytes.IndexByte(peeked, '\n'); end >= 0 {
+			return append([]byte(nil), peeked[:end+1]...)
+		}
+
+		if _, err := b.Peek(len(peeked) + 1); err != nil {
+			return nil
+		}
+	}
+}
+
+// headerSectionBounds finds the header lines that follow the start line, end is -1 while the empty line that
+// ends them wasn't seen
+func headerSectionBounds(data []byte) (start int, end int) {
+	start = bytes.IndexByte(data, '\n') + 1
+	if start == 0 {
+		return 0, -1
+	}
+
+	for position := start; position < len(data); {
+		lineEnd := bytes.IndexByte(data[position:], '\n')
+		if lineEnd < 0 {
+			break
+		}
+
+		line := data[position : position+lineEnd]
+		position += lineEnd + 1
+		if len(line) == 0 || (len(line) == 1 && line[0] == '\r') {
+			return start, position
+		}
+	}
+
+	return start, -1
+}
+
+// parseRawHeaders parses the header lines, the sensitive ones are masked when redact is set and the ones of the
+// header redaction rules are always masked
+func parseRawHeaders(block []byte, redact bool, rules []api.RedactionRule) *api.RawHeaders {
+	rawHeaders := &api.RawHeaders{Headers: make([]api.RawHeader, 0)}
+	var redactedBlock bytes.Buffer
+
+	lines := bytes.SplitAfter(block, []byte("\n"))
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if len(bytes.TrimRight(line, "\r\n")) == 0 {
+			redactedBlock.Write(line)
+			continue
+		}
+
+		// obsolete line folding continues the previous line
+		for i+1 < len(lines) && len(lines[i+1]) > 0 && (lines[i+1][0] == ' ' || lines[i+1][0] == '\t') {
+			i++
+			line = append(append([]byte(nil), line...), lines[i]...)
+		}
+
+		header := parseRawHeaderLine(line)
+		if redact && isRawHeaderSensitive(header.Name) {
+			header.Value = maskedFieldPlaceholderValue
+			header.Raw = nil
+			redactedBlock.WriteString(header.Name + ": " + maskedFieldPlaceholderValue + "\r\n")
+			rawHeaders.Redacted = true
+		} else if value, matched := redactRawHeaderValue(header, rules); matched {
+			header.Value = value
+			header.Raw = nil
+			redactedBlock.WriteString(header.Name + ": " + value + "\r\n")
+			rawHeaders.Redacted = true
+		} else {
+			redactedBlock.Write(line)
+		}
+
+		rawHeaders.Headers = append(rawHeaders.Headers, header)
+	}
+
+	rawHeaders.Block = redactedBlock.Bytes()
+	return rawHeaders
+}
+
+func parseRawHeaderLine(line []byte) api.RawHeader {
+	var header api.RawHeader
+	if !utf8.Valid(line) {
+		header.Raw = line
+	}
+
+	content := strings.TrimRight(string(line), "\r\n")
+	colon := strings.IndexByte(content, ':')
+	if colon < 0 {
+		header.Name = content
+		return header
+	}
+
+	header.Name = content[:colon]
+	header.Value = strings.Trim(content[colon+1:], " \t")
+	return header
+}
+
+// isRawHeaderSensitive follows filterHeaders, except that cookies are masked instead of dropped so the
+// ordering stays visible
+func isRawHeaderSensitive(name string) bool {
+	lowerName := strings.ToLower(name)
+	if lowerName == userAgent {
+		return false
+	}
+
+	return lowerName == "cookie" || isFieldNameSensitive(name)
+}
+
+// redactRawHeaderValue applies the header redaction rules to the value of a raw header, matched is false when no rule
+// changed it
+func redactRawHeaderValue(header api.RawHeader, rules []api.RedactionRule) (value string, matched bool) {
+	value = header.Value
+	for i := range rules {
+		rule := &rules[i]
+		if rule.In != api.RedactionInHeader || !rule.MatchesName(header.Name) {
+			continue
+		}
+
+		if redactedValue := redactValue(value, rule); redactedValue != value {
+			value = redactedValue
+			matched = true
+		}
+	}
+
+	return value, matched
+}
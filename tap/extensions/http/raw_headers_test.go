@@ -0,0 +1,49 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeekRawHeadersKeepsMessageUnread(t *testing.T) {
+	message := "GET /path HTTP/1.1\r\nHost: example.com\r\nX-Custom: a\r\nx-custom: b\r\n\r\n"
+	b := bufio.NewReader(bytes.NewBufferString(message))
+
+	block := peekRawHeaders(b)
+	assert.Equal(t, "Host: example.com\r\nX-Custom: a\r\nx-custom: b\r\n\r\n", string(block))
+
+	req, err := http.ReadRequest(b)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"a", "b"}, req.Header.Values("X-Custom"))
+}
+
+func TestPeekRawHeadersTooLarge(t *testing.T) {
+	message := "GET / HTTP/1.1\r\nX-Large: " + string(bytes.Repeat([]byte("a"), 64)) + "\r\n\r\n"
+	b := bufio.NewReaderSize(bytes.NewBufferString(message), 16)
+
+	assert.Nil(t, peekRawHeaders(b))
+}
+
+func TestParseRawHeaders(t *testing.T) {
+	block := []byte("content-TYPE: text/plain\r\nX-Folded: a\r\n b\r\nX-Invalid: \xff\r\nAuthorization: secret\r\n\r\n")
+
+	rawHeaders := parseRawHeaders(block, false, nil)
+	assert.False(t, rawHeaders.Redacted)
+	assert.Equal(t, block, rawHeaders.Block)
+	assert.Len(t, rawHeaders.Headers, 4)
+	assert.Equal(t, "content-TYPE", rawHeaders.Headers[0].Name)
+	assert.Equal(t, "text/plain", rawHeaders.Headers[0].Value)
+	assert.Nil(t, rawHeaders.Headers[0].Raw)
+	assert.Equal(t, "a\r\n b", rawHeaders.Headers[1].Value)
+	assert.Equal(t, []byte("X-Invalid: \xff\r\n"), rawHeaders.Headers[2].Raw)
+
+	redacted := parseRawHeaders(block, true, nil)
+	assert.True(t, redacted.Redacted)
+	assert.Equal(t, maskedFieldPlaceholderValue, redacted.Headers[3].Value)
+	assert.NotContains(t, string(redacted.Block), "secret")
+	assert.Contains(t, string(redacted.Block), "content-TYPE: text/plain\r\n")
+}
@@ -0,0 +1,160 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// ApplyRedactionRules masks the values the rules select, it's applied on top of FilterSensitiveData
+func ApplyRedactionRules(item *api.OutputChannelItem, rules []api.RedactionRule) {
+	request := item.Pair.Request.Payload.(api.HTTPPayload).Data.(*http.Request)
+	response := item.Pair.Response.Payload.(api.HTTPPayload).Data.(*http.Response)
+
+	for i := range rules {
+		rule := &rules[i]
+		switch rule.In {
+		case api.RedactionInHeader:
+			redactHeaders(request.Header, rule)
+			redactHeaders(response.Header, rule)
+			redactHeaders(request.Trailer, rule)
+			redactHeaders(response.Trailer, rule)
+			for _, interimResponse := range item.Pair.Response.Payload.(api.HTTPPayload).InterimResponses {
+				redactHeaders(interimResponse.Header, rule)
+			}
+		case api.RedactionInQuery:
+			redactQuery(request.URL, rule)
+		}
+	}
+
+	if body, err := ioutil.ReadAll(request.Body); err == nil {
+		request.Body = ioutil.NopCloser(bytes.NewBuffer(redactBody(body, getContentTypeHeaderValue(request.Header), rules)))
+	}
+	if body, err := ioutil.ReadAll(response.Body); err == nil {
+		response.Body = ioutil.NopCloser(bytes.NewBuffer(redactBody(body, getContentTypeHeaderValue(response.Header), rules)))
+	}
+}
+
+// redactValue masks the parts of value matching the regex of the rule, or the whole value when it has none
+func redactValue(value string, rule *api.RedactionRule) string {
+	if rule.Regex == nil {
+		return maskedFieldPlaceholderValue
+	}
+
+	return rule.Regex.ReplaceAllString(value, maskedFieldPlaceholderValue)
+}
+
+func redactHeaders(headers http.Header, rule *api.RedactionRule) {
+	for name, values := range headers {
+		if !rule.MatchesName(name) {
+			continue
+		}
+
+		for i, value := range values {
+			values[i] = redactValue(value, rule)
+		}
+	}
+}
+
+// redactQuery masks the query params of the rule, the params are kept as they are written like filterUrl does
+func redactQuery(url *url.URL, rule *api.RedactionRule) {
+	if len(url.RawQuery) == 0 {
+		return
+	}
+
+	queryArgs := make([]string, 0)
+	for queryParamName, queryParamValues := range url.Query() {
+		for _, paramValue := range queryParamValues {
+			if rule.MatchesName(queryParamName) {
+				paramValue = redactValue(paramValue, rule)
+			}
+			queryArgs = append(queryArgs, fmt.Sprintf("%s=%s", queryParamName, paramValue))
+		}
+	}
+
+	url.RawQuery = strings.Join(queryArgs, "&")
+}
+
+// redactBody masks the body with the body rules, the rules without a name apply to the text of any body and the JSON
+// path ones to the fields of JSON bodies, a body that isn't valid JSON is left to the other rules
+func redactBody(body []byte, contentType string, rules []api.RedactionRule) []byte {
+	var jsonRules []*api.RedactionRule
+	for i := range rules {
+		rule := &rules[i]
+		if rule.In != api.RedactionInBody {
+			continue
+		}
+
+		if rule.Name == "" {
+			body = rule.Regex.ReplaceAll(body, []byte(maskedFieldPlaceholderValue))
+		} else {
+			jsonRules = append(jsonRules, rule)
+		}
+	}
+
+	if len(jsonRules) == 0 || !isJsonContentType(contentType) {
+		return body
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// the numbers are kept as they're written
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return body
+	}
+
+	redacted := false
+	for _, rule := range jsonRules {
+		segments, err := api.ParseJsonPath(rule.Name)
+		if err != nil {
+			continue
+		}
+
+		var found bool
+		value, found = api.ReplaceJsonPath(value, segments, func(field interface{}) interface{} {
+			return redactJsonValue(field, rule)
+		})
+		redacted = redacted || found
+	}
+
+	if !redacted {
+		return body
+	}
+
+	redactedBody, err := json.Marshal(value)
+	if err != nil {
+		return body
+	}
+
+	return redactedBody
+}
+
+// redactJsonValue masks a field of a JSON body, with a regex only the strings and the numbers are masked, as strings
+func redactJsonValue(value interface{}, rule *api.RedactionRule) interface{} {
+	if rule.Regex == nil {
+		return maskedFieldPlaceholderValue
+	}
+
+	switch typed := value.(type) {
+	case string:
+		return redactValue(typed, rule)
+	case json.Number:
+		if redactedValue := redactValue(typed.String(), rule); redactedValue != typed.String() {
+			return redactedValue
+		}
+	}
+
+	return value
+}
+
+func isJsonContentType(contentType string) bool {
+	mimeType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	return mimeType == "application/json" || strings.HasSuffix(mimeType, "+json")
+}
@@ -0,0 +1,116 @@
+package http
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/up9inc/mizu/tap/api"
+)
+
+func newRedactionItem(requestBody string, responseBody string) *api.OutputChannelItem {
+	request := &http.Request{
+		URL:    &url.URL{Path: "/orders", RawQuery: "token=abc123&page=2"},
+		Header: http.Header{"Content-Type": []string{"application/json"}, "X-Tenant-Id": []string{"tenant-42"}},
+		Body:   ioutil.NopCloser(bytes.NewBufferString(requestBody)),
+	}
+	response := &http.Response{
+		Header: http.Header{"Content-Type": []string{"application/problem+json; charset=utf-8"}},
+		Body:   ioutil.NopCloser(bytes.NewBufferString(responseBody)),
+	}
+
+	return &api.OutputChannelItem{
+		Pair: &api.RequestResponsePair{
+			Request:  api.GenericMessage{Payload: api.HTTPPayload{Data: request}},
+			Response: api.GenericMessage{Payload: api.HTTPPayload{Data: response}},
+		},
+	}
+}
+
+func mustCompile(t *testing.T, expr string) *api.SerializableRegexp {
+	regex, err := api.CompileRegexToSerializableRegexp(expr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return regex
+}
+
+func TestApplyRedactionRules(t *testing.T) {
+	item := newRedactionItem(
+		`{"card":{"number":"4111111111111111","holder":"Jane"},"items":[{"price":10},{"price":12.5}],"contact":{"ssn":"123-45-6789"}}`,
+		`{"detail":"user jane@example.com not found","id":7}`,
+	)
+
+	ApplyRedactionRules(item, []api.RedactionRule{
+		{In: api.RedactionInHeader, Name: "x-tenant-id"},
+		{In: api.RedactionInQuery, Name: "token"},
+		{In: api.RedactionInBody, Name: "$.card.number", Regex: mustCompile(t, `\d{12}`)},
+		{In: api.RedactionInBody, Name: "$.items[*].price"},
+		{In: api.RedactionInBody, Name: "$..ssn"},
+		{In: api.RedactionInBody, Regex: mustCompile(t, `[a-z]+@[a-z.]+`)},
+	})
+
+	request := item.Pair.Request.Payload.(api.HTTPPayload).Data.(*http.Request)
+	response := item.Pair.Response.Payload.(api.HTTPPayload).Data.(*http.Response)
+
+	assert.Equal(t, maskedFieldPlaceholderValue, request.Header.Get("X-Tenant-Id"))
+	assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+	assert.Equal(t, maskedFieldPlaceholderValue, request.URL.Query().Get("token"))
+	assert.Equal(t, "2", request.URL.Query().Get("page"))
+
+	requestBody, _ := ioutil.ReadAll(request.Body)
+	assert.JSONEq(t, `{"card":{"number":"[REDACTED]1111","holder":"Jane"},"items":[{"price":"[REDACTED]"},{"price":"[REDACTED]"}],"contact":{"ssn":"[REDACTED]"}}`, string(requestBody))
+
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.JSONEq(t, `{"detail":"user [REDACTED] not found","id":7}`, string(responseBody))
+}
+
+func TestApplyRedactionRulesKeepsUnmatchedBodies(t *testing.T) {
+	item := newRedactionItem(`{"amount": 1.50}`, `not json`)
+
+	ApplyRedactionRules(item, []api.RedactionRule{{In: api.RedactionInBody, Name: "$.password"}})
+
+	request := item.Pair.Request.Payload.(api.HTTPPayload).Data.(*http.Request)
+	response := item.Pair.Response.Payload.(api.HTTPPayload).Data.(*http.Response)
+
+	requestBody, _ := ioutil.ReadAll(request.Body)
+	assert.Equal(t, `{"amount": 1.50}`, string(requestBody))
+	responseBody, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, `not json`, string(responseBody))
+}
+
+func TestParseRawHeadersRedactionRules(t *testing.T) {
+	block := []byte("X-Tenant-Id: tenant-42\r\nX-Trace: abc-123-def\r\n\r\n")
+
+	rawHeaders := parseRawHeaders(block, false, []api.RedactionRule{
+		{In: api.RedactionInHeader, Name: "X-TENANT-ID"},
+		{In: api.RedactionInHeader, Name: "X-Trace", Regex: mustCompile(t, `\d+`)},
+	})
+
+	assert.True(t, rawHeaders.Redacted)
+	assert.Equal(t, maskedFieldPlaceholderValue, rawHeaders.Headers[0].Value)
+	assert.Equal(t, "abc-[REDACTED]-def", rawHeaders.Headers[1].Value)
+	assert.Equal(t, "X-Tenant-Id: [REDACTED]\r\nX-Trace: abc-[REDACTED]-def\r\n\r\n", string(rawHeaders.Block))
+}
+
+func TestRedactionRuleValidate(t *testing.T) {
+	tests := []struct {
+		rule  api.RedactionRule
+		valid bool
+	}{
+		{rule: api.RedactionRule{In: api.RedactionInHeader, Name: "Authorization"}, valid: true},
+		{rule: api.RedactionRule{In: api.RedactionInBody, Name: "$['card number'][0]"}, valid: true},
+		{rule: api.RedactionRule{In: api.RedactionInBody, Name: "card.number"}, valid: false},
+		{rule: api.RedactionRule{In: api.RedactionInBody, Name: "$.items[x]"}, valid: false},
+		{rule: api.RedactionRule{In: api.RedactionInQuery}, valid: false},
+		{rule: api.RedactionRule{In: "cookie", Name: "session"}, valid: false},
+	}
+
+	for _, test := range tests {
+		err := test.rule.Validate()
+		assert.Equal(t, test.valid, err == nil, "%+v: %v", test.rule, err)
+	}
+}
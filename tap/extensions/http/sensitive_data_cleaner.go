@@ -55,6 +55,11 @@ func FilterSensitiveData(item *api.OutputChannelItem, options *api.TrafficFilter
 
 	filterHeaders(&request.Header)
 	filterHeaders(&response.Header)
+	filterHeaders(&request.Trailer)
+	filterHeaders(&response.Trailer)
+	for _, interimResponse := range item.Pair.Response.Payload.(api.HTTPPayload).InterimResponses {
+		filterHeaders(&interimResponse.Header)
+	}
 	filterUrl(request.URL)
 	filterRequestBody(request, options)
 	filterResponseBody(response, options)
@@ -0,0 +1,395 @@
+package http
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+var webSocketProtocol api.Protocol = api.Protocol{
+	Name:            "http",
+	LongName:        "The WebSocket Protocol",
+	Abbreviation:    "WS",
+	Macro:           "websocket",
+	Version:         "6455",
+	BackgroundColor: "#3c763d",
+	ForegroundColor: "#ffffff",
+	FontSize:        12,
+	ReferenceLink:   "https://datatracker.ietf.org/doc/html/rfc6455",
+	Ports:           []string{"80", "443", "8080"},
+	Priority:        0,
+}
+
+const (
+	webSocketOpcodeContinuation = 0x0
+	webSocketOpcodeText         = 0x1
+	webSocketOpcodeBinary       = 0x2
+	webSocketOpcodeClose        = 0x8
+	webSocketOpcodePing         = 0x9
+	webSocketOpcodePong         = 0xa
+
+	webSocketDirectionClient = "client"
+	webSocketDirectionServer = "server"
+
+	// only the beginning of larger payloads is kept, the rest of the frame is skipped
+	maxWebSocketPayloadSize = 64 * 1024
+)
+
+var webSocketOpcodeNames = map[byte]string{
+	webSocketOpcodeContinuation: "continuation",
+	webSocketOpcodeText:         "text",
+	webSocketOpcodeBinary:       "binary",
+	webSocketOpcodeClose:        "close",
+	webSocketOpcodePing:         "ping",
+	webSocketOpcodePong:         "pong",
+}
+
+// WebSocketFrame is a frame sent on a connection upgraded to WebSocket, WebSocketId links it to the entry of the
+// upgrade request
+type WebSocketFrame struct {
+	WebSocketId   string `json:"websocketId"`
+	Path          string `json:"path"`
+	Direction     string `json:"direction"`
+	Fin           bool   `json:"fin"`
+	Compressed    bool   `json:"compressed"`
+	Opcode        int    `json:"opcode"`
+	OpcodeName    string `json:"opcodeName"`
+	Masked        bool   `json:"masked"`
+	PayloadLength int64  `json:"payloadLength"`
+	Payload       string `json:"payload"`
+	Encoding      string `json:"encoding,omitempty"`
+	Truncated     bool   `json:"truncated,omitempty"`
+	CloseCode     int    `json:"closeCode,omitempty"`
+	CloseReason   string `json:"closeReason,omitempty"`
+}
+
+type WebSocketPayload struct {
+	Frame *WebSocketFrame
+}
+
+func (p WebSocketPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(p.Frame)
+}
+
+func isWebSocketUpgrade(header http.Header) bool {
+	return strings.Contains(strings.ToLower(header.Get("Connection")), "upgrade") && strings.EqualFold(header.Get("Upgrade"), "websocket")
+}
+
+// getWebSocketId identifies a connection upgraded to WebSocket by the ident of its upgrade request, both sides
+// of the stream can build it since the request and the response counters match
+func getWebSocketId(clientIP string, serverIP string, clientPort string, serverPort string, counter uint) string {
+	return fmt.Sprintf("%s_%s_%s_%s_%d", clientIP, serverIP, clientPort, serverPort, counter)
+}
+
+// peekWebSocketClientFrame tells whether the client sent a frame rather than another HTTP request, a request
+// starts with an ASCII token while the frames of the clients must be masked
+func peekWebSocketClientFrame(b *bufio.Reader) bool {
+	header, err := b.Peek(2)
+	if err != nil {
+		return false
+	}
+
+	_, knownOpcode := webSocketOpcodeNames[header[0]&0x0f]
+	return knownOpcode && header[1]&0x80 != 0
+}
+
+func readWebSocketFrame(b *bufio.Reader) (*WebSocketFrame, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(b, header); err != nil {
+		return nil, err
+	}
+
+	opcode := header[0] & 0x0f
+	opcodeName, ok := webSocketOpcodeNames[opcode]
+	if !ok {
+		return nil, fmt.Errorf("unknown WebSocket opcode %#x", opcode)
+	}
+
+	frame := &WebSocketFrame{
+		Fin:        header[0]&0x80 != 0,
+		Compressed: header[0]&0x40 != 0,
+		Opcode:     int(opcode),
+		OpcodeName: opcodeName,
+		Masked:     header[1]&0x80 != 0,
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		extended := make([]byte, 2)
+		if _, err := io.ReadFull(b, extended); err != nil {
+			return nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(extended))
+	case 127:
+		extended := make([]byte, 8)
+		if _, err := io.ReadFull(b, extended); err != nil {
+			return nil, err
+		}
+		length = binary.BigEndian.Uint64(extended)
+		if length&(1<<63) != 0 {
+			return nil, fmt.Errorf("invalid WebSocket payload length %d", length)
+		}
+	}
+	frame.PayloadLength = int64(length)
+
+	var maskingKey []byte
+	if frame.Masked {
+		maskingKey = make([]byte, 4)
+		if _, err := io.ReadFull(b, maskingKey); err != nil {
+			return nil, err
+		}
+	}
+
+	payloadSize := length
+	if payloadSize > maxWebSocketPayloadSize {
+		payloadSize = maxWebSocketPayloadSize
+		frame.Truncated = true
+	}
+	payload := make([]byte, payloadSize)
+	if _, err := io.ReadFull(b, payload); err != nil {
+		return nil, err
+	}
+	if frame.Truncated {
+		if _, err := b.Discard(int(length - payloadSize)); err != nil {
+			return nil, err
+		}
+	}
+
+	if maskingKey != nil {
+		for i := range payload {
+			payload[i] ^= maskingKey[i%4]
+		}
+	}
+
+	if opcode == webSocketOpcodeClose && len(payload) >= 2 {
+		frame.CloseCode = int(binary.BigEndian.Uint16(payload))
+		frame.CloseReason = string(payload[2:])
+		payload = payload[2:]
+	}
+
+	// compressed payloads can't be inflated without the preceding messages of the connection
+	if !frame.Compressed && opcode != webSocketOpcodeBinary && utf8.Valid(payload) {
+		frame.Payload = string(payload)
+	} else {
+		frame.Payload = base64.StdEncoding.EncodeToString(payload)
+		frame.Encoding = "base64"
+	}
+
+	return frame, nil
+}
+
+func handleWebSocketFrame(b *bufio.Reader, isClient bool, webSocketId string, tcpID *api.TcpID, superTimer *api.SuperTimer, emitter api.Emitter, options *api.TrafficFilteringOptions, reqResMatcher *requestResponseMatcher) error {
+	frame, err := readWebSocketFrame(b)
+	if err != nil {
+		return err
+	}
+
+	if reqResMatcher.isMizuWebSocket(webSocketId) {
+		return nil
+	}
+
+	frame.WebSocketId = webSocketId
+	frame.Path = reqResMatcher.getWebSocketPath(webSocketId)
+
+	connectionInfo := &api.ConnectionInfo{
+		ClientIP:   tcpID.SrcIP,
+		ClientPort: tcpID.SrcPort,
+		ServerIP:   tcpID.DstIP,
+		ServerPort: tcpID.DstPort,
+		IsOutgoing: true,
+	}
+	frame.Direction = webSocketDirectionClient
+	if !isClient {
+		connectionInfo = &api.ConnectionInfo{
+			ClientIP:   tcpID.DstIP,
+			ClientPort: tcpID.DstPort,
+			ServerIP:   tcpID.SrcIP,
+			ServerPort: tcpID.SrcPort,
+			IsOutgoing: false,
+		}
+		frame.Direction = webSocketDirectionServer
+	}
+
+	if !options.DisableRedaction && frame.Encoding == "" {
+		frame.Payload = string(filterWebSocketPayload([]byte(frame.Payload), options))
+	}
+
+	emitter.Emit(&api.OutputChannelItem{
+		Protocol:       webSocketProtocol,
+		Timestamp:      superTimer.CaptureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: connectionInfo,
+		Pair: &api.RequestResponsePair{
+			Request: api.GenericMessage{
+				IsRequest:   true,
+				CaptureTime: superTimer.CaptureTime,
+				Payload:     WebSocketPayload{Frame: frame},
+			},
+			Response: api.GenericMessage{},
+		},
+	})
+
+	return nil
+}
+
+// filterWebSocketPayload redacts the text frames the same way as the bodies, most of them carry JSON messages
+func filterWebSocketPayload(payload []byte, options *api.TrafficFilteringOptions) []byte {
+	if json.Valid(payload) {
+		if filtered, err := filterJsonBody(payload); err == nil {
+			return filtered
+		}
+	}
+
+	if options.PlainTextMaskingRegexes != nil {
+		return filterPlainText(payload, options)
+	}
+
+	return payload
+}
+
+func analyzeWebSocketFrame(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	request := item.Pair.Request.Payload.(map[string]interface{})
+
+	return &api.Entry{
+		Protocol: item.Protocol,
+		Source: &api.TCP{
+			Name: resolvedSource,
+			IP:   item.ConnectionInfo.ClientIP,
+			Port: item.ConnectionInfo.ClientPort,
+		},
+		Destination: &api.TCP{
+			Name: resolvedDestination,
+			IP:   item.ConnectionInfo.ServerIP,
+			Port: item.ConnectionInfo.ServerPort,
+		},
+		Namespace:   namespace,
+		Outgoing:    item.ConnectionInfo.IsOutgoing,
+		Request:     request,
+		Response:    make(map[string]interface{}),
+		Timestamp:   item.Timestamp,
+		StartTime:   item.Pair.Request.CaptureTime,
+		ElapsedTime: 0,
+	}
+}
+
+func summarizeWebSocketFrame(entry *api.Entry) *api.BaseEntry {
+	summary, _ := entry.Request["path"].(string)
+	summaryQuery := fmt.Sprintf(`request.path == "%s"`, summary)
+	method, _ := entry.Request["opcodeName"].(string)
+	methodQuery := fmt.Sprintf(`request.opcodeName == "%s"`, method)
+	closeCode, _ := entry.Request["closeCode"].(float64)
+	status := int(closeCode)
+	statusQuery := fmt.Sprintf(`request.closeCode == %d`, status)
+
+	return &api.BaseEntry{
+		Id:             entry.Id,
+		EntryId:        entry.EntryId,
+		Protocol:       entry.Protocol,
+		Summary:        summary,
+		SummaryQuery:   summaryQuery,
+		Status:         status,
+		StatusQuery:    statusQuery,
+		Method:         method,
+		MethodQuery:    methodQuery,
+		Timestamp:      entry.Timestamp,
+		Source:         entry.Source,
+		Destination:    entry.Destination,
+		IsOutgoing:     entry.Outgoing,
+		Latency:        entry.ElapsedTime,
+		Rules:          entry.Rules,
+		ContractStatus: entry.ContractStatus,
+	}
+}
+
+func representWebSocketFrame(request map[string]interface{}) (repRequest []interface{}, bodySize int64) {
+	bodySize = int64(request["payloadLength"].(float64))
+
+	details, _ := json.Marshal([]api.TableData{
+		{
+			Name:     "WebSocket Id",
+			Value:    request["websocketId"].(string),
+			Selector: `request.websocketId`,
+		},
+		{
+			Name:     "Path",
+			Value:    request["path"].(string),
+			Selector: `request.path`,
+		},
+		{
+			Name:     "Sent By",
+			Value:    request["direction"].(string),
+			Selector: `request.direction`,
+		},
+		{
+			Name:     "Opcode",
+			Value:    request["opcodeName"].(string),
+			Selector: `request.opcodeName`,
+		},
+		{
+			Name:     "Final Fragment",
+			Value:    request["fin"].(bool),
+			Selector: `request.fin`,
+		},
+		{
+			Name:     "Compressed",
+			Value:    request["compressed"].(bool),
+			Selector: `request.compressed`,
+		},
+		{
+			Name:     "Payload Length (bytes)",
+			Value:    bodySize,
+			Selector: `request.payloadLength`,
+		},
+	})
+	repRequest = append(repRequest, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(details),
+	})
+
+	if closeCode, ok := request["closeCode"].(float64); ok {
+		closeReason, _ := request["closeReason"].(string)
+		closeDetails, _ := json.Marshal([]api.TableData{
+			{
+				Name:     "Close Code",
+				Value:    int64(closeCode),
+				Selector: `request.closeCode`,
+			},
+			{
+				Name:     "Close Reason",
+				Value:    closeReason,
+				Selector: `request.closeReason`,
+			},
+		})
+		repRequest = append(repRequest, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Close",
+			Data:  string(closeDetails),
+		})
+	}
+
+	encoding, _ := request["encoding"].(string)
+	mimeType := "text/plain"
+	if encoding != "" {
+		mimeType = "application/octet-stream"
+	}
+	repRequest = append(repRequest, api.SectionData{
+		Type:     api.BODY,
+		Title:    "Payload",
+		Encoding: encoding,
+		MimeType: mimeType,
+		Data:     request["payload"].(string),
+		Selector: `request.payload`,
+	})
+
+	return
+}
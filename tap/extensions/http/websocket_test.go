@@ -0,0 +1,224 @@
+package http
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type collectingEmitter struct {
+	sync.Mutex
+	items []*api.OutputChannelItem
+}
+
+func (e *collectingEmitter) Emit(item *api.OutputChannelItem) {
+	e.Lock()
+	defer e.Unlock()
+	e.items = append(e.items, item)
+}
+
+func webSocketFrame(opcode byte, payload []byte, mask bool) []byte {
+	frame := []byte{0x80 | opcode}
+	lengthByte := byte(0)
+	if mask {
+		lengthByte = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, lengthByte|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, lengthByte|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(payload)))
+	default:
+		frame = append(frame, lengthByte|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(payload)))
+	}
+	if !mask {
+		return append(frame, payload...)
+	}
+
+	maskingKey := []byte{0x37, 0xfa, 0x21, 0x3d}
+	frame = append(frame, maskingKey...)
+	for i, c := range payload {
+		frame = append(frame, c^maskingKey[i%4])
+	}
+	return frame
+}
+
+// roundTrip gives the payload the shape the dissector gets in Analyze, after the item is sent to the API server
+func roundTrip(t *testing.T, item *api.OutputChannelItem) *api.OutputChannelItem {
+	data, err := json.Marshal(item)
+	assert.Nil(t, err)
+	var roundTripped *api.OutputChannelItem
+	assert.Nil(t, json.Unmarshal(data, &roundTripped))
+	return roundTripped
+}
+
+func TestReadWebSocketFrame(t *testing.T) {
+	frame, err := readWebSocketFrame(bufio.NewReader(bytes.NewReader(webSocketFrame(webSocketOpcodeText, []byte(`{"type":"hello"}`), true))))
+	assert.Nil(t, err)
+	assert.Equal(t, "text", frame.OpcodeName)
+	assert.True(t, frame.Fin)
+	assert.True(t, frame.Masked)
+	assert.Equal(t, `{"type":"hello"}`, frame.Payload)
+	assert.Equal(t, "", frame.Encoding)
+
+	frame, err = readWebSocketFrame(bufio.NewReader(bytes.NewReader(webSocketFrame(webSocketOpcodeBinary, []byte{0xff, 0x00}, false))))
+	assert.Nil(t, err)
+	assert.Equal(t, "binary", frame.OpcodeName)
+	assert.Equal(t, "/wA=", frame.Payload)
+	assert.Equal(t, "base64", frame.Encoding)
+
+	frame, err = readWebSocketFrame(bufio.NewReader(bytes.NewReader(webSocketFrame(webSocketOpcodeClose, []byte{0x03, 0xe8, 'b', 'y', 'e'}, false))))
+	assert.Nil(t, err)
+	assert.Equal(t, 1000, frame.CloseCode)
+	assert.Equal(t, "bye", frame.CloseReason)
+
+	_, err = readWebSocketFrame(bufio.NewReader(bytes.NewReader([]byte{0x83, 0x00})))
+	assert.NotNil(t, err)
+}
+
+func TestReadWebSocketFrameTruncated(t *testing.T) {
+	payload := bytes.Repeat([]byte("a"), maxWebSocketPayloadSize+10)
+	data := append(webSocketFrame(webSocketOpcodeText, payload, true), webSocketFrame(webSocketOpcodePing, nil, true)...)
+	b := bufio.NewReader(bytes.NewReader(data))
+
+	frame, err := readWebSocketFrame(b)
+	assert.Nil(t, err)
+	assert.True(t, frame.Truncated)
+	assert.Equal(t, int64(len(payload)), frame.PayloadLength)
+	assert.Len(t, frame.Payload, maxWebSocketPayloadSize)
+
+	// the rest of the large frame is skipped
+	frame, err = readWebSocketFrame(b)
+	assert.Nil(t, err)
+	assert.Equal(t, "ping", frame.OpcodeName)
+}
+
+func TestDissectWebSocket(t *testing.T) {
+	client := strings.Join([]string{
+		"GET /chat HTTP/1.1",
+		"Host: chat.sock-shop",
+		"Connection: Upgrade",
+		"Upgrade: websocket",
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==",
+		"Sec-WebSocket-Version: 13",
+		"", "",
+	}, "\r\n")
+	clientData := append([]byte(client), webSocketFrame(webSocketOpcodeText, []byte(`{"message":"hi","password":"secret"}`), true)...)
+	clientData = append(clientData, webSocketFrame(webSocketOpcodeClose, []byte{0x03, 0xe8}, true)...)
+
+	server := strings.Join([]string{
+		"HTTP/1.1 101 Switching Protocols",
+		"Connection: Upgrade",
+		"Upgrade: websocket",
+		"Sec-WebSocket-Accept: s3pPLMBiTxaQ9kYGzzhZRbK+xOo=",
+		"", "",
+	}, "\r\n")
+	serverData := append([]byte(server), webSocketFrame(webSocketOpcodeText, []byte("welcome"), false)...)
+
+	dissector := NewDissector()
+	reqResMatcher := dissector.NewResponseRequestMatcher()
+	counterPair := &api.CounterPair{}
+	emitter := &collectingEmitter{}
+	options := &api.TrafficFilteringOptions{}
+
+	clientID := &api.TcpID{SrcIP: "1.1.1.1", DstIP: "2.2.2.2", SrcPort: "40000", DstPort: "80"}
+	err := dissector.Dissect(bufio.NewReader(bytes.NewReader(clientData)), true, clientID, counterPair, &api.SuperTimer{}, &api.SuperIdentifier{}, emitter, options, reqResMatcher)
+	assert.True(t, err == nil || err == io.EOF)
+
+	serverID := &api.TcpID{SrcIP: "2.2.2.2", DstIP: "1.1.1.1", SrcPort: "80", DstPort: "40000"}
+	err = dissector.Dissect(bufio.NewReader(bytes.NewReader(serverData)), false, serverID, counterPair, &api.SuperTimer{}, &api.SuperIdentifier{}, emitter, options, reqResMatcher)
+	assert.True(t, err == nil || err == io.EOF)
+
+	assert.Len(t, emitter.items, 4)
+	webSocketId := "1.1.1.1_2.2.2.2_40000_80_1"
+
+	// the frames of the client
+	text := roundTrip(t, emitter.items[0])
+	assert.Equal(t, "websocket", text.Protocol.Macro)
+	entry := dissector.Analyze(text, "", "", "")
+	assert.Equal(t, webSocketId, entry.Request["websocketId"])
+	assert.Equal(t, "/chat", entry.Request["path"])
+	assert.Equal(t, webSocketDirectionClient, entry.Request["direction"])
+	assert.Equal(t, "2.2.2.2", entry.Destination.IP)
+	assert.Contains(t, entry.Request["payload"], `"hi"`)
+	assert.NotContains(t, entry.Request["payload"], "secret")
+
+	summary := dissector.Summarize(entry)
+	assert.Equal(t, "/chat", summary.Summary)
+	assert.Equal(t, "text", summary.Method)
+
+	closeEntry := dissector.Analyze(roundTrip(t, emitter.items[1]), "", "", "")
+	assert.Equal(t, 1000, dissector.Summarize(closeEntry).Status)
+
+	// the upgrade pair links to the frames
+	upgrade := roundTrip(t, emitter.items[2])
+	assert.Equal(t, "1.1", upgrade.Protocol.Version)
+	entry = dissector.Analyze(upgrade, "", "", "")
+	assert.Equal(t, webSocketId, entry.Request["websocketId"])
+
+	// the frame of the server
+	entry = dissector.Analyze(roundTrip(t, emitter.items[3]), "", "", "")
+	assert.Equal(t, webSocketId, entry.Request["websocketId"])
+	assert.Equal(t, webSocketDirectionServer, entry.Request["direction"])
+	assert.Equal(t, "1.1.1.1", entry.Source.IP)
+	assert.Equal(t, "welcome", entry.Request["payload"])
+
+	representation, bodySize, err := dissector.Represent(entry.Request, entry.Response)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(7), bodySize)
+	assert.Contains(t, string(representation), "welcome")
+}
+
+func TestDissectWebSocketRefused(t *testing.T) {
+	client := "GET /chat HTTP/1.1\r\nHost: chat\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n" +
+		"GET /index.html HTTP/1.1\r\nHost: chat\r\n\r\n"
+
+	dissector := NewDissector()
+	reqResMatcher := dissector.NewResponseRequestMatcher()
+	emitter := &collectingEmitter{}
+	clientID := &api.TcpID{SrcIP: "1.1.1.1", DstIP: "2.2.2.2", SrcPort: "40000", DstPort: "80"}
+	_ = dissector.Dissect(bufio.NewReader(strings.NewReader(client)), true, clientID, &api.CounterPair{}, &api.SuperTimer{}, &api.SuperIdentifier{}, emitter, &api.TrafficFilteringOptions{}, reqResMatcher)
+
+	// both requests are read as HTTP and wait for their responses
+	assert.Len(t, emitter.items, 0)
+	openRequests := 0
+	reqResMatcher.GetMap().Range(func(key, value interface{}) bool {
+		openRequests++
+		return true
+	})
+	assert.Equal(t, 2, openRequests)
+}
+
+func TestDissectMizuWebSocket(t *testing.T) {
+	client := "GET /wsTapper HTTP/1.1\r\nHost: mizu-api-server\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n" +
+		"X-Mizu-Traffic: tapper\r\n\r\n"
+	clientData := append([]byte(client), webSocketFrame(webSocketOpcodeText, []byte(`{"messageType":"entry"}`), true)...)
+	serverData := append([]byte("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"), webSocketFrame(webSocketOpcodeText, []byte("ack"), false)...)
+
+	dissector := NewDissector()
+	reqResMatcher := dissector.NewResponseRequestMatcher()
+	counterPair := &api.CounterPair{}
+	emitter := &collectingEmitter{}
+	options := &api.TrafficFilteringOptions{}
+
+	clientID := &api.TcpID{SrcIP: "1.1.1.1", DstIP: "2.2.2.2", SrcPort: "40000", DstPort: "8899"}
+	err := dissector.Dissect(bufio.NewReader(bytes.NewReader(clientData)), true, clientID, counterPair, &api.SuperTimer{}, &api.SuperIdentifier{}, emitter, options, reqResMatcher)
+	assert.True(t, err == nil || err == io.EOF)
+
+	serverID := &api.TcpID{SrcIP: "2.2.2.2", DstIP: "1.1.1.1", SrcPort: "8899", DstPort: "40000"}
+	err = dissector.Dissect(bufio.NewReader(bytes.NewReader(serverData)), false, serverID, counterPair, &api.SuperTimer{}, &api.SuperIdentifier{}, emitter, options, reqResMatcher)
+	assert.True(t, err == nil || err == io.EOF)
+
+	// neither the upgrade pair nor the frames of the connection of the tapper are emitted
+	assert.Len(t, emitter.items, 0)
+}
@@ -0,0 +1,298 @@
+package mongodb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"time"
+)
+
+const maxDocumentDepth = 100
+
+var errMalformedDocument = errors.New("malformed BSON document")
+
+type bsonElement struct {
+	Key   string
+	Value interface{}
+}
+
+// bsonDocument keeps the order of the fields, the first field of a command is its name
+type bsonDocument []bsonElement
+
+func (d bsonDocument) MarshalJSON() ([]byte, error) {
+	var buffer bytes.Buffer
+	buffer.WriteByte('{')
+	for i, element := range d {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		key, err := json.Marshal(element.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(element.Value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+func (d bsonDocument) lookup(key string) (interface{}, bool) {
+	for _, element := range d {
+		if element.Key == key {
+			return element.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (d bsonDocument) firstKey() string {
+	if len(d) == 0 {
+		return ""
+	}
+	return d[0].Key
+}
+
+// decodeDocument decodes the document at the beginning of data, the values that have no JSON counterpart are
+// represented as in MongoDB Extended JSON (e.g. {"$oid": "..."})
+func decodeDocument(data []byte) (bsonDocument, int, error) {
+	return decodeDocumentAtDepth(data, 0)
+}
+
+func decodeDocumentAtDepth(data []byte, depth int) (bsonDocument, int, error) {
+	if depth > maxDocumentDepth {
+		return nil, 0, errMalformedDocument
+	}
+	if len(data) < 5 {
+		return nil, 0, errMalformedDocument
+	}
+	length := int(int32(binary.LittleEndian.Uint32(data)))
+	if length < 5 || length > len(data) || data[length-1] != 0 {
+		return nil, 0, errMalformedDocument
+	}
+
+	document := make(bsonDocument, 0)
+	elements := data[4 : length-1]
+	for offset := 0; offset < len(elements); {
+		elementType := elements[offset]
+		offset++
+
+		key, n, err := readCString(elements[offset:])
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += n
+
+		value, n, err := decodeValue(elementType, elements[offset:], depth)
+		if err != nil {
+			return nil, 0, err
+		}
+		offset += n
+
+		document = append(document, bsonElement{Key: key, Value: value})
+	}
+
+	return document, length, nil
+}
+
+func decodeValue(elementType byte, data []byte, depth int) (interface{}, int, error) {
+	switch elementType {
+	case 0x01:
+		if len(data) < 8 {
+			return nil, 0, errMalformedDocument
+		}
+		value := math.Float64frombits(binary.LittleEndian.Uint64(data))
+		if math.IsNaN(value) || math.IsInf(value, 0) {
+			return map[string]string{"$numberDouble": fmt.Sprintf("%v", value)}, 8, nil
+		}
+		return value, 8, nil
+	case 0x02, 0x0D, 0x0E:
+		value, n, err := readString(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		switch elementType {
+		case 0x0D:
+			return map[string]string{"$code": value}, n, nil
+		case 0x0E:
+			return map[string]string{"$symbol": value}, n, nil
+		}
+		return value, n, nil
+	case 0x03:
+		return decodeDocumentAtDepth(data, depth+1)
+	case 0x04:
+		document, n, err := decodeDocumentAtDepth(data, depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		array := make([]interface{}, 0, len(document))
+		for _, element := range document {
+			array = append(array, element.Value)
+		}
+		return array, n, nil
+	case 0x05:
+		if len(data) < 5 {
+			return nil, 0, errMalformedDocument
+		}
+		length := int(int32(binary.LittleEndian.Uint32(data)))
+		if length < 0 || 5+length > len(data) {
+			return nil, 0, errMalformedDocument
+		}
+		return map[string]interface{}{
+			"$binary": map[string]string{
+				"base64":  base64.StdEncoding.EncodeToString(data[5 : 5+length]),
+				"subType": fmt.Sprintf("%02x", data[4]),
+			},
+		}, 5 + length, nil
+	case 0x06:
+		return map[string]bool{"$undefined": true}, 0, nil
+	case 0x07:
+		if len(data) < 12 {
+			return nil, 0, errMalformedDocument
+		}
+		return map[string]string{"$oid": hex.EncodeToString(data[:12])}, 12, nil
+	case 0x08:
+		if len(data) < 1 {
+			return nil, 0, errMalformedDocument
+		}
+		return data[0] != 0, 1, nil
+	case 0x09:
+		if len(data) < 8 {
+			return nil, 0, errMalformedDocument
+		}
+		milliseconds := int64(binary.LittleEndian.Uint64(data))
+		return map[string]string{"$date": time.UnixMilli(milliseconds).UTC().Format(time.RFC3339Nano)}, 8, nil
+	case 0x0A:
+		return nil, 0, nil
+	case 0x0B:
+		pattern, n, err := readCString(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		options, m, err := readCString(data[n:])
+		if err != nil {
+			return nil, 0, err
+		}
+		return map[string]interface{}{
+			"$regularExpression": map[string]string{"pattern": pattern, "options": options},
+		}, n + m, nil
+	case 0x0C:
+		namespace, n, err := readString(data)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(data) < n+12 {
+			return nil, 0, errMalformedDocument
+		}
+		return map[string]interface{}{
+			"$dbPointer": map[string]interface{}{"$ref": namespace, "$id": map[string]string{"$oid": hex.EncodeToString(data[n : n+12])}},
+		}, n + 12, nil
+	case 0x0F:
+		if len(data) < 4 {
+			return nil, 0, errMalformedDocument
+		}
+		length := int(int32(binary.LittleEndian.Uint32(data)))
+		if length < 4 || length > len(data) {
+			return nil, 0, errMalformedDocument
+		}
+		code, n, err := readString(data[4:length])
+		if err != nil {
+			return nil, 0, err
+		}
+		scope, _, err := decodeDocumentAtDepth(data[4+n:length], depth+1)
+		if err != nil {
+			return nil, 0, err
+		}
+		return map[string]interface{}{"$code": code, "$scope": scope}, length, nil
+	case 0x10:
+		if len(data) < 4 {
+			return nil, 0, errMalformedDocument
+		}
+		return int32(binary.LittleEndian.Uint32(data)), 4, nil
+	case 0x11:
+		if len(data) < 8 {
+			return nil, 0, errMalformedDocument
+		}
+		return map[string]interface{}{
+			"$timestamp": map[string]uint32{"t": binary.LittleEndian.Uint32(data[4:8]), "i": binary.LittleEndian.Uint32(data[0:4])},
+		}, 8, nil
+	case 0x12:
+		if len(data) < 8 {
+			return nil, 0, errMalformedDocument
+		}
+		return int64(binary.LittleEndian.Uint64(data)), 8, nil
+	case 0x13:
+		if len(data) < 16 {
+			return nil, 0, errMalformedDocument
+		}
+		return map[string]string{"$numberDecimal": decimal128String(binary.LittleEndian.Uint64(data[8:16]), binary.LittleEndian.Uint64(data[0:8]))}, 16, nil
+	case 0x7F:
+		return map[string]int{"$maxKey": 1}, 0, nil
+	case 0xFF:
+		return map[string]int{"$minKey": 1}, 0, nil
+	default:
+		return nil, 0, fmt.Errorf("unknown BSON type 0x%02x", elementType)
+	}
+}
+
+// decimal128String renders an IEEE 754-2008 128-bit decimal as its coefficient and exponent (e.g. 12345E-2)
+func decimal128String(high uint64, low uint64) string {
+	sign := ""
+	if high>>63 == 1 {
+		sign = "-"
+	}
+
+	var exponent int
+	coefficient := new(big.Int)
+	if (high>>61)&3 == 3 {
+		switch (high >> 58) & 0x1F {
+		case 0x1F:
+			return "NaN"
+		case 0x1E:
+			return sign + "Infinity"
+		}
+		// the coefficients of this form exceed the maximum of 10^34-1, so they're treated as zero
+		exponent = int((high>>47)&0x3FFF) - 6176
+	} else {
+		exponent = int((high>>49)&0x3FFF) - 6176
+		coefficient.SetUint64(high & (1<<49 - 1))
+		coefficient.Lsh(coefficient, 64)
+		coefficient.Or(coefficient, new(big.Int).SetUint64(low))
+	}
+
+	if exponent == 0 {
+		return sign + coefficient.String()
+	}
+	return fmt.Sprintf("%s%sE%d", sign, coefficient.String(), exponent)
+}
+
+func readCString(data []byte) (string, int, error) {
+	end := bytes.IndexByte(data, 0)
+	if end < 0 {
+		return "", 0, errMalformedDocument
+	}
+	return string(data[:end]), end + 1, nil
+}
+
+// readString reads a length prefixed string, the length includes the terminating null
+func readString(data []byte) (string, int, error) {
+	if len(data) < 4 {
+		return "", 0, errMalformedDocument
+	}
+	length := int(int32(binary.LittleEndian.Uint32(data)))
+	if length < 1 || 4+length > len(data) || data[3+length] != 0 {
+		return "", 0, errMalformedDocument
+	}
+	return string(data[4 : 3+length]), 4 + length, nil
+}
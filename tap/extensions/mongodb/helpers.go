@@ -0,0 +1,154 @@
+package mongodb
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type MongoPayload struct {
+	Data interface{}
+}
+
+type MongoPayloader interface {
+	MarshalJSON() ([]byte, error)
+}
+
+func (h MongoPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Data)
+}
+
+type MongoWrapper struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Details interface{} `json:"details"`
+}
+
+func representRequest(request map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Op Code",
+			Value:    request["opCode"].(string),
+			Selector: `request.opCode`,
+		},
+		{
+			Name:     "Command",
+			Value:    request["command"].(string),
+			Selector: `request.command`,
+		},
+		{
+			Name:     "Database",
+			Value:    request["database"].(string),
+			Selector: `request.database`,
+		},
+		{
+			Name:     "Collection",
+			Value:    request["collection"].(string),
+			Selector: `request.collection`,
+		},
+		{
+			Name:     "Request Id",
+			Value:    fmt.Sprintf("%v", request["requestId"]),
+			Selector: `request.requestId`,
+		},
+	}
+	if compressor, ok := request["compressor"].(string); ok {
+		details = append(details, api.TableData{
+			Name:     "Compressor",
+			Value:    compressor,
+			Selector: `request.compressor`,
+		})
+	}
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if request["document"] != nil {
+		representation = append(representation, representDocument(request["document"], `request.document`))
+	}
+
+	return
+}
+
+func representResponse(response map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Op Code",
+			Value:    response["opCode"].(string),
+			Selector: `response.opCode`,
+		},
+		{
+			Name:     "Status",
+			Value:    response["status"].(string),
+			Selector: `response.status`,
+		},
+		{
+			Name:     "Returned",
+			Value:    fmt.Sprintf("%v", response["returned"]),
+			Selector: `response.returned`,
+		},
+	}
+	if compressor, ok := response["compressor"].(string); ok {
+		details = append(details, api.TableData{
+			Name:     "Compressor",
+			Value:    compressor,
+			Selector: `response.compressor`,
+		})
+	}
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if mongoError, ok := response["error"].(map[string]interface{}); ok {
+		code := ""
+		if mongoError["code"] != nil {
+			code = fmt.Sprintf("%v", mongoError["code"])
+		}
+		errorJson, _ := json.Marshal([]api.TableData{
+			{
+				Name:     "Code",
+				Value:    code,
+				Selector: `response.error.code`,
+			},
+			{
+				Name:     "Code Name",
+				Value:    mongoError["codeName"],
+				Selector: `response.error.codeName`,
+			},
+			{
+				Name:     "Message",
+				Value:    mongoError["message"],
+				Selector: `response.error.message`,
+			},
+		})
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Error",
+			Data:  string(errorJson),
+		})
+	}
+
+	if response["document"] != nil {
+		representation = append(representation, representDocument(response["document"], `response.document`))
+	}
+
+	return
+}
+
+func representDocument(document interface{}, selector string) api.SectionData {
+	documentJson, _ := json.MarshalIndent(document, "", "  ")
+	return api.SectionData{
+		Type:     api.BODY,
+		Title:    "Document",
+		MimeType: "application/json",
+		Data:     string(documentJson),
+		Selector: selector,
+	}
+}
@@ -0,0 +1,180 @@
+package mongodb
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+var protocol api.Protocol = api.Protocol{
+	Name:            "mongodb",
+	LongName:        "MongoDB Wire Protocol",
+	Abbreviation:    "MONGO",
+	Macro:           "mongodb",
+	Version:         "3.6",
+	BackgroundColor: "#13aa52",
+	ForegroundColor: "#ffffff",
+	FontSize:        11,
+	ReferenceLink:   "https://www.mongodb.com/docs/manual/reference/mongodb-wire-protocol/",
+	Ports:           []string{"27017"},
+	Priority:        5,
+}
+
+type dissecting string
+
+func (d dissecting) Register(extension *api.Extension) {
+	extension.Protocol = &protocol
+}
+
+func (d dissecting) Ping() {
+	log.Printf("pong %s", protocol.Name)
+}
+
+func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, superIdentifier *api.SuperIdentifier, emitter api.Emitter, options *api.TrafficFilteringOptions, _reqResMatcher api.RequestResponseMatcher) error {
+	reqResMatcher := _reqResMatcher.(*requestResponseMatcher)
+	for {
+		if superIdentifier.Protocol != nil && superIdentifier.Protocol != &protocol {
+			return errors.New("Identified by another protocol")
+		}
+
+		if isClient {
+			msg, err := readMessage(b, opMsg, opQuery, opGetMore, opKillCursors, opCompressed)
+			if err != nil {
+				return err
+			}
+			request, expectsResponse, err := parseRequest(msg)
+			if err != nil {
+				return err
+			}
+			superIdentifier.Protocol = &protocol
+
+			if !expectsResponse {
+				continue
+			}
+			if err := handleClientStream(tcpID, superTimer, emitter, request, reqResMatcher); err != nil {
+				return err
+			}
+		} else {
+			msg, err := readMessage(b, opMsg, opReply, opCompressed)
+			if err != nil {
+				return err
+			}
+			response, err := parseResponse(msg)
+			if err != nil {
+				return err
+			}
+			superIdentifier.Protocol = &protocol
+
+			if err := handleServerStream(tcpID, superTimer, emitter, response, reqResMatcher); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	request := item.Pair.Request.Payload.(map[string]interface{})
+	response := item.Pair.Response.Payload.(map[string]interface{})
+	reqDetails := request["details"].(map[string]interface{})
+	resDetails := response["details"].(map[string]interface{})
+
+	elapsedTime := item.Pair.Response.CaptureTime.Sub(item.Pair.Request.CaptureTime).Round(time.Millisecond).Milliseconds()
+	if elapsedTime < 0 {
+		elapsedTime = 0
+	}
+	return &api.Entry{
+		Protocol: protocol,
+		Source: &api.TCP{
+			Name: resolvedSource,
+			IP:   item.ConnectionInfo.ClientIP,
+			Port: item.ConnectionInfo.ClientPort,
+		},
+		Destination: &api.TCP{
+			Name: resolvedDestination,
+			IP:   item.ConnectionInfo.ServerIP,
+			Port: item.ConnectionInfo.ServerPort,
+		},
+		Namespace:   namespace,
+		Outgoing:    item.ConnectionInfo.IsOutgoing,
+		Request:     reqDetails,
+		Response:    resDetails,
+		Timestamp:   item.Timestamp,
+		StartTime:   item.Pair.Request.CaptureTime,
+		ElapsedTime: elapsedTime,
+	}
+
+}
+
+func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
+	status := 0
+	statusQuery := ""
+
+	method := ""
+	methodQuery := ""
+	if entry.Request["command"] != nil {
+		method = entry.Request["command"].(string)
+		methodQuery = fmt.Sprintf(`request.command == "%s"`, method)
+	}
+
+	summary := ""
+	summaryQuery := ""
+	if entry.Request["collection"] != nil && entry.Request["collection"].(string) != "" {
+		summary = entry.Request["collection"].(string)
+		summaryQuery = fmt.Sprintf(`request.collection == %s`, strconv.Quote(summary))
+	} else if entry.Request["database"] != nil {
+		summary = entry.Request["database"].(string)
+		summaryQuery = fmt.Sprintf(`request.database == %s`, strconv.Quote(summary))
+	}
+
+	return &api.BaseEntry{
+		Id:             entry.Id,
+		EntryId:        entry.EntryId,
+		Protocol:       entry.Protocol,
+		Summary:        summary,
+		SummaryQuery:   summaryQuery,
+		Status:         status,
+		StatusQuery:    statusQuery,
+		Method:         method,
+		MethodQuery:    methodQuery,
+		Timestamp:      entry.Timestamp,
+		Source:         entry.Source,
+		Destination:    entry.Destination,
+		IsOutgoing:     entry.Outgoing,
+		Latency:        entry.ElapsedTime,
+		Rules:          entry.Rules,
+		ContractStatus: entry.ContractStatus,
+	}
+}
+
+func (d dissecting) Represent(request map[string]interface{}, response map[string]interface{}) (object []byte, bodySize int64, err error) {
+	bodySize = 0
+	representation := make(map[string]interface{})
+	repRequest := representRequest(request)
+	repResponse := representResponse(response)
+	representation["request"] = repRequest
+	representation["response"] = repResponse
+	object, err = json.Marshal(representation)
+	return
+}
+
+func (d dissecting) Macros() map[string]string {
+	return map[string]string{
+		`mongodb`: fmt.Sprintf(`proto.name == "%s"`, protocol.Name),
+	}
+}
+
+func (d dissecting) NewResponseRequestMatcher() api.RequestResponseMatcher {
+	return createResponseRequestMatcher()
+}
+
+var Dissector dissecting
+
+func NewDissector() api.Dissector {
+	return Dissector
+}
@@ -0,0 +1,87 @@
+package mongodb
+
+import (
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// Key is `{src_ip}_{dst_ip}_{src_port}_{dst_port}_{request_id}`
+type requestResponseMatcher struct {
+	openMessagesMap *sync.Map
+}
+
+func createResponseRequestMatcher() api.RequestResponseMatcher {
+	return &requestResponseMatcher{openMessagesMap: &sync.Map{}}
+}
+
+func (matcher *requestResponseMatcher) GetMap() *sync.Map {
+	return matcher.openMessagesMap
+}
+func (matcher *requestResponseMatcher) SetMaxTry(value int) {
+}
+
+func (matcher *requestResponseMatcher) registerRequest(ident string, request *MongoRequest, captureTime time.Time) *api.OutputChannelItem {
+	requestMongoMessage := api.GenericMessage{
+		IsRequest:   true,
+		CaptureTime: captureTime,
+		Payload: MongoPayload{
+			Data: &MongoWrapper{
+				Method:  request.Command,
+				Url:     "",
+				Details: request,
+			},
+		},
+	}
+
+	if response, found := matcher.openMessagesMap.LoadAndDelete(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		responseMongoMessage := response.(*api.GenericMessage)
+		if responseMongoMessage.IsRequest {
+			return nil
+		}
+		return matcher.preparePair(&requestMongoMessage, responseMongoMessage)
+	}
+
+	matcher.openMessagesMap.Store(ident, &requestMongoMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) registerResponse(ident string, response *MongoResponse, captureTime time.Time) *api.OutputChannelItem {
+	responseMongoMessage := api.GenericMessage{
+		IsRequest:   false,
+		CaptureTime: captureTime,
+		Payload: MongoPayload{
+			Data: &MongoWrapper{
+				Method:  response.Status,
+				Url:     "",
+				Details: response,
+			},
+		},
+	}
+
+	if request, found := matcher.openMessagesMap.LoadAndDelete(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		requestMongoMessage := request.(*api.GenericMessage)
+		if !requestMongoMessage.IsRequest {
+			return nil
+		}
+		return matcher.preparePair(requestMongoMessage, &responseMongoMessage)
+	}
+
+	matcher.openMessagesMap.Store(ident, &responseMongoMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) preparePair(requestMongoMessage *api.GenericMessage, responseMongoMessage *api.GenericMessage) *api.OutputChannelItem {
+	return &api.OutputChannelItem{
+		Protocol:       protocol,
+		Timestamp:      requestMongoMessage.CaptureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: nil,
+		Pair: &api.RequestResponsePair{
+			Request:  *requestMongoMessage,
+			Response: *responseMongoMessage,
+		},
+	}
+}
@@ -0,0 +1,422 @@
+package mongodb
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+var errMalformedMessage = errors.New("malformed message")
+
+type messageHeader struct {
+	length     int32
+	requestId  int32
+	responseTo int32
+	opCode     int32
+}
+
+type wireMessage struct {
+	header messageHeader
+	// opCode is the op code of the compressed message for OP_COMPRESSED
+	opCode     int32
+	compressor string
+	// body is nil when the message is compressed with an unsupported compressor
+	body []byte
+}
+
+// readMessage reads a message whose op code is one of allowed, the messages are validated strictly since the
+// header is the only way to tell MongoDB apart from the other protocols
+func readMessage(b *bufio.Reader, allowed ...int32) (*wireMessage, error) {
+	var headerBytes [headerLength]byte
+	if _, err := io.ReadFull(b, headerBytes[:]); err != nil {
+		return nil, err
+	}
+
+	header := messageHeader{
+		length:     int32(binary.LittleEndian.Uint32(headerBytes[0:4])),
+		requestId:  int32(binary.LittleEndian.Uint32(headerBytes[4:8])),
+		responseTo: int32(binary.LittleEndian.Uint32(headerBytes[8:12])),
+		opCode:     int32(binary.LittleEndian.Uint32(headerBytes[12:16])),
+	}
+	if header.length < headerLength || header.length > maxMessageLength {
+		return nil, fmt.Errorf("invalid message length %d", header.length)
+	}
+	if !isAllowedOpCode(header.opCode, allowed) {
+		return nil, fmt.Errorf("unexpected op code %d", header.opCode)
+	}
+
+	body := make([]byte, header.length-headerLength)
+	if _, err := io.ReadFull(b, body); err != nil {
+		return nil, err
+	}
+
+	msg := &wireMessage{header: header, opCode: header.opCode, body: body}
+	if header.opCode == opCompressed {
+		if err := decompress(msg, allowed); err != nil {
+			return nil, err
+		}
+	}
+	return msg, nil
+}
+
+func isAllowedOpCode(opCode int32, allowed []int32) bool {
+	for _, allowedOpCode := range allowed {
+		if opCode == allowedOpCode {
+			return true
+		}
+	}
+	return false
+}
+
+// decompress replaces the body of an OP_COMPRESSED message with the compressed message, snappy and zstd need
+// libraries the tapper doesn't ship, so those bodies are dropped and only the header is used for pairing
+func decompress(msg *wireMessage, allowed []int32) error {
+	if len(msg.body) < 9 {
+		return errMalformedMessage
+	}
+	originalOpCode := int32(binary.LittleEndian.Uint32(msg.body[0:4]))
+	uncompressedSize := int32(binary.LittleEndian.Uint32(msg.body[4:8]))
+	compressorId := msg.body[8]
+	compressed := msg.body[9:]
+
+	if originalOpCode == opCompressed || !isAllowedOpCode(originalOpCode, allowed) {
+		return fmt.Errorf("unexpected compressed op code %d", originalOpCode)
+	}
+	if uncompressedSize < 0 || uncompressedSize > maxMessageLength {
+		return fmt.Errorf("invalid uncompressed size %d", uncompressedSize)
+	}
+	compressor, ok := compressors[compressorId]
+	if !ok {
+		return fmt.Errorf("unknown compressor %d", compressorId)
+	}
+
+	msg.opCode = originalOpCode
+	msg.compressor = compressor
+
+	switch compressorId {
+	case 0:
+		msg.body = compressed
+	case 2:
+		reader, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return err
+		}
+		defer reader.Close()
+
+		body, err := ioutil.ReadAll(io.LimitReader(reader, int64(uncompressedSize)+1))
+		if err != nil {
+			return err
+		}
+		if len(body) != int(uncompressedSize) {
+			return errMalformedMessage
+		}
+		msg.body = body
+	default:
+		msg.body = nil
+	}
+
+	return nil
+}
+
+// parseOpMsg returns the body section of an OP_MSG, the document sequences (e.g. the documents of an insert) are
+// added to it as arrays named by their identifiers, the same as a command that carries them in its body
+func parseOpMsg(body []byte) (uint32, bsonDocument, error) {
+	if len(body) < 4 {
+		return 0, nil, errMalformedMessage
+	}
+	flags := binary.LittleEndian.Uint32(body)
+	if flags&^(flagChecksumPresent|flagMoreToCome|flagExhaustAllowed) != 0 {
+		return 0, nil, fmt.Errorf("unknown OP_MSG flags 0x%x", flags)
+	}
+
+	sections := body[4:]
+	if flags&flagChecksumPresent != 0 {
+		if len(sections) < 4 {
+			return 0, nil, errMalformedMessage
+		}
+		sections = sections[:len(sections)-4]
+	}
+
+	var document bsonDocument
+	var sequences bsonDocument
+	for len(sections) > 0 {
+		kind := sections[0]
+		sections = sections[1:]
+
+		switch kind {
+		case 0:
+			if document != nil {
+				return 0, nil, errors.New("OP_MSG with more than one body section")
+			}
+			sectionDocument, n, err := decodeDocument(sections)
+			if err != nil {
+				return 0, nil, err
+			}
+			document = sectionDocument
+			sections = sections[n:]
+		case 1:
+			if len(sections) < 4 {
+				return 0, nil, errMalformedMessage
+			}
+			size := int(int32(binary.LittleEndian.Uint32(sections)))
+			if size < 4 || size > len(sections) {
+				return 0, nil, errMalformedMessage
+			}
+			identifier, n, err := readCString(sections[4:size])
+			if err != nil {
+				return 0, nil, err
+			}
+
+			documents := make([]interface{}, 0)
+			for sequence := sections[4+n : size]; len(sequence) > 0; {
+				sequenceDocument, m, err := decodeDocument(sequence)
+				if err != nil {
+					return 0, nil, err
+				}
+				documents = append(documents, sequenceDocument)
+				sequence = sequence[m:]
+			}
+			sequences = append(sequences, bsonElement{Key: identifier, Value: documents})
+			sections = sections[size:]
+		default:
+			return 0, nil, fmt.Errorf("unknown OP_MSG section kind %d", kind)
+		}
+	}
+
+	if document == nil {
+		return 0, nil, errors.New("OP_MSG without a body section")
+	}
+	return flags, append(document, sequences...), nil
+}
+
+// parseRequest returns the request of a client message, false is returned for the messages the server doesn't reply to
+func parseRequest(msg *wireMessage) (*MongoRequest, bool, error) {
+	request := &MongoRequest{
+		OpCode:     opCodes[msg.opCode],
+		RequestId:  msg.header.requestId,
+		Compressor: msg.compressor,
+	}
+	if msg.body == nil {
+		return request, true, nil
+	}
+
+	switch msg.opCode {
+	case opMsg:
+		flags, document, err := parseOpMsg(msg.body)
+		if err != nil {
+			return nil, false, err
+		}
+		request.Command = document.firstKey()
+		if len(document) > 0 {
+			request.Collection, _ = document[0].Value.(string)
+		}
+		if database, ok := document.lookup("$db"); ok {
+			request.Database, _ = database.(string)
+		}
+		request.Document = document
+		return request, flags&flagMoreToCome == 0, nil
+	case opQuery:
+		if len(msg.body) < 4 {
+			return nil, false, errMalformedMessage
+		}
+		namespace, n, err := readCString(msg.body[4:])
+		if err != nil {
+			return nil, false, err
+		}
+		// skip the number to skip and the number to return
+		offset := 4 + n + 8
+		if offset > len(msg.body) {
+			return nil, false, errMalformedMessage
+		}
+		query, _, err := decodeDocument(msg.body[offset:])
+		if err != nil {
+			return nil, false, err
+		}
+
+		request.Database, request.Collection = splitNamespace(namespace)
+		request.Document = query
+		if request.Collection == "$cmd" {
+			command := unwrapQuery(query)
+			request.Command = command.firstKey()
+			request.Collection = ""
+			if len(command) > 0 {
+				request.Collection, _ = command[0].Value.(string)
+			}
+		} else {
+			request.Command = "find"
+		}
+		return request, true, nil
+	case opGetMore:
+		if len(msg.body) < 4 {
+			return nil, false, errMalformedMessage
+		}
+		namespace, n, err := readCString(msg.body[4:])
+		if err != nil {
+			return nil, false, err
+		}
+		offset := 4 + n
+		if offset+12 > len(msg.body) {
+			return nil, false, errMalformedMessage
+		}
+		request.Command = "getMore"
+		request.Database, request.Collection = splitNamespace(namespace)
+		request.Document = bsonDocument{
+			{Key: "getMore", Value: int64(binary.LittleEndian.Uint64(msg.body[offset+4:]))},
+			{Key: "batchSize", Value: int32(binary.LittleEndian.Uint32(msg.body[offset:]))},
+		}
+		return request, true, nil
+	default:
+		// OP_KILL_CURSORS
+		return nil, false, nil
+	}
+}
+
+// parseResponse returns the response of a server message
+func parseResponse(msg *wireMessage) (*MongoResponse, error) {
+	response := &MongoResponse{
+		OpCode:     opCodes[msg.opCode],
+		ResponseTo: msg.header.responseTo,
+		Status:     StatusOk,
+		Compressor: msg.compressor,
+	}
+	if msg.body == nil {
+		return response, nil
+	}
+
+	switch msg.opCode {
+	case opMsg:
+		_, document, err := parseOpMsg(msg.body)
+		if err != nil {
+			return nil, err
+		}
+		response.Document = document
+		analyzeReply(response, document)
+	default:
+		// OP_REPLY
+		if len(msg.body) < 20 {
+			return nil, errMalformedMessage
+		}
+		flags := binary.LittleEndian.Uint32(msg.body[0:4])
+		numberReturned := int(int32(binary.LittleEndian.Uint32(msg.body[16:20])))
+
+		documents := make([]interface{}, 0)
+		for data := msg.body[20:]; len(data) > 0; {
+			document, n, err := decodeDocument(data)
+			if err != nil {
+				return nil, err
+			}
+			documents = append(documents, document)
+			data = data[n:]
+		}
+		response.Returned = numberReturned
+
+		if len(documents) != 1 {
+			response.Document = documents
+			return response, nil
+		}
+
+		document := documents[0].(bsonDocument)
+		response.Document = document
+		if flags&replyFlagQueryFailure != 0 {
+			message, _ := document.lookup("$err")
+			response.Status = StatusError
+			response.Error = &MongoError{Message: fmt.Sprintf("%v", message)}
+			if code, ok := document.lookup("code"); ok {
+				response.Error.Code = int(toFloat(code))
+			}
+		} else if _, ok := document.lookup("ok"); ok {
+			// the reply of a command sent with OP_QUERY
+			response.Returned = 0
+			analyzeReply(response, document)
+		}
+	}
+
+	return response, nil
+}
+
+// analyzeReply fills the status, the error and the returned count from the reply of a command
+func analyzeReply(response *MongoResponse, document bsonDocument) {
+	if ok, found := document.lookup("ok"); found && toFloat(ok) != 1 {
+		response.Status = StatusError
+		response.Error = getError(document)
+	} else if writeErrors, found := document.lookup("writeErrors"); found {
+		if errs, isArray := writeErrors.([]interface{}); isArray && len(errs) > 0 {
+			response.Status = StatusError
+			if writeError, isDocument := errs[0].(bsonDocument); isDocument {
+				response.Error = getError(writeError)
+			}
+		}
+	} else if writeConcernError, found := document.lookup("writeConcernError"); found {
+		if writeConcernErrorDocument, isDocument := writeConcernError.(bsonDocument); isDocument {
+			response.Status = StatusError
+			response.Error = getError(writeConcernErrorDocument)
+		}
+	}
+
+	if cursor, found := document.lookup("cursor"); found {
+		if cursorDocument, isDocument := cursor.(bsonDocument); isDocument {
+			for _, batch := range []string{"firstBatch", "nextBatch"} {
+				if documents, found := cursorDocument.lookup(batch); found {
+					if array, isArray := documents.([]interface{}); isArray {
+						response.Returned = len(array)
+					}
+				}
+			}
+		}
+	} else if n, found := document.lookup("n"); found {
+		response.Returned = int(toFloat(n))
+	}
+}
+
+func getError(document bsonDocument) *MongoError {
+	mongoError := &MongoError{}
+	if message, ok := document.lookup("errmsg"); ok {
+		mongoError.Message, _ = message.(string)
+	}
+	if code, ok := document.lookup("code"); ok {
+		mongoError.Code = int(toFloat(code))
+	}
+	if codeName, ok := document.lookup("codeName"); ok {
+		mongoError.CodeName, _ = codeName.(string)
+	}
+	return mongoError
+}
+
+func toFloat(value interface{}) float64 {
+	switch number := value.(type) {
+	case float64:
+		return number
+	case int32:
+		return float64(number)
+	case int64:
+		return float64(number)
+	case bool:
+		if number {
+			return 1
+		}
+	}
+	return 0
+}
+
+func splitNamespace(namespace string) (string, string) {
+	if index := strings.IndexByte(namespace, '.'); index >= 0 {
+		return namespace[:index], namespace[index+1:]
+	}
+	return namespace, ""
+}
+
+// unwrapQuery returns the command of a query that drivers wrapped to add read preferences (e.g. {$query: {...}})
+func unwrapQuery(query bsonDocument) bsonDocument {
+	if key := query.firstKey(); key == "$query" || key == "query" {
+		if command, ok := query[0].Value.(bsonDocument); ok {
+			return command
+		}
+	}
+	return query
+}
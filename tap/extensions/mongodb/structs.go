@@ -0,0 +1,64 @@
+package mongodb
+
+const (
+	opReply       = 1
+	opQuery       = 2004
+	opGetMore     = 2005
+	opKillCursors = 2007
+	opCompressed  = 2012
+	opMsg         = 2013
+
+	headerLength     = 16
+	maxMessageLength = 48 * 1000 * 1000
+
+	flagChecksumPresent = 1 << 0
+	flagMoreToCome      = 1 << 1
+	flagExhaustAllowed  = 1 << 16
+
+	replyFlagQueryFailure = 1 << 1
+
+	StatusOk    = "OK"
+	StatusError = "ERROR"
+)
+
+var opCodes = map[int32]string{
+	opReply:       "OP_REPLY",
+	opQuery:       "OP_QUERY",
+	opGetMore:     "OP_GET_MORE",
+	opKillCursors: "OP_KILL_CURSORS",
+	opCompressed:  "OP_COMPRESSED",
+	opMsg:         "OP_MSG",
+}
+
+var compressors = map[byte]string{
+	0: "noop",
+	1: "snappy",
+	2: "zlib",
+	3: "zstd",
+}
+
+type MongoRequest struct {
+	OpCode     string      `json:"opCode"`
+	RequestId  int32       `json:"requestId"`
+	Command    string      `json:"command"`
+	Database   string      `json:"database"`
+	Collection string      `json:"collection"`
+	Compressor string      `json:"compressor,omitempty"`
+	Document   interface{} `json:"document"`
+}
+
+type MongoResponse struct {
+	OpCode     string      `json:"opCode"`
+	ResponseTo int32       `json:"responseTo"`
+	Status     string      `json:"status"`
+	Returned   int         `json:"returned"`
+	Compressor string      `json:"compressor,omitempty"`
+	Error      *MongoError `json:"error,omitempty"`
+	Document   interface{} `json:"document"`
+}
+
+type MongoError struct {
+	Code     int    `json:"code,omitempty"`
+	CodeName string `json:"codeName,omitempty"`
+	Message  string `json:"message"`
+}
@@ -0,0 +1,225 @@
+package mqtt
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type MqttPayload struct {
+	Data interface{}
+}
+
+type MqttPayloader interface {
+	MarshalJSON() ([]byte, error)
+}
+
+func (h MqttPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Data)
+}
+
+type MqttWrapper struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Details interface{} `json:"details"`
+}
+
+func emitMQTT(event interface{}, method string, connectionInfo *api.ConnectionInfo, captureTime time.Time, emitter api.Emitter) {
+	request := &api.GenericMessage{
+		IsRequest:   true,
+		CaptureTime: captureTime,
+		Payload: MqttPayload{
+			Data: &MqttWrapper{
+				Method:  method,
+				Url:     "",
+				Details: event,
+			},
+		},
+	}
+	item := &api.OutputChannelItem{
+		Protocol:       protocol,
+		Timestamp:      captureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: connectionInfo,
+		Pair: &api.RequestResponsePair{
+			Request:  *request,
+			Response: api.GenericMessage{},
+		},
+	}
+	emitter.Emit(item)
+}
+
+func representTable(rep []interface{}, title string, rows []api.TableData) []interface{} {
+	data, _ := json.Marshal(rows)
+	return append(rep, api.SectionData{
+		Type:  api.TABLE,
+		Title: title,
+		Data:  string(data),
+	})
+}
+
+func representProperties(rep []interface{}, properties interface{}, selector string) []interface{} {
+	list, ok := properties.([]interface{})
+	if !ok || len(list) == 0 {
+		return rep
+	}
+
+	rows := make([]api.TableData, 0, len(list))
+	for i, item := range list {
+		property := item.(map[string]interface{})
+		rows = append(rows, api.TableData{
+			Name:     property["name"].(string),
+			Value:    property["value"].(string),
+			Selector: fmt.Sprintf(`%s[%d].value`, selector, i),
+		})
+	}
+	return representTable(rep, "Properties", rows)
+}
+
+func representConnect(event map[string]interface{}) []interface{} {
+	rep := make([]interface{}, 0)
+
+	rep = representTable(rep, "Details", []api.TableData{
+		{
+			Name:     "Client ID",
+			Value:    event["clientId"].(string),
+			Selector: `request.clientId`,
+		},
+		{
+			Name:     "Protocol Version",
+			Value:    event["protocolVersion"].(string),
+			Selector: `request.protocolVersion`,
+		},
+		{
+			Name:     "Username",
+			Value:    event["username"].(string),
+			Selector: `request.username`,
+		},
+		{
+			Name:     "Password",
+			Value:    strconv.FormatBool(event["hasPassword"].(bool)),
+			Selector: `request.hasPassword`,
+		},
+		{
+			Name:     "Clean Session",
+			Value:    strconv.FormatBool(event["cleanSession"].(bool)),
+			Selector: `request.cleanSession`,
+		},
+		{
+			Name:     "Keep Alive",
+			Value:    fmt.Sprintf("%g", event["keepAlive"].(float64)),
+			Selector: `request.keepAlive`,
+		},
+	})
+	rep = representProperties(rep, event["properties"], `request.properties`)
+
+	if will, ok := event["will"].(map[string]interface{}); ok {
+		rep = representTable(rep, "Will", []api.TableData{
+			{
+				Name:     "Topic",
+				Value:    will["topic"].(string),
+				Selector: `request.will.topic`,
+			},
+			{
+				Name:     "QoS",
+				Value:    fmt.Sprintf("%g", will["qos"].(float64)),
+				Selector: `request.will.qos`,
+			},
+			{
+				Name:     "Retain",
+				Value:    strconv.FormatBool(will["retain"].(bool)),
+				Selector: `request.will.retain`,
+			},
+		})
+		if will["payload"] != nil {
+			rep = append(rep, api.SectionData{
+				Type:     api.BODY,
+				Title:    "Will Payload",
+				Encoding: "base64",
+				Data:     will["payload"].(string),
+				Selector: `request.will.payload`,
+			})
+		}
+	}
+
+	return rep
+}
+
+func representPublish(event map[string]interface{}) []interface{} {
+	rep := make([]interface{}, 0)
+
+	rep = representTable(rep, "Details", []api.TableData{
+		{
+			Name:     "Topic",
+			Value:    event["topic"].(string),
+			Selector: `request.topic`,
+		},
+		{
+			Name:     "QoS",
+			Value:    fmt.Sprintf("%g", event["qos"].(float64)),
+			Selector: `request.qos`,
+		},
+		{
+			Name:     "Retain",
+			Value:    strconv.FormatBool(event["retain"].(bool)),
+			Selector: `request.retain`,
+		},
+		{
+			Name:     "Duplicate",
+			Value:    strconv.FormatBool(event["dup"].(bool)),
+			Selector: `request.dup`,
+		},
+		{
+			Name:     "Packet ID",
+			Value:    fmt.Sprintf("%g", event["packetId"].(float64)),
+			Selector: `request.packetId`,
+		},
+		{
+			Name:     "Size",
+			Value:    fmt.Sprintf("%g", event["size"].(float64)),
+			Selector: `request.size`,
+		},
+	})
+	rep = representProperties(rep, event["properties"], `request.properties`)
+
+	if event["payload"] != nil {
+		rep = append(rep, api.SectionData{
+			Type:     api.BODY,
+			Title:    "Payload",
+			Encoding: "base64",
+			MimeType: event["contentType"].(string),
+			Data:     event["payload"].(string),
+			Selector: `request.payload`,
+		})
+	}
+
+	return rep
+}
+
+func representSubscribe(event map[string]interface{}) []interface{} {
+	rep := make([]interface{}, 0)
+
+	rep = representTable(rep, "Details", []api.TableData{
+		{
+			Name:     "Packet ID",
+			Value:    fmt.Sprintf("%g", event["packetId"].(float64)),
+			Selector: `request.packetId`,
+		},
+	})
+
+	subscriptions := event["subscriptions"].([]interface{})
+	rows := make([]api.TableData, 0, len(subscriptions))
+	for i, item := range subscriptions {
+		subscription := item.(map[string]interface{})
+		rows = append(rows, api.TableData{
+			Name:     subscription["topic"].(string),
+			Value:    fmt.Sprintf("QoS %g", subscription["qos"].(float64)),
+			Selector: fmt.Sprintf(`request.subscriptions[%d].topic`, i),
+		})
+	}
+	rep = representTable(rep, "Subscriptions", rows)
+
+	return representProperties(rep, event["properties"], `request.properties`)
+}
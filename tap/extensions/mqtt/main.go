@@ -0,0 +1,220 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+var protocol api.Protocol = api.Protocol{
+	Name:            "mqtt",
+	LongName:        "Message Queuing Telemetry Transport",
+	Abbreviation:    "MQTT",
+	Macro:           "mqtt",
+	Version:         "5.0",
+	BackgroundColor: "#660066",
+	ForegroundColor: "#ffffff",
+	FontSize:        12,
+	ReferenceLink:   "https://docs.oasis-open.org/mqtt/mqtt/v5.0/mqtt-v5.0.html",
+	Ports:           []string{"1883", "8883"},
+	Priority:        8,
+}
+
+type dissecting string
+
+func (d dissecting) Register(extension *api.Extension) {
+	extension.Protocol = &protocol
+}
+
+func (d dissecting) Ping() {
+	log.Printf("pong %s", protocol.Name)
+}
+
+func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, superIdentifier *api.SuperIdentifier, emitter api.Emitter, options *api.TrafficFilteringOptions, _reqResMatcher api.RequestResponseMatcher) error {
+	state := _reqResMatcher.(*connectionState)
+
+	// like in AMQP every packet is emitted on its own, the MQTT client is the source of the messages of both directions
+	connectionInfo := &api.ConnectionInfo{
+		ClientIP:   tcpID.SrcIP,
+		ClientPort: tcpID.SrcPort,
+		ServerIP:   tcpID.DstIP,
+		ServerPort: tcpID.DstPort,
+		IsOutgoing: true,
+	}
+	if !isClient {
+		connectionInfo = &api.ConnectionInfo{
+			ClientIP:   tcpID.DstIP,
+			ClientPort: tcpID.DstPort,
+			ServerIP:   tcpID.SrcIP,
+			ServerPort: tcpID.SrcPort,
+			IsOutgoing: false,
+		}
+	}
+
+	// a session already connected when the tapping started is followed on the broker ports only, elsewhere
+	// the CONNECT and its CONNACK are expected first
+	identified := isMqttPort(connectionInfo.ServerPort)
+
+	for {
+		if superIdentifier.Protocol != nil && superIdentifier.Protocol != &protocol {
+			return errors.New("Identified by another protocol")
+		}
+
+		p, err := readPacket(b, maxRetainedPayload)
+		if err != nil {
+			return err
+		}
+
+		if !identified {
+			if (isClient && p.packetType != packetConnect) || (!isClient && !isConnack(p)) {
+				return errors.New("Not a MQTT connection")
+			}
+			identified = true
+		}
+
+		switch p.packetType {
+		case packetConnect:
+			connect, level, err := parseConnect(p.body)
+			if err != nil {
+				return err
+			}
+			state.setLevel(level)
+			superIdentifier.Protocol = &protocol
+			emitMQTT(*connect, packetTypeName(packetConnect), connectionInfo, superTimer.CaptureTime, emitter)
+
+		case packetPublish:
+			publish, topicAlias, err := parsePublish(p.body, p.flags, p.length, state.getLevel())
+			if err != nil {
+				return err
+			}
+			publish.Topic = state.resolveTopic(isClient, publish.Topic, topicAlias)
+			superIdentifier.Protocol = &protocol
+			emitMQTT(*publish, packetTypeName(packetPublish), connectionInfo, superTimer.CaptureTime, emitter)
+
+		case packetSubscribe:
+			subscribe, err := parseSubscribe(p.body, state.getLevel())
+			if err != nil {
+				return err
+			}
+			superIdentifier.Protocol = &protocol
+			emitMQTT(*subscribe, packetTypeName(packetSubscribe), connectionInfo, superTimer.CaptureTime, emitter)
+
+		default:
+			// the acknowledgements, pings and the rest of the control packets are dropped
+		}
+	}
+}
+
+func isMqttPort(port string) bool {
+	for _, mqttPort := range protocol.Ports {
+		if port == mqttPort {
+			return true
+		}
+	}
+	return false
+}
+
+func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	request := item.Pair.Request.Payload.(map[string]interface{})
+	reqDetails := request["details"].(map[string]interface{})
+
+	reqDetails["method"] = request["method"]
+	return &api.Entry{
+		Protocol: protocol,
+		Source: &api.TCP{
+			Name: resolvedSource,
+			IP:   item.ConnectionInfo.ClientIP,
+			Port: item.ConnectionInfo.ClientPort,
+		},
+		Destination: &api.TCP{
+			Name: resolvedDestination,
+			IP:   item.ConnectionInfo.ServerIP,
+			Port: item.ConnectionInfo.ServerPort,
+		},
+		Namespace:   namespace,
+		Outgoing:    item.ConnectionInfo.IsOutgoing,
+		Request:     reqDetails,
+		Timestamp:   item.Timestamp,
+		StartTime:   item.Pair.Request.CaptureTime,
+		ElapsedTime: 0,
+	}
+
+}
+
+func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
+	summary := ""
+	summaryQuery := ""
+	method := entry.Request["method"].(string)
+	methodQuery := fmt.Sprintf(`request.method == "%s"`, method)
+	switch method {
+	case packetTypeName(packetConnect):
+		summary = entry.Request["clientId"].(string)
+		summaryQuery = fmt.Sprintf(`request.clientId == %s`, strconv.Quote(summary))
+	case packetTypeName(packetPublish):
+		summary = entry.Request["topic"].(string)
+		summaryQuery = fmt.Sprintf(`request.topic == %s`, strconv.Quote(summary))
+	case packetTypeName(packetSubscribe):
+		if subscriptions, ok := entry.Request["subscriptions"].([]interface{}); ok && len(subscriptions) > 0 {
+			summary = subscriptions[0].(map[string]interface{})["topic"].(string)
+			summaryQuery = fmt.Sprintf(`request.subscriptions[0].topic == %s`, strconv.Quote(summary))
+		}
+	}
+
+	return &api.BaseEntry{
+		Id:             entry.Id,
+		EntryId:        entry.EntryId,
+		Protocol:       entry.Protocol,
+		Summary:        summary,
+		SummaryQuery:   summaryQuery,
+		Status:         0,
+		StatusQuery:    "",
+		Method:         method,
+		MethodQuery:    methodQuery,
+		Timestamp:      entry.Timestamp,
+		Source:         entry.Source,
+		Destination:    entry.Destination,
+		IsOutgoing:     entry.Outgoing,
+		Latency:        entry.ElapsedTime,
+		Rules:          entry.Rules,
+		ContractStatus: entry.ContractStatus,
+	}
+}
+
+func (d dissecting) Represent(request map[string]interface{}, response map[string]interface{}) (object []byte, bodySize int64, err error) {
+	bodySize = 0
+	representation := make(map[string]interface{})
+	var repRequest []interface{}
+	switch request["method"].(string) {
+	case packetTypeName(packetConnect):
+		repRequest = representConnect(request)
+	case packetTypeName(packetPublish):
+		repRequest = representPublish(request)
+		bodySize = int64(request["size"].(float64))
+	case packetTypeName(packetSubscribe):
+		repRequest = representSubscribe(request)
+	}
+	representation["request"] = repRequest
+	object, err = json.Marshal(representation)
+	return
+}
+
+func (d dissecting) Macros() map[string]string {
+	return map[string]string{
+		`mqtt`: fmt.Sprintf(`proto.name == "%s"`, protocol.Name),
+	}
+}
+
+func (d dissecting) NewResponseRequestMatcher() api.RequestResponseMatcher {
+	return createConnectionState()
+}
+
+var Dissector dissecting
+
+func NewDissector() api.Dissector {
+	return Dissector
+}
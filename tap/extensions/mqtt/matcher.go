@@ -0,0 +1,65 @@
+package mqtt
+
+import (
+	"sync"
+)
+
+// connectionState is shared by the two directions of a connection, MQTT has no responses to match so the
+// map stays empty, it keeps the protocol level of the CONNECT and the topic aliases of MQTT 5.0 instead
+type connectionState struct {
+	openMessagesMap *sync.Map
+	lock            sync.Mutex
+	level           byte
+	// the topics by alias, an alias is set separately by the client and by the broker
+	clientAliases map[int]string
+	serverAliases map[int]string
+}
+
+func createConnectionState() *connectionState {
+	return &connectionState{
+		openMessagesMap: &sync.Map{},
+		level:           level311,
+		clientAliases:   make(map[int]string),
+		serverAliases:   make(map[int]string),
+	}
+}
+
+func (state *connectionState) GetMap() *sync.Map {
+	return state.openMessagesMap
+}
+
+func (state *connectionState) SetMaxTry(value int) {
+}
+
+func (state *connectionState) setLevel(level byte) {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	state.level = level
+}
+
+// getLevel is 3.1.1 until a CONNECT is seen, the connections opened before the tapping started are read as 3.1.1
+func (state *connectionState) getLevel() byte {
+	state.lock.Lock()
+	defer state.lock.Unlock()
+	return state.level
+}
+
+// resolveTopic stores the topic of an alias or, when the topic is empty, returns the topic the alias stands for
+func (state *connectionState) resolveTopic(isClient bool, topic string, topicAlias int) string {
+	if topicAlias == 0 {
+		return topic
+	}
+
+	state.lock.Lock()
+	defer state.lock.Unlock()
+
+	aliases := state.serverAliases
+	if isClient {
+		aliases = state.clientAliases
+	}
+	if topic != "" {
+		aliases[topicAlias] = topic
+		return topic
+	}
+	return aliases[topicAlias]
+}
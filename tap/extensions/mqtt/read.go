@@ -0,0 +1,404 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"unicode/utf8"
+)
+
+var errMalformedPacket = errors.New("malformed packet")
+
+type packet struct {
+	packetType byte
+	flags      byte
+	// the remaining length announced by the fixed header, body holds only its first bytes if it's long
+	length int
+	body   []byte
+}
+
+// readPacket reads a control packet, only the first maxRetained bytes of its body are kept
+func readPacket(b *bufio.Reader, maxRetained int) (*packet, error) {
+	first, err := b.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &packet{
+		packetType: first >> 4,
+		flags:      first & 0x0f,
+	}
+	if !isValidFixedHeader(p.packetType, p.flags) {
+		return nil, errMalformedPacket
+	}
+
+	if p.length, err = readVarint(b); err != nil {
+		return nil, err
+	}
+
+	retained := p.length
+	if retained > maxRetained {
+		retained = maxRetained
+	}
+	p.body = make([]byte, retained)
+	if _, err := io.ReadFull(b, p.body); err != nil {
+		return nil, err
+	}
+	if _, err := b.Discard(p.length - retained); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// isValidFixedHeader checks the reserved flags, they are what tells a MQTT stream apart from the other protocols
+func isValidFixedHeader(packetType byte, flags byte) bool {
+	switch packetType {
+	case 0:
+		return false
+	case packetPublish:
+		return flags&flagQos != flagQos
+	case packetPubrel, packetSubscribe, packetUnsubscribe:
+		return flags == 0x02
+	default:
+		return flags == 0
+	}
+}
+
+// readVarint reads a variable byte integer, 7 bits per byte in at most 4 bytes
+func readVarint(b io.ByteReader) (int, error) {
+	value := 0
+	for i := 0; i < 4; i++ {
+		digit, err := b.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= int(digit&0x7f) << (7 * i)
+		if digit&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errMalformedPacket
+}
+
+type bodyReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *bodyReader) remaining() int {
+	return len(r.data) - r.offset
+}
+
+func (r *bodyReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.offset+n > len(r.data) {
+		return nil, errMalformedPacket
+	}
+	value := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return value, nil
+}
+
+func (r *bodyReader) ReadByte() (byte, error) {
+	value, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return value[0], nil
+}
+
+func (r *bodyReader) uint16() (uint16, error) {
+	value, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint16(value), nil
+}
+
+func (r *bodyReader) uint32() (uint32, error) {
+	value, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(value), nil
+}
+
+func (r *bodyReader) binary() ([]byte, error) {
+	length, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+	return r.bytes(int(length))
+}
+
+func (r *bodyReader) string() (string, error) {
+	value, err := r.binary()
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(value) {
+		return "", errMalformedPacket
+	}
+	return string(value), nil
+}
+
+func (r *bodyReader) rest() []byte {
+	value := r.data[r.offset:]
+	r.offset = len(r.data)
+	return value
+}
+
+// readProperties reads the properties of MQTT 5.0, the topic alias is returned apart since it changes the meaning of a PUBLISH
+func (r *bodyReader) readProperties() (result []MqttProperty, topicAlias int, err error) {
+	length, err := readVarint(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	data, err := r.bytes(length)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result = make([]MqttProperty, 0)
+	propertiesReader := &bodyReader{data: data}
+	for propertiesReader.remaining() > 0 {
+		if len(result) >= maxProperties {
+			return nil, 0, errMalformedPacket
+		}
+
+		identifier, err := propertiesReader.ReadByte()
+		if err != nil {
+			return nil, 0, err
+		}
+		definition, ok := properties[identifier]
+		if !ok {
+			return nil, 0, fmt.Errorf("unknown property 0x%02x: %w", identifier, errMalformedPacket)
+		}
+
+		property := MqttProperty{Name: definition.name}
+		switch definition.kind {
+		case propertyByte:
+			value, err := propertiesReader.ReadByte()
+			if err != nil {
+				return nil, 0, err
+			}
+			property.Value = strconv.Itoa(int(value))
+		case propertyUint16:
+			value, err := propertiesReader.uint16()
+			if err != nil {
+				return nil, 0, err
+			}
+			property.Value = strconv.Itoa(int(value))
+			if identifier == propertyTopicAlias {
+				topicAlias = int(value)
+			}
+		case propertyUint32:
+			value, err := propertiesReader.uint32()
+			if err != nil {
+				return nil, 0, err
+			}
+			property.Value = strconv.FormatUint(uint64(value), 10)
+		case propertyVarint:
+			value, err := readVarint(propertiesReader)
+			if err != nil {
+				return nil, 0, err
+			}
+			property.Value = strconv.Itoa(value)
+		case propertyString:
+			if property.Value, err = propertiesReader.string(); err != nil {
+				return nil, 0, err
+			}
+		case propertyBinary:
+			value, err := propertiesReader.binary()
+			if err != nil {
+				return nil, 0, err
+			}
+			property.Value = fmt.Sprintf("%x", value)
+		case propertyStringPair:
+			if property.Name, err = propertiesReader.string(); err != nil {
+				return nil, 0, err
+			}
+			if property.Value, err = propertiesReader.string(); err != nil {
+				return nil, 0, err
+			}
+		}
+		result = append(result, property)
+	}
+
+	return result, topicAlias, nil
+}
+
+func parseConnect(body []byte) (*MqttConnect, byte, error) {
+	r := &bodyReader{data: body}
+
+	protocolName, err := r.string()
+	if err != nil {
+		return nil, 0, err
+	}
+	level, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case protocolName == "MQIsdp" && level == level31:
+	case protocolName == "MQTT" && (level == level311 || level == level5):
+	default:
+		return nil, 0, fmt.Errorf("unsupported protocol %s level %d: %w", protocolName, level, errMalformedPacket)
+	}
+
+	flags, err := r.ReadByte()
+	if err != nil {
+		return nil, 0, err
+	}
+	// the reserved flag must be zero
+	if flags&0x01 != 0 {
+		return nil, 0, errMalformedPacket
+	}
+	keepAlive, err := r.uint16()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	connect := &MqttConnect{
+		ProtocolName:    protocolName,
+		ProtocolVersion: versionName(level),
+		CleanSession:    flags&flagCleanSession != 0,
+		KeepAlive:       int(keepAlive),
+		HasPassword:     flags&flagPassword != 0,
+		Properties:      make([]MqttProperty, 0),
+	}
+	if level == level5 {
+		if connect.Properties, _, err = r.readProperties(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	if connect.ClientId, err = r.string(); err != nil {
+		return nil, 0, err
+	}
+
+	if flags&flagWill != 0 {
+		will := &MqttWill{
+			Qos:        int(flags&flagWillQos) >> willQosShift,
+			Retain:     flags&flagWillRetain != 0,
+			Properties: make([]MqttProperty, 0),
+		}
+		if level == level5 {
+			if will.Properties, _, err = r.readProperties(); err != nil {
+				return nil, 0, err
+			}
+		}
+		if will.Topic, err = r.string(); err != nil {
+			return nil, 0, err
+		}
+		if will.Payload, err = r.binary(); err != nil {
+			return nil, 0, err
+		}
+		connect.Will = will
+	}
+
+	if flags&flagUsername != 0 {
+		if connect.Username, err = r.string(); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return connect, level, nil
+}
+
+// parsePublish reads a PUBLISH whose body may have been cut at maxRetainedPayload, length is the announced one
+func parsePublish(body []byte, flags byte, length int, level byte) (*MqttPublish, int, error) {
+	r := &bodyReader{data: body}
+
+	publish := &MqttPublish{
+		Qos:        int(flags&flagQos) >> qosShift,
+		Retain:     flags&flagRetain != 0,
+		Dup:        flags&flagDup != 0,
+		Properties: make([]MqttProperty, 0),
+	}
+
+	var err error
+	if publish.Topic, err = r.string(); err != nil {
+		return nil, 0, err
+	}
+	if publish.Qos > 0 {
+		packetId, err := r.uint16()
+		if err != nil {
+			return nil, 0, err
+		}
+		publish.PacketId = int(packetId)
+	}
+
+	topicAlias := 0
+	if level == level5 {
+		if publish.Properties, topicAlias, err = r.readProperties(); err != nil {
+			return nil, 0, err
+		}
+		for _, property := range publish.Properties {
+			if property.Name == properties[propertyContentType].name {
+				publish.ContentType = property.Value
+			}
+		}
+	}
+
+	publish.Size = length - r.offset
+	publish.Payload = r.rest()
+	return publish, topicAlias, nil
+}
+
+func parseSubscribe(body []byte, level byte) (*MqttSubscribe, error) {
+	r := &bodyReader{data: body}
+
+	packetId, err := r.uint16()
+	if err != nil {
+		return nil, err
+	}
+	subscribe := &MqttSubscribe{
+		PacketId:      int(packetId),
+		Subscriptions: make([]MqttSubscription, 0),
+		Properties:    make([]MqttProperty, 0),
+	}
+	if level == level5 {
+		if subscribe.Properties, _, err = r.readProperties(); err != nil {
+			return nil, err
+		}
+	}
+
+	for r.remaining() > 0 && len(subscribe.Subscriptions) < maxSubscriptions {
+		topic, err := r.string()
+		if err != nil {
+			return nil, err
+		}
+		options, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		subscription := MqttSubscription{
+			Topic: topic,
+			Qos:   int(options & optionQos),
+		}
+		if level == level5 {
+			subscription.NoLocal = options&optionNoLocal != 0
+			subscription.RetainAsPublished = options&optionRetainAsPublished != 0
+			subscription.RetainHandling = int(options&optionRetainHandling) >> retainHandlingShift
+		}
+		subscribe.Subscriptions = append(subscribe.Subscriptions, subscription)
+	}
+
+	if len(subscribe.Subscriptions) == 0 {
+		return nil, errMalformedPacket
+	}
+	return subscribe, nil
+}
+
+// isConnack tells a CONNACK apart, it's the first packet a broker sends
+func isConnack(p *packet) bool {
+	if p.packetType != packetConnack || len(p.body) < 2 {
+		return false
+	}
+	// only the session present flag may be set in the acknowledge flags
+	return p.body[0]&0xfe == 0
+}
@@ -0,0 +1,210 @@
+package mqtt
+
+import "fmt"
+
+const (
+	// the remaining length is a variable byte integer of at most 4 bytes
+	maxRemainingLength = 268435455
+	// the payload of a PUBLISH is kept up to this size, the rest is discarded
+	maxRetainedPayload = 64 * 1024
+	maxSubscriptions   = 256
+	maxProperties      = 256
+)
+
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetPubrec      = 5
+	packetPubrel      = 6
+	packetPubcomp     = 7
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetUnsubscribe = 10
+	packetUnsuback    = 11
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+	packetAuth        = 15
+)
+
+var packetTypes = map[byte]string{
+	packetConnect:     "CONNECT",
+	packetConnack:     "CONNACK",
+	packetPublish:     "PUBLISH",
+	packetPuback:      "PUBACK",
+	packetPubrec:      "PUBREC",
+	packetPubrel:      "PUBREL",
+	packetPubcomp:     "PUBCOMP",
+	packetSubscribe:   "SUBSCRIBE",
+	packetSuback:      "SUBACK",
+	packetUnsubscribe: "UNSUBSCRIBE",
+	packetUnsuback:    "UNSUBACK",
+	packetPingreq:     "PINGREQ",
+	packetPingresp:    "PINGRESP",
+	packetDisconnect:  "DISCONNECT",
+	packetAuth:        "AUTH",
+}
+
+// the protocol levels of the CONNECT packet
+const (
+	level31  = 3
+	level311 = 4
+	level5   = 5
+)
+
+var versions = map[byte]string{
+	level31:  "3.1",
+	level311: "3.1.1",
+	level5:   "5.0",
+}
+
+// CONNECT flags
+const (
+	flagUsername     = 0x80
+	flagPassword     = 0x40
+	flagWillRetain   = 0x20
+	flagWillQos      = 0x18
+	flagWill         = 0x04
+	flagCleanSession = 0x02
+
+	willQosShift = 3
+)
+
+// PUBLISH flags
+const (
+	flagDup    = 0x08
+	flagQos    = 0x06
+	flagRetain = 0x01
+
+	qosShift = 1
+)
+
+// subscription options
+const (
+	optionQos               = 0x03
+	optionNoLocal           = 0x04
+	optionRetainAsPublished = 0x08
+	optionRetainHandling    = 0x30
+
+	retainHandlingShift = 4
+)
+
+const (
+	propertyByte = iota
+	propertyUint16
+	propertyUint32
+	propertyVarint
+	propertyString
+	propertyBinary
+	propertyStringPair
+)
+
+type propertyDefinition struct {
+	name string
+	kind int
+}
+
+const (
+	propertyContentType = 0x03
+	propertyTopicAlias  = 0x23
+)
+
+// the properties of MQTT 5.0, by identifier
+var properties = map[byte]propertyDefinition{
+	0x01:                {"Payload Format Indicator", propertyByte},
+	0x02:                {"Message Expiry Interval", propertyUint32},
+	propertyContentType: {"Content Type", propertyString},
+	0x08:                {"Response Topic", propertyString},
+	0x09:                {"Correlation Data", propertyBinary},
+	0x0b:                {"Subscription Identifier", propertyVarint},
+	0x11:                {"Session Expiry Interval", propertyUint32},
+	0x12:                {"Assigned Client Identifier", propertyString},
+	0x13:                {"Server Keep Alive", propertyUint16},
+	0x15:                {"Authentication Method", propertyString},
+	0x16:                {"Authentication Data", propertyBinary},
+	0x17:                {"Request Problem Information", propertyByte},
+	0x18:                {"Will Delay Interval", propertyUint32},
+	0x19:                {"Request Response Information", propertyByte},
+	0x1a:                {"Response Information", propertyString},
+	0x1c:                {"Server Reference", propertyString},
+	0x1f:                {"Reason String", propertyString},
+	0x21:                {"Receive Maximum", propertyUint16},
+	0x22:                {"Topic Alias Maximum", propertyUint16},
+	propertyTopicAlias:  {"Topic Alias", propertyUint16},
+	0x24:                {"Maximum QoS", propertyByte},
+	0x25:                {"Retain Available", propertyByte},
+	0x26:                {"User Property", propertyStringPair},
+	0x27:                {"Maximum Packet Size", propertyUint32},
+	0x28:                {"Wildcard Subscription Available", propertyByte},
+	0x29:                {"Subscription Identifier Available", propertyByte},
+	0x2a:                {"Shared Subscription Available", propertyByte},
+}
+
+func packetTypeName(value byte) string {
+	if name, ok := packetTypes[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", value)
+}
+
+func versionName(level byte) string {
+	if name, ok := versions[level]; ok {
+		return name
+	}
+	return fmt.Sprintf("LEVEL%d", level)
+}
+
+// MqttProperty is a MQTT 5.0 property, the user properties keep their own key as the name
+type MqttProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type MqttWill struct {
+	Topic      string         `json:"topic"`
+	Qos        int            `json:"qos"`
+	Retain     bool           `json:"retain"`
+	Payload    []byte         `json:"payload"`
+	Properties []MqttProperty `json:"properties"`
+}
+
+// MqttConnect leaves the password out, only whether one was sent is kept
+type MqttConnect struct {
+	ProtocolName    string         `json:"protocolName"`
+	ProtocolVersion string         `json:"protocolVersion"`
+	ClientId        string         `json:"clientId"`
+	CleanSession    bool           `json:"cleanSession"`
+	KeepAlive       int            `json:"keepAlive"`
+	Username        string         `json:"username"`
+	HasPassword     bool           `json:"hasPassword"`
+	Will            *MqttWill      `json:"will"`
+	Properties      []MqttProperty `json:"properties"`
+}
+
+type MqttPublish struct {
+	Topic       string         `json:"topic"`
+	Qos         int            `json:"qos"`
+	Retain      bool           `json:"retain"`
+	Dup         bool           `json:"dup"`
+	PacketId    int            `json:"packetId"`
+	ContentType string         `json:"contentType"`
+	Size        int            `json:"size"`
+	Payload     []byte         `json:"payload"`
+	Properties  []MqttProperty `json:"properties"`
+}
+
+type MqttSubscription struct {
+	Topic             string `json:"topic"`
+	Qos               int    `json:"qos"`
+	NoLocal           bool   `json:"noLocal"`
+	RetainAsPublished bool   `json:"retainAsPublished"`
+	RetainHandling    int    `json:"retainHandling"`
+}
+
+type MqttSubscribe struct {
+	PacketId      int                `json:"packetId"`
+	Subscriptions []MqttSubscription `json:"subscriptions"`
+	Properties    []MqttProperty     `json:"properties"`
+}
@@ -0,0 +1,148 @@
+package mysql
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type MysqlPayload struct {
+	Data interface{}
+}
+
+type MysqlPayloader interface {
+	MarshalJSON() ([]byte, error)
+}
+
+func (h MysqlPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Data)
+}
+
+type MysqlWrapper struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Details interface{} `json:"details"`
+}
+
+func representRequest(request map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Command",
+			Value:    request["command"].(string),
+			Selector: `request.command`,
+		},
+	}
+	details = appendOptionalTableData(details, request, "Keyword", "keyword", `request.`)
+	if statementId, ok := request["statementId"].(float64); ok {
+		details = append(details, api.TableData{
+			Name:     "Statement Id",
+			Value:    fmt.Sprintf("%v", statementId),
+			Selector: `request.statementId`,
+		})
+	}
+	details = appendOptionalTableData(details, request, "User", "user", `request.`)
+	details = appendOptionalTableData(details, request, "Database", "database", `request.`)
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if query, ok := request["query"].(string); ok && query != "" {
+		representation = append(representation, api.SectionData{
+			Type:     api.BODY,
+			Title:    "Query",
+			Data:     query,
+			Selector: `request.query`,
+		})
+	}
+
+	return
+}
+
+func representResponse(response map[string]interface{}) (representation []interface{}) {
+	columns := make([]string, 0)
+	if values, ok := response["columns"].([]interface{}); ok {
+		for _, value := range values {
+			columns = append(columns, fmt.Sprintf("%v", value))
+		}
+	}
+
+	details := []api.TableData{
+		{
+			Name:     "Status",
+			Value:    response["status"].(string),
+			Selector: `response.status`,
+		},
+		{
+			Name:     "Rows",
+			Value:    fmt.Sprintf("%v", response["rows"]),
+			Selector: `response.rows`,
+		},
+		{
+			Name:     "Columns",
+			Value:    strings.Join(columns, ", "),
+			Selector: `response.columns`,
+		},
+		{
+			Name:     "Affected Rows",
+			Value:    fmt.Sprintf("%v", response["affectedRows"]),
+			Selector: `response.affectedRows`,
+		},
+		{
+			Name:     "Last Insert Id",
+			Value:    fmt.Sprintf("%v", response["lastInsertId"]),
+			Selector: `response.lastInsertId`,
+		},
+		{
+			Name:     "Warnings",
+			Value:    fmt.Sprintf("%v", response["warnings"]),
+			Selector: `response.warnings`,
+		},
+	}
+	details = appendOptionalTableData(details, response, "Info", "info", `response.`)
+	details = appendOptionalTableData(details, response, "Server Version", "serverVersion", `response.`)
+	details = appendOptionalTableData(details, response, "Authentication Plugin", "authPlugin", `response.`)
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if mysqlError, ok := response["error"].(map[string]interface{}); ok {
+		rows := []api.TableData{
+			{
+				Name:     "Code",
+				Value:    fmt.Sprintf("%v", mysqlError["code"]),
+				Selector: `response.error.code`,
+			},
+		}
+		rows = appendOptionalTableData(rows, mysqlError, "SQL State", "sqlState", `response.error.`)
+		rows = appendOptionalTableData(rows, mysqlError, "Message", "message", `response.error.`)
+		errorJson, _ := json.Marshal(rows)
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Error",
+			Data:  string(errorJson),
+		})
+	}
+
+	return
+}
+
+func appendOptionalTableData(rows []api.TableData, generic map[string]interface{}, name string, key string, selectorPrefix string) []api.TableData {
+	value, ok := generic[key].(string)
+	if !ok || value == "" {
+		return rows
+	}
+
+	return append(rows, api.TableData{
+		Name:     name,
+		Value:    value,
+		Selector: fmt.Sprintf("%s%s", selectorPrefix, key),
+	})
+}
@@ -0,0 +1,124 @@
+package mysql
+
+import (
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// Key is `{src_ip}_{dst_ip}_{src_ip}_{src_port}_{incremental_counter}`
+type requestResponseMatcher struct {
+	openMessagesMap *sync.Map
+	// the queries of the statements prepared on the connection, by statement id
+	statements     map[uint32]string
+	statementsLock sync.Mutex
+}
+
+func createResponseRequestMatcher() api.RequestResponseMatcher {
+	return &requestResponseMatcher{
+		openMessagesMap: &sync.Map{},
+		statements:      make(map[uint32]string),
+	}
+}
+
+func (matcher *requestResponseMatcher) GetMap() *sync.Map {
+	return matcher.openMessagesMap
+}
+func (matcher *requestResponseMatcher) SetMaxTry(value int) {
+}
+
+func (matcher *requestResponseMatcher) registerRequest(ident string, request *MysqlRequest, captureTime time.Time) *api.OutputChannelItem {
+	requestMysqlMessage := api.GenericMessage{
+		IsRequest:   true,
+		CaptureTime: captureTime,
+		Payload: MysqlPayload{
+			Data: &MysqlWrapper{
+				Method:  request.Command,
+				Url:     "",
+				Details: request,
+			},
+		},
+	}
+
+	if response, found := matcher.openMessagesMap.LoadAndDelete(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		responseMysqlMessage := response.(*api.GenericMessage)
+		if responseMysqlMessage.IsRequest {
+			return nil
+		}
+		return matcher.preparePair(&requestMysqlMessage, responseMysqlMessage)
+	}
+
+	matcher.openMessagesMap.Store(ident, &requestMysqlMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) registerResponse(ident string, response *MysqlResponse, captureTime time.Time) *api.OutputChannelItem {
+	responseMysqlMessage := api.GenericMessage{
+		IsRequest:   false,
+		CaptureTime: captureTime,
+		Payload: MysqlPayload{
+			Data: &MysqlWrapper{
+				Method:  response.Status,
+				Url:     "",
+				Details: response,
+			},
+		},
+	}
+
+	if request, found := matcher.openMessagesMap.LoadAndDelete(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		requestMysqlMessage := request.(*api.GenericMessage)
+		if !requestMysqlMessage.IsRequest {
+			return nil
+		}
+		return matcher.preparePair(requestMysqlMessage, &responseMysqlMessage)
+	}
+
+	matcher.openMessagesMap.Store(ident, &responseMysqlMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) preparePair(requestMysqlMessage *api.GenericMessage, responseMysqlMessage *api.GenericMessage) *api.OutputChannelItem {
+	request := requestMysqlMessage.Payload.(MysqlPayload).Data.(*MysqlWrapper).Details.(*MysqlRequest)
+	response := responseMysqlMessage.Payload.(MysqlPayload).Data.(*MysqlWrapper).Details.(*MysqlResponse)
+	matcher.resolveStatement(request, response)
+
+	return &api.OutputChannelItem{
+		Protocol:       protocol,
+		Timestamp:      requestMysqlMessage.CaptureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: nil,
+		Pair: &api.RequestResponsePair{
+			Request:  *requestMysqlMessage,
+			Response: *responseMysqlMessage,
+		},
+	}
+}
+
+// resolveStatement remembers the prepared statements and fills in the query of the commands referring to them,
+// the server answers in order so a statement is always prepared before its executions are paired
+func (matcher *requestResponseMatcher) resolveStatement(request *MysqlRequest, response *MysqlResponse) {
+	matcher.statementsLock.Lock()
+	defer matcher.statementsLock.Unlock()
+
+	if request.Command == commands[comStmtPrepare] {
+		if response.Status != StatusOk {
+			return
+		}
+		if _, found := matcher.statements[response.StatementId]; !found && len(matcher.statements) >= maxStatements {
+			// COM_STMT_CLOSE isn't answered so the closed statements are evicted only to make room
+			for statementId := range matcher.statements {
+				delete(matcher.statements, statementId)
+				break
+			}
+		}
+		matcher.statements[response.StatementId] = request.Query
+		return
+	}
+
+	if request.StatementId != 0 && request.Query == "" {
+		request.Query = matcher.statements[request.StatementId]
+		request.Keyword = getKeyword(request.Query)
+	}
+}
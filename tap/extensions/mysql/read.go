@@ -0,0 +1,750 @@
+package mysql
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+var errMalformedPacket = errors.New("malformed packet")
+
+type packet struct {
+	seq     byte
+	length  int
+	payload []byte
+}
+
+// readPacket reads a packet and its continuations, only the first maxRetained bytes of the payload are kept
+func readPacket(b *bufio.Reader, maxRetained int) (*packet, error) {
+	p := &packet{}
+	for first := true; ; first = false {
+		var header [4]byte
+		if _, err := io.ReadFull(b, header[:]); err != nil {
+			return nil, err
+		}
+		length := int(header[0]) | int(header[1])<<8 | int(header[2])<<16
+		if first {
+			p.seq = header[3]
+		}
+		p.length += length
+
+		retained := maxRetained - len(p.payload)
+		if retained > length {
+			retained = length
+		}
+		if retained > 0 {
+			chunk := make([]byte, retained)
+			if _, err := io.ReadFull(b, chunk); err != nil {
+				return nil, err
+			}
+			p.payload = append(p.payload, chunk...)
+		}
+		if _, err := b.Discard(length - retained); err != nil {
+			return nil, err
+		}
+
+		// a payload of exactly 2^24-1 bytes is continued by the next packet
+		if length < maxPacketLength {
+			return p, nil
+		}
+	}
+}
+
+// peekHeader returns the length and the sequence id of the next packet without consuming it
+func peekHeader(b *bufio.Reader) (int, byte, error) {
+	header, err := b.Peek(4)
+	if err != nil {
+		return 0, 0, err
+	}
+	return int(header[0]) | int(header[1])<<8 | int(header[2])<<16, header[3], nil
+}
+
+type payloadReader struct {
+	data   []byte
+	offset int
+}
+
+func (r *payloadReader) remaining() int {
+	return len(r.data) - r.offset
+}
+
+func (r *payloadReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.offset+n > len(r.data) {
+		return nil, errMalformedPacket
+	}
+	value := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return value, nil
+}
+
+func (r *payloadReader) byte() (byte, error) {
+	value, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return value[0], nil
+}
+
+func (r *payloadReader) uint16() (uint16, error) {
+	value, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint16(value), nil
+}
+
+func (r *payloadReader) uint32() (uint32, error) {
+	value, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint32(value), nil
+}
+
+func (r *payloadReader) cString() (string, error) {
+	end := bytes.IndexByte(r.data[r.offset:], 0)
+	if end < 0 {
+		return "", errMalformedPacket
+	}
+	value := string(r.data[r.offset : r.offset+end])
+	r.offset += end + 1
+	return value, nil
+}
+
+func (r *payloadReader) restString() string {
+	value := string(r.data[r.offset:])
+	r.offset = len(r.data)
+	return value
+}
+
+// lengthEncodedInt reads an integer prefixed by its size, 0xfb (NULL) is read as zero
+func (r *payloadReader) lengthEncodedInt() (uint64, error) {
+	first, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+
+	size := 0
+	switch first {
+	case 0xfb:
+		return 0, nil
+	case 0xfc:
+		size = 2
+	case 0xfd:
+		size = 3
+	case 0xfe:
+		size = 8
+	case 0xff:
+		return 0, errMalformedPacket
+	default:
+		return uint64(first), nil
+	}
+
+	value, err := r.bytes(size)
+	if err != nil {
+		return 0, err
+	}
+	var n uint64
+	for i := size - 1; i >= 0; i-- {
+		n = n<<8 | uint64(value[i])
+	}
+	return n, nil
+}
+
+func (r *payloadReader) lengthEncodedString() (string, error) {
+	length, err := r.lengthEncodedInt()
+	if err != nil {
+		return "", err
+	}
+	if length > uint64(r.remaining()) {
+		return "", errMalformedPacket
+	}
+	value, err := r.bytes(int(length))
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// clientReader turns the packets of the client into requests, one per command the server is expected to answer
+type clientReader struct {
+	reader         *bufio.Reader
+	allowMidStream bool
+	identified     bool
+}
+
+func newClientReader(reader *bufio.Reader, allowMidStream bool) *clientReader {
+	return &clientReader{
+		reader:         reader,
+		allowMidStream: allowMidStream,
+	}
+}
+
+func (c *clientReader) next() (*MysqlRequest, error) {
+	for {
+		if !c.identified {
+			length, seq, err := peekHeader(c.reader)
+			if err != nil {
+				return nil, err
+			}
+			// the client speaks first only on the connections picked up mid-stream, otherwise its first packet
+			// is the answer to the handshake of the server
+			if seq == 1 && length <= maxHandshakeLength {
+				p, err := readPacket(c.reader, maxHandshakeLength)
+				if err != nil {
+					return nil, err
+				}
+				request, err := readHandshakeResponse(p)
+				if err != nil {
+					return nil, err
+				}
+				c.identified = true
+				return request, nil
+			}
+			if !c.allowMidStream {
+				return nil, errors.New("not a handshake response")
+			}
+		}
+
+		p, err := readPacket(c.reader, maxRetainedLength)
+		if err != nil {
+			return nil, err
+		}
+
+		// the authentication exchange and the contents of LOCAL INFILE continue a sequence, commands start one
+		if p.seq != 0 {
+			continue
+		}
+		if len(p.payload) == 0 {
+			return nil, errMalformedPacket
+		}
+
+		command, ok := commands[p.payload[0]]
+		if !ok {
+			return nil, fmt.Errorf("unknown command 0x%02x", p.payload[0])
+		}
+		c.identified = true
+
+		request := &MysqlRequest{Command: command}
+		reader := &payloadReader{data: p.payload, offset: 1}
+		switch p.payload[0] {
+		case comQuit, comStmtClose, comStmtSendLongData:
+			// these are never answered
+			continue
+		case comQuery, comStmtPrepare:
+			request.Query = reader.restString()
+			request.Keyword = getKeyword(request.Query)
+		case comInitDb:
+			request.Database = reader.restString()
+		case comFieldList:
+			table, err := reader.cString()
+			if err != nil {
+				return nil, err
+			}
+			request.Query = table
+		case comStmtExecute, comStmtReset:
+			statementId, err := reader.uint32()
+			if err != nil {
+				return nil, err
+			}
+			request.StatementId = statementId
+		case comChangeUser:
+			user, err := reader.cString()
+			if err != nil {
+				return nil, err
+			}
+			request.User = user
+		}
+		return request, nil
+	}
+}
+
+func readHandshakeResponse(p *packet) (*MysqlRequest, error) {
+	reader := &payloadReader{data: p.payload}
+	capabilities, err := reader.uint32()
+	if err != nil {
+		return nil, err
+	}
+	if capabilities&clientProtocol41 == 0 {
+		return nil, errors.New("unsupported protocol version")
+	}
+	// max packet size and character set
+	if _, err := reader.bytes(5); err != nil {
+		return nil, err
+	}
+	filler, err := reader.bytes(23)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range filler {
+		if b != 0 {
+			return nil, errMalformedPacket
+		}
+	}
+
+	// the SSLRequest is the truncated handshake response, the rest of the connection is TLS
+	if reader.remaining() == 0 && capabilities&clientSsl != 0 {
+		return nil, errors.New("encrypted connection")
+	}
+
+	user, err := reader.cString()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case capabilities&clientPluginAuthLenencClientData != 0:
+		length, err := reader.lengthEncodedInt()
+		if err != nil {
+			return nil, err
+		}
+		if length > uint64(reader.remaining()) {
+			return nil, errMalformedPacket
+		}
+		_, err = reader.bytes(int(length))
+		if err != nil {
+			return nil, err
+		}
+	case capabilities&clientSecureConnection != 0:
+		length, err := reader.byte()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := reader.bytes(int(length)); err != nil {
+			return nil, err
+		}
+	default:
+		if _, err := reader.cString(); err != nil {
+			return nil, err
+		}
+	}
+
+	request := &MysqlRequest{
+		Command: CommandConnect,
+		User:    user,
+	}
+	if capabilities&clientConnectWithDb != 0 {
+		// the database is missing from the packets of some old clients despite the flag
+		if database, err := reader.cString(); err == nil {
+			request.Database = database
+		}
+	}
+	return request, nil
+}
+
+// getKeyword returns the first keyword of the query, skipping the leading comments
+func getKeyword(query string) string {
+	query = strings.TrimSpace(query)
+	for {
+		if strings.HasPrefix(query, "--") || strings.HasPrefix(query, "#") {
+			end := strings.IndexByte(query, '\n')
+			if end < 0 {
+				return ""
+			}
+			query = strings.TrimSpace(query[end+1:])
+		} else if strings.HasPrefix(query, "/*") {
+			end := strings.Index(query, "*/")
+			if end < 0 {
+				return ""
+			}
+			query = strings.TrimSpace(query[end+2:])
+		} else {
+			break
+		}
+	}
+
+	end := strings.IndexFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if end >= 0 {
+		query = query[:end]
+	}
+	return strings.ToUpper(query)
+}
+
+// serverReader turns the packets of the server into responses, one per answered command
+type serverReader struct {
+	reader         *bufio.Reader
+	allowMidStream bool
+	identified     bool
+}
+
+func newServerReader(reader *bufio.Reader, allowMidStream bool) *serverReader {
+	return &serverReader{
+		reader:         reader,
+		allowMidStream: allowMidStream,
+	}
+}
+
+func (s *serverReader) next() (*MysqlResponse, error) {
+	if s.identified {
+		p, err := readPacket(s.reader, maxRetainedLength)
+		if err != nil {
+			return nil, err
+		}
+		return s.readResult(p)
+	}
+
+	length, seq, err := peekHeader(s.reader)
+	if err != nil {
+		return nil, err
+	}
+	if seq == 0 && length <= maxHandshakeLength {
+		first, err := s.reader.Peek(5)
+		if err != nil {
+			return nil, err
+		}
+		if first[4] == protocolVersion10 {
+			response, err := s.readHandshake()
+			if err != nil {
+				return nil, err
+			}
+			s.identified = true
+			return response, nil
+		}
+	}
+	if !s.allowMidStream {
+		return nil, errors.New("not a handshake")
+	}
+
+	// every response starts a sequence with the id 1, the packets before it belong to a response that
+	// started before the tapping
+	for {
+		p, err := readPacket(s.reader, maxRetainedLength)
+		if err != nil {
+			return nil, err
+		}
+		if p.seq == 1 {
+			s.identified = true
+			return s.readResult(p)
+		}
+	}
+}
+
+func (s *serverReader) readHandshake() (*MysqlResponse, error) {
+	p, err := readPacket(s.reader, maxHandshakeLength)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := &payloadReader{data: p.payload, offset: 1}
+	serverVersion, err := reader.cString()
+	if err != nil {
+		return nil, err
+	}
+	connectionId, err := reader.uint32()
+	if err != nil {
+		return nil, err
+	}
+	response := &MysqlResponse{
+		Status:        StatusOk,
+		Columns:       make([]string, 0),
+		ServerVersion: serverVersion,
+		ConnectionId:  connectionId,
+	}
+
+	// auth plugin data, filler, capability flags, character set, status flags, upper capability flags,
+	// length of the auth plugin data and the reserved bytes
+	if header, err := reader.bytes(27); err == nil {
+		capabilities := uint32(binary.LittleEndian.Uint16(header[9:11])) | uint32(binary.LittleEndian.Uint16(header[14:16]))<<16
+		if capabilities&clientPluginAuth != 0 {
+			length := int(header[16]) - 8
+			if length < 13 {
+				length = 13
+			}
+			if _, err := reader.bytes(length); err == nil {
+				response.AuthPlugin, _ = reader.cString()
+			}
+		}
+	}
+
+	// the TLS handshake follows when the client asked for encryption
+	first, err := s.reader.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	if first[0] == tlsHandshakeRecordByte && first[1] == tlsMajorVersion {
+		return nil, errors.New("encrypted connection")
+	}
+
+	if err := s.readAuthentication(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// readAuthentication skips the exchange of the authentication method until its outcome
+func (s *serverReader) readAuthentication(response *MysqlResponse) error {
+	for {
+		p, err := readPacket(s.reader, maxRetainedLength)
+		if err != nil {
+			return err
+		}
+		if len(p.payload) == 0 {
+			return errMalformedPacket
+		}
+
+		switch p.payload[0] {
+		case okByte:
+			_, err := readOk(&payloadReader{data: p.payload, offset: 1}, response)
+			return err
+		case errByte:
+			return readErr(&payloadReader{data: p.payload, offset: 1}, response)
+		case eofByte:
+			if plugin, err := (&payloadReader{data: p.payload, offset: 1}).cString(); err == nil {
+				response.AuthPlugin = plugin
+			}
+		}
+	}
+}
+
+func (s *serverReader) readResult(p *packet) (*MysqlResponse, error) {
+	response := &MysqlResponse{
+		Status:  StatusOk,
+		Columns: make([]string, 0),
+	}
+
+	for {
+		if len(p.payload) == 0 {
+			return nil, errMalformedPacket
+		}
+
+		more := false
+		var err error
+		reader := &payloadReader{data: p.payload, offset: 1}
+		switch {
+		case p.payload[0] == okByte && p.length == prepareOkLength && p.payload[9] == 0:
+			err = s.readPrepareOk(reader, response)
+		case p.payload[0] == okByte:
+			more, err = readOk(reader, response)
+		case p.payload[0] == errByte:
+			err = readErr(reader, response)
+		case p.payload[0] == localInfileByte:
+			// the client sends the file and the outcome of the command follows
+			more = true
+		case p.payload[0] == eofByte && p.length < maxEofLength:
+			more, err = readEof(reader)
+		case p.payload[0] == eofByte:
+			if plugin, err := reader.cString(); err == nil {
+				response.AuthPlugin = plugin
+			}
+			err = s.readAuthentication(response)
+		case bytes.HasPrefix(p.payload, columnDefinitionPrefix):
+			// COM_FIELD_LIST is answered with the column definitions only
+			if err = addColumn(p, response); err == nil {
+				_, err = s.readColumns(-1, response)
+			}
+		default:
+			reader.offset = 0
+			columnCount, countErr := reader.lengthEncodedInt()
+			if countErr == nil && reader.remaining() <= 1 && columnCount > 0 && columnCount <= maxColumns {
+				more, err = s.readResultSet(int(columnCount), response)
+			} else {
+				response.Info = string(p.payload)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !more {
+			return response, nil
+		}
+
+		p, err = readPacket(s.reader, maxRetainedLength)
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (s *serverReader) readPrepareOk(reader *payloadReader, response *MysqlResponse) error {
+	statementId, err := reader.uint32()
+	if err != nil {
+		return err
+	}
+	columnCount, err := reader.uint16()
+	if err != nil {
+		return err
+	}
+	parameterCount, err := reader.uint16()
+	if err != nil {
+		return err
+	}
+	if _, err := reader.byte(); err != nil {
+		return err
+	}
+	warnings, err := reader.uint16()
+	if err != nil {
+		return err
+	}
+	response.StatementId = statementId
+	response.Warnings += int(warnings)
+
+	parameters := &MysqlResponse{}
+	if _, err := s.readColumns(int(parameterCount), parameters); err != nil {
+		return err
+	}
+	_, err = s.readColumns(int(columnCount), response)
+	return err
+}
+
+// readColumns reads the column definitions, count is -1 when the definitions are terminated by an EOF packet
+func (s *serverReader) readColumns(count int, response *MysqlResponse) (bool, error) {
+	for i := 0; count < 0 || i < count; i++ {
+		p, err := readPacket(s.reader, maxRetainedLength)
+		if err != nil {
+			return false, err
+		}
+		if count < 0 && len(p.payload) > 0 && p.payload[0] == eofByte && p.length < maxEofLength {
+			return readEof(&payloadReader{data: p.payload, offset: 1})
+		}
+		if err := addColumn(p, response); err != nil {
+			return false, err
+		}
+	}
+	if count == 0 {
+		return false, nil
+	}
+
+	// the definitions are followed by an EOF packet unless the client set CLIENT_DEPRECATE_EOF
+	length, _, err := peekHeader(s.reader)
+	if err != nil {
+		return false, err
+	}
+	if length == legacyEofLength {
+		first, err := s.reader.Peek(5)
+		if err != nil {
+			return false, err
+		}
+		if first[4] == eofByte {
+			p, err := readPacket(s.reader, legacyEofLength)
+			if err != nil {
+				return false, err
+			}
+			return readEof(&payloadReader{data: p.payload, offset: 1})
+		}
+	}
+	return false, nil
+}
+
+func addColumn(p *packet, response *MysqlResponse) error {
+	reader := &payloadReader{data: p.payload}
+	// catalog, schema, table and original table precede the name
+	for i := 0; i < 4; i++ {
+		if _, err := reader.lengthEncodedString(); err != nil {
+			return err
+		}
+	}
+	name, err := reader.lengthEncodedString()
+	if err != nil {
+		return err
+	}
+	response.Columns = append(response.Columns, name)
+	return nil
+}
+
+func (s *serverReader) readResultSet(columnCount int, response *MysqlResponse) (bool, error) {
+	if _, err := s.readColumns(columnCount, response); err != nil {
+		return false, err
+	}
+
+	for {
+		p, err := readPacket(s.reader, maxRowRetainedLength)
+		if err != nil {
+			return false, err
+		}
+		if len(p.payload) == 0 {
+			return false, errMalformedPacket
+		}
+
+		// a row starting with 0xfe holds a value of at least 2^24 bytes, so it's always continued
+		switch {
+		case p.payload[0] == eofByte && p.length < maxPacketLength:
+			reader := &payloadReader{data: p.payload, offset: 1}
+			if p.length < maxEofLength {
+				return readEof(reader)
+			}
+			return readOk(reader, response)
+		case p.payload[0] == errByte:
+			return false, readErr(&payloadReader{data: p.payload, offset: 1}, response)
+		default:
+			response.Rows++
+		}
+	}
+}
+
+// readOk reads an OK packet and returns whether more results follow
+func readOk(reader *payloadReader, response *MysqlResponse) (bool, error) {
+	affectedRows, err := reader.lengthEncodedInt()
+	if err != nil {
+		return false, err
+	}
+	lastInsertId, err := reader.lengthEncodedInt()
+	if err != nil {
+		return false, err
+	}
+	response.AffectedRows += affectedRows
+	if lastInsertId != 0 {
+		response.LastInsertId = lastInsertId
+	}
+
+	status, err := reader.uint16()
+	if err != nil {
+		// the status is missing only from the OK packets of the pre-4.1 protocol
+		return false, nil
+	}
+	warnings, err := reader.uint16()
+	if err != nil {
+		return false, err
+	}
+	response.Warnings += int(warnings)
+
+	if info, err := reader.lengthEncodedString(); err == nil && info != "" {
+		response.Info = info
+	}
+	return status&serverMoreResultsExists != 0, nil
+}
+
+// readEof reads an EOF packet and returns whether more results follow
+func readEof(reader *payloadReader) (bool, error) {
+	if reader.remaining() < 4 {
+		return false, nil
+	}
+	if _, err := reader.uint16(); err != nil {
+		return false, err
+	}
+	status, err := reader.uint16()
+	if err != nil {
+		return false, err
+	}
+	return status&serverMoreResultsExists != 0, nil
+}
+
+func readErr(reader *payloadReader, response *MysqlResponse) error {
+	code, err := reader.uint16()
+	if err != nil {
+		return err
+	}
+	mysqlError := &MysqlError{Code: code}
+	if first, err := reader.bytes(1); err == nil {
+		if first[0] == '#' {
+			sqlState, err := reader.bytes(5)
+			if err != nil {
+				return err
+			}
+			mysqlError.SqlState = string(sqlState)
+		} else {
+			reader.offset--
+		}
+	}
+	mysqlError.Message = reader.restString()
+
+	response.Status = StatusError
+	response.Error = mysqlError
+	return nil
+}
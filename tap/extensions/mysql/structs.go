@@ -0,0 +1,116 @@
+package mysql
+
+const (
+	maxPacketLength   = 0xffffff
+	maxRetainedLength = 64 * 1024
+	// the rows aren't kept, only their first bytes are needed to recognize the packet ending the result set
+	maxRowRetainedLength = 32
+	maxHandshakeLength   = 8 * 1024
+	maxColumns           = 4096
+	maxStatements        = 1000
+
+	protocolVersion10 = 0x0a
+	prepareOkLength   = 12
+
+	tlsHandshakeRecordByte = 0x16
+	tlsMajorVersion        = 0x03
+
+	okByte          = 0x00
+	localInfileByte = 0xfb
+	eofByte         = 0xfe
+	errByte         = 0xff
+
+	// an EOF packet is always shorter than this, a longer packet starting with 0xfe is an auth switch request
+	maxEofLength = 9
+	// the EOF packet following the column definitions unless the client set CLIENT_DEPRECATE_EOF
+	legacyEofLength = 5
+
+	StatusOk    = "OK"
+	StatusError = "ERROR"
+)
+
+// capability flags
+const (
+	clientConnectWithDb              = 0x00000008
+	clientProtocol41                 = 0x00000200
+	clientSsl                        = 0x00000800
+	clientSecureConnection           = 0x00008000
+	clientPluginAuth                 = 0x00080000
+	clientPluginAuthLenencClientData = 0x00200000
+)
+
+// status flags
+const (
+	serverMoreResultsExists = 0x0008
+)
+
+const (
+	comQuit             = 0x01
+	comInitDb           = 0x02
+	comQuery            = 0x03
+	comFieldList        = 0x04
+	comStatistics       = 0x09
+	comProcessKill      = 0x0c
+	comPing             = 0x0e
+	comChangeUser       = 0x11
+	comStmtPrepare      = 0x16
+	comStmtExecute      = 0x17
+	comStmtSendLongData = 0x18
+	comStmtClose        = 0x19
+	comStmtReset        = 0x1a
+	comSetOption        = 0x1b
+	comResetConnection  = 0x1f
+)
+
+var commands = map[byte]string{
+	comQuit:             "COM_QUIT",
+	comInitDb:           "COM_INIT_DB",
+	comQuery:            "COM_QUERY",
+	comFieldList:        "COM_FIELD_LIST",
+	comStatistics:       "COM_STATISTICS",
+	comProcessKill:      "COM_PROCESS_KILL",
+	comPing:             "COM_PING",
+	comChangeUser:       "COM_CHANGE_USER",
+	comStmtPrepare:      "COM_STMT_PREPARE",
+	comStmtExecute:      "COM_STMT_EXECUTE",
+	comStmtSendLongData: "COM_STMT_SEND_LONG_DATA",
+	comStmtClose:        "COM_STMT_CLOSE",
+	comStmtReset:        "COM_STMT_RESET",
+	comSetOption:        "COM_SET_OPTION",
+	comResetConnection:  "COM_RESET_CONNECTION",
+}
+
+const CommandConnect = "CONNECT"
+
+// every column definition starts with the catalog, which is always "def"
+var columnDefinitionPrefix = []byte("\x03def")
+
+type MysqlRequest struct {
+	Command     string `json:"command"`
+	Keyword     string `json:"keyword"`
+	Query       string `json:"query"`
+	StatementId uint32 `json:"statementId,omitempty"`
+	User        string `json:"user,omitempty"`
+	Database    string `json:"database,omitempty"`
+}
+
+type MysqlResponse struct {
+	Status        string      `json:"status"`
+	AffectedRows  uint64      `json:"affectedRows"`
+	LastInsertId  uint64      `json:"lastInsertId"`
+	Warnings      int         `json:"warnings"`
+	Rows          int         `json:"rows"`
+	Columns       []string    `json:"columns"`
+	StatementId   uint32      `json:"statementId,omitempty"`
+	Info          string      `json:"info,omitempty"`
+	ServerVersion string      `json:"serverVersion,omitempty"`
+	AuthPlugin    string      `json:"authPlugin,omitempty"`
+	ConnectionId  uint32      `json:"connectionId,omitempty"`
+	Error         *MysqlError `json:"error,omitempty"`
+}
+
+type MysqlError struct {
+	Code     uint16 `json:"code"`
+	SqlState string `json:"sqlState,omitempty"`
+	Message  string `json:"message"`
+}
@@ -0,0 +1,175 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type PostgresPayload struct {
+	Data interface{}
+}
+
+type PostgresPayloader interface {
+	MarshalJSON() ([]byte, error)
+}
+
+func (h PostgresPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Data)
+}
+
+type PostgresWrapper struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Details interface{} `json:"details"`
+}
+
+func representRequest(request map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Type",
+			Value:    request["type"].(string),
+			Selector: `request.type`,
+		},
+		{
+			Name:     "Command",
+			Value:    request["command"].(string),
+			Selector: `request.command`,
+		},
+	}
+	details = appendOptionalTableData(details, request, "Statement", "statement", `request.`)
+	details = appendOptionalTableData(details, request, "Portal", "portal", `request.`)
+	details = appendOptionalTableData(details, request, "User", "user", `request.`)
+	details = appendOptionalTableData(details, request, "Database", "database", `request.`)
+	details = appendOptionalTableData(details, request, "Application", "application", `request.`)
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if query, ok := request["query"].(string); ok && query != "" {
+		representation = append(representation, api.SectionData{
+			Type:     api.BODY,
+			Title:    "Query",
+			Data:     query,
+			Selector: `request.query`,
+		})
+	}
+
+	if parameters, ok := request["parameters"].([]interface{}); ok && len(parameters) > 0 {
+		rows := make([]api.TableData, 0, len(parameters))
+		for i, parameter := range parameters {
+			value := "NULL"
+			if parameter != nil {
+				value = fmt.Sprintf("%v", parameter)
+			}
+			rows = append(rows, api.TableData{
+				Name:     fmt.Sprintf("$%d", i+1),
+				Value:    value,
+				Selector: fmt.Sprintf(`request.parameters[%d]`, i),
+			})
+		}
+		parametersJson, _ := json.Marshal(rows)
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Parameters",
+			Data:  string(parametersJson),
+		})
+	}
+
+	return
+}
+
+func representResponse(response map[string]interface{}) (representation []interface{}) {
+	columns := make([]string, 0)
+	if values, ok := response["columns"].([]interface{}); ok {
+		for _, value := range values {
+			columns = append(columns, fmt.Sprintf("%v", value))
+		}
+	}
+
+	details := []api.TableData{
+		{
+			Name:     "Status",
+			Value:    response["status"].(string),
+			Selector: `response.status`,
+		},
+		{
+			Name:     "Command Tag",
+			Value:    response["commandTag"].(string),
+			Selector: `response.commandTag`,
+		},
+		{
+			Name:     "Rows",
+			Value:    fmt.Sprintf("%v", response["rows"]),
+			Selector: `response.rows`,
+		},
+		{
+			Name:     "Columns",
+			Value:    strings.Join(columns, ", "),
+			Selector: `response.columns`,
+		},
+	}
+	details = appendOptionalTableData(details, response, "Authentication", "authentication", `response.`)
+	detailsJson, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(detailsJson),
+	})
+
+	if postgresError, ok := response["error"].(map[string]interface{}); ok {
+		rows := make([]api.TableData, 0)
+		rows = appendOptionalTableData(rows, postgresError, "Severity", "severity", `response.error.`)
+		rows = appendOptionalTableData(rows, postgresError, "Code", "code", `response.error.`)
+		rows = appendOptionalTableData(rows, postgresError, "Message", "message", `response.error.`)
+		rows = appendOptionalTableData(rows, postgresError, "Detail", "detail", `response.error.`)
+		rows = appendOptionalTableData(rows, postgresError, "Hint", "hint", `response.error.`)
+		errorJson, _ := json.Marshal(rows)
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Error",
+			Data:  string(errorJson),
+		})
+	}
+
+	if parameters, ok := response["parameters"].(map[string]interface{}); ok && len(parameters) > 0 {
+		rows := make([]api.TableData, 0, len(parameters))
+		for name, value := range parameters {
+			rows = append(rows, api.TableData{
+				Name:     name,
+				Value:    value,
+				Selector: fmt.Sprintf(`response.parameters["%s"]`, name),
+			})
+		}
+		sort.Slice(rows, func(i, j int) bool {
+			return rows[i].Name < rows[j].Name
+		})
+		parametersJson, _ := json.Marshal(rows)
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Parameters",
+			Data:  string(parametersJson),
+		})
+	}
+
+	return
+}
+
+func appendOptionalTableData(rows []api.TableData, generic map[string]interface{}, name string, key string, selectorPrefix string) []api.TableData {
+	value, ok := generic[key].(string)
+	if !ok || value == "" {
+		return rows
+	}
+
+	return append(rows, api.TableData{
+		Name:     name,
+		Value:    value,
+		Selector: fmt.Sprintf("%s%s", selectorPrefix, key),
+	})
+}
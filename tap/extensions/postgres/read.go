@@ -0,0 +1,602 @@
+package postgres
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const maxPreparedStatements = 1000
+
+var errMalformedMessage = errors.New("malformed message")
+
+type message struct {
+	kind      byte
+	payload   []byte
+	truncated bool
+}
+
+// readMessage reads a typed message, the payloads of the kinds in skip aren't needed (e.g. the rows) so they're discarded
+// and only the first maxRetainedLength bytes of the other payloads are kept
+func readMessage(b *bufio.Reader, kinds string, skip string) (*message, error) {
+	kind, err := b.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if strings.IndexByte(kinds, kind) < 0 {
+		return nil, fmt.Errorf("unknown message type %q", kind)
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(b, header[:]); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header[:])
+	if length < 4 || length > maxMessageLength {
+		return nil, fmt.Errorf("invalid length %d of message type %q", length, kind)
+	}
+
+	size := int(length - 4)
+	if strings.IndexByte(skip, kind) >= 0 {
+		if _, err := b.Discard(size); err != nil {
+			return nil, err
+		}
+		return &message{kind: kind}, nil
+	}
+
+	retained := size
+	if retained > maxRetainedLength {
+		retained = maxRetainedLength
+	}
+	payload := make([]byte, retained)
+	if _, err := io.ReadFull(b, payload); err != nil {
+		return nil, err
+	}
+	if _, err := b.Discard(size - retained); err != nil {
+		return nil, err
+	}
+	return &message{kind: kind, payload: payload, truncated: retained < size}, nil
+}
+
+// readStartupMessage reads an untyped message of the frontend, the parameters are returned only for a StartupMessage
+func readStartupMessage(b *bufio.Reader) (uint32, map[string]string, error) {
+	var header [8]byte
+	if _, err := io.ReadFull(b, header[:]); err != nil {
+		return 0, nil, err
+	}
+	length := binary.BigEndian.Uint32(header[0:4])
+	code := binary.BigEndian.Uint32(header[4:8])
+	if length < 8 || length > maxStartupLength {
+		return 0, nil, fmt.Errorf("invalid startup message length %d", length)
+	}
+
+	payload := make([]byte, length-8)
+	if _, err := io.ReadFull(b, payload); err != nil {
+		return 0, nil, err
+	}
+
+	switch code {
+	case protocolVersion3:
+		parameters := make(map[string]string)
+		reader := &payloadReader{data: payload}
+		for {
+			name, err := reader.cString()
+			if err != nil {
+				return 0, nil, err
+			}
+			if name == "" {
+				return code, parameters, nil
+			}
+			value, err := reader.cString()
+			if err != nil {
+				return 0, nil, err
+			}
+			parameters[name] = value
+		}
+	case sslRequestCode, gssEncRequestCode, cancelRequestCode:
+		return code, nil, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported protocol version %d", code)
+	}
+}
+
+// payloadReader reads the fields of a payload, the last string of a truncated payload is read up to its end
+type payloadReader struct {
+	data      []byte
+	offset    int
+	truncated bool
+}
+
+func newPayloadReader(msg *message) *payloadReader {
+	return &payloadReader{data: msg.payload, truncated: msg.truncated}
+}
+
+func (r *payloadReader) cString() (string, error) {
+	end := bytes.IndexByte(r.data[r.offset:], 0)
+	if end < 0 {
+		if !r.truncated {
+			return "", errMalformedMessage
+		}
+		value := string(r.data[r.offset:])
+		r.offset = len(r.data)
+		return value, nil
+	}
+	value := string(r.data[r.offset : r.offset+end])
+	r.offset += end + 1
+	return value, nil
+}
+
+func (r *payloadReader) bytes(n int) ([]byte, error) {
+	if n < 0 || r.offset+n > len(r.data) {
+		return nil, errMalformedMessage
+	}
+	value := r.data[r.offset : r.offset+n]
+	r.offset += n
+	return value, nil
+}
+
+func (r *payloadReader) byte() (byte, error) {
+	value, err := r.bytes(1)
+	if err != nil {
+		return 0, err
+	}
+	return value[0], nil
+}
+
+func (r *payloadReader) int16() (int16, error) {
+	value, err := r.bytes(2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(value)), nil
+}
+
+func (r *payloadReader) int32() (int32, error) {
+	value, err := r.bytes(4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(value)), nil
+}
+
+// frontendReader groups the messages of the client into requests, one per ReadyForQuery the server is expected
+// to answer with
+type frontendReader struct {
+	reader         *bufio.Reader
+	allowMidStream bool
+	identified     bool
+	statements     map[string]string
+	pending        *PostgresRequest
+}
+
+func newFrontendReader(reader *bufio.Reader, allowMidStream bool) *frontendReader {
+	return &frontendReader{
+		reader:         reader,
+		allowMidStream: allowMidStream,
+		statements:     make(map[string]string),
+	}
+}
+
+func (f *frontendReader) next() (*PostgresRequest, error) {
+	for {
+		first, err := f.reader.Peek(1)
+		if err != nil {
+			return nil, err
+		}
+
+		// the untyped startup messages begin with their length, which is far below 2^24
+		if first[0] == 0 {
+			code, parameters, err := readStartupMessage(f.reader)
+			if err != nil {
+				return nil, err
+			}
+			f.identified = true
+
+			if code == protocolVersion3 {
+				return &PostgresRequest{
+					Type:        TypeStartup,
+					Command:     "STARTUP",
+					Parameters:  []interface{}{},
+					User:        parameters["user"],
+					Database:    parameters["database"],
+					Application: parameters["application_name"],
+				}, nil
+			}
+			// SSLRequest and GSSENCRequest are answered with a single byte, the StartupMessage follows unless
+			// the encryption was accepted, CancelRequest is the only message of its connection
+			continue
+		}
+
+		if !f.identified && !f.allowMidStream {
+			return nil, errors.New("not a startup message")
+		}
+
+		msg, err := readMessage(f.reader, frontendMessageTypes, string(copyDataByte))
+		if err != nil {
+			return nil, err
+		}
+		f.identified = true
+
+		switch msg.kind {
+		case queryByte:
+			query, err := newPayloadReader(msg).cString()
+			if err != nil {
+				return nil, err
+			}
+			return &PostgresRequest{
+				Type:       TypeSimpleQuery,
+				Command:    getCommand(query),
+				Query:      query,
+				Parameters: []interface{}{},
+			}, nil
+		case parseByte:
+			if err := f.handleParse(newPayloadReader(msg)); err != nil {
+				return nil, err
+			}
+		case bindByte:
+			if err := f.handleBind(newPayloadReader(msg)); err != nil {
+				return nil, err
+			}
+		case describeByte, executeByte, closeByte:
+			f.extended()
+		case syncByte:
+			// every Sync is answered with a ReadyForQuery, even when nothing preceded it
+			request := f.extended()
+			f.pending = nil
+			return request, nil
+		case functionCallByte:
+			return &PostgresRequest{
+				Type:       TypeFunctionCall,
+				Command:    "FUNCTION CALL",
+				Parameters: []interface{}{},
+			}, nil
+		}
+	}
+}
+
+func (f *frontendReader) extended() *PostgresRequest {
+	if f.pending == nil {
+		f.pending = &PostgresRequest{
+			Type:       TypeExtended,
+			Parameters: []interface{}{},
+		}
+	}
+	return f.pending
+}
+
+func (f *frontendReader) handleParse(reader *payloadReader) error {
+	name, err := reader.cString()
+	if err != nil {
+		return err
+	}
+	query, err := reader.cString()
+	if err != nil {
+		return err
+	}
+
+	// the unnamed statement is replaced by every Parse, the named ones are kept up to a limit
+	if _, ok := f.statements[name]; ok || len(f.statements) < maxPreparedStatements {
+		f.statements[name] = query
+	}
+
+	request := f.extended()
+	request.Statement = name
+	request.Query = query
+	request.Command = getCommand(query)
+	return nil
+}
+
+func (f *frontendReader) handleBind(reader *payloadReader) error {
+	portal, err := reader.cString()
+	if err != nil {
+		return err
+	}
+	statement, err := reader.cString()
+	if err != nil {
+		return err
+	}
+
+	formatCount, err := reader.int16()
+	if err != nil {
+		return err
+	}
+	formats := make([]int16, 0, formatCount)
+	for i := 0; i < int(formatCount); i++ {
+		format, err := reader.int16()
+		if err != nil {
+			return err
+		}
+		formats = append(formats, format)
+	}
+
+	parameterCount, err := reader.int16()
+	if err != nil {
+		return err
+	}
+	parameters := make([]interface{}, 0, parameterCount)
+	for i := 0; i < int(parameterCount); i++ {
+		length, err := reader.int32()
+		if err != nil {
+			if reader.truncated {
+				// the parameters past the retained prefix of the message are left out
+				break
+			}
+			return err
+		}
+		if length < 0 {
+			parameters = append(parameters, nil)
+			continue
+		}
+		value, err := reader.bytes(int(length))
+		if err != nil {
+			if reader.truncated {
+				break
+			}
+			return err
+		}
+		parameters = append(parameters, representParameter(value, getParameterFormat(formats, i)))
+	}
+
+	request := f.extended()
+	request.Portal = portal
+	request.Statement = statement
+	request.Parameters = parameters
+	if request.Query == "" {
+		request.Query = f.statements[statement]
+		request.Command = getCommand(request.Query)
+	}
+	return nil
+}
+
+// getParameterFormat follows the Bind rules: no codes means text, a single code applies to all the parameters
+func getParameterFormat(formats []int16, index int) int16 {
+	switch len(formats) {
+	case 0:
+		return 0
+	case 1:
+		return formats[0]
+	default:
+		if index < len(formats) {
+			return formats[index]
+		}
+		return 0
+	}
+}
+
+func representParameter(value []byte, format int16) string {
+	truncated := ""
+	if len(value) > maxParameterSize {
+		value = value[:maxParameterSize]
+		truncated = "..."
+	}
+
+	if format == 0 {
+		return string(value) + truncated
+	}
+	return `\x` + hex.EncodeToString(value) + truncated
+}
+
+// getCommand returns the first keyword of the query, skipping the leading comments
+func getCommand(query string) string {
+	query = strings.TrimSpace(query)
+	for {
+		if strings.HasPrefix(query, "--") {
+			end := strings.IndexByte(query, '\n')
+			if end < 0 {
+				return ""
+			}
+			query = strings.TrimSpace(query[end+1:])
+		} else if strings.HasPrefix(query, "/*") {
+			end := strings.Index(query, "*/")
+			if end < 0 {
+				return ""
+			}
+			query = strings.TrimSpace(query[end+2:])
+		} else {
+			break
+		}
+	}
+
+	end := strings.IndexFunc(query, func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if end >= 0 {
+		query = query[:end]
+	}
+	return strings.ToUpper(query)
+}
+
+// backendReader groups the messages of the server into responses, each ending with a ReadyForQuery
+type backendReader struct {
+	reader         *bufio.Reader
+	allowMidStream bool
+	identified     bool
+	dataRows       int
+	pending        *PostgresResponse
+}
+
+func newBackendReader(reader *bufio.Reader, allowMidStream bool) *backendReader {
+	return &backendReader{
+		reader:         reader,
+		allowMidStream: allowMidStream,
+	}
+}
+
+func (r *backendReader) next() (*PostgresResponse, error) {
+	for {
+		if !r.identified {
+			first, err := r.reader.Peek(2)
+			if err != nil {
+				return nil, err
+			}
+			if first[0] == sslAcceptedByte && first[1] == tlsHandshakeRecordByte {
+				return nil, errors.New("encrypted connection")
+			}
+			if first[0] == sslRejectedByte && (first[1] == authenticationByte || first[1] == errorResponseByte) {
+				if _, err := r.reader.Discard(1); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if first[0] != authenticationByte && first[0] != errorResponseByte && !r.allowMidStream {
+				return nil, errors.New("not a startup response")
+			}
+		}
+
+		msg, err := readMessage(r.reader, backendMessageTypes, string([]byte{dataRowByte, copyDataByte}))
+		if err != nil {
+			// a fatal error closes the connection without a ReadyForQuery
+			if errors.Is(err, io.EOF) && r.pending != nil && r.pending.Error != nil {
+				response := r.pending
+				r.pending = nil
+				return response, nil
+			}
+			return nil, err
+		}
+		r.identified = true
+
+		if r.pending == nil {
+			r.pending = &PostgresResponse{
+				Status:  StatusOk,
+				Columns: []string{},
+			}
+			r.dataRows = 0
+		}
+
+		if err := r.handleMessage(msg); err != nil {
+			return nil, err
+		}
+
+		if msg.kind == readyForQueryByte {
+			response := r.pending
+			r.pending = nil
+			return response, nil
+		}
+	}
+}
+
+func (r *backendReader) handleMessage(msg *message) error {
+	response := r.pending
+	reader := newPayloadReader(msg)
+
+	switch msg.kind {
+	case authenticationByte:
+		code, err := reader.int32()
+		if err != nil {
+			return err
+		}
+		if method, ok := authenticationMethods[uint32(code)]; ok && (code != authenticationOk || response.Authentication == "") {
+			response.Authentication = method
+		}
+	case parameterStatusByte:
+		name, err := reader.cString()
+		if err != nil {
+			return err
+		}
+		value, err := reader.cString()
+		if err != nil {
+			return err
+		}
+		if response.Parameters == nil {
+			response.Parameters = make(map[string]string)
+		}
+		response.Parameters[name] = value
+	case rowDescriptionByte:
+		count, err := reader.int16()
+		if err != nil {
+			return err
+		}
+		columns := make([]string, 0, count)
+		for i := 0; i < int(count); i++ {
+			name, err := reader.cString()
+			if err != nil {
+				return err
+			}
+			// table oid, column number, type oid, type size, type modifier and format code
+			if _, err := reader.bytes(18); err != nil {
+				if reader.truncated {
+					break
+				}
+				return err
+			}
+			columns = append(columns, name)
+		}
+		response.Columns = columns
+	case dataRowByte:
+		r.dataRows++
+	case commandCompleteByte:
+		tag, err := reader.cString()
+		if err != nil {
+			return err
+		}
+		response.CommandTag = tag
+		response.Rows += getRowCount(tag, r.dataRows)
+		r.dataRows = 0
+	case errorResponseByte:
+		postgresError, err := readError(reader)
+		if err != nil {
+			return err
+		}
+		response.Status = StatusError
+		response.Error = postgresError
+	}
+
+	return nil
+}
+
+// getRowCount returns the count the command tag ends with (e.g. "INSERT 0 5" or "SELECT 5"), the tags without
+// one (e.g. "SHOW") fall back to the counted rows
+func getRowCount(tag string, dataRows int) int {
+	fields := strings.Fields(tag)
+	if len(fields) > 1 {
+		if count, err := strconv.Atoi(fields[len(fields)-1]); err == nil {
+			return count
+		}
+	}
+	return dataRows
+}
+
+func readError(reader *payloadReader) (*PostgresError, error) {
+	postgresError := &PostgresError{}
+	for {
+		field, err := reader.byte()
+		if err != nil {
+			if reader.truncated {
+				return postgresError, nil
+			}
+			return nil, err
+		}
+		if field == 0 {
+			return postgresError, nil
+		}
+		value, err := reader.cString()
+		if err != nil {
+			return nil, err
+		}
+
+		switch field {
+		case 'V':
+			postgresError.Severity = value
+		case 'S':
+			// the localized severity, the non-localized one is sent only by 9.6+ servers
+			if postgresError.Severity == "" {
+				postgresError.Severity = value
+			}
+		case 'C':
+			postgresError.Code = value
+		case 'M':
+			postgresError.Message = value
+		case 'D':
+			postgresError.Detail = value
+		case 'H':
+			postgresError.Hint = value
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package postgres
+
+const (
+	protocolVersion3  = 196608
+	sslRequestCode    = 80877103
+	gssEncRequestCode = 80877104
+	cancelRequestCode = 80877102
+
+	maxStartupLength  = 10000
+	maxMessageLength  = 1 << 30
+	maxRetainedLength = 64 * 1024
+	maxParameterSize  = 1024
+)
+
+const (
+	TypeStartup      = "Startup"
+	TypeSimpleQuery  = "Simple Query"
+	TypeExtended     = "Extended Query"
+	TypeFunctionCall = "Function Call"
+
+	StatusOk    = "OK"
+	StatusError = "ERROR"
+)
+
+// frontend message types, the startup messages have no type byte
+const (
+	bindByte             = 'B'
+	closeByte            = 'C'
+	copyDataByte         = 'd'
+	describeByte         = 'D'
+	executeByte          = 'E'
+	functionCallByte     = 'F'
+	parseByte            = 'P'
+	queryByte            = 'Q'
+	syncByte             = 'S'
+	frontendMessageTypes = "BCdcfDEHFPpQSX"
+)
+
+// backend message types
+const (
+	authenticationByte     = 'R'
+	commandCompleteByte    = 'C'
+	dataRowByte            = 'D'
+	errorResponseByte      = 'E'
+	parameterStatusByte    = 'S'
+	readyForQueryByte      = 'Z'
+	rowDescriptionByte     = 'T'
+	backendMessageTypes    = "RK123ACcdDEGHIWnNstSTvVZ"
+	sslAcceptedByte        = 'S'
+	sslRejectedByte        = 'N'
+	tlsHandshakeRecordByte = 0x16
+)
+
+const (
+	authenticationOk           = 0
+	authenticationCleartext    = 3
+	authenticationMD5          = 5
+	authenticationGSS          = 7
+	authenticationSSPI         = 9
+	authenticationSASL         = 10
+	authenticationSASLContinue = 11
+	authenticationSASLFinal    = 12
+)
+
+var authenticationMethods = map[uint32]string{
+	authenticationOk:           "OK",
+	authenticationCleartext:    "Cleartext Password",
+	authenticationMD5:          "MD5 Password",
+	authenticationGSS:          "GSSAPI",
+	authenticationSSPI:         "SSPI",
+	authenticationSASL:         "SASL",
+	authenticationSASLContinue: "SASL",
+	authenticationSASLFinal:    "SASL",
+}
+
+// PostgresRequest is a query cycle of the frontend, either a simple query, an extended query from its first
+// message up to the Sync, or the startup message of the connection
+type PostgresRequest struct {
+	Type        string        `json:"type"`
+	Command     string        `json:"command"`
+	Query       string        `json:"query"`
+	Statement   string        `json:"statement,omitempty"`
+	Portal      string        `json:"portal,omitempty"`
+	Parameters  []interface{} `json:"parameters"`
+	User        string        `json:"user,omitempty"`
+	Database    string        `json:"database,omitempty"`
+	Application string        `json:"application,omitempty"`
+}
+
+// PostgresResponse is everything the backend sent up to its ReadyForQuery
+type PostgresResponse struct {
+	Status         string            `json:"status"`
+	CommandTag     string            `json:"commandTag"`
+	Rows           int               `json:"rows"`
+	Columns        []string          `json:"columns"`
+	Authentication string            `json:"authentication,omitempty"`
+	Parameters     map[string]string `json:"parameters,omitempty"`
+	Error          *PostgresError    `json:"error,omitempty"`
+}
+
+type PostgresError struct {
+	Severity string `json:"severity"`
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	Detail   string `json:"detail,omitempty"`
+	Hint     string `json:"hint,omitempty"`
+}
@@ -0,0 +1,65 @@
+package thrift
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// the sequence id pairs the messages, the name guards against the clients that leave it at 0 on every call
+func handleRequest(tcpID *api.TcpID, captureTime time.Time, emitter api.Emitter, request *ThriftRequest, reqResMatcher *requestResponseMatcher) {
+	connectionInfo := &api.ConnectionInfo{
+		ClientIP:   tcpID.SrcIP,
+		ClientPort: tcpID.SrcPort,
+		ServerIP:   tcpID.DstIP,
+		ServerPort: tcpID.DstPort,
+		IsOutgoing: true,
+	}
+
+	// a oneway call has no response, it's emitted on its own
+	if request.Type == messageTypeName(messageOneway) {
+		emitOneway(request, connectionInfo, captureTime, emitter)
+		return
+	}
+
+	ident := fmt.Sprintf(
+		"%s_%s_%s_%s_%s_%d",
+		tcpID.SrcIP,
+		tcpID.DstIP,
+		tcpID.SrcPort,
+		tcpID.DstPort,
+		request.Name,
+		request.SequenceId,
+	)
+
+	item := reqResMatcher.registerRequest(ident, request, captureTime)
+	if item != nil {
+		item.ConnectionInfo = connectionInfo
+		emitter.Emit(item)
+	}
+}
+
+func handleResponse(tcpID *api.TcpID, captureTime time.Time, emitter api.Emitter, name string, response *ThriftResponse, reqResMatcher *requestResponseMatcher) {
+	ident := fmt.Sprintf(
+		"%s_%s_%s_%s_%s_%d",
+		tcpID.DstIP,
+		tcpID.SrcIP,
+		tcpID.DstPort,
+		tcpID.SrcPort,
+		name,
+		response.SequenceId,
+	)
+
+	item := reqResMatcher.registerResponse(ident, response, captureTime)
+	if item != nil {
+		item.ConnectionInfo = &api.ConnectionInfo{
+			ClientIP:   tcpID.DstIP,
+			ClientPort: tcpID.DstPort,
+			ServerIP:   tcpID.SrcIP,
+			ServerPort: tcpID.SrcPort,
+			IsOutgoing: false,
+		}
+		emitter.Emit(item)
+	}
+}
@@ -0,0 +1,179 @@
+package thrift
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+type ThriftPayload struct {
+	Data interface{}
+}
+
+type ThriftPayloader interface {
+	MarshalJSON() ([]byte, error)
+}
+
+func (h ThriftPayload) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.Data)
+}
+
+type ThriftWrapper struct {
+	Method  string      `json:"method"`
+	Url     string      `json:"url"`
+	Details interface{} `json:"details"`
+}
+
+func emitOneway(request *ThriftRequest, connectionInfo *api.ConnectionInfo, captureTime time.Time, emitter api.Emitter) {
+	requestThriftMessage := &api.GenericMessage{
+		IsRequest:   true,
+		CaptureTime: captureTime,
+		Payload: ThriftPayload{
+			Data: &ThriftWrapper{
+				Method:  request.Method,
+				Url:     request.Name,
+				Details: request,
+			},
+		},
+	}
+	item := &api.OutputChannelItem{
+		Protocol:       protocol,
+		Timestamp:      captureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: connectionInfo,
+		Pair: &api.RequestResponsePair{
+			Request:  *requestThriftMessage,
+			Response: api.GenericMessage{},
+		},
+	}
+	emitter.Emit(item)
+}
+
+// formatValue shows the scalars as they are and the structs and containers as JSON
+func formatValue(value interface{}) string {
+	switch value.(type) {
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(value)
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+func representFields(representation []interface{}, title string, fields interface{}, selector string) []interface{} {
+	list, ok := fields.([]interface{})
+	if !ok || len(list) == 0 {
+		return representation
+	}
+
+	rows := make([]api.TableData, 0, len(list))
+	for i, item := range list {
+		field := item.(map[string]interface{})
+		rows = append(rows, api.TableData{
+			Name:     fmt.Sprintf("%g: %s", field["id"].(float64), field["type"].(string)),
+			Value:    formatValue(field["value"]),
+			Selector: fmt.Sprintf(`%s[%d].value`, selector, i),
+		})
+	}
+
+	data, _ := json.Marshal(rows)
+	return append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: title,
+		Data:  string(data),
+	})
+}
+
+func representRequest(request map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Method",
+			Value:    request["method"].(string),
+			Selector: `request.method`,
+		},
+		{
+			Name:     "Service",
+			Value:    request["service"].(string),
+			Selector: `request.service`,
+		},
+		{
+			Name:     "Type",
+			Value:    request["type"].(string),
+			Selector: `request.type`,
+		},
+		{
+			Name:     "Sequence Id",
+			Value:    fmt.Sprintf("%g", request["sequenceId"].(float64)),
+			Selector: `request.sequenceId`,
+		},
+		{
+			Name:     "Protocol",
+			Value:    request["protocol"].(string),
+			Selector: `request.protocol`,
+		},
+		{
+			Name:     "Framed",
+			Value:    fmt.Sprintf("%v", request["framed"]),
+			Selector: `request.framed`,
+		},
+	}
+
+	data, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(data),
+	})
+
+	return representFields(representation, "Arguments", request["arguments"], `request.arguments`)
+}
+
+func representResponse(response map[string]interface{}) (representation []interface{}) {
+	details := []api.TableData{
+		{
+			Name:     "Status",
+			Value:    response["status"].(string),
+			Selector: `response.status`,
+		},
+		{
+			Name:     "Type",
+			Value:    response["type"].(string),
+			Selector: `response.type`,
+		},
+		{
+			Name:     "Sequence Id",
+			Value:    fmt.Sprintf("%g", response["sequenceId"].(float64)),
+			Selector: `response.sequenceId`,
+		},
+	}
+
+	data, _ := json.Marshal(details)
+	representation = append(representation, api.SectionData{
+		Type:  api.TABLE,
+		Title: "Details",
+		Data:  string(data),
+	})
+
+	if exception, ok := response["exception"].(map[string]interface{}); ok {
+		data, _ := json.Marshal([]api.TableData{
+			{
+				Name:     "Type",
+				Value:    exception["type"].(string),
+				Selector: `response.exception.type`,
+			},
+			{
+				Name:     "Message",
+				Value:    exception["message"].(string),
+				Selector: `response.exception.message`,
+			},
+		})
+		representation = append(representation, api.SectionData{
+			Type:  api.TABLE,
+			Title: "Application Exception",
+			Data:  string(data),
+		})
+	}
+
+	return representFields(representation, "Result", response["result"], `response.result`)
+}
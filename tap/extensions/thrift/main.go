@@ -0,0 +1,164 @@
+package thrift
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+var protocol api.Protocol = api.Protocol{
+	Name:            "thrift",
+	LongName:        "Apache Thrift",
+	Abbreviation:    "THRIFT",
+	Macro:           "thrift",
+	Version:         "1",
+	BackgroundColor: "#c0392b",
+	ForegroundColor: "#ffffff",
+	FontSize:        11,
+	ReferenceLink:   "https://github.com/apache/thrift/blob/master/doc/specs/thrift-rpc.md",
+	Ports:           []string{"9090"},
+	Priority:        9,
+}
+
+type dissecting string
+
+func (d dissecting) Register(extension *api.Extension) {
+	extension.Protocol = &protocol
+}
+
+func (d dissecting) Ping() {
+	log.Printf("pong %s", protocol.Name)
+}
+
+// Dissect reads the binary and the compact protocols over the buffered and the framed transports
+func (d dissecting) Dissect(b *bufio.Reader, isClient bool, tcpID *api.TcpID, counterPair *api.CounterPair, superTimer *api.SuperTimer, superIdentifier *api.SuperIdentifier, emitter api.Emitter, options *api.TrafficFilteringOptions, _reqResMatcher api.RequestResponseMatcher) error {
+	reqResMatcher := _reqResMatcher.(*requestResponseMatcher)
+
+	for {
+		if superIdentifier.Protocol != nil && superIdentifier.Protocol != &protocol {
+			return errors.New("Identified by another protocol")
+		}
+
+		m, err := readMessage(b)
+		if err != nil {
+			return err
+		}
+
+		// the calls are only sent by the client and the replies by the server
+		if isClient != isRequest(m.messageType) {
+			return errors.New("Not a Thrift message")
+		}
+		superIdentifier.Protocol = &protocol
+
+		if isClient {
+			handleRequest(tcpID, superTimer.CaptureTime, emitter, toRequest(m), reqResMatcher)
+		} else {
+			handleResponse(tcpID, superTimer.CaptureTime, emitter, m.name, toResponse(m), reqResMatcher)
+		}
+	}
+}
+
+func (d dissecting) Analyze(item *api.OutputChannelItem, resolvedSource string, resolvedDestination string, namespace string) *api.Entry {
+	request := item.Pair.Request.Payload.(map[string]interface{})
+	reqDetails := request["details"].(map[string]interface{})
+
+	// a oneway call comes without a response
+	var resDetails map[string]interface{}
+	var elapsedTime int64
+	if response, ok := item.Pair.Response.Payload.(map[string]interface{}); ok {
+		resDetails = response["details"].(map[string]interface{})
+		elapsedTime = item.Pair.Response.CaptureTime.Sub(item.Pair.Request.CaptureTime).Round(time.Millisecond).Milliseconds()
+		if elapsedTime < 0 {
+			elapsedTime = 0
+		}
+	}
+
+	return &api.Entry{
+		Protocol: protocol,
+		Source: &api.TCP{
+			Name: resolvedSource,
+			IP:   item.ConnectionInfo.ClientIP,
+			Port: item.ConnectionInfo.ClientPort,
+		},
+		Destination: &api.TCP{
+			Name: resolvedDestination,
+			IP:   item.ConnectionInfo.ServerIP,
+			Port: item.ConnectionInfo.ServerPort,
+		},
+		Namespace:   namespace,
+		Outgoing:    item.ConnectionInfo.IsOutgoing,
+		Request:     reqDetails,
+		Response:    resDetails,
+		Timestamp:   item.Timestamp,
+		StartTime:   item.Pair.Request.CaptureTime,
+		ElapsedTime: elapsedTime,
+	}
+
+}
+
+func (d dissecting) Summarize(entry *api.Entry) *api.BaseEntry {
+	status := 0
+	statusQuery := ""
+
+	method := entry.Request["method"].(string)
+	methodQuery := fmt.Sprintf(`request.method == %s`, strconv.Quote(method))
+
+	summary := entry.Request["name"].(string)
+	summaryQuery := fmt.Sprintf(`request.name == %s`, strconv.Quote(summary))
+
+	if responseStatus, ok := entry.Response["status"].(string); ok && responseStatus != StatusOk {
+		summary = fmt.Sprintf("%s (%s)", summary, responseStatus)
+	}
+
+	return &api.BaseEntry{
+		Id:             entry.Id,
+		EntryId:        entry.EntryId,
+		Protocol:       entry.Protocol,
+		Summary:        summary,
+		SummaryQuery:   summaryQuery,
+		Status:         status,
+		StatusQuery:    statusQuery,
+		Method:         method,
+		MethodQuery:    methodQuery,
+		Timestamp:      entry.Timestamp,
+		Source:         entry.Source,
+		Destination:    entry.Destination,
+		IsOutgoing:     entry.Outgoing,
+		Latency:        entry.ElapsedTime,
+		Rules:          entry.Rules,
+		ContractStatus: entry.ContractStatus,
+	}
+}
+
+func (d dissecting) Represent(request map[string]interface{}, response map[string]interface{}) (object []byte, bodySize int64, err error) {
+	bodySize = 0
+	representation := make(map[string]interface{})
+	representation["request"] = representRequest(request)
+	if response != nil {
+		representation["response"] = representResponse(response)
+	}
+	object, err = json.Marshal(representation)
+	return
+}
+
+func (d dissecting) Macros() map[string]string {
+	return map[string]string{
+		`thrift`: fmt.Sprintf(`proto.name == "%s"`, protocol.Name),
+	}
+}
+
+func (d dissecting) NewResponseRequestMatcher() api.RequestResponseMatcher {
+	return createResponseRequestMatcher()
+}
+
+var Dissector dissecting
+
+func NewDissector() api.Dissector {
+	return Dissector
+}
@@ -0,0 +1,87 @@
+package thrift
+
+import (
+	"sync"
+	"time"
+
+	"github.com/up9inc/mizu/tap/api"
+)
+
+// Key is `{client_ip}_{server_ip}_{client_port}_{server_port}_{name}_{sequence_id}`
+type requestResponseMatcher struct {
+	openMessagesMap *sync.Map
+}
+
+func createResponseRequestMatcher() api.RequestResponseMatcher {
+	return &requestResponseMatcher{openMessagesMap: &sync.Map{}}
+}
+
+func (matcher *requestResponseMatcher) GetMap() *sync.Map {
+	return matcher.openMessagesMap
+}
+func (matcher *requestResponseMatcher) SetMaxTry(value int) {
+}
+
+func (matcher *requestResponseMatcher) registerRequest(ident string, request *ThriftRequest, captureTime time.Time) *api.OutputChannelItem {
+	requestThriftMessage := api.GenericMessage{
+		IsRequest:   true,
+		CaptureTime: captureTime,
+		Payload: ThriftPayload{
+			Data: &ThriftWrapper{
+				Method:  request.Method,
+				Url:     request.Name,
+				Details: request,
+			},
+		},
+	}
+
+	if response, found := matcher.openMessagesMap.Load(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		responseThriftMessage := response.(*api.GenericMessage)
+		if !responseThriftMessage.IsRequest {
+			matcher.openMessagesMap.Delete(ident)
+			return matcher.preparePair(&requestThriftMessage, responseThriftMessage)
+		}
+	}
+
+	matcher.openMessagesMap.Store(ident, &requestThriftMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) registerResponse(ident string, response *ThriftResponse, captureTime time.Time) *api.OutputChannelItem {
+	responseThriftMessage := api.GenericMessage{
+		IsRequest:   false,
+		CaptureTime: captureTime,
+		Payload: ThriftPayload{
+			Data: &ThriftWrapper{
+				Method:  response.Status,
+				Url:     "",
+				Details: response,
+			},
+		},
+	}
+
+	if request, found := matcher.openMessagesMap.Load(ident); found {
+		// Type assertion always succeeds because all of the map's values are of api.GenericMessage type
+		requestThriftMessage := request.(*api.GenericMessage)
+		if requestThriftMessage.IsRequest {
+			matcher.openMessagesMap.Delete(ident)
+			return matcher.preparePair(requestThriftMessage, &responseThriftMessage)
+		}
+	}
+
+	matcher.openMessagesMap.Store(ident, &responseThriftMessage)
+	return nil
+}
+
+func (matcher *requestResponseMatcher) preparePair(requestThriftMessage *api.GenericMessage, responseThriftMessage *api.GenericMessage) *api.OutputChannelItem {
+	return &api.OutputChannelItem{
+		Protocol:       protocol,
+		Timestamp:      requestThriftMessage.CaptureTime.UnixNano() / int64(time.Millisecond),
+		ConnectionInfo: nil,
+		Pair: &api.RequestResponsePair{
+			Request:  *requestThriftMessage,
+			Response: *responseThriftMessage,
+		},
+	}
+}
@@ -0,0 +1,599 @@
+package thrift
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+var errMalformedMessage = errors.New("malformed message")
+
+type source interface {
+	io.Reader
+	io.ByteReader
+}
+
+// message is a decoded Thrift message, the name is the method prefixed by the service when multiplexed
+type message struct {
+	name        string
+	messageType byte
+	sequenceId  int32
+	protocol    string
+	framed      bool
+	fields      []ThriftField
+}
+
+type decoder interface {
+	readMessageBegin() (name string, messageType byte, sequenceId int32, err error)
+	readStruct(depth int) ([]ThriftField, error)
+}
+
+// readMessage reads a message of the binary or the compact protocol, either unframed or with the length prefix of the framed transport
+func readMessage(b *bufio.Reader) (*message, error) {
+	first, err := b.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	var src source = b
+	protocolId := first[0]
+	framed := false
+	if protocolId != binaryProtocolId && protocolId != compactProtocolId {
+		header, err := b.Peek(5)
+		if err != nil {
+			return nil, err
+		}
+		length := binary.BigEndian.Uint32(header)
+		protocolId = header[4]
+		if length == 0 || length > maxFrameLength || (protocolId != binaryProtocolId && protocolId != compactProtocolId) {
+			return nil, errMalformedMessage
+		}
+		if _, err := b.Discard(4); err != nil {
+			return nil, err
+		}
+		frame := make([]byte, length)
+		if _, err := io.ReadFull(b, frame); err != nil {
+			return nil, err
+		}
+		src = bytes.NewReader(frame)
+		framed = true
+	}
+
+	m := &message{framed: framed}
+	var d decoder
+	if protocolId == compactProtocolId {
+		d = &compactDecoder{src: src}
+		m.protocol = ProtocolCompact
+	} else {
+		d = &binaryDecoder{src: src}
+		m.protocol = ProtocolBinary
+	}
+
+	if m.name, m.messageType, m.sequenceId, err = d.readMessageBegin(); err != nil {
+		return nil, err
+	}
+	if m.fields, err = d.readStruct(0); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+// isValidName rejects the names no IDL allows, it's the main check telling a Thrift stream apart from the other protocols
+func isValidName(name string) bool {
+	if name == "" || len(name) > maxNameLength {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '.' || c == ':') {
+			return false
+		}
+	}
+	return true
+}
+
+func readFull(src source, length int) ([]byte, error) {
+	data := make([]byte, length)
+	if _, err := io.ReadFull(src, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// readBinary reads a string or a binary, only its first maxRetainedString bytes are kept
+func readBinary(src source, length int) (interface{}, error) {
+	if length < 0 || length > maxFrameLength {
+		return nil, errMalformedMessage
+	}
+
+	retained := length
+	if retained > maxRetainedString {
+		retained = maxRetainedString
+	}
+	data, err := readFull(src, retained)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.CopyN(io.Discard, src, int64(length-retained)); err != nil {
+		return nil, err
+	}
+
+	// the IDL types string and binary share the wire type, a valid UTF-8 value is shown as text
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	return data, nil
+}
+
+func formatUuid(data []byte) string {
+	return fmt.Sprintf("%x-%x-%x-%x-%x", data[0:4], data[4:6], data[6:8], data[8:10], data[10:16])
+}
+
+type binaryDecoder struct {
+	src source
+}
+
+func (d *binaryDecoder) readInt16() (int16, error) {
+	data, err := readFull(d.src, 2)
+	if err != nil {
+		return 0, err
+	}
+	return int16(binary.BigEndian.Uint16(data)), nil
+}
+
+func (d *binaryDecoder) readInt32() (int32, error) {
+	data, err := readFull(d.src, 4)
+	if err != nil {
+		return 0, err
+	}
+	return int32(binary.BigEndian.Uint32(data)), nil
+}
+
+func (d *binaryDecoder) readInt64() (int64, error) {
+	data, err := readFull(d.src, 8)
+	if err != nil {
+		return 0, err
+	}
+	return int64(binary.BigEndian.Uint64(data)), nil
+}
+
+// readMessageBegin reads the strict header, the old header without a version isn't supported as nothing tells it apart
+func (d *binaryDecoder) readMessageBegin() (name string, messageType byte, sequenceId int32, err error) {
+	version, err := d.readInt32()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if uint32(version)&binaryVersionMask != binaryVersion1 {
+		return "", 0, 0, errMalformedMessage
+	}
+	messageType = byte(version)
+	if _, ok := messageTypes[messageType]; !ok {
+		return "", 0, 0, errMalformedMessage
+	}
+
+	length, err := d.readInt32()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if length <= 0 || length > maxNameLength {
+		return "", 0, 0, errMalformedMessage
+	}
+	data, err := readFull(d.src, int(length))
+	if err != nil {
+		return "", 0, 0, err
+	}
+	name = string(data)
+	if !isValidName(name) {
+		return "", 0, 0, errMalformedMessage
+	}
+
+	if sequenceId, err = d.readInt32(); err != nil {
+		return "", 0, 0, err
+	}
+	return name, messageType, sequenceId, nil
+}
+
+func (d *binaryDecoder) readStruct(depth int) ([]ThriftField, error) {
+	if depth > maxDepth {
+		return nil, errMalformedMessage
+	}
+
+	fields := make([]ThriftField, 0)
+	for {
+		fieldType, err := d.src.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if fieldType == typeStop {
+			return fields, nil
+		}
+		id, err := d.readInt16()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readValue(fieldType, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(fields) < maxElements {
+			fields = append(fields, ThriftField{Id: id, Type: typeName(fieldType), Value: value})
+		}
+	}
+}
+
+func (d *binaryDecoder) readValue(valueType byte, depth int) (interface{}, error) {
+	switch valueType {
+	case typeBool:
+		value, err := d.src.ReadByte()
+		return value != 0, err
+	case typeByte:
+		value, err := d.src.ReadByte()
+		return int8(value), err
+	case typeDouble:
+		value, err := d.readInt64()
+		return math.Float64frombits(uint64(value)), err
+	case typeI16:
+		return d.readInt16()
+	case typeI32:
+		return d.readInt32()
+	case typeI64:
+		return d.readInt64()
+	case typeString:
+		length, err := d.readInt32()
+		if err != nil {
+			return nil, err
+		}
+		return readBinary(d.src, int(length))
+	case typeStruct:
+		return d.readStruct(depth)
+	case typeMap:
+		keyType, err := d.src.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		valueType, err := d.src.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := d.readInt32()
+		if err != nil {
+			return nil, err
+		}
+		return readMap(d.readValue, keyType, valueType, int(size), depth)
+	case typeSet, typeList:
+		elementType, err := d.src.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := d.readInt32()
+		if err != nil {
+			return nil, err
+		}
+		return readList(d.readValue, elementType, int(size), depth)
+	case typeUuid:
+		data, err := readFull(d.src, 16)
+		if err != nil {
+			return nil, err
+		}
+		return formatUuid(data), nil
+	default:
+		return nil, fmt.Errorf("unknown type %d: %w", valueType, errMalformedMessage)
+	}
+}
+
+type compactDecoder struct {
+	src source
+}
+
+func (d *compactDecoder) readVarint() (uint64, error) {
+	var value uint64
+	for shift := 0; shift < 64; shift += 7 {
+		digit, err := d.src.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		value |= uint64(digit&0x7f) << shift
+		if digit&0x80 == 0 {
+			return value, nil
+		}
+	}
+	return 0, errMalformedMessage
+}
+
+func (d *compactDecoder) readZigzag() (int64, error) {
+	value, err := d.readVarint()
+	if err != nil {
+		return 0, err
+	}
+	return int64(value>>1) ^ -int64(value&1), nil
+}
+
+func (d *compactDecoder) readMessageBegin() (name string, messageType byte, sequenceId int32, err error) {
+	protocolId, err := d.src.ReadByte()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	versionAndType, err := d.src.ReadByte()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if protocolId != compactProtocolId || versionAndType&compactVersionMask != compactVersion {
+		return "", 0, 0, errMalformedMessage
+	}
+	messageType = (versionAndType >> compactTypeShift) & compactTypeMask
+	if _, ok := messageTypes[messageType]; !ok {
+		return "", 0, 0, errMalformedMessage
+	}
+
+	sequence, err := d.readVarint()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	length, err := d.readVarint()
+	if err != nil {
+		return "", 0, 0, err
+	}
+	if length == 0 || length > maxNameLength {
+		return "", 0, 0, errMalformedMessage
+	}
+	data, err := readFull(d.src, int(length))
+	if err != nil {
+		return "", 0, 0, err
+	}
+	name = string(data)
+	if !isValidName(name) {
+		return "", 0, 0, errMalformedMessage
+	}
+
+	return name, messageType, int32(sequence), nil
+}
+
+// readStruct reads the fields, their ids are written as a delta from the previous id when it's small enough
+func (d *compactDecoder) readStruct(depth int) ([]ThriftField, error) {
+	if depth > maxDepth {
+		return nil, errMalformedMessage
+	}
+
+	fields := make([]ThriftField, 0)
+	var lastId int16
+	for {
+		header, err := d.src.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if header == typeStop {
+			return fields, nil
+		}
+
+		id := lastId + int16(header>>4)
+		if header>>4 == 0 {
+			value, err := d.readZigzag()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(value)
+		}
+		lastId = id
+
+		compactType := header & 0x0f
+		fieldType, ok := compactTypes[compactType]
+		if !ok {
+			return nil, fmt.Errorf("unknown type %d: %w", compactType, errMalformedMessage)
+		}
+
+		var value interface{}
+		if fieldType == typeBool {
+			// the value of a boolean field is its type
+			value = compactType == compactBoolTrue
+		} else if value, err = d.readValue(fieldType, depth+1); err != nil {
+			return nil, err
+		}
+		if len(fields) < maxElements {
+			fields = append(fields, ThriftField{Id: id, Type: typeName(fieldType), Value: value})
+		}
+	}
+}
+
+func (d *compactDecoder) readElementType(compactType byte) (byte, error) {
+	elementType, ok := compactTypes[compactType]
+	if !ok {
+		return 0, fmt.Errorf("unknown type %d: %w", compactType, errMalformedMessage)
+	}
+	return elementType, nil
+}
+
+func (d *compactDecoder) readValue(valueType byte, depth int) (interface{}, error) {
+	switch valueType {
+	case typeBool:
+		// the booleans of a container are a byte each
+		value, err := d.src.ReadByte()
+		return value == compactBoolTrue, err
+	case typeByte:
+		value, err := d.src.ReadByte()
+		return int8(value), err
+	case typeDouble:
+		data, err := readFull(d.src, 8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(data)), nil
+	case typeI16:
+		value, err := d.readZigzag()
+		return int16(value), err
+	case typeI32:
+		value, err := d.readZigzag()
+		return int32(value), err
+	case typeI64:
+		return d.readZigzag()
+	case typeString:
+		length, err := d.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if length > maxFrameLength {
+			return nil, errMalformedMessage
+		}
+		return readBinary(d.src, int(length))
+	case typeStruct:
+		return d.readStruct(depth)
+	case typeMap:
+		size, err := d.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return make([]ThriftMapEntry, 0), nil
+		}
+		if size > maxFrameLength {
+			return nil, errMalformedMessage
+		}
+		types, err := d.src.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		keyType, err := d.readElementType(types >> 4)
+		if err != nil {
+			return nil, err
+		}
+		elementType, err := d.readElementType(types & 0x0f)
+		if err != nil {
+			return nil, err
+		}
+		return readMap(d.readValue, keyType, elementType, int(size), depth)
+	case typeSet, typeList:
+		header, err := d.src.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		// the size is written apart when it doesn't fit the 4 bits of the header
+		if size == 0x0f {
+			if size, err = d.readVarint(); err != nil {
+				return nil, err
+			}
+			if size > maxFrameLength {
+				return nil, errMalformedMessage
+			}
+		}
+		elementType, err := d.readElementType(header & 0x0f)
+		if err != nil {
+			return nil, err
+		}
+		return readList(d.readValue, elementType, int(size), depth)
+	case typeUuid:
+		data, err := readFull(d.src, 16)
+		if err != nil {
+			return nil, err
+		}
+		return formatUuid(data), nil
+	default:
+		return nil, fmt.Errorf("unknown type %d: %w", valueType, errMalformedMessage)
+	}
+}
+
+type valueReader func(valueType byte, depth int) (interface{}, error)
+
+func readList(readValue valueReader, elementType byte, size int, depth int) (interface{}, error) {
+	if size < 0 || size > maxFrameLength || depth > maxDepth {
+		return nil, errMalformedMessage
+	}
+
+	elements := make([]interface{}, 0)
+	for i := 0; i < size; i++ {
+		element, err := readValue(elementType, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(elements) < maxElements {
+			elements = append(elements, element)
+		}
+	}
+	return elements, nil
+}
+
+func readMap(readValue valueReader, keyType byte, valueType byte, size int, depth int) (interface{}, error) {
+	if size < 0 || size > maxFrameLength || depth > maxDepth {
+		return nil, errMalformedMessage
+	}
+
+	entries := make([]ThriftMapEntry, 0)
+	for i := 0; i < size; i++ {
+		key, err := readValue(keyType, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		value, err := readValue(valueType, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		if len(entries) < maxElements {
+			entries = append(entries, ThriftMapEntry{Key: key, Value: value})
+		}
+	}
+	return entries, nil
+}
+
+func isRequest(messageType byte) bool {
+	return messageType == messageCall || messageType == messageOneway
+}
+
+// toRequest splits the name of a call made through the multiplexed protocol into the service and the method
+func toRequest(m *message) *ThriftRequest {
+	request := &ThriftRequest{
+		Name:       m.name,
+		Method:     m.name,
+		Type:       messageTypeName(m.messageType),
+		SequenceId: m.sequenceId,
+		Protocol:   m.protocol,
+		Framed:     m.framed,
+		Arguments:  m.fields,
+	}
+	if i := strings.LastIndex(m.name, ":"); i >= 0 {
+		request.Service = m.name[:i]
+		request.Method = m.name[i+1:]
+	}
+	return request
+}
+
+func toResponse(m *message) *ThriftResponse {
+	response := &ThriftResponse{
+		Type:       messageTypeName(m.messageType),
+		SequenceId: m.sequenceId,
+		Status:     StatusOk,
+		Result:     m.fields,
+	}
+
+	if m.messageType == messageException {
+		exception := &ThriftApplicationException{Type: applicationExceptionTypeName(0)}
+		for _, field := range m.fields {
+			switch field.Id {
+			case 1:
+				if message, ok := field.Value.(string); ok {
+					exception.Message = message
+				}
+			case 2:
+				if exceptionType, ok := field.Value.(int32); ok {
+					exception.Type = applicationExceptionTypeName(exceptionType)
+				}
+			}
+		}
+		response.Status = StatusApplicationException
+		response.Exception = exception
+		response.Result = make([]ThriftField, 0)
+		return response
+	}
+
+	// the returned value is the field 0 of the result, any other field is one of the exceptions declared by the method
+	for _, field := range m.fields {
+		if field.Id != 0 {
+			response.Status = StatusException
+		}
+	}
+	return response
+}
@@ -0,0 +1,177 @@
+package thrift
+
+import "fmt"
+
+const (
+	maxFrameLength = 16 * 1024 * 1024
+	maxNameLength  = 1024
+	// bounds the nesting of structs and containers, a deeper message is malformed
+	maxDepth = 32
+	// the elements of a container past this count are read but not kept
+	maxElements = 256
+	// the strings and binaries are kept up to this size
+	maxRetainedString = 4096
+
+	ProtocolBinary  = "binary"
+	ProtocolCompact = "compact"
+)
+
+// the first byte of a message of each protocol
+const (
+	binaryProtocolId  = 0x80
+	compactProtocolId = 0x82
+
+	binaryVersion1     = 0x80010000
+	binaryVersionMask  = 0xffff0000
+	compactVersion     = 1
+	compactVersionMask = 0x1f
+	compactTypeShift   = 5
+	compactTypeMask    = 0x07
+)
+
+const (
+	messageCall      = 1
+	messageReply     = 2
+	messageException = 3
+	messageOneway    = 4
+)
+
+var messageTypes = map[byte]string{
+	messageCall:      "CALL",
+	messageReply:     "REPLY",
+	messageException: "EXCEPTION",
+	messageOneway:    "ONEWAY",
+}
+
+// the field types, numbered as in the binary protocol
+const (
+	typeStop   = 0
+	typeVoid   = 1
+	typeBool   = 2
+	typeByte   = 3
+	typeDouble = 4
+	typeI16    = 6
+	typeI32    = 8
+	typeI64    = 10
+	typeString = 11
+	typeStruct = 12
+	typeMap    = 13
+	typeSet    = 14
+	typeList   = 15
+	typeUuid   = 16
+)
+
+var types = map[byte]string{
+	typeBool:   "bool",
+	typeByte:   "byte",
+	typeDouble: "double",
+	typeI16:    "i16",
+	typeI32:    "i32",
+	typeI64:    "i64",
+	typeString: "string",
+	typeStruct: "struct",
+	typeMap:    "map",
+	typeSet:    "set",
+	typeList:   "list",
+	typeUuid:   "uuid",
+}
+
+// the compact protocol numbers the types on its own, true and false are two types of the field header
+const (
+	compactBoolTrue  = 1
+	compactBoolFalse = 2
+)
+
+var compactTypes = map[byte]byte{
+	compactBoolTrue:  typeBool,
+	compactBoolFalse: typeBool,
+	3:                typeByte,
+	4:                typeI16,
+	5:                typeI32,
+	6:                typeI64,
+	7:                typeDouble,
+	8:                typeString,
+	9:                typeList,
+	10:               typeSet,
+	11:               typeMap,
+	12:               typeStruct,
+	13:               typeUuid,
+}
+
+var applicationExceptionTypes = map[int32]string{
+	0:  "UNKNOWN",
+	1:  "UNKNOWN_METHOD",
+	2:  "INVALID_MESSAGE_TYPE",
+	3:  "WRONG_METHOD_NAME",
+	4:  "BAD_SEQUENCE_ID",
+	5:  "MISSING_RESULT",
+	6:  "INTERNAL_ERROR",
+	7:  "PROTOCOL_ERROR",
+	8:  "INVALID_TRANSFORM",
+	9:  "INVALID_PROTOCOL",
+	10: "UNSUPPORTED_CLIENT_TYPE",
+}
+
+const (
+	StatusOk                   = "OK"
+	StatusException            = "EXCEPTION"
+	StatusApplicationException = "APPLICATION_EXCEPTION"
+)
+
+func messageTypeName(value byte) string {
+	if name, ok := messageTypes[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", value)
+}
+
+func typeName(value byte) string {
+	if name, ok := types[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", value)
+}
+
+func applicationExceptionTypeName(value int32) string {
+	if name, ok := applicationExceptionTypes[value]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", value)
+}
+
+// ThriftField is a field of a struct, the value of a struct is a list of fields and of a map a list of entries
+type ThriftField struct {
+	Id    int16       `json:"id"`
+	Type  string      `json:"type"`
+	Value interface{} `json:"value"`
+}
+
+type ThriftMapEntry struct {
+	Key   interface{} `json:"key"`
+	Value interface{} `json:"value"`
+}
+
+type ThriftApplicationException struct {
+	Message string `json:"message"`
+	Type    string `json:"type"`
+}
+
+type ThriftRequest struct {
+	Name       string        `json:"name"`
+	Service    string        `json:"service"`
+	Method     string        `json:"method"`
+	Type       string        `json:"type"`
+	SequenceId int32         `json:"sequenceId"`
+	Protocol   string        `json:"protocol"`
+	Framed     bool          `json:"framed"`
+	Arguments  []ThriftField `json:"arguments"`
+}
+
+// ThriftResponse keeps the fields of the result struct, the field 0 is the returned value and the others the declared exceptions
+type ThriftResponse struct {
+	Type       string                      `json:"type"`
+	SequenceId int32                       `json:"sequenceId"`
+	Status     string                      `json:"status"`
+	Result     []ThriftField               `json:"result"`
+	Exception  *ThriftApplicationException `json:"exception"`
+}
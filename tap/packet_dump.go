@@ -0,0 +1,130 @@
+package tap
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+	"github.com/up9inc/mizu/shared/logger"
+)
+
+// packetDumper writes the captured packets to a pcap file for deep debugging of a single node, the dump stops
+// by itself once maxBytes were written or the deadline has passed
+type packetDumper struct {
+	active       int32
+	mutex        sync.Mutex
+	file         *os.File
+	writer       *pcapgo.Writer
+	path         string
+	maxBytes     int64
+	writtenBytes int64
+	deadline     time.Time
+}
+
+var dumper = &packetDumper{}
+
+// StartPacketDump starts dumping the packets of this tapper to path, an active dump is stopped first
+func StartPacketDump(path string, maxBytes int64, duration time.Duration) error {
+	if maxBytes <= 0 {
+		return fmt.Errorf("packet dump max bytes must be positive")
+	}
+
+	dumper.mutex.Lock()
+	defer dumper.mutex.Unlock()
+
+	dumper.stop()
+
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	// packets come from several interfaces with different link layers, so only the network layer is kept
+	writer := pcapgo.NewWriter(file)
+	if err := writer.WriteFileHeader(uint32(*snaplen), layers.LinkTypeRaw); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	dumper.file = file
+	dumper.writer = writer
+	dumper.path = path
+	dumper.maxBytes = maxBytes
+	dumper.writtenBytes = 0
+	dumper.deadline = time.Now().Add(duration)
+	atomic.StoreInt32(&dumper.active, 1)
+
+	logger.Log.Infof("Started packet dump to %s, max bytes: %d, until: %v", path, maxBytes, dumper.deadline)
+	return nil
+}
+
+func StopPacketDump() {
+	dumper.mutex.Lock()
+	defer dumper.mutex.Unlock()
+
+	dumper.stop()
+}
+
+func (d *packetDumper) write(packet gopacket.Packet) {
+	// checked without locking since it's called for every packet
+	if atomic.LoadInt32(&d.active) == 0 {
+		return
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if d.writer == nil {
+		return
+	}
+
+	if time.Now().After(d.deadline) {
+		d.stop()
+		return
+	}
+
+	networkLayer := packet.NetworkLayer()
+	if networkLayer == nil {
+		return
+	}
+
+	data := make([]byte, 0, len(networkLayer.LayerContents())+len(networkLayer.LayerPayload()))
+	data = append(data, networkLayer.LayerContents()...)
+	data = append(data, networkLayer.LayerPayload()...)
+	if d.writtenBytes+int64(len(data)) > d.maxBytes {
+		d.stop()
+		return
+	}
+
+	captureInfo := packet.Metadata().CaptureInfo
+	captureInfo.CaptureLength = len(data)
+	captureInfo.Length = len(data)
+	if err := d.writer.WritePacket(captureInfo, data); err != nil {
+		logger.Log.Errorf("Failed writing to packet dump %s, stopping it: %v", d.path, err)
+		d.stop()
+		return
+	}
+
+	d.writtenBytes += int64(len(data))
+}
+
+// stop must be called while holding the mutex
+func (d *packetDumper) stop() {
+	if d.writer == nil {
+		return
+	}
+
+	if err := d.file.Close(); err != nil {
+		logger.Log.Errorf("Failed closing packet dump %s: %v", d.path, err)
+	}
+	logger.Log.Infof("Stopped packet dump to %s, %d bytes were written", d.path, d.writtenBytes)
+
+	atomic.StoreInt32(&d.active, 0)
+	d.file = nil
+	d.writer = nil
+}
@@ -13,12 +13,14 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"reflect"
 	"runtime"
 	"strings"
 	"time"
 
 	"github.com/up9inc/mizu/shared/logger"
 	"github.com/up9inc/mizu/tap/api"
+	"github.com/up9inc/mizu/tap/capturepolicy"
 	"github.com/up9inc/mizu/tap/diagnose"
 	"github.com/up9inc/mizu/tap/source"
 	"github.com/up9inc/mizu/tap/tlstapper"
@@ -58,12 +60,14 @@ var tls = flag.Bool("tls", false, "Enable TLS tapper")
 var memprofile = flag.String("memprofile", "", "Write memory profile")
 
 type TapOpts struct {
-	HostMode          bool
-	FilterAuthorities []v1.Pod
+	HostMode            bool
+	FilterAuthorities   []v1.Pod
+	CapturePolicySource capturepolicy.Source
 }
 
 var extensions []*api.Extension                     // global
 var filteringOptions *api.TrafficFilteringOptions   // global
+var entryLimiter *api.EntryLimiter                  // global
 var tapTargets []v1.Pod                             // global
 var packetSourceManager *source.PacketSourceManager // global
 var mainPacketInputChan chan source.TcpPacketInfo   // global
@@ -89,6 +93,12 @@ func inArrayString(arr []string, valueToCheck string) bool {
 func StartPassiveTapper(opts *TapOpts, outputItems chan *api.OutputChannelItem, extensionsRef []*api.Extension, options *api.TrafficFilteringOptions) {
 	extensions = extensionsRef
 	filteringOptions = options
+	entryLimiter = api.NewEntryLimiter(options.SampleRate, options.MaxEntriesPerSecondPerPod)
+	if entryLimiter != nil {
+		logger.Log.Infof("Sampling the connections at %v (0 records all of them), at most %d entries per second per pod (0 is unlimited)", options.SampleRate, options.MaxEntriesPerSecondPerPod)
+	}
+
+	capturepolicy.Start(opts.CapturePolicySource)
 
 	if opts.FilterAuthorities == nil {
 		tapTargets = []v1.Pod{}
@@ -113,10 +123,21 @@ func StartPassiveTapper(opts *TapOpts, outputItems chan *api.OutputChannelItem,
 }
 
 func UpdateTapTargets(newTapTargets []v1.Pod) {
+	// the API server sends the targets again when the tapper reconnects, reopening the sources would drop packets
+	if reflect.DeepEqual(newTapTargets, tapTargets) {
+		return
+	}
+
 	tapTargets = newTapTargets
 	if err := initializePacketSources(); err != nil {
 		logger.Log.Fatal(err)
 	}
+
+	select {
+	case tapTargetsUpdated <- struct{}{}:
+	default:
+		// NOP: the assembler wasn't done with the previous update yet
+	}
 	printNewTapTargets()
 }
 
@@ -221,6 +242,7 @@ func startPassiveTapper(opts *TapOpts, outputItems chan *api.OutputChannelItem)
 		cleanPeriod:       cleanPeriod,
 		connectionTimeout: staleConnectionTimeout,
 		streamsMap:        streamsMap,
+		datagramMatchers:  assembler.udpHandler.reqResMatchers(),
 	}
 	cleaner.start()
 
@@ -269,6 +291,7 @@ func startTlsTapper(extension *api.Extension, outputItems chan *api.OutputChanne
 	var emitter api.Emitter = &api.Emitting{
 		AppStats:      &diagnose.AppStats,
 		OutputChannel: outputItems,
+		Limiter:       entryLimiter,
 	}
 
 	go tls.Poll(emitter, options)
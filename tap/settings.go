@@ -13,9 +13,11 @@ const (
 	MaxBufferedPagesTotalEnvVarName           = "MAX_BUFFERED_PAGES_TOTAL"
 	MaxBufferedPagesPerConnectionEnvVarName   = "MAX_BUFFERED_PAGES_PER_CONNECTION"
 	TcpStreamChannelTimeoutMsEnvVarName       = "TCP_STREAM_CHANNEL_TIMEOUT_MS"
+	StartupBufferMsEnvVarName                 = "STARTUP_BUFFER_MS"
 	MaxBufferedPagesTotalDefaultValue         = 5000
 	MaxBufferedPagesPerConnectionDefaultValue = 5000
 	TcpStreamChannelTimeoutMsDefaultValue     = 10000
+	StartupBufferMsDefaultValue               = 10000
 )
 
 func GetMaxBufferedPagesTotal() int {
@@ -42,6 +44,15 @@ func GetTcpChannelTimeoutMs() time.Duration {
 	return time.Duration(valueFromEnv) * time.Millisecond
 }
 
+// GetStartupBufferMaxAge is how long the packets are held while a tap target has no address, 0 disables the buffer
+func GetStartupBufferMaxAge() time.Duration {
+	valueFromEnv, err := strconv.Atoi(os.Getenv(StartupBufferMsEnvVarName))
+	if err != nil {
+		return StartupBufferMsDefaultValue * time.Millisecond
+	}
+	return time.Duration(valueFromEnv) * time.Millisecond
+}
+
 func GetMemoryProfilingEnabled() bool {
 	return os.Getenv(MemoryProfilingEnabledEnvVarName) == "1"
 }
@@ -109,6 +109,11 @@ func buildBPFExpr(pods []v1.Pod) string {
 	hostsFilter := make([]string, 0)
 
 	for _, pod := range pods {
+		// the traffic of a pending pod is captured before it's given an address, so the tapper holds it until the
+		// address is known, the packets of the other pods are dropped then
+		if pod.Status.PodIP == "" {
+			return "port not 443"
+		}
 		hostsFilter = append(hostsFilter, fmt.Sprintf("host %s", pod.Status.PodIP))
 	}
 
@@ -0,0 +1,100 @@
+package tap
+
+import (
+	"time"
+
+	"github.com/up9inc/mizu/shared/logger"
+	"github.com/up9inc/mizu/tap/source"
+	v1 "k8s.io/api/core/v1"
+)
+
+const startupBufferMaxBytes = 32 * 1024 * 1024
+
+// tapTargetsUpdated is signaled by UpdateTapTargets, the assembler releases the buffered packets of the new targets
+var tapTargetsUpdated = make(chan struct{}, 1)
+
+// startupBuffer holds the packets of the addresses that aren't tapped while one of the tap targets is a pending pod
+// without an address. A pod is given its address before its init containers run, but the tapper is sent the address a
+// moment later, so the packets of the migrations and the config fetches of the pod are replayed once it's known.
+type startupBuffer struct {
+	maxAge  time.Duration
+	packets []source.TcpPacketInfo
+	bytes   int
+}
+
+func newStartupBuffer(maxAge time.Duration) *startupBuffer {
+	return &startupBuffer{maxAge: maxAge}
+}
+
+func hasPendingTapTarget(targets []v1.Pod) bool {
+	for _, pod := range targets {
+		if pod.Status.PodIP == "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isTapTargetPacket(packetInfo source.TcpPacketInfo, targets []v1.Pod) bool {
+	networkLayer := packetInfo.Packet.NetworkLayer()
+	if networkLayer == nil {
+		return false
+	}
+
+	src, dst := networkLayer.NetworkFlow().Endpoints()
+	return inArrayPod(targets, src.String()) || inArrayPod(targets, dst.String())
+}
+
+// hold buffers the packet when it isn't of a target while a target has no address, the packet may be of that target
+func (buffer *startupBuffer) hold(packetInfo source.TcpPacketInfo, targets []v1.Pod) bool {
+	if buffer.maxAge <= 0 || packetInfo.Packet.NetworkLayer() == nil || !hasPendingTapTarget(targets) || isTapTargetPacket(packetInfo, targets) {
+		return false
+	}
+
+	buffer.packets = append(buffer.packets, packetInfo)
+	buffer.bytes += len(packetInfo.Packet.Data())
+	buffer.expire(packetInfo.Packet.Metadata().Timestamp)
+	return true
+}
+
+// expire drops the oldest packets, the ones captured maxAge before now and the ones over the size limit
+func (buffer *startupBuffer) expire(now time.Time) {
+	expired := 0
+	for expired < len(buffer.packets) {
+		packet := buffer.packets[expired].Packet
+		if now.Sub(packet.Metadata().Timestamp) <= buffer.maxAge && buffer.bytes <= startupBufferMaxBytes {
+			break
+		}
+
+		buffer.bytes -= len(packet.Data())
+		expired++
+	}
+
+	buffer.packets = buffer.packets[expired:]
+}
+
+// release returns the buffered packets of the targets in their capture order, the packets of the other addresses are
+// kept while a target is still pending
+func (buffer *startupBuffer) release(targets []v1.Pod) []source.TcpPacketInfo {
+	released := make([]source.TcpPacketInfo, 0)
+	kept := make([]source.TcpPacketInfo, 0)
+	keptBytes := 0
+	pending := hasPendingTapTarget(targets)
+
+	for _, packetInfo := range buffer.packets {
+		if isTapTargetPacket(packetInfo, targets) {
+			released = append(released, packetInfo)
+		} else if pending {
+			kept = append(kept, packetInfo)
+			keptBytes += len(packetInfo.Packet.Data())
+		}
+	}
+
+	if len(released) > 0 {
+		logger.Log.Infof("Replaying %d packets captured before the tap targets were given their addresses", len(released))
+	}
+
+	buffer.packets = kept
+	buffer.bytes = keptBytes
+	return released
+}
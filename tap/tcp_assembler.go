@@ -12,6 +12,7 @@ import (
 	"github.com/google/gopacket/reassembly"
 	"github.com/up9inc/mizu/shared/logger"
 	"github.com/up9inc/mizu/tap/api"
+	"github.com/up9inc/mizu/tap/capturepolicy"
 	"github.com/up9inc/mizu/tap/diagnose"
 	"github.com/up9inc/mizu/tap/source"
 )
@@ -22,6 +23,8 @@ type tcpAssembler struct {
 	*reassembly.Assembler
 	streamPool     *reassembly.StreamPool
 	streamFactory  *tcpStreamFactory
+	udpHandler     *udpHandler
+	startupBuffer  *startupBuffer
 	assemblerMutex sync.Mutex
 }
 
@@ -39,6 +42,7 @@ func NewTcpAssembler(outputItems chan *api.OutputChannelItem, streamsMap *tcpStr
 	var emitter api.Emitter = &api.Emitting{
 		AppStats:      &diagnose.AppStats,
 		OutputChannel: outputItems,
+		Limiter:       entryLimiter,
 	}
 
 	streamFactory := NewTcpStreamFactory(emitter, streamsMap, opts)
@@ -56,6 +60,8 @@ func NewTcpAssembler(outputItems chan *api.OutputChannelItem, streamsMap *tcpStr
 		Assembler:     assembler,
 		streamPool:    streamPool,
 		streamFactory: streamFactory,
+		udpHandler:    NewUdpHandler(streamFactory),
+		startupBuffer: newStartupBuffer(GetStartupBufferMaxAge()),
 	}
 }
 
@@ -70,30 +76,17 @@ func (a *tcpAssembler) processPackets(dumpPacket bool, packets <-chan source.Tcp
 			logger.Log.Debugf("Packets seen: #%d", packetsCount)
 		}
 
-		packet := packetInfo.Packet
-		data := packet.Data()
-		diagnose.AppStats.UpdateProcessedBytes(uint64(len(data)))
-		if dumpPacket {
-			logger.Log.Debugf("Packet content (%d/0x%x) - %s", len(data), len(data), hex.Dump(data))
+		select {
+		case <-tapTargetsUpdated:
+			for _, releasedPacketInfo := range a.startupBuffer.release(tapTargets) {
+				a.processPacket(dumpPacket, releasedPacketInfo)
+			}
+		default:
+			// NOP: the targets didn't change
 		}
 
-		tcp := packet.Layer(layers.LayerTypeTCP)
-		if tcp != nil {
-			diagnose.AppStats.IncTcpPacketsCount()
-			tcp := tcp.(*layers.TCP)
-			if *checksum {
-				err := tcp.SetNetworkLayerForChecksum(packet.NetworkLayer())
-				if err != nil {
-					logger.Log.Fatalf("Failed to set network layer for checksum: %s", err)
-				}
-			}
-			c := context{
-				CaptureInfo: packet.Metadata().CaptureInfo,
-			}
-			diagnose.InternalStats.Totalsz += len(tcp.Payload)
-			a.assemblerMutex.Lock()
-			a.AssembleWithContext(packet.NetworkLayer().NetworkFlow(), tcp, &c)
-			a.assemblerMutex.Unlock()
+		if !a.startupBuffer.hold(packetInfo, tapTargets) {
+			a.processPacket(dumpPacket, packetInfo)
 		}
 
 		done := *maxcount > 0 && int64(diagnose.AppStats.PacketsCount) >= *maxcount
@@ -125,6 +118,45 @@ func (a *tcpAssembler) processPackets(dumpPacket bool, packets <-chan source.Tcp
 	logger.Log.Debugf("Final flush: %d closed", closed)
 }
 
+func (a *tcpAssembler) processPacket(dumpPacket bool, packetInfo source.TcpPacketInfo) {
+	packet := packetInfo.Packet
+	if networkLayer := packet.NetworkLayer(); networkLayer != nil {
+		// the packets of the hosts the capture policy doesn't allow are neither dumped nor dissected
+		src, dst := networkLayer.NetworkFlow().Endpoints()
+		if !capturepolicy.Allows(src.String(), dst.String()) {
+			return
+		}
+	}
+
+	data := packet.Data()
+	diagnose.AppStats.UpdateProcessedBytes(uint64(len(data)))
+	if dumpPacket {
+		logger.Log.Debugf("Packet content (%d/0x%x) - %s", len(data), len(data), hex.Dump(data))
+	}
+	dumper.write(packet)
+
+	tcp := packet.Layer(layers.LayerTypeTCP)
+	if tcp != nil {
+		diagnose.AppStats.IncTcpPacketsCount()
+		tcp := tcp.(*layers.TCP)
+		if *checksum {
+			err := tcp.SetNetworkLayerForChecksum(packet.NetworkLayer())
+			if err != nil {
+				logger.Log.Fatalf("Failed to set network layer for checksum: %s", err)
+			}
+		}
+		c := context{
+			CaptureInfo: packet.Metadata().CaptureInfo,
+		}
+		diagnose.InternalStats.Totalsz += len(tcp.Payload)
+		a.assemblerMutex.Lock()
+		a.AssembleWithContext(packet.NetworkLayer().NetworkFlow(), tcp, &c)
+		a.assemblerMutex.Unlock()
+	} else if udp := packet.Layer(layers.LayerTypeUDP); udp != nil {
+		a.udpHandler.handle(packet, udp.(*layers.UDP))
+	}
+}
+
 func (a *tcpAssembler) dumpStreamPool() {
 	a.streamPool.Dump()
 }
@@ -71,7 +71,6 @@ func (factory *tcpStreamFactory) New(net, transport gopacket.Flow, tcp *layers.T
 	stream := &tcpStream{
 		net:             net,
 		transport:       transport,
-		isDNS:           tcp.SrcPort == 53 || tcp.DstPort == 53,
 		isTapTarget:     isTapTarget,
 		tcpstate:        reassembly.NewTCPSimpleFSM(fsmOptions),
 		ident:           fmt.Sprintf("%s:%s", net, transport),
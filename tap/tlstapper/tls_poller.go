@@ -16,6 +16,7 @@ import (
 	"github.com/go-errors/errors"
 	"github.com/up9inc/mizu/shared/logger"
 	"github.com/up9inc/mizu/tap/api"
+	"github.com/up9inc/mizu/tap/capturepolicy"
 )
 
 type tlsPoller struct {
@@ -120,6 +121,10 @@ func (p *tlsPoller) handleTlsChunk(chunk *tlsChunk, extension *api.Extension,
 		return err
 	}
 
+	if !capturepolicy.Allows(ip.String()) {
+		return nil
+	}
+
 	key := buildTlsKey(chunk, ip, port)
 	reader, exists := p.readers[key]
 
@@ -0,0 +1,74 @@
+package tap
+
+import (
+	"strconv"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/up9inc/mizu/tap/api"
+	"github.com/up9inc/mizu/tap/diagnose"
+)
+
+// udpHandler passes the UDP datagrams of the tap targets to the extensions implementing api.DatagramDissector
+type udpHandler struct {
+	streamFactory *tcpStreamFactory
+	dissectors    []*datagramExtension
+}
+
+type datagramExtension struct {
+	extension     *api.Extension
+	dissector     api.DatagramDissector
+	reqResMatcher api.RequestResponseMatcher
+}
+
+func NewUdpHandler(streamFactory *tcpStreamFactory) *udpHandler {
+	dissectors := make([]*datagramExtension, 0)
+	for _, extension := range extensions {
+		if dissector, ok := extension.Dissector.(api.DatagramDissector); ok {
+			dissectors = append(dissectors, &datagramExtension{
+				extension:     extension,
+				dissector:     dissector,
+				reqResMatcher: extension.Dissector.NewResponseRequestMatcher(),
+			})
+		}
+	}
+
+	return &udpHandler{
+		streamFactory: streamFactory,
+		dissectors:    dissectors,
+	}
+}
+
+func (h *udpHandler) handle(packet gopacket.Packet, udp *layers.UDP) {
+	if len(h.dissectors) == 0 || len(udp.Payload) == 0 || packet.NetworkLayer() == nil {
+		return
+	}
+
+	src, dst := packet.NetworkLayer().NetworkFlow().Endpoints()
+	tcpID := &api.TcpID{
+		SrcIP:   src.String(),
+		DstIP:   dst.String(),
+		SrcPort: strconv.Itoa(int(udp.SrcPort)),
+		DstPort: strconv.Itoa(int(udp.DstPort)),
+	}
+	if !h.streamFactory.getStreamProps(tcpID.SrcIP, tcpID.SrcPort, tcpID.DstIP, tcpID.DstPort).isTapTarget {
+		return
+	}
+
+	captureTime := packet.Metadata().CaptureInfo.Timestamp
+	for _, datagramExtension := range h.dissectors {
+		err := datagramExtension.dissector.DissectDatagram(udp.Payload, tcpID, captureTime, h.streamFactory.Emitter, filteringOptions, datagramExtension.reqResMatcher)
+		if err == nil {
+			return
+		}
+		diagnose.TapErrors.Debug("UDP datagram not dissected by %s %s:%s -> %s:%s: %v", datagramExtension.extension.Protocol.Name, tcpID.SrcIP, tcpID.SrcPort, tcpID.DstIP, tcpID.DstPort, err)
+	}
+}
+
+func (h *udpHandler) reqResMatchers() []api.RequestResponseMatcher {
+	matchers := make([]api.RequestResponseMatcher, 0, len(h.dissectors))
+	for _, datagramExtension := range h.dissectors {
+		matchers = append(matchers, datagramExtension.reqResMatcher)
+	}
+	return matchers
+}
@@ -38,6 +38,7 @@ interface Entry {
     latency: number;
     rules: Rules;
     contractStatus: number,
+    tag?: string,
 }
 
 interface Rules {
@@ -204,6 +205,20 @@ export const EntryItem: React.FC<EntryProps> = ({entry, style, headingMode}) =>
                     </div>
                 : ""
             }
+            {
+                entry.tag ?
+                    <Queryable
+                        query={`tag == "${entry.tag}"`}
+                        displayIconOnMouseOver={true}
+                        flipped={true}
+                        iconStyle={{marginTop: "4px", right: "16px", position: "relative"}}
+                    >
+                        <span className={styles.tag} title="Tag">
+                            {entry.tag}
+                        </span>
+                    </Queryable>
+                : ""
+            }
             <div className={styles.separatorRight}>
                 <Queryable
                         query={`src.ip == "${entry.src.ip}"`}
@@ -297,6 +297,22 @@ export const QueryForm: React.FC<QueryFormProps> = ({backgroundColor, openWebSoc
                                 code={`timestamp >= datetime("10/19/2021, 6:29:02.593 PM")`}
                                 language="python"
                             />
+                            <Typography id="modal-modal-description">
+                                matches the records of the last minutes, hours (<code style={{fontSize: "14px"}}>h</code>) or days (<code style={{fontSize: "14px"}}>d</code>), or of a time window given in RFC 3339 times, UNIX milliseconds or durations:
+                            </Typography>
+                            <SyntaxHighlighter
+                                showLineNumbers={false}
+                                code={`since("5m")\nbetween("2022-03-01T10:00:00Z", "1h")`}
+                                language="python"
+                            />
+                            <Typography id="modal-modal-description">
+                                counts the records per interval over a window, served by <code style={{fontSize: "14px"}}>/query/rate</code>. As a filter it matches the records of its window:
+                            </Typography>
+                            <SyntaxHighlighter
+                                showLineNumbers={false}
+                                code={`http and rate("1m", "1h")`}
+                                language="python"
+                            />
                             <Typography id="modal-modal-description">
                                 limits the number of records that are streamed back as a result of a query. Always evaluates to true:
                             </Typography>
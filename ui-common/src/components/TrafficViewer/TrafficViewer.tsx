@@ -169,6 +169,16 @@ const TrafficViewer: React.FC<TrafficViewerProps> = ({ setAnalyzeStatus, message
       case "startTime":
         setStartTime(message.data);
         break;
+      case "streamConfig":
+        if (message.data.maxBytesPerSec > 0) {
+          toast.info(`Streaming is limited to ${(message.data.maxBytesPerSec / 1000).toFixed(1)}KB/s`, {
+            position: "bottom-right",
+            theme: "colored",
+            autoClose: 3000,
+            hideProgressBar: true,
+          });
+        }
+        break;
       default:
         console.error(
           `unsupported websocket message type, Got: ${message.messageType}`
@@ -9,7 +9,12 @@ enum WebSocketReadyState {
 
 export const DEFAULT_QUERY = "leftOff(-1)";
 
-const useWS = (wsUrl: string) => {
+// maxBytesPerSec caps the bandwidth of streaming the entries, for slow links, 0 is the bandwidth of the api server
+export interface StreamOptions {
+  maxBytesPerSec?: number
+}
+
+const useWS = (wsUrl: string, streamOptions: StreamOptions = {}) => {
   const [message, setMessage] = useState(null);
   const [error, setError] = useState(null);
   const [isOpen, setisOpen] = useState(false);
@@ -38,7 +43,7 @@ const useWS = (wsUrl: string) => {
 
   const sendQuery = (query: string) => {
     if (ws.current && (ws.current.readyState === WebSocketReadyState.OPEN)) {
-      ws.current.send(JSON.stringify({ "query": query, "enableFullEntries": false }));
+      ws.current.send(JSON.stringify({ "query": query, "enableFullEntries": false, "maxBytesPerSec": streamOptions.maxBytesPerSec || 0, "adaptive": true }));
     }
   }
 
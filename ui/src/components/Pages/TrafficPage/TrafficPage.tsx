@@ -17,12 +17,18 @@ interface TrafficPageProps {
 
 const api = Api.getInstance();
 
+// mizu view --max-bandwidth opens the GUI with the bandwidth it streams the entries with
+const getMaxBytesPerSec = () => {
+  const maxBandwidth = new URLSearchParams(window.location.search).get("maxBandwidth");
+  return maxBandwidth ? parseInt(maxBandwidth, 10) || 0 : 0;
+}
+
 export const TrafficPage: React.FC<TrafficPageProps> = ({setAnalyzeStatus}) => {
   const commonClasses = useCommonStyles();
   const setServiceMapModalOpen = useSetRecoilState(serviceMapModalOpenAtom);
   const [openOasModal, setOpenOasModal] = useRecoilState(oasModalOpenAtom);
 
-  const {message,error,isOpen, openSocket, closeSocket, sendQuery} = useWS(getWebsocketUrl())
+  const {message,error,isOpen, openSocket, closeSocket, sendQuery} = useWS(getWebsocketUrl(), {maxBytesPerSec: getMaxBytesPerSec()})
   const trafficViewerApi = {...api, webSocket:{open : openSocket, close: closeSocket, sendQuery: sendQuery}}
 
   const handleOpenOasModal = () => {	
@@ -199,9 +199,17 @@ export const ServiceMapModal: React.FC<ServiceMapModalProps> = ({ isOpen, onOpen
                                 <li><span style={{ background: '#205cf5' }}></span>HTTP</li>
                                 <li><span style={{ background: '#244c5a' }}></span>HTTP/2</li>
                                 <li><span style={{ background: '#244c5a' }}></span>gRPC</li>
+                                <li><span style={{ background: '#3c763d' }}></span>WS</li>
                                 <li><span style={{ background: '#ff6600' }}></span>AMQP</li>
                                 <li><span style={{ background: '#000000' }}></span>KAFKA</li>
                                 <li><span style={{ background: '#a41e11' }}></span>REDIS</li>
+                                <li><span style={{ background: '#336791' }}></span>PGSQL</li>
+                                <li><span style={{ background: '#13aa52' }}></span>MONGO</li>
+                                <li><span style={{ background: '#00758f' }}></span>MYSQL</li>
+                                <li><span style={{ background: '#5b6abf' }}></span>DNS</li>
+                                <li><span style={{ background: '#660066' }}></span>MQTT</li>
+                                <li><span style={{ background: '#c0392b' }}></span>THRIFT</li>
+                                <li><span style={{ background: '#1287b1' }}></span>CQL</li>
                             </ul>
                         </div>
                     </div>}